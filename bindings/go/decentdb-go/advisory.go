@@ -0,0 +1,89 @@
+package decentdb
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// AdvisoryLock takes the advisory lock key, waiting until it is free or
+// ctx is done, and returns a function that releases it. Advisory locks
+// mean nothing to the engine: cooperating handles and processes sharing a
+// database use them to elect a leader or run a singleton job. A lock is
+// held by the handle, not by a transaction, and is released when the
+// handle closes if unlock is never called. Locks are reentrant, so each
+// successful call needs its own unlock.
+//
+// Handles on the same file in other processes are excluded only when
+// process coordination is enabled.
+func (d *DB) AdvisoryLock(ctx context.Context, key int64) (unlock func() error, err error) {
+	if atomic.LoadUint32(&d.closed) != 0 {
+		return nil, driver.ErrBadConn
+	}
+	backoff := 100 * time.Microsecond
+	for {
+		acquired, err := d.advisoryCall(ctx, "advisory_try_lock", key)
+		if err != nil {
+			return nil, err
+		}
+		if acquired {
+			break
+		}
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+		backoff = min(backoff*2, 5*time.Millisecond)
+	}
+	var released atomic.Bool
+	return func() error {
+		// Closing the handle already released the lock.
+		if !released.CompareAndSwap(false, true) || atomic.LoadUint32(&d.closed) != 0 {
+			return nil
+		}
+		_, err := d.advisoryCall(context.Background(), "advisory_unlock", key)
+		return err
+	}, nil
+}
+
+// TryAdvisoryLock takes the advisory lock key if it is free and reports
+// whether it did. Release it with AdvisoryUnlock.
+func (d *DB) TryAdvisoryLock(key int64) (bool, error) {
+	if atomic.LoadUint32(&d.closed) != 0 {
+		return false, driver.ErrBadConn
+	}
+	return d.advisoryCall(context.Background(), "advisory_try_lock", key)
+}
+
+// AdvisoryUnlock releases one hold of the advisory lock key and reports
+// whether the handle held it.
+func (d *DB) AdvisoryUnlock(key int64) (bool, error) {
+	if atomic.LoadUint32(&d.closed) != 0 {
+		return false, driver.ErrBadConn
+	}
+	return d.advisoryCall(context.Background(), "advisory_unlock", key)
+}
+
+// advisoryCall runs the advisory lock SQL function fn on key and returns
+// its result.
+func (d *DB) advisoryCall(ctx context.Context, fn string, key int64) (bool, error) {
+	rows, err := d.c.QueryContext(ctx, "SELECT "+fn+"($1)", []driver.NamedValue{{Ordinal: 1, Value: key}})
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	dest := make([]driver.Value, 1)
+	if err := rows.Next(dest); err != nil {
+		return false, err
+	}
+	held, ok := dest[0].(bool)
+	if !ok {
+		return false, fmt.Errorf("decentdb: %s returned %T, want bool", fn, dest[0])
+	}
+	return held, nil
+}
@@ -0,0 +1,124 @@
+package decentdb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAdvisoryLock_ExcludesOtherHandles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "advisory.ddb")
+	var dbs [2]*DB
+	for i := range dbs {
+		db, err := OpenDirect(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+		dbs[i] = db
+	}
+	leader, follower := dbs[0], dbs[1]
+
+	unlock, err := leader.AdvisoryLock(context.Background(), 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := follower.TryAdvisoryLock(42); err != nil || ok {
+		t.Fatalf("follower TryAdvisoryLock = %v, %v; want false", ok, err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := follower.AdvisoryLock(ctx, 42); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("follower AdvisoryLock = %v, want deadline exceeded", err)
+	}
+	if ok, err := follower.TryAdvisoryLock(7); err != nil || !ok {
+		t.Fatalf("unrelated key = %v, %v; want true", ok, err)
+	}
+
+	if err := unlock(); err != nil {
+		t.Fatal(err)
+	}
+	if err := unlock(); err != nil {
+		t.Fatalf("second unlock = %v", err)
+	}
+	if _, err := follower.AdvisoryLock(context.Background(), 42); err != nil {
+		t.Fatal(err)
+	}
+	if err := follower.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := leader.TryAdvisoryLock(42); err != nil || !ok {
+		t.Fatalf("lock not released by Close: %v, %v", ok, err)
+	}
+}
+
+func TestAdvisoryLock_SQLFunctions(t *testing.T) {
+	db, err := OpenDirect(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, tc := range []struct {
+		sql  string
+		want bool
+	}{
+		{"SELECT advisory_lock(5)", true},
+		{"SELECT pg_advisory_unlock(5)", true},
+		{"SELECT advisory_unlock(5)", false},
+		{"select Advisory_Try_Lock(5) AS held FROM (SELECT 1) AS t", true},
+		{"SELECT pg_advisory_unlock(5) AND 1 = 1", true},
+	} {
+		var got []any
+		for row, err := range db.Rows(context.Background(), tc.sql) {
+			if err != nil {
+				t.Fatalf("%s: %v", tc.sql, err)
+			}
+			got = append(got, row.Values()[0])
+		}
+		if len(got) != 1 || got[0] != tc.want {
+			t.Fatalf("%s = %v, want %v", tc.sql, got, tc.want)
+		}
+	}
+}
+
+func TestAdvisoryLock_BypassesResultCache(t *testing.T) {
+	ctx := context.Background()
+	cache := NewResultCache(16)
+	connector, err := NewConnector(fmt.Sprintf("file:%s", filepath.Join(t.TempDir(), "advisory.ddb")), WithResultCache(cache))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	var conns [2]*sql.Conn
+	for i := range conns {
+		c, err := db.Conn(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+		conns[i] = c
+	}
+	tryLock := func(c *sql.Conn) bool {
+		t.Helper()
+		var held bool
+		if err := c.QueryRowContext(ctx, "SELECT advisory_try_lock($1)", 9).Scan(&held); err != nil {
+			t.Fatal(err)
+		}
+		return held
+	}
+	if !tryLock(conns[0]) {
+		t.Fatal("first connection did not get the lock")
+	}
+	if tryLock(conns[1]) {
+		t.Fatal("second connection got a lock the first one holds")
+	}
+	if stats := cache.Stats(); stats.Hits != 0 || stats.Entries != 0 {
+		t.Fatalf("advisory lock calls were cached: %+v", stats)
+	}
+}
@@ -0,0 +1,30 @@
+package decentdb
+
+// WithApplicationName names the service using the connector's connections.
+// The name is reported by ListSessions and recorded in the engine's
+// sys.sessions and sys.slow_queries views and in audit event context, so
+// activity on a shared database can be attributed. The application_name DSN
+// option does the same for sql.Open and takes precedence.
+func WithApplicationName(name string) ConnectorOption {
+	return func(c *connector) {
+		c.applicationName = name
+	}
+}
+
+// applicationNameSQL returns the statement that sets a connection's
+// application name.
+func applicationNameSQL(name string) string {
+	return "PRAGMA application_name = " + quoteLiteral(name)
+}
+
+// useApplicationName attributes c's activity to name.
+func (c *conn) useApplicationName(name string) error {
+	if name == "" {
+		return nil
+	}
+	if err := c.execSessionSQL(applicationNameSQL(name)); err != nil {
+		return err
+	}
+	c.applicationName = name
+	return nil
+}
@@ -0,0 +1,45 @@
+package decentdb
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+func TestApplicationNameSQL(t *testing.T) {
+	if got := applicationNameSQL("o'brien reports"); got != "PRAGMA application_name = 'o''brien reports'" {
+		t.Fatalf("applicationNameSQL = %q", got)
+	}
+}
+
+func TestApplicationName_DSNAndConnectorOption(t *testing.T) {
+	dir := t.TempDir()
+	sessionName := func(db *sql.DB) string {
+		t.Helper()
+		var name sql.NullString
+		if err := db.QueryRow("SELECT application_name FROM sys.sessions").Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		return name.String
+	}
+
+	db, err := sql.Open("decentdb", fmt.Sprintf("file:%s?application_name=billing%%20worker", filepath.Join(dir, "dsn.ddb")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if got := sessionName(db); got != "billing worker" {
+		t.Fatalf("DSN application name = %q, want %q", got, "billing worker")
+	}
+
+	connector, err := NewConnector(filepath.Join(dir, "option.ddb"), WithApplicationName("reports"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	optioned := sql.OpenDB(connector)
+	defer optioned.Close()
+	if got := sessionName(optioned); got != "reports" {
+		t.Fatalf("connector application name = %q, want %q", got, "reports")
+	}
+}
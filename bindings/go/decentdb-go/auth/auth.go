@@ -0,0 +1,197 @@
+// Package auth is the user and role model shared by the DecentDB server
+// frontends (server, grpcserver, and pgwire).
+//
+// A Store holds users, roles, and API tokens. Users authenticate with a
+// password, checked against SCRAM-SHA-256 verifiers so the plaintext is
+// never stored, or with an API token. Roles carry grants; a user holds the
+// union of its roles' grants, and superusers bypass grant checks.
+//
+//	store, err := auth.Open("/var/lib/decentdb/auth.json")
+//	_ = store.CreateRole("reporting")
+//	_ = store.Grant("reporting", auth.Grant{Database: "app", Table: "orders", Privileges: []auth.Privilege{auth.Select}})
+//	_ = store.CreateUser("alice", "correct horse")
+//	_ = store.GrantRole("alice", "reporting")
+//	_, secret, err := store.CreateToken("alice", "dashboards")
+//
+// Grants are resolved when a statement is authorized, not when a user
+// logs in, so revocations apply to sessions that are already open.
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Privilege is a kind of access a grant allows.
+type Privilege string
+
+const (
+	Select Privilege = "SELECT"
+	Insert Privilege = "INSERT"
+	Update Privilege = "UPDATE"
+	Delete Privilege = "DELETE"
+	// DDL covers CREATE, ALTER, DROP, TRUNCATE, ANALYZE, and index
+	// maintenance. It is only honored on database-wide grants.
+	DDL Privilege = "DDL"
+)
+
+// AllPrivileges lists every privilege.
+var AllPrivileges = []Privilege{Select, Insert, Update, Delete, DDL}
+
+// AnyDatabase in a Grant matches every database.
+const AnyDatabase = "*"
+
+// Grant allows privileges on one table of a database, or on every table
+// when Table is empty.
+type Grant struct {
+	Database   string      `json:"database"`
+	Table      string      `json:"table,omitempty"`
+	Privileges []Privilege `json:"privileges"`
+}
+
+var (
+	ErrNotFound           = errors.New("auth: not found")
+	ErrExists             = errors.New("auth: already exists")
+	ErrInvalidCredentials = errors.New("auth: invalid credentials")
+	ErrPermissionDenied   = errors.New("auth: permission denied")
+)
+
+// Statement is what the engine reports about a statement for
+// authorization. internal/authz builds it from a decentdb.StmtInfo.
+type Statement struct {
+	// Kind is the engine's statement kind, such as "query" or
+	// "create_table". KindTransaction marks transaction control, and an
+	// empty Kind a statement the engine could not describe.
+	Kind string
+	// Tables lists the tables the statement touches; Complete is false
+	// when the list may be missing some.
+	Tables   []string
+	Complete bool
+	// Target is the table an INSERT, UPDATE, or DELETE writes.
+	Target string
+}
+
+// KindTransaction is the Kind of BEGIN, COMMIT, ROLLBACK, and savepoint
+// statements, which every user may run.
+const KindTransaction = "transaction"
+
+// Principal is an authenticated user of a Store.
+type Principal struct {
+	User  string
+	store *Store
+}
+
+// CanAccess reports whether the user is a superuser or holds any grant
+// on database.
+func (p *Principal) CanAccess(database string) bool {
+	u, grants, ok := p.store.resolve(p.User)
+	if !ok {
+		return false
+	}
+	if u.Superuser {
+		return true
+	}
+	for _, g := range grants {
+		if g.Database == AnyDatabase || g.Database == database {
+			return true
+		}
+	}
+	return false
+}
+
+// Authorize returns an error wrapping ErrPermissionDenied unless the user
+// may run st against database.
+//
+// Queries need SELECT on every table they read; INSERT, UPDATE, and DELETE
+// need that privilege on the target table and SELECT on the others. When
+// the engine cannot list the tables exhaustively, the privilege must be
+// held database-wide. Schema changes need DDL on the database, and
+// statements the engine cannot describe need every privilege on it.
+func (p *Principal) Authorize(database string, st Statement) error {
+	u, grants, ok := p.store.resolve(p.User)
+	if !ok {
+		return fmt.Errorf("%w: user %q no longer exists", ErrPermissionDenied, p.User)
+	}
+	if u.Superuser || st.Kind == KindTransaction {
+		return nil
+	}
+	check := func(priv Privilege, table string) error {
+		if allowed(grants, database, table, priv) {
+			return nil
+		}
+		on := fmt.Sprintf("database %q", database)
+		if table != "" {
+			on = fmt.Sprintf("table %q in %s", table, on)
+		}
+		return fmt.Errorf("%w: user %q lacks %s on %s", ErrPermissionDenied, p.User, priv, on)
+	}
+
+	var write Privilege
+	switch st.Kind {
+	case "query", "explain":
+	case "insert":
+		write = Insert
+	case "update":
+		write = Update
+	case "delete":
+		write = Delete
+	case "":
+		for _, priv := range AllPrivileges {
+			if err := check(priv, ""); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return check(DDL, "")
+	}
+	if write != "" {
+		if err := check(write, st.Target); err != nil {
+			return err
+		}
+	}
+	if !st.Complete {
+		return check(Select, "")
+	}
+	for _, table := range st.Tables {
+		if write != "" && strings.EqualFold(table, st.Target) {
+			continue
+		}
+		if err := check(Select, table); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// allowed reports whether grants give priv on table, or database-wide
+// when table is empty. Table names compare case-insensitively, like SQL
+// identifiers.
+func allowed(grants []Grant, database, table string, priv Privilege) bool {
+	for _, g := range grants {
+		if g.Database != AnyDatabase && g.Database != database {
+			continue
+		}
+		if g.Table != "" && (table == "" || priv == DDL || !strings.EqualFold(g.Table, table)) {
+			continue
+		}
+		if slices.Contains(g.Privileges, priv) {
+			return true
+		}
+	}
+	return false
+}
+
+func validPrivileges(privs []Privilege) error {
+	if len(privs) == 0 {
+		return errors.New("auth: grant has no privileges")
+	}
+	for _, p := range privs {
+		if !slices.Contains(AllPrivileges, p) {
+			return fmt.Errorf("auth: unknown privilege %q", p)
+		}
+	}
+	return nil
+}
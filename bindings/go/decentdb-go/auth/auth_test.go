@@ -0,0 +1,178 @@
+package auth
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func mustNoErr(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	s := NewStore()
+	mustNoErr(t, s.CreateRole("clerk"))
+	mustNoErr(t, s.Grant("clerk", Grant{Database: "app", Table: "orders", Privileges: []Privilege{Select, Insert}}))
+	mustNoErr(t, s.Grant("clerk", Grant{Database: "app", Table: "Customers", Privileges: []Privilege{Select}}))
+	mustNoErr(t, s.CreateUser("clerk1", "pw"))
+	mustNoErr(t, s.GrantRole("clerk1", "clerk"))
+	p, err := s.AuthenticatePassword("clerk1", "pw")
+	mustNoErr(t, err)
+
+	cases := []struct {
+		name     string
+		database string
+		st       Statement
+		ok       bool
+	}{
+		{"join of granted tables", "app", Statement{Kind: "query", Tables: []string{"customers", "orders"}, Complete: true}, true},
+		{"ungranted table", "app", Statement{Kind: "query", Tables: []string{"orders", "payroll"}, Complete: true}, false},
+		{"other database", "hr", Statement{Kind: "query", Tables: []string{"orders"}, Complete: true}, false},
+		{"insert select", "app", Statement{Kind: "insert", Tables: []string{"customers", "orders"}, Complete: true, Target: "orders"}, true},
+		{"delete without privilege", "app", Statement{Kind: "delete", Tables: []string{"orders"}, Complete: true, Target: "orders"}, false},
+		{"incomplete table list", "app", Statement{Kind: "query", Tables: []string{"orders"}}, false},
+		{"ddl", "app", Statement{Kind: "create_table"}, false},
+		{"undescribed", "app", Statement{}, false},
+		{"transaction control", "hr", Statement{Kind: KindTransaction}, true},
+	}
+	for _, c := range cases {
+		err := p.Authorize(c.database, c.st)
+		if c.ok && err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if !c.ok && !errors.Is(err, ErrPermissionDenied) {
+			t.Fatalf("%s: err = %v, want ErrPermissionDenied", c.name, err)
+		}
+	}
+	if !p.CanAccess("app") || p.CanAccess("hr") {
+		t.Fatal("CanAccess does not follow grants")
+	}
+
+	// Grants resolve at check time, so changes reach existing principals.
+	mustNoErr(t, s.Grant("clerk", Grant{Database: AnyDatabase, Privileges: []Privilege{Select, DDL}}))
+	mustNoErr(t, p.Authorize("hr", Statement{Kind: "query", Tables: []string{"payroll"}}))
+	mustNoErr(t, p.Authorize("app", Statement{Kind: "create_index"}))
+	mustNoErr(t, s.RevokeRole("clerk1", "clerk"))
+	if err := p.Authorize("app", Statement{Kind: "query", Tables: []string{"orders"}, Complete: true}); !errors.Is(err, ErrPermissionDenied) {
+		t.Fatalf("after RevokeRole: err = %v", err)
+	}
+
+	mustNoErr(t, s.SetSuperuser("clerk1", true))
+	mustNoErr(t, p.Authorize("hr", Statement{}))
+	mustNoErr(t, s.DropUser("clerk1"))
+	if err := p.Authorize("hr", Statement{}); !errors.Is(err, ErrPermissionDenied) {
+		t.Fatalf("after DropUser: err = %v", err)
+	}
+}
+
+func TestGrantAndRevoke(t *testing.T) {
+	s := NewStore()
+	mustNoErr(t, s.CreateRole("r"))
+	if err := s.Grant("r", Grant{Database: "app", Table: "t", Privileges: []Privilege{DDL}}); err == nil {
+		t.Fatal("Grant accepted DDL on a table")
+	}
+	if err := s.Grant("r", Grant{Database: "app", Privileges: []Privilege{"TRUNCATE"}}); err == nil {
+		t.Fatal("Grant accepted an unknown privilege")
+	}
+	if err := s.Grant("missing", Grant{Database: "app", Privileges: []Privilege{Select}}); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Grant to a missing role: err = %v", err)
+	}
+	mustNoErr(t, s.Grant("r", Grant{Database: "app", Table: "t", Privileges: []Privilege{Select}}))
+	mustNoErr(t, s.Grant("r", Grant{Database: "app", Table: "T", Privileges: []Privilege{Update, Select}}))
+	mustNoErr(t, s.Revoke("r", Grant{Database: "app", Table: "t", Privileges: []Privilege{Select}}))
+	roles := s.Roles()
+	if len(roles) != 1 || len(roles[0].Grants) != 1 {
+		t.Fatalf("Roles() = %+v", roles)
+	}
+	if g := roles[0].Grants[0]; len(g.Privileges) != 1 || g.Privileges[0] != Update {
+		t.Fatalf("grant after revoke = %+v", g)
+	}
+	mustNoErr(t, s.Revoke("r", Grant{Database: "app", Table: "t", Privileges: []Privilege{Update}}))
+	if roles := s.Roles(); len(roles[0].Grants) != 0 {
+		t.Fatalf("empty grant kept: %+v", roles[0].Grants)
+	}
+}
+
+func TestPasswordsAndTokens(t *testing.T) {
+	s := NewStore()
+	mustNoErr(t, s.CreateUser("alice", "secret"))
+	mustNoErr(t, s.CreateUser("svc", ""))
+	if err := s.CreateUser("alice", "x"); !errors.Is(err, ErrExists) {
+		t.Fatalf("duplicate user: err = %v", err)
+	}
+	if err := s.CreateUser("bad:name", "x"); err == nil {
+		t.Fatal("CreateUser accepted a name with a colon")
+	}
+
+	for _, c := range []struct{ user, password string }{{"alice", "wrong"}, {"nobody", "secret"}, {"svc", ""}} {
+		if _, err := s.AuthenticatePassword(c.user, c.password); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("AuthenticatePassword(%q, %q): err = %v", c.user, c.password, err)
+		}
+	}
+	mustNoErr(t, s.SetPassword("alice", "rotated"))
+	if _, err := s.AuthenticatePassword("alice", "secret"); err == nil {
+		t.Fatal("old password still accepted")
+	}
+	if p, err := s.AuthenticatePassword("alice", "rotated"); err != nil || p.User != "alice" {
+		t.Fatalf("AuthenticatePassword = %v, %v", p, err)
+	}
+
+	tok, secret, err := s.CreateToken("svc", "ci")
+	mustNoErr(t, err)
+	if p, err := s.AuthenticateToken(secret); err != nil || p.User != "svc" {
+		t.Fatalf("AuthenticateToken = %v, %v", p, err)
+	}
+	if _, err := s.AuthenticateToken(secret + "x"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("tampered token: err = %v", err)
+	}
+	if got := s.Tokens("svc"); len(got) != 1 || got[0].ID != tok.ID || got[0].Name != "ci" {
+		t.Fatalf("Tokens = %+v", got)
+	}
+	mustNoErr(t, s.RevokeToken(tok.ID))
+	if _, err := s.AuthenticateToken(secret); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("revoked token: err = %v", err)
+	}
+	if _, _, err := s.CreateToken("nobody", ""); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("token for a missing user: err = %v", err)
+	}
+}
+
+func TestOpenPersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "auth.json")
+	s, err := Open(path)
+	mustNoErr(t, err)
+	mustNoErr(t, s.CreateUser("alice", "secret"))
+	mustNoErr(t, s.CreateRole("reader"))
+	mustNoErr(t, s.Grant("reader", Grant{Database: "app", Privileges: []Privilege{Select}}))
+	mustNoErr(t, s.GrantRole("alice", "reader"))
+	_, secret, err := s.CreateToken("alice", "")
+	mustNoErr(t, err)
+
+	fi, err := os.Stat(path)
+	mustNoErr(t, err)
+	if fi.Mode().Perm() != 0o600 {
+		t.Fatalf("store file mode = %v, want 0600", fi.Mode().Perm())
+	}
+	raw, err := os.ReadFile(path)
+	mustNoErr(t, err)
+	for _, plaintext := range []string{"secret", secret} {
+		if bytes.Contains(raw, []byte(plaintext)) {
+			t.Fatalf("store file contains plaintext %q", plaintext)
+		}
+	}
+
+	reopened, err := Open(path)
+	mustNoErr(t, err)
+	p, err := reopened.AuthenticatePassword("alice", "secret")
+	mustNoErr(t, err)
+	mustNoErr(t, p.Authorize("app", Statement{Kind: "query", Tables: []string{"t"}, Complete: true}))
+	if _, err := reopened.AuthenticateToken(secret); err != nil {
+		t.Fatalf("token after reopen: %v", err)
+	}
+}
@@ -0,0 +1,202 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// SCRAMSHA256 is the SASL mechanism name of SCRAM-SHA-256.
+const SCRAMSHA256 = "SCRAM-SHA-256"
+
+// scramIterations matches the PostgreSQL default.
+const scramIterations = 4096
+
+// scramCredential is the SCRAM verifier of a password (RFC 5802 section
+// 3). It can check a password or a SCRAM proof but cannot recover the
+// password.
+type scramCredential struct {
+	Salt       []byte `json:"salt"`
+	Iterations int    `json:"iterations"`
+	StoredKey  []byte `json:"stored_key"`
+	ServerKey  []byte `json:"server_key"`
+}
+
+func newSCRAMCredential(password string) (*scramCredential, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return deriveCredential(password, salt, scramIterations)
+}
+
+func deriveCredential(password string, salt []byte, iterations int) (*scramCredential, error) {
+	salted, err := pbkdf2.Key(sha256.New, password, salt, iterations, sha256.Size)
+	if err != nil {
+		return nil, err
+	}
+	clientKey := hmacSHA256(salted, "Client Key")
+	storedKey := sha256.Sum256(clientKey)
+	return &scramCredential{
+		Salt:       salt,
+		Iterations: iterations,
+		StoredKey:  storedKey[:],
+		ServerKey:  hmacSHA256(salted, "Server Key"),
+	}, nil
+}
+
+// mockCredential stands in for users that do not exist or have no
+// password. The salt is derived from the name so repeated attempts see a
+// stable salt, as they would for a real user.
+func mockCredential(user string) *scramCredential {
+	salt := sha256.Sum256([]byte("decentdb-scram-mock\x00" + user))
+	var key [sha256.Size]byte
+	_, _ = rand.Read(key[:])
+	return &scramCredential{Salt: salt[:16], Iterations: scramIterations, StoredKey: key[:], ServerKey: key[:]}
+}
+
+func (c *scramCredential) verifyPassword(password string) bool {
+	derived, err := deriveCredential(password, c.Salt, c.Iterations)
+	return err == nil && subtle.ConstantTimeCompare(derived.StoredKey, c.StoredKey) == 1
+}
+
+func hmacSHA256(key []byte, msg string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(msg))
+	return h.Sum(nil)
+}
+
+// SCRAM is the server side of one SCRAM-SHA-256 exchange (RFC 7677)
+// without channel binding, as used by PostgreSQL SASL authentication:
+//
+//	conv := store.NewSCRAM(user)
+//	serverFirst, err := conv.ServerFirst(clientFirst)
+//	serverFinal, principal, err := conv.ServerFinal(clientFinal)
+//
+// The user name inside the client's messages is ignored; the exchange
+// authenticates the user passed to NewSCRAM.
+type SCRAM struct {
+	store *Store
+	user  string
+	known bool
+	cred  *scramCredential
+
+	clientFirstBare string
+	serverFirst     string
+	nonce           string
+	gs2Header       string
+}
+
+// NewSCRAM starts an exchange for user. Unknown users run the exchange
+// against a mock verifier and fail at the final step, so clients cannot
+// tell them apart from wrong passwords.
+func (s *Store) NewSCRAM(user string) *SCRAM {
+	conv := &SCRAM{store: s, user: user}
+	s.mu.RLock()
+	if u, ok := s.data.Users[user]; ok && u.SCRAM != nil {
+		conv.cred, conv.known = u.SCRAM, true
+	}
+	s.mu.RUnlock()
+	if conv.cred == nil {
+		conv.cred = mockCredential(user)
+	}
+	return conv
+}
+
+// ServerFirst answers the client-first-message.
+func (c *SCRAM) ServerFirst(clientFirst []byte) ([]byte, error) {
+	msg := string(clientFirst)
+	// gs2-header: channel binding flag, optional authzid, then the bare
+	// message.
+	cbFlag, rest, ok := strings.Cut(msg, ",")
+	if !ok {
+		return nil, errors.New("auth: malformed SCRAM client-first-message")
+	}
+	switch {
+	case cbFlag == "n" || cbFlag == "y":
+	case strings.HasPrefix(cbFlag, "p="):
+		return nil, errors.New("auth: SCRAM channel binding is not supported")
+	default:
+		return nil, errors.New("auth: malformed SCRAM client-first-message")
+	}
+	authzid, bare, ok := strings.Cut(rest, ",")
+	if !ok {
+		return nil, errors.New("auth: malformed SCRAM client-first-message")
+	}
+	if authzid != "" {
+		return nil, errors.New("auth: SCRAM authorization identities are not supported")
+	}
+	attrs := scramAttributes(bare)
+	clientNonce := attrs["r"]
+	if clientNonce == "" {
+		return nil, errors.New("auth: SCRAM client-first-message has no nonce")
+	}
+	if _, ok := attrs["m"]; ok {
+		return nil, errors.New("auth: unsupported SCRAM extension")
+	}
+	var serverNonce [18]byte
+	if _, err := rand.Read(serverNonce[:]); err != nil {
+		return nil, err
+	}
+	c.gs2Header = cbFlag + "," + authzid + ","
+	c.clientFirstBare = bare
+	c.nonce = clientNonce + base64.StdEncoding.EncodeToString(serverNonce[:])
+	c.serverFirst = fmt.Sprintf("r=%s,s=%s,i=%d", c.nonce, base64.StdEncoding.EncodeToString(c.cred.Salt), c.cred.Iterations)
+	return []byte(c.serverFirst), nil
+}
+
+// ServerFinal checks the client-final-message. On success it returns the
+// server-final-message, which proves to the client that the server knows
+// the verifier, and the authenticated principal.
+func (c *SCRAM) ServerFinal(clientFinal []byte) ([]byte, *Principal, error) {
+	if c.serverFirst == "" {
+		return nil, nil, errors.New("auth: SCRAM exchange out of order")
+	}
+	msg := string(clientFinal)
+	withoutProof, proofAttr, ok := strings.Cut(msg, ",p=")
+	if !ok {
+		return nil, nil, errors.New("auth: SCRAM client-final-message has no proof")
+	}
+	attrs := scramAttributes(withoutProof)
+	if attrs["c"] != base64.StdEncoding.EncodeToString([]byte(c.gs2Header)) {
+		return nil, nil, errors.New("auth: SCRAM channel binding mismatch")
+	}
+	if attrs["r"] != c.nonce {
+		return nil, nil, errors.New("auth: SCRAM nonce mismatch")
+	}
+	proof, err := base64.StdEncoding.DecodeString(proofAttr)
+	if err != nil || len(proof) != sha256.Size {
+		return nil, nil, errors.New("auth: malformed SCRAM proof")
+	}
+
+	authMessage := c.clientFirstBare + "," + c.serverFirst + "," + withoutProof
+	signature := hmacSHA256(c.cred.StoredKey, authMessage)
+	clientKey := make([]byte, sha256.Size)
+	for i := range clientKey {
+		clientKey[i] = proof[i] ^ signature[i]
+	}
+	storedKey := sha256.Sum256(clientKey)
+	if subtle.ConstantTimeCompare(storedKey[:], c.cred.StoredKey) != 1 || !c.known {
+		return nil, nil, ErrInvalidCredentials
+	}
+	serverSignature := hmacSHA256(c.cred.ServerKey, authMessage)
+	final := "v=" + base64.StdEncoding.EncodeToString(serverSignature)
+	return []byte(final), &Principal{User: c.user, store: c.store}, nil
+}
+
+// scramAttributes splits "k=v,k=v" into a map; later keys win.
+func scramAttributes(s string) map[string]string {
+	attrs := make(map[string]string)
+	for _, field := range strings.Split(s, ",") {
+		if key, value, ok := strings.Cut(field, "="); ok && len(key) == 1 {
+			attrs[key] = value
+		}
+	}
+	return attrs
+}
@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// scramClient computes the client side of an exchange per RFC 5802.
+func scramClient(t *testing.T, conv *SCRAM, password string) ([]byte, *Principal, error) {
+	t.Helper()
+	clientFirstBare := "n=,r=rOprNGfwEbeRWgbNEkqO"
+	serverFirst, err := conv.ServerFirst([]byte("n,," + clientFirstBare))
+	if err != nil {
+		t.Fatal(err)
+	}
+	attrs := scramAttributes(string(serverFirst))
+	salt, err := base64.StdEncoding.DecodeString(attrs["s"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	iterations, err := strconv.Atoi(attrs["i"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(attrs["r"], "rOprNGfwEbeRWgbNEkqO") {
+		t.Fatalf("server nonce %q does not extend the client nonce", attrs["r"])
+	}
+	salted, err := pbkdf2.Key(sha256.New, password, salt, iterations, sha256.Size)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientKey := hmacSHA256(salted, "Client Key")
+	storedKey := sha256.Sum256(clientKey)
+	withoutProof := "c=biws,r=" + attrs["r"]
+	authMessage := clientFirstBare + "," + string(serverFirst) + "," + withoutProof
+	signature := hmacSHA256(storedKey[:], authMessage)
+	proof := make([]byte, len(clientKey))
+	for i := range proof {
+		proof[i] = clientKey[i] ^ signature[i]
+	}
+	serverFinal, p, err := conv.ServerFinal([]byte(withoutProof + ",p=" + base64.StdEncoding.EncodeToString(proof)))
+	if err == nil {
+		want := "v=" + base64.StdEncoding.EncodeToString(hmacSHA256(hmacSHA256(salted, "Server Key"), authMessage))
+		if !hmac.Equal(serverFinal, []byte(want)) {
+			t.Fatalf("server signature = %q, want %q", serverFinal, want)
+		}
+	}
+	return serverFinal, p, err
+}
+
+func TestSCRAM(t *testing.T) {
+	s := NewStore()
+	mustNoErr(t, s.CreateUser("alice", "pencil"))
+
+	_, p, err := scramClient(t, s.NewSCRAM("alice"), "pencil")
+	if err != nil || p.User != "alice" {
+		t.Fatalf("exchange = %v, %v", p, err)
+	}
+	if _, _, err := scramClient(t, s.NewSCRAM("alice"), "pen"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("wrong password: err = %v", err)
+	}
+	if _, _, err := scramClient(t, s.NewSCRAM("bob"), "pencil"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("unknown user: err = %v", err)
+	}
+}
+
+func TestSCRAMRejectsMalformedMessages(t *testing.T) {
+	s := NewStore()
+	mustNoErr(t, s.CreateUser("alice", "pencil"))
+	for _, first := range []string{"", "p=tls-server-end-point,,n=,r=abc", "n,a=bob,n=,r=abc", "n,,n="} {
+		if _, err := s.NewSCRAM("alice").ServerFirst([]byte(first)); err == nil {
+			t.Fatalf("ServerFirst(%q) accepted", first)
+		}
+	}
+
+	conv := s.NewSCRAM("alice")
+	serverFirst, err := conv.ServerFirst([]byte("n,,n=,r=abc"))
+	mustNoErr(t, err)
+	nonce := scramAttributes(string(serverFirst))["r"]
+	proof := base64.StdEncoding.EncodeToString(make([]byte, sha256.Size))
+	for _, final := range []string{
+		"c=biws,r=" + nonce,
+		"c=eSws,r=" + nonce + ",p=" + proof,
+		"c=biws,r=abc,p=" + proof,
+		"c=biws,r=" + nonce + ",p=short",
+	} {
+		if _, _, err := conv.ServerFinal([]byte(final)); err == nil {
+			t.Fatalf("ServerFinal(%q) accepted", final)
+		}
+	}
+}
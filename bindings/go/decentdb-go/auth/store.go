@@ -0,0 +1,534 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// tokenPrefix starts every API token secret, so leaked tokens are easy to
+// recognize in logs and secret scanners.
+const tokenPrefix = "ddbt_"
+
+// User describes a user of a Store.
+type User struct {
+	Name      string
+	Superuser bool
+	Roles     []string
+	// HasPassword is false for users that can only authenticate with
+	// API tokens.
+	HasPassword bool
+}
+
+// Role is a named set of grants.
+type Role struct {
+	Name   string
+	Grants []Grant
+}
+
+// Token describes an API token. The secret itself is only returned by
+// CreateToken.
+type Token struct {
+	ID      string
+	User    string
+	Name    string
+	Created time.Time
+}
+
+// Store holds users, roles, and tokens. Stores opened with Open persist
+// every change to their file; the zero value is not usable, use NewStore
+// or Open. A Store is safe for concurrent use.
+type Store struct {
+	mu   sync.RWMutex
+	path string
+	data storeData
+}
+
+type storeData struct {
+	Users  map[string]*userRecord  `json:"users"`
+	Roles  map[string]*roleRecord  `json:"roles"`
+	Tokens map[string]*tokenRecord `json:"tokens"`
+}
+
+type userRecord struct {
+	Superuser bool             `json:"superuser,omitempty"`
+	Roles     []string         `json:"roles,omitempty"`
+	SCRAM     *scramCredential `json:"scram,omitempty"`
+}
+
+type roleRecord struct {
+	Grants []Grant `json:"grants"`
+}
+
+type tokenRecord struct {
+	User    string    `json:"user"`
+	Name    string    `json:"name,omitempty"`
+	Hash    []byte    `json:"hash"`
+	Created time.Time `json:"created"`
+}
+
+// NewStore returns an empty in-memory Store.
+func NewStore() *Store {
+	s := &Store{}
+	s.data.init()
+	return s
+}
+
+// Open loads the Store saved at path, or starts an empty one that is
+// written there on the first change. The file holds password verifiers
+// and token hashes, never plaintext secrets, and is created with mode
+// 0600.
+func Open(path string) (*Store, error) {
+	s := &Store{path: path}
+	raw, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return nil, err
+	default:
+		if err := json.Unmarshal(raw, &s.data); err != nil {
+			return nil, fmt.Errorf("auth: reading %s: %w", path, err)
+		}
+	}
+	s.data.init()
+	return s, nil
+}
+
+func (d *storeData) init() {
+	if d.Users == nil {
+		d.Users = make(map[string]*userRecord)
+	}
+	if d.Roles == nil {
+		d.Roles = make(map[string]*roleRecord)
+	}
+	if d.Tokens == nil {
+		d.Tokens = make(map[string]*tokenRecord)
+	}
+}
+
+// update applies fn under the write lock and persists the result. If
+// saving fails the change is rolled back.
+func (s *Store) update(fn func(d *storeData) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	before, err := json.Marshal(&s.data)
+	if err != nil {
+		return err
+	}
+	if err := fn(&s.data); err != nil {
+		return err
+	}
+	if err := s.save(); err != nil {
+		var restored storeData
+		if json.Unmarshal(before, &restored) == nil {
+			restored.init()
+			s.data = restored
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *Store) save() error {
+	if s.path == "" {
+		return nil
+	}
+	raw, err := json.MarshalIndent(&s.data, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(raw); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+func validName(kind, name string) error {
+	if name == "" {
+		return fmt.Errorf("auth: %s name is empty", kind)
+	}
+	if strings.ContainsRune(name, ':') || strings.ContainsFunc(name, func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsControl(r)
+	}) {
+		return fmt.Errorf("auth: invalid %s name %q", kind, name)
+	}
+	return nil
+}
+
+func notFound(kind, name string) error {
+	return fmt.Errorf("%w: %s %q", ErrNotFound, kind, name)
+}
+
+// CreateUser adds a user. An empty password creates a user that can only
+// authenticate with API tokens.
+func (s *Store) CreateUser(name, password string) error {
+	if err := validName("user", name); err != nil {
+		return err
+	}
+	u := &userRecord{}
+	if password != "" {
+		cred, err := newSCRAMCredential(password)
+		if err != nil {
+			return err
+		}
+		u.SCRAM = cred
+	}
+	return s.update(func(d *storeData) error {
+		if _, ok := d.Users[name]; ok {
+			return fmt.Errorf("%w: user %q", ErrExists, name)
+		}
+		d.Users[name] = u
+		return nil
+	})
+}
+
+// SetPassword replaces a user's password. An empty password disables
+// password authentication for the user.
+func (s *Store) SetPassword(name, password string) error {
+	var cred *scramCredential
+	if password != "" {
+		var err error
+		if cred, err = newSCRAMCredential(password); err != nil {
+			return err
+		}
+	}
+	return s.update(func(d *storeData) error {
+		u, ok := d.Users[name]
+		if !ok {
+			return notFound("user", name)
+		}
+		u.SCRAM = cred
+		return nil
+	})
+}
+
+// SetSuperuser makes a user a superuser, which bypasses every grant
+// check, or revokes that status.
+func (s *Store) SetSuperuser(name string, superuser bool) error {
+	return s.update(func(d *storeData) error {
+		u, ok := d.Users[name]
+		if !ok {
+			return notFound("user", name)
+		}
+		u.Superuser = superuser
+		return nil
+	})
+}
+
+// DropUser removes a user and revokes its API tokens.
+func (s *Store) DropUser(name string) error {
+	return s.update(func(d *storeData) error {
+		if _, ok := d.Users[name]; !ok {
+			return notFound("user", name)
+		}
+		delete(d.Users, name)
+		for id, t := range d.Tokens {
+			if t.User == name {
+				delete(d.Tokens, id)
+			}
+		}
+		return nil
+	})
+}
+
+// Users lists the users sorted by name.
+func (s *Store) Users() []User {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]User, 0, len(s.data.Users))
+	for name, u := range s.data.Users {
+		out = append(out, User{
+			Name:        name,
+			Superuser:   u.Superuser,
+			Roles:       slices.Clone(u.Roles),
+			HasPassword: u.SCRAM != nil,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// CreateRole adds a role with no grants.
+func (s *Store) CreateRole(name string) error {
+	if err := validName("role", name); err != nil {
+		return err
+	}
+	return s.update(func(d *storeData) error {
+		if _, ok := d.Roles[name]; ok {
+			return fmt.Errorf("%w: role %q", ErrExists, name)
+		}
+		d.Roles[name] = &roleRecord{}
+		return nil
+	})
+}
+
+// DropRole removes a role and takes it away from every user holding it.
+func (s *Store) DropRole(name string) error {
+	return s.update(func(d *storeData) error {
+		if _, ok := d.Roles[name]; !ok {
+			return notFound("role", name)
+		}
+		delete(d.Roles, name)
+		for _, u := range d.Users {
+			u.Roles = slices.DeleteFunc(u.Roles, func(r string) bool { return r == name })
+		}
+		return nil
+	})
+}
+
+// Roles lists the roles sorted by name.
+func (s *Store) Roles() []Role {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]Role, 0, len(s.data.Roles))
+	for name, r := range s.data.Roles {
+		grants := make([]Grant, len(r.Grants))
+		for i, g := range r.Grants {
+			grants[i] = Grant{Database: g.Database, Table: g.Table, Privileges: slices.Clone(g.Privileges)}
+		}
+		out = append(out, Role{Name: name, Grants: grants})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// GrantRole gives a role to a user.
+func (s *Store) GrantRole(user, role string) error {
+	return s.update(func(d *storeData) error {
+		u, ok := d.Users[user]
+		if !ok {
+			return notFound("user", user)
+		}
+		if _, ok := d.Roles[role]; !ok {
+			return notFound("role", role)
+		}
+		if !slices.Contains(u.Roles, role) {
+			u.Roles = append(u.Roles, role)
+			slices.Sort(u.Roles)
+		}
+		return nil
+	})
+}
+
+// RevokeRole takes a role away from a user.
+func (s *Store) RevokeRole(user, role string) error {
+	return s.update(func(d *storeData) error {
+		u, ok := d.Users[user]
+		if !ok {
+			return notFound("user", user)
+		}
+		u.Roles = slices.DeleteFunc(u.Roles, func(r string) bool { return r == role })
+		return nil
+	})
+}
+
+// Grant adds g's privileges to a role. Privileges merge with an existing
+// grant on the same database and table.
+func (s *Store) Grant(role string, g Grant) error {
+	if g.Database == "" {
+		return errors.New("auth: grant has no database")
+	}
+	if err := validPrivileges(g.Privileges); err != nil {
+		return err
+	}
+	if g.Table != "" && slices.Contains(g.Privileges, DDL) {
+		return errors.New("auth: DDL can only be granted on a whole database")
+	}
+	return s.update(func(d *storeData) error {
+		r, ok := d.Roles[role]
+		if !ok {
+			return notFound("role", role)
+		}
+		for i := range r.Grants {
+			existing := &r.Grants[i]
+			if existing.Database == g.Database && strings.EqualFold(existing.Table, g.Table) {
+				for _, p := range g.Privileges {
+					if !slices.Contains(existing.Privileges, p) {
+						existing.Privileges = append(existing.Privileges, p)
+					}
+				}
+				return nil
+			}
+		}
+		r.Grants = append(r.Grants, Grant{Database: g.Database, Table: g.Table, Privileges: slices.Clone(g.Privileges)})
+		return nil
+	})
+}
+
+// Revoke removes g's privileges from the role's grant on the same
+// database and table. It does not narrow broader grants: revoking SELECT
+// on one table leaves a database-wide SELECT in place.
+func (s *Store) Revoke(role string, g Grant) error {
+	if err := validPrivileges(g.Privileges); err != nil {
+		return err
+	}
+	return s.update(func(d *storeData) error {
+		r, ok := d.Roles[role]
+		if !ok {
+			return notFound("role", role)
+		}
+		kept := r.Grants[:0]
+		for _, existing := range r.Grants {
+			if existing.Database == g.Database && strings.EqualFold(existing.Table, g.Table) {
+				existing.Privileges = slices.DeleteFunc(slices.Clone(existing.Privileges), func(p Privilege) bool {
+					return slices.Contains(g.Privileges, p)
+				})
+				if len(existing.Privileges) == 0 {
+					continue
+				}
+			}
+			kept = append(kept, existing)
+		}
+		r.Grants = kept
+		return nil
+	})
+}
+
+// CreateToken issues an API token for user and returns its description
+// and secret. Only a hash of the secret is kept, so it cannot be shown
+// again.
+func (s *Store) CreateToken(user, name string) (Token, string, error) {
+	var raw [32]byte
+	if _, err := rand.Read(raw[:]); err != nil {
+		return Token{}, "", err
+	}
+	var idBytes [8]byte
+	if _, err := rand.Read(idBytes[:]); err != nil {
+		return Token{}, "", err
+	}
+	secret := tokenPrefix + base64.RawURLEncoding.EncodeToString(raw[:])
+	hash := sha256.Sum256([]byte(secret))
+	t := Token{ID: hex.EncodeToString(idBytes[:]), User: user, Name: name, Created: time.Now().UTC()}
+	err := s.update(func(d *storeData) error {
+		if _, ok := d.Users[user]; !ok {
+			return notFound("user", user)
+		}
+		d.Tokens[t.ID] = &tokenRecord{User: user, Name: name, Hash: hash[:], Created: t.Created}
+		return nil
+	})
+	if err != nil {
+		return Token{}, "", err
+	}
+	return t, secret, nil
+}
+
+// RevokeToken deletes an API token.
+func (s *Store) RevokeToken(id string) error {
+	return s.update(func(d *storeData) error {
+		if _, ok := d.Tokens[id]; !ok {
+			return notFound("token", id)
+		}
+		delete(d.Tokens, id)
+		return nil
+	})
+}
+
+// Tokens lists the API tokens of user, or of every user when user is
+// empty, oldest first.
+func (s *Store) Tokens(user string) []Token {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var out []Token
+	for id, t := range s.data.Tokens {
+		if user == "" || t.User == user {
+			out = append(out, Token{ID: id, User: t.User, Name: t.Name, Created: t.Created})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].Created.Equal(out[j].Created) {
+			return out[i].Created.Before(out[j].Created)
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
+
+// AuthenticatePassword checks a user's password.
+func (s *Store) AuthenticatePassword(user, password string) (*Principal, error) {
+	s.mu.RLock()
+	u, ok := s.data.Users[user]
+	var cred *scramCredential
+	if ok {
+		cred = u.SCRAM
+	}
+	s.mu.RUnlock()
+	if cred == nil {
+		// Spend the same work as a real check so response times do not
+		// reveal which users exist.
+		cred = mockCredential(user)
+		ok = false
+	}
+	if !cred.verifyPassword(password) || !ok {
+		return nil, ErrInvalidCredentials
+	}
+	return &Principal{User: user, store: s}, nil
+}
+
+// AuthenticateToken checks an API token secret.
+func (s *Store) AuthenticateToken(secret string) (*Principal, error) {
+	if !strings.HasPrefix(secret, tokenPrefix) {
+		return nil, ErrInvalidCredentials
+	}
+	hash := sha256.Sum256([]byte(secret))
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, t := range s.data.Tokens {
+		if subtle.ConstantTimeCompare(t.Hash, hash[:]) == 1 {
+			if _, ok := s.data.Users[t.User]; !ok {
+				break
+			}
+			return &Principal{User: t.User, store: s}, nil
+		}
+	}
+	return nil, ErrInvalidCredentials
+}
+
+// resolve returns a user and the grants of its roles.
+func (s *Store) resolve(name string) (userRecord, []Grant, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	u, ok := s.data.Users[name]
+	if !ok {
+		return userRecord{}, nil, false
+	}
+	var grants []Grant
+	for _, role := range u.Roles {
+		if r, ok := s.data.Roles[role]; ok {
+			for _, g := range r.Grants {
+				grants = append(grants, Grant{Database: g.Database, Table: g.Table, Privileges: slices.Clone(g.Privileges)})
+			}
+		}
+	}
+	return userRecord{Superuser: u.Superuser}, grants, true
+}
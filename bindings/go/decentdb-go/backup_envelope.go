@@ -0,0 +1,340 @@
+package decentdb
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"math"
+)
+
+// Backup envelope layout. All integers are little-endian.
+//
+//	header: magic "DDBBAK01" | version u16 | flags u16 | chunk size u32 |
+//	        key id length u16 | key id | nonce prefix [4] (encrypted only)
+//	frame:  kind u8 | payload length u32 | payload | CRC-32C u32 (plain only)
+//
+// A data frame's payload is one chunk of the database file, zstd-compressed
+// when the compression flag is set. The final frame's payload is the
+// database length u64 followed by its SHA-256. With the encryption flag each
+// payload is sealed with AES-GCM under a nonce of the prefix and the frame's
+// index, with the header and frame kind as additional data, so frames cannot
+// be reordered, dropped, or moved between backups.
+const (
+	backupEnvelopeMagic   = "DDBBAK01"
+	backupEnvelopeVersion = 1
+
+	envelopeFlagZstd   = 1 << 0
+	envelopeFlagAESGCM = 1 << 1
+
+	envelopeFrameData  = 0
+	envelopeFrameFinal = 1
+
+	envelopeTrailerLen = 8 + sha256.Size
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// ErrBackupIntegrity is returned when an envelope backup fails verification
+// on restore: a checksum, authentication tag, or the final digest does not
+// match, or the stream ends early.
+var ErrBackupIntegrity = errors.New("decentdb: backup failed integrity verification")
+
+// KeyProvider supplies the keys that encrypt envelope backups. Keys are
+// AES keys of 16, 24, or 32 bytes.
+type KeyProvider interface {
+	// BackupKey returns the key to encrypt a new backup with and an ID that
+	// is stored, unencrypted, in the backup header.
+	BackupKey(ctx context.Context) (keyID string, key []byte, err error)
+	// RestoreKey returns the key a backup header names.
+	RestoreKey(ctx context.Context, keyID string) ([]byte, error)
+}
+
+// StaticKeyProvider is a KeyProvider holding a single key.
+type StaticKeyProvider struct {
+	KeyID string
+	Key   []byte
+}
+
+// BackupKey returns the provider's key.
+func (p StaticKeyProvider) BackupKey(context.Context) (string, []byte, error) {
+	return p.KeyID, p.Key, nil
+}
+
+// RestoreKey returns the provider's key if keyID matches its ID.
+func (p StaticKeyProvider) RestoreKey(_ context.Context, keyID string) ([]byte, error) {
+	if keyID != p.KeyID {
+		return nil, fmt.Errorf("decentdb: no backup key with id %q", keyID)
+	}
+	return p.Key, nil
+}
+
+// BackupOptions configures BackupToWriterWithOptions.
+type BackupOptions struct {
+	// Compress zstd-compresses each chunk.
+	Compress bool
+	// CompressionLevel is the zstd level; zero uses zstd's default.
+	CompressionLevel int
+	// Keys encrypts the backup with AES-GCM when set.
+	Keys KeyProvider
+}
+
+// RestoreOptions configures RestoreFromReaderWithOptions.
+type RestoreOptions struct {
+	// Keys supplies the key of an encrypted backup.
+	Keys KeyProvider
+}
+
+type envelopeWriter struct {
+	w           io.Writer
+	header      []byte
+	aead        cipher.AEAD
+	noncePrefix []byte
+	frames      uint64
+	digest      hash.Hash
+	length      uint64
+}
+
+func newEnvelopeWriter(ctx context.Context, w io.Writer, opts BackupOptions) (*envelopeWriter, error) {
+	env := &envelopeWriter{
+		w:      w,
+		digest: sha256.New(),
+	}
+	var flags uint16
+	if opts.Compress {
+		flags |= envelopeFlagZstd
+	}
+	var keyID string
+	if opts.Keys != nil {
+		id, key, err := opts.Keys.BackupKey(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("decentdb: backup key: %w", err)
+		}
+		if len(id) > math.MaxUint16 {
+			return nil, fmt.Errorf("decentdb: backup key id is %d bytes, over the %d byte limit", len(id), math.MaxUint16)
+		}
+		if env.aead, err = newBackupAEAD(key); err != nil {
+			return nil, err
+		}
+		env.noncePrefix = make([]byte, env.aead.NonceSize()-8)
+		if _, err := rand.Read(env.noncePrefix); err != nil {
+			return nil, err
+		}
+		keyID = id
+		flags |= envelopeFlagAESGCM
+	}
+
+	header := []byte(backupEnvelopeMagic)
+	header = binary.LittleEndian.AppendUint16(header, backupEnvelopeVersion)
+	header = binary.LittleEndian.AppendUint16(header, flags)
+	header = binary.LittleEndian.AppendUint32(header, backupChunkSize)
+	header = binary.LittleEndian.AppendUint16(header, uint16(len(keyID)))
+	header = append(header, keyID...)
+	header = append(header, env.noncePrefix...)
+	env.header = header
+	if _, err := w.Write(header); err != nil {
+		return nil, fmt.Errorf("decentdb: write backup: %w", err)
+	}
+	return env, nil
+}
+
+func newBackupAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("decentdb: backup key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+func envelopeNonce(prefix []byte, frame uint64) []byte {
+	return binary.BigEndian.AppendUint64(append([]byte(nil), prefix...), frame)
+}
+
+func envelopeAAD(header []byte, kind byte) []byte {
+	return append(append([]byte(nil), header...), kind)
+}
+
+func (e *envelopeWriter) writeChunk(chunk []byte) error {
+	return e.writeCompressedChunk(chunk, chunk)
+}
+
+// writeCompressedChunk writes a data frame carrying payload, the
+// compressed form of chunk.
+func (e *envelopeWriter) writeCompressedChunk(chunk, payload []byte) error {
+	e.digest.Write(chunk)
+	e.length += uint64(len(chunk))
+	return e.writeFrame(envelopeFrameData, payload)
+}
+
+func (e *envelopeWriter) finish() error {
+	trailer := binary.LittleEndian.AppendUint64(nil, e.length)
+	trailer = e.digest.Sum(trailer)
+	return e.writeFrame(envelopeFrameFinal, trailer)
+}
+
+func (e *envelopeWriter) writeFrame(kind byte, payload []byte) error {
+	if e.aead != nil {
+		payload = e.aead.Seal(nil, envelopeNonce(e.noncePrefix, e.frames), payload, envelopeAAD(e.header, kind))
+	}
+	e.frames++
+	frame := make([]byte, 0, 5+len(payload)+4)
+	frame = append(frame, kind)
+	frame = binary.LittleEndian.AppendUint32(frame, uint32(len(payload)))
+	frame = append(frame, payload...)
+	if e.aead == nil {
+		frame = binary.LittleEndian.AppendUint32(frame, crc32.Checksum(frame, castagnoli))
+	}
+	if _, err := e.w.Write(frame); err != nil {
+		return fmt.Errorf("decentdb: write backup: %w", err)
+	}
+	return nil
+}
+
+// isBackupEnvelope reports whether br starts with an envelope header.
+func isBackupEnvelope(br *bufio.Reader) bool {
+	magic, err := br.Peek(len(backupEnvelopeMagic))
+	return err == nil && string(magic) == backupEnvelopeMagic
+}
+
+func integrityError(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrBackupIntegrity, fmt.Sprintf(format, args...))
+}
+
+// envelopePayloadLimit is the largest frame payload a chunk of chunkSize
+// bytes may produce. A zstd frame of incompressible input grows slightly;
+// allow for that plus the GCM tag before treating a length as corrupt.
+func envelopePayloadLimit(chunkSize int) int {
+	return chunkSize + chunkSize/64 + 1024
+}
+
+// readEnvelope verifies and unpacks an envelope backup from r into out,
+// which it finishes once the final digest checks out.
+func readEnvelope(ctx context.Context, r io.Reader, opts RestoreOptions, out *restoreFile) error {
+	fixed := make([]byte, len(backupEnvelopeMagic)+2+2+4+2)
+	if _, err := io.ReadFull(r, fixed); err != nil {
+		return integrityError("backup header is truncated")
+	}
+	header := bytes.Clone(fixed)
+	fields := fixed[len(backupEnvelopeMagic):]
+	version := binary.LittleEndian.Uint16(fields[0:])
+	flags := binary.LittleEndian.Uint16(fields[2:])
+	chunkSize := binary.LittleEndian.Uint32(fields[4:])
+	keyIDLen := binary.LittleEndian.Uint16(fields[8:])
+	if version != backupEnvelopeVersion {
+		return fmt.Errorf("decentdb: unsupported backup envelope version %d", version)
+	}
+	if flags&^(envelopeFlagZstd|envelopeFlagAESGCM) != 0 {
+		return fmt.Errorf("decentdb: backup envelope uses unknown flags %#x", flags)
+	}
+	if chunkSize == 0 || chunkSize > 64<<20 {
+		return integrityError("implausible chunk size %d", chunkSize)
+	}
+	keyID := make([]byte, keyIDLen)
+	if _, err := io.ReadFull(r, keyID); err != nil {
+		return integrityError("backup header is truncated")
+	}
+	header = append(header, keyID...)
+
+	var aead cipher.AEAD
+	var noncePrefix []byte
+	if flags&envelopeFlagAESGCM != 0 {
+		if opts.Keys == nil {
+			return errors.New("decentdb: backup is encrypted; RestoreOptions.Keys is required")
+		}
+		key, err := opts.Keys.RestoreKey(ctx, string(keyID))
+		if err != nil {
+			return fmt.Errorf("decentdb: backup key: %w", err)
+		}
+		if aead, err = newBackupAEAD(key); err != nil {
+			return err
+		}
+		noncePrefix = make([]byte, aead.NonceSize()-8)
+		if _, err := io.ReadFull(r, noncePrefix); err != nil {
+			return integrityError("backup header is truncated")
+		}
+		header = append(header, noncePrefix...)
+	}
+
+	maxPayload := envelopePayloadLimit(int(chunkSize))
+	var length uint64
+	for frame := uint64(0); ; frame++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		frameHeader := make([]byte, 5)
+		if _, err := io.ReadFull(r, frameHeader); err != nil {
+			return integrityError("backup ends before its final frame")
+		}
+		kind := frameHeader[0]
+		payloadLen := binary.LittleEndian.Uint32(frameHeader[1:])
+		if int64(payloadLen) > int64(maxPayload) {
+			return integrityError("frame %d claims %d bytes", frame, payloadLen)
+		}
+		payload := make([]byte, payloadLen)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return integrityError("backup ends inside frame %d", frame)
+		}
+		if aead != nil {
+			opened, err := aead.Open(nil, envelopeNonce(noncePrefix, frame), payload, envelopeAAD(header, kind))
+			if err != nil {
+				return integrityError("frame %d failed authentication", frame)
+			}
+			payload = opened
+		} else {
+			sum := make([]byte, 4)
+			if _, err := io.ReadFull(r, sum); err != nil {
+				return integrityError("backup ends inside frame %d", frame)
+			}
+			crc := crc32.Update(crc32.Checksum(frameHeader, castagnoli), castagnoli, payload)
+			if crc != binary.LittleEndian.Uint32(sum) {
+				return integrityError("frame %d checksum mismatch", frame)
+			}
+		}
+
+		switch kind {
+		case envelopeFrameData:
+			n := len(payload)
+			var err error
+			if flags&envelopeFlagZstd != 0 {
+				n, err = out.writeCompressed(payload, int(chunkSize))
+				if errors.Is(err, ErrCorrupt) {
+					return integrityError("frame %d does not decompress: %v", frame, err)
+				}
+			} else if n > int(chunkSize) {
+				return integrityError("frame %d holds %d bytes", frame, n)
+			} else {
+				err = out.write(payload)
+			}
+			if err != nil {
+				return fmt.Errorf("decentdb: restore: %w", err)
+			}
+			length += uint64(n)
+		case envelopeFrameFinal:
+			if len(payload) != envelopeTrailerLen {
+				return integrityError("final frame is %d bytes", len(payload))
+			}
+			if binary.LittleEndian.Uint64(payload) != length {
+				return integrityError("backup holds %d bytes, expected %d", length, binary.LittleEndian.Uint64(payload))
+			}
+			_, digest, err := out.finish()
+			if err != nil {
+				return fmt.Errorf("decentdb: restore: %w", err)
+			}
+			if !bytes.Equal(digest[:], payload[8:]) {
+				return integrityError("database digest mismatch")
+			}
+			return nil
+		default:
+			return integrityError("frame %d has unknown kind %d", frame, kind)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package decentdb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func envelopeBackupOf(t *testing.T, opts BackupOptions) []byte {
+	t.Helper()
+	db, err := OpenDirect(filepath.Join(t.TempDir(), "source.ddb"))
+	if err != nil {
+		t.Fatalf("OpenDirect failed: %v", err)
+	}
+	defer db.Close()
+	for _, stmt := range []string{
+		"CREATE TABLE t (id INT PRIMARY KEY, body TEXT)",
+		"INSERT INTO t SELECT value, repeat('x', 100) FROM generate_series(1, 300)",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var backup bytes.Buffer
+	if err := db.BackupToWriterWithOptions(context.Background(), &backup, opts); err != nil {
+		t.Fatal(err)
+	}
+	return backup.Bytes()
+}
+
+func TestBackupEnvelope_CompressedEncryptedRoundTrip(t *testing.T) {
+	keys := StaticKeyProvider{KeyID: "2026-10", Key: bytes.Repeat([]byte{7}, 32)}
+	backup := envelopeBackupOf(t, BackupOptions{Compress: true, Keys: keys})
+	if bytes.Contains(backup, bytes.Repeat([]byte("x"), 100)) {
+		t.Fatal("encrypted backup contains plaintext rows")
+	}
+
+	path := filepath.Join(t.TempDir(), "restored.ddb")
+	if err := RestoreFromReader(context.Background(), bytes.NewReader(backup), path); err == nil {
+		t.Fatal("expected restore without keys to fail")
+	}
+	if err := RestoreFromReaderWithOptions(context.Background(), bytes.NewReader(backup), path, RestoreOptions{Keys: keys}); err != nil {
+		t.Fatal(err)
+	}
+	db, err := OpenDirect(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	tables, err := db.ListTables()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tables) != 1 || tables[0] != "t" {
+		t.Fatalf("restored tables = %v", tables)
+	}
+}
+
+func TestBackupEnvelope_DetectsDamage(t *testing.T) {
+	keys := StaticKeyProvider{KeyID: "k", Key: bytes.Repeat([]byte{1}, 16)}
+	for name, opts := range map[string]BackupOptions{
+		"plain":     {},
+		"zstd":      {Compress: true},
+		"encrypted": {Keys: keys},
+	} {
+		t.Run(name, func(t *testing.T) {
+			backup := envelopeBackupOf(t, opts)
+			restore := func(data []byte) error {
+				path := filepath.Join(t.TempDir(), "restored.ddb")
+				return RestoreFromReaderWithOptions(context.Background(), bytes.NewReader(data), path, RestoreOptions{Keys: keys})
+			}
+
+			flipped := bytes.Clone(backup)
+			flipped[len(flipped)/2] ^= 0x01
+			if err := restore(flipped); !errors.Is(err, ErrBackupIntegrity) {
+				t.Fatalf("flipped byte: expected ErrBackupIntegrity, got %v", err)
+			}
+			if err := restore(backup[:len(backup)-10]); !errors.Is(err, ErrBackupIntegrity) {
+				t.Fatalf("truncated: expected ErrBackupIntegrity, got %v", err)
+			}
+			if err := restore(backup); err != nil {
+				t.Fatalf("intact backup: %v", err)
+			}
+		})
+	}
+}
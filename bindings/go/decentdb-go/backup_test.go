@@ -0,0 +1,82 @@
+package decentdb
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBackupToWriter_RestoreFromReaderRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	db, err := OpenDirect(filepath.Join(dir, "source.ddb"))
+	if err != nil {
+		t.Fatalf("OpenDirect failed: %v", err)
+	}
+	defer db.Close()
+	for _, stmt := range []string{
+		"CREATE TABLE t (id INT PRIMARY KEY, body TEXT)",
+		"INSERT INTO t SELECT value, repeat('x', 100) FROM generate_series(1, 300)",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var backup bytes.Buffer
+	if err := db.BackupToWriter(context.Background(), &backup); err != nil {
+		t.Fatal(err)
+	}
+	if backup.Len() == 0 || backup.Len()%4096 != 0 {
+		t.Fatalf("unexpected backup length %d", backup.Len())
+	}
+
+	restored := filepath.Join(dir, "restored.ddb")
+	if err := RestoreFromReader(context.Background(), bytes.NewReader(backup.Bytes()), restored); err != nil {
+		t.Fatal(err)
+	}
+	check, err := sql.Open("decentdb", fmt.Sprintf("file:%s", restored))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer check.Close()
+	var count int64
+	if err := check.QueryRow("SELECT COUNT(*) FROM t").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 300 {
+		t.Fatalf("restored row count = %d, want 300", count)
+	}
+
+	if err := RestoreFromReader(context.Background(), bytes.NewReader(backup.Bytes()), restored); err == nil {
+		t.Fatal("expected restore over an existing file to fail")
+	}
+}
+
+func TestRestoreFromReader_RejectsGarbage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "garbage.ddb")
+	err := RestoreFromReader(context.Background(), strings.NewReader(strings.Repeat("not a database", 1000)), path)
+	if err == nil {
+		t.Fatal("expected garbage restore to fail")
+	}
+	if _, statErr := os.Stat(path); !os.IsNotExist(statErr) {
+		t.Fatalf("partial restore left %s behind: %v", path, statErr)
+	}
+}
+
+func TestBackupToWriter_HonorsCanceledContext(t *testing.T) {
+	db, err := OpenDirect(filepath.Join(t.TempDir(), "canceled.ddb"))
+	if err != nil {
+		t.Fatalf("OpenDirect failed: %v", err)
+	}
+	defer db.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := db.BackupToWriter(ctx, &bytes.Buffer{}); err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+package decentdb
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+// BusyHandler decides what happens when a statement fails because another
+// writer holds the database. attempt counts the busy failures of the
+// statement so far, starting at 1. Returning retry=true runs the statement
+// again after backoff; returning false hands the ErrBusy error to the
+// caller. Handlers can log contention, follow any backoff curve, or give up
+// early for low-priority work.
+type BusyHandler func(attempt int) (retry bool, backoff time.Duration)
+
+// WithBusyHandler installs h on every connection the connector opens. See
+// DB.SetBusyHandler.
+func WithBusyHandler(h BusyHandler) ConnectorOption {
+	return func(c *connector) {
+		c.busy = h
+	}
+}
+
+// SetBusyHandler replaces the connection's busy handler; nil removes it.
+func (c *conn) SetBusyHandler(h BusyHandler) {
+	if h == nil {
+		c.busy.Store(nil)
+		return
+	}
+	c.busy.Store(&h)
+}
+
+// SetBusyHandler calls h whenever a statement outside a transaction, or a
+// BEGIN, fails with ErrBusy, and re-runs the statement while h asks to. Such
+// a statement failed before it changed anything, so re-running it is safe.
+// Statements inside a transaction, and COMMIT, are not re-run: wrap the
+// whole transaction in WithTx instead. The handler runs on the goroutine
+// that issued the statement; a canceled context ends the wait early. nil
+// removes the handler, leaving the busy_timeout PRAGMA as the only wait.
+func (d *DB) SetBusyHandler(h BusyHandler) {
+	d.c.SetBusyHandler(h)
+}
+
+// handleBusy calls attempt and, while it fails with ErrBusy outside a
+// transaction, asks the connection's busy handler whether to call it again.
+func handleBusy[T any](ctx context.Context, c *conn, attempt func() (T, error)) (T, error) {
+	h := c.busy.Load()
+	if h == nil || c.InTransaction() {
+		return attempt()
+	}
+	result, err := attempt()
+	for n := 1; err != nil && errors.Is(err, ErrBusy); n++ {
+		retry, backoff := (*h)(n)
+		if !retry {
+			return result, err
+		}
+		logDebug(ctx, "decentdb: busy handler retrying statement", slog.Int("attempt", n),
+			slog.Duration("backoff", backoff))
+		if backoff > 0 {
+			timer := time.NewTimer(backoff)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return result, err
+			case <-timer.C:
+			}
+		} else if ctx.Err() != nil {
+			return result, err
+		}
+		result, err = attempt()
+	}
+	return result, err
+}
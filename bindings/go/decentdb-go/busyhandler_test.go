@@ -0,0 +1,101 @@
+package decentdb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBusyHandler(t *testing.T) {
+	var failures atomic.Int32
+	var attempts []int
+	giveUpAfter := 3
+	handler := func(attempt int) (bool, time.Duration) {
+		attempts = append(attempts, attempt)
+		return attempt < giveUpAfter, time.Microsecond
+	}
+	connector, err := NewConnector(":memory:", WithInterceptors(busyInterceptor{failures: &failures}), WithBusyHandler(handler))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := sql.OpenDB(connector)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	ctx := context.Background()
+	if _, err := db.ExecContext(ctx, "CREATE TABLE t (id INT64 PRIMARY KEY)"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Writes are re-run while the handler asks to.
+	failures.Store(2)
+	if _, err := db.ExecContext(ctx, "INSERT INTO t VALUES (1)"); err != nil {
+		t.Fatalf("insert after two busy failures: %v", err)
+	}
+	if !reflect.DeepEqual(attempts, []int{1, 2}) {
+		t.Fatalf("handler attempts = %v, want [1 2]", attempts)
+	}
+
+	// The handler giving up surfaces ErrBusy.
+	attempts = nil
+	failures.Store(5)
+	var count int64
+	err = db.QueryRowContext(ctx, "SELECT count(*) FROM t").Scan(&count)
+	if !errors.Is(err, ErrBusy) || !reflect.DeepEqual(attempts, []int{1, 2, 3}) {
+		t.Fatalf("query = %v after attempts %v, want ErrBusy after [1 2 3]", err, attempts)
+	}
+
+	// Statements inside a transaction are not re-run.
+	failures.Store(0)
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	attempts = nil
+	failures.Store(1)
+	if _, err := tx.ExecContext(ctx, "INSERT INTO t VALUES (2)"); !errors.Is(err, ErrBusy) || attempts != nil {
+		t.Fatalf("in-transaction insert = %v with attempts %v", err, attempts)
+	}
+}
+
+func TestBusyHandlerCanceledContext(t *testing.T) {
+	var failures atomic.Int32
+	connector, err := NewConnector(":memory:", WithInterceptors(busyInterceptor{failures: &failures}),
+		WithBusyHandler(func(int) (bool, time.Duration) { return true, time.Hour }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := sql.OpenDB(connector)
+	defer db.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	failures.Store(1)
+	if _, err := db.ExecContext(ctx, "SELECT 1"); !errors.Is(err, ErrBusy) {
+		t.Fatalf("exec = %v, want the busy error once the context ends", err)
+	}
+}
+
+func TestOpenDirect_SetBusyHandler(t *testing.T) {
+	db, err := OpenDirect(filepath.Join(t.TempDir(), "busy.ddb"))
+	if err != nil {
+		t.Fatalf("OpenDirect failed: %v", err)
+	}
+	defer db.Close()
+	var calls atomic.Int32
+	db.SetBusyHandler(func(int) (bool, time.Duration) {
+		calls.Add(1)
+		return false, 0
+	})
+	if _, err := db.Exec("CREATE TABLE t (id INT64 PRIMARY KEY)"); err != nil {
+		t.Fatal(err)
+	}
+	db.SetBusyHandler(nil)
+	if calls.Load() != 0 {
+		t.Fatalf("handler ran %d times without contention", calls.Load())
+	}
+}
@@ -0,0 +1,144 @@
+package decentdb
+
+import (
+	"slices"
+	"sync/atomic"
+	"time"
+)
+
+var cgoLatencyBuckets = [...]time.Duration{
+	250 * time.Nanosecond,
+	500 * time.Nanosecond,
+	time.Microsecond,
+	2 * time.Microsecond,
+	5 * time.Microsecond,
+	10 * time.Microsecond,
+	25 * time.Microsecond,
+	50 * time.Microsecond,
+	100 * time.Microsecond,
+	250 * time.Microsecond,
+	time.Millisecond,
+	10 * time.Millisecond,
+}
+
+// CgoLatencyBuckets returns the upper bounds of the cgo call latency
+// histogram buckets. A final, unbounded bucket counts slower calls.
+func CgoLatencyBuckets() []time.Duration {
+	return slices.Clone(cgoLatencyBuckets[:])
+}
+
+// cgoCall is a kind of instrumented crossing into the native library.
+type cgoCall int
+
+const (
+	// cgoPrepare compiles a statement.
+	cgoPrepare cgoCall = iota
+	// cgoReset resets a statement and clears its bindings.
+	cgoReset
+	// cgoBind binds one parameter.
+	cgoBind
+	// cgoStep executes a statement for Exec.
+	cgoStep
+	// cgoRowView steps to or fetches rows and returns borrowed views of
+	// their values.
+	cgoRowView
+	// cgoExecute rebinds and executes in one crossing, or executes a batch.
+	cgoExecute
+	numCgoCalls
+)
+
+var cgoCallNames = [numCgoCalls]string{"prepare", "reset", "bind", "step", "row_view", "execute"}
+
+type cgoHistogram struct {
+	count   atomic.Uint64
+	nanos   atomic.Uint64
+	buckets [len(cgoLatencyBuckets) + 1]atomic.Uint64
+}
+
+var (
+	cgoMetricsEnabled atomic.Bool
+	cgoHistograms     [numCgoCalls]cgoHistogram
+)
+
+// CgoCallStats is the latency histogram of one kind of cgo call.
+type CgoCallStats struct {
+	// Call is "prepare", "reset", "bind", "step", "row_view", or "execute".
+	Call  string
+	Count uint64
+	Total time.Duration
+	// Buckets[i] counts the calls that took at most CgoLatencyBuckets()[i]
+	// and longer than the previous bound; the last entry counts the rest.
+	Buckets []uint64
+}
+
+// Mean returns the average latency of the calls.
+func (s CgoCallStats) Mean() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Total / time.Duration(s.Count)
+}
+
+// EnableCgoMetrics turns cgo call instrumentation on or off for the
+// process. It is off by default because timing every crossing costs two
+// clock reads per call, which is noticeable next to a row step.
+func EnableCgoMetrics(enabled bool) {
+	cgoMetricsEnabled.Store(enabled)
+}
+
+// CgoMetrics returns the count and latency histogram of each kind of cgo
+// call made since instrumentation was enabled or last reset.
+func CgoMetrics() []CgoCallStats {
+	stats := make([]CgoCallStats, numCgoCalls)
+	for k := range cgoHistograms {
+		h := &cgoHistograms[k]
+		s := CgoCallStats{
+			Call:    cgoCallNames[k],
+			Count:   h.count.Load(),
+			Total:   time.Duration(h.nanos.Load()),
+			Buckets: make([]uint64, len(h.buckets)),
+		}
+		for i := range h.buckets {
+			s.Buckets[i] = h.buckets[i].Load()
+		}
+		stats[k] = s
+	}
+	return stats
+}
+
+// ResetCgoMetrics zeroes the cgo call histograms.
+func ResetCgoMetrics() {
+	for k := range cgoHistograms {
+		h := &cgoHistograms[k]
+		h.count.Store(0)
+		h.nanos.Store(0)
+		for i := range h.buckets {
+			h.buckets[i].Store(0)
+		}
+	}
+}
+
+// cgoStart returns the start time of a cgo call, or the zero time when
+// instrumentation is off.
+func cgoStart() time.Time {
+	if !cgoMetricsEnabled.Load() {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+// done records a call of kind k that started at start.
+func (k cgoCall) done(start time.Time) {
+	if start.IsZero() {
+		return
+	}
+	d := time.Since(start)
+	h := &cgoHistograms[k]
+	h.count.Add(1)
+	h.nanos.Add(uint64(d))
+	i := 0
+	for i < len(cgoLatencyBuckets) && d > cgoLatencyBuckets[i] {
+		i++
+	}
+	h.buckets[i].Add(1)
+}
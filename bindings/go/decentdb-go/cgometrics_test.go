@@ -0,0 +1,79 @@
+package decentdb
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+func cgoStatsByCall() map[string]CgoCallStats {
+	stats := map[string]CgoCallStats{}
+	for _, s := range CgoMetrics() {
+		stats[s.Call] = s
+	}
+	return stats
+}
+
+func TestCgoMetrics_CountsCrossings(t *testing.T) {
+	db, err := sql.Open("decentdb", fmt.Sprintf("file:%s", filepath.Join(t.TempDir(), "cgo.ddb")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("CREATE TABLE t (id INT PRIMARY KEY, name TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+
+	ResetCgoMetrics()
+	EnableCgoMetrics(true)
+	t.Cleanup(func() {
+		EnableCgoMetrics(false)
+		ResetCgoMetrics()
+	})
+	if _, err := db.Exec("INSERT INTO t VALUES ($1, $2)", 1, "a"); err != nil {
+		t.Fatal(err)
+	}
+	var name string
+	if err := db.QueryRow("SELECT name FROM t WHERE id = $1", 1).Scan(&name); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := cgoStatsByCall()
+	for call, min := range map[string]uint64{"prepare": 2, "reset": 2, "bind": 3, "step": 1, "row_view": 1} {
+		s := stats[call]
+		if s.Count < min {
+			t.Errorf("%s: count %d, want at least %d", call, s.Count, min)
+		}
+		var bucketed uint64
+		for _, n := range s.Buckets {
+			bucketed += n
+		}
+		if bucketed != s.Count {
+			t.Errorf("%s: buckets hold %d calls, count is %d", call, bucketed, s.Count)
+		}
+		if len(s.Buckets) != len(CgoLatencyBuckets())+1 {
+			t.Errorf("%s: %d buckets, want %d", call, len(s.Buckets), len(CgoLatencyBuckets())+1)
+		}
+	}
+	if stats["prepare"].Mean() <= 0 {
+		t.Error("prepare mean latency not recorded")
+	}
+}
+
+func TestCgoMetrics_OffByDefault(t *testing.T) {
+	ResetCgoMetrics()
+	db, err := OpenDirect(filepath.Join(t.TempDir(), "off.ddb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("CREATE TABLE t (id INT PRIMARY KEY)"); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range CgoMetrics() {
+		if s.Count != 0 {
+			t.Fatalf("%s counted %d calls with instrumentation off", s.Call, s.Count)
+		}
+	}
+}
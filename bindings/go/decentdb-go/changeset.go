@@ -0,0 +1,278 @@
+package decentdb
+
+/*
+#include <stdlib.h>
+#include "decentdb.h"
+*/
+import "C"
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"unsafe"
+)
+
+// Session records the changes committed to a database so they can be
+// shipped to another database as a changeset. Changes are read from the
+// sync journal, so a session sees every commit made through the handle
+// after it started, whichever statement or transaction made it. A Session
+// holds no resources and needs no closing.
+type Session struct {
+	d     *DB
+	since uint64
+}
+
+// ChangesetResult summarizes one ApplyChangeset call.
+type ChangesetResult struct {
+	// Outcome is "applied", "conflict_recorded", or "already_applied" when
+	// the same changeset was applied before.
+	Outcome        string `json:"outcome"`
+	ChangesetID    string `json:"changeset_id"`
+	RowsSeen       uint64 `json:"rows_seen"`
+	RowsApplied    uint64 `json:"rows_applied"`
+	RowsSkipped    uint64 `json:"rows_skipped"`
+	RowsConflicted uint64 `json:"rows_conflicted"`
+	// RowsResolved counts the conflicts the resolver settled with
+	// ChangesetKeepLocal or ChangesetApplyRemote.
+	RowsResolved uint64 `json:"-"`
+}
+
+// ChangesetConflict describes a changeset row that could not be applied
+// as is, such as an insert whose primary key already exists.
+type ChangesetConflict struct {
+	ID         int64          `json:"conflict_id"`
+	Table      string         `json:"table_name"`
+	Operation  string         `json:"operation"`
+	Type       string         `json:"conflict_type"`
+	Message    string         `json:"message"`
+	PrimaryKey map[string]any `json:"primary_key_json"`
+	// Local is the row currently stored under the primary key, if any.
+	Local map[string]any `json:"local_row_json"`
+	// Remote is the row the changeset wants to write; nil for deletes.
+	Remote map[string]any `json:"-"`
+}
+
+// ChangesetResolution is a ChangesetResolver's decision for one conflict.
+type ChangesetResolution int
+
+const (
+	// ChangesetRecord leaves the conflict open in the sync conflict log for
+	// later review.
+	ChangesetRecord ChangesetResolution = iota
+	// ChangesetKeepLocal keeps the local row and closes the conflict.
+	ChangesetKeepLocal
+	// ChangesetApplyRemote overwrites the local row with the changeset's
+	// version and closes the conflict.
+	ChangesetApplyRemote
+)
+
+// ChangesetResolver decides how ApplyChangeset settles a conflict.
+type ChangesetResolver func(ChangesetConflict) ChangesetResolution
+
+// StartSession starts recording changes. The first session on a database
+// turns on the sync journal under replicaID, which names this database to
+// the databases it syncs with; later sessions must use the same ID.
+func (d *DB) StartSession(replicaID string) (*Session, error) {
+	if atomic.LoadUint32(&d.closed) != 0 {
+		return nil, driver.ErrBadConn
+	}
+	nextSequence, err := d.c.enableSyncJournal(replicaID)
+	if err != nil {
+		return nil, err
+	}
+	since := uint64(0)
+	if nextSequence > 0 {
+		since = nextSequence - 1
+	}
+	return &Session{d: d, since: since}, nil
+}
+
+// enableSyncJournal turns the sync journal on under replicaID, initializing
+// the replica on first use, and returns the next journal sequence.
+func (c *conn) enableSyncJournal(replicaID string) (uint64, error) {
+	var status struct {
+		Enabled      bool   `json:"enabled"`
+		ReplicaID    string `json:"replica_id"`
+		NextSequence uint64 `json:"next_sequence"`
+	}
+	if err := c.syncExecute(map[string]any{"op": "status"}, &status); err != nil {
+		return 0, err
+	}
+	switch {
+	case status.ReplicaID == "":
+		if err := c.syncExecute(map[string]any{"op": "init_replica", "replica_id": replicaID}, &status); err != nil {
+			return 0, err
+		}
+	case status.ReplicaID != replicaID:
+		return 0, fmt.Errorf("decentdb: database is sync replica %q, not %q", status.ReplicaID, replicaID)
+	case !status.Enabled:
+		if err := c.syncExecute(map[string]any{"op": "set_enabled", "enabled": true}, &status); err != nil {
+			return 0, err
+		}
+	}
+	return status.NextSequence, nil
+}
+
+// Changeset returns the changes committed since the session started as an
+// opaque changeset blob. Every row change carries its before image, so the
+// blob can be inverted with InvertChangeset.
+func (s *Session) Changeset() ([]byte, error) {
+	if atomic.LoadUint32(&s.d.closed) != 0 {
+		return nil, driver.ErrBadConn
+	}
+	return s.d.c.syncChangeset(func(db *C.ddb_db_t, req *C.char, out **C.char) C.ddb_status_t {
+		return C.ddb_sync_changeset_create_json(db, req, out)
+	}, map[string]any{
+		"source": map[string]any{"kind": "checkpoint", "peer": "session", "since_sequence": s.since},
+	})
+}
+
+// InvertChangeset returns a changeset that undoes changeset: inserts become
+// deletes, deletes become inserts, and updates restore the before image,
+// in reverse order. Applying it to a database that changeset was applied
+// to rolls those changes back.
+func (d *DB) InvertChangeset(changeset []byte) ([]byte, error) {
+	if atomic.LoadUint32(&d.closed) != 0 {
+		return nil, driver.ErrBadConn
+	}
+	return d.c.syncChangeset(func(db *C.ddb_db_t, req *C.char, out **C.char) C.ddb_status_t {
+		return C.ddb_sync_changeset_invert_json(db, req, out)
+	}, map[string]any{"changeset": json.RawMessage(changeset)})
+}
+
+// ApplyChangeset applies changeset atomically. The database must have the
+// same schema as the one the changeset came from. Rows that conflict with
+// local data are set aside in the sync conflict log; when resolve is not
+// nil it is called for each of them once the changeset is applied, and its
+// answer settles the conflict. A nil resolve applies the database's
+// configured conflict policy instead. Applying the same changeset again is
+// a no-op reported as "already_applied".
+func (d *DB) ApplyChangeset(changeset []byte, resolve ChangesetResolver) (ChangesetResult, error) {
+	if atomic.LoadUint32(&d.closed) != 0 {
+		return ChangesetResult{}, driver.ErrBadConn
+	}
+	request := map[string]any{"changeset": json.RawMessage(changeset)}
+	open := map[int64]bool{}
+	if resolve != nil {
+		request["options"] = map[string]any{"conflict_policy": "record"}
+		conflicts, err := d.c.syncConflicts()
+		if err != nil {
+			return ChangesetResult{}, err
+		}
+		for _, conflict := range conflicts {
+			open[conflict.ID] = true
+		}
+	}
+	out, err := d.c.syncChangeset(func(db *C.ddb_db_t, req *C.char, out **C.char) C.ddb_status_t {
+		return C.ddb_sync_changeset_apply_json(db, req, out)
+	}, request)
+	if err != nil {
+		return ChangesetResult{}, err
+	}
+	var result ChangesetResult
+	if err := json.Unmarshal(out, &result); err != nil {
+		return ChangesetResult{}, fmt.Errorf("decentdb: decode changeset result: %w", err)
+	}
+	if resolve == nil || result.RowsConflicted == 0 {
+		return result, nil
+	}
+	conflicts, err := d.c.syncConflicts()
+	if err != nil {
+		return result, err
+	}
+	for _, conflict := range conflicts {
+		if open[conflict.ID] {
+			continue
+		}
+		var action string
+		switch resolve(conflict) {
+		case ChangesetKeepLocal:
+			action = "keep_local"
+		case ChangesetApplyRemote:
+			action = "apply_remote"
+		default:
+			continue
+		}
+		if err := d.c.syncExecute(map[string]any{
+			"op":     "resolve_conflict",
+			"id":     conflict.ID,
+			"action": action,
+			"by":     "changeset_resolver",
+		}, nil); err != nil {
+			return result, err
+		}
+		result.RowsResolved++
+	}
+	return result, nil
+}
+
+// syncConflicts returns the open conflicts in the sync conflict log.
+func (c *conn) syncConflicts() ([]ChangesetConflict, error) {
+	var raw []struct {
+		ChangesetConflict
+		RemoteRecord struct {
+			After map[string]any `json:"after"`
+		} `json:"remote_record_json"`
+	}
+	if err := c.syncExecute(map[string]any{"op": "conflicts", "all": false}, &raw); err != nil {
+		return nil, err
+	}
+	conflicts := make([]ChangesetConflict, len(raw))
+	for i, entry := range raw {
+		conflicts[i] = entry.ChangesetConflict
+		conflicts[i].Remote = entry.RemoteRecord.After
+	}
+	return conflicts, nil
+}
+
+func (c *conn) syncExecute(request map[string]any, out any) error {
+	if c.db == nil {
+		return driver.ErrBadConn
+	}
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	cPayload := C.CString(string(payload))
+	defer C.free(unsafe.Pointer(cPayload))
+
+	var cOut *C.char
+	status := C.ddb_db_sync_execute_json(c.db, cPayload, &cOut)
+	if status != C.DDB_OK {
+		return statusError(status, "sync_execute_json")
+	}
+	defer freeAPIString(cOut)
+	if out != nil && cOut != nil {
+		if err := json.Unmarshal([]byte(C.GoString(cOut)), out); err != nil {
+			return fmt.Errorf("decentdb: decode sync response: %w", err)
+		}
+	}
+	return nil
+}
+
+func (c *conn) syncChangeset(
+	call func(*C.ddb_db_t, *C.char, **C.char) C.ddb_status_t,
+	request map[string]any,
+) ([]byte, error) {
+	if c.db == nil {
+		return nil, driver.ErrBadConn
+	}
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("decentdb: encode changeset request: %w", err)
+	}
+	cPayload := C.CString(string(payload))
+	defer C.free(unsafe.Pointer(cPayload))
+
+	var cOut *C.char
+	status := call(c.db, cPayload, &cOut)
+	if status != C.DDB_OK {
+		return nil, statusError(status, "")
+	}
+	defer freeAPIString(cOut)
+	if cOut == nil {
+		return nil, fmt.Errorf("decentdb: changeset call returned no output")
+	}
+	return []byte(C.GoString(cOut)), nil
+}
@@ -0,0 +1,125 @@
+package decentdb
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func openChangesetPair(t *testing.T) (*DB, *DB) {
+	t.Helper()
+	var dbs [2]*DB
+	for i, name := range []string{"device.ddb", "hub.ddb"} {
+		db, err := OpenDirect(filepath.Join(t.TempDir(), name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+		if _, err := db.Exec("CREATE TABLE notes (id INT64 PRIMARY KEY, body TEXT)"); err != nil {
+			t.Fatal(err)
+		}
+		dbs[i] = db
+	}
+	return dbs[0], dbs[1]
+}
+
+func noteBodies(t *testing.T, db *DB) map[int64]string {
+	t.Helper()
+	bodies := map[int64]string{}
+	for row, err := range db.Rows(context.Background(), "SELECT id, body FROM notes") {
+		if err != nil {
+			t.Fatal(err)
+		}
+		values := row.Values()
+		bodies[values[0].(int64)] = values[1].(string)
+	}
+	return bodies
+}
+
+func TestChangeset_RecordApplyInvert(t *testing.T) {
+	device, hub := openChangesetPair(t)
+
+	session, err := device.StartSession("device-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range []string{
+		"INSERT INTO notes VALUES (1, 'draft')",
+		"INSERT INTO notes VALUES (2, 'scratch')",
+		"UPDATE notes SET body = 'final' WHERE id = 1",
+		"DELETE FROM notes WHERE id = 2",
+	} {
+		if _, err := device.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	changeset, err := session.Changeset()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := hub.ApplyChangeset(changeset, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Outcome != "applied" || result.RowsApplied != 4 {
+		t.Fatalf("apply result = %+v", result)
+	}
+	if got := noteBodies(t, hub); len(got) != 1 || got[1] != "final" {
+		t.Fatalf("hub rows after apply = %v", got)
+	}
+	if again, err := hub.ApplyChangeset(changeset, nil); err != nil || again.Outcome != "already_applied" {
+		t.Fatalf("reapply = %+v, %v", again, err)
+	}
+
+	inverse, err := hub.InvertChangeset(changeset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := hub.ApplyChangeset(inverse, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := noteBodies(t, hub); len(got) != 0 {
+		t.Fatalf("hub rows after inverse = %v", got)
+	}
+}
+
+func TestChangeset_ResolverSettlesConflicts(t *testing.T) {
+	device, hub := openChangesetPair(t)
+	if _, err := hub.Exec("INSERT INTO notes VALUES (1, 'hub'), (2, 'hub')"); err != nil {
+		t.Fatal(err)
+	}
+
+	session, err := device.StartSession("device-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := device.Exec("INSERT INTO notes VALUES (1, 'device'), (2, 'device'), (3, 'device')"); err != nil {
+		t.Fatal(err)
+	}
+	changeset, err := session.Changeset()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var seen []ChangesetConflict
+	result, err := hub.ApplyChangeset(changeset, func(conflict ChangesetConflict) ChangesetResolution {
+		seen = append(seen, conflict)
+		if conflict.PrimaryKey["id"] == float64(1) {
+			return ChangesetApplyRemote
+		}
+		return ChangesetKeepLocal
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.RowsConflicted != 2 || result.RowsResolved != 2 || len(seen) != 2 {
+		t.Fatalf("result = %+v, conflicts = %+v", result, seen)
+	}
+	if seen[0].Table != "notes" || seen[0].Local["body"] != "hub" || seen[0].Remote["body"] != "device" {
+		t.Fatalf("conflict detail = %+v", seen[0])
+	}
+	if got := noteBodies(t, hub); got[1] != "device" || got[2] != "hub" || got[3] != "device" {
+		t.Fatalf("hub rows = %v", got)
+	}
+}
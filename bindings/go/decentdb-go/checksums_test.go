@@ -0,0 +1,67 @@
+package decentdb
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStatusError_CorruptionMapping(t *testing.T) {
+	err := statusError(2, "")
+	if !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("expected ErrCorrupt, got %v", err)
+	}
+	if _, ok := CorruptPage(errors.New("other")); ok {
+		t.Fatal("CorruptPage reported a page for an unrelated error")
+	}
+}
+
+func TestVerifyChecksums_ReportsCorruptPage(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "checksums.ddb")
+	db, err := OpenDirect(dbPath)
+	if err != nil {
+		t.Fatalf("OpenDirect failed: %v", err)
+	}
+	for _, stmt := range []string{
+		"CREATE TABLE t (id INT PRIMARY KEY, body TEXT)",
+		"INSERT INTO t SELECT value, repeat('x', 200) FROM generate_series(1, 500)",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Checkpoint(); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	bytes, err := os.ReadFile(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const pageSize = 4096
+	lastPage := len(bytes) / pageSize
+	bytes[(lastPage-1)*pageSize+pageSize/2] ^= 0x40
+	if err := os.WriteFile(dbPath, bytes, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	verified, err := sql.Open("decentdb", fmt.Sprintf("file:%s?verify_checksums=on", dbPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer verified.Close()
+	var count, maxLen int64
+	err = verified.QueryRow("SELECT COUNT(*), MAX(length(body)) FROM t").Scan(&count, &maxLen)
+	if !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("expected ErrCorrupt, got %v", err)
+	}
+	if page, ok := CorruptPage(err); !ok || page != uint32(lastPage) {
+		t.Fatalf("CorruptPage = %d, %v; want %d", page, ok, lastPage)
+	}
+}
@@ -0,0 +1,156 @@
+package decentdb
+
+/*
+#include <stdlib.h>
+#include "decentdb.h"
+*/
+import "C"
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"unsafe"
+)
+
+// CloneOptions selects what Clone copies.
+type CloneOptions struct {
+	// Dest is the path of the new database, which must not exist.
+	Dest string
+	// Tables restricts the clone to these tables and their indexes and
+	// triggers; views are left out. Empty clones every table and view.
+	Tables []string
+	// Where maps a table name to a SQL boolean expression over its
+	// columns, such as "created_at > '2026-01-01'". Only rows for which it
+	// is true are copied.
+	Where map[string]string
+	// Transforms maps a table name and then a column name to the Transform
+	// that computes the column's value in the clone, to scrub personal
+	// data out of a copy shared with developers.
+	Transforms map[string]map[string]Transform
+}
+
+// A Transform rewrites one column of every row Clone copies. The engine
+// evaluates it as a SQL expression over the source row, so every transform
+// of a row sees the row's original values.
+type Transform struct {
+	expr func(column string) string
+}
+
+// SQLTransform replaces the column with the value of expr, a SQL expression
+// over the source row's columns such as "LOWER(name)" or
+// "'user' || CAST(id AS TEXT) || '@example.invalid'".
+func SQLTransform(expr string) Transform {
+	return Transform{func(string) string { return expr }}
+}
+
+// Nullify replaces the column with NULL, for free text that cannot be
+// shared at all.
+func Nullify() Transform {
+	return SQLTransform("NULL")
+}
+
+// MaskEmail replaces an address with user-<12 hex digits>@example.invalid,
+// taken from the SHA-256 of the original, so equal addresses stay equal and
+// a UNIQUE column stays unique in practice. NULL stays NULL.
+func MaskEmail() Transform {
+	return Transform{func(column string) string {
+		return "'user-' || SUBSTR(SHA256(" + quoteIdentifier(column) + "), 1, 12) || '@example.invalid'"
+	}}
+}
+
+// HashText replaces a text column, such as an external ID, with the
+// hex-encoded HMAC-SHA256 of its value under key. Equal values hash alike,
+// so columns that join or reference each other still match when given the
+// same key, and without the key the originals cannot be recovered by
+// hashing guesses. NULL stays NULL.
+func HashText(key string) Transform {
+	return Transform{func(column string) string {
+		return "HMAC(" + quoteIdentifier(column) + ", " + quoteLiteral(key) + ", 'sha256')"
+	}}
+}
+
+// CloneReport lists the rows Clone copied into each table.
+type CloneReport struct {
+	Tables []ClonedTable `json:"tables"`
+}
+
+// ClonedTable is the copy result for one table. RowsSkipped counts rows that
+// passed the table's filter but referenced, through a foreign key, a row the
+// clone left out.
+type ClonedTable struct {
+	Name        string `json:"name"`
+	RowsCopied  uint64 `json:"rows_copied"`
+	RowsSkipped uint64 `json:"rows_skipped"`
+}
+
+// Clone copies the schema and the rows selected by opts into a new database
+// at opts.Dest, reading one consistent snapshot. ctx is checked before the
+// clone starts; the copy itself is not interruptible.
+func (c *conn) Clone(ctx context.Context, opts CloneOptions) (*CloneReport, error) {
+	if c.db == nil {
+		return nil, driver.ErrBadConn
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	transforms := make(map[string]map[string]string, len(opts.Transforms))
+	for table, columns := range opts.Transforms {
+		transforms[table] = make(map[string]string, len(columns))
+		for column, t := range columns {
+			if t.expr == nil {
+				return nil, fmt.Errorf("decentdb: Clone: transform for %s.%s is empty", table, column)
+			}
+			transforms[table][column] = t.expr(column)
+		}
+	}
+	options, err := json.Marshal(struct {
+		Tables     []string                     `json:"tables,omitempty"`
+		Where      map[string]string            `json:"where,omitempty"`
+		Transforms map[string]map[string]string `json:"transforms,omitempty"`
+	}{opts.Tables, opts.Where, transforms})
+	if err != nil {
+		return nil, err
+	}
+	cDest := C.CString(opts.Dest)
+	defer C.free(unsafe.Pointer(cDest))
+	cOptions := C.CString(string(options))
+	defer C.free(unsafe.Pointer(cOptions))
+
+	var cOut *C.char
+	status := C.ddb_db_clone_to_json(c.db, cDest, cOptions, &cOut)
+	if status != C.DDB_OK {
+		return nil, statusError(status, "")
+	}
+	defer freeAPIString(cOut)
+	var report CloneReport
+	if err := json.Unmarshal([]byte(C.GoString(cOut)), &report); err != nil {
+		return nil, fmt.Errorf("decentdb: decode clone report: %w", err)
+	}
+	return &report, nil
+}
+
+// Clone copies the schema and the rows selected by opts into a new database
+// at opts.Dest, for example to produce a trimmed staging dataset:
+//
+//	report, err := db.Clone(ctx, decentdb.CloneOptions{
+//		Dest:   "staging.ddb",
+//		Tables: []string{"users", "orders"},
+//		Where:  map[string]string{"orders": "created_at > '2026-01-01'"},
+//	})
+//
+// Tables are created parents first, and every cloned table must be cloned
+// together with the tables its foreign keys reference. A row whose foreign
+// key points at a row a filter left out is skipped and counted in
+// RowsSkipped. Transforms scrub column values on the way:
+//
+//	Transforms: map[string]map[string]decentdb.Transform{
+//		"users": {"email": decentdb.MaskEmail(), "notes": decentdb.Nullify()},
+//	}
+func (d *DB) Clone(ctx context.Context, opts CloneOptions) (*CloneReport, error) {
+	if atomic.LoadUint32(&d.closed) != 0 {
+		return nil, driver.ErrBadConn
+	}
+	return d.c.Clone(ctx, opts)
+}
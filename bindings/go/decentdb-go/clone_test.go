@@ -0,0 +1,131 @@
+package decentdb
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDBClone_FiltersTablesAndRows(t *testing.T) {
+	dir := t.TempDir()
+	db, err := OpenDirect(filepath.Join(dir, "prod.ddb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, stmt := range []string{
+		"CREATE TABLE users (id INT64 PRIMARY KEY, email TEXT NOT NULL)",
+		"CREATE TABLE orders (id INT64 PRIMARY KEY, user_id INT64 REFERENCES users (id), created_at TEXT)",
+		"CREATE TABLE secrets (id INT64 PRIMARY KEY, token TEXT)",
+		"INSERT INTO users VALUES (1, 'ada@example.com'), (2, 'grace@example.com')",
+		"INSERT INTO orders VALUES (10, 1, '2025-12-01'), (11, 2, '2026-02-01'), (12, 1, '2026-03-01')",
+		"INSERT INTO secrets VALUES (1, 'hunter2')",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+
+	ctx := context.Background()
+	dest := filepath.Join(dir, "staging.ddb")
+	report, err := db.Clone(ctx, CloneOptions{
+		Dest:   dest,
+		Tables: []string{"users", "orders"},
+		Where: map[string]string{
+			"users":  "id = 1",
+			"orders": "created_at > '2026-01-01'",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ClonedTable{{Name: "users", RowsCopied: 1}, {Name: "orders", RowsCopied: 1, RowsSkipped: 1}}
+	if len(report.Tables) != len(want) || report.Tables[0] != want[0] || report.Tables[1] != want[1] {
+		t.Fatalf("report = %+v, want %+v", report.Tables, want)
+	}
+
+	staging, err := OpenDirect(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer staging.Close()
+	if _, err := staging.Exec("SELECT * FROM secrets"); err == nil {
+		t.Fatal("unselected table was cloned")
+	}
+	if _, err := staging.Exec("INSERT INTO orders VALUES (13, 2, '2026-04-01')"); err == nil {
+		t.Fatal("foreign key to a filtered-out user accepted")
+	}
+
+	if _, err := db.Clone(ctx, CloneOptions{Dest: dest}); err == nil {
+		t.Fatal("existing destination accepted")
+	}
+	if _, err := db.Clone(ctx, CloneOptions{Dest: filepath.Join(dir, "bad.ddb"), Where: map[string]string{"missing": "1 = 1"}}); err == nil {
+		t.Fatal("filter on an unknown table accepted")
+	}
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := db.Clone(canceled, CloneOptions{Dest: filepath.Join(dir, "other.ddb")}); err != context.Canceled {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+}
+
+func TestDBClone_ScrubsColumns(t *testing.T) {
+	dir := t.TempDir()
+	db, err := OpenDirect(filepath.Join(dir, "prod.ddb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, stmt := range []string{
+		"CREATE TABLE users (id INT64 PRIMARY KEY, email TEXT UNIQUE, external_id TEXT, notes TEXT, name TEXT)",
+		"INSERT INTO users VALUES (1, 'ada@example.com', 'cus_1', 'vip', 'Ada'), (2, NULL, 'cus_2', 'late payer', 'Grace')",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+
+	ctx := context.Background()
+	dest := filepath.Join(dir, "shared.ddb")
+	_, err = db.Clone(ctx, CloneOptions{
+		Dest: dest,
+		Transforms: map[string]map[string]Transform{"users": {
+			"email":       MaskEmail(),
+			"external_id": HashText("pepper"),
+			"notes":       Nullify(),
+			"name":        SQLTransform("UPPER(name)"),
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shared, err := sql.Open("decentdb", "file:"+dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shared.Close()
+	var masked, hashed, name string
+	var notes, nullEmail sql.NullString
+	if err := shared.QueryRow("SELECT email, external_id, notes, name FROM users WHERE id = 1").Scan(&masked, &hashed, &notes, &name); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(masked, "user-") || !strings.HasSuffix(masked, "@example.invalid") || len(masked) != len("user-")+12+len("@example.invalid") {
+		t.Fatalf("masked email = %q", masked)
+	}
+	if len(hashed) != 64 || hashed == "cus_1" || notes.Valid || name != "ADA" {
+		t.Fatalf("hashed = %q, notes = %v, name = %q", hashed, notes, name)
+	}
+	if err := shared.QueryRow("SELECT email FROM users WHERE id = 2").Scan(&nullEmail); err != nil || nullEmail.Valid {
+		t.Fatalf("NULL email became %v, %v", nullEmail, err)
+	}
+
+	if _, err := db.Clone(ctx, CloneOptions{
+		Dest:       filepath.Join(dir, "bad.ddb"),
+		Transforms: map[string]map[string]Transform{"users": {"email": {}}},
+	}); err == nil {
+		t.Fatal("empty transform accepted")
+	}
+}
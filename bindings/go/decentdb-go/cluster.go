@@ -0,0 +1,286 @@
+package decentdb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const defaultClusterHealthCheckInterval = 5 * time.Second
+
+// maxClusterClassifications bounds how many statements' read/write
+// classification a Cluster remembers before starting over.
+const maxClusterClassifications = 1024
+
+// ClusterOptions configures NewClusterWithOptions. The zero value checks
+// replica health every five seconds and ignores replication lag.
+type ClusterOptions struct {
+	// HealthCheckInterval is how often replicas are pinged and their lag
+	// measured. Each check must finish within one interval.
+	HealthCheckInterval time.Duration
+	// MaxLag takes a replica out of rotation while it is further behind the
+	// primary than this. It needs Lag; zero disables the check.
+	MaxLag time.Duration
+	// Lag reports how far a replica is behind the primary, for example by
+	// comparing a heartbeat row the application writes on the primary. A
+	// replica whose probe fails is treated as unhealthy.
+	Lag func(ctx context.Context, replica *sql.DB) (time.Duration, error)
+}
+
+// ReplicaStatus is the result of the most recent health check of a replica.
+type ReplicaStatus struct {
+	DSN     string
+	Healthy bool
+	// Lag is the last value reported by ClusterOptions.Lag, or zero.
+	Lag       time.Duration
+	Err       error
+	CheckedAt time.Time
+}
+
+// Cluster routes statements across a primary database and read replicas.
+// Writes and read-write transactions go to the primary; reads and read-only
+// transactions go to a healthy replica, round robin, and fall back to the
+// primary when no replica is healthy. Replicas are kept in sync by the
+// application, for example with DecentDB sync or file shipping; Cluster
+// only routes.
+//
+// A Cluster is safe for concurrent use. Reads that must observe the
+// caller's own recent writes should use Primary directly.
+type Cluster struct {
+	primary  *sql.DB
+	replicas []*clusterReplica
+	opts     ClusterOptions
+	next     atomic.Uint64
+	stop     chan struct{}
+	done     chan struct{}
+	closed   sync.Once
+
+	// writes remembers, by SQL text, whether a statement writes.
+	writesMu sync.Mutex
+	writes   map[string]bool
+}
+
+type clusterReplica struct {
+	dsn string
+	db  *sql.DB
+
+	mu     sync.Mutex
+	status ReplicaStatus
+}
+
+// NewCluster opens the primary and replica DSNs with the decentdb driver
+// and starts health checks with the default options.
+func NewCluster(primaryDSN string, replicaDSNs ...string) (*Cluster, error) {
+	return NewClusterWithOptions(ClusterOptions{}, primaryDSN, replicaDSNs...)
+}
+
+// NewClusterWithOptions is NewCluster with options. Replicas are checked
+// once before it returns, so routing starts from their current state.
+func NewClusterWithOptions(opts ClusterOptions, primaryDSN string, replicaDSNs ...string) (*Cluster, error) {
+	if opts.HealthCheckInterval <= 0 {
+		opts.HealthCheckInterval = defaultClusterHealthCheckInterval
+	}
+	if opts.MaxLag > 0 && opts.Lag == nil {
+		return nil, errors.New("decentdb: ClusterOptions.MaxLag needs Lag")
+	}
+	primary, err := sql.Open("decentdb", primaryDSN)
+	if err != nil {
+		return nil, err
+	}
+	c := &Cluster{
+		primary: primary,
+		opts:    opts,
+		writes:  make(map[string]bool),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	for _, dsn := range replicaDSNs {
+		db, err := sql.Open("decentdb", dsn)
+		if err != nil {
+			c.closeDBs()
+			return nil, err
+		}
+		c.replicas = append(c.replicas, &clusterReplica{dsn: dsn, db: db, status: ReplicaStatus{DSN: dsn}})
+	}
+	c.checkReplicas()
+	go c.healthLoop()
+	return c, nil
+}
+
+// Primary returns the primary database.
+func (c *Cluster) Primary() *sql.DB { return c.primary }
+
+// Replica returns a healthy replica, or the primary when there is none.
+func (c *Cluster) Replica() *sql.DB {
+	if n := len(c.replicas); n > 0 {
+		start := c.next.Add(1)
+		for i := 0; i < n; i++ {
+			r := c.replicas[(start+uint64(i))%uint64(n)]
+			if r.healthy() {
+				return r.db
+			}
+		}
+	}
+	return c.primary
+}
+
+// ExecContext runs query on the primary.
+func (c *Cluster) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return c.primary.ExecContext(ctx, query, args...)
+}
+
+// QueryContext runs query on a replica, or on the primary when the query
+// writes.
+func (c *Cluster) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return c.route(ctx, query).QueryContext(ctx, query, args...)
+}
+
+// QueryRowContext runs query on a replica, or on the primary when the query
+// writes.
+func (c *Cluster) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return c.route(ctx, query).QueryRowContext(ctx, query, args...)
+}
+
+// BeginTx starts a transaction on a replica when opts.ReadOnly is set, and
+// on the primary otherwise.
+func (c *Cluster) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	if opts != nil && opts.ReadOnly {
+		return c.Replica().BeginTx(ctx, opts)
+	}
+	return c.primary.BeginTx(ctx, opts)
+}
+
+// ReplicaStatus returns the latest health check result of every replica, in
+// the order they were passed to NewCluster.
+func (c *Cluster) ReplicaStatus() []ReplicaStatus {
+	out := make([]ReplicaStatus, len(c.replicas))
+	for i, r := range c.replicas {
+		r.mu.Lock()
+		out[i] = r.status
+		r.mu.Unlock()
+	}
+	return out
+}
+
+// Close stops health checks and closes every database.
+func (c *Cluster) Close() error {
+	var err error
+	c.closed.Do(func() {
+		close(c.stop)
+		<-c.done
+		err = c.closeDBs()
+	})
+	return err
+}
+
+func (c *Cluster) closeDBs() error {
+	errs := []error{c.primary.Close()}
+	for _, r := range c.replicas {
+		errs = append(errs, r.db.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// route picks the database for query. A replica describes the query, and
+// only a statement the engine reports as read-only stays on it, so writes
+// led by WITH and statements that cannot be described, such as LOCK TABLE,
+// go to the primary. Reads never check out a primary connection.
+func (c *Cluster) route(ctx context.Context, query string) *sql.DB {
+	replica := c.Replica()
+	if replica == c.primary || c.statementWrites(ctx, replica, query) {
+		return c.primary
+	}
+	return replica
+}
+
+// statementWrites reports whether query writes, describing it on a
+// connection from db the first time the cluster sees it. A query that
+// cannot be described counts as a write and is described again next time.
+func (c *Cluster) statementWrites(ctx context.Context, db *sql.DB, query string) bool {
+	c.writesMu.Lock()
+	writes, ok := c.writes[query]
+	c.writesMu.Unlock()
+	if ok {
+		return writes
+	}
+	sqlConn, err := db.Conn(ctx)
+	if err != nil {
+		return true
+	}
+	defer sqlConn.Close()
+	var info *StmtInfo
+	sqlConn.Raw(func(driverConn any) error {
+		if dc, ok := driverConn.(*conn); ok {
+			info, err = dc.StmtInfo(query)
+		}
+		return nil
+	})
+	if info == nil || err != nil {
+		return true
+	}
+	c.writesMu.Lock()
+	if len(c.writes) >= maxClusterClassifications {
+		clear(c.writes)
+	}
+	c.writes[query] = !info.ReadOnly
+	c.writesMu.Unlock()
+	return !info.ReadOnly
+}
+
+func (c *Cluster) healthLoop() {
+	defer close(c.done)
+	if len(c.replicas) == 0 {
+		<-c.stop
+		return
+	}
+	ticker := time.NewTicker(c.opts.HealthCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+			c.checkReplicas()
+		}
+	}
+}
+
+func (c *Cluster) checkReplicas() {
+	var wg sync.WaitGroup
+	for _, r := range c.replicas {
+		wg.Add(1)
+		go func(r *clusterReplica) {
+			defer wg.Done()
+			c.checkReplica(r)
+		}(r)
+	}
+	wg.Wait()
+}
+
+func (c *Cluster) checkReplica(r *clusterReplica) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.opts.HealthCheckInterval)
+	defer cancel()
+	status := ReplicaStatus{DSN: r.dsn, CheckedAt: time.Now()}
+	status.Err = r.db.PingContext(ctx)
+	if status.Err == nil && c.opts.Lag != nil {
+		status.Lag, status.Err = c.opts.Lag(ctx, r.db)
+		if status.Err == nil && c.opts.MaxLag > 0 && status.Lag > c.opts.MaxLag {
+			status.Err = errReplicaLagging
+		}
+	}
+	status.Healthy = status.Err == nil
+	r.mu.Lock()
+	r.status = status
+	r.mu.Unlock()
+}
+
+var errReplicaLagging = errors.New("decentdb: replica lag exceeds ClusterOptions.MaxLag")
+
+func (r *clusterReplica) healthy() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.status.Healthy
+}
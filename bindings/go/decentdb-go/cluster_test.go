@@ -0,0 +1,180 @@
+package decentdb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestCluster creates a primary and two replica files whose "node" table
+// names the file, so tests can see where a statement ran.
+func newTestCluster(t *testing.T, opts ClusterOptions) (*Cluster, []string) {
+	t.Helper()
+	dir := t.TempDir()
+	names := []string{"primary", "replica1", "replica2"}
+	dsns := make([]string, len(names))
+	for i, name := range names {
+		dsns[i] = fmt.Sprintf("file:%s", filepath.Join(dir, name+".ddb"))
+		db, err := sql.Open("decentdb", dsns[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.Exec("CREATE TABLE node (name TEXT)"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.Exec("INSERT INTO node VALUES ($1)", name); err != nil {
+			t.Fatal(err)
+		}
+		db.Close()
+	}
+	c, err := NewClusterWithOptions(opts, dsns[0], dsns[1:]...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c, dsns
+}
+
+func nodeName(t *testing.T, c *Cluster) string {
+	t.Helper()
+	var name string
+	if err := c.QueryRowContext(context.Background(), "SELECT name FROM node").Scan(&name); err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
+
+func TestCluster_RoutesReadsToReplicas(t *testing.T) {
+	c, _ := newTestCluster(t, ClusterOptions{})
+	ctx := context.Background()
+
+	seen := map[string]bool{}
+	for i := 0; i < 4; i++ {
+		seen[nodeName(t, c)] = true
+	}
+	if !seen["replica1"] || !seen["replica2"] || seen["primary"] {
+		t.Fatalf("reads were not spread over the replicas: %v", seen)
+	}
+
+	if _, err := c.ExecContext(ctx, "INSERT INTO node VALUES ('written')"); err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	if err := c.Primary().QueryRow("SELECT COUNT(*) FROM node").Scan(&n); err != nil || n != 2 {
+		t.Fatalf("write did not reach the primary: count %d, err %v", n, err)
+	}
+	rows, err := c.QueryContext(ctx, "INSERT INTO node VALUES ('returned') RETURNING name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+	if err := c.Primary().QueryRow("SELECT COUNT(*) FROM node").Scan(&n); err != nil || n != 3 {
+		t.Fatalf("writing query did not reach the primary: count %d, err %v", n, err)
+	}
+
+	tx, err := c.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var name string
+	if err := tx.QueryRow("SELECT name FROM node").Scan(&name); err != nil {
+		t.Fatal(err)
+	}
+	tx.Rollback()
+	if name == "primary" {
+		t.Fatal("read-only transaction ran on the primary")
+	}
+}
+
+func TestCluster_RoutesByEngineReadOnlyFlag(t *testing.T) {
+	c, _ := newTestCluster(t, ClusterOptions{})
+	ctx := context.Background()
+
+	for _, query := range []string{
+		"WITH src AS (SELECT 'cte' AS name) INSERT INTO node SELECT name FROM src",
+		"LOCK TABLE node IN EXCLUSIVE MODE",
+	} {
+		if db := c.route(ctx, query); db != c.Primary() {
+			t.Errorf("%q was routed to a replica", query)
+		}
+	}
+	if db := c.route(ctx, "WITH n AS (SELECT name FROM node) SELECT name FROM n"); db == c.Primary() {
+		t.Error("read led by WITH was routed to the primary")
+	}
+
+	rows, err := c.QueryContext(ctx, "WITH src AS (SELECT 'cte' AS name) INSERT INTO node SELECT name FROM src RETURNING name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+	var n int
+	if err := c.Primary().QueryRow("SELECT COUNT(*) FROM node WHERE name = 'cte'").Scan(&n); err != nil || n != 1 {
+		t.Fatalf("WITH-led write did not reach the primary: count %d, err %v", n, err)
+	}
+}
+
+func TestCluster_ReadsDoNotUseThePrimaryPool(t *testing.T) {
+	c, _ := newTestCluster(t, ClusterOptions{})
+	for i := 0; i < 4; i++ {
+		if name := nodeName(t, c); name == "primary" {
+			t.Fatal("read ran on the primary")
+		}
+	}
+	if open := c.Primary().Stats().OpenConnections; open != 0 {
+		t.Fatalf("routing reads opened %d primary connections", open)
+	}
+	if _, ok := c.writes["SELECT name FROM node"]; !ok {
+		t.Fatal("read classification was not remembered")
+	}
+}
+
+func TestCluster_FallsBackWhenReplicasLag(t *testing.T) {
+	var lag atomic.Int64
+	c, dsns := newTestCluster(t, ClusterOptions{
+		HealthCheckInterval: 10 * time.Millisecond,
+		MaxLag:              time.Second,
+		Lag: func(ctx context.Context, replica *sql.DB) (time.Duration, error) {
+			var name string
+			if err := replica.QueryRowContext(ctx, "SELECT name FROM node").Scan(&name); err != nil {
+				return 0, err
+			}
+			if name == "replica1" {
+				return 0, errors.New("replica1 is down")
+			}
+			return time.Duration(lag.Load()), nil
+		},
+	})
+
+	for i := 0; i < 4; i++ {
+		if name := nodeName(t, c); name != "replica2" {
+			t.Fatalf("read ran on %s, want the only healthy replica", name)
+		}
+	}
+	status := c.ReplicaStatus()
+	if status[0].DSN != dsns[1] || status[0].Healthy || status[0].Err == nil || !status[1].Healthy {
+		t.Fatalf("unexpected replica status: %+v", status)
+	}
+
+	lag.Store(int64(time.Minute))
+	deadline := time.Now().Add(5 * time.Second)
+	for c.ReplicaStatus()[1].Healthy {
+		if time.Now().After(deadline) {
+			t.Fatal("lagging replica stayed in rotation")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if name := nodeName(t, c); name != "primary" {
+		t.Fatalf("read ran on %s, want the primary fallback", name)
+	}
+}
+
+func TestCluster_MaxLagNeedsLag(t *testing.T) {
+	if _, err := NewClusterWithOptions(ClusterOptions{MaxLag: time.Second}, "file::memory:"); err == nil {
+		t.Fatal("MaxLag without Lag was accepted")
+	}
+}
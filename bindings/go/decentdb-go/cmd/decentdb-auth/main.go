@@ -0,0 +1,157 @@
+// Command decentdb-auth manages the auth store read by decentdb-pgwire and
+// decentdb-grpc with -auth. Servers load the store when they start, so
+// restart them after making changes.
+//
+//	decentdb-auth -store auth.json user add alice          # password from $DECENTDB_PASSWORD
+//	decentdb-auth -store auth.json role add reporting
+//	decentdb-auth -store auth.json grant reporting app.orders SELECT
+//	decentdb-auth -store auth.json role assign alice reporting
+//	decentdb-auth -store auth.json token add alice dashboards
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/sphildreth/decentdb-go/auth"
+)
+
+const usage = `usage: decentdb-auth -store PATH COMMAND [ARGS]
+
+commands:
+  user add NAME             create a user; the password is read from
+                            $DECENTDB_PASSWORD (unset for a token-only user)
+  user passwd NAME          set the password from $DECENTDB_PASSWORD
+  user superuser NAME on|off
+  user drop NAME
+  role add NAME
+  role drop NAME
+  role assign USER ROLE
+  role unassign USER ROLE
+  grant ROLE DB[.TABLE] PRIV[,PRIV...]    DB may be *; PRIV is SELECT,
+  revoke ROLE DB[.TABLE] PRIV[,PRIV...]   INSERT, UPDATE, DELETE, DDL, or ALL
+  token add USER [LABEL]    print a new API token
+  token revoke ID
+  list
+`
+
+func main() {
+	storePath := flag.String("store", "", "path to the auth store file (required)")
+	flag.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	flag.Parse()
+	if *storePath == "" || flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	store, err := auth.Open(*storePath)
+	if err != nil {
+		fatal(err)
+	}
+	if err := run(store, flag.Args()); err != nil {
+		fatal(err)
+	}
+}
+
+func run(store *auth.Store, args []string) error {
+	cmd := strings.Join(args[:min(2, len(args))], " ")
+	switch {
+	case cmd == "user add" && len(args) == 3:
+		return store.CreateUser(args[2], os.Getenv("DECENTDB_PASSWORD"))
+	case cmd == "user passwd" && len(args) == 3:
+		return store.SetPassword(args[2], os.Getenv("DECENTDB_PASSWORD"))
+	case cmd == "user superuser" && len(args) == 4 && (args[3] == "on" || args[3] == "off"):
+		return store.SetSuperuser(args[2], args[3] == "on")
+	case cmd == "user drop" && len(args) == 3:
+		return store.DropUser(args[2])
+	case cmd == "role add" && len(args) == 3:
+		return store.CreateRole(args[2])
+	case cmd == "role drop" && len(args) == 3:
+		return store.DropRole(args[2])
+	case cmd == "role assign" && len(args) == 4:
+		return store.GrantRole(args[2], args[3])
+	case cmd == "role unassign" && len(args) == 4:
+		return store.RevokeRole(args[2], args[3])
+	case (args[0] == "grant" || args[0] == "revoke") && len(args) == 4:
+		g := parseGrant(args[2], args[3])
+		if args[0] == "grant" {
+			return store.Grant(args[1], g)
+		}
+		return store.Revoke(args[1], g)
+	case cmd == "token add" && (len(args) == 3 || len(args) == 4):
+		label := ""
+		if len(args) == 4 {
+			label = args[3]
+		}
+		t, secret, err := store.CreateToken(args[2], label)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("token %s for %s:\n%s\n", t.ID, t.User, secret)
+		return nil
+	case cmd == "token revoke" && len(args) == 3:
+		return store.RevokeToken(args[2])
+	case args[0] == "list" && len(args) == 1:
+		list(store)
+		return nil
+	}
+	flag.Usage()
+	os.Exit(2)
+	return nil
+}
+
+// parseGrant reads DB[.TABLE] and a comma-separated privilege list.
+func parseGrant(target, privs string) auth.Grant {
+	db, table, _ := strings.Cut(target, ".")
+	g := auth.Grant{Database: db, Table: table}
+	for _, p := range strings.Split(privs, ",") {
+		p = strings.ToUpper(strings.TrimSpace(p))
+		if p == "ALL" {
+			if table == "" {
+				g.Privileges = append(g.Privileges, auth.AllPrivileges...)
+			} else {
+				g.Privileges = append(g.Privileges, auth.Select, auth.Insert, auth.Update, auth.Delete)
+			}
+			continue
+		}
+		g.Privileges = append(g.Privileges, auth.Privilege(p))
+	}
+	return g
+}
+
+func list(store *auth.Store) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	defer w.Flush()
+	fmt.Fprintln(w, "USER\tSUPERUSER\tPASSWORD\tROLES")
+	for _, u := range store.Users() {
+		fmt.Fprintf(w, "%s\t%v\t%v\t%s\n", u.Name, u.Superuser, u.HasPassword, strings.Join(u.Roles, ","))
+	}
+	fmt.Fprintln(w, "\nROLE\tDATABASE\tTABLE\tPRIVILEGES")
+	for _, r := range store.Roles() {
+		if len(r.Grants) == 0 {
+			fmt.Fprintf(w, "%s\t\t\t\n", r.Name)
+		}
+		for _, g := range r.Grants {
+			table := g.Table
+			if table == "" {
+				table = "*"
+			}
+			privs := make([]string, len(g.Privileges))
+			for i, p := range g.Privileges {
+				privs[i] = string(p)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", r.Name, g.Database, table, strings.Join(privs, ","))
+		}
+	}
+	fmt.Fprintln(w, "\nTOKEN\tUSER\tLABEL\tCREATED")
+	for _, t := range store.Tokens("") {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", t.ID, t.User, t.Name, t.Created.Format("2006-01-02 15:04"))
+	}
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "decentdb-auth:", err)
+	os.Exit(1)
+}
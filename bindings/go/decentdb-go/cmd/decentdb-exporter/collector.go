@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sphildreth/decentdb-go"
+)
+
+// inspector is the part of the driver connection the collector uses.
+type inspector interface {
+	StorageState() (*decentdb.StorageState, error)
+	ListTables() ([]string, error)
+}
+
+// target is one exported database and its most recent collection.
+type target struct {
+	name              string
+	path              string
+	rowCountInterval  time.Duration
+	integrityInterval time.Duration
+
+	mu   sync.Mutex
+	snap snapshot
+}
+
+// snapshot holds what the last collections found. Row counts and the
+// integrity result keep their previous values between their own, less
+// frequent, runs and when a collection fails.
+type snapshot struct {
+	up          bool
+	collectedAt time.Time
+	duration    time.Duration
+
+	dbSize       int64
+	walSize      int64
+	checkpointAt time.Time
+	state        *decentdb.StorageState
+
+	rows   map[string]int64
+	rowsAt time.Time
+
+	integrityOK bool
+	integrityAt time.Time
+}
+
+// run collects now and then every interval, forever.
+func (t *target) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := t.collect(context.Background(), time.Now()); err != nil {
+			log.Printf("%s: %v", t.name, err)
+		}
+		<-ticker.C
+	}
+}
+
+func (t *target) collect(ctx context.Context, now time.Time) error {
+	t.mu.Lock()
+	next := t.snap
+	t.mu.Unlock()
+	next.up = false
+	next.collectedAt = now
+
+	err := t.collectInto(ctx, now, &next)
+	next.up = err == nil
+	next.duration = time.Since(now)
+
+	t.mu.Lock()
+	t.snap = next
+	t.mu.Unlock()
+	return err
+}
+
+func (t *target) collectInto(ctx context.Context, now time.Time, s *snapshot) error {
+	db, err := sql.Open("decentdb", "file:"+t.path+"?mode=open&defensive=true")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var tables []string
+	err = conn.Raw(func(dc any) error {
+		in, ok := dc.(inspector)
+		if !ok {
+			return errors.New("driver connection does not report storage state")
+		}
+		state, err := in.StorageState()
+		if err != nil {
+			return err
+		}
+		s.state = state
+		tables, err = in.ListTables()
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	// In WAL mode only checkpoints write the main file, so its modification
+	// time is when the last checkpoint finished.
+	info, err := os.Stat(t.path)
+	if err != nil {
+		return err
+	}
+	s.dbSize = info.Size()
+	s.checkpointAt = info.ModTime()
+	s.walSize = 0
+	if info, err := os.Stat(s.state.WALPath); err == nil {
+		s.walSize = info.Size()
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	if due(s.rowsAt, t.rowCountInterval, now) {
+		rows := make(map[string]int64, len(tables))
+		for _, table := range tables {
+			var n int64
+			query := "SELECT COUNT(*) FROM " + quoteIdent(table)
+			if err := conn.QueryRowContext(ctx, query).Scan(&n); err != nil {
+				return fmt.Errorf("count rows of %s: %w", table, err)
+			}
+			rows[table] = n
+		}
+		s.rows, s.rowsAt = rows, now
+	}
+
+	if due(s.integrityAt, t.integrityInterval, now) {
+		ok, err := integrityCheck(ctx, conn)
+		if err != nil {
+			return fmt.Errorf("integrity check: %w", err)
+		}
+		s.integrityOK, s.integrityAt = ok, now
+	}
+	return nil
+}
+
+// due reports whether a job last run at last and repeating every interval
+// should run at now. A zero interval disables the job.
+func due(last time.Time, interval time.Duration, now time.Time) bool {
+	return interval > 0 && (last.IsZero() || now.Sub(last) >= interval)
+}
+
+// integrityCheck runs PRAGMA integrity_check, which returns the single row
+// "ok" for a sound database and one row per problem otherwise.
+func integrityCheck(ctx context.Context, conn *sql.Conn) (bool, error) {
+	rows, err := conn.QueryContext(ctx, "PRAGMA integrity_check")
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	var results []string
+	for rows.Next() {
+		var result string
+		if err := rows.Scan(&result); err != nil {
+			return false, err
+		}
+		results = append(results, result)
+	}
+	if err := rows.Err(); err != nil {
+		return false, err
+	}
+	return len(results) == 1 && results[0] == "ok", nil
+}
+
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
@@ -0,0 +1,79 @@
+// Command decentdb-exporter serves Prometheus metrics for one or more
+// DecentDB files: file and WAL sizes, WAL positions, checkpoint age, per-table
+// row counts, and the result and age of the last integrity check.
+//
+//	decentdb-exporter -db app=/var/lib/app/app.ddb -listen :9464
+//
+// Each collection opens the file defensively (mode=open, no DDL, no open
+// checkpoint) and closes it again, so the exporter never holds the WAL
+// against the application's checkpoints and always sees its latest commit.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	_ "github.com/sphildreth/decentdb-go"
+)
+
+// dbFlags collects repeated -db name=path flags.
+type dbFlags map[string]string
+
+func (f dbFlags) String() string { return fmt.Sprint(map[string]string(f)) }
+
+func (f dbFlags) Set(v string) error {
+	name, path, ok := strings.Cut(v, "=")
+	if !ok {
+		path = v
+		name = strings.TrimSuffix(filepath.Base(v), filepath.Ext(v))
+	}
+	if name == "" || path == "" {
+		return fmt.Errorf("want name=path, got %q", v)
+	}
+	f[name] = path
+	return nil
+}
+
+func main() {
+	paths := dbFlags{}
+	flag.Var(paths, "db", "database to export as name=path; repeatable (a bare path is named after its file)")
+	listen := flag.String("listen", "127.0.0.1:9464", "TCP address to serve /metrics on")
+	interval := flag.Duration("interval", 15*time.Second, "how often to collect sizes, WAL state, and checkpoint age")
+	rowCountInterval := flag.Duration("row-count-interval", 5*time.Minute, "how often to count table rows; 0 disables")
+	integrityInterval := flag.Duration("integrity-interval", time.Hour, "how often to run PRAGMA integrity_check; 0 disables")
+	flag.Parse()
+
+	if len(paths) == 0 {
+		fmt.Fprintln(os.Stderr, "decentdb-exporter: at least one -db is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *interval <= 0 {
+		log.Fatal("-interval must be positive")
+	}
+
+	targets := make([]*target, 0, len(paths))
+	for name, path := range paths {
+		if _, err := os.Stat(path); err != nil {
+			log.Fatal(err)
+		}
+		t := &target{
+			name:              name,
+			path:              path,
+			rowCountInterval:  *rowCountInterval,
+			integrityInterval: *integrityInterval,
+		}
+		targets = append(targets, t)
+		go t.run(*interval)
+	}
+
+	http.Handle("/metrics", metricsHandler(targets))
+	log.Printf("exporting %d database(s) on %s/metrics", len(targets), *listen)
+	log.Fatal(http.ListenAndServe(*listen, nil))
+}
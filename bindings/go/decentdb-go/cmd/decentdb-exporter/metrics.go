@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// family is one metric in the Prometheus text exposition format.
+type family struct {
+	name    string
+	help    string
+	typ     string
+	samples []sample
+}
+
+type sample struct {
+	labels []string // alternating names and values
+	value  float64
+}
+
+func (f *family) add(value float64, labels ...string) {
+	f.samples = append(f.samples, sample{labels: labels, value: value})
+}
+
+// metricsHandler serves the latest snapshot of every target.
+func metricsHandler(targets []*target) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		bw := bufio.NewWriter(w)
+		for _, f := range gather(targets, time.Now()) {
+			writeFamily(bw, f)
+		}
+		bw.Flush()
+	})
+}
+
+func gather(targets []*target, now time.Time) []*family {
+	var (
+		up          = &family{name: "decentdb_up", typ: "gauge", help: "Whether the last collection of the database succeeded."}
+		duration    = &family{name: "decentdb_collect_duration_seconds", typ: "gauge", help: "How long the last collection took."}
+		dbSize      = &family{name: "decentdb_database_size_bytes", typ: "gauge", help: "Size of the main database file."}
+		walSize     = &family{name: "decentdb_wal_size_bytes", typ: "gauge", help: "Size of the WAL file; 0 when it does not exist."}
+		pageSize    = &family{name: "decentdb_page_size_bytes", typ: "gauge", help: "Page size of the database."}
+		pages       = &family{name: "decentdb_pages", typ: "gauge", help: "Pages in the main database file."}
+		walEnd      = &family{name: "decentdb_wal_end_lsn", typ: "gauge", help: "WAL position of the latest commit."}
+		ckptLSN     = &family{name: "decentdb_last_checkpoint_lsn", typ: "gauge", help: "WAL position the main file was last checkpointed to."}
+		walVersions = &family{name: "decentdb_wal_versions", typ: "gauge", help: "Page versions held in the WAL index."}
+		ckptAge     = &family{name: "decentdb_checkpoint_age_seconds", typ: "gauge", help: "Seconds since a checkpoint last wrote the main database file."}
+		rows        = &family{name: "decentdb_table_rows", typ: "gauge", help: "Rows in each table as of the last row count."}
+		rowsAge     = &family{name: "decentdb_row_count_age_seconds", typ: "gauge", help: "Seconds since the table rows were last counted."}
+		integrityOK = &family{name: "decentdb_integrity_check_ok", typ: "gauge", help: "Whether the last PRAGMA integrity_check reported ok."}
+		integrity   = &family{name: "decentdb_integrity_check_age_seconds", typ: "gauge", help: "Seconds since PRAGMA integrity_check last ran."}
+	)
+	sorted := slices.Clone(targets)
+	slices.SortFunc(sorted, func(a, b *target) int { return strings.Compare(a.name, b.name) })
+	for _, t := range sorted {
+		t.mu.Lock()
+		s := t.snap
+		t.mu.Unlock()
+		if s.collectedAt.IsZero() {
+			continue
+		}
+		db := []string{"db", t.name}
+		up.add(boolValue(s.up), db...)
+		duration.add(s.duration.Seconds(), db...)
+		if s.state != nil {
+			dbSize.add(float64(s.dbSize), db...)
+			walSize.add(float64(s.walSize), db...)
+			pageSize.add(float64(s.state.PageSize), db...)
+			pages.add(float64(s.state.PageCount), db...)
+			walEnd.add(float64(s.state.WALEndLSN), db...)
+			ckptLSN.add(float64(s.state.LastCheckpointLSN), db...)
+			walVersions.add(float64(s.state.WALVersions), db...)
+			ckptAge.add(now.Sub(s.checkpointAt).Seconds(), db...)
+		}
+		if !s.rowsAt.IsZero() {
+			names := make([]string, 0, len(s.rows))
+			for name := range s.rows {
+				names = append(names, name)
+			}
+			slices.Sort(names)
+			for _, name := range names {
+				rows.add(float64(s.rows[name]), "db", t.name, "table", name)
+			}
+			rowsAge.add(now.Sub(s.rowsAt).Seconds(), db...)
+		}
+		if !s.integrityAt.IsZero() {
+			integrityOK.add(boolValue(s.integrityOK), db...)
+			integrity.add(now.Sub(s.integrityAt).Seconds(), db...)
+		}
+	}
+	return []*family{up, duration, dbSize, walSize, pageSize, pages, walEnd, ckptLSN, walVersions, ckptAge, rows, rowsAge, integrityOK, integrity}
+}
+
+func writeFamily(w *bufio.Writer, f *family) {
+	if len(f.samples) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", f.name, f.help, f.name, f.typ)
+	for _, s := range f.samples {
+		w.WriteString(f.name)
+		if len(s.labels) > 0 {
+			w.WriteByte('{')
+			for i := 0; i < len(s.labels); i += 2 {
+				if i > 0 {
+					w.WriteByte(',')
+				}
+				fmt.Fprintf(w, "%s=\"%s\"", s.labels[i], escapeLabel(s.labels[i+1]))
+			}
+			w.WriteByte('}')
+		}
+		w.WriteByte(' ')
+		w.WriteString(strconv.FormatFloat(s.value, 'g', -1, 64))
+		w.WriteByte('\n')
+	}
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(v string) string { return labelEscaper.Replace(v) }
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,103 @@
+// Command decentdb-grpc serves DecentDB files over the DecentDB gRPC
+// service so remote clients can connect with a decentdb://host:port/name DSN.
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	_ "github.com/sphildreth/decentdb-go"
+	"github.com/sphildreth/decentdb-go/auth"
+	"github.com/sphildreth/decentdb-go/grpcserver"
+	"github.com/sphildreth/decentdb-go/tlsconfig"
+)
+
+// dbFlags collects repeated -db name=path flags.
+type dbFlags map[string]string
+
+func (f dbFlags) String() string { return fmt.Sprint(map[string]string(f)) }
+
+func (f dbFlags) Set(v string) error {
+	name, path, ok := strings.Cut(v, "=")
+	if !ok {
+		path = v
+		name = strings.TrimSuffix(filepath.Base(v), filepath.Ext(v))
+	}
+	if name == "" || path == "" {
+		return fmt.Errorf("want name=path, got %q", v)
+	}
+	f[name] = path
+	return nil
+}
+
+func main() {
+	paths := dbFlags{}
+	flag.Var(paths, "db", "database to serve as name=path; repeatable (a bare path is named after its file)")
+	listen := flag.String("listen", "127.0.0.1:7070", "TCP address to listen on")
+	tokenEnv := flag.String("token-env", "", "environment variable holding the bearer token clients must send")
+	readOnly := flag.Bool("read-only", false, "reject writes and run sessions read-only")
+	authPath := flag.String("auth", "", "auth store of users and grants (see decentdb-auth); replaces -token-env")
+	tlsCert := flag.String("tls-cert", "", "PEM certificate chain to serve TLS with; reloaded when it changes")
+	tlsKey := flag.String("tls-key", "", "PEM private key for -tls-cert")
+	tlsClientCA := flag.String("tls-client-ca", "", "PEM CAs to verify client certificates against")
+	tlsRequireClientCert := flag.Bool("tls-require-client-cert", false, "reject clients without a certificate signed by -tls-client-ca")
+	flag.Parse()
+
+	if len(paths) == 0 {
+		fmt.Fprintln(os.Stderr, "decentdb-grpc: at least one -db is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	dbs := make(map[string]*sql.DB, len(paths))
+	for name, path := range paths {
+		db, err := sql.Open("decentdb", "file:"+path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer db.Close()
+		dbs[name] = db
+	}
+
+	var opts []grpcserver.Option
+	if *authPath != "" {
+		store, err := auth.Open(*authPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		opts = append(opts, grpcserver.WithAuth(store))
+	} else if *tokenEnv != "" {
+		token := os.Getenv(*tokenEnv)
+		if token == "" {
+			log.Fatalf("%s is empty", *tokenEnv)
+		}
+		opts = append(opts, grpcserver.WithBearerToken(token))
+	}
+	if *readOnly {
+		opts = append(opts, grpcserver.WithReadOnly())
+	}
+
+	if *tlsCert != "" || *tlsKey != "" {
+		r, err := tlsconfig.Server{
+			CertFile:          *tlsCert,
+			KeyFile:           *tlsKey,
+			ClientCAFile:      *tlsClientCA,
+			RequireClientCert: *tlsRequireClientCert,
+		}.Load()
+		if err != nil {
+			log.Fatal(err)
+		}
+		opts = append(opts, grpcserver.WithTLSConfig(r.Config()))
+	} else if *tlsClientCA != "" || *tlsRequireClientCert {
+		log.Fatal("-tls-client-ca and -tls-require-client-cert need -tls-cert and -tls-key")
+	}
+
+	srv := grpcserver.New(dbs, opts...)
+	log.Printf("serving %d database(s) on %s", len(dbs), *listen)
+	log.Fatal(srv.ListenAndServe(*listen))
+}
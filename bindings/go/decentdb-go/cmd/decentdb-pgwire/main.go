@@ -0,0 +1,82 @@
+// Command decentdb-pgwire serves a DecentDB file over the PostgreSQL wire
+// protocol so psql and other Postgres clients can connect to it.
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	_ "github.com/sphildreth/decentdb-go"
+	"github.com/sphildreth/decentdb-go/auth"
+	"github.com/sphildreth/decentdb-go/pgwire"
+	"github.com/sphildreth/decentdb-go/tlsconfig"
+)
+
+func main() {
+	dbPath := flag.String("db", "", "path to the database file (required)")
+	listen := flag.String("listen", "127.0.0.1:5432", "TCP address to listen on")
+	user := flag.String("user", "", "user name clients must authenticate as")
+	passwordEnv := flag.String("password-env", "", "environment variable holding the password for -user")
+	authPath := flag.String("auth", "", "auth store of users and grants (see decentdb-auth); replaces -user")
+	database := flag.String("database", "", "name of the database in the auth store (default: the file name without extension)")
+	tlsCert := flag.String("tls-cert", "", "PEM certificate chain to serve TLS with; reloaded when it changes")
+	tlsKey := flag.String("tls-key", "", "PEM private key for -tls-cert")
+	tlsClientCA := flag.String("tls-client-ca", "", "PEM CAs to verify client certificates against")
+	tlsRequireClientCert := flag.Bool("tls-require-client-cert", false, "reject clients without a certificate signed by -tls-client-ca")
+	flag.Parse()
+
+	if *dbPath == "" {
+		fmt.Fprintln(os.Stderr, "decentdb-pgwire: -db is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	db, err := sql.Open("decentdb", "file:"+*dbPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	var opts []pgwire.Option
+	if *authPath != "" {
+		store, err := auth.Open(*authPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		name := *database
+		if name == "" {
+			name = strings.TrimSuffix(filepath.Base(*dbPath), filepath.Ext(*dbPath))
+		}
+		opts = append(opts, pgwire.WithAuth(store, name))
+	} else if *passwordEnv != "" {
+		password := os.Getenv(*passwordEnv)
+		if *user == "" || password == "" {
+			log.Fatalf("-password-env requires -user and a non-empty %s", *passwordEnv)
+		}
+		opts = append(opts, pgwire.WithPassword(*user, password))
+	}
+
+	if *tlsCert != "" || *tlsKey != "" {
+		r, err := tlsconfig.Server{
+			CertFile:          *tlsCert,
+			KeyFile:           *tlsKey,
+			ClientCAFile:      *tlsClientCA,
+			RequireClientCert: *tlsRequireClientCert,
+		}.Load()
+		if err != nil {
+			log.Fatal(err)
+		}
+		opts = append(opts, pgwire.WithTLSConfig(r.Config()))
+	} else if *tlsClientCA != "" || *tlsRequireClientCert {
+		log.Fatal("-tls-client-ca and -tls-require-client-cert need -tls-cert and -tls-key")
+	}
+
+	srv := pgwire.New(db, opts...)
+	log.Printf("serving %s on %s", *dbPath, *listen)
+	log.Fatal(srv.ListenAndServe(*listen))
+}
@@ -0,0 +1,13 @@
+package decentdb
+
+// WithDefaultCollation sets the collation ("binary", "nocase", or "rtrim")
+// that CREATE TABLE and ALTER TABLE ADD COLUMN give TEXT columns declared
+// without a COLLATE clause. The collation is stored with each column, so it
+// keeps applying after the database is reopened with a different default.
+// The default_collation DSN option does the same for sql.Open and takes
+// precedence.
+func WithDefaultCollation(name string) ConnectorOption {
+	return func(c *connector) {
+		c.defaultCollation = name
+	}
+}
@@ -0,0 +1,55 @@
+package decentdb
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+func TestDefaultCollation_DSNAndConnectorOption(t *testing.T) {
+	dir := t.TempDir()
+	matches := func(db *sql.DB) int {
+		t.Helper()
+		if _, err := db.Exec("CREATE TABLE users (id INT PRIMARY KEY, email TEXT)"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.Exec("INSERT INTO users VALUES (1, 'Alice@Example.com')"); err != nil {
+			t.Fatal(err)
+		}
+		var n int
+		if err := db.QueryRow("SELECT COUNT(*) FROM users WHERE email = $1", "alice@example.com").Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	db, err := sql.Open("decentdb", fmt.Sprintf("file:%s?default_collation=NOCASE", filepath.Join(dir, "dsn.ddb")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if got := matches(db); got != 1 {
+		t.Fatalf("DSN default_collation=nocase matched %d rows, want 1", got)
+	}
+
+	connector, err := NewConnector(filepath.Join(dir, "option.ddb"), WithDefaultCollation("nocase"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	optioned := sql.OpenDB(connector)
+	defer optioned.Close()
+	if got := matches(optioned); got != 1 {
+		t.Fatalf("WithDefaultCollation(nocase) matched %d rows, want 1", got)
+	}
+
+	overridden, err := NewConnector(fmt.Sprintf("file:%s?default_collation=binary", filepath.Join(dir, "override.ddb")), WithDefaultCollation("nocase"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	binary := sql.OpenDB(overridden)
+	defer binary.Close()
+	if got := matches(binary); got != 0 {
+		t.Fatalf("DSN default_collation=binary over WithDefaultCollation matched %d rows, want 0", got)
+	}
+}
@@ -0,0 +1,74 @@
+package decentdb
+
+/*
+#include "decentdb.h"
+*/
+import "C"
+import (
+	"context"
+	"fmt"
+	"slices"
+	"unsafe"
+)
+
+type columnProjectionKey struct{}
+
+// WithColumnProjection returns a context whose queries decode only the
+// result columns at the given zero-based indexes. The engine hands the
+// driver just those columns, so reading a few columns of a wide row skips
+// converting and copying the rest. Columns left out still appear in
+// Columns and scan as NULL; scan them into *any or sql.RawBytes, or select
+// fewer columns when the query can be changed. Calling it with no indexes
+// removes a projection set by a parent context.
+func WithColumnProjection(ctx context.Context, columns ...int) context.Context {
+	return context.WithValue(ctx, columnProjectionKey{}, slices.Clone(columns))
+}
+
+// ColumnProjectionFromContext returns the columns set by
+// WithColumnProjection, if any.
+func ColumnProjectionFromContext(ctx context.Context) ([]int, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	columns, ok := ctx.Value(columnProjectionKey{}).([]int)
+	return columns, ok && len(columns) > 0
+}
+
+// useViewColumns restricts the statement's row views to columns, or
+// restores every column when columns is empty.
+func (s *stmtStruct) useViewColumns(columns []int) error {
+	if slices.Equal(columns, s.viewColumns) {
+		return nil
+	}
+	native := make([]C.size_t, len(columns))
+	for i, column := range columns {
+		if column < 0 {
+			return fmt.Errorf("decentdb: column projection index %d is negative", column)
+		}
+		native[i] = C.size_t(column)
+	}
+	var ptr *C.size_t
+	if len(native) > 0 {
+		ptr = (*C.size_t)(unsafe.Pointer(&native[0]))
+	}
+	status := C.ddb_stmt_set_row_view_columns(s.stmt, ptr, C.size_t(len(native)))
+	if status != C.DDB_OK {
+		return statusError(status, s.query)
+	}
+	s.viewColumns = slices.Clone(columns)
+	return nil
+}
+
+// viewIndex returns where result column sits in r.views, or -1 when the
+// column was projected away.
+func (r *rows) viewIndex(column int) int {
+	if r.projection == nil {
+		return column
+	}
+	for i, projected := range r.projection {
+		if projected == column {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,67 @@
+package decentdb
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+func TestDriver_ColumnProjection(t *testing.T) {
+	db, err := sql.Open("decentdb", "file:"+filepath.Join(t.TempDir(), "projection.ddb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, stmt := range []string{
+		"CREATE TABLE t (id INTEGER PRIMARY KEY, a TEXT, b TEXT, c INTEGER)",
+		"INSERT INTO t VALUES (1, 'one', 'uno', 10), (2, 'two', 'dos', 20)",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+
+	ctx := WithColumnProjection(context.Background(), 0, 3)
+	rows, err := db.QueryContext(ctx, "SELECT id, a, b, c FROM t ORDER BY id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	if columns, _ := rows.Columns(); len(columns) != 4 {
+		t.Fatalf("columns = %v, want all four", columns)
+	}
+	var got [][2]int64
+	for rows.Next() {
+		var id, c int64
+		var a, b any
+		if err := rows.Scan(&id, &a, &b, &c); err != nil {
+			t.Fatal(err)
+		}
+		if a != nil || b != nil {
+			t.Fatalf("unprojected columns = %v, %v, want nil", a, b)
+		}
+		got = append(got, [2]int64{id, c})
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0] != [2]int64{1, 10} || got[1] != [2]int64{2, 20} {
+		t.Fatalf("rows = %v", got)
+	}
+
+	// A plain context on the same pooled statement sees every column again.
+	var b string
+	if err := db.QueryRow("SELECT b FROM t WHERE id = 2").Scan(&b); err != nil || b != "dos" {
+		t.Fatalf("unprojected query = %q, %v", b, err)
+	}
+
+	if _, err := db.QueryContext(WithColumnProjection(context.Background(), -1), "SELECT id FROM t"); err == nil {
+		t.Fatal("negative projection index was accepted")
+	}
+	var id int64
+	err = db.QueryRowContext(WithColumnProjection(context.Background(), 9), "SELECT id FROM t").Scan(&id)
+	if err == nil {
+		t.Fatal("out-of-range projection index was accepted")
+	}
+}
@@ -0,0 +1,295 @@
+package decentdb
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// ColumnKind identifies which typed slice of a Column holds its values.
+type ColumnKind int
+
+const (
+	// ColumnNull means every value of the column in the batch is NULL.
+	ColumnNull ColumnKind = iota
+	ColumnInt64
+	ColumnFloat64
+	ColumnBool
+	ColumnText
+	// ColumnBytes holds BLOB, UUID, GEOMETRY, and GEOGRAPHY values.
+	ColumnBytes
+	// ColumnTime holds TIMESTAMP values in UTC.
+	ColumnTime
+	// ColumnAny holds boxed values in Values: decimals, the semantic types
+	// (dates, intervals, enums, network addresses), and columns whose
+	// values change type within the batch.
+	ColumnAny
+)
+
+// Column is one result column of a ColumnBatch. Exactly one typed slice,
+// chosen by Kind, is populated and has one element per row; rows that are
+// NULL hold the zero value and are marked in Nulls.
+type Column struct {
+	Name string
+	Kind ColumnKind
+	// Nulls marks the NULL rows. It is nil when the batch has no NULLs in
+	// this column.
+	Nulls []bool
+
+	Int64   []int64
+	Float64 []float64
+	Bool    []bool
+	Text    []string
+	Bytes   [][]byte
+	Time    []time.Time
+	Values  []any
+
+	rows int
+	hint int
+}
+
+// IsNull reports whether row i of the column is NULL.
+func (c *Column) IsNull(i int) bool {
+	return c.Kind == ColumnNull || (c.Nulls != nil && c.Nulls[i])
+}
+
+// Value returns row i boxed, or nil for NULL. It is a convenience for
+// generic code; analytics loops should read the typed slice directly.
+func (c *Column) Value(i int) any {
+	if c.IsNull(i) {
+		return nil
+	}
+	switch c.Kind {
+	case ColumnInt64:
+		return c.Int64[i]
+	case ColumnFloat64:
+		return c.Float64[i]
+	case ColumnBool:
+		return c.Bool[i]
+	case ColumnText:
+		return c.Text[i]
+	case ColumnBytes:
+		return c.Bytes[i]
+	case ColumnTime:
+		return c.Time[i]
+	default:
+		return c.Values[i]
+	}
+}
+
+// accept prepares the column for a non-NULL value of kind k. It reports
+// false when the value must be boxed into Values instead.
+func (c *Column) accept(k ColumnKind) bool {
+	switch c.Kind {
+	case k:
+		return true
+	case ColumnNull:
+		c.Kind = k
+		size := c.hint
+		if size < c.rows {
+			size = c.rows
+		}
+		switch k {
+		case ColumnInt64:
+			c.Int64 = make([]int64, c.rows, size)
+		case ColumnFloat64:
+			c.Float64 = make([]float64, c.rows, size)
+		case ColumnBool:
+			c.Bool = make([]bool, c.rows, size)
+		case ColumnText:
+			c.Text = make([]string, c.rows, size)
+		case ColumnBytes:
+			c.Bytes = make([][]byte, c.rows, size)
+		case ColumnTime:
+			c.Time = make([]time.Time, c.rows, size)
+		default:
+			c.Values = make([]any, c.rows, size)
+		}
+		return true
+	case ColumnAny:
+		return false
+	default:
+		c.box()
+		return false
+	}
+}
+
+// box moves the typed values already appended into Values.
+func (c *Column) box() {
+	values := make([]any, c.rows, max(c.hint, c.rows))
+	for i := range values {
+		values[i] = c.Value(i)
+	}
+	*c = Column{Name: c.Name, Kind: ColumnAny, Nulls: c.Nulls, Values: values, rows: c.rows, hint: c.hint}
+}
+
+func (c *Column) appended(null bool) {
+	if null && c.Nulls == nil {
+		c.Nulls = make([]bool, c.rows, max(c.hint, c.rows+1))
+	}
+	if c.Nulls != nil {
+		c.Nulls = append(c.Nulls, null)
+	}
+	c.rows++
+}
+
+func (c *Column) appendNull() {
+	switch c.Kind {
+	case ColumnInt64:
+		c.Int64 = append(c.Int64, 0)
+	case ColumnFloat64:
+		c.Float64 = append(c.Float64, 0)
+	case ColumnBool:
+		c.Bool = append(c.Bool, false)
+	case ColumnText:
+		c.Text = append(c.Text, "")
+	case ColumnBytes:
+		c.Bytes = append(c.Bytes, nil)
+	case ColumnTime:
+		c.Time = append(c.Time, time.Time{})
+	case ColumnAny:
+		c.Values = append(c.Values, nil)
+	}
+	c.appended(true)
+}
+
+func (c *Column) appendInt64(v int64) {
+	if c.accept(ColumnInt64) {
+		c.Int64 = append(c.Int64, v)
+	} else {
+		c.Values = append(c.Values, v)
+	}
+	c.appended(false)
+}
+
+func (c *Column) appendFloat64(v float64) {
+	if c.accept(ColumnFloat64) {
+		c.Float64 = append(c.Float64, v)
+	} else {
+		c.Values = append(c.Values, v)
+	}
+	c.appended(false)
+}
+
+func (c *Column) appendBool(v bool) {
+	if c.accept(ColumnBool) {
+		c.Bool = append(c.Bool, v)
+	} else {
+		c.Values = append(c.Values, v)
+	}
+	c.appended(false)
+}
+
+func (c *Column) appendText(v string) {
+	if c.accept(ColumnText) {
+		c.Text = append(c.Text, v)
+	} else {
+		c.Values = append(c.Values, v)
+	}
+	c.appended(false)
+}
+
+func (c *Column) appendBytes(v []byte) {
+	if c.accept(ColumnBytes) {
+		c.Bytes = append(c.Bytes, v)
+	} else {
+		c.Values = append(c.Values, v)
+	}
+	c.appended(false)
+}
+
+func (c *Column) appendTime(v time.Time) {
+	if c.accept(ColumnTime) {
+		c.Time = append(c.Time, v)
+	} else {
+		c.Values = append(c.Values, v)
+	}
+	c.appended(false)
+}
+
+func (c *Column) appendValue(v any) {
+	c.accept(ColumnAny)
+	c.Values = append(c.Values, v)
+	c.appended(false)
+}
+
+// ColumnBatch is a block of result rows stored column by column.
+type ColumnBatch struct {
+	// Rows is the number of rows in the batch.
+	Rows    int
+	Columns []Column
+}
+
+func newColumnBatch(names []string, rows int) *ColumnBatch {
+	b := &ColumnBatch{Rows: rows, Columns: make([]Column, len(names))}
+	for i, name := range names {
+		b.Columns[i] = Column{Name: name, hint: rows}
+	}
+	return b
+}
+
+// Column returns the column called name, or nil if the batch has none.
+func (b *ColumnBatch) Column(name string) *Column {
+	for i := range b.Columns {
+		if b.Columns[i].Name == name {
+			return &b.Columns[i]
+		}
+	}
+	return nil
+}
+
+// ColumnRows is a result read in column batches with FetchColumns.
+type ColumnRows struct {
+	r     *rows
+	names []string
+}
+
+// QueryColumns runs query and returns its result for column-oriented
+// reads. Each value is decoded straight into a typed slice, so analytics
+// code avoids boxing every cell in an interface. Close the result when done.
+func (d *DB) QueryColumns(ctx context.Context, query string, args ...driver.Value) (*ColumnRows, error) {
+	if atomic.LoadUint32(&d.closed) != 0 {
+		return nil, driver.ErrBadConn
+	}
+	namedArgs := make([]driver.NamedValue, len(args))
+	for i, a := range args {
+		namedArgs[i] = driver.NamedValue{Ordinal: i + 1, Value: a}
+	}
+	s, err := d.c.prepareStmt(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+	r, err := s.queryContext(ctx, namedArgs)
+	if err != nil {
+		return nil, err
+	}
+	rows := r.(*rows)
+	return &ColumnRows{r: rows, names: rows.Columns()}, nil
+}
+
+// Columns returns the result's column names.
+func (cr *ColumnRows) Columns() []string {
+	return cr.names
+}
+
+// FetchColumns returns the next batch of up to n rows, or every remaining
+// row when n <= 0. It returns io.EOF once the result is exhausted.
+func (cr *ColumnRows) FetchColumns(n int) (*ColumnBatch, error) {
+	if cr.r == nil {
+		return nil, errors.New("decentdb: column rows are closed")
+	}
+	return cr.r.fetchColumns(cr.names, n)
+}
+
+// Close releases the result and its statement.
+func (cr *ColumnRows) Close() error {
+	if cr.r == nil {
+		return nil
+	}
+	r := cr.r
+	cr.r = nil
+	return r.Close()
+}
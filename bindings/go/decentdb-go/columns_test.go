@@ -0,0 +1,111 @@
+package decentdb
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestColumnBuilderPadsNullsAndWidens(t *testing.T) {
+	var c Column
+	c.appendNull()
+	c.appendInt64(7)
+	c.appendNull()
+	if c.Kind != ColumnInt64 || !reflect.DeepEqual(c.Int64, []int64{0, 7, 0}) {
+		t.Fatalf("unexpected int column: %+v", c)
+	}
+	if !reflect.DeepEqual(c.Nulls, []bool{true, false, true}) || !c.IsNull(0) || c.Value(1) != int64(7) {
+		t.Fatalf("unexpected null mask: %+v", c)
+	}
+
+	c.appendText("x")
+	if c.Kind != ColumnAny || c.Int64 != nil {
+		t.Fatalf("a mixed column should be boxed: %+v", c)
+	}
+	if want := []any{nil, int64(7), nil, "x"}; !reflect.DeepEqual(c.Values, want) {
+		t.Fatalf("boxed values = %v, want %v", c.Values, want)
+	}
+
+	var dense Column
+	dense.appendFloat64(1.5)
+	dense.appendFloat64(2.5)
+	if dense.Nulls != nil || dense.IsNull(1) {
+		t.Fatalf("a column without NULLs should have no mask: %+v", dense)
+	}
+
+	var empty Column
+	empty.appendNull()
+	if empty.Kind != ColumnNull || !empty.IsNull(0) || empty.Value(0) != nil {
+		t.Fatalf("unexpected all-NULL column: %+v", empty)
+	}
+}
+
+func TestOpenDirect_QueryColumns(t *testing.T) {
+	db, err := OpenDirect(filepath.Join(t.TempDir(), "columns.ddb"))
+	if err != nil {
+		t.Fatalf("OpenDirect failed: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec("CREATE TABLE m (id INT PRIMARY KEY, v FLOAT, label TEXT, ok BOOL)"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		var label any
+		if i != 3 {
+			label = "row"
+		}
+		if _, err := db.Exec("INSERT INTO m VALUES ($1, $2, $3, $4)", int64(i), float64(i)/2, label, i%2 == 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cr, err := db.QueryColumns(context.Background(), "SELECT id, v, label, ok FROM m WHERE id >= $1 ORDER BY id", int64(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cr.Close()
+	if got := cr.Columns(); !reflect.DeepEqual(got, []string{"id", "v", "label", "ok"}) {
+		t.Fatalf("Columns() = %q", got)
+	}
+
+	batch, err := cr.FetchColumns(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if batch.Rows != 3 {
+		t.Fatalf("first batch has %d rows, want 3", batch.Rows)
+	}
+	if id := batch.Column("id"); id.Kind != ColumnInt64 || !reflect.DeepEqual(id.Int64, []int64{1, 2, 3}) {
+		t.Fatalf("unexpected id column: %+v", id)
+	}
+	if v := batch.Column("v"); v.Kind != ColumnFloat64 || !reflect.DeepEqual(v.Float64, []float64{0.5, 1, 1.5}) {
+		t.Fatalf("unexpected v column: %+v", v)
+	}
+	if label := batch.Column("label"); label.Kind != ColumnText || !reflect.DeepEqual(label.Nulls, []bool{false, false, true}) {
+		t.Fatalf("unexpected label column: %+v", label)
+	}
+	if ok := batch.Column("ok"); ok.Kind != ColumnBool || !reflect.DeepEqual(ok.Bool, []bool{false, true, false}) {
+		t.Fatalf("unexpected ok column: %+v", ok)
+	}
+
+	batch, err = cr.FetchColumns(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if batch.Rows != 1 || batch.Columns[0].Int64[0] != 4 {
+		t.Fatalf("unexpected final batch: %+v", batch)
+	}
+	if _, err := cr.FetchColumns(10); !errors.Is(err, io.EOF) {
+		t.Fatalf("exhausted result returned %v, want io.EOF", err)
+	}
+	if err := cr.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cr.FetchColumns(1); err == nil {
+		t.Fatal("fetching from closed column rows should fail")
+	}
+}
@@ -0,0 +1,76 @@
+// Package compat lets SQL written for SQLite or MySQL call functions and
+// operators DecentDB does not provide under those names. It rewrites each
+// into the equivalent DecentDB expression before the statement reaches the
+// engine:
+//
+//	connector, err := decentdb.NewConnector(dsn, compat.Option())
+//	db := sql.OpenDB(connector)
+//	db.QueryRow("SELECT IFNULL(nickname, name), DATE_FORMAT(created_at, '%Y-%m') FROM users")
+//
+// The shims are:
+//
+//	IFNULL(a, b), NVL(a, b)          COALESCE(a, b)
+//	IF(cond, a, b)                   IIF(cond, a, b)
+//	UCASE(s), LCASE(s)               UPPER(s), LOWER(s)
+//	MID(s, pos[, len])               SUBSTR(s, pos[, len])
+//	CHAR_LENGTH(s), LEN(s)           LENGTH(s)
+//	LOCATE(sub, s)                   INSTR(s, sub)
+//	CURDATE(), CURTIME(), SYSDATE()  CURRENT_DATE, CURRENT_TIME, NOW()
+//	FROM_UNIXTIME(n)                 TO_TIMESTAMP(n)
+//	UNIX_TIMESTAMP([t]), UNIXEPOCH([t])
+//	                                 CAST(STRFTIME('%s', t) AS INT64)
+//	DATEDIFF(a, b)                   DATE_DIFF('day', b, a)
+//	DATEDIFF(part, start, end)       DATE_DIFF('part', start, end)
+//	DATE_FORMAT(t, 'fmt')            STRFTIME('fmt', t), with MySQL
+//	                                 specifiers translated
+//	GROUP_CONCAT(x [ORDER BY o] SEPARATOR s)
+//	                                 GROUP_CONCAT(x, s [ORDER BY o])
+//	s REGEXP p, s RLIKE p            s ~ p
+//	s NOT REGEXP p, s NOT RLIKE p    s !~ p
+//	REGEXP(p, s)                     (s ~ p)
+//	REGEXP_LIKE(s, p[, 'i'])         (s ~ p), or (s ~* p) with 'i'
+//
+// Patterns use the engine's regular expression syntax, which like Go's
+// regexp package follows RE2: no backreferences or lookaround. Matching is
+// case-sensitive, as in SQLite; prefix a pattern with (?i), or use
+// REGEXP_LIKE with 'i', to ignore case as MySQL does by default. The engine
+// caches compiled patterns, so a pattern applied to every row of a scan is
+// compiled once.
+//
+// STRFTIME, GROUP_CONCAT without SEPARATOR, INSTR, SUBSTR, and the other
+// SQLite functions DecentDB already implements pass through unchanged. A
+// call with an argument count the shim does not know, or a DATE_FORMAT whose
+// format is not a string literal, is left as written, so the engine reports
+// it. Schema-qualified calls such as app.ifnull(x) are never rewritten.
+package compat
+
+import (
+	"context"
+	"database/sql/driver"
+
+	"github.com/sphildreth/decentdb-go"
+)
+
+// Option returns a connector option that applies Rewrite to every statement
+// the connector's connections prepare or run.
+func Option() decentdb.ConnectorOption {
+	return decentdb.WithInterceptors(Interceptor{})
+}
+
+// Interceptor applies Rewrite to statements. Use Option unless it must run
+// at a particular place in a chain of interceptors.
+type Interceptor struct {
+	decentdb.NoopInterceptor
+}
+
+func (Interceptor) InterceptPrepare(ctx context.Context, query string, next decentdb.PrepareFunc) (driver.Stmt, error) {
+	return next(ctx, Rewrite(query))
+}
+
+func (Interceptor) InterceptExec(ctx context.Context, query string, args []driver.NamedValue, next decentdb.ExecFunc) (driver.Result, error) {
+	return next(ctx, Rewrite(query), args)
+}
+
+func (Interceptor) InterceptQuery(ctx context.Context, query string, args []driver.NamedValue, next decentdb.QueryFunc) (driver.Rows, error) {
+	return next(ctx, Rewrite(query), args)
+}
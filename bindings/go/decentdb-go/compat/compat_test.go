@@ -0,0 +1,86 @@
+package compat
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/sphildreth/decentdb-go"
+	"github.com/sphildreth/decentdb-go/decentdbtest"
+)
+
+func TestRewrite(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"SELECT IFNULL(a, 'x') FROM t", "SELECT COALESCE(a, 'x') FROM t"},
+		{"SELECT nvl(ifnull(a, b), c)", "SELECT COALESCE(COALESCE(a, b), c)"},
+		{"SELECT IF(qty > 0, 'in', 'out'), UCASE(name), lcase(name)", "SELECT IIF(qty > 0, 'in', 'out'), UPPER(name), LOWER(name)"},
+		{"SELECT MID(s, 2, 3), MID(s, 2), CHAR_LENGTH(s), LEN(s)", "SELECT SUBSTR(s, 2, 3), SUBSTR(s, 2), LENGTH(s), LENGTH(s)"},
+		{"SELECT LOCATE('b', name)", "SELECT INSTR(name, 'b')"},
+		{"SELECT CURDATE(), CURTIME(), SYSDATE()", "SELECT CURRENT_DATE, CURRENT_TIME, NOW()"},
+		{"SELECT FROM_UNIXTIME(ts), UNIX_TIMESTAMP(), unixepoch(created)", "SELECT TO_TIMESTAMP(ts), CAST(STRFTIME('%s', 'now') AS INT64), CAST(STRFTIME('%s', created) AS INT64)"},
+		{"SELECT DATEDIFF(shipped, ordered), DATEDIFF(hour, a, b)", "SELECT DATE_DIFF('day', ordered, shipped), DATE_DIFF('hour', a, b)"},
+		{"SELECT DATE_FORMAT(at, '%Y-%m-%d %H:%i:%s %% %D''s')", "SELECT STRFTIME('%Y-%m-%d %H:%M:%S %% D''s', at)"},
+		{"SELECT GROUP_CONCAT(name ORDER BY name DESC SEPARATOR '; ')", "SELECT GROUP_CONCAT(name, '; ' ORDER BY name DESC)"},
+		{"SELECT group_concat(DISTINCT tag separator ',')", "SELECT GROUP_CONCAT(DISTINCT tag, ',')"},
+		{"SELECT * FROM t WHERE name REGEXP '^a' AND code NOT RLIKE '[0-9]$'", "SELECT * FROM t WHERE name ~ '^a' AND code !~ '[0-9]$'"},
+		{"SELECT NOT name regexp 'x', regexp('^a', name), REGEXP_LIKE(name, 'a', 'i')", "SELECT NOT name ~ 'x', (name ~ '^a'), (name ~* 'a')"},
+
+		// Left as written.
+		{"SELECT GROUP_CONCAT(name), STRFTIME('%Y', at), INSTR(a, b)", "SELECT GROUP_CONCAT(name), STRFTIME('%Y', at), INSTR(a, b)"},
+		{"SELECT IFNULL(a), DATE_FORMAT(at, fmt), app.ifnull(a, b)", "SELECT IFNULL(a), DATE_FORMAT(at, fmt), app.ifnull(a, b)"},
+		{"SELECT 'IFNULL(a, b)', \"ifnull\"(a, b) -- ifnull(a, b)\n", "SELECT 'IFNULL(a, b)', \"ifnull\"(a, b) -- ifnull(a, b)\n"},
+		{"INSERT INTO len (a, b) VALUES (1, 2)", "INSERT INTO len (a, b) VALUES (1, 2)"},
+		{"SELECT t.regexp FROM t WHERE note = 'a REGEXP b'", "SELECT t.regexp FROM t WHERE note = 'a REGEXP b'"},
+		{"CREATE TABLE IF NOT EXISTS t (id INT64)", "CREATE TABLE IF NOT EXISTS t (id INT64)"},
+	}
+	for _, tt := range tests {
+		if got := Rewrite(tt.in); got != tt.want {
+			t.Errorf("Rewrite(%q)\n got %q\nwant %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestOption(t *testing.T) {
+	connector, err := decentdb.NewConnector(decentdbtest.New(t).DSN, Option())
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := sql.OpenDB(connector)
+	defer db.Close()
+	if _, err := db.Exec("CREATE TABLE people (id INT64 PRIMARY KEY, name TEXT NOT NULL, nickname TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("INSERT INTO people VALUES (1, 'Ada', NULL), (2, 'Grace', 'Amazing')"); err != nil {
+		t.Fatal(err)
+	}
+
+	var names, first, formatted string
+	var epoch, days int64
+	err = db.QueryRow(`SELECT
+		GROUP_CONCAT(IFNULL(nickname, name) ORDER BY id SEPARATOR '|'),
+		MIN(UCASE(MID(name, 1, 3))),
+		DATE_FORMAT('2024-03-05 14:07:09', '%Y/%m/%d %H:%i'),
+		UNIX_TIMESTAMP('2024-01-01 00:00:00'),
+		DATEDIFF('2024-03-15', '2024-03-10')
+		FROM people WHERE LOCATE('a', name) > $1`, 0).Scan(&names, &first, &formatted, &epoch, &days)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names != "Ada|Amazing" || first != "ADA" || formatted != "2024/03/05 14:07" || epoch != 1704067200 || days != 5 {
+		t.Fatalf("got %q, %q, %q, %d, %d", names, first, formatted, epoch, days)
+	}
+
+	var matched int
+	if err := db.QueryRow("SELECT COUNT(*) FROM people WHERE name REGEXP $1 AND nickname NOT REGEXP 'q'", "^[A-G]").Scan(&matched); err != nil || matched != 1 {
+		t.Fatalf("REGEXP matched %d, %v", matched, err)
+	}
+
+	stmt, err := db.Prepare("SELECT IF(id = $1, 'yes', 'no') FROM people ORDER BY id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+	var answer string
+	if err := stmt.QueryRow(int64(2)).Scan(&answer); err != nil || answer != "no" {
+		t.Fatalf("prepared IF = %q, %v", answer, err)
+	}
+}
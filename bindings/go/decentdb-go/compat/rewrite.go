@@ -0,0 +1,381 @@
+package compat
+
+import "strings"
+
+// shim returns the DecentDB expression for a call with the given, already
+// rewritten, arguments, or false to leave the call as written.
+type shim func(args []string) (string, bool)
+
+var shims = map[string]shim{
+	"ifnull":           call("COALESCE", 2),
+	"nvl":              call("COALESCE", 2),
+	"if":               call("IIF", 3),
+	"ucase":            call("UPPER", 1),
+	"lcase":            call("LOWER", 1),
+	"mid":              call("SUBSTR", 2, 3),
+	"char_length":      call("LENGTH", 1),
+	"character_length": call("LENGTH", 1),
+	"len":              call("LENGTH", 1),
+	"from_unixtime":    call("TO_TIMESTAMP", 1),
+	"curdate":          constant("CURRENT_DATE"),
+	"curtime":          constant("CURRENT_TIME"),
+	"sysdate":          constant("NOW()"),
+	"locate":           locate,
+	"unix_timestamp":   unixTimestamp,
+	"unixepoch":        unixTimestamp,
+	"datediff":         dateDiff,
+	"date_format":      dateFormat,
+	"group_concat":     groupConcat,
+	"regexp":           regexpCall,
+	"regexp_like":      regexpLike,
+}
+
+// tableKeywords precede a name that is a table, not a function, even when
+// a parenthesis follows: INSERT INTO len (a, b).
+var tableKeywords = map[string]bool{
+	"into": true, "table": true, "references": true, "update": true,
+	"from": true, "join": true, "view": true, "exists": true,
+}
+
+// regexpOperators are MySQL's and SQLite's infix pattern-match operators.
+var regexpOperators = map[string]bool{"regexp": true, "rlike": true}
+
+// Rewrite returns query with the package's shimmed function calls and
+// operators replaced by DecentDB expressions. String literals, quoted
+// identifiers, and comments are left untouched.
+func Rewrite(query string) string {
+	var b []byte
+	prev := ""
+	notAt := -1 // where a NOT that may negate a REGEXP starts in b
+	for i := 0; i < len(query); {
+		if end := skipQuoted(query, i); end > i {
+			b = append(b, query[i:end]...)
+			i = end
+			continue
+		}
+		if !isWordStart(query[i]) {
+			if !isSpace(query[i]) {
+				prev, notAt = query[i:i+1], -1
+			}
+			b = append(b, query[i])
+			i++
+			continue
+		}
+		end := i
+		for end < len(query) && isWordChar(query[end]) {
+			end++
+		}
+		word, lower := query[i:end], strings.ToLower(query[i:end])
+		open := end
+		for open < len(query) && isSpace(query[open]) {
+			open++
+		}
+		call := open < len(query) && query[open] == '(' && prev != "." && !tableKeywords[prev]
+		if fn := shims[lower]; fn != nil && call {
+			if close := closingParen(query, open); close > 0 {
+				args := splitArgs(query[open+1 : close])
+				for k := range args {
+					args[k] = Rewrite(args[k])
+				}
+				if out, ok := fn(args); ok {
+					b = append(b, out...)
+					prev, notAt = ")", -1
+					i = close + 1
+					continue
+				}
+			}
+		}
+		if regexpOperators[lower] && !call && prev != "." {
+			if notAt >= 0 {
+				b = append(b[:notAt], "!~"...)
+			} else {
+				b = append(b, '~')
+			}
+			prev, notAt = "~", -1
+			i = end
+			continue
+		}
+		if lower == "not" {
+			notAt = len(b)
+		} else {
+			notAt = -1
+		}
+		b = append(b, word...)
+		prev = lower
+		i = end
+	}
+	return string(b)
+}
+
+// call renames a function that takes one of the given argument counts.
+func call(name string, counts ...int) shim {
+	return func(args []string) (string, bool) {
+		for _, n := range counts {
+			if len(args) == n {
+				return name + "(" + strings.Join(args, ", ") + ")", true
+			}
+		}
+		return "", false
+	}
+}
+
+// constant replaces a call without arguments.
+func constant(expr string) shim {
+	return func(args []string) (string, bool) {
+		return expr, len(args) == 0
+	}
+}
+
+func locate(args []string) (string, bool) {
+	if len(args) != 2 {
+		return "", false
+	}
+	return "INSTR(" + args[1] + ", " + args[0] + ")", true
+}
+
+func unixTimestamp(args []string) (string, bool) {
+	switch len(args) {
+	case 0:
+		return "CAST(STRFTIME('%s', 'now') AS INT64)", true
+	case 1:
+		return "CAST(STRFTIME('%s', " + args[0] + ") AS INT64)", true
+	}
+	return "", false
+}
+
+// regexpCall is SQLite's regexp(pattern, s), which its REGEXP operator
+// calls.
+func regexpCall(args []string) (string, bool) {
+	if len(args) != 2 {
+		return "", false
+	}
+	return "(" + args[1] + " ~ " + args[0] + ")", true
+}
+
+// regexpLike is MySQL's and Oracle's REGEXP_LIKE(s, pattern[, flags]), with
+// flags 'c' (case-sensitive, the default here) or 'i'.
+func regexpLike(args []string) (string, bool) {
+	op := " ~ "
+	switch len(args) {
+	case 2:
+	case 3:
+		switch flags, _ := unquote(args[2]); flags {
+		case "c":
+		case "i":
+			op = " ~* "
+		default:
+			return "", false
+		}
+	default:
+		return "", false
+	}
+	return "(" + args[0] + op + args[1] + ")", true
+}
+
+// dateDiff handles MySQL's DATEDIFF(end, start) in days and SQL Server's
+// DATEDIFF(part, start, end), whose part is a bare word.
+func dateDiff(args []string) (string, bool) {
+	switch len(args) {
+	case 2:
+		return "DATE_DIFF('day', " + args[1] + ", " + args[0] + ")", true
+	case 3:
+		part := args[0]
+		if _, ok := unquote(part); !ok {
+			part = "'" + strings.ToLower(part) + "'"
+		}
+		return "DATE_DIFF(" + part + ", " + args[1] + ", " + args[2] + ")", true
+	}
+	return "", false
+}
+
+func dateFormat(args []string) (string, bool) {
+	if len(args) != 2 {
+		return "", false
+	}
+	format, ok := unquote(args[1])
+	if !ok {
+		return "", false
+	}
+	return "STRFTIME(" + quote(strftimeFormat(format)) + ", " + args[0] + ")", true
+}
+
+// mysqlFormats maps MySQL DATE_FORMAT specifiers to STRFTIME ones.
+var mysqlFormats = map[byte]string{
+	'Y': "%Y", 'y': "%y", 'm': "%m", 'c': "%-m", 'd': "%d", 'e': "%-d",
+	'H': "%H", 'k': "%-H", 'h': "%I", 'I': "%I", 'l': "%-I", 'i': "%M",
+	's': "%S", 'S': "%S", 'f': "%6f", 'p': "%p", 'W': "%A", 'a': "%a",
+	'M': "%B", 'b': "%b", 'j': "%j", 'w': "%w", 'U': "%U", 'u': "%W",
+	'T': "%H:%M:%S", 'r': "%I:%M:%S %p", '%': "%%",
+}
+
+// strftimeFormat translates a MySQL DATE_FORMAT format. As in MySQL, an
+// unknown specifier stands for its letter.
+func strftimeFormat(format string) string {
+	var b strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' || i+1 == len(format) {
+			b.WriteByte(format[i])
+			continue
+		}
+		i++
+		if spec, ok := mysqlFormats[format[i]]; ok {
+			b.WriteString(spec)
+		} else {
+			b.WriteByte(format[i])
+		}
+	}
+	return b.String()
+}
+
+// groupConcat moves MySQL's SEPARATOR clause into a second argument, ahead
+// of any ORDER BY.
+func groupConcat(args []string) (string, bool) {
+	if len(args) != 1 {
+		return "", false
+	}
+	at := keyword(args[0], "separator")
+	if at < 0 {
+		return "", false
+	}
+	expr, sep := strings.TrimSpace(args[0][:at]), strings.TrimSpace(args[0][at+len("separator"):])
+	order := ""
+	if at := keyword(expr, "order"); at >= 0 {
+		expr, order = strings.TrimSpace(expr[:at]), " "+strings.TrimSpace(expr[at:])
+	}
+	return "GROUP_CONCAT(" + expr + ", " + sep + order + ")", true
+}
+
+// keyword returns the index of the first top-level occurrence of the word
+// kw in s, or -1.
+func keyword(s, kw string) int {
+	depth := 0
+	for i := 0; i < len(s); {
+		if end := skipQuoted(s, i); end > i {
+			i = end
+			continue
+		}
+		switch c := s[i]; {
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case isWordStart(c):
+			end := i
+			for end < len(s) && isWordChar(s[end]) {
+				end++
+			}
+			if depth == 0 && strings.EqualFold(s[i:end], kw) {
+				return i
+			}
+			i = end
+			continue
+		}
+		i++
+	}
+	return -1
+}
+
+// splitArgs splits an argument list at its top-level commas.
+func splitArgs(s string) []string {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+	var args []string
+	depth, start := 0, 0
+	for i := 0; i < len(s); {
+		if end := skipQuoted(s, i); end > i {
+			i = end
+			continue
+		}
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				args = append(args, strings.TrimSpace(s[start:i]))
+				start = i + 1
+			}
+		}
+		i++
+	}
+	return append(args, strings.TrimSpace(s[start:]))
+}
+
+// closingParen returns the index of the parenthesis closing s[open], or -1.
+func closingParen(s string, open int) int {
+	depth := 0
+	for i := open; i < len(s); {
+		if end := skipQuoted(s, i); end > i {
+			i = end
+			continue
+		}
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+		i++
+	}
+	return -1
+}
+
+// skipQuoted returns the index just past the string literal, quoted
+// identifier, or comment starting at s[i], or i if none starts there.
+func skipQuoted(s string, i int) int {
+	switch {
+	case s[i] == '\'' || s[i] == '"' || s[i] == '`':
+		q := s[i]
+		for j := i + 1; j < len(s); j++ {
+			if s[j] != q {
+				continue
+			}
+			if j+1 < len(s) && s[j+1] == q {
+				j++
+				continue
+			}
+			return j + 1
+		}
+		return len(s)
+	case strings.HasPrefix(s[i:], "--"):
+		if end := strings.IndexByte(s[i:], '\n'); end >= 0 {
+			return i + end + 1
+		}
+		return len(s)
+	case strings.HasPrefix(s[i:], "/*"):
+		if end := strings.Index(s[i+2:], "*/"); end >= 0 {
+			return i + 2 + end + 2
+		}
+		return len(s)
+	}
+	return i
+}
+
+// unquote returns the text of a single-quoted string literal.
+func unquote(s string) (string, bool) {
+	if len(s) < 2 || s[0] != '\'' || s[len(s)-1] != '\'' || skipQuoted(s, 0) != len(s) {
+		return "", false
+	}
+	return strings.ReplaceAll(s[1:len(s)-1], "''", "'"), true
+}
+
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+func isWordStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isWordChar(c byte) bool {
+	return isWordStart(c) || c >= '0' && c <= '9' || c == '$'
+}
@@ -0,0 +1,88 @@
+package decentdb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func openConflictPair(t *testing.T) (*sql.DB, *sql.DB) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "conflict.ddb")
+	var dbs [2]*sql.DB
+	for i := range dbs {
+		db, err := sql.Open("decentdb", path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+		db.SetMaxOpenConns(1)
+		dbs[i] = db
+	}
+	if _, err := dbs[0].Exec("CREATE TABLE a (id INT64 PRIMARY KEY)"); err != nil {
+		t.Fatal(err)
+	}
+	return dbs[0], dbs[1]
+}
+
+func TestTransactionConflict_ReportsVictimDetail(t *testing.T) {
+	db, other := openConflictPair(t)
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Exec("INSERT INTO a VALUES (1)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.Exec("INSERT INTO a VALUES (2)"); err != nil {
+		t.Fatal(err)
+	}
+	err = tx.Commit()
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("expected ErrConflict, got %v", err)
+	}
+	if !IsRetryable(err) {
+		t.Fatalf("conflict should be retryable: %v", err)
+	}
+	conflict, ok := TransactionConflict(err)
+	if !ok || conflict.Table != "a" || conflict.Page == 0 || !conflict.Retryable {
+		t.Fatalf("TransactionConflict = %+v, %v", conflict, ok)
+	}
+	if _, ok := TransactionConflict(ErrBusy); ok {
+		t.Fatal("TransactionConflict matched an unrelated error")
+	}
+}
+
+func TestWithTx_RetriesTransactionConflict(t *testing.T) {
+	db, other := openConflictPair(t)
+
+	attempts := 0
+	err := WithTx(context.Background(), db, func(tx *sql.Tx) error {
+		attempts++
+		if _, err := tx.Exec("INSERT INTO a VALUES ($1)", 1); err != nil {
+			return err
+		}
+		if attempts == 1 {
+			_, err := other.Exec("INSERT INTO a VALUES (2)")
+			return err
+		}
+		return nil
+	}, &TxOptions{InitialBackoff: time.Millisecond})
+	if err != nil {
+		t.Fatalf("WithTx failed: %v", err)
+	}
+	if attempts != 2 {
+		t.Fatalf("expected 2 attempts, got %d", attempts)
+	}
+	var n int64
+	if err := other.QueryRow("SELECT COUNT(*) FROM a").Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Fatalf("expected both rows, got %d", n)
+	}
+}
@@ -0,0 +1,28 @@
+package decentdb
+
+import "database/sql/driver"
+
+// ConnectorOption configures a connector built by NewConnector.
+type ConnectorOption func(*connector)
+
+// NewConnector returns a connector for dsn, for use with sql.OpenDB. It
+// accepts the same DSNs as sql.Open("decentdb", dsn). For remote
+// decentdb://host:port/dbname DSNs only WithInterceptors applies.
+func NewConnector(dsn string, opts ...ConnectorOption) (driver.Connector, error) {
+	if isRemoteDSN(dsn) {
+		c := &connector{dsn: dsn}
+		for _, opt := range opts {
+			opt(c)
+		}
+		return newRemoteConnector(dsn, c.interceptors)
+	}
+	dc, err := (&Driver{}).OpenConnector(dsn)
+	if err != nil {
+		return nil, err
+	}
+	c := dc.(*connector)
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
@@ -0,0 +1,375 @@
+package decentdb
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// defaultCSVSampleRows is how many rows ImportCSV inspects by default, the
+// same as `decentdb import --create`.
+const defaultCSVSampleRows = 1000
+
+// csvDecimalDigits is the precision of an inferred DECIMAL column: its scaled
+// value is stored as an int64.
+const csvDecimalDigits = 18
+
+// ImportCSVOptions configures ImportCSV.
+type ImportCSVOptions struct {
+	// SampleRows is how many data rows are inspected to infer column types.
+	// Zero means 1000.
+	SampleRows int
+	// Comma is the field delimiter. Zero means ','.
+	Comma rune
+}
+
+// CSVColumn is a column of a table created by ImportCSV.
+type CSVColumn struct {
+	Name string
+	// Type is the SQL type, such as "INT64" or "DECIMAL(18, 2)".
+	Type string
+}
+
+// ImportCSVResult describes what ImportCSV created and loaded.
+type ImportCSVResult struct {
+	Columns []CSVColumn
+	Rows    int64
+}
+
+// csvType is an inferred column type; scale is only set for DECIMAL.
+type csvType struct {
+	kind  string
+	scale int
+}
+
+func (t csvType) sql() string {
+	if t.kind == "DECIMAL" {
+		return fmt.Sprintf("DECIMAL(%d, %d)", csvDecimalDigits, t.scale)
+	}
+	return t.kind
+}
+
+// csvCandidates tracks the types a column's sampled values still allow.
+type csvCandidates struct {
+	boolean, integer, float, timestamp bool
+	decimal                            bool
+	scale                              int // common fractional digits, -1 until one is seen
+	seen                               bool
+}
+
+func newCSVCandidates() csvCandidates {
+	return csvCandidates{boolean: true, integer: true, float: true, timestamp: true, decimal: true, scale: -1}
+}
+
+func (c *csvCandidates) observe(raw string) {
+	c.seen = true
+	_, ok := csvBool(raw)
+	c.boolean = c.boolean && ok
+	_, ok = csvInt(raw)
+	c.integer = c.integer && ok
+	_, ok = csvFloat(raw)
+	c.float = c.float && ok
+	c.timestamp = c.timestamp && csvTimestamp(raw)
+	scale, ok := csvDecimalScale(raw)
+	switch {
+	case !ok:
+		c.decimal = false
+	case scale == 0:
+	case c.scale == -1:
+		c.scale = scale
+	case c.scale != scale:
+		c.decimal = false
+	}
+}
+
+func (c *csvCandidates) resolve() csvType {
+	switch {
+	case !c.seen:
+		return csvType{kind: "TEXT"}
+	case c.boolean:
+		return csvType{kind: "BOOL"}
+	case c.integer:
+		return csvType{kind: "INT64"}
+	case c.decimal && c.scale > 0:
+		return csvType{kind: "DECIMAL", scale: c.scale}
+	case c.float:
+		return csvType{kind: "FLOAT64"}
+	case c.timestamp:
+		return csvType{kind: "TIMESTAMP"}
+	}
+	return csvType{kind: "TEXT"}
+}
+
+// inferCSVColumns picks a name and type per header field from the first
+// sampleRows rows, naming empty headers column_N and suffixing repeats.
+func inferCSVColumns(header []string, rows [][]string, sampleRows int) ([]string, []csvType) {
+	candidates := make([]csvCandidates, len(header))
+	for i := range candidates {
+		candidates[i] = newCSVCandidates()
+	}
+	for n, row := range rows {
+		if n == sampleRows {
+			break
+		}
+		for i, raw := range row {
+			if i < len(candidates) && raw != "" {
+				candidates[i].observe(raw)
+			}
+		}
+	}
+	names := make([]string, len(header))
+	types := make([]csvType, len(header))
+	used := make(map[string]bool, len(header))
+	for i, field := range header {
+		base := field
+		if base == "" {
+			base = "column_" + strconv.Itoa(i+1)
+		}
+		name := base
+		for suffix := 2; used[strings.ToLower(name)]; suffix++ {
+			name = base + "_" + strconv.Itoa(suffix)
+		}
+		used[strings.ToLower(name)] = true
+		names[i] = name
+		types[i] = candidates[i].resolve()
+	}
+	return names, types
+}
+
+// csvRowValues converts one data row to bind values for the inferred types.
+// DECIMAL and TIMESTAMP values bind as text for the engine to convert.
+func csvRowValues(names []string, types []csvType, row []string, rowNumber int) ([]driver.NamedValue, error) {
+	if len(row) > len(types) {
+		return nil, fmt.Errorf("decentdb: ImportCSV: row %d has %d fields but the header has %d", rowNumber, len(row), len(types))
+	}
+	args := make([]driver.NamedValue, len(types))
+	for i, t := range types {
+		args[i] = driver.NamedValue{Ordinal: i + 1}
+		if i >= len(row) || row[i] == "" {
+			continue
+		}
+		raw := row[i]
+		var value any
+		ok := true
+		switch t.kind {
+		case "BOOL":
+			value, ok = csvBool(raw)
+		case "INT64":
+			value, ok = csvInt(raw)
+		case "FLOAT64":
+			value, ok = csvFloat(raw)
+		case "DECIMAL":
+			var scale int
+			scale, ok = csvDecimalScale(raw)
+			ok = ok && scale <= t.scale
+			value = raw
+		case "TIMESTAMP":
+			value, ok = raw, csvTimestamp(raw)
+		default:
+			value = raw
+		}
+		if !ok {
+			return nil, fmt.Errorf("decentdb: ImportCSV: row %d, column %s: %q is not a valid %s; the type was inferred from a sample, raise SampleRows to inspect more rows",
+				rowNumber, names[i], raw, t.sql())
+		}
+		args[i].Value = value
+	}
+	return args, nil
+}
+
+func csvBool(raw string) (bool, bool) {
+	switch {
+	case strings.EqualFold(raw, "true"):
+		return true, true
+	case strings.EqualFold(raw, "false"):
+		return false, true
+	}
+	return false, false
+}
+
+// csvUnsigned strips an optional sign and rejects integer parts with leading
+// zeros, which usually mark identifiers such as postal codes.
+func csvUnsigned(raw string) (string, bool) {
+	digits := strings.TrimLeft(raw, "+-")
+	if len(raw)-len(digits) > 1 {
+		return "", false
+	}
+	integer, _, _ := strings.Cut(digits, ".")
+	if len(integer) > 1 && integer[0] == '0' {
+		return "", false
+	}
+	return digits, true
+}
+
+func csvAllDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func csvInt(raw string) (int64, bool) {
+	digits, ok := csvUnsigned(raw)
+	if !ok || digits == "" || !csvAllDigits(digits) {
+		return 0, false
+	}
+	v, err := strconv.ParseInt(raw, 10, 64)
+	return v, err == nil
+}
+
+func csvFloat(raw string) (float64, bool) {
+	if _, ok := csvUnsigned(raw); !ok {
+		return 0, false
+	}
+	// ParseFloat also takes inf, NaN, and hex floats; those are more likely text.
+	if strings.IndexFunc(raw, func(r rune) bool {
+		return (r < '0' || r > '9') && !strings.ContainsRune("+-.eE", r)
+	}) >= 0 {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(raw, 64)
+	return v, err == nil
+}
+
+// csvDecimalScale returns the fractional digits of a fixed-point literal that
+// fits an inferred DECIMAL column.
+func csvDecimalScale(raw string) (int, bool) {
+	digits, ok := csvUnsigned(raw)
+	if !ok {
+		return 0, false
+	}
+	integer, fraction, _ := strings.Cut(digits, ".")
+	if integer == "" && fraction == "" || !csvAllDigits(integer) || !csvAllDigits(fraction) ||
+		len(integer)+len(fraction) > csvDecimalDigits {
+		return 0, false
+	}
+	return len(fraction), true
+}
+
+// csvTimestampLayouts are the ISO-like forms the engine parses into a
+// TIMESTAMP. time.Parse also accepts fractional seconds after the seconds.
+var csvTimestampLayouts = []string{
+	"2006-01-02",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05Z07:00",
+	"2006-01-02 15:04:05Z07:00",
+}
+
+func csvTimestamp(raw string) bool {
+	for _, layout := range csvTimestampLayouts {
+		if _, err := time.Parse(layout, raw); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// ImportCSV creates table from a CSV file with a header line and loads it.
+// Column types are inferred from the first opts.SampleRows rows, the same way
+// `decentdb import --create` infers them: BOOL, INT64, DECIMAL, FLOAT64, or
+// TIMESTAMP when every non-empty sampled value fits, otherwise TEXT. Empty
+// fields load as NULL. Every row is checked before the table is created, so a
+// value the sample did not anticipate fails the import without side effects;
+// the rows are then inserted in one transaction.
+func (c *conn) ImportCSV(ctx context.Context, table string, r io.Reader, opts ImportCSVOptions) (ImportCSVResult, error) {
+	if c.db == nil {
+		return ImportCSVResult{}, driver.ErrBadConn
+	}
+	if table == "" {
+		return ImportCSVResult{}, errors.New("decentdb: ImportCSV needs a table name")
+	}
+	reader := csv.NewReader(r)
+	if opts.Comma != 0 {
+		reader.Comma = opts.Comma
+	}
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return ImportCSVResult{}, fmt.Errorf("decentdb: ImportCSV: %w", err)
+	}
+	if len(records) == 0 {
+		return ImportCSVResult{}, errors.New("decentdb: ImportCSV: CSV input is empty")
+	}
+	for _, record := range records {
+		for i := range record {
+			record[i] = strings.TrimSpace(record[i])
+		}
+	}
+	sampleRows := opts.SampleRows
+	if sampleRows <= 0 {
+		sampleRows = defaultCSVSampleRows
+	}
+	names, types := inferCSVColumns(records[0], records[1:], sampleRows)
+	rows := make([][]driver.NamedValue, len(records)-1)
+	for i, record := range records[1:] {
+		if rows[i], err = csvRowValues(names, types, record, i+1); err != nil {
+			return ImportCSVResult{}, err
+		}
+	}
+
+	result := ImportCSVResult{Columns: make([]CSVColumn, len(names))}
+	defs := make([]string, len(names))
+	quoted := make([]string, len(names))
+	placeholders := make([]string, len(names))
+	for i, name := range names {
+		result.Columns[i] = CSVColumn{Name: name, Type: types[i].sql()}
+		quoted[i] = quoteIdentifier(name)
+		defs[i] = quoted[i] + " " + types[i].sql()
+		placeholders[i] = "$" + strconv.Itoa(i+1)
+	}
+	create := fmt.Sprintf("CREATE TABLE %s (%s)", quoteRelationName(table), strings.Join(defs, ", "))
+	if _, err := c.ExecContext(ctx, create, nil); err != nil {
+		return ImportCSVResult{}, err
+	}
+	insert := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		quoteRelationName(table), strings.Join(quoted, ", "), strings.Join(placeholders, ", "))
+
+	tx, err := c.BeginTx(ctx, driver.TxOptions{})
+	if err != nil {
+		return ImportCSVResult{}, err
+	}
+	stmt, err := c.PrepareContext(ctx, insert)
+	if err != nil {
+		_ = tx.Rollback()
+		return ImportCSVResult{}, err
+	}
+	exec := stmt.(driver.StmtExecContext)
+	for i, args := range rows {
+		if _, err := exec.ExecContext(ctx, args); err != nil {
+			stmt.Close()
+			_ = tx.Rollback()
+			return ImportCSVResult{}, fmt.Errorf("decentdb: ImportCSV: row %d: %w", i+1, err)
+		}
+	}
+	if err := stmt.Close(); err != nil {
+		_ = tx.Rollback()
+		return ImportCSVResult{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return ImportCSVResult{}, err
+	}
+	result.Rows = int64(len(rows))
+	return result, nil
+}
+
+// ImportCSV creates table with column types inferred from a CSV file and
+// loads the file into it, like `decentdb import --create`:
+//
+//	f, _ := os.Open("sales.csv")
+//	res, err := db.ImportCSV(ctx, "sales", f, decentdb.ImportCSVOptions{})
+func (d *DB) ImportCSV(ctx context.Context, table string, r io.Reader, opts ImportCSVOptions) (ImportCSVResult, error) {
+	if atomic.LoadUint32(&d.closed) != 0 {
+		return ImportCSVResult{}, driver.ErrBadConn
+	}
+	return d.c.ImportCSV(ctx, table, r, opts)
+}
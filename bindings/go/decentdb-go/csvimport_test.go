@@ -0,0 +1,115 @@
+package decentdb
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestInferCSVColumns(t *testing.T) {
+	header := []string{"flag", "n", "price", "ratio", "at", "zip", "", "N", "empty"}
+	rows := [][]string{
+		{"true", "1", "12.50", "0.5", "2024-02-29", "02134", "x", "1", ""},
+		{"FALSE", "-20", "3", "1e3", "2024-03-01 12:30:00.25", "10001", "y", "2", ""},
+		{"", "+3", "-0.75", "", "2024-03-01T12:30:00+02:00", "94105", "z", "3", ""},
+	}
+	names, types := inferCSVColumns(header, rows, 10)
+	wantNames := []string{"flag", "n", "price", "ratio", "at", "zip", "column_7", "N_2", "empty"}
+	if !reflect.DeepEqual(names, wantNames) {
+		t.Fatalf("names = %v, want %v", names, wantNames)
+	}
+	got := make([]string, len(types))
+	for i, typ := range types {
+		got[i] = typ.sql()
+	}
+	want := []string{"BOOL", "INT64", "DECIMAL(18, 2)", "FLOAT64", "TIMESTAMP", "TEXT", "TEXT", "INT64", "TEXT"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("types = %v, want %v", got, want)
+	}
+
+	for _, raw := range []string{"inf", "NaN", "0x10", "2023-02-29", "1.2.3"} {
+		_, types := inferCSVColumns([]string{"c"}, [][]string{{raw}}, 10)
+		if types[0].kind != "TEXT" {
+			t.Errorf("%q inferred as %s, want TEXT", raw, types[0].sql())
+		}
+	}
+}
+
+func TestCSVRowValuesReportsValuesOutsideTheSample(t *testing.T) {
+	rows := [][]string{{"1"}, {"2"}, {"three"}}
+	names, types := inferCSVColumns([]string{"n"}, rows, 2)
+	args, err := csvRowValues(names, types, rows[1], 2)
+	if err != nil || args[0].Value != int64(2) {
+		t.Fatalf("csvRowValues = %v, %v", args, err)
+	}
+	if _, err := csvRowValues(names, types, rows[2], 3); err == nil || !strings.Contains(err.Error(), "row 3, column n") {
+		t.Fatalf("value outside the sample: %v", err)
+	}
+	if _, err := csvRowValues(names, types, []string{"1", "2"}, 4); err == nil {
+		t.Fatal("row wider than the header accepted")
+	}
+}
+
+func TestImportCSV(t *testing.T) {
+	db, err := sql.Open("decentdb", "file:"+filepath.Join(t.TempDir(), "import.ddb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	sqlConn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlConn.Close()
+	importCSV := func(table, input string, opts ImportCSVOptions) (res ImportCSVResult, err error) {
+		err = sqlConn.Raw(func(raw any) error {
+			res, err = raw.(*conn).ImportCSV(ctx, table, strings.NewReader(input), opts)
+			return err
+		})
+		return res, err
+	}
+
+	input := "id,amount,paid,placed_at,zip\n" +
+		"1,12.50,true,2024-01-05 10:00:00,02134\n" +
+		"2,3.00,false,2024-01-06,10001\n" +
+		"3,,TRUE,2024-01-07T08:30:00Z,\"94105\"\n"
+	res, err := importCSV("sales", input, ImportCSVOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantColumns := []CSVColumn{
+		{Name: "id", Type: "INT64"},
+		{Name: "amount", Type: "DECIMAL(18, 2)"},
+		{Name: "paid", Type: "BOOL"},
+		{Name: "placed_at", Type: "TIMESTAMP"},
+		{Name: "zip", Type: "TEXT"},
+	}
+	if res.Rows != 3 || !reflect.DeepEqual(res.Columns, wantColumns) {
+		t.Fatalf("ImportCSV = %+v", res)
+	}
+	var count, paid, amounts int64
+	var zip string
+	if err := sqlConn.QueryRowContext(ctx,
+		"SELECT COUNT(*), SUM(CASE WHEN paid THEN 1 ELSE 0 END), MIN(zip), COUNT(amount) FROM sales",
+	).Scan(&count, &paid, &zip, &amounts); err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 || paid != 2 || zip != "02134" || amounts != 2 {
+		t.Fatalf("summary = %d, %d, %q, %d", count, paid, zip, amounts)
+	}
+
+	if _, err := importCSV("late", "n\n1\n2\nthree\n", ImportCSVOptions{SampleRows: 2}); err == nil {
+		t.Fatal("value outside the sample accepted")
+	}
+	if _, err := sqlConn.ExecContext(ctx, "SELECT * FROM late"); err == nil {
+		t.Fatal("failed import created its table")
+	}
+
+	if res, err := importCSV("semi", "a;b\nx;1\n", ImportCSVOptions{Comma: ';'}); err != nil || len(res.Columns) != 2 {
+		t.Fatalf("ImportCSV with ';' = %+v, %v", res, err)
+	}
+}
@@ -0,0 +1,100 @@
+package decentdb
+
+import (
+	"database/sql"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCTE_RecursiveHierarchiesInOneRoundTrip(t *testing.T) {
+	db, err := sql.Open("decentdb", filepath.Join(t.TempDir(), "cte.ddb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, stmt := range []string{
+		"CREATE TABLE categories (id INT64 PRIMARY KEY, parent_id INT64, name TEXT NOT NULL)",
+		`INSERT INTO categories VALUES
+			(1, NULL, 'root'), (2, 1, 'books'), (3, 2, 'fiction'), (4, 2, 'poetry'),
+			(5, 1, 'music'), (6, 5, 'vinyl'), (7, 3, 'crime')`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+
+	// Walk down from a parameterized root, carrying the depth and path.
+	rows, err := db.Query(`WITH RECURSIVE tree(id, depth, path) AS (
+			SELECT id, 0, name FROM categories WHERE id = $1
+			UNION ALL
+			SELECT c.id, t.depth + 1, t.path || '/' || c.name
+			FROM categories c JOIN tree t ON c.parent_id = t.id
+		)
+		SELECT id, depth, path FROM tree ORDER BY path`, int64(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	type node struct {
+		id, depth int64
+		path      string
+	}
+	var got []node
+	for rows.Next() {
+		var n node
+		if err := rows.Scan(&n.id, &n.depth, &n.path); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, n)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	want := []node{
+		{2, 0, "books"},
+		{3, 1, "books/fiction"},
+		{7, 2, "books/fiction/crime"},
+		{4, 1, "books/poetry"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("subtree = %+v, want %+v", got, want)
+	}
+
+	// Walk up to the root as a prepared statement.
+	stmt, err := db.Prepare(`WITH RECURSIVE up(id, parent_id, name, step) AS (
+			SELECT id, parent_id, name, 0 FROM categories WHERE id = $1
+			UNION ALL
+			SELECT c.id, c.parent_id, c.name, up.step + 1 FROM categories c JOIN up ON c.id = up.parent_id
+		)
+		SELECT GROUP_CONCAT(name, ' < ' ORDER BY step) FROM up`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+	var breadcrumb string
+	if err := stmt.QueryRow(int64(7)).Scan(&breadcrumb); err != nil {
+		t.Fatal(err)
+	}
+	if breadcrumb != "crime < fiction < books < root" {
+		t.Fatalf("breadcrumb = %q", breadcrumb)
+	}
+
+	// A leading WITH drives data changes too.
+	res, err := db.Exec(`WITH RECURSIVE doomed(id) AS (
+			SELECT id FROM categories WHERE name = $1
+			UNION
+			SELECT c.id FROM categories c JOIN doomed d ON c.parent_id = d.id
+		)
+		DELETE FROM categories WHERE id IN (SELECT id FROM doomed)`, "music")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n != 2 {
+		t.Fatalf("deleted %d rows, %v", n, err)
+	}
+	var left int
+	if err := db.QueryRow("SELECT COUNT(*) FROM categories").Scan(&left); err != nil || left != 5 {
+		t.Fatalf("%d categories left, %v", left, err)
+	}
+}
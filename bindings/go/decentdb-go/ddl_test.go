@@ -0,0 +1,161 @@
+package decentdb
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestTx_DDLCommitsAndRollsBackWithTransaction(t *testing.T) {
+	db, err := sql.Open("decentdb", filepath.Join(t.TempDir(), "ddl.ddb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, stmt := range []string{
+		"CREATE TABLE accounts (id INT64 PRIMARY KEY, name TEXT)",
+		"INSERT INTO accounts VALUES (1, 'ada')",
+		"CREATE TABLE legacy (id INT64)",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+
+	// A migration that fails partway must leave no trace.
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range []string{
+		"CREATE TABLE audit (id INT64 PRIMARY KEY, note TEXT)",
+		"ALTER TABLE accounts ADD COLUMN email TEXT",
+		"CREATE INDEX accounts_name_idx ON accounts (name)",
+		"DROP TABLE legacy",
+		"UPDATE accounts SET email = 'ada@example.com'",
+	} {
+		if _, err := tx.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("SELECT * FROM audit"); err == nil {
+		t.Fatal("table created in a rolled-back transaction exists")
+	}
+	if _, err := db.Exec("SELECT email FROM accounts"); err == nil {
+		t.Fatal("column added in a rolled-back transaction exists")
+	}
+	if _, err := db.Exec("SELECT * FROM legacy"); err != nil {
+		t.Fatalf("table dropped in a rolled-back transaction is gone: %v", err)
+	}
+
+	tx, err = db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Exec("ALTER TABLE accounts ADD COLUMN email TEXT"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Exec("UPDATE accounts SET email = 'ada@example.com'"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Exec("DROP TABLE legacy"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	var email string
+	if err := db.QueryRow("SELECT email FROM accounts WHERE id = 1").Scan(&email); err != nil || email != "ada@example.com" {
+		t.Fatalf("email = %q, %v", email, err)
+	}
+	if _, err := db.Exec("SELECT * FROM legacy"); err == nil {
+		t.Fatal("table dropped in a committed transaction exists")
+	}
+}
+
+func TestTx_NonTransactionalStatementFailsWithTypedError(t *testing.T) {
+	db, err := sql.Open("decentdb", filepath.Join(t.TempDir(), "analyze.ddb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("CREATE TABLE t (id INT64 PRIMARY KEY)"); err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec("INSERT INTO t VALUES (1)"); err != nil {
+		t.Fatal(err)
+	}
+	_, err = tx.Exec("ANALYZE t")
+	if !errors.Is(err, ErrDDLNotTransactional) {
+		t.Fatalf("ANALYZE in a transaction: %v, want ErrDDLNotTransactional", err)
+	}
+	var dbErr *DecentDBError
+	if !errors.As(err, &dbErr) || dbErr.SQLState != "25001" || dbErr.Retryable {
+		t.Fatalf("diagnostic = %+v", dbErr)
+	}
+	// The transaction survives the refusal and still commits its work.
+	if _, err := tx.Exec("INSERT INTO t VALUES (2)"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	if err := db.QueryRow("SELECT COUNT(*) FROM t").Scan(&n); err != nil || n != 2 {
+		t.Fatalf("count = %d, %v", n, err)
+	}
+	if _, err := db.Exec("ANALYZE t"); err != nil {
+		t.Fatalf("ANALYZE outside a transaction: %v", err)
+	}
+}
+
+func TestExec_CreateTableAsAndInsertSelectReportRowsAffected(t *testing.T) {
+	db, err := OpenDirect(filepath.Join(t.TempDir(), "ctas.ddb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, stmt := range []string{
+		"CREATE TABLE events (id INT64 PRIMARY KEY, kind TEXT, amount DECIMAL(10, 2), at TIMESTAMP)",
+		"INSERT INTO events VALUES (1, 'a', 1.50, '2026-01-01 00:00:00'), (2, 'b', 2.25, '2026-01-02 00:00:00'), (3, 'a', 3.00, '2026-01-03 00:00:00')",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+
+	n, err := db.Exec("CREATE TABLE events_a AS SELECT * FROM events WHERE kind = 'a'")
+	if err != nil || n != 2 {
+		t.Fatalf("CREATE TABLE AS affected %d, %v; want 2", n, err)
+	}
+	n, err = db.Exec("INSERT INTO events_a SELECT * FROM events WHERE kind = $1", "b")
+	if err != nil || n != 1 {
+		t.Fatalf("INSERT ... SELECT affected %d, %v; want 1", n, err)
+	}
+
+	// An empty result still takes its column types from the source table.
+	if _, err := db.Exec("CREATE TABLE events_none AS SELECT * FROM events WHERE id < 0"); err != nil {
+		t.Fatal(err)
+	}
+	columns, err := db.GetTableColumns("events_none")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var types []string
+	for _, column := range columns {
+		types = append(types, column.Type)
+	}
+	if want := []string{"INT64", "TEXT", "DECIMAL", "TIMESTAMP"}; len(types) != len(want) || types[0] != want[0] || types[1] != want[1] || types[2] != want[2] || types[3] != want[3] {
+		t.Fatalf("column types = %v, want %v", types, want)
+	}
+}
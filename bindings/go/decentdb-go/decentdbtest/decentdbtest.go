@@ -0,0 +1,69 @@
+// Package decentdbtest opens DecentDB databases for tests. Each database
+// lives entirely in memory, WAL included, on its own decentdb.MemVFS, so
+// tests run without touching disk and need no temp directory:
+//
+//	func TestOrders(t *testing.T) {
+//		db := decentdbtest.Open(t)
+//		if _, err := db.Exec("CREATE TABLE orders (id INT PRIMARY KEY)"); err != nil {
+//			t.Fatal(err)
+//		}
+//	}
+//
+// The database is closed and its VFS unregistered when the test ends.
+package decentdbtest
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"sync/atomic"
+	"testing"
+
+	"github.com/sphildreth/decentdb-go"
+)
+
+// next numbers VFS registrations so parallel tests never share a name or
+// a database path.
+var next atomic.Uint64
+
+// Database is an in-memory test database.
+type Database struct {
+	// VFS holds the database's files.
+	VFS *decentdb.MemVFS
+	// DSN opens the database; append further options with "&".
+	DSN string
+}
+
+// New registers a fresh MemVFS and returns a DSN for a database on it.
+// Use it to open extra connections or pass driver options; Open covers the
+// common case.
+func New(tb testing.TB) *Database {
+	tb.Helper()
+	id := next.Add(1)
+	name := fmt.Sprintf("decentdbtest-%d", id)
+	vfs := decentdb.NewMemVFS()
+	if err := decentdb.RegisterVFS(name, vfs); err != nil {
+		tb.Fatalf("decentdbtest: register VFS: %v", err)
+	}
+	tb.Cleanup(func() { _ = decentdb.UnregisterVFS(name) })
+	path := fmt.Sprintf("/decentdbtest/%d/%s.ddb", id, url.PathEscape(tb.Name()))
+	return &Database{
+		VFS: vfs,
+		DSN: (&url.URL{Scheme: "file", Path: path, RawQuery: "vfs=" + name}).String(),
+	}
+}
+
+// Open returns a *sql.DB on a new in-memory database, closed when the test
+// ends.
+func Open(tb testing.TB) *sql.DB {
+	tb.Helper()
+	db, err := sql.Open("decentdb", New(tb).DSN)
+	if err != nil {
+		tb.Fatalf("decentdbtest: open: %v", err)
+	}
+	tb.Cleanup(func() { _ = db.Close() })
+	if err := db.Ping(); err != nil {
+		tb.Fatalf("decentdbtest: open: %v", err)
+	}
+	return db
+}
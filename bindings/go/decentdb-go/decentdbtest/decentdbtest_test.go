@@ -0,0 +1,46 @@
+package decentdbtest
+
+import (
+	"database/sql"
+	"testing"
+)
+
+func TestOpen_DatabaseLivesInMemory(t *testing.T) {
+	tdb := New(t)
+	db, err := sql.Open("decentdb", tdb.DSN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("CREATE TABLE t (id INT PRIMARY KEY)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("INSERT INTO t VALUES (1), (2), (3)"); err != nil {
+		t.Fatal(err)
+	}
+	if len(tdb.VFS.Names()) == 0 {
+		t.Fatal("expected the database files on the MemVFS")
+	}
+
+	other, err := sql.Open("decentdb", tdb.DSN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	var count int
+	if err := other.QueryRow("SELECT COUNT(*) FROM t").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Fatalf("count = %d, want 3", count)
+	}
+}
+
+func TestOpen_IsolatesTests(t *testing.T) {
+	for i := 0; i < 2; i++ {
+		db := Open(t)
+		if _, err := db.Exec("CREATE TABLE t (id INT PRIMARY KEY)"); err != nil {
+			t.Fatalf("database %d: %v", i, err)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package decentdb
+
+/*
+#include "decentdb.h"
+*/
+import "C"
+import (
+	"database/sql/driver"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unsafe"
+)
+
+// declaredType returns the declared type of result column index, or "" when
+// the engine cannot describe it. The statement's query contract is loaded on
+// first use and cached on the statement.
+func (r *rows) declaredType(index int) string {
+	if !r.declTypesLoaded {
+		r.declTypesLoaded = true
+		if info, err := r.s.StmtInfo(); err == nil {
+			r.declTypes = make([]string, len(info.Columns))
+			for i, col := range info.Columns {
+				r.declTypes[i] = strings.ToUpper(col.TypeName)
+			}
+		}
+	}
+	if index < 0 || index >= len(r.declTypes) {
+		return ""
+	}
+	return r.declTypes[index]
+}
+
+// columnValue returns result column index of the current row, read from
+// its row view v and decoded by the column's declared type unless the
+// connection was opened with raw values. Next and the Go 1.27 ScanColumn
+// path both go through it, so every toolchain sees the same values.
+func (r *rows) columnValue(index int, v C.ddb_value_view_t) driver.Value {
+	if r.s.c.rawValues {
+		return viewToDriverValue(v)
+	}
+	if v.tag == C.DDB_VALUE_UUID {
+		var u UUID
+		copy(u[:], unsafe.Slice((*byte)(unsafe.Pointer(&v.uuid_bytes[0])), len(u)))
+		return u
+	}
+	value := viewToDriverValue(v)
+	switch value.(type) {
+	case int64, string, []byte:
+		// Only raw kinds can carry a value of another declared type, so
+		// the statement is described only when one turns up.
+		return decodeDeclared(r.declaredType(index), value)
+	}
+	return value
+}
+
+// ColumnTypeScanType returns the Go type of the values Next produces for
+// result column index, or the empty interface type when the column's
+// declared type is unknown or has no single Go type.
+func (r *rows) ColumnTypeScanType(index int) reflect.Type {
+	switch r.declaredType(index) {
+	case "INT64":
+		return reflect.TypeFor[int64]()
+	case "FLOAT64":
+		return reflect.TypeFor[float64]()
+	case "BOOL":
+		return reflect.TypeFor[bool]()
+	case "TEXT":
+		return reflect.TypeFor[string]()
+	case "BLOB", "GEOMETRY", "GEOGRAPHY":
+		return reflect.TypeFor[[]byte]()
+	case "TIMESTAMP":
+		return reflect.TypeFor[time.Time]()
+	case "DECIMAL", "NUMERIC":
+		return reflect.TypeFor[Decimal]()
+	case "UUID":
+		if r.s.c.rawValues {
+			return reflect.TypeFor[[]byte]()
+		}
+		return reflect.TypeFor[UUID]()
+	}
+	return reflect.TypeFor[any]()
+}
+
+// decodesDeclared reports whether decodeDeclared converts values of
+// declType.
+func decodesDeclared(declType string) bool {
+	switch declType {
+	case "UUID", "TIMESTAMP", "DECIMAL", "NUMERIC":
+		return true
+	}
+	return false
+}
+
+// decodeDeclared converts a raw row value to the Go type matching the
+// column's declared type. Values that do not fit the declared type are
+// returned unchanged.
+func decodeDeclared(declType string, v driver.Value) driver.Value {
+	switch declType {
+	case "UUID":
+		switch raw := v.(type) {
+		case []byte:
+			if len(raw) == 16 {
+				var u UUID
+				copy(u[:], raw)
+				return u
+			}
+		case string:
+			if u, err := ParseUUID(raw); err == nil {
+				return u
+			}
+		}
+	case "TIMESTAMP":
+		switch raw := v.(type) {
+		case int64:
+			return decodeTimestampMicrosValue(raw)
+		case string:
+			if t, err := time.Parse(time.RFC3339Nano, raw); err == nil {
+				return t.UTC()
+			}
+		}
+	case "DECIMAL", "NUMERIC":
+		switch raw := v.(type) {
+		case int64:
+			return Decimal{Unscaled: raw}
+		case string:
+			if d, ok := parseDecimalText(raw); ok {
+				return d
+			}
+		}
+	}
+	return v
+}
+
+// parseDecimalText parses plain decimal text such as "-12.340" into a
+// Decimal, preserving the written scale.
+func parseDecimalText(text string) (Decimal, bool) {
+	text = strings.TrimSpace(text)
+	intPart, fracPart, _ := strings.Cut(text, ".")
+	if strings.ContainsAny(fracPart, "+-") || len(fracPart) > 255 {
+		return Decimal{}, false
+	}
+	unscaled, err := strconv.ParseInt(intPart+fracPart, 10, 64)
+	if err != nil {
+		return Decimal{}, false
+	}
+	return Decimal{Unscaled: unscaled, Scale: len(fracPart)}, true
+}
+
+// WithRawValues keeps the legacy result values: they are returned as the
+// row view's raw kind ([]byte for UUID, for example) instead of being decoded
+// by the column's declared type. The raw_values=true DSN
+// option does the same for sql.Open.
+func WithRawValues() ConnectorOption {
+	return func(c *connector) {
+		c.rawValues = true
+	}
+}
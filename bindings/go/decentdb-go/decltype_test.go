@@ -0,0 +1,145 @@
+package decentdb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDecodeDeclared(t *testing.T) {
+	u := UUID{0x12, 0x3e, 0x45, 0x67, 0xe8, 0x9b, 0x12, 0xd3, 0xa4, 0x56, 0x42, 0x66, 0x14, 0x17, 0x40, 0x00}
+	cases := []struct {
+		declType string
+		in       any
+		want     any
+	}{
+		{"UUID", u[:], u},
+		{"UUID", "123e4567-e89b-12d3-a456-426614174000", u},
+		{"UUID", []byte{1, 2, 3}, []byte{1, 2, 3}},
+		{"TIMESTAMP", int64(1_700_000_000_000_000), time.UnixMicro(1_700_000_000_000_000).UTC()},
+		{"DECIMAL", int64(42), Decimal{Unscaled: 42}},
+		{"DECIMAL", "-12.340", Decimal{Unscaled: -12340, Scale: 3}},
+		{"DECIMAL", "1e5", "1e5"},
+		{"TEXT", []byte("x"), []byte("x")},
+	}
+	for _, tc := range cases {
+		if got := decodeDeclared(tc.declType, tc.in); !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("decodeDeclared(%q, %#v) = %#v, want %#v", tc.declType, tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestUUIDParseAndString(t *testing.T) {
+	const canonical = "123e4567-e89b-12d3-a456-426614174000"
+	for _, input := range []string{canonical, "{123E4567-E89B-12D3-A456-426614174000}", "123e4567e89b12d3a456426614174000"} {
+		u, err := ParseUUID(input)
+		if err != nil {
+			t.Fatalf("ParseUUID(%q): %v", input, err)
+		}
+		if u.String() != canonical {
+			t.Fatalf("ParseUUID(%q).String() = %q", input, u.String())
+		}
+	}
+	if _, err := ParseUUID("not-a-uuid"); err == nil {
+		t.Fatal("expected invalid UUID to be rejected")
+	}
+	var scanned UUID
+	if err := scanned.Scan(canonical); err != nil || scanned.String() != canonical {
+		t.Fatalf("Scan(string) = %v, %v", scanned, err)
+	}
+}
+
+func TestDriver_InterfaceScanUsesDeclaredTypes(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "decltype.ddb")
+	db, err := sql.Open("decentdb", fmt.Sprintf("file:%s", dbPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("CREATE TABLE orders (id UUID PRIMARY KEY, placed_at TIMESTAMP, total DECIMAL(10,2), note TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+	id, err := ParseUUID("123e4567-e89b-12d3-a456-426614174000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	placed := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	if _, err := db.Exec("INSERT INTO orders (id, placed_at, total, note) VALUES ($1, $2, $3, 'rush')", id, placed, Decimal{Unscaled: 1999, Scale: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	var gotID, gotPlaced, gotTotal any
+	if err := db.QueryRow("SELECT id, placed_at, total FROM orders").Scan(&gotID, &gotPlaced, &gotTotal); err != nil {
+		t.Fatal(err)
+	}
+	if gotID != id {
+		t.Fatalf("expected decentdb.UUID %v, got %#v", id, gotID)
+	}
+	if ts, ok := gotPlaced.(time.Time); !ok || !ts.Equal(placed) {
+		t.Fatalf("expected time.Time %v, got %#v", placed, gotPlaced)
+	}
+	if dec, ok := gotTotal.(Decimal); !ok || dec.Unscaled != 1999 || dec.Scale != 2 {
+		t.Fatalf("expected Decimal 19.99, got %#v", gotTotal)
+	}
+
+	var typedID UUID
+	if err := db.QueryRow("SELECT id FROM orders").Scan(&typedID); err != nil || typedID != id {
+		t.Fatalf("typed UUID scan = %v, %v", typedID, err)
+	}
+
+	// Next, which database/sql uses before Go 1.27, decodes the same way, and
+	// the scan types match what it produces.
+	ctx := context.Background()
+	sqlConn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = sqlConn.Raw(func(driverConn any) error {
+		rows, err := driverConn.(driver.QueryerContext).QueryContext(ctx, "SELECT id, placed_at, total, note FROM orders", nil)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		dest := make([]driver.Value, 4)
+		if err := rows.Next(dest); err != nil {
+			return err
+		}
+		scanTypes := rows.(driver.RowsColumnTypeScanType)
+		for i, want := range []reflect.Type{reflect.TypeFor[UUID](), reflect.TypeFor[time.Time](), reflect.TypeFor[Decimal](), reflect.TypeFor[string]()} {
+			if got := reflect.TypeOf(dest[i]); got != want {
+				t.Errorf("Next column %d = %v, want %v", i, got, want)
+			}
+			if got := scanTypes.ColumnTypeScanType(i); got != want {
+				t.Errorf("ColumnTypeScanType(%d) = %v, want %v", i, got, want)
+			}
+		}
+		return nil
+	})
+	sqlConn.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	raw, err := sql.Open("decentdb", fmt.Sprintf("file:%s?raw_values=true", dbPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer raw.Close()
+	var rawID any
+	var idBytes []byte
+	if err := raw.QueryRow("SELECT id, id FROM orders").Scan(&rawID, &idBytes); err != nil {
+		t.Fatal(err)
+	}
+	if b, ok := rawID.([]byte); !ok || len(b) != 16 {
+		t.Fatalf("expected raw 16-byte UUID, got %#v", rawID)
+	}
+	if len(idBytes) != 16 {
+		t.Fatalf("expected 16-byte UUID in []byte, got %v", idBytes)
+	}
+}
@@ -0,0 +1,166 @@
+package decentdb
+
+/*
+#include <stdlib.h>
+#include "decentdb.h"
+*/
+import "C"
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"unsafe"
+)
+
+// DatabaseDiff is the row-level difference between two databases, as
+// returned by DB.Diff. Left is the database Diff was called on and Right
+// the file it was compared with.
+type DatabaseDiff struct {
+	Left          string      `json:"left_ref"`
+	Right         string      `json:"right_ref"`
+	TableCount    int         `json:"table_count"`
+	ChangedTables int         `json:"changed_table_count"`
+	AddedRows     int         `json:"added_row_count"`
+	UpdatedRows   int         `json:"updated_row_count"`
+	DeletedRows   int         `json:"deleted_row_count"`
+	Tables        []TableDiff `json:"tables"`
+}
+
+// TableDiff lists the rows of one table that differ between the databases.
+type TableDiff struct {
+	Table string `json:"table"`
+	// Status is "Unchanged", "Added", "Removed", "Changed", or
+	// "Unsupported" for tables whose rows cannot be compared, such as
+	// tables without a primary key; Message then says why.
+	Status        string    `json:"status"`
+	SchemaChanged bool      `json:"schema_changed"`
+	Added         []RowDiff `json:"added"`
+	Updated       []RowDiff `json:"updated"`
+	Deleted       []RowDiff `json:"deleted"`
+	Message       string    `json:"message,omitempty"`
+}
+
+// RowDiff is one differing row. Values are rendered as SQL literals in
+// column order, such as 42 or 'text'; Before is nil for added rows and
+// After is nil for deleted rows.
+type RowDiff struct {
+	PrimaryKey []string `json:"primary_key"`
+	Before     []string `json:"before"`
+	After      []string `json:"after"`
+}
+
+// Diff compares the rows of every table in the database with those of the
+// database file at otherPath.
+func (d *DB) Diff(otherPath string) (*DatabaseDiff, error) {
+	if atomic.LoadUint32(&d.closed) != 0 {
+		return nil, driver.ErrBadConn
+	}
+	out, err := d.c.diffJSON(otherPath, false)
+	if err != nil {
+		return nil, err
+	}
+	var diff DatabaseDiff
+	if err := json.Unmarshal(out, &diff); err != nil {
+		return nil, fmt.Errorf("decentdb: decode diff report: %w", err)
+	}
+	return &diff, nil
+}
+
+// DiffChangeset returns the difference between the database and the file at
+// otherPath as a changeset. Passing it to ApplyChangeset on this database
+// makes its rows match the other file's, which reconciles a replica that has
+// drifted from its source.
+func (d *DB) DiffChangeset(otherPath string) ([]byte, error) {
+	if atomic.LoadUint32(&d.closed) != 0 {
+		return nil, driver.ErrBadConn
+	}
+	return d.c.diffJSON(otherPath, true)
+}
+
+func (c *conn) diffJSON(otherPath string, changeset bool) ([]byte, error) {
+	if c.db == nil {
+		return nil, driver.ErrBadConn
+	}
+	cPath := C.CString(otherPath)
+	defer C.free(unsafe.Pointer(cPath))
+
+	var cOut *C.char
+	var status C.ddb_status_t
+	if changeset {
+		status = C.ddb_db_diff_changeset_json(c.db, cPath, &cOut)
+	} else {
+		status = C.ddb_db_diff_json(c.db, cPath, &cOut)
+	}
+	if status != C.DDB_OK {
+		return nil, statusError(status, "")
+	}
+	defer freeAPIString(cOut)
+	return []byte(C.GoString(cOut)), nil
+}
+
+// Verification compares the tables of two databases by row count and content
+// checksum, as returned by DB.VerifyAgainst.
+type Verification struct {
+	Tables []TableVerification `json:"tables"`
+}
+
+// Matches reports whether every table exists in both databases with the
+// same rows.
+func (v *Verification) Matches() bool {
+	for _, table := range v.Tables {
+		if !table.Matches {
+			return false
+		}
+	}
+	return true
+}
+
+// TableVerification is the comparison of one table. Left is the database
+// VerifyAgainst was called on and Right the file it was compared with; a
+// side's fields are nil when the table does not exist there. Checksums are
+// hex strings that do not depend on row order.
+type TableVerification struct {
+	Name          string  `json:"name"`
+	LeftRows      *uint64 `json:"left_row_count"`
+	RightRows     *uint64 `json:"right_row_count"`
+	LeftChecksum  *string `json:"left_checksum"`
+	RightChecksum *string `json:"right_checksum"`
+	Matches       bool    `json:"matches"`
+}
+
+// VerifyAgainst compares the row count and a checksum of the contents of
+// every table with those in the database file at otherPath. It reads each
+// table once without pairing up rows, so it is a quick way to confirm that a
+// backup, replica, or migrated copy holds the same data; use Diff to find the
+// rows that differ when it does not.
+func (d *DB) VerifyAgainst(otherPath string) (*Verification, error) {
+	if atomic.LoadUint32(&d.closed) != 0 {
+		return nil, driver.ErrBadConn
+	}
+	out, err := d.c.verifyJSON(otherPath)
+	if err != nil {
+		return nil, err
+	}
+	var verification Verification
+	if err := json.Unmarshal(out, &verification); err != nil {
+		return nil, fmt.Errorf("decentdb: decode verification report: %w", err)
+	}
+	return &verification, nil
+}
+
+func (c *conn) verifyJSON(otherPath string) ([]byte, error) {
+	if c.db == nil {
+		return nil, driver.ErrBadConn
+	}
+	cPath := C.CString(otherPath)
+	defer C.free(unsafe.Pointer(cPath))
+
+	var cOut *C.char
+	status := C.ddb_db_verify_json(c.db, cPath, &cOut)
+	if status != C.DDB_OK {
+		return nil, statusError(status, "")
+	}
+	defer freeAPIString(cOut)
+	return []byte(C.GoString(cOut)), nil
+}
@@ -0,0 +1,122 @@
+package decentdb
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestDiff_ReportAndReconcile(t *testing.T) {
+	dir := t.TempDir()
+	var dbs [2]*DB
+	for i, name := range []string{"replica.ddb", "source.ddb"} {
+		db, err := OpenDirect(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+		if _, err := db.Exec("CREATE TABLE notes (id INT64 PRIMARY KEY, body TEXT)"); err != nil {
+			t.Fatal(err)
+		}
+		dbs[i] = db
+	}
+	replica, source := dbs[0], dbs[1]
+	if _, err := replica.Exec("INSERT INTO notes VALUES (1, 'old'), (2, 'stale')"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := source.Exec("INSERT INTO notes VALUES (1, 'new'), (3, 'fresh')"); err != nil {
+		t.Fatal(err)
+	}
+	sourcePath := filepath.Join(dir, "source.ddb")
+
+	diff, err := replica.Diff(sourcePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff.ChangedTables != 1 || diff.AddedRows != 1 || diff.UpdatedRows != 1 || diff.DeletedRows != 1 {
+		t.Fatalf("diff = %+v", diff)
+	}
+	notes := diff.Tables[0]
+	if notes.Table != "notes" || notes.Status != "Changed" || len(notes.Updated) != 1 {
+		t.Fatalf("table diff = %+v", notes)
+	}
+	if row := notes.Updated[0]; row.PrimaryKey[0] != "1" || row.Before == nil || row.After == nil {
+		t.Fatalf("updated row = %+v", row)
+	}
+
+	changeset, err := replica.DiffChangeset(sourcePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := replica.ApplyChangeset(changeset, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := noteBodies(t, replica); len(got) != 2 || got[1] != "new" || got[3] != "fresh" {
+		t.Fatalf("replica rows after reconcile = %v", got)
+	}
+	if diff, err := replica.Diff(sourcePath); err != nil || diff.ChangedTables != 0 {
+		t.Fatalf("diff after reconcile = %+v, %v", diff, err)
+	}
+}
+
+func TestVerifyAgainst_MatchesBackupAndFlagsDrift(t *testing.T) {
+	dir := t.TempDir()
+	primary, err := OpenDirect(filepath.Join(dir, "primary.ddb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer primary.Close()
+	for _, stmt := range []string{
+		"CREATE TABLE notes (id INT64 PRIMARY KEY, body TEXT)",
+		"INSERT INTO notes VALUES (1, 'a'), (2, 'b')",
+	} {
+		if _, err := primary.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	backupPath := filepath.Join(dir, "backup.ddb")
+	backup, err := OpenDirect(backupPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backup.Close()
+	for _, stmt := range []string{
+		"CREATE TABLE notes (id INT64 PRIMARY KEY, body TEXT)",
+		"INSERT INTO notes VALUES (2, 'b'), (1, 'a')",
+	} {
+		if _, err := backup.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+
+	v, err := primary.VerifyAgainst(backupPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !v.Matches() || len(v.Tables) != 1 {
+		t.Fatalf("verification = %+v", v.Tables)
+	}
+	if notes := v.Tables[0]; notes.Name != "notes" || *notes.LeftRows != 2 || *notes.LeftChecksum != *notes.RightChecksum {
+		t.Fatalf("notes = %+v", notes)
+	}
+
+	if _, err := backup.Exec("DELETE FROM notes WHERE id = 2"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := backup.Exec("CREATE TABLE extra (id INT64)"); err != nil {
+		t.Fatal(err)
+	}
+	v, err = primary.VerifyAgainst(backupPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Matches() || len(v.Tables) != 2 {
+		t.Fatalf("verification = %+v", v.Tables)
+	}
+	extra, notes := v.Tables[0], v.Tables[1]
+	if extra.Name != "extra" || extra.LeftRows != nil || extra.RightRows == nil || extra.Matches {
+		t.Fatalf("extra = %+v", extra)
+	}
+	if notes.Matches || *notes.LeftRows != 2 || *notes.RightRows != 1 {
+		t.Fatalf("notes = %+v", notes)
+	}
+}
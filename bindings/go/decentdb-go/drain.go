@@ -0,0 +1,75 @@
+package decentdb
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+// ErrCloseDeferred is returned by Close when statements or rows derived
+// from the connection are still open after the close_drain_timeout_ms wait.
+// The native handle is not freed under them; the last one to close frees
+// it.
+var ErrCloseDeferred = errors.New("decentdb: close deferred until open statements and rows finish")
+
+// beginStatement counts a new native statement against c. It fails once
+// Close has started, so no statement is prepared on a handle being freed.
+func (c *conn) beginStatement() error {
+	c.lifeMu.Lock()
+	defer c.lifeMu.Unlock()
+	if c.closing {
+		return driver.ErrBadConn
+	}
+	c.liveStmts++
+	return nil
+}
+
+// endStatement uncounts a freed native statement, waking a draining Close
+// and finishing a deferred one when it was the last.
+func (c *conn) endStatement() {
+	c.lifeMu.Lock()
+	c.liveStmts--
+	last := c.liveStmts == 0
+	if last && c.drained != nil {
+		close(c.drained)
+		c.drained = nil
+	}
+	deferred := last && c.closeDeferred
+	c.lifeMu.Unlock()
+	if deferred {
+		err := c.closeHandle()
+		logDebug(context.Background(), "decentdb: deferred close finished", slog.String("path", c.path), errAttr(err))
+	}
+}
+
+// drain marks c closing and waits up to the drain timeout for its open
+// statements to be freed. It reports whether the caller should free the
+// handle now; otherwise the last statement does.
+func (c *conn) drain() (closeNow bool, err error) {
+	c.lifeMu.Lock()
+	if c.closing {
+		c.lifeMu.Unlock()
+		return false, nil
+	}
+	c.closing = true
+	if c.liveStmts > 0 && c.closeDrainTimeout > 0 {
+		drained := make(chan struct{})
+		c.drained = drained
+		c.lifeMu.Unlock()
+		timer := time.NewTimer(c.closeDrainTimeout)
+		select {
+		case <-drained:
+		case <-timer.C:
+		}
+		timer.Stop()
+		c.lifeMu.Lock()
+	}
+	defer c.lifeMu.Unlock()
+	if c.liveStmts > 0 {
+		c.closeDeferred = true
+		return false, ErrCloseDeferred
+	}
+	return true, nil
+}
@@ -0,0 +1,126 @@
+package decentdb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func openDrainDB(t *testing.T) *DB {
+	t.Helper()
+	db, err := OpenDirect(filepath.Join(t.TempDir(), "drain.ddb"))
+	if err != nil {
+		t.Fatalf("OpenDirect failed: %v", err)
+	}
+	for _, stmt := range []string{
+		"CREATE TABLE t (id INT PRIMARY KEY)",
+		"INSERT INTO t SELECT value FROM generate_series(1, 1000)",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+func TestDrain_CloseDefersWhileRowsOpen(t *testing.T) {
+	db := openDrainDB(t)
+	cr, err := db.QueryColumns(context.Background(), "SELECT id FROM t")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.Close(); !errors.Is(err, ErrCloseDeferred) {
+		t.Fatalf("Close with open rows: expected ErrCloseDeferred, got %v", err)
+	}
+	if db.c.db == nil {
+		t.Fatal("native handle freed under open rows")
+	}
+	batch, err := cr.FetchColumns(0)
+	if err != nil {
+		t.Fatalf("rows unusable after deferred close: %v", err)
+	}
+	if batch.Rows != 1000 {
+		t.Fatalf("read %d rows, want 1000", batch.Rows)
+	}
+	if err := cr.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if db.c.db != nil {
+		t.Fatal("native handle not freed by the last rows to close")
+	}
+	if _, err := db.c.prepareStmt(context.Background(), "SELECT 1"); !errors.Is(err, driver.ErrBadConn) {
+		t.Fatalf("prepare after close: expected ErrBadConn, got %v", err)
+	}
+}
+
+func TestDrain_CloseWaitsForInFlightRows(t *testing.T) {
+	db := openDrainDB(t)
+	db.c.closeDrainTimeout = 5 * time.Second
+
+	// Fetches are serialized, as on any one connection; Close runs
+	// concurrently with them.
+	var fetchMu sync.Mutex
+	var wg sync.WaitGroup
+	started := make(chan struct{})
+	errs := make(chan error, 4)
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		cr, err := db.QueryColumns(context.Background(), "SELECT id FROM t")
+		if err != nil {
+			t.Fatal(err)
+		}
+		go func() {
+			defer wg.Done()
+			<-started
+			for {
+				fetchMu.Lock()
+				batch, err := cr.FetchColumns(50)
+				fetchMu.Unlock()
+				if err != nil || batch.Rows == 0 {
+					break
+				}
+				time.Sleep(time.Millisecond)
+			}
+			fetchMu.Lock()
+			errs <- cr.Close()
+			fetchMu.Unlock()
+		}()
+	}
+	close(started)
+	if err := db.Close(); err != nil {
+		t.Fatalf("Close did not drain in-flight rows: %v", err)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if db.c.db != nil {
+		t.Fatal("native handle not freed after draining")
+	}
+}
+
+func TestDrain_DSNOption(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "drain.ddb")
+	db, err := sql.Open("decentdb", fmt.Sprintf("file:%s?close_drain_timeout_ms=250", path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err := db.Ping(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := sql.Open("decentdb", fmt.Sprintf("file:%s?close_drain_timeout_ms=soon", path)); err == nil {
+		t.Fatal("expected an invalid close_drain_timeout_ms to be rejected")
+	}
+}
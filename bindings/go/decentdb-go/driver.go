@@ -17,11 +17,11 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/netip"
-	"net/url"
 	"runtime"
-	"strconv"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 	"unsafe"
@@ -54,103 +54,144 @@ func (d *Driver) Open(dsn string) (driver.Conn, error) {
 }
 
 func (d *Driver) OpenConnector(dsn string) (driver.Connector, error) {
-	return &connector{dsn: dsn}, nil
+	if isRemoteDSN(dsn) {
+		return newRemoteConnector(dsn, nil)
+	}
+	cfg, err := parseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	c := &connector{dsn: dsn}
+	if cfg.debugLeaks {
+		c.leaks = newLeakTracker()
+	}
+	if cfg.pool == poolModeSingleWriter {
+		c.writer = newWriterGate()
+	}
+	if cfg.resultCacheSize > 0 {
+		c.results = NewResultCache(cfg.resultCacheSize)
+	}
+	return c, nil
 }
 
 type connector struct {
 	dsn string
+	// writer is shared by every connection of a pool=singlewriter connector.
+	writer *writerGate
+	// interceptors wrap every connection's Prepare, Exec, Query, and Tx calls.
+	interceptors []Interceptor
+	// rawValues disables declared-type decoding of result values.
+	rawValues bool
+	// results caches read query results across the connector's connections.
+	results *ResultCache
+	// leaks tracks open statements for debug_leaks=true DSNs.
+	leaks *leakTracker
+	// recoveryProgress is called during WAL recovery on open.
+	recoveryProgress func(RecoveryProgress)
+	// retry re-executes transient statement failures, if set.
+	retry *StatementRetry
+	// errorParams attaches bound argument values to statement errors.
+	errorParams bool
+	// busy decides whether to re-run statements that fail with ErrBusy.
+	busy BusyHandler
+	// applicationName attributes the connections' activity to a service.
+	applicationName string
+	// queryLog records the connections' statements, if set.
+	queryLog *QueryLog
+	// defaultCollation is the collation new TEXT columns get without COLLATE.
+	defaultCollation string
+	// tempDir, if set, holds temporary table rows instead of memory.
+	tempDir string
 }
 
 func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
-	// Parse DSN: file:/path/to.ddb?opt=val or :memory:
-	var path string
-	var rawQuery string
-	var options string
-	useWriteQueue := false
-	var queueDefaultTimeoutMs *uint64
-
-	if c.dsn == ":memory:" {
-		path = ":memory:"
-	} else {
-		u, err := url.Parse(c.dsn)
-		if err != nil {
-			return nil, err
-		}
-		path = u.Path
-		if u.Scheme == "file" {
-			// Handle file:/// path
-		} else if u.Scheme == "" && path == "" {
-			path = c.dsn
+	cfg, err := parseDSN(c.dsn)
+	if err != nil {
+		return nil, err
+	}
+	path, options, mode := cfg.path, cfg.options(), cfg.mode
+	if c.defaultCollation != "" {
+		// The engine applies the last occurrence, so the DSN still wins.
+		options = strings.TrimSpace(appendOption("", "default_collation", c.defaultCollation) + " " + options)
+	}
+	if c.tempDir != "" {
+		if strings.ContainsAny(c.tempDir, " \t\r\n,;") {
+			return nil, fmt.Errorf("temp directory %q must not contain spaces, commas, or semicolons", c.tempDir)
 		}
-		rawQuery = u.RawQuery
+		temp := appendOption(appendOption("", "temp_store", "file"), "temp_dir", c.tempDir)
+		options = strings.TrimSpace(temp + " " + options)
+	}
+	rawValues := c.rawValues
+	if cfg.rawValues != nil {
+		rawValues = *cfg.rawValues
+	}
+	errorParams := c.errorParams
+	if cfg.errorParams != nil {
+		errorParams = *cfg.errorParams
+	}
+	applicationName := c.applicationName
+	if cfg.applicationName != nil {
+		applicationName = *cfg.applicationName
+	}
 
-		if rawQuery != "" {
-			query, err := url.ParseQuery(rawQuery)
-			if err != nil {
-				return nil, err
-			}
-			if opt := query.Get("options"); opt != "" {
-				options += opt
-			}
-			if enabledValue, ok := query["write_queue_enabled"]; ok && len(enabledValue) > 0 {
-				enabled, err := strconv.ParseBool(enabledValue[0])
-				if err != nil {
-					return nil, fmt.Errorf("invalid write_queue_enabled value %q: %w", enabledValue[0], err)
-				}
-				if enabled {
-					useWriteQueue = true
-				}
-				options = appendOption(options, "write_queue_enabled", fmt.Sprintf("%v", enabled))
-			}
-			if capacityValue, ok := query["write_queue_capacity"]; ok && len(capacityValue) > 0 {
-				if _, err := strconv.ParseUint(capacityValue[0], 10, 64); err != nil {
-					return nil, fmt.Errorf("invalid write_queue_capacity value %q: %w", capacityValue[0], err)
-				}
-				useWriteQueue = true
-				options = appendOption(options, "write_queue_capacity", capacityValue[0])
-			}
-			if timeoutValue, ok := query["write_queue_default_timeout_ms"]; ok && len(timeoutValue) > 0 {
-				parsed, err := strconv.ParseUint(timeoutValue[0], 10, 64)
-				if err != nil {
-					return nil, fmt.Errorf("invalid write_queue_default_timeout_ms value %q: %w", timeoutValue[0], err)
-				}
-				useWriteQueue = true
-				tmp := parsed
-				queueDefaultTimeoutMs = &tmp
-				options = appendOption(options, "write_queue_default_timeout_ms", timeoutValue[0])
-			}
-			if value, ok := query["write_queue_group_commit"]; ok && len(value) > 0 {
-				commit, err := strconv.ParseBool(value[0])
-				if err != nil {
-					return nil, fmt.Errorf("invalid write_queue_group_commit value %q: %w", value[0], err)
-				}
-				useWriteQueue = true
-				options = appendOption(options, "write_queue_group_commit", fmt.Sprintf("%v", commit))
-			}
-			if maxBatchValue, ok := query["write_queue_max_batch"]; ok && len(maxBatchValue) > 0 {
-				if _, err := strconv.ParseUint(maxBatchValue[0], 10, 64); err != nil {
-					return nil, fmt.Errorf("invalid write_queue_max_batch value %q: %w", maxBatchValue[0], err)
-				}
-				useWriteQueue = true
-				options = appendOption(options, "write_queue_max_batch", maxBatchValue[0])
-			}
-			if value, ok := query["write_queue_max_group_delay_us"]; ok && len(value) > 0 {
-				if _, err := strconv.ParseUint(value[0], 10, 64); err != nil {
-					return nil, fmt.Errorf("invalid write_queue_max_group_delay_us value %q: %w", value[0], err)
-				}
-				useWriteQueue = true
-				options = appendOption(options, "write_queue_max_group_delay_us", value[0])
-			}
+	var results *ResultCache
+	if c.results != nil && path != ":memory:" && path != "" {
+		if err := c.results.bind(path); err != nil {
+			return nil, err
 		}
+		results = c.results
 	}
 
-	// Parse mode before any native call to avoid the open-then-recreate bug
-	mode := ""
-	if rawQuery != "" {
-		if q, err := url.ParseQuery(rawQuery); err == nil {
-			mode = q.Get("mode")
+	if cfg.shareEngine && path != ":memory:" && path != "" {
+		root := func() (*conn, error) { return c.open(ctx, path, options, mode) }
+		session, engine, err := acquireSharedSession(path, options, root)
+		if err != nil {
+			return nil, err
+		}
+		logDebug(ctx, "decentdb: shared engine session opened", slog.String("path", path))
+		session.path = path
+		session.useWriteQueue = cfg.useWriteQueue
+		session.writeQueueDefaultMs = cfg.queueDefaultTimeoutMs
+		session.writer = c.writer
+		session.interceptors = withQueryLog(c.interceptors, c.queryLog)
+		session.retry = c.retry
+		session.rawValues = rawValues
+		session.errorParams = errorParams
+		session.SetBusyHandler(c.busy)
+		session.results = results
+		session.engine = engine
+		session.leaks = c.leaks
+		session.closeDrainTimeout = cfg.closeDrainTimeout
+		if err := session.useApplicationName(applicationName); err != nil {
+			_ = session.Close()
+			return nil, err
 		}
+		registerSession(session)
+		return session, nil
+	}
+	db, err := c.open(ctx, path, options, mode)
+	if err != nil {
+		return nil, err
+	}
+	conn := &conn{db: db.db, useWriteQueue: cfg.useWriteQueue, writer: c.writer, interceptors: withQueryLog(c.interceptors, c.queryLog), retry: c.retry, rawValues: rawValues, errorParams: errorParams, results: results, leaks: c.leaks}
+	if cfg.queueDefaultTimeoutMs != nil {
+		conn.writeQueueDefaultMs = cfg.queueDefaultTimeoutMs
+	}
+	conn.closeDrainTimeout = cfg.closeDrainTimeout
+	conn.path = path
+	conn.SetBusyHandler(c.busy)
+	if err := conn.useApplicationName(applicationName); err != nil {
+		_ = conn.Close()
+		return nil, err
 	}
+	registerSession(conn)
+
+	return conn, nil
+}
+
+// openEngine opens a new engine handle on path with the given open options
+// and DSN mode.
+func openEngine(path, options, mode string) (*conn, error) {
 	cPath := C.CString(path)
 	defer C.free(unsafe.Pointer(cPath))
 
@@ -194,13 +235,7 @@ func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
 	if status != C.DDB_OK || db == nil {
 		return nil, statusError(status, "")
 	}
-
-	conn := &conn{db: db, useWriteQueue: useWriteQueue}
-	if queueDefaultTimeoutMs != nil {
-		conn.writeQueueDefaultMs = queueDefaultTimeoutMs
-	}
-
-	return conn, nil
+	return &conn{db: db}, nil
 }
 
 func (c *connector) Driver() driver.Driver {
@@ -219,6 +254,13 @@ type DecentDBError struct {
 	SQLState       string
 	Retryable      bool
 	Permanent      bool
+	// Fingerprint is the normalized form of SQL, with literals replaced by
+	// ?, and ParamTypes the Go types of the bound arguments. Params holds
+	// the argument values only on connectors opened with WithErrorParams.
+	// They are set for errors raised executing a statement.
+	Fingerprint string
+	ParamTypes  []string
+	Params      []any
 }
 
 type Decimal struct {
@@ -270,6 +312,46 @@ var (
 	ErrQueueFull   = errors.New("decentdb queue is full")
 	ErrQueueClosed = errors.New("decentdb queue is closed")
 	ErrQueueClose  = ErrQueueClosed
+	// ErrLocked reports that another process holds the database's
+	// cross-process coordination lock, or that the lock could not be set up.
+	ErrLocked = errors.New("decentdb database is locked by another process")
+	// ErrCorrupt reports that the database file failed an integrity check,
+	// such as a page whose contents no longer match its recorded checksum.
+	// CorruptPage returns the page number when the engine reported one.
+	ErrCorrupt = errors.New("decentdb database is corrupt")
+	// ErrConflict reports that another writer committed while a transaction
+	// was open, so the transaction was rolled back. TransactionConflict
+	// returns which table and page were contended.
+	ErrConflict = errors.New("decentdb transaction conflict")
+	// ErrQuotaExceeded reports that a commit would have grown the database
+	// past its max_database_size_bytes limit. Nothing was written.
+	ErrQuotaExceeded = errors.New("decentdb database size quota exceeded")
+	// ErrMemoryLimitExceeded reports that a statement would have held more
+	// memory than its statement_memory_limit_bytes limit and was aborted.
+	ErrMemoryLimitExceeded = errors.New("decentdb statement memory limit exceeded")
+	// ErrResultRowLimitExceeded reports that a query would have returned
+	// more rows than its max_result_rows limit and was aborted.
+	ErrResultRowLimitExceeded = errors.New("decentdb result row limit exceeded")
+	// ErrStatementTimeout reports that a statement ran longer than its
+	// max_statement_seconds limit and was aborted. Errors that wrap it also
+	// wrap ErrTimeout.
+	ErrStatementTimeout = errors.New("decentdb statement timeout")
+	// ErrDDLNotTransactional reports that a statement, such as ANALYZE,
+	// cannot run inside an explicit transaction. It was not run and the
+	// transaction is still open. CREATE, ALTER, and DROP are transactional
+	// and never fail this way.
+	ErrDDLNotTransactional = errors.New("decentdb statement is not transactional")
+)
+
+const (
+	subcodeCoordinationLockTimeout        = "coordination.lock_timeout"
+	subcodeCoordinationSidecarUnavailable = "coordination.sidecar_unavailable"
+	subcodeTransactionConflict            = "transaction.conflict"
+	subcodeIOQuotaExceeded                = "io.quota_exceeded"
+	subcodeSQLMemoryLimitExceeded         = "sql.memory_limit_exceeded"
+	subcodeSQLResultRowLimitExceeded      = "sql.result_row_limit_exceeded"
+	subcodeSQLStatementTimeout            = "sql.statement_timeout"
+	subcodeTransactionDDLNotTransactional = "transaction.ddl_not_transactional"
 )
 
 func statusCode(status C.ddb_status_t) int {
@@ -334,6 +416,30 @@ func statusError(status C.ddb_status_t, sql string) error {
 		v.Err = ErrQueueFull
 	case C.DDB_ERR_QUEUE_CLOSED:
 		v.Err = ErrQueueClosed
+	case C.DDB_ERR_CORRUPTION:
+		v.Err = ErrCorrupt
+	}
+	switch v.Subcode {
+	case subcodeCoordinationLockTimeout, subcodeCoordinationSidecarUnavailable:
+		if v.Err != nil {
+			return fmt.Errorf("%w: %w: %w", ErrLocked, v.Err, v)
+		}
+		v.Err = ErrLocked
+	case subcodeTransactionConflict:
+		v.Err = ErrConflict
+	case subcodeIOQuotaExceeded:
+		v.Err = ErrQuotaExceeded
+	case subcodeSQLMemoryLimitExceeded:
+		v.Err = ErrMemoryLimitExceeded
+	case subcodeSQLResultRowLimitExceeded:
+		v.Err = ErrResultRowLimitExceeded
+	case subcodeSQLStatementTimeout:
+		if v.Err != nil {
+			return fmt.Errorf("%w: %w: %w", ErrStatementTimeout, v.Err, v)
+		}
+		v.Err = ErrStatementTimeout
+	case subcodeTransactionDDLNotTransactional:
+		v.Err = ErrDDLNotTransactional
 	}
 	if v.Err != nil {
 		return fmt.Errorf("%w: %w", v.Err, v)
@@ -341,6 +447,53 @@ func statusError(status C.ddb_status_t, sql string) error {
 	return v
 }
 
+// CorruptPage returns the database page named by a corruption error, such
+// as one raised when verify_checksums finds a page whose checksum does not
+// match.
+func CorruptPage(err error) (uint32, bool) {
+	var dbErr *DecentDBError
+	if !errors.As(err, &dbErr) || !errors.Is(err, ErrCorrupt) {
+		return 0, false
+	}
+	details, _ := dbErr.Diagnostic["details"].(map[string]any)
+	page, ok := details["page_id"].(float64)
+	if !ok || page < 1 {
+		return 0, false
+	}
+	return uint32(page), true
+}
+
+// Conflict describes the victim side of a transaction conflict.
+type Conflict struct {
+	// Table is a table both the rolled-back transaction and the winning
+	// commit changed, or "" when they changed different tables.
+	Table string
+	// Page is a database page both commits wrote, or 0 if none was
+	// reported.
+	Page uint32
+	// Retryable reports whether running the whole transaction again is
+	// safe, as WithTx does. The rolled-back transaction applied nothing.
+	Retryable bool
+}
+
+// TransactionConflict returns the details of a conflict error raised by
+// COMMIT when another writer committed first.
+func TransactionConflict(err error) (Conflict, bool) {
+	var dbErr *DecentDBError
+	if !errors.As(err, &dbErr) || !errors.Is(err, ErrConflict) {
+		return Conflict{}, false
+	}
+	conflict := Conflict{
+		Table:     diagnosticString(dbErr.Diagnostic, "relation"),
+		Retryable: dbErr.Retryable && !dbErr.Permanent,
+	}
+	details, _ := dbErr.Diagnostic["details"].(map[string]any)
+	if page, ok := details["page_id"].(float64); ok && page >= 1 {
+		conflict.Page = uint32(page)
+	}
+	return conflict, true
+}
+
 func lastErrorDiagnostic() (string, map[string]any) {
 	var out *C.char
 	if C.ddb_last_error_json(&out) != C.DDB_OK || out == nil {
@@ -388,6 +541,23 @@ func appendOption(options string, key string, value string) string {
 	return options + " " + part
 }
 
+// normalizeProcessCoordination validates a process_coordination DSN value and
+// returns the engine spelling.
+func normalizeProcessCoordination(value string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "auto":
+		return "auto", nil
+	case "required":
+		return "required", nil
+	case "exclusive":
+		return "exclusive", nil
+	case "off", "single_process_unsafe":
+		return "single_process_unsafe", nil
+	default:
+		return "", fmt.Errorf("invalid process_coordination value %q: expected auto, required, exclusive, or off", value)
+	}
+}
+
 func queryHasQueueOpenSupport() bool {
 	return true
 }
@@ -396,13 +566,67 @@ type conn struct {
 	db                  *C.ddb_db_t
 	useWriteQueue       bool
 	writeQueueDefaultMs *uint64
+	writer              *writerGate
+	holdsWriter         bool
+	interceptors        []Interceptor
+	retry               *StatementRetry
+	rawValues           bool
+	errorParams         bool
+	// busy is the handler set by SetBusyHandler or WithBusyHandler.
+	busy atomic.Pointer[BusyHandler]
+	// schema is the search_path schema last set through WithSchema; empty
+	// means the default path.
+	schema string
+	// workers is the worker budget last set through WithMaxParallelWorkers;
+	// workersSet is false while the handle uses its configured default.
+	workers    int
+	workersSet bool
+	// readAhead is the window last set through WithReadAhead; readAheadSet
+	// is false while the handle uses its configured default.
+	readAhead    int
+	readAheadSet bool
+	// memoryLimit is the limit last set through WithStatementMemoryLimit;
+	// memoryLimitSet is false while the handle uses its configured default.
+	memoryLimit    int64
+	memoryLimitSet bool
+	// results is the connector's result cache, if any. sessionState is set
+	// once the connection runs SET or creates temporary objects, after which
+	// its queries bypass the cache.
+	results      *ResultCache
+	sessionState bool
+	// schemaHooks is set once DB.OnSchemaChange registers a callback.
+	schemaHooks atomic.Pointer[schemaHooks]
+	// walHooks is set once DB.OnWALThreshold registers a callback.
+	walHooks atomic.Pointer[walHooks]
+	// engine is the shared engine this connection is a session of, for
+	// shared_engine=true DSNs.
+	engine *sharedEngine
+	// leaks is the connector's statement tracker for debug_leaks=true DSNs.
+	leaks *leakTracker
+	// lifeMu guards the close state. liveStmts counts native statements
+	// not yet freed; Close waits up to closeDrainTimeout for them, then
+	// leaves freeing the handle to the last one.
+	lifeMu            sync.Mutex
+	liveStmts         int
+	closing           bool
+	closeDeferred     bool
+	drained           chan struct{}
+	closeDrainTimeout time.Duration
+	// path is the database path, for logging and ListSessions.
+	path string
+	// activity is what the connection is running, for ListSessions.
+	activity sessionActivity
+	// applicationName is the name set through application_name, if any.
+	applicationName string
 }
 
 // DB provides direct access to DecentDB-specific operations beyond
 // the standard database/sql interface.
 type DB struct {
-	c      *conn
-	closed uint32
+	c       *conn
+	path    string
+	closed  uint32
+	archive walArchiveState
 }
 
 type WriteQueueMetrics struct {
@@ -454,7 +678,8 @@ func OpenDirect(path string) (*DB, error) {
 	if status != C.DDB_OK || db == nil {
 		return nil, statusError(status, "")
 	}
-	wrapper := &DB{c: &conn{db: db}}
+	wrapper := &DB{c: &conn{db: db, path: path}, path: path}
+	registerSession(wrapper.c)
 	runtime.SetFinalizer(wrapper, func(d *DB) {
 		if atomic.LoadUint32(&d.closed) == 1 {
 			return
@@ -502,6 +727,16 @@ func (d *DB) WriteQueueMetrics() (WriteQueueMetrics, error) {
 // Checkpoint flushes the WAL to the main database file.
 func (d *DB) Checkpoint() error { return d.c.Checkpoint() }
 
+// Flush blocks until every commit acknowledged before the call is on stable
+// storage. Under the default synchronous=full it returns at once; after a
+// load run with synchronous=off or normal it is the durability barrier.
+func (d *DB) Flush(ctx context.Context) error {
+	if atomic.LoadUint32(&d.closed) != 0 {
+		return driver.ErrBadConn
+	}
+	return d.c.Flush(ctx)
+}
+
 // SaveAs exports the database to a new on-disk file at destPath.
 func (d *DB) SaveAs(destPath string) error { return d.c.SaveAs(destPath) }
 
@@ -513,6 +748,11 @@ func (d *DB) GetTableColumns(tableName string) ([]ColumnInfo, error) {
 	return d.c.GetTableColumns(tableName)
 }
 
+// GetTableInfo returns table metadata, including comments, for a given table.
+func (d *DB) GetTableInfo(tableName string) (TableInfo, error) {
+	return d.c.GetTableInfo(tableName)
+}
+
 // ListIndexes returns metadata about all indexes.
 func (d *DB) ListIndexes() ([]IndexInfo, error) { return d.c.ListIndexes() }
 
@@ -706,7 +946,7 @@ func (d *DB) QueryOnBranchInt64(branch string, query string, args ...driver.Valu
 
 func (c *conn) CheckNamedValue(nv *driver.NamedValue) error {
 	switch nv.Value.(type) {
-	case Decimal:
+	case Decimal, UUID:
 		return nil
 	}
 	return driver.ErrSkip
@@ -811,6 +1051,11 @@ func convertQueueArgs(args []driver.NamedValue) (*queuedArgSet, error) {
 				out.Values[i].len = C.size_t(len(value))
 				out.Buffers = append(out.Buffers, ptr)
 			}
+		case UUID:
+			out.Values[i].tag = C.DDB_VALUE_UUID
+			for j, b := range value {
+				out.Values[i].uuid_bytes[j] = C.uint8_t(b)
+			}
 		case Decimal:
 			out.Values[i].tag = C.DDB_VALUE_DECIMAL
 			out.Values[i].decimal_scaled = C.int64_t(value.Unscaled)
@@ -909,7 +1154,7 @@ func (c *conn) executeOnBranch(
 	cBranch := C.CString(normalizeBranchName(branch))
 	defer C.free(unsafe.Pointer(cBranch))
 
-	cQuery := C.CString(query)
+	cQuery := C.CString(taggedQuery(ctx, query))
 	defer C.free(unsafe.Pointer(cQuery))
 
 	var result *C.ddb_result_t
@@ -949,25 +1194,173 @@ func (c *conn) Prepare(query string) (driver.Stmt, error) {
 }
 
 func (c *conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if len(c.interceptors) == 0 {
+		return c.prepareDriverStmt(ctx, query)
+	}
+	return chainPrepare(c.interceptors, c.prepareDriverStmt)(ctx, query)
+}
+
+func (c *conn) prepareDriverStmt(ctx context.Context, query string) (driver.Stmt, error) {
+	s, err := c.prepareStmt(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (c *conn) prepareStmt(ctx context.Context, query string) (*stmtStruct, error) {
 	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
 	if hasUnsupportedParamStyle(query) {
 		return nil, fmt.Errorf("unsupported parameter style: use $1..$N only")
 	}
-	cQuery := C.CString(query)
+	c.noteSessionState(query)
+	if err := c.useContextSchema(ctx); err != nil {
+		return nil, err
+	}
+	if c.leaks != nil {
+		c.leaks.sweep(c)
+	}
+	if err := c.beginStatement(); err != nil {
+		return nil, err
+	}
+	cQuery := C.CString(taggedQuery(ctx, query))
 	defer C.free(unsafe.Pointer(cQuery))
 
 	var stmt *C.ddb_stmt_t
+	start := cgoStart()
 	status := C.ddb_db_prepare(c.db, cQuery, &stmt)
+	cgoPrepare.done(start)
 	if status != C.DDB_OK {
+		c.endStatement()
 		return nil, statusError(status, query)
 	}
 
-	return &stmtStruct{c: c, query: query, stmt: stmt}, nil
+	s := &stmtStruct{c: c, query: query, stmt: stmt}
+	s.refs.Store(1)
+	if c.leaks != nil {
+		c.leaks.track(s)
+	}
+	return s, nil
+}
+
+// useContextSchema switches the connection's search_path to the schema set
+// by WithSchema on ctx, or back to the default when ctx has none.
+func (c *conn) useContextSchema(ctx context.Context) error {
+	schema, _ := SchemaFromContext(ctx)
+	if schema == c.schema {
+		return nil
+	}
+	if c.db == nil {
+		return driver.ErrBadConn
+	}
+	if err := c.execSessionSQL(searchPathSQL(schema)); err != nil {
+		return err
+	}
+	c.schema = schema
+	return nil
+}
+
+// useContextWorkers applies the worker budget set by WithMaxParallelWorkers
+// on ctx, or restores the handle's configured budget when ctx has none.
+func (c *conn) useContextWorkers(ctx context.Context) error {
+	workers, ok := MaxParallelWorkersFromContext(ctx)
+	if ok == c.workersSet && (!ok || workers == c.workers) {
+		return nil
+	}
+	if c.db == nil {
+		return driver.ErrBadConn
+	}
+	if err := c.execSessionSQL(parallelWorkersSQL(workers, ok)); err != nil {
+		return err
+	}
+	c.workers, c.workersSet = workers, ok
+	return nil
+}
+
+// useContextReadAhead applies the read-ahead window set by WithReadAhead on
+// ctx, or restores the handle's configured window when ctx has none.
+func (c *conn) useContextReadAhead(ctx context.Context) error {
+	pages, ok := ReadAheadFromContext(ctx)
+	if ok == c.readAheadSet && (!ok || pages == c.readAhead) {
+		return nil
+	}
+	if c.db == nil {
+		return driver.ErrBadConn
+	}
+	if err := c.execSessionSQL(readAheadSQL(pages, ok)); err != nil {
+		return err
+	}
+	c.readAhead, c.readAheadSet = pages, ok
+	return nil
+}
+
+// useContextMemoryLimit applies the statement memory limit set by
+// WithStatementMemoryLimit on ctx, or restores the handle's configured limit
+// when ctx has none.
+func (c *conn) useContextMemoryLimit(ctx context.Context) error {
+	bytes, ok := StatementMemoryLimitFromContext(ctx)
+	if ok == c.memoryLimitSet && (!ok || bytes == c.memoryLimit) {
+		return nil
+	}
+	if c.db == nil {
+		return driver.ErrBadConn
+	}
+	if err := c.execSessionSQL(memoryLimitSQL(bytes, ok)); err != nil {
+		return err
+	}
+	c.memoryLimit, c.memoryLimitSet = bytes, ok
+	return nil
+}
+
+// execSessionSQL runs a statement that only changes session settings.
+func (c *conn) execSessionSQL(query string) error {
+	cQuery := C.CString(query)
+	defer C.free(unsafe.Pointer(cQuery))
+	var result *C.ddb_result_t
+	status := C.ddb_db_execute(c.db, cQuery, nil, 0, &result)
+	if status != C.DDB_OK {
+		return statusError(status, query)
+	}
+	C.ddb_result_free(&result)
+	return nil
+}
+
+// noteSessionState marks the connection as unsafe for the result cache once
+// query changes its session state.
+func (c *conn) noteSessionState(query string) {
+	if c.results != nil && !c.sessionState {
+		c.sessionState = changesSessionState(query)
+	}
 }
 
 func (c *conn) Close() error {
+	if c.results != nil {
+		c.results.release(c)
+	}
+	if c.holdsWriter {
+		c.holdsWriter = false
+		c.writer.release()
+	}
+	if c.leaks != nil {
+		c.leaks.sweep(c)
+	}
+	closeNow, err := c.drain()
+	if !closeNow {
+		if err != nil {
+			logDebug(context.Background(), "decentdb: connection close deferred", slog.String("path", c.path), errAttr(err))
+		}
+		return err
+	}
+	err = c.closeHandle()
+	logDebug(context.Background(), "decentdb: connection closed", slog.String("path", c.path), errAttr(err))
+	return err
+}
+
+// closeHandle frees the native handle once no statement uses it.
+func (c *conn) closeHandle() error {
+	unregisterSession(c)
 	if c.db != nil {
 		dbp := c.db
 		status := C.ddb_db_free(&dbp)
@@ -976,21 +1369,116 @@ func (c *conn) Close() error {
 		}
 		c.db = nil
 	}
+	if c.engine != nil {
+		c.engine.release()
+		c.engine = nil
+	}
 	return nil
 }
 
+// openSession opens a session sharing this connection's page cache, file
+// handles, and WAL.
+func (c *conn) openSession() (*conn, error) {
+	if c.db == nil {
+		return nil, driver.ErrBadConn
+	}
+	var db *C.ddb_db_t
+	status := C.ddb_db_open_session(c.db, &db)
+	if status != C.DDB_OK || db == nil {
+		return nil, statusError(status, "")
+	}
+	return &conn{db: db}, nil
+}
+
+// PageCacheStatsJson returns the page cache statistics of the handle and
+// the sessions sharing its cache as JSON.
+func (c *conn) PageCacheStatsJson() (string, error) {
+	if c.db == nil {
+		return "", driver.ErrBadConn
+	}
+	var ptr *C.char
+	status := C.ddb_db_page_cache_stats_json(c.db, &ptr)
+	if status != C.DDB_OK {
+		return "", statusError(status, "")
+	}
+	defer freeAPIString(ptr)
+	return C.GoString(ptr), nil
+}
+
 // Checkpoint flushes the WAL to the main database file.
 func (c *conn) Checkpoint() error {
 	if c.db == nil {
 		return driver.ErrBadConn
 	}
+	start := time.Now()
 	status := C.ddb_db_checkpoint(c.db)
+	var err error
+	if status != C.DDB_OK {
+		err = statusError(status, "")
+	}
+	logDebug(context.Background(), "decentdb: checkpoint", slog.String("path", c.path), slog.Duration("duration", time.Since(start)), errAttr(err))
+	return err
+}
+
+// Flush blocks until every commit acknowledged on this connection's database
+// is on stable storage.
+func (c *conn) Flush(ctx context.Context) error {
+	if c.db == nil {
+		return driver.ErrBadConn
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	start := time.Now()
+	status := C.ddb_db_flush(c.db)
+	var err error
+	if status != C.DDB_OK {
+		err = statusError(status, "")
+	}
+	logDebug(ctx, "decentdb: flush", slog.String("path", c.path), slog.Duration("duration", time.Since(start)), errAttr(err))
+	return err
+}
+
+// EnableWALArchive makes checkpoints queue the WAL segments they truncate,
+// holding at most capacity undrained segments.
+func (c *conn) EnableWALArchive(capacity int) error {
+	if c.db == nil {
+		return driver.ErrBadConn
+	}
+	status := C.ddb_db_wal_archive_enable(c.db, C.size_t(capacity))
+	if status != C.DDB_OK {
+		return statusError(status, "")
+	}
+	return nil
+}
+
+// DisableWALArchive stops queueing WAL segments and drops undrained ones.
+func (c *conn) DisableWALArchive() error {
+	if c.db == nil {
+		return driver.ErrBadConn
+	}
+	status := C.ddb_db_wal_archive_disable(c.db)
 	if status != C.DDB_OK {
 		return statusError(status, "")
 	}
 	return nil
 }
 
+// NextWALSegmentJson takes the oldest queued WAL segment as JSON, or "null"
+// when none is waiting.
+func (c *conn) NextWALSegmentJson() (string, error) {
+	if c.db == nil {
+		return "", driver.ErrBadConn
+	}
+	var ptr *C.char
+	status := C.ddb_db_wal_archive_next_json(c.db, &ptr)
+	if status != C.DDB_OK {
+		return "", statusError(status, "")
+	}
+	defer freeAPIString(ptr)
+	return C.GoString(ptr), nil
+}
+
 func (c *conn) queueTimeoutFromContext(ctx context.Context) C.uint64_t {
 	if c == nil {
 		return C.uint64_t(writeQueueTimeoutDefault)
@@ -1236,7 +1724,12 @@ func (c *conn) execQueuedNamed(ctx context.Context, query string, args []driver.
 	}
 	defer queueArgs.Free()
 
-	cQuery := C.CString(query)
+	c.noteSessionState(query)
+	if err := c.useContextSchema(ctx); err != nil {
+		return nil, err
+	}
+	change := c.describeSchemaChange(query, func() (*StmtInfo, error) { return c.StmtInfo(query) })
+	cQuery := C.CString(taggedQuery(ctx, query))
 	defer C.free(unsafe.Pointer(cQuery))
 
 	var result *C.ddb_result_t
@@ -1262,6 +1755,8 @@ func (c *conn) execQueuedNamed(ctx context.Context, query string, args []driver.
 	if status != C.DDB_OK {
 		return nil, statusError(status, query)
 	}
+	c.noteSchemaChange(query, change)
+	c.noteWALGrowth()
 	return driver.RowsAffected(affected), nil
 }
 
@@ -1297,105 +1792,363 @@ func (c *conn) SaveAs(destPath string) error {
 	return nil
 }
 
-// ListTables returns the names of all tables in the database.
-func (c *conn) ListTables() ([]string, error) {
+// backupStream reads a streaming backup from the engine. It pins the
+// snapshot the backup was started at until Close.
+type backupStream struct {
+	handle *C.ddb_backup_t
+}
+
+// beginBackup starts a streaming backup of the database as of now.
+func (c *conn) beginBackup() (*backupStream, error) {
 	if c.db == nil {
 		return nil, driver.ErrBadConn
 	}
-	var ptr *C.char
-	status := C.ddb_db_list_tables_json(c.db, &ptr)
-	if status != C.DDB_OK || ptr == nil {
+	var handle *C.ddb_backup_t
+	status := C.ddb_db_backup_begin(c.db, &handle)
+	if status != C.DDB_OK {
 		return nil, statusError(status, "")
 	}
-	defer freeAPIString(ptr)
-	jsonStr := C.GoString(ptr)
-	var tables []string
-	if err := json.Unmarshal([]byte(jsonStr), &tables); err == nil {
-		return tables, nil
-	}
-	var tableObjects []struct {
-		Name string `json:"name"`
+	return &backupStream{handle: handle}, nil
+}
+
+// Read implements io.Reader.
+func (b *backupStream) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
 	}
-	if err := json.Unmarshal([]byte(jsonStr), &tableObjects); err != nil {
-		return nil, fmt.Errorf("failed to parse table list: %w", err)
+	var n C.size_t
+	status := C.ddb_backup_read(b.handle, bytesPtr(p), C.size_t(len(p)), &n)
+	if status != C.DDB_OK {
+		return 0, statusError(status, "")
 	}
-	tables = make([]string, 0, len(tableObjects))
-	for _, entry := range tableObjects {
-		if entry.Name != "" {
-			tables = append(tables, entry.Name)
-		}
+	if n == 0 {
+		return 0, io.EOF
 	}
-	return tables, nil
+	return int(n), nil
 }
 
-// ColumnInfo describes a column in a table.
-type ColumnInfo struct {
-	Name        string `json:"name"`
-	Type        string `json:"type"`
-	NotNull     bool   `json:"not_null"`
+// Close releases the backup snapshot.
+func (b *backupStream) Close() error {
+	if b.handle == nil {
+		return nil
+	}
+	status := C.ddb_backup_free(&b.handle)
+	if status != C.DDB_OK {
+		return statusError(status, "")
+	}
+	return nil
+}
+
+// readCompressed fills raw with the next chunk of the stream and writes it
+// zstd-compressed at level into out. rawN is zero once the stream is
+// complete.
+func (b *backupStream) readCompressed(raw, out []byte, level int) (rawN, outN int, err error) {
+	var nRaw, nOut C.size_t
+	status := C.ddb_backup_read_compressed(b.handle, bytesPtr(raw), C.size_t(len(raw)), C.int32_t(level),
+		bytesPtr(out), C.size_t(len(out)), &nRaw, &nOut)
+	if status != C.DDB_OK {
+		return 0, 0, statusError(status, "")
+	}
+	return int(nRaw), int(nOut), nil
+}
+
+// restoreFile writes a restored database file through the engine, which
+// decompresses envelope chunks and hashes what it writes. Closing it before
+// finish removes the file.
+type restoreFile struct {
+	handle *C.ddb_restore_t
+}
+
+// createRestoreFile creates the database file at path, which must not exist.
+func createRestoreFile(path string) (*restoreFile, error) {
+	cPath := C.CString(path)
+	defer C.free(unsafe.Pointer(cPath))
+	var handle *C.ddb_restore_t
+	status := C.ddb_restore_begin(cPath, &handle)
+	if status != C.DDB_OK {
+		return nil, statusError(status, "")
+	}
+	return &restoreFile{handle: handle}, nil
+}
+
+// write appends database bytes to the file.
+func (f *restoreFile) write(data []byte) error {
+	_, err := f.writeChunk(data, false, 0)
+	return err
+}
+
+// writeCompressed appends one zstd-compressed chunk of at most maxLen
+// bytes and returns its decompressed length. A chunk that does not
+// decompress fails with ErrCorrupt.
+func (f *restoreFile) writeCompressed(data []byte, maxLen int) (int, error) {
+	return f.writeChunk(data, true, maxLen)
+}
+
+func (f *restoreFile) writeChunk(data []byte, compressed bool, maxLen int) (int, error) {
+	var flag C.uint8_t
+	if compressed {
+		flag = 1
+	}
+	var n C.size_t
+	status := C.ddb_restore_write(f.handle, bytesPtr(data), C.size_t(len(data)), flag, C.size_t(maxLen), &n)
+	if status != C.DDB_OK {
+		return 0, statusError(status, "")
+	}
+	return int(n), nil
+}
+
+// finish syncs and closes the file and returns the length and SHA-256 of
+// everything written.
+func (f *restoreFile) finish() (length uint64, digest [32]byte, err error) {
+	var n C.uint64_t
+	status := C.ddb_restore_finish(f.handle, &n, (*C.uint8_t)(unsafe.Pointer(&digest[0])))
+	if status != C.DDB_OK {
+		return 0, digest, statusError(status, "")
+	}
+	return uint64(n), digest, nil
+}
+
+// Close releases the restore, removing the file unless finish succeeded.
+func (f *restoreFile) Close() error {
+	if f.handle == nil {
+		return nil
+	}
+	status := C.ddb_restore_free(&f.handle)
+	if status != C.DDB_OK {
+		return statusError(status, "")
+	}
+	return nil
+}
+
+func bytesPtr(b []byte) *C.uint8_t {
+	if len(b) == 0 {
+		return nil
+	}
+	return (*C.uint8_t)(unsafe.Pointer(&b[0]))
+}
+
+// validateDatabaseFile opens the database at path and closes it again,
+// which checks its header.
+func validateDatabaseFile(path string) error {
+	cPath := C.CString(path)
+	defer C.free(unsafe.Pointer(cPath))
+	var db *C.ddb_db_t
+	status := C.ddb_db_open(cPath, &db)
+	if status != C.DDB_OK {
+		return statusError(status, "")
+	}
+	C.ddb_db_free(&db)
+	return nil
+}
+
+// ListTables returns the names of all tables in the database.
+func (c *conn) ListTables() ([]string, error) {
+	if c.db == nil {
+		return nil, driver.ErrBadConn
+	}
+	var ptr *C.char
+	status := C.ddb_db_list_tables_json(c.db, &ptr)
+	if status != C.DDB_OK || ptr == nil {
+		return nil, statusError(status, "")
+	}
+	defer freeAPIString(ptr)
+	jsonStr := C.GoString(ptr)
+	var tables []string
+	if err := json.Unmarshal([]byte(jsonStr), &tables); err == nil {
+		return tables, nil
+	}
+	var tableObjects []struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal([]byte(jsonStr), &tableObjects); err != nil {
+		return nil, fmt.Errorf("failed to parse table list: %w", err)
+	}
+	tables = make([]string, 0, len(tableObjects))
+	for _, entry := range tableObjects {
+		if entry.Name != "" {
+			tables = append(tables, entry.Name)
+		}
+	}
+	return tables, nil
+}
+
+// ColumnInfo describes a column in a table.
+type ColumnInfo struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	NotNull     bool   `json:"not_null"`
 	Unique      bool   `json:"unique"`
 	PrimaryKey  bool   `json:"primary_key"`
 	RefTable    string `json:"ref_table,omitempty"`
 	RefColumn   string `json:"ref_column,omitempty"`
 	RefOnDelete string `json:"ref_on_delete,omitempty"`
 	RefOnUpdate string `json:"ref_on_update,omitempty"`
+	// Default is the SQL of the column's DEFAULT expression, or empty.
+	Default       string `json:"default,omitempty"`
+	AutoIncrement bool   `json:"auto_increment,omitempty"`
+	// Checks holds the SQL of the column's CHECK constraints.
+	Checks []string `json:"checks,omitempty"`
+	// Generated is the expression of a GENERATED ALWAYS AS column, and
+	// GeneratedStorage is "STORED" or "VIRTUAL". Both are empty for ordinary
+	// columns, which are the only ones an INSERT or UPDATE may set.
+	Generated        string `json:"generated,omitempty"`
+	GeneratedStorage string `json:"generated_storage,omitempty"`
+	// Comment is the text set by COMMENT ON COLUMN, or empty.
+	Comment string `json:"comment,omitempty"`
+}
+
+// TableInfo describes a table and its columns.
+type TableInfo struct {
+	Name      string `json:"name"`
+	Temporary bool   `json:"temporary,omitempty"`
+	// Comment is the text set by COMMENT ON TABLE, or empty.
+	Comment           string       `json:"comment,omitempty"`
+	PrimaryKeyColumns []string     `json:"primary_key_columns,omitempty"`
+	RowCount          int64        `json:"row_count"`
+	Columns           []ColumnInfo `json:"columns"`
+	// Checks holds the SQL of the table-level CHECK constraints; column
+	// checks are on ColumnInfo.Checks. ForeignKeys holds every FOREIGN KEY
+	// constraint, including those declared on a column.
+	Checks      []string         `json:"checks,omitempty"`
+	ForeignKeys []ForeignKeyInfo `json:"foreign_keys,omitempty"`
+}
+
+// ForeignKeyInfo describes a FOREIGN KEY constraint.
+type ForeignKeyInfo struct {
+	Name       string   `json:"name,omitempty"`
+	Columns    []string `json:"columns"`
+	RefTable   string   `json:"ref_table"`
+	RefColumns []string `json:"ref_columns"`
+	OnDelete   string   `json:"on_delete,omitempty"`
+	OnUpdate   string   `json:"on_update,omitempty"`
+}
+
+// describeForeignKey is a foreign key as the engine reports it.
+type describeForeignKey struct {
+	Name              *string  `json:"name"`
+	Columns           []string `json:"columns"`
+	ReferencedTable   string   `json:"referenced_table"`
+	ReferencedColumns []string `json:"referenced_columns"`
+	OnDelete          string   `json:"on_delete"`
+	OnUpdate          string   `json:"on_update"`
+}
+
+func (fk describeForeignKey) info() ForeignKeyInfo {
+	info := ForeignKeyInfo{
+		Columns:    fk.Columns,
+		RefTable:   fk.ReferencedTable,
+		RefColumns: fk.ReferencedColumns,
+		OnDelete:   fk.OnDelete,
+		OnUpdate:   fk.OnUpdate,
+	}
+	if fk.Name != nil {
+		info.Name = *fk.Name
+	}
+	return info
 }
 
 // GetTableColumns returns column metadata for a given table.
 func (c *conn) GetTableColumns(tableName string) ([]ColumnInfo, error) {
+	info, err := c.GetTableInfo(tableName)
+	if err != nil {
+		return nil, err
+	}
+	return info.Columns, nil
+}
+
+// GetTableInfo returns table metadata, including comments, for a given table.
+func (c *conn) GetTableInfo(tableName string) (TableInfo, error) {
 	if c.db == nil {
-		return nil, driver.ErrBadConn
+		return TableInfo{}, driver.ErrBadConn
 	}
 	cName := C.CString(tableName)
 	defer C.free(unsafe.Pointer(cName))
 	var ptr *C.char
 	status := C.ddb_db_describe_table_json(c.db, cName, &ptr)
 	if status != C.DDB_OK || ptr == nil {
-		return nil, statusError(status, "")
+		return TableInfo{}, statusError(status, "")
 	}
 	defer freeAPIString(ptr)
 	jsonStr := C.GoString(ptr)
 	var cols []ColumnInfo
 	if err := json.Unmarshal([]byte(jsonStr), &cols); err == nil {
-		return cols, nil
+		return TableInfo{Name: tableName, Columns: cols}, nil
 	}
 
 	var describe struct {
-		Columns []struct {
-			Name       string `json:"name"`
-			ColumnType string `json:"column_type"`
-			Nullable   bool   `json:"nullable"`
-			Unique     bool   `json:"unique"`
-			PrimaryKey bool   `json:"primary_key"`
-			ForeignKey *struct {
-				Table    string `json:"table"`
-				Column   string `json:"column"`
-				OnDelete string `json:"on_delete"`
-				OnUpdate string `json:"on_update"`
-			} `json:"foreign_key"`
+		Name              string               `json:"name"`
+		Temporary         bool                 `json:"temporary"`
+		Comment           *string              `json:"comment"`
+		PrimaryKeyColumns []string             `json:"primary_key_columns"`
+		RowCount          int64                `json:"row_count"`
+		Checks            []string             `json:"checks"`
+		ForeignKeys       []describeForeignKey `json:"foreign_keys"`
+		Columns           []struct {
+			Name            string              `json:"name"`
+			ColumnType      string              `json:"column_type"`
+			Nullable        bool                `json:"nullable"`
+			DefaultSQL      *string             `json:"default_sql"`
+			Unique          bool                `json:"unique"`
+			PrimaryKey      bool                `json:"primary_key"`
+			AutoIncrement   bool                `json:"auto_increment"`
+			GeneratedSQL    *string             `json:"generated_sql"`
+			GeneratedStored bool                `json:"generated_stored"`
+			Checks          []string            `json:"checks"`
+			Comment         *string             `json:"comment"`
+			ForeignKey      *describeForeignKey `json:"foreign_key"`
 		} `json:"columns"`
 	}
 	if err := json.Unmarshal([]byte(jsonStr), &describe); err != nil {
-		return nil, fmt.Errorf("failed to parse column info: %w", err)
+		return TableInfo{}, fmt.Errorf("failed to parse column info: %w", err)
+	}
+	table := TableInfo{
+		Name:              describe.Name,
+		Temporary:         describe.Temporary,
+		PrimaryKeyColumns: describe.PrimaryKeyColumns,
+		RowCount:          describe.RowCount,
+		Columns:           make([]ColumnInfo, 0, len(describe.Columns)),
+		Checks:            describe.Checks,
+	}
+	for _, fk := range describe.ForeignKeys {
+		table.ForeignKeys = append(table.ForeignKeys, fk.info())
+	}
+	if describe.Comment != nil {
+		table.Comment = *describe.Comment
 	}
-	cols = make([]ColumnInfo, 0, len(describe.Columns))
 	for _, c := range describe.Columns {
 		info := ColumnInfo{
-			Name:       c.Name,
-			Type:       c.ColumnType,
-			NotNull:    !c.Nullable,
-			Unique:     c.Unique,
-			PrimaryKey: c.PrimaryKey,
+			Name:          c.Name,
+			Type:          c.ColumnType,
+			NotNull:       !c.Nullable,
+			Unique:        c.Unique,
+			PrimaryKey:    c.PrimaryKey,
+			AutoIncrement: c.AutoIncrement,
+			Checks:        c.Checks,
+		}
+		if c.DefaultSQL != nil {
+			info.Default = *c.DefaultSQL
+		}
+		if c.GeneratedSQL != nil {
+			info.Generated = *c.GeneratedSQL
+			info.GeneratedStorage = "VIRTUAL"
+			if c.GeneratedStored {
+				info.GeneratedStorage = "STORED"
+			}
+		}
+		if c.Comment != nil {
+			info.Comment = *c.Comment
 		}
 		if c.ForeignKey != nil {
-			info.RefTable = c.ForeignKey.Table
-			info.RefColumn = c.ForeignKey.Column
+			info.RefTable = c.ForeignKey.ReferencedTable
+			if len(c.ForeignKey.ReferencedColumns) > 0 {
+				info.RefColumn = c.ForeignKey.ReferencedColumns[0]
+			}
 			info.RefOnDelete = c.ForeignKey.OnDelete
 			info.RefOnUpdate = c.ForeignKey.OnUpdate
 		}
-		cols = append(cols, info)
+		table.Columns = append(table.Columns, info)
 	}
-	return cols, nil
+	return table, nil
 }
 
 // IndexInfo describes an index in the database.
@@ -1478,6 +2231,81 @@ func (c *conn) DescribeQueryJson(sql string) (string, error) {
 	return C.GoString(ptr), nil
 }
 
+// TableStatisticsJson returns the planner statistics of a table as JSON.
+func (c *conn) TableStatisticsJson(table string) (string, error) {
+	if c.db == nil {
+		return "", driver.ErrBadConn
+	}
+	cName := C.CString(table)
+	defer C.free(unsafe.Pointer(cName))
+	var ptr *C.char
+	status := C.ddb_db_table_statistics_json(c.db, cName, &ptr)
+	if status != C.DDB_OK {
+		return "", statusError(status, "")
+	}
+	defer freeAPIString(ptr)
+	return C.GoString(ptr), nil
+}
+
+// PlanCacheStats returns the counters of the engine's plan cache.
+func (c *conn) PlanCacheStats() (PlanCacheStats, error) {
+	if c.db == nil {
+		return PlanCacheStats{}, driver.ErrBadConn
+	}
+	var summary C.ddb_plan_cache_summary_t
+	status := C.ddb_plan_cache_summary(c.db, &summary)
+	if status != C.DDB_OK {
+		return PlanCacheStats{}, statusError(status, "")
+	}
+	return PlanCacheStats{
+		Entries:           uint64(summary.total_entries),
+		Hits:              uint64(summary.total_hits),
+		Misses:            uint64(summary.total_misses),
+		Evictions:         uint64(summary.total_evictions),
+		SizeBytes:         uint64(summary.total_size_bytes),
+		MaxSizeBytes:      uint64(summary.max_size_bytes),
+		OversizedRefusals: uint64(summary.total_oversized_refusals),
+		HitRate:           float64(summary.hit_rate),
+	}, nil
+}
+
+// FlushPlanCache evicts every cached plan and resets the counters.
+func (c *conn) FlushPlanCache() error {
+	if c.db == nil {
+		return driver.ErrBadConn
+	}
+	status := C.ddb_plan_cache_flush(c.db)
+	if status != C.DDB_OK {
+		return statusError(status, "")
+	}
+	logDebug(context.Background(), "decentdb: plan cache flushed", slog.String("path", c.path))
+	return nil
+}
+
+// recoverToJSON opens the existing database at src and salvages it into a
+// new database at dst, returning the engine's recovery report as JSON.
+func recoverToJSON(src, dst string) (string, error) {
+	cSrc := C.CString(src)
+	defer C.free(unsafe.Pointer(cSrc))
+	cDst := C.CString(dst)
+	defer C.free(unsafe.Pointer(cDst))
+
+	var db *C.ddb_db_t
+	status := C.ddb_db_open(cSrc, &db)
+	if status != C.DDB_OK || db == nil {
+		return "", statusError(status, "")
+	}
+	defer C.ddb_db_free(&db)
+
+	var ptr *C.char
+	status = C.ddb_db_recover_to_json(db, cDst, &ptr)
+	if status != C.DDB_OK {
+		return "", statusError(status, "")
+	}
+	defer freeAPIString(ptr)
+	return C.GoString(ptr), nil
+}
+
 // ListViews returns metadata about all views as a JSON array.
 func (c *conn) ListViews() (string, error) {
 	if c.db == nil {
@@ -1541,6 +2369,8 @@ func (c *conn) ExecImmediate(sqlText string) (string, error) {
 	if c.db == nil {
 		return "", driver.ErrBadConn
 	}
+	c.noteSessionState(sqlText)
+	change := c.describeSchemaChange(sqlText, func() (*StmtInfo, error) { return c.StmtInfo(sqlText) })
 	cSQL := C.CString(sqlText)
 	defer C.free(unsafe.Pointer(cSQL))
 
@@ -1550,6 +2380,8 @@ func (c *conn) ExecImmediate(sqlText string) (string, error) {
 		return "", statusError(status, sqlText)
 	}
 	defer C.ddb_result_free(&result)
+	c.noteSchemaChange(sqlText, change)
+	c.noteWALGrowth()
 
 	// Read result metadata using the full result set API
 	var affected C.uint64_t
@@ -1789,17 +2621,31 @@ func (c *conn) Begin() (driver.Tx, error) {
 }
 
 func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if len(c.interceptors) == 0 {
+		return c.beginTx(ctx, opts)
+	}
+	return chainBeginTx(c.interceptors, c.beginTx)(ctx, opts)
+}
+
+func (c *conn) beginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
 	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
+	if !opts.ReadOnly {
+		if err := c.acquireWriter(ctx); err != nil {
+			return nil, err
+		}
+	}
 	status := C.ddb_db_begin_transaction(c.db)
 	if status != C.DDB_OK {
-		_, err := c.ExecContext(ctx, "BEGIN", nil)
+		_, err := c.execContext(ctx, "BEGIN", nil)
 		if err != nil {
+			c.releaseWriter()
 			return nil, err
 		}
 		return &tx{c: c}, nil
 	}
+	c.setInTransaction(true)
 	return &tx{c: c}, nil
 }
 
@@ -1822,25 +2668,13 @@ func isTransactionControlQuery(query string, args []driver.NamedValue) string {
 	}
 }
 
-func isLikelyWriteQuery(query string) bool {
-	normalized := strings.TrimSpace(strings.ToUpper(strings.TrimSuffix(strings.TrimSpace(query), ";")))
-	if normalized == "" {
-		return false
-	}
-	writePrefixes := []string{
-		"INSERT ",
-		"INSERT\t", "UPDATE ", "UPDATE\t", "DELETE ", "DELETE\t", "CREATE ",
-		"CREATE\t", "ALTER ", "ALTER\t", "DROP ", "DROP\t", "TRUNCATE ",
-		"TRUNCATE\t", "RENAME ", "RENAME\t", "REINDEX ", "REINDEX\t", "VACUUM ",
-		"VACUUM\t", "ANALYZE ", "ANALYZE\t", "ATTACH ", "ATTACH\t", "DETACH ",
-		"DETACH\t", "INSERT", "UPDATE", "DELETE", "CREATE", "ALTER", "DROP", "TRUNCATE", "VACUUM",
-	}
-	for _, prefix := range writePrefixes {
-		if strings.HasPrefix(normalized, prefix) {
-			return true
-		}
-	}
-	return false
+// statementWrites reports whether the statement describe describes may
+// write, using the engine's read-only flag so that writes led by a WITH
+// clause are recognized. A statement the engine cannot describe, such as
+// LOCK TABLE, counts as a write.
+func statementWrites(describe func() (*StmtInfo, error)) bool {
+	info, err := describe()
+	return err != nil || !info.ReadOnly
 }
 
 func (c *conn) executeTransactionControl(ctx context.Context, control string) (driver.Result, error) {
@@ -1850,6 +2684,9 @@ func (c *conn) executeTransactionControl(ctx context.Context, control string) (d
 	var status C.ddb_status_t
 	switch control {
 	case "BEGIN":
+		if err := c.acquireWriter(ctx); err != nil {
+			return nil, err
+		}
 		status = C.ddb_db_begin_transaction(c.db)
 	case "COMMIT":
 		var lsn C.uint64_t
@@ -1859,41 +2696,95 @@ func (c *conn) executeTransactionControl(ctx context.Context, control string) (d
 	default:
 		return nil, fmt.Errorf("unsupported transaction control: %s", control)
 	}
+	c.releaseWriter()
 	if status != C.DDB_OK {
 		return nil, statusError(status, control)
 	}
+	c.setInTransaction(control == "BEGIN")
+	c.noteSchemaChange(control, nil)
+	c.noteWALGrowth()
 	return driver.RowsAffected(0), nil
 }
 
 func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	exec := c.execContext
+	if len(c.interceptors) > 0 {
+		exec = chainExec(c.interceptors, exec)
+	}
+	if c.busy.Load() != nil {
+		next := exec
+		exec = func(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+			return handleBusy(ctx, c, func() (driver.Result, error) { return next(ctx, query, args) })
+		}
+	}
+	if c.retry != nil {
+		return c.retry.execContext(ctx, c, query, args, exec)
+	}
+	return exec(ctx, query, args)
+}
+
+func (c *conn) execContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
 	if control := isTransactionControlQuery(query, args); control != "" {
 		return c.executeTransactionControl(ctx, control)
 	}
-	if c.useWriteQueue && isLikelyWriteQuery(query) {
+	if isTwoPhaseControlQuery(query, args) {
+		err := c.execSessionSQL(query)
+		c.releaseWriter()
+		if err != nil {
+			return nil, err
+		}
+		return driver.RowsAffected(0), nil
+	}
+	if isLockTableQuery(query, args) {
+		if err := c.lockTables(ctx, query); err != nil {
+			return nil, err
+		}
+		return driver.RowsAffected(0), nil
+	}
+	if c.useWriteQueue && statementWrites(func() (*StmtInfo, error) { return c.StmtInfo(query) }) {
 		return c.execQueuedNamed(ctx, query, args)
 	}
-	s, err := c.PrepareContext(ctx, query)
+	s, err := c.prepareStmt(ctx, query)
 	if err != nil {
-		return nil, err
+		return nil, c.annotateError(err, query, args)
 	}
 	defer s.Close()
-	return s.(driver.StmtExecContext).ExecContext(ctx, args)
+	return s.execContext(ctx, args)
 }
 
 func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
-	s, err := c.PrepareContext(ctx, query)
-	if err != nil {
-		return nil, err
+	run := c.queryContext
+	if len(c.interceptors) > 0 {
+		run = chainQuery(c.interceptors, run)
+	}
+	if c.busy.Load() != nil {
+		next := run
+		run = func(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+			return handleBusy(ctx, c, func() (driver.Rows, error) { return next(ctx, query, args) })
+		}
+	}
+	if c.retry != nil {
+		return c.retry.queryContext(ctx, c, query, args, run)
+	}
+	return run(ctx, query, args)
+}
+
+func (c *conn) queryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if c.results != nil {
+		return c.results.query(ctx, c, query, args)
 	}
-	// We don't close the stmt here because Rows needs it.
-	// But database/sql handles it if we return it as part of Rows or if we use Stmt directly.
-	// Actually for QueryContext on Conn, we should probably follow what other drivers do.
-	rows, err := s.(driver.StmtQueryContext).QueryContext(ctx, args)
+	return c.queryUncached(ctx, query, args)
+}
+
+func (c *conn) queryUncached(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	s, err := c.prepareStmt(ctx, query)
 	if err != nil {
-		s.Close()
-		return nil, err
+		return nil, c.annotateError(err, query, args)
 	}
-	return &rowsWithStmt{Rows: rows, stmt: s}, nil
+	// The rows hold their own reference, so the statement is freed when
+	// they close.
+	defer s.Close()
+	return s.queryContext(ctx, args)
 }
 
 type tx struct {
@@ -1901,35 +2792,91 @@ type tx struct {
 }
 
 func (t *tx) Commit() error {
-	_, err := t.c.ExecContext(context.Background(), "COMMIT", nil)
-	return err
+	return chainEndTx(t.c.interceptors, Interceptor.InterceptCommit, func() error {
+		_, err := t.c.execContext(context.Background(), "COMMIT", nil)
+		return err
+	})()
 }
 
 func (t *tx) Rollback() error {
-	_, err := t.c.ExecContext(context.Background(), "ROLLBACK", nil)
-	return err
+	return chainEndTx(t.c.interceptors, Interceptor.InterceptRollback, func() error {
+		_, err := t.c.execContext(context.Background(), "ROLLBACK", nil)
+		return err
+	})()
 }
 
 type stmtStruct struct {
 	c     *conn
 	query string
 	stmt  *C.ddb_stmt_t
+	// info caches the engine's query contract for NumInput and StmtInfo.
+	info    *StmtInfo
+	infoErr error
+	// leak is the statement's debug_leaks=true tracking record.
+	leak *leakRecord
+	// refs counts the statement's owner plus each open rows reading from
+	// it. The native handle is freed when the last of them lets go, so
+	// closing a statement never pulls the handle out from under its rows.
+	refs   atomic.Int32
+	closed atomic.Bool
+	// viewColumns is the row-view projection last set on the native
+	// statement; nil returns every column.
+	viewColumns []int
+}
+
+// Close releases the owner's reference. The native handle stays alive until
+// rows returned by the statement are closed too.
+func (s *stmtStruct) Close() error {
+	if !s.closed.CompareAndSwap(false, true) {
+		return nil
+	}
+	return s.release()
 }
 
-func (s *stmtStruct) Close() error {
-	if s.stmt != nil {
-		stmtp := s.stmt
-		status := C.ddb_stmt_free(&stmtp)
-		if status != C.DDB_OK {
-			return statusError(status, s.query)
-		}
+// retain takes a reference for rows reading from the statement.
+func (s *stmtStruct) retain() {
+	s.refs.Add(1)
+}
+
+// release drops a reference, freeing the native handle with the last one.
+func (s *stmtStruct) release() error {
+	if s.refs.Add(-1) > 0 {
+		return nil
+	}
+	return s.free()
+}
+
+func (s *stmtStruct) free() error {
+	if s.leak != nil && !s.c.leaks.untrack(s) {
+		// The leak tracker already freed the handle.
 		s.stmt = nil
 	}
+	if s.stmt == nil {
+		return nil
+	}
+	stmtp := s.stmt
+	s.stmt = nil
+	status := C.ddb_stmt_free(&stmtp)
+	s.c.endStatement()
+	if status != C.DDB_OK {
+		return statusError(status, s.query)
+	}
 	return nil
 }
 
+// NumInput reports the placeholder count so database/sql can validate
+// argument counts. It returns -1, disabling the check, when the engine cannot
+// describe the statement or its contract disagrees with the SQL text.
 func (s *stmtStruct) NumInput() int {
-	return -1 // Driver doesn't know until bind
+	info, err := s.StmtInfo()
+	if err != nil {
+		return -1
+	}
+	n := info.NumInput()
+	if n != maxPlaceholder(s.query) {
+		return -1
+	}
+	return n
 }
 
 func (s *stmtStruct) Exec(args []driver.Value) (driver.Result, error) {
@@ -1945,11 +2892,12 @@ func (s *stmtStruct) bind(args []driver.NamedValue) error {
 		return errors.New("statement is closed")
 	}
 	// Ensure statement reuse is safe: clear previous execution state and bindings.
+	start := cgoStart()
 	status := C.ddb_stmt_reset(s.stmt)
-	if status != C.DDB_OK {
-		return statusError(status, s.query)
+	if status == C.DDB_OK {
+		status = C.ddb_stmt_clear_bindings(s.stmt)
 	}
-	status = C.ddb_stmt_clear_bindings(s.stmt)
+	cgoReset.done(start)
 	if status != C.DDB_OK {
 		return statusError(status, s.query)
 	}
@@ -1959,6 +2907,7 @@ func (s *stmtStruct) bind(args []driver.NamedValue) error {
 			return fmt.Errorf("invalid bind ordinal: %d", arg.Ordinal)
 		}
 		idx := C.size_t(arg.Ordinal) // 1-based
+		start := cgoStart()
 		switch v := arg.Value.(type) {
 		case nil:
 			status = C.ddb_stmt_bind_null(s.stmt, idx)
@@ -2005,6 +2954,8 @@ func (s *stmtStruct) bind(args []driver.NamedValue) error {
 				defer pinner.Unpin()
 				status = C.ddb_stmt_bind_blob(s.stmt, idx, (*C.uint8_t)(unsafe.Pointer(&v[0])), C.size_t(len(v)))
 			}
+		case UUID:
+			status = C.ddb_stmt_bind_uuid(s.stmt, idx, (*C.uint8_t)(unsafe.Pointer(&v[0])))
 		case time.Time:
 			// Microseconds since Unix epoch UTC
 			micros := v.UnixNano() / 1e3
@@ -2021,6 +2972,7 @@ func (s *stmtStruct) bind(args []driver.NamedValue) error {
 		default:
 			return fmt.Errorf("unsupported type: %T", v)
 		}
+		cgoBind.done(start)
 		if status != C.DDB_OK {
 			return statusError(status, s.query)
 		}
@@ -2029,17 +2981,45 @@ func (s *stmtStruct) bind(args []driver.NamedValue) error {
 }
 
 func (s *stmtStruct) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	if len(s.c.interceptors) == 0 {
+		return s.execContext(ctx, args)
+	}
+	return chainExec(s.c.interceptors, func(ctx context.Context, _ string, args []driver.NamedValue) (driver.Result, error) {
+		return s.execContext(ctx, args)
+	})(ctx, s.query, args)
+}
+
+func (s *stmtStruct) execContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
 	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
-	if err := s.bind(args); err != nil {
+	if err := s.c.useContextWorkers(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.c.useContextReadAhead(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.c.useContextMemoryLimit(ctx); err != nil {
 		return nil, err
 	}
+	release, err := s.c.gateAutocommitWrite(ctx, s.StmtInfo)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	if err := s.bind(args); err != nil {
+		return nil, s.c.annotateError(err, s.query, args)
+	}
+	change := s.c.describeSchemaChange(s.query, s.StmtInfo)
+	s.c.beginActivity(ctx, s.query)
+	defer s.c.endActivity()
 
 	var hasRow C.uint8_t
+	start := cgoStart()
 	status := C.ddb_stmt_step(s.stmt, &hasRow)
+	cgoStep.done(start)
 	if status != C.DDB_OK {
-		return nil, statusError(status, s.query)
+		return nil, s.c.annotateError(statusError(status, s.query), s.query, args)
 	}
 
 	var affected C.uint64_t
@@ -2047,18 +3027,50 @@ func (s *stmtStruct) ExecContext(ctx context.Context, args []driver.NamedValue)
 	if status != C.DDB_OK {
 		return nil, statusError(status, s.query)
 	}
+	s.c.noteSchemaChange(s.query, change)
+	s.c.noteWALGrowth()
 	return driver.RowsAffected(affected), nil
 }
 
 func (s *stmtStruct) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	if len(s.c.interceptors) == 0 {
+		return s.queryContext(ctx, args)
+	}
+	return chainQuery(s.c.interceptors, func(ctx context.Context, _ string, args []driver.NamedValue) (driver.Rows, error) {
+		return s.queryContext(ctx, args)
+	})(ctx, s.query, args)
+}
+
+func (s *stmtStruct) queryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
 	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
+	if err := s.c.useContextWorkers(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.c.useContextReadAhead(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.c.useContextMemoryLimit(ctx); err != nil {
+		return nil, err
+	}
+	release, err := s.c.gateAutocommitWrite(ctx, s.StmtInfo)
+	if err != nil {
+		return nil, err
+	}
 	if err := s.bind(args); err != nil {
+		release()
+		return nil, s.c.annotateError(err, s.query, args)
+	}
+	projection, _ := ColumnProjectionFromContext(ctx)
+	if err := s.useViewColumns(projection); err != nil {
+		release()
 		return nil, err
 	}
 
-	return &rows{s: s, ctx: ctx}, nil
+	s.retain()
+	s.c.beginActivity(ctx, s.query)
+	return &rows{s: s, ctx: ctx, args: args, release: release, projection: projection}, nil
 }
 
 // RebindInt64Execute rebinds the first parameter as int64 and re-executes
@@ -2068,7 +3080,9 @@ func (s *stmtStruct) RebindInt64Execute(value int64) (int64, error) {
 		return 0, errors.New("statement is closed")
 	}
 	var affected C.uint64_t
+	start := cgoStart()
 	status := C.ddb_stmt_rebind_int64_execute(s.stmt, C.int64_t(value), &affected)
+	cgoExecute.done(start)
 	if status != C.DDB_OK {
 		return 0, statusError(status, s.query)
 	}
@@ -2084,16 +3098,19 @@ func (s *stmtStruct) ExecuteBatchI64(values []int64) (int64, error) {
 	if len(values) == 0 {
 		return 0, nil
 	}
+	start := cgoStart()
 	status := C.ddb_stmt_reset(s.stmt)
-	if status != C.DDB_OK {
-		return 0, statusError(status, s.query)
+	if status == C.DDB_OK {
+		status = C.ddb_stmt_clear_bindings(s.stmt)
 	}
-	status = C.ddb_stmt_clear_bindings(s.stmt)
+	cgoReset.done(start)
 	if status != C.DDB_OK {
 		return 0, statusError(status, s.query)
 	}
 	var affected C.uint64_t
+	start = cgoStart()
 	status = C.ddb_stmt_execute_batch_i64(s.stmt, C.size_t(len(values)), (*C.int64_t)(&values[0]), &affected)
+	cgoExecute.done(start)
 	if status != C.DDB_OK {
 		return 0, statusError(status, s.query)
 	}
@@ -2108,7 +3125,9 @@ func (s *stmtStruct) RebindTextInt64Execute(text string, intValue int64) (int64,
 	ct := C.CString(text)
 	defer C.free(unsafe.Pointer(ct))
 	var affected C.uint64_t
+	start := cgoStart()
 	status := C.ddb_stmt_rebind_text_int64_execute(s.stmt, ct, C.size_t(len(text)), C.int64_t(intValue), &affected)
+	cgoExecute.done(start)
 	if status != C.DDB_OK {
 		return 0, statusError(status, s.query)
 	}
@@ -2123,7 +3142,9 @@ func (s *stmtStruct) RebindInt64TextExecute(intValue int64, text string) (int64,
 	ct := C.CString(text)
 	defer C.free(unsafe.Pointer(ct))
 	var affected C.uint64_t
+	start := cgoStart()
 	status := C.ddb_stmt_rebind_int64_text_execute(s.stmt, C.int64_t(intValue), ct, C.size_t(len(text)), &affected)
+	cgoExecute.done(start)
 	if status != C.DDB_OK {
 		return 0, statusError(status, s.query)
 	}
@@ -2136,7 +3157,9 @@ func (s *stmtStruct) BindAndStepRowView(index int, value int64) (hasRow bool, vi
 		return false, nil, 0, errors.New("statement is closed")
 	}
 	var hasRowByte C.uint8_t
+	start := cgoStart()
 	status := C.ddb_stmt_bind_int64_step_row_view(s.stmt, C.size_t(index), C.int64_t(value), &views, &count, &hasRowByte)
+	cgoRowView.done(start)
 	if status != C.DDB_OK {
 		return false, nil, 0, statusError(status, s.query)
 	}
@@ -2165,7 +3188,9 @@ func (s *stmtStruct) FetchRowViews(includeCurrent bool, maxRows int) (views *C.d
 	if includeCurrent {
 		inc = 1
 	}
+	start := cgoStart()
 	status := C.ddb_stmt_fetch_row_views(s.stmt, inc, C.size_t(maxRows), &views, &rowCount, &colCount)
+	cgoRowView.done(start)
 	if status != C.DDB_OK {
 		return nil, 0, 0, statusError(status, s.query)
 	}
@@ -2175,6 +3200,22 @@ func (s *stmtStruct) FetchRowViews(includeCurrent bool, maxRows int) (views *C.d
 type rows struct {
 	s   *stmtStruct
 	ctx context.Context
+	// args are the bound arguments, kept to describe errors raised by step.
+	args []driver.NamedValue
+	// views borrows the current row from the native statement. The backing
+	// memory is engine-owned and only valid until the next step, reset, or
+	// free of s.stmt.
+	views []C.ddb_value_view_t
+	// projection lists the result columns views holds, in order, when the
+	// query ran with WithColumnProjection; nil means every column.
+	projection []int
+	// release drops any writer gate held for a write statement with RETURNING.
+	release func()
+	// declTypes holds each result column's declared type once loaded for
+	// decoding values and reporting scan types.
+	declTypes       []string
+	declTypesLoaded bool
+	closed          bool
 }
 
 func (r *rows) Columns() []string {
@@ -2196,15 +3237,30 @@ func (r *rows) Columns() []string {
 	return cols
 }
 
+// Close resets the statement, so it can be reused and drops any held read
+// snapshot, then releases the rows' reference to it.
 func (r *rows) Close() error {
-	// Make statement reusable (and release any held read snapshot).
-	if r.s != nil && r.s.stmt != nil {
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	r.views = nil
+	r.s.c.endActivity()
+	if r.release != nil {
+		r.release()
+		r.release = nil
+	}
+	if r.s.stmt != nil {
+		start := cgoStart()
 		C.ddb_stmt_reset(r.s.stmt)
+		cgoReset.done(start)
 	}
-	return nil
+	return r.s.release()
 }
 
-func (r *rows) Next(dest []driver.Value) error {
+// step advances the statement and points r.views at the new row.
+func (r *rows) step() error {
+	r.views = nil
 	if r.ctx != nil {
 		select {
 		case <-r.ctx.Done():
@@ -2218,89 +3274,151 @@ func (r *rows) Next(dest []driver.Value) error {
 	var hasRow C.uint8_t
 
 	// Fused step+row_view: single cgo crossing instead of two
+	start := cgoStart()
 	status := C.ddb_stmt_step_row_view(r.s.stmt, &views, &count, &hasRow)
+	cgoRowView.done(start)
 	if status != C.DDB_OK {
-		return statusError(status, r.s.query)
+		return r.s.c.annotateError(statusError(status, r.s.query), r.s.query, r.args)
 	}
 	if hasRow == 0 {
 		return io.EOF
 	}
-	if count == 0 {
-		return nil
+	if count > 0 {
+		r.views = unsafe.Slice((*C.ddb_value_view_t)(unsafe.Pointer(views)), int(count))
 	}
-	goViews := unsafe.Slice((*C.ddb_value_view_t)(unsafe.Pointer(views)), int(count))
-	for i := 0; i < int(count) && i < len(dest); i++ {
-		v := goViews[i]
-		if v.tag == C.DDB_VALUE_NULL {
+	return nil
+}
+
+func (r *rows) Next(dest []driver.Value) error {
+	if err := r.step(); err != nil {
+		return err
+	}
+	if r.projection != nil {
+		for i := range dest {
 			dest[i] = nil
-			continue
-		}
-		switch v.tag {
-		case C.DDB_VALUE_INT64:
-			dest[i] = int64(v.int64_value)
-		case C.DDB_VALUE_BOOL:
-			dest[i] = v.bool_value != 0
-		case C.DDB_VALUE_FLOAT64:
-			dest[i] = float64(v.float64_value)
-		case C.DDB_VALUE_TEXT:
-			if v.len == 0 || v.data == nil {
-				dest[i] = ""
-				continue
-			}
-			dest[i] = C.GoStringN((*C.char)(unsafe.Pointer(v.data)), C.int(v.len))
-		case C.DDB_VALUE_BLOB:
-			if v.len == 0 || v.data == nil {
-				dest[i] = []byte{}
-				continue
-			}
-			dest[i] = C.GoBytes(unsafe.Pointer(v.data), C.int(v.len))
-		case C.DDB_VALUE_GEOMETRY, C.DDB_VALUE_GEOGRAPHY:
-			if v.len == 0 || v.data == nil {
-				dest[i] = []byte{}
-				continue
-			}
-			dest[i] = C.GoBytes(unsafe.Pointer(v.data), C.int(v.len))
-		case C.DDB_VALUE_UUID:
-			dest[i] = C.GoBytes(unsafe.Pointer(&v.uuid_bytes[0]), 16)
-		case C.DDB_VALUE_DECIMAL:
-			dest[i] = Decimal{
-				Unscaled: int64(v.decimal_scaled),
-				Scale:    int(v.decimal_scale),
+			if v := r.viewIndex(i); v >= 0 && v < len(r.views) {
+				dest[i] = r.columnValue(i, r.views[v])
 			}
-		case C.DDB_VALUE_TIMESTAMP_MICROS:
-			dest[i] = decodeTimestampMicrosValue(int64(v.timestamp_micros))
-		case C.DDB_VALUE_ENUM, C.DDB_VALUE_IPADDR, C.DDB_VALUE_CIDR,
-			C.DDB_VALUE_DATE, C.DDB_VALUE_TIME, C.DDB_VALUE_TIMESTAMPTZ_MICROS,
-			C.DDB_VALUE_INTERVAL, C.DDB_VALUE_MACADDR:
-			dest[i] = decodeSemanticTag(
-				uint32(v.tag),
-				uint64(v.enum_type_id),
-				uint64(v.enum_label_id),
-				uint8(v.ip_family),
-				uint8(v.cidr_prefix_len),
-				ipCIDRBytesFromView(v),
-				int32(v.date_days),
-				int64(v.time_micros),
-				int64(v.timestamptz_micros),
-				int32(v.interval_months),
-				int32(v.interval_days),
-				int64(v.interval_micros),
-			)
-		default:
-			dest[i] = nil
 		}
+		return nil
+	}
+	for i := 0; i < len(r.views) && i < len(dest); i++ {
+		dest[i] = r.columnValue(i, r.views[i])
 	}
-
 	return nil
 }
 
-type rowsWithStmt struct {
-	driver.Rows
-	stmt driver.Stmt
+// fetchColumns fetches up to n rows (all remaining when n <= 0) in one cgo
+// crossing and decodes them column by column into a ColumnBatch.
+func (r *rows) fetchColumns(names []string, n int) (*ColumnBatch, error) {
+	r.views = nil
+	if r.ctx != nil {
+		if err := r.ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
+	if n < 0 {
+		n = 0
+	}
+	views, rowCount, colCount, err := r.s.FetchRowViews(false, n)
+	if err != nil {
+		return nil, err
+	}
+	if rowCount == 0 {
+		return nil, io.EOF
+	}
+	cells := unsafe.Slice((*C.ddb_value_view_t)(unsafe.Pointer(views)), int(rowCount)*int(colCount))
+	batch := newColumnBatch(names, int(rowCount))
+	for i := range batch.Columns {
+		col := &batch.Columns[i]
+		source := r.viewIndex(i)
+		if source < 0 {
+			for row := 0; row < int(rowCount); row++ {
+				col.appendNull()
+			}
+			continue
+		}
+		if source >= int(colCount) {
+			break
+		}
+		for row := 0; row < int(rowCount); row++ {
+			v := cells[row*int(colCount)+source]
+			switch v.tag {
+			case C.DDB_VALUE_NULL:
+				col.appendNull()
+			case C.DDB_VALUE_INT64:
+				col.appendInt64(int64(v.int64_value))
+			case C.DDB_VALUE_BOOL:
+				col.appendBool(v.bool_value != 0)
+			case C.DDB_VALUE_FLOAT64:
+				col.appendFloat64(float64(v.float64_value))
+			case C.DDB_VALUE_TEXT:
+				col.appendText(viewToDriverValue(v).(string))
+			case C.DDB_VALUE_BLOB, C.DDB_VALUE_GEOMETRY, C.DDB_VALUE_GEOGRAPHY, C.DDB_VALUE_UUID:
+				col.appendBytes(viewToDriverValue(v).([]byte))
+			case C.DDB_VALUE_TIMESTAMP_MICROS:
+				col.appendTime(decodeTimestampMicrosValue(int64(v.timestamp_micros)))
+			default:
+				col.appendValue(viewToDriverValue(v))
+			}
+		}
+	}
+	return batch, nil
 }
 
-func (r *rowsWithStmt) Close() error {
-	err := r.Rows.Close()
-	r.stmt.Close()
-	return err
+// viewToDriverValue decodes a borrowed row-view cell into an owned Go value.
+func viewToDriverValue(v C.ddb_value_view_t) driver.Value {
+	switch v.tag {
+	case C.DDB_VALUE_NULL:
+		return nil
+	case C.DDB_VALUE_INT64:
+		return int64(v.int64_value)
+	case C.DDB_VALUE_BOOL:
+		return v.bool_value != 0
+	case C.DDB_VALUE_FLOAT64:
+		return float64(v.float64_value)
+	case C.DDB_VALUE_TEXT:
+		if v.len == 0 || v.data == nil {
+			return ""
+		}
+		return C.GoStringN((*C.char)(unsafe.Pointer(v.data)), C.int(v.len))
+	case C.DDB_VALUE_BLOB:
+		if v.len == 0 || v.data == nil {
+			return []byte{}
+		}
+		return C.GoBytes(unsafe.Pointer(v.data), C.int(v.len))
+	case C.DDB_VALUE_GEOMETRY, C.DDB_VALUE_GEOGRAPHY:
+		if v.len == 0 || v.data == nil {
+			return []byte{}
+		}
+		return C.GoBytes(unsafe.Pointer(v.data), C.int(v.len))
+	case C.DDB_VALUE_UUID:
+		return C.GoBytes(unsafe.Pointer(&v.uuid_bytes[0]), 16)
+	case C.DDB_VALUE_DECIMAL:
+		return Decimal{
+			Unscaled: int64(v.decimal_scaled),
+			Scale:    int(v.decimal_scale),
+		}
+	case C.DDB_VALUE_TIMESTAMP_MICROS:
+		return decodeTimestampMicrosValue(int64(v.timestamp_micros))
+	case C.DDB_VALUE_ENUM, C.DDB_VALUE_IPADDR, C.DDB_VALUE_CIDR,
+		C.DDB_VALUE_DATE, C.DDB_VALUE_TIME, C.DDB_VALUE_TIMESTAMPTZ_MICROS,
+		C.DDB_VALUE_INTERVAL, C.DDB_VALUE_MACADDR:
+		return decodeSemanticTag(
+			uint32(v.tag),
+			uint64(v.enum_type_id),
+			uint64(v.enum_label_id),
+			uint8(v.ip_family),
+			uint8(v.cidr_prefix_len),
+			ipCIDRBytesFromView(v),
+			int32(v.date_days),
+			int64(v.time_micros),
+			int64(v.timestamptz_micros),
+			int32(v.interval_months),
+			int32(v.interval_days),
+			int64(v.interval_micros),
+		)
+	default:
+		return nil
+	}
 }
@@ -16,6 +16,19 @@ import (
 	"time"
 )
 
+// openTestDB opens a pool on a new database in the test's temp directory,
+// appending dsnQuery (such as "?shared_engine=true") to the DSN, and closes
+// it when the test ends.
+func openTestDB(t *testing.T, dsnQuery string) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("decentdb", fmt.Sprintf("file:%s%s", filepath.Join(t.TempDir(), "test.ddb"), dsnQuery))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
 func TestDriver(t *testing.T) {
 	tmpDir, err := os.MkdirTemp("", "decentdb-test-*")
 	if err != nil {
@@ -511,14 +524,12 @@ func TestDriver_UUID(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	var u2 []byte
+	// UUID columns scan as UUID, which is a 16-byte array.
+	var u2 UUID
 	err = db.QueryRow("SELECT u FROM t").Scan(&u2)
 	if err != nil {
 		t.Fatal(err)
 	}
-	if len(u2) != 16 {
-		t.Errorf("expected 16 bytes, got %d", len(u2))
-	}
 	for i := range u1 {
 		if u1[i] != u2[i] {
 			t.Errorf("byte mismatch at %d", i)
@@ -636,6 +647,58 @@ func TestBindLargeBlob_NoCgoPointerViolation(t *testing.T) {
 	}
 }
 
+func TestDriver_RawBytesScan(t *testing.T) {
+	db, err := sql.Open("decentdb", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec("CREATE TABLE t (id INT PRIMARY KEY, name TEXT, data BLOB)"); err != nil {
+		t.Fatal(err)
+	}
+	payload := bytes.Repeat([]byte{0xAB}, 4096)
+	if _, err := db.Exec("INSERT INTO t (id, name, data) VALUES ($1, $2, $3)", 1, "alpha", payload); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("INSERT INTO t (id, name, data) VALUES ($1, $2, $3)", 2, nil, []byte{}); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := db.Query("SELECT id, name, data FROM t ORDER BY id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	var id, name, data sql.RawBytes
+	if !rows.Next() {
+		t.Fatal("expected first row")
+	}
+	if err := rows.Scan(&id, &name, &data); err != nil {
+		t.Fatal(err)
+	}
+	if string(id) != "1" || string(name) != "alpha" || !bytes.Equal(data, payload) {
+		t.Fatalf("unexpected first row: id=%q name=%q len(data)=%d", id, name, len(data))
+	}
+
+	if !rows.Next() {
+		t.Fatal("expected second row")
+	}
+	if err := rows.Scan(&id, &name, &data); err != nil {
+		t.Fatal(err)
+	}
+	if string(id) != "2" || name != nil || data == nil || len(data) != 0 {
+		t.Fatalf("unexpected second row: id=%q name=%v data=%v", id, name, data)
+	}
+	if rows.Next() {
+		t.Fatal("unexpected third row")
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestDriver_Null(t *testing.T) {
 	tmpDir, err := os.MkdirTemp("", "decentdb-test-null-*")
 	if err != nil {
@@ -956,6 +1019,121 @@ func TestOpenDirect_GetTableColumns(t *testing.T) {
 	}
 }
 
+func TestOpenDirect_GetTableInfoComments(t *testing.T) {
+	db, err := OpenDirect(filepath.Join(t.TempDir(), "comments.ddb"))
+	if err != nil {
+		t.Fatalf("OpenDirect failed: %v", err)
+	}
+	defer db.Close()
+
+	for _, stmt := range []string{
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT)",
+		"INSERT INTO users VALUES (1, 'a@example.com')",
+		"COMMENT ON TABLE users IS 'Registered accounts'",
+		"COMMENT ON COLUMN users.email IS 'Login address'",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+
+	info, err := db.GetTableInfo("users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Name != "users" || info.Comment != "Registered accounts" || info.RowCount != 1 {
+		t.Fatalf("unexpected table info: %+v", info)
+	}
+	if len(info.PrimaryKeyColumns) != 1 || info.PrimaryKeyColumns[0] != "id" {
+		t.Fatalf("unexpected primary key columns: %v", info.PrimaryKeyColumns)
+	}
+	if len(info.Columns) != 2 || info.Columns[0].Comment != "" || info.Columns[1].Comment != "Login address" {
+		t.Fatalf("unexpected column comments: %+v", info.Columns)
+	}
+
+	if _, err := db.Exec("COMMENT ON TABLE users IS NULL"); err != nil {
+		t.Fatal(err)
+	}
+	cols, err := db.GetTableColumns("users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cols[1].Comment != "Login address" {
+		t.Fatalf("column comment = %q", cols[1].Comment)
+	}
+	if info, err = db.GetTableInfo("users"); err != nil || info.Comment != "" {
+		t.Fatalf("table comment after removal = %q, %v", info.Comment, err)
+	}
+}
+
+func TestDriver_GeneratedColumns(t *testing.T) {
+	db, err := sql.Open("decentdb", fmt.Sprintf("file:%s", filepath.Join(t.TempDir(), "generated.ddb")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(`CREATE TABLE lines (
+		id INTEGER PRIMARY KEY,
+		qty INTEGER,
+		price REAL,
+		total REAL GENERATED ALWAYS AS (qty * price) STORED,
+		label TEXT GENERATED ALWAYS AS ('line ' || CAST(id AS TEXT)) VIRTUAL
+	)`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("INSERT INTO lines (id, qty, price) VALUES ($1, $2, $3)", 1, 3, 2.5); err != nil {
+		t.Fatal(err)
+	}
+	var total float64
+	var label string
+	if err := db.QueryRow("SELECT total, label FROM lines WHERE id = $1", 1).Scan(&total, &label); err != nil {
+		t.Fatal(err)
+	}
+	if total != 7.5 || label != "line 1" {
+		t.Fatalf("generated values = %v, %q", total, label)
+	}
+
+	for _, stmt := range []string{
+		"INSERT INTO lines (id, qty, price, total) VALUES ($1, $2, $3, $4)",
+		"INSERT INTO lines (id, qty, price, label) VALUES ($1, $2, $3, $4)",
+	} {
+		if _, err := db.Exec(stmt, 2, 1, 1.0, 99); err == nil || !strings.Contains(err.Error(), "generated column") {
+			t.Fatalf("%s: binding a generated column returned %v", stmt, err)
+		}
+	}
+	if _, err := db.Exec("UPDATE lines SET total = $1 WHERE id = 1", 0.0); err == nil {
+		t.Fatal("updating a generated column should fail")
+	}
+
+	sqlConn, err := db.Conn(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlConn.Close()
+	var cols []ColumnInfo
+	if err := sqlConn.Raw(func(raw any) error {
+		var err error
+		cols, err = raw.(*conn).GetTableColumns("lines")
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	byName := map[string]ColumnInfo{}
+	for _, col := range cols {
+		byName[col.Name] = col
+	}
+	if c := byName["qty"]; c.Generated != "" || c.GeneratedStorage != "" {
+		t.Fatalf("ordinary column reported as generated: %+v", c)
+	}
+	if c := byName["total"]; c.Generated == "" || c.GeneratedStorage != "STORED" {
+		t.Fatalf("unexpected total column: %+v", c)
+	}
+	if c := byName["label"]; !strings.Contains(c.Generated, "line ") || c.GeneratedStorage != "VIRTUAL" {
+		t.Fatalf("unexpected label column: %+v", c)
+	}
+}
+
 func TestOpenDirect_ListIndexes(t *testing.T) {
 	tmpDir, err := os.MkdirTemp("", "decentdb-test-*")
 	if err != nil {
@@ -1336,3 +1514,284 @@ func TestExplicitCloseDetachesFinalizer(t *testing.T) {
 		t.Fatalf("expected close hook to run once, got %d", got)
 	}
 }
+
+func TestDriver_ProcessCoordinationDSNOptions(t *testing.T) {
+	for input, want := range map[string]string{
+		"auto":                  "auto",
+		"REQUIRED":              "required",
+		"exclusive":             "exclusive",
+		"off":                   "single_process_unsafe",
+		"single_process_unsafe": "single_process_unsafe",
+	} {
+		got, err := normalizeProcessCoordination(input)
+		if err != nil || got != want {
+			t.Fatalf("normalizeProcessCoordination(%q) = %q, %v; want %q", input, got, err, want)
+		}
+	}
+	if _, err := normalizeProcessCoordination("shared"); err == nil {
+		t.Fatal("expected unknown process_coordination mode to be rejected")
+	}
+
+	tmpDir, err := os.MkdirTemp("", "decentdb-test-coordination-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	dbPath := filepath.Join(tmpDir, "coord.ddb")
+
+	db, err := sql.Open("decentdb", fmt.Sprintf("file:%s?process_coordination=required&process_coordination_timeout_ms=250", dbPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("CREATE TABLE coord (id INT PRIMARY KEY)"); err != nil {
+		t.Fatalf("open with process coordination options failed: %v", err)
+	}
+
+	for _, query := range []string{
+		"process_coordination=shared",
+		"process_coordination_timeout_ms=soon",
+	} {
+		if _, err := sql.Open("decentdb", fmt.Sprintf("file:%s?%s", dbPath, query)); err == nil {
+			t.Fatalf("expected %q to be rejected", query)
+		}
+	}
+}
+
+func TestDriver_ExclusiveProcessCoordination(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "exclusive.ddb")
+	open := func(mode string) *sql.DB {
+		db, err := sql.Open("decentdb", fmt.Sprintf("file:%s?process_coordination=%s&process_coordination_timeout_ms=0", dbPath, mode))
+		if err != nil {
+			t.Fatal(err)
+		}
+		db.SetMaxOpenConns(1)
+		return db
+	}
+
+	first := open("exclusive")
+	if _, err := first.Exec("CREATE TABLE t (id INT PRIMARY KEY)"); err != nil {
+		t.Fatalf("exclusive open failed: %v", err)
+	}
+	for _, mode := range []string{"exclusive", "auto"} {
+		second := open(mode)
+		err := second.Ping()
+		second.Close()
+		if !errors.Is(err, ErrLocked) {
+			t.Fatalf("%s open while an exclusive handle is open: got %v, want ErrLocked", mode, err)
+		}
+	}
+	first.Close()
+
+	shared := open("auto")
+	defer shared.Close()
+	if err := shared.Ping(); err != nil {
+		t.Fatal(err)
+	}
+	contender := open("exclusive")
+	defer contender.Close()
+	if err := contender.Ping(); !errors.Is(err, ErrLocked) {
+		t.Fatalf("exclusive open while another handle is open: got %v, want ErrLocked", err)
+	}
+}
+
+func TestStmtLifecycle_RowsOutliveClosedStatement(t *testing.T) {
+	db, err := sql.Open("decentdb", fmt.Sprintf("file:%s", filepath.Join(t.TempDir(), "lifecycle.ddb")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("CREATE TABLE t (id INT PRIMARY KEY)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("INSERT INTO t SELECT value FROM generate_series(1, 10)"); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	c, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	err = c.Raw(func(dc any) error {
+		s, err := dc.(*conn).prepareStmt(ctx, "SELECT id FROM t ORDER BY id")
+		if err != nil {
+			return err
+		}
+		r, err := s.queryContext(ctx, nil)
+		if err != nil {
+			return err
+		}
+		if err := s.Close(); err != nil {
+			return err
+		}
+		if s.stmt == nil {
+			return errors.New("statement freed while rows were open")
+		}
+		dest := make([]driver.Value, 1)
+		n := 0
+		for r.Next(dest) == nil {
+			n++
+		}
+		if n != 10 {
+			return fmt.Errorf("read %d rows after closing the statement, want 10", n)
+		}
+		if err := r.Close(); err != nil {
+			return err
+		}
+		if s.stmt != nil {
+			return errors.New("statement not freed after its rows closed")
+		}
+		// Repeated closes are no-ops.
+		if err := r.Close(); err != nil {
+			return err
+		}
+		return s.Close()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStmtLifecycle_ConcurrentQueriesAndEarlyClose(t *testing.T) {
+	db, err := sql.Open("decentdb", fmt.Sprintf("file:%s?debug_leaks=true", filepath.Join(t.TempDir(), "lifecycle.ddb")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("CREATE TABLE t (id INT PRIMARY KEY)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("INSERT INTO t SELECT value FROM generate_series(1, 100)"); err != nil {
+		t.Fatal(err)
+	}
+	stmt, err := db.Prepare("SELECT id FROM t WHERE id > $1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errs := make(chan error, 8)
+	for g := 0; g < 8; g++ {
+		go func(g int) {
+			for i := 0; i < 50; i++ {
+				rows, err := stmt.Query(i)
+				if err != nil {
+					errs <- err
+					return
+				}
+				// Abandon half the results early; Close must still
+				// release the statement.
+				for j := 0; rows.Next() && (g%2 == 0 || j < 3); j++ {
+				}
+				if err := rows.Close(); err != nil {
+					errs <- err
+					return
+				}
+				if _, err := db.Query("SELECT COUNT(*) FROM missing_table"); err == nil {
+					errs <- errors.New("expected query on a missing table to fail")
+					return
+				}
+			}
+			errs <- nil
+		}(g)
+	}
+	for g := 0; g < 8; g++ {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := stmt.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("statements leaked: %v", err)
+	}
+}
+
+func TestCloseOptions_TruncateWALLeavesSingleFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "close.ddb")
+	db, err := sql.Open("decentdb", fmt.Sprintf("file:%s?truncate_wal_on_close=true", path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("CREATE TABLE t (id INT PRIMARY KEY)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("INSERT INTO t SELECT value FROM generate_series(1, 100)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path + ".wal"); err != nil {
+		t.Fatalf("expected a WAL while open: %v", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path + ".wal"); !os.IsNotExist(err) {
+		t.Fatalf("WAL left behind after close: %v", err)
+	}
+
+	reopened, err := sql.Open("decentdb", fmt.Sprintf("file:%s", path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	var count int
+	if err := reopened.QueryRow("SELECT COUNT(*) FROM t").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 100 {
+		t.Fatalf("count = %d, want 100", count)
+	}
+
+	if _, err := sql.Open("decentdb", fmt.Sprintf("file:%s?checkpoint_on_close=maybe", path)); err == nil {
+		t.Fatal("expected an invalid checkpoint_on_close to be rejected")
+	}
+}
+
+func TestSynchronousDSNAndFlush(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bulk.ddb")
+	db, err := sql.Open("decentdb", fmt.Sprintf("file:%s?synchronous=off", path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var level int
+	if err := db.QueryRow("PRAGMA synchronous").Scan(&level); err != nil || level != 0 {
+		t.Fatalf("PRAGMA synchronous = %d, %v, want 0", level, err)
+	}
+	if _, err := db.Exec("CREATE TABLE t (id INT PRIMARY KEY)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("INSERT INTO t SELECT value FROM generate_series(1, 100)"); err != nil {
+		t.Fatal(err)
+	}
+	sqlConn, err := db.Conn(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlConn.Close()
+	err = sqlConn.Raw(func(driverConn any) error {
+		return driverConn.(interface{ Flush(context.Context) error }).Flush(context.Background())
+	})
+	if err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	direct, err := OpenDirect(filepath.Join(t.TempDir(), "direct.ddb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer direct.Close()
+	if err := direct.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush under synchronous=full: %v", err)
+	}
+	if err := direct.Flush(ctx); err != context.Canceled {
+		t.Fatalf("Flush with a canceled context = %v", err)
+	}
+
+	if _, err := sql.Open("decentdb", fmt.Sprintf("file:%s?synchronous=extra", path)); err == nil {
+		t.Fatal("expected synchronous=extra to be rejected")
+	}
+}
@@ -0,0 +1,386 @@
+package decentdb
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dsnConfig is a parsed local DSN: a plain path, a file: URL, or :memory:.
+type dsnConfig struct {
+	path string
+	// mode is "create", "open", or empty for open-or-create.
+	mode string
+	// rawOptions is the options= value, passed to the engine verbatim
+	// ahead of the options set by individual DSN keys.
+	rawOptions string
+	native     map[string]string
+
+	useWriteQueue         bool
+	queueDefaultTimeoutMs *uint64
+	// rawValues and errorParams are nil unless the DSN sets them, so
+	// connector options apply by default.
+	rawValues         *bool
+	errorParams       *bool
+	shareEngine       bool
+	closeDrainTimeout time.Duration
+	pool              string
+	resultCacheSize   int
+	debugLeaks        bool
+	applicationName   *string
+}
+
+// options returns the native open option list for the engine. Keys are
+// emitted in a fixed order so equal DSNs share one engine under
+// shared_engine=true.
+func (cfg *dsnConfig) options() string {
+	options := cfg.rawOptions
+	keys := make([]string, 0, len(cfg.native))
+	for key := range cfg.native {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		options = appendOption(options, key, cfg.native[key])
+	}
+	return options
+}
+
+// dsnOption applies the value of one DSN key to cfg.
+type dsnOption func(cfg *dsnConfig, key, value string) error
+
+// dsnOptions lists every key a local DSN accepts.
+var dsnOptions = map[string]dsnOption{
+	"mode": func(cfg *dsnConfig, _, value string) error {
+		switch value {
+		case "create", "open":
+			cfg.mode = value
+		case "open_or_create":
+			cfg.mode = ""
+		default:
+			return fmt.Errorf("expected create, open, or open_or_create")
+		}
+		return nil
+	},
+	"options": func(cfg *dsnConfig, _, value string) error {
+		cfg.rawOptions = value
+		return nil
+	},
+	"pool": func(cfg *dsnConfig, _, value string) error {
+		mode := strings.ToLower(strings.TrimSpace(value))
+		if mode != "" && mode != poolModeSingleWriter {
+			return fmt.Errorf("expected %q", poolModeSingleWriter)
+		}
+		cfg.pool = mode
+		return nil
+	},
+	"result_cache_size": func(cfg *dsnConfig, _, value string) error {
+		size, err := strconv.Atoi(value)
+		if err != nil || size < 0 {
+			return fmt.Errorf("expected a non-negative integer")
+		}
+		cfg.resultCacheSize = size
+		return nil
+	},
+	"debug_leaks": func(cfg *dsnConfig, _, value string) (err error) {
+		cfg.debugLeaks, err = strconv.ParseBool(value)
+		return err
+	},
+	"raw_values": func(cfg *dsnConfig, _, value string) error {
+		parsed, err := strconv.ParseBool(value)
+		cfg.rawValues = &parsed
+		return err
+	},
+	"application_name": func(cfg *dsnConfig, _, value string) error {
+		cfg.applicationName = &value
+		return nil
+	},
+	"error_params": func(cfg *dsnConfig, _, value string) error {
+		parsed, err := strconv.ParseBool(value)
+		cfg.errorParams = &parsed
+		return err
+	},
+	"shared_engine": func(cfg *dsnConfig, _, value string) (err error) {
+		cfg.shareEngine, err = strconv.ParseBool(value)
+		return err
+	},
+	"close_drain_timeout_ms": func(cfg *dsnConfig, _, value string) error {
+		ms, err := strconv.ParseUint(value, 10, 32)
+		cfg.closeDrainTimeout = time.Duration(ms) * time.Millisecond
+		return err
+	},
+
+	"write_queue_enabled": func(cfg *dsnConfig, key, value string) error {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		cfg.useWriteQueue = cfg.useWriteQueue || enabled
+		cfg.native[key] = strconv.FormatBool(enabled)
+		return nil
+	},
+	"write_queue_capacity": writeQueueUint,
+	"write_queue_default_timeout_ms": func(cfg *dsnConfig, key, value string) error {
+		if err := writeQueueUint(cfg, key, value); err != nil {
+			return err
+		}
+		timeout, _ := strconv.ParseUint(value, 10, 64)
+		cfg.queueDefaultTimeoutMs = &timeout
+		return nil
+	},
+	"write_queue_group_commit": func(cfg *dsnConfig, key, value string) error {
+		if err := nativeBool(cfg, key, value); err != nil {
+			return err
+		}
+		cfg.useWriteQueue = true
+		return nil
+	},
+	"write_queue_max_batch":          writeQueueUint,
+	"write_queue_max_group_delay_us": writeQueueUint,
+
+	"process_coordination": func(cfg *dsnConfig, key, value string) error {
+		mode, err := normalizeProcessCoordination(value)
+		if err != nil {
+			return err
+		}
+		cfg.native[key] = mode
+		return nil
+	},
+	"process_coordination_timeout_ms": nativeUint(64),
+	"auto_analyze_min_rows":           nativeUint(64),
+	"auto_analyze_churn_percent":      nativeUint(32),
+	"max_parallel_workers":            nativeUint(64),
+	"read_ahead_pages":                nativeUint(64),
+	"statement_memory_limit_bytes":    nativeUint(64),
+	"max_result_rows":                 nativeUint(64),
+	"max_statement_seconds":           nativeUint(64),
+	"foreign_keys":                    nativeOnOff,
+	"verify_checksums":                nativeOnOff,
+	"max_database_size_bytes":         nativeUint(64),
+	"defensive":                       nativeBool,
+	"plan_cache_enabled":              nativeBool,
+	"plan_cache_max_bytes":            nativeUint(64),
+	"statement_stats":                 nativeBool,
+	"statement_stats_max": func(cfg *dsnConfig, key, value string) error {
+		if n, err := strconv.ParseUint(value, 10, 32); err != nil || n == 0 {
+			return fmt.Errorf("expected a positive integer")
+		}
+		cfg.native[key] = value
+		return nil
+	},
+	"checkpoint_on_close":   nativeBool,
+	"truncate_wal_on_close": nativeBool,
+	"vfs": func(cfg *dsnConfig, key, value string) error {
+		if value == "" || strings.ContainsAny(value, " ,;=") {
+			return fmt.Errorf("expected a registered VFS name")
+		}
+		cfg.native[key] = value
+		return nil
+	},
+	"attach": func(*dsnConfig, string, string) error {
+		// Recognized so the error explains the gap instead of suggesting
+		// another key.
+		return errors.New("the engine cannot attach other database files; open a separate *sql.DB for each file")
+	},
+	"synchronous": func(cfg *dsnConfig, key, value string) error {
+		level := strings.ToLower(value)
+		if level != "off" && level != "normal" && level != "full" {
+			return fmt.Errorf("expected off, normal, or full")
+		}
+		cfg.native[key] = level
+		return nil
+	},
+	"default_collation": func(cfg *dsnConfig, key, value string) error {
+		collation := strings.ToLower(value)
+		if collation != "binary" && collation != "nocase" && collation != "rtrim" {
+			return fmt.Errorf("expected binary, nocase, or rtrim")
+		}
+		cfg.native[key] = collation
+		return nil
+	},
+	"temp_store": func(cfg *dsnConfig, key, value string) error {
+		store := strings.ToLower(value)
+		if store != "memory" && store != "file" {
+			return fmt.Errorf("expected memory or file")
+		}
+		cfg.native[key] = store
+		return nil
+	},
+	"temp_dir": func(cfg *dsnConfig, key, value string) error {
+		if value == "" || strings.ContainsAny(value, " \t\r\n,;") {
+			return fmt.Errorf("expected a directory path without spaces, commas, or semicolons")
+		}
+		cfg.native[key] = value
+		return nil
+	},
+	"cache_size":                     nativeText,
+	"profile":                        nativeText,
+	"wal_checkpoint_threshold_pages": nativeUint(64),
+	"wal_checkpoint_threshold_bytes": nativeUint(64),
+}
+
+func nativeBool(cfg *dsnConfig, key, value string) error {
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		return err
+	}
+	cfg.native[key] = strconv.FormatBool(enabled)
+	return nil
+}
+
+func nativeOnOff(cfg *dsnConfig, key, value string) error {
+	enabled, err := parseOnOff(value)
+	if err != nil {
+		return err
+	}
+	cfg.native[key] = strconv.FormatBool(enabled)
+	return nil
+}
+
+func nativeUint(bits int) dsnOption {
+	return func(cfg *dsnConfig, key, value string) error {
+		if _, err := strconv.ParseUint(value, 10, bits); err != nil {
+			return err
+		}
+		cfg.native[key] = value
+		return nil
+	}
+}
+
+// nativeText passes a value the engine validates itself, such as "64MB".
+func nativeText(cfg *dsnConfig, key, value string) error {
+	if value == "" || strings.ContainsAny(value, " ;") {
+		return fmt.Errorf("expected a single word")
+	}
+	cfg.native[key] = value
+	return nil
+}
+
+func writeQueueUint(cfg *dsnConfig, key, value string) error {
+	if err := nativeUint(64)(cfg, key, value); err != nil {
+		return err
+	}
+	cfg.useWriteQueue = true
+	return nil
+}
+
+// parseDSN parses a local DSN. It accepts
+//
+//   - :memory:
+//   - plain paths, relative or absolute, including Windows paths such as
+//     C:\data\app.ddb, taken literally up to the first ?
+//   - file: URLs: file:/abs/app.ddb, file:///abs/app.ddb,
+//     file://localhost/abs/app.ddb, file:rel/app.ddb, file:C:/data/app.ddb,
+//     and file:///C:/data/app.ddb, with %XX escapes decoded
+//
+// followed by ?key=value options. Unknown keys, repeated keys, and invalid
+// values are errors.
+func parseDSN(dsn string) (*dsnConfig, error) {
+	if dsn == "" {
+		return nil, fmt.Errorf("decentdb: empty DSN")
+	}
+	cfg := &dsnConfig{native: make(map[string]string)}
+	var rawQuery string
+	if strings.HasPrefix(dsn, "file:") {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return nil, fmt.Errorf("decentdb: invalid DSN %q: %w", dsn, err)
+		}
+		if u.Fragment != "" {
+			return nil, fmt.Errorf("decentdb: invalid DSN %q: unexpected #%s", dsn, u.Fragment)
+		}
+		rawQuery = u.RawQuery
+		if u.Opaque != "" {
+			// file:rel/app.ddb and file:C:/data/app.ddb have no slash after
+			// the scheme, so url.Parse leaves the path undecoded.
+			cfg.path, err = url.PathUnescape(u.Opaque)
+			if err != nil {
+				return nil, fmt.Errorf("decentdb: invalid DSN %q: %w", dsn, err)
+			}
+		} else {
+			if u.Host != "" && !strings.EqualFold(u.Host, "localhost") {
+				return nil, fmt.Errorf("decentdb: DSN %q names host %q: file: DSNs must name a local path, as in file:///path/to/app.ddb", dsn, u.Host)
+			}
+			cfg.path = u.Path
+			if len(cfg.path) > 1 && cfg.path[0] == '/' && isWindowsDrivePath(cfg.path[1:]) {
+				cfg.path = cfg.path[1:]
+			}
+		}
+	} else {
+		cfg.path, rawQuery, _ = strings.Cut(dsn, "?")
+	}
+	if cfg.path == "" {
+		return nil, fmt.Errorf("decentdb: DSN %q has no database path", dsn)
+	}
+
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return nil, fmt.Errorf("decentdb: invalid options in DSN %q: %w", dsn, err)
+	}
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		apply, ok := dsnOptions[key]
+		if !ok {
+			if suggestion := closestDSNOption(key); suggestion != "" {
+				return nil, fmt.Errorf("decentdb: unknown DSN option %q (did you mean %q?)", key, suggestion)
+			}
+			return nil, fmt.Errorf("decentdb: unknown DSN option %q", key)
+		}
+		values := query[key]
+		if len(values) > 1 {
+			return nil, fmt.Errorf("decentdb: DSN option %q is given %d times", key, len(values))
+		}
+		if err := apply(cfg, key, values[0]); err != nil {
+			return nil, fmt.Errorf("invalid %s value %q: %w", key, values[0], err)
+		}
+	}
+	return cfg, nil
+}
+
+// isWindowsDrivePath reports whether path starts with a drive letter, as in
+// C:\data or C:/data.
+func isWindowsDrivePath(path string) bool {
+	return len(path) >= 3 && path[1] == ':' && (path[2] == '\\' || path[2] == '/') &&
+		('a' <= path[0] && path[0] <= 'z' || 'A' <= path[0] && path[0] <= 'Z')
+}
+
+// closestDSNOption returns the known option nearest to key by edit distance,
+// or "" if none is close enough to be a likely typo.
+func closestDSNOption(key string) string {
+	best, bestDistance := "", 3
+	for known := range dsnOptions {
+		if d := editDistance(strings.ToLower(key), known); d < bestDistance || d == bestDistance && known < best {
+			best, bestDistance = known, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,112 @@
+package decentdb
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDSNPaths(t *testing.T) {
+	cases := map[string]string{
+		":memory:":                          ":memory:",
+		":memory:?mode=create":              ":memory:",
+		"file::memory:":                     ":memory:",
+		"/tmp/app.ddb":                      "/tmp/app.ddb",
+		"/tmp/app.ddb?mode=open":            "/tmp/app.ddb",
+		"data/app.ddb":                      "data/app.ddb",
+		"./app.ddb":                         "./app.ddb",
+		"/tmp/100%.ddb":                     "/tmp/100%.ddb",
+		`C:\data\app.ddb`:                   `C:\data\app.ddb`,
+		`C:\data\app.ddb?mode=open`:         `C:\data\app.ddb`,
+		"file:/tmp/app.ddb":                 "/tmp/app.ddb",
+		"file:///tmp/app.ddb":               "/tmp/app.ddb",
+		"file://localhost/tmp/app.ddb":      "/tmp/app.ddb",
+		"file:data/app.ddb":                 "data/app.ddb",
+		"file:/tmp/my%20app.ddb":            "/tmp/my app.ddb",
+		"file:data/my%20app.ddb?mode=open":  "data/my app.ddb",
+		"file:/tmp/caf%C3%A9.ddb":           "/tmp/café.ddb",
+		"file:C:/data/app.ddb":              "C:/data/app.ddb",
+		"file:///C:/data/app.ddb":           "C:/data/app.ddb",
+		"file:///c:/data/app.ddb?mode=open": "c:/data/app.ddb",
+	}
+	for dsn, want := range cases {
+		cfg, err := parseDSN(dsn)
+		if err != nil {
+			t.Errorf("parseDSN(%q): %v", dsn, err)
+			continue
+		}
+		if cfg.path != want {
+			t.Errorf("parseDSN(%q).path = %q, want %q", dsn, cfg.path, want)
+		}
+	}
+}
+
+func TestParseDSNOptions(t *testing.T) {
+	cfg, err := parseDSN("file:/tmp/app.ddb?mode=open&write_queue_capacity=64&write_queue_default_timeout_ms=500" +
+		"&foreign_keys=on&defensive=1&process_coordination=off&cache_size=64MB&options=profile%3Dembedded_fast" +
+		"&raw_values=true&shared_engine=true&close_drain_timeout_ms=250&pool=singlewriter&result_cache_size=8&debug_leaks=true" +
+		"&synchronous=NORMAL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.mode != "open" || cfg.pool != poolModeSingleWriter || cfg.resultCacheSize != 8 || !cfg.debugLeaks {
+		t.Fatalf("unexpected connector settings: %+v", cfg)
+	}
+	if !cfg.useWriteQueue || cfg.queueDefaultTimeoutMs == nil || *cfg.queueDefaultTimeoutMs != 500 {
+		t.Fatalf("unexpected write queue settings: %+v", cfg)
+	}
+	if cfg.rawValues == nil || !*cfg.rawValues || cfg.errorParams != nil {
+		t.Fatalf("unexpected scan settings: %+v", cfg)
+	}
+	if !cfg.shareEngine || cfg.closeDrainTimeout != 250*time.Millisecond {
+		t.Fatalf("unexpected connection settings: %+v", cfg)
+	}
+	want := "profile=embedded_fast cache_size=64MB defensive=true foreign_keys=true " +
+		"process_coordination=single_process_unsafe synchronous=normal write_queue_capacity=64 write_queue_default_timeout_ms=500"
+	if got := cfg.options(); got != want {
+		t.Fatalf("options() = %q, want %q", got, want)
+	}
+
+	cfg, err = parseDSN("file:/tmp/app.ddb?mode=open_or_create&write_queue_enabled=false")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.mode != "" || cfg.useWriteQueue || cfg.options() != "write_queue_enabled=false" {
+		t.Fatalf("unexpected settings: %+v", cfg)
+	}
+}
+
+func TestParseDSNErrors(t *testing.T) {
+	cases := map[string]string{
+		"":                                                  "empty DSN",
+		"file:":                                             "no database path",
+		"?mode=open":                                        "no database path",
+		"file://server/share/app.ddb":                       `names host "server"`,
+		"file:/tmp/app.ddb#frag":                            "unexpected #frag",
+		"file:/tmp/bad%zz.ddb":                              "invalid DSN",
+		"file:/tmp/app.ddb?mode=readonly":                   "invalid mode value",
+		"file:/tmp/app.ddb?mode=open&mode=open":             "given 2 times",
+		"file:/tmp/app.ddb?fooreign_keys=on":                `unknown DSN option "fooreign_keys" (did you mean "foreign_keys"?)`,
+		"file:/tmp/app.ddb?Mode=open":                       `(did you mean "mode"?)`,
+		"file:/tmp/app.ddb?nonsense=1":                      `unknown DSN option "nonsense"`,
+		"file:/tmp/app.ddb?write_queue_max_batch=-1":        "invalid write_queue_max_batch value",
+		"file:/tmp/app.ddb?vfs=a,b":                         "invalid vfs value",
+		"file:/tmp/app.ddb?cache_size=64%20MB":              "invalid cache_size value",
+		"file:/tmp/app.ddb?synchronous=extra":               "invalid synchronous value",
+		"file:/tmp/app.ddb?default_collation=icu":           "invalid default_collation value",
+		"file:/tmp/app.ddb?temp_store=disk":                 "invalid temp_store value",
+		"file:/tmp/app.ddb?temp_dir=/tmp/a,b":               "invalid temp_dir value",
+		"/tmp/app.ddb?statement_stats_max=0":                "invalid statement_stats_max value",
+		"/tmp/app.ddb?attach=analytics:/data/analytics.ddb": "cannot attach other database files",
+		"/tmp/app.ddb?a=%zz":                                "invalid options",
+	}
+	for dsn, want := range cases {
+		_, err := parseDSN(dsn)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("parseDSN(%q) = %v, want an error containing %q", dsn, err, want)
+		}
+	}
+	if _, err := parseDSN("file:/tmp/app.ddb?nonsense=1"); strings.Contains(err.Error(), "did you mean") {
+		t.Errorf("unrelated option got a suggestion: %v", err)
+	}
+}
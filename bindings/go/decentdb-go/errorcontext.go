@@ -0,0 +1,77 @@
+package decentdb
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// WithErrorParams attaches bound argument values to statement errors as
+// DecentDBError.Params, and to their log output. Values may hold personal
+// data, so by default errors carry only the arguments' types. The
+// error_params=true DSN option does the same for sql.Open.
+func WithErrorParams() ConnectorOption {
+	return func(c *connector) {
+		c.errorParams = true
+	}
+}
+
+// annotateError describes the statement behind the DecentDBError in err: the
+// fingerprint of query, the types of args, and their values when the
+// connector opted in. err is returned as is, so any wrapping survives.
+func (c *conn) annotateError(err error, query string, args []driver.NamedValue) error {
+	var dbErr *DecentDBError
+	if !errors.As(err, &dbErr) || dbErr.Fingerprint != "" {
+		return err
+	}
+	if fingerprint, normErr := NormalizeQuery(query); normErr == nil {
+		dbErr.Fingerprint = fingerprint
+	}
+	dbErr.ParamTypes = make([]string, len(args))
+	for i, arg := range args {
+		dbErr.ParamTypes[i] = paramTypeName(arg.Value)
+	}
+	if c.errorParams {
+		dbErr.Params = make([]any, len(args))
+		for i, arg := range args {
+			dbErr.Params[i] = arg.Value
+		}
+	}
+	return err
+}
+
+// paramTypeName names the Go type of a bound value, or "null" for nil.
+func paramTypeName(v any) string {
+	if v == nil {
+		return "null"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// LogValue implements slog.LogValuer. It logs the error's code, message,
+// fingerprint, and parameter types, but never the raw SQL, whose literals
+// may hold personal data; parameter values appear only when the connector
+// opted in with WithErrorParams.
+func (e *DecentDBError) LogValue() slog.Value {
+	attrs := []slog.Attr{
+		slog.Int("code", e.Code),
+		slog.String("message", e.Message),
+	}
+	if e.CodeName != "" {
+		attrs = append(attrs, slog.String("code_name", e.CodeName))
+	}
+	if e.SQLState != "" {
+		attrs = append(attrs, slog.String("sqlstate", e.SQLState))
+	}
+	if e.Fingerprint != "" {
+		attrs = append(attrs, slog.String("fingerprint", e.Fingerprint))
+	}
+	if e.ParamTypes != nil {
+		attrs = append(attrs, slog.Any("param_types", e.ParamTypes))
+	}
+	if e.Params != nil {
+		attrs = append(attrs, slog.Any("params", e.Params))
+	}
+	return slog.GroupValue(attrs...)
+}
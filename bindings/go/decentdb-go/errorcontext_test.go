@@ -0,0 +1,86 @@
+package decentdb
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStatementErrorContext(t *testing.T) {
+	dir := t.TempDir()
+	for _, tc := range []struct {
+		name       string
+		dsn        string
+		wantParams []any
+	}{
+		{name: "redacted", dsn: "file:" + filepath.Join(dir, "redacted.ddb")},
+		{name: "params", dsn: "file:" + filepath.Join(dir, "params.ddb") + "?error_params=true", wantParams: []any{int64(1), "ada@example.com"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			db, err := sql.Open("decentdb", tc.dsn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			if _, err := db.Exec("CREATE TABLE users (id INT64 PRIMARY KEY, email TEXT)"); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := db.Exec("INSERT INTO users VALUES ($1, $2)", 1, "ada@example.com"); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = db.Exec("INSERT INTO users VALUES ($1, $2)", 1, "ada@example.com")
+			wrapped := fmt.Errorf("saving user: %w", err)
+			var dbErr *DecentDBError
+			if !errors.As(wrapped, &dbErr) {
+				t.Fatalf("duplicate insert returned %v, want a DecentDBError", err)
+			}
+			if dbErr.Fingerprint != "insert into users values ($1, $2)" {
+				t.Fatalf("Fingerprint = %q", dbErr.Fingerprint)
+			}
+			if !reflect.DeepEqual(dbErr.ParamTypes, []string{"int64", "string"}) {
+				t.Fatalf("ParamTypes = %v", dbErr.ParamTypes)
+			}
+			if !reflect.DeepEqual(dbErr.Params, tc.wantParams) {
+				t.Fatalf("Params = %v, want %v", dbErr.Params, tc.wantParams)
+			}
+
+			var buf bytes.Buffer
+			slog.New(slog.NewJSONHandler(&buf, nil)).Error("insert failed", "error", dbErr)
+			logged := buf.String()
+			if !strings.Contains(logged, `"fingerprint":"insert into users values ($1, $2)"`) {
+				t.Fatalf("log output lacks the fingerprint: %s", logged)
+			}
+			if strings.Contains(logged, "ada@example.com") != (tc.wantParams != nil) {
+				t.Fatalf("log output has unexpected parameter values: %s", logged)
+			}
+		})
+	}
+}
+
+func TestStatementErrorContextRedactsLiterals(t *testing.T) {
+	db, err := sql.Open("decentdb", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	_, err = db.QueryContext(context.Background(), "SELECT * FROM missing WHERE email = 'ada@example.com' AND id = $1", nil)
+	var dbErr *DecentDBError
+	if !errors.As(err, &dbErr) {
+		t.Fatalf("query returned %v, want a DecentDBError", err)
+	}
+	if strings.Contains(dbErr.Fingerprint, "ada@example.com") || !strings.Contains(dbErr.Fingerprint, "email = ?") {
+		t.Fatalf("Fingerprint = %q", dbErr.Fingerprint)
+	}
+	if !reflect.DeepEqual(dbErr.ParamTypes, []string{"null"}) {
+		t.Fatalf("ParamTypes = %v", dbErr.ParamTypes)
+	}
+}
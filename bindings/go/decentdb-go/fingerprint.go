@@ -0,0 +1,27 @@
+package decentdb
+
+/*
+#include "decentdb.h"
+#include <stdlib.h>
+*/
+import "C"
+import "unsafe"
+
+// NormalizeQuery returns the fingerprint of query: the statement with its
+// literals replaced by ?, comments dropped, whitespace and the case of
+// unquoted words normalized, and IN lists of constants collapsed to
+// "in (...)". Executions that differ only in constants share a fingerprint,
+// which makes it a bounded label for metrics and the key the engine's slow
+// query log aggregates by. Parameters such as $1 are kept. It fails only on
+// unterminated quotes or comments.
+func NormalizeQuery(query string) (string, error) {
+	cQuery := C.CString(query)
+	defer C.free(unsafe.Pointer(cQuery))
+	var ptr *C.char
+	status := C.ddb_normalize_query(cQuery, &ptr)
+	if status != C.DDB_OK {
+		return "", statusError(status, query)
+	}
+	defer freeAPIString(ptr)
+	return C.GoString(ptr), nil
+}
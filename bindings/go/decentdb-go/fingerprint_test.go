@@ -0,0 +1,34 @@
+package decentdb
+
+import "testing"
+
+func TestNormalizeQuery(t *testing.T) {
+	for _, tc := range []struct{ a, b string }{
+		{"SELECT * FROM users WHERE id = 1", "select *\n  from Users -- by key\n where id=42;"},
+		{"SELECT * FROM t WHERE id IN (1, 2, 3)", "SELECT * FROM t WHERE id IN ($1)"},
+		{"UPDATE t SET name = 'a' WHERE id = -1", "update t set name = E'b\\'c' where id = 7"},
+	} {
+		fa, err := NormalizeQuery(tc.a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fb, err := NormalizeQuery(tc.b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fa != fb {
+			t.Errorf("fingerprints differ:\n%q -> %q\n%q -> %q", tc.a, fa, tc.b, fb)
+		}
+	}
+
+	got, err := NormalizeQuery(`SELECT "Name" FROM t WHERE a = $1 AND b = 'secret'`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `select "Name" from t where a = $1 and b = ?`; got != want {
+		t.Fatalf("NormalizeQuery = %q, want %q", got, want)
+	}
+	if _, err := NormalizeQuery("SELECT 'unterminated"); err == nil {
+		t.Fatal("expected an unterminated string to fail")
+	}
+}
@@ -0,0 +1,95 @@
+package decentdb
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync/atomic"
+)
+
+// ForeignKeyViolation is a row whose foreign key references a missing parent
+// row, as reported by CheckForeignKeys.
+type ForeignKeyViolation struct {
+	Table  string
+	RowID  int64
+	Parent string
+	// FKIndex is the violated constraint's id in PRAGMA foreign_key_list.
+	FKIndex int
+}
+
+// parseOnOff parses the boolean spellings the engine accepts for
+// foreign_keys: on/off, true/false, yes/no, and 1/0.
+func parseOnOff(value string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "1", "on", "true", "yes":
+		return true, nil
+	case "0", "off", "false", "no":
+		return false, nil
+	}
+	return false, fmt.Errorf("expected on or off")
+}
+
+// SetForeignKeysEnabled turns foreign key enforcement on or off for writes
+// through this connection, overriding the foreign_keys DSN option.
+func (c *conn) SetForeignKeysEnabled(enabled bool) error {
+	if c.db == nil {
+		return driver.ErrBadConn
+	}
+	if enabled {
+		return c.execSessionSQL("PRAGMA foreign_keys = ON")
+	}
+	return c.execSessionSQL("PRAGMA foreign_keys = OFF")
+}
+
+// CheckForeignKeys lists the rows whose foreign keys reference a missing
+// parent row.
+func (c *conn) CheckForeignKeys() ([]ForeignKeyViolation, error) {
+	rows, err := c.QueryContext(context.Background(), "PRAGMA foreign_key_check", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var violations []ForeignKeyViolation
+	dest := make([]driver.Value, 4)
+	for {
+		if err := rows.Next(dest); err != nil {
+			if err == io.EOF {
+				return violations, nil
+			}
+			return nil, err
+		}
+		table, _ := dest[0].(string)
+		rowID, _ := dest[1].(int64)
+		parent, _ := dest[2].(string)
+		fkIndex, _ := dest[3].(int64)
+		violations = append(violations, ForeignKeyViolation{
+			Table:   table,
+			RowID:   rowID,
+			Parent:  parent,
+			FKIndex: int(fkIndex),
+		})
+	}
+}
+
+// SetForeignKeysEnabled turns foreign key enforcement on or off for writes
+// through this handle. Bulk loads can turn it off, load tables in any order,
+// and validate with CheckForeignKeys before turning it back on. While it is
+// off, child rows are not checked and ON DELETE / ON UPDATE actions do not
+// run.
+func (d *DB) SetForeignKeysEnabled(enabled bool) error {
+	if atomic.LoadUint32(&d.closed) != 0 {
+		return driver.ErrBadConn
+	}
+	return d.c.SetForeignKeysEnabled(enabled)
+}
+
+// CheckForeignKeys lists the rows whose foreign keys reference a missing
+// parent row. An empty result means every foreign key holds.
+func (d *DB) CheckForeignKeys() ([]ForeignKeyViolation, error) {
+	if atomic.LoadUint32(&d.closed) != 0 {
+		return nil, driver.ErrBadConn
+	}
+	return d.c.CheckForeignKeys()
+}
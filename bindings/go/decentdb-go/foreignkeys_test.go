@@ -0,0 +1,88 @@
+package decentdb
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseOnOff(t *testing.T) {
+	for value, want := range map[string]bool{"on": true, "OFF": false, "1": true, "false": false} {
+		got, err := parseOnOff(value)
+		if err != nil || got != want {
+			t.Fatalf("parseOnOff(%q) = %v, %v; want %v", value, got, err, want)
+		}
+	}
+	if _, err := parseOnOff("sometimes"); err == nil {
+		t.Fatal("expected invalid value to fail")
+	}
+}
+
+func TestOpenDirect_ForeignKeysToggleAndCheck(t *testing.T) {
+	db, err := OpenDirect(filepath.Join(t.TempDir(), "fk.ddb"))
+	if err != nil {
+		t.Fatalf("OpenDirect failed: %v", err)
+	}
+	defer db.Close()
+
+	for _, stmt := range []string{
+		"CREATE TABLE parent (id INT PRIMARY KEY)",
+		"CREATE TABLE child (id INT PRIMARY KEY, parent_id INT REFERENCES parent(id))",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := db.Exec("INSERT INTO child VALUES ($1, $2)", int64(1), int64(7)); err == nil {
+		t.Fatal("expected foreign key violation while enforcement is on")
+	}
+
+	if err := db.SetForeignKeysEnabled(false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("INSERT INTO child VALUES ($1, $2)", int64(1), int64(7)); err != nil {
+		t.Fatalf("insert with enforcement off: %v", err)
+	}
+	violations, err := db.CheckForeignKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(violations) != 1 || violations[0].Table != "child" || violations[0].Parent != "parent" || violations[0].FKIndex != 0 {
+		t.Fatalf("unexpected violations: %+v", violations)
+	}
+
+	if _, err := db.Exec("INSERT INTO parent VALUES ($1)", int64(7)); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SetForeignKeysEnabled(true); err != nil {
+		t.Fatal(err)
+	}
+	violations, err = db.CheckForeignKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(violations) != 0 {
+		t.Fatalf("expected no violations, got %+v", violations)
+	}
+}
+
+func TestForeignKeysDSNOption(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "fk_dsn.ddb")
+	db, err := sql.Open("decentdb", fmt.Sprintf("file:%s?foreign_keys=off", dbPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var enabled int64
+	if err := db.QueryRow("PRAGMA foreign_keys").Scan(&enabled); err != nil {
+		t.Fatal(err)
+	}
+	if enabled != 0 {
+		t.Fatalf("PRAGMA foreign_keys = %d, want 0", enabled)
+	}
+	if _, err := sql.Open("decentdb", fmt.Sprintf("file:%s?foreign_keys=maybe", dbPath)); err == nil {
+		t.Fatal("expected invalid foreign_keys value to fail")
+	}
+}
@@ -0,0 +1,78 @@
+package decentdb
+
+import (
+	"database/sql"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestGrouping_RollupSubtotals(t *testing.T) {
+	db, err := sql.Open("decentdb", filepath.Join(t.TempDir(), "grouping.ddb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, stmt := range []string{
+		"CREATE TABLE sales (id INT64 PRIMARY KEY, region TEXT, product TEXT, amount INT64)",
+		`INSERT INTO sales VALUES
+			(1, 'east', 'apples', 10), (2, 'east', 'pears', 20), (3, 'west', 'apples', 7),
+			(4, NULL, 'figs', 1)`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+
+	rows, err := db.Query(`SELECT region, product, SUM(amount) AS total, GROUPING(region, product) AS level
+		FROM sales
+		GROUP BY ROLLUP (region, product)
+		HAVING SUM(amount) > $1
+		ORDER BY level, region, product`, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	type line struct {
+		region, product sql.NullString
+		total           int64
+		level           int64
+	}
+	var got []line
+	for rows.Next() {
+		var l line
+		if err := rows.Scan(&l.region, &l.product, &l.total, &l.level); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, l)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	str := func(s string) sql.NullString { return sql.NullString{String: s, Valid: true} }
+	want := []line{
+		{str("east"), str("apples"), 10, 0},
+		{str("east"), str("pears"), 20, 0},
+		{str("west"), str("apples"), 7, 0},
+		{str("east"), sql.NullString{}, 30, 1},
+		{str("west"), sql.NullString{}, 7, 1},
+		{sql.NullString{}, sql.NullString{}, 38, 3},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got  %+v\nwant %+v", got, want)
+	}
+
+	// The NULL region's own group stays at level 0; only the grand total
+	// reports the region as rolled up.
+	var level int64
+	if err := db.QueryRow(`SELECT GROUPING(region) FROM sales
+		GROUP BY GROUPING SETS ((region), ())
+		HAVING region IS NULL AND COUNT(*) = 1`).Scan(&level); err != nil {
+		t.Fatal(err)
+	}
+	if level != 0 {
+		t.Fatalf("NULL region group reported GROUPING = %d", level)
+	}
+}
@@ -0,0 +1,844 @@
+// Package grpcserver serves DecentDB databases over the gRPC service defined
+// in remote/decentdb.proto. The remote package is its database/sql client:
+//
+//	srv := grpcserver.New(map[string]*sql.DB{"app": db})
+//	defer srv.Close()
+//	log.Fatal(srv.ListenAndServe(":7070"))
+//
+//	client, err := sql.Open("decentdb-remote", "decentdb://localhost:7070/app")
+//
+// Each client connection is a session pinned to one server-side *sql.Conn,
+// so prepared statements and transactions behave as they do in-process.
+// Sessions left idle past the idle timeout are closed and their open
+// transactions rolled back.
+//
+// With WithAuth, every call carries the credentials of a user in an
+// auth.Store, sessions belong to the user that opened them, and each
+// statement is checked against that user's grants on the session's
+// database.
+//
+// ListenAndServe and Serve speak HTTP/2 without TLS unless WithTLSConfig
+// is given; the tlsconfig package builds configurations that reload
+// rotated certificates.
+package grpcserver
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"crypto/tls"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	decentdb "github.com/sphildreth/decentdb-go"
+	"github.com/sphildreth/decentdb-go/auth"
+	"github.com/sphildreth/decentdb-go/internal/authz"
+	"github.com/sphildreth/decentdb-go/internal/rpcwire"
+)
+
+const (
+	defaultFetchSize   = 256
+	maxFetchSize       = 10000
+	defaultIdleTimeout = 5 * time.Minute
+)
+
+// Option configures a Server.
+type Option func(*Server)
+
+// WithBearerToken requires clients to send `authorization: Bearer <token>`
+// metadata on every call.
+func WithBearerToken(token string) Option {
+	return func(s *Server) {
+		s.bearerToken = token
+	}
+}
+
+// WithAuth authenticates every call against store, with an API token sent
+// as `authorization: Bearer <token>` or a user's password sent as HTTP
+// basic credentials. It replaces WithBearerToken. Serve over TLS when
+// clients send passwords.
+func WithAuth(store *auth.Store) Option {
+	return func(s *Server) {
+		s.store = store
+	}
+}
+
+// WithTLSConfig makes ListenAndServe and Serve speak HTTP/2 over TLS with
+// cfg. Set cfg.ClientAuth to require client certificates.
+func WithTLSConfig(cfg *tls.Config) Option {
+	return func(s *Server) {
+		s.tlsConfig = cfg
+	}
+}
+
+// WithReadOnly rejects Execute calls and runs every session read-only.
+func WithReadOnly() Option {
+	return func(s *Server) {
+		s.readOnly = true
+	}
+}
+
+// WithIdleTimeout sets how long a session may sit idle before it is closed.
+// The default is five minutes.
+func WithIdleTimeout(d time.Duration) Option {
+	return func(s *Server) {
+		if d > 0 {
+			s.idleTimeout = d
+		}
+	}
+}
+
+// Server is an http.Handler implementing the DecentDB gRPC service.
+type Server struct {
+	dbs         map[string]*sql.DB
+	bearerToken string
+	store       *auth.Store
+	tlsConfig   *tls.Config
+	readOnly    bool
+	idleTimeout time.Duration
+
+	mu       sync.Mutex
+	sessions map[string]*session
+	servers  map[*http.Server]struct{}
+	closed   bool
+}
+
+type session struct {
+	mu       sync.Mutex
+	database string
+	// principal is the user that opened the session, nil without an
+	// auth store.
+	principal *auth.Principal
+	conn      *sql.Conn
+	tx        *sql.Tx
+	stmts     map[string]*statement
+	lastUsed  time.Time
+	// streams counts Fetch streams in flight; the session is not idle
+	// while any are open.
+	streams int
+}
+
+type statement struct {
+	query string
+	stmt  *sql.Stmt
+}
+
+// New returns a Server for the named databases. Clients select one with
+// the path of their DSN; when only one database is registered, an empty
+// name selects it. The caller keeps ownership of the databases; call Close
+// before closing them.
+func New(dbs map[string]*sql.DB, opts ...Option) *Server {
+	s := &Server{
+		dbs:         dbs,
+		idleTimeout: defaultIdleTimeout,
+		sessions:    make(map[string]*session),
+		servers:     make(map[*http.Server]struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// ListenAndServe listens on the TCP address addr and serves HTTP/2 until
+// Close.
+func (s *Server) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve serves HTTP/2 on l until Close, which makes it return
+// http.ErrServerClosed. Connections use TLS when WithTLSConfig is given.
+func (s *Server) Serve(l net.Listener) error {
+	hs := &http.Server{Handler: s, Protocols: new(http.Protocols), TLSConfig: s.tlsConfig}
+	if s.tlsConfig != nil {
+		hs.Protocols.SetHTTP2(true)
+	} else {
+		hs.Protocols.SetUnencryptedHTTP2(true)
+	}
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		l.Close()
+		return http.ErrServerClosed
+	}
+	s.servers[hs] = struct{}{}
+	s.mu.Unlock()
+	if s.tlsConfig != nil {
+		return hs.ServeTLS(l, "", "")
+	}
+	return hs.Serve(l)
+}
+
+// Close stops servers started by Serve and closes every session, rolling
+// back open transactions.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	servers := s.servers
+	s.servers = make(map[*http.Server]struct{})
+	sessions := s.sessions
+	s.sessions = make(map[string]*session)
+	s.mu.Unlock()
+
+	var errs []error
+	for hs := range servers {
+		if err := hs.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, sess := range sessions {
+		sess.mu.Lock()
+		if err := sess.close(); err != nil {
+			errs = append(errs, err)
+		}
+		sess.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || !strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+		http.Error(w, "gRPC requests only", http.StatusUnsupportedMediaType)
+		return
+	}
+	w.Header().Set("Content-Type", rpcwire.ContentType)
+	w.Header().Set("Trailer", strings.Join([]string{
+		rpcwire.TrailerStatus, rpcwire.TrailerMessage,
+		rpcwire.TrailerSQLState, rpcwire.TrailerNativeCode, rpcwire.TrailerSubcode,
+	}, ", "))
+	principal, err := s.authenticate(r)
+	if err != nil {
+		writeStatus(w, err)
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), principalKey{}, principal)
+	if timeout, ok := parseTimeout(r.Header.Get("Grpc-Timeout")); ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	switch r.URL.Path {
+	case rpcwire.MethodConnect:
+		unary(w, r, new(rpcwire.ConnectRequest), func(req *rpcwire.ConnectRequest) (rpcwire.Message, error) {
+			return s.connect(ctx, req)
+		})
+	case rpcwire.MethodDisconnect:
+		unary(w, r, new(rpcwire.SessionRequest), func(req *rpcwire.SessionRequest) (rpcwire.Message, error) {
+			return s.disconnect(ctx, req)
+		})
+	case rpcwire.MethodPrepare:
+		unary(w, r, new(rpcwire.PrepareRequest), func(req *rpcwire.PrepareRequest) (rpcwire.Message, error) {
+			return s.prepare(ctx, req)
+		})
+	case rpcwire.MethodCloseStatement:
+		unary(w, r, new(rpcwire.StatementRequest), func(req *rpcwire.StatementRequest) (rpcwire.Message, error) {
+			return s.closeStatement(ctx, req)
+		})
+	case rpcwire.MethodExecute:
+		unary(w, r, new(rpcwire.ExecuteRequest), func(req *rpcwire.ExecuteRequest) (rpcwire.Message, error) {
+			return s.execute(ctx, req)
+		})
+	case rpcwire.MethodFetch:
+		s.fetch(ctx, w, r)
+	case rpcwire.MethodBegin:
+		unary(w, r, new(rpcwire.BeginRequest), func(req *rpcwire.BeginRequest) (rpcwire.Message, error) {
+			return s.begin(ctx, req)
+		})
+	case rpcwire.MethodCommit:
+		unary(w, r, new(rpcwire.SessionRequest), func(req *rpcwire.SessionRequest) (rpcwire.Message, error) {
+			return s.endTx(ctx, req, true)
+		})
+	case rpcwire.MethodRollback:
+		unary(w, r, new(rpcwire.SessionRequest), func(req *rpcwire.SessionRequest) (rpcwire.Message, error) {
+			return s.endTx(ctx, req, false)
+		})
+	default:
+		writeStatus(w, &statusError{code: rpcwire.CodeUnimplemented, msg: "unknown method " + r.URL.Path})
+	}
+}
+
+type principalKey struct{}
+
+// callPrincipal returns the user a call authenticated as, nil without an
+// auth store.
+func callPrincipal(ctx context.Context) *auth.Principal {
+	p, _ := ctx.Value(principalKey{}).(*auth.Principal)
+	return p
+}
+
+// authenticate checks the call's credentials.
+func (s *Server) authenticate(r *http.Request) (*auth.Principal, error) {
+	unauthenticated := &statusError{code: rpcwire.CodeUnauthenticated, msg: "missing or invalid credentials"}
+	if s.store == nil {
+		if s.bearerToken == "" {
+			return nil, nil
+		}
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.bearerToken)) != 1 {
+			return nil, unauthenticated
+		}
+		return nil, nil
+	}
+	var p *auth.Principal
+	var err error
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		p, err = s.store.AuthenticateToken(token)
+	} else if user, password, ok := r.BasicAuth(); ok {
+		p, err = s.store.AuthenticatePassword(user, password)
+	} else {
+		return nil, unauthenticated
+	}
+	if err != nil {
+		return nil, unauthenticated
+	}
+	return p, nil
+}
+
+func (s *Server) connect(ctx context.Context, req *rpcwire.ConnectRequest) (rpcwire.Message, error) {
+	s.expire()
+	name := req.Database
+	db, ok := s.dbs[name]
+	if !ok && name == "" && len(s.dbs) == 1 {
+		for only, onlyDB := range s.dbs {
+			name, db, ok = only, onlyDB, true
+		}
+	}
+	if !ok {
+		return nil, &statusError{code: rpcwire.CodeNotFound, msg: fmt.Sprintf("database %q not found", req.Database)}
+	}
+	principal := callPrincipal(ctx)
+	if principal != nil && !principal.CanAccess(name) {
+		return nil, &statusError{code: rpcwire.CodePermissionDenied, msg: fmt.Sprintf("permission denied for database %q", name)}
+	}
+	// The session outlives this call, so its connection must not be bound
+	// to the request context.
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	id, err := newID("sess-")
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		conn.Close()
+		return nil, &statusError{code: rpcwire.CodeUnavailable, msg: "server is shutting down"}
+	}
+	s.sessions[id] = &session{
+		database:  name,
+		principal: principal,
+		conn:      conn,
+		stmts:     make(map[string]*statement),
+		lastUsed:  time.Now(),
+	}
+	s.mu.Unlock()
+	return &rpcwire.ConnectResponse{Session: id}, nil
+}
+
+func (s *Server) disconnect(ctx context.Context, req *rpcwire.SessionRequest) (rpcwire.Message, error) {
+	s.mu.Lock()
+	sess := s.sessions[req.Session]
+	if sess != nil && !sess.ownedBy(callPrincipal(ctx)) {
+		sess = nil
+	}
+	if sess != nil {
+		delete(s.sessions, req.Session)
+	}
+	s.mu.Unlock()
+	if sess == nil {
+		return &rpcwire.Empty{}, nil
+	}
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	return &rpcwire.Empty{}, sess.close()
+}
+
+func (s *Server) prepare(ctx context.Context, req *rpcwire.PrepareRequest) (rpcwire.Message, error) {
+	sess, err := s.lock(ctx, req.Session)
+	if err != nil {
+		return nil, err
+	}
+	defer sess.unlock()
+
+	resp := &rpcwire.PrepareResponse{NumParams: -1}
+	// Statement metadata is best effort: transaction control and other
+	// statements the engine cannot describe still prepare.
+	_ = sess.conn.Raw(func(dc any) error {
+		d, ok := dc.(interface {
+			StmtInfo(string) (*decentdb.StmtInfo, error)
+		})
+		if !ok {
+			return nil
+		}
+		info, err := d.StmtInfo(req.SQL)
+		if err != nil {
+			return err
+		}
+		resp.NumParams = int32(info.NumInput())
+		for _, c := range info.Columns {
+			resp.Columns = append(resp.Columns, rpcwire.Column{Name: c.Name, TypeName: c.TypeName})
+		}
+		return nil
+	})
+
+	st := &statement{query: req.SQL}
+	if sess.tx == nil {
+		if st.stmt, err = sess.conn.PrepareContext(ctx, req.SQL); err != nil {
+			return nil, err
+		}
+	}
+	if resp.Statement, err = newID("stmt-"); err != nil {
+		if st.stmt != nil {
+			st.stmt.Close()
+		}
+		return nil, err
+	}
+	sess.stmts[resp.Statement] = st
+	return resp, nil
+}
+
+func (s *Server) closeStatement(ctx context.Context, req *rpcwire.StatementRequest) (rpcwire.Message, error) {
+	sess, err := s.lock(ctx, req.Session)
+	if err != nil {
+		return nil, err
+	}
+	defer sess.unlock()
+	if st, ok := sess.stmts[req.Statement]; ok {
+		delete(sess.stmts, req.Statement)
+		if st.stmt != nil {
+			st.stmt.Close()
+		}
+	}
+	return &rpcwire.Empty{}, nil
+}
+
+func (s *Server) execute(ctx context.Context, req *rpcwire.ExecuteRequest) (rpcwire.Message, error) {
+	if s.readOnly {
+		return nil, &statusError{code: rpcwire.CodeFailedPrecondition, msg: "server is read-only"}
+	}
+	sess, err := s.lock(ctx, req.Session)
+	if err != nil {
+		return nil, err
+	}
+	defer sess.unlock()
+	args, err := bindArgs(req.Params)
+	if err != nil {
+		return nil, err
+	}
+
+	st, err := sess.statement(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := authz.Check(ctx, sess.conn, sess.principal, sess.database, sess.query(req, st)); err != nil {
+		return nil, err
+	}
+	var res sql.Result
+	switch {
+	case st != nil && st.stmt != nil && sess.tx == nil:
+		res, err = st.stmt.ExecContext(ctx, args...)
+	case sess.tx != nil:
+		res, err = sess.tx.ExecContext(ctx, sess.query(req, st), args...)
+	default:
+		res, err = sess.conn.ExecContext(ctx, sess.query(req, st), args...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	resp := &rpcwire.ExecuteResponse{}
+	if resp.RowsAffected, err = res.RowsAffected(); err != nil {
+		return nil, err
+	}
+	if id, err := res.LastInsertId(); err == nil {
+		resp.LastInsertID, resp.HasLastInsertID = id, true
+	}
+	return resp, nil
+}
+
+func (s *Server) fetch(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	var req rpcwire.ExecuteRequest
+	if err := rpcwire.ReadMessage(r.Body, &req); err != nil {
+		writeStatus(w, &statusError{code: rpcwire.CodeInvalidArgument, msg: err.Error()})
+		return
+	}
+	rows, sess, err := s.openRows(ctx, &req)
+	if err != nil {
+		writeStatus(w, err)
+		return
+	}
+	defer func() {
+		sess.mu.Lock()
+		sess.streams--
+		sess.lastUsed = time.Now()
+		sess.mu.Unlock()
+	}()
+	defer rows.Close()
+	writeStatus(w, streamRows(w, rows, int(req.FetchSize)))
+}
+
+// openRows starts a query for Fetch. The session lock is released before
+// rows are streamed, so other calls on the session are not blocked by a
+// slow reader.
+func (s *Server) openRows(ctx context.Context, req *rpcwire.ExecuteRequest) (*sql.Rows, *session, error) {
+	sess, err := s.lock(ctx, req.Session)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer sess.unlock()
+	args, err := bindArgs(req.Params)
+	if err != nil {
+		return nil, nil, err
+	}
+	st, err := sess.statement(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := authz.Check(ctx, sess.conn, sess.principal, sess.database, sess.query(req, st)); err != nil {
+		return nil, nil, err
+	}
+	var rows *sql.Rows
+	switch {
+	case st != nil && st.stmt != nil && sess.tx == nil:
+		rows, err = st.stmt.QueryContext(ctx, args...)
+	case sess.tx != nil:
+		rows, err = sess.tx.QueryContext(ctx, sess.query(req, st), args...)
+	default:
+		rows, err = sess.conn.QueryContext(ctx, sess.query(req, st), args...)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	sess.streams++
+	return rows, sess, nil
+}
+
+func streamRows(w http.ResponseWriter, rows *sql.Rows, fetchSize int) error {
+	if fetchSize <= 0 {
+		fetchSize = defaultFetchSize
+	}
+	fetchSize = min(fetchSize, maxFetchSize)
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return err
+	}
+	batch := &rpcwire.FetchResponse{Columns: make([]rpcwire.Column, len(types))}
+	for i, ct := range types {
+		batch.Columns[i] = rpcwire.Column{Name: ct.Name(), TypeName: ct.DatabaseTypeName()}
+	}
+	// Send the columns right away so the client can describe the result
+	// before the first row is ready.
+	send := func() error {
+		if err := rpcwire.WriteMessage(w, batch); err != nil {
+			return err
+		}
+		batch = &rpcwire.FetchResponse{}
+		return http.NewResponseController(w).Flush()
+	}
+	if err := send(); err != nil {
+		return err
+	}
+	for rows.Next() {
+		values := make([]any, len(types))
+		ptrs := make([]any, len(types))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return err
+		}
+		for i, v := range values {
+			values[i] = wireValue(v)
+		}
+		batch.Rows = append(batch.Rows, values)
+		if len(batch.Rows) >= fetchSize {
+			if err := send(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(batch.Rows) > 0 {
+		return send()
+	}
+	return nil
+}
+
+func (s *Server) begin(ctx context.Context, req *rpcwire.BeginRequest) (rpcwire.Message, error) {
+	sess, err := s.lock(ctx, req.Session)
+	if err != nil {
+		return nil, err
+	}
+	defer sess.unlock()
+	if sess.tx != nil {
+		return nil, &statusError{code: rpcwire.CodeFailedPrecondition, msg: "session already has an open transaction"}
+	}
+	tx, err := sess.conn.BeginTx(context.Background(), &sql.TxOptions{ReadOnly: req.ReadOnly || s.readOnly})
+	if err != nil {
+		return nil, err
+	}
+	sess.tx = tx
+	return &rpcwire.Empty{}, nil
+}
+
+func (s *Server) endTx(ctx context.Context, req *rpcwire.SessionRequest, commit bool) (rpcwire.Message, error) {
+	sess, err := s.lock(ctx, req.Session)
+	if err != nil {
+		return nil, err
+	}
+	defer sess.unlock()
+	if sess.tx == nil {
+		return nil, &statusError{code: rpcwire.CodeFailedPrecondition, msg: "session has no open transaction"}
+	}
+	tx := sess.tx
+	sess.tx = nil
+	if commit {
+		err = tx.Commit()
+	} else {
+		err = tx.Rollback()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rpcwire.Empty{}, nil
+}
+
+// lock finds a session and locks it for one call. Sessions of other users
+// are reported as not found.
+func (s *Server) lock(ctx context.Context, id string) (*session, error) {
+	s.expire()
+	s.mu.Lock()
+	sess := s.sessions[id]
+	s.mu.Unlock()
+	if sess == nil || !sess.ownedBy(callPrincipal(ctx)) {
+		return nil, &statusError{code: rpcwire.CodeNotFound, msg: "session not found or expired"}
+	}
+	sess.mu.Lock()
+	return sess, nil
+}
+
+func (sess *session) ownedBy(p *auth.Principal) bool {
+	if sess.principal == nil {
+		return true
+	}
+	return p != nil && p.User == sess.principal.User
+}
+
+func (sess *session) unlock() {
+	sess.lastUsed = time.Now()
+	sess.mu.Unlock()
+}
+
+// statement returns the prepared statement a request names, or nil for
+// inline SQL.
+func (sess *session) statement(req *rpcwire.ExecuteRequest) (*statement, error) {
+	if req.Statement == "" {
+		if req.SQL == "" {
+			return nil, &statusError{code: rpcwire.CodeInvalidArgument, msg: "statement or sql is required"}
+		}
+		return nil, nil
+	}
+	st, ok := sess.stmts[req.Statement]
+	if !ok {
+		return nil, &statusError{code: rpcwire.CodeFailedPrecondition, msg: "statement not found"}
+	}
+	return st, nil
+}
+
+func (sess *session) query(req *rpcwire.ExecuteRequest, st *statement) string {
+	if st != nil {
+		return st.query
+	}
+	return req.SQL
+}
+
+func (sess *session) close() error {
+	var errs []error
+	if sess.tx != nil {
+		errs = append(errs, sess.tx.Rollback())
+		sess.tx = nil
+	}
+	for id, st := range sess.stmts {
+		if st.stmt != nil {
+			st.stmt.Close()
+		}
+		delete(sess.stmts, id)
+	}
+	errs = append(errs, sess.conn.Close())
+	return errors.Join(errs...)
+}
+
+// expire closes sessions idle for longer than the idle timeout. Sessions
+// busy with a call or a Fetch stream are left alone.
+func (s *Server) expire() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, sess := range s.sessions {
+		if !sess.mu.TryLock() {
+			continue
+		}
+		if sess.streams == 0 && time.Since(sess.lastUsed) > s.idleTimeout {
+			_ = sess.close()
+			delete(s.sessions, id)
+		}
+		sess.mu.Unlock()
+	}
+}
+
+// bindArgs converts wire parameters to driver arguments.
+func bindArgs(params []any) ([]any, error) {
+	args := make([]any, len(params))
+	for i, p := range params {
+		if d, ok := p.(rpcwire.Decimal); ok {
+			dec, err := parseDecimal(string(d))
+			if err != nil {
+				return nil, &statusError{code: rpcwire.CodeInvalidArgument, msg: fmt.Sprintf("parameter $%d: %v", i+1, err)}
+			}
+			args[i] = dec
+			continue
+		}
+		args[i] = p
+	}
+	return args, nil
+}
+
+// wireValue converts a scanned value to one the Value message carries.
+func wireValue(v any) any {
+	switch t := v.(type) {
+	case decentdb.Decimal:
+		return rpcwire.Decimal(formatDecimal(t))
+	case decentdb.UUID:
+		return t[:]
+	case fmt.Stringer:
+		if rpcwire.CheckValue(v) != nil {
+			return t.String()
+		}
+	}
+	if rpcwire.CheckValue(v) != nil {
+		return fmt.Sprint(v)
+	}
+	return v
+}
+
+func formatDecimal(d decentdb.Decimal) string {
+	if d.Scale <= 0 {
+		return strconv.FormatInt(d.Unscaled, 10)
+	}
+	sign, digits := "", strconv.FormatInt(d.Unscaled, 10)
+	if d.Unscaled < 0 {
+		sign, digits = "-", digits[1:]
+	}
+	for len(digits) <= d.Scale {
+		digits = "0" + digits
+	}
+	cut := len(digits) - d.Scale
+	return sign + digits[:cut] + "." + digits[cut:]
+}
+
+func parseDecimal(text string) (decentdb.Decimal, error) {
+	intPart, fracPart, _ := strings.Cut(text, ".")
+	unscaled, err := strconv.ParseInt(intPart+fracPart, 10, 64)
+	if err != nil || strings.ContainsAny(fracPart, "+-") {
+		return decentdb.Decimal{}, fmt.Errorf("invalid decimal %q", text)
+	}
+	return decentdb.Decimal{Unscaled: unscaled, Scale: len(fracPart)}, nil
+}
+
+func newID(prefix string) (string, error) {
+	var buf [16]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		return "", err
+	}
+	return prefix + hex.EncodeToString(buf[:]), nil
+}
+
+// parseTimeout parses a grpc-timeout header such as "250m" or "5S".
+func parseTimeout(v string) (time.Duration, bool) {
+	if len(v) < 2 {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(v[:len(v)-1], 10, 64)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	units := map[byte]time.Duration{
+		'H': time.Hour, 'M': time.Minute, 'S': time.Second,
+		'm': time.Millisecond, 'u': time.Microsecond, 'n': time.Nanosecond,
+	}
+	unit, ok := units[v[len(v)-1]]
+	return time.Duration(n) * unit, ok
+}
+
+type statusError struct {
+	code int
+	msg  string
+}
+
+func (e *statusError) Error() string { return e.msg }
+
+func unary[Req rpcwire.Message](w http.ResponseWriter, r *http.Request, req Req, fn func(Req) (rpcwire.Message, error)) {
+	if err := rpcwire.ReadMessage(r.Body, req); err != nil {
+		writeStatus(w, &statusError{code: rpcwire.CodeInvalidArgument, msg: err.Error()})
+		return
+	}
+	resp, err := fn(req)
+	if err == nil {
+		err = rpcwire.WriteMessage(w, resp)
+	}
+	writeStatus(w, err)
+}
+
+// writeStatus sets the gRPC status trailers for err, which may be nil.
+func writeStatus(w http.ResponseWriter, err error) {
+	h := w.Header()
+	if err == nil {
+		h.Set(rpcwire.TrailerStatus, strconv.Itoa(rpcwire.CodeOK))
+		return
+	}
+	code := rpcwire.CodeUnknown
+	var se *statusError
+	var dbErr *decentdb.DecentDBError
+	switch {
+	case errors.As(err, &se):
+		code = se.code
+	case errors.Is(err, auth.ErrPermissionDenied):
+		code = rpcwire.CodePermissionDenied
+	case errors.As(err, &dbErr):
+		code = rpcwire.CodeInvalidArgument
+		if dbErr.Retryable {
+			code = rpcwire.CodeAborted
+		}
+		h.Set(rpcwire.TrailerNativeCode, strconv.Itoa(dbErr.Code))
+		if dbErr.SQLState != "" {
+			h.Set(rpcwire.TrailerSQLState, dbErr.SQLState)
+		}
+		if dbErr.Subcode != "" {
+			h.Set(rpcwire.TrailerSubcode, dbErr.Subcode)
+		}
+	case errors.Is(err, context.Canceled):
+		code = rpcwire.CodeCanceled
+	case errors.Is(err, context.DeadlineExceeded):
+		code = rpcwire.CodeDeadlineExceeded
+	}
+	h.Set(rpcwire.TrailerStatus, strconv.Itoa(code))
+	h.Set(rpcwire.TrailerMessage, rpcwire.EncodeStatusMessage(err.Error()))
+}
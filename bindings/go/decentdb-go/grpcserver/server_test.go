@@ -0,0 +1,306 @@
+package grpcserver
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	decentdb "github.com/sphildreth/decentdb-go"
+	"github.com/sphildreth/decentdb-go/auth"
+	"github.com/sphildreth/decentdb-go/internal/testcerts"
+	"github.com/sphildreth/decentdb-go/remote"
+	"github.com/sphildreth/decentdb-go/tlsconfig"
+)
+
+func TestDecimalText(t *testing.T) {
+	for _, text := range []string{"123.45", "-0.005", "42"} {
+		d, err := parseDecimal(text)
+		if err != nil {
+			t.Fatalf("parseDecimal(%q): %v", text, err)
+		}
+		if got := formatDecimal(d); got != text {
+			t.Fatalf("formatDecimal(parseDecimal(%q)) = %q", text, got)
+		}
+	}
+	if _, err := parseDecimal("1.-5"); err == nil {
+		t.Fatal("parseDecimal accepted 1.-5")
+	}
+	if got := formatDecimal(decentdb.Decimal{Unscaled: 5, Scale: 3}); got != "0.005" {
+		t.Fatalf("formatDecimal = %q", got)
+	}
+}
+
+func startServer(t *testing.T, opts ...Option) string {
+	t.Helper()
+	tmpDir, err := os.MkdirTemp("", "decentdb-test-grpcserver-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(tmpDir) })
+	db, err := sql.Open("decentdb", fmt.Sprintf("file:%s", filepath.Join(tmpDir, "grpc.ddb")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := New(map[string]*sql.DB{"app": db}, opts...)
+	go srv.Serve(l)
+	t.Cleanup(func() { srv.Close() })
+	return l.Addr().String()
+}
+
+func TestServer_RemoteClientRoundTrip(t *testing.T) {
+	addr := startServer(t)
+	db, err := sql.Open("decentdb-remote", "decentdb://"+addr+"/app?fetch_size=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec("CREATE TABLE items (id INT64 PRIMARY KEY, name TEXT, price DECIMAL(10,2))"); err != nil {
+		t.Fatal(err)
+	}
+	stmt, err := db.Prepare("INSERT INTO items VALUES ($1, $2, 9.99)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 5; i++ {
+		if _, err := stmt.Exec(i, fmt.Sprintf("item-%d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stmt.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Exec("DELETE FROM items"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Five rows with fetch_size=2 arrive in three streamed batches.
+	rows, err := db.Query("SELECT id, name, price FROM items WHERE id >= $1 ORDER BY id", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := types[0].DatabaseTypeName(); got != "INT64" {
+		t.Fatalf("id type = %q", got)
+	}
+	var n int
+	for rows.Next() {
+		var id int64
+		var name, price string
+		if err := rows.Scan(&id, &name, &price); err != nil {
+			t.Fatal(err)
+		}
+		if price != "9.99" {
+			t.Fatalf("price = %q, want decimal text", price)
+		}
+		n++
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+	if n != 5 {
+		t.Fatalf("rows = %d, want 5", n)
+	}
+
+	_, err = db.Exec("INSERT INTO missing VALUES (1)")
+	var remoteErr *remote.Error
+	if !errors.As(err, &remoteErr) || remoteErr.NativeCode == 0 {
+		t.Fatalf("missing table error = %#v", err)
+	}
+}
+
+func TestServer_BearerTokenAndUnknownDatabase(t *testing.T) {
+	addr := startServer(t, WithBearerToken("s3cret"))
+
+	for dsn, wantCode := range map[string]int{
+		"decentdb://" + addr + "/app":                16, // UNAUTHENTICATED
+		"decentdb://" + addr + "/other?token=s3cret": 5,  // NOT_FOUND
+	} {
+		db, err := sql.Open("decentdb-remote", dsn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = db.Ping()
+		db.Close()
+		var remoteErr *remote.Error
+		if !errors.As(err, &remoteErr) || remoteErr.Code != wantCode {
+			t.Fatalf("%s: ping error = %v, want code %d", dsn, err, wantCode)
+		}
+	}
+
+	db, err := sql.Open("decentdb-remote", "decentdb://"+addr+"/app?token=s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var v int64
+	if err := db.QueryRow("SELECT 1").Scan(&v); err != nil || v != 1 {
+		t.Fatalf("SELECT 1 = %d, %v", v, err)
+	}
+}
+
+func TestServer_MainDriverRemoteDSN(t *testing.T) {
+	addr := startServer(t)
+	db, err := sql.Open("decentdb", "decentdb://"+addr+"/app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec("CREATE TABLE prices (id INT64 PRIMARY KEY, amount DECIMAL(10,2))"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("INSERT INTO prices VALUES ($1, $2)", 1, decentdb.Decimal{Unscaled: 1999, Scale: 2}); err != nil {
+		t.Fatal(err)
+	}
+	var amount any
+	if err := db.QueryRow("SELECT amount FROM prices WHERE id = $1", 1).Scan(&amount); err != nil {
+		t.Fatal(err)
+	}
+	if amount != (decentdb.Decimal{Unscaled: 1999, Scale: 2}) {
+		t.Fatalf("amount = %#v, want decentdb.Decimal", amount)
+	}
+
+	_, err = db.Exec("INSERT INTO prices VALUES (1, 0)")
+	var dbErr *decentdb.DecentDBError
+	if !errors.As(err, &dbErr) || dbErr.Code == 0 {
+		t.Fatalf("duplicate key error = %#v, want *decentdb.DecentDBError", err)
+	}
+}
+
+func TestServer_AuthStoreGrants(t *testing.T) {
+	store := auth.NewStore()
+	for _, err := range []error{
+		store.CreateUser("admin", "adminpw"),
+		store.SetSuperuser("admin", true),
+		store.CreateUser("svc", ""),
+		store.CreateRole("writer"),
+		store.Grant("writer", auth.Grant{Database: "app", Table: "events", Privileges: []auth.Privilege{auth.Select, auth.Insert}}),
+		store.GrantRole("svc", "writer"),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, token, err := store.CreateToken("svc", "ingest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := startServer(t, WithAuth(store))
+
+	admin, err := sql.Open("decentdb-remote", "decentdb://admin:adminpw@"+addr+"/app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer admin.Close()
+	for _, stmt := range []string{
+		"CREATE TABLE events (id INT64 PRIMARY KEY)",
+		"CREATE TABLE audit (id INT64 PRIMARY KEY)",
+	} {
+		if _, err := admin.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+
+	svc, err := sql.Open("decentdb-remote", "decentdb://"+addr+"/app?token="+token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer svc.Close()
+	if _, err := svc.Exec("INSERT INTO events VALUES ($1)", 1); err != nil {
+		t.Fatalf("granted insert: %v", err)
+	}
+	var n int64
+	if err := svc.QueryRow("SELECT COUNT(*) FROM events").Scan(&n); err != nil || n != 1 {
+		t.Fatalf("granted select = %d, %v", n, err)
+	}
+	for _, query := range []string{"SELECT * FROM audit", "DELETE FROM events", "DROP TABLE audit"} {
+		_, err := svc.Exec(query)
+		var remoteErr *remote.Error
+		if !errors.As(err, &remoteErr) || remoteErr.Code != 7 { // PERMISSION_DENIED
+			t.Fatalf("%s: err = %v, want PERMISSION_DENIED", query, err)
+		}
+	}
+
+	for dsn, wantCode := range map[string]int{
+		"decentdb://admin:wrong@" + addr + "/app":  16, // UNAUTHENTICATED
+		"decentdb://" + addr + "/app?token=ddbt_x": 16,
+	} {
+		db, err := sql.Open("decentdb-remote", dsn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = db.Ping()
+		db.Close()
+		var remoteErr *remote.Error
+		if !errors.As(err, &remoteErr) || remoteErr.Code != wantCode {
+			t.Fatalf("%s: ping error = %v, want code %d", dsn, err, wantCode)
+		}
+	}
+}
+
+func TestServer_MutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := testcerts.NewCA(t, dir, "ca")
+	serverPair := ca.Server(t, "server")
+	clientPair := ca.Client(t, "client", "alice")
+	r, err := tlsconfig.Server{
+		CertFile:          serverPair.CertFile,
+		KeyFile:           serverPair.KeyFile,
+		ClientCAFile:      ca.File,
+		RequireClientCert: true,
+	}.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := startServer(t, WithTLSConfig(r.Config()))
+
+	base := "decentdb://" + addr + "/app?tls_ca=" + url.QueryEscape(ca.File)
+	withCert := base + "&tls_server_name=localhost&tls_cert=" + url.QueryEscape(clientPair.CertFile) + "&tls_key=" + url.QueryEscape(clientPair.KeyFile)
+	db, err := sql.Open("decentdb-remote", withCert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var v int64
+	if err := db.QueryRow("SELECT 1").Scan(&v); err != nil || v != 1 {
+		t.Fatalf("SELECT 1 over TLS = %d, %v", v, err)
+	}
+
+	for _, dsn := range []string{
+		base + "&tls_server_name=localhost",             // no client certificate
+		"decentdb://" + addr + "/app",                   // plaintext
+		"decentdb://" + addr + "/app?tls_insecure=true", // no client certificate
+	} {
+		db, err := sql.Open("decentdb-remote", dsn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = db.Ping()
+		db.Close()
+		if err == nil {
+			t.Fatalf("%s: ping succeeded", dsn)
+		}
+	}
+}
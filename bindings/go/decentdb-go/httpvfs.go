@@ -0,0 +1,372 @@
+package decentdb
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultHTTPRangeBlockSize   = 64 << 10
+	defaultHTTPRangeCacheBlocks = 256
+	defaultHTTPRangeTimeout     = 30 * time.Second
+)
+
+// ErrReadOnlyVFS is returned when the engine tries to change a file that an
+// HTTPRangeVFS serves from remote storage.
+var ErrReadOnlyVFS = errors.New("decentdb: file is read-only")
+
+// HTTPRangeOptions configures NewHTTPRangeVFS.
+type HTTPRangeOptions struct {
+	// Client sends the requests; nil uses http.DefaultClient.
+	Client *http.Client
+	// BlockSize is how many bytes one range request fetches. Zero uses
+	// 64 KiB. Use a multiple of the database page size.
+	BlockSize int
+	// CacheBlocks caps how many fetched blocks are kept, least recently used
+	// first out. Zero uses 256.
+	CacheBlocks int
+	// Timeout bounds each request. Zero uses 30 seconds.
+	Timeout time.Duration
+	// Header, if set, is called to add headers such as authorization to
+	// every request.
+	Header func(http.Header)
+}
+
+// HTTPRangeStats reports HTTPRangeVFS cache activity.
+type HTTPRangeStats struct {
+	Hits     uint64
+	Misses   uint64
+	Requests uint64
+	Blocks   int
+}
+
+// HTTPRangeVFS is a read-only VFS serving database files from object
+// storage or a CDN with HTTP range requests, so a large database can be
+// queried without downloading it. A name the engine opens is appended to
+// the base URL; objects must answer HEAD with their length and GET with 206
+// Partial Content. Fetched blocks are kept in an LRU cache.
+//
+// Files the remote store does not have, such as the WAL and other sidecars
+// the engine creates on open, live in memory for the life of the VFS, so
+// queries work as usual. Statements that change the database fail once
+// they reach the remote file, at the latest on checkpoint; open such
+// databases only for reading.
+//
+// When the server sends an ETag, every block is fetched with If-Match, so a
+// database replaced mid-query fails with an error rather than mixing pages
+// of two versions.
+type HTTPRangeVFS struct {
+	base    string
+	opts    HTTPRangeOptions
+	hits    atomic.Uint64
+	misses  atomic.Uint64
+	fetches atomic.Uint64
+
+	mu      sync.Mutex
+	remote  map[string]*httpRangeObject
+	scratch map[string]*memFile
+	blocks  map[httpRangeBlockKey]*list.Element
+	lru     list.List
+}
+
+type httpRangeObject struct {
+	url  string
+	size int64
+	etag string
+}
+
+type httpRangeBlockKey struct {
+	url   string
+	index int64
+}
+
+type httpRangeBlock struct {
+	key  httpRangeBlockKey
+	data []byte
+}
+
+// NewHTTPRangeVFS returns a VFS reading objects below baseURL. Register it
+// with RegisterVFS and open the database by its path below the base URL:
+//
+//	v, _ := decentdb.NewHTTPRangeVFS("https://cdn.example.com/dbs", decentdb.HTTPRangeOptions{})
+//	_ = decentdb.RegisterVFS("cdn", v)
+//	db, _ := sql.Open("decentdb", "file:/reports/2026.ddb?vfs=cdn&mode=open")
+func NewHTTPRangeVFS(baseURL string, opts HTTPRangeOptions) (*HTTPRangeVFS, error) {
+	if !strings.HasPrefix(baseURL, "http://") && !strings.HasPrefix(baseURL, "https://") {
+		return nil, fmt.Errorf("decentdb: HTTP range VFS base URL %q must be http or https", baseURL)
+	}
+	if opts.BlockSize < 0 || opts.CacheBlocks < 0 || opts.Timeout < 0 {
+		return nil, errors.New("decentdb: HTTP range VFS options must not be negative")
+	}
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+	if opts.BlockSize == 0 {
+		opts.BlockSize = defaultHTTPRangeBlockSize
+	}
+	if opts.CacheBlocks == 0 {
+		opts.CacheBlocks = defaultHTTPRangeCacheBlocks
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = defaultHTTPRangeTimeout
+	}
+	return &HTTPRangeVFS{
+		base:    strings.TrimSuffix(baseURL, "/"),
+		opts:    opts,
+		remote:  map[string]*httpRangeObject{},
+		scratch: map[string]*memFile{},
+		blocks:  map[httpRangeBlockKey]*list.Element{},
+	}, nil
+}
+
+// Stats returns cache counters. Requests counts range requests sent.
+func (v *HTTPRangeVFS) Stats() HTTPRangeStats {
+	v.mu.Lock()
+	blocks := v.lru.Len()
+	v.mu.Unlock()
+	return HTTPRangeStats{Hits: v.hits.Load(), Misses: v.misses.Load(), Requests: v.fetches.Load(), Blocks: blocks}
+}
+
+// Purge drops every cached block and forgets the remote objects' lengths,
+// so the next open sees a replaced database.
+func (v *HTTPRangeVFS) Purge() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.remote = map[string]*httpRangeObject{}
+	v.blocks = map[httpRangeBlockKey]*list.Element{}
+	v.lru.Init()
+}
+
+// Open opens name from the remote store, or from local memory when the
+// store does not have it.
+func (v *HTTPRangeVFS) Open(name string, flag int) (VFSFile, error) {
+	v.mu.Lock()
+	if f, ok := v.scratch[name]; ok {
+		v.mu.Unlock()
+		if flag&os.O_EXCL != 0 {
+			return nil, fs.ErrExist
+		}
+		return f, nil
+	}
+	v.mu.Unlock()
+
+	obj, err := v.object(name)
+	if err != nil {
+		return nil, err
+	}
+	if obj != nil {
+		if flag&os.O_EXCL != 0 {
+			return nil, fs.ErrExist
+		}
+		return &httpRangeFile{vfs: v, obj: obj}, nil
+	}
+	if flag&os.O_CREATE == 0 {
+		return nil, fs.ErrNotExist
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	f, ok := v.scratch[name]
+	if !ok {
+		f = &memFile{}
+		v.scratch[name] = f
+	}
+	return f, nil
+}
+
+// Exists reports whether name is in local memory or the remote store.
+func (v *HTTPRangeVFS) Exists(name string) (bool, error) {
+	v.mu.Lock()
+	_, ok := v.scratch[name]
+	v.mu.Unlock()
+	if ok {
+		return true, nil
+	}
+	obj, err := v.object(name)
+	return obj != nil, err
+}
+
+// Remove deletes name from local memory. Remote objects cannot be removed.
+func (v *HTTPRangeVFS) Remove(name string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if _, ok := v.scratch[name]; ok {
+		delete(v.scratch, name)
+		return nil
+	}
+	if _, ok := v.remote[name]; ok {
+		return ErrReadOnlyVFS
+	}
+	return nil
+}
+
+// object returns the remote object for name, or nil when the store does
+// not have it. Lengths and ETags are looked up once and remembered.
+func (v *HTTPRangeVFS) object(name string) (*httpRangeObject, error) {
+	v.mu.Lock()
+	obj, ok := v.remote[name]
+	v.mu.Unlock()
+	if ok {
+		return obj, nil
+	}
+
+	url := v.base + "/" + strings.TrimPrefix(name, "/")
+	resp, err := v.do(http.MethodHead, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, nil
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("decentdb: HEAD %s: %s", url, resp.Status)
+	case resp.ContentLength < 0:
+		return nil, fmt.Errorf("decentdb: HEAD %s: no Content-Length", url)
+	}
+	obj = &httpRangeObject{url: url, size: resp.ContentLength, etag: resp.Header.Get("ETag")}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if existing, ok := v.remote[name]; ok {
+		return existing, nil
+	}
+	v.remote[name] = obj
+	return obj, nil
+}
+
+func (v *HTTPRangeVFS) do(method, url string, header http.Header) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), v.opts.Timeout)
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	if v.opts.Header != nil {
+		v.opts.Header(req.Header)
+	}
+	resp, err := v.opts.Client.Do(req)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("decentdb: %s %s: %w", method, url, err)
+	}
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
+
+// block returns block index of obj, fetching it on a cache miss.
+func (v *HTTPRangeVFS) block(obj *httpRangeObject, index int64) ([]byte, error) {
+	key := httpRangeBlockKey{url: obj.url, index: index}
+	v.mu.Lock()
+	if elem, ok := v.blocks[key]; ok {
+		v.lru.MoveToFront(elem)
+		data := elem.Value.(*httpRangeBlock).data
+		v.mu.Unlock()
+		v.hits.Add(1)
+		return data, nil
+	}
+	v.mu.Unlock()
+	v.misses.Add(1)
+
+	start := index * int64(v.opts.BlockSize)
+	end := min(start+int64(v.opts.BlockSize), obj.size) - 1
+	header := http.Header{"Range": {"bytes=" + strconv.FormatInt(start, 10) + "-" + strconv.FormatInt(end, 10)}}
+	if obj.etag != "" {
+		header.Set("If-Match", obj.etag)
+	}
+	v.fetches.Add(1)
+	resp, err := v.do(http.MethodGet, obj.url, header)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+	case http.StatusPreconditionFailed:
+		return nil, fmt.Errorf("decentdb: %s changed while open", obj.url)
+	case http.StatusOK:
+		return nil, fmt.Errorf("decentdb: %s: server does not support range requests", obj.url)
+	default:
+		return nil, fmt.Errorf("decentdb: GET %s: %s", obj.url, resp.Status)
+	}
+	data := make([]byte, end-start+1)
+	if _, err := io.ReadFull(resp.Body, data); err != nil {
+		return nil, fmt.Errorf("decentdb: GET %s: %w", obj.url, err)
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if elem, ok := v.blocks[key]; ok {
+		v.lru.MoveToFront(elem)
+		return data, nil
+	}
+	v.blocks[key] = v.lru.PushFront(&httpRangeBlock{key: key, data: data})
+	for v.lru.Len() > v.opts.CacheBlocks {
+		oldest := v.lru.Back()
+		v.lru.Remove(oldest)
+		delete(v.blocks, oldest.Value.(*httpRangeBlock).key)
+	}
+	return data, nil
+}
+
+// httpRangeFile is a remote object opened through an HTTPRangeVFS.
+type httpRangeFile struct {
+	vfs *HTTPRangeVFS
+	obj *httpRangeObject
+}
+
+func (f *httpRangeFile) ReadAt(p []byte, off int64) (int, error) {
+	if off >= f.obj.size {
+		return 0, io.EOF
+	}
+	blockSize := int64(f.vfs.opts.BlockSize)
+	n := 0
+	for n < len(p) && off < f.obj.size {
+		data, err := f.vfs.block(f.obj, off/blockSize)
+		if err != nil {
+			return n, err
+		}
+		copied := copy(p[n:], data[off%blockSize:])
+		n += copied
+		off += int64(copied)
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (f *httpRangeFile) WriteAt([]byte, int64) (int, error) { return 0, ErrReadOnlyVFS }
+func (f *httpRangeFile) Truncate(int64) error               { return ErrReadOnlyVFS }
+func (f *httpRangeFile) Size() (int64, error)               { return f.obj.size, nil }
+func (f *httpRangeFile) Sync(bool) error                    { return nil }
+
+// Lock grants every range: the remote file never changes through this VFS,
+// so readers need no coordination.
+func (f *httpRangeFile) Lock(int64, int64, bool) (bool, error) { return true, nil }
+func (f *httpRangeFile) Unlock(int64, int64) error             { return nil }
+func (f *httpRangeFile) Close() error                          { return nil }
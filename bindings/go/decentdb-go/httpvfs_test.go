@@ -0,0 +1,106 @@
+package decentdb
+
+import (
+	"bytes"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHTTPRangeVFS_QueriesRemoteDatabase(t *testing.T) {
+	local := filepath.Join(t.TempDir(), "remote.ddb")
+	db, err := OpenDirect(local)
+	if err != nil {
+		t.Fatalf("OpenDirect failed: %v", err)
+	}
+	for _, stmt := range []string{
+		"CREATE TABLE t (id INT PRIMARY KEY, body TEXT)",
+		"INSERT INTO t SELECT value, repeat('x', 200) FROM generate_series(1, 2000)",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Checkpoint(); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+	image, err := os.ReadFile(local)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var gets atomic.Uint64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/dbs/reports.ddb" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method == http.MethodGet {
+			gets.Add(1)
+		}
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeContent(w, r, "reports.ddb", time.Time{}, bytes.NewReader(image))
+	}))
+	defer srv.Close()
+
+	v, err := NewHTTPRangeVFS(srv.URL+"/dbs", HTTPRangeOptions{BlockSize: 8192, CacheBlocks: 64})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterVFS("httptest", v); err != nil {
+		t.Fatal(err)
+	}
+	defer UnregisterVFS("httptest")
+
+	remote, err := sql.Open("decentdb", "file:/reports.ddb?vfs=httptest&mode=open")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer remote.Close()
+	var body string
+	if err := remote.QueryRow("SELECT body FROM t WHERE id = 1500").Scan(&body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body) != 200 {
+		t.Fatalf("body length = %d, want 200", len(body))
+	}
+	stats := v.Stats()
+	if stats.Requests == 0 || gets.Load() != stats.Requests {
+		t.Fatalf("requests = %d, server GETs = %d", stats.Requests, gets.Load())
+	}
+	if fetched := stats.Requests * 8192; fetched >= uint64(len(image)) {
+		t.Fatalf("point lookup fetched %d bytes of a %d byte database", fetched, len(image))
+	}
+
+	before := v.Stats().Requests
+	if err := remote.QueryRow("SELECT body FROM t WHERE id = 1500").Scan(&body); err != nil {
+		t.Fatal(err)
+	}
+	if after := v.Stats(); after.Requests != before || after.Hits == 0 {
+		t.Fatalf("repeated lookup was not served from cache: %+v", after)
+	}
+}
+
+func TestHTTPRangeVFS_MissingObjectAndBadURL(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+	v, err := NewHTTPRangeVFS(srv.URL, HTTPRangeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := v.Exists("/missing.ddb"); ok || err != nil {
+		t.Fatalf("Exists = %v, %v", ok, err)
+	}
+	if _, err := v.Open("/missing.ddb", os.O_RDWR); !os.IsNotExist(err) {
+		t.Fatalf("expected not-exist, got %v", err)
+	}
+	if _, err := NewHTTPRangeVFS("ftp://example.com", HTTPRangeOptions{}); err == nil {
+		t.Fatal("expected a non-HTTP base URL to be rejected")
+	}
+}
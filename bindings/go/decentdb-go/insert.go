@@ -0,0 +1,134 @@
+package decentdb
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// insertReturningSQL returns an INSERT of cols into table that returns the
+// key column, with cols bound as $1..$n in name order.
+func insertReturningSQL(table, key string, cols map[string]any) (string, []driver.NamedValue) {
+	names := make([]string, 0, len(cols))
+	for name := range cols {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	quoted := make([]string, len(names))
+	placeholders := make([]string, len(names))
+	args := make([]driver.NamedValue, len(names))
+	for i, name := range names {
+		quoted[i] = quoteIdentifier(name)
+		placeholders[i] = "$" + strconv.Itoa(i+1)
+		args[i] = driver.NamedValue{Ordinal: i + 1, Value: cols[name]}
+	}
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) RETURNING %s",
+		quoteRelationName(table), strings.Join(quoted, ", "), strings.Join(placeholders, ", "),
+		quoteIdentifier(key))
+	return query, args
+}
+
+// quoteIdentifier quotes a column name, which unlike a table name is never
+// split at dots.
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// InsertReturningID inserts one row into table and returns its primary key,
+// which must be a single integer column. cols maps column names to values;
+// columns left out take their defaults, so leaving out an INT64 primary key
+// has it assigned. Values bind as they do for Exec.
+func (c *conn) InsertReturningID(ctx context.Context, table string, cols map[string]any) (int64, error) {
+	v, key, err := c.insertReturningKey(ctx, "InsertReturningID", table, cols, "INT64", "an integer")
+	if err != nil {
+		return 0, err
+	}
+	id, ok := v.(int64)
+	if !ok {
+		return 0, fmt.Errorf("decentdb: InsertReturningID: primary key %s of %s returned %T", key, table, v)
+	}
+	return id, nil
+}
+
+// InsertReturningUUID is InsertReturningID for a table whose primary key is
+// a single UUID column. Leaving the key out of cols has its default, such as
+// GEN_RANDOM_UUID() or UUID_V7(), generate it.
+func (c *conn) InsertReturningUUID(ctx context.Context, table string, cols map[string]any) (UUID, error) {
+	v, key, err := c.insertReturningKey(ctx, "InsertReturningUUID", table, cols, "UUID", "a UUID")
+	if err != nil {
+		return UUID{}, err
+	}
+	var u UUID
+	if err := u.Scan(v); err != nil {
+		return UUID{}, fmt.Errorf("decentdb: InsertReturningUUID: primary key %s of %s: %w", key, table, err)
+	}
+	return u, nil
+}
+
+// insertReturningKey inserts one row into table and returns the value of its
+// single-column primary key, which must have type keyType, along with the
+// key's name.
+func (c *conn) insertReturningKey(ctx context.Context, method, table string, cols map[string]any, keyType, keyKind string) (driver.Value, string, error) {
+	if c.db == nil {
+		return nil, "", driver.ErrBadConn
+	}
+	if len(cols) == 0 {
+		return nil, "", fmt.Errorf("decentdb: %s needs at least one column", method)
+	}
+	info, err := c.GetTableInfo(table)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(info.PrimaryKeyColumns) != 1 {
+		return nil, "", fmt.Errorf("decentdb: %s: table %s has no single-column primary key", method, table)
+	}
+	key := info.PrimaryKeyColumns[0]
+	for _, column := range info.Columns {
+		if strings.EqualFold(column.Name, key) && column.Type != keyType {
+			return nil, "", fmt.Errorf("decentdb: %s: primary key %s of %s is %s, not %s", method, key, table, column.Type, keyKind)
+		}
+	}
+
+	query, args := insertReturningSQL(table, key, cols)
+	rows, err := c.QueryContext(ctx, query, args)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+	dest := make([]driver.Value, 1)
+	if err := rows.Next(dest); err != nil {
+		if err == io.EOF {
+			return nil, "", fmt.Errorf("decentdb: %s: no row inserted into %s", method, table)
+		}
+		return nil, "", err
+	}
+	return dest[0], key, nil
+}
+
+// InsertReturningID inserts one row into table and returns its single-column
+// integer primary key, replacing a hand-written INSERT ... RETURNING and Scan:
+//
+//	id, err := db.InsertReturningID(ctx, "users", map[string]any{"name": "Ada"})
+func (d *DB) InsertReturningID(ctx context.Context, table string, cols map[string]any) (int64, error) {
+	if atomic.LoadUint32(&d.closed) != 0 {
+		return 0, driver.ErrBadConn
+	}
+	return d.c.InsertReturningID(ctx, table, cols)
+}
+
+// InsertReturningUUID inserts one row into table and returns its
+// single-column UUID primary key, typically generated by the column's
+// default:
+//
+//	id, err := db.InsertReturningUUID(ctx, "orders", map[string]any{"total": 42})
+func (d *DB) InsertReturningUUID(ctx context.Context, table string, cols map[string]any) (UUID, error) {
+	if atomic.LoadUint32(&d.closed) != 0 {
+		return UUID{}, driver.ErrBadConn
+	}
+	return d.c.InsertReturningUUID(ctx, table, cols)
+}
@@ -0,0 +1,101 @@
+package decentdb
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestInsertReturningSQL(t *testing.T) {
+	query, args := insertReturningSQL("users", "id", map[string]any{"name": "Ada", "email": "ada@example.com"})
+	want := `INSERT INTO "users" ("email", "name") VALUES ($1, $2) RETURNING "id"`
+	if query != want {
+		t.Fatalf("insertReturningSQL = %q, want %q", query, want)
+	}
+	wantArgs := []driver.NamedValue{
+		{Ordinal: 1, Value: "ada@example.com"},
+		{Ordinal: 2, Value: "Ada"},
+	}
+	if !reflect.DeepEqual(args, wantArgs) {
+		t.Fatalf("args = %v, want %v", args, wantArgs)
+	}
+}
+
+func TestDBInsertReturningID(t *testing.T) {
+	db, err := OpenDirect(filepath.Join(t.TempDir(), "insert.ddb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	if _, err := db.Exec("CREATE TABLE users (id INT PRIMARY KEY, name TEXT NOT NULL)"); err != nil {
+		t.Fatal(err)
+	}
+	for want := int64(1); want <= 3; want++ {
+		id, err := db.InsertReturningID(ctx, "users", map[string]any{"name": "user"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id != want {
+			t.Fatalf("InsertReturningID = %d, want %d", id, want)
+		}
+	}
+	if id, err := db.InsertReturningID(ctx, "users", map[string]any{"id": int64(42), "name": "fixed"}); err != nil || id != 42 {
+		t.Fatalf("InsertReturningID with explicit id = %d, %v", id, err)
+	}
+	if _, err := db.InsertReturningID(ctx, "users", map[string]any{"id": int64(42), "name": "dup"}); err == nil {
+		t.Fatal("duplicate key accepted")
+	}
+	if _, err := db.InsertReturningID(ctx, "users", nil); err == nil {
+		t.Fatal("empty column map accepted")
+	}
+
+	if _, err := db.Exec("CREATE TABLE tags (name TEXT PRIMARY KEY)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.InsertReturningID(ctx, "tags", map[string]any{"name": "go"}); err == nil {
+		t.Fatal("text primary key returned as an id")
+	}
+	if _, err := db.Exec("CREATE TABLE notes (body TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.InsertReturningID(ctx, "notes", map[string]any{"body": "x"}); err == nil {
+		t.Fatal("table without a primary key accepted")
+	}
+}
+
+func TestDBInsertReturningUUID(t *testing.T) {
+	db, err := OpenDirect(filepath.Join(t.TempDir(), "insert.ddb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	if _, err := db.Exec("CREATE TABLE orders (id UUID PRIMARY KEY DEFAULT UUID_V7(), total INT64)"); err != nil {
+		t.Fatal(err)
+	}
+	var prev UUID
+	for i := 0; i < 3; i++ {
+		id, err := db.InsertReturningUUID(ctx, "orders", map[string]any{"total": int64(i)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id[6]>>4 != 7 || bytes.Compare(id[:], prev[:]) <= 0 {
+			t.Fatalf("InsertReturningUUID = %s after %s, want ascending version 7 UUIDs", id, prev)
+		}
+		prev = id
+	}
+	if n, err := db.Exec("UPDATE orders SET total = 20 WHERE id = $1 AND total = 2", prev); err != nil || n != 1 {
+		t.Fatalf("update by returned id affected %d rows, %v", n, err)
+	}
+	fixed := UUID{15: 1}
+	if id, err := db.InsertReturningUUID(ctx, "orders", map[string]any{"id": fixed, "total": int64(9)}); err != nil || id != fixed {
+		t.Fatalf("InsertReturningUUID with explicit id = %s, %v", id, err)
+	}
+	if _, err := db.InsertReturningID(ctx, "orders", map[string]any{"total": int64(1)}); err == nil {
+		t.Fatal("UUID primary key returned as an integer id")
+	}
+}
@@ -0,0 +1,124 @@
+package decentdb
+
+import (
+	"context"
+	"database/sql/driver"
+)
+
+// PrepareFunc prepares query on the underlying connection.
+type PrepareFunc func(ctx context.Context, query string) (driver.Stmt, error)
+
+// ExecFunc executes query with args on the underlying connection.
+type ExecFunc func(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error)
+
+// QueryFunc runs query with args on the underlying connection.
+type QueryFunc func(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error)
+
+// BeginTxFunc starts a transaction on the underlying connection.
+type BeginTxFunc func(ctx context.Context, opts driver.TxOptions) (driver.Tx, error)
+
+// EndTxFunc commits or rolls back the current transaction.
+type EndTxFunc func() error
+
+// Interceptor wraps driver operations. Each method receives the next step in
+// the chain and decides whether, when, and with what arguments to call it, so
+// work can happen before and after the call or replace it entirely.
+//
+// Exec and Query are intercepted both for ad-hoc statements and for prepared
+// statements. For prepared statements the query argument reports the prepared
+// SQL; changing it has no effect because the statement is already compiled.
+//
+// Embed NoopInterceptor to implement only the methods you need.
+type Interceptor interface {
+	InterceptPrepare(ctx context.Context, query string, next PrepareFunc) (driver.Stmt, error)
+	InterceptExec(ctx context.Context, query string, args []driver.NamedValue, next ExecFunc) (driver.Result, error)
+	InterceptQuery(ctx context.Context, query string, args []driver.NamedValue, next QueryFunc) (driver.Rows, error)
+	InterceptBeginTx(ctx context.Context, opts driver.TxOptions, next BeginTxFunc) (driver.Tx, error)
+	InterceptCommit(next EndTxFunc) error
+	InterceptRollback(next EndTxFunc) error
+}
+
+// NoopInterceptor passes every operation through unchanged.
+type NoopInterceptor struct{}
+
+func (NoopInterceptor) InterceptPrepare(ctx context.Context, query string, next PrepareFunc) (driver.Stmt, error) {
+	return next(ctx, query)
+}
+
+func (NoopInterceptor) InterceptExec(ctx context.Context, query string, args []driver.NamedValue, next ExecFunc) (driver.Result, error) {
+	return next(ctx, query, args)
+}
+
+func (NoopInterceptor) InterceptQuery(ctx context.Context, query string, args []driver.NamedValue, next QueryFunc) (driver.Rows, error) {
+	return next(ctx, query, args)
+}
+
+func (NoopInterceptor) InterceptBeginTx(ctx context.Context, opts driver.TxOptions, next BeginTxFunc) (driver.Tx, error) {
+	return next(ctx, opts)
+}
+
+func (NoopInterceptor) InterceptCommit(next EndTxFunc) error {
+	return next()
+}
+
+func (NoopInterceptor) InterceptRollback(next EndTxFunc) error {
+	return next()
+}
+
+// WithInterceptors appends interceptors to the connector's chain. The first
+// interceptor is outermost: it sees each call first and its result last.
+func WithInterceptors(interceptors ...Interceptor) ConnectorOption {
+	return func(c *connector) {
+		c.interceptors = append(c.interceptors, interceptors...)
+	}
+}
+
+func chainPrepare(interceptors []Interceptor, final PrepareFunc) PrepareFunc {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		ic, next := interceptors[i], final
+		final = func(ctx context.Context, query string) (driver.Stmt, error) {
+			return ic.InterceptPrepare(ctx, query, next)
+		}
+	}
+	return final
+}
+
+func chainExec(interceptors []Interceptor, final ExecFunc) ExecFunc {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		ic, next := interceptors[i], final
+		final = func(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+			return ic.InterceptExec(ctx, query, args, next)
+		}
+	}
+	return final
+}
+
+func chainQuery(interceptors []Interceptor, final QueryFunc) QueryFunc {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		ic, next := interceptors[i], final
+		final = func(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+			return ic.InterceptQuery(ctx, query, args, next)
+		}
+	}
+	return final
+}
+
+func chainBeginTx(interceptors []Interceptor, final BeginTxFunc) BeginTxFunc {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		ic, next := interceptors[i], final
+		final = func(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+			return ic.InterceptBeginTx(ctx, opts, next)
+		}
+	}
+	return final
+}
+
+func chainEndTx(interceptors []Interceptor, method func(Interceptor, EndTxFunc) error, final EndTxFunc) EndTxFunc {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		ic, next := interceptors[i], final
+		final = func() error {
+			return method(ic, next)
+		}
+	}
+	return final
+}
@@ -0,0 +1,138 @@
+package decentdb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type recordingInterceptor struct {
+	NoopInterceptor
+	name string
+	mu   *sync.Mutex
+	log  *[]string
+}
+
+func (r recordingInterceptor) record(event string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	*r.log = append(*r.log, r.name+":"+event)
+}
+
+func (r recordingInterceptor) InterceptPrepare(ctx context.Context, query string, next PrepareFunc) (driver.Stmt, error) {
+	r.record("prepare")
+	return next(ctx, query)
+}
+
+func (r recordingInterceptor) InterceptExec(ctx context.Context, query string, args []driver.NamedValue, next ExecFunc) (driver.Result, error) {
+	r.record("exec:before")
+	res, err := next(ctx, query, args)
+	r.record("exec:after")
+	return res, err
+}
+
+func (r recordingInterceptor) InterceptQuery(ctx context.Context, query string, args []driver.NamedValue, next QueryFunc) (driver.Rows, error) {
+	r.record("query")
+	return next(ctx, query, args)
+}
+
+func (r recordingInterceptor) InterceptBeginTx(ctx context.Context, opts driver.TxOptions, next BeginTxFunc) (driver.Tx, error) {
+	r.record("begin")
+	return next(ctx, opts)
+}
+
+func (r recordingInterceptor) InterceptCommit(next EndTxFunc) error {
+	r.record("commit")
+	return next()
+}
+
+type failingExecInterceptor struct {
+	NoopInterceptor
+}
+
+var errInjected = errors.New("injected failure")
+
+func (failingExecInterceptor) InterceptExec(ctx context.Context, query string, args []driver.NamedValue, next ExecFunc) (driver.Result, error) {
+	if strings.HasPrefix(query, "DELETE") {
+		return nil, errInjected
+	}
+	return next(ctx, query, args)
+}
+
+func TestInterceptors_ChainOrderAndCoverage(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "decentdb-test-interceptor-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	var mu sync.Mutex
+	var log []string
+	connector, err := NewConnector(
+		fmt.Sprintf("file:%s", filepath.Join(tmpDir, "intercept.ddb")),
+		WithInterceptors(
+			recordingInterceptor{name: "outer", mu: &mu, log: &log},
+			recordingInterceptor{name: "inner", mu: &mu, log: &log},
+			failingExecInterceptor{},
+		),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := sql.OpenDB(connector)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec("CREATE TABLE items (id INT PRIMARY KEY)"); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	got := strings.Join(log, ",")
+	log = nil
+	mu.Unlock()
+	if want := "outer:exec:before,inner:exec:before,inner:exec:after,outer:exec:after"; got != want {
+		t.Fatalf("unexpected exec chain: %s", got)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stmt, err := tx.Prepare("INSERT INTO items (id) VALUES ($1)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stmt.Exec(1); err != nil {
+		t.Fatal(err)
+	}
+	stmt.Close()
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM items").Scan(&count); err != nil || count != 1 {
+		t.Fatalf("count = %d, err = %v", count, err)
+	}
+	mu.Lock()
+	got = strings.Join(log, ",")
+	mu.Unlock()
+	for _, want := range []string{"outer:begin", "inner:prepare", "inner:exec:after", "outer:commit", "inner:query"} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("expected %s in %s", want, got)
+		}
+	}
+
+	if _, err := db.Exec("DELETE FROM items"); !errors.Is(err, errInjected) {
+		t.Fatalf("expected injected failure, got %v", err)
+	}
+	if err := db.QueryRow("SELECT COUNT(*) FROM items").Scan(&count); err != nil || count != 1 {
+		t.Fatalf("short-circuited delete should not run: count = %d, err = %v", count, err)
+	}
+}
@@ -0,0 +1,73 @@
+// Package authz checks statements against auth grants for the server
+// frontends, using the engine's statement description.
+package authz
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	decentdb "github.com/sphildreth/decentdb-go"
+	"github.com/sphildreth/decentdb-go/auth"
+)
+
+type stmtInfoer interface {
+	StmtInfo(query string) (*decentdb.StmtInfo, error)
+}
+
+var errNoStmtInfo = errors.New("driver does not describe statements")
+
+// Describe asks the engine to describe query on conn.
+func Describe(conn *sql.Conn, query string) (*decentdb.StmtInfo, error) {
+	var info *decentdb.StmtInfo
+	err := conn.Raw(func(dc any) error {
+		d, ok := dc.(stmtInfoer)
+		if !ok {
+			return errNoStmtInfo
+		}
+		var err error
+		info, err = d.StmtInfo(query)
+		return err
+	})
+	return info, err
+}
+
+// Statement converts the engine's description of query for auth. info is
+// nil when the engine could not describe the statement; transaction
+// control is then recognized by its leading keyword, and anything else is
+// left undescribed, which only principals holding every privilege pass.
+func Statement(query string, info *decentdb.StmtInfo) auth.Statement {
+	if info == nil {
+		fields := strings.Fields(query)
+		if len(fields) > 0 {
+			switch strings.ToUpper(strings.TrimRight(fields[0], ";")) {
+			case "BEGIN", "START", "COMMIT", "END", "ROLLBACK", "ABORT", "SAVEPOINT", "RELEASE":
+				return auth.Statement{Kind: auth.KindTransaction}
+			}
+		}
+		return auth.Statement{}
+	}
+	return auth.Statement{
+		Kind:     info.StatementKind,
+		Tables:   info.Tables,
+		Complete: info.TablesComplete,
+		Target:   info.TargetTable,
+	}
+}
+
+// Check authorizes p to run query against database. A nil p allows
+// everything, for frontends running without an auth store.
+func Check(ctx context.Context, conn *sql.Conn, p *auth.Principal, database, query string) error {
+	if p == nil {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	info, err := Describe(conn, query)
+	if err != nil {
+		info = nil
+	}
+	return p.Authorize(database, Statement(query, info))
+}
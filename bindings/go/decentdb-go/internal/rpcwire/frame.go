@@ -0,0 +1,126 @@
+package rpcwire
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Service is the fully qualified gRPC service name.
+const Service = "decentdb.v1.DecentDB"
+
+// Method paths, as sent in the HTTP/2 :path header.
+const (
+	MethodConnect        = "/" + Service + "/Connect"
+	MethodDisconnect     = "/" + Service + "/Disconnect"
+	MethodPrepare        = "/" + Service + "/Prepare"
+	MethodCloseStatement = "/" + Service + "/CloseStatement"
+	MethodExecute        = "/" + Service + "/Execute"
+	MethodFetch          = "/" + Service + "/Fetch"
+	MethodBegin          = "/" + Service + "/Begin"
+	MethodCommit         = "/" + Service + "/Commit"
+	MethodRollback       = "/" + Service + "/Rollback"
+)
+
+// ContentType is the gRPC content type for protobuf payloads.
+const ContentType = "application/grpc+proto"
+
+// gRPC status codes used by the service.
+const (
+	CodeOK                 = 0
+	CodeCanceled           = 1
+	CodeUnknown            = 2
+	CodeInvalidArgument    = 3
+	CodeDeadlineExceeded   = 4
+	CodeNotFound           = 5
+	CodePermissionDenied   = 7
+	CodeFailedPrecondition = 9
+	CodeAborted            = 10
+	CodeUnimplemented      = 12
+	CodeInternal           = 13
+	CodeUnavailable        = 14
+	CodeUnauthenticated    = 16
+)
+
+// Trailer names. Engine errors add the DecentDB trailers next to the
+// standard grpc-status and grpc-message.
+const (
+	TrailerStatus     = "Grpc-Status"
+	TrailerMessage    = "Grpc-Message"
+	TrailerSQLState   = "Decentdb-Sqlstate"
+	TrailerNativeCode = "Decentdb-Native-Code"
+	TrailerSubcode    = "Decentdb-Subcode"
+)
+
+// MaxMessageSize bounds a single framed message.
+const MaxMessageSize = 64 << 20
+
+// WriteMessage writes m as one length-prefixed, uncompressed gRPC frame.
+func WriteMessage(w io.Writer, m Message) error {
+	payload := m.MarshalProto()
+	frame := make([]byte, 5, 5+len(payload))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(payload)))
+	_, err := w.Write(append(frame, payload...))
+	return err
+}
+
+// ReadMessage reads one gRPC frame into m. It returns io.EOF when the
+// stream ends cleanly before a frame starts.
+func ReadMessage(r io.Reader, m Message) error {
+	var header [5]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return errTruncated
+		}
+		return err
+	}
+	if header[0] != 0 {
+		return errors.New("rpcwire: compressed messages are not supported")
+	}
+	n := binary.BigEndian.Uint32(header[1:])
+	if n > MaxMessageSize {
+		return fmt.Errorf("rpcwire: message of %d bytes exceeds limit", n)
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return errTruncated
+	}
+	return m.UnmarshalProto(payload)
+}
+
+// EncodeStatusMessage percent-encodes a grpc-message value.
+func EncodeStatusMessage(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < 0x20 || c > 0x7e || c == '%' {
+			fmt.Fprintf(&b, "%%%02X", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// DecodeStatusMessage reverses EncodeStatusMessage, leaving malformed
+// escapes as they are.
+func DecodeStatusMessage(s string) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+	out := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] == '%' && i+2 < len(s) {
+			if v, err := strconv.ParseUint(s[i+1:i+3], 16, 8); err == nil {
+				out = append(out, byte(v))
+				i += 2
+				continue
+			}
+		}
+		out = append(out, s[i])
+	}
+	return string(out)
+}
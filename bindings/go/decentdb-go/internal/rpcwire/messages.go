@@ -0,0 +1,418 @@
+package rpcwire
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Message is a protobuf message of the DecentDB service.
+type Message interface {
+	MarshalProto() []byte
+	UnmarshalProto(b []byte) error
+}
+
+// Decimal carries a DECIMAL value as its exact decimal text, such as
+// "-12.50".
+type Decimal string
+
+// Empty is google.protobuf.Empty-shaped: no fields.
+type Empty struct{}
+
+func (*Empty) MarshalProto() []byte          { return nil }
+func (*Empty) UnmarshalProto(b []byte) error { return fields(b, skip) }
+
+type ConnectRequest struct {
+	Database string
+}
+
+func (m *ConnectRequest) MarshalProto() []byte {
+	var e encoder
+	e.string(1, m.Database)
+	return e.buf
+}
+
+func (m *ConnectRequest) UnmarshalProto(b []byte) error {
+	return fields(b, func(f field) error {
+		if f.number == 1 {
+			m.Database = string(f.data)
+		}
+		return nil
+	})
+}
+
+type ConnectResponse struct {
+	Session string
+}
+
+func (m *ConnectResponse) MarshalProto() []byte {
+	var e encoder
+	e.string(1, m.Session)
+	return e.buf
+}
+
+func (m *ConnectResponse) UnmarshalProto(b []byte) error {
+	return fields(b, func(f field) error {
+		if f.number == 1 {
+			m.Session = string(f.data)
+		}
+		return nil
+	})
+}
+
+type SessionRequest struct {
+	Session string
+}
+
+func (m *SessionRequest) MarshalProto() []byte {
+	var e encoder
+	e.string(1, m.Session)
+	return e.buf
+}
+
+func (m *SessionRequest) UnmarshalProto(b []byte) error {
+	return fields(b, func(f field) error {
+		if f.number == 1 {
+			m.Session = string(f.data)
+		}
+		return nil
+	})
+}
+
+type BeginRequest struct {
+	Session  string
+	ReadOnly bool
+}
+
+func (m *BeginRequest) MarshalProto() []byte {
+	var e encoder
+	e.string(1, m.Session)
+	e.bool(2, m.ReadOnly)
+	return e.buf
+}
+
+func (m *BeginRequest) UnmarshalProto(b []byte) error {
+	return fields(b, func(f field) error {
+		switch f.number {
+		case 1:
+			m.Session = string(f.data)
+		case 2:
+			m.ReadOnly = f.num != 0
+		}
+		return nil
+	})
+}
+
+type PrepareRequest struct {
+	Session string
+	SQL     string
+}
+
+func (m *PrepareRequest) MarshalProto() []byte {
+	var e encoder
+	e.string(1, m.Session)
+	e.string(2, m.SQL)
+	return e.buf
+}
+
+func (m *PrepareRequest) UnmarshalProto(b []byte) error {
+	return fields(b, func(f field) error {
+		switch f.number {
+		case 1:
+			m.Session = string(f.data)
+		case 2:
+			m.SQL = string(f.data)
+		}
+		return nil
+	})
+}
+
+type Column struct {
+	Name     string
+	TypeName string
+}
+
+func (c *Column) marshal(e *encoder) {
+	e.string(1, c.Name)
+	e.string(2, c.TypeName)
+}
+
+func (c *Column) unmarshal(b []byte) error {
+	return fields(b, func(f field) error {
+		switch f.number {
+		case 1:
+			c.Name = string(f.data)
+		case 2:
+			c.TypeName = string(f.data)
+		}
+		return nil
+	})
+}
+
+func appendColumns(e *encoder, field int, cols []Column) {
+	for i := range cols {
+		e.message(field, cols[i].marshal)
+	}
+}
+
+func decodeColumn(f field, cols *[]Column) error {
+	var c Column
+	if err := c.unmarshal(f.data); err != nil {
+		return err
+	}
+	*cols = append(*cols, c)
+	return nil
+}
+
+// PrepareResponse describes a prepared statement. NumParams is -1 when the
+// server cannot tell how many placeholders the statement has.
+type PrepareResponse struct {
+	Statement string
+	NumParams int32
+	Columns   []Column
+}
+
+func (m *PrepareResponse) MarshalProto() []byte {
+	var e encoder
+	e.string(1, m.Statement)
+	e.int64(2, int64(m.NumParams))
+	appendColumns(&e, 3, m.Columns)
+	return e.buf
+}
+
+func (m *PrepareResponse) UnmarshalProto(b []byte) error {
+	return fields(b, func(f field) error {
+		switch f.number {
+		case 1:
+			m.Statement = string(f.data)
+		case 2:
+			m.NumParams = int32(f.num)
+		case 3:
+			return decodeColumn(f, &m.Columns)
+		}
+		return nil
+	})
+}
+
+type StatementRequest struct {
+	Session   string
+	Statement string
+}
+
+func (m *StatementRequest) MarshalProto() []byte {
+	var e encoder
+	e.string(1, m.Session)
+	e.string(2, m.Statement)
+	return e.buf
+}
+
+func (m *StatementRequest) UnmarshalProto(b []byte) error {
+	return fields(b, func(f field) error {
+		switch f.number {
+		case 1:
+			m.Session = string(f.data)
+		case 2:
+			m.Statement = string(f.data)
+		}
+		return nil
+	})
+}
+
+// ExecuteRequest binds Params to a prepared Statement, or to SQL when
+// Statement is empty, and runs it. Fetch uses it too; FetchSize bounds the
+// rows per streamed response.
+type ExecuteRequest struct {
+	Session   string
+	Statement string
+	SQL       string
+	Params    []any
+	FetchSize int32
+}
+
+func (m *ExecuteRequest) MarshalProto() []byte {
+	var e encoder
+	e.string(1, m.Session)
+	e.string(2, m.Statement)
+	e.string(3, m.SQL)
+	for _, v := range m.Params {
+		e.message(4, func(inner *encoder) { appendValue(inner, v) })
+	}
+	e.int64(5, int64(m.FetchSize))
+	return e.buf
+}
+
+func (m *ExecuteRequest) UnmarshalProto(b []byte) error {
+	return fields(b, func(f field) error {
+		switch f.number {
+		case 1:
+			m.Session = string(f.data)
+		case 2:
+			m.Statement = string(f.data)
+		case 3:
+			m.SQL = string(f.data)
+		case 4:
+			v, err := decodeValue(f.data)
+			if err != nil {
+				return err
+			}
+			m.Params = append(m.Params, v)
+		case 5:
+			m.FetchSize = int32(f.num)
+		}
+		return nil
+	})
+}
+
+type ExecuteResponse struct {
+	RowsAffected    int64
+	LastInsertID    int64
+	HasLastInsertID bool
+}
+
+func (m *ExecuteResponse) MarshalProto() []byte {
+	var e encoder
+	e.int64(1, m.RowsAffected)
+	e.int64(2, m.LastInsertID)
+	e.bool(3, m.HasLastInsertID)
+	return e.buf
+}
+
+func (m *ExecuteResponse) UnmarshalProto(b []byte) error {
+	return fields(b, func(f field) error {
+		switch f.number {
+		case 1:
+			m.RowsAffected = int64(f.num)
+		case 2:
+			m.LastInsertID = int64(f.num)
+		case 3:
+			m.HasLastInsertID = f.num != 0
+		}
+		return nil
+	})
+}
+
+// FetchResponse is one message of the Fetch stream. Only the first message
+// carries Columns.
+type FetchResponse struct {
+	Columns []Column
+	Rows    [][]any
+}
+
+func (m *FetchResponse) MarshalProto() []byte {
+	var e encoder
+	appendColumns(&e, 1, m.Columns)
+	for _, row := range m.Rows {
+		e.message(2, func(re *encoder) {
+			for _, v := range row {
+				re.message(1, func(ve *encoder) { appendValue(ve, v) })
+			}
+		})
+	}
+	return e.buf
+}
+
+func (m *FetchResponse) UnmarshalProto(b []byte) error {
+	return fields(b, func(f field) error {
+		switch f.number {
+		case 1:
+			return decodeColumn(f, &m.Columns)
+		case 2:
+			row := []any{}
+			err := fields(f.data, func(vf field) error {
+				if vf.number != 1 {
+					return nil
+				}
+				v, err := decodeValue(vf.data)
+				if err != nil {
+					return err
+				}
+				row = append(row, v)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			m.Rows = append(m.Rows, row)
+		}
+		return nil
+	})
+}
+
+// Value oneof field numbers.
+const (
+	valueNull            = 1
+	valueInt64           = 2
+	valueFloat64         = 3
+	valueBool            = 4
+	valueText            = 5
+	valueBlob            = 6
+	valueDecimal         = 7
+	valueTimestampMicros = 8
+	valueTimeMicros      = 9
+)
+
+// CheckValue reports whether v can be carried by the Value message.
+func CheckValue(v any) error {
+	switch v.(type) {
+	case nil, int64, float64, bool, string, []byte, Decimal, time.Time, time.Duration:
+		return nil
+	}
+	return fmt.Errorf("rpcwire: unsupported value type %T", v)
+}
+
+func appendValue(e *encoder, v any) {
+	switch t := v.(type) {
+	case int64:
+		e.oneofVarint(valueInt64, uint64(t))
+	case float64:
+		e.oneofDouble(valueFloat64, t)
+	case bool:
+		if t {
+			e.oneofVarint(valueBool, 1)
+		} else {
+			e.oneofVarint(valueBool, 0)
+		}
+	case string:
+		e.bytesField(valueText, []byte(t))
+	case []byte:
+		e.bytesField(valueBlob, t)
+	case Decimal:
+		e.bytesField(valueDecimal, []byte(t))
+	case time.Time:
+		e.oneofVarint(valueTimestampMicros, uint64(t.UnixMicro()))
+	case time.Duration:
+		e.oneofVarint(valueTimeMicros, uint64(t.Microseconds()))
+	default:
+		e.oneofVarint(valueNull, 1)
+	}
+}
+
+func decodeValue(b []byte) (any, error) {
+	var v any
+	err := fields(b, func(f field) error {
+		switch f.number {
+		case valueNull:
+			v = nil
+		case valueInt64:
+			v = int64(f.num)
+		case valueFloat64:
+			v = math.Float64frombits(f.num)
+		case valueBool:
+			v = f.num != 0
+		case valueText:
+			v = string(f.data)
+		case valueBlob:
+			v = append([]byte{}, f.data...)
+		case valueDecimal:
+			v = Decimal(f.data)
+		case valueTimestampMicros:
+			v = time.UnixMicro(int64(f.num)).UTC()
+		case valueTimeMicros:
+			v = time.Duration(int64(f.num)) * time.Microsecond
+		}
+		return nil
+	})
+	return v, err
+}
+
+func skip(field) error { return nil }
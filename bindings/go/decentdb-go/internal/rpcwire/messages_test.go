@@ -0,0 +1,77 @@
+package rpcwire
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFetchResponseRoundTrip(t *testing.T) {
+	ts := time.Date(2024, 3, 9, 14, 5, 6, 250000000, time.UTC)
+	in := &FetchResponse{
+		Columns: []Column{{Name: "id", TypeName: "INT64"}, {Name: "v", TypeName: ""}},
+		Rows: [][]any{
+			{int64(0), nil},
+			{int64(-7), "text"},
+			{false, 1.5},
+			{[]byte{1, 2}, Decimal("-12.50")},
+			{ts, 90 * time.Minute},
+		},
+	}
+	var out FetchResponse
+	if err := out.UnmarshalProto(in.MarshalProto()); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, &out) {
+		t.Fatalf("round trip = %#v, want %#v", out, *in)
+	}
+}
+
+func TestExecuteRequestRoundTrip(t *testing.T) {
+	in := &ExecuteRequest{Session: "s", Statement: "st", Params: []any{int64(1), "x", nil}, FetchSize: 10}
+	var out ExecuteRequest
+	if err := out.UnmarshalProto(in.MarshalProto()); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, &out) {
+		t.Fatalf("round trip = %#v, want %#v", out, *in)
+	}
+
+	prep := &PrepareResponse{Statement: "st", NumParams: -1}
+	var prepOut PrepareResponse
+	if err := prepOut.UnmarshalProto(prep.MarshalProto()); err != nil {
+		t.Fatal(err)
+	}
+	if prepOut.NumParams != -1 {
+		t.Fatalf("NumParams = %d, want -1", prepOut.NumParams)
+	}
+}
+
+func TestFraming(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteMessage(&buf, &ConnectRequest{Database: "app"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.Bytes()[:5]; !bytes.Equal(got, []byte{0, 0, 0, 0, 5}) {
+		t.Fatalf("frame header = %v", got)
+	}
+	var req ConnectRequest
+	if err := ReadMessage(&buf, &req); err != nil || req.Database != "app" {
+		t.Fatalf("ReadMessage = %+v, %v", req, err)
+	}
+	if err := ReadMessage(&buf, &req); err == nil {
+		t.Fatal("ReadMessage on empty stream succeeded")
+	}
+}
+
+func TestStatusMessageEncoding(t *testing.T) {
+	msg := "no such table: \"naïve\" 100%\n"
+	encoded := EncodeStatusMessage(msg)
+	if encoded != `no such table: "na%C3%AFve" 100%25%0A` {
+		t.Fatalf("EncodeStatusMessage = %q", encoded)
+	}
+	if got := DecodeStatusMessage(encoded); got != msg {
+		t.Fatalf("DecodeStatusMessage = %q, want %q", got, msg)
+	}
+}
@@ -0,0 +1,128 @@
+// Package rpcwire implements the wire format of the DecentDB gRPC service
+// described in remote/decentdb.proto: protobuf encoding of its messages,
+// gRPC length-prefixed framing, and status trailers. It is shared by the
+// remote client and grpcserver so neither needs a protobuf or gRPC
+// dependency.
+package rpcwire
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+var errTruncated = errors.New("rpcwire: truncated message")
+
+// encoder appends protobuf fields. Scalar helpers skip proto3 default
+// values, matching what protoc-generated code emits.
+type encoder struct{ buf []byte }
+
+func (e *encoder) tag(field, wireType int) {
+	e.buf = binary.AppendUvarint(e.buf, uint64(field)<<3|uint64(wireType))
+}
+
+func (e *encoder) uint64(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	e.tag(field, wireVarint)
+	e.buf = binary.AppendUvarint(e.buf, v)
+}
+
+func (e *encoder) int64(field int, v int64) { e.uint64(field, uint64(v)) }
+
+func (e *encoder) bool(field int, v bool) {
+	if v {
+		e.uint64(field, 1)
+	}
+}
+
+func (e *encoder) string(field int, s string) {
+	if s == "" {
+		return
+	}
+	e.bytesField(field, []byte(s))
+}
+
+func (e *encoder) bytesField(field int, b []byte) {
+	e.tag(field, wireBytes)
+	e.buf = binary.AppendUvarint(e.buf, uint64(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+func (e *encoder) message(field int, fn func(*encoder)) {
+	var inner encoder
+	fn(&inner)
+	e.bytesField(field, inner.buf)
+}
+
+// oneof helpers always emit the field, because presence is what selects
+// the oneof case even for zero values.
+func (e *encoder) oneofVarint(field int, v uint64) {
+	e.tag(field, wireVarint)
+	e.buf = binary.AppendUvarint(e.buf, v)
+}
+
+func (e *encoder) oneofDouble(field int, v float64) {
+	e.tag(field, wireFixed64)
+	e.buf = binary.LittleEndian.AppendUint64(e.buf, math.Float64bits(v))
+}
+
+// field is one decoded protobuf field. For varint and fixed fields the
+// value is in num; for length-delimited fields it is in data.
+type field struct {
+	number   int
+	wireType int
+	num      uint64
+	data     []byte
+}
+
+// fields decodes every field of a message, in order.
+func fields(b []byte, fn func(f field) error) error {
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return errTruncated
+		}
+		b = b[n:]
+		f := field{number: int(key >> 3), wireType: int(key & 7)}
+		switch f.wireType {
+		case wireVarint:
+			v, n := binary.Uvarint(b)
+			if n <= 0 {
+				return errTruncated
+			}
+			f.num, b = v, b[n:]
+		case wireFixed64:
+			if len(b) < 8 {
+				return errTruncated
+			}
+			f.num, b = binary.LittleEndian.Uint64(b), b[8:]
+		case wireFixed32:
+			if len(b) < 4 {
+				return errTruncated
+			}
+			f.num, b = uint64(binary.LittleEndian.Uint32(b)), b[4:]
+		case wireBytes:
+			size, n := binary.Uvarint(b)
+			if n <= 0 || uint64(len(b)-n) < size {
+				return errTruncated
+			}
+			f.data, b = b[n:n+int(size)], b[n+int(size):]
+		default:
+			return fmt.Errorf("rpcwire: unsupported wire type %d", f.wireType)
+		}
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package decentdb
+
+/*
+#include "decentdb.h"
+*/
+import "C"
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+)
+
+// ErrLeakedStatements is matched by the LeakError that sql.DB.Close returns
+// for debug_leaks=true DSNs when statements were never closed.
+var ErrLeakedStatements = errors.New("decentdb: leaked statements")
+
+// LeakedStatement is a native statement handle that was not closed by its
+// owner.
+type LeakedStatement struct {
+	// Query is the SQL the statement was prepared from.
+	Query string
+	// Stack is the goroutine stack at prepare time.
+	Stack string
+	// Finalized is true when the statement became unreachable without
+	// Close and was freed by the garbage-collection safety net, rather than
+	// still being open when the connector closed.
+	Finalized bool
+}
+
+// LeakError lists the statements a debug_leaks=true connector found leaked.
+type LeakError struct {
+	Statements []LeakedStatement
+}
+
+func (e *LeakError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "decentdb: %d leaked statement(s)", len(e.Statements))
+	for _, s := range e.Statements {
+		fmt.Fprintf(&b, "\n\n%s\nprepared at:\n%s", s.Query, s.Stack)
+	}
+	return b.String()
+}
+
+func (e *LeakError) Is(target error) bool {
+	return target == ErrLeakedStatements
+}
+
+// leakTracker records the live statements of a debug_leaks=true connector's
+// connections. Records hold the native handle but not the Go statement, so
+// a statement dropped without Close stays collectable and its finalizer can
+// flag it. Flagged handles are freed on the owning connection's goroutine,
+// in prepare or Close, never in the finalizer.
+type leakTracker struct {
+	mu     sync.Mutex
+	live   map[*leakRecord]struct{}
+	leaked []LeakedStatement
+}
+
+type leakRecord struct {
+	conn      *conn
+	stmt      *C.ddb_stmt_t
+	query     string
+	stack     string
+	finalized bool
+}
+
+func newLeakTracker() *leakTracker {
+	return &leakTracker{live: map[*leakRecord]struct{}{}}
+}
+
+// track registers s with its creation stack and arms the finalizer safety
+// net.
+func (t *leakTracker) track(s *stmtStruct) {
+	rec := &leakRecord{conn: s.c, stmt: s.stmt, query: s.query, stack: string(debug.Stack())}
+	t.mu.Lock()
+	t.live[rec] = struct{}{}
+	t.mu.Unlock()
+	s.leak = rec
+	runtime.SetFinalizer(s, func(s *stmtStruct) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if _, ok := t.live[s.leak]; ok {
+			s.leak.finalized = true
+		}
+	})
+}
+
+// untrack forgets s as its last reference frees it. It returns false when
+// the tracker already freed the handle.
+func (t *leakTracker) untrack(s *stmtStruct) bool {
+	runtime.SetFinalizer(s, nil)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.live[s.leak]; !ok {
+		return false
+	}
+	delete(t.live, s.leak)
+	return true
+}
+
+// sweep frees c's statements whose Go side was finalized without Close,
+// recording each as leaked. Statements still reachable are left alone: they
+// are reported by report and keep c's handle open until they close.
+func (t *leakTracker) sweep(c *conn) {
+	t.mu.Lock()
+	freed := 0
+	for rec := range t.live {
+		if rec.conn != c || !rec.finalized {
+			continue
+		}
+		delete(t.live, rec)
+		stmtp := rec.stmt
+		C.ddb_stmt_free(&stmtp)
+		t.leaked = append(t.leaked, LeakedStatement{Query: rec.query, Stack: rec.stack, Finalized: true})
+		freed++
+	}
+	t.mu.Unlock()
+	for ; freed > 0; freed-- {
+		c.endStatement()
+	}
+}
+
+// report returns a LeakError for every statement leaked so far, including
+// ones still open on connections that were never closed, and resets the
+// leaked list.
+func (t *leakTracker) report() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	leaked := t.leaked
+	t.leaked = nil
+	for rec := range t.live {
+		leaked = append(leaked, LeakedStatement{Query: rec.query, Stack: rec.stack, Finalized: rec.finalized})
+	}
+	if len(leaked) == 0 {
+		return nil
+	}
+	return &LeakError{Statements: leaked}
+}
+
+// Close reports statements leaked through the connector's connections. The
+// database/sql package calls it from sql.DB.Close.
+func (c *connector) Close() error {
+	if c.leaks == nil {
+		return nil
+	}
+	return c.leaks.report()
+}
@@ -0,0 +1,122 @@
+package decentdb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func openLeakCheckedDB(t *testing.T) *sql.DB {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "leaks.ddb")
+	db, err := sql.Open("decentdb", fmt.Sprintf("file:%s?debug_leaks=true", path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec("CREATE TABLE t (id INT PRIMARY KEY)"); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestLeakCheck_CleanCloseReportsNothing(t *testing.T) {
+	db := openLeakCheckedDB(t)
+	rows, err := db.Query("SELECT id FROM t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+	if err := db.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+}
+
+func TestLeakCheck_ReportsUnclosedStatement(t *testing.T) {
+	db := openLeakCheckedDB(t)
+	ctx := context.Background()
+	c, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.Raw(func(dc any) error {
+		_, err := dc.(*conn).prepareStmt(ctx, "SELECT id FROM t WHERE id = $1")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+
+	err = db.Close()
+	var leakErr *LeakError
+	if !errors.Is(err, ErrLeakedStatements) || !errors.As(err, &leakErr) {
+		t.Fatalf("expected LeakError, got %v", err)
+	}
+	if len(leakErr.Statements) != 1 {
+		t.Fatalf("leaked = %d, want 1", len(leakErr.Statements))
+	}
+	leaked := leakErr.Statements[0]
+	if leaked.Query != "SELECT id FROM t WHERE id = $1" || leaked.Finalized {
+		t.Fatalf("unexpected leak record %+v", leaked)
+	}
+	if !strings.Contains(leaked.Stack, "TestLeakCheck_ReportsUnclosedStatement") {
+		t.Fatalf("stack does not name the preparing test:\n%s", leaked.Stack)
+	}
+}
+
+func TestLeakCheck_FinalizerFlagsDroppedStatement(t *testing.T) {
+	db := openLeakCheckedDB(t)
+	ctx := context.Background()
+	c, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tracker *leakTracker
+	err = c.Raw(func(dc any) error {
+		tracker = dc.(*conn).leaks
+		_, err := dc.(*conn).prepareStmt(ctx, "SELECT COUNT(*) FROM t")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		runtime.GC()
+		tracker.mu.Lock()
+		finalized := false
+		for rec := range tracker.live {
+			finalized = finalized || rec.finalized
+		}
+		tracker.mu.Unlock()
+		if finalized {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("dropped statement was never finalized")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	// The next prepare on the connection frees the finalized handle.
+	var n int
+	if err := c.QueryRowContext(ctx, "SELECT COUNT(*) FROM t").Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+
+	var leakErr *LeakError
+	if err := db.Close(); !errors.As(err, &leakErr) {
+		t.Fatalf("expected LeakError, got %v", err)
+	}
+	if len(leakErr.Statements) != 1 || !leakErr.Statements[0].Finalized {
+		t.Fatalf("unexpected leaks %+v", leakErr.Statements)
+	}
+}
@@ -0,0 +1,61 @@
+package decentdb
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestDriver_ResultRowAndStatementTimeLimits(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "limits.ddb")
+	db, err := sql.Open("decentdb", "file:"+path+"?max_result_rows=5&max_statement_seconds=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	for _, stmt := range []string{
+		"CREATE TABLE t (id INTEGER PRIMARY KEY)",
+		"INSERT INTO t SELECT value FROM generate_series(1, 5000)",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+
+	rows, err := db.Query("SELECT id FROM t ORDER BY id")
+	if err == nil {
+		rows.Close()
+	}
+	if !errors.Is(err, ErrResultRowLimitExceeded) {
+		t.Fatalf("unbounded query error = %v, want ErrResultRowLimitExceeded", err)
+	}
+	var n int64
+	if err := db.QueryRow("SELECT COUNT(*) FROM (SELECT id FROM t LIMIT 5) AS s").Scan(&n); err != nil || n != 5 {
+		t.Fatalf("query within the row limit = %d, %v", n, err)
+	}
+
+	err = db.QueryRow("SELECT COUNT(*) FROM t a JOIN t b ON a.id + b.id < 0").Scan(&n)
+	if !errors.Is(err, ErrStatementTimeout) || !errors.Is(err, ErrTimeout) {
+		t.Fatalf("long query error = %v, want ErrStatementTimeout and ErrTimeout", err)
+	}
+
+	if _, err := db.Exec("PRAGMA max_result_rows = 0"); err != nil {
+		t.Fatal(err)
+	}
+	rows, err = db.Query("SELECT id FROM t")
+	if err != nil {
+		t.Fatalf("query after lifting the row limit: %v", err)
+	}
+	var count int
+	for rows.Next() {
+		count++
+	}
+	if err := rows.Close(); err != nil || count != 5000 {
+		t.Fatalf("query after lifting the row limit returned %d rows, %v", count, err)
+	}
+	if err := db.QueryRow("PRAGMA max_statement_seconds").Scan(&n); err != nil || n != 1 {
+		t.Fatalf("max_statement_seconds = %d, %v", n, err)
+	}
+}
@@ -0,0 +1,46 @@
+package decentdb
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"time"
+)
+
+// isLockTableQuery reports whether query is a LOCK TABLE statement, which
+// the engine runs only as an immediate statement.
+func isLockTableQuery(query string, args []driver.NamedValue) bool {
+	if len(args) != 0 {
+		return false
+	}
+	fields := strings.Fields(query)
+	return len(fields) >= 2 && strings.EqualFold(fields[0], "LOCK")
+}
+
+// lockTables runs a LOCK TABLE statement. Unless the statement says NOWAIT
+// itself, it is retried with NOWAIT while the tables are held elsewhere, so
+// ctx rather than the busy_timeout PRAGMA bounds the wait.
+func (c *conn) lockTables(ctx context.Context, query string) error {
+	stmt := strings.TrimRight(strings.TrimSpace(query), "; \t\r\n")
+	fields := strings.Fields(stmt)
+	if strings.EqualFold(fields[len(fields)-1], "NOWAIT") {
+		return c.execSessionSQL(stmt)
+	}
+	stmt += " NOWAIT"
+	backoff := 100 * time.Microsecond
+	for {
+		err := c.execSessionSQL(stmt)
+		if err == nil || !errors.Is(err, ErrBusy) {
+			return err
+		}
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		backoff = min(backoff*2, 5*time.Millisecond)
+	}
+}
@@ -0,0 +1,66 @@
+package decentdb
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLockTable_ExcludesOtherWriters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "locktable.ddb")
+	var dbs [2]*DB
+	for i := range dbs {
+		db, err := OpenDirect(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+		dbs[i] = db
+	}
+	maintainer, writer := dbs[0], dbs[1]
+	if _, err := maintainer.Exec("CREATE TABLE jobs (id INT64 PRIMARY KEY)"); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.SetSetting("busy_timeout", "20"); err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := maintainer.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Exec("LOCK TABLE jobs IN EXCLUSIVE MODE"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := writer.Exec("INSERT INTO jobs VALUES (1)"); !errors.Is(err, ErrBusy) {
+		t.Fatalf("insert into locked table = %v, want ErrBusy", err)
+	}
+
+	writerTx, err := writer.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := writer.c.ExecContext(ctx, "LOCK TABLE jobs", nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("LOCK TABLE while held = %v, want deadline exceeded", err)
+	}
+	if _, err := writer.Exec("LOCK TABLE jobs NOWAIT"); !errors.Is(err, ErrBusy) {
+		t.Fatalf("LOCK TABLE NOWAIT while held = %v, want ErrBusy", err)
+	}
+	if err := writerTx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := tx.Exec("INSERT INTO jobs VALUES (2)"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := writer.Exec("INSERT INTO jobs VALUES (1)"); err != nil {
+		t.Fatalf("insert after the lock was released: %v", err)
+	}
+}
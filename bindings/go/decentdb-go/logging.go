@@ -0,0 +1,45 @@
+package decentdb
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+)
+
+var driverLogger atomic.Pointer[slog.Logger]
+
+// SetLogger sends the driver's structured logs to l: connections opening
+// and closing, checkpoints, WithTx retries after busy or conflicting
+// transactions, and result and plan cache activity. Every event is logged at
+// slog.LevelDebug and built only when l's handler enables that level, so a
+// logger left at Info costs a level check per event. A nil l, the default,
+// turns logging off.
+func SetLogger(l *slog.Logger) {
+	driverLogger.Store(l)
+}
+
+// debugLogger returns the driver logger if it records debug events for ctx,
+// or nil.
+func debugLogger(ctx context.Context) *slog.Logger {
+	l := driverLogger.Load()
+	if l == nil || !l.Enabled(ctx, slog.LevelDebug) {
+		return nil
+	}
+	return l
+}
+
+// logDebug logs msg at debug level when enabled. Callers on hot paths check
+// debugLogger first so the attributes are not built for nothing.
+func logDebug(ctx context.Context, msg string, attrs ...slog.Attr) {
+	if l := debugLogger(ctx); l != nil {
+		l.LogAttrs(ctx, slog.LevelDebug, msg, attrs...)
+	}
+}
+
+// errAttr is the error attribute of a logged outcome; err may be nil.
+func errAttr(err error) slog.Attr {
+	if err == nil {
+		return slog.Attr{}
+	}
+	return slog.Any("error", err)
+}
@@ -0,0 +1,98 @@
+package decentdb
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// syncBuffer is a bytes.Buffer safe for a handler shared across goroutines.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func captureLogs(t *testing.T, level slog.Level) *syncBuffer {
+	t.Helper()
+	var buf syncBuffer
+	SetLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: level})))
+	t.Cleanup(func() { SetLogger(nil) })
+	return &buf
+}
+
+func TestSetLogger_LogsLifecycleCheckpointAndCache(t *testing.T) {
+	logs := captureLogs(t, slog.LevelDebug)
+	path := filepath.Join(t.TempDir(), "logs.ddb")
+	db, err := sql.Open("decentdb", fmt.Sprintf("file:%s?result_cache_size=16", path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec("CREATE TABLE t (id INT PRIMARY KEY)"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		var n int
+		if err := db.QueryRow("SELECT COUNT(*) FROM t").Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	direct, err := OpenDirect(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := direct.Checkpoint(); err != nil {
+		t.Fatal(err)
+	}
+	direct.Close()
+
+	out := logs.String()
+	for _, want := range []string{
+		"decentdb: connection opened",
+		"decentdb: result cache miss",
+		"decentdb: result cache hit",
+		"decentdb: connection closed",
+		"decentdb: checkpoint",
+		"path=" + path,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("logs missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestSetLogger_GatedByLevel(t *testing.T) {
+	logs := captureLogs(t, slog.LevelInfo)
+	db, err := OpenDirect(filepath.Join(t.TempDir(), "quiet.ddb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Checkpoint(); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+	if out := logs.String(); out != "" {
+		t.Fatalf("debug events logged at Info level:\n%s", out)
+	}
+}
@@ -0,0 +1,128 @@
+package decentdb
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// structFields caches, per struct type, the field index path for each column
+// name the type accepts.
+var structFields sync.Map // reflect.Type -> map[string][]int
+
+var scannerType = reflect.TypeFor[sql.Scanner]()
+
+// scansWhole reports whether values of t are scanned from a single column as
+// a whole rather than field by field.
+func scansWhole(t reflect.Type) bool {
+	return t.Kind() != reflect.Struct ||
+		t == reflect.TypeFor[time.Time]() ||
+		reflect.PointerTo(t).Implements(scannerType)
+}
+
+// fieldsOf returns the column names t maps, lower-cased. A field's column is
+// its `db` tag, or else its name in snake_case; fields tagged `db:"-"` and
+// unexported fields are skipped, and the fields of embedded structs are
+// promoted unless the embedded field is tagged.
+func fieldsOf(t reflect.Type) map[string][]int {
+	if cached, ok := structFields.Load(t); ok {
+		return cached.(map[string][]int)
+	}
+	fields := make(map[string][]int)
+	var walk func(t reflect.Type, prefix []int)
+	walk = func(t reflect.Type, prefix []int) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			tag, tagged := f.Tag.Lookup("db")
+			if tag == "-" {
+				continue
+			}
+			index := append(append([]int(nil), prefix...), i)
+			if f.Anonymous && !tagged && f.Type.Kind() == reflect.Struct && !scansWhole(f.Type) {
+				walk(f.Type, index)
+				continue
+			}
+			if !f.IsExported() {
+				continue
+			}
+			name := tag
+			if name == "" {
+				name = snakeCase(f.Name)
+			}
+			name = strings.ToLower(name)
+			// An outer field shadows a promoted one of the same name.
+			if existing, ok := fields[name]; !ok || len(existing) > len(index) {
+				fields[name] = index
+			}
+		}
+	}
+	walk(t, nil)
+	cached, _ := structFields.LoadOrStore(t, fields)
+	return cached.(map[string][]int)
+}
+
+// snakeCase converts a Go field name such as "UserID" to "user_id".
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			prevLower := unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1])
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if prevLower || (unicode.IsUpper(runes[i-1]) && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// rowMapper scans result rows into values of type T.
+type rowMapper[T any] struct {
+	// paths holds the field index path of each column, or is nil when T is
+	// scanned whole from a single column.
+	paths [][]int
+}
+
+// newRowMapper maps columns onto T. Struct columns are matched to fields
+// case-insensitively; every column must have a field.
+func newRowMapper[T any](columns []string) (*rowMapper[T], error) {
+	t := reflect.TypeFor[T]()
+	if scansWhole(t) {
+		if len(columns) != 1 {
+			return nil, fmt.Errorf("decentdb: cannot scan %d columns into %s", len(columns), t)
+		}
+		return &rowMapper[T]{}, nil
+	}
+	fields := fieldsOf(t)
+	paths := make([][]int, len(columns))
+	for i, column := range columns {
+		path, ok := fields[strings.ToLower(column)]
+		if !ok {
+			return nil, fmt.Errorf("decentdb: column %q has no matching field in %s", column, t)
+		}
+		paths[i] = path
+	}
+	return &rowMapper[T]{paths: paths}, nil
+}
+
+// scan reads the current row of rows into a new T.
+func (m *rowMapper[T]) scan(rows *sql.Rows) (T, error) {
+	var v T
+	if m.paths == nil {
+		err := rows.Scan(&v)
+		return v, err
+	}
+	target := reflect.ValueOf(&v).Elem()
+	dest := make([]any, len(m.paths))
+	for i, path := range m.paths {
+		dest[i] = target.FieldByIndex(path).Addr().Interface()
+	}
+	err := rows.Scan(dest...)
+	return v, err
+}
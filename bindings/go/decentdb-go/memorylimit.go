@@ -0,0 +1,42 @@
+package decentdb
+
+import (
+	"context"
+	"strconv"
+)
+
+type memoryLimitKey struct{}
+
+// WithStatementMemoryLimit returns a context whose statements may hold at
+// most bytes in sorts, grouping and DISTINCT hash tables, and intermediate
+// results such as join outputs and CTEs; 0 removes the limit. A statement
+// that would exceed it fails with an error wrapping ErrMemoryLimitExceeded
+// instead of exhausting process memory. It overrides the
+// statement_memory_limit_bytes DSN option for statements run with the
+// context, and the connection returns to the DSN value for statements run
+// without it.
+//
+// EXPLAIN ANALYZE reports the peak memory a query reached, which is a good
+// starting point for choosing a limit.
+func WithStatementMemoryLimit(ctx context.Context, bytes int64) context.Context {
+	return context.WithValue(ctx, memoryLimitKey{}, bytes)
+}
+
+// StatementMemoryLimitFromContext returns the limit set by
+// WithStatementMemoryLimit, if any.
+func StatementMemoryLimitFromContext(ctx context.Context) (int64, bool) {
+	if ctx == nil {
+		return 0, false
+	}
+	bytes, ok := ctx.Value(memoryLimitKey{}).(int64)
+	return bytes, ok
+}
+
+// memoryLimitSQL returns the statement that sets a connection's statement
+// memory limit, or restores the configured one when set is false.
+func memoryLimitSQL(bytes int64, set bool) string {
+	if !set {
+		return "PRAGMA statement_memory_limit_bytes = DEFAULT"
+	}
+	return "PRAGMA statement_memory_limit_bytes = " + strconv.FormatInt(bytes, 10)
+}
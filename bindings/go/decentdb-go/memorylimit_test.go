@@ -0,0 +1,52 @@
+package decentdb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestMemoryLimitSQL(t *testing.T) {
+	if got := memoryLimitSQL(0, false); got != "PRAGMA statement_memory_limit_bytes = DEFAULT" {
+		t.Fatalf("memoryLimitSQL(unset) = %q", got)
+	}
+	if got := memoryLimitSQL(1<<20, true); got != "PRAGMA statement_memory_limit_bytes = 1048576" {
+		t.Fatalf("memoryLimitSQL(1 MiB) = %q", got)
+	}
+	if _, ok := StatementMemoryLimitFromContext(context.Background()); ok {
+		t.Fatal("a plain context reported a memory limit")
+	}
+}
+
+func TestDriver_StatementMemoryLimit(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("decentdb", "file:"+filepath.Join(t.TempDir(), "memory.ddb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	for _, stmt := range []string{
+		"CREATE TABLE t (id INTEGER PRIMARY KEY, body TEXT)",
+		"INSERT INTO t SELECT value, repeat('x', 100) FROM generate_series(1, 200)",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	const join = "SELECT COUNT(*) FROM (SELECT a.body, b.body FROM t a JOIN t b ON a.id < b.id ORDER BY a.id, b.id) AS j"
+
+	var n int64
+	err = db.QueryRowContext(WithStatementMemoryLimit(ctx, 1<<20), join).Scan(&n)
+	if !errors.Is(err, ErrMemoryLimitExceeded) {
+		t.Fatalf("limited join error = %v, want ErrMemoryLimitExceeded", err)
+	}
+	if err := db.QueryRowContext(ctx, join).Scan(&n); err != nil || n != 200*199/2 {
+		t.Fatalf("unlimited join = %d, %v", n, err)
+	}
+	if err := db.QueryRowContext(ctx, "PRAGMA statement_memory_limit_bytes").Scan(&n); err != nil || n != 0 {
+		t.Fatalf("statement_memory_limit_bytes after context statement = %d, %v", n, err)
+	}
+}
@@ -0,0 +1,72 @@
+package decentdb
+
+import (
+	"io/fs"
+	"os"
+	"slices"
+	"sync"
+)
+
+// MemVFS is a VFS keeping every file, including the WAL and sidecars, in
+// memory. Databases on it are fast to create and vanish with the MemVFS, so
+// tests need no temp directory or cleanup; the decentdbtest package wraps
+// it. Unlike :memory: databases, a MemVFS database is shared by every
+// connection that opens the same name and survives closing them, so a test
+// can reopen it.
+//
+// A MemVFS must not be used by more than one process, and its contents are
+// lost when the process exits.
+type MemVFS struct {
+	mu    sync.Mutex
+	files map[string]*memFile
+}
+
+// NewMemVFS returns an empty MemVFS.
+func NewMemVFS() *MemVFS {
+	return &MemVFS{files: map[string]*memFile{}}
+}
+
+// Open opens name, creating it when flag includes os.O_CREATE.
+func (v *MemVFS) Open(name string, flag int) (VFSFile, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	f, ok := v.files[name]
+	switch {
+	case ok && flag&os.O_EXCL != 0:
+		return nil, fs.ErrExist
+	case !ok && flag&os.O_CREATE == 0:
+		return nil, fs.ErrNotExist
+	case !ok:
+		f = &memFile{}
+		v.files[name] = f
+	}
+	return f, nil
+}
+
+// Exists reports whether name exists.
+func (v *MemVFS) Exists(name string) (bool, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	_, ok := v.files[name]
+	return ok, nil
+}
+
+// Remove deletes name. Files still open keep their contents.
+func (v *MemVFS) Remove(name string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	delete(v.files, name)
+	return nil
+}
+
+// Names returns the names of the files in v, sorted.
+func (v *MemVFS) Names() []string {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	names := make([]string, 0, len(v.files))
+	for name := range v.files {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
@@ -0,0 +1,98 @@
+package decentdb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrStaleRow is returned by UpdateVersioned when the row no longer has the
+// version the caller read, because another writer updated or deleted it.
+var ErrStaleRow = errors.New("decentdb: stale row")
+
+// Execer is satisfied by *sql.DB, *sql.Tx, *sql.Conn, and *Cluster.
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// VersionedUpdate describes an optimistic update of one row guarded by a
+// version column.
+type VersionedUpdate struct {
+	Table string
+	// KeyColumn names the column identifying the row. It defaults to "id".
+	KeyColumn string
+	Key       any
+	// VersionColumn names the integer version column. It defaults to
+	// "version".
+	VersionColumn string
+	// Version is the version the caller read the row at.
+	Version int64
+	// Set maps the columns to update to their new values. It may be empty to
+	// only bump the version.
+	Set map[string]any
+}
+
+// versionedUpdateSQL returns the UPDATE statement for u and its arguments:
+// the Set values in column name order, then the key and version.
+func versionedUpdateSQL(u VersionedUpdate) (string, []any) {
+	keyColumn := u.KeyColumn
+	if keyColumn == "" {
+		keyColumn = "id"
+	}
+	versionColumn := u.VersionColumn
+	if versionColumn == "" {
+		versionColumn = "version"
+	}
+	versionColumn = quoteIdentifier(versionColumn)
+	names := make([]string, 0, len(u.Set))
+	for name := range u.Set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	assignments := make([]string, 0, len(names)+1)
+	args := make([]any, 0, len(names)+2)
+	for _, name := range names {
+		args = append(args, u.Set[name])
+		assignments = append(assignments, quoteIdentifier(name)+" = $"+strconv.Itoa(len(args)))
+	}
+	assignments = append(assignments, versionColumn+" = "+versionColumn+" + 1")
+	args = append(args, u.Key, u.Version)
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s = $%d AND %s = $%d",
+		quoteRelationName(u.Table), strings.Join(assignments, ", "),
+		quoteIdentifier(keyColumn), len(args)-1, versionColumn, len(args))
+	return query, args
+}
+
+// UpdateVersioned applies u if the row still has version u.Version, bumping
+// the version in the same statement, and returns the new version. If the row
+// was updated or deleted since it was read, nothing changes and the error
+// wraps ErrStaleRow; callers typically re-read the row and try again:
+//
+//	version, err := decentdb.UpdateVersioned(ctx, db, decentdb.VersionedUpdate{
+//		Table:   "accounts",
+//		Key:     id,
+//		Version: readVersion,
+//		Set:     map[string]any{"balance": balance},
+//	})
+//	if errors.Is(err, decentdb.ErrStaleRow) {
+//		// reload and retry
+//	}
+func UpdateVersioned(ctx context.Context, db Execer, u VersionedUpdate) (int64, error) {
+	query, args := versionedUpdateSQL(u)
+	result, err := db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if affected == 0 {
+		return 0, fmt.Errorf("%w: %s row %v is no longer at version %d", ErrStaleRow, u.Table, u.Key, u.Version)
+	}
+	return u.Version + 1, nil
+}
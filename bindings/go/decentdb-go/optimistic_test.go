@@ -0,0 +1,75 @@
+package decentdb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestVersionedUpdateSQL(t *testing.T) {
+	query, args := versionedUpdateSQL(VersionedUpdate{
+		Table:   "accounts",
+		Key:     7,
+		Version: 3,
+		Set:     map[string]any{"owner": "ada", "balance": 10},
+	})
+	want := `UPDATE "accounts" SET "balance" = $1, "owner" = $2, "version" = "version" + 1 WHERE "id" = $3 AND "version" = $4`
+	if query != want {
+		t.Fatalf("versionedUpdateSQL = %q, want %q", query, want)
+	}
+	if !reflect.DeepEqual(args, []any{10, "ada", 7, int64(3)}) {
+		t.Fatalf("args = %v", args)
+	}
+
+	query, _ = versionedUpdateSQL(VersionedUpdate{Table: "docs", KeyColumn: "slug", VersionColumn: "rev", Key: "a"})
+	want = `UPDATE "docs" SET "rev" = "rev" + 1 WHERE "slug" = $1 AND "rev" = $2`
+	if query != want {
+		t.Fatalf("versionedUpdateSQL = %q, want %q", query, want)
+	}
+}
+
+func TestUpdateVersioned(t *testing.T) {
+	db, err := sql.Open("decentdb", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	ctx := context.Background()
+	if _, err := db.Exec("CREATE TABLE accounts (id INT64 PRIMARY KEY, balance INT64, version INT64 NOT NULL DEFAULT 1)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("INSERT INTO accounts (id, balance) VALUES (1, 100)"); err != nil {
+		t.Fatal(err)
+	}
+
+	update := VersionedUpdate{Table: "accounts", Key: 1, Version: 1, Set: map[string]any{"balance": 90}}
+	version, err := UpdateVersioned(ctx, db, update)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != 2 {
+		t.Fatalf("new version = %d, want 2", version)
+	}
+
+	// A second writer still holding version 1 loses.
+	update.Set["balance"] = 50
+	if _, err := UpdateVersioned(ctx, db, update); !errors.Is(err, ErrStaleRow) {
+		t.Fatalf("stale update returned %v, want ErrStaleRow", err)
+	}
+	var balance, stored int64
+	if err := db.QueryRow("SELECT balance, version FROM accounts WHERE id = 1").Scan(&balance, &stored); err != nil {
+		t.Fatal(err)
+	}
+	if balance != 90 || stored != 2 {
+		t.Fatalf("row is balance=%d version=%d, want 90 and 2", balance, stored)
+	}
+
+	update.Key = 2
+	update.Version = 2
+	if _, err := UpdateVersioned(ctx, db, update); !errors.Is(err, ErrStaleRow) {
+		t.Fatalf("update of a missing row returned %v, want ErrStaleRow", err)
+	}
+}
@@ -0,0 +1,340 @@
+package decentdb
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCursor is returned by Paginate when a cursor cannot be decoded
+// or does not match the key columns it is used with.
+var ErrInvalidCursor = errors.New("decentdb: invalid pagination cursor")
+
+// Page is one page of rows returned by Paginate.
+type Page struct {
+	Columns []string
+	Rows    [][]any
+	// NextCursor resumes after the last row of the page. It is empty when
+	// this is the last page.
+	NextCursor string
+}
+
+// Paginate runs query and returns up to limit of its rows that come after
+// cursor in key order, without the cost of OFFSET: the rows are selected
+// with a WHERE clause on keyColumns, so a deep page is as cheap as the
+// first one when an index covers the keys. Pass an empty cursor for the
+// first page and the returned NextCursor for the next:
+//
+//	var cursor string
+//	for {
+//		page, err := decentdb.Paginate(ctx, db, "SELECT id, name FROM users", []string{"id"}, cursor, 100)
+//		if err != nil {
+//			return err
+//		}
+//		// use page.Rows
+//		if page.NextCursor == "" {
+//			break
+//		}
+//		cursor = page.NextCursor
+//	}
+//
+// keyColumns name result columns of query, optionally followed by ASC or
+// DESC. Together they must be unique and non-NULL, so end them with the
+// primary key. The query's own ORDER BY is replaced by key order, and a
+// query with its own LIMIT, OFFSET, or FETCH is rejected, since it would
+// cut the result short before paging. args bind the query's $n
+// placeholders; cursor values are bound after them.
+func Paginate(ctx context.Context, db Queryer, query string, keyColumns []string, cursor string, limit int, args ...any) (*Page, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("decentdb: page limit must be positive, got %d", limit)
+	}
+	keys, err := parsePageKeys(keyColumns)
+	if err != nil {
+		return nil, err
+	}
+	if hasTopLevelLimit(query) {
+		return nil, errors.New("decentdb: Paginate query must not have its own LIMIT, OFFSET, or FETCH")
+	}
+	var after []any
+	if cursor != "" {
+		if after, err = decodePageCursor(cursor, len(keys)); err != nil {
+			return nil, err
+		}
+	}
+	pageQuery, pageArgs := paginateSQL(query, keys, after, limit, args)
+	rows, err := db.QueryContext(ctx, pageQuery, pageArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	positions := make([]int, len(keys))
+	for i, key := range keys {
+		positions[i] = -1
+		for j, column := range columns {
+			if strings.EqualFold(column, key.name) {
+				positions[i] = j
+				break
+			}
+		}
+		if positions[i] < 0 {
+			return nil, fmt.Errorf("decentdb: key column %q is not in the query result", key.name)
+		}
+	}
+
+	page := &Page{Columns: columns}
+	hasMore := false
+	for rows.Next() {
+		if len(page.Rows) == limit {
+			hasMore = true
+			break
+		}
+		row := make([]any, len(columns))
+		dest := make([]any, len(columns))
+		for i := range row {
+			dest[i] = &row[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		page.Rows = append(page.Rows, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if hasMore {
+		last := page.Rows[len(page.Rows)-1]
+		values := make([]any, len(keys))
+		for i, position := range positions {
+			values[i] = last[position]
+		}
+		if page.NextCursor, err = encodePageCursor(keys, values); err != nil {
+			return nil, err
+		}
+	}
+	return page, nil
+}
+
+type pageKey struct {
+	name string
+	desc bool
+}
+
+func parsePageKeys(keyColumns []string) ([]pageKey, error) {
+	if len(keyColumns) == 0 {
+		return nil, errors.New("decentdb: Paginate needs at least one key column")
+	}
+	keys := make([]pageKey, len(keyColumns))
+	for i, column := range keyColumns {
+		fields := strings.Fields(column)
+		switch {
+		case len(fields) == 1:
+		case len(fields) == 2 && strings.EqualFold(fields[1], "ASC"):
+		case len(fields) == 2 && strings.EqualFold(fields[1], "DESC"):
+			keys[i].desc = true
+		default:
+			return nil, fmt.Errorf("decentdb: invalid key column %q", column)
+		}
+		keys[i].name = fields[0]
+	}
+	return keys, nil
+}
+
+// hasTopLevelLimit reports whether query has a LIMIT, OFFSET, or FETCH
+// clause outside parentheses. It skips string literals, quoted identifiers,
+// and comments, so a limit inside a subquery or a literal is not counted.
+func hasTopLevelLimit(query string) bool {
+	depth := 0
+	for i := 0; i < len(query); i++ {
+		ch := query[i]
+		switch {
+		case ch == '\'' || ch == '"':
+			for i++; i < len(query); i++ {
+				if query[i] == ch {
+					if i+1 < len(query) && query[i+1] == ch {
+						i++
+						continue
+					}
+					break
+				}
+			}
+		case ch == '-' && i+1 < len(query) && query[i+1] == '-':
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+		case ch == '/' && i+1 < len(query) && query[i+1] == '*':
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				return false
+			}
+			i += end + 3
+		case ch == '(':
+			depth++
+		case ch == ')':
+			depth--
+		case isIdentByte(ch):
+			start := i
+			for i+1 < len(query) && isIdentByte(query[i+1]) {
+				i++
+			}
+			if depth == 0 {
+				switch strings.ToUpper(query[start : i+1]) {
+				case "LIMIT", "OFFSET", "FETCH":
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func isIdentByte(ch byte) bool {
+	return ch == '_' || ch >= '0' && ch <= '9' || ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z'
+}
+
+// paginateSQL wraps query so that it returns the limit+1 rows after the
+// key values in after, or from the start when after is nil. The extra row
+// tells Paginate whether another page follows.
+func paginateSQL(query string, keys []pageKey, after []any, limit int, args []any) (string, []any) {
+	pageArgs := append([]any(nil), args...)
+	placeholder := func(v any) string {
+		pageArgs = append(pageArgs, v)
+		return "$" + strconv.Itoa(len(pageArgs))
+	}
+	var b strings.Builder
+	b.WriteString("SELECT * FROM (")
+	b.WriteString(strings.TrimRight(strings.TrimSpace(query), ";"))
+	b.WriteString(") AS page_source")
+	if after != nil {
+		// (k1 > v1) OR (k1 = v1 AND k2 > v2) OR ..., with < for DESC keys.
+		terms := make([]string, len(keys))
+		for i := range keys {
+			parts := make([]string, 0, i+1)
+			for j := 0; j < i; j++ {
+				parts = append(parts, quoteIdentifier(keys[j].name)+" = "+placeholder(after[j]))
+			}
+			op := " > "
+			if keys[i].desc {
+				op = " < "
+			}
+			parts = append(parts, quoteIdentifier(keys[i].name)+op+placeholder(after[i]))
+			terms[i] = "(" + strings.Join(parts, " AND ") + ")"
+		}
+		b.WriteString(" WHERE ")
+		b.WriteString(strings.Join(terms, " OR "))
+	}
+	order := make([]string, len(keys))
+	for i, key := range keys {
+		order[i] = quoteIdentifier(key.name)
+		if key.desc {
+			order[i] += " DESC"
+		}
+	}
+	fmt.Fprintf(&b, " ORDER BY %s LIMIT %d", strings.Join(order, ", "), limit+1)
+	return b.String(), pageArgs
+}
+
+// pageCursorValue is one key value in a cursor, tagged with its Go type so
+// it binds back exactly as it was read.
+type pageCursorValue struct {
+	Type  string `json:"t"`
+	Value any    `json:"v"`
+}
+
+func encodePageCursor(keys []pageKey, values []any) (string, error) {
+	encoded := make([]pageCursorValue, len(values))
+	for i, value := range values {
+		switch v := value.(type) {
+		case nil:
+			return "", fmt.Errorf("decentdb: key column %q is NULL; keyset pagination needs non-NULL keys", keys[i].name)
+		case int64:
+			encoded[i] = pageCursorValue{"int64", strconv.FormatInt(v, 10)}
+		case float64:
+			encoded[i] = pageCursorValue{"float64", strconv.FormatFloat(v, 'g', -1, 64)}
+		case bool:
+			encoded[i] = pageCursorValue{"bool", v}
+		case string:
+			encoded[i] = pageCursorValue{"string", v}
+		case []byte:
+			encoded[i] = pageCursorValue{"bytes", base64.StdEncoding.EncodeToString(v)}
+		case time.Time:
+			encoded[i] = pageCursorValue{"time", v.Format(time.RFC3339Nano)}
+		case Decimal:
+			encoded[i] = pageCursorValue{"decimal", decimalText(v)}
+		case UUID:
+			encoded[i] = pageCursorValue{"uuid", v.String()}
+		default:
+			return "", fmt.Errorf("decentdb: key column %q has type %T, which cannot be used in a cursor", keys[i].name, value)
+		}
+	}
+	payload, err := json.Marshal(encoded)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(payload), nil
+}
+
+func decodePageCursor(cursor string, keyCount int) ([]any, error) {
+	payload, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+	var encoded []pageCursorValue
+	if err := json.Unmarshal(payload, &encoded); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+	if len(encoded) != keyCount {
+		return nil, fmt.Errorf("%w: cursor has %d keys, want %d", ErrInvalidCursor, len(encoded), keyCount)
+	}
+	values := make([]any, len(encoded))
+	for i, e := range encoded {
+		if values[i], err = e.decode(); err != nil {
+			return nil, fmt.Errorf("%w: key %d: %v", ErrInvalidCursor, i+1, err)
+		}
+	}
+	return values, nil
+}
+
+func (e pageCursorValue) decode() (any, error) {
+	if e.Type == "bool" {
+		v, ok := e.Value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("bad bool %v", e.Value)
+		}
+		return v, nil
+	}
+	s, ok := e.Value.(string)
+	if !ok {
+		return nil, fmt.Errorf("bad %s value %v", e.Type, e.Value)
+	}
+	switch e.Type {
+	case "int64":
+		return strconv.ParseInt(s, 10, 64)
+	case "float64":
+		return strconv.ParseFloat(s, 64)
+	case "string":
+		return s, nil
+	case "bytes":
+		return base64.StdEncoding.DecodeString(s)
+	case "time":
+		return time.Parse(time.RFC3339Nano, s)
+	case "decimal":
+		d, ok := parseDecimalText(s)
+		if !ok {
+			return nil, fmt.Errorf("bad decimal %q", s)
+		}
+		return d, nil
+	case "uuid":
+		return ParseUUID(s)
+	default:
+		return nil, fmt.Errorf("unknown type %q", e.Type)
+	}
+}
@@ -0,0 +1,99 @@
+package decentdb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPaginateSQL(t *testing.T) {
+	keys, err := parsePageKeys([]string{"created DESC", "id"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	query, args := paginateSQL("SELECT * FROM t WHERE owner = $1;", keys, []any{int64(9), int64(4)}, 10, []any{"ann"})
+	want := `SELECT * FROM (SELECT * FROM t WHERE owner = $1) AS page_source ` +
+		`WHERE ("created" < $2) OR ("created" = $3 AND "id" > $4) ORDER BY "created" DESC, "id" LIMIT 11`
+	if query != want {
+		t.Fatalf("query =\n%s\nwant\n%s", query, want)
+	}
+	if len(args) != 4 || args[0] != "ann" || args[1] != int64(9) || args[3] != int64(4) {
+		t.Fatalf("args = %v", args)
+	}
+	if _, err := parsePageKeys([]string{"id sideways"}); err == nil {
+		t.Fatal("invalid key direction was accepted")
+	}
+}
+
+func TestPaginate_RejectsOwnLimit(t *testing.T) {
+	for query, want := range map[string]bool{
+		"SELECT id FROM t LIMIT 5":                                   true,
+		"SELECT id FROM t ORDER BY id offset 10":                     true,
+		"SELECT id FROM t FETCH FIRST 3 ROWS ONLY;":                  true,
+		"SELECT id FROM t ORDER BY id":                               false,
+		"SELECT id FROM t WHERE id IN (SELECT id FROM u LIMIT 5)":    false,
+		"SELECT 'LIMIT 5' AS note, \"offset\" FROM t -- LIMIT 1":     false,
+		"SELECT id FROM t /* LIMIT 1 */ WHERE limited = $1":          false,
+		"WITH recent AS (SELECT id FROM t LIMIT 5) SELECT id FROM t": false,
+	} {
+		if got := hasTopLevelLimit(query); got != want {
+			t.Errorf("hasTopLevelLimit(%q) = %v, want %v", query, got, want)
+		}
+	}
+	_, err := Paginate(context.Background(), nil, "SELECT id FROM t LIMIT 5", []string{"id"}, "", 10)
+	if err == nil || !strings.Contains(err.Error(), "LIMIT") {
+		t.Fatalf("Paginate with LIMIT: err = %v", err)
+	}
+}
+
+func TestPaginate_WalksAllPages(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("decentdb", "file:"+filepath.Join(t.TempDir(), "paginate.ddb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, stmt := range []string{
+		"CREATE TABLE items (id INTEGER PRIMARY KEY, grp TEXT, name TEXT)",
+		"INSERT INTO items SELECT value, CASE WHEN value % 2 = 0 THEN 'even' ELSE 'odd' END, 'item' FROM generate_series(1, 25)",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+
+	var seen []int64
+	var cursor string
+	pages := 0
+	for {
+		page, err := Paginate(ctx, db, "SELECT id, grp FROM items WHERE name = $1", []string{"grp DESC", "id"}, cursor, 10, "item")
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages++
+		for _, row := range page.Rows {
+			seen = append(seen, row[0].(int64))
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+	if pages != 3 || len(seen) != 25 {
+		t.Fatalf("pages = %d, rows = %v", pages, seen)
+	}
+	// "odd" sorts before "even" descending; ids ascend within each group.
+	if seen[0] != 1 || seen[12] != 25 || seen[13] != 2 || seen[24] != 24 {
+		t.Fatalf("rows out of key order: %v", seen)
+	}
+
+	if _, err := Paginate(ctx, db, "SELECT id FROM items", []string{"id"}, "not-a-cursor", 10); !errors.Is(err, ErrInvalidCursor) {
+		t.Fatalf("bad cursor error = %v, want ErrInvalidCursor", err)
+	}
+	if _, err := Paginate(ctx, db, "SELECT id FROM items", []string{"id", "grp"}, cursor, 10); !errors.Is(err, ErrInvalidCursor) {
+		t.Fatalf("mismatched cursor error = %v, want ErrInvalidCursor", err)
+	}
+}
@@ -0,0 +1,39 @@
+package decentdb
+
+import (
+	"context"
+	"strconv"
+)
+
+type parallelWorkersKey struct{}
+
+// WithMaxParallelWorkers returns a context whose statements may use up to n
+// engine worker threads to filter large scans; 0 means one per core and 1
+// keeps execution on the calling thread. It overrides the
+// max_parallel_workers DSN option for statements run with the context, and
+// the connection returns to the DSN value for statements run without it.
+//
+// The engine joins its workers before it hands a result back, so rows are
+// still read on the goroutine that calls Next and need no extra locking.
+func WithMaxParallelWorkers(ctx context.Context, n int) context.Context {
+	return context.WithValue(ctx, parallelWorkersKey{}, n)
+}
+
+// MaxParallelWorkersFromContext returns the budget set by
+// WithMaxParallelWorkers, if any.
+func MaxParallelWorkersFromContext(ctx context.Context) (int, bool) {
+	if ctx == nil {
+		return 0, false
+	}
+	n, ok := ctx.Value(parallelWorkersKey{}).(int)
+	return n, ok
+}
+
+// parallelWorkersSQL returns the statement that sets a connection's worker
+// budget, or restores the configured one when set is false.
+func parallelWorkersSQL(n int, set bool) string {
+	if !set {
+		return "PRAGMA max_parallel_workers = DEFAULT"
+	}
+	return "PRAGMA max_parallel_workers = " + strconv.Itoa(n)
+}
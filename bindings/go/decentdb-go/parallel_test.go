@@ -0,0 +1,125 @@
+package decentdb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestParallelWorkersSQL(t *testing.T) {
+	if got := parallelWorkersSQL(0, false); got != "PRAGMA max_parallel_workers = DEFAULT" {
+		t.Fatalf("parallelWorkersSQL(unset) = %q", got)
+	}
+	if got := parallelWorkersSQL(4, true); got != "PRAGMA max_parallel_workers = 4" {
+		t.Fatalf("parallelWorkersSQL(4) = %q", got)
+	}
+	if _, ok := MaxParallelWorkersFromContext(context.Background()); ok {
+		t.Fatal("a plain context reported a worker budget")
+	}
+	if n, ok := MaxParallelWorkersFromContext(WithMaxParallelWorkers(context.Background(), 0)); !ok || n != 0 {
+		t.Fatalf("MaxParallelWorkersFromContext = %d, %v, want 0, true", n, ok)
+	}
+}
+
+func TestDriver_ParallelScansFromManyGoroutines(t *testing.T) {
+	ctx := context.Background()
+	dsn := fmt.Sprintf("file:%s?max_parallel_workers=2", filepath.Join(t.TempDir(), "parallel.ddb"))
+	db, err := sql.Open("decentdb", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	const rows = 40000
+	if _, err := db.Exec("CREATE TABLE t (id INTEGER PRIMARY KEY, v INTEGER)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(fmt.Sprintf("INSERT INTO t SELECT value, value %% 97 FROM generate_series(1, %d)", rows)); err != nil {
+		t.Fatal(err)
+	}
+	var want int64
+	for id := int64(1); id <= rows; id++ {
+		if id%97%7 == 3 {
+			want += id
+		}
+	}
+
+	sum := func(ctx context.Context) (int64, error) {
+		r, err := db.QueryContext(ctx, "SELECT id FROM t WHERE v % 7 = $1", 3)
+		if err != nil {
+			return 0, err
+		}
+		defer r.Close()
+		var total int64
+		for r.Next() {
+			var id int64
+			if err := r.Scan(&id); err != nil {
+				return 0, err
+			}
+			total += id
+		}
+		return total, r.Err()
+	}
+
+	budgets := []func(context.Context) context.Context{
+		func(ctx context.Context) context.Context { return ctx },
+		func(ctx context.Context) context.Context { return WithMaxParallelWorkers(ctx, 0) },
+		func(ctx context.Context) context.Context { return WithMaxParallelWorkers(ctx, 1) },
+		func(ctx context.Context) context.Context { return WithMaxParallelWorkers(ctx, 4) },
+	}
+	var wg sync.WaitGroup
+	errs := make(chan error, 16)
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(budget func(context.Context) context.Context) {
+			defer wg.Done()
+			got, err := sum(budget(ctx))
+			if err == nil && got != want {
+				err = fmt.Errorf("sum = %d, want %d", got, want)
+			}
+			if err != nil {
+				errs <- err
+			}
+		}(budgets[i%len(budgets)])
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	c, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	state := func() (int, bool) {
+		var workers int
+		var set bool
+		if err := c.Raw(func(dc any) error {
+			workers, set = dc.(*conn).workers, dc.(*conn).workersSet
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		return workers, set
+	}
+	if _, err := c.ExecContext(WithMaxParallelWorkers(ctx, 3), "SELECT 1"); err != nil {
+		t.Fatal(err)
+	}
+	if workers, set := state(); workers != 3 || !set {
+		t.Fatalf("connection budget = %d, %v after a statement with a budget", workers, set)
+	}
+	if _, err := c.ExecContext(ctx, "SELECT 1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, set := state(); set {
+		t.Fatal("connection kept the context budget for a statement without one")
+	}
+	if _, err := c.ExecContext(WithMaxParallelWorkers(ctx, -1), "SELECT 1"); err == nil {
+		t.Fatal("a negative worker budget was accepted")
+	}
+}
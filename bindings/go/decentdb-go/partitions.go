@@ -0,0 +1,144 @@
+package decentdb
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// partitionBoundLiteral renders a range partition bound as SQL. A nil bound
+// renders as unbounded, which is MINVALUE or MAXVALUE depending on the side.
+func partitionBoundLiteral(v any, unbounded string) (string, error) {
+	switch b := v.(type) {
+	case nil:
+		return unbounded, nil
+	case int:
+		return strconv.FormatInt(int64(b), 10), nil
+	case int32:
+		return strconv.FormatInt(int64(b), 10), nil
+	case int64:
+		return strconv.FormatInt(b, 10), nil
+	case uint32:
+		return strconv.FormatUint(uint64(b), 10), nil
+	case uint64:
+		return strconv.FormatUint(b, 10), nil
+	case float64:
+		return strconv.FormatFloat(b, 'g', -1, 64), nil
+	case string:
+		return `'` + strings.ReplaceAll(b, `'`, `''`) + `'`, nil
+	case time.Time:
+		b = b.UTC()
+		if b.Equal(b.Truncate(24 * time.Hour)) {
+			return `'` + b.Format("2006-01-02") + `'`, nil
+		}
+		return `'` + b.Format("2006-01-02 15:04:05.999999") + `'`, nil
+	}
+	return "", fmt.Errorf("decentdb: unsupported partition bound type %T", v)
+}
+
+// rangePartitionSQL returns the CREATE TABLE statement for a range partition
+// of parent holding keys from from (inclusive) to to (exclusive).
+func rangePartitionSQL(parent, name string, from, to any) (string, error) {
+	lower, err := partitionBoundLiteral(from, "MINVALUE")
+	if err != nil {
+		return "", err
+	}
+	upper, err := partitionBoundLiteral(to, "MAXVALUE")
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("CREATE TABLE %s PARTITION OF %s FOR VALUES FROM (%s) TO (%s)",
+		quoteRelationName(name), quoteRelationName(parent), lower, upper), nil
+}
+
+// CreateRangePartition creates partition name of the range-partitioned
+// table parent for keys from from (inclusive) to to (exclusive). A nil from
+// or to leaves that side unbounded. Bounds may be integers, float64,
+// strings, or time.Time values, which are compared as UTC.
+func (c *conn) CreateRangePartition(ctx context.Context, parent, name string, from, to any) error {
+	if c.db == nil {
+		return driver.ErrBadConn
+	}
+	query, err := rangePartitionSQL(parent, name, from, to)
+	if err != nil {
+		return err
+	}
+	_, err = c.ExecContext(ctx, query, nil)
+	return err
+}
+
+// CreateHashPartition creates partition name of the hash-partitioned table
+// parent holding the rows whose key hashes to remainder modulo modulus.
+func (c *conn) CreateHashPartition(ctx context.Context, parent, name string, modulus, remainder int) error {
+	if c.db == nil {
+		return driver.ErrBadConn
+	}
+	query := fmt.Sprintf("CREATE TABLE %s PARTITION OF %s FOR VALUES WITH (MODULUS %d, REMAINDER %d)",
+		quoteRelationName(name), quoteRelationName(parent), modulus, remainder)
+	_, err := c.ExecContext(ctx, query, nil)
+	return err
+}
+
+// DetachPartition removes partition name from parent. The partition keeps
+// its rows as an ordinary table.
+func (c *conn) DetachPartition(ctx context.Context, parent, name string) error {
+	if c.db == nil {
+		return driver.ErrBadConn
+	}
+	query := fmt.Sprintf("ALTER TABLE %s DETACH PARTITION %s",
+		quoteRelationName(parent), quoteRelationName(name))
+	_, err := c.ExecContext(ctx, query, nil)
+	return err
+}
+
+// DropPartition detaches partition name from parent and drops it with all
+// of its rows. If the drop fails, the partition is left detached.
+func (c *conn) DropPartition(ctx context.Context, parent, name string) error {
+	if err := c.DetachPartition(ctx, parent, name); err != nil {
+		return err
+	}
+	_, err := c.ExecContext(ctx, "DROP TABLE "+quoteRelationName(name), nil)
+	return err
+}
+
+// CreateRangePartition creates partition name of the range-partitioned
+// table parent for keys from from (inclusive) to to (exclusive); nil leaves
+// a side unbounded.
+func (d *DB) CreateRangePartition(ctx context.Context, parent, name string, from, to any) error {
+	if atomic.LoadUint32(&d.closed) != 0 {
+		return driver.ErrBadConn
+	}
+	return d.c.CreateRangePartition(ctx, parent, name, from, to)
+}
+
+// CreateHashPartition creates partition name of the hash-partitioned table
+// parent for keys hashing to remainder modulo modulus.
+func (d *DB) CreateHashPartition(ctx context.Context, parent, name string, modulus, remainder int) error {
+	if atomic.LoadUint32(&d.closed) != 0 {
+		return driver.ErrBadConn
+	}
+	return d.c.CreateHashPartition(ctx, parent, name, modulus, remainder)
+}
+
+// DetachPartition removes partition name from parent, keeping it as an
+// ordinary table.
+func (d *DB) DetachPartition(ctx context.Context, parent, name string) error {
+	if atomic.LoadUint32(&d.closed) != 0 {
+		return driver.ErrBadConn
+	}
+	return d.c.DetachPartition(ctx, parent, name)
+}
+
+// DropPartition drops partition name of parent with all of its rows. On a
+// time-partitioned table this expires a whole range at once, without the
+// per-row cost of a DELETE.
+func (d *DB) DropPartition(ctx context.Context, parent, name string) error {
+	if atomic.LoadUint32(&d.closed) != 0 {
+		return driver.ErrBadConn
+	}
+	return d.c.DropPartition(ctx, parent, name)
+}
@@ -0,0 +1,94 @@
+package decentdb
+
+import (
+	"context"
+	"database/sql/driver"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRangePartitionSQL(t *testing.T) {
+	got, err := rangePartitionSQL("events", "events_2024", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `CREATE TABLE "events_2024" PARTITION OF "events" FOR VALUES FROM ('2024-01-01') TO (MAXVALUE)`
+	if got != want {
+		t.Fatalf("rangePartitionSQL = %q, want %q", got, want)
+	}
+	if got, _ := partitionBoundLiteral("it's", "MINVALUE"); got != `'it''s'` {
+		t.Fatalf("string bound rendered as %q", got)
+	}
+	if _, err := rangePartitionSQL("events", "p", []byte("x"), nil); err == nil {
+		t.Fatal("[]byte bound accepted")
+	}
+}
+
+func TestDBPartitions(t *testing.T) {
+	db, err := OpenDirect(filepath.Join(t.TempDir(), "partitions.ddb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	if _, err := db.Exec("CREATE TABLE metrics (id INT, day DATE, value FLOAT) PARTITION BY RANGE (day)"); err != nil {
+		t.Fatal(err)
+	}
+	for month := 1; month <= 3; month++ {
+		from := time.Date(2024, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+		if err := db.CreateRangePartition(ctx, "metrics", from.Format("metrics_2006_01"), from, from.AddDate(0, 1, 0)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, day := range []string{"2024-01-15", "2024-02-15", "2024-03-15", "2024-03-16"} {
+		if _, err := db.Exec("INSERT INTO metrics VALUES ($1, $2, 1.5)", i, day); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.DropPartition(ctx, "metrics", "metrics_2024_01"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.DetachPartition(ctx, "metrics", "metrics_2024_03"); err != nil {
+		t.Fatal(err)
+	}
+	if n := countRows(t, db, "metrics"); n != 1 {
+		t.Fatalf("%d rows left in metrics, want 1", n)
+	}
+	if n := countRows(t, db, "metrics_2024_03"); n != 2 {
+		t.Fatalf("detached partition has %d rows, want 2", n)
+	}
+	if err := db.DropPartition(ctx, "metrics", "metrics_2024_03"); err == nil {
+		t.Fatal("dropped a table that is no longer a partition")
+	}
+
+	if _, err := db.Exec("CREATE TABLE users (id INT PRIMARY KEY, name TEXT) PARTITION BY HASH (id)"); err != nil {
+		t.Fatal(err)
+	}
+	for r := 0; r < 2; r++ {
+		if err := db.CreateHashPartition(ctx, "users", []string{"users_even", "users_odd"}[r], 2, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := db.Exec("INSERT INTO users VALUES (1, 'a'), (2, 'b'), (3, 'c')"); err != nil {
+		t.Fatal(err)
+	}
+	if n := countRows(t, db, "users_odd"); n != 2 {
+		t.Fatalf("users_odd has %d rows, want 2", n)
+	}
+}
+
+func countRows(t *testing.T, db *DB, table string) int64 {
+	t.Helper()
+	rows, err := db.c.QueryContext(context.Background(), "SELECT COUNT(*) FROM "+table, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	dest := make([]driver.Value, 1)
+	if err := rows.Next(dest); err != nil {
+		t.Fatal(err)
+	}
+	n, _ := dest[0].(int64)
+	return n
+}
@@ -0,0 +1,493 @@
+// Package pgwire serves a DecentDB database over the PostgreSQL wire
+// protocol (version 3), so psql, DBeaver, and Postgres drivers can connect
+// for ad-hoc inspection.
+//
+// Both the simple and the extended query protocol are supported. Each client
+// connection gets a dedicated *sql.Conn, so BEGIN/COMMIT issued by the client
+// apply to that session only. SQL is passed to the engine unchanged; DecentDB
+// already uses $N placeholders. The server does not emulate pg_catalog or
+// information_schema, so client features that depend on Postgres system
+// catalogs (such as psql's \d) are unavailable.
+package pgwire
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"crypto/tls"
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/sphildreth/decentdb-go/auth"
+)
+
+const (
+	protocolVersion3 = 196608
+	sslRequestCode   = 80877103
+	gssRequestCode   = 80877104
+	cancelCode       = 80877102
+
+	// maxMessageSize bounds a single frontend message.
+	maxMessageSize = 64 << 20
+)
+
+// ServerVersion is reported to clients as the server_version parameter.
+// Clients use it to pick protocol features; DecentDB speaks the version 3
+// protocol they expect from Postgres 14.
+const ServerVersion = "14.0 (DecentDB)"
+
+// ErrServerClosed is returned by Serve after Close.
+var ErrServerClosed = errors.New("pgwire: server closed")
+
+// Option configures a Server.
+type Option func(*Server)
+
+// WithPassword requires clients to authenticate as user with password using
+// cleartext password authentication. Combine it with WithTLSConfig when
+// clients connect over an untrusted network.
+func WithPassword(user, password string) Option {
+	return func(s *Server) {
+		s.user, s.password = user, password
+	}
+}
+
+// WithAuth authenticates clients with SCRAM-SHA-256 against the users of
+// store and checks every statement against their grants on database, the
+// name this server's database has in the store. It replaces WithPassword.
+// Users without a password (token-only users) cannot connect, since
+// Postgres clients only send passwords.
+func WithAuth(store *auth.Store, database string) Option {
+	return func(s *Server) {
+		s.store, s.database = store, database
+	}
+}
+
+// WithTLSConfig accepts SSLRequest negotiation and upgrades connections to
+// TLS with cfg. Without it, SSL requests are declined and clients fall back
+// to plaintext if they allow it. When cfg.ClientAuth requires a client
+// certificate, clients that do not negotiate TLS are refused.
+func WithTLSConfig(cfg *tls.Config) Option {
+	return func(s *Server) {
+		s.tlsConfig = cfg
+	}
+}
+
+// Server accepts PostgreSQL protocol connections for a database.
+type Server struct {
+	db        *sql.DB
+	user      string
+	password  string
+	store     *auth.Store
+	database  string
+	tlsConfig *tls.Config
+
+	mu        sync.Mutex
+	listeners map[net.Listener]struct{}
+	conns     map[net.Conn]struct{}
+	closed    bool
+	wg        sync.WaitGroup
+}
+
+// New returns a Server for db. The caller keeps ownership of db.
+func New(db *sql.DB, opts ...Option) *Server {
+	s := &Server{
+		db:        db,
+		listeners: make(map[net.Listener]struct{}),
+		conns:     make(map[net.Conn]struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// ListenAndServe listens on the TCP address addr, such as ":5432", and
+// serves connections until Close.
+func (s *Server) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve accepts connections on l until Close. It always returns a non-nil
+// error; after Close the error is ErrServerClosed.
+func (s *Server) Serve(l net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		l.Close()
+		return ErrServerClosed
+	}
+	s.listeners[l] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.listeners, l)
+		s.mu.Unlock()
+	}()
+
+	for {
+		nc, err := l.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return ErrServerClosed
+			}
+			return err
+		}
+		if !s.track(nc) {
+			nc.Close()
+			return ErrServerClosed
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer s.untrack(nc)
+			s.serveConn(nc)
+		}()
+	}
+}
+
+// Close stops every listener, closes client connections, and waits for
+// their sessions to finish. Open client transactions are rolled back.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	var errs []error
+	for l := range s.listeners {
+		if err := l.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for nc := range s.conns {
+		nc.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+	return errors.Join(errs...)
+}
+
+func (s *Server) track(nc net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+	s.conns[nc] = struct{}{}
+	return true
+}
+
+func (s *Server) untrack(nc net.Conn) {
+	s.mu.Lock()
+	delete(s.conns, nc)
+	s.mu.Unlock()
+	nc.Close()
+}
+
+func (s *Server) serveConn(nc net.Conn) {
+	rw, params, principal, err := s.startup(nc)
+	if err != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sqlConn, err := s.db.Conn(ctx)
+	if err != nil {
+		rw.writeError(err)
+		rw.flush()
+		return
+	}
+	sess := newSession(rw, sqlConn)
+	sess.principal, sess.database = principal, s.database
+	defer sess.close()
+	if err := sess.ready(params); err != nil {
+		return
+	}
+	_ = sess.run(ctx)
+}
+
+// startup runs SSL negotiation, reads the startup packet, and
+// authenticates the client. The principal is nil when the server runs
+// without an auth store.
+func (s *Server) startup(nc net.Conn) (*wire, map[string]string, *auth.Principal, error) {
+	conn := nc
+	for {
+		payload, err := readStartupPacket(conn)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		code := binary.BigEndian.Uint32(payload[:4])
+		switch code {
+		case sslRequestCode:
+			if s.tlsConfig == nil {
+				if _, err := conn.Write([]byte{'N'}); err != nil {
+					return nil, nil, nil, err
+				}
+				continue
+			}
+			if _, err := conn.Write([]byte{'S'}); err != nil {
+				return nil, nil, nil, err
+			}
+			tlsConn := tls.Server(conn, s.tlsConfig)
+			if err := tlsConn.Handshake(); err != nil {
+				return nil, nil, nil, err
+			}
+			conn = tlsConn
+			continue
+		case gssRequestCode:
+			if _, err := conn.Write([]byte{'N'}); err != nil {
+				return nil, nil, nil, err
+			}
+			continue
+		case cancelCode:
+			// Cancellation is not supported; the client just closes.
+			return nil, nil, nil, io.EOF
+		case protocolVersion3:
+			if _, ok := conn.(*tls.Conn); !ok && s.requiresTLS() {
+				rw := newWire(conn)
+				rw.writeErrorFields("28000", "server requires an SSL connection with a client certificate")
+				rw.flush()
+				return nil, nil, nil, errors.New("plaintext connection refused")
+			}
+		default:
+			rw := newWire(conn)
+			rw.writeErrorFields("08P01", fmt.Sprintf("unsupported frontend protocol %d.%d", code>>16, code&0xffff))
+			rw.flush()
+			return nil, nil, nil, fmt.Errorf("unsupported protocol %d", code)
+		}
+
+		params := parseStartupParams(payload[4:])
+		rw := newWire(conn)
+		principal, err := s.authenticate(rw, params["user"])
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		return rw, params, principal, nil
+	}
+}
+
+func (s *Server) authenticate(rw *wire, user string) (*auth.Principal, error) {
+	if s.store != nil {
+		return s.authenticateSCRAM(rw, user)
+	}
+	if s.user == "" && s.password == "" {
+		return nil, nil
+	}
+	rw.begin('R')
+	rw.int32(3) // AuthenticationCleartextPassword
+	rw.end()
+	if err := rw.flush(); err != nil {
+		return nil, err
+	}
+	typ, body, err := rw.readMessage()
+	if err != nil {
+		return nil, err
+	}
+	password := string(trimNUL(body))
+	if typ != 'p' ||
+		subtle.ConstantTimeCompare([]byte(user), []byte(s.user)) != 1 ||
+		subtle.ConstantTimeCompare([]byte(password), []byte(s.password)) != 1 {
+		rw.writeErrorFields("28P01", fmt.Sprintf("password authentication failed for user %q", user))
+		rw.flush()
+		return nil, errors.New("authentication failed")
+	}
+	return nil, nil
+}
+
+// authenticateSCRAM runs SASL authentication with SCRAM-SHA-256, the
+// mechanism libpq and the common Postgres drivers support.
+func (s *Server) authenticateSCRAM(rw *wire, user string) (*auth.Principal, error) {
+	fail := func(code, message string) (*auth.Principal, error) {
+		rw.writeErrorFields(code, message)
+		rw.flush()
+		return nil, errors.New(message)
+	}
+	rw.begin('R')
+	rw.int32(10) // AuthenticationSASL
+	rw.cstring(auth.SCRAMSHA256)
+	rw.byte(0)
+	rw.end()
+	if err := rw.flush(); err != nil {
+		return nil, err
+	}
+	typ, body, err := rw.readMessage()
+	if err != nil {
+		return nil, err
+	}
+	r := &reader{b: body}
+	mechanism := r.cstring()
+	n := r.int32()
+	clientFirst := r.next(int(n))
+	if typ != 'p' || r.err != nil {
+		return fail("08P01", "malformed SASLInitialResponse message")
+	}
+	if mechanism != auth.SCRAMSHA256 {
+		return fail("28000", fmt.Sprintf("unsupported SASL mechanism %q", mechanism))
+	}
+	conv := s.store.NewSCRAM(user)
+	serverFirst, err := conv.ServerFirst(clientFirst)
+	if err != nil {
+		return fail("08P01", err.Error())
+	}
+	rw.begin('R')
+	rw.int32(11) // AuthenticationSASLContinue
+	rw.bytes(serverFirst)
+	rw.end()
+	if err := rw.flush(); err != nil {
+		return nil, err
+	}
+	typ, body, err = rw.readMessage()
+	if err != nil {
+		return nil, err
+	}
+	if typ != 'p' {
+		return fail("08P01", "expected SASLResponse message")
+	}
+	serverFinal, principal, err := conv.ServerFinal(body)
+	if err != nil {
+		return fail("28P01", fmt.Sprintf("password authentication failed for user %q", user))
+	}
+	if !principal.CanAccess(s.database) {
+		return fail("42501", fmt.Sprintf("permission denied for database %q", s.database))
+	}
+	rw.begin('R')
+	rw.int32(12) // AuthenticationSASLFinal
+	rw.bytes(serverFinal)
+	rw.end()
+	return principal, nil
+}
+
+// requiresTLS reports whether every client must present a certificate,
+// which plaintext connections cannot do.
+func (s *Server) requiresTLS() bool {
+	if s.tlsConfig == nil {
+		return false
+	}
+	return s.tlsConfig.ClientAuth == tls.RequireAnyClientCert || s.tlsConfig.ClientAuth == tls.RequireAndVerifyClientCert
+}
+
+func readStartupPacket(r io.Reader) ([]byte, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	n := int(binary.BigEndian.Uint32(header[:]))
+	if n < 8 || n > 10000 {
+		return nil, fmt.Errorf("invalid startup packet length %d", n)
+	}
+	payload := make([]byte, n-4)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+func parseStartupParams(b []byte) map[string]string {
+	params := make(map[string]string)
+	for {
+		key, rest, ok := cutNUL(b)
+		if !ok || key == "" {
+			return params
+		}
+		value, rest2, ok := cutNUL(rest)
+		if !ok {
+			return params
+		}
+		params[key] = value
+		b = rest2
+	}
+}
+
+func newBackendKey() (pid, secret uint32) {
+	var buf [8]byte
+	_, _ = rand.Read(buf[:])
+	return binary.BigEndian.Uint32(buf[:4]) & 0x7fffffff, binary.BigEndian.Uint32(buf[4:])
+}
+
+// wire frames backend messages and reads frontend messages.
+type wire struct {
+	r   *bufio.Reader
+	w   *bufio.Writer
+	buf []byte
+}
+
+func newWire(conn net.Conn) *wire {
+	return &wire{r: bufio.NewReader(conn), w: bufio.NewWriter(conn)}
+}
+
+func (w *wire) readMessage() (byte, []byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(w.r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	n := int(binary.BigEndian.Uint32(header[1:]))
+	if n < 4 || n > maxMessageSize {
+		return 0, nil, fmt.Errorf("invalid message length %d", n)
+	}
+	body := make([]byte, n-4)
+	if _, err := io.ReadFull(w.r, body); err != nil {
+		return 0, nil, err
+	}
+	return header[0], body, nil
+}
+
+func (w *wire) begin(typ byte) {
+	w.buf = append(w.buf[:0], typ, 0, 0, 0, 0)
+}
+
+func (w *wire) byte(b byte)      { w.buf = append(w.buf, b) }
+func (w *wire) int16(v int16)    { w.buf = binary.BigEndian.AppendUint16(w.buf, uint16(v)) }
+func (w *wire) int32(v int32)    { w.buf = binary.BigEndian.AppendUint32(w.buf, uint32(v)) }
+func (w *wire) bytes(b []byte)   { w.buf = append(w.buf, b...) }
+func (w *wire) cstring(s string) { w.buf = append(append(w.buf, s...), 0) }
+
+func (w *wire) end() {
+	binary.BigEndian.PutUint32(w.buf[1:5], uint32(len(w.buf)-1))
+	_, _ = w.w.Write(w.buf)
+}
+
+func (w *wire) flush() error { return w.w.Flush() }
+
+func (w *wire) writeError(err error) {
+	w.writeErrorFields(sqlState(err), err.Error())
+}
+
+func (w *wire) writeErrorFields(code, message string) {
+	w.begin('E')
+	w.byte('S')
+	w.cstring("ERROR")
+	w.byte('V')
+	w.cstring("ERROR")
+	w.byte('C')
+	w.cstring(code)
+	w.byte('M')
+	w.cstring(message)
+	w.byte(0)
+	w.end()
+}
+
+func cutNUL(b []byte) (string, []byte, bool) {
+	for i, c := range b {
+		if c == 0 {
+			return string(b[:i]), b[i+1:], true
+		}
+	}
+	return "", nil, false
+}
+
+func trimNUL(b []byte) []byte {
+	if len(b) > 0 && b[len(b)-1] == 0 {
+		return b[:len(b)-1]
+	}
+	return b
+}
@@ -0,0 +1,412 @@
+package pgwire
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/sha256"
+	"crypto/tls"
+	"database/sql"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/sphildreth/decentdb-go/auth"
+	"github.com/sphildreth/decentdb-go/internal/testcerts"
+	"github.com/sphildreth/decentdb-go/tlsconfig"
+)
+
+// testClient speaks just enough of the frontend protocol for the tests.
+type testClient struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+type backendMessage struct {
+	typ  byte
+	body []byte
+}
+
+func dial(t *testing.T, addr string, params ...string) *testClient {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return startup(t, conn, params...)
+}
+
+// dialTLS negotiates TLS with an SSLRequest, as libpq does with
+// sslmode=require, before sending the startup packet.
+func dialTLS(t *testing.T, addr string, cfg *tls.Config, params ...string) (*testClient, error) {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := binary.BigEndian.AppendUint32([]byte{0, 0, 0, 8}, sslRequestCode)
+	if _, err := conn.Write(request); err != nil {
+		t.Fatal(err)
+	}
+	var answer [1]byte
+	if _, err := io.ReadFull(conn, answer[:]); err != nil || answer[0] != 'S' {
+		t.Fatalf("SSLRequest answer = %q, %v", answer[0], err)
+	}
+	tlsConn := tls.Client(conn, cfg)
+	if err := tlsConn.Handshake(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return startup(t, tlsConn, params...), nil
+}
+
+func startup(t *testing.T, conn net.Conn, params ...string) *testClient {
+	t.Helper()
+	c := &testClient{t: t, conn: conn, r: bufio.NewReader(conn)}
+	body := binary.BigEndian.AppendUint32(nil, protocolVersion3)
+	for _, p := range params {
+		body = append(append(body, p...), 0)
+	}
+	body = append(body, 0)
+	packet := binary.BigEndian.AppendUint32(nil, uint32(len(body)+4))
+	if _, err := conn.Write(append(packet, body...)); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func (c *testClient) send(typ byte, body []byte) {
+	c.t.Helper()
+	msg := binary.BigEndian.AppendUint32([]byte{typ}, uint32(len(body)+4))
+	if _, err := c.conn.Write(append(msg, body...)); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// until reads messages up to and including one of type last.
+func (c *testClient) until(last byte) []backendMessage {
+	c.t.Helper()
+	return c.untilAny(string(last))
+}
+
+// untilAny reads messages up to and including one whose type is in lasts.
+func (c *testClient) untilAny(lasts string) []backendMessage {
+	c.t.Helper()
+	var out []backendMessage
+	for {
+		var header [5]byte
+		if _, err := io.ReadFull(c.r, header[:]); err != nil {
+			c.t.Fatal(err)
+		}
+		body := make([]byte, binary.BigEndian.Uint32(header[1:])-4)
+		if _, err := io.ReadFull(c.r, body); err != nil {
+			c.t.Fatal(err)
+		}
+		out = append(out, backendMessage{typ: header[0], body: body})
+		if strings.IndexByte(lasts, header[0]) >= 0 {
+			return out
+		}
+	}
+}
+
+func types(msgs []backendMessage) string {
+	out := make([]byte, len(msgs))
+	for i, m := range msgs {
+		out[i] = m.typ
+	}
+	return string(out)
+}
+
+func dataRow(m backendMessage) []string {
+	n := int(binary.BigEndian.Uint16(m.body))
+	b := m.body[2:]
+	out := make([]string, n)
+	for i := range out {
+		size := int32(binary.BigEndian.Uint32(b))
+		b = b[4:]
+		if size < 0 {
+			out[i] = "NULL"
+			continue
+		}
+		out[i] = string(b[:size])
+		b = b[size:]
+	}
+	return out
+}
+
+func cstrings(parts ...string) []byte {
+	var out []byte
+	for _, p := range parts {
+		out = append(append(out, p...), 0)
+	}
+	return out
+}
+
+func startServer(t *testing.T, opts ...Option) (*Server, string) {
+	t.Helper()
+	tmpDir, err := os.MkdirTemp("", "decentdb-test-pgwire-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(tmpDir) })
+	db, err := sql.Open("decentdb", fmt.Sprintf("file:%s", filepath.Join(tmpDir, "pgwire.ddb")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := New(db, opts...)
+	go srv.Serve(l)
+	t.Cleanup(func() { srv.Close() })
+	return srv, l.Addr().String()
+}
+
+func TestServer_SimpleAndExtendedQuery(t *testing.T) {
+	_, addr := startServer(t)
+	c := dial(t, addr, "user", "ada", "database", "pgwire")
+	defer c.conn.Close()
+	if got := types(c.until('Z')); got[0] != 'R' || got[len(got)-2:] != "KZ" {
+		t.Fatalf("startup messages = %q", got)
+	}
+
+	c.send('Q', cstrings("CREATE TABLE users (id INT64 PRIMARY KEY, name TEXT); INSERT INTO users VALUES (1, 'Ada'), (2, 'Grace')"))
+	msgs := c.until('Z')
+	if got := types(msgs); got != "CCZ" {
+		t.Fatalf("simple query messages = %q", got)
+	}
+	if tag := string(trimNUL(msgs[1].body)); tag != "INSERT 0 2" {
+		t.Fatalf("insert tag = %q", tag)
+	}
+
+	c.send('Q', cstrings("SELECT id, name FROM users ORDER BY id"))
+	msgs = c.until('Z')
+	if got := types(msgs); got != "TDDCZ" {
+		t.Fatalf("select messages = %q", got)
+	}
+	if row := dataRow(msgs[2]); row[0] != "2" || row[1] != "Grace" {
+		t.Fatalf("second row = %q", row)
+	}
+
+	c.send('P', append(cstrings("find", "SELECT name FROM users WHERE id = $1"), 0, 0))
+	c.send('B', append(append(cstrings("", "find"), 0, 0, 0, 1), append(binary.BigEndian.AppendUint32(nil, 1), '1', 0, 0)...))
+	c.send('D', append([]byte{'P'}, 0))
+	c.send('E', append(cstrings(""), 0, 0, 0, 0))
+	c.send('S', nil)
+	msgs = c.until('Z')
+	if got := types(msgs); got != "12TDCZ" {
+		t.Fatalf("extended query messages = %q", got)
+	}
+	if row := dataRow(msgs[3]); row[0] != "Ada" {
+		t.Fatalf("extended row = %q", row)
+	}
+
+	c.send('Q', cstrings("SELECT * FROM missing"))
+	if got := types(c.until('Z')); got != "EZ" {
+		t.Fatalf("error messages = %q", got)
+	}
+	c.send('Q', cstrings("SHOW server_version"))
+	msgs = c.until('Z')
+	if row := dataRow(msgs[1]); row[0] != ServerVersion {
+		t.Fatalf("server_version = %q", row)
+	}
+	c.send('X', nil)
+}
+
+func TestServer_PasswordAuthentication(t *testing.T) {
+	_, addr := startServer(t, WithPassword("ada", "s3cret"))
+
+	c := dial(t, addr, "user", "ada")
+	defer c.conn.Close()
+	msgs := c.until('R')
+	if code := binary.BigEndian.Uint32(msgs[0].body); code != 3 {
+		t.Fatalf("auth request = %d, want cleartext password", code)
+	}
+	c.send('p', cstrings("wrong"))
+	if got := types(c.until('E')); got != "E" {
+		t.Fatalf("bad password messages = %q", got)
+	}
+
+	c2 := dial(t, addr, "user", "ada")
+	defer c2.conn.Close()
+	c2.until('R')
+	c2.send('p', cstrings("s3cret"))
+	if got := types(c2.until('Z')); got[0] != 'R' {
+		t.Fatalf("good password messages = %q", got)
+	}
+}
+
+func TestServer_TLSWithClientCertificates(t *testing.T) {
+	dir := t.TempDir()
+	ca := testcerts.NewCA(t, dir, "ca")
+	serverPair := ca.Server(t, "server")
+	clientPair := ca.Client(t, "client", "ada")
+	r, err := tlsconfig.Server{
+		CertFile:          serverPair.CertFile,
+		KeyFile:           serverPair.KeyFile,
+		ClientCAFile:      ca.File,
+		RequireClientCert: true,
+	}.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, addr := startServer(t, WithTLSConfig(r.Config()))
+	client := func(p testcerts.Pair) *tls.Config {
+		r, err := tlsconfig.Client{CAFile: ca.File, CertFile: p.CertFile, KeyFile: p.KeyFile, ServerName: "localhost"}.Load()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r.Config()
+	}
+
+	c, err := dialTLS(t, addr, client(clientPair), "user", "ada")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.conn.Close()
+	if got := types(c.until('Z')); got[0] != 'R' {
+		t.Fatalf("startup messages = %q", got)
+	}
+	c.send('Q', cstrings("SELECT 1"))
+	if msgs := c.until('Z'); types(msgs) != "TDCZ" || dataRow(msgs[1])[0] != "1" {
+		t.Fatalf("query over TLS = %q", types(msgs))
+	}
+
+	// Without a certificate the server rejects the handshake; TLS 1.3
+	// clients only learn of it on their first read.
+	if c, err := dialTLS(t, addr, client(testcerts.Pair{}), "user", "ada"); err == nil {
+		defer c.conn.Close()
+		var b [1]byte
+		if _, err := c.conn.Read(b[:]); err == nil {
+			t.Fatal("server accepted a client without a certificate")
+		}
+	}
+
+	plain := dial(t, addr, "user", "ada")
+	defer plain.conn.Close()
+	if msgs := plain.untilAny("ER"); msgs[len(msgs)-1].typ != 'E' || errorCode(msgs[len(msgs)-1]) != "28000" {
+		t.Fatalf("plaintext startup = %q", types(msgs))
+	}
+}
+
+// scramLogin completes SASL authentication as a libpq client would.
+func (c *testClient) scramLogin(password string) []backendMessage {
+	c.t.Helper()
+	msgs := c.until('R')
+	if code := binary.BigEndian.Uint32(msgs[0].body); code != 10 || !bytes.Contains(msgs[0].body, []byte(auth.SCRAMSHA256)) {
+		c.t.Fatalf("auth request = %d %q, want SASL", code, msgs[0].body)
+	}
+	clientFirstBare := "n=,r=fyko+d2lbbFgONRv9qkxdawL"
+	initial := cstrings(auth.SCRAMSHA256)
+	initial = binary.BigEndian.AppendUint32(initial, uint32(len(clientFirstBare)+3))
+	c.send('p', append(initial, "n,,"+clientFirstBare...))
+
+	msgs = c.untilAny("RE")
+	last := msgs[len(msgs)-1]
+	if last.typ != 'R' || binary.BigEndian.Uint32(last.body) != 11 {
+		return msgs
+	}
+	serverFirst := string(last.body[4:])
+	attrs := map[string]string{}
+	for _, field := range strings.Split(serverFirst, ",") {
+		attrs[field[:1]] = field[2:]
+	}
+	salt, _ := base64.StdEncoding.DecodeString(attrs["s"])
+	iterations, _ := strconv.Atoi(attrs["i"])
+	salted, err := pbkdf2.Key(sha256.New, password, salt, iterations, sha256.Size)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	mac := func(key []byte, msg string) []byte {
+		h := hmac.New(sha256.New, key)
+		h.Write([]byte(msg))
+		return h.Sum(nil)
+	}
+	clientKey := mac(salted, "Client Key")
+	storedKey := sha256.Sum256(clientKey)
+	withoutProof := "c=biws,r=" + attrs["r"]
+	signature := mac(storedKey[:], clientFirstBare+","+serverFirst+","+withoutProof)
+	for i := range clientKey {
+		clientKey[i] ^= signature[i]
+	}
+	c.send('p', []byte(withoutProof+",p="+base64.StdEncoding.EncodeToString(clientKey)))
+	return c.untilAny("ZE")
+}
+
+// errorCode returns the SQLSTATE of an ErrorResponse.
+func errorCode(m backendMessage) string {
+	for b := m.body; len(b) > 1; {
+		field, rest, _ := bytes.Cut(b[1:], []byte{0})
+		if b[0] == 'C' {
+			return string(field)
+		}
+		b = rest
+	}
+	return ""
+}
+
+func TestServer_SCRAMAuthenticationAndGrants(t *testing.T) {
+	store := auth.NewStore()
+	for _, err := range []error{
+		store.CreateUser("admin", "adminpw"),
+		store.SetSuperuser("admin", true),
+		store.CreateUser("ada", "s3cret"),
+		store.CreateRole("reader"),
+		store.Grant("reader", auth.Grant{Database: "app", Table: "users", Privileges: []auth.Privilege{auth.Select}}),
+		store.GrantRole("ada", "reader"),
+		store.CreateUser("eve", "evepw"),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, addr := startServer(t, WithAuth(store, "app"))
+
+	admin := dial(t, addr, "user", "admin")
+	defer admin.conn.Close()
+	if got := types(admin.scramLogin("adminpw")); !strings.HasPrefix(got, "RR") || !strings.HasSuffix(got, "KZ") {
+		t.Fatalf("admin login messages = %q", got)
+	}
+	admin.send('Q', cstrings("CREATE TABLE users (id INT64 PRIMARY KEY); CREATE TABLE secrets (id INT64 PRIMARY KEY); INSERT INTO users VALUES (1)"))
+	if got := types(admin.until('Z')); got != "CCCZ" {
+		t.Fatalf("admin setup messages = %q", got)
+	}
+
+	ada := dial(t, addr, "user", "ada")
+	defer ada.conn.Close()
+	ada.scramLogin("s3cret")
+	ada.send('Q', cstrings("SELECT id FROM users"))
+	if got := types(ada.until('Z')); got != "TDCZ" {
+		t.Fatalf("granted select messages = %q", got)
+	}
+	for _, query := range []string{"SELECT id FROM secrets", "INSERT INTO users VALUES (2)", "DROP TABLE users"} {
+		ada.send('Q', cstrings(query))
+		msgs := ada.until('Z')
+		if types(msgs) != "EZ" || errorCode(msgs[0]) != "42501" {
+			t.Fatalf("%s: messages = %q, code %q", query, types(msgs), errorCode(msgs[0]))
+		}
+	}
+
+	wrong := dial(t, addr, "user", "ada")
+	defer wrong.conn.Close()
+	if msgs := wrong.scramLogin("nope"); msgs[len(msgs)-1].typ != 'E' || errorCode(msgs[len(msgs)-1]) != "28P01" {
+		t.Fatalf("wrong password messages = %q", types(msgs))
+	}
+	noAccess := dial(t, addr, "user", "eve")
+	defer noAccess.conn.Close()
+	if msgs := noAccess.scramLogin("evepw"); msgs[len(msgs)-1].typ != 'E' || errorCode(msgs[len(msgs)-1]) != "42501" {
+		t.Fatalf("user without grants messages = %q", types(msgs))
+	}
+}
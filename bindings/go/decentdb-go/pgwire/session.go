@@ -0,0 +1,732 @@
+package pgwire
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	decentdb "github.com/sphildreth/decentdb-go"
+	"github.com/sphildreth/decentdb-go/auth"
+	"github.com/sphildreth/decentdb-go/internal/authz"
+)
+
+// session is one client connection bound to a dedicated *sql.Conn.
+type session struct {
+	rw       *wire
+	conn     *sql.Conn
+	params   map[string]string
+	stmts    map[string]*prepared
+	portals  map[string]*portal
+	txStatus byte
+	// principal is the authenticated user, nil without an auth store.
+	principal *auth.Principal
+	database  string
+	// failed is set after an error in the extended protocol; messages are
+	// then discarded until the next Sync.
+	failed bool
+}
+
+type column struct {
+	name string
+	oid  uint32
+}
+
+// shape is what the engine knows about a statement before it runs.
+type shape struct {
+	returnsRows bool
+	columns     []column
+	paramTypes  []string
+	// info is the engine's description, nil for statements it could not
+	// describe.
+	info *decentdb.StmtInfo
+}
+
+type prepared struct {
+	query     string
+	paramOIDs []uint32
+	shape     *shape
+}
+
+type portal struct {
+	stmt    *prepared
+	args    []any
+	formats []int16
+	result  *resultSet
+	pos     int
+}
+
+type resultSet struct {
+	columns []column
+	rows    [][]any
+	tag     string
+}
+
+type stmtInfoer interface {
+	StmtInfo(query string) (*decentdb.StmtInfo, error)
+}
+
+var errNoStmtInfo = errors.New("driver does not describe statements")
+
+func newSession(rw *wire, conn *sql.Conn) *session {
+	return &session{
+		rw:       rw,
+		conn:     conn,
+		stmts:    make(map[string]*prepared),
+		portals:  make(map[string]*portal),
+		txStatus: 'I',
+	}
+}
+
+func (s *session) close() {
+	if s.txStatus != 'I' {
+		_, _ = s.conn.ExecContext(context.Background(), "ROLLBACK")
+	}
+	s.conn.Close()
+}
+
+// ready completes the startup sequence with AuthenticationOk, the server
+// parameters, backend key data, and the first ReadyForQuery.
+func (s *session) ready(startup map[string]string) error {
+	s.params = map[string]string{
+		"server_version":              ServerVersion,
+		"server_encoding":             "UTF8",
+		"client_encoding":             "UTF8",
+		"DateStyle":                   "ISO, MDY",
+		"TimeZone":                    "UTC",
+		"integer_datetimes":           "on",
+		"standard_conforming_strings": "on",
+		"application_name":            startup["application_name"],
+	}
+	s.rw.begin('R')
+	s.rw.int32(0)
+	s.rw.end()
+	for _, key := range []string{"server_version", "server_encoding", "client_encoding", "DateStyle", "TimeZone", "integer_datetimes", "standard_conforming_strings", "application_name"} {
+		s.rw.begin('S')
+		s.rw.cstring(key)
+		s.rw.cstring(s.params[key])
+		s.rw.end()
+	}
+	pid, secret := newBackendKey()
+	s.rw.begin('K')
+	s.rw.int32(int32(pid))
+	s.rw.int32(int32(secret))
+	s.rw.end()
+	return s.readyForQuery()
+}
+
+func (s *session) readyForQuery() error {
+	s.rw.begin('Z')
+	s.rw.byte(s.txStatus)
+	s.rw.end()
+	return s.rw.flush()
+}
+
+func (s *session) run(ctx context.Context) error {
+	for {
+		typ, body, err := s.rw.readMessage()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if typ == 'X' {
+			return nil
+		}
+		if s.failed && typ != 'S' {
+			continue
+		}
+		switch typ {
+		case 'Q':
+			err = s.simpleQuery(ctx, string(trimNUL(body)))
+		case 'P':
+			err = s.parse(ctx, body)
+		case 'B':
+			err = s.bind(body)
+		case 'D':
+			err = s.describe(body)
+		case 'E':
+			err = s.execute(ctx, body)
+		case 'C':
+			err = s.closeObject(body)
+		case 'S':
+			s.failed = false
+			err = s.readyForQuery()
+		case 'H':
+			err = s.rw.flush()
+		default:
+			err = fmt.Errorf("unsupported frontend message %q", typ)
+		}
+		if err != nil {
+			var pe *protocolError
+			if !errors.As(err, &pe) {
+				return err
+			}
+			s.rw.writeError(pe.err)
+			if typ == 'Q' {
+				if err := s.readyForQuery(); err != nil {
+					return err
+				}
+			} else {
+				s.failed = true
+			}
+		}
+	}
+}
+
+// protocolError is an error reported to the client with ErrorResponse; the
+// connection stays open. Any other error from a message handler ends it.
+type protocolError struct{ err error }
+
+func (e *protocolError) Error() string { return e.err.Error() }
+
+func clientError(err error) error { return &protocolError{err: err} }
+
+func (s *session) simpleQuery(ctx context.Context, text string) error {
+	statements := splitStatements(text)
+	if len(statements) == 0 {
+		s.rw.begin('I')
+		s.rw.end()
+		return s.readyForQuery()
+	}
+	for _, query := range statements {
+		rs, err := s.runStatement(ctx, query, s.describeQuery(query), nil)
+		if err != nil {
+			return clientError(err)
+		}
+		if rs.columns != nil {
+			s.writeRowDescription(rs.columns, nil)
+		}
+		if err := s.writeRows(rs.rows, rs.columns, nil); err != nil {
+			return clientError(err)
+		}
+		s.writeCommandComplete(rs.tag)
+	}
+	return s.readyForQuery()
+}
+
+func (s *session) parse(ctx context.Context, body []byte) error {
+	name, rest, ok := cutNUL(body)
+	query, rest2, ok2 := cutNUL(rest)
+	if !ok || !ok2 || len(rest2) < 2 {
+		return clientError(errors.New("malformed Parse message"))
+	}
+	n := int(binary.BigEndian.Uint16(rest2))
+	rest2 = rest2[2:]
+	if len(rest2) < 4*n {
+		return clientError(errors.New("malformed Parse message"))
+	}
+	oids := make([]uint32, n)
+	for i := range oids {
+		oids[i] = binary.BigEndian.Uint32(rest2[4*i:])
+	}
+	query = strings.TrimSpace(query)
+	s.stmts[name] = &prepared{query: query, paramOIDs: oids, shape: s.describeQuery(query)}
+	s.rw.begin('1')
+	s.rw.end()
+	return nil
+}
+
+func (s *session) bind(body []byte) error {
+	r := &reader{b: body}
+	portalName := r.cstring()
+	stmtName := r.cstring()
+	paramFormats := r.int16s()
+	nParams := int(r.int16())
+	raws := make([][]byte, nParams)
+	for i := range raws {
+		n := r.int32()
+		if n >= 0 {
+			raws[i] = r.next(int(n))
+		}
+	}
+	resultFormats := r.int16s()
+	if r.err != nil {
+		return clientError(errors.New("malformed Bind message"))
+	}
+	stmt, ok := s.stmts[stmtName]
+	if !ok {
+		return clientError(fmt.Errorf("prepared statement %q does not exist", stmtName))
+	}
+	args := make([]any, nParams)
+	for i, raw := range raws {
+		var oid uint32
+		if i < len(stmt.paramOIDs) {
+			oid = stmt.paramOIDs[i]
+		}
+		typeName := ""
+		if i < len(stmt.shape.paramTypes) {
+			typeName = stmt.shape.paramTypes[i]
+		}
+		v, err := decodeParam(raw, formatFor(paramFormats, i), oid, typeName)
+		if err != nil {
+			return clientError(fmt.Errorf("parameter $%d: %w", i+1, err))
+		}
+		args[i] = v
+	}
+	s.portals[portalName] = &portal{stmt: stmt, args: args, formats: resultFormats}
+	s.rw.begin('2')
+	s.rw.end()
+	return nil
+}
+
+func (s *session) describe(body []byte) error {
+	if len(body) < 2 {
+		return clientError(errors.New("malformed Describe message"))
+	}
+	name, _, _ := cutNUL(body[1:])
+	switch body[0] {
+	case 'S':
+		stmt, ok := s.stmts[name]
+		if !ok {
+			return clientError(fmt.Errorf("prepared statement %q does not exist", name))
+		}
+		n := max(len(stmt.paramOIDs), len(stmt.shape.paramTypes))
+		s.rw.begin('t')
+		s.rw.int16(int16(n))
+		for i := 0; i < n; i++ {
+			oid := uint32(oidText)
+			if i < len(stmt.paramOIDs) && stmt.paramOIDs[i] != 0 {
+				oid = stmt.paramOIDs[i]
+			} else if i < len(stmt.shape.paramTypes) {
+				oid = oidForType(stmt.shape.paramTypes[i])
+			}
+			s.rw.int32(int32(oid))
+		}
+		s.rw.end()
+		s.writeShape(stmt.shape, nil)
+	case 'P':
+		p, ok := s.portals[name]
+		if !ok {
+			return clientError(fmt.Errorf("portal %q does not exist", name))
+		}
+		s.writeShape(p.stmt.shape, p.formats)
+	default:
+		return clientError(errors.New("malformed Describe message"))
+	}
+	return nil
+}
+
+func (s *session) writeShape(sh *shape, formats []int16) {
+	if !sh.returnsRows {
+		s.rw.begin('n')
+		s.rw.end()
+		return
+	}
+	s.writeRowDescription(sh.columns, formats)
+}
+
+func (s *session) execute(ctx context.Context, body []byte) error {
+	name, rest, ok := cutNUL(body)
+	if !ok || len(rest) < 4 {
+		return clientError(errors.New("malformed Execute message"))
+	}
+	maxRows := int(int32(binary.BigEndian.Uint32(rest)))
+	p, ok := s.portals[name]
+	if !ok {
+		return clientError(fmt.Errorf("portal %q does not exist", name))
+	}
+	if p.result == nil {
+		if p.stmt.query == "" {
+			s.rw.begin('I')
+			s.rw.end()
+			return nil
+		}
+		rs, err := s.runStatement(ctx, p.stmt.query, p.stmt.shape, p.args)
+		if err != nil {
+			return clientError(err)
+		}
+		p.result = rs
+	}
+	rows := p.result.rows[p.pos:]
+	if maxRows > 0 && len(rows) > maxRows {
+		rows = rows[:maxRows]
+	}
+	if err := s.writeRows(rows, p.result.columns, p.formats); err != nil {
+		return clientError(err)
+	}
+	p.pos += len(rows)
+	if p.pos < len(p.result.rows) {
+		s.rw.begin('s')
+		s.rw.end()
+		return nil
+	}
+	s.writeCommandComplete(p.result.tag)
+	return nil
+}
+
+func (s *session) closeObject(body []byte) error {
+	if len(body) < 2 {
+		return clientError(errors.New("malformed Close message"))
+	}
+	name, _, _ := cutNUL(body[1:])
+	if body[0] == 'S' {
+		delete(s.stmts, name)
+	} else {
+		delete(s.portals, name)
+	}
+	s.rw.begin('3')
+	s.rw.end()
+	return nil
+}
+
+// describeQuery asks the engine for the statement's result columns and
+// parameter types. When the driver cannot describe it (transaction control,
+// or a statement the engine rejects), the leading keyword decides whether
+// it returns rows and errors surface when it runs.
+func (s *session) describeQuery(query string) *shape {
+	keyword := leadingKeyword(query)
+	if keyword == "SHOW" {
+		return &shape{returnsRows: true, columns: []column{{name: showTarget(query), oid: oidText}}}
+	}
+	var info *decentdb.StmtInfo
+	err := s.conn.Raw(func(dc any) error {
+		d, ok := dc.(stmtInfoer)
+		if !ok {
+			return errNoStmtInfo
+		}
+		var err error
+		info, err = d.StmtInfo(query)
+		return err
+	})
+	if err != nil {
+		return &shape{returnsRows: keywordReturnsRows(keyword)}
+	}
+	sh := &shape{returnsRows: len(info.Columns) > 0, info: info}
+	for _, c := range info.Columns {
+		sh.columns = append(sh.columns, column{name: c.Name, oid: oidForType(c.TypeName)})
+	}
+	sh.paramTypes = make([]string, info.NumInput())
+	for _, p := range info.Params {
+		if p.Position >= 1 && p.Position <= len(sh.paramTypes) {
+			sh.paramTypes[p.Position-1] = p.TypeName
+		}
+	}
+	return sh
+}
+
+// runStatement executes one statement and buffers its result.
+func (s *session) runStatement(ctx context.Context, query string, sh *shape, args []any) (*resultSet, error) {
+	keyword := leadingKeyword(query)
+	switch keyword {
+	case "SET", "RESET", "DISCARD":
+		return &resultSet{tag: keyword}, nil
+	case "SHOW":
+		target := showTarget(query)
+		value, ok := s.params[target]
+		if !ok {
+			for key, v := range s.params {
+				if strings.EqualFold(key, target) {
+					value, ok = v, true
+				}
+			}
+		}
+		if !ok {
+			return nil, fmt.Errorf("unrecognized configuration parameter %q", target)
+		}
+		return &resultSet{columns: sh.columns, rows: [][]any{{value}}, tag: "SHOW"}, nil
+	}
+	if s.principal != nil {
+		if err := s.principal.Authorize(s.database, authz.Statement(query, sh.info)); err != nil {
+			return nil, err
+		}
+	}
+
+	if !sh.returnsRows {
+		res, err := s.conn.ExecContext(ctx, query, args...)
+		if err != nil {
+			return nil, err
+		}
+		n, _ := res.RowsAffected()
+		tag := commandTag(query, n)
+		s.trackTx(tag)
+		return &resultSet{tag: tag}, nil
+	}
+
+	rows, err := s.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	names, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	rs := &resultSet{columns: make([]column, len(names))}
+	for i, name := range names {
+		rs.columns[i] = column{name: name, oid: oidText}
+		if i < len(sh.columns) {
+			rs.columns[i].oid = sh.columns[i].oid
+		}
+	}
+	for rows.Next() {
+		values := make([]any, len(names))
+		ptrs := make([]any, len(names))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		rs.rows = append(rs.rows, values)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rs.tag = commandTag(query, int64(len(rs.rows)))
+	return rs, nil
+}
+
+func (s *session) trackTx(tag string) {
+	switch tag {
+	case "BEGIN":
+		s.txStatus = 'T'
+	case "COMMIT", "ROLLBACK":
+		s.txStatus = 'I'
+	}
+}
+
+func (s *session) writeRowDescription(columns []column, formats []int16) {
+	s.rw.begin('T')
+	s.rw.int16(int16(len(columns)))
+	for i, c := range columns {
+		s.rw.cstring(c.name)
+		s.rw.int32(0) // table OID
+		s.rw.int16(0) // column attribute number
+		s.rw.int32(int32(c.oid))
+		s.rw.int16(typeSize(c.oid))
+		s.rw.int32(-1) // type modifier
+		s.rw.int16(formatFor(formats, i))
+	}
+	s.rw.end()
+}
+
+func (s *session) writeRows(rows [][]any, columns []column, formats []int16) error {
+	for _, row := range rows {
+		s.rw.begin('D')
+		s.rw.int16(int16(len(row)))
+		for i, v := range row {
+			oid := uint32(oidText)
+			if i < len(columns) {
+				oid = columns[i].oid
+			}
+			encoded, err := encodeValue(v, oid, formatFor(formats, i))
+			if err != nil {
+				return err
+			}
+			if encoded == nil {
+				s.rw.int32(-1)
+				continue
+			}
+			s.rw.int32(int32(len(encoded)))
+			s.rw.bytes(encoded)
+		}
+		s.rw.end()
+	}
+	return nil
+}
+
+func (s *session) writeCommandComplete(tag string) {
+	s.rw.begin('C')
+	s.rw.cstring(tag)
+	s.rw.end()
+}
+
+// formatFor applies the Bind format-code rules: no codes means text, one
+// code applies to every column, otherwise codes are per column.
+func formatFor(formats []int16, i int) int16 {
+	switch {
+	case len(formats) == 0:
+		return 0
+	case len(formats) == 1:
+		return formats[0]
+	case i < len(formats):
+		return formats[i]
+	default:
+		return 0
+	}
+}
+
+func typeSize(oid uint32) int16 {
+	switch oid {
+	case oidBool:
+		return 1
+	case oidInt8, oidFloat8, oidTimestamp, oidTimestamptz, oidTime:
+		return 8
+	case oidDate:
+		return 4
+	case oidUUID:
+		return 16
+	default:
+		return -1
+	}
+}
+
+// commandTag builds the CommandComplete tag Postgres clients expect.
+func commandTag(query string, n int64) string {
+	fields := strings.Fields(strings.ToUpper(query))
+	if len(fields) == 0 {
+		return ""
+	}
+	count := strconv.FormatInt(n, 10)
+	switch fields[0] {
+	case "INSERT":
+		return "INSERT 0 " + count
+	case "UPDATE", "DELETE":
+		return fields[0] + " " + count
+	case "SELECT", "WITH", "VALUES", "TABLE", "EXPLAIN":
+		return "SELECT " + count
+	case "BEGIN", "START":
+		return "BEGIN"
+	case "COMMIT", "END":
+		return "COMMIT"
+	case "ROLLBACK", "ABORT":
+		return "ROLLBACK"
+	case "CREATE", "DROP", "ALTER":
+		if len(fields) > 1 {
+			object := fields[1]
+			if object == "UNIQUE" && len(fields) > 2 {
+				object = fields[2]
+			}
+			return fields[0] + " " + object
+		}
+	}
+	return fields[0]
+}
+
+func keywordReturnsRows(keyword string) bool {
+	switch keyword {
+	case "SELECT", "WITH", "VALUES", "TABLE", "EXPLAIN", "SHOW":
+		return true
+	}
+	return false
+}
+
+func leadingKeyword(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToUpper(strings.TrimRight(fields[0], ";"))
+}
+
+func showTarget(query string) string {
+	fields := strings.Fields(strings.TrimRight(strings.TrimSpace(query), ";"))
+	if len(fields) < 2 {
+		return ""
+	}
+	return strings.ToLower(strings.Join(fields[1:], " "))
+}
+
+// splitStatements splits a simple-query string on semicolons outside
+// quotes and comments, dropping empty statements.
+func splitStatements(text string) []string {
+	var out []string
+	start := 0
+	flush := func(end int) {
+		if stmt := strings.TrimSpace(text[start:end]); stmt != "" {
+			out = append(out, stmt)
+		}
+		start = end + 1
+	}
+	for i := 0; i < len(text); i++ {
+		switch ch := text[i]; {
+		case ch == '\'' || ch == '"':
+			for i++; i < len(text); i++ {
+				if text[i] == ch {
+					if i+1 < len(text) && text[i+1] == ch {
+						i++
+						continue
+					}
+					break
+				}
+			}
+		case ch == '-' && i+1 < len(text) && text[i+1] == '-':
+			for i < len(text) && text[i] != '\n' {
+				i++
+			}
+		case ch == '/' && i+1 < len(text) && text[i+1] == '*':
+			end := strings.Index(text[i+2:], "*/")
+			if end < 0 {
+				i = len(text)
+				break
+			}
+			i += end + 3
+		case ch == ';':
+			flush(i)
+		}
+	}
+	if start < len(text) {
+		flush(len(text))
+	}
+	return out
+}
+
+// reader decodes frontend message fields, recording the first error.
+type reader struct {
+	b   []byte
+	err error
+}
+
+func (r *reader) next(n int) []byte {
+	if r.err != nil || n < 0 || len(r.b) < n {
+		r.err = io.ErrUnexpectedEOF
+		return nil
+	}
+	out := r.b[:n]
+	r.b = r.b[n:]
+	return out
+}
+
+func (r *reader) cstring() string {
+	s, rest, ok := cutNUL(r.b)
+	if !ok {
+		r.err = io.ErrUnexpectedEOF
+		return ""
+	}
+	r.b = rest
+	return s
+}
+
+func (r *reader) int16() int16 {
+	b := r.next(2)
+	if b == nil {
+		return 0
+	}
+	return int16(binary.BigEndian.Uint16(b))
+}
+
+func (r *reader) int32() int32 {
+	b := r.next(4)
+	if b == nil {
+		return 0
+	}
+	return int32(binary.BigEndian.Uint32(b))
+}
+
+func (r *reader) int16s() []int16 {
+	n := int(r.int16())
+	out := make([]int16, 0, max(n, 0))
+	for i := 0; i < n && r.err == nil; i++ {
+		out = append(out, r.int16())
+	}
+	return out
+}
+
+// sqlState picks the SQLSTATE for an engine or authorization error,
+// defaulting to internal_error.
+func sqlState(err error) string {
+	if errors.Is(err, auth.ErrPermissionDenied) {
+		return "42501"
+	}
+	var dbErr *decentdb.DecentDBError
+	if errors.As(err, &dbErr) && dbErr.SQLState != "" {
+		return dbErr.SQLState
+	}
+	return "XX000"
+}
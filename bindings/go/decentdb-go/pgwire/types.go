@@ -0,0 +1,340 @@
+package pgwire
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	decentdb "github.com/sphildreth/decentdb-go"
+)
+
+// Postgres type OIDs advertised in RowDescription and ParameterDescription.
+const (
+	oidBool        = 16
+	oidBytea       = 17
+	oidInt8        = 20
+	oidInt2        = 21
+	oidInt4        = 23
+	oidText        = 25
+	oidFloat4      = 700
+	oidFloat8      = 701
+	oidVarchar     = 1043
+	oidDate        = 1082
+	oidTime        = 1083
+	oidTimestamp   = 1114
+	oidTimestamptz = 1184
+	oidNumeric     = 1700
+	oidUUID        = 2950
+)
+
+var postgresEpoch = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// oidForType maps a DecentDB declared type name to the Postgres type OID
+// reported to clients. Types without a close Postgres equivalent, such as
+// ENUM, INTERVAL, and the network types, are reported as text.
+func oidForType(typeName string) uint32 {
+	switch strings.ToUpper(typeName) {
+	case "INT64", "INT", "INTEGER", "BIGINT":
+		return oidInt8
+	case "FLOAT64", "REAL", "DOUBLE":
+		return oidFloat8
+	case "BOOL", "BOOLEAN":
+		return oidBool
+	case "BLOB", "BYTEA", "GEOMETRY", "GEOGRAPHY":
+		return oidBytea
+	case "DECIMAL", "NUMERIC":
+		return oidNumeric
+	case "UUID":
+		return oidUUID
+	case "TIMESTAMP":
+		return oidTimestamp
+	case "TIMESTAMPTZ":
+		return oidTimestamptz
+	case "DATE":
+		return oidDate
+	case "TIME":
+		return oidTime
+	default:
+		return oidText
+	}
+}
+
+// encodeValue renders v for a column of type oid in the requested format
+// (0 text, 1 binary). A nil result encodes SQL NULL.
+func encodeValue(v any, oid uint32, format int16) ([]byte, error) {
+	if v == nil {
+		return nil, nil
+	}
+	if format == 0 {
+		return []byte(textValue(v, oid)), nil
+	}
+	switch oid {
+	case oidInt8:
+		if n, ok := v.(int64); ok {
+			return binary.BigEndian.AppendUint64(nil, uint64(n)), nil
+		}
+	case oidFloat8:
+		if f, ok := v.(float64); ok {
+			return binary.BigEndian.AppendUint64(nil, math.Float64bits(f)), nil
+		}
+	case oidBool:
+		if b, ok := v.(bool); ok {
+			if b {
+				return []byte{1}, nil
+			}
+			return []byte{0}, nil
+		}
+	case oidBytea:
+		if b, ok := v.([]byte); ok {
+			return b, nil
+		}
+	case oidUUID:
+		if u, ok := uuidValue(v); ok {
+			return u[:], nil
+		}
+	case oidTimestamp, oidTimestamptz:
+		if t, ok := v.(time.Time); ok {
+			return binary.BigEndian.AppendUint64(nil, uint64(t.Sub(postgresEpoch).Microseconds())), nil
+		}
+	case oidDate:
+		if t, ok := v.(time.Time); ok {
+			days := int32(t.Sub(postgresEpoch).Hours() / 24)
+			return binary.BigEndian.AppendUint32(nil, uint32(days)), nil
+		}
+	case oidTime:
+		if d, ok := v.(time.Duration); ok {
+			return binary.BigEndian.AppendUint64(nil, uint64(d.Microseconds())), nil
+		}
+	case oidNumeric:
+		return encodeNumeric(textValue(v, oid))
+	case oidText:
+		return []byte(textValue(v, oid)), nil
+	}
+	return nil, fmt.Errorf("cannot encode %T as binary type %d", v, oid)
+}
+
+// textValue renders v in Postgres text format.
+func textValue(v any, oid uint32) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case int64:
+		return strconv.FormatInt(t, 10)
+	case float64:
+		return strconv.FormatFloat(t, 'g', -1, 64)
+	case bool:
+		if t {
+			return "t"
+		}
+		return "f"
+	case []byte:
+		if oid == oidUUID && len(t) == 16 {
+			var u decentdb.UUID
+			copy(u[:], t)
+			return u.String()
+		}
+		return `\x` + hex.EncodeToString(t)
+	case time.Time:
+		switch oid {
+		case oidDate:
+			return t.Format("2006-01-02")
+		case oidTimestamptz:
+			return t.UTC().Format("2006-01-02 15:04:05.999999-07")
+		default:
+			return t.UTC().Format("2006-01-02 15:04:05.999999")
+		}
+	case time.Duration:
+		return formatTimeOfDay(t)
+	case decentdb.Decimal:
+		return formatDecimal(t)
+	case decentdb.IntervalValue:
+		return fmt.Sprintf("%d mons %d days %s", t.Months, t.Days, formatTimeOfDay(time.Duration(t.Micros)*time.Microsecond))
+	case fmt.Stringer:
+		return t.String()
+	default:
+		return fmt.Sprint(t)
+	}
+}
+
+func uuidValue(v any) (decentdb.UUID, bool) {
+	switch t := v.(type) {
+	case decentdb.UUID:
+		return t, true
+	case []byte:
+		var u decentdb.UUID
+		if len(t) != 16 {
+			return u, false
+		}
+		copy(u[:], t)
+		return u, true
+	case string:
+		u, err := decentdb.ParseUUID(t)
+		return u, err == nil
+	}
+	return decentdb.UUID{}, false
+}
+
+func formatTimeOfDay(d time.Duration) string {
+	sign := ""
+	if d < 0 {
+		sign, d = "-", -d
+	}
+	micros := d.Microseconds()
+	h, m, s, us := micros/3600e6, micros/60e6%60, micros/1e6%60, micros%1e6
+	out := fmt.Sprintf("%s%02d:%02d:%02d", sign, h, m, s)
+	if us != 0 {
+		out += strings.TrimRight(fmt.Sprintf(".%06d", us), "0")
+	}
+	return out
+}
+
+func formatDecimal(d decentdb.Decimal) string {
+	if d.Scale <= 0 {
+		return strconv.FormatInt(d.Unscaled, 10)
+	}
+	sign, digits := "", strconv.FormatInt(d.Unscaled, 10)
+	if d.Unscaled < 0 {
+		sign, digits = "-", digits[1:]
+	}
+	for len(digits) <= d.Scale {
+		digits = "0" + digits
+	}
+	cut := len(digits) - d.Scale
+	return sign + digits[:cut] + "." + digits[cut:]
+}
+
+// encodeNumeric converts decimal text to the Postgres binary numeric form:
+// base-10000 digit groups with a weight, sign, and display scale.
+func encodeNumeric(text string) ([]byte, error) {
+	sign := uint16(0)
+	if strings.HasPrefix(text, "-") {
+		sign, text = 0x4000, text[1:]
+	}
+	intPart, fracPart, _ := strings.Cut(text, ".")
+	if strings.Trim(intPart+fracPart, "0123456789") != "" {
+		return nil, fmt.Errorf("invalid numeric %q", text)
+	}
+	dscale := len(fracPart)
+	for len(intPart)%4 != 0 {
+		intPart = "0" + intPart
+	}
+	for len(fracPart)%4 != 0 {
+		fracPart += "0"
+	}
+	var groups []uint16
+	digits := intPart + fracPart
+	for i := 0; i < len(digits); i += 4 {
+		n, _ := strconv.Atoi(digits[i : i+4])
+		groups = append(groups, uint16(n))
+	}
+	weight := len(intPart)/4 - 1
+	for len(groups) > 0 && groups[0] == 0 {
+		groups = groups[1:]
+		weight--
+	}
+	for len(groups) > 0 && groups[len(groups)-1] == 0 {
+		groups = groups[:len(groups)-1]
+	}
+	if len(groups) == 0 {
+		weight, sign = 0, 0
+	}
+	out := make([]byte, 0, 8+2*len(groups))
+	out = binary.BigEndian.AppendUint16(out, uint16(len(groups)))
+	out = binary.BigEndian.AppendUint16(out, uint16(int16(weight)))
+	out = binary.BigEndian.AppendUint16(out, sign)
+	out = binary.BigEndian.AppendUint16(out, uint16(dscale))
+	for _, g := range groups {
+		out = binary.BigEndian.AppendUint16(out, g)
+	}
+	return out, nil
+}
+
+// decodeParam converts a Bind parameter to a driver argument. oid is the
+// type the client declared in Parse (0 when unspecified) and typeName is the
+// type the engine inferred for the placeholder.
+func decodeParam(raw []byte, format int16, oid uint32, typeName string) (any, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	if oid == 0 {
+		oid = oidForType(typeName)
+	}
+	if format == 1 {
+		return decodeBinaryParam(raw, oid)
+	}
+	text := string(raw)
+	switch oid {
+	case oidInt8, oidInt4, oidInt2:
+		return strconv.ParseInt(text, 10, 64)
+	case oidFloat8, oidFloat4:
+		return strconv.ParseFloat(text, 64)
+	case oidBool:
+		switch strings.ToLower(text) {
+		case "t", "true", "y", "yes", "on", "1":
+			return true, nil
+		case "f", "false", "n", "no", "off", "0":
+			return false, nil
+		}
+		return nil, fmt.Errorf("invalid boolean %q", text)
+	case oidBytea:
+		if strings.HasPrefix(text, `\x`) {
+			return hex.DecodeString(text[2:])
+		}
+		return raw, nil
+	case oidUUID:
+		return decentdb.ParseUUID(text)
+	default:
+		return text, nil
+	}
+}
+
+func decodeBinaryParam(raw []byte, oid uint32) (any, error) {
+	switch oid {
+	case oidInt8:
+		if len(raw) == 8 {
+			return int64(binary.BigEndian.Uint64(raw)), nil
+		}
+	case oidInt4:
+		if len(raw) == 4 {
+			return int64(int32(binary.BigEndian.Uint32(raw))), nil
+		}
+	case oidInt2:
+		if len(raw) == 2 {
+			return int64(int16(binary.BigEndian.Uint16(raw))), nil
+		}
+	case oidFloat8:
+		if len(raw) == 8 {
+			return math.Float64frombits(binary.BigEndian.Uint64(raw)), nil
+		}
+	case oidFloat4:
+		if len(raw) == 4 {
+			return float64(math.Float32frombits(binary.BigEndian.Uint32(raw))), nil
+		}
+	case oidBool:
+		if len(raw) == 1 {
+			return raw[0] != 0, nil
+		}
+	case oidBytea:
+		return append([]byte(nil), raw...), nil
+	case oidUUID:
+		if len(raw) == 16 {
+			var u decentdb.UUID
+			copy(u[:], raw)
+			return u, nil
+		}
+	case oidText, oidVarchar:
+		return string(raw), nil
+	case oidTimestamp, oidTimestamptz:
+		if len(raw) == 8 {
+			micros := int64(binary.BigEndian.Uint64(raw))
+			return postgresEpoch.Add(time.Duration(micros) * time.Microsecond), nil
+		}
+	default:
+		return nil, fmt.Errorf("binary parameters of type %d are not supported", oid)
+	}
+	return nil, fmt.Errorf("invalid binary parameter for type %d", oid)
+}
@@ -0,0 +1,109 @@
+package pgwire
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	decentdb "github.com/sphildreth/decentdb-go"
+)
+
+func TestEncodeNumeric(t *testing.T) {
+	cases := map[string][]byte{
+		// ndigits, weight, sign, dscale, digits...
+		"123.45": {0, 2, 0, 0, 0, 0, 0, 2, 0, 123, 0x11, 0x94},
+		"-0.005": {0, 1, 0xff, 0xff, 0x40, 0, 0, 3, 0, 50},
+		"0":      {0, 0, 0, 0, 0, 0, 0, 0},
+	}
+	for text, want := range cases {
+		got, err := encodeNumeric(text)
+		if err != nil {
+			t.Fatalf("encodeNumeric(%q): %v", text, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("encodeNumeric(%q) = %v, want %v", text, got, want)
+		}
+	}
+	if _, err := encodeNumeric("1e5"); err == nil {
+		t.Fatal("encodeNumeric accepted exponent notation")
+	}
+}
+
+func TestTextValue(t *testing.T) {
+	ts := time.Date(2024, 3, 9, 14, 5, 6, 250000000, time.UTC)
+	cases := []struct {
+		v    any
+		oid  uint32
+		want string
+	}{
+		{int64(-7), oidInt8, "-7"},
+		{true, oidBool, "t"},
+		{[]byte{0xde, 0xad}, oidBytea, `\xdead`},
+		{ts, oidTimestamp, "2024-03-09 14:05:06.25"},
+		{ts, oidTimestamptz, "2024-03-09 14:05:06.25+00"},
+		{ts, oidDate, "2024-03-09"},
+		{decentdb.Decimal{Unscaled: 12345, Scale: 2}, oidNumeric, "123.45"},
+		{90*time.Minute + 1500*time.Microsecond, oidTime, "01:30:00.0015"},
+	}
+	for _, c := range cases {
+		if got := textValue(c.v, c.oid); got != c.want {
+			t.Fatalf("textValue(%v, %d) = %q, want %q", c.v, c.oid, got, c.want)
+		}
+	}
+}
+
+func TestDecodeParam(t *testing.T) {
+	cases := []struct {
+		raw      []byte
+		format   int16
+		oid      uint32
+		typeName string
+		want     any
+	}{
+		{[]byte("42"), 0, 0, "INT64", int64(42)},
+		{[]byte("42"), 0, oidText, "INT64", "42"},
+		{[]byte("on"), 0, oidBool, "", true},
+		{[]byte{0, 0, 0, 9}, 1, oidInt4, "", int64(9)},
+		{[]byte("hello"), 1, 0, "", "hello"},
+		{nil, 0, oidInt8, "", nil},
+	}
+	for _, c := range cases {
+		got, err := decodeParam(c.raw, c.format, c.oid, c.typeName)
+		if err != nil {
+			t.Fatalf("decodeParam(%q, %d, %d, %q): %v", c.raw, c.format, c.oid, c.typeName, err)
+		}
+		if got != c.want {
+			t.Fatalf("decodeParam(%q, %d, %d, %q) = %#v, want %#v", c.raw, c.format, c.oid, c.typeName, got, c.want)
+		}
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	got := splitStatements("SELECT ';'; -- a; comment\nSELECT 2 /* ; */;;  ")
+	want := []string{"SELECT ';'", "-- a; comment\nSELECT 2 /* ; */"}
+	if len(got) != len(want) {
+		t.Fatalf("splitStatements = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("splitStatements = %q, want %q", got, want)
+		}
+	}
+}
+
+func TestCommandTag(t *testing.T) {
+	cases := map[string]string{
+		"insert into t values (1)":       "INSERT 0 3",
+		"UPDATE t SET a = 1":             "UPDATE 3",
+		"SELECT * FROM t":                "SELECT 3",
+		"CREATE UNIQUE INDEX i ON t (a)": "CREATE INDEX",
+		"DROP TABLE t":                   "DROP TABLE",
+		"START TRANSACTION":              "BEGIN",
+		"END":                            "COMMIT",
+	}
+	for query, want := range cases {
+		if got := commandTag(query, 3); got != want {
+			t.Fatalf("commandTag(%q) = %q, want %q", query, got, want)
+		}
+	}
+}
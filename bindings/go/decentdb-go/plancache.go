@@ -0,0 +1,40 @@
+package decentdb
+
+import (
+	"database/sql/driver"
+	"sync/atomic"
+)
+
+// PlanCacheStats reports the engine's plan cache for one handle. Plans are
+// keyed by the statement text with whitespace and comments collapsed, so
+// reformatted copies of a query share an entry; DDL and ANALYZE evict every
+// entry. The counters survive those invalidations and reset only on
+// FlushPlanCache.
+type PlanCacheStats struct {
+	Entries           uint64
+	Hits              uint64
+	Misses            uint64
+	Evictions         uint64
+	SizeBytes         uint64
+	MaxSizeBytes      uint64
+	OversizedRefusals uint64
+	// HitRate is Hits as a percentage of all lookups.
+	HitRate float64
+}
+
+// PlanCacheStats returns the plan cache counters. Tune the cache with the
+// plan_cache_enabled and plan_cache_max_bytes DSN options.
+func (d *DB) PlanCacheStats() (PlanCacheStats, error) {
+	if atomic.LoadUint32(&d.closed) != 0 {
+		return PlanCacheStats{}, driver.ErrBadConn
+	}
+	return d.c.PlanCacheStats()
+}
+
+// FlushPlanCache evicts every cached plan and resets the counters.
+func (d *DB) FlushPlanCache() error {
+	if atomic.LoadUint32(&d.closed) != 0 {
+		return driver.ErrBadConn
+	}
+	return d.c.FlushPlanCache()
+}
@@ -0,0 +1,56 @@
+package decentdb
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpenDirect_PlanCacheStats(t *testing.T) {
+	db, err := OpenDirect(filepath.Join(t.TempDir(), "plans.ddb"))
+	if err != nil {
+		t.Fatalf("OpenDirect failed: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec("CREATE TABLE items (id INT PRIMARY KEY, name TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("INSERT INTO items VALUES ($1, $2)", int64(1), "apple"); err != nil {
+		t.Fatal(err)
+	}
+	update := func(query string) {
+		t.Helper()
+		if _, err := db.Exec(query, int64(1), "pear"); err != nil {
+			t.Fatalf("%q: %v", query, err)
+		}
+	}
+
+	update("UPDATE items SET name = $2 WHERE id = $1")
+	before, err := db.PlanCacheStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	update("UPDATE items\n   SET name = $2 -- by key\n WHERE id = $1")
+	after, err := db.PlanCacheStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.Hits <= before.Hits || after.Entries == 0 {
+		t.Fatalf("reformatted statement missed the plan cache: %+v then %+v", before, after)
+	}
+
+	if err := db.Analyze(context.Background(), "items"); err != nil {
+		t.Fatal(err)
+	}
+	if stats, err := db.PlanCacheStats(); err != nil || stats.Entries != 0 || stats.Hits < after.Hits {
+		t.Fatalf("ANALYZE did not invalidate the plan cache: %+v, %v", stats, err)
+	}
+
+	if err := db.FlushPlanCache(); err != nil {
+		t.Fatal(err)
+	}
+	if stats, err := db.PlanCacheStats(); err != nil || stats.Hits != 0 || stats.Misses != 0 {
+		t.Fatalf("FlushPlanCache did not reset the counters: %+v, %v", stats, err)
+	}
+}
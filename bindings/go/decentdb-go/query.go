@@ -0,0 +1,76 @@
+package decentdb
+
+import (
+	"context"
+	"database/sql"
+	"iter"
+)
+
+// Queryer is satisfied by *sql.DB, *sql.Tx, *sql.Conn, and *Cluster.
+type Queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// Query runs query and yields each result row as a T. When T is a struct,
+// columns are matched to its fields by `db` tag or snake_case field name,
+// ignoring case, and every column must match a field. Other types, as well
+// as time.Time and sql.Scanner implementations, are scanned from a single
+// column:
+//
+//	type User struct {
+//		ID   int64
+//		Name string `db:"display_name"`
+//	}
+//	for user, err := range decentdb.Query[User](ctx, db, "SELECT id, display_name FROM users") {
+//		if err != nil {
+//			return err
+//		}
+//		fmt.Println(user.ID, user.Name)
+//	}
+//
+// An error ends the sequence, which yields it with the zero T. Breaking out
+// of the loop closes the rows.
+func Query[T any](ctx context.Context, db Queryer, query string, args ...any) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		rows, err := db.QueryContext(ctx, query, args...)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		defer rows.Close()
+		columns, err := rows.Columns()
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		mapper, err := newRowMapper[T](columns)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		for rows.Next() {
+			v, err := mapper.scan(rows)
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+			if !yield(v, nil) {
+				return
+			}
+		}
+		if err := rows.Err(); err != nil {
+			yield(zero, err)
+		}
+	}
+}
+
+// QueryOne runs query and returns its first row as a T, mapped as by Query.
+// It returns sql.ErrNoRows when the query returns no rows.
+func QueryOne[T any](ctx context.Context, db Queryer, query string, args ...any) (T, error) {
+	for v, err := range Query[T](ctx, db, query, args...) {
+		return v, err
+	}
+	var zero T
+	return zero, sql.ErrNoRows
+}
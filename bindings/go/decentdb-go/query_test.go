@@ -0,0 +1,116 @@
+package decentdb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSnakeCase(t *testing.T) {
+	for name, want := range map[string]string{
+		"ID":        "id",
+		"UserID":    "user_id",
+		"CreatedAt": "created_at",
+		"HTTPCode":  "http_code",
+		"Line2":     "line2",
+	} {
+		if got := snakeCase(name); got != want {
+			t.Errorf("snakeCase(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+type queryAudit struct {
+	CreatedBy string
+}
+
+type queryUser struct {
+	queryAudit
+	ID       int64
+	Name     string `db:"display_name"`
+	Email    sql.NullString
+	Internal string `db:"-"`
+}
+
+func TestQueryMapsStructs(t *testing.T) {
+	db, err := sql.Open("decentdb", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	ctx := context.Background()
+	if _, err := db.Exec("CREATE TABLE users (id INT64 PRIMARY KEY, display_name TEXT, email TEXT, created_by TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("INSERT INTO users VALUES (1, 'Ada', 'ada@example.com', 'admin'), (2, 'Grace', NULL, 'admin'), (3, 'Linus', NULL, 'ops')"); err != nil {
+		t.Fatal(err)
+	}
+
+	var users []queryUser
+	for user, err := range Query[queryUser](ctx, db, "SELECT id, display_name, EMAIL, created_by FROM users ORDER BY id") {
+		if err != nil {
+			t.Fatal(err)
+		}
+		users = append(users, user)
+	}
+	if len(users) != 3 {
+		t.Fatalf("got %d users, want 3", len(users))
+	}
+	if users[0].ID != 1 || users[0].Name != "Ada" || users[0].Email.String != "ada@example.com" || users[0].CreatedBy != "admin" {
+		t.Fatalf("first user = %+v", users[0])
+	}
+	if users[1].Email.Valid {
+		t.Fatalf("second user email = %+v, want NULL", users[1].Email)
+	}
+
+	// Breaking early closes the rows so the connection can be reused.
+	for _, err := range Query[queryUser](ctx, db, "SELECT id FROM users ORDER BY id") {
+		if err != nil {
+			t.Fatal(err)
+		}
+		break
+	}
+
+	user, err := QueryOne[queryUser](ctx, db, "SELECT id, display_name FROM users WHERE id = $1", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Name != "Linus" {
+		t.Fatalf("QueryOne = %+v", user)
+	}
+	if _, err := QueryOne[queryUser](ctx, db, "SELECT id FROM users WHERE id = $1", 99); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("QueryOne on no rows returned %v, want sql.ErrNoRows", err)
+	}
+	if _, err := QueryOne[queryUser](ctx, db, "SELECT id, 1 AS extra FROM users"); err == nil || !strings.Contains(err.Error(), `"extra"`) {
+		t.Fatalf("unmatched column returned %v", err)
+	}
+}
+
+func TestQueryScalars(t *testing.T) {
+	db, err := sql.Open("decentdb", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	ctx := context.Background()
+
+	count, err := QueryOne[int64](ctx, db, "SELECT 42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 42 {
+		t.Fatalf("QueryOne[int64] = %d, want 42", count)
+	}
+	if _, err := QueryOne[string](ctx, db, "SELECT 'a', 'b'"); err == nil {
+		t.Fatal("scanning two columns into a string succeeded")
+	}
+	for _, err := range Query[int64](ctx, db, "SELECT * FROM missing") {
+		if err == nil {
+			t.Fatal("query of a missing table yielded no error")
+		}
+	}
+}
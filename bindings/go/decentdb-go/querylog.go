@@ -0,0 +1,242 @@
+package decentdb
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// QueryLog records the statements a connector's connections run as JSON
+// lines, one QueryLogEntry per line, for Replay against another database or
+// engine version. Create one with NewQueryLog and attach it with
+// WithQueryLog.
+type QueryLog struct {
+	mu       sync.Mutex
+	enc      *json.Encoder
+	err      error
+	sessions atomic.Int64
+}
+
+// QueryLogEntry is one recorded operation. Entries are written when the
+// operation finishes, so the log is in completion order; Time is when it
+// started.
+type QueryLogEntry struct {
+	Time time.Time `json:"time"`
+	// Session numbers the connection that ran the operation, starting at 1.
+	Session int64 `json:"session"`
+	// Op is "exec", "query", "begin", "commit", or "rollback".
+	Op   string        `json:"op"`
+	SQL  string        `json:"sql,omitempty"`
+	Args []QueryLogArg `json:"args,omitempty"`
+	// ReadOnly is set on a "begin" of a read-only transaction.
+	ReadOnly bool          `json:"read_only,omitempty"`
+	Duration time.Duration `json:"duration_ns"`
+	// RowsAffected is reported by a successful "exec".
+	RowsAffected *int64 `json:"rows_affected,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+// QueryLogArg is a bound parameter in a portable form. Type is "null",
+// "int64", "float64", "bool", "text", "blob", "uuid", "timestamp",
+// "decimal", "geometry", or "geography"; Value holds its text, with blobs
+// and geometries in base64 and timestamps in RFC 3339.
+type QueryLogArg struct {
+	Name  string `json:"name,omitempty"`
+	Type  string `json:"type"`
+	Value string `json:"value,omitempty"`
+}
+
+// NewQueryLog returns a QueryLog that writes to w. Writes are serialized, so
+// w need not be safe for concurrent use.
+func NewQueryLog(w io.Writer) *QueryLog {
+	return &QueryLog{enc: json.NewEncoder(w)}
+}
+
+// Err returns the first error writing the log. Recording stops after a
+// write fails; the statements themselves are not affected.
+func (l *QueryLog) Err() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.err
+}
+
+// WithQueryLog records every statement and transaction boundary of the
+// connector's connections in l. The log sees statements after any
+// interceptors from WithInterceptors, so it holds what the engine ran.
+func WithQueryLog(l *QueryLog) ConnectorOption {
+	return func(c *connector) {
+		c.queryLog = l
+	}
+}
+
+func (l *QueryLog) write(e QueryLogEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.err != nil {
+		return
+	}
+	l.err = l.enc.Encode(e)
+}
+
+// interceptor returns the interceptor that records one new connection.
+func (l *QueryLog) interceptor() Interceptor {
+	return &queryLogSession{log: l, id: l.sessions.Add(1)}
+}
+
+// withQueryLog returns interceptors with l's recorder for a new connection
+// appended innermost.
+func withQueryLog(interceptors []Interceptor, l *QueryLog) []Interceptor {
+	if l == nil {
+		return interceptors
+	}
+	out := make([]Interceptor, 0, len(interceptors)+1)
+	return append(append(out, interceptors...), l.interceptor())
+}
+
+type queryLogSession struct {
+	NoopInterceptor
+	log *QueryLog
+	id  int64
+}
+
+func (s *queryLogSession) record(e QueryLogEntry, start time.Time, err error) {
+	e.Time, e.Session, e.Duration = start, s.id, time.Since(start)
+	if err != nil {
+		e.Error = err.Error()
+	}
+	s.log.write(e)
+}
+
+func (s *queryLogSession) InterceptExec(ctx context.Context, query string, args []driver.NamedValue, next ExecFunc) (driver.Result, error) {
+	start := time.Now()
+	res, err := next(ctx, query, args)
+	e := QueryLogEntry{Op: "exec", SQL: query, Args: queryLogArgs(args)}
+	if err == nil && res != nil {
+		if n, rerr := res.RowsAffected(); rerr == nil {
+			e.RowsAffected = &n
+		}
+	}
+	s.record(e, start, err)
+	return res, err
+}
+
+func (s *queryLogSession) InterceptQuery(ctx context.Context, query string, args []driver.NamedValue, next QueryFunc) (driver.Rows, error) {
+	start := time.Now()
+	rows, err := next(ctx, query, args)
+	s.record(QueryLogEntry{Op: "query", SQL: query, Args: queryLogArgs(args)}, start, err)
+	return rows, err
+}
+
+func (s *queryLogSession) InterceptBeginTx(ctx context.Context, opts driver.TxOptions, next BeginTxFunc) (driver.Tx, error) {
+	start := time.Now()
+	tx, err := next(ctx, opts)
+	s.record(QueryLogEntry{Op: "begin", ReadOnly: opts.ReadOnly}, start, err)
+	return tx, err
+}
+
+func (s *queryLogSession) InterceptCommit(next EndTxFunc) error {
+	start := time.Now()
+	err := next()
+	s.record(QueryLogEntry{Op: "commit"}, start, err)
+	return err
+}
+
+func (s *queryLogSession) InterceptRollback(next EndTxFunc) error {
+	start := time.Now()
+	err := next()
+	s.record(QueryLogEntry{Op: "rollback"}, start, err)
+	return err
+}
+
+func queryLogArgs(args []driver.NamedValue) []QueryLogArg {
+	if len(args) == 0 {
+		return nil
+	}
+	out := make([]QueryLogArg, len(args))
+	for i, arg := range args {
+		out[i] = encodeQueryLogArg(arg.Value)
+		out[i].Name = arg.Name
+	}
+	return out
+}
+
+func encodeQueryLogArg(v any) QueryLogArg {
+	switch v := v.(type) {
+	case nil:
+		return QueryLogArg{Type: "null"}
+	case int64:
+		return QueryLogArg{Type: "int64", Value: strconv.FormatInt(v, 10)}
+	case float64:
+		return QueryLogArg{Type: "float64", Value: strconv.FormatFloat(v, 'g', -1, 64)}
+	case bool:
+		return QueryLogArg{Type: "bool", Value: strconv.FormatBool(v)}
+	case string:
+		return QueryLogArg{Type: "text", Value: v}
+	case []byte:
+		return QueryLogArg{Type: "blob", Value: base64.StdEncoding.EncodeToString(v)}
+	case GeometryWKB:
+		return QueryLogArg{Type: "geometry", Value: base64.StdEncoding.EncodeToString(v)}
+	case GeographyWKB:
+		return QueryLogArg{Type: "geography", Value: base64.StdEncoding.EncodeToString(v)}
+	case UUID:
+		return QueryLogArg{Type: "uuid", Value: v.String()}
+	case time.Time:
+		return QueryLogArg{Type: "timestamp", Value: v.UTC().Format(time.RFC3339Nano)}
+	case Decimal:
+		return QueryLogArg{Type: "decimal", Value: decimalText(v)}
+	}
+	return QueryLogArg{Type: "text", Value: fmt.Sprint(v)}
+}
+
+// Decode returns the parameter value a QueryLogArg records.
+func (a QueryLogArg) Decode() (any, error) {
+	var (
+		v   any
+		err error
+	)
+	switch a.Type {
+	case "null":
+		return nil, nil
+	case "int64":
+		v, err = strconv.ParseInt(a.Value, 10, 64)
+	case "float64":
+		v, err = strconv.ParseFloat(a.Value, 64)
+	case "bool":
+		v, err = strconv.ParseBool(a.Value)
+	case "text":
+		return a.Value, nil
+	case "blob":
+		v, err = base64.StdEncoding.DecodeString(a.Value)
+	case "geometry", "geography":
+		var b []byte
+		b, err = base64.StdEncoding.DecodeString(a.Value)
+		if a.Type == "geometry" {
+			v = GeometryWKB(b)
+		} else {
+			v = GeographyWKB(b)
+		}
+	case "uuid":
+		v, err = ParseUUID(a.Value)
+	case "timestamp":
+		v, err = time.Parse(time.RFC3339Nano, a.Value)
+	case "decimal":
+		d, ok := parseDecimalText(a.Value)
+		if !ok {
+			return nil, fmt.Errorf("decentdb: invalid decimal %q in query log", a.Value)
+		}
+		return d, nil
+	default:
+		return nil, fmt.Errorf("decentdb: unknown query log argument type %q", a.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("decentdb: invalid %s %q in query log: %w", a.Type, a.Value, err)
+	}
+	return v, nil
+}
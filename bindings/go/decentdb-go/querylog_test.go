@@ -0,0 +1,132 @@
+package decentdb
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestQueryLogArgRoundTrip(t *testing.T) {
+	ts := time.Date(2026, 3, 1, 12, 30, 0, 123456000, time.UTC)
+	values := []any{nil, int64(-7), 0.1, true, "o'brien", []byte{0, 1, 2}, ts,
+		Decimal{Unscaled: -1205, Scale: 2}, UUID{1, 2, 3}, GeometryWKB{1, 1}}
+	for _, v := range values {
+		arg := encodeQueryLogArg(v)
+		got, err := arg.Decode()
+		if err != nil {
+			t.Fatalf("Decode(%+v): %v", arg, err)
+		}
+		if !reflect.DeepEqual(got, v) {
+			t.Fatalf("%T round trip: got %#v from %+v", v, got, arg)
+		}
+	}
+	if _, err := (QueryLogArg{Type: "int64", Value: "x"}).Decode(); err == nil {
+		t.Fatal("invalid int64 accepted")
+	}
+	if _, err := (QueryLogArg{Type: "money", Value: "1"}).Decode(); err == nil {
+		t.Fatal("unknown type accepted")
+	}
+}
+
+func TestQueryLogCaptureAndReplay(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	var buf bytes.Buffer
+	qlog := NewQueryLog(&buf)
+	connector, err := NewConnector("file:"+filepath.Join(dir, "source.ddb"), WithQueryLog(qlog))
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := sql.OpenDB(connector)
+	defer source.Close()
+
+	if _, err := source.Exec("CREATE TABLE items (id INT64 PRIMARY KEY, name TEXT NOT NULL UNIQUE, price DECIMAL(10, 2))"); err != nil {
+		t.Fatal(err)
+	}
+	tx, err := source.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, name := range []string{"bolt", "nut", "washer"} {
+		if _, err := tx.Exec("INSERT INTO items (id, name, price) VALUES ($1, $2, $3)", int64(i+1), name, Decimal{Unscaled: int64(10 * (i + 1)), Scale: 2}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := source.Exec("INSERT INTO items (id, name) VALUES ($1, $2)", int64(4), "bolt"); err == nil {
+		t.Fatal("duplicate name accepted")
+	}
+	if _, err := source.Exec("UPDATE items SET price = price * 2 WHERE id > $1", int64(1)); err != nil {
+		t.Fatal(err)
+	}
+	var count int
+	if err := source.QueryRow("SELECT COUNT(*) FROM items").Scan(&count); err != nil || count != 3 {
+		t.Fatalf("count = %d, %v", count, err)
+	}
+	if err := qlog.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	var ops []string
+	var entries []QueryLogEntry
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var e QueryLogEntry
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("log line %q: %v", line, err)
+		}
+		if e.Session < 1 || e.Time.IsZero() {
+			t.Fatalf("entry without session or time: %s", line)
+		}
+		entries = append(entries, e)
+		ops = append(ops, e.Op)
+	}
+	wantOps := []string{"exec", "begin", "exec", "exec", "exec", "commit", "exec", "exec", "query"}
+	if !reflect.DeepEqual(ops, wantOps) {
+		t.Fatalf("ops = %v, want %v", ops, wantOps)
+	}
+	insert := entries[2]
+	if len(insert.Args) != 3 || insert.Args[1] != (QueryLogArg{Type: "text", Value: "bolt"}) ||
+		insert.Args[2] != (QueryLogArg{Type: "decimal", Value: "0.10"}) {
+		t.Fatalf("insert args = %+v", insert.Args)
+	}
+	if entries[6].Error == "" || entries[7].RowsAffected == nil || *entries[7].RowsAffected != 2 {
+		t.Fatalf("failed insert = %+v, update = %+v", entries[6], entries[7])
+	}
+
+	for _, speed := range []float64{0, 50} {
+		target, err := sql.Open("decentdb", "file:"+filepath.Join(dir, "target.ddb"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := Replay(ctx, target, bytes.NewReader(buf.Bytes()), ReplayOptions{Speed: speed})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if speed == 0 {
+			if res.Entries != len(entries) || res.Errors != 1 || len(res.Mismatches) != 0 {
+				t.Fatalf("replay = %+v", res)
+			}
+			var doubled int
+			if err := target.QueryRow("SELECT COUNT(*) FROM items WHERE price >= 0.4").Scan(&doubled); err != nil || doubled != 2 {
+				t.Fatalf("replayed updates = %d, %v", doubled, err)
+			}
+		} else if len(res.Mismatches) == 0 || res.Mismatches[0].Line != 1 || res.Mismatches[0].Got.Error == "" {
+			// The table exists from the first replay, so CREATE TABLE and
+			// every insert now fail.
+			t.Fatalf("second replay = %+v", res)
+		}
+		target.Close()
+	}
+
+	if _, err := Replay(ctx, source, strings.NewReader("{\"op\":\"exec\"}\nnot json\n"), ReplayOptions{}); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Fatalf("malformed log: %v", err)
+	}
+}
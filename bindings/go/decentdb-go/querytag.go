@@ -0,0 +1,54 @@
+package decentdb
+
+import (
+	"context"
+	"strings"
+)
+
+type queryTagKey struct{}
+
+// WithQueryTag returns a context that attributes every statement run with it
+// to tag. The tag is appended to the SQL text as a trailing comment, so it
+// appears in engine slow-query records and SQL fingerprints without changing
+// what the statement does. An empty tag removes any tag set by a parent.
+func WithQueryTag(ctx context.Context, tag string) context.Context {
+	return context.WithValue(ctx, queryTagKey{}, sanitizeQueryTag(tag))
+}
+
+// QueryTagFromContext returns the tag set by WithQueryTag, if any.
+func QueryTagFromContext(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	tag, ok := ctx.Value(queryTagKey{}).(string)
+	return tag, ok && tag != ""
+}
+
+// sanitizeQueryTag keeps a tag from terminating its comment or spanning lines.
+func sanitizeQueryTag(tag string) string {
+	tag = strings.ReplaceAll(tag, "*/", "* /")
+	tag = strings.ReplaceAll(tag, "/*", "/ *")
+	tag = strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\r' || r == 0 {
+			return ' '
+		}
+		return r
+	}, tag)
+	return strings.TrimSpace(tag)
+}
+
+// taggedQuery returns query with the context's tag appended as a comment.
+// Trailing semicolons are dropped first so the comment stays part of the
+// statement, and a newline ends any trailing line comment.
+func taggedQuery(ctx context.Context, query string) string {
+	tag, ok := QueryTagFromContext(ctx)
+	if !ok {
+		return query
+	}
+	trimmed := strings.TrimRight(strings.TrimRightFunc(query, isSQLSpace), ";")
+	return trimmed + "\n/* decentdb:tag=" + tag + " */"
+}
+
+func isSQLSpace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == '\f'
+}
@@ -0,0 +1,57 @@
+package decentdb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTaggedQuery(t *testing.T) {
+	ctx := context.Background()
+	if got := taggedQuery(ctx, "SELECT 1"); got != "SELECT 1" {
+		t.Fatalf("untagged query changed: %q", got)
+	}
+
+	tagged := WithQueryTag(ctx, "checkout-service:get-cart")
+	if got := taggedQuery(tagged, "SELECT 1;  \n"); got != "SELECT 1\n/* decentdb:tag=checkout-service:get-cart */" {
+		t.Fatalf("unexpected tagged query: %q", got)
+	}
+	if got := taggedQuery(WithQueryTag(ctx, "evil */ DROP TABLE t; /*\nx"), "SELECT 1"); got != "SELECT 1\n/* decentdb:tag=evil * / DROP TABLE t; / * x */" {
+		t.Fatalf("tag was not sanitized: %q", got)
+	}
+	if _, ok := QueryTagFromContext(WithQueryTag(tagged, "")); ok {
+		t.Fatal("empty tag should clear the parent tag")
+	}
+}
+
+func TestDriver_QueryTagExecutes(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "decentdb-test-query-tag-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	db, err := sql.Open("decentdb", fmt.Sprintf("file:%s", filepath.Join(tmpDir, "tag.ddb")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := WithQueryTag(context.Background(), "tests:query-tag")
+	if _, err := db.ExecContext(ctx, "CREATE TABLE carts (id INT PRIMARY KEY, note TEXT);"); err != nil {
+		t.Fatalf("tagged create failed: %v", err)
+	}
+	if _, err := db.ExecContext(ctx, "INSERT INTO carts (id, note) VALUES ($1, $2) -- trailing comment", 1, "a"); err != nil {
+		t.Fatalf("tagged insert failed: %v", err)
+	}
+	var note string
+	if err := db.QueryRowContext(ctx, "SELECT note FROM carts WHERE id = $1", 1).Scan(&note); err != nil {
+		t.Fatalf("tagged query failed: %v", err)
+	}
+	if note != "a" {
+		t.Fatalf("unexpected note %q", note)
+	}
+}
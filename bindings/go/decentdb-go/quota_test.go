@@ -0,0 +1,44 @@
+package decentdb
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+func TestMaxDatabaseSize_RejectsGrowth(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "quota.ddb")
+	db, err := sql.Open("decentdb", fmt.Sprintf("file:%s?max_database_size_bytes=262144", dbPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("CREATE TABLE t (id INT PRIMARY KEY, body TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+
+	var batches int
+	for ; batches < 20; batches++ {
+		_, err = db.Exec("INSERT INTO t SELECT value, repeat('x', 1000) FROM generate_series($1, $2)",
+			batches*100+1, batches*100+100)
+		if err != nil {
+			break
+		}
+	}
+	if !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("expected ErrQuotaExceeded after %d batches, got %v", batches, err)
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM t").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != batches*100 {
+		t.Fatalf("count = %d, want %d: the rejected batch must not be written", count, batches*100)
+	}
+	if _, err := db.Exec("DELETE FROM t"); err != nil {
+		t.Fatalf("DELETE over quota: %v", err)
+	}
+}
@@ -0,0 +1,40 @@
+package decentdb
+
+import (
+	"context"
+	"strconv"
+)
+
+type readAheadKey struct{}
+
+// WithReadAhead returns a context whose statements read up to pages database
+// pages ahead when a scan reads the file sequentially, fetching them with a
+// single I/O; 0 turns read-ahead off. It overrides the read_ahead_pages DSN
+// option for statements run with the context, and the connection returns to
+// the DSN value for statements run without it. Read-ahead helps cold-cache
+// scans on spinning disks and network filesystems and rarely matters once
+// the file is in the OS page cache.
+//
+// A single statement can ask for the same thing with a leading
+// /*+ READ_AHEAD(n) */ comment.
+func WithReadAhead(ctx context.Context, pages int) context.Context {
+	return context.WithValue(ctx, readAheadKey{}, pages)
+}
+
+// ReadAheadFromContext returns the window set by WithReadAhead, if any.
+func ReadAheadFromContext(ctx context.Context) (int, bool) {
+	if ctx == nil {
+		return 0, false
+	}
+	pages, ok := ctx.Value(readAheadKey{}).(int)
+	return pages, ok
+}
+
+// readAheadSQL returns the statement that sets a connection's read-ahead
+// window, or restores the configured one when set is false.
+func readAheadSQL(pages int, set bool) string {
+	if !set {
+		return "PRAGMA read_ahead_pages = DEFAULT"
+	}
+	return "PRAGMA read_ahead_pages = " + strconv.Itoa(pages)
+}
@@ -0,0 +1,77 @@
+package decentdb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadAheadSQL(t *testing.T) {
+	if got := readAheadSQL(0, false); got != "PRAGMA read_ahead_pages = DEFAULT" {
+		t.Fatalf("readAheadSQL(unset) = %q", got)
+	}
+	if got := readAheadSQL(64, true); got != "PRAGMA read_ahead_pages = 64" {
+		t.Fatalf("readAheadSQL(64) = %q", got)
+	}
+	if _, ok := ReadAheadFromContext(context.Background()); ok {
+		t.Fatal("a plain context reported a read-ahead window")
+	}
+	if n, ok := ReadAheadFromContext(WithReadAhead(context.Background(), 0)); !ok || n != 0 {
+		t.Fatalf("ReadAheadFromContext = %d, %v, want 0, true", n, ok)
+	}
+}
+
+func TestDriver_ReadAheadScans(t *testing.T) {
+	ctx := context.Background()
+	dbPath := filepath.Join(t.TempDir(), "readahead.ddb")
+	direct, err := OpenDirect(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range []string{
+		"CREATE TABLE t (id INTEGER PRIMARY KEY, body TEXT)",
+		"INSERT INTO t SELECT value, repeat('x', 300) FROM generate_series(1, 5000)",
+	} {
+		if _, err := direct.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	// Checkpoint so the scans below read the main file, not the WAL.
+	if err := direct.Checkpoint(); err != nil {
+		t.Fatal(err)
+	}
+	if err := direct.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := sql.Open("decentdb", fmt.Sprintf("file:%s?read_ahead_pages=16", dbPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	count := func(ctx context.Context, query string) int64 {
+		t.Helper()
+		var n int64
+		if err := db.QueryRowContext(ctx, query).Scan(&n); err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+		return n
+	}
+	const want = 5000 * 300
+	if got := count(ctx, "SELECT SUM(length(body)) FROM t"); got != want {
+		t.Fatalf("DSN read-ahead sum = %d, want %d", got, want)
+	}
+	if got := count(WithReadAhead(ctx, 256), "SELECT SUM(length(body)) FROM t"); got != want {
+		t.Fatalf("context read-ahead sum = %d, want %d", got, want)
+	}
+	if got := count(ctx, "/*+ READ_AHEAD(64) */ SELECT SUM(length(body)) FROM t"); got != want {
+		t.Fatalf("hinted sum = %d, want %d", got, want)
+	}
+	if got := count(ctx, "PRAGMA read_ahead_pages"); got != 16 {
+		t.Fatalf("read_ahead_pages after context statement = %d, want 16", got)
+	}
+}
@@ -0,0 +1,66 @@
+package decentdb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// RecoveryReport describes what Recover salvaged.
+type RecoveryReport struct {
+	Tables      []RecoveredTable `json:"tables"`
+	LostObjects []RecoveryLoss   `json:"lost_objects"`
+}
+
+// RecoveredTable is the salvage result for one table. Error is set when the
+// table's schema or some of its rows could not be copied; the rows counted
+// in RowsRecovered were copied before the failure.
+type RecoveredTable struct {
+	Name            string  `json:"name"`
+	SchemaRecovered bool    `json:"schema_recovered"`
+	RowsRecovered   uint64  `json:"rows_recovered"`
+	Error           *string `json:"error"`
+}
+
+// RecoveryLoss is a view, index, or trigger that could not be recreated.
+type RecoveryLoss struct {
+	Kind  string `json:"kind"`
+	Name  string `json:"name"`
+	Error string `json:"error"`
+}
+
+// Complete reports whether every table, row, and schema object was salvaged.
+func (r *RecoveryReport) Complete() bool {
+	if len(r.LostObjects) > 0 {
+		return false
+	}
+	for _, t := range r.Tables {
+		if t.Error != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Recover salvages the schema and rows of the damaged database at src into a
+// new database at dst, which must not exist. Each table is copied up to its
+// first unreadable row, then views, indexes, and triggers are recreated;
+// anything that could not be copied is listed in the report rather than
+// returned as an error. An error is returned only when src cannot be opened
+// or dst cannot be created.
+//
+// ctx is checked before recovery starts; the copy itself is not interruptible.
+func Recover(ctx context.Context, src, dst string) (*RecoveryReport, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	raw, err := recoverToJSON(src, dst)
+	if err != nil {
+		return nil, err
+	}
+	var report RecoveryReport
+	if err := json.Unmarshal([]byte(raw), &report); err != nil {
+		return nil, fmt.Errorf("failed to parse recovery report: %w", err)
+	}
+	return &report, nil
+}
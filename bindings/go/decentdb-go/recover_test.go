@@ -0,0 +1,70 @@
+package decentdb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRecover_SalvagesIntoNewFile(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "decentdb-test-recover-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	srcPath := filepath.Join(tmpDir, "broken.ddb")
+	dstPath := filepath.Join(tmpDir, "salvaged.ddb")
+
+	src, err := sql.Open("decentdb", fmt.Sprintf("file:%s", srcPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range []string{
+		"CREATE TABLE users (id INT64 PRIMARY KEY, name TEXT)",
+		"CREATE INDEX users_name_idx ON users (name)",
+		"INSERT INTO users VALUES (1, 'Ada'), (2, 'Grace')",
+	} {
+		if _, err := src.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	if err := src.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := Recover(context.Background(), srcPath, dstPath)
+	if err != nil {
+		t.Fatalf("Recover: %v", err)
+	}
+	if !report.Complete() {
+		t.Fatalf("expected complete recovery, got %+v", report)
+	}
+	if len(report.Tables) != 1 || report.Tables[0].Name != "users" || report.Tables[0].RowsRecovered != 2 {
+		t.Fatalf("unexpected tables: %+v", report.Tables)
+	}
+
+	dst, err := sql.Open("decentdb", fmt.Sprintf("file:%s", dstPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dst.Close()
+	var name string
+	if err := dst.QueryRow("SELECT name FROM users WHERE id = 2").Scan(&name); err != nil {
+		t.Fatal(err)
+	}
+	if name != "Grace" {
+		t.Fatalf("name = %q, want Grace", name)
+	}
+
+	if _, err := Recover(context.Background(), srcPath, dstPath); err == nil {
+		t.Fatal("expected error when destination exists")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Recover(ctx, srcPath, filepath.Join(tmpDir, "other.ddb")); err != context.Canceled {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,188 @@
+package decentdb
+
+import (
+	"context"
+	"database/sql/driver"
+	"strconv"
+	"strings"
+
+	"github.com/sphildreth/decentdb-go/internal/rpcwire"
+	"github.com/sphildreth/decentdb-go/remote"
+)
+
+// isRemoteDSN reports whether dsn names a DecentDB server rather than a
+// database file.
+func isRemoteDSN(dsn string) bool {
+	return strings.HasPrefix(dsn, remote.Scheme+"://")
+}
+
+// remoteConnector serves a decentdb://host:port/dbname DSN through the
+// remote client, so the same application code runs against an embedded
+// file or a server. Errors are reported as *DecentDBError and DECIMAL
+// values as Decimal, as they are for embedded connections.
+type remoteConnector struct {
+	inner        driver.Connector
+	interceptors []Interceptor
+}
+
+func newRemoteConnector(dsn string, interceptors []Interceptor) (driver.Connector, error) {
+	inner, err := remote.NewConnector(dsn,
+		remote.WithErrorMapper(remoteError),
+		remote.WithValueDecoder(decodeRemoteValue),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &remoteConnector{inner: inner, interceptors: interceptors}, nil
+}
+
+func (c *remoteConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	inner, err := c.inner.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &remoteConn{Conn: inner, interceptors: c.interceptors}, nil
+}
+
+func (c *remoteConnector) Driver() driver.Driver {
+	return &Driver{}
+}
+
+func remoteError(e *remote.Error) error {
+	return &DecentDBError{
+		Code:      e.NativeCode,
+		Message:   e.Message,
+		Err:       e,
+		Subcode:   e.Subcode,
+		SQLState:  e.SQLState,
+		Retryable: e.Code == rpcwire.CodeAborted,
+	}
+}
+
+func decodeRemoteValue(typeName string, v driver.Value) driver.Value {
+	if text, ok := v.(string); ok {
+		switch declType, _, _ := strings.Cut(strings.ToUpper(typeName), "("); declType {
+		case "DECIMAL", "NUMERIC":
+			if d, ok := parseDecimalText(text); ok {
+				return d
+			}
+		}
+	}
+	return v
+}
+
+// remoteConn runs the connector's interceptors around a remote connection.
+type remoteConn struct {
+	driver.Conn
+	interceptors []Interceptor
+}
+
+var (
+	_ driver.ConnPrepareContext = (*remoteConn)(nil)
+	_ driver.ConnBeginTx        = (*remoteConn)(nil)
+	_ driver.ExecerContext      = (*remoteConn)(nil)
+	_ driver.QueryerContext     = (*remoteConn)(nil)
+	_ driver.NamedValueChecker  = (*remoteConn)(nil)
+	_ driver.Validator          = (*remoteConn)(nil)
+)
+
+// CheckNamedValue passes Decimal and UUID arguments to the server in their
+// wire forms; everything else uses the default conversion.
+func (c *remoteConn) CheckNamedValue(nv *driver.NamedValue) error {
+	switch v := nv.Value.(type) {
+	case Decimal:
+		nv.Value = rpcwire.Decimal(decimalText(v))
+		return nil
+	case UUID:
+		nv.Value = v[:]
+		return nil
+	}
+	return driver.ErrSkip
+}
+
+func (c *remoteConn) IsValid() bool {
+	v, ok := c.Conn.(driver.Validator)
+	return !ok || v.IsValid()
+}
+
+func (c *remoteConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *remoteConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	return chainPrepare(c.interceptors, func(ctx context.Context, query string) (driver.Stmt, error) {
+		s, err := c.Conn.(driver.ConnPrepareContext).PrepareContext(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		return &remoteStmt{Stmt: s, query: query, interceptors: c.interceptors}, nil
+	})(ctx, query)
+}
+
+func (c *remoteConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return chainExec(c.interceptors, c.Conn.(driver.ExecerContext).ExecContext)(ctx, query, args)
+}
+
+func (c *remoteConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return chainQuery(c.interceptors, c.Conn.(driver.QueryerContext).QueryContext)(ctx, query, args)
+}
+
+func (c *remoteConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *remoteConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	return chainBeginTx(c.interceptors, func(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+		t, err := c.Conn.(driver.ConnBeginTx).BeginTx(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		return &remoteTx{Tx: t, interceptors: c.interceptors}, nil
+	})(ctx, opts)
+}
+
+type remoteStmt struct {
+	driver.Stmt
+	query        string
+	interceptors []Interceptor
+}
+
+func (s *remoteStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return chainExec(s.interceptors, func(ctx context.Context, _ string, args []driver.NamedValue) (driver.Result, error) {
+		return s.Stmt.(driver.StmtExecContext).ExecContext(ctx, args)
+	})(ctx, s.query, args)
+}
+
+func (s *remoteStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return chainQuery(s.interceptors, func(ctx context.Context, _ string, args []driver.NamedValue) (driver.Rows, error) {
+		return s.Stmt.(driver.StmtQueryContext).QueryContext(ctx, args)
+	})(ctx, s.query, args)
+}
+
+type remoteTx struct {
+	driver.Tx
+	interceptors []Interceptor
+}
+
+func (t *remoteTx) Commit() error {
+	return chainEndTx(t.interceptors, Interceptor.InterceptCommit, t.Tx.Commit)()
+}
+
+func (t *remoteTx) Rollback() error {
+	return chainEndTx(t.interceptors, Interceptor.InterceptRollback, t.Tx.Rollback)()
+}
+
+func decimalText(d Decimal) string {
+	if d.Scale <= 0 {
+		return strconv.FormatInt(d.Unscaled, 10)
+	}
+	sign, digits := "", strconv.FormatInt(d.Unscaled, 10)
+	if d.Unscaled < 0 {
+		sign, digits = "-", digits[1:]
+	}
+	for len(digits) <= d.Scale {
+		digits = "0" + digits
+	}
+	cut := len(digits) - d.Scale
+	return sign + digits[:cut] + "." + digits[cut:]
+}
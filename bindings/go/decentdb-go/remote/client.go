@@ -0,0 +1,159 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sphildreth/decentdb-go/internal/rpcwire"
+	"github.com/sphildreth/decentdb-go/tlsconfig"
+)
+
+// Error is a failed remote call. Engine errors carry the SQLSTATE, native
+// error code, and subcode the server reported.
+type Error struct {
+	// Code is the gRPC status code.
+	Code       int
+	Message    string
+	SQLState   string
+	NativeCode int
+	Subcode    string
+}
+
+func (e *Error) Error() string {
+	return "decentdb remote: " + e.Message
+}
+
+// client issues gRPC calls over one shared HTTP/2 transport.
+type client struct {
+	http     *http.Client
+	base     string
+	token    string
+	user     string
+	password string
+}
+
+func newClient(cfg *config, tlsConfig *tls.Config) (*client, error) {
+	protocols := new(http.Protocols)
+	transport := &http.Transport{Protocols: protocols}
+	scheme := "http"
+	if cfg.tls || tlsConfig != nil {
+		if tlsConfig == nil {
+			name := cfg.tlsName
+			if name == "" {
+				name = cfg.host
+			}
+			r, err := tlsconfig.Client{
+				CAFile:             cfg.tlsCA,
+				CertFile:           cfg.tlsCert,
+				KeyFile:            cfg.tlsKey,
+				ServerName:         name,
+				InsecureSkipVerify: cfg.tlsInsecure,
+			}.Load()
+			if err != nil {
+				return nil, err
+			}
+			tlsConfig = r.Config()
+		}
+		scheme = "https"
+		protocols.SetHTTP2(true)
+		transport.TLSClientConfig = tlsConfig
+	} else {
+		protocols.SetUnencryptedHTTP2(true)
+	}
+	return &client{
+		http:     &http.Client{Transport: transport},
+		base:     scheme + "://" + cfg.addr,
+		token:    cfg.token,
+		user:     cfg.user,
+		password: cfg.password,
+	}, nil
+}
+
+// send starts a call and returns the response once headers arrive.
+func (c *client) send(ctx context.Context, method string, req rpcwire.Message) (*http.Response, error) {
+	var body bytes.Buffer
+	if err := rpcwire.WriteMessage(&body, req); err != nil {
+		return nil, err
+	}
+	hreq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.base+method, &body)
+	if err != nil {
+		return nil, err
+	}
+	hreq.Header.Set("Content-Type", rpcwire.ContentType)
+	hreq.Header.Set("Te", "trailers")
+	if c.token != "" {
+		hreq.Header.Set("Authorization", "Bearer "+c.token)
+	} else if c.user != "" {
+		hreq.SetBasicAuth(c.user, c.password)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		ms := max(time.Until(deadline).Milliseconds(), 1)
+		hreq.Header.Set("Grpc-Timeout", strconv.FormatInt(ms, 10)+"m")
+	}
+	resp, err := c.http.Do(hreq)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, &Error{Code: rpcwire.CodeUnavailable, Message: fmt.Sprintf("unexpected HTTP status %s", resp.Status)}
+	}
+	return resp, nil
+}
+
+// call runs a unary call.
+func (c *client) call(ctx context.Context, method string, req, out rpcwire.Message) error {
+	resp, err := c.send(ctx, method, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	err = rpcwire.ReadMessage(resp.Body, out)
+	if errors.Is(err, io.EOF) {
+		if st := callStatus(resp); st != nil {
+			return st
+		}
+		return &Error{Code: rpcwire.CodeInternal, Message: "server sent no response message"}
+	}
+	if err != nil {
+		return err
+	}
+	// Trailers are only available once the body is read to the end.
+	if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+		return err
+	}
+	return callStatus(resp)
+}
+
+// callStatus reads the gRPC status of a finished call, looking at the
+// headers too for trailers-only responses.
+func callStatus(resp *http.Response) error {
+	get := func(key string) string {
+		if v := resp.Trailer.Get(key); v != "" {
+			return v
+		}
+		return resp.Header.Get(key)
+	}
+	code, err := strconv.Atoi(get(rpcwire.TrailerStatus))
+	if err != nil {
+		return &Error{Code: rpcwire.CodeInternal, Message: "server sent no grpc-status"}
+	}
+	if code == rpcwire.CodeOK {
+		return nil
+	}
+	e := &Error{
+		Code:     code,
+		Message:  rpcwire.DecodeStatusMessage(get(rpcwire.TrailerMessage)),
+		SQLState: get(rpcwire.TrailerSQLState),
+		Subcode:  get(rpcwire.TrailerSubcode),
+	}
+	e.NativeCode, _ = strconv.Atoi(get(rpcwire.TrailerNativeCode))
+	return e
+}
@@ -0,0 +1,47 @@
+package decentdb
+
+import (
+	"database/sql/driver"
+	"testing"
+
+	"github.com/sphildreth/decentdb-go/internal/rpcwire"
+)
+
+func TestIsRemoteDSN(t *testing.T) {
+	for dsn, want := range map[string]bool{
+		"decentdb://localhost:7070/app": true,
+		"file:/tmp/app.ddb":             false,
+		"/tmp/decentdb/app.ddb":         false,
+		":memory:":                      false,
+	} {
+		if got := isRemoteDSN(dsn); got != want {
+			t.Fatalf("isRemoteDSN(%q) = %v, want %v", dsn, got, want)
+		}
+	}
+}
+
+func TestRemoteValueConversion(t *testing.T) {
+	if got := decodeRemoteValue("DECIMAL(10,2)", "-12.50"); got != (Decimal{Unscaled: -1250, Scale: 2}) {
+		t.Fatalf("decodeRemoteValue(DECIMAL) = %#v", got)
+	}
+	if got := decodeRemoteValue("TEXT", "-12.50"); got != "-12.50" {
+		t.Fatalf("decodeRemoteValue(TEXT) = %#v", got)
+	}
+	for d, want := range map[Decimal]string{
+		{Unscaled: 1999, Scale: 2}: "19.99",
+		{Unscaled: -5, Scale: 3}:   "-0.005",
+		{Unscaled: 7}:              "7",
+	} {
+		if got := decimalText(d); got != want {
+			t.Fatalf("decimalText(%+v) = %q, want %q", d, got, want)
+		}
+	}
+
+	nv := &driver.NamedValue{Value: Decimal{Unscaled: 1999, Scale: 2}}
+	if err := (&remoteConn{}).CheckNamedValue(nv); err != nil || nv.Value != rpcwire.Decimal("19.99") {
+		t.Fatalf("CheckNamedValue(Decimal) = %#v, %v", nv.Value, err)
+	}
+	if err := (&remoteConn{}).CheckNamedValue(&driver.NamedValue{Value: int64(1)}); err != driver.ErrSkip {
+		t.Fatalf("CheckNamedValue(int64) = %v, want ErrSkip", err)
+	}
+}
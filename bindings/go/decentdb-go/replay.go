@@ -0,0 +1,320 @@
+package decentdb
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// ReplayOptions configures Replay.
+type ReplayOptions struct {
+	// Speed scales the recorded pacing: 1 starts each operation at its
+	// original offset from the first, 2 at half that offset. Sessions then
+	// run concurrently, as they did when recorded. Zero replays as fast as
+	// possible, one operation at a time in log order.
+	Speed float64
+}
+
+// ReplayResult summarizes a replay.
+type ReplayResult struct {
+	// Entries is the number of log entries replayed.
+	Entries int
+	// Errors counts entries that failed in the replay, whether or not they
+	// failed when recorded.
+	Errors int
+	// Mismatches lists entries whose outcome differs from the recording.
+	Mismatches []ReplayMismatch
+	// Recorded and Replayed sum the operations' durations in the log and in
+	// the replay.
+	Recorded, Replayed time.Duration
+	// Elapsed is the wall-clock time of the replay.
+	Elapsed time.Duration
+}
+
+// ReplayMismatch is an entry that succeeded in one run and failed in the
+// other, or affected a different number of rows.
+type ReplayMismatch struct {
+	// Line is the entry's line in the log, starting at 1.
+	Line int
+	Want QueryLogEntry
+	// Got is the replayed outcome: its Duration, RowsAffected, and Error.
+	Got QueryLogEntry
+}
+
+// Replay re-executes a log written by a QueryLog against db. Each recorded
+// session runs on its own connection, so transactions and session state
+// carry over, and results are compared with the recording. Failed
+// statements are counted rather than returned; the error reports an
+// unreadable log, a connection that could not be obtained, or ctx ending.
+func Replay(ctx context.Context, db *sql.DB, log io.Reader, opts ReplayOptions) (ReplayResult, error) {
+	if opts.Speed < 0 {
+		return ReplayResult{}, fmt.Errorf("decentdb: invalid replay speed %g", opts.Speed)
+	}
+	r := &replayer{db: db, paced: opts.Speed > 0, sessions: map[int64]*replaySession{}}
+	start := time.Now()
+	err := r.run(ctx, bufio.NewReader(log), opts.Speed)
+	r.close()
+	r.result.Elapsed = time.Since(start)
+	return r.result, err
+}
+
+type replayer struct {
+	db       *sql.DB
+	paced    bool
+	sessions map[int64]*replaySession
+	wg       sync.WaitGroup
+
+	mu     sync.Mutex
+	result ReplayResult
+}
+
+func (r *replayer) run(ctx context.Context, log *bufio.Reader, speed float64) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var first time.Time
+	start := time.Now()
+	for line := 1; ; line++ {
+		raw, readErr := log.ReadBytes('\n')
+		if len(bytes.TrimSpace(raw)) > 0 {
+			var e QueryLogEntry
+			if err := json.Unmarshal(raw, &e); err != nil {
+				return fmt.Errorf("decentdb: query log line %d: %w", line, err)
+			}
+			s, err := r.session(ctx, e.Session)
+			if err != nil {
+				return err
+			}
+			if !r.paced {
+				r.finish(line, e, s.do(ctx, e))
+			} else {
+				if first.IsZero() {
+					first = e.Time
+				}
+				at := start.Add(time.Duration(float64(e.Time.Sub(first)) / speed))
+				if err := sleepUntil(ctx, at); err != nil {
+					return err
+				}
+				s.enqueue(replayItem{line: line, entry: e})
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return readErr
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+	r.stop()
+	return ctx.Err()
+}
+
+// session returns the connection replaying session id, starting its worker
+// when the replay is paced.
+func (r *replayer) session(ctx context.Context, id int64) (*replaySession, error) {
+	if s, ok := r.sessions[id]; ok {
+		return s, nil
+	}
+	c, err := r.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("decentdb: replay session %d: %w", id, err)
+	}
+	s := &replaySession{conn: c, wake: make(chan struct{}, 1)}
+	r.sessions[id] = s
+	if r.paced {
+		r.wg.Add(1)
+		go func() {
+			defer r.wg.Done()
+			s.work(ctx, r)
+		}()
+	}
+	return s, nil
+}
+
+func (r *replayer) finish(line int, want, got QueryLogEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.result.Entries++
+	r.result.Recorded += want.Duration
+	r.result.Replayed += got.Duration
+	if got.Error != "" {
+		r.result.Errors++
+	}
+	if (want.Error == "") != (got.Error == "") ||
+		(want.RowsAffected != nil && got.RowsAffected != nil && *want.RowsAffected != *got.RowsAffected) {
+		r.result.Mismatches = append(r.result.Mismatches, ReplayMismatch{Line: line, Want: want, Got: got})
+	}
+}
+
+// stop waits for paced sessions to run the entries queued so far.
+func (r *replayer) stop() {
+	for _, s := range r.sessions {
+		s.enqueue(replayItem{last: true})
+	}
+	r.wg.Wait()
+}
+
+// close ends the sessions, rolling back transactions left open.
+func (r *replayer) close() {
+	r.stop()
+	for _, s := range r.sessions {
+		if s.tx != nil {
+			_ = s.tx.Rollback()
+		}
+		_ = s.conn.Close()
+	}
+}
+
+func sleepUntil(ctx context.Context, at time.Time) error {
+	d := time.Until(at)
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type replayItem struct {
+	line  int
+	entry QueryLogEntry
+	last  bool
+}
+
+type replaySession struct {
+	conn *sql.Conn
+	tx   *sql.Tx
+
+	mu    sync.Mutex
+	queue []replayItem
+	wake  chan struct{}
+}
+
+// enqueue hands a paced entry to the session's worker. The queue is
+// unbounded so a session blocked on a lock never stalls the others.
+func (s *replaySession) enqueue(item replayItem) {
+	s.mu.Lock()
+	s.queue = append(s.queue, item)
+	s.mu.Unlock()
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *replaySession) work(ctx context.Context, r *replayer) {
+	for {
+		s.mu.Lock()
+		var item replayItem
+		ok := len(s.queue) > 0
+		if ok {
+			item, s.queue = s.queue[0], s.queue[1:]
+		}
+		s.mu.Unlock()
+		if !ok {
+			select {
+			case <-s.wake:
+			case <-ctx.Done():
+				return
+			}
+			continue
+		}
+		if item.last || ctx.Err() != nil {
+			return
+		}
+		r.finish(item.line, item.entry, s.do(ctx, item.entry))
+	}
+}
+
+// do runs one entry and returns its outcome.
+func (s *replaySession) do(ctx context.Context, e QueryLogEntry) QueryLogEntry {
+	got := QueryLogEntry{Time: time.Now(), Session: e.Session, Op: e.Op, SQL: e.SQL}
+	err := s.exec(ctx, e, &got)
+	got.Duration = time.Since(got.Time)
+	if err != nil {
+		got.Error = err.Error()
+	}
+	return got
+}
+
+func (s *replaySession) exec(ctx context.Context, e QueryLogEntry, got *QueryLogEntry) error {
+	switch e.Op {
+	case "begin":
+		tx, err := s.conn.BeginTx(ctx, &sql.TxOptions{ReadOnly: e.ReadOnly})
+		if err == nil {
+			s.tx = tx
+		}
+		return err
+	case "commit", "rollback":
+		if s.tx == nil {
+			return errors.New("no transaction is open")
+		}
+		tx := s.tx
+		s.tx = nil
+		if e.Op == "commit" {
+			return tx.Commit()
+		}
+		return tx.Rollback()
+	case "exec", "query":
+	default:
+		return fmt.Errorf("unknown query log operation %q", e.Op)
+	}
+	args := make([]any, len(e.Args))
+	for i, a := range e.Args {
+		v, err := a.Decode()
+		if err != nil {
+			return err
+		}
+		if a.Name != "" {
+			v = sql.Named(a.Name, v)
+		}
+		args[i] = v
+	}
+	if e.Op == "exec" {
+		var res sql.Result
+		var err error
+		if s.tx != nil {
+			res, err = s.tx.ExecContext(ctx, e.SQL, args...)
+		} else {
+			res, err = s.conn.ExecContext(ctx, e.SQL, args...)
+		}
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err == nil {
+			got.RowsAffected = &n
+		}
+		return nil
+	}
+	var rows *sql.Rows
+	var err error
+	if s.tx != nil {
+		rows, err = s.tx.QueryContext(ctx, e.SQL, args...)
+	} else {
+		rows, err = s.conn.QueryContext(ctx, e.SQL, args...)
+	}
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return err
+	}
+	return rows.Close()
+}
@@ -0,0 +1,182 @@
+package decentdb
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Publication emits the row changes committed to a set of tables so a
+// Subscription on another database can replay them, like a PostgreSQL
+// publication. Publications are stored in the database and can also be
+// managed with CREATE PUBLICATION and DROP PUBLICATION.
+type Publication struct {
+	d    *DB
+	name string
+}
+
+// CreatePublication publishes tables under name, replacing the table list
+// of an existing publication with that name. Like StartSession, it turns
+// on the sync journal under replicaID; subscribers name the publisher by
+// that ID.
+func (d *DB) CreatePublication(replicaID, name string, tables ...string) (*Publication, error) {
+	if atomic.LoadUint32(&d.closed) != 0 {
+		return nil, driver.ErrBadConn
+	}
+	if _, err := d.c.enableSyncJournal(replicaID); err != nil {
+		return nil, err
+	}
+	if err := d.c.syncExecute(map[string]any{
+		"op":             "create_scope",
+		"name":           name,
+		"include_tables": tables,
+	}, nil); err != nil {
+		return nil, err
+	}
+	return &Publication{d: d, name: name}, nil
+}
+
+// Name returns the publication's name.
+func (p *Publication) Name() string {
+	return p.name
+}
+
+// Changes returns the published row changes among the next limit journal
+// entries after sequence since, as an opaque batch. Serve it to
+// subscribers over any transport; a ChangeFetcher passes since along.
+func (p *Publication) Changes(since uint64, limit int) ([]byte, error) {
+	if atomic.LoadUint32(&p.d.closed) != 0 {
+		return nil, driver.ErrBadConn
+	}
+	var batch json.RawMessage
+	if err := p.d.c.syncExecute(map[string]any{
+		"op":    "export_batch",
+		"since": since,
+		"limit": limit,
+		"scope": p.name,
+	}, &batch); err != nil {
+		return nil, err
+	}
+	return batch, nil
+}
+
+// ChangeFetcher retrieves a publication's changes after sequence since,
+// typically by calling Publication.Changes on the publisher over the
+// network.
+type ChangeFetcher func(ctx context.Context, since uint64) ([]byte, error)
+
+// Subscription applies a remote publication's changes to a database, like
+// a PostgreSQL subscription. Its position in the publisher's journal is
+// stored in the database, so a subscription recreated after a restart
+// resumes where it stopped.
+type Subscription struct {
+	d         *DB
+	publisher string
+	fetch     ChangeFetcher
+}
+
+// SubscriptionResult summarizes one Subscription.Poll.
+type SubscriptionResult struct {
+	Seen       uint64 `json:"seen"`
+	Applied    uint64 `json:"applied"`
+	Skipped    uint64 `json:"skipped"`
+	Conflicted uint64 `json:"conflicted"`
+	// Position is the publisher journal sequence applied through.
+	Position uint64 `json:"-"`
+}
+
+// Subscribe creates a subscription to the publication of the replica
+// publisherID, fetching batches with fetch. The published tables must
+// exist in this database with the publisher's columns; the rest of the
+// schema may differ. Conflicting rows follow the database's sync conflict
+// policy.
+func (d *DB) Subscribe(publisherID string, fetch ChangeFetcher) (*Subscription, error) {
+	if atomic.LoadUint32(&d.closed) != 0 {
+		return nil, driver.ErrBadConn
+	}
+	if publisherID == "" || fetch == nil {
+		return nil, fmt.Errorf("decentdb: Subscribe needs a publisher ID and a fetcher")
+	}
+	return &Subscription{d: d, publisher: publisherID, fetch: fetch}, nil
+}
+
+// Position returns the last publisher journal sequence the subscription
+// has applied, or 0 before the first batch.
+func (s *Subscription) Position() (uint64, error) {
+	if atomic.LoadUint32(&s.d.closed) != 0 {
+		return 0, driver.ErrBadConn
+	}
+	var out struct {
+		Watermark *uint64 `json:"watermark"`
+	}
+	if err := s.d.c.syncExecute(map[string]any{"op": "peer_watermark", "replica_id": s.publisher}, &out); err != nil {
+		return 0, err
+	}
+	if out.Watermark == nil {
+		return 0, nil
+	}
+	return *out.Watermark, nil
+}
+
+// Poll fetches one batch of changes after the subscription's position and
+// applies it in a single transaction.
+func (s *Subscription) Poll(ctx context.Context) (SubscriptionResult, error) {
+	since, err := s.Position()
+	if err != nil {
+		return SubscriptionResult{}, err
+	}
+	batch, err := s.fetch(ctx, since)
+	if err != nil {
+		return SubscriptionResult{}, err
+	}
+	var header struct {
+		SourceReplicaID string `json:"source_replica_id"`
+	}
+	if err := json.Unmarshal(batch, &header); err != nil {
+		return SubscriptionResult{}, fmt.Errorf("decentdb: decode change batch: %w", err)
+	}
+	if header.SourceReplicaID != "" && header.SourceReplicaID != s.publisher {
+		return SubscriptionResult{}, fmt.Errorf("decentdb: change batch comes from replica %q, not %q", header.SourceReplicaID, s.publisher)
+	}
+	var result SubscriptionResult
+	if err := s.d.c.syncExecute(map[string]any{
+		"op":    "import_publication_batch",
+		"batch": json.RawMessage(batch),
+	}, &result); err != nil {
+		return SubscriptionResult{}, err
+	}
+	result.Position, err = s.Position()
+	return result, err
+}
+
+// Run polls until ctx is done, sleeping interval whenever a poll finds
+// nothing new. It returns ctx's error, or the first error from Poll.
+func (s *Subscription) Run(ctx context.Context, interval time.Duration) error {
+	last, err := s.Position()
+	if err != nil {
+		return err
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		result, err := s.Poll(ctx)
+		if err != nil {
+			return err
+		}
+		if result.Position != last {
+			last = result.Position
+			continue
+		}
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package decentdb
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReplication_PublishSubscribe(t *testing.T) {
+	dir := t.TempDir()
+	publisher, err := OpenDirect(filepath.Join(dir, "publisher.ddb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer publisher.Close()
+	subscriber, err := OpenDirect(filepath.Join(dir, "subscriber.ddb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer subscriber.Close()
+	for _, stmt := range []string{
+		"CREATE TABLE notes (id INT64 PRIMARY KEY, body TEXT)",
+		"CREATE TABLE secrets (id INT64 PRIMARY KEY, body TEXT)",
+	} {
+		if _, err := publisher.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := subscriber.Exec("CREATE TABLE notes (id INT64 PRIMARY KEY, body TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+
+	pub, err := publisher.CreatePublication("pub-1", "notes_feed", "notes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range []string{
+		"INSERT INTO notes VALUES (1, 'draft')",
+		"INSERT INTO secrets VALUES (1, 'hidden')",
+		"UPDATE notes SET body = 'final' WHERE id = 1",
+		"INSERT INTO notes VALUES (2, 'second')",
+	} {
+		if _, err := publisher.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+
+	sub, err := subscriber.Subscribe("pub-1", func(ctx context.Context, since uint64) ([]byte, error) {
+		return pub.Changes(since, 2)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if err := sub.Run(ctx, 10*time.Millisecond); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Run = %v", err)
+	}
+	if got := noteBodies(t, subscriber); len(got) != 2 || got[1] != "final" || got[2] != "second" {
+		t.Fatalf("subscriber rows = %v", got)
+	}
+	if position, err := sub.Position(); err != nil || position != 4 {
+		t.Fatalf("Position = %d, %v", position, err)
+	}
+
+	result, err := sub.Poll(context.Background())
+	if err != nil || result.Seen != 0 || result.Position != 4 {
+		t.Fatalf("Poll when caught up = %+v, %v", result, err)
+	}
+
+	other, err := subscriber.Subscribe("someone-else", func(ctx context.Context, since uint64) ([]byte, error) {
+		return pub.Changes(since, 10)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.Poll(context.Background()); err == nil {
+		t.Fatal("Poll accepted a batch from the wrong publisher")
+	}
+}
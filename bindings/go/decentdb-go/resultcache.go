@@ -0,0 +1,630 @@
+package decentdb
+
+import (
+	"container/list"
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultResultCacheEntries = 1024
+	// resultCacheMaxRows caps the rows of one cached result. Larger results
+	// are returned normally and not cached.
+	resultCacheMaxRows = 1000
+)
+
+// ResultCache keeps the rows of read queries so that repeating a query with
+// the same SQL and arguments skips execution until a table it reads changes.
+// Entries are invalidated through per-table change counters fed by the
+// engine's change stream, so a commit on any connection in this process is
+// seen by the next query, and DDL drops every entry.
+//
+// Only single-statement, read-only queries outside transactions are cached,
+// and only when the engine can list every table they depend on: queries on
+// temporary tables, queries that call volatile functions such as now() or
+// random(), and queries on connections that ran SET or created temporary
+// objects always execute. Statements prepared with Prepare are not cached.
+// Commits made by other processes are not observed, and cached rows ignore
+// row-level security policies that depend on per-connection session state,
+// so leave the cache off in those deployments.
+//
+// A ResultCache serves one database file and is safe for concurrent use.
+type ResultCache struct {
+	maxEntries int
+	hits       atomic.Uint64
+	misses     atomic.Uint64
+
+	mu sync.Mutex
+	// path is the database file the cache is bound to by its first
+	// connection.
+	path    string
+	entries map[string]*list.Element
+	lru     list.List
+	plans   map[string]resultCachePlan
+	// versions counts committed changes per lowercased table name.
+	versions map[string]uint64
+	// epoch advances whenever every entry and plan is dropped: on schema
+	// changes, lost change events, and a new change stream.
+	epoch        uint64
+	schemaCookie uint64
+	// watch follows committed changes. It belongs to owner's native handle
+	// and is closed with it.
+	watch *Watch
+	owner *conn
+}
+
+// ResultCacheStats reports result cache activity. Misses counts cacheable
+// queries that had to execute.
+type ResultCacheStats struct {
+	Hits    uint64
+	Misses  uint64
+	Entries int
+}
+
+// resultCachePlan records whether a query can be cached. tables is nil when
+// it cannot.
+type resultCachePlan struct {
+	tables    []string
+	declTypes []string
+}
+
+type resultCacheEntry struct {
+	key      string
+	epoch    uint64
+	tables   []string
+	versions []uint64
+	result   *cachedResult
+}
+
+// cachedResult is a materialized result shared read-only by every hit.
+type cachedResult struct {
+	columns   []string
+	declTypes []string
+	rows      [][]driver.Value
+}
+
+// NewResultCache returns a cache holding up to maxEntries results, or 1024
+// when maxEntries is not positive. Pass it to WithResultCache.
+func NewResultCache(maxEntries int) *ResultCache {
+	if maxEntries <= 0 {
+		maxEntries = defaultResultCacheEntries
+	}
+	return &ResultCache{
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		plans:      make(map[string]resultCachePlan),
+		versions:   make(map[string]uint64),
+	}
+}
+
+// WithResultCache caches read query results of every connection the
+// connector opens in cache. The result_cache_size=N DSN option does the same
+// with a private cache for sql.Open. In-memory databases are never cached.
+func WithResultCache(cache *ResultCache) ConnectorOption {
+	return func(c *connector) {
+		c.results = cache
+	}
+}
+
+// Stats returns the cache's hit and miss counters and current size.
+func (rc *ResultCache) Stats() ResultCacheStats {
+	rc.mu.Lock()
+	entries := rc.lru.Len()
+	rc.mu.Unlock()
+	return ResultCacheStats{Hits: rc.hits.Load(), Misses: rc.misses.Load(), Entries: entries}
+}
+
+// Purge drops every cached result.
+func (rc *ResultCache) Purge() {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.resetLocked()
+}
+
+// bind ties the cache to the database at path, so one cache never mixes
+// results of different files.
+func (rc *ResultCache) bind(path string) error {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if rc.path == "" {
+		rc.path = path
+	} else if rc.path != path {
+		return fmt.Errorf("decentdb: result cache already serves %s, not %s", rc.path, path)
+	}
+	return nil
+}
+
+// release closes the change stream when c, its owner, is closed. The next
+// query starts a new stream on another connection.
+func (rc *ResultCache) release(c *conn) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if rc.owner == c {
+		rc.dropWatchLocked()
+	}
+}
+
+func (rc *ResultCache) resetLocked() {
+	if len(rc.entries) > 0 {
+		logDebug(context.Background(), "decentdb: result cache reset", slog.Int("entries", len(rc.entries)))
+	}
+	rc.epoch++
+	clear(rc.entries)
+	rc.lru.Init()
+	clear(rc.plans)
+	clear(rc.versions)
+}
+
+func (rc *ResultCache) dropWatchLocked() {
+	if rc.watch != nil {
+		_ = rc.watch.Close()
+	}
+	rc.watch, rc.owner = nil, nil
+	rc.resetLocked()
+}
+
+// followLocked starts the change stream on c if none is open, then applies
+// every event committed so far. It returns false when results cannot be
+// validated.
+func (rc *ResultCache) followLocked(c *conn) bool {
+	if rc.watch == nil {
+		watch, err := c.ChangeStreamJson(nil)
+		if err != nil {
+			return false
+		}
+		rc.watch, rc.owner = watch, c
+		rc.resetLocked()
+	}
+	rc.drainLocked()
+	return rc.watch != nil
+}
+
+func (rc *ResultCache) drainLocked() {
+	for rc.watch != nil {
+		event, ok, err := rc.watch.NextJson(0)
+		if err != nil {
+			rc.dropWatchLocked()
+			return
+		}
+		if !ok {
+			return
+		}
+		rc.applyLocked(event)
+	}
+}
+
+func (rc *ResultCache) applyLocked(jsonText string) {
+	var event struct {
+		Type         string `json:"type"`
+		SchemaCookie uint64 `json:"schema_cookie"`
+		TableChanges []struct {
+			Table         string `json:"table"`
+			SchemaChanged bool   `json:"schema_changed"`
+		} `json:"table_changes"`
+	}
+	if err := json.Unmarshal([]byte(jsonText), &event); err != nil {
+		rc.resetLocked()
+		return
+	}
+	switch event.Type {
+	case "initial":
+		rc.schemaCookie = event.SchemaCookie
+	case "change":
+		if event.SchemaCookie != rc.schemaCookie {
+			rc.schemaCookie = event.SchemaCookie
+			rc.resetLocked()
+			return
+		}
+		for _, change := range event.TableChanges {
+			if change.SchemaChanged {
+				rc.resetLocked()
+				return
+			}
+			rc.versions[strings.ToLower(change.Table)]++
+		}
+	case "closed":
+		rc.dropWatchLocked()
+	default:
+		// A lagged stream dropped events, so any entry may be stale.
+		rc.resetLocked()
+	}
+}
+
+func (rc *ResultCache) versionsLocked(tables []string) []uint64 {
+	versions := make([]uint64, len(tables))
+	for i, table := range tables {
+		versions[i] = rc.versions[table]
+	}
+	return versions
+}
+
+func (rc *ResultCache) validLocked(entry *resultCacheEntry) bool {
+	return rc.watch != nil && entry.epoch == rc.epoch &&
+		slices.Equal(entry.versions, rc.versionsLocked(entry.tables))
+}
+
+func (rc *ResultCache) removeLocked(elem *list.Element) {
+	delete(rc.entries, elem.Value.(*resultCacheEntry).key)
+	rc.lru.Remove(elem)
+}
+
+// store caches result unless a table it read changed while it ran.
+func (rc *ResultCache) store(entry *resultCacheEntry, result *cachedResult) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.drainLocked()
+	if !rc.validLocked(entry) {
+		return
+	}
+	entry.result = result
+	if elem, ok := rc.entries[entry.key]; ok {
+		rc.removeLocked(elem)
+	}
+	rc.entries[entry.key] = rc.lru.PushFront(entry)
+	for rc.lru.Len() > rc.maxEntries {
+		rc.removeLocked(rc.lru.Back())
+	}
+}
+
+// query answers query from the cache, or runs it on c and records the
+// result for the next caller.
+func (rc *ResultCache) query(ctx context.Context, c *conn, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	text, cacheable := resultCacheSQL(query)
+	if !cacheable || c.sessionState || c.InTransaction() {
+		return c.queryUncached(ctx, query, args)
+	}
+	schema, _ := SchemaFromContext(ctx)
+	planKey := schema + "\x00" + text
+	key := resultCacheKey(planKey, args)
+
+	rc.mu.Lock()
+	if !rc.followLocked(c) {
+		rc.mu.Unlock()
+		return c.queryUncached(ctx, query, args)
+	}
+	if elem, ok := rc.entries[key]; ok {
+		entry := elem.Value.(*resultCacheEntry)
+		if rc.validLocked(entry) {
+			rc.lru.MoveToFront(elem)
+			rc.mu.Unlock()
+			rc.hits.Add(1)
+			if l := debugLogger(ctx); l != nil {
+				l.LogAttrs(ctx, slog.LevelDebug, "decentdb: result cache hit", slog.String("sql", text))
+			}
+			return &cachedRows{result: entry.result, rawValues: c.rawValues}, nil
+		}
+		rc.removeLocked(elem)
+	}
+	plan, planned := rc.plans[planKey]
+	epoch := rc.epoch
+	rc.mu.Unlock()
+
+	s, err := c.prepareStmt(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+	if !planned {
+		plan, err = resultCachePlanFor(s)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var entry *resultCacheEntry
+	if plan.tables != nil {
+		rc.mu.Lock()
+		rc.drainLocked()
+		if rc.watch != nil && rc.epoch == epoch {
+			if !planned {
+				rc.plans[planKey] = plan
+			}
+			entry = &resultCacheEntry{
+				key:      key,
+				epoch:    epoch,
+				tables:   plan.tables,
+				versions: rc.versionsLocked(plan.tables),
+			}
+		}
+		rc.mu.Unlock()
+	}
+
+	rows, err := s.queryContext(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return rows, nil
+	}
+	rc.misses.Add(1)
+	if l := debugLogger(ctx); l != nil {
+		l.LogAttrs(ctx, slog.LevelDebug, "decentdb: result cache miss", slog.String("sql", text), slog.Any("tables", entry.tables))
+	}
+	columns := rows.Columns()
+	return &recordingRows{
+		Rows:      rows,
+		cache:     rc,
+		entry:     entry,
+		columns:   columns,
+		declTypes: plan.declTypes,
+		rawValues: c.rawValues,
+		result:    &cachedResult{columns: columns, declTypes: plan.declTypes},
+	}, nil
+}
+
+// resultCachePlanFor asks the engine whether s can be cached and which
+// tables its result depends on.
+func resultCachePlanFor(s *stmtStruct) (resultCachePlan, error) {
+	info, err := s.StmtInfo()
+	if err != nil {
+		return resultCachePlan{}, err
+	}
+	if !info.ReadOnly || info.DependencyTables == nil {
+		return resultCachePlan{}, nil
+	}
+	plan := resultCachePlan{
+		tables:    make([]string, len(info.DependencyTables)),
+		declTypes: make([]string, len(info.Columns)),
+	}
+	for i, table := range info.DependencyTables {
+		plan.tables[i] = strings.ToLower(table)
+	}
+	for i, col := range info.Columns {
+		plan.declTypes[i] = strings.ToUpper(col.TypeName)
+	}
+	return plan, nil
+}
+
+// resultCacheKey appends the bound arguments, tagged with their types, to
+// planKey.
+func resultCacheKey(planKey string, args []driver.NamedValue) string {
+	var b strings.Builder
+	b.WriteString(planKey)
+	for _, arg := range args {
+		fmt.Fprintf(&b, "\x00%d:", arg.Ordinal)
+		switch v := arg.Value.(type) {
+		case nil:
+			b.WriteString("null")
+		case string:
+			fmt.Fprintf(&b, "string:%q", v)
+		case time.Time:
+			fmt.Fprintf(&b, "time:%d", v.UnixNano()/1e3)
+		default:
+			fmt.Fprintf(&b, "%T:%v", v, v)
+		}
+	}
+	return b.String()
+}
+
+// volatileSQLFunctions are the built-in functions whose result can differ
+// between two runs of a query over the same data.
+var volatileSQLFunctions = map[string]bool{
+	// Advisory lock functions act on the calling connection's locks.
+	"advisory_lock":         true,
+	"advisory_try_lock":     true,
+	"advisory_unlock":       true,
+	"pg_advisory_lock":      true,
+	"pg_advisory_try_lock":  true,
+	"pg_advisory_unlock":    true,
+	"pg_try_advisory_lock":  true,
+	"age":                   true,
+	"current_actor":         true,
+	"current_audit_context": true,
+	"current_date":          true,
+	"current_tenant":        true,
+	"current_time":          true,
+	"current_timestamp":     true,
+	"gen_random_bytes":      true,
+	"gen_random_uuid":       true,
+	"localtime":             true,
+	"localtimestamp":        true,
+	"now":                   true,
+	"random":                true,
+	"random_bytes":          true,
+	"uuid_v7":               true,
+	"uuidv4":                true,
+	"uuidv7":                true,
+	// Not a function, but an unseeded TABLESAMPLE draws new rows every run.
+	"tablesample": true,
+}
+
+// resultCacheSQL normalizes query for use as a cache key: comments are
+// dropped, whitespace outside literals collapses to one space, and a
+// trailing semicolon is removed. It returns false when query may call a
+// volatile function, including date and time functions given 'now'.
+func resultCacheSQL(query string) (string, bool) {
+	var b strings.Builder
+	space := false
+	write := func(token string) {
+		if space && b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		space = false
+		b.WriteString(token)
+	}
+	for i := 0; i < len(query); {
+		ch := query[i]
+		switch {
+		case ch == '\'' || ch == '"':
+			end := i + 1
+			for end < len(query) {
+				if query[end] == ch {
+					if end+1 < len(query) && query[end+1] == ch {
+						end += 2
+						continue
+					}
+					end++
+					break
+				}
+				end++
+			}
+			if ch == '\'' && strings.EqualFold(strings.Trim(query[i:end], "'"), "now") {
+				return "", false
+			}
+			write(query[i:end])
+			i = end
+		case ch == '-' && i+1 < len(query) && query[i+1] == '-':
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+			space = true
+		case ch == '/' && i+1 < len(query) && query[i+1] == '*':
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				i = len(query)
+			} else {
+				i += end + 4
+			}
+			space = true
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r' || ch == '\f':
+			space = true
+			i++
+		case isSQLWordByte(ch):
+			end := i + 1
+			for end < len(query) && isSQLWordByte(query[end]) {
+				end++
+			}
+			word := query[i:end]
+			name := strings.ToLower(word[strings.LastIndexByte(word, '.')+1:])
+			if volatileSQLFunctions[name] {
+				return "", false
+			}
+			write(word)
+			i = end
+		default:
+			write(query[i : i+1])
+			i++
+		}
+	}
+	text := strings.TrimRight(b.String(), "; ")
+	if len(text) >= 6 && strings.EqualFold(text[:6], "PRAGMA") {
+		// PRAGMA reads report connection and engine state that no table
+		// write invalidates.
+		return "", false
+	}
+	return text, text != ""
+}
+
+func isSQLWordByte(ch byte) bool {
+	return ch == '_' || ch == '$' || ch == '.' || ch >= 0x80 ||
+		('0' <= ch && ch <= '9') || ('a' <= ch && ch <= 'z') || ('A' <= ch && ch <= 'Z')
+}
+
+// changesSessionState reports whether query changes how later statements on
+// the same connection resolve names or evaluate, which a pool-wide cache
+// cannot key on.
+func changesSessionState(query string) bool {
+	fields := strings.Fields(strings.ToUpper(query))
+	if len(fields) == 0 {
+		return false
+	}
+	switch fields[0] {
+	case "SET", "RESET":
+		return true
+	case "CREATE":
+		for _, field := range fields[1:min(len(fields), 3)] {
+			if field == "TEMP" || field == "TEMPORARY" {
+				return true
+			}
+			if field != "LOCAL" && field != "GLOBAL" {
+				return false
+			}
+		}
+	}
+	return false
+}
+
+func cloneDriverValue(v driver.Value) driver.Value {
+	if b, ok := v.([]byte); ok {
+		return slices.Clone(b)
+	}
+	return v
+}
+
+// cachedRows replays a cached result.
+type cachedRows struct {
+	result    *cachedResult
+	pos       int
+	rawValues bool
+	// current is the row last returned by NextRow.
+	current []driver.Value
+}
+
+func (r *cachedRows) Columns() []string { return slices.Clone(r.result.columns) }
+
+func (r *cachedRows) Close() error { return nil }
+
+func (r *cachedRows) Next(dest []driver.Value) error {
+	if r.pos >= len(r.result.rows) {
+		return io.EOF
+	}
+	row := r.result.rows[r.pos]
+	r.pos++
+	for i := 0; i < len(row) && i < len(dest); i++ {
+		dest[i] = cloneDriverValue(row[i])
+	}
+	return nil
+}
+
+// recordingRows returns a query's rows while copying them for the cache. The
+// result is stored once the rows are exhausted; Close reads the rest of a
+// result that fits the cache so QueryRow results are cached too.
+type recordingRows struct {
+	driver.Rows
+	cache     *ResultCache
+	entry     *resultCacheEntry
+	columns   []string
+	declTypes []string
+	rawValues bool
+	// result collects the rows; nil once stored or too large to cache.
+	result  *cachedResult
+	current []driver.Value
+}
+
+func (r *recordingRows) Next(dest []driver.Value) error {
+	err := r.Rows.Next(dest)
+	if r.result == nil {
+		return err
+	}
+	switch {
+	case err == io.EOF:
+		r.cache.store(r.entry, r.result)
+		r.result = nil
+	case err != nil || len(r.result.rows) == resultCacheMaxRows:
+		r.result = nil
+	default:
+		row := make([]driver.Value, len(dest))
+		for i, v := range dest {
+			row[i] = cloneDriverValue(v)
+		}
+		r.result.rows = append(r.result.rows, row)
+	}
+	return err
+}
+
+func (r *recordingRows) Close() error {
+	if r.result != nil {
+		dest := make([]driver.Value, len(r.columns))
+		for r.result != nil {
+			if r.Next(dest) != nil {
+				break
+			}
+		}
+	}
+	return r.Rows.Close()
+}
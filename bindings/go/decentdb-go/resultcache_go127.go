@@ -0,0 +1,55 @@
+//go:build go1.27
+
+package decentdb
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+)
+
+// NextRow advances a cached result without copying the row.
+func (r *cachedRows) NextRow() error {
+	if r.pos >= len(r.result.rows) {
+		return io.EOF
+	}
+	r.current = r.result.rows[r.pos]
+	r.pos++
+	return nil
+}
+
+// ScanColumn assigns one column of the current cached row to dest, decoding
+// *any destinations by declared type the way uncached rows do.
+func (r *cachedRows) ScanColumn(scanCtx driver.ScanContext, index int, dest any) error {
+	return scanResultValue(scanCtx, r.current, r.result.declTypes, r.rawValues, index, dest)
+}
+
+func (r *recordingRows) NextRow() error {
+	if r.current == nil {
+		r.current = make([]driver.Value, len(r.columns))
+	}
+	return r.Next(r.current)
+}
+
+func (r *recordingRows) ScanColumn(scanCtx driver.ScanContext, index int, dest any) error {
+	return scanResultValue(scanCtx, r.current, r.declTypes, r.rawValues, index, dest)
+}
+
+// scanResultValue converts a copy of row[index] into dest, so callers can
+// never alias the cached value.
+func scanResultValue(scanCtx driver.ScanContext, row []driver.Value, declTypes []string, rawValues bool, index int, dest any) error {
+	if index < 0 || index >= len(row) {
+		return fmt.Errorf("column index %d out of range for %d columns", index, len(row))
+	}
+	v := cloneDriverValue(row[index])
+	if d, ok := dest.(*any); ok && !rawValues {
+		declType := ""
+		if index < len(declTypes) {
+			declType = declTypes[index]
+		}
+		*d = decodeDeclared(declType, v)
+		return nil
+	}
+	return sql.ConvertAssign(scanCtx, dest, v)
+}
@@ -0,0 +1,182 @@
+package decentdb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+func TestResultCacheSQL(t *testing.T) {
+	cases := map[string]string{
+		"SELECT  name\n\tFROM items WHERE id = $1;": "SELECT name FROM items WHERE id = $1",
+		"SELECT 'a  b' -- note\nFROM t":             "SELECT 'a  b' FROM t",
+		"SELECT /* x */ \"Odd  Name\" FROM t ; ":    `SELECT "Odd  Name" FROM t`,
+		"SELECT 'it''s' FROM t":                     "SELECT 'it''s' FROM t",
+	}
+	for query, want := range cases {
+		if got, ok := resultCacheSQL(query); !ok || got != want {
+			t.Fatalf("resultCacheSQL(%q) = %q, %v, want %q", query, got, ok, want)
+		}
+	}
+	for _, query := range []string{
+		"SELECT now()",
+		"SELECT id FROM t WHERE created < CURRENT_TIMESTAMP",
+		"SELECT date('now')",
+		"SELECT pg_catalog.random()",
+		"SELECT * FROM t WHERE owner = current_actor()",
+		"SELECT COUNT(*) FROM t TABLESAMPLE SYSTEM (5)",
+		"pragma foreign_keys",
+		";",
+	} {
+		if _, ok := resultCacheSQL(query); ok {
+			t.Fatalf("resultCacheSQL(%q) should not be cacheable", query)
+		}
+	}
+	if _, ok := resultCacheSQL("SELECT 'now is' FROM t"); !ok {
+		t.Fatal("a literal merely containing now should stay cacheable")
+	}
+}
+
+func TestChangesSessionState(t *testing.T) {
+	cases := map[string]bool{
+		"SET search_path TO a":               true,
+		"reset search_path":                  true,
+		"CREATE TEMP TABLE t (id INT)":       true,
+		"create local temporary view v AS 1": true,
+		"CREATE TABLE temp (id INT)":         false,
+		"CREATE INDEX temp_idx ON t (id)":    false,
+		"SELECT 1":                           false,
+		"":                                   false,
+	}
+	for query, want := range cases {
+		if got := changesSessionState(query); got != want {
+			t.Fatalf("changesSessionState(%q) = %v, want %v", query, got, want)
+		}
+	}
+}
+
+func TestResultCacheKeyTagsArgumentTypes(t *testing.T) {
+	key := func(v any) string {
+		return resultCacheKey("q", []driver.NamedValue{{Ordinal: 1, Value: v}})
+	}
+	if key("1") == key(int64(1)) || key(nil) == key("null") || key([]byte("a")) == key("a") {
+		t.Fatal("arguments of different types share a cache key")
+	}
+	if key(int64(7)) != key(int64(7)) {
+		t.Fatal("equal arguments should share a cache key")
+	}
+}
+
+func TestParseResultCacheSize(t *testing.T) {
+	cases := map[string]int{
+		":memory:":                             0,
+		"file:/tmp/a.ddb":                      0,
+		"file:/tmp/a.ddb?result_cache_size=64": 64,
+	}
+	for dsn, want := range cases {
+		if cfg, err := parseDSN(dsn); err != nil || cfg.resultCacheSize != want {
+			t.Fatalf("parseDSN(%q) = %+v, %v, want result cache size %d", dsn, cfg, err, want)
+		}
+	}
+	if _, err := parseDSN("file:/tmp/a.ddb?result_cache_size=-1"); err == nil {
+		t.Fatal("negative result_cache_size was accepted")
+	}
+}
+
+func TestDriver_ResultCacheInvalidatesOnCommit(t *testing.T) {
+	ctx := context.Background()
+	cache := NewResultCache(16)
+	connector, err := NewConnector(fmt.Sprintf("file:%s", filepath.Join(t.TempDir(), "cache.ddb")), WithResultCache(cache))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	for _, stmt := range []string{
+		"CREATE TABLE items (id INT PRIMARY KEY, name TEXT)",
+		"CREATE TABLE other (id INT PRIMARY KEY)",
+		"INSERT INTO items VALUES (1, 'apple'), (2, 'pear')",
+		"CREATE VIEW item_names AS SELECT name FROM items",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	name := func(q *sql.Conn) string {
+		t.Helper()
+		var name string
+		if err := q.QueryRowContext(ctx, "SELECT name FROM items WHERE id = $1", 1).Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		return name
+	}
+	reader, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	writer, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer writer.Close()
+
+	if got := name(reader); got != "apple" {
+		t.Fatalf("name = %q", got)
+	}
+	if got := name(reader); got != "apple" {
+		t.Fatalf("cached name = %q", got)
+	}
+	if stats := cache.Stats(); stats.Hits != 1 || stats.Misses != 1 || stats.Entries != 1 {
+		t.Fatalf("unexpected stats after a repeated query: %+v", stats)
+	}
+
+	if _, err := writer.ExecContext(ctx, "INSERT INTO other VALUES (1)"); err != nil {
+		t.Fatal(err)
+	}
+	name(reader)
+	if stats := cache.Stats(); stats.Hits != 2 {
+		t.Fatalf("a write to an unrelated table invalidated the entry: %+v", stats)
+	}
+
+	if _, err := writer.ExecContext(ctx, "UPDATE items SET name = 'apricot' WHERE id = 1"); err != nil {
+		t.Fatal(err)
+	}
+	if got := name(reader); got != "apricot" {
+		t.Fatalf("name after update = %q, want the committed value", got)
+	}
+
+	var count int
+	for i := 0; i < 2; i++ {
+		if err := reader.QueryRowContext(ctx, "SELECT COUNT(*) FROM item_names").Scan(&count); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := writer.ExecContext(ctx, "INSERT INTO items VALUES (3, 'plum')"); err != nil {
+		t.Fatal(err)
+	}
+	if err := reader.QueryRowContext(ctx, "SELECT COUNT(*) FROM item_names").Scan(&count); err != nil || count != 3 {
+		t.Fatalf("view count = %d, %v, want 3 after a base table insert", count, err)
+	}
+
+	before := cache.Stats()
+	if err := reader.QueryRowContext(ctx, "SELECT COUNT(*) FROM items WHERE id < random()").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	tx, err := reader.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got string
+	if err := tx.QueryRow("SELECT name FROM items WHERE id = $1", 1).Scan(&got); err != nil {
+		t.Fatal(err)
+	}
+	tx.Rollback()
+	if after := cache.Stats(); after.Hits != before.Hits || after.Misses != before.Misses {
+		t.Fatalf("volatile and transactional queries used the cache: %+v then %+v", before, after)
+	}
+}
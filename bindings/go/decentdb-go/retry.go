@@ -0,0 +1,101 @@
+package decentdb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+const (
+	defaultTxMaxAttempts    = 5
+	defaultTxInitialBackoff = 10 * time.Millisecond
+	defaultTxMaxBackoff     = 500 * time.Millisecond
+)
+
+// TxOptions configures WithTx. The zero value retries up to five attempts with
+// exponential backoff from 10ms capped at 500ms.
+type TxOptions struct {
+	// Tx is passed through to sql.DB.BeginTx.
+	Tx *sql.TxOptions
+	// MaxAttempts bounds the total number of attempts, including the first.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry. It doubles after
+	// every retryable failure.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between attempts.
+	MaxBackoff time.Duration
+}
+
+// IsRetryable reports whether err is a transient DecentDB failure, such as a
+// busy writer or a transaction conflict, that may succeed if the whole
+// transaction is run again.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, ErrBusy) {
+		return true
+	}
+	var dbErr *DecentDBError
+	if errors.As(err, &dbErr) {
+		return dbErr.Retryable && !dbErr.Permanent
+	}
+	return false
+}
+
+// WithTx runs fn inside a transaction and commits it. If beginning, running,
+// or committing fails with a retryable error, the transaction is rolled back
+// and fn runs again after a bounded backoff. fn must therefore be safe to
+// re-run. Non-retryable errors, including those returned by fn, are returned
+// after rolling back. opts may be nil.
+func WithTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error, opts *TxOptions) error {
+	var o TxOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.MaxAttempts <= 0 {
+		o.MaxAttempts = defaultTxMaxAttempts
+	}
+	if o.InitialBackoff <= 0 {
+		o.InitialBackoff = defaultTxInitialBackoff
+	}
+	if o.MaxBackoff <= 0 {
+		o.MaxBackoff = defaultTxMaxBackoff
+	}
+
+	backoff := o.InitialBackoff
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = runTxOnce(ctx, db, fn, o.Tx)
+		if err == nil || !IsRetryable(err) || attempt >= o.MaxAttempts {
+			return err
+		}
+		logDebug(ctx, "decentdb: retrying transaction", slog.Int("attempt", attempt),
+			slog.Duration("backoff", backoff), errAttr(err))
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		backoff *= 2
+		if backoff > o.MaxBackoff {
+			backoff = o.MaxBackoff
+		}
+	}
+}
+
+func runTxOnce(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error, txOpts *sql.TxOptions) error {
+	tx, err := db.BeginTx(ctx, txOpts)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,96 @@
+package decentdb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestIsRetryable(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"plain", errors.New("boom"), false},
+		{"busy sentinel", fmt.Errorf("wrapped: %w", ErrBusy), true},
+		{"retryable diagnostic", &DecentDBError{Code: 4, Retryable: true}, true},
+		{"permanent diagnostic", &DecentDBError{Code: 4, Retryable: true, Permanent: true}, false},
+		{"non-retryable diagnostic", &DecentDBError{Code: 5}, false},
+	}
+	for _, tc := range cases {
+		if got := IsRetryable(tc.err); got != tc.want {
+			t.Errorf("%s: IsRetryable = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestWithTx_RetriesRetryableErrors(t *testing.T) {
+	db, err := sql.Open("decentdb", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec("CREATE TABLE counters (id INT64 PRIMARY KEY, n INT64)"); err != nil {
+		t.Fatal(err)
+	}
+
+	attempts := 0
+	err = WithTx(context.Background(), db, func(tx *sql.Tx) error {
+		attempts++
+		if _, err := tx.Exec("INSERT INTO counters (id, n) VALUES ($1, $2)", 1, attempts); err != nil {
+			return err
+		}
+		if attempts < 3 {
+			return &DecentDBError{Code: 4, Message: "conflict", Retryable: true}
+		}
+		return nil
+	}, &TxOptions{InitialBackoff: time.Millisecond})
+	if err != nil {
+		t.Fatalf("WithTx failed: %v", err)
+	}
+	if attempts != 3 {
+		t.Fatalf("expected 3 attempts, got %d", attempts)
+	}
+
+	var n int64
+	if err := db.QueryRow("SELECT n FROM counters WHERE id = 1").Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Fatalf("expected only the final attempt to commit, got n=%d", n)
+	}
+}
+
+func TestWithTx_StopsOnPermanentErrorsAndAttemptBudget(t *testing.T) {
+	db, err := sql.Open("decentdb", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	sentinel := errors.New("validation failed")
+	attempts := 0
+	err = WithTx(context.Background(), db, func(tx *sql.Tx) error {
+		attempts++
+		return sentinel
+	}, nil)
+	if !errors.Is(err, sentinel) || attempts != 1 {
+		t.Fatalf("expected one attempt returning sentinel, got attempts=%d err=%v", attempts, err)
+	}
+
+	attempts = 0
+	err = WithTx(context.Background(), db, func(tx *sql.Tx) error {
+		attempts++
+		return ErrBusy
+	}, &TxOptions{MaxAttempts: 2, InitialBackoff: time.Millisecond})
+	if !errors.Is(err, ErrBusy) || attempts != 2 {
+		t.Fatalf("expected two attempts ending in ErrBusy, got attempts=%d err=%v", attempts, err)
+	}
+}
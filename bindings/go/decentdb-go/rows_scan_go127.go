@@ -0,0 +1,72 @@
+//go:build go1.27
+
+package decentdb
+
+/*
+#include "decentdb.h"
+*/
+import "C"
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"unsafe"
+)
+
+// NextRow advances to the next row without decoding it. Since Go 1.27,
+// database/sql calls NextRow and ScanColumn instead of Next, which lets each
+// column be converted straight from the native row view.
+func (r *rows) NextRow() error {
+	return r.step()
+}
+
+// ScanColumn assigns one column of the current row to dest. It is a fast
+// path for Next: dest receives the value Next would have produced, decoded
+// by the column's declared type unless the connection was opened with raw
+// values.
+//
+// Text, blob, and spatial columns scanned into *sql.RawBytes alias the
+// engine's row-view buffer instead of being copied. The slice is only valid
+// until the next Next, Scan, or Close call on the owning sql.Rows, which is the
+// lifetime database/sql already documents for RawBytes. *[]byte destinations
+// are converted from the same borrowed buffer and always receive a copy.
+func (r *rows) ScanColumn(scanCtx driver.ScanContext, index int, dest any) error {
+	if index < 0 {
+		return fmt.Errorf("column index %d out of range for %d columns", index, len(r.views))
+	}
+	source := r.viewIndex(index)
+	if source < 0 {
+		return sql.ConvertAssign(scanCtx, dest, nil)
+	}
+	if source >= len(r.views) {
+		return fmt.Errorf("column index %d out of range for %d columns", index, len(r.views))
+	}
+	v := r.views[source]
+	switch v.tag {
+	case C.DDB_VALUE_TEXT, C.DDB_VALUE_BLOB, C.DDB_VALUE_GEOMETRY, C.DDB_VALUE_GEOGRAPHY:
+		if r.s.c.rawValues || !decodesDeclared(r.declaredType(index)) {
+			switch d := dest.(type) {
+			case *sql.RawBytes:
+				*d = borrowViewBytes(v)
+				return nil
+			case *[]byte:
+				return sql.ConvertAssign(scanCtx, d, []byte(borrowViewBytes(v)))
+			}
+		}
+	}
+	value := r.columnValue(index, v)
+	if d, ok := dest.(*any); ok {
+		*d = value
+		return nil
+	}
+	return sql.ConvertAssign(scanCtx, dest, value)
+}
+
+// borrowViewBytes returns the view's payload without copying. The result must
+// not outlive the row view it came from.
+func borrowViewBytes(v C.ddb_value_view_t) sql.RawBytes {
+	if v.len == 0 || v.data == nil {
+		return sql.RawBytes{}
+	}
+	return unsafe.Slice((*byte)(unsafe.Pointer(v.data)), int(v.len))
+}
@@ -0,0 +1,87 @@
+package decentdb
+
+import (
+	"context"
+	"database/sql/driver"
+	"io"
+	"iter"
+	"sync/atomic"
+)
+
+// Row is one result row yielded by DB.Rows. Its values are owned copies that
+// stay valid after the loop moves on.
+type Row struct {
+	columns []string
+	values  []driver.Value
+}
+
+// Columns returns the result's column names.
+func (r Row) Columns() []string {
+	return r.columns
+}
+
+// Values returns the row's values in column order.
+func (r Row) Values() []driver.Value {
+	return r.values
+}
+
+// Value returns the value of the column called name, and false if the result
+// has no such column.
+func (r Row) Value(name string) (driver.Value, bool) {
+	for i, column := range r.columns {
+		if column == name {
+			return r.values[i], true
+		}
+	}
+	return nil, false
+}
+
+// Rows runs query and yields its rows for use with range:
+//
+//	for row, err := range db.Rows(ctx, "SELECT id, name FROM users") {
+//		if err != nil {
+//			return err
+//		}
+//		fmt.Println(row.Values()...)
+//	}
+//
+// The statement is prepared when the loop starts and released when it ends,
+// including on break, return, or panic in the loop body. An error ends the
+// sequence and is yielded with a zero Row.
+func (d *DB) Rows(ctx context.Context, query string, args ...driver.Value) iter.Seq2[Row, error] {
+	return func(yield func(Row, error) bool) {
+		if atomic.LoadUint32(&d.closed) != 0 {
+			yield(Row{}, driver.ErrBadConn)
+			return
+		}
+		namedArgs := make([]driver.NamedValue, len(args))
+		for i, a := range args {
+			namedArgs[i] = driver.NamedValue{Ordinal: i + 1, Value: a}
+		}
+		s, err := d.c.prepareStmt(ctx, query)
+		if err != nil {
+			yield(Row{}, err)
+			return
+		}
+		defer s.Close()
+		r, err := s.queryContext(ctx, namedArgs)
+		if err != nil {
+			yield(Row{}, err)
+			return
+		}
+		defer r.Close()
+		columns := r.Columns()
+		for {
+			values := make([]driver.Value, len(columns))
+			if err := r.Next(values); err != nil {
+				if err != io.EOF {
+					yield(Row{}, err)
+				}
+				return
+			}
+			if !yield(Row{columns: columns, values: values}, nil) {
+				return
+			}
+		}
+	}
+}
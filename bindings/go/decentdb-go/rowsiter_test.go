@@ -0,0 +1,78 @@
+package decentdb
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpenDirect_Rows(t *testing.T) {
+	db, err := OpenDirect(filepath.Join(t.TempDir(), "rows.ddb"))
+	if err != nil {
+		t.Fatalf("OpenDirect failed: %v", err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+
+	if _, err := db.Exec("CREATE TABLE m (id INT PRIMARY KEY, label TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		if _, err := db.Exec("INSERT INTO m VALUES ($1, $2)", int64(i), "row"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var ids []int64
+	for row, err := range db.Rows(ctx, "SELECT id, label FROM m WHERE id >= $1 ORDER BY id", int64(2)) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cols := row.Columns(); len(cols) != 2 || cols[0] != "id" || cols[1] != "label" {
+			t.Fatalf("unexpected columns: %v", cols)
+		}
+		id, _ := row.Value("id")
+		ids = append(ids, id.(int64))
+		if label, ok := row.Value("label"); !ok || label != "row" {
+			t.Fatalf("label = %v, %v", label, ok)
+		}
+		if _, ok := row.Value("missing"); ok {
+			t.Fatal("Value found a missing column")
+		}
+	}
+	if len(ids) != 3 || ids[0] != 2 || ids[2] != 4 {
+		t.Fatalf("unexpected ids: %v", ids)
+	}
+
+	// Breaking out early releases the statement, so the handle stays usable
+	// for writes and schema changes.
+	for i := 0; i < 3; i++ {
+		for _, err := range db.Rows(ctx, "SELECT id FROM m ORDER BY id") {
+			if err != nil {
+				t.Fatal(err)
+			}
+			break
+		}
+	}
+	if _, err := db.Exec("DROP TABLE m"); err != nil {
+		t.Fatalf("DROP TABLE after an early break: %v", err)
+	}
+
+	var sawErr bool
+	for _, err := range db.Rows(ctx, "SELECT * FROM m") {
+		if err == nil {
+			t.Fatal("query of a dropped table yielded a row")
+		}
+		sawErr = true
+	}
+	if !sawErr {
+		t.Fatal("query of a dropped table yielded no error")
+	}
+
+	db.Close()
+	for _, err := range db.Rows(ctx, "SELECT 1") {
+		if err == nil {
+			t.Fatal("Rows on a closed handle succeeded")
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package decentdb
+
+import (
+	"context"
+	"strings"
+)
+
+type schemaKey struct{}
+
+// WithSchema returns a context whose statements resolve unqualified table
+// and view names in schema before the default main schema, and create new
+// tables and views in schema. The connection running a statement switches
+// its search_path to match the context first, so one pool can serve many
+// tenants. An empty name restores the default path for statements run with
+// the context.
+//
+// Statements are resolved when they are prepared: a *sql.Stmt keeps the
+// schema of the context it was prepared with.
+func WithSchema(ctx context.Context, schema string) context.Context {
+	return context.WithValue(ctx, schemaKey{}, schema)
+}
+
+// SchemaFromContext returns the schema set by WithSchema, if any.
+func SchemaFromContext(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	schema, ok := ctx.Value(schemaKey{}).(string)
+	return schema, ok && schema != ""
+}
+
+// searchPathSQL returns the statement that points a connection at schema,
+// or restores the default path when schema is empty.
+func searchPathSQL(schema string) string {
+	if schema == "" {
+		return "RESET search_path"
+	}
+	return `SET search_path TO "` + strings.ReplaceAll(schema, `"`, `""`) + `"`
+}
@@ -0,0 +1,71 @@
+package decentdb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSearchPathSQL(t *testing.T) {
+	ctx := context.Background()
+	if _, ok := SchemaFromContext(ctx); ok {
+		t.Fatal("background context should carry no schema")
+	}
+	if schema, ok := SchemaFromContext(WithSchema(ctx, "tenant_42")); !ok || schema != "tenant_42" {
+		t.Fatalf("SchemaFromContext = %q, %v", schema, ok)
+	}
+	if _, ok := SchemaFromContext(WithSchema(WithSchema(ctx, "a"), "")); ok {
+		t.Fatal("empty schema should clear the parent schema")
+	}
+	if got := searchPathSQL(`odd"name`); got != `SET search_path TO "odd""name"` {
+		t.Fatalf("unexpected SET statement: %q", got)
+	}
+	if got := searchPathSQL(""); got != "RESET search_path" {
+		t.Fatalf("unexpected reset statement: %q", got)
+	}
+}
+
+func TestDriver_WithSchemaSeparatesTenants(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "decentdb-test-schema-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	db, err := sql.Open("decentdb", fmt.Sprintf("file:%s", filepath.Join(tmpDir, "schema.ddb")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	for i, tenant := range []string{"tenant_a", "tenant_b"} {
+		if _, err := db.Exec("CREATE SCHEMA " + tenant); err != nil {
+			t.Fatal(err)
+		}
+		ctx := WithSchema(context.Background(), tenant)
+		if _, err := db.ExecContext(ctx, "CREATE TABLE orders (id INT PRIMARY KEY, total INT)"); err != nil {
+			t.Fatalf("%s: create failed: %v", tenant, err)
+		}
+		if _, err := db.ExecContext(ctx, "INSERT INTO orders VALUES ($1, $2)", 1, 10*(i+1)); err != nil {
+			t.Fatalf("%s: insert failed: %v", tenant, err)
+		}
+	}
+
+	for tenant, want := range map[string]int{"tenant_a": 10, "tenant_b": 20} {
+		var total int
+		ctx := WithSchema(context.Background(), tenant)
+		if err := db.QueryRowContext(ctx, "SELECT total FROM orders WHERE id = $1", 1).Scan(&total); err != nil {
+			t.Fatalf("%s: query failed: %v", tenant, err)
+		}
+		if total != want {
+			t.Fatalf("%s: total = %d, want %d", tenant, total, want)
+		}
+	}
+	if _, err := db.Query("SELECT total FROM orders"); err == nil {
+		t.Fatal("unqualified query without a schema should not see tenant tables")
+	}
+}
@@ -0,0 +1,103 @@
+package decentdb
+
+import (
+	"strings"
+	"sync"
+)
+
+// SchemaChange describes a committed DDL statement.
+type SchemaChange struct {
+	// ObjectKind is "table", "index", "view", "trigger", or "schema".
+	ObjectKind string
+	ObjectName string
+	// StatementKind is the engine's statement kind, such as "create_table"
+	// or "comment_on". Statement is the SQL text that was executed.
+	StatementKind string
+	Statement     string
+}
+
+// schemaHooks holds the OnSchemaChange callbacks for one handle along with
+// the changes made by its open transaction, which are published only once
+// that transaction commits.
+type schemaHooks struct {
+	mu      sync.Mutex
+	funcs   []func(SchemaChange)
+	pending []SchemaChange
+}
+
+// OnSchemaChange registers fn to run after each committed DDL statement on
+// this handle. Statements executed inside an explicit transaction are
+// reported when it commits and dropped when it rolls back. Callbacks run
+// synchronously on the committing goroutine, in registration order.
+func (d *DB) OnSchemaChange(fn func(event SchemaChange)) {
+	hooks := d.c.schemaHooks.Load()
+	if hooks == nil {
+		d.c.schemaHooks.CompareAndSwap(nil, &schemaHooks{})
+		hooks = d.c.schemaHooks.Load()
+	}
+	hooks.mu.Lock()
+	hooks.funcs = append(hooks.funcs, fn)
+	hooks.mu.Unlock()
+}
+
+// isSchemaStatement reports whether query may change the schema, so only
+// those statements pay for a describe call.
+func isSchemaStatement(query string) bool {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return false
+	}
+	switch strings.ToUpper(fields[0]) {
+	case "CREATE", "DROP", "ALTER", "COMMENT":
+		return true
+	}
+	return false
+}
+
+// describeSchemaChange returns the change query would make when hooks are
+// registered, or nil. It runs before execution, while the object a DROP
+// names still exists.
+func (c *conn) describeSchemaChange(query string, describe func() (*StmtInfo, error)) *SchemaChange {
+	if c.schemaHooks.Load() == nil || !isSchemaStatement(query) {
+		return nil
+	}
+	info, err := describe()
+	if err != nil || info.ObjectKind == "" {
+		return nil
+	}
+	return &SchemaChange{
+		ObjectKind:    info.ObjectKind,
+		ObjectName:    info.ObjectName,
+		StatementKind: info.StatementKind,
+		Statement:     query,
+	}
+}
+
+// noteSchemaChange records the outcome of query, which succeeded. A DDL
+// change is queued, a rollback drops the queue, and the queue is published
+// once no transaction remains open.
+func (c *conn) noteSchemaChange(query string, change *SchemaChange) {
+	hooks := c.schemaHooks.Load()
+	if hooks == nil {
+		return
+	}
+	hooks.mu.Lock()
+	if change != nil {
+		hooks.pending = append(hooks.pending, *change)
+	}
+	if isTransactionControlQuery(query, nil) == "ROLLBACK" {
+		hooks.pending = nil
+	}
+	if c.InTransaction() {
+		hooks.mu.Unlock()
+		return
+	}
+	pending, funcs := hooks.pending, hooks.funcs
+	hooks.pending = nil
+	hooks.mu.Unlock()
+	for _, event := range pending {
+		for _, fn := range funcs {
+			fn(event)
+		}
+	}
+}
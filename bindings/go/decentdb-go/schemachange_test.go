@@ -0,0 +1,93 @@
+package decentdb
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestIsSchemaStatement(t *testing.T) {
+	cases := map[string]bool{
+		"CREATE TABLE t (id INT)":        true,
+		"  drop index t_idx":             true,
+		"ALTER TABLE t ADD COLUMN n INT": true,
+		"COMMENT ON TABLE t IS 'x'":      true,
+		"INSERT INTO t VALUES (1)":       false,
+		"SELECT 1":                       false,
+		"":                               false,
+	}
+	for query, want := range cases {
+		if got := isSchemaStatement(query); got != want {
+			t.Fatalf("%q: got %v, want %v", query, got, want)
+		}
+	}
+}
+
+func TestOpenDirect_OnSchemaChange(t *testing.T) {
+	db, err := OpenDirect(filepath.Join(t.TempDir(), "schema_change.ddb"))
+	if err != nil {
+		t.Fatalf("OpenDirect failed: %v", err)
+	}
+	defer db.Close()
+
+	var events []SchemaChange
+	db.OnSchemaChange(func(event SchemaChange) {
+		events = append(events, event)
+	})
+
+	if _, err := db.Exec("CREATE TABLE items (id INT PRIMARY KEY, name TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("INSERT INTO items VALUES ($1, $2)", int64(1), "apple"); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("expected one event after CREATE TABLE and INSERT, got %+v", events)
+	}
+	want := SchemaChange{
+		ObjectKind:    "table",
+		ObjectName:    "items",
+		StatementKind: "create_table",
+		Statement:     "CREATE TABLE items (id INT PRIMARY KEY, name TEXT)",
+	}
+	if events[0] != want {
+		t.Fatalf("got %+v, want %+v", events[0], want)
+	}
+
+	if _, err := db.Exec("BEGIN"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("CREATE INDEX items_name ON items (name)"); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("uncommitted DDL was reported: %+v", events)
+	}
+	if _, err := db.Exec("ROLLBACK"); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("rolled back DDL was reported: %+v", events)
+	}
+
+	if _, err := db.Exec("BEGIN"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("CREATE INDEX items_name ON items (name)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("DROP TABLE items"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("COMMIT"); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("expected committed DDL to be reported, got %+v", events)
+	}
+	if events[1].ObjectKind != "index" || events[1].ObjectName != "items_name" {
+		t.Fatalf("unexpected index event: %+v", events[1])
+	}
+	if events[2].StatementKind != "drop_table" || events[2].ObjectName != "items" {
+		t.Fatalf("unexpected drop event: %+v", events[2])
+	}
+}
@@ -0,0 +1,255 @@
+package seed
+
+import (
+	"strconv"
+	"strings"
+)
+
+// bounds are the limits simple CHECK constraints put on one column.
+type bounds struct {
+	min, max       *float64 // inclusive
+	strictMin      bool     // min itself is excluded
+	strictMax      bool     // max itself is excluded
+	in             []any    // allowed values; nil means any
+	minLen, maxLen int      // 0 means no limit
+}
+
+// applyChecks narrows the bounds of the columns named in checks. The engine
+// reports a CHECK fully parenthesized, for example
+// "((qty >= 1) AND (qty <= 100))", and only conjunctions of comparisons
+// with a constant, BETWEEN, IN lists, and length() limits are understood.
+// Anything else is left for the insert to reject and the row to be retried.
+func applyChecks(checks []string, columns map[string]*bounds) {
+	for _, check := range checks {
+		for _, term := range conjuncts(check) {
+			applyTerm(term, columns)
+		}
+	}
+}
+
+// conjuncts splits an expression at its top-level ANDs.
+func conjuncts(expr string) []string {
+	expr = stripParens(expr)
+	if _, _, ok := splitTopLevel(expr, " BETWEEN "); ok {
+		return []string{expr}
+	}
+	left, right, ok := splitTopLevel(expr, " AND ")
+	if !ok {
+		return []string{expr}
+	}
+	return append(conjuncts(left), conjuncts(right)...)
+}
+
+func applyTerm(term string, columns map[string]*bounds) {
+	term = stripParens(term)
+	if operand, rest, ok := splitTopLevel(term, " BETWEEN "); ok {
+		low, high, ok := splitTopLevel(rest, " AND ")
+		lo, okLo := number(low)
+		hi, okHi := number(high)
+		if ok && okLo && okHi {
+			narrow(operand, ">=", lo, columns)
+			narrow(operand, "<=", hi, columns)
+		}
+		return
+	}
+	if operand, rest, ok := splitTopLevel(term, " IN "); ok {
+		b := columnBounds(operand, columns)
+		list := stripParens(rest)
+		if b == nil || strings.TrimSpace(rest) == list {
+			return
+		}
+		var values []any
+		for _, item := range splitList(list) {
+			value, ok := literal(item)
+			if !ok {
+				return
+			}
+			values = append(values, value)
+		}
+		b.in = values
+		return
+	}
+	for _, op := range []string{">=", "<=", "<>", "!=", ">", "<", "="} {
+		left, right, ok := splitTopLevel(term, " "+op+" ")
+		if !ok {
+			continue
+		}
+		if value, ok := number(right); ok {
+			narrow(left, op, value, columns)
+		} else if value, ok := number(left); ok {
+			narrow(right, flip(op), value, columns)
+		} else if op == "=" {
+			if value, ok := literal(right); ok {
+				if b := columnBounds(left, columns); b != nil {
+					b.in = []any{value}
+				}
+			}
+		}
+		return
+	}
+}
+
+// narrow applies "operand op value", where operand is a column or
+// length(column).
+func narrow(operand, op string, value float64, columns map[string]*bounds) {
+	operand = stripParens(operand)
+	lower := strings.ToLower(operand)
+	for _, fn := range []string{"length(", "char_length(", "character_length("} {
+		if strings.HasPrefix(lower, fn) && strings.HasSuffix(lower, ")") {
+			b := columnBounds(operand[len(fn):len(operand)-1], columns)
+			if b == nil {
+				return
+			}
+			n := int(value)
+			switch op {
+			case "<":
+				b.maxLen = n - 1
+			case "<=":
+				b.maxLen = n
+			case ">":
+				b.minLen = n + 1
+			case ">=":
+				b.minLen = n
+			case "=":
+				b.minLen, b.maxLen = n, n
+			}
+			return
+		}
+	}
+	b := columnBounds(operand, columns)
+	if b == nil {
+		return
+	}
+	switch op {
+	case ">", ">=":
+		if b.min == nil || value >= *b.min {
+			b.min, b.strictMin = &value, op == ">"
+		}
+	case "<", "<=":
+		if b.max == nil || value <= *b.max {
+			b.max, b.strictMax = &value, op == "<"
+		}
+	case "=":
+		b.in = []any{value}
+	}
+}
+
+func flip(op string) string {
+	switch op {
+	case ">":
+		return "<"
+	case "<":
+		return ">"
+	case ">=":
+		return "<="
+	case "<=":
+		return ">="
+	}
+	return op
+}
+
+// columnBounds returns the bounds of the column an operand names, if any.
+func columnBounds(operand string, columns map[string]*bounds) *bounds {
+	name := strings.TrimSpace(stripParens(operand))
+	if i := strings.LastIndexByte(name, '.'); i >= 0 {
+		name = name[i+1:]
+	}
+	name = strings.Trim(name, `"`)
+	return columns[strings.ToLower(name)]
+}
+
+// number parses a numeric literal, including the "-(5)" form of a negative
+// constant.
+func number(s string) (float64, bool) {
+	s = strings.TrimSpace(s)
+	negative := false
+	if rest, ok := strings.CutPrefix(s, "-"); ok {
+		negative, s = true, stripParens(rest)
+	}
+	v, err := strconv.ParseFloat(stripParens(s), 64)
+	if err != nil {
+		return 0, false
+	}
+	if negative {
+		v = -v
+	}
+	return v, true
+}
+
+// literal parses a string or numeric literal.
+func literal(s string) (any, bool) {
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'' {
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'"), true
+	}
+	if v, ok := number(s); ok {
+		return v, true
+	}
+	return nil, false
+}
+
+// stripParens removes parentheses that enclose the whole expression.
+func stripParens(s string) string {
+	for {
+		s = strings.TrimSpace(s)
+		if len(s) < 2 || s[0] != '(' || s[len(s)-1] != ')' || closingParen(s) != len(s)-1 {
+			return s
+		}
+		s = s[1 : len(s)-1]
+	}
+}
+
+// closingParen returns the index of the parenthesis closing s[0].
+func closingParen(s string) int {
+	depth := 0
+	quoted := false
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\'':
+			quoted = !quoted
+		case quoted:
+		case s[i] == '(':
+			depth++
+		case s[i] == ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// splitTopLevel splits s at the first sep, matched case-insensitively, that
+// is outside parentheses and string literals.
+func splitTopLevel(s, sep string) (string, string, bool) {
+	depth := 0
+	quoted := false
+	for i := 0; i+len(sep) <= len(s); i++ {
+		switch {
+		case s[i] == '\'':
+			quoted = !quoted
+		case quoted:
+		case s[i] == '(':
+			depth++
+		case s[i] == ')':
+			depth--
+		case depth == 0 && strings.EqualFold(s[i:i+len(sep)], sep):
+			return s[:i], s[i+len(sep):], true
+		}
+	}
+	return "", "", false
+}
+
+// splitList splits a comma-separated list outside string literals.
+func splitList(s string) []string {
+	var items []string
+	for {
+		item, rest, ok := splitTopLevel(s, ",")
+		if !ok {
+			return append(items, s)
+		}
+		items = append(items, item)
+		s = rest
+	}
+}
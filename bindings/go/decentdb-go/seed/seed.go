@@ -0,0 +1,601 @@
+// Package seed fills a DecentDB database with generated rows, so load tests
+// and demos do not need a copy of production data. It reads the schema and
+// generates values that satisfy it:
+//
+//   - tables are filled parents first, and foreign key columns take keys of
+//     rows that exist in the referenced table;
+//   - primary key, UNIQUE column, and unique index values are never repeated;
+//   - simple CHECK constraints (comparisons with constants, BETWEEN, IN
+//     lists, and length limits) bound the generated values, and rows that
+//     violate any other constraint are regenerated.
+//
+// Values look like real data where a column's name suggests what it holds
+// (email, first_name, city, price, created_at, ...), and the same Seed
+// produces the same rows for the same schema:
+//
+//	results, err := seed.Run(ctx, db, seed.Options{Rows: 10_000, Seed: 42})
+//
+// An integer primary key is left for the engine to assign. Generated
+// columns and columns of types that cannot be generated (ENUM, INTERVAL,
+// GEOMETRY, GEOGRAPHY) are left to their defaults, or NULL. Nullable
+// foreign keys that would close a cycle between tables are left NULL.
+package seed
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+
+	"github.com/sphildreth/decentdb-go"
+)
+
+const (
+	// defaultRows is how many rows Run adds to each table by default.
+	defaultRows = 100
+	// defaultNullFraction is the default share of nullable values left NULL.
+	defaultNullFraction = 0.05
+	// maxAttempts bounds how often one row is regenerated after the engine
+	// rejects it.
+	maxAttempts = 50
+	// batchRows is how many rows are inserted per transaction.
+	batchRows = 1000
+)
+
+// Options configures Run.
+type Options struct {
+	// Rows is how many rows are added to each table. Zero means 100; a
+	// negative value adds none, so only the tables in TableRows are filled.
+	Rows int
+	// TableRows overrides Rows for the named tables.
+	TableRows map[string]int
+	// Tables limits seeding to the named tables; empty means every table.
+	// Tables they reference that are not seeded must already have rows.
+	Tables []string
+	// Seed seeds the random generator.
+	Seed uint64
+	// NullFraction is the share of values left NULL in nullable columns that
+	// are not foreign keys. Zero means 0.05; a negative value means never.
+	NullFraction float64
+}
+
+// TableResult reports the rows added to one table.
+type TableResult struct {
+	Table string
+	Rows  int64
+}
+
+// column is a column Run supplies values for.
+type column struct {
+	info   decentdb.ColumnInfo
+	typ    string
+	unique bool // values must not repeat on their own
+	bounds bounds
+	fk     *foreignKey
+}
+
+// foreignKey is a foreign key with the keys its columns can take.
+type foreignKey struct {
+	info    decentdb.ForeignKeyInfo
+	columns []int // positions in table.columns, in info.Columns order
+	keys    [][]any
+	unique  bool // each key may be used once
+	next    int  // next unused key when unique
+	null    bool // left NULL: the parent is filled later
+}
+
+// table is a table Run fills.
+type table struct {
+	info    decentdb.TableInfo
+	columns []*column
+	fks     []*foreignKey
+	uniques [][]int // column sets whose tuples must not repeat
+	checked bool    // has CHECK constraints
+}
+
+// schemaConn is the part of the driver connection Run reads the schema from.
+type schemaConn interface {
+	ListTables() ([]string, error)
+	GetTableInfo(string) (decentdb.TableInfo, error)
+	ListIndexes() ([]decentdb.IndexInfo, error)
+}
+
+// Run adds generated rows to the tables of db and reports how many rows each
+// filled table received, in the order the tables were filled. A table with a
+// unique foreign key receives at most one row per unused parent key.
+func Run(ctx context.Context, db *sql.DB, opts Options) ([]TableResult, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	tables, err := loadSchema(conn, opts.Tables)
+	if err != nil {
+		return nil, err
+	}
+	ordered, err := fillOrder(tables)
+	if err != nil {
+		return nil, err
+	}
+	nullFraction := opts.NullFraction
+	if nullFraction == 0 {
+		nullFraction = defaultNullFraction
+	}
+	r := rand.New(rand.NewPCG(opts.Seed, opts.Seed^0x5eed))
+	results := make([]TableResult, 0, len(ordered))
+	for _, t := range ordered {
+		rows := opts.Rows
+		if rows == 0 {
+			rows = defaultRows
+		}
+		if n, ok := lookup(opts.TableRows, t.info.Name); ok {
+			rows = n
+		}
+		if rows <= 0 {
+			continue
+		}
+		n, err := fill(ctx, conn, r, t, rows, nullFraction)
+		if err != nil {
+			return results, fmt.Errorf("seed: %s: %w", t.info.Name, err)
+		}
+		results = append(results, TableResult{Table: t.info.Name, Rows: n})
+	}
+	return results, nil
+}
+
+func lookup(m map[string]int, name string) (int, bool) {
+	for key, n := range m {
+		if strings.EqualFold(key, name) {
+			return n, true
+		}
+	}
+	return 0, false
+}
+
+// loadSchema describes the tables to fill.
+func loadSchema(conn *sql.Conn, names []string) ([]*table, error) {
+	var tables []*table
+	err := conn.Raw(func(dc any) error {
+		sc, ok := dc.(schemaConn)
+		if !ok {
+			return fmt.Errorf("seed: %T is not a DecentDB connection", dc)
+		}
+		if len(names) == 0 {
+			var err error
+			if names, err = sc.ListTables(); err != nil {
+				return err
+			}
+		}
+		indexes, err := sc.ListIndexes()
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			info, err := sc.GetTableInfo(name)
+			if err != nil {
+				return fmt.Errorf("seed: %s: %w", name, err)
+			}
+			if info.Temporary {
+				continue
+			}
+			tables = append(tables, newTable(info, indexes))
+		}
+		return nil
+	})
+	return tables, err
+}
+
+func newTable(info decentdb.TableInfo, indexes []decentdb.IndexInfo) *table {
+	t := &table{info: info, checked: len(info.Checks) > 0}
+	byName := make(map[string]*bounds)
+	positions := make(map[string]int)
+	autoKey := len(info.PrimaryKeyColumns) == 1
+	for _, c := range info.Columns {
+		typ := strings.ToUpper(c.Type)
+		if i := strings.IndexByte(typ, '('); i >= 0 {
+			typ = typ[:i]
+		}
+		primary := c.PrimaryKey || containsFold(info.PrimaryKeyColumns, c.Name)
+		switch {
+		case c.Generated != "":
+			continue
+		case primary && autoKey && typ == "INT64" && !referenced(info.ForeignKeys, c.Name):
+			continue
+		case !supportedType(typ) && (!c.NotNull || c.Default != ""):
+			continue
+		}
+		col := &column{info: c, typ: typ, unique: c.Unique || primary && autoKey}
+		t.columns = append(t.columns, col)
+		byName[strings.ToLower(c.Name)] = &col.bounds
+		positions[strings.ToLower(c.Name)] = len(t.columns) - 1
+		if len(c.Checks) > 0 {
+			t.checked = true
+		}
+	}
+	for _, c := range t.columns {
+		applyChecks(c.info.Checks, byName)
+	}
+	applyChecks(info.Checks, byName)
+
+	addUnique := func(names []string) {
+		set := make([]int, 0, len(names))
+		for _, name := range names {
+			i, ok := positions[strings.ToLower(name)]
+			if !ok {
+				return // includes a column the engine assigns
+			}
+			set = append(set, i)
+		}
+		for _, other := range t.uniques {
+			if len(other) == len(set) && coveredBy(other, set) {
+				return
+			}
+		}
+		if len(set) == 1 {
+			t.columns[set[0]].unique = true
+		}
+		t.uniques = append(t.uniques, set)
+	}
+	if len(info.PrimaryKeyColumns) > 0 {
+		addUnique(info.PrimaryKeyColumns)
+	}
+	for _, c := range t.columns {
+		if c.unique {
+			addUnique([]string{c.info.Name})
+		}
+	}
+	for _, idx := range indexes {
+		if idx.Unique && strings.EqualFold(idx.Table, info.Name) && len(idx.Columns) > 0 {
+			addUnique(idx.Columns)
+		}
+	}
+
+	for _, fkInfo := range info.ForeignKeys {
+		fk := &foreignKey{info: fkInfo}
+		for _, name := range fkInfo.Columns {
+			i, ok := positions[strings.ToLower(name)]
+			if !ok {
+				fk = nil
+				break
+			}
+			fk.columns = append(fk.columns, i)
+		}
+		if fk == nil {
+			continue
+		}
+		for _, set := range t.uniques {
+			if coveredBy(set, fk.columns) {
+				fk.unique = true
+			}
+		}
+		for _, i := range fk.columns {
+			t.columns[i].fk = fk
+		}
+		t.fks = append(t.fks, fk)
+	}
+	return t
+}
+
+func containsFold(list []string, name string) bool {
+	for _, s := range list {
+		if strings.EqualFold(s, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func referenced(fks []decentdb.ForeignKeyInfo, name string) bool {
+	for _, fk := range fks {
+		if containsFold(fk.Columns, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// coveredBy reports whether every column of set is in cols.
+func coveredBy(set, cols []int) bool {
+	for _, i := range set {
+		found := false
+		for _, j := range cols {
+			found = found || i == j
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// fillOrder sorts tables so each is filled after the tables it references.
+// A cycle is broken at a table whose foreign keys into the cycle are all
+// nullable; those keys are left NULL.
+func fillOrder(tables []*table) ([]*table, error) {
+	byName := make(map[string]*table, len(tables))
+	for _, t := range tables {
+		byName[strings.ToLower(t.info.Name)] = t
+	}
+	done := make(map[*table]bool, len(tables))
+	var ordered []*table
+	waiting := func(t *table, fk *foreignKey) bool {
+		parent, ok := byName[strings.ToLower(fk.info.RefTable)]
+		return ok && parent != t && !done[parent]
+	}
+	for len(ordered) < len(tables) {
+		progressed := false
+		for _, t := range tables {
+			if done[t] {
+				continue
+			}
+			ready := true
+			for _, fk := range t.fks {
+				ready = ready && !waiting(t, fk)
+			}
+			if ready {
+				done[t] = true
+				ordered = append(ordered, t)
+				progressed = true
+			}
+		}
+		if progressed {
+			continue
+		}
+		var broken *table
+		for _, t := range tables {
+			if !done[t] && broken == nil && nullableWaits(t, waiting) {
+				broken = t
+			}
+		}
+		if broken == nil {
+			var names []string
+			for _, t := range tables {
+				if !done[t] {
+					names = append(names, t.info.Name)
+				}
+			}
+			return nil, fmt.Errorf("seed: NOT NULL foreign keys form a cycle between %s", strings.Join(names, ", "))
+		}
+		for _, fk := range broken.fks {
+			if waiting(broken, fk) {
+				fk.null = true
+			}
+		}
+		done[broken] = true
+		ordered = append(ordered, broken)
+	}
+	return ordered, nil
+}
+
+// nullableWaits reports whether every foreign key t waits on is nullable.
+func nullableWaits(t *table, waiting func(*table, *foreignKey) bool) bool {
+	for _, fk := range t.fks {
+		if !waiting(t, fk) {
+			continue
+		}
+		for _, i := range fk.columns {
+			if t.columns[i].info.NotNull {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// fill adds up to rows rows to t and returns how many it added.
+func fill(ctx context.Context, conn *sql.Conn, r *rand.Rand, t *table, rows int, nullFraction float64) (int64, error) {
+	var existing int64
+	if err := conn.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+quote(t.info.Name)).Scan(&existing); err != nil {
+		return 0, err
+	}
+	for _, fk := range t.fks {
+		if fk.null {
+			continue
+		}
+		if err := loadKeys(ctx, conn, r, t.info.Name, fk); err != nil {
+			return 0, err
+		}
+		if len(fk.keys) == 0 && t.columns[fk.columns[0]].info.NotNull {
+			return 0, fmt.Errorf("references %s, which has no rows", fk.info.RefTable)
+		}
+		if fk.unique && len(fk.keys) < rows {
+			rows = len(fk.keys)
+		}
+	}
+	if len(t.columns) == 0 {
+		return 0, errors.New("no columns can be generated")
+	}
+	for _, c := range t.columns {
+		if !supportedType(c.typ) && c.fk == nil {
+			return 0, fmt.Errorf("cannot generate values for NOT NULL column %s of type %s", c.info.Name, c.info.Type)
+		}
+	}
+
+	names := make([]string, len(t.columns))
+	placeholders := make([]string, len(t.columns))
+	for i, c := range t.columns {
+		names[i] = quote(c.info.Name)
+		placeholders[i] = "$" + strconv.Itoa(i+1)
+	}
+	insert := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		quote(t.info.Name), strings.Join(names, ", "), strings.Join(placeholders, ", "))
+	// Rows that may violate a constraint run under a savepoint so a rejected
+	// row can be regenerated without losing the batch.
+	retry := t.checked || len(t.uniques) > 0
+
+	seen := make([]map[string]bool, len(t.uniques))
+	for i := range seen {
+		seen[i] = make(map[string]bool)
+	}
+	var added int64
+	for added < int64(rows) {
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return added, err
+		}
+		stmt, err := tx.PrepareContext(ctx, insert)
+		if err != nil {
+			_ = tx.Rollback()
+			return added, err
+		}
+		batch := int64(0)
+		for batch < batchRows && added+batch < int64(rows) {
+			seq := existing + added + batch + 1
+			if err := insertRow(ctx, tx, stmt, r, t, seq, nullFraction, seen, retry); err != nil {
+				_ = tx.Rollback()
+				return added, err
+			}
+			batch++
+		}
+		if err := tx.Commit(); err != nil {
+			return added, err
+		}
+		added += batch
+	}
+	return added, nil
+}
+
+// insertRow generates and inserts one row, regenerating it when it repeats a
+// unique tuple or the engine rejects it with a constraint error.
+func insertRow(ctx context.Context, tx *sql.Tx, stmt *sql.Stmt, r *rand.Rand, t *table, seq int64,
+	nullFraction float64, seen []map[string]bool, retry bool) error {
+	var lastErr error
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		values := generateRow(r, t, seq+int64(attempt)*10_000_000, nullFraction)
+		keys, ok := uniqueKeys(t, values, seen)
+		if !ok {
+			lastErr = errors.New("generated values repeat a unique key")
+			continue
+		}
+		if !retry {
+			if _, err := stmt.ExecContext(ctx, values...); err != nil {
+				return err
+			}
+			return nil
+		}
+		if _, err := tx.ExecContext(ctx, "SAVEPOINT seed_row"); err != nil {
+			return err
+		}
+		if _, err := stmt.ExecContext(ctx, values...); err != nil {
+			var dbErr *decentdb.DecentDBError
+			if !errors.As(err, &dbErr) || dbErr.Code != constraintCode {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT seed_row"); err != nil {
+				return err
+			}
+			lastErr = err
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT seed_row"); err != nil {
+			return err
+		}
+		for i, key := range keys {
+			seen[i][key] = true
+		}
+		return nil
+	}
+	return fmt.Errorf("no row satisfying the constraints after %d attempts: %w", maxAttempts, lastErr)
+}
+
+// constraintCode is DecentDBError.Code for a constraint violation.
+const constraintCode = 3
+
+func generateRow(r *rand.Rand, t *table, seq int64, nullFraction float64) []any {
+	values := make([]any, len(t.columns))
+	for _, fk := range t.fks {
+		var key []any
+		switch {
+		case fk.null || len(fk.keys) == 0:
+		case fk.unique:
+			key = fk.keys[fk.next%len(fk.keys)]
+			fk.next++
+		default:
+			key = fk.keys[r.IntN(len(fk.keys))]
+		}
+		for k, i := range fk.columns {
+			if key != nil {
+				values[i] = key[k]
+			}
+		}
+	}
+	for i, c := range t.columns {
+		if c.fk != nil || !supportedType(c.typ) {
+			continue
+		}
+		if !c.info.NotNull && !c.unique && nullFraction > 0 && r.Float64() < nullFraction {
+			continue
+		}
+		values[i] = generateValue(r, c, seq)
+	}
+	return values
+}
+
+// uniqueKeys returns the tuple of each unique column set in values, and
+// false when one was already inserted. Tuples with a NULL never conflict.
+func uniqueKeys(t *table, values []any, seen []map[string]bool) ([]string, bool) {
+	keys := make([]string, len(t.uniques))
+	for u, set := range t.uniques {
+		parts := make([]string, len(set))
+		for k, i := range set {
+			if values[i] == nil {
+				parts = nil
+				break
+			}
+			parts[k] = fmt.Sprintf("%T:%v", values[i], values[i])
+		}
+		if parts == nil {
+			continue
+		}
+		keys[u] = strings.Join(parts, "\x00")
+		if seen[u][keys[u]] {
+			return nil, false
+		}
+	}
+	return keys, true
+}
+
+// loadKeys reads the keys fk of child can reference, in random order. A
+// unique single-column foreign key skips keys existing rows already use.
+func loadKeys(ctx context.Context, conn *sql.Conn, r *rand.Rand, child string, fk *foreignKey) error {
+	quoted := make([]string, len(fk.info.RefColumns))
+	for i, name := range fk.info.RefColumns {
+		quoted[i] = quote(name)
+	}
+	list := strings.Join(quoted, ", ")
+	query := fmt.Sprintf("SELECT DISTINCT %s FROM %s", list, quote(fk.info.RefTable))
+	if fk.unique && len(quoted) == 1 {
+		used := quote(fk.info.Columns[0])
+		query += fmt.Sprintf(" WHERE %s NOT IN (SELECT %s FROM %s WHERE %s IS NOT NULL)",
+			quoted[0], used, quote(child), used)
+	}
+	rows, err := conn.QueryContext(ctx, query+" ORDER BY "+list)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	fk.keys = nil
+	for rows.Next() {
+		key := make([]any, len(quoted))
+		dest := make([]any, len(key))
+		for i := range key {
+			dest[i] = &key[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return err
+		}
+		fk.keys = append(fk.keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	r.Shuffle(len(fk.keys), func(i, j int) { fk.keys[i], fk.keys[j] = fk.keys[j], fk.keys[i] })
+	return nil
+}
+
+func quote(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
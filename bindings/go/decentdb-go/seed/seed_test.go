@@ -0,0 +1,151 @@
+package seed
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sphildreth/decentdb-go"
+	"github.com/sphildreth/decentdb-go/decentdbtest"
+)
+
+func TestApplyChecks(t *testing.T) {
+	qty, status, code, price, age := &bounds{}, &bounds{}, &bounds{}, &bounds{}, &bounds{}
+	columns := map[string]*bounds{"qty": qty, "status": status, "code": code, "price": price, "age": age}
+	applyChecks([]string{
+		"((qty >= 1) AND (qty < 100))",
+		"(status IN ('new', 'it''s', 'done'))",
+		"((LENGTH(code) <= 8) AND (code <> ''))",
+		"(0 < price)",
+		"(age BETWEEN 18 AND -(-65))",
+		"((qty > 5) OR (qty < 0))",
+	}, columns)
+	if *qty.min != 1 || qty.strictMin || *qty.max != 100 || !qty.strictMax {
+		t.Fatalf("qty bounds = %+v", qty)
+	}
+	if len(status.in) != 3 || status.in[1] != "it's" {
+		t.Fatalf("status values = %v", status.in)
+	}
+	if code.maxLen != 8 {
+		t.Fatalf("code maxLen = %d", code.maxLen)
+	}
+	if *price.min != 0 || !price.strictMin || price.max != nil {
+		t.Fatalf("price bounds = %+v", price)
+	}
+	if *age.min != 18 || *age.max != 65 {
+		t.Fatalf("age bounds = %v, %v", *age.min, *age.max)
+	}
+}
+
+func TestRunRespectsSchema(t *testing.T) {
+	db := decentdbtest.Open(t)
+	db.SetMaxOpenConns(1)
+	for _, stmt := range []string{
+		`CREATE TABLE customers (
+			id INT64 PRIMARY KEY,
+			email TEXT NOT NULL UNIQUE,
+			first_name TEXT NOT NULL,
+			age INT64 CHECK (age BETWEEN 21 AND 30),
+			tier TEXT CHECK (tier IN ('gold', 'silver'))
+		)`,
+		`CREATE TABLE profiles (
+			id INT64 PRIMARY KEY,
+			customer_id INT64 NOT NULL UNIQUE REFERENCES customers(id),
+			bio TEXT
+		)`,
+		`CREATE TABLE orders (
+			id INT64 PRIMARY KEY,
+			customer_id INT64 NOT NULL REFERENCES customers(id),
+			price DECIMAL(10, 2) NOT NULL CHECK (price > 0),
+			created_at TIMESTAMP NOT NULL,
+			sku TEXT NOT NULL
+		)`,
+		`CREATE UNIQUE INDEX orders_customer_sku ON orders (customer_id, sku)`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	ctx := context.Background()
+	results, err := Run(ctx, db, Options{Rows: 50, TableRows: map[string]int{"orders": 300, "profiles": 80}, Seed: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]int64{}
+	var order []string
+	for _, r := range results {
+		got[r.Table] = r.Rows
+		order = append(order, r.Table)
+	}
+	if order[0] != "customers" || got["customers"] != 50 || got["orders"] != 300 || got["profiles"] != 50 {
+		t.Fatalf("results = %+v", results)
+	}
+
+	var n int
+	for query, want := range map[string]int{
+		"SELECT COUNT(*) FROM customers WHERE age IS NOT NULL AND (age < 21 OR age > 30)":                0,
+		"SELECT COUNT(*) FROM customers WHERE tier IS NOT NULL AND tier NOT IN ('gold', 'silver')":       0,
+		"SELECT COUNT(DISTINCT email) FROM customers":                                                    50,
+		"SELECT COUNT(DISTINCT customer_id) FROM profiles":                                               50,
+		"SELECT COUNT(*) FROM orders o LEFT JOIN customers c ON c.id = o.customer_id WHERE c.id IS NULL": 0,
+		"SELECT COUNT(*) FROM orders WHERE price <= 0":                                                   0,
+	} {
+		if err := db.QueryRow(query).Scan(&n); err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+		if n != want {
+			t.Errorf("%s = %d, want %d", query, n, want)
+		}
+	}
+	var email string
+	if err := db.QueryRow("SELECT email FROM customers LIMIT 1").Scan(&email); err != nil || !strings.Contains(email, "@") {
+		t.Fatalf("email = %q, %v", email, err)
+	}
+
+	// Seeding again adds rows next to the existing ones.
+	if _, err := Run(ctx, db, Options{Rows: 10, Tables: []string{"customers"}, Seed: 7}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.QueryRow("SELECT COUNT(DISTINCT email) FROM customers").Scan(&n); err != nil || n != 60 {
+		t.Fatalf("customers after reseeding = %d, %v", n, err)
+	}
+}
+
+func TestRunSelfReference(t *testing.T) {
+	db := decentdbtest.Open(t)
+	if _, err := db.Exec("CREATE TABLE employees (id INT64 PRIMARY KEY, name TEXT NOT NULL, manager_id INT64 REFERENCES employees(id))"); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	for range 2 {
+		if _, err := Run(ctx, db, Options{Rows: 20, NullFraction: -1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var managed int
+	if err := db.QueryRow("SELECT COUNT(*) FROM employees WHERE manager_id IS NOT NULL").Scan(&managed); err != nil || managed != 20 {
+		t.Fatalf("employees with a manager = %d, %v; want the second 20", managed, err)
+	}
+}
+
+func TestFillOrderBreaksNullableCycles(t *testing.T) {
+	newCycle := func(bNullable bool) []*table {
+		a := &table{info: decentdb.TableInfo{Name: "a"}}
+		b := &table{info: decentdb.TableInfo{Name: "b"}}
+		a.columns = []*column{{info: decentdb.ColumnInfo{Name: "b_id", NotNull: true}}}
+		b.columns = []*column{{info: decentdb.ColumnInfo{Name: "a_id", NotNull: !bNullable}}}
+		a.fks = []*foreignKey{{info: decentdb.ForeignKeyInfo{RefTable: "b"}, columns: []int{0}}}
+		b.fks = []*foreignKey{{info: decentdb.ForeignKeyInfo{RefTable: "A"}, columns: []int{0}}}
+		return []*table{a, b}
+	}
+	if _, err := fillOrder(newCycle(false)); err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Fatalf("NOT NULL cycle: %v", err)
+	}
+	ordered, err := fillOrder(newCycle(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ordered[0].info.Name != "b" || !ordered[0].fks[0].null || ordered[1].fks[0].null {
+		t.Fatalf("order = %s, %s", ordered[0].info.Name, ordered[1].info.Name)
+	}
+}
@@ -0,0 +1,345 @@
+package seed
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/sphildreth/decentdb-go"
+)
+
+var (
+	firstNames = []string{
+		"Ada", "Alan", "Grace", "Linus", "Margaret", "Dennis", "Barbara", "Ken", "Radia", "Edsger",
+		"Frances", "Donald", "Hedy", "Tim", "Katherine", "John", "Annie", "Guido", "Sophie", "Niklaus",
+		"Mary", "James", "Joan", "Bjarne", "Evelyn", "Leslie", "Karen", "Brian", "Lynn", "Yukihiro",
+	}
+	lastNames = []string{
+		"Lovelace", "Turing", "Hopper", "Torvalds", "Hamilton", "Ritchie", "Liskov", "Thompson",
+		"Perlman", "Dijkstra", "Allen", "Knuth", "Lamarr", "Berners-Lee", "Johnson", "McCarthy",
+		"Easley", "van Rossum", "Wilson", "Wirth", "Keller", "Gosling", "Clarke", "Stroustrup",
+		"Berezin", "Lamport", "Jones", "Kernighan", "Conway", "Matsumoto",
+	}
+	cities = []string{
+		"Lisbon", "Osaka", "Toronto", "Nairobi", "Melbourne", "Austin", "Bergen", "Valparaiso",
+		"Krakow", "Porto", "Denver", "Seoul", "Cork", "Leeds", "Lyon", "Tucson", "Bologna", "Quebec",
+	}
+	countries = []string{
+		"Portugal", "Japan", "Canada", "Kenya", "Australia", "United States", "Norway", "Chile",
+		"Poland", "Brazil", "South Korea", "Ireland", "United Kingdom", "France", "Italy", "Germany",
+	}
+	streets = []string{
+		"Main St", "Oak Ave", "Maple Dr", "Cedar Ln", "Elm St", "Pine Rd", "Lake View", "Hill St",
+		"Park Ave", "River Rd", "Sunset Blvd", "Mill Ln",
+	}
+	companyWords = []string{
+		"Acme", "Globex", "Initech", "Umbrella", "Stark", "Wayne", "Hooli", "Vandelay", "Cyberdyne",
+		"Soylent", "Tyrell", "Wonka", "Aperture", "Massive", "Dynamic", "Northwind",
+	}
+	companySuffixes = []string{"Inc", "LLC", "Ltd", "Group", "Labs", "Systems", "Partners", "Co"}
+	domains         = []string{"example.com", "example.org", "example.net", "mail.test", "corp.test"}
+	statuses        = []string{"active", "pending", "inactive", "archived", "suspended"}
+	colors          = []string{"red", "green", "blue", "black", "white", "silver", "orange", "purple"}
+	words           = strings.Fields(`lorem ipsum dolor sit amet consectetur adipiscing elit sed do
+		eiusmod tempor incididunt ut labore et dolore magna aliqua enim ad minim veniam quis nostrud
+		exercitation ullamco laboris nisi aliquip ex ea commodo consequat duis aute irure in
+		reprehenderit voluptate velit esse cillum fugiat nulla pariatur excepteur sint occaecat
+		cupidatat non proident sunt culpa qui officia deserunt mollit anim id est laborum`)
+)
+
+// timeRangeStart and timeRangeEnd bound generated dates and timestamps.
+var (
+	timeRangeStart = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	timeRangeEnd   = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// supportedType reports whether values of a column type can be generated.
+func supportedType(typ string) bool {
+	switch typ {
+	case "INT64", "FLOAT64", "DECIMAL", "TEXT", "BOOL", "BLOB", "UUID", "TIMESTAMP", "TIMESTAMPTZ",
+		"DATE", "TIME", "IPADDR", "CIDR", "MACADDR":
+		return true
+	}
+	return false
+}
+
+func pick(r *rand.Rand, list []string) string {
+	return list[r.IntN(len(list))]
+}
+
+func sentence(r *rand.Rand, minWords, maxWords int) string {
+	n := minWords + r.IntN(maxWords-minWords+1)
+	parts := make([]string, n)
+	for i := range parts {
+		parts[i] = pick(r, words)
+	}
+	parts[0] = strings.ToUpper(parts[0][:1]) + parts[0][1:]
+	return strings.Join(parts, " ")
+}
+
+// hasWord reports whether a lower-case column name contains any of the
+// given words as a whole underscore-separated part or as its suffix.
+func hasWord(name string, candidates ...string) bool {
+	for _, candidate := range candidates {
+		if name == candidate || strings.HasSuffix(name, "_"+candidate) ||
+			strings.HasPrefix(name, candidate+"_") || strings.Contains(name, "_"+candidate+"_") ||
+			strings.HasSuffix(name, candidate) && len(candidate) > 4 {
+			return true
+		}
+	}
+	return false
+}
+
+// generateValue returns a value for column c. seq numbers the generated row
+// within the whole table, existing rows included, so unique columns can
+// embed it to stay distinct.
+func generateValue(r *rand.Rand, c *column, seq int64) any {
+	b := c.bounds
+	if len(b.in) > 0 {
+		v := b.in[r.IntN(len(b.in))]
+		if f, ok := v.(float64); ok {
+			switch c.typ {
+			case "INT64":
+				return int64(f)
+			case "DECIMAL":
+				return decimalFromFloat(f)
+			case "TEXT":
+				return fmt.Sprint(f)
+			}
+		}
+		return v
+	}
+	name := strings.ToLower(c.info.Name)
+	switch c.typ {
+	case "INT64":
+		lo, hi := intRange(name, b)
+		if c.unique && lo+seq-1 <= hi {
+			return lo + seq - 1
+		}
+		return lo + r.Int64N(hi-lo+1)
+	case "FLOAT64":
+		lo, hi := floatRange(name, b)
+		if c.unique {
+			return lo + float64(seq-1) + r.Float64()*0.5
+		}
+		return math.Round((lo+r.Float64()*(hi-lo))*1e4) / 1e4
+	case "DECIMAL":
+		lo, hi := floatRange(name, b)
+		if c.unique {
+			return decimalFromFloat(lo + float64(seq-1) + float64(r.IntN(100))/100)
+		}
+		return decimalFromFloat(lo + r.Float64()*(hi-lo))
+	case "BOOL":
+		return r.IntN(2) == 0
+	case "BLOB":
+		buf := make([]byte, 16)
+		for i := range buf {
+			buf[i] = byte(r.UintN(256))
+		}
+		return buf
+	case "UUID":
+		var u decentdb.UUID
+		for i := range u {
+			u[i] = byte(r.UintN(256))
+		}
+		u[6] = u[6]&0x0f | 0x40
+		u[8] = u[8]&0x3f | 0x80
+		return u
+	case "TIMESTAMP":
+		return timestamp(r, seq, c.unique)
+	case "TIMESTAMPTZ":
+		return timestamp(r, seq, c.unique).Format(time.RFC3339)
+	case "DATE":
+		if c.unique {
+			return timeRangeStart.AddDate(0, 0, int(seq-1)).Format(time.DateOnly)
+		}
+		return timestamp(r, seq, false).Format(time.DateOnly)
+	case "TIME":
+		return fmt.Sprintf("%02d:%02d:%02d", r.IntN(24), r.IntN(60), r.IntN(60))
+	case "IPADDR":
+		if c.unique {
+			return fmt.Sprintf("10.%d.%d.%d", seq>>16&0xff, seq>>8&0xff, seq&0xff)
+		}
+		return fmt.Sprintf("10.%d.%d.%d", r.IntN(256), r.IntN(256), 1+r.IntN(254))
+	case "CIDR":
+		return fmt.Sprintf("10.%d.%d.0/24", seq>>8&0xff, seq&0xff)
+	case "MACADDR":
+		return fmt.Sprintf("02:00:%02x:%02x:%02x:%02x", seq>>24&0xff, seq>>16&0xff, seq>>8&0xff, seq&0xff)
+	}
+	return fitText(r, textValue(r, name, seq, c.unique), b)
+}
+
+func timestamp(r *rand.Rand, seq int64, unique bool) time.Time {
+	span := timeRangeEnd.Sub(timeRangeStart)
+	if unique {
+		return timeRangeStart.Add(time.Duration(seq) * time.Minute)
+	}
+	return timeRangeStart.Add(time.Duration(r.Int64N(int64(span/time.Second))) * time.Second)
+}
+
+func decimalFromFloat(f float64) decentdb.Decimal {
+	return decentdb.Decimal{Unscaled: int64(math.Round(f * 100)), Scale: 2}
+}
+
+// intRange picks a plausible range for an integer column from its name,
+// narrowed to its CHECK bounds.
+func intRange(name string, b bounds) (int64, int64) {
+	lo, hi := int64(1), int64(10_000)
+	switch {
+	case hasWord(name, "age"):
+		lo, hi = 18, 90
+	case hasWord(name, "year"):
+		lo, hi = 1970, 2025
+	case hasWord(name, "rating", "stars", "score", "priority", "level"):
+		lo, hi = 1, 5
+	case hasWord(name, "quantity", "qty", "count", "stock"):
+		lo, hi = 1, 100
+	case hasWord(name, "percent", "pct"):
+		lo, hi = 0, 100
+	case hasWord(name, "price", "amount", "total", "cost", "balance", "salary"):
+		lo, hi = 1, 1000
+	}
+	if b.min != nil {
+		min := int64(math.Ceil(*b.min))
+		if b.strictMin && float64(min) == *b.min {
+			min++
+		}
+		lo = min
+		if hi < lo {
+			hi = lo + 10_000
+		}
+	}
+	if b.max != nil {
+		max := int64(math.Floor(*b.max))
+		if b.strictMax && float64(max) == *b.max {
+			max--
+		}
+		hi = max
+		if lo > hi {
+			lo = hi - 10_000
+			if b.min != nil {
+				lo = hi
+			}
+		}
+	}
+	return lo, hi
+}
+
+// floatRange is intRange for FLOAT64 and DECIMAL columns.
+func floatRange(name string, b bounds) (float64, float64) {
+	lo, hi := 0.0, 1000.0
+	switch {
+	case hasWord(name, "lat", "latitude"):
+		lo, hi = -90, 90
+	case hasWord(name, "lon", "lng", "longitude"):
+		lo, hi = -180, 180
+	case hasWord(name, "rating", "score"):
+		lo, hi = 1, 5
+	case hasWord(name, "percent", "pct", "ratio", "rate"):
+		lo, hi = 0, 100
+	case hasWord(name, "price", "amount", "total", "cost", "balance", "salary"):
+		lo, hi = 1, 1000
+	}
+	const step = 0.01
+	if b.min != nil {
+		lo = *b.min
+		if b.strictMin {
+			lo += step
+		}
+		if hi < lo {
+			hi = lo + 1000
+		}
+	}
+	if b.max != nil {
+		hi = *b.max
+		if b.strictMax {
+			hi -= step
+		}
+		if lo > hi {
+			lo = hi - 1000
+			if b.min != nil {
+				lo = hi
+			}
+		}
+	}
+	return lo, hi
+}
+
+// textValue picks realistic text for a column from its name. Unique
+// columns embed seq so values never repeat.
+func textValue(r *rand.Rand, name string, seq int64, unique bool) string {
+	first, last := pick(r, firstNames), pick(r, lastNames)
+	suffix := ""
+	if unique {
+		suffix = fmt.Sprint(seq)
+	}
+	switch {
+	case hasWord(name, "email", "mail"):
+		local := strings.ToLower(first + "." + strings.ReplaceAll(last, " ", ""))
+		return local + suffix + "@" + pick(r, domains)
+	case hasWord(name, "username", "login", "handle", "user"):
+		return strings.ToLower(first[:1]+strings.ReplaceAll(last, " ", "")) + suffix
+	case hasWord(name, "first_name", "firstname", "given_name"):
+		return join(first, suffix)
+	case hasWord(name, "last_name", "lastname", "surname", "family_name"):
+		return join(last, suffix)
+	case hasWord(name, "company", "organization", "org", "employer", "vendor", "supplier"):
+		return join(pick(r, companyWords)+" "+pick(r, companySuffixes), suffix)
+	case hasWord(name, "city", "town"):
+		return join(pick(r, cities), suffix)
+	case hasWord(name, "country"):
+		return join(pick(r, countries), suffix)
+	case hasWord(name, "address", "street"):
+		return join(fmt.Sprintf("%d %s", 1+r.IntN(9999), pick(r, streets)), suffix)
+	case hasWord(name, "zip", "postcode", "postal_code"):
+		if unique {
+			return fmt.Sprintf("%05d", seq%100_000)
+		}
+		return fmt.Sprintf("%05d", r.IntN(100_000))
+	case hasWord(name, "phone", "mobile", "fax"):
+		if unique {
+			return fmt.Sprintf("+1-555-%07d", seq%10_000_000)
+		}
+		return fmt.Sprintf("+1-555-%03d-%04d", r.IntN(1000), r.IntN(10_000))
+	case hasWord(name, "url", "website", "homepage", "link"):
+		return fmt.Sprintf("https://%s/%s%s", pick(r, domains), pick(r, words), suffix)
+	case hasWord(name, "status", "state"):
+		return join(pick(r, statuses), suffix)
+	case hasWord(name, "color", "colour"):
+		return join(pick(r, colors), suffix)
+	case hasWord(name, "sku", "code", "ref", "reference", "slug", "token", "key"):
+		if unique {
+			return fmt.Sprintf("%c%c-%06d", 'A'+r.IntN(26), 'A'+r.IntN(26), seq)
+		}
+		return fmt.Sprintf("%c%c-%06d", 'A'+r.IntN(26), 'A'+r.IntN(26), r.IntN(1_000_000))
+	case hasWord(name, "title", "subject", "headline", "label"):
+		return join(sentence(r, 2, 5), suffix)
+	case hasWord(name, "description", "body", "comment", "comments", "notes", "note", "bio",
+		"summary", "content", "message", "text"):
+		return join(sentence(r, 8, 20)+".", suffix)
+	case hasWord(name, "name", "full_name", "fullname", "customer", "author", "owner", "contact"):
+		return join(first+" "+last, suffix)
+	}
+	return join(sentence(r, 1, 3), suffix)
+}
+
+func join(s, suffix string) string {
+	if suffix == "" {
+		return s
+	}
+	return s + " " + suffix
+}
+
+// fitText pads or trims text to the length limits of its checks, keeping
+// the end of the value, where a unique suffix lives.
+func fitText(r *rand.Rand, s string, b bounds) string {
+	for b.minLen > 0 && len(s) < b.minLen {
+		s += " " + pick(r, words)
+	}
+	if b.maxLen > 0 && len(s) > b.maxLen {
+		s = s[len(s)-b.maxLen:]
+	}
+	return s
+}
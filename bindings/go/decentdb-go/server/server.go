@@ -0,0 +1,617 @@
+// Package server exposes a database/sql handle over HTTP with JSON results.
+//
+// The routes follow the JSON API served by `decentdb serve`, so the same
+// clients work against either:
+//
+//	GET  /healthz
+//	POST /api/v1/query              {"sql": "...", "params": [...], "tx": "..."}
+//	POST /api/v1/exec               {"sql": "...", "params": [...], "tx": "..."}
+//	POST /api/v1/tx                 begins a transaction and returns {"tx": "..."}
+//	POST /api/v1/tx/{tx}/commit
+//	POST /api/v1/tx/{tx}/rollback
+//
+// Each request runs one statement. Requests that carry a "tx" token run
+// inside that transaction; idle transactions are rolled back after the
+// transaction timeout.
+//
+// With WithAuth, users of an auth.Store authenticate with HTTP basic
+// credentials or an API token, transactions belong to the user that began
+// them, and each statement is checked against the user's grants.
+//
+// To serve over TLS, run the Server in an http.Server whose TLSConfig comes
+// from the tlsconfig package.
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	decentdb "github.com/sphildreth/decentdb-go"
+	"github.com/sphildreth/decentdb-go/auth"
+	"github.com/sphildreth/decentdb-go/internal/authz"
+)
+
+const (
+	defaultMaxResultRows = 1000
+	defaultMaxBodySize   = 4 << 20
+	defaultTxTimeout     = 60 * time.Second
+)
+
+// Option configures a Server.
+type Option func(*Server)
+
+// WithBasicAuth requires HTTP basic credentials on /api/v1 routes.
+func WithBasicAuth(user, password string) Option {
+	return func(s *Server) {
+		s.basicUser, s.basicPassword = user, password
+	}
+}
+
+// WithBearerToken requires `Authorization: Bearer <token>` on /api/v1 routes.
+// When basic auth is also configured, either credential is accepted.
+func WithBearerToken(token string) Option {
+	return func(s *Server) {
+		s.bearerToken = token
+	}
+}
+
+// WithAuth authenticates /api/v1 requests against store, with a user's
+// password sent as HTTP basic credentials or an API token sent as
+// `Authorization: Bearer <token>`, and checks every statement against the
+// user's grants on database, the name this server's database has in the
+// store. It replaces WithBasicAuth and WithBearerToken.
+func WithAuth(store *auth.Store, database string) Option {
+	return func(s *Server) {
+		s.store, s.database = store, database
+	}
+}
+
+// WithReadOnly rejects exec requests and runs every query and transaction
+// read-only.
+func WithReadOnly() Option {
+	return func(s *Server) {
+		s.readOnly = true
+	}
+}
+
+// WithMaxResultRows caps the rows returned by one query; extra rows are
+// dropped and the response is marked truncated. The default is 1000.
+func WithMaxResultRows(n int) Option {
+	return func(s *Server) {
+		if n > 0 {
+			s.maxResultRows = n
+		}
+	}
+}
+
+// WithTxTimeout sets how long a transaction may sit idle before it is rolled
+// back. The default is 60 seconds.
+func WithTxTimeout(d time.Duration) Option {
+	return func(s *Server) {
+		if d > 0 {
+			s.txTimeout = d
+		}
+	}
+}
+
+// Server is an http.Handler serving the DecentDB JSON API over db.
+type Server struct {
+	db  *sql.DB
+	mux *http.ServeMux
+
+	basicUser     string
+	basicPassword string
+	bearerToken   string
+	store         *auth.Store
+	database      string
+	readOnly      bool
+	maxResultRows int
+	txTimeout     time.Duration
+
+	mu  sync.Mutex
+	txs map[string]*session
+}
+
+type session struct {
+	mu   sync.Mutex
+	conn *sql.Conn
+	tx   *sql.Tx
+	// user began the transaction; empty without an auth store.
+	user     string
+	lastUsed time.Time
+}
+
+// New returns a Server for db. The caller keeps ownership of db; call Close
+// to roll back transactions that are still open before closing it.
+func New(db *sql.DB, opts ...Option) *Server {
+	s := &Server{
+		db:            db,
+		mux:           http.NewServeMux(),
+		maxResultRows: defaultMaxResultRows,
+		txTimeout:     defaultTxTimeout,
+		txs:           make(map[string]*session),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+	})
+	s.mux.HandleFunc("POST /api/v1/query", s.handleQuery)
+	s.mux.HandleFunc("POST /api/v1/exec", s.handleExec)
+	s.mux.HandleFunc("POST /api/v1/tx", s.handleBegin)
+	s.mux.HandleFunc("POST /api/v1/tx/{tx}/commit", s.handleEnd(true))
+	s.mux.HandleFunc("POST /api/v1/tx/{tx}/rollback", s.handleEnd(false))
+	return s
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.store != nil && strings.HasPrefix(r.URL.Path, "/api/v1") {
+		p, err := s.authenticate(r)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Basic realm="decentdb"`)
+			code, message := "AUTH_INVALID", "invalid credentials"
+			if r.Header.Get("Authorization") == "" {
+				code, message = "AUTH_REQUIRED", "missing credentials"
+			}
+			writeError(w, http.StatusUnauthorized, code, message)
+			return
+		}
+		if !p.CanAccess(s.database) {
+			writeError(w, http.StatusForbidden, "PERMISSION_DENIED", fmt.Sprintf("permission denied for database %q", s.database))
+			return
+		}
+		r = r.WithContext(context.WithValue(r.Context(), principalKey{}, p))
+	} else if strings.HasPrefix(r.URL.Path, "/api/v1") && !s.authorized(r) {
+		if s.basicUser != "" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="decentdb"`)
+		}
+		code, message := "AUTH_INVALID", "invalid credentials"
+		if r.Header.Get("Authorization") == "" {
+			code, message = "AUTH_REQUIRED", "missing credentials"
+		}
+		writeError(w, http.StatusUnauthorized, code, message)
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// Close rolls back every open transaction.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	txs := s.txs
+	s.txs = make(map[string]*session)
+	s.mu.Unlock()
+
+	var errs []error
+	for _, sess := range txs {
+		sess.mu.Lock()
+		if err := sess.tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+			errs = append(errs, err)
+		}
+		errs = append(errs, sess.conn.Close())
+		sess.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+type principalKey struct{}
+
+// requestPrincipal returns the user a request authenticated as, nil
+// without an auth store.
+func requestPrincipal(r *http.Request) *auth.Principal {
+	p, _ := r.Context().Value(principalKey{}).(*auth.Principal)
+	return p
+}
+
+func (s *Server) authenticate(r *http.Request) (*auth.Principal, error) {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return s.store.AuthenticateToken(token)
+	}
+	if user, password, ok := r.BasicAuth(); ok {
+		return s.store.AuthenticatePassword(user, password)
+	}
+	return nil, auth.ErrInvalidCredentials
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	if s.basicUser == "" && s.bearerToken == "" {
+		return true
+	}
+	if s.basicUser != "" {
+		if user, password, ok := r.BasicAuth(); ok &&
+			subtle.ConstantTimeCompare([]byte(user), []byte(s.basicUser)) == 1 &&
+			subtle.ConstantTimeCompare([]byte(password), []byte(s.basicPassword)) == 1 {
+			return true
+		}
+	}
+	if s.bearerToken != "" {
+		want := "Bearer " + s.bearerToken
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte(want)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+type sqlRequest struct {
+	SQL    string            `json:"sql"`
+	Params []json.RawMessage `json:"params"`
+	Tx     string            `json:"tx"`
+}
+
+type column struct {
+	Name string `json:"name"`
+	Type string `json:"type,omitempty"`
+}
+
+type result struct {
+	Columns      []column `json:"columns"`
+	Rows         [][]any  `json:"rows"`
+	RowCount     int      `json:"rowCount"`
+	RowsAffected int64    `json:"rowsAffected"`
+	Truncated    bool     `json:"truncated"`
+	Limit        int      `json:"limit"`
+}
+
+// querier is the subset shared by *sql.DB and *sql.Tx.
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func (s *Server) handleQuery(w http.ResponseWriter, r *http.Request) {
+	s.runStatement(w, r, false)
+}
+
+func (s *Server) handleExec(w http.ResponseWriter, r *http.Request) {
+	if s.readOnly {
+		writeError(w, http.StatusBadRequest, "READ_ONLY", "read-only mode does not allow mutating SQL")
+		return
+	}
+	s.runStatement(w, r, true)
+}
+
+func (s *Server) runStatement(w http.ResponseWriter, r *http.Request, exec bool) {
+	req, args, ok := decodeSQLRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var q querier
+	var conn *sql.Conn
+	if req.Tx != "" {
+		sess := s.lookup(req.Tx, requestPrincipal(r))
+		if sess == nil {
+			writeError(w, http.StatusNotFound, "TX_NOT_FOUND", "transaction not found or expired")
+			return
+		}
+		sess.mu.Lock()
+		defer sess.mu.Unlock()
+		sess.lastUsed = time.Now()
+		q, conn = sess.tx, sess.conn
+	} else {
+		var err error
+		if conn, err = s.db.Conn(r.Context()); err != nil {
+			writeEngineError(w, err)
+			return
+		}
+		defer conn.Close()
+		q = conn
+		if s.readOnly {
+			tx, err := conn.BeginTx(r.Context(), &sql.TxOptions{ReadOnly: true})
+			if err != nil {
+				writeEngineError(w, err)
+				return
+			}
+			defer tx.Rollback()
+			q = tx
+		}
+	}
+	if err := authz.Check(r.Context(), conn, requestPrincipal(r), s.database, req.SQL); err != nil {
+		writeError(w, http.StatusForbidden, "PERMISSION_DENIED", err.Error())
+		return
+	}
+
+	started := time.Now()
+	var res result
+	var err error
+	if exec {
+		res, err = s.exec(r.Context(), q, req.SQL, args)
+	} else {
+		res, err = s.query(r.Context(), q, req.SQL, args)
+	}
+	if err != nil {
+		writeEngineError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"ok":        true,
+		"elapsedMs": float64(time.Since(started).Microseconds()) / 1000,
+		"results":   []result{res},
+		"truncated": res.Truncated,
+	})
+}
+
+func (s *Server) query(ctx context.Context, q querier, query string, args []any) (result, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return result{}, err
+	}
+	defer rows.Close()
+
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return result{}, err
+	}
+	res := result{Columns: make([]column, len(types)), Rows: [][]any{}, Limit: s.maxResultRows}
+	for i, ct := range types {
+		res.Columns[i] = column{Name: ct.Name(), Type: ct.DatabaseTypeName()}
+	}
+	for rows.Next() {
+		if len(res.Rows) == s.maxResultRows {
+			res.Truncated = true
+			break
+		}
+		values := make([]any, len(types))
+		ptrs := make([]any, len(types))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return result{}, err
+		}
+		for i, v := range values {
+			values[i] = jsonValue(v)
+		}
+		res.Rows = append(res.Rows, values)
+	}
+	if err := rows.Err(); err != nil {
+		return result{}, err
+	}
+	res.RowCount = len(res.Rows)
+	return res, nil
+}
+
+func (s *Server) exec(ctx context.Context, q querier, query string, args []any) (result, error) {
+	out, err := q.ExecContext(ctx, query, args...)
+	if err != nil {
+		return result{}, err
+	}
+	affected, err := out.RowsAffected()
+	if err != nil {
+		return result{}, err
+	}
+	return result{Columns: []column{}, Rows: [][]any{}, RowsAffected: affected, Limit: s.maxResultRows}, nil
+}
+
+func (s *Server) handleBegin(w http.ResponseWriter, r *http.Request) {
+	s.expire()
+	// The transaction outlives this request, so it must not be bound to the
+	// request context.
+	// The transaction outlives this request, so it must not be bound to the
+	// request context. It keeps its own connection so statements can be
+	// described for authorization while it is open.
+	conn, err := s.db.Conn(context.Background())
+	if err != nil {
+		writeEngineError(w, err)
+		return
+	}
+	tx, err := conn.BeginTx(context.Background(), &sql.TxOptions{ReadOnly: s.readOnly})
+	if err != nil {
+		conn.Close()
+		writeEngineError(w, err)
+		return
+	}
+	id, err := newTxID()
+	if err != nil {
+		_ = tx.Rollback()
+		conn.Close()
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		return
+	}
+	sess := &session{conn: conn, tx: tx, lastUsed: time.Now()}
+	if p := requestPrincipal(r); p != nil {
+		sess.user = p.User
+	}
+	s.mu.Lock()
+	s.txs[id] = sess
+	s.mu.Unlock()
+	writeJSON(w, http.StatusCreated, map[string]any{
+		"ok":        true,
+		"tx":        id,
+		"timeoutMs": s.txTimeout.Milliseconds(),
+	})
+}
+
+func (s *Server) handleEnd(commit bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.expire()
+		id := r.PathValue("tx")
+		s.mu.Lock()
+		sess := s.txs[id]
+		if sess != nil && !sess.ownedBy(requestPrincipal(r)) {
+			sess = nil
+		}
+		if sess != nil {
+			delete(s.txs, id)
+		}
+		s.mu.Unlock()
+		if sess == nil {
+			writeError(w, http.StatusNotFound, "TX_NOT_FOUND", "transaction not found or expired")
+			return
+		}
+		sess.mu.Lock()
+		defer sess.mu.Unlock()
+		var err error
+		if commit {
+			err = sess.tx.Commit()
+		} else {
+			err = sess.tx.Rollback()
+		}
+		sess.conn.Close()
+		if err != nil {
+			writeEngineError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+	}
+}
+
+// lookup finds a transaction. Transactions of other users are reported as
+// not found.
+func (s *Server) lookup(id string, p *auth.Principal) *session {
+	s.expire()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if sess := s.txs[id]; sess != nil && sess.ownedBy(p) {
+		return sess
+	}
+	return nil
+}
+
+func (sess *session) ownedBy(p *auth.Principal) bool {
+	if sess.user == "" {
+		return true
+	}
+	return p != nil && p.User == sess.user
+}
+
+// expire rolls back transactions idle for longer than the transaction
+// timeout. Sessions busy with a request are left alone.
+func (s *Server) expire() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, sess := range s.txs {
+		if !sess.mu.TryLock() {
+			continue
+		}
+		if time.Since(sess.lastUsed) > s.txTimeout {
+			_ = sess.tx.Rollback()
+			sess.conn.Close()
+			delete(s.txs, id)
+		}
+		sess.mu.Unlock()
+	}
+}
+
+func newTxID() (string, error) {
+	var buf [16]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		return "", err
+	}
+	return "tx-" + hex.EncodeToString(buf[:]), nil
+}
+
+func decodeSQLRequest(w http.ResponseWriter, r *http.Request) (sqlRequest, []any, bool) {
+	var req sqlRequest
+	body := http.MaxBytesReader(w, r.Body, defaultMaxBodySize)
+	if err := json.NewDecoder(body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid JSON body: "+err.Error())
+		return req, nil, false
+	}
+	if req.SQL == "" {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "sql is required")
+		return req, nil, false
+	}
+	args := make([]any, len(req.Params))
+	for i, raw := range req.Params {
+		v, err := paramValue(raw)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "INVALID_REQUEST", fmt.Sprintf("params[%d]: %v", i, err))
+			return req, nil, false
+		}
+		args[i] = v
+	}
+	return req, args, true
+}
+
+// paramValue converts a JSON parameter to a bind value: integers bind as
+// int64, other numbers as float64, and arrays or objects as their JSON text.
+func paramValue(raw json.RawMessage) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	switch t := v.(type) {
+	case json.Number:
+		if n, err := t.Int64(); err == nil {
+			return n, nil
+		}
+		return t.Float64()
+	case map[string]any, []any:
+		return string(raw), nil
+	default:
+		return t, nil
+	}
+}
+
+// jsonValue renders a scanned value the way `decentdb serve` does: blobs as
+// 0x-prefixed hex and rich types as their text form.
+func jsonValue(v any) any {
+	switch t := v.(type) {
+	case nil, bool, int64, float64, string:
+		return t
+	case []byte:
+		return "0x" + hex.EncodeToString(t)
+	case time.Time:
+		return t.Format(time.RFC3339Nano)
+	case decentdb.Decimal:
+		return formatDecimal(t)
+	case fmt.Stringer:
+		return t.String()
+	default:
+		return fmt.Sprint(t)
+	}
+}
+
+func formatDecimal(d decentdb.Decimal) string {
+	if d.Scale <= 0 {
+		return fmt.Sprint(d.Unscaled)
+	}
+	sign, digits := "", fmt.Sprint(d.Unscaled)
+	if d.Unscaled < 0 {
+		sign, digits = "-", digits[1:]
+	}
+	for len(digits) <= d.Scale {
+		digits = "0" + digits
+	}
+	cut := len(digits) - d.Scale
+	return sign + digits[:cut] + "." + digits[cut:]
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, map[string]any{"error": map[string]any{"code": code, "message": message}})
+}
+
+func writeEngineError(w http.ResponseWriter, err error) {
+	detail := map[string]any{"code": "INVALID_REQUEST", "message": err.Error()}
+	var dbErr *decentdb.DecentDBError
+	if errors.As(err, &dbErr) {
+		detail["native_code"] = dbErr.Code
+		if dbErr.Subcode != "" {
+			detail["subcode"] = dbErr.Subcode
+		}
+	}
+	writeJSON(w, http.StatusBadRequest, map[string]any{"error": detail})
+}
@@ -0,0 +1,237 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	decentdb "github.com/sphildreth/decentdb-go"
+	"github.com/sphildreth/decentdb-go/auth"
+)
+
+func TestFormatDecimal(t *testing.T) {
+	cases := map[decentdb.Decimal]string{
+		{Unscaled: 12345, Scale: 2}: "123.45",
+		{Unscaled: -5, Scale: 3}:    "-0.005",
+		{Unscaled: 42}:              "42",
+	}
+	for d, want := range cases {
+		if got := formatDecimal(d); got != want {
+			t.Fatalf("formatDecimal(%+v) = %q, want %q", d, got, want)
+		}
+	}
+}
+
+func TestParamValue(t *testing.T) {
+	cases := map[string]any{
+		`7`:        int64(7),
+		`1.5`:      1.5,
+		`"x"`:      "x",
+		`true`:     true,
+		`null`:     nil,
+		`{"a": 1}`: `{"a": 1}`,
+	}
+	for raw, want := range cases {
+		got, err := paramValue(json.RawMessage(raw))
+		if err != nil {
+			t.Fatalf("paramValue(%s): %v", raw, err)
+		}
+		if got != want {
+			t.Fatalf("paramValue(%s) = %#v, want %#v", raw, got, want)
+		}
+	}
+}
+
+type response struct {
+	OK      bool     `json:"ok"`
+	Tx      string   `json:"tx"`
+	Results []result `json:"results"`
+	Error   *struct {
+		Code string `json:"code"`
+	} `json:"error"`
+}
+
+func post(t *testing.T, srv *httptest.Server, path, body string, auth bool) (int, response) {
+	t.Helper()
+	authorization := ""
+	if auth {
+		authorization = "Basic " + base64.StdEncoding.EncodeToString([]byte("admin:s3cret"))
+	}
+	return postAuthorized(t, srv, path, body, authorization)
+}
+
+func postAuthorized(t *testing.T, srv *httptest.Server, path, body, authorization string) (int, response) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, srv.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var out response
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, out
+}
+
+func TestServer_QueryExecTransactionsAndAuth(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "decentdb-test-server-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	db, err := sql.Open("decentdb", fmt.Sprintf("file:%s", filepath.Join(tmpDir, "server.ddb")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("CREATE TABLE users (id INT64 PRIMARY KEY, name TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := New(db, WithBasicAuth("admin", "s3cret"))
+	defer handler.Close()
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	if status, out := post(t, srv, "/api/v1/query", `{"sql": "SELECT 1"}`, false); status != http.StatusUnauthorized || out.Error.Code != "AUTH_REQUIRED" {
+		t.Fatalf("unauthenticated query: status %d, %+v", status, out)
+	}
+
+	status, out := post(t, srv, "/api/v1/exec", `{"sql": "INSERT INTO users VALUES ($1, $2)", "params": [1, "Ada"]}`, true)
+	if status != http.StatusOK || out.Results[0].RowsAffected != 1 {
+		t.Fatalf("exec: status %d, %+v", status, out)
+	}
+
+	status, out = post(t, srv, "/api/v1/tx", ``, true)
+	if status != http.StatusCreated || out.Tx == "" {
+		t.Fatalf("begin: status %d, %+v", status, out)
+	}
+	tx := out.Tx
+	body := fmt.Sprintf(`{"sql": "INSERT INTO users VALUES ($1, $2)", "params": [2, "Grace"], "tx": %q}`, tx)
+	if status, out := post(t, srv, "/api/v1/exec", body, true); status != http.StatusOK {
+		t.Fatalf("exec in tx: status %d, %+v", status, out)
+	}
+	if status, out := post(t, srv, "/api/v1/tx/"+tx+"/rollback", ``, true); status != http.StatusOK {
+		t.Fatalf("rollback: status %d, %+v", status, out)
+	}
+	if status, out := post(t, srv, "/api/v1/tx/"+tx+"/commit", ``, true); status != http.StatusNotFound || out.Error.Code != "TX_NOT_FOUND" {
+		t.Fatalf("commit after rollback: status %d, %+v", status, out)
+	}
+
+	status, out = post(t, srv, "/api/v1/query", `{"sql": "SELECT id, name FROM users ORDER BY id"}`, true)
+	if status != http.StatusOK {
+		t.Fatalf("query: status %d, %+v", status, out)
+	}
+	rows := out.Results[0].Rows
+	if len(rows) != 1 || rows[0][1] != "Ada" {
+		t.Fatalf("rows = %v, want only Ada", rows)
+	}
+	if out.Results[0].Columns[0].Name != "id" {
+		t.Fatalf("columns = %+v", out.Results[0].Columns)
+	}
+}
+
+func TestServer_ReadOnlyRejectsExec(t *testing.T) {
+	db, err := sql.Open("decentdb", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	srv := httptest.NewServer(New(db, WithReadOnly()))
+	defer srv.Close()
+
+	status, out := post(t, srv, "/api/v1/exec", `{"sql": "CREATE TABLE t (id INT64)"}`, false)
+	if status != http.StatusBadRequest || out.Error.Code != "READ_ONLY" {
+		t.Fatalf("exec: status %d, %+v", status, out)
+	}
+}
+
+func TestServer_AuthStoreGrants(t *testing.T) {
+	db, err := sql.Open("decentdb", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec("CREATE TABLE notes (id INT64 PRIMARY KEY, body TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("CREATE TABLE salaries (id INT64 PRIMARY KEY)"); err != nil {
+		t.Fatal(err)
+	}
+
+	store := auth.NewStore()
+	for _, err := range []error{
+		store.CreateUser("ada", "pw"),
+		store.CreateUser("grace", "pw"),
+		store.CreateRole("editor"),
+		store.Grant("editor", auth.Grant{Database: "app", Table: "notes", Privileges: []auth.Privilege{auth.Select, auth.Insert}}),
+		store.GrantRole("ada", "editor"),
+		store.GrantRole("grace", "editor"),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, token, err := store.CreateToken("ada", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := New(db, WithAuth(store, "app"))
+	defer handler.Close()
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+	ada := "Bearer " + token
+	grace := "Basic " + base64.StdEncoding.EncodeToString([]byte("grace:pw"))
+
+	if status, out := postAuthorized(t, srv, "/api/v1/query", `{"sql": "SELECT 1"}`, "Basic "+base64.StdEncoding.EncodeToString([]byte("grace:nope"))); status != http.StatusUnauthorized || out.Error.Code != "AUTH_INVALID" {
+		t.Fatalf("wrong password: status %d, %+v", status, out)
+	}
+	if status, out := postAuthorized(t, srv, "/api/v1/exec", `{"sql": "INSERT INTO notes VALUES (1, 'hi')"}`, ada); status != http.StatusOK {
+		t.Fatalf("granted insert: status %d, %+v", status, out)
+	}
+	for _, body := range []string{
+		`{"sql": "SELECT id FROM salaries"}`,
+		`{"sql": "DELETE FROM notes"}`,
+		`{"sql": "DROP TABLE notes"}`,
+	} {
+		if status, out := postAuthorized(t, srv, "/api/v1/exec", body, ada); status != http.StatusForbidden || out.Error.Code != "PERMISSION_DENIED" {
+			t.Fatalf("%s: status %d, %+v", body, status, out)
+		}
+	}
+
+	// Transactions belong to the user that began them, and statements in
+	// them are authorized on the transaction's own connection.
+	status, out := postAuthorized(t, srv, "/api/v1/tx", ``, ada)
+	if status != http.StatusCreated {
+		t.Fatalf("begin: status %d, %+v", status, out)
+	}
+	tx := out.Tx
+	body := fmt.Sprintf(`{"sql": "SELECT body FROM notes", "tx": %q}`, tx)
+	if status, out := postAuthorized(t, srv, "/api/v1/query", body, ada); status != http.StatusOK || out.Results[0].Rows[0][0] != "hi" {
+		t.Fatalf("query in tx: status %d, %+v", status, out)
+	}
+	if status, _ := postAuthorized(t, srv, "/api/v1/query", body, grace); status != http.StatusNotFound {
+		t.Fatalf("another user's tx: status %d", status)
+	}
+	if status, out := postAuthorized(t, srv, "/api/v1/tx/"+tx+"/commit", ``, ada); status != http.StatusOK {
+		t.Fatalf("commit: status %d, %+v", status, out)
+	}
+}
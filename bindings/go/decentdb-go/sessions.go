@@ -0,0 +1,175 @@
+package decentdb
+
+/*
+#include "decentdb.h"
+*/
+import "C"
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrSessionNotFound is returned by CancelSession for an id that names no
+// open connection to the database.
+var ErrSessionNotFound = errors.New("decentdb: session not found")
+
+// Session states reported by ListSessions.
+const (
+	SessionIdle              = "idle"
+	SessionActive            = "active"
+	SessionIdleInTransaction = "idle in transaction"
+)
+
+// SessionInfo describes one open connection to a database.
+type SessionInfo struct {
+	// ID identifies the connection for CancelSession. It is unique within
+	// the process and never reused.
+	ID    uint64
+	State string
+	// Fingerprint is the normalized text of the running statement, as
+	// returned by NormalizeQuery; StartedAt is when it started. Both are
+	// zero unless State is SessionActive.
+	Fingerprint string
+	StartedAt   time.Time
+	// QueryTag is the WithQueryTag tag of the running statement, if any.
+	QueryTag string
+	// ApplicationName is the connection's application_name, if any.
+	ApplicationName string
+}
+
+// sessions tracks every open connection in the process so ListSessions and
+// CancelSession can reach connections owned by other pools.
+var sessions = struct {
+	sync.Mutex
+	next  uint64
+	conns map[uint64]*conn
+}{conns: make(map[uint64]*conn)}
+
+// sessionActivity is what a connection is doing, for ListSessions.
+type sessionActivity struct {
+	mu        sync.Mutex
+	id        uint64
+	query     string
+	tag       string
+	startedAt time.Time
+	active    bool
+	inTx      bool
+}
+
+// registerSession makes c visible to ListSessions and CancelSession.
+func registerSession(c *conn) {
+	sessions.Lock()
+	defer sessions.Unlock()
+	sessions.next++
+	c.activity.id = sessions.next
+	sessions.conns[c.activity.id] = c
+}
+
+// unregisterSession removes c before its handle is freed, so CancelSession
+// never interrupts a freed handle.
+func unregisterSession(c *conn) {
+	sessions.Lock()
+	defer sessions.Unlock()
+	if c.activity.id != 0 && sessions.conns[c.activity.id] == c {
+		delete(sessions.conns, c.activity.id)
+	}
+}
+
+// beginActivity records that c started running query.
+func (c *conn) beginActivity(ctx context.Context, query string) {
+	tag, _ := QueryTagFromContext(ctx)
+	c.activity.mu.Lock()
+	c.activity.query = query
+	c.activity.tag = tag
+	c.activity.startedAt = time.Now()
+	c.activity.active = true
+	c.activity.mu.Unlock()
+}
+
+// endActivity records that c finished its statement.
+func (c *conn) endActivity() {
+	c.activity.mu.Lock()
+	c.activity.query = ""
+	c.activity.tag = ""
+	c.activity.startedAt = time.Time{}
+	c.activity.active = false
+	c.activity.mu.Unlock()
+}
+
+// setInTransaction records whether c has an open transaction.
+func (c *conn) setInTransaction(inTx bool) {
+	c.activity.mu.Lock()
+	c.activity.inTx = inTx
+	c.activity.mu.Unlock()
+}
+
+func (c *conn) sessionInfo() SessionInfo {
+	c.activity.mu.Lock()
+	info := SessionInfo{ID: c.activity.id, State: SessionIdle, ApplicationName: c.applicationName}
+	query := c.activity.query
+	switch {
+	case c.activity.active:
+		info.State = SessionActive
+		info.StartedAt = c.activity.startedAt
+		info.QueryTag = c.activity.tag
+	case c.activity.inTx:
+		info.State = SessionIdleInTransaction
+	}
+	c.activity.mu.Unlock()
+	if info.State == SessionActive {
+		if fingerprint, err := NormalizeQuery(query); err == nil {
+			info.Fingerprint = fingerprint
+		} else {
+			info.Fingerprint = query
+		}
+	}
+	return info
+}
+
+// ListSessions returns the open connections to this database in the
+// current process, whether opened with OpenDirect or through database/sql,
+// ordered by ID. Use it with CancelSession to find and stop a stuck query:
+//
+//	for _, s := range sessions {
+//		if s.State == decentdb.SessionActive && time.Since(s.StartedAt) > time.Minute {
+//			_ = db.CancelSession(s.ID)
+//		}
+//	}
+func (d *DB) ListSessions() []SessionInfo {
+	sessions.Lock()
+	conns := make([]*conn, 0, len(sessions.conns))
+	for _, c := range sessions.conns {
+		if c.path == d.path {
+			conns = append(conns, c)
+		}
+	}
+	sessions.Unlock()
+	infos := make([]SessionInfo, len(conns))
+	for i, c := range conns {
+		infos[i] = c.sessionInfo()
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].ID < infos[j].ID })
+	return infos
+}
+
+// CancelSession interrupts the statement running on the connection with the
+// given ID, which fails with ErrCanceled. The connection stays open, and an
+// open transaction is left for its owner to roll back. Cancelling an idle
+// session does nothing. It returns ErrSessionNotFound when no open
+// connection to this database has that ID.
+func (d *DB) CancelSession(id uint64) error {
+	sessions.Lock()
+	defer sessions.Unlock()
+	c, ok := sessions.conns[id]
+	if !ok || c.path != d.path || c.db == nil {
+		return ErrSessionNotFound
+	}
+	if status := C.ddb_db_interrupt(c.db); status != C.DDB_OK {
+		return statusError(status, "")
+	}
+	return nil
+}
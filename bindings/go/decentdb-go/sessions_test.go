@@ -0,0 +1,76 @@
+package decentdb
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestListSessionsAndCancelSession(t *testing.T) {
+	db, err := OpenDirect(filepath.Join(t.TempDir(), "sessions.ddb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, stmt := range []string{
+		"CREATE TABLE t (id INTEGER PRIMARY KEY)",
+		"INSERT INTO t SELECT value FROM generate_series(1, 3000)",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+
+	listed := db.ListSessions()
+	if len(listed) != 1 || listed[0].State != SessionIdle || listed[0].ID == 0 {
+		t.Fatalf("ListSessions = %+v, want one idle session", listed)
+	}
+	id := listed[0].ID
+	if err := db.CancelSession(id); err != nil {
+		t.Fatalf("cancel idle session: %v", err)
+	}
+	if _, err := db.Exec("SELECT COUNT(*) FROM t"); err != nil {
+		t.Fatalf("statement after cancelling an idle session: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := db.Exec("SELECT COUNT(*) FROM t a JOIN t b ON a.id + b.id < 0")
+		done <- err
+	}()
+	var sawActive bool
+	deadline := time.After(30 * time.Second)
+	for {
+		select {
+		case err := <-done:
+			if !sawActive {
+				t.Fatal("the statement was never listed as active")
+			}
+			if !errors.Is(err, ErrCanceled) {
+				t.Fatalf("cancelled statement returned %v, want ErrCanceled", err)
+			}
+			if got := db.ListSessions()[0].State; got != SessionIdle {
+				t.Fatalf("state after cancel = %q, want idle", got)
+			}
+			if err := db.CancelSession(id + 1000); !errors.Is(err, ErrSessionNotFound) {
+				t.Fatalf("cancel unknown session: %v", err)
+			}
+			return
+		case <-deadline:
+			t.Fatal("statement was not cancelled")
+		case <-time.After(10 * time.Millisecond):
+		}
+		info := db.ListSessions()[0]
+		if info.State != SessionActive {
+			continue
+		}
+		if info.Fingerprint == "" || info.StartedAt.IsZero() {
+			t.Fatalf("active session = %+v, want a fingerprint and start time", info)
+		}
+		sawActive = true
+		if err := db.CancelSession(id); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
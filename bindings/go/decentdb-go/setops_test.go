@@ -0,0 +1,187 @@
+package decentdb
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+)
+
+func querySetOp(t *testing.T, db *sql.DB, query string) [][]any {
+	t.Helper()
+	rows, err := db.Query(query)
+	if err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+	defer rows.Close()
+	names, err := rows.Columns()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out [][]any
+	for rows.Next() {
+		values := make([]any, len(names))
+		dest := make([]any, len(names))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, values)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestSetOps_UnifyNumericColumns(t *testing.T) {
+	db := openTestDB(t, "")
+	for _, stmt := range []string{
+		"CREATE TABLE retail (sku TEXT, price DECIMAL(10,2), qty INT64, weight FLOAT64)",
+		"CREATE TABLE wholesale (sku TEXT, price DECIMAL(12,4), qty INT64, weight FLOAT64)",
+		"INSERT INTO retail VALUES ('a', 1.50, 1, 0.5), ('b', 2.00, 2, NULL), ('c', NULL, NULL, NULL)",
+		"INSERT INTO wholesale VALUES ('a', 1.5, 1, 1), ('d', 3.1250, 4, 2.5), ('c', NULL, NULL, NULL)",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+
+	dec := func(unscaled int64) any { return Decimal{Unscaled: unscaled, Scale: 4} }
+	cases := []struct {
+		name, query string
+		want        [][]any
+	}{
+		{
+			"decimal scales",
+			"SELECT price FROM retail UNION SELECT price FROM wholesale ORDER BY price",
+			[][]any{{nil}, {dec(15000)}, {dec(20000)}, {dec(31250)}},
+		},
+		{
+			"integers join decimals",
+			"SELECT qty FROM retail UNION ALL SELECT price FROM wholesale ORDER BY qty",
+			[][]any{{nil}, {nil}, {dec(10000)}, {dec(15000)}, {dec(20000)}, {dec(31250)}},
+		},
+		{
+			"floats absorb integers",
+			"SELECT qty FROM wholesale UNION SELECT weight FROM wholesale ORDER BY qty",
+			[][]any{{nil}, {1.0}, {2.5}, {4.0}},
+		},
+		{
+			"intersect matches by value",
+			"SELECT sku, price FROM retail INTERSECT SELECT sku, price FROM wholesale ORDER BY sku",
+			[][]any{{"a", dec(15000)}, {"c", nil}},
+		},
+		{
+			"except matches by value",
+			"SELECT sku, price FROM retail EXCEPT SELECT sku, price FROM wholesale",
+			[][]any{{"b", dec(20000)}},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := querySetOp(t, db, tc.query); !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("got  %#v\nwant %#v", got, tc.want)
+			}
+		})
+	}
+
+	// Every non-NULL row scans as a DECIMAL at the widest scale.
+	for _, row := range querySetOp(t, db, "SELECT price FROM retail UNION ALL SELECT price FROM wholesale") {
+		if price, ok := row[0].(Decimal); row[0] != nil && (!ok || price.Scale != 4) {
+			t.Fatalf("price %#v was not rescaled", row[0])
+		}
+	}
+}
+
+func TestSetOps_DuplicatesAndNulls(t *testing.T) {
+	db := openTestDB(t, "")
+	if _, err := db.Exec("CREATE TABLE tags (id INT64 PRIMARY KEY, name TEXT, payload BLOB)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("INSERT INTO tags VALUES (1, 'red', $1), (2, 'red', $1), (3, NULL, NULL), (4, NULL, NULL), (5, 'blue', $2)",
+		[]byte{0x01}, []byte{0x02, 0x00}); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		query string
+		want  [][]any
+	}{
+		{"SELECT name FROM tags UNION SELECT name FROM tags ORDER BY name", [][]any{{nil}, {"blue"}, {"red"}}},
+		{"SELECT payload FROM tags UNION SELECT payload FROM tags ORDER BY payload",
+			[][]any{{nil}, {[]byte{0x01}}, {[]byte{0x02, 0x00}}}},
+		{"SELECT name FROM tags INTERSECT ALL SELECT name FROM tags WHERE id IN (1, 3, 5) ORDER BY name",
+			[][]any{{nil}, {"blue"}, {"red"}}},
+		{"SELECT name FROM tags EXCEPT ALL SELECT name FROM tags WHERE id IN (1, 3) ORDER BY name",
+			[][]any{{nil}, {"blue"}, {"red"}}},
+		{"SELECT name FROM tags EXCEPT SELECT NULL ORDER BY name", [][]any{{"blue"}, {"red"}}},
+	}
+	for _, tc := range cases {
+		if got := querySetOp(t, db, tc.query); !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("%s:\ngot  %#v\nwant %#v", tc.query, got, tc.want)
+		}
+	}
+
+	if _, err := db.Query("SELECT name FROM tags UNION SELECT payload FROM tags"); err == nil {
+		t.Fatal("TEXT and BLOB column accepted in one set-operation column")
+	}
+}
+
+// A set operation over one column must return every value kind exactly as
+// the driver returns the column itself.
+func TestSetOps_PreserveEveryValueKind(t *testing.T) {
+	db := openTestDB(t, "")
+	columns := []struct{ name, typ, value string }{
+		{"i", "INT64", "-2"},
+		{"f", "FLOAT64", "1.5"},
+		{"b", "BOOL", "TRUE"},
+		{"s", "TEXT", "'ada'"},
+		{"bl", "BLOB", "$1"},
+		{"d", "DECIMAL(10,2)", "12.34"},
+		{"u", "UUID", "'0190a5e6-1c2b-7d3e-8f40-123456789abc'"},
+		{"ts", "TIMESTAMP", "'2026-02-24 17:30:00'"},
+		{"tz", "TIMESTAMPTZ", "'2026-05-18T09:10:11.123456-05:00'"},
+		{"dt", "DATE", "'2026-05-18'"},
+		{"tm", "TIME", "'09:30:00.123456'"},
+		{"iv", "INTERVAL", "'1 year 2 months 3 days'"},
+		{"ip", "INET", "'2001:db8::1'"},
+		{"net", "CIDR", "'192.168.10.0/24'"},
+		{"mac", "MACADDR", "'08:00:2b:01:02:03'"},
+		{"e", "ENUM('low', 'high')", "'high'"},
+		{"g", "GEOMETRY", "ST_GeomFromText('POINT(1 2)')"},
+	}
+	create := "CREATE TABLE kinds (id INT64 PRIMARY KEY"
+	insert := "INSERT INTO kinds VALUES (1"
+	for _, c := range columns {
+		create += ", " + c.name + " " + c.typ
+		insert += ", " + c.value
+	}
+	if _, err := db.Exec(create + ")"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(insert+")", []byte{0x00, 0xff}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("INSERT INTO kinds (id) VALUES (2)"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range columns {
+		t.Run(c.typ, func(t *testing.T) {
+			want := querySetOp(t, db, "SELECT id, "+c.name+" FROM kinds ORDER BY id")
+			from := " FROM kinds"
+			for _, query := range []string{
+				"SELECT id, " + c.name + from + " UNION SELECT id, " + c.name + from + " ORDER BY id",
+				"SELECT id, " + c.name + from + " WHERE id = 1 UNION ALL SELECT id, " + c.name + from + " WHERE id = 2 ORDER BY id",
+				"SELECT id, " + c.name + from + " INTERSECT SELECT id, " + c.name + from + " ORDER BY id",
+				"SELECT id, " + c.name + from + " EXCEPT SELECT id, NULL" + from + " WHERE id = 3 ORDER BY id",
+			} {
+				if got := querySetOp(t, db, query); !reflect.DeepEqual(got, want) {
+					t.Fatalf("%s:\ngot  %#v\nwant %#v", query, got, want)
+				}
+			}
+		})
+	}
+}
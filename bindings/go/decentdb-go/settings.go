@@ -0,0 +1,117 @@
+package decentdb
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strconv"
+	"sync/atomic"
+)
+
+// GetSetting returns the value of the engine setting name as PRAGMA name
+// reports it, for example GetSetting("cache_size").
+func (c *conn) GetSetting(name string) (string, error) {
+	if c.db == nil {
+		return "", driver.ErrBadConn
+	}
+	if !isSettingName(name) {
+		return "", fmt.Errorf("decentdb: invalid setting name %q", name)
+	}
+	rows, err := c.QueryContext(context.Background(), "PRAGMA "+name, nil)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+	if len(rows.Columns()) != 1 {
+		return "", fmt.Errorf("decentdb: %s is not a single-valued setting", name)
+	}
+	dest := make([]driver.Value, 1)
+	if err := rows.Next(dest); err != nil {
+		if err == io.EOF {
+			return "", fmt.Errorf("decentdb: %s is not a single-valued setting", name)
+		}
+		return "", err
+	}
+	switch v := dest[0].(type) {
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
+
+// SetSetting runs PRAGMA name = value on this connection.
+func (c *conn) SetSetting(name, value string) error {
+	if c.db == nil {
+		return driver.ErrBadConn
+	}
+	if !isSettingName(name) {
+		return fmt.Errorf("decentdb: invalid setting name %q", name)
+	}
+	if !isSettingValue(value) {
+		return fmt.Errorf("decentdb: invalid %s value %q: expected an integer or a single word", name, value)
+	}
+	return c.execSessionSQL("PRAGMA " + name + " = " + value)
+}
+
+// isSettingName reports whether name is a bare lower-case PRAGMA name, so
+// it can be spliced into the statement text.
+func isSettingName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		ch := name[i]
+		if ch != '_' && (ch < 'a' || ch > 'z') && (i == 0 || ch < '0' || ch > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// isSettingValue reports whether value is an optionally signed integer or a
+// word such as FULL, DEFAULT, or UTF-8.
+func isSettingValue(value string) bool {
+	if value == "" {
+		return false
+	}
+	if _, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return true
+	}
+	for i := 0; i < len(value); i++ {
+		ch := value[i]
+		if ch != '_' && ch != '-' && (ch < 'a' || ch > 'z') && (ch < 'A' || ch > 'Z') && (ch < '0' || ch > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// GetSetting returns the current value of an engine setting, as the PRAGMA
+// of the same name reports it: cache_size, synchronous, busy_timeout,
+// foreign_keys, max_parallel_workers, wal_checkpoint_threshold_pages,
+// wal_checkpoint_threshold_bytes, and the rest of the PRAGMA subset.
+func (d *DB) GetSetting(name string) (string, error) {
+	if atomic.LoadUint32(&d.closed) != 0 {
+		return "", driver.ErrBadConn
+	}
+	return d.c.GetSetting(name)
+}
+
+// SetSetting changes an engine setting with PRAGMA name = value. The engine
+// decides what can change while the database is open: cache_size and
+// synchronous accept only their open-time values, while busy_timeout,
+// foreign_keys, max_parallel_workers, and the WAL checkpoint thresholds
+// take effect on the next statement. The checkpoint thresholds apply to
+// every handle open on the same file.
+func (d *DB) SetSetting(name, value string) error {
+	if atomic.LoadUint32(&d.closed) != 0 {
+		return driver.ErrBadConn
+	}
+	return d.c.SetSetting(name, value)
+}
@@ -0,0 +1,75 @@
+package decentdb
+
+import (
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOpenDirect_Settings(t *testing.T) {
+	db, err := OpenDirect(filepath.Join(t.TempDir(), "settings.ddb"))
+	if err != nil {
+		t.Fatalf("OpenDirect failed: %v", err)
+	}
+	defer db.Close()
+
+	if got, err := db.GetSetting("journal_mode"); err != nil || got != "wal" {
+		t.Fatalf("GetSetting(journal_mode) = %q, %v", got, err)
+	}
+	for name, value := range map[string]string{
+		"busy_timeout":                   "250",
+		"wal_checkpoint_threshold_pages": "128",
+		"wal_checkpoint_threshold_bytes": "1048576",
+	} {
+		if err := db.SetSetting(name, value); err != nil {
+			t.Fatalf("SetSetting(%s): %v", name, err)
+		}
+		if got, err := db.GetSetting(name); err != nil || got != value {
+			t.Fatalf("GetSetting(%s) = %q, %v, want %q", name, got, err, value)
+		}
+	}
+
+	cacheSize, err := db.GetSetting("cache_size")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SetSetting("cache_size", cacheSize); err != nil {
+		t.Fatalf("re-setting the open-time cache_size: %v", err)
+	}
+	if err := db.SetSetting("cache_size", "1"); err == nil || !strings.Contains(err.Error(), "cannot be changed") {
+		t.Fatalf("SetSetting(cache_size, 1) = %v", err)
+	}
+
+	for _, name := range []string{"", "Cache_size", "cache_size; DROP TABLE t", "9lives"} {
+		if _, err := db.GetSetting(name); err == nil {
+			t.Errorf("GetSetting(%q) succeeded", name)
+		}
+	}
+	if err := db.SetSetting("busy_timeout", "1; DROP TABLE t"); err == nil {
+		t.Fatal("SetSetting accepted a value with a statement separator")
+	}
+	if _, err := db.GetSetting("table_list"); err == nil {
+		t.Fatal("GetSetting(table_list) accepted a multi-column PRAGMA")
+	}
+}
+
+func TestPragmaThroughDatabaseSQL(t *testing.T) {
+	db, err := sql.Open("decentdb", ":memory:?result_cache_size=8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	var workers int64
+	if err := db.QueryRow("PRAGMA max_parallel_workers").Scan(&workers); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("PRAGMA max_parallel_workers = 3"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.QueryRow("PRAGMA max_parallel_workers").Scan(&workers); err != nil || workers != 3 {
+		t.Fatalf("max_parallel_workers = %d, %v after assignment", workers, err)
+	}
+}
@@ -0,0 +1,115 @@
+package decentdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// sharedEngine is one engine handle per path and open options, shared by
+// the connections of shared_engine=true DSNs. Each connection is an engine
+// session with its own transactions and session state, but all of them use
+// the root handle's page cache, file descriptors, and WAL. The root handle
+// is opened by the first connection and freed with the last.
+type sharedEngine struct {
+	key   string
+	path  string
+	root  *conn
+	conns int
+}
+
+var (
+	sharedEnginesMu sync.Mutex
+	sharedEngines   = map[string]*sharedEngine{}
+)
+
+// acquireSharedSession returns a new session on the shared engine for path
+// and options, opening the engine with open if no connection holds it.
+func acquireSharedSession(path, options string, open func() (*conn, error)) (*conn, *sharedEngine, error) {
+	key := path + "\x00" + options
+	sharedEnginesMu.Lock()
+	defer sharedEnginesMu.Unlock()
+	engine, ok := sharedEngines[key]
+	if !ok {
+		root, err := open()
+		if err != nil {
+			return nil, nil, err
+		}
+		engine = &sharedEngine{key: key, path: path, root: root}
+	}
+	session, err := engine.root.openSession()
+	if err != nil {
+		if !ok {
+			_ = engine.root.Close()
+		}
+		return nil, nil, err
+	}
+	sharedEngines[key] = engine
+	engine.conns++
+	return session, engine, nil
+}
+
+// release drops a connection's hold on e, freeing the root handle when it
+// was the last.
+func (e *sharedEngine) release() {
+	sharedEnginesMu.Lock()
+	defer sharedEnginesMu.Unlock()
+	e.conns--
+	if e.conns > 0 {
+		return
+	}
+	delete(sharedEngines, e.key)
+	_ = e.root.Close()
+}
+
+// SharedEngineStats describes an engine shared by shared_engine=true
+// connections.
+type SharedEngineStats struct {
+	// Path is the database path.
+	Path string
+	// Connections counts open connections using the engine.
+	Connections int
+	// CacheCapacityPages is how many pages the shared cache holds.
+	CacheCapacityPages int
+	// CachedPages is how many pages are cached now.
+	CachedPages int
+	// CacheHits and CacheMisses count page reads served from the cache and
+	// from storage, across every connection.
+	CacheHits   uint64
+	CacheMisses uint64
+}
+
+// SharedEngines reports the engines currently shared by shared_engine=true
+// connections in this process, sorted by path.
+func SharedEngines() ([]SharedEngineStats, error) {
+	sharedEnginesMu.Lock()
+	defer sharedEnginesMu.Unlock()
+	stats := make([]SharedEngineStats, 0, len(sharedEngines))
+	for _, engine := range sharedEngines {
+		raw, err := engine.root.PageCacheStatsJson()
+		if err != nil {
+			return nil, err
+		}
+		var cache struct {
+			CapacityPages int    `json:"capacity_pages"`
+			ResidentPages int    `json:"resident_pages"`
+			Hits          uint64 `json:"hits"`
+			Misses        uint64 `json:"misses"`
+		}
+		if err := json.Unmarshal([]byte(raw), &cache); err != nil {
+			return nil, fmt.Errorf("decentdb: decode page cache stats: %w", err)
+		}
+		stats = append(stats, SharedEngineStats{
+			Path:               engine.path,
+			Connections:        engine.conns,
+			CacheCapacityPages: cache.CapacityPages,
+			CachedPages:        cache.ResidentPages,
+			CacheHits:          cache.Hits,
+			CacheMisses:        cache.Misses,
+		})
+	}
+	slices.SortFunc(stats, func(a, b SharedEngineStats) int { return strings.Compare(a.Path, b.Path) })
+	return stats, nil
+}
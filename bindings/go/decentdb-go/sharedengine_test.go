@@ -0,0 +1,85 @@
+package decentdb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+func TestSharedEngine_PooledConnectionsShareOneCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shared.ddb")
+	db, err := sql.Open("decentdb", fmt.Sprintf("file:%s?shared_engine=true", path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("CREATE TABLE t (id INT PRIMARY KEY)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("INSERT INTO t SELECT value FROM generate_series(1, 50)"); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	c1, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c1.Close()
+	c2, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c2.Close()
+
+	// Sessions keep their own transactions.
+	tx, err := c1.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Exec("DELETE FROM t WHERE id <= 25"); err != nil {
+		t.Fatal(err)
+	}
+	var count int
+	if err := c2.QueryRowContext(ctx, "SELECT COUNT(*) FROM t").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 50 {
+		t.Fatalf("uncommitted delete visible to another connection: count = %d", count)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	engines, err := SharedEngines()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found *SharedEngineStats
+	for i := range engines {
+		if engines[i].Path == path {
+			found = &engines[i]
+		}
+	}
+	if found == nil {
+		t.Fatalf("no shared engine for %s in %+v", path, engines)
+	}
+	if found.Connections < 2 || found.CacheHits+found.CacheMisses == 0 {
+		t.Fatalf("unexpected stats %+v", *found)
+	}
+
+	c1.Close()
+	c2.Close()
+	db.Close()
+	engines, err = SharedEngines()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, engine := range engines {
+		if engine.Path == path {
+			t.Fatalf("engine still shared after every connection closed: %+v", engine)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package decentdb
+
+import "context"
+
+const poolModeSingleWriter = "singlewriter"
+
+// writerGate serializes writers across every connection opened by one
+// connector. It is enabled with the pool=singlewriter DSN option so that
+// database/sql pools stop racing each other into engine busy errors, while
+// readers keep running concurrently.
+type writerGate struct {
+	ch chan struct{}
+}
+
+func newWriterGate() *writerGate {
+	return &writerGate{ch: make(chan struct{}, 1)}
+}
+
+func (g *writerGate) acquire(ctx context.Context) error {
+	select {
+	case g.ch <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (g *writerGate) release() {
+	<-g.ch
+}
+
+// acquireWriter takes the connector's writer gate for the rest of an explicit
+// transaction. It is a no-op without a gate or when already held.
+func (c *conn) acquireWriter(ctx context.Context) error {
+	if c.writer == nil || c.holdsWriter {
+		return nil
+	}
+	if err := c.writer.acquire(ctx); err != nil {
+		return err
+	}
+	c.holdsWriter = true
+	return nil
+}
+
+// releaseWriter gives the writer gate back once no transaction remains open
+// on the engine handle.
+func (c *conn) releaseWriter() {
+	if c.writer == nil || !c.holdsWriter {
+		return
+	}
+	if c.db != nil && c.InTransaction() {
+		return
+	}
+	c.holdsWriter = false
+	c.writer.release()
+}
+
+// gateAutocommitWrite holds the writer gate around a single write statement
+// executed outside an explicit transaction. describe supplies the engine's
+// description of the statement, which decides whether it writes. The
+// returned func releases the gate.
+func (c *conn) gateAutocommitWrite(ctx context.Context, describe func() (*StmtInfo, error)) (func(), error) {
+	if c.writer == nil || c.holdsWriter || !statementWrites(describe) {
+		return func() {}, nil
+	}
+	if err := c.writer.acquire(ctx); err != nil {
+		return nil, err
+	}
+	return c.writer.release, nil
+}
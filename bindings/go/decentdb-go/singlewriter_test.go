@@ -0,0 +1,128 @@
+package decentdb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParsePoolMode(t *testing.T) {
+	cases := map[string]string{
+		":memory:":                          "",
+		"file:/tmp/a.ddb":                   "",
+		"file:/tmp/a.ddb?pool=singlewriter": poolModeSingleWriter,
+		"/tmp/a.ddb?pool=SingleWriter":      poolModeSingleWriter,
+	}
+	for dsn, want := range cases {
+		cfg, err := parseDSN(dsn)
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", dsn, err)
+		}
+		if cfg.pool != want {
+			t.Fatalf("%s: got %q, want %q", dsn, cfg.pool, want)
+		}
+	}
+	if _, err := parseDSN("file:/tmp/a.ddb?pool=many"); err == nil {
+		t.Fatal("expected invalid pool value to fail")
+	}
+}
+
+func TestSingleWriterPool_SerializesWriteTransactions(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "singlewriter.ddb")
+	db, err := sql.Open("decentdb", fmt.Sprintf("file:%s?pool=singlewriter", dbPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(4)
+
+	ctx := context.Background()
+	if _, err := db.ExecContext(ctx, "CREATE TABLE jobs (id INT64 PRIMARY KEY, name TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+
+	tx1, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx1.ExecContext(ctx, "INSERT INTO jobs (id, name) VALUES ($1, $2)", 1, "first"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Readers are not gated while a write transaction is open.
+	var count int64
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM jobs").Scan(&count); err != nil {
+		t.Fatalf("concurrent read failed: %v", err)
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := db.BeginTx(waitCtx, nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected second writer to wait for the gate, got %v", err)
+	}
+	if _, err := db.ExecContext(waitCtx, "INSERT INTO jobs (id, name) VALUES ($1, $2)", 2, "blocked"); err == nil {
+		t.Fatal("expected autocommit write to wait for the gate")
+	}
+
+	if err := tx1.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	tx2, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("writer gate not released after commit: %v", err)
+	}
+	if _, err := tx2.ExecContext(ctx, "INSERT INTO jobs (id, name) VALUES ($1, $2)", 2, "second"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx2.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.ExecContext(ctx, "INSERT INTO jobs (id, name) VALUES ($1, $2)", 3, "third"); err != nil {
+		t.Fatalf("writer gate not released after rollback: %v", err)
+	}
+}
+
+func TestSingleWriterPool_GatesWritesLedByWith(t *testing.T) {
+	db := openTestDB(t, "?pool=singlewriter")
+	db.SetMaxOpenConns(4)
+
+	ctx := context.Background()
+	if _, err := db.ExecContext(ctx, "CREATE TABLE jobs (id INT64 PRIMARY KEY, name TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	// A read led by WITH is not gated.
+	var count int64
+	if err := db.QueryRowContext(ctx, "WITH j AS (SELECT id FROM jobs) SELECT COUNT(*) FROM j").Scan(&count); err != nil {
+		t.Fatalf("WITH read was gated: %v", err)
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	const write = "WITH src AS (SELECT 1 AS id) INSERT INTO jobs (id, name) SELECT id, 'cte' FROM src"
+	if _, err := db.ExecContext(waitCtx, write); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected WITH-led write to wait for the gate, got %v", err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.ExecContext(ctx, write); err != nil {
+		t.Fatalf("WITH-led write after rollback: %v", err)
+	}
+}
+
+func TestSingleWriterPool_RejectsUnknownPoolMode(t *testing.T) {
+	if _, err := sql.Open("decentdb", "file:/tmp/unused.ddb?pool=multi"); err == nil {
+		t.Fatal("expected sql.Open to reject an unknown pool mode")
+	}
+}
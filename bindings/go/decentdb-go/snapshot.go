@@ -0,0 +1,70 @@
+package decentdb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync/atomic"
+)
+
+// Snapshot is a read-only, point-in-time view of a database.
+//
+// Queries run through a Snapshot observe the committed state as of the moment
+// it was taken, even while other handles and pooled connections keep writing.
+// This makes it suitable for multi-query reports that must agree with each
+// other. A Snapshot owns its own native handle; call Close to release it.
+type Snapshot struct {
+	pool   *sql.DB
+	tx     *sql.Tx
+	closed uint32
+}
+
+// Snapshot pins a consistent read view of the database.
+//
+// The snapshot is backed by a dedicated handle holding an open transaction, so
+// it is only available for file-backed databases. Anything written through
+// the snapshot is discarded when it is closed.
+func (d *DB) Snapshot(ctx context.Context) (*Snapshot, error) {
+	if atomic.LoadUint32(&d.closed) != 0 {
+		return nil, driver.ErrBadConn
+	}
+	if d.path == "" || d.path == ":memory:" {
+		return nil, errors.New("decentdb snapshots require a file-backed database")
+	}
+
+	pool := sql.OpenDB(&connector{dsn: d.path})
+	pool.SetMaxOpenConns(1)
+	tx, err := pool.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		_ = pool.Close()
+		return nil, err
+	}
+	return &Snapshot{pool: pool, tx: tx}, nil
+}
+
+// QueryContext runs a query against the snapshot.
+func (s *Snapshot) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	if atomic.LoadUint32(&s.closed) != 0 {
+		return nil, sql.ErrTxDone
+	}
+	return s.tx.QueryContext(ctx, query, args...)
+}
+
+// QueryRowContext runs a query that is expected to return at most one row.
+func (s *Snapshot) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return s.tx.QueryRowContext(ctx, query, args...)
+}
+
+// Close releases the snapshot and its native handle. It is safe to call more
+// than once.
+func (s *Snapshot) Close() error {
+	if s == nil || !atomic.CompareAndSwapUint32(&s.closed, 0, 1) {
+		return nil
+	}
+	err := s.tx.Rollback()
+	if closeErr := s.pool.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
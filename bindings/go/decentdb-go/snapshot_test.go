@@ -0,0 +1,89 @@
+package decentdb
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpenDirect_SnapshotSeesPointInTimeView(t *testing.T) {
+	ctx := context.Background()
+	db, err := OpenDirect(filepath.Join(t.TempDir(), "snapshot.ddb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec("CREATE TABLE events (id INT64 PRIMARY KEY, name TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("INSERT INTO events (id, name) VALUES ($1, $2)", 1, "first"); err != nil {
+		t.Fatal(err)
+	}
+
+	snap, err := db.Snapshot(ctx)
+	if err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+	defer snap.Close()
+
+	if _, err := db.Exec("INSERT INTO events (id, name) VALUES ($1, $2)", 2, "second"); err != nil {
+		t.Fatal(err)
+	}
+
+	var snapCount int64
+	if err := snap.QueryRowContext(ctx, "SELECT COUNT(*) FROM events").Scan(&snapCount); err != nil {
+		t.Fatal(err)
+	}
+	if snapCount != 1 {
+		t.Fatalf("snapshot should see 1 row, got %d", snapCount)
+	}
+	liveCount, err := db.QueryOnBranchInt64("main", "SELECT COUNT(*) FROM events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if liveCount != 2 {
+		t.Fatalf("live handle should see 2 rows, got %d", liveCount)
+	}
+
+	rows, err := snap.QueryContext(ctx, "SELECT name FROM events ORDER BY id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	if err := rows.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 1 || names[0] != "first" {
+		t.Fatalf("unexpected snapshot rows: %v", names)
+	}
+
+	if err := snap.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if err := snap.Close(); err != nil {
+		t.Fatalf("second Close failed: %v", err)
+	}
+	if _, err := snap.QueryContext(ctx, "SELECT 1"); err == nil {
+		t.Fatal("expected error querying a closed snapshot")
+	}
+}
+
+func TestOpenDirect_SnapshotRequiresFileBackedDatabase(t *testing.T) {
+	db, err := OpenDirect(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := db.Snapshot(context.Background()); err == nil {
+		t.Fatal("expected snapshot of :memory: database to fail")
+	}
+}
@@ -0,0 +1,41 @@
+package decentdb
+
+/*
+#include "decentdb.h"
+#include <stdlib.h>
+*/
+import "C"
+import (
+	"context"
+	"database/sql/driver"
+	"log/slog"
+	"sync/atomic"
+	"unsafe"
+)
+
+// ResetStatementStats clears the per-fingerprint statement statistics
+// reported by decentdb_stat_statements(). The statistics are shared by every
+// connection to the database file in the process, so this resets them for
+// all of them.
+func (c *conn) ResetStatementStats() error {
+	if c.db == nil {
+		return driver.ErrBadConn
+	}
+	kind := C.CString("statement_stats")
+	defer C.free(unsafe.Pointer(kind))
+	status := C.ddb_runtime_tracing_reset(c.db, kind)
+	if status != C.DDB_OK {
+		return statusError(status, "")
+	}
+	logDebug(context.Background(), "decentdb: statement stats reset", slog.String("path", c.path))
+	return nil
+}
+
+// ResetStatementStats clears the per-fingerprint statement statistics
+// reported by decentdb_stat_statements().
+func (d *DB) ResetStatementStats() error {
+	if atomic.LoadUint32(&d.closed) != 0 {
+		return driver.ErrBadConn
+	}
+	return d.c.ResetStatementStats()
+}
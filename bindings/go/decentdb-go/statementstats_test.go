@@ -0,0 +1,64 @@
+package decentdb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+func TestStatementStats_AggregateAndReset(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stats.ddb")
+	db, err := sql.Open("decentdb", fmt.Sprintf("file:%s?statement_stats=true", path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("CREATE TABLE t (id INT PRIMARY KEY, name TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 3; i++ {
+		if _, err := db.Exec("INSERT INTO t VALUES ($1, $2)", i, "n"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var calls, rows int64
+	err = db.QueryRow(
+		"SELECT calls, total_rows FROM decentdb_stat_statements() WHERE fingerprint = $1",
+		"insert into t values ($1, $2)",
+	).Scan(&calls, &rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 || rows != 3 {
+		t.Fatalf("calls=%d rows=%d, want 3 and 3", calls, rows)
+	}
+
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	err = conn.Raw(func(driverConn any) error {
+		return driverConn.(interface{ ResetStatementStats() error }).ResetStatementStats()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	if err := conn.QueryRowContext(context.Background(),
+		"SELECT COUNT(*) FROM decentdb_stat_statements() WHERE fingerprint LIKE 'insert%'").Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Fatalf("%d insert fingerprints after reset", n)
+	}
+}
+
+func TestStatementStats_InvalidMax(t *testing.T) {
+	if _, err := sql.Open("decentdb", fmt.Sprintf("file:%s?statement_stats_max=0", filepath.Join(t.TempDir(), "bad.ddb"))); err == nil {
+		t.Fatal("statement_stats_max=0 accepted")
+	}
+}
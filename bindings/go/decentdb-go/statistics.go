@@ -0,0 +1,138 @@
+package decentdb
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// TableStatistics is what the planner knows about a table. RowCount is nil
+// until the table has been analyzed.
+type TableStatistics struct {
+	Table    string `json:"table_name"`
+	RowCount *int64 `json:"row_count"`
+	// ModifiedRows counts rows inserted, updated, or deleted through this
+	// handle since the last ANALYZE; auto-analyze compares it against the
+	// auto_analyze_min_rows and auto_analyze_churn_percent thresholds.
+	ModifiedRows uint64             `json:"modified_rows_since_analyze"`
+	Columns      []ColumnStatistics `json:"columns"`
+	Indexes      []IndexStatistics  `json:"indexes"`
+}
+
+// ColumnStatistics describes one column as of the last ANALYZE.
+type ColumnStatistics struct {
+	Column        string `json:"column_name"`
+	NullCount     int64  `json:"null_count"`
+	DistinctCount int64  `json:"distinct_count"`
+	// HistogramBounds are the upper bounds of equi-depth buckets, rendered
+	// as text. Empty for types without an ordering, such as BLOB or UUID.
+	HistogramBounds []string `json:"histogram_bounds"`
+}
+
+// IndexStatistics describes one index of the table as of the last ANALYZE.
+type IndexStatistics struct {
+	Index            string `json:"index_name"`
+	Entries          int64  `json:"entry_count"`
+	DistinctKeyCount int64  `json:"distinct_key_count"`
+}
+
+// analyzeSQL returns the ANALYZE statements for tables, or a single
+// statement covering every table when none are given.
+func analyzeSQL(tables []string) []string {
+	if len(tables) == 0 {
+		return []string{"ANALYZE"}
+	}
+	stmts := make([]string, len(tables))
+	for i, table := range tables {
+		stmts[i] = "ANALYZE " + quoteRelationName(table)
+	}
+	return stmts
+}
+
+// quoteRelationName quotes each dot-separated part of a table name.
+func quoteRelationName(table string) string {
+	parts := strings.Split(table, ".")
+	for i, part := range parts {
+		parts[i] = `"` + strings.ReplaceAll(part, `"`, `""`) + `"`
+	}
+	return strings.Join(parts, ".")
+}
+
+// Analyze refreshes planner statistics for the named tables, or for every
+// table when none are given. A "schema.table" name is split at the dot.
+func (c *conn) Analyze(ctx context.Context, tables ...string) error {
+	for _, stmt := range analyzeSQL(tables) {
+		if _, err := c.ExecContext(ctx, stmt, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TableStatistics returns the statistics the planner uses for table.
+func (c *conn) TableStatistics(table string) (*TableStatistics, error) {
+	raw, err := c.TableStatisticsJson(table)
+	if err != nil {
+		return nil, err
+	}
+	var stats TableStatistics
+	if err := json.Unmarshal([]byte(raw), &stats); err != nil {
+		return nil, fmt.Errorf("failed to parse table statistics: %w", err)
+	}
+	return &stats, nil
+}
+
+// EstimatedRowCount returns the row count recorded by the last ANALYZE of
+// table, falling back to an exact COUNT(*) when it was never analyzed.
+func (c *conn) EstimatedRowCount(table string) (int64, error) {
+	stats, err := c.TableStatistics(table)
+	if err != nil {
+		return 0, err
+	}
+	if stats.RowCount != nil {
+		return *stats.RowCount, nil
+	}
+	rows, err := c.QueryContext(context.Background(), "SELECT COUNT(*) FROM "+quoteRelationName(table), nil)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	dest := make([]driver.Value, 1)
+	if err := rows.Next(dest); err != nil {
+		return 0, err
+	}
+	count, _ := dest[0].(int64)
+	return count, nil
+}
+
+// Analyze refreshes planner statistics for the named tables, or for every
+// table when none are given.
+func (d *DB) Analyze(ctx context.Context, tables ...string) error {
+	if atomic.LoadUint32(&d.closed) != 0 {
+		return driver.ErrBadConn
+	}
+	return d.c.Analyze(ctx, tables...)
+}
+
+// TableStatistics returns the row count, per-column NULL and distinct
+// counts, histograms, and index statistics recorded by the last ANALYZE.
+func (d *DB) TableStatistics(table string) (*TableStatistics, error) {
+	if atomic.LoadUint32(&d.closed) != 0 {
+		return nil, driver.ErrBadConn
+	}
+	return d.c.TableStatistics(table)
+}
+
+// EstimatedRowCount answers "roughly how many rows" from planner statistics
+// without scanning table. The count is as of the last ANALYZE, so writes
+// since then are not reflected until auto-analyze or an explicit Analyze
+// refreshes it. A table that was never analyzed is counted exactly.
+func (d *DB) EstimatedRowCount(table string) (int64, error) {
+	if atomic.LoadUint32(&d.closed) != 0 {
+		return 0, driver.ErrBadConn
+	}
+	return d.c.EstimatedRowCount(table)
+}
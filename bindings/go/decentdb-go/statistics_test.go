@@ -0,0 +1,123 @@
+package decentdb
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestAnalyzeSQL(t *testing.T) {
+	if got := analyzeSQL(nil); !reflect.DeepEqual(got, []string{"ANALYZE"}) {
+		t.Fatalf("analyzeSQL(nil) = %q", got)
+	}
+	got := analyzeSQL([]string{"orders", `sales."odd"`})
+	want := []string{`ANALYZE "orders"`, `ANALYZE "sales"."""odd"""`}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("analyzeSQL = %q, want %q", got, want)
+	}
+}
+
+func TestOpenDirect_AnalyzeAndTableStatistics(t *testing.T) {
+	db, err := OpenDirect(filepath.Join(t.TempDir(), "stats.ddb"))
+	if err != nil {
+		t.Fatalf("OpenDirect failed: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec("CREATE TABLE items (id INT PRIMARY KEY, category TEXT, note TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		var note any
+		if i%4 == 0 {
+			note = "noted"
+		}
+		if _, err := db.Exec("INSERT INTO items VALUES ($1, $2, $3)", int64(i), []string{"a", "b", "c"}[i%3], note); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats, err := db.TableStatistics("items")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.RowCount != nil || stats.ModifiedRows != 100 {
+		t.Fatalf("unexpected statistics before ANALYZE: %+v", stats)
+	}
+
+	if err := db.Analyze(context.Background(), "items"); err != nil {
+		t.Fatal(err)
+	}
+	stats, err = db.TableStatistics("items")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.RowCount == nil || *stats.RowCount != 100 || stats.ModifiedRows != 0 {
+		t.Fatalf("unexpected statistics after ANALYZE: %+v", stats)
+	}
+	byName := map[string]ColumnStatistics{}
+	for _, column := range stats.Columns {
+		byName[column.Column] = column
+	}
+	if c := byName["category"]; c.DistinctCount != 3 || c.NullCount != 0 || len(c.HistogramBounds) == 0 {
+		t.Fatalf("unexpected category statistics: %+v", c)
+	}
+	if c := byName["note"]; c.DistinctCount != 1 || c.NullCount != 75 {
+		t.Fatalf("unexpected note statistics: %+v", c)
+	}
+	if _, err := db.TableStatistics("missing"); err == nil {
+		t.Fatal("statistics for an unknown table should fail")
+	}
+}
+
+func TestOpenDirect_EstimatedRowCountAndTableSample(t *testing.T) {
+	db, err := OpenDirect(filepath.Join(t.TempDir(), "estimate.ddb"))
+	if err != nil {
+		t.Fatalf("OpenDirect failed: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec("CREATE TABLE events (id INT PRIMARY KEY)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("INSERT INTO events SELECT value FROM generate_series(1, 5000)"); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := db.EstimatedRowCount("events"); err != nil || n != 5000 {
+		t.Fatalf("EstimatedRowCount before ANALYZE = %d, %v, want an exact 5000", n, err)
+	}
+	if err := db.Analyze(context.Background(), "events"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("INSERT INTO events VALUES (5001)"); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := db.EstimatedRowCount("events"); err != nil || n != 5000 {
+		t.Fatalf("EstimatedRowCount = %d, %v, want the analyzed 5000", n, err)
+	}
+	if _, err := db.EstimatedRowCount("missing"); err == nil {
+		t.Fatal("estimating an unknown table should fail")
+	}
+
+	sample := func() int64 {
+		t.Helper()
+		cr, err := db.QueryColumns(context.Background(), "SELECT COUNT(*) FROM events TABLESAMPLE BERNOULLI (20) REPEATABLE (9)")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer cr.Close()
+		batch, err := cr.FetchColumns(0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return batch.Columns[0].Int64[0]
+	}
+	first := sample()
+	if first < 700 || first > 1300 {
+		t.Fatalf("a 20%% sample of 5001 rows returned %d", first)
+	}
+	if again := sample(); again != first {
+		t.Fatalf("REPEATABLE sample returned %d then %d", first, again)
+	}
+}
@@ -0,0 +1,139 @@
+package decentdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// StmtInfo describes a statement's parameters and result columns as inferred
+// by the engine without executing it.
+type StmtInfo struct {
+	SQL           string `json:"sql"`
+	StatementKind string `json:"statement_kind"`
+	// ReadOnly is false for writes and for queries that take or release
+	// advisory locks.
+	ReadOnly    bool           `json:"read_only"`
+	Params      []ParamInfo    `json:"parameters"`
+	Columns     []ResultColumn `json:"result_columns"`
+	Diagnostics []string       `json:"diagnostics,omitempty"`
+	// Tables lists the tables and views the statement touches.
+	// TablesComplete is false when the engine could not prove the list
+	// exhaustive, which is always the case for DDL.
+	Tables         []string `json:"referenced_tables"`
+	TablesComplete bool     `json:"referenced_tables_complete"`
+	// DependencyTables lists the tables whose committed changes can alter
+	// a read-only statement's result, with views expanded. It is nil for
+	// writes and when the engine cannot prove the list, for example for
+	// queries on temporary tables.
+	DependencyTables []string `json:"dependency_tables"`
+	// TargetTable is the table an INSERT, UPDATE, or DELETE writes.
+	TargetTable string `json:"target_table,omitempty"`
+	// ObjectKind ("table", "index", "view", "trigger", or "schema") and
+	// ObjectName name the object a DDL statement creates, alters, comments
+	// on, or drops.
+	ObjectKind string `json:"object_kind,omitempty"`
+	ObjectName string `json:"object_name,omitempty"`
+}
+
+// ParamInfo describes one $N placeholder. TypeName is empty when the engine
+// cannot infer a declared type from the parameter's context.
+type ParamInfo struct {
+	Position     int    `json:"position"`
+	Name         string `json:"name"`
+	TypeName     string `json:"type_name,omitempty"`
+	Nullable     *bool  `json:"nullable,omitempty"`
+	SourceTable  string `json:"source_table,omitempty"`
+	SourceColumn string `json:"source_column,omitempty"`
+}
+
+// ResultColumn describes one output column of a statement.
+type ResultColumn struct {
+	Ordinal       int    `json:"ordinal"`
+	Name          string `json:"name"`
+	TypeName      string `json:"type_name,omitempty"`
+	Nullable      *bool  `json:"nullable,omitempty"`
+	SourceTable   string `json:"source_table,omitempty"`
+	SourceColumn  string `json:"source_column,omitempty"`
+	ExpressionSQL string `json:"expression_sql,omitempty"`
+}
+
+// NumInput returns the highest placeholder position, which is the number of
+// arguments the statement binds.
+func (i *StmtInfo) NumInput() int {
+	n := 0
+	for _, p := range i.Params {
+		if p.Position > n {
+			n = p.Position
+		}
+	}
+	return n
+}
+
+// StmtInfo describes query's parameters and result columns without
+// executing it.
+func (c *conn) StmtInfo(query string) (*StmtInfo, error) {
+	raw, err := c.DescribeQueryJson(query)
+	if err != nil {
+		return nil, err
+	}
+	var info StmtInfo
+	if err := json.Unmarshal([]byte(raw), &info); err != nil {
+		return nil, fmt.Errorf("failed to parse query contract: %w", err)
+	}
+	return &info, nil
+}
+
+// StmtInfo describes query's parameters and result columns without
+// executing it.
+func (d *DB) StmtInfo(query string) (*StmtInfo, error) { return d.c.StmtInfo(query) }
+
+// StmtInfo describes the prepared statement. The result is computed once per
+// statement.
+func (s *stmtStruct) StmtInfo() (*StmtInfo, error) {
+	if s.info == nil && s.infoErr == nil {
+		s.info, s.infoErr = s.c.StmtInfo(s.query)
+	}
+	return s.info, s.infoErr
+}
+
+// maxPlaceholder returns the highest $N placeholder in sqlText, skipping
+// quoted strings, quoted identifiers, and comments.
+func maxPlaceholder(sqlText string) int {
+	highest := 0
+	for i := 0; i < len(sqlText); i++ {
+		switch ch := sqlText[i]; {
+		case ch == '\'' || ch == '"':
+			for i++; i < len(sqlText); i++ {
+				if sqlText[i] == ch {
+					if i+1 < len(sqlText) && sqlText[i+1] == ch {
+						i++
+						continue
+					}
+					break
+				}
+			}
+		case ch == '-' && i+1 < len(sqlText) && sqlText[i+1] == '-':
+			for i < len(sqlText) && sqlText[i] != '\n' {
+				i++
+			}
+		case ch == '/' && i+1 < len(sqlText) && sqlText[i+1] == '*':
+			end := strings.Index(sqlText[i+2:], "*/")
+			if end < 0 {
+				return highest
+			}
+			i += end + 3
+		case ch == '$':
+			n, j := 0, i+1
+			for j < len(sqlText) && sqlText[j] >= '0' && sqlText[j] <= '9' {
+				n = n*10 + int(sqlText[j]-'0')
+				j++
+			}
+			if n > highest {
+				highest = n
+			}
+			i = j - 1
+		}
+	}
+	return highest
+}
@@ -0,0 +1,87 @@
+package decentdb
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMaxPlaceholder(t *testing.T) {
+	cases := map[string]int{
+		"SELECT 1":                             0,
+		"SELECT $1, $2":                        2,
+		"SELECT '$9', $1 -- $5\n, $3 /* $7 */": 3,
+		`SELECT "a$4" FROM t WHERE x = $12`:    12,
+		"SELECT 'it''s $8', $2":                2,
+		"SELECT $2 /* unterminated $6":         2,
+	}
+	for query, want := range cases {
+		if got := maxPlaceholder(query); got != want {
+			t.Fatalf("maxPlaceholder(%q) = %d, want %d", query, got, want)
+		}
+	}
+}
+
+func TestDriver_StmtInfoAndNumInput(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "decentdb-test-stmtinfo-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	dbPath := filepath.Join(tmpDir, "stmtinfo.ddb")
+
+	db, err := sql.Open("decentdb", fmt.Sprintf("file:%s", dbPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("CREATE TABLE users (id INT64 PRIMARY KEY, email TEXT NOT NULL)"); err != nil {
+		t.Fatal(err)
+	}
+
+	stmt, err := db.Prepare("INSERT INTO users (id, email) VALUES ($1, $2)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+	if _, err := stmt.Exec(1); err == nil || !strings.Contains(err.Error(), "expected 2 arguments") {
+		t.Fatalf("expected argument count validation, got %v", err)
+	}
+	if _, err := stmt.Exec(1, "ada@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	direct, err := OpenDirect(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer direct.Close()
+	info, err := direct.StmtInfo("SELECT id, email FROM users WHERE id = $1 AND email = $2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.ReadOnly || info.NumInput() != 2 {
+		t.Fatalf("unexpected statement info: %+v", info)
+	}
+	if info.Params[0].TypeName != "INT64" || info.Params[1].TypeName != "TEXT" {
+		t.Fatalf("unexpected parameter types: %+v", info.Params)
+	}
+	if len(info.Columns) != 2 || info.Columns[1].Name != "email" || info.Columns[1].TypeName != "TEXT" {
+		t.Fatalf("unexpected result columns: %+v", info.Columns)
+	}
+	if !info.TablesComplete || len(info.Tables) != 1 || info.Tables[0] != "users" || info.TargetTable != "" {
+		t.Fatalf("unexpected referenced tables: %+v", info)
+	}
+
+	info, err = direct.StmtInfo("DELETE FROM users WHERE id = $1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.TargetTable != "users" || !info.TablesComplete {
+		t.Fatalf("unexpected delete info: %+v", info)
+	}
+}
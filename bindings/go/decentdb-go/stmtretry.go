@@ -0,0 +1,214 @@
+package decentdb
+
+import (
+	"context"
+	"database/sql/driver"
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultStatementMaxAttempts    = 3
+	defaultStatementInitialBackoff = 5 * time.Millisecond
+	defaultStatementMaxBackoff     = 200 * time.Millisecond
+	defaultStatementBudgetRatio    = 0.1
+	defaultStatementBudgetTokens   = 10
+)
+
+// StatementRetryOptions configures NewStatementRetry. Zero fields take their
+// defaults.
+type StatementRetryOptions struct {
+	// MaxAttempts bounds the attempts of one statement, including the first.
+	// It defaults to 3.
+	MaxAttempts int
+	// InitialBackoff is the upper bound of the jittered delay before the
+	// first retry. It doubles after every retry, up to MaxBackoff. The
+	// defaults are 5ms and 200ms.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// BudgetRatio is the fraction of a retry each statement earns, so that
+	// across the connector retries stay near that share of statements. It
+	// defaults to 0.1.
+	BudgetRatio float64
+	// BudgetTokens caps the retries banked for a burst of failures, and is
+	// the budget the connector starts with. It defaults to 10.
+	BudgetTokens int
+}
+
+// StatementRetryStats reports statement retry activity.
+type StatementRetryStats struct {
+	// Retries counts re-executions after a transient error.
+	Retries uint64
+	// Recovered counts statements that succeeded after at least one retry.
+	Recovered uint64
+	// Exhausted counts statements that still failed after MaxAttempts.
+	Exhausted uint64
+	// BudgetDenied counts retries skipped because the budget was spent.
+	BudgetDenied uint64
+}
+
+// StatementRetry re-executes single statements that fail with a transient
+// error, such as a busy writer or an I/O error the engine reports as
+// retryable. Read-only statements are always eligible; writes only when
+// their context is marked with WithIdempotent. Statements inside a
+// transaction are never retried, because the failure may already have ended
+// the transaction; use WithTx to retry whole transactions instead.
+//
+// Retries back off with full jitter and draw on a budget shared by every
+// connection of the connector, so a sustained outage does not multiply load.
+// A StatementRetry is safe for concurrent use.
+type StatementRetry struct {
+	opts StatementRetryOptions
+
+	retries      atomic.Uint64
+	recovered    atomic.Uint64
+	exhausted    atomic.Uint64
+	budgetDenied atomic.Uint64
+
+	mu     sync.Mutex
+	tokens float64
+}
+
+// NewStatementRetry returns a retry policy to pass to WithStatementRetry.
+func NewStatementRetry(opts StatementRetryOptions) *StatementRetry {
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = defaultStatementMaxAttempts
+	}
+	if opts.InitialBackoff <= 0 {
+		opts.InitialBackoff = defaultStatementInitialBackoff
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = defaultStatementMaxBackoff
+	}
+	if opts.BudgetRatio <= 0 {
+		opts.BudgetRatio = defaultStatementBudgetRatio
+	}
+	if opts.BudgetTokens <= 0 {
+		opts.BudgetTokens = defaultStatementBudgetTokens
+	}
+	return &StatementRetry{opts: opts, tokens: float64(opts.BudgetTokens)}
+}
+
+// WithStatementRetry retries transient statement failures on every
+// connection the connector opens according to r. Interceptors see each
+// attempt.
+func WithStatementRetry(r *StatementRetry) ConnectorOption {
+	return func(c *connector) {
+		c.retry = r
+	}
+}
+
+// Stats returns the policy's retry counters.
+func (r *StatementRetry) Stats() StatementRetryStats {
+	return StatementRetryStats{
+		Retries:      r.retries.Load(),
+		Recovered:    r.recovered.Load(),
+		Exhausted:    r.exhausted.Load(),
+		BudgetDenied: r.budgetDenied.Load(),
+	}
+}
+
+type idempotentKey struct{}
+
+// WithIdempotent returns a context marking the statements run with it as safe
+// to execute more than once, which makes writes eligible for statement
+// retry. Mark only writes whose repetition cannot change the outcome, such as
+// upserts or updates that set absolute values.
+func WithIdempotent(ctx context.Context) context.Context {
+	return context.WithValue(ctx, idempotentKey{}, true)
+}
+
+// isIdempotent reports whether ctx was marked with WithIdempotent.
+func isIdempotent(ctx context.Context) bool {
+	marked, _ := ctx.Value(idempotentKey{}).(bool)
+	return marked
+}
+
+// earn credits the budget for one executed statement.
+func (r *StatementRetry) earn() {
+	r.mu.Lock()
+	r.tokens = min(r.tokens+r.opts.BudgetRatio, float64(r.opts.BudgetTokens))
+	r.mu.Unlock()
+}
+
+// spend takes one retry from the budget, reporting false if none is left.
+func (r *StatementRetry) spend() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.tokens < 1 {
+		return false
+	}
+	r.tokens--
+	return true
+}
+
+// eligible reports whether query may be re-executed on c.
+func (r *StatementRetry) eligible(ctx context.Context, c *conn, query string) bool {
+	if c.InTransaction() || isTransactionControlQuery(query, nil) != "" {
+		return false
+	}
+	if isIdempotent(ctx) {
+		return true
+	}
+	info, err := c.StmtInfo(query)
+	return err == nil && info.ReadOnly
+}
+
+// execContext runs exec, re-executing it on transient failures.
+func (r *StatementRetry) execContext(ctx context.Context, c *conn, query string, args []driver.NamedValue, exec ExecFunc) (driver.Result, error) {
+	return retryStatement(ctx, r, c, query, func() (driver.Result, error) {
+		return exec(ctx, query, args)
+	})
+}
+
+// queryContext runs query, re-executing it on transient failures. Errors
+// raised while reading the returned rows are not retried.
+func (r *StatementRetry) queryContext(ctx context.Context, c *conn, query string, args []driver.NamedValue, run QueryFunc) (driver.Rows, error) {
+	return retryStatement(ctx, r, c, query, func() (driver.Rows, error) {
+		return run(ctx, query, args)
+	})
+}
+
+// retryStatement calls attempt until it succeeds, fails permanently, or the
+// attempts or budget run out.
+func retryStatement[T any](ctx context.Context, r *StatementRetry, c *conn, query string, attempt func() (T, error)) (T, error) {
+	r.earn()
+	result, err := attempt()
+	if err == nil || !IsRetryable(err) || !r.eligible(ctx, c, query) {
+		return result, err
+	}
+	backoff := r.opts.InitialBackoff
+	for n := 2; ; n++ {
+		if n > r.opts.MaxAttempts {
+			r.exhausted.Add(1)
+			return result, err
+		}
+		if !r.spend() {
+			r.budgetDenied.Add(1)
+			return result, err
+		}
+		delay := rand.N(backoff + 1)
+		logDebug(ctx, "decentdb: retrying statement", slog.Int("attempt", n),
+			slog.Duration("backoff", delay), errAttr(err))
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return result, err
+		case <-timer.C:
+		}
+		r.retries.Add(1)
+		result, err = attempt()
+		if err == nil {
+			r.recovered.Add(1)
+			return result, nil
+		}
+		if !IsRetryable(err) {
+			return result, err
+		}
+		backoff = min(backoff*2, r.opts.MaxBackoff)
+	}
+}
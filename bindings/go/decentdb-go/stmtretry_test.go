@@ -0,0 +1,124 @@
+package decentdb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// busyInterceptor fails the next `failures` statements with ErrBusy before
+// they reach the engine.
+type busyInterceptor struct {
+	NoopInterceptor
+	failures *atomic.Int32
+}
+
+func (b busyInterceptor) fail() error {
+	if b.failures.Add(-1) >= 0 {
+		return fmt.Errorf("%w: injected", ErrBusy)
+	}
+	return nil
+}
+
+func (b busyInterceptor) InterceptExec(ctx context.Context, query string, args []driver.NamedValue, next ExecFunc) (driver.Result, error) {
+	if err := b.fail(); err != nil {
+		return nil, err
+	}
+	return next(ctx, query, args)
+}
+
+func (b busyInterceptor) InterceptQuery(ctx context.Context, query string, args []driver.NamedValue, next QueryFunc) (driver.Rows, error) {
+	if err := b.fail(); err != nil {
+		return nil, err
+	}
+	return next(ctx, query, args)
+}
+
+func TestStatementRetry(t *testing.T) {
+	var failures atomic.Int32
+	retry := NewStatementRetry(StatementRetryOptions{MaxAttempts: 3, InitialBackoff: time.Microsecond})
+	connector, err := NewConnector(":memory:", WithInterceptors(busyInterceptor{failures: &failures}), WithStatementRetry(retry))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := sql.OpenDB(connector)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	ctx := context.Background()
+	if _, err := db.ExecContext(ctx, "CREATE TABLE t (id INT64 PRIMARY KEY)"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A read recovers after one busy failure.
+	failures.Store(1)
+	var count int64
+	if err := db.QueryRowContext(ctx, "SELECT count(*) FROM t").Scan(&count); err != nil {
+		t.Fatalf("read was not retried: %v", err)
+	}
+	if stats := retry.Stats(); stats.Retries != 1 || stats.Recovered != 1 {
+		t.Fatalf("stats after recovered read = %+v", stats)
+	}
+
+	// Writes are not retried unless marked idempotent.
+	failures.Store(1)
+	if _, err := db.ExecContext(ctx, "INSERT INTO t VALUES (1)"); !errors.Is(err, ErrBusy) {
+		t.Fatalf("unmarked write returned %v, want ErrBusy", err)
+	}
+	failures.Store(1)
+	if _, err := db.ExecContext(WithIdempotent(ctx), "INSERT INTO t VALUES (1) ON CONFLICT (id) DO NOTHING"); err != nil {
+		t.Fatalf("idempotent write was not retried: %v", err)
+	}
+	if stats := retry.Stats(); stats.Retries != 2 || stats.Recovered != 2 {
+		t.Fatalf("stats after idempotent write = %+v", stats)
+	}
+
+	// Statements inside a transaction are left to the transaction.
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	failures.Store(1)
+	if _, err := tx.QueryContext(ctx, "SELECT id FROM t"); !errors.Is(err, ErrBusy) {
+		t.Fatalf("read in a transaction returned %v, want ErrBusy", err)
+	}
+	_ = tx.Rollback()
+
+	// A persistent failure gives up after MaxAttempts.
+	failures.Store(10)
+	if _, err := db.QueryContext(ctx, "SELECT id FROM t"); !errors.Is(err, ErrBusy) {
+		t.Fatalf("persistent failure returned %v, want ErrBusy", err)
+	}
+	if stats := retry.Stats(); stats.Exhausted != 1 || stats.Retries != 4 {
+		t.Fatalf("stats after exhausted read = %+v", stats)
+	}
+}
+
+func TestStatementRetryBudget(t *testing.T) {
+	retry := NewStatementRetry(StatementRetryOptions{BudgetRatio: 0.5, BudgetTokens: 2})
+	if !retry.spend() || !retry.spend() {
+		t.Fatal("a fresh budget refused a retry")
+	}
+	if retry.spend() {
+		t.Fatal("a spent budget allowed a retry")
+	}
+	retry.earn()
+	if retry.spend() {
+		t.Fatal("half a token allowed a retry")
+	}
+	retry.earn()
+	retry.earn()
+	if !retry.spend() {
+		t.Fatal("an earned token was refused")
+	}
+	for i := 0; i < 10; i++ {
+		retry.earn()
+	}
+	if retry.tokens != 2 {
+		t.Fatalf("budget grew to %v, want it capped at 2", retry.tokens)
+	}
+}
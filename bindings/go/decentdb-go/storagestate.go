@@ -0,0 +1,66 @@
+package decentdb
+
+/*
+#include "decentdb.h"
+*/
+import "C"
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+)
+
+// StorageState is a point-in-time view of a handle's database file and WAL.
+type StorageState struct {
+	Path     string `json:"path"`
+	PageSize uint32 `json:"page_size"`
+	// PageCount is the number of pages in the main database file.
+	PageCount    uint64 `json:"page_count"`
+	SchemaCookie uint32 `json:"schema_cookie"`
+	// WALEndLSN is the WAL position of the latest commit the handle sees.
+	WALEndLSN   uint64 `json:"wal_end_lsn"`
+	WALFileSize uint64 `json:"wal_file_size"`
+	WALPath     string `json:"wal_path"`
+	// LastCheckpointLSN is the WAL position the main file was last
+	// checkpointed to, as recorded in its header.
+	LastCheckpointLSN   uint64 `json:"last_checkpoint_lsn"`
+	ActiveReaders       uint64 `json:"active_readers"`
+	WALVersions         uint64 `json:"wal_versions"`
+	WALResidentVersions uint64 `json:"wal_resident_versions"`
+	WALOnDiskVersions   uint64 `json:"wal_on_disk_versions"`
+	WarningCount        uint64 `json:"warning_count"`
+	SharedWAL           bool   `json:"shared_wal"`
+	TablesInMemoryBytes uint64 `json:"tables_in_memory_bytes"`
+	RowsInMemoryCount   uint64 `json:"rows_in_memory_count"`
+	LoadedTableCount    uint64 `json:"loaded_table_count"`
+	DeferredTableCount  uint64 `json:"deferred_table_count"`
+}
+
+// StorageState returns the storage state of the handle.
+func (c *conn) StorageState() (*StorageState, error) {
+	if c.db == nil {
+		return nil, driver.ErrBadConn
+	}
+	var ptr *C.char
+	status := C.ddb_db_inspect_storage_state_json(c.db, &ptr)
+	if status != C.DDB_OK {
+		return nil, statusError(status, "")
+	}
+	defer freeAPIString(ptr)
+	var state StorageState
+	if err := json.Unmarshal([]byte(C.GoString(ptr)), &state); err != nil {
+		return nil, fmt.Errorf("decentdb: decode storage state: %w", err)
+	}
+	return &state, nil
+}
+
+// StorageState returns the page count, WAL position and size, and last
+// checkpoint of the database. database/sql users reach it through
+// sql.Conn.Raw.
+func (d *DB) StorageState() (*StorageState, error) {
+	if atomic.LoadUint32(&d.closed) != 0 {
+		return nil, driver.ErrBadConn
+	}
+	return d.c.StorageState()
+}
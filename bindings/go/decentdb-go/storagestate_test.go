@@ -0,0 +1,42 @@
+package decentdb
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestOpenDirect_StorageState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.ddb")
+	db, err := OpenDirect(path)
+	if err != nil {
+		t.Fatalf("OpenDirect failed: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec("CREATE TABLE t (id INT PRIMARY KEY)"); err != nil {
+		t.Fatal(err)
+	}
+	before, err := db.StorageState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before.Path != path || before.PageSize == 0 || before.WALEndLSN == 0 {
+		t.Fatalf("unexpected storage state %+v", before)
+	}
+	if err := db.Checkpoint(); err != nil {
+		t.Fatal(err)
+	}
+	after, err := db.StorageState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.LastCheckpointLSN < before.WALEndLSN {
+		t.Fatalf("checkpoint not reflected: last_checkpoint_lsn %d, wal_end_lsn was %d",
+			after.LastCheckpointLSN, before.WALEndLSN)
+	}
+
+	db.Close()
+	if _, err := db.StorageState(); err == nil {
+		t.Fatal("expected StorageState on a closed DB to fail")
+	}
+}
@@ -0,0 +1,12 @@
+package decentdb
+
+// WithTempDir keeps the rows of temporary tables in files under dir instead
+// of memory. Each connection writes its rows out between statements, reads
+// them back when the next statement starts, and removes the files when the
+// table is dropped or the connection closes. The temp_store=memory|file and
+// temp_dir DSN options do the same for sql.Open and take precedence.
+func WithTempDir(dir string) ConnectorOption {
+	return func(c *connector) {
+		c.tempDir = dir
+	}
+}
@@ -0,0 +1,151 @@
+package decentdb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTempTable_LivesWithItsConnection(t *testing.T) {
+	for _, query := range []string{"", "?shared_engine=true"} {
+		t.Run("dsn"+query, func(t *testing.T) {
+			db, err := sql.Open("decentdb", fmt.Sprintf("file:%s%s", filepath.Join(t.TempDir(), "etl.ddb"), query))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			db.SetMaxIdleConns(0)
+			if _, err := db.Exec("CREATE TABLE orders (id INT64 PRIMARY KEY, total DECIMAL(10,2))"); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := db.Exec("INSERT INTO orders VALUES (1, 10.50), (2, 99.00), (3, 5.25)"); err != nil {
+				t.Fatal(err)
+			}
+
+			ctx := context.Background()
+			staging, err := db.Conn(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, stmt := range []string{
+				"CREATE TEMP TABLE big_orders AS SELECT id, total FROM orders WHERE total > 10",
+				"CREATE TABLE order_report (id INT64 PRIMARY KEY, total DECIMAL(10,2))",
+				"INSERT INTO order_report SELECT id, total FROM big_orders",
+			} {
+				if _, err := staging.ExecContext(ctx, stmt); err != nil {
+					t.Fatalf("%s: %v", stmt, err)
+				}
+			}
+
+			other, err := db.Conn(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var n int
+			if err := other.QueryRowContext(ctx, "SELECT COUNT(*) FROM order_report").Scan(&n); err != nil || n != 2 {
+				t.Fatalf("order_report has %d rows, %v", n, err)
+			}
+			if _, err := other.ExecContext(ctx, "SELECT * FROM big_orders"); err == nil {
+				t.Fatal("temp table visible to another connection")
+			}
+			// Each connection may stage under the same name.
+			if _, err := other.ExecContext(ctx, "CREATE TEMP TABLE big_orders (id INT64)"); err != nil {
+				t.Fatal(err)
+			}
+			if err := staging.QueryRowContext(ctx, "SELECT COUNT(*) FROM big_orders").Scan(&n); err != nil || n != 2 {
+				t.Fatalf("staging big_orders has %d rows, %v", n, err)
+			}
+			other.Close()
+
+			// With no idle connections kept, releasing the connection closes
+			// it and drops its temp tables.
+			staging.Close()
+			fresh, err := db.Conn(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer fresh.Close()
+			if _, err := fresh.ExecContext(ctx, "SELECT * FROM big_orders"); err == nil {
+				t.Fatal("temp table survived its connection")
+			}
+		})
+	}
+}
+
+func TestTempTable_TempStore(t *testing.T) {
+	openDSN := func(dsn string) (*sql.DB, error) { return sql.Open("decentdb", dsn) }
+	openTempDir := func(path, dir string) (*sql.DB, error) {
+		connector, err := NewConnector(path, WithTempDir(dir))
+		if err != nil {
+			return nil, err
+		}
+		return sql.OpenDB(connector), nil
+	}
+	cases := []struct {
+		name      string
+		open      func(path, dir string) (*sql.DB, error)
+		wantFiles int
+	}{
+		{"memory", func(path, dir string) (*sql.DB, error) {
+			return openDSN(fmt.Sprintf("file:%s?temp_store=memory&temp_dir=%s", path, dir))
+		}, 0},
+		{"file", func(path, dir string) (*sql.DB, error) {
+			return openDSN(fmt.Sprintf("file:%s?temp_store=file&temp_dir=%s", path, dir))
+		}, 1},
+		{"WithTempDir", openTempDir, 1},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			spillDir := filepath.Join(dir, "spill")
+			if err := os.Mkdir(spillDir, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			spillFiles := func() int {
+				t.Helper()
+				entries, err := os.ReadDir(spillDir)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return len(entries)
+			}
+			db, err := tc.open(filepath.Join(dir, "etl.ddb"), spillDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			db.SetMaxIdleConns(0)
+
+			ctx := context.Background()
+			staging, err := db.Conn(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, stmt := range []string{
+				"CREATE TEMP TABLE staged (id INT64 PRIMARY KEY, note TEXT)",
+				"INSERT INTO staged VALUES (1, 'a'), (2, 'b'), (3, 'c')",
+				"DELETE FROM staged WHERE id = 2",
+			} {
+				if _, err := staging.ExecContext(ctx, stmt); err != nil {
+					t.Fatalf("%s: %v", stmt, err)
+				}
+			}
+			if got := spillFiles(); got != tc.wantFiles {
+				t.Fatalf("temp dir holds %d files, want %d", got, tc.wantFiles)
+			}
+			var n int
+			if err := staging.QueryRowContext(ctx, "SELECT COUNT(*) FROM staged").Scan(&n); err != nil || n != 2 {
+				t.Fatalf("staged has %d rows, %v", n, err)
+			}
+
+			// Closing the connection drops its temp tables and their files.
+			staging.Close()
+			if got := spillFiles(); got != 0 {
+				t.Fatalf("temp dir holds %d files after close, want 0", got)
+			}
+		})
+	}
+}
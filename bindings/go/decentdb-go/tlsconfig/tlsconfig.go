@@ -0,0 +1,297 @@
+// Package tlsconfig builds *tls.Config values for the DecentDB server
+// frontends and their clients from PEM files, and reloads the files when
+// they change so certificates can be rotated without a restart:
+//
+//	r, err := tlsconfig.Server{CertFile: "server.pem", KeyFile: "server.key"}.Load()
+//	srv := grpcserver.New(dbs, grpcserver.WithTLSConfig(r.Config()))
+//
+// Handshakes check the files for changes at most once per second. A file
+// that fails to load keeps the previous certificates in use; Err reports
+// the failure until a later load succeeds.
+//
+// Client certificates, and server certificates checked against a custom CA
+// file, are verified in VerifyConnection rather than by crypto/tls so the
+// CA bundle can be reloaded too. ConnectionState.VerifiedChains is
+// therefore empty on those connections.
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// checkInterval is how often handshakes look for changed files.
+var checkInterval = time.Second
+
+// Server describes the TLS setup of a server frontend.
+type Server struct {
+	// CertFile and KeyFile hold the PEM certificate chain and private key
+	// the server presents.
+	CertFile string
+	KeyFile  string
+	// ClientCAFile holds PEM CA certificates that client certificates are
+	// verified against. When it is empty, clients are not asked for a
+	// certificate.
+	ClientCAFile string
+	// RequireClientCert rejects clients that present no certificate. It
+	// needs ClientCAFile; without it, certificates are verified only when
+	// a client sends one.
+	RequireClientCert bool
+	// MinVersion is the lowest TLS version accepted. The default is TLS
+	// 1.2.
+	MinVersion uint16
+}
+
+// Client describes the TLS setup of a client of a server frontend.
+type Client struct {
+	// CAFile holds PEM CA certificates that the server certificate is
+	// verified against. When it is empty, the system roots are used.
+	CAFile string
+	// CertFile and KeyFile hold the PEM client certificate and private
+	// key, for servers that require client certificates.
+	CertFile string
+	KeyFile  string
+	// ServerName is the name the server certificate must be valid for.
+	ServerName string
+	// InsecureSkipVerify accepts any server certificate. Use it only
+	// against development servers.
+	InsecureSkipVerify bool
+	// MinVersion is the lowest TLS version offered. The default is TLS
+	// 1.2.
+	MinVersion uint16
+}
+
+// Load reads the server's files and returns a Reloader for them.
+func (c Server) Load() (*Reloader, error) {
+	if c.CertFile == "" || c.KeyFile == "" {
+		return nil, errors.New("tlsconfig: server needs CertFile and KeyFile")
+	}
+	if c.RequireClientCert && c.ClientCAFile == "" {
+		return nil, errors.New("tlsconfig: RequireClientCert needs ClientCAFile")
+	}
+	r := &Reloader{certFile: c.CertFile, keyFile: c.KeyFile, caFile: c.ClientCAFile}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	cfg := &tls.Config{
+		MinVersion:     minVersion(c.MinVersion),
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) { return r.certificate(), nil },
+	}
+	if c.ClientCAFile != "" {
+		cfg.ClientAuth = tls.RequestClientCert
+		if c.RequireClientCert {
+			cfg.ClientAuth = tls.RequireAnyClientCert
+		}
+		cfg.VerifyConnection = r.verifyClient
+	}
+	r.cfg = cfg
+	return r, nil
+}
+
+// Load reads the client's files and returns a Reloader for them.
+func (c Client) Load() (*Reloader, error) {
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return nil, errors.New("tlsconfig: client needs both CertFile and KeyFile, or neither")
+	}
+	r := &Reloader{certFile: c.CertFile, keyFile: c.KeyFile, caFile: c.CAFile}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	cfg := &tls.Config{
+		MinVersion:         minVersion(c.MinVersion),
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+	if c.CertFile != "" {
+		cfg.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return r.certificate(), nil
+		}
+	}
+	if c.CAFile != "" && !c.InsecureSkipVerify {
+		// crypto/tls would verify against a fixed pool; verify here
+		// instead so a reloaded CA file takes effect.
+		cfg.InsecureSkipVerify = true
+		cfg.VerifyConnection = r.verifyServer
+	}
+	r.cfg = cfg
+	return r, nil
+}
+
+func minVersion(v uint16) uint16 {
+	if v == 0 {
+		return tls.VersionTLS12
+	}
+	return v
+}
+
+// Reloader serves certificates loaded from files and reloads them when the
+// files change. It is safe for concurrent use.
+type Reloader struct {
+	certFile string
+	keyFile  string
+	caFile   string
+	cfg      *tls.Config
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	pool    *x509.CertPool
+	stamps  []fileStamp
+	checked time.Time
+	err     error
+}
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// Config returns the TLS configuration. Every call returns the same value,
+// which picks up reloaded certificates on later handshakes; clone it
+// before changing fields.
+func (r *Reloader) Config() *tls.Config {
+	return r.cfg
+}
+
+// Reload reads the files now. On failure the previous certificates stay in
+// use and the error is returned.
+func (r *Reloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checked = time.Now()
+	r.err = r.load()
+	return r.err
+}
+
+// Err returns the error of the most recent load, or nil once a load
+// succeeds.
+func (r *Reloader) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+func (r *Reloader) files() []string {
+	var files []string
+	for _, f := range []string{r.certFile, r.keyFile, r.caFile} {
+		if f != "" {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+// load reads every file. r.mu is held.
+func (r *Reloader) load() error {
+	stamps, err := statFiles(r.files())
+	if err != nil {
+		return err
+	}
+	var cert *tls.Certificate
+	if r.certFile != "" {
+		c, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+		if err != nil {
+			return fmt.Errorf("tlsconfig: %w", err)
+		}
+		cert = &c
+	}
+	var pool *x509.CertPool
+	if r.caFile != "" {
+		pem, err := os.ReadFile(r.caFile)
+		if err != nil {
+			return fmt.Errorf("tlsconfig: %w", err)
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("tlsconfig: no certificates in %s", r.caFile)
+		}
+	}
+	r.cert, r.pool, r.stamps = cert, pool, stamps
+	return nil
+}
+
+func statFiles(files []string) ([]fileStamp, error) {
+	stamps := make([]fileStamp, len(files))
+	for i, f := range files {
+		fi, err := os.Stat(f)
+		if err != nil {
+			return nil, fmt.Errorf("tlsconfig: %w", err)
+		}
+		stamps[i] = fileStamp{fi.ModTime(), fi.Size()}
+	}
+	return stamps, nil
+}
+
+// refresh reloads the files if they changed since the last load, checking
+// at most once per checkInterval, and returns the current certificates.
+func (r *Reloader) refresh() (*tls.Certificate, *x509.CertPool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if now := time.Now(); now.Sub(r.checked) >= checkInterval {
+		r.checked = now
+		stamps, err := statFiles(r.files())
+		switch {
+		case err != nil:
+			r.err = err
+		case !equalStamps(stamps, r.stamps):
+			r.err = r.load()
+		default:
+			r.err = nil
+		}
+	}
+	return r.cert, r.pool
+}
+
+func equalStamps(a, b []fileStamp) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].modTime.Equal(b[i].modTime) || a[i].size != b[i].size {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *Reloader) certificate() *tls.Certificate {
+	cert, _ := r.refresh()
+	return cert
+}
+
+func (r *Reloader) verifyClient(cs tls.ConnectionState) error {
+	if len(cs.PeerCertificates) == 0 {
+		// ClientAuth already rejected the handshake if a certificate
+		// was required.
+		return nil
+	}
+	_, pool := r.refresh()
+	return verifyChain(cs.PeerCertificates, x509.VerifyOptions{
+		Roots:     pool,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+}
+
+func (r *Reloader) verifyServer(cs tls.ConnectionState) error {
+	_, pool := r.refresh()
+	return verifyChain(cs.PeerCertificates, x509.VerifyOptions{
+		Roots:   pool,
+		DNSName: cs.ServerName,
+	})
+}
+
+func verifyChain(certs []*x509.Certificate, opts x509.VerifyOptions) error {
+	if len(certs) == 0 {
+		return errors.New("tlsconfig: peer sent no certificate")
+	}
+	opts.Intermediates = x509.NewCertPool()
+	for _, c := range certs[1:] {
+		opts.Intermediates.AddCert(c)
+	}
+	_, err := certs[0].Verify(opts)
+	return err
+}
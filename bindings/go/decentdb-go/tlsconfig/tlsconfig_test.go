@@ -0,0 +1,141 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/sphildreth/decentdb-go/internal/testcerts"
+)
+
+// handshake runs a TLS handshake over loopback TCP and returns the server
+// and client results.
+func handshake(t *testing.T, server, client *tls.Config) (serverErr, clientErr error) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	done := make(chan error, 1)
+	go func() {
+		nc, err := l.Accept()
+		if err != nil {
+			done <- err
+			return
+		}
+		defer nc.Close()
+		done <- tls.Server(nc, server).Handshake()
+	}()
+	nc, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nc.Close()
+	clientErr = tls.Client(nc, client).Handshake()
+	return <-done, clientErr
+}
+
+func mustLoad(t *testing.T, load func() (*Reloader, error)) *Reloader {
+	t.Helper()
+	r, err := load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestClientCertificates(t *testing.T) {
+	dir := t.TempDir()
+	ca := testcerts.NewCA(t, dir, "ca")
+	other := testcerts.NewCA(t, dir, "other")
+	serverPair := ca.Server(t, "server")
+	clientPair := ca.Client(t, "client", "alice")
+	strangerPair := other.Client(t, "stranger", "mallory")
+
+	srv := mustLoad(t, Server{
+		CertFile: serverPair.CertFile, KeyFile: serverPair.KeyFile,
+		ClientCAFile: ca.File, RequireClientCert: true,
+	}.Load)
+	client := func(p testcerts.Pair) *tls.Config {
+		return mustLoad(t, Client{CAFile: ca.File, CertFile: p.CertFile, KeyFile: p.KeyFile, ServerName: "localhost"}.Load).Config()
+	}
+
+	if serverErr, clientErr := handshake(t, srv.Config(), client(clientPair)); serverErr != nil || clientErr != nil {
+		t.Fatalf("trusted client: server %v, client %v", serverErr, clientErr)
+	}
+	if serverErr, _ := handshake(t, srv.Config(), client(strangerPair)); serverErr == nil {
+		t.Fatal("server accepted a certificate from an untrusted CA")
+	}
+	if serverErr, _ := handshake(t, srv.Config(), client(testcerts.Pair{})); serverErr == nil {
+		t.Fatal("server accepted a client without a certificate")
+	}
+
+	optional := mustLoad(t, Server{CertFile: serverPair.CertFile, KeyFile: serverPair.KeyFile, ClientCAFile: ca.File}.Load)
+	if serverErr, clientErr := handshake(t, optional.Config(), client(testcerts.Pair{})); serverErr != nil || clientErr != nil {
+		t.Fatalf("optional client certificate: server %v, client %v", serverErr, clientErr)
+	}
+	if serverErr, _ := handshake(t, optional.Config(), client(strangerPair)); serverErr == nil {
+		t.Fatal("optional mode accepted a certificate from an untrusted CA")
+	}
+
+	wrongName := mustLoad(t, Client{CAFile: ca.File, ServerName: "db.example.com"}.Load)
+	if _, clientErr := handshake(t, optional.Config(), wrongName.Config()); clientErr == nil {
+		t.Fatal("client accepted a certificate for another name")
+	}
+}
+
+func TestReload(t *testing.T) {
+	old := checkInterval
+	checkInterval = 0
+	t.Cleanup(func() { checkInterval = old })
+
+	dir := t.TempDir()
+	ca1 := testcerts.NewCA(t, dir, "ca1")
+	ca2 := testcerts.NewCA(t, dir, "ca2")
+	serverPair := ca1.Server(t, "server")
+	srv := mustLoad(t, Server{CertFile: serverPair.CertFile, KeyFile: serverPair.KeyFile}.Load)
+	trustsCA2 := mustLoad(t, Client{CAFile: ca2.File, ServerName: "localhost"}.Load)
+
+	if _, clientErr := handshake(t, srv.Config(), trustsCA2.Config()); clientErr == nil {
+		t.Fatal("client trusted a certificate from another CA")
+	}
+	// Rotate the server certificate in place.
+	ca2.Server(t, "server")
+	if serverErr, clientErr := handshake(t, srv.Config(), trustsCA2.Config()); serverErr != nil || clientErr != nil {
+		t.Fatalf("after rotation: server %v, client %v", serverErr, clientErr)
+	}
+
+	// A broken file keeps the previous certificate in use.
+	if err := os.WriteFile(serverPair.KeyFile, []byte("not a key"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if serverErr, clientErr := handshake(t, srv.Config(), trustsCA2.Config()); serverErr != nil || clientErr != nil {
+		t.Fatalf("after a broken rotation: server %v, client %v", serverErr, clientErr)
+	}
+	if srv.Err() == nil {
+		t.Fatal("Err() = nil after a broken rotation")
+	}
+	ca2.Server(t, "server")
+	if err := srv.Reload(); err != nil || srv.Err() != nil {
+		t.Fatalf("Reload() = %v, Err() = %v", err, srv.Err())
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	dir := t.TempDir()
+	ca := testcerts.NewCA(t, dir, "ca")
+	pair := ca.Server(t, "server")
+	for name, load := range map[string]func() (*Reloader, error){
+		"server without key":      Server{CertFile: pair.CertFile}.Load,
+		"require without CA":      Server{CertFile: pair.CertFile, KeyFile: pair.KeyFile, RequireClientCert: true}.Load,
+		"missing CA file":         Server{CertFile: pair.CertFile, KeyFile: pair.KeyFile, ClientCAFile: dir + "/missing.pem"}.Load,
+		"key as CA file":          Client{CAFile: pair.KeyFile}.Load,
+		"client cert without key": Client{CertFile: pair.CertFile}.Load,
+	} {
+		if _, err := load(); err == nil {
+			t.Errorf("%s: Load succeeded", name)
+		}
+	}
+}
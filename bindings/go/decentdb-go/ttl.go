@@ -0,0 +1,131 @@
+package decentdb
+
+/*
+#include "decentdb.h"
+*/
+import "C"
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultTTLSweepInterval  = time.Minute
+	defaultTTLSweepBatchSize = 1000
+)
+
+// TTLSweepOptions configures SweepTTL. The zero value sweeps every minute in
+// batches of 1000 rows per table.
+type TTLSweepOptions struct {
+	// Interval is the delay between sweeps.
+	Interval time.Duration
+	// BatchSize bounds the rows deleted from each table by one DELETE, which
+	// bounds how long a sweep holds the writer.
+	BatchSize int
+}
+
+// SweepExpiredRows deletes up to about batchSize expired rows from each table
+// declared WITH (ttl_column = ...) and returns the number deleted. A row
+// expires once its TTL column is at or before the current time.
+func (c *conn) SweepExpiredRows(batchSize int) (int64, error) {
+	if c.db == nil {
+		return 0, driver.ErrBadConn
+	}
+	var deleted C.uint64_t
+	status := C.ddb_db_sweep_expired_rows(c.db, C.size_t(batchSize), &deleted)
+	if status != C.DDB_OK {
+		return 0, statusError(status, "")
+	}
+	return int64(deleted), nil
+}
+
+// SweepExpiredRows deletes up to about batchSize expired rows from each table
+// declared WITH (ttl_column = ...) and returns the number deleted.
+func (d *DB) SweepExpiredRows(batchSize int) (int64, error) {
+	if atomic.LoadUint32(&d.closed) != 0 {
+		return 0, driver.ErrBadConn
+	}
+	return d.c.SweepExpiredRows(batchSize)
+}
+
+// SweepTTL deletes expired rows from the tables of db declared
+// WITH (ttl_column = ...) every opts.Interval until ctx is done, then
+// returns ctx.Err(). Each sweep deletes batch after batch until none are
+// left, so a backlog drains in one sweep without one long write. Retryable
+// errors, such as a busy writer, are logged and the sweep is tried again at
+// the next interval; other errors are returned. opts may be nil.
+//
+// Run it in its own goroutine:
+//
+//	go decentdb.SweepTTL(ctx, db, &decentdb.TTLSweepOptions{Interval: 30 * time.Second})
+func SweepTTL(ctx context.Context, db *sql.DB, opts *TTLSweepOptions) error {
+	var o TTLSweepOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.Interval <= 0 {
+		o.Interval = defaultTTLSweepInterval
+	}
+	if o.BatchSize <= 0 {
+		o.BatchSize = defaultTTLSweepBatchSize
+	}
+
+	ticker := time.NewTicker(o.Interval)
+	defer ticker.Stop()
+	for {
+		deleted, err := sweepTTLOnce(ctx, db, o.BatchSize)
+		switch {
+		case err == nil:
+			logDebug(ctx, "decentdb: ttl sweep", slog.Int64("deleted", deleted))
+		case ctx.Err() != nil:
+			return ctx.Err()
+		case IsRetryable(err):
+			logDebug(ctx, "decentdb: ttl sweep deferred", errAttr(err))
+		default:
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// sweepTTLOnce sweeps batches on one pooled connection until a batch
+// deletes nothing.
+func sweepTTLOnce(ctx context.Context, db *sql.DB, batchSize int) (int64, error) {
+	sqlConn, err := db.Conn(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer sqlConn.Close()
+	var total int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+		var deleted int64
+		err := sqlConn.Raw(func(driverConn any) error {
+			c, ok := driverConn.(*conn)
+			if !ok {
+				return errors.New("decentdb: SweepTTL requires a decentdb connection")
+			}
+			var err error
+			deleted, err = c.SweepExpiredRows(batchSize)
+			return err
+		})
+		if err != nil {
+			return total, err
+		}
+		total += deleted
+		if deleted == 0 {
+			return total, nil
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package decentdb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSweepTTL_DeletesExpiredRows(t *testing.T) {
+	db, err := sql.Open("decentdb", fmt.Sprintf("file:%s", filepath.Join(t.TempDir(), "ttl.ddb")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("CREATE TABLE sessions (id INT PRIMARY KEY, expires_at TIMESTAMP) WITH (ttl_column = expires_at)"); err != nil {
+		t.Fatal(err)
+	}
+	past := time.Now().Add(-time.Hour).UTC()
+	future := time.Now().Add(time.Hour).UTC()
+	for i := 1; i <= 25; i++ {
+		if _, err := db.Exec("INSERT INTO sessions VALUES ($1, $2)", i, past); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := db.Exec("INSERT INTO sessions VALUES ($1, $2)", 100, future); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("INSERT INTO sessions VALUES ($1, NULL)", 101); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	err = SweepTTL(ctx, db, &TTLSweepOptions{Interval: 50 * time.Millisecond, BatchSize: 10})
+	if err != context.DeadlineExceeded {
+		t.Fatalf("SweepTTL returned %v", err)
+	}
+
+	var n int
+	if err := db.QueryRow("SELECT COUNT(*) FROM sessions").Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Fatalf("%d rows left after sweep, want 2", n)
+	}
+}
+
+func TestDBSweepExpiredRows(t *testing.T) {
+	db, err := OpenDirect(filepath.Join(t.TempDir(), "direct.ddb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("CREATE TABLE cache (k TEXT PRIMARY KEY, expires_at TIMESTAMP) WITH (ttl_column = expires_at)"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := db.Exec("INSERT INTO cache VALUES ($1, $2)", fmt.Sprint(i), time.Now().Add(-time.Minute).UTC()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	deleted, err := db.SweepExpiredRows(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 2 {
+		t.Fatalf("first batch deleted %d rows, want 2", deleted)
+	}
+	if deleted, err = db.SweepExpiredRows(2); err != nil || deleted != 1 {
+		t.Fatalf("second batch deleted %d rows (%v), want 1", deleted, err)
+	}
+	if _, err := db.SweepExpiredRows(0); err == nil {
+		t.Fatal("batch size 0 accepted")
+	}
+}
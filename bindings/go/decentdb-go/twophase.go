@@ -0,0 +1,114 @@
+package decentdb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"strings"
+	"sync/atomic"
+)
+
+// Tx is an explicit transaction on a direct DB handle. Statements run
+// through the DB's own methods, such as DB.Exec and DB.Rows, take part in
+// it until it ends.
+type Tx struct {
+	d    *DB
+	tx   driver.Tx
+	done bool
+}
+
+// Begin starts an explicit transaction on the handle.
+func (d *DB) Begin() (*Tx, error) {
+	if atomic.LoadUint32(&d.closed) != 0 {
+		return nil, driver.ErrBadConn
+	}
+	tx, err := d.c.BeginTx(context.Background(), driver.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{d: d, tx: tx}, nil
+}
+
+// Exec runs a statement inside the transaction and returns the number of
+// affected rows.
+func (t *Tx) Exec(query string, args ...driver.Value) (int64, error) {
+	if t.done {
+		return 0, sql.ErrTxDone
+	}
+	return t.d.Exec(query, args...)
+}
+
+// Commit commits the transaction.
+func (t *Tx) Commit() error {
+	if t.done {
+		return sql.ErrTxDone
+	}
+	t.done = true
+	return t.tx.Commit()
+}
+
+// Rollback rolls the transaction back.
+func (t *Tx) Rollback() error {
+	if t.done {
+		return sql.ErrTxDone
+	}
+	t.done = true
+	return t.tx.Rollback()
+}
+
+// PrepareTwoPhase ends the transaction with PREPARE TRANSACTION gid, the
+// first phase of a two-phase commit: the changes are recorded in the
+// database under gid and the handle returns to autocommit. Finish with
+// DB.CommitPrepared or DB.RollbackPrepared on any handle, also after the
+// database is closed and reopened.
+//
+// Until then, the tables the transaction wrote stay reserved: other writes
+// to them wait up to the busy timeout and then fail with ErrBusy.
+func (t *Tx) PrepareTwoPhase(gid string) error {
+	if t.done {
+		return sql.ErrTxDone
+	}
+	err := t.d.c.execSessionSQL("PREPARE TRANSACTION " + quoteLiteral(gid))
+	if err == nil || !t.d.c.InTransaction() {
+		// A conflict rolls the transaction back as well.
+		t.done = true
+		t.d.c.releaseWriter()
+	}
+	return err
+}
+
+// CommitPrepared commits the transaction prepared as gid.
+func (d *DB) CommitPrepared(gid string) error {
+	if atomic.LoadUint32(&d.closed) != 0 {
+		return driver.ErrBadConn
+	}
+	return d.c.execSessionSQL("COMMIT PREPARED " + quoteLiteral(gid))
+}
+
+// RollbackPrepared discards the transaction prepared as gid.
+func (d *DB) RollbackPrepared(gid string) error {
+	if atomic.LoadUint32(&d.closed) != 0 {
+		return driver.ErrBadConn
+	}
+	return d.c.execSessionSQL("ROLLBACK PREPARED " + quoteLiteral(gid))
+}
+
+// quoteLiteral returns s as a SQL string literal.
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// isTwoPhaseControlQuery reports whether query is PREPARE TRANSACTION,
+// COMMIT PREPARED, or ROLLBACK PREPARED, which the engine runs only as
+// immediate statements.
+func isTwoPhaseControlQuery(query string, args []driver.NamedValue) bool {
+	if len(args) != 0 {
+		return false
+	}
+	fields := strings.Fields(query)
+	if len(fields) < 3 {
+		return false
+	}
+	command := strings.ToUpper(fields[0] + " " + fields[1])
+	return command == "PREPARE TRANSACTION" || command == "COMMIT PREPARED" || command == "ROLLBACK PREPARED"
+}
@@ -0,0 +1,137 @@
+package decentdb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestTwoPhaseCommit_DirectAPI(t *testing.T) {
+	db, err := OpenDirect(filepath.Join(t.TempDir(), "twophase.ddb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("CREATE TABLE outbox (id INT64 PRIMARY KEY)"); err != nil {
+		t.Fatal(err)
+	}
+	count := func() int64 {
+		t.Helper()
+		n, err := db.QueryOnBranchInt64("main", "SELECT COUNT(*) FROM outbox")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Exec("INSERT INTO outbox VALUES ($1)", int64(1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.PrepareTwoPhase("order's 42"); err != nil {
+		t.Fatal(err)
+	}
+	if db.InTransaction() || count() != 0 {
+		t.Fatalf("prepared transaction should be detached and invisible")
+	}
+	if err := tx.Commit(); !errors.Is(err, sql.ErrTxDone) {
+		t.Fatalf("Commit after PrepareTwoPhase = %v, want ErrTxDone", err)
+	}
+	if err := db.CommitPrepared("order's 42"); err != nil {
+		t.Fatal(err)
+	}
+	if count() != 1 {
+		t.Fatalf("committed prepared transaction is not visible")
+	}
+
+	tx, err = db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Exec("INSERT INTO outbox VALUES (2)"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.PrepareTwoPhase("undo"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.RollbackPrepared("undo"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.CommitPrepared("undo"); err == nil {
+		t.Fatal("committing a rolled-back prepared transaction succeeded")
+	}
+	if count() != 1 {
+		t.Fatalf("rolled-back prepared transaction is visible")
+	}
+}
+
+func TestTwoPhaseCommit_SurvivesReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "twophase.ddb")
+	db, err := OpenDirect(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("CREATE TABLE outbox (id INT64 PRIMARY KEY)"); err != nil {
+		t.Fatal(err)
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Exec("INSERT INTO outbox VALUES (1)"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.PrepareTwoPhase("restart"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err = OpenDirect(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err := db.CommitPrepared("restart"); err != nil {
+		t.Fatal(err)
+	}
+	n, err := db.QueryOnBranchInt64("main", "SELECT COUNT(*) FROM outbox")
+	if err != nil || n != 1 {
+		t.Fatalf("count = %d, %v", n, err)
+	}
+}
+
+func TestTwoPhaseCommit_SQLOnConn(t *testing.T) {
+	db, err := sql.Open("decentdb", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	for _, stmt := range []string{
+		"CREATE TABLE t (id INT64 PRIMARY KEY)",
+		"BEGIN",
+		"INSERT INTO t VALUES (1)",
+		"PREPARE TRANSACTION 'g1'",
+		"COMMIT PREPARED 'g1'",
+	} {
+		if _, err := conn.ExecContext(ctx, stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	var n int64
+	if err := conn.QueryRowContext(ctx, "SELECT COUNT(*) FROM t").Scan(&n); err != nil || n != 1 {
+		t.Fatalf("count = %d, %v", n, err)
+	}
+}
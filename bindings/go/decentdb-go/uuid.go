@@ -0,0 +1,70 @@
+package decentdb
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// UUID is a 16-byte UUID value. It binds as a native UUID parameter and is
+// produced when a UUID column is scanned into an interface{} destination.
+type UUID [16]byte
+
+// ParseUUID parses the canonical 8-4-4-4-12 form, with or without hyphens or
+// surrounding braces.
+func ParseUUID(s string) (UUID, error) {
+	var u UUID
+	trimmed := strings.TrimSuffix(strings.TrimPrefix(s, "{"), "}")
+	compact := strings.ReplaceAll(trimmed, "-", "")
+	if len(compact) != 32 {
+		return u, fmt.Errorf("invalid UUID %q", s)
+	}
+	if _, err := hex.Decode(u[:], []byte(compact)); err != nil {
+		return u, fmt.Errorf("invalid UUID %q: %w", s, err)
+	}
+	return u, nil
+}
+
+// String returns the canonical lower-case hyphenated form.
+func (u UUID) String() string {
+	var buf [36]byte
+	hex.Encode(buf[0:8], u[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], u[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], u[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], u[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], u[10:])
+	return string(buf[:])
+}
+
+// Scan implements sql.Scanner for 16-byte values and UUID strings.
+func (u *UUID) Scan(src any) error {
+	switch v := src.(type) {
+	case UUID:
+		*u = v
+		return nil
+	case []byte:
+		if len(v) == 16 {
+			copy(u[:], v)
+			return nil
+		}
+		parsed, err := ParseUUID(string(v))
+		if err != nil {
+			return err
+		}
+		*u = parsed
+		return nil
+	case string:
+		parsed, err := ParseUUID(v)
+		if err != nil {
+			return err
+		}
+		*u = parsed
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into decentdb.UUID", src)
+	}
+}
@@ -0,0 +1,222 @@
+package decentdb
+
+/*
+#include "decentdb.h"
+#include <stdlib.h>
+
+extern int32_t goVFSOpen(void *user_data, char *path, uint32_t mode, uint32_t kind, uint64_t *out_file);
+extern int32_t goVFSExists(void *user_data, char *path, uint8_t *out_exists);
+extern int32_t goVFSRemove(void *user_data, char *path);
+extern int32_t goVFSReadAt(void *user_data, uint64_t file, uint64_t offset, uint8_t *buf, size_t len, size_t *out_read);
+extern int32_t goVFSWriteAt(void *user_data, uint64_t file, uint64_t offset, uint8_t *buf, size_t len, size_t *out_written);
+extern int32_t goVFSSync(void *user_data, uint64_t file, uint8_t metadata);
+extern int32_t goVFSSize(void *user_data, uint64_t file, uint64_t *out_size);
+extern int32_t goVFSTruncate(void *user_data, uint64_t file, uint64_t len);
+extern int32_t goVFSLock(void *user_data, uint64_t file, uint64_t offset, uint64_t len, uint8_t exclusive, uint8_t *out_acquired);
+extern int32_t goVFSUnlock(void *user_data, uint64_t file, uint64_t offset, uint64_t len);
+extern void goVFSClose(void *user_data, uint64_t file);
+
+static int32_t ddb_go_vfs_open(void *u, const char *path, uint32_t mode, uint32_t kind, uint64_t *out_file) {
+	return goVFSOpen(u, (char *)path, mode, kind, out_file);
+}
+
+static int32_t ddb_go_vfs_exists(void *u, const char *path, uint8_t *out_exists) {
+	return goVFSExists(u, (char *)path, out_exists);
+}
+
+static int32_t ddb_go_vfs_remove(void *u, const char *path) {
+	return goVFSRemove(u, (char *)path);
+}
+
+static int32_t ddb_go_vfs_write_at(void *u, uint64_t file, uint64_t offset, const uint8_t *buf, size_t len, size_t *out_written) {
+	return goVFSWriteAt(u, file, offset, (uint8_t *)buf, len, out_written);
+}
+
+static void ddb_go_vfs_methods(ddb_vfs_methods_t *m, void *user_data) {
+	m->user_data = user_data;
+	m->open = ddb_go_vfs_open;
+	m->exists = ddb_go_vfs_exists;
+	m->remove = ddb_go_vfs_remove;
+	m->read_at = goVFSReadAt;
+	m->write_at = ddb_go_vfs_write_at;
+	m->sync = goVFSSync;
+	m->size = goVFSSize;
+	m->truncate = goVFSTruncate;
+	m->lock = goVFSLock;
+	m->unlock = goVFSUnlock;
+	m->close = goVFSClose;
+}
+*/
+import "C"
+import (
+	"errors"
+	"fmt"
+	"io"
+	"runtime/cgo"
+	"strings"
+	"sync"
+	"unsafe"
+)
+
+// VFS is a filesystem the engine routes database, WAL, and sidecar file I/O
+// through instead of the OS, so a database can live in memory, inside an
+// encrypted container, or in remote storage without engine changes.
+// Register one with RegisterVFS and select it with the vfs=<name> DSN
+// option. Names passed to a VFS are the DSN path and the engine's sidecar
+// paths derived from it (such as path + ".wal"), unchanged.
+//
+// Methods may be called from any goroutine, concurrently.
+type VFS interface {
+	// Open opens name. flag is os.O_RDWR combined with os.O_CREATE, and
+	// with os.O_EXCL when the file must not already exist. Open should
+	// return an error wrapping fs.ErrNotExist or fs.ErrExist where those
+	// apply, since the engine tells a missing file from a broken one.
+	Open(name string, flag int) (VFSFile, error)
+	// Exists reports whether name exists.
+	Exists(name string) (bool, error)
+	// Remove deletes name.
+	Remove(name string) error
+}
+
+// VFSFile is a file opened by a VFS.
+type VFSFile interface {
+	// ReadAt follows io.ReaderAt; a short read at end of file may return
+	// io.EOF alongside the bytes read.
+	io.ReaderAt
+	io.WriterAt
+	// Sync makes written data durable. metadata is true when the file's
+	// size must be durable too.
+	Sync(metadata bool) error
+	// Size returns the file's current length.
+	Size() (int64, error)
+	// Truncate sets the file's length, zero-filling when it grows.
+	Truncate(size int64) error
+	// Lock tries to take a shared or exclusive lock on a byte range
+	// without blocking, returning false if another holder conflicts. The
+	// engine uses these to coordinate processes sharing a database; a VFS
+	// only ever used by one process may grant every lock.
+	Lock(off, n int64, exclusive bool) (bool, error)
+	// Unlock releases a range taken by Lock.
+	Unlock(off, n int64) error
+	Close() error
+}
+
+// registeredVFS is the per-registration state a VFS's callbacks reach
+// through their user data pointer. Its handle is never deleted, because
+// databases opened with the VFS keep using it after UnregisterVFS.
+type registeredVFS struct {
+	vfs   VFS
+	mu    sync.Mutex
+	files map[uint64]VFSFile
+	next  uint64
+}
+
+// RegisterVFS makes vfs available to databases opened with the vfs=<name>
+// DSN option, replacing any VFS already registered under name. Databases
+// already open keep the VFS they were opened with.
+func RegisterVFS(name string, vfs VFS) error {
+	if vfs == nil {
+		return errors.New("decentdb: RegisterVFS with nil VFS")
+	}
+	if name == "" || strings.ContainsAny(name, " ,;=") {
+		return fmt.Errorf("decentdb: invalid VFS name %q", name)
+	}
+	reg := &registeredVFS{vfs: vfs, files: map[uint64]VFSFile{}}
+	handle := C.malloc(C.size_t(unsafe.Sizeof(C.uintptr_t(0))))
+	*(*C.uintptr_t)(handle) = C.uintptr_t(cgo.NewHandle(reg))
+
+	var methods C.ddb_vfs_methods_t
+	C.ddb_go_vfs_methods(&methods, handle)
+	cName := C.CString(name)
+	defer C.free(unsafe.Pointer(cName))
+	if status := C.ddb_vfs_register(cName, &methods); status != C.DDB_OK {
+		cgo.Handle(*(*C.uintptr_t)(handle)).Delete()
+		C.free(handle)
+		return statusError(status, "")
+	}
+	return nil
+}
+
+// UnregisterVFS removes the VFS registered under name, so later opens
+// naming it fail. Databases already open with it keep working.
+func UnregisterVFS(name string) error {
+	cName := C.CString(name)
+	defer C.free(unsafe.Pointer(cName))
+	if status := C.ddb_vfs_unregister(cName); status != C.DDB_OK {
+		return statusError(status, "")
+	}
+	return nil
+}
+
+func (r *registeredVFS) add(f VFSFile) uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.next++
+	r.files[r.next] = f
+	return r.next
+}
+
+func (r *registeredVFS) file(id uint64) (VFSFile, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f, ok := r.files[id]
+	return f, ok
+}
+
+func (r *registeredVFS) remove(id uint64) (VFSFile, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f, ok := r.files[id]
+	delete(r.files, id)
+	return f, ok
+}
+
+// memFile is a VFSFile held in memory.
+type memFile struct {
+	mu   sync.RWMutex
+	data []byte
+}
+
+func (f *memFile) ReadAt(p []byte, off int64) (int, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if off >= int64(len(f.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, f.data[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (f *memFile) WriteAt(p []byte, off int64) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if end := off + int64(len(p)); end > int64(len(f.data)) {
+		f.data = append(f.data, make([]byte, end-int64(len(f.data)))...)
+	}
+	return copy(f.data[off:], p), nil
+}
+
+func (f *memFile) Truncate(size int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if size <= int64(len(f.data)) {
+		f.data = f.data[:size]
+	} else {
+		f.data = append(f.data, make([]byte, size-int64(len(f.data)))...)
+	}
+	return nil
+}
+
+func (f *memFile) Size() (int64, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return int64(len(f.data)), nil
+}
+
+func (f *memFile) Sync(bool) error                       { return nil }
+func (f *memFile) Lock(int64, int64, bool) (bool, error) { return true, nil }
+func (f *memFile) Unlock(int64, int64) error             { return nil }
+func (f *memFile) Close() error                          { return nil }
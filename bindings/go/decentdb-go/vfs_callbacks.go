@@ -0,0 +1,222 @@
+package decentdb
+
+/*
+#include <stddef.h>
+#include <stdint.h>
+*/
+import "C"
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"runtime/cgo"
+	"unsafe"
+)
+
+// Status codes the engine's ddb_vfs_methods_t callbacks return.
+const (
+	vfsOK          = 0
+	vfsErrIO       = 1
+	vfsErrNotFound = 2
+	vfsErrExists   = 3
+)
+
+// Open modes the engine passes to the open callback.
+const (
+	vfsModeCreateNew    = 0
+	vfsModeOpenExisting = 1
+)
+
+func vfsFromUserData(userData unsafe.Pointer) *registeredVFS {
+	return cgo.Handle(*(*C.uintptr_t)(userData)).Value().(*registeredVFS)
+}
+
+// vfsStatus maps a Go error onto a VFS callback status code.
+func vfsStatus(err error) C.int32_t {
+	switch {
+	case err == nil:
+		return vfsOK
+	case errors.Is(err, fs.ErrNotExist):
+		return vfsErrNotFound
+	case errors.Is(err, fs.ErrExist):
+		return vfsErrExists
+	default:
+		return vfsErrIO
+	}
+}
+
+// vfsCall runs fn, turning a panic in user VFS code into an I/O error
+// rather than letting it unwind through the engine.
+func vfsCall(fn func() error) (status C.int32_t) {
+	defer func() {
+		if recover() != nil {
+			status = vfsErrIO
+		}
+	}()
+	return vfsStatus(fn())
+}
+
+func vfsFile(reg *registeredVFS, id C.uint64_t) (VFSFile, error) {
+	f, ok := reg.file(uint64(id))
+	if !ok {
+		return nil, errors.New("decentdb: unknown VFS file")
+	}
+	return f, nil
+}
+
+//export goVFSOpen
+func goVFSOpen(userData unsafe.Pointer, path *C.char, mode C.uint32_t, kind C.uint32_t, outFile *C.uint64_t) C.int32_t {
+	reg := vfsFromUserData(userData)
+	return vfsCall(func() error {
+		flag := os.O_RDWR | os.O_CREATE
+		switch mode {
+		case vfsModeCreateNew:
+			flag |= os.O_EXCL
+		case vfsModeOpenExisting:
+			flag = os.O_RDWR
+		}
+		f, err := reg.vfs.Open(C.GoString(path), flag)
+		if err != nil {
+			return err
+		}
+		*outFile = C.uint64_t(reg.add(f))
+		return nil
+	})
+}
+
+//export goVFSExists
+func goVFSExists(userData unsafe.Pointer, path *C.char, outExists *C.uint8_t) C.int32_t {
+	reg := vfsFromUserData(userData)
+	return vfsCall(func() error {
+		exists, err := reg.vfs.Exists(C.GoString(path))
+		if err != nil {
+			return err
+		}
+		*outExists = 0
+		if exists {
+			*outExists = 1
+		}
+		return nil
+	})
+}
+
+//export goVFSRemove
+func goVFSRemove(userData unsafe.Pointer, path *C.char) C.int32_t {
+	reg := vfsFromUserData(userData)
+	return vfsCall(func() error {
+		return reg.vfs.Remove(C.GoString(path))
+	})
+}
+
+//export goVFSReadAt
+func goVFSReadAt(userData unsafe.Pointer, id C.uint64_t, offset C.uint64_t, buf *C.uint8_t, n C.size_t, outRead *C.size_t) C.int32_t {
+	reg := vfsFromUserData(userData)
+	return vfsCall(func() error {
+		f, err := vfsFile(reg, id)
+		if err != nil {
+			return err
+		}
+		read, err := f.ReadAt(unsafe.Slice((*byte)(buf), int(n)), int64(offset))
+		*outRead = C.size_t(read)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		return err
+	})
+}
+
+//export goVFSWriteAt
+func goVFSWriteAt(userData unsafe.Pointer, id C.uint64_t, offset C.uint64_t, buf *C.uint8_t, n C.size_t, outWritten *C.size_t) C.int32_t {
+	reg := vfsFromUserData(userData)
+	return vfsCall(func() error {
+		f, err := vfsFile(reg, id)
+		if err != nil {
+			return err
+		}
+		written, err := f.WriteAt(unsafe.Slice((*byte)(buf), int(n)), int64(offset))
+		*outWritten = C.size_t(written)
+		return err
+	})
+}
+
+//export goVFSSync
+func goVFSSync(userData unsafe.Pointer, id C.uint64_t, metadata C.uint8_t) C.int32_t {
+	reg := vfsFromUserData(userData)
+	return vfsCall(func() error {
+		f, err := vfsFile(reg, id)
+		if err != nil {
+			return err
+		}
+		return f.Sync(metadata != 0)
+	})
+}
+
+//export goVFSSize
+func goVFSSize(userData unsafe.Pointer, id C.uint64_t, outSize *C.uint64_t) C.int32_t {
+	reg := vfsFromUserData(userData)
+	return vfsCall(func() error {
+		f, err := vfsFile(reg, id)
+		if err != nil {
+			return err
+		}
+		size, err := f.Size()
+		if err != nil {
+			return err
+		}
+		*outSize = C.uint64_t(size)
+		return nil
+	})
+}
+
+//export goVFSTruncate
+func goVFSTruncate(userData unsafe.Pointer, id C.uint64_t, size C.uint64_t) C.int32_t {
+	reg := vfsFromUserData(userData)
+	return vfsCall(func() error {
+		f, err := vfsFile(reg, id)
+		if err != nil {
+			return err
+		}
+		return f.Truncate(int64(size))
+	})
+}
+
+//export goVFSLock
+func goVFSLock(userData unsafe.Pointer, id C.uint64_t, offset C.uint64_t, n C.uint64_t, exclusive C.uint8_t, outAcquired *C.uint8_t) C.int32_t {
+	reg := vfsFromUserData(userData)
+	return vfsCall(func() error {
+		f, err := vfsFile(reg, id)
+		if err != nil {
+			return err
+		}
+		acquired, err := f.Lock(int64(offset), int64(n), exclusive != 0)
+		if err != nil {
+			return err
+		}
+		*outAcquired = 0
+		if acquired {
+			*outAcquired = 1
+		}
+		return nil
+	})
+}
+
+//export goVFSUnlock
+func goVFSUnlock(userData unsafe.Pointer, id C.uint64_t, offset C.uint64_t, n C.uint64_t) C.int32_t {
+	reg := vfsFromUserData(userData)
+	return vfsCall(func() error {
+		f, err := vfsFile(reg, id)
+		if err != nil {
+			return err
+		}
+		return f.Unlock(int64(offset), int64(n))
+	})
+}
+
+//export goVFSClose
+func goVFSClose(userData unsafe.Pointer, id C.uint64_t) {
+	reg := vfsFromUserData(userData)
+	if f, ok := reg.remove(uint64(id)); ok {
+		_ = vfsCall(f.Close)
+	}
+}
@@ -0,0 +1,63 @@
+package decentdb
+
+import (
+	"database/sql"
+	"errors"
+	"io/fs"
+	"os"
+	"testing"
+)
+
+func TestVFS_RoutesFileIOThroughGo(t *testing.T) {
+	vfs := NewMemVFS()
+	if err := RegisterVFS("gotest", vfs); err != nil {
+		t.Fatal(err)
+	}
+	defer UnregisterVFS("gotest")
+
+	dsn := "file:/virtual/vfs.ddb?vfs=gotest"
+	db, err := sql.Open("decentdb", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range []string{
+		"CREATE TABLE t (id INT PRIMARY KEY, v TEXT)",
+		"INSERT INTO t VALUES (1, 'one'), (2, 'two')",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db.Close()
+
+	if ok, _ := vfs.Exists("/virtual/vfs.ddb"); !ok {
+		t.Fatal("database file was not created through the VFS")
+	}
+	if _, err := os.Stat("/virtual/vfs.ddb"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("database leaked onto the OS filesystem: %v", err)
+	}
+
+	db, err = sql.Open("decentdb", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var v string
+	if err := db.QueryRow("SELECT v FROM t WHERE id = 2").Scan(&v); err != nil {
+		t.Fatal(err)
+	}
+	if v != "two" {
+		t.Fatalf("v = %q, want two", v)
+	}
+}
+
+func TestVFS_UnknownNameFailsOpen(t *testing.T) {
+	db, err := sql.Open("decentdb", "file:/virtual/missing.ddb?vfs=not-registered")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err := db.Ping(); err == nil {
+		t.Fatal("expected open with an unregistered VFS to fail")
+	}
+}
@@ -0,0 +1,143 @@
+package decentdb
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// walArchiveQueueSegments is how many closed segments the engine holds for
+// ArchiveWAL. Once they are all waiting, checkpoints stop truncating the WAL
+// until the sink catches up.
+const walArchiveQueueSegments = 4
+
+// walArchivePollInterval is how often ArchiveWAL looks for a new segment
+// when the queue is empty.
+const walArchivePollInterval = 100 * time.Millisecond
+
+// ErrWALArchiveBusy is returned by ArchiveWAL when another call is already
+// streaming segments from the same handle.
+var ErrWALArchiveBusy = errors.New("decentdb: ArchiveWAL is already running")
+
+// WALSegment is the WAL content covered by one checkpoint: every frame
+// committed since the previous checkpoint, ending with the checkpoint frame.
+// Frames is a complete WAL file image, header included, so it can be stored
+// as-is and replayed in sequence on top of a base backup.
+type WALSegment struct {
+	// Sequence numbers segments from 1 for the lifetime of the handle. A
+	// sink that sees a gap has lost a segment and needs a new base backup.
+	Sequence uint64 `json:"sequence"`
+	// StartLSN and EndLSN bound the frames in the segment. LSNs are WAL
+	// offsets and restart after every checkpoint; order segments by Sequence.
+	StartLSN uint64 `json:"start_lsn"`
+	EndLSN   uint64 `json:"end_lsn"`
+	PageSize uint32 `json:"page_size"`
+	Frames   []byte `json:"frames"`
+}
+
+// WALSink receives the segments streamed by ArchiveWAL, in sequence order.
+// A segment is only handed out again if WriteSegment returned an error for
+// it, so WriteSegment must not return nil until the segment is durable.
+type WALSink interface {
+	WriteSegment(ctx context.Context, segment WALSegment) error
+}
+
+// WALSinkFunc adapts a function to the WALSink interface.
+type WALSinkFunc func(ctx context.Context, segment WALSegment) error
+
+// WriteSegment calls f(ctx, segment).
+func (f WALSinkFunc) WriteSegment(ctx context.Context, segment WALSegment) error {
+	return f(ctx, segment)
+}
+
+// walArchiveState carries a segment the sink rejected over to the next
+// ArchiveWAL call so the stream has no gaps.
+type walArchiveState struct {
+	mu      sync.Mutex
+	pending *WALSegment
+}
+
+// nextWALSegment takes the oldest segment from the engine queue.
+func (c *conn) nextWALSegment() (*WALSegment, error) {
+	raw, err := c.NextWALSegmentJson()
+	if err != nil {
+		return nil, err
+	}
+	var segment *WALSegment
+	if err := json.Unmarshal([]byte(raw), &segment); err != nil {
+		return nil, fmt.Errorf("failed to parse WAL segment: %w", err)
+	}
+	return segment, nil
+}
+
+// ArchiveWAL streams completed WAL segments to sink until ctx is done or
+// the sink fails. A segment completes at each checkpoint, automatic or
+// through Checkpoint, and is captured before the WAL is truncated.
+//
+// Archiving stays enabled after ArchiveWAL returns: up to four segments
+// queue in the engine, after which checkpoints leave the WAL in place, and
+// the next ArchiveWAL call resumes where this one stopped, starting with the
+// segment the sink rejected, if any. Call StopWALArchive to turn archiving
+// off and discard what is queued.
+func (d *DB) ArchiveWAL(ctx context.Context, sink WALSink) error {
+	if atomic.LoadUint32(&d.closed) != 0 {
+		return driver.ErrBadConn
+	}
+	if !d.archive.mu.TryLock() {
+		return ErrWALArchiveBusy
+	}
+	defer d.archive.mu.Unlock()
+	if err := d.c.EnableWALArchive(walArchiveQueueSegments); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(walArchivePollInterval)
+	defer ticker.Stop()
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		segment := d.archive.pending
+		if segment == nil {
+			if atomic.LoadUint32(&d.closed) != 0 {
+				return driver.ErrBadConn
+			}
+			next, err := d.c.nextWALSegment()
+			if err != nil {
+				return err
+			}
+			segment = next
+		}
+		if segment == nil {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-ticker.C:
+			}
+			continue
+		}
+		if err := sink.WriteSegment(ctx, *segment); err != nil {
+			d.archive.pending = segment
+			return fmt.Errorf("decentdb: archive WAL segment %d: %w", segment.Sequence, err)
+		}
+		d.archive.pending = nil
+	}
+}
+
+// StopWALArchive turns WAL archiving off and discards queued segments,
+// including one a sink rejected. It waits for a running ArchiveWAL to
+// return, so cancel its context first.
+func (d *DB) StopWALArchive() error {
+	if atomic.LoadUint32(&d.closed) != 0 {
+		return driver.ErrBadConn
+	}
+	d.archive.mu.Lock()
+	defer d.archive.mu.Unlock()
+	d.archive.pending = nil
+	return d.c.DisableWALArchive()
+}
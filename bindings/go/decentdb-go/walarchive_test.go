@@ -0,0 +1,101 @@
+package decentdb
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestArchiveWAL_StreamsCheckpointedSegments(t *testing.T) {
+	db, err := OpenDirect(filepath.Join(t.TempDir(), "archive.ddb"))
+	if err != nil {
+		t.Fatalf("OpenDirect failed: %v", err)
+	}
+	defer db.Close()
+
+	segments := make(chan WALSegment, 8)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- db.ArchiveWAL(ctx, WALSinkFunc(func(ctx context.Context, segment WALSegment) error {
+			segments <- segment
+			return nil
+		}))
+	}()
+
+	for i, stmt := range []string{
+		"CREATE TABLE t (id INT PRIMARY KEY)",
+		"INSERT INTO t VALUES (1)",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Checkpoint(); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case segment := <-segments:
+			if segment.Sequence != uint64(i+1) {
+				t.Fatalf("segment sequence = %d, want %d", segment.Sequence, i+1)
+			}
+			if segment.StartLSN != 32 || uint64(len(segment.Frames)) != segment.EndLSN {
+				t.Fatalf("unexpected segment bounds: start %d end %d len %d", segment.StartLSN, segment.EndLSN, len(segment.Frames))
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for a WAL segment")
+		}
+	}
+
+	if err := db.ArchiveWAL(ctx, WALSinkFunc(func(context.Context, WALSegment) error { return nil })); !errors.Is(err, ErrWALArchiveBusy) {
+		t.Fatalf("expected ErrWALArchiveBusy, got %v", err)
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("ArchiveWAL returned %v, want context.Canceled", err)
+	}
+	if err := db.StopWALArchive(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestArchiveWAL_RetriesRejectedSegment(t *testing.T) {
+	db, err := OpenDirect(filepath.Join(t.TempDir(), "archive_retry.ddb"))
+	if err != nil {
+		t.Fatalf("OpenDirect failed: %v", err)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := db.c.EnableWALArchive(walArchiveQueueSegments); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("CREATE TABLE t (id INT PRIMARY KEY)"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Checkpoint(); err != nil {
+		t.Fatal(err)
+	}
+
+	sinkErr := errors.New("bucket unavailable")
+	err = db.ArchiveWAL(ctx, WALSinkFunc(func(context.Context, WALSegment) error { return sinkErr }))
+	if !errors.Is(err, sinkErr) {
+		t.Fatalf("expected sink error, got %v", err)
+	}
+
+	var got []uint64
+	retryCtx, stop := context.WithCancel(ctx)
+	err = db.ArchiveWAL(retryCtx, WALSinkFunc(func(_ context.Context, segment WALSegment) error {
+		got = append(got, segment.Sequence)
+		stop()
+		return nil
+	}))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("ArchiveWAL returned %v, want context.Canceled", err)
+	}
+	if len(got) != 1 || got[0] != 1 {
+		t.Fatalf("retried sequences = %v, want [1]", got)
+	}
+}
@@ -0,0 +1,110 @@
+package decentdb
+
+/*
+#include "decentdb.h"
+#include <stdlib.h>
+
+extern int32_t goRecoveryProgress(void *user_data, uint64_t frames_replayed, uint64_t bytes_replayed, uint64_t bytes_total);
+*/
+import "C"
+import (
+	"context"
+	"log/slog"
+	"runtime/cgo"
+	"time"
+	"unsafe"
+)
+
+// RecoveryProgress reports WAL replay while a database opens after an
+// unclean shutdown.
+type RecoveryProgress struct {
+	// FramesReplayed is the number of WAL frames replayed so far.
+	FramesReplayed uint64
+	// BytesReplayed and BytesTotal measure the replay in WAL bytes.
+	BytesReplayed uint64
+	BytesTotal    uint64
+}
+
+// WithRecoveryProgress calls fn periodically while a connection's open
+// replays a WAL left by a crash, which can take a long time for large WALs.
+// fn runs on the opening goroutine and should return quickly. Canceling the
+// context passed to Connect, such as the one given to sql.DB.PingContext,
+// stops recovery whether or not fn is set; the WAL is kept for a later open.
+func WithRecoveryProgress(fn func(RecoveryProgress)) ConnectorOption {
+	return func(c *connector) {
+		c.recoveryProgress = fn
+	}
+}
+
+// recoveryState is what goRecoveryProgress reaches through its user data.
+type recoveryState struct {
+	ctx      context.Context
+	progress func(RecoveryProgress)
+}
+
+// open opens an engine handle for Connect, reporting WAL recovery to the
+// connector's progress callback and canceling it with ctx.
+func (c *connector) open(ctx context.Context, path, options, mode string) (*conn, error) {
+	start := time.Now()
+	var db *conn
+	var err error
+	if mode == "create" || (c.recoveryProgress == nil && ctx.Done() == nil) {
+		db, err = openEngine(path, options, mode)
+	} else {
+		db, err = openEngineWithRecovery(ctx, path, options, mode != "open", c.recoveryProgress)
+	}
+	logDebug(ctx, "decentdb: connection opened", slog.String("path", path), slog.String("mode", mode),
+		slog.Duration("duration", time.Since(start)), errAttr(err))
+	if err != nil {
+		return nil, err
+	}
+	db.path = path
+	return db, nil
+}
+
+// openEngineWithRecovery opens path, creating it when create is set, with
+// the recovery progress callback installed.
+func openEngineWithRecovery(ctx context.Context, path, options string, create bool, progress func(RecoveryProgress)) (*conn, error) {
+	cPath := C.CString(path)
+	defer C.free(unsafe.Pointer(cPath))
+	var cOptions *C.char
+	if options != "" {
+		cOptions = C.CString(options)
+		defer C.free(unsafe.Pointer(cOptions))
+	}
+	handle := C.malloc(C.size_t(unsafe.Sizeof(C.uintptr_t(0))))
+	defer C.free(handle)
+	h := cgo.NewHandle(&recoveryState{ctx: ctx, progress: progress})
+	defer h.Delete()
+	*(*C.uintptr_t)(handle) = C.uintptr_t(h)
+
+	var createFlag C.uint8_t
+	if create {
+		createFlag = 1
+	}
+	var db *C.ddb_db_t
+	status := C.ddb_db_open_with_recovery_progress(cPath, cOptions, createFlag, C.ddb_recovery_progress_fn(C.goRecoveryProgress), handle, &db)
+	if status != C.DDB_OK || db == nil {
+		if err := ctx.Err(); err != nil && status == C.DDB_ERR_CANCELED {
+			return nil, err
+		}
+		return nil, statusError(status, "")
+	}
+	return &conn{db: db}, nil
+}
+
+//export goRecoveryProgress
+func goRecoveryProgress(userData unsafe.Pointer, framesReplayed, bytesReplayed, bytesTotal C.uint64_t) C.int32_t {
+	state := cgo.Handle(*(*C.uintptr_t)(userData)).Value().(*recoveryState)
+	if state.ctx.Err() != nil {
+		return 1
+	}
+	if state.progress != nil {
+		state.progress(RecoveryProgress{
+			FramesReplayed: uint64(framesReplayed),
+			BytesReplayed:  uint64(bytesReplayed),
+			BytesTotal:     uint64(bytesTotal),
+		})
+	}
+	return 0
+}
@@ -0,0 +1,90 @@
+package decentdb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// crashCopy writes a database with committed but uncheckpointed WAL frames
+// and copies its files while it is still open, as a crash would leave them.
+func crashCopy(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	src := filepath.Join(dir, "live.ddb")
+	db, err := sql.Open("decentdb", fmt.Sprintf("file:%s", src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, stmt := range []string{
+		"CREATE TABLE t (id INT PRIMARY KEY, body TEXT)",
+		"INSERT INTO t SELECT value, repeat('x', 200) FROM generate_series(1, 2000)",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dst := filepath.Join(dir, "crashed.ddb")
+	for _, suffix := range []string{"", ".wal"} {
+		data, err := os.ReadFile(src + suffix)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(dst+suffix, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dst
+}
+
+func TestRecoveryProgress_ReportsReplay(t *testing.T) {
+	path := crashCopy(t)
+	var reports []RecoveryProgress
+	connector, err := NewConnector(fmt.Sprintf("file:%s", path), WithRecoveryProgress(func(p RecoveryProgress) {
+		reports = append(reports, p)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := sql.OpenDB(connector)
+	defer db.Close()
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM t").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 2000 {
+		t.Fatalf("count = %d, want 2000", count)
+	}
+	if len(reports) == 0 {
+		t.Fatal("no recovery progress reported")
+	}
+	last := reports[len(reports)-1]
+	if last.FramesReplayed == 0 || last.BytesTotal == 0 || last.BytesReplayed != last.BytesTotal {
+		t.Fatalf("unexpected final progress %+v", last)
+	}
+}
+
+func TestRecoveryProgress_ContextCancelsOpen(t *testing.T) {
+	path := crashCopy(t)
+	connector, err := NewConnector(fmt.Sprintf("file:%s", path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := connector.Connect(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+
+	// The WAL survives a canceled recovery.
+	c, err := connector.Connect(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+}
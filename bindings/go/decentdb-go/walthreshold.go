@@ -0,0 +1,69 @@
+package decentdb
+
+/*
+#include "decentdb.h"
+*/
+import "C"
+import "sync"
+
+// walHooks holds the OnWALThreshold callbacks for one handle.
+type walHooks struct {
+	mu         sync.Mutex
+	thresholds []*walThreshold
+}
+
+type walThreshold struct {
+	bytes int64
+	fn    func(size int64)
+	// above is set while the WAL was last seen larger than bytes, so fn
+	// runs once per crossing rather than after every commit.
+	above bool
+}
+
+// OnWALThreshold registers fn to run when a statement or commit on this
+// handle leaves the WAL file larger than bytes. It fires once per crossing:
+// after a checkpoint shrinks the WAL back to bytes or less, the next growth
+// past bytes fires it again. fn receives the WAL size and runs
+// synchronously on the committing goroutine, so it may call Checkpoint
+// directly; hand slower work such as alerting to another goroutine.
+//
+// Only commits made through this handle are checked. Writes by other
+// handles or processes are noticed at this handle's next commit.
+func (d *DB) OnWALThreshold(bytes int64, fn func(size int64)) {
+	hooks := d.c.walHooks.Load()
+	if hooks == nil {
+		d.c.walHooks.CompareAndSwap(nil, &walHooks{})
+		hooks = d.c.walHooks.Load()
+	}
+	hooks.mu.Lock()
+	hooks.thresholds = append(hooks.thresholds, &walThreshold{bytes: bytes, fn: fn})
+	hooks.mu.Unlock()
+}
+
+// noteWALGrowth runs the OnWALThreshold callbacks whose threshold the WAL
+// has just crossed. Statements inside a transaction are checked when it
+// commits.
+func (c *conn) noteWALGrowth() {
+	hooks := c.walHooks.Load()
+	if hooks == nil || c.InTransaction() {
+		return
+	}
+	var cSize C.uint64_t
+	if C.ddb_db_wal_size(c.db, &cSize) != C.DDB_OK {
+		return
+	}
+	size := int64(cSize)
+	var due []func(int64)
+	hooks.mu.Lock()
+	for _, threshold := range hooks.thresholds {
+		above := size > threshold.bytes
+		if above && !threshold.above {
+			due = append(due, threshold.fn)
+		}
+		threshold.above = above
+	}
+	hooks.mu.Unlock()
+	for _, fn := range due {
+		fn(size)
+	}
+}
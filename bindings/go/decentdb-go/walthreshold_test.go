@@ -0,0 +1,55 @@
+package decentdb
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOnWALThreshold_FiresOncePerCrossing(t *testing.T) {
+	db, err := OpenDirect(filepath.Join(t.TempDir(), "walthreshold.ddb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("CREATE TABLE blobs (id INT64 PRIMARY KEY, body TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+	state, err := db.StorageState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	threshold := int64(state.WALFileSize) + 64*1024
+
+	var sizes []int64
+	db.OnWALThreshold(threshold, func(size int64) { sizes = append(sizes, size) })
+	body := strings.Repeat("x", 16*1024)
+	for i := int64(1); i <= 16; i++ {
+		if _, err := db.Exec("INSERT INTO blobs VALUES ($1, $2)", i, body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(sizes) != 1 || sizes[0] <= threshold {
+		t.Fatalf("threshold %d fired with sizes %v, want one call above it", threshold, sizes)
+	}
+
+	// Statements inside a transaction are checked at commit.
+	sizes = nil
+	db.OnWALThreshold(0, func(size int64) { sizes = append(sizes, size) })
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Exec("INSERT INTO blobs VALUES (100, 'late')"); err != nil {
+		t.Fatal(err)
+	}
+	if len(sizes) != 0 {
+		t.Fatalf("fired inside a transaction: %v", sizes)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if len(sizes) != 1 {
+		t.Fatalf("sizes after commit = %v, want one call", sizes)
+	}
+}
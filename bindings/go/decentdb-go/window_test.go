@@ -0,0 +1,184 @@
+package decentdb
+
+import (
+	"database/sql"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func openWindowTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("decentdb", filepath.Join(t.TempDir(), "window.ddb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestWindow_RankingAndFrames(t *testing.T) {
+	db := openWindowTestDB(t)
+	for _, stmt := range []string{
+		"CREATE TABLE sales (id INT64 PRIMARY KEY, region TEXT, day INT64, amount DECIMAL(10,2), units INT64)",
+		`INSERT INTO sales VALUES
+			(1, 'east', 1, 10.00, 1), (2, 'east', 2, 20.50, 2), (3, 'east', 2, 5.25, 3),
+			(4, 'east', 5, 40.00, 4), (5, 'west', 1, 7.00, 5), (6, 'west', 3, 3.00, 6)`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+
+	rows, err := db.Query(`SELECT id,
+		ROW_NUMBER() OVER (PARTITION BY region ORDER BY day, id),
+		RANK() OVER (PARTITION BY region ORDER BY day),
+		DENSE_RANK() OVER (PARTITION BY region ORDER BY day),
+		NTILE(2) OVER (PARTITION BY region ORDER BY id),
+		LAG(units) OVER (PARTITION BY region ORDER BY id),
+		LEAD(units, 2, -1) OVER (PARTITION BY region ORDER BY id),
+		SUM(units) OVER (PARTITION BY region ORDER BY id ROWS BETWEEN 1 PRECEDING AND CURRENT ROW),
+		SUM(amount) OVER (PARTITION BY region ORDER BY day RANGE BETWEEN 1 PRECEDING AND CURRENT ROW),
+		SUM(units) OVER (PARTITION BY region),
+		AVG(units) OVER (ORDER BY id ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW),
+		PERCENT_RANK() OVER (ORDER BY id)
+		FROM sales ORDER BY id`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	type result struct {
+		id, rowNumber, rank, denseRank, tile int64
+		lag                                  sql.NullInt64
+		lead, rollingUnits                   int64
+		recentAmount                         Decimal
+		regionUnits                          int64
+		runningAvg, percentRank              float64
+	}
+	var got []result
+	for rows.Next() {
+		var r result
+		if err := rows.Scan(&r.id, &r.rowNumber, &r.rank, &r.denseRank, &r.tile, &r.lag, &r.lead,
+			&r.rollingUnits, &r.recentAmount, &r.regionUnits, &r.runningAvg, &r.percentRank); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, r)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	null := sql.NullInt64{}
+	lag := func(v int64) sql.NullInt64 { return sql.NullInt64{Int64: v, Valid: true} }
+	want := []result{
+		{1, 1, 1, 1, 1, null, 3, 1, Decimal{Unscaled: 1000, Scale: 2}, 10, 1, 0},
+		{2, 2, 2, 2, 1, lag(1), 4, 3, Decimal{Unscaled: 3575, Scale: 2}, 10, 1.5, 0.2},
+		{3, 3, 2, 2, 2, lag(2), -1, 5, Decimal{Unscaled: 3575, Scale: 2}, 10, 2, 0.4},
+		{4, 4, 4, 3, 2, lag(3), -1, 7, Decimal{Unscaled: 4000, Scale: 2}, 10, 2.5, 0.6},
+		{5, 1, 1, 1, 1, null, -1, 5, Decimal{Unscaled: 700, Scale: 2}, 11, 3, 0.8},
+		{6, 2, 2, 2, 2, lag(5), -1, 11, Decimal{Unscaled: 300, Scale: 2}, 11, 3.5, 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got  %+v\nwant %+v", got, want)
+	}
+}
+
+// Every value kind must come back from a window function exactly as the
+// driver returns the column itself.
+func TestWindow_ValueFunctionsPreserveEveryValueKind(t *testing.T) {
+	db := openWindowTestDB(t)
+	columns := []struct{ name, typ, first, second string }{
+		{"i", "INT64", "1", "-2"},
+		{"f", "FLOAT64", "1.5", "-2.25"},
+		{"b", "BOOL", "TRUE", "FALSE"},
+		{"s", "TEXT", "'ada'", "'grace'"},
+		{"bl", "BLOB", "$1", "$1"},
+		{"d", "DECIMAL(10,2)", "12.34", "-0.50"},
+		{"u", "UUID", "'0190a5e6-1c2b-7d3e-8f40-123456789abc'", "'0190a5e6-1c2b-7d3e-8f40-cba987654321'"},
+		{"ts", "TIMESTAMP", "'2026-02-24 17:30:00'", "'2026-02-25 08:00:00.5'"},
+		{"tz", "TIMESTAMPTZ", "'2026-05-18T09:10:11.123456-05:00'", "'2026-05-18 14:10:11Z'"},
+		{"dt", "DATE", "'2026-05-18'", "'1999-12-31'"},
+		{"tm", "TIME", "'09:30:00.123456'", "'23:59:59'"},
+		{"iv", "INTERVAL", "'1 year 2 months 3 days'", "'4.5 seconds'"},
+		{"ip", "INET", "'192.168.10.20'", "'2001:db8::1'"},
+		{"net", "CIDR", "'192.168.10.0/24'", "'2001:db8::/32'"},
+		{"mac", "MACADDR", "'08:00:2b:01:02:03'", "'08:00:2b:ff:fe:01:02:03'"},
+		{"e", "ENUM('low', 'high')", "'high'", "'low'"},
+		{"g", "GEOMETRY", "ST_GeomFromText('POINT(1 2)')", "ST_GeomFromText('LINESTRING(0 0, 1 1)')"},
+	}
+	create := "CREATE TABLE kinds (id INT64 PRIMARY KEY"
+	first, second := "INSERT INTO kinds VALUES (1", "INSERT INTO kinds VALUES (2"
+	for _, c := range columns {
+		create += ", " + c.name + " " + c.typ
+		first += ", " + c.first
+		second += ", " + c.second
+	}
+	if _, err := db.Exec(create + ")"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(first+")", []byte{0x00, 0xff}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(second+")", []byte{0x01}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("INSERT INTO kinds (id) VALUES (3)"); err != nil {
+		t.Fatal(err)
+	}
+
+	queryAll := func(t *testing.T, query string) [][]any {
+		t.Helper()
+		rows, err := db.Query(query)
+		if err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+		defer rows.Close()
+		names, err := rows.Columns()
+		if err != nil {
+			t.Fatal(err)
+		}
+		width := len(names)
+		var out [][]any
+		for rows.Next() {
+			values := make([]any, width)
+			dest := make([]any, width)
+			for i := range values {
+				dest[i] = &values[i]
+			}
+			if err := rows.Scan(dest...); err != nil {
+				t.Fatal(err)
+			}
+			out = append(out, values)
+		}
+		if err := rows.Err(); err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+
+	for _, c := range columns {
+		t.Run(c.typ, func(t *testing.T) {
+			plain := queryAll(t, "SELECT "+c.name+" FROM kinds ORDER BY id")
+			v1, v2, v3 := plain[0][0], plain[1][0], plain[2][0]
+			if v1 == nil || v2 == nil || v3 != nil {
+				t.Fatalf("column values = %v", plain)
+			}
+			got := queryAll(t, "SELECT "+
+				"LAG("+c.name+") OVER (ORDER BY id), "+
+				"LEAD("+c.name+") OVER (ORDER BY id), "+
+				"FIRST_VALUE("+c.name+") OVER (ORDER BY id), "+
+				"LAST_VALUE("+c.name+") OVER (ORDER BY id ROWS BETWEEN CURRENT ROW AND UNBOUNDED FOLLOWING), "+
+				"NTH_VALUE("+c.name+", 2) OVER (ORDER BY id ROWS BETWEEN UNBOUNDED PRECEDING AND UNBOUNDED FOLLOWING) "+
+				"FROM kinds ORDER BY id")
+			want := [][]any{
+				{nil, v2, v1, v3, v2},
+				{v1, v3, v1, v3, v2},
+				{v2, nil, v1, v3, v2},
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("got  %#v\nwant %#v", got, want)
+			}
+		})
+	}
+}
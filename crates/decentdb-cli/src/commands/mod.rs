@@ -13,15 +13,18 @@ use clap::{ArgAction, Parser, Subcommand, ValueEnum};
 use decentdb::{
     evict_shared_wal, render_markdown, run_doctor, BranchDiffReport, BranchInfo, BranchLogEntry,
     BranchMergeOperation, BranchMergeReport, BranchRestoreReport, BranchTableDiffStatus,
-    BulkLoadOptions, ColumnInfo, Db, DbConfig, DbError, DoctorCategory, DoctorCheckSelection,
-    DoctorIndexVerification, DoctorOptions, DoctorPathMode, DoctorReport, DoctorSeverity,
-    ExtensionTrustAnchor, ExtensionValidationOptions, ForeignKeyInfo, HeaderInfo,
-    IndexVerification, NamedSnapshot, QueryResult, ShapeAckOptions, StorageInfo, SyncChangeBatch,
-    SyncChangeset, SyncChangesetSource, SyncConflict, SyncConflictPolicy, SyncHandshake,
-    SyncImportSummary, SyncPeer, SyncPeerScopeBinding, SyncPrincipal, SyncRelayHello,
-    SyncRunDirection, SyncRunSummary, SyncScope, SyncShape, SyncSubjectKind, TableInfo, Value,
+    BulkLoadOptions, CloneOptions, ColumnInfo, Db, DbConfig, DbError, DoctorCategory,
+    DoctorCheckSelection, DoctorIndexVerification, DoctorOptions, DoctorPathMode, DoctorReport,
+    DoctorSeverity, DumpOptions, ExtensionTrustAnchor, ExtensionValidationOptions, ForeignKeyInfo,
+    HeaderInfo, IndexVerification, NamedSnapshot, QueryResult, ShapeAckOptions, StorageInfo,
+    SyncChangeBatch, SyncChangeset, SyncChangesetSource, SyncConflict, SyncConflictPolicy,
+    SyncHandshake, SyncImportSummary, SyncPeer, SyncPeerScopeBinding, SyncPrincipal,
+    SyncRelayHello, SyncRunDirection, SyncRunSummary, SyncScope, SyncShape, SyncSubjectKind,
+    TableInfo, Value,
 };
 
+use crate::csv_schema;
+use crate::explain::{parse_plan, render_plan, ExplainFormat};
 use crate::output::{
     render_error_json_for_error, render_exec_success_json, render_key_value_rows, render_rows,
     rows_from_query_result, stringify_value, OutputFormat,
@@ -68,10 +71,14 @@ pub enum Commands {
     /// Manage database branches
     #[command(subcommand)]
     Branch(BranchCommand),
+    /// Compare the rows of two database files
+    Diff(DiffCommand),
     /// Quick diagnostic view of database file headers, format version, and WAL state
     Info(InfoCommand),
     /// Describe table structure
     Describe(DescribeCommand),
+    /// Show a query plan as annotated text, JSON, or a Graphviz graph
+    Explain(ExplainCommand),
     /// List all tables in the database
     ListTables(ListTablesCommand),
     /// List all indexes
@@ -80,6 +87,12 @@ pub enum Commands {
     ListViews(ListViewsCommand),
     /// Dump database as SQL
     Dump(DumpCommand),
+    /// Restore a SQL dump into a database
+    Restore(RestoreCommand),
+    /// Salvage schema and rows from a damaged database into a new file
+    Recover(RecoverCommand),
+    /// Copy a database's schema and filtered rows into a new file
+    Clone(CloneCommand),
     /// Dump raw database header fields
     DumpHeader(DumpHeaderCommand),
     /// Rebuild an index
@@ -96,6 +109,8 @@ pub enum Commands {
     VerifyHeader(VerifyHeaderCommand),
     /// Verify index integrity
     VerifyIndex(VerifyIndexCommand),
+    /// Compare per-table row counts and content checksums with another database
+    Verify(VerifyCommand),
     /// Runtime tracing views and diagnostics
     Tracing(TracingCommand),
     /// Plan cache diagnostics and management
@@ -138,6 +153,9 @@ pub struct ServeCommand {
     /// Compatibility form for host:port binding.
     #[arg(long, hide = true)]
     pub bind: Option<String>,
+    /// Listen address in host:port form; `:8080` listens on all interfaces
+    #[arg(long, conflicts_with = "bind")]
+    pub http: Option<String>,
     #[arg(long, default_value_t = false)]
     pub read_only: bool,
     #[arg(long, default_value_t = false)]
@@ -154,6 +172,9 @@ pub struct ServeCommand {
     pub busy_timeout: String,
     #[arg(long = "token-env")]
     pub token_env: Option<String>,
+    /// Environment variable holding `user:password` for HTTP basic auth
+    #[arg(long = "basic-auth-env", conflicts_with = "token_env")]
+    pub basic_auth_env: Option<String>,
     #[arg(long, default_value_t = false)]
     pub show_token: bool,
     #[arg(long, default_value_t = false)]
@@ -162,6 +183,9 @@ pub struct ServeCommand {
     pub cors_origin: Option<String>,
     #[arg(long, default_value = "text", value_parser = ["text", "json"])]
     pub log_format: String,
+    /// Idle time after which an open HTTP transaction is rolled back
+    #[arg(long, default_value = "60s")]
+    pub tx_timeout: String,
 }
 
 #[derive(Clone, Debug, Parser)]
@@ -300,10 +324,18 @@ pub enum DataFormat {
 pub struct ImportCommand {
     #[arg(long)]
     pub db: String,
-    #[arg(long)]
-    pub table: String,
-    #[arg(long)]
-    pub input: PathBuf,
+    #[arg(long, required_unless_present = "create")]
+    pub table: Option<String>,
+    /// Input file; may also be given positionally
+    #[arg(long, required_unless_present = "file", conflicts_with = "file")]
+    pub input: Option<PathBuf>,
+    pub file: Option<PathBuf>,
+    /// Create the table from column types inferred from the file
+    #[arg(long, default_value_t = false)]
+    pub create: bool,
+    /// Rows sampled to infer column types with --create
+    #[arg(long = "sampleRows", default_value_t = 1000)]
+    pub sample_rows: usize,
     #[arg(long, value_enum, default_value_t = DataFormat::Csv)]
     pub format: DataFormat,
     #[arg(long = "batchSize", default_value_t = 10_000)]
@@ -466,6 +498,22 @@ pub struct BranchDiffCommand {
     pub format: OutputFormat,
 }
 
+#[derive(Clone, Debug, Parser)]
+pub struct DiffCommand {
+    /// Database to compare from
+    #[arg(value_name = "LEFT")]
+    pub left: String,
+    /// Database to compare against
+    #[arg(value_name = "RIGHT")]
+    pub right: String,
+    /// Also write a changeset that turns LEFT's rows into RIGHT's; apply it
+    /// with `sync changeset apply --db=LEFT`
+    #[arg(long)]
+    pub patchset: Option<PathBuf>,
+    #[arg(long, value_enum, default_value_t = OutputFormat::Table)]
+    pub format: OutputFormat,
+}
+
 #[derive(Clone, Debug, Parser)]
 pub struct BranchRestoreCommand {
     #[arg(long)]
@@ -560,6 +608,22 @@ pub struct InfoCommand {
     pub format: OutputFormat,
 }
 
+#[derive(Clone, Debug, Parser)]
+pub struct ExplainCommand {
+    #[arg(long)]
+    pub db: String,
+    /// Query to explain; a leading EXPLAIN is optional
+    #[arg(value_name = "SQL")]
+    pub sql: String,
+    #[arg(long = "params")]
+    pub params: Vec<String>,
+    /// Run the query and report actual rows, time, and memory
+    #[arg(long, default_value_t = false)]
+    pub analyze: bool,
+    #[arg(long, value_enum, default_value_t = ExplainFormat::Text)]
+    pub format: ExplainFormat,
+}
+
 #[derive(Clone, Debug, Parser)]
 pub struct DescribeCommand {
     #[arg(long)]
@@ -598,10 +662,72 @@ pub struct ListViewsCommand {
 
 #[derive(Clone, Debug, Parser)]
 pub struct DumpCommand {
+    /// Database file to dump. Equivalent to --db.
+    #[arg(value_name = "DB")]
+    pub db_path: Option<String>,
     #[arg(long)]
-    pub db: String,
+    pub db: Option<String>,
     #[arg(long)]
     pub output: Option<PathBuf>,
+    /// Only dump these tables, with their indexes and triggers (repeatable)
+    #[arg(long = "table")]
+    pub tables: Vec<String>,
+    /// Emit CREATE statements only
+    #[arg(long, default_value_t = false, conflicts_with = "data_only")]
+    pub schema_only: bool,
+    /// Emit INSERT statements only
+    #[arg(long, default_value_t = false)]
+    pub data_only: bool,
+}
+
+#[derive(Clone, Debug, Parser)]
+pub struct RestoreCommand {
+    /// Database file to restore into. Equivalent to --db.
+    #[arg(value_name = "DB")]
+    pub db_path: Option<String>,
+    #[arg(long)]
+    pub db: Option<String>,
+    /// SQL dump to read; defaults to stdin
+    #[arg(long)]
+    pub input: Option<PathBuf>,
+}
+
+#[derive(Clone, Debug, Parser)]
+pub struct RecoverCommand {
+    /// Damaged database to read
+    #[arg(value_name = "SRC")]
+    pub src: String,
+    /// New database file to write; must not exist
+    #[arg(value_name = "DST")]
+    pub dst: PathBuf,
+    #[arg(long, value_enum, default_value_t = OutputFormat::Table)]
+    pub format: OutputFormat,
+}
+
+#[derive(Clone, Debug, Parser)]
+pub struct CloneCommand {
+    /// Database to copy
+    #[arg(value_name = "SRC")]
+    pub src: String,
+    /// New database file to write; must not exist
+    #[arg(value_name = "DST")]
+    pub dst: PathBuf,
+    /// Comma-separated tables to copy, with their indexes and triggers;
+    /// defaults to every table and view
+    #[arg(long)]
+    pub include: Option<String>,
+    /// Row filter as TABLE:EXPR, such as 'orders:created_at > now()' (repeatable)
+    #[arg(long = "where", value_name = "TABLE:EXPR")]
+    pub filters: Vec<String>,
+    /// Column rewrite as TABLE.COLUMN=EXPR, such as 'users.bio=NULL' (repeatable)
+    #[arg(long = "transform", value_name = "TABLE.COLUMN=EXPR")]
+    pub transforms: Vec<String>,
+    /// JSON file with "tables", "where", and "transforms" settings; the
+    /// other flags add to it
+    #[arg(long)]
+    pub config: Option<PathBuf>,
+    #[arg(long, value_enum, default_value_t = OutputFormat::Table)]
+    pub format: OutputFormat,
 }
 
 #[derive(Clone, Debug, Parser)]
@@ -678,6 +804,17 @@ pub struct VerifyIndexCommand {
     pub format: OutputFormat,
 }
 
+#[derive(Clone, Debug, Parser)]
+pub struct VerifyCommand {
+    #[arg(long)]
+    pub db: String,
+    /// Database to compare with, such as a backup or replica of --db
+    #[arg(long)]
+    pub against: String,
+    #[arg(long, value_enum, default_value_t = OutputFormat::Table)]
+    pub format: OutputFormat,
+}
+
 #[derive(Clone, Debug, Parser)]
 pub struct MigrateCommand {
     /// The source legacy database file path
@@ -1433,12 +1570,17 @@ fn dispatch(cli: Cli) -> Result<()> {
         }
         Commands::Snapshot(command) => run_snapshot(command)?,
         Commands::Branch(command) => run_branch(command)?,
+        Commands::Diff(command) => run_diff(command)?,
         Commands::Info(command) => run_info(command)?,
         Commands::Describe(command) => run_describe(command)?,
+        Commands::Explain(command) => run_explain(command)?,
         Commands::ListTables(command) => run_list_tables(command)?,
         Commands::ListIndexes(command) => run_list_indexes(command)?,
         Commands::ListViews(command) => run_list_views(command)?,
         Commands::Dump(command) => run_dump(command)?,
+        Commands::Restore(command) => run_restore(command)?,
+        Commands::Recover(command) => run_recover(command)?,
+        Commands::Clone(command) => run_clone(command)?,
         Commands::DumpHeader(command) => run_dump_header(command)?,
         Commands::RebuildIndex(command) => {
             open_db(&command.db, false, 0, 0)?.rebuild_index(&command.index)?;
@@ -1450,6 +1592,7 @@ fn dispatch(cli: Cli) -> Result<()> {
         Commands::Vacuum(command) => run_vacuum(command)?,
         Commands::VerifyHeader(command) => run_verify_header(command)?,
         Commands::VerifyIndex(command) => run_verify_index(command)?,
+        Commands::Verify(command) => run_verify(command)?,
         Commands::Migrate(command) => run_migrate(command)?,
         Commands::Sync(command) => run_sync(command)?,
         Commands::Relay(command) => run_relay(command)?,
@@ -1665,19 +1808,22 @@ fn run_extension_test(command: ExtensionValidateCommand) -> Result<()> {
     )
 }
 
+fn resolve_db_arg(db: Option<String>, db_path: Option<String>) -> Result<String> {
+    match (db, db_path) {
+        (Some(_), Some(_)) => Err(anyhow!("provide either --db or positional DB, not both")),
+        (Some(db), None) | (None, Some(db)) => Ok(db),
+        (None, None) => Err(anyhow!("missing database path; use --db <file>.ddb")),
+    }
+}
+
 fn run_serve(command: ServeCommand) -> Result<()> {
-    let db = match (command.db, command.db_path) {
-        (Some(_), Some(_)) => {
-            return Err(anyhow!("provide either --db or positional DB, not both"))
-        }
-        (Some(db), None) | (None, Some(db)) => db,
-        (None, None) => return Err(anyhow!("missing database path; use --db <file>.ddb")),
-    };
+    let db = resolve_db_arg(command.db, command.db_path)?;
     crate::serve::run_serve(crate::serve::ServeCommandOptions {
         db,
         host: command.host,
         port: command.port,
         bind: command.bind,
+        http: command.http,
         read_only: command.read_only,
         open: command.open,
         max_result_rows: command.max_result_rows,
@@ -1686,10 +1832,12 @@ fn run_serve(command: ServeCommand) -> Result<()> {
         max_concurrent_requests: command.max_concurrent_requests,
         busy_timeout: command.busy_timeout,
         token_env: command.token_env,
+        basic_auth_env: command.basic_auth_env,
         show_token: command.show_token,
         no_auth: command.no_auth,
         cors_origin: command.cors_origin,
         log_format: command.log_format,
+        tx_timeout: command.tx_timeout,
     })?;
     Ok(())
 }
@@ -2055,6 +2203,17 @@ fn print_branch_restore(format: OutputFormat, report: &BranchRestoreReport) -> R
     Ok(())
 }
 
+fn run_diff(command: DiffCommand) -> Result<()> {
+    let left = open_db(&command.left, false, 0, 0)?;
+    let right = open_db(&command.right, false, 0, 0)?;
+    if let Some(path) = command.patchset.as_ref() {
+        let changeset = left.diff_database_changeset(&right)?;
+        fs::write(path, serde_json::to_string_pretty(&changeset)?)?;
+    }
+    let report = left.diff_database(&right)?;
+    print_branch_diff(command.format, &report)
+}
+
 fn print_branch_diff(format: OutputFormat, report: &BranchDiffReport) -> Result<()> {
     if format == OutputFormat::Json {
         println!("{}", serde_json::to_string_pretty(report)?);
@@ -2102,11 +2261,42 @@ fn run_import(command: ImportCommand) -> Result<()> {
     if command.format != DataFormat::Csv {
         return Err(anyhow!("JSON import is not supported by the Rust CLI yet"));
     }
+    let input = command
+        .input
+        .as_deref()
+        .or(command.file.as_deref())
+        .ok_or_else(|| anyhow!("import requires an input file"))?;
     let db = open_db(&command.db, true, 0, 0)?;
-    let (columns, rows) = parse_csv_file(&command.input)?;
+    let (table, columns, rows) = if command.create {
+        let table = match &command.table {
+            Some(table) => table.clone(),
+            None => csv_schema::table_name_from_stem(
+                &input.file_stem().unwrap_or_default().to_string_lossy(),
+            ),
+        };
+        let (header, raw_rows) = read_csv_file(input)?;
+        let inferred = csv_schema::infer_columns(&header, &raw_rows, command.sample_rows);
+        let rows = raw_rows
+            .iter()
+            .enumerate()
+            .map(|(index, row)| csv_schema::convert_row(&inferred, row, index + 1))
+            .collect::<Result<Vec<_>>>()?;
+        let ddl = csv_schema::create_table_sql(&table, &inferred);
+        eprintln!("{ddl}");
+        db.execute(&ddl)?;
+        let columns = inferred.into_iter().map(|column| column.name).collect();
+        (table, columns, rows)
+    } else {
+        let table = command
+            .table
+            .clone()
+            .ok_or_else(|| anyhow!("import requires --table unless --create is given"))?;
+        let (columns, rows) = parse_csv_file(input)?;
+        (table, columns, rows)
+    };
     let column_refs = columns.iter().map(String::as_str).collect::<Vec<_>>();
     db.bulk_load_rows(
-        &command.table,
+        &table,
         &column_refs,
         &rows,
         BulkLoadOptions {
@@ -2243,6 +2433,28 @@ fn run_describe(command: DescribeCommand) -> Result<()> {
     Ok(())
 }
 
+fn run_explain(command: ExplainCommand) -> Result<()> {
+    let db = open_db(&command.db, false, 0, 0)?;
+    let params = command
+        .params
+        .iter()
+        .map(|param| parse_param(param))
+        .collect::<Result<Vec<_>>>()?;
+    let sql = command.sql.trim();
+    let already_explain = sql
+        .get(..8)
+        .is_some_and(|head| head.eq_ignore_ascii_case("EXPLAIN "));
+    let sql = match (already_explain, command.analyze) {
+        (true, _) => sql.to_string(),
+        (false, false) => format!("EXPLAIN {sql}"),
+        (false, true) => format!("EXPLAIN ANALYZE {sql}"),
+    };
+    let result = db.execute_with_params(&sql, &params)?;
+    let plan = parse_plan(result.explain_lines());
+    println!("{}", render_plan(&plan, command.format));
+    Ok(())
+}
+
 fn describe_column_foreign_keys(table: &TableInfo, column: &ColumnInfo) -> String {
     let mut foreign_keys = Vec::new();
     if let Some(foreign_key) = &column.foreign_key {
@@ -2348,7 +2560,13 @@ fn run_list_views(command: ListViewsCommand) -> Result<()> {
 }
 
 fn run_dump(command: DumpCommand) -> Result<()> {
-    let dump = open_db(&command.db, false, 0, 0)?.dump_sql()?;
+    let db = resolve_db_arg(command.db, command.db_path)?;
+    let options = DumpOptions {
+        tables: command.tables,
+        include_schema: !command.data_only,
+        include_data: !command.schema_only,
+    };
+    let dump = open_db(&db, false, 0, 0)?.dump_sql_with_options(&options)?;
     if let Some(path) = command.output {
         fs::write(path, dump)?;
     } else {
@@ -2357,6 +2575,131 @@ fn run_dump(command: DumpCommand) -> Result<()> {
     Ok(())
 }
 
+fn run_restore(command: RestoreCommand) -> Result<()> {
+    let db_path = resolve_db_arg(command.db, command.db_path)?;
+    let sql = match &command.input {
+        Some(path) => fs::read_to_string(path)?,
+        None => {
+            let mut sql = String::new();
+            std::io::stdin().read_to_string(&mut sql)?;
+            sql
+        }
+    };
+    let db = open_db(&db_path, true, 0, 0)?;
+    db.begin_transaction()?;
+    if let Err(error) = db.execute_batch(&sql) {
+        db.rollback_transaction()?;
+        return Err(error.into());
+    }
+    db.commit_transaction()?;
+    Ok(())
+}
+
+fn run_recover(command: RecoverCommand) -> Result<()> {
+    let report = open_db(&command.src, false, 0, 0)?.recover_to(&command.dst)?;
+    if command.format == OutputFormat::Json {
+        println!("{}", serde_json::to_string_pretty(&report)?);
+    } else {
+        let mut rows = report
+            .tables
+            .iter()
+            .map(|table| {
+                vec![
+                    "table".to_string(),
+                    table.name.clone(),
+                    if table.schema_recovered {
+                        table.rows_recovered.to_string()
+                    } else {
+                        String::new()
+                    },
+                    table.error.clone().unwrap_or_default(),
+                ]
+            })
+            .collect::<Vec<_>>();
+        rows.extend(report.lost_objects.iter().map(|lost| {
+            vec![
+                lost.kind.clone(),
+                lost.name.clone(),
+                String::new(),
+                lost.error.clone(),
+            ]
+        }));
+        let columns = vec![
+            "kind".to_string(),
+            "name".to_string(),
+            "rows_recovered".to_string(),
+            "error".to_string(),
+        ];
+        println!("{}", render_rows(command.format, &columns, &rows, true));
+    }
+    if !report.is_complete() {
+        eprintln!(
+            "warning: recovery was incomplete; see errors above. Salvaged data is in {}",
+            command.dst.display()
+        );
+    }
+    Ok(())
+}
+
+fn run_clone(command: CloneCommand) -> Result<()> {
+    let mut options = match &command.config {
+        Some(path) => serde_json::from_str::<CloneOptions>(&fs::read_to_string(path)?)
+            .map_err(|error| anyhow!("invalid clone config {}: {error}", path.display()))?,
+        None => CloneOptions::default(),
+    };
+    if let Some(include) = command.include.as_deref() {
+        options.tables.extend(split_scope_tables(include));
+    }
+    for raw in &command.filters {
+        let Some((table, filter)) = raw.split_once(':') else {
+            return Err(anyhow!("invalid --where {raw}; expected TABLE:EXPR"));
+        };
+        options
+            .filters
+            .insert(table.trim().to_string(), filter.trim().to_string());
+    }
+    for raw in &command.transforms {
+        let Some((table, column, transform)) =
+            raw.split_once('=').and_then(|(target, transform)| {
+                let (table, column) = target.rsplit_once('.')?;
+                Some((table, column, transform))
+            })
+        else {
+            return Err(anyhow!(
+                "invalid --transform {raw}; expected TABLE.COLUMN=EXPR"
+            ));
+        };
+        options
+            .transforms
+            .entry(table.trim().to_string())
+            .or_default()
+            .insert(column.trim().to_string(), transform.trim().to_string());
+    }
+    let report = open_db(&command.src, false, 0, 0)?.clone_to(&command.dst, &options)?;
+    if command.format == OutputFormat::Json {
+        println!("{}", serde_json::to_string_pretty(&report)?);
+        return Ok(());
+    }
+    let rows = report
+        .tables
+        .iter()
+        .map(|table| {
+            vec![
+                table.name.clone(),
+                table.rows_copied.to_string(),
+                table.rows_skipped.to_string(),
+            ]
+        })
+        .collect::<Vec<_>>();
+    let columns = vec![
+        "table".to_string(),
+        "rows_copied".to_string(),
+        "rows_skipped".to_string(),
+    ];
+    println!("{}", render_rows(command.format, &columns, &rows, true));
+    Ok(())
+}
+
 fn run_dump_header(command: DumpHeaderCommand) -> Result<()> {
     let header = Db::read_header_info(&command.db)?;
     print_header_info(command.format, &header);
@@ -2471,6 +2814,50 @@ fn run_verify_index(command: VerifyIndexCommand) -> Result<()> {
     Ok(())
 }
 
+fn run_verify(command: VerifyCommand) -> Result<()> {
+    let db = open_db(&command.db, false, 0, 0)?;
+    let other = open_db(&command.against, false, 0, 0)?;
+    let report = db.verify_against(&other)?;
+    if command.format == OutputFormat::Json {
+        println!("{}", serde_json::to_string_pretty(&report)?);
+    } else {
+        let side = |value: Option<String>| value.unwrap_or_else(|| "-".to_string());
+        let rows = report
+            .tables
+            .iter()
+            .map(|table| {
+                vec![
+                    table.name.clone(),
+                    side(table.left_row_count.map(|count| count.to_string())),
+                    side(table.right_row_count.map(|count| count.to_string())),
+                    side(table.left_checksum.clone()),
+                    side(table.right_checksum.clone()),
+                    table.matches.to_string(),
+                ]
+            })
+            .collect::<Vec<_>>();
+        let columns = vec![
+            "table".to_string(),
+            "left_rows".to_string(),
+            "right_rows".to_string(),
+            "left_checksum".to_string(),
+            "right_checksum".to_string(),
+            "match".to_string(),
+        ];
+        println!("{}", render_rows(command.format, &columns, &rows, true));
+    }
+    let mismatched = report.tables.iter().filter(|table| !table.matches).count();
+    if mismatched > 0 {
+        return Err(anyhow!(
+            "{mismatched} of {} tables differ between {} and {}",
+            report.tables.len(),
+            command.db,
+            command.against
+        ));
+    }
+    Ok(())
+}
+
 fn run_migrate(command: MigrateCommand) -> Result<()> {
     let source_path = Path::new(&command.source);
 
@@ -4071,7 +4458,7 @@ fn websocket_accept_key(key: &str) -> String {
     base64_encode(&sha1_digest(&bytes))
 }
 
-fn base64_encode(input: &[u8]) -> String {
+pub(crate) fn base64_encode(input: &[u8]) -> String {
     const TABLE: &[u8; 64] = b"ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/";
     let mut output = String::with_capacity(input.len().div_ceil(3) * 4);
     for chunk in input.chunks(3) {
@@ -5925,19 +6312,23 @@ fn parse_param(raw: &str) -> Result<Value> {
 }
 
 fn parse_csv_file(path: &Path) -> Result<(Vec<String>, Vec<Vec<Value>>)> {
+    let (columns, rows) = read_csv_file(path)?;
+    let rows = rows
+        .into_iter()
+        .map(|row| row.iter().map(|value| infer_value(value)).collect())
+        .collect::<Vec<Vec<Value>>>();
+    Ok((columns, rows))
+}
+
+fn read_csv_file(path: &Path) -> Result<(Vec<String>, Vec<Vec<String>>)> {
     let input = fs::read_to_string(path)?;
     let mut lines = input.lines();
     let header = lines.next().ok_or_else(|| anyhow!("CSV input is empty"))?;
     let columns = split_csv_line(header);
     let rows = lines
         .filter(|line| !line.trim().is_empty())
-        .map(|line| {
-            split_csv_line(line)
-                .into_iter()
-                .map(|value| infer_value(&value))
-                .collect()
-        })
-        .collect::<Vec<Vec<Value>>>();
+        .map(split_csv_line)
+        .collect();
     Ok((columns, rows))
 }
 
@@ -5985,7 +6376,7 @@ fn completion_script(shell: ShellKind) -> &'static str {
     match shell {
         ShellKind::Bash => {
             r#"_decentdb_complete() {
-  local commands="version exec repl import export bulk-load checkpoint save-as info describe list-tables list-indexes list-views dump dump-header rebuild-index rebuild-indexes completion stats vacuum verify-header verify-index sync"
+  local commands="version exec repl import export bulk-load checkpoint save-as info describe list-tables list-indexes list-views dump restore recover dump-header rebuild-index rebuild-indexes completion stats vacuum verify-header verify-index sync"
   COMPREPLY=( $(compgen -W "$commands" -- "${COMP_WORDS[1]}") )
 }
 complete -F _decentdb_complete decentdb
@@ -6010,6 +6401,8 @@ _decentdb() {
     list-indexes
     list-views
     dump
+    restore
+    recover
     dump-header
     rebuild-index
     rebuild-indexes
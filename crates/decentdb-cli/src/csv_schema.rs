@@ -0,0 +1,443 @@
+//! Schema inference for `decentdb import --create`.
+//!
+//! [`infer_columns`] looks at the first rows of a CSV file and picks the
+//! narrowest column type every non-empty sampled value fits, trying `BOOL`,
+//! `INT64`, `DECIMAL`, `FLOAT64` and `TIMESTAMP` in that order before falling
+//! back to `TEXT`. Empty values are NULL and do not constrain the type. Because
+//! only a sample is inspected, every row is checked again by [`convert_row`]
+//! while loading, and a value that does not fit fails the import with its row
+//! and column.
+
+use anyhow::{anyhow, Result};
+use decentdb::Value;
+
+/// Digits a `DECIMAL` column can hold: its scaled value is stored as an `i64`.
+const DECIMAL_MAX_DIGITS: usize = 18;
+
+#[derive(Clone, Copy, Debug, PartialEq, Eq)]
+pub enum CsvType {
+    Bool,
+    Int64,
+    Decimal { scale: u8 },
+    Float64,
+    Timestamp,
+    Text,
+}
+
+impl CsvType {
+    pub fn sql(self) -> String {
+        match self {
+            Self::Bool => "BOOL".to_string(),
+            Self::Int64 => "INT64".to_string(),
+            Self::Decimal { scale } => format!("DECIMAL({DECIMAL_MAX_DIGITS}, {scale})"),
+            Self::Float64 => "FLOAT64".to_string(),
+            Self::Timestamp => "TIMESTAMP".to_string(),
+            Self::Text => "TEXT".to_string(),
+        }
+    }
+}
+
+#[derive(Clone, Debug, PartialEq, Eq)]
+pub struct CsvColumn {
+    pub name: String,
+    pub column_type: CsvType,
+}
+
+/// The types a column's sampled values still allow.
+struct Candidates {
+    bool: bool,
+    int: bool,
+    /// The common scale of the fixed-point values seen so far, if they agree.
+    decimal: Option<Option<u8>>,
+    float: bool,
+    timestamp: bool,
+    seen: bool,
+}
+
+impl Candidates {
+    fn new() -> Self {
+        Self {
+            bool: true,
+            int: true,
+            decimal: Some(None),
+            float: true,
+            timestamp: true,
+            seen: false,
+        }
+    }
+
+    fn observe(&mut self, raw: &str) {
+        self.seen = true;
+        self.bool &= parse_bool(raw).is_some();
+        self.int &= parse_int(raw).is_some();
+        self.float &= parse_float(raw).is_some();
+        self.timestamp &= is_timestamp(raw);
+        self.decimal = match (self.decimal, decimal_scale(raw)) {
+            (Some(common), Some(0)) => Some(common),
+            (Some(None), Some(scale)) => Some(Some(scale)),
+            (Some(Some(common)), Some(scale)) if common == scale => Some(Some(common)),
+            _ => None,
+        };
+    }
+
+    fn resolve(&self) -> CsvType {
+        if !self.seen {
+            CsvType::Text
+        } else if self.bool {
+            CsvType::Bool
+        } else if self.int {
+            CsvType::Int64
+        } else if let Some(Some(scale)) = self.decimal {
+            CsvType::Decimal { scale }
+        } else if self.float {
+            CsvType::Float64
+        } else if self.timestamp {
+            CsvType::Timestamp
+        } else {
+            CsvType::Text
+        }
+    }
+}
+
+/// Infers a column per header field from the first `sample_rows` rows.
+/// Headers that are empty are named `column_N`, and repeated names get a
+/// numeric suffix so the generated table is valid.
+pub fn infer_columns(
+    header: &[String],
+    rows: &[Vec<String>],
+    sample_rows: usize,
+) -> Vec<CsvColumn> {
+    let mut candidates = header.iter().map(|_| Candidates::new()).collect::<Vec<_>>();
+    for row in rows.iter().take(sample_rows) {
+        for (candidate, raw) in candidates.iter_mut().zip(row) {
+            if !raw.is_empty() {
+                candidate.observe(raw);
+            }
+        }
+    }
+    let mut used = Vec::<String>::new();
+    header
+        .iter()
+        .zip(candidates)
+        .enumerate()
+        .map(|(index, (name, candidate))| {
+            let base = if name.is_empty() {
+                format!("column_{}", index + 1)
+            } else {
+                name.clone()
+            };
+            let mut name = base.clone();
+            let mut suffix = 2;
+            while used.iter().any(|other| other.eq_ignore_ascii_case(&name)) {
+                name = format!("{base}_{suffix}");
+                suffix += 1;
+            }
+            used.push(name.clone());
+            CsvColumn {
+                name,
+                column_type: candidate.resolve(),
+            }
+        })
+        .collect()
+}
+
+/// Renders the `CREATE TABLE` statement for the inferred columns.
+pub fn create_table_sql(table: &str, columns: &[CsvColumn]) -> String {
+    let columns = columns
+        .iter()
+        .map(|column| {
+            format!(
+                "{} {}",
+                quote_identifier(&column.name),
+                column.column_type.sql()
+            )
+        })
+        .collect::<Vec<_>>()
+        .join(", ");
+    format!("CREATE TABLE {} ({columns})", quote_identifier(table))
+}
+
+/// Converts one CSV row to values for the inferred columns. `row_number` is
+/// the 1-based data row, used in the error for a value that does not fit.
+pub fn convert_row(columns: &[CsvColumn], row: &[String], row_number: usize) -> Result<Vec<Value>> {
+    if row.len() > columns.len() {
+        return Err(anyhow!(
+            "row {row_number} has {} fields but the header has {}",
+            row.len(),
+            columns.len()
+        ));
+    }
+    columns
+        .iter()
+        .enumerate()
+        .map(|(index, column)| {
+            let raw = row.get(index).map(String::as_str).unwrap_or("");
+            convert_value(column.column_type, raw).ok_or_else(|| {
+                anyhow!(
+                    "row {row_number}, column {}: {raw:?} is not a valid {}; \
+                     the type was inferred from a sample, raise --sampleRows to inspect more rows",
+                    column.name,
+                    column.column_type.sql()
+                )
+            })
+        })
+        .collect()
+}
+
+/// Returns a table name derived from a file stem, with every character that
+/// would need quoting replaced by `_`.
+pub fn table_name_from_stem(stem: &str) -> String {
+    let mut name = stem
+        .chars()
+        .map(|ch| if ch.is_ascii_alphanumeric() { ch } else { '_' })
+        .collect::<String>();
+    if name.is_empty() || name.starts_with(|ch: char| ch.is_ascii_digit()) {
+        name.insert(0, '_');
+    }
+    name
+}
+
+fn convert_value(column_type: CsvType, raw: &str) -> Option<Value> {
+    if raw.is_empty() {
+        return Some(Value::Null);
+    }
+    match column_type {
+        CsvType::Bool => parse_bool(raw).map(Value::Bool),
+        CsvType::Int64 => parse_int(raw).map(Value::Int64),
+        CsvType::Float64 => parse_float(raw).map(Value::Float64),
+        CsvType::Decimal { scale } => decimal_scale(raw)
+            .filter(|value_scale| *value_scale <= scale)
+            .map(|_| Value::Text(raw.to_string())),
+        // The engine parses the text into the column type on insert.
+        CsvType::Timestamp => is_timestamp(raw).then(|| Value::Text(raw.to_string())),
+        CsvType::Text => Some(Value::Text(raw.to_string())),
+    }
+}
+
+fn quote_identifier(name: &str) -> String {
+    format!("\"{}\"", name.replace('"', "\"\""))
+}
+
+fn parse_bool(raw: &str) -> Option<bool> {
+    if raw.eq_ignore_ascii_case("true") {
+        Some(true)
+    } else if raw.eq_ignore_ascii_case("false") {
+        Some(false)
+    } else {
+        None
+    }
+}
+
+/// Splits an optional sign off a numeric literal and rejects integer parts
+/// with leading zeros, which usually mark identifiers such as postal codes.
+fn unsigned_number(raw: &str) -> Option<&str> {
+    let digits = raw.strip_prefix(['-', '+']).unwrap_or(raw);
+    let integer = digits.split('.').next().unwrap_or("");
+    if integer.len() > 1 && integer.starts_with('0') {
+        return None;
+    }
+    Some(digits)
+}
+
+fn parse_int(raw: &str) -> Option<i64> {
+    let digits = unsigned_number(raw)?;
+    if digits.is_empty() || !digits.bytes().all(|byte| byte.is_ascii_digit()) {
+        return None;
+    }
+    raw.parse().ok()
+}
+
+fn parse_float(raw: &str) -> Option<f64> {
+    unsigned_number(raw)?;
+    // Rust also parses `inf` and `NaN`; those are more likely text.
+    if !raw
+        .bytes()
+        .all(|byte| byte.is_ascii_digit() || matches!(byte, b'-' | b'+' | b'.' | b'e' | b'E'))
+    {
+        return None;
+    }
+    raw.parse().ok()
+}
+
+/// Returns the number of fractional digits of a fixed-point literal that fits
+/// a `DECIMAL` column, or `None` for anything else.
+fn decimal_scale(raw: &str) -> Option<u8> {
+    let digits = unsigned_number(raw)?;
+    let (integer, fraction) = digits.split_once('.').unwrap_or((digits, ""));
+    if integer.is_empty() && fraction.is_empty()
+        || !integer.bytes().all(|byte| byte.is_ascii_digit())
+        || !fraction.bytes().all(|byte| byte.is_ascii_digit())
+        || integer.len() + fraction.len() > DECIMAL_MAX_DIGITS
+    {
+        return None;
+    }
+    u8::try_from(fraction.len()).ok()
+}
+
+/// Accepts the ISO-like forms the engine parses into a `TIMESTAMP`: a
+/// `YYYY-MM-DD` date, optionally followed by `T` or a space, `HH:MM:SS`,
+/// fractional seconds, and a `Z` or `+HH:MM` offset.
+fn is_timestamp(raw: &str) -> bool {
+    let bytes = raw.as_bytes();
+    if bytes.len() < 10 || !is_date(&bytes[..10]) {
+        return false;
+    }
+    let rest = &bytes[10..];
+    if rest.is_empty() {
+        return true;
+    }
+    if !matches!(rest[0], b'T' | b' ') || rest.len() < 9 || !is_time(&rest[1..9]) {
+        return false;
+    }
+    let mut rest = &rest[9..];
+    if let Some(fraction) = rest.strip_prefix(b".") {
+        let digits = fraction
+            .iter()
+            .take_while(|byte| byte.is_ascii_digit())
+            .count();
+        if digits == 0 {
+            return false;
+        }
+        rest = &fraction[digits..];
+    }
+    match rest {
+        [] | [b'Z'] => true,
+        [b'+' | b'-', offset @ ..] => {
+            offset.len() == 5
+                && offset[2] == b':'
+                && two_digits(&offset[..2]).is_some_and(|hours| hours < 24)
+                && two_digits(&offset[3..]).is_some_and(|minutes| minutes < 60)
+        }
+        _ => false,
+    }
+}
+
+fn is_date(bytes: &[u8]) -> bool {
+    if bytes[4] != b'-' || bytes[7] != b'-' || !bytes[..4].iter().all(u8::is_ascii_digit) {
+        return false;
+    }
+    let year = bytes[..4]
+        .iter()
+        .fold(0_u32, |year, byte| year * 10 + u32::from(byte - b'0'));
+    let (Some(month), Some(day)) = (two_digits(&bytes[5..7]), two_digits(&bytes[8..10])) else {
+        return false;
+    };
+    let leap = year % 4 == 0 && (year % 100 != 0 || year % 400 == 0);
+    let days = match month {
+        1 | 3 | 5 | 7 | 8 | 10 | 12 => 31,
+        4 | 6 | 9 | 11 => 30,
+        2 if leap => 29,
+        2 => 28,
+        _ => return false,
+    };
+    (1..=days).contains(&day)
+}
+
+fn is_time(bytes: &[u8]) -> bool {
+    bytes[2] == b':'
+        && bytes[5] == b':'
+        && two_digits(&bytes[..2]).is_some_and(|hours| hours < 24)
+        && two_digits(&bytes[3..5]).is_some_and(|minutes| minutes < 60)
+        && two_digits(&bytes[6..8]).is_some_and(|seconds| seconds < 60)
+}
+
+fn two_digits(bytes: &[u8]) -> Option<u32> {
+    match bytes {
+        [tens @ b'0'..=b'9', ones @ b'0'..=b'9'] => {
+            Some(u32::from(tens - b'0') * 10 + u32::from(ones - b'0'))
+        }
+        _ => None,
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn strings(values: &[&str]) -> Vec<String> {
+        values.iter().map(|value| value.to_string()).collect()
+    }
+
+    fn infer(values: &[&str]) -> CsvType {
+        let rows = values
+            .iter()
+            .map(|value| strings(&[value]))
+            .collect::<Vec<_>>();
+        infer_columns(&strings(&["c"]), &rows, usize::MAX)[0].column_type
+    }
+
+    #[test]
+    fn infers_the_narrowest_type_for_each_column() {
+        assert_eq!(infer(&["true", "FALSE", ""]), CsvType::Bool);
+        assert_eq!(infer(&["1", "-20", "+3"]), CsvType::Int64);
+        assert_eq!(
+            infer(&["12.50", "3", "-0.75"]),
+            CsvType::Decimal { scale: 2 }
+        );
+        assert_eq!(infer(&["1.5", "2.25"]), CsvType::Float64);
+        assert_eq!(infer(&["1e3", "2"]), CsvType::Float64);
+        assert_eq!(
+            infer(&[
+                "2024-02-29",
+                "2024-03-01 12:30:00",
+                "2024-03-01T12:30:00.5+02:00"
+            ]),
+            CsvType::Timestamp
+        );
+        assert_eq!(infer(&["", ""]), CsvType::Text);
+    }
+
+    #[test]
+    fn values_that_look_numeric_but_are_not_stay_text() {
+        assert_eq!(infer(&["02134", "10001"]), CsvType::Text);
+        assert_eq!(infer(&["inf", "1"]), CsvType::Text);
+        assert_eq!(infer(&["NaN"]), CsvType::Text);
+        assert_eq!(infer(&["2023-02-29"]), CsvType::Text);
+        assert_eq!(infer(&["2024-01-01 25:00:00"]), CsvType::Text);
+        assert_eq!(infer(&["1", "yes"]), CsvType::Text);
+    }
+
+    #[test]
+    fn header_names_are_filled_in_and_deduplicated() {
+        let columns = infer_columns(&strings(&["id", "", "ID", "name"]), &[], 10);
+        let names = columns
+            .iter()
+            .map(|column| column.name.as_str())
+            .collect::<Vec<_>>();
+        assert_eq!(names, vec!["id", "column_2", "ID_2", "name"]);
+        assert_eq!(
+            create_table_sql("my \"data\"", &columns[..2]),
+            "CREATE TABLE \"my \"\"data\"\"\" (\"id\" TEXT, \"column_2\" TEXT)"
+        );
+    }
+
+    #[test]
+    fn sampling_limits_inference_and_conversion_reports_misfits() {
+        let rows = vec![strings(&["1"]), strings(&["2"]), strings(&["x"])];
+        let columns = infer_columns(&strings(&["n"]), &rows, 2);
+        assert_eq!(columns[0].column_type, CsvType::Int64);
+        assert_eq!(
+            convert_row(&columns, &rows[1], 2).unwrap(),
+            vec![Value::Int64(2)]
+        );
+        let error = convert_row(&columns, &rows[2], 3).unwrap_err().to_string();
+        assert!(error.contains("row 3, column n"), "{error}");
+        assert!(error.contains("--sampleRows"), "{error}");
+    }
+
+    #[test]
+    fn short_rows_are_padded_with_nulls() {
+        let columns = infer_columns(&strings(&["a", "b"]), &[strings(&["1", "2"])], 10);
+        assert_eq!(
+            convert_row(&columns, &strings(&["5"]), 1).unwrap(),
+            vec![Value::Int64(5), Value::Null]
+        );
+        assert!(convert_row(&columns, &strings(&["1", "2", "3"]), 2).is_err());
+    }
+
+    #[test]
+    fn table_names_come_from_the_file_stem() {
+        assert_eq!(table_name_from_stem("sales-2024"), "sales_2024");
+        assert_eq!(table_name_from_stem("2024 sales"), "_2024_sales");
+    }
+}
@@ -0,0 +1,291 @@
+//! Rendering for `decentdb explain`.
+//!
+//! The engine reports a plan as `EXPLAIN` lines: one operator per line, written
+//! `Operator(details, estRows=N, estCost=C)` and indented two spaces per level
+//! below its parent. [`parse_plan`] turns those lines back into a tree so it can
+//! be printed as an annotated outline, JSON, or a Graphviz graph. Lines that are
+//! not operators, such as the `Actual Rows:` summary of `EXPLAIN ANALYZE`, are
+//! kept as notes.
+
+use clap::ValueEnum;
+use serde::Serialize;
+
+#[derive(Clone, Copy, Debug, PartialEq, Eq, ValueEnum)]
+pub enum ExplainFormat {
+    Text,
+    Json,
+    Dot,
+}
+
+#[derive(Clone, Debug, PartialEq, Serialize)]
+pub struct PlanNode {
+    pub operator: String,
+    pub details: String,
+    pub est_rows: Option<u64>,
+    pub est_cost: Option<f64>,
+    pub children: Vec<PlanNode>,
+}
+
+#[derive(Clone, Debug, Default, PartialEq, Serialize)]
+pub struct Plan {
+    pub roots: Vec<PlanNode>,
+    pub notes: Vec<String>,
+}
+
+/// Rebuilds the operator tree from the engine's `EXPLAIN` lines.
+pub fn parse_plan(lines: &[String]) -> Plan {
+    let mut plan = Plan::default();
+    // Ancestors of the next node, each with the depth it was rendered at.
+    let mut stack: Vec<(usize, PlanNode)> = Vec::new();
+    for line in lines {
+        let trimmed = line.trim_start_matches(' ');
+        let depth = (line.len() - trimmed.len()) / 2;
+        let Some(node) = parse_operator(trimmed) else {
+            plan.notes.push(line.trim().to_string());
+            continue;
+        };
+        while stack.last().is_some_and(|(top, _)| *top >= depth) {
+            attach(&mut stack, &mut plan.roots);
+        }
+        stack.push((depth, node));
+    }
+    while !stack.is_empty() {
+        attach(&mut stack, &mut plan.roots);
+    }
+    plan
+}
+
+fn attach(stack: &mut Vec<(usize, PlanNode)>, roots: &mut Vec<PlanNode>) {
+    if let Some((_, node)) = stack.pop() {
+        match stack.last_mut() {
+            Some((_, parent)) => parent.children.push(node),
+            None => roots.push(node),
+        }
+    }
+}
+
+fn parse_operator(line: &str) -> Option<PlanNode> {
+    let open = line.find('(')?;
+    let operator = &line[..open];
+    if operator.is_empty()
+        || !operator.starts_with(|c: char| c.is_ascii_uppercase())
+        || !operator.chars().all(|c| c.is_ascii_alphanumeric())
+    {
+        return None;
+    }
+    let mut args = line[open + 1..].strip_suffix(')')?.trim();
+    let mut est_cost = None;
+    if let Some((rest, cost)) = split_trailing_arg(args, "estCost=") {
+        est_cost = cost.parse().ok();
+        args = rest;
+    }
+    let mut est_rows = None;
+    if let Some((rest, rows)) = split_trailing_arg(args, "estRows=") {
+        est_rows = rows.parse().ok();
+        args = rest;
+    }
+    Some(PlanNode {
+        operator: operator.to_string(),
+        details: args.trim_end_matches([' ', ',']).to_string(),
+        est_rows,
+        est_cost,
+        children: Vec::new(),
+    })
+}
+
+/// Splits a trailing `key=value` argument off an argument list, returning the
+/// arguments before it and the value.
+fn split_trailing_arg<'a>(args: &'a str, key: &str) -> Option<(&'a str, &'a str)> {
+    let start = args.rfind(key)?;
+    let (rest, arg) = args.split_at(start);
+    if !(rest.is_empty() || rest.ends_with(", ")) || arg.contains(',') {
+        return None;
+    }
+    Some((rest.trim_end_matches(", "), &arg[key.len()..]))
+}
+
+pub fn render_plan(plan: &Plan, format: ExplainFormat) -> String {
+    match format {
+        ExplainFormat::Text => render_text(plan),
+        ExplainFormat::Json => serde_json::to_string_pretty(plan).expect("plan serializes to JSON"),
+        ExplainFormat::Dot => render_dot(plan),
+    }
+}
+
+fn estimate_label(node: &PlanNode) -> String {
+    match (node.est_rows, node.est_cost) {
+        (Some(rows), Some(cost)) => format!("rows={rows} cost={cost:.3}"),
+        (Some(rows), None) => format!("rows={rows}"),
+        (None, Some(cost)) => format!("cost={cost:.3}"),
+        (None, None) => String::new(),
+    }
+}
+
+fn render_text(plan: &Plan) -> String {
+    let mut output = Vec::new();
+    for root in &plan.roots {
+        render_text_node(root, "", true, &mut output);
+    }
+    if !plan.notes.is_empty() {
+        if !output.is_empty() {
+            output.push(String::new());
+        }
+        output.extend(plan.notes.iter().cloned());
+    }
+    output.join("\n")
+}
+
+fn render_text_node(node: &PlanNode, indent: &str, root: bool, output: &mut Vec<String>) {
+    let mut line = if root {
+        node.operator.clone()
+    } else {
+        format!("{indent}-> {}", node.operator)
+    };
+    if !node.details.is_empty() {
+        line.push_str(&format!("  ({})", node.details));
+    }
+    let estimate = estimate_label(node);
+    if !estimate.is_empty() {
+        line.push_str(&format!("  [{estimate}]"));
+    }
+    output.push(line);
+    let child_indent = if root {
+        "  ".to_string()
+    } else {
+        format!("{indent}     ")
+    };
+    for child in &node.children {
+        render_text_node(child, &child_indent, false, output);
+    }
+}
+
+fn render_dot(plan: &Plan) -> String {
+    let mut output = vec![
+        "digraph plan {".to_string(),
+        "  node [shape=box, fontname=\"monospace\"];".to_string(),
+    ];
+    let mut next_id = 0;
+    for root in &plan.roots {
+        render_dot_node(root, &mut next_id, &mut output);
+    }
+    if !plan.notes.is_empty() {
+        let label = plan
+            .notes
+            .iter()
+            .map(|note| format!("{}\\l", dot_escape(note)))
+            .collect::<String>();
+        output.push(format!("  notes [shape=note, label=\"{label}\"];"));
+    }
+    output.push("}".to_string());
+    output.join("\n")
+}
+
+fn render_dot_node(node: &PlanNode, next_id: &mut usize, output: &mut Vec<String>) -> usize {
+    let id = *next_id;
+    *next_id += 1;
+    let mut label = dot_escape(&node.operator);
+    if !node.details.is_empty() {
+        label.push_str("\\n");
+        label.push_str(&dot_escape(&node.details));
+    }
+    let estimate = estimate_label(node);
+    if !estimate.is_empty() {
+        label.push_str("\\n");
+        label.push_str(&estimate);
+    }
+    output.push(format!("  n{id} [label=\"{label}\"];"));
+    for child in &node.children {
+        let child_id = render_dot_node(child, next_id, output);
+        output.push(format!("  n{id} -> n{child_id};"));
+    }
+    id
+}
+
+fn dot_escape(text: &str) -> String {
+    text.replace('\\', "\\\\").replace('"', "\\\"")
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn lines(lines: &[&str]) -> Vec<String> {
+        lines.iter().map(|line| line.to_string()).collect()
+    }
+
+    fn sample() -> Plan {
+        parse_plan(&lines(&[
+            "ANALYZE true",
+            "Limit(limit=5, offset=none, estRows=5, estCost=12.500)",
+            "  Project(name , estRows=40, estCost=12.000)",
+            "    HashJoin(kind=Inner, on=a.id = b.a_id, estRows=40, estCost=11.000)",
+            "      TableScan(table=a, estRows=10, estCost=1.000)",
+            "      StreamingAggregate(group_by=b.a_id, b.kind, estRows=4, estCost=2.000)",
+            "        TableScan(table=b, estRows=20, estCost=2.000)",
+            "Actual Rows: 5",
+        ]))
+    }
+
+    #[test]
+    fn parse_plan_rebuilds_the_operator_tree() {
+        let plan = sample();
+        assert_eq!(plan.notes, vec!["ANALYZE true", "Actual Rows: 5"]);
+        assert_eq!(plan.roots.len(), 1);
+        let limit = &plan.roots[0];
+        assert_eq!(limit.operator, "Limit");
+        assert_eq!(limit.details, "limit=5, offset=none");
+        assert_eq!((limit.est_rows, limit.est_cost), (Some(5), Some(12.5)));
+        let join = &limit.children[0].children[0];
+        assert_eq!(limit.children[0].details, "name");
+        assert_eq!(join.operator, "HashJoin");
+        assert_eq!(join.children.len(), 2);
+        assert_eq!(join.children[1].details, "group_by=b.a_id, b.kind");
+        assert_eq!(join.children[1].children[0].details, "table=b");
+    }
+
+    #[test]
+    fn parse_plan_keeps_operators_without_details() {
+        let plan = parse_plan(&lines(&["Empty(estRows=0, estCost=0.000)"]));
+        assert_eq!(plan.roots[0].operator, "Empty");
+        assert_eq!(plan.roots[0].details, "");
+        assert_eq!(plan.roots[0].est_rows, Some(0));
+    }
+
+    #[test]
+    fn text_format_indents_children_with_estimates() {
+        let rendered = render_plan(&sample(), ExplainFormat::Text);
+        let rendered = rendered.lines().collect::<Vec<_>>();
+        assert_eq!(
+            rendered[0],
+            "Limit  (limit=5, offset=none)  [rows=5 cost=12.500]"
+        );
+        assert_eq!(rendered[1], "  -> Project  (name)  [rows=40 cost=12.000]");
+        assert_eq!(
+            rendered[3],
+            "            -> TableScan  (table=a)  [rows=10 cost=1.000]"
+        );
+        assert_eq!(rendered.last(), Some(&"Actual Rows: 5"));
+    }
+
+    #[test]
+    fn dot_format_links_parents_to_children() {
+        let rendered = render_plan(&sample(), ExplainFormat::Dot);
+        assert!(rendered.starts_with("digraph plan {"), "{rendered}");
+        assert!(
+            rendered.contains("n0 [label=\"Limit\\nlimit=5, offset=none\\nrows=5 cost=12.500\"];")
+        );
+        assert!(rendered.contains("n2 -> n3;") && rendered.contains("n2 -> n4;"));
+        assert!(rendered.contains("notes [shape=note"), "{rendered}");
+    }
+
+    #[test]
+    fn json_format_is_the_tree() {
+        let rendered = render_plan(&sample(), ExplainFormat::Json);
+        let value: serde_json::Value = serde_json::from_str(&rendered).expect("json");
+        assert_eq!(value["roots"][0]["operator"], "Limit");
+        assert_eq!(
+            value["roots"][0]["children"][0]["children"][0]["est_rows"],
+            40
+        );
+    }
+}
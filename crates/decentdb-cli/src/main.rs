@@ -1,4 +1,6 @@
 mod commands;
+mod csv_schema;
+mod explain;
 mod output;
 mod repl;
 mod serve;
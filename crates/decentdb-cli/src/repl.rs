@@ -206,6 +206,11 @@ impl ReplSession {
             self.emit(self.render_views()?)?;
             return Ok(true);
         }
+        if let Some(value) = command_arg(trimmed, ".settings") {
+            self.remember_meta(trimmed);
+            self.handle_settings(value)?;
+            return Ok(true);
+        }
         if let Some(mode) = command_arg(trimmed, ".mode") {
             self.remember_meta(trimmed);
             self.set_mode(mode)?;
@@ -462,6 +467,39 @@ impl ReplSession {
         ))
     }
 
+    fn handle_settings(&mut self, value: &str) -> Result<()> {
+        let (name, setting) = match value.split_once(char::is_whitespace) {
+            Some((name, setting)) => (name, setting.trim()),
+            None => (value, ""),
+        };
+        if !setting.is_empty() {
+            self.db.execute(&format!("PRAGMA {name} = {setting}"))?;
+            return Ok(());
+        }
+        let names = if name.is_empty() {
+            ENGINE_SETTINGS.to_vec()
+        } else {
+            vec![name]
+        };
+        let mut rows = Vec::with_capacity(names.len());
+        for name in names {
+            let result = self.db.execute(&format!("PRAGMA {name}"))?;
+            let value = result
+                .rows()
+                .first()
+                .and_then(|row| row.values().first())
+                .map(stringify_value)
+                .unwrap_or_default();
+            rows.push(vec![name.to_string(), value]);
+        }
+        let rendered = render_rows_config(
+            &self.settings,
+            &["name".to_string(), "value".to_string()],
+            &rows,
+        );
+        self.emit(rendered)
+    }
+
     fn set_mode(&mut self, mode: &str) -> Result<()> {
         let mode = required_arg(mode, "mode")?;
         self.settings.format = match mode.to_ascii_lowercase().as_str() {
@@ -744,6 +782,9 @@ fn column_constraints(table: &TableInfo, column: &ColumnInfo) -> String {
     if column.auto_increment {
         constraints.push("AUTO INCREMENT".to_string());
     }
+    if let Some(collation) = &column.collation {
+        constraints.push(format!("COLLATE {collation}"));
+    }
     if let Some(default_sql) = &column.default_sql {
         constraints.push(format!("DEFAULT {default_sql}"));
     }
@@ -792,6 +833,23 @@ fn format_foreign_key_for_column(foreign_key: &ForeignKeyInfo, column_name: &str
     formatted
 }
 
+/// Engine knobs listed by `.settings` with no argument. Each is read and
+/// assigned through the PRAGMA of the same name.
+const ENGINE_SETTINGS: &[&str] = &[
+    "cache_size",
+    "synchronous",
+    "busy_timeout",
+    "foreign_keys",
+    "max_parallel_workers",
+    "read_ahead_pages",
+    "statement_memory_limit_bytes",
+    "max_result_rows",
+    "max_statement_seconds",
+    "application_name",
+    "wal_checkpoint_threshold_pages",
+    "wal_checkpoint_threshold_bytes",
+];
+
 fn print_help(topic: &str) -> String {
     match topic.to_ascii_lowercase().as_str() {
         "" => [
@@ -805,6 +863,7 @@ fn print_help(topic: &str) -> String {
             ".schema [object]      Show schema DDL",
             ".indexes [table]      List indexes",
             ".views                List views",
+            ".settings [name [value]]",
             ".df, .functions       List functions",
             ".g                    Run last SQL command",
             ".s, .history          Show session history",
@@ -832,6 +891,8 @@ fn print_help(topic: &str) -> String {
             ".schema <object> prints DDL for a table, view, index, or trigger.",
             ".indexes [table] lists all indexes or indexes for one table.",
             ".views lists views.",
+            ".settings lists engine settings; .settings <name> shows one.",
+            ".settings <name> <value> runs PRAGMA <name> = <value>.",
         ]
         .join("\n"),
         "output" => [
@@ -1347,10 +1408,12 @@ const BUILTIN_FUNCTIONS: &[(&str, &str)] = &[
     ("extract", "scalar"),
     ("first_value", "window"),
     ("floor", "scalar"),
+    ("gen_random_bytes", "scalar"),
     ("gen_random_uuid", "scalar"),
     ("greatest", "scalar"),
     ("group_concat", "aggregate/window"),
     ("hex", "scalar"),
+    ("hmac", "scalar"),
     ("iif", "scalar"),
     ("initcap", "scalar"),
     ("instr", "scalar"),
@@ -1368,6 +1431,7 @@ const BUILTIN_FUNCTIONS: &[(&str, &str)] = &[
     ("least", "scalar"),
     ("left", "scalar"),
     ("length", "scalar"),
+    ("levenshtein", "scalar"),
     ("ln", "scalar"),
     ("localtime", "scalar"),
     ("localtimestamp", "scalar"),
@@ -1398,6 +1462,7 @@ const BUILTIN_FUNCTIONS: &[(&str, &str)] = &[
     ("quote_literal", "scalar"),
     ("radians", "scalar"),
     ("random", "scalar"),
+    ("random_bytes", "scalar"),
     ("rank", "window"),
     ("regexp_replace", "scalar"),
     ("repeat", "scalar"),
@@ -1409,8 +1474,11 @@ const BUILTIN_FUNCTIONS: &[(&str, &str)] = &[
     ("rpad", "scalar"),
     ("rtrim", "scalar"),
     ("sha256", "scalar"),
+    ("sha512", "scalar"),
     ("sign", "scalar"),
+    ("similarity", "scalar"),
     ("sin", "scalar"),
+    ("soundex", "scalar"),
     ("split_part", "scalar"),
     ("sqrt", "scalar"),
     ("st_area", "spatial"),
@@ -1463,6 +1531,7 @@ const BUILTIN_FUNCTIONS: &[(&str, &str)] = &[
     ("upper", "scalar"),
     ("uuid_parse", "scalar"),
     ("uuid_to_string", "scalar"),
+    ("uuid_v7", "scalar"),
     ("var_pop", "aggregate"),
     ("var_samp", "aggregate"),
     ("variance", "aggregate"),
@@ -1,16 +1,18 @@
 use std::collections::hash_map::DefaultHasher;
+use std::collections::HashMap;
 use std::hash::{Hash, Hasher};
 use std::io::{BufRead, BufReader, Read, Write};
 use std::net::{IpAddr, TcpListener, TcpStream};
 use std::process::Command;
-use std::sync::atomic::{AtomicUsize, Ordering};
-use std::sync::Arc;
+use std::sync::atomic::{AtomicU64, AtomicUsize, Ordering};
+use std::sync::{Arc, Mutex, MutexGuard, PoisonError};
 use std::thread;
 use std::time::{Duration, Instant, SystemTime, UNIX_EPOCH};
 
 use anyhow::{anyhow, Result};
 use decentdb::{Db, DbConfig, DbError, StorageInfo, Value};
 
+use crate::commands::base64_encode;
 use crate::output::stringify_value;
 
 pub struct ServeCommandOptions {
@@ -18,6 +20,7 @@ pub struct ServeCommandOptions {
     pub host: String,
     pub port: u16,
     pub bind: Option<String>,
+    pub http: Option<String>,
     pub read_only: bool,
     pub open: bool,
     pub max_result_rows: usize,
@@ -26,14 +29,19 @@ pub struct ServeCommandOptions {
     pub max_concurrent_requests: usize,
     pub busy_timeout: String,
     pub token_env: Option<String>,
+    pub basic_auth_env: Option<String>,
     pub show_token: bool,
     pub no_auth: bool,
     pub cors_origin: Option<String>,
     pub log_format: String,
+    pub tx_timeout: String,
 }
 
-pub fn run_serve(command: ServeCommandOptions) -> Result<()> {
+pub fn run_serve(mut command: ServeCommandOptions) -> Result<()> {
     validate_limits(&command)?;
+    if command.bind.is_none() {
+        command.bind = command.http.as_deref().map(http_bind_addr);
+    }
 
     let bind_host = command
         .bind
@@ -45,9 +53,14 @@ pub fn run_serve(command: ServeCommandOptions) -> Result<()> {
     if command.no_auth && !localhost_bind {
         return Err(anyhow!("--no-auth is only allowed with localhost binding"));
     }
-    if !localhost_bind && command.token_env.is_none() {
+    if command.no_auth && command.basic_auth_env.is_some() {
+        return Err(anyhow!(
+            "--no-auth cannot be combined with --basic-auth-env"
+        ));
+    }
+    if !localhost_bind && command.token_env.is_none() && command.basic_auth_env.is_none() {
         return Err(anyhow!(
-            "--token-env is required when binding decentdb serve to a non-localhost host"
+            "--token-env or --basic-auth-env is required when binding decentdb serve to a non-localhost host"
         ));
     }
     if matches!(command.cors_origin.as_deref(), Some("*")) {
@@ -63,10 +76,13 @@ pub fn run_serve(command: ServeCommandOptions) -> Result<()> {
     };
     let query_timeout = parse_duration(&command.query_timeout, "--query-timeout")?;
     let busy_timeout = parse_duration(&command.busy_timeout, "--busy-timeout")?;
+    let tx_timeout = parse_duration(&command.tx_timeout, "--tx-timeout")?;
     let max_body_size = parse_byte_size(&command.max_body_size)?;
     let log_format = LogFormat::parse(&command.log_format)?;
     let auth = if command.no_auth {
         AuthMode::Disabled
+    } else if let Some(env_name) = command.basic_auth_env.as_deref() {
+        resolve_basic_auth(env_name)?
     } else {
         AuthMode::Bearer(resolve_token(
             command.token_env.as_deref(),
@@ -101,6 +117,7 @@ pub fn run_serve(command: ServeCommandOptions) -> Result<()> {
 
     let state = Arc::new(ServeState {
         db,
+        db_path: command.db.clone(),
         read_only: command.read_only,
         bind: bound_addr.to_string(),
         max_result_rows: command.max_result_rows,
@@ -115,6 +132,9 @@ pub fn run_serve(command: ServeCommandOptions) -> Result<()> {
         active_requests: AtomicUsize::new(0),
         startup_instant: Instant::now(),
         startup_timestamp: SystemTime::now(),
+        transactions: Mutex::new(HashMap::new()),
+        tx_timeout,
+        next_tx_id: AtomicU64::new(1),
     });
 
     for stream in listener.incoming() {
@@ -153,6 +173,7 @@ pub fn run_serve(command: ServeCommandOptions) -> Result<()> {
 
 struct ServeState {
     db: Db,
+    db_path: String,
     read_only: bool,
     bind: String,
     max_result_rows: usize,
@@ -167,11 +188,24 @@ struct ServeState {
     active_requests: AtomicUsize,
     startup_instant: Instant,
     startup_timestamp: SystemTime,
+    transactions: Mutex<HashMap<String, Arc<Mutex<TransactionSession>>>>,
+    tx_timeout: Duration,
+    next_tx_id: AtomicU64,
+}
+
+/// An explicit transaction opened through `POST /api/v1/tx`. Each session
+/// owns a dedicated handle so its uncommitted writes stay isolated from other
+/// requests until commit.
+struct TransactionSession {
+    db: Db,
+    last_used: Instant,
 }
 
 enum AuthMode {
     Disabled,
     Bearer(String),
+    /// Expected `Authorization` header value, `Basic <base64(user:password)>`.
+    Basic(String),
 }
 
 enum LogFormat {
@@ -356,8 +390,24 @@ fn route_request(
         ("GET", "/api/v1/indexes") => handle_indexes(stream, state),
         ("GET", "/api/v1/views") => handle_views(stream, state),
         ("GET", "/api/v1/triggers") => handle_triggers(stream, state),
-        ("POST", "/api/v1/sql") => handle_sql(stream, state, context, false),
+        ("POST", "/api/v1/sql") | ("POST", "/api/v1/query") | ("POST", "/api/v1/exec") => {
+            handle_sql(stream, state, context, false)
+        }
         ("POST", "/api/v1/explain") => handle_sql(stream, state, context, true),
+        ("POST", "/api/v1/tx") => handle_begin_tx(stream, state),
+        _ if context.method == "POST" && context.path.starts_with("/api/v1/tx/") => {
+            let rest = context.path.trim_start_matches("/api/v1/tx/");
+            match rest.rsplit_once('/') {
+                Some((id, "commit")) => handle_end_tx(stream, state, id, true),
+                Some((id, "rollback")) => handle_end_tx(stream, state, id, false),
+                _ => write_json_response(
+                    stream,
+                    404,
+                    api_error("NOT_FOUND", "resource not found"),
+                    state,
+                ),
+            }
+        }
         _ if context.method == "GET" && context.path.starts_with("/api/v1/tables/") => {
             let name = context.path.trim_start_matches("/api/v1/tables/");
             handle_table_detail(stream, state, name)
@@ -393,6 +443,19 @@ fn authorize(header: Option<&str>, auth: &AuthMode) -> std::result::Result<(), A
                 message: "invalid bearer token",
             }),
         },
+        AuthMode::Basic(expected) => match header {
+            None => Err(AuthError {
+                status: 401,
+                code: "AUTH_REQUIRED",
+                message: "missing basic credentials",
+            }),
+            Some(value) if value == expected => Ok(()),
+            Some(_) => Err(AuthError {
+                status: 401,
+                code: "AUTH_INVALID",
+                message: "invalid basic credentials",
+            }),
+        },
     }
 }
 
@@ -406,6 +469,7 @@ fn handle_api_docs(stream: &mut TcpStream, state: &ServeState) -> Result<u16> {
             "auth": match &state.auth {
                 AuthMode::Disabled => "disabled",
                 AuthMode::Bearer(_) => "bearer",
+                AuthMode::Basic(_) => "basic",
             },
             "routes": [
                 {"method": "GET", "path": "/healthz"},
@@ -418,7 +482,12 @@ fn handle_api_docs(stream: &mut TcpStream, state: &ServeState) -> Result<u16> {
                 {"method": "GET", "path": "/api/v1/views"},
                 {"method": "GET", "path": "/api/v1/triggers"},
                 {"method": "POST", "path": "/api/v1/sql"},
-                {"method": "POST", "path": "/api/v1/explain"}
+                {"method": "POST", "path": "/api/v1/query"},
+                {"method": "POST", "path": "/api/v1/exec"},
+                {"method": "POST", "path": "/api/v1/explain"},
+                {"method": "POST", "path": "/api/v1/tx"},
+                {"method": "POST", "path": "/api/v1/tx/{tx}/commit"},
+                {"method": "POST", "path": "/api/v1/tx/{tx}/rollback"}
             ]
         }),
         state,
@@ -611,6 +680,33 @@ fn handle_sql(
         },
     };
 
+    let session = match payload.get("tx") {
+        None | Some(serde_json::Value::Null) => None,
+        Some(serde_json::Value::String(id)) => match lookup_transaction(state, id) {
+            Some(session) => Some(session),
+            None => {
+                return write_json_response(
+                    stream,
+                    404,
+                    api_error("TX_NOT_FOUND", "transaction not found or expired"),
+                    state,
+                );
+            }
+        },
+        Some(_) => {
+            return write_json_response(
+                stream,
+                400,
+                api_error("INVALID_REQUEST", "tx must be a string"),
+                state,
+            );
+        }
+    };
+    let session_guard = session.as_deref().map(lock_session);
+    let db = session_guard
+        .as_ref()
+        .map_or(&state.db, |session| &session.db);
+
     let request_read_only = payload
         .get("readonly")
         .and_then(serde_json::Value::as_bool)
@@ -633,7 +729,7 @@ fn handle_sql(
 
     let mut contracts = Vec::with_capacity(statements.len());
     for statement in &statements {
-        let contract = match state.db.describe_query_contract(statement) {
+        let contract = match db.describe_query_contract(statement) {
             Ok(contract) => contract,
             Err(error) => {
                 return write_json_response(
@@ -656,7 +752,7 @@ fn handle_sql(
     }
 
     let started = Instant::now();
-    let results = match state.db.execute_batch_with_params(&sql, &params) {
+    let results = match db.execute_batch_with_params(&sql, &params) {
         Ok(results) => results,
         Err(error) => {
             return write_json_response(
@@ -695,6 +791,142 @@ fn handle_sql(
     )
 }
 
+fn handle_begin_tx(stream: &mut TcpStream, state: &ServeState) -> Result<u16> {
+    expire_transactions(state);
+    if state
+        .db
+        .path()
+        .to_string_lossy()
+        .eq_ignore_ascii_case(":memory:")
+    {
+        return write_json_response(
+            stream,
+            400,
+            api_error(
+                "INVALID_REQUEST",
+                "transactions require a file-backed database",
+            ),
+            state,
+        );
+    }
+    let db = Db::open(&state.db_path, DbConfig::default())?;
+    if let Err(error) = db.begin_transaction() {
+        return write_json_response(
+            stream,
+            400,
+            api_engine_error("INVALID_REQUEST", &error),
+            state,
+        );
+    }
+    let id = transaction_id(state);
+    lock_transactions(state).insert(
+        id.clone(),
+        Arc::new(Mutex::new(TransactionSession {
+            db,
+            last_used: Instant::now(),
+        })),
+    );
+    write_json_response(
+        stream,
+        201,
+        serde_json::json!({
+            "ok": true,
+            "tx": id,
+            "timeoutMs": state.tx_timeout.as_millis(),
+        }),
+        state,
+    )
+}
+
+fn handle_end_tx(
+    stream: &mut TcpStream,
+    state: &ServeState,
+    id: &str,
+    commit: bool,
+) -> Result<u16> {
+    expire_transactions(state);
+    let Some(session) = lock_transactions(state).remove(id) else {
+        return write_json_response(
+            stream,
+            404,
+            api_error("TX_NOT_FOUND", "transaction not found or expired"),
+            state,
+        );
+    };
+    let session = lock_session(&session);
+    if commit {
+        match session.db.commit_transaction() {
+            Ok(lsn) => write_json_response(
+                stream,
+                200,
+                serde_json::json!({"ok": true, "lsn": lsn}),
+                state,
+            ),
+            Err(error) => {
+                let _ = session.db.rollback_transaction();
+                write_json_response(
+                    stream,
+                    400,
+                    api_engine_error("INVALID_REQUEST", &error),
+                    state,
+                )
+            }
+        }
+    } else {
+        session.db.rollback_transaction()?;
+        write_json_response(stream, 200, serde_json::json!({"ok": true}), state)
+    }
+}
+
+/// Returns the open session for `id` and refreshes its idle deadline.
+fn lookup_transaction(state: &ServeState, id: &str) -> Option<Arc<Mutex<TransactionSession>>> {
+    expire_transactions(state);
+    let session = lock_transactions(state).get(id).cloned()?;
+    lock_session(&session).last_used = Instant::now();
+    Some(session)
+}
+
+/// Rolls back and drops sessions idle for longer than `--tx-timeout`.
+/// Sessions busy with a request are left alone.
+fn expire_transactions(state: &ServeState) {
+    lock_transactions(state).retain(|_, session| {
+        let Ok(session) = session.try_lock() else {
+            return true;
+        };
+        if session.last_used.elapsed() <= state.tx_timeout {
+            return true;
+        }
+        let _ = session.db.rollback_transaction();
+        false
+    });
+}
+
+fn lock_transactions(
+    state: &ServeState,
+) -> MutexGuard<'_, HashMap<String, Arc<Mutex<TransactionSession>>>> {
+    state
+        .transactions
+        .lock()
+        .unwrap_or_else(PoisonError::into_inner)
+}
+
+fn lock_session(session: &Mutex<TransactionSession>) -> MutexGuard<'_, TransactionSession> {
+    session.lock().unwrap_or_else(PoisonError::into_inner)
+}
+
+fn transaction_id(state: &ServeState) -> String {
+    let sequence = state.next_tx_id.fetch_add(1, Ordering::SeqCst);
+    let now = SystemTime::now()
+        .duration_since(UNIX_EPOCH)
+        .unwrap_or_else(|_| Duration::from_secs(0));
+    let mut hasher = DefaultHasher::new();
+    state.db_path.hash(&mut hasher);
+    std::process::id().hash(&mut hasher);
+    sequence.hash(&mut hasher);
+    now.as_nanos().hash(&mut hasher);
+    format!("tx-{:016x}-{sequence:x}", hasher.finish())
+}
+
 fn render_results(
     state: &ServeState,
     results: &[decentdb::QueryResult],
@@ -864,7 +1096,7 @@ fn write_static_page(stream: &mut TcpStream, state: &ServeState) -> Result<u16>
     let token = match &state.auth {
         AuthMode::Disabled => "null".to_string(),
         AuthMode::Bearer(token) if state.bootstrap_ui_token => serde_json::to_string(token)?,
-        AuthMode::Bearer(_) => "null".to_string(),
+        AuthMode::Bearer(_) | AuthMode::Basic(_) => "null".to_string(),
     };
     let bootstrap = format!(
         "window.DECENTDB_BOOTSTRAP_TOKEN={token};window.DECENTDB_BOOTSTRAP={{readOnly:{},maxRows:{}}};",
@@ -906,6 +1138,9 @@ fn write_response(
         status_reason(status),
         body.len()
     )?;
+    if status == 401 && matches!(state.auth, AuthMode::Basic(_)) {
+        write!(stream, "WWW-Authenticate: Basic realm=\"decentdb\"\r\n")?;
+    }
     if let Some(origin) = &state.cors_origin {
         write!(
             stream,
@@ -921,6 +1156,7 @@ fn write_response(
 fn status_reason(status: u16) -> &'static str {
     match status {
         200 => "OK",
+        201 => "Created",
         204 => "No Content",
         400 => "Bad Request",
         401 => "Unauthorized",
@@ -966,6 +1202,9 @@ fn validate_limits(command: &ServeCommandOptions) -> Result<()> {
     if parse_duration(&command.busy_timeout, "--busy-timeout")?.is_zero() {
         return Err(anyhow!("--busy-timeout must be greater than 0"));
     }
+    if parse_duration(&command.tx_timeout, "--tx-timeout")?.is_zero() {
+        return Err(anyhow!("--tx-timeout must be greater than 0"));
+    }
     Ok(())
 }
 
@@ -990,6 +1229,20 @@ fn resolve_token(token_env: Option<&str>, db: &str, bind_host: &str) -> Result<S
     Ok(format!("ddb-{:016x}-{:x}", hasher.finish(), now.as_nanos()))
 }
 
+/// Reads `user:password` credentials from `env_name` for HTTP basic auth.
+fn resolve_basic_auth(env_name: &str) -> Result<AuthMode> {
+    let credentials = std::env::var(env_name)
+        .map_err(|_| anyhow!("required basic auth env var {env_name} is not set"))?;
+    match credentials.split_once(':') {
+        Some((user, password)) if !user.is_empty() && !password.is_empty() => Ok(AuthMode::Basic(
+            format!("Basic {}", base64_encode(credentials.as_bytes())),
+        )),
+        _ => Err(anyhow!(
+            "basic auth env var {env_name} must contain user:password"
+        )),
+    }
+}
+
 fn print_startup(
     db: &Db,
     read_only: bool,
@@ -1017,6 +1270,7 @@ fn print_startup(
         match auth {
             AuthMode::Disabled => "no auth (localhost only)",
             AuthMode::Bearer(_) => "local browser session",
+            AuthMode::Basic(_) => "HTTP basic auth",
         }
     )?;
     if let (true, AuthMode::Bearer(token)) = (show_token, auth) {
@@ -1125,6 +1379,16 @@ fn parse_duration(raw: &str, flag_name: &str) -> Result<Duration> {
     }
 }
 
+/// Expands a Go-style `--http` listen address; `:8080` listens on all
+/// interfaces.
+fn http_bind_addr(addr: &str) -> String {
+    if addr.starts_with(':') {
+        format!("0.0.0.0{addr}")
+    } else {
+        addr.to_string()
+    }
+}
+
 fn host_from_bind(bind: &str) -> String {
     if let Some(rest) = bind.strip_prefix('[') {
         if let Some((host, _)) = rest.split_once(']') {
@@ -193,6 +193,47 @@ fn describe_command_shows_foreign_keys() {
     assert!(json.contains("REFERENCES artists(id)"));
 }
 
+#[test]
+fn explain_command_renders_plans_as_text_json_and_dot() {
+    let dir = temp_dir();
+    let db = dir.join("explain.ddb");
+    let db_str = db.display().to_string();
+
+    run(&[
+        "exec",
+        "--db",
+        &db_str,
+        "--sql",
+        "CREATE TABLE t (id INT64 PRIMARY KEY, name TEXT); \
+         INSERT INTO t VALUES (1, 'a'), (2, 'b');",
+        "--format",
+        "json",
+    ]);
+    let query = "SELECT name FROM t WHERE name <> 'z' ORDER BY name";
+
+    let text = run(&["explain", "--db", &db_str, query]);
+    assert!(text.contains("-> "), "{text}");
+    assert!(text.contains("rows=") && text.contains("cost="), "{text}");
+
+    let json = run(&["explain", "--db", &db_str, "--format", "json", query]);
+    let plan: serde_json::Value = serde_json::from_str(&json).expect("plan json");
+    assert!(plan["roots"][0]["operator"].is_string(), "{json}");
+    assert!(plan["roots"][0]["children"].is_array(), "{json}");
+
+    let dot = run(&[
+        "explain",
+        "--db",
+        &db_str,
+        "--format",
+        "dot",
+        "--analyze",
+        query,
+    ]);
+    assert!(dot.starts_with("digraph plan {"), "{dot}");
+    assert!(dot.contains("n0 -> n1;"), "{dot}");
+    assert!(dot.contains("Actual Rows: 2"), "{dot}");
+}
+
 #[test]
 fn checkpoint_command_flushes_wal_and_preserves_data_without_wal_file() {
     let dir = temp_dir();
@@ -573,6 +614,59 @@ fn branch_commands_and_exec_branch_work() {
     assert!(deleted.contains("true"));
 }
 
+#[test]
+fn diff_command_reports_rows_and_writes_applicable_patchset() {
+    let dir = temp_dir();
+    let replica = dir.join("replica.ddb");
+    let primary = dir.join("primary.ddb");
+    let replica_str = replica.display().to_string();
+    let primary_str = primary.display().to_string();
+    for (db, rows) in [
+        (&replica_str, "(1, 'same'), (2, 'stale'), (3, 'gone')"),
+        (&primary_str, "(1, 'same'), (2, 'fresh'), (4, 'new')"),
+    ] {
+        run(&[
+            "exec",
+            "--db",
+            db,
+            "--sql",
+            &format!(
+                "CREATE TABLE items (id INT64 PRIMARY KEY, name TEXT); \
+                 INSERT INTO items (id, name) VALUES {rows};"
+            ),
+            "--format",
+            "json",
+        ]);
+    }
+
+    let patchset = dir.join("patch.json");
+    let patchset_str = patchset.display().to_string();
+    let diff = run(&[
+        "diff",
+        &replica_str,
+        &primary_str,
+        "--patchset",
+        &patchset_str,
+        "--format",
+        "json",
+    ]);
+    assert!(diff.contains("\"added_row_count\": 1"));
+    assert!(diff.contains("\"updated_row_count\": 1"));
+    assert!(diff.contains("\"deleted_row_count\": 1"));
+
+    run(&[
+        "sync",
+        "changeset",
+        "apply",
+        "--db",
+        &replica_str,
+        "--input",
+        &patchset_str,
+    ]);
+    let after = run(&["diff", &replica_str, &primary_str, "--format", "json"]);
+    assert!(after.contains("\"changed_table_count\": 0"));
+}
+
 #[test]
 fn import_export_bulk_load_and_maintenance_commands_work() {
     let dir = temp_dir();
@@ -664,6 +758,67 @@ fn import_export_bulk_load_and_maintenance_commands_work() {
     assert!(rebuilt_all.contains("ok"));
 }
 
+#[test]
+fn import_create_infers_schema_from_the_file() {
+    let dir = temp_dir();
+    let db = dir.join("wizard.ddb");
+    let csv = dir.join("sales-2024.csv");
+    fs::write(
+        &csv,
+        "id,amount,paid,placed_at,zip,ratio\n\
+         1,12.50,true,2024-01-05 10:00:00,02134,0.5\n\
+         2,3.00,false,2024-01-06,10001,0.125\n\
+         3,,TRUE,2024-01-07T08:30:00Z,94105,\n",
+    )
+    .expect("write csv");
+    let db_str = db.display().to_string();
+    let csv_str = csv.display().to_string();
+
+    let imported = run(&["import", "--db", &db_str, &csv_str, "--create"]);
+    assert_eq!(imported.trim(), "3");
+
+    let described = run(&[
+        "describe",
+        "--db",
+        &db_str,
+        "--table",
+        "sales_2024",
+        "--format",
+        "json",
+    ]);
+    for expected in ["INT64", "DECIMAL", "BOOL", "TIMESTAMP", "TEXT", "FLOAT64"] {
+        assert!(described.contains(expected), "{expected}: {described}");
+    }
+    let totals = run(&[
+        "exec",
+        "--db",
+        &db_str,
+        "--sql",
+        "SELECT SUM(amount), SUM(CASE WHEN paid THEN 1 ELSE 0 END), MIN(zip) FROM sales_2024",
+        "--format",
+        "csv",
+    ]);
+    assert!(totals.contains("15.5"), "{totals}");
+    assert!(totals.contains(",2,02134"), "{totals}");
+
+    let late = dir.join("late.csv");
+    fs::write(&late, "n\n1\n2\nthree\n").expect("write csv");
+    let (code, _, stderr) = run_result(&[
+        "import",
+        "--db",
+        &db_str,
+        "--input",
+        &late.display().to_string(),
+        "--create",
+        "--table",
+        "late",
+        "--sampleRows",
+        "2",
+    ]);
+    assert_ne!(code, 0);
+    assert!(stderr.contains("row 3, column n"), "{stderr}");
+}
+
 #[test]
 fn header_only_commands_ignore_sparse_huge_wal_files() {
     let dir = temp_dir();
@@ -750,6 +905,8 @@ fn completion_and_repl_smoke_work() {
              .schema items\n\
              .indexes items\n\
              .views\n\
+             .settings busy_timeout 250\n\
+             .settings\n\
              .df\n\
              .mode csv\n\
              .headers off\n\
@@ -795,6 +952,8 @@ fn completion_and_repl_smoke_work() {
     assert!(stdout.contains("CREATE TABLE"));
     assert!(stdout.contains("items_name_idx"));
     assert!(stdout.contains("item_names"));
+    assert!(stdout.contains("wal_checkpoint_threshold_pages"));
+    assert!(stdout.contains("busy_timeout                   | 250"));
     assert!(stdout.contains("length"));
     assert!(stdout.contains("(null)"));
     assert!(stdout.contains("plan"));
@@ -1057,3 +1216,308 @@ fn version_command_reports_engine_version() {
     let version = run(&["version"]);
     assert!(version.contains("DecentDB version:"));
 }
+
+#[test]
+fn dump_filters_and_restore_round_trip() {
+    let dir = temp_dir();
+    let source = dir.join("source.ddb");
+    let target = dir.join("target.ddb");
+    let source_str = source.display().to_string();
+    let target_str = target.display().to_string();
+
+    run(&[
+        "exec",
+        "--db",
+        &source_str,
+        "--sql",
+        "CREATE TABLE users (id INT64 PRIMARY KEY, name TEXT)",
+    ]);
+    run(&[
+        "exec",
+        "--db",
+        &source_str,
+        "--sql",
+        "CREATE TABLE audit (id INT64 PRIMARY KEY, note TEXT)",
+    ]);
+    run(&[
+        "exec",
+        "--db",
+        &source_str,
+        "--sql",
+        "INSERT INTO users (id, name) VALUES (1, 'Ada')",
+    ]);
+
+    let users_only = run(&["dump", &source_str, "--table", "users"]);
+    assert!(users_only.contains("CREATE TABLE \"users\""));
+    assert!(!users_only.contains("\"audit\""));
+
+    let schema_only = run(&["dump", "--db", &source_str, "--schema-only"]);
+    assert!(schema_only.contains("CREATE TABLE \"audit\""));
+    assert!(!schema_only.contains("INSERT INTO"));
+
+    let data_only = run(&["dump", "--db", &source_str, "--data-only"]);
+    assert!(data_only.contains("INSERT INTO"));
+    assert!(!data_only.contains("CREATE TABLE"));
+
+    let (code, _, _) = run_result(&["dump", "--db", &source_str, "--table", "missing"]);
+    assert_ne!(code, 0);
+
+    let dump = run(&["dump", "--db", &source_str]);
+    let mut child = Command::new(bin())
+        .args(["restore", &target_str])
+        .stdin(Stdio::piped())
+        .stdout(Stdio::piped())
+        .stderr(Stdio::piped())
+        .spawn()
+        .expect("spawn restore");
+    child
+        .stdin
+        .as_mut()
+        .expect("stdin")
+        .write_all(dump.as_bytes())
+        .expect("write dump");
+    let output = child.wait_with_output().expect("wait for restore");
+    assert!(
+        output.status.success(),
+        "restore failed: {}",
+        String::from_utf8_lossy(&output.stderr)
+    );
+
+    let restored = run(&[
+        "exec",
+        "--db",
+        &target_str,
+        "--sql",
+        "SELECT name FROM users WHERE id = 1",
+    ]);
+    assert!(restored.contains("Ada"));
+
+    let (code, _, _) = run_result(&[
+        "restore",
+        "--db",
+        &target_str,
+        "--input",
+        &dir.join("nope.sql").display().to_string(),
+    ]);
+    assert_ne!(code, 0);
+}
+
+#[test]
+fn recover_salvages_into_new_file_and_reports_tables() {
+    let dir = temp_dir();
+    let source = dir.join("broken.ddb");
+    let target = dir.join("salvaged.ddb");
+    let source_str = source.display().to_string();
+    let target_str = target.display().to_string();
+
+    run(&[
+        "exec",
+        "--db",
+        &source_str,
+        "--sql",
+        "CREATE TABLE users (id INT64 PRIMARY KEY, name TEXT)",
+    ]);
+    run(&[
+        "exec",
+        "--db",
+        &source_str,
+        "--sql",
+        "INSERT INTO users (id, name) VALUES (1, 'Ada'), (2, 'Grace')",
+    ]);
+
+    let report = run(&["recover", &source_str, &target_str, "--format", "json"]);
+    assert!(report.contains("\"name\": \"users\""));
+    assert!(report.contains("\"rows_recovered\": 2"));
+
+    let salvaged = run(&[
+        "exec",
+        "--db",
+        &target_str,
+        "--sql",
+        "SELECT name FROM users WHERE id = 2",
+    ]);
+    assert!(salvaged.contains("Grace"));
+
+    let (code, _, _) = run_result(&["recover", &source_str, &target_str]);
+    assert_ne!(code, 0);
+}
+
+#[test]
+fn clone_copies_included_tables_with_row_filters() {
+    let dir = temp_dir();
+    let source = dir.join("prod.ddb");
+    let target = dir.join("staging.ddb");
+    let source_str = source.display().to_string();
+    let target_str = target.display().to_string();
+
+    run(&[
+        "exec",
+        "--db",
+        &source_str,
+        "--sql",
+        "CREATE TABLE users (id INT64 PRIMARY KEY, email TEXT)",
+    ]);
+    run(&[
+        "exec",
+        "--db",
+        &source_str,
+        "--sql",
+        "CREATE TABLE orders (id INT64 PRIMARY KEY, total INT64)",
+    ]);
+    run(&[
+        "exec",
+        "--db",
+        &source_str,
+        "--sql",
+        "CREATE TABLE tokens (id INT64 PRIMARY KEY, secret TEXT)",
+    ]);
+    run(&[
+        "exec",
+        "--db",
+        &source_str,
+        "--sql",
+        "INSERT INTO orders (id, total) VALUES (1, 5), (2, 50), (3, 70)",
+    ]);
+
+    let report = run(&[
+        "clone",
+        &source_str,
+        &target_str,
+        "--include",
+        "users,orders",
+        "--where",
+        "orders:total > 10",
+        "--format",
+        "json",
+    ]);
+    assert!(report.contains("\"name\": \"orders\""));
+    assert!(report.contains("\"rows_copied\": 2"));
+
+    let cloned = run(&[
+        "exec",
+        "--db",
+        &target_str,
+        "--sql",
+        "SELECT COUNT(*) FROM orders",
+    ]);
+    assert!(cloned.contains('2'));
+    let (code, _, _) = run_result(&["exec", "--db", &target_str, "--sql", "SELECT * FROM tokens"]);
+    assert_ne!(code, 0);
+
+    let (code, _, _) = run_result(&["clone", &source_str, &target_str]);
+    assert_ne!(code, 0);
+}
+
+#[test]
+fn clone_scrubs_columns_from_config_and_transform_flags() {
+    let dir = temp_dir();
+    let source = dir.join("prod.ddb");
+    let target = dir.join("shared.ddb");
+    let config = dir.join("scrub.json");
+    let source_str = source.display().to_string();
+    let target_str = target.display().to_string();
+    let config_str = config.display().to_string();
+
+    run(&[
+        "exec",
+        "--db",
+        &source_str,
+        "--sql",
+        "CREATE TABLE users (id INT64 PRIMARY KEY, email TEXT, notes TEXT)",
+    ]);
+    run(&[
+        "exec",
+        "--db",
+        &source_str,
+        "--sql",
+        "INSERT INTO users (id, email, notes) VALUES (1, 'ada@example.com', 'vip')",
+    ]);
+    fs::write(
+        &config,
+        r#"{"transforms": {"users": {"email": "'redacted@example.invalid'"}}}"#,
+    )
+    .expect("write config");
+
+    run(&[
+        "clone",
+        &source_str,
+        &target_str,
+        "--config",
+        &config_str,
+        "--transform",
+        "users.notes=NULL",
+    ]);
+    let scrubbed = run(&[
+        "exec",
+        "--db",
+        &target_str,
+        "--sql",
+        "SELECT email, COALESCE(notes, 'scrubbed') FROM users",
+        "--format",
+        "json",
+    ]);
+    assert!(scrubbed.contains("\"rows\":[[\"redacted@example.invalid\",\"scrubbed\"]]"));
+
+    let (code, _, stderr) = run_result(&[
+        "clone",
+        &source_str,
+        &dir.join("bad.ddb").display().to_string(),
+        "--transform",
+        "notes=NULL",
+    ]);
+    assert_ne!(code, 0);
+    assert!(stderr.contains("TABLE.COLUMN=EXPR"));
+}
+
+#[test]
+fn verify_compares_row_counts_and_checksums_against_backup() {
+    let dir = temp_dir();
+    let primary = dir.join("primary.ddb");
+    let backup = dir.join("backup.ddb");
+    let primary_str = primary.display().to_string();
+    let backup_str = backup.display().to_string();
+
+    run(&[
+        "exec",
+        "--db",
+        &primary_str,
+        "--sql",
+        "CREATE TABLE items (id INT64 PRIMARY KEY, name TEXT)",
+    ]);
+    run(&[
+        "exec",
+        "--db",
+        &primary_str,
+        "--sql",
+        "INSERT INTO items (id, name) VALUES (1, 'a'), (2, 'b')",
+    ]);
+    run(&["save-as", "--db", &primary_str, "--output", &backup_str]);
+
+    let report = run(&[
+        "verify",
+        "--db",
+        &primary_str,
+        "--against",
+        &backup_str,
+        "--format",
+        "json",
+    ]);
+    let report: serde_json::Value = serde_json::from_str(&report).expect("verify json");
+    assert_eq!(report["tables"][0]["name"], "items");
+    assert_eq!(report["tables"][0]["left_row_count"], 2);
+    assert_eq!(report["tables"][0]["right_row_count"], 2);
+    assert_eq!(report["tables"][0]["matches"], true);
+
+    run(&[
+        "exec",
+        "--db",
+        &backup_str,
+        "--sql",
+        "UPDATE items SET name = 'z' WHERE id = 2",
+    ]);
+    let (code, stdout, stderr) =
+        run_result(&["verify", "--db", &primary_str, "--against", &backup_str]);
+    assert_ne!(code, 0);
+    assert!(stdout.contains("items"));
+    assert!(stderr.contains("1 of 1 tables differ"));
+}
@@ -164,6 +164,17 @@ fn http_request(
     path: &str,
     body: Option<&str>,
     token: Option<&str>,
+) -> (u16, String, Vec<u8>) {
+    let authorization = token.map(|token_value| format!("Bearer {token_value}"));
+    http_request_with_authorization(port, method, path, body, authorization.as_deref())
+}
+
+fn http_request_with_authorization(
+    port: u16,
+    method: &str,
+    path: &str,
+    body: Option<&str>,
+    authorization: Option<&str>,
 ) -> (u16, String, Vec<u8>) {
     let addr = format!("127.0.0.1:{port}");
     let mut stream = TcpStream::connect(&addr).expect("connect");
@@ -176,8 +187,8 @@ fn http_request(
     if !body.is_empty() {
         request.push_str("Content-Type: application/json\r\n");
     }
-    if let Some(token_value) = token {
-        request.push_str(&format!("Authorization: Bearer {token_value}\r\n"));
+    if let Some(authorization) = authorization {
+        request.push_str(&format!("Authorization: {authorization}\r\n"));
     }
     request.push_str(&format!("Content-Length: {}\r\n", body.len()));
     request.push_str("\r\n");
@@ -444,3 +455,91 @@ fn serve_remote_bind_does_not_bootstrap_token_into_root_page() {
     let info = http_request(port, "GET", "/api/v1/info", None, Some(token));
     assert_eq!(info.0, 200);
 }
+
+#[test]
+fn serve_http_flag_basic_auth_and_transaction_tokens() {
+    let dir = temp_dir("serve-http-tx");
+    let db = dir.join("tx.ddb");
+    setup_db(&db);
+
+    let port_guard = port_allocation_lock().lock().expect("port lock");
+    let port = next_free_port();
+    let child = Command::new(bin())
+        .args([
+            "serve",
+            &db.display().to_string(),
+            "--http",
+            &format!("127.0.0.1:{port}"),
+            "--basic-auth-env",
+            "DECENTDB_SERVE_BASIC",
+        ])
+        .env("DECENTDB_SERVE_BASIC", "admin:s3cret")
+        .stdout(Stdio::null())
+        .stderr(Stdio::null())
+        .spawn()
+        .expect("spawn serve");
+    let _child = ChildGuard(child);
+    wait_for_connect(port);
+    drop(port_guard);
+
+    // base64("admin:s3cret")
+    let auth = Some("Basic YWRtaW46czNjcmV0");
+    let unauth = http_request(port, "GET", "/api/v1/info", None, None);
+    assert_eq!(unauth.0, 401);
+    let wrong = http_request_with_authorization(
+        port,
+        "GET",
+        "/api/v1/info",
+        None,
+        Some("Basic YWRtaW46d3Jvbmc="),
+    );
+    assert_eq!(wrong.0, 401);
+
+    let begin = http_request_with_authorization(port, "POST", "/api/v1/tx", None, auth);
+    assert_eq!(begin.0, 201);
+    let begin_json: serde_json::Value = serde_json::from_str(&begin.1).expect("json");
+    let tx = begin_json["tx"].as_str().expect("tx token").to_string();
+
+    let insert = serde_json::json!({
+        "sql": "INSERT INTO users VALUES ($1, $2)",
+        "params": [4, "Dee"],
+        "tx": tx,
+    });
+    let insert = http_request_with_authorization(
+        port,
+        "POST",
+        "/api/v1/exec",
+        Some(&insert.to_string()),
+        auth,
+    );
+    assert_eq!(insert.0, 200, "{}", insert.1);
+
+    let count_sql = serde_json::json!({"sql": "SELECT COUNT(*) FROM users"}).to_string();
+    let outside =
+        http_request_with_authorization(port, "POST", "/api/v1/query", Some(&count_sql), auth);
+    let outside_json: serde_json::Value = serde_json::from_str(&outside.1).expect("json");
+    assert_eq!(outside_json["results"][0]["rows"][0][0], 3);
+
+    let commit = http_request_with_authorization(
+        port,
+        "POST",
+        &format!("/api/v1/tx/{tx}/commit"),
+        None,
+        auth,
+    );
+    assert_eq!(commit.0, 200, "{}", commit.1);
+
+    let after =
+        http_request_with_authorization(port, "POST", "/api/v1/query", Some(&count_sql), auth);
+    let after_json: serde_json::Value = serde_json::from_str(&after.1).expect("json");
+    assert_eq!(after_json["results"][0]["rows"][0][0], 4);
+
+    let gone = http_request_with_authorization(
+        port,
+        "POST",
+        &format!("/api/v1/tx/{tx}/rollback"),
+        None,
+        auth,
+    );
+    assert_eq!(gone.0, 404);
+}
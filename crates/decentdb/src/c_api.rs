@@ -1,7 +1,7 @@
 //! Stable C ABI for DecentDB.
 
 use std::cell::RefCell;
-use std::ffi::{c_char, CStr, CString};
+use std::ffi::{c_char, c_void, CStr, CString};
 use std::panic::{self, AssertUnwindSafe};
 use std::ptr;
 use std::str::FromStr;
@@ -9,10 +9,12 @@ use std::time::Duration;
 
 use crate::db::PreparedStatement;
 use crate::error::{DbDiagnostic, DbError, DbErrorCode, Result};
+use crate::vfs::external::{register_external_vfs, unregister_external_vfs, DdbVfsMethods};
 use crate::{
-    evict_shared_wal, ChangeStreamOptions, Db, DbConfig, DbEncryptionConfig,
-    ProcessCoordinationMode, QueryResult, QueryWatchOptions, QueuedWriteOptions, RangeWatchOptions,
-    TableWatchOptions, Value, WalSyncMode,
+    evict_shared_wal, normalize_query, ChangeStreamOptions, CloneOptions, Db, DbConfig,
+    DbEncryptionConfig, ProcessCoordinationMode, QueryResult, QueryWatchOptions,
+    QueuedWriteOptions, RangeWatchOptions, RecoveryProgressHook, TableWatchOptions, TempStore,
+    TextCollation, Value, WalSyncMode,
 };
 
 const DDB_OK: u32 = 0;
@@ -223,6 +225,19 @@ pub struct WatchHandle {
     watch: crate::WatchHandle,
 }
 
+#[repr(C)]
+#[derive(Debug)]
+pub struct BackupHandle {
+    reader: crate::BackupReader,
+}
+
+#[cfg(not(all(target_arch = "wasm32", target_os = "unknown")))]
+#[repr(C)]
+#[derive(Debug)]
+pub struct RestoreHandle {
+    writer: crate::db::RestoreWriter,
+}
+
 #[repr(C)]
 #[derive(Debug)]
 pub struct StmtHandle {
@@ -234,6 +249,9 @@ pub struct StmtHandle {
     current_row: Option<usize>,
     next_row_index: usize,
     row_views: Vec<DdbValueView>,
+    /// Result columns the row-view functions return, in order; `None`
+    /// returns every column.
+    view_columns: Option<Vec<usize>>,
     row_i64_text_f64_views: Vec<DdbRowI64TextF64View>,
 }
 
@@ -749,13 +767,33 @@ fn populate_stmt_row_views(stmt: &mut StmtHandle) -> Result<()> {
         .ok_or_else(|| DbError::internal("statement row cursor is out of bounds"))?;
     let values = row.values();
 
-    stmt.row_views.resize(values.len(), DdbValueView::default());
-    for (idx, value) in values.iter().enumerate() {
-        fill_ffi_value_view(&mut stmt.row_views[idx], value);
+    match &stmt.view_columns {
+        None => {
+            stmt.row_views.resize(values.len(), DdbValueView::default());
+            for (idx, value) in values.iter().enumerate() {
+                fill_ffi_value_view(&mut stmt.row_views[idx], value);
+            }
+        }
+        Some(columns) => {
+            stmt.row_views
+                .resize(columns.len(), DdbValueView::default());
+            for (idx, column) in columns.iter().enumerate() {
+                fill_ffi_value_view(&mut stmt.row_views[idx], view_column(values, *column)?);
+            }
+        }
     }
     Ok(())
 }
 
+fn view_column(values: &[Value], column: usize) -> Result<&Value> {
+    values.get(column).ok_or_else(|| {
+        DbError::sql(format!(
+            "row view column index {column} is out of range for {} columns",
+            values.len()
+        ))
+    })
+}
+
 fn row_i64_text_f64_view(result: &QueryResult, row_index: usize) -> Result<DdbRowI64TextF64View> {
     let row = result
         .rows()
@@ -1341,6 +1379,7 @@ fn parse_process_coordination_option(value: &str) -> Result<ProcessCoordinationM
     match value.trim().to_ascii_lowercase().as_str() {
         "auto" => Ok(ProcessCoordinationMode::Auto),
         "required" => Ok(ProcessCoordinationMode::Required),
+        "exclusive" => Ok(ProcessCoordinationMode::Exclusive),
         "single_process_unsafe" | "off" => Ok(ProcessCoordinationMode::SingleProcessUnsafe),
         _ => Err(DbError::sql(format!(
             "invalid process_coordination value: {value}"
@@ -1348,10 +1387,30 @@ fn parse_process_coordination_option(value: &str) -> Result<ProcessCoordinationM
     }
 }
 
+fn parse_text_collation_option(value: &str) -> Result<TextCollation> {
+    match value.trim().to_ascii_lowercase().as_str() {
+        "binary" => Ok(TextCollation::Binary),
+        "nocase" => Ok(TextCollation::NoCase),
+        "rtrim" => Ok(TextCollation::RTrim),
+        _ => Err(DbError::sql(format!(
+            "invalid default_collation value: {value}"
+        ))),
+    }
+}
+
+fn parse_temp_store_option(value: &str) -> Result<TempStore> {
+    match value.trim().to_ascii_lowercase().as_str() {
+        "memory" => Ok(TempStore::Memory),
+        "file" => Ok(TempStore::File),
+        _ => Err(DbError::sql(format!("invalid temp_store value: {value}"))),
+    }
+}
+
 fn parse_wal_sync_mode_option(value: &str, key: &str) -> Result<WalSyncMode> {
     match value.trim().to_ascii_lowercase().as_str() {
         "full" => Ok(WalSyncMode::Full),
         "normal" => Ok(WalSyncMode::Normal),
+        "off" => Ok(WalSyncMode::TestingOnlyUnsafeNoSync),
         other if other.starts_with("async_commit") => {
             let interval_ms = other
                 .strip_prefix("async_commit")
@@ -1590,6 +1649,74 @@ fn db_config_from_options(options: Option<&str>) -> Result<DbConfig> {
             "plan_cache_max_bytes" => {
                 config.plan_cache.max_size_bytes = parse_u64_option(&value, key.as_str())?;
             }
+            "auto_analyze_min_rows" => {
+                config.auto_analyze_min_rows = parse_u64_option(&value, key.as_str())?;
+            }
+            "auto_analyze_churn_percent" => {
+                config.auto_analyze_churn_percent = parse_u32_option(&value, key.as_str())?;
+            }
+            "max_parallel_workers" => {
+                config.max_parallel_workers = parse_usize_option(&value, key.as_str())?;
+            }
+            "read_ahead_pages" => {
+                config.read_ahead_pages = parse_usize_option(&value, key.as_str())?;
+            }
+            "statement_memory_limit_bytes" => {
+                config.statement_memory_limit_bytes = parse_usize_option(&value, key.as_str())?;
+            }
+            "max_result_rows" => {
+                config.max_result_rows = parse_u64_option(&value, key.as_str())?;
+            }
+            "max_statement_seconds" => {
+                config.max_statement_seconds = parse_u64_option(&value, key.as_str())?;
+            }
+            "application_name" => {
+                config.application_name = (!value.is_empty()).then_some(value);
+            }
+            "foreign_keys" => {
+                config.foreign_keys = parse_bool_option(&value, key.as_str())?;
+            }
+            "default_collation" => {
+                config.default_collation = parse_text_collation_option(&value)?;
+            }
+            "temp_store" => {
+                config.temp_store = parse_temp_store_option(&value)?;
+            }
+            "temp_dir" => {
+                if value.trim().is_empty() {
+                    return Err(DbError::sql("temp_dir must not be empty"));
+                }
+                config.temp_dir = std::path::PathBuf::from(value.trim());
+            }
+            "defensive" => {
+                config.defensive = parse_bool_option(&value, key.as_str())?;
+            }
+            "verify_checksums" => {
+                config.verify_checksums = parse_bool_option(&value, key.as_str())?;
+            }
+            "max_database_size_bytes" => {
+                config.max_database_size_bytes = parse_u64_option(&value, key.as_str())?;
+            }
+            "vfs" => {
+                config.vfs = Some(value);
+            }
+            "checkpoint_on_close" => {
+                config.checkpoint_on_close = parse_bool_option(&value, key.as_str())?;
+            }
+            "truncate_wal_on_close" => {
+                config.truncate_wal_on_close = parse_bool_option(&value, key.as_str())?;
+            }
+            "statement_stats" => {
+                let enabled = parse_bool_option(&value, key.as_str())?;
+                config.tracing.statement_stats.enabled = enabled;
+                if enabled {
+                    config.tracing.enabled = true;
+                }
+            }
+            "statement_stats_max" => {
+                config.tracing.statement_stats.max_entries =
+                    parse_usize_option(&value, key.as_str())?.max(1);
+            }
             "wal_sync_mode" | "synchronous" => {
                 config.wal_sync_mode = parse_wal_sync_mode_option(&value, key.as_str())?;
             }
@@ -1712,6 +1839,70 @@ pub extern "C" fn ddb_db_open_or_create_with_options(
     })
 }
 
+/// Host callback for `ddb_db_open_with_recovery_progress`, called with the
+/// frames and bytes of WAL replayed so far and the WAL bytes to replay.
+/// Returning non-zero cancels the open.
+pub type DdbRecoveryProgressFn = unsafe extern "C" fn(*mut c_void, u64, u64, u64) -> i32;
+
+/// A host progress callback and its user data. The pointer is only used
+/// during the open call that installed it, on the calling thread.
+struct HostRecoveryProgress {
+    callback: DdbRecoveryProgressFn,
+    user_data: *mut c_void,
+}
+
+unsafe impl Send for HostRecoveryProgress {}
+unsafe impl Sync for HostRecoveryProgress {}
+
+#[no_mangle]
+/// Opens a database, creating it first when `create_if_missing` is non-zero,
+/// and calls `progress` periodically while WAL frames left by an unclean
+/// shutdown are replayed. `progress` only runs during this call. Returning
+/// non-zero from it fails the open with `DDB_ERR_CANCELED` and leaves the WAL
+/// in place for a later open. `progress` may be NULL.
+pub extern "C" fn ddb_db_open_with_recovery_progress(
+    path: *const c_char,
+    options: *const c_char,
+    create_if_missing: u8,
+    progress: Option<DdbRecoveryProgressFn>,
+    user_data: *mut c_void,
+    out_db: *mut *mut DbHandle,
+) -> u32 {
+    ffi_boundary(|| {
+        let path = utf8_arg(path, "path")?;
+        let options = options_arg(options)?;
+        let mut config = db_config_from_options(options.as_deref())?;
+        if let Some(callback) = progress {
+            let host = HostRecoveryProgress {
+                callback,
+                user_data,
+            };
+            config.recovery_progress = Some(RecoveryProgressHook::new(move |progress| {
+                // Borrow the whole struct so the closure captures it, and
+                // its Send impl, rather than the raw pointer field alone.
+                let host = &host;
+                // SAFETY: the host keeps `user_data` valid for the duration
+                // of the open call, which is the only time recovery runs.
+                unsafe {
+                    (host.callback)(
+                        host.user_data,
+                        progress.frames_replayed,
+                        progress.bytes_replayed,
+                        progress.bytes_total,
+                    ) == 0
+                }
+            }));
+        }
+        let db = if create_if_missing != 0 {
+            Db::open_or_create(path, config)?
+        } else {
+            Db::open(path, config)?
+        };
+        *out_ptr(out_db, "out_db")? = Box::into_raw(Box::new(DbHandle { db }));
+        Ok(())
+    })
+}
+
 #[no_mangle]
 pub extern "C" fn ddb_db_sync_execute_json(
     db: *mut DbHandle,
@@ -1826,6 +2017,29 @@ pub extern "C" fn ddb_db_sync_execute_json(
                 };
                 sync_to_value(summary)?
             }
+            "import_publication_batch" => {
+                let batch = sync_request_json_value_field(request_object, &op, "batch")?;
+                let batch: crate::sync::SyncChangeBatch =
+                    serde_json::from_value(batch).map_err(|error| {
+                        DbError::sql(format!("invalid sync batch request body: {error}"))
+                    })?;
+                let policy = match sync_request_optional_string_field(
+                    request_object,
+                    &op,
+                    "conflict_policy",
+                )? {
+                    Some(policy) => crate::sync::SyncConflictPolicy::from_str(&policy)?,
+                    None => db.db.sync_conflict_policy()?.default_policy,
+                };
+                sync_to_value(db.db.sync_import_publication_batch(&batch, policy)?)?
+            }
+            "peer_watermark" => {
+                let replica_id = sync_request_string_field(request_object, &op, "replica_id")?;
+                sync_to_value(serde_json::json!({
+                    "replica_id": replica_id,
+                    "watermark": db.db.sync_peer_watermark(&replica_id)?,
+                }))?
+            }
             "add_peer" => {
                 let name = sync_request_string_field(request_object, &op, "name")?;
                 let endpoint = sync_request_string_field(request_object, &op, "endpoint")?;
@@ -2760,6 +2974,109 @@ pub extern "C" fn ddb_db_checkpoint(db: *mut DbHandle) -> u32 {
     ffi_boundary(|| handle_ref(db, "db")?.db.checkpoint())
 }
 
+#[no_mangle]
+pub extern "C" fn ddb_db_wal_size(db: *mut DbHandle, out_bytes: *mut u64) -> u32 {
+    ffi_boundary(|| {
+        *out_ptr(out_bytes, "out_bytes")? = handle_ref(db, "db")?.db.wal_size()?;
+        Ok(())
+    })
+}
+
+#[no_mangle]
+/// Blocks until every commit acknowledged before the call is on stable
+/// storage, whatever the open-time `synchronous` mode.
+pub extern "C" fn ddb_db_flush(db: *mut DbHandle) -> u32 {
+    ffi_boundary(|| handle_ref(db, "db")?.db.sync())
+}
+
+#[no_mangle]
+/// Cancels the statement running on `db`, which fails with
+/// `DDB_ERR_CANCELED`. Unlike the other calls on a handle, this one may be
+/// made from any thread while a statement is running; it is a no-op when the
+/// handle is idle.
+pub extern "C" fn ddb_db_interrupt(db: *mut DbHandle) -> u32 {
+    ffi_boundary(|| {
+        handle_ref(db, "db")?.db.interrupt();
+        Ok(())
+    })
+}
+
+#[no_mangle]
+/// Deletes up to about `batch_size` expired rows from each table declared
+/// `WITH (ttl_column = ...)` and stores the number deleted in `out_deleted`.
+pub extern "C" fn ddb_db_sweep_expired_rows(
+    db: *mut DbHandle,
+    batch_size: usize,
+    out_deleted: *mut u64,
+) -> u32 {
+    ffi_boundary(|| {
+        let deleted = handle_ref(db, "db")?.db.sweep_expired_rows(batch_size)?;
+        *out_ptr(out_deleted, "out_deleted")? = deleted;
+        Ok(())
+    })
+}
+
+#[no_mangle]
+pub extern "C" fn ddb_db_wal_archive_enable(db: *mut DbHandle, capacity: usize) -> u32 {
+    ffi_boundary(|| handle_ref(db, "db")?.db.enable_wal_archive(capacity))
+}
+
+#[no_mangle]
+pub extern "C" fn ddb_db_wal_archive_disable(db: *mut DbHandle) -> u32 {
+    ffi_boundary(|| handle_ref(db, "db")?.db.disable_wal_archive())
+}
+
+#[no_mangle]
+/// Takes the oldest archived WAL segment as JSON, or `null` when none is
+/// waiting. The segment's frames are base64-encoded under `frames`.
+pub extern "C" fn ddb_db_wal_archive_next_json(
+    db: *mut DbHandle,
+    out_json: *mut *mut c_char,
+) -> u32 {
+    ffi_boundary(|| {
+        use base64::Engine as _;
+        let segment = handle_ref(db, "db")?.db.next_wal_segment()?;
+        let value = match segment {
+            Some(segment) => serde_json::json!({
+                "sequence": segment.sequence,
+                "start_lsn": segment.start_lsn,
+                "end_lsn": segment.end_lsn,
+                "page_size": segment.page_size,
+                "frames": base64::engine::general_purpose::STANDARD.encode(&segment.frames),
+            }),
+            None => serde_json::Value::Null,
+        };
+        *out_ptr(out_json, "out_json")? = cstring_from_string(to_json_string(&value)?)?;
+        Ok(())
+    })
+}
+
+#[no_mangle]
+/// Opens a session on `db` that shares its page cache, file handles, and
+/// WAL but has its own transaction and session state. Free it with
+/// `ddb_db_free`; it stays valid after `db` is freed.
+pub extern "C" fn ddb_db_open_session(db: *mut DbHandle, out_db: *mut *mut DbHandle) -> u32 {
+    ffi_boundary(|| {
+        let session = handle_ref(db, "db")?.db.open_session()?;
+        *out_ptr(out_db, "out_db")? = Box::into_raw(Box::new(DbHandle { db: session }));
+        Ok(())
+    })
+}
+
+#[no_mangle]
+/// Returns page cache statistics for `db` and the sessions sharing its
+/// cache as JSON.
+pub extern "C" fn ddb_db_page_cache_stats_json(
+    db: *mut DbHandle,
+    out_json: *mut *mut c_char,
+) -> u32 {
+    ffi_boundary(|| {
+        let stats = handle_ref(db, "db")?.db.page_cache_stats()?;
+        *out_ptr(out_json, "out_json")? = cstring_from_string(to_json_string(&stats)?)?;
+        Ok(())
+    })
+}
+
 #[no_mangle]
 pub extern "C" fn ddb_db_begin_transaction(db: *mut DbHandle) -> u32 {
     ffi_boundary(|| handle_ref(db, "db")?.db.begin_transaction())
@@ -2787,6 +3104,23 @@ pub extern "C" fn ddb_db_in_transaction(db: *mut DbHandle, out_flag: *mut u8) ->
     })
 }
 
+#[no_mangle]
+pub extern "C" fn ddb_db_advisory_try_lock(db: *mut DbHandle, key: i64, out_flag: *mut u8) -> u32 {
+    ffi_boundary(|| {
+        *out_ptr(out_flag, "out_flag")? =
+            u8::from(handle_ref(db, "db")?.db.advisory_try_lock(key)?);
+        Ok(())
+    })
+}
+
+#[no_mangle]
+pub extern "C" fn ddb_db_advisory_unlock(db: *mut DbHandle, key: i64, out_flag: *mut u8) -> u32 {
+    ffi_boundary(|| {
+        *out_ptr(out_flag, "out_flag")? = u8::from(handle_ref(db, "db")?.db.advisory_unlock(key)?);
+        Ok(())
+    })
+}
+
 #[no_mangle]
 pub extern "C" fn ddb_db_save_as(db: *mut DbHandle, dest_path: *const c_char) -> u32 {
     ffi_boundary(|| {
@@ -2795,6 +3129,188 @@ pub extern "C" fn ddb_db_save_as(db: *mut DbHandle, dest_path: *const c_char) ->
     })
 }
 
+#[no_mangle]
+/// Starts a streaming backup and transfers ownership of the returned handle
+/// to the caller.
+///
+/// Call `ddb_backup_free` exactly once for each successful call. The handle
+/// pins a WAL snapshot, so checkpoints do not fold the WAL until it is freed.
+pub extern "C" fn ddb_db_backup_begin(
+    db: *mut DbHandle,
+    out_backup: *mut *mut BackupHandle,
+) -> u32 {
+    ffi_boundary(|| {
+        let reader = handle_ref(db, "db")?.db.backup_reader()?;
+        *out_ptr(out_backup, "out_backup")? = Box::into_raw(Box::new(BackupHandle { reader }));
+        Ok(())
+    })
+}
+
+#[no_mangle]
+/// Copies up to `len` bytes of the backup stream into `buf`. `*out_read` is
+/// zero once the stream is complete.
+pub extern "C" fn ddb_backup_read(
+    backup: *mut BackupHandle,
+    buf: *mut u8,
+    len: usize,
+    out_read: *mut usize,
+) -> u32 {
+    ffi_boundary(|| {
+        let backup = handle_mut(backup, "backup")?;
+        if buf.is_null() && len != 0 {
+            return Err(DbError::internal("buf must not be null"));
+        }
+        let chunk = if len == 0 {
+            &mut [][..]
+        } else {
+            // SAFETY: null was checked above and the caller owns `len` writable bytes at `buf`.
+            unsafe { std::slice::from_raw_parts_mut(buf, len) }
+        };
+        *out_ptr(out_read, "out_read")? = backup.reader.read_chunk(chunk)?;
+        Ok(())
+    })
+}
+
+#[no_mangle]
+pub extern "C" fn ddb_backup_free(backup: *mut *mut BackupHandle) -> u32 {
+    ffi_boundary(|| {
+        let backup = out_ptr(backup, "backup")?;
+        if (*backup).is_null() {
+            return Ok(());
+        }
+        // SAFETY: pointer was created by `Box::into_raw` in this module.
+        unsafe {
+            drop(Box::from_raw(*backup));
+        }
+        *backup = ptr::null_mut();
+        Ok(())
+    })
+}
+
+#[cfg(not(all(target_arch = "wasm32", target_os = "unknown")))]
+#[no_mangle]
+/// Reads up to `raw_len` bytes of the backup stream into `raw` and writes
+/// them zstd-compressed at `level` into `out`. `*out_raw_read` is zero once
+/// the stream is complete; `*out_len` is the compressed length. Fails when
+/// `out_cap` is too small for the compressed chunk.
+pub extern "C" fn ddb_backup_read_compressed(
+    backup: *mut BackupHandle,
+    raw: *mut u8,
+    raw_len: usize,
+    level: i32,
+    out: *mut u8,
+    out_cap: usize,
+    out_raw_read: *mut usize,
+    out_len: *mut usize,
+) -> u32 {
+    ffi_boundary(|| {
+        let backup = handle_mut(backup, "backup")?;
+        if raw.is_null() || out.is_null() {
+            return Err(DbError::internal("raw and out must not be null"));
+        }
+        // SAFETY: null was checked above and the caller owns `raw_len`
+        // writable bytes at `raw` and `out_cap` at `out`.
+        let (raw, out) = unsafe {
+            (
+                std::slice::from_raw_parts_mut(raw, raw_len),
+                std::slice::from_raw_parts_mut(out, out_cap),
+            )
+        };
+        let (read, written) = backup.reader.read_compressed_chunk(raw, level, out)?;
+        *out_ptr(out_raw_read, "out_raw_read")? = read;
+        *out_ptr(out_len, "out_len")? = written;
+        Ok(())
+    })
+}
+
+#[cfg(not(all(target_arch = "wasm32", target_os = "unknown")))]
+#[no_mangle]
+/// Creates the database file at `path` for a restore and transfers ownership
+/// of the returned handle to the caller. `path` must not exist.
+///
+/// Call `ddb_restore_free` exactly once for each successful call. Freeing a
+/// handle that `ddb_restore_finish` has not completed removes the file.
+pub extern "C" fn ddb_restore_begin(
+    path: *const c_char,
+    out_restore: *mut *mut RestoreHandle,
+) -> u32 {
+    ffi_boundary(|| {
+        let writer = crate::db::RestoreWriter::create(utf8_arg(path, "path")?)?;
+        *out_ptr(out_restore, "out_restore")? = Box::into_raw(Box::new(RestoreHandle { writer }));
+        Ok(())
+    })
+}
+
+#[cfg(not(all(target_arch = "wasm32", target_os = "unknown")))]
+#[no_mangle]
+/// Appends `len` bytes of database file to a restore. When `compressed` is
+/// non-zero the bytes are one zstd-compressed backup chunk that must
+/// decompress to at most `max_len` bytes; a chunk that does not fails with
+/// `DDB_ERR_CORRUPTION`. `*out_written` is the number of database bytes
+/// appended.
+pub extern "C" fn ddb_restore_write(
+    restore: *mut RestoreHandle,
+    data: *const u8,
+    len: usize,
+    compressed: u8,
+    max_len: usize,
+    out_written: *mut usize,
+) -> u32 {
+    ffi_boundary(|| {
+        let restore = handle_mut(restore, "restore")?;
+        let data = ptr_slice(data, len, "data")?;
+        let written = if compressed != 0 {
+            restore.writer.write_compressed(data, max_len)?
+        } else {
+            restore.writer.write(data)?;
+            data.len()
+        };
+        *out_ptr(out_written, "out_written")? = written;
+        Ok(())
+    })
+}
+
+#[cfg(not(all(target_arch = "wasm32", target_os = "unknown")))]
+#[no_mangle]
+/// Syncs and closes a restored file. `*out_len` is the number of bytes
+/// written and `out_sha256` receives their 32-byte SHA-256.
+pub extern "C" fn ddb_restore_finish(
+    restore: *mut RestoreHandle,
+    out_len: *mut u64,
+    out_sha256: *mut u8,
+) -> u32 {
+    ffi_boundary(|| {
+        let restore = handle_mut(restore, "restore")?;
+        if out_sha256.is_null() {
+            return Err(DbError::internal("out_sha256 must not be null"));
+        }
+        let (len, digest) = restore.writer.finish()?;
+        // SAFETY: null was checked above and the caller owns 32 writable
+        // bytes at `out_sha256`.
+        unsafe { std::slice::from_raw_parts_mut(out_sha256, digest.len()) }
+            .copy_from_slice(&digest);
+        *out_ptr(out_len, "out_len")? = len;
+        Ok(())
+    })
+}
+
+#[cfg(not(all(target_arch = "wasm32", target_os = "unknown")))]
+#[no_mangle]
+pub extern "C" fn ddb_restore_free(restore: *mut *mut RestoreHandle) -> u32 {
+    ffi_boundary(|| {
+        let restore = out_ptr(restore, "restore")?;
+        if (*restore).is_null() {
+            return Ok(());
+        }
+        // SAFETY: pointer was created by `Box::into_raw` in this module.
+        unsafe {
+            drop(Box::from_raw(*restore));
+        }
+        *restore = ptr::null_mut();
+        Ok(())
+    })
+}
+
 #[no_mangle]
 /// Prepares SQL and transfers ownership of the returned statement handle to
 /// the caller.
@@ -2821,6 +3337,7 @@ pub extern "C" fn ddb_db_prepare(
             current_row: None,
             next_row_index: 0,
             row_views: Vec::new(),
+            view_columns: None,
             row_i64_text_f64_views: Vec::new(),
         });
         *out_ptr(out_stmt, "out_stmt")? = Box::into_raw(handle);
@@ -3587,6 +4104,26 @@ pub extern "C" fn ddb_stmt_value_copy(
     })
 }
 
+#[no_mangle]
+/// Restricts the row-view functions of `stmt` to the result columns in
+/// `column_indexes`, in that order, so callers reading a few columns of a
+/// wide row skip converting the rest. A zero `count` restores every
+/// column. The projection stays in effect across steps and resets until
+/// changed.
+pub extern "C" fn ddb_stmt_set_row_view_columns(
+    stmt: *mut StmtHandle,
+    column_indexes: *const usize,
+    count: usize,
+) -> u32 {
+    ffi_boundary(|| {
+        let stmt = handle_mut(stmt, "stmt")?;
+        let columns = ptr_slice(column_indexes, count, "column_indexes")?;
+        stmt.view_columns = (!columns.is_empty()).then(|| columns.to_vec());
+        stmt.row_views.clear();
+        Ok(())
+    })
+}
+
 #[no_mangle]
 pub extern "C" fn ddb_stmt_row_view(
     stmt: *mut StmtHandle,
@@ -3666,7 +4203,10 @@ pub extern "C" fn ddb_stmt_fetch_row_views(
             .as_ref()
             .ok_or_else(|| DbError::internal("statement execution did not produce a result"))?;
         let total_rows = result.rows().len();
-        let col_count = result.columns().len();
+        let col_count = stmt
+            .view_columns
+            .as_ref()
+            .map_or(result.columns().len(), Vec::len);
         let start_index = if include_current_row != 0 {
             stmt.current_row.unwrap_or(stmt.next_row_index)
         } else {
@@ -3692,10 +4232,23 @@ pub extern "C" fn ddb_stmt_fetch_row_views(
         stmt.row_views.resize(view_len, DdbValueView::default());
 
         for row_offset in 0..fetch_rows {
-            let row = &result.rows()[start_index + row_offset];
-            for (col, value) in row.values().iter().enumerate() {
-                let idx = row_offset * col_count + col;
-                fill_ffi_value_view(&mut stmt.row_views[idx], value);
+            let values = result.rows()[start_index + row_offset].values();
+            match &stmt.view_columns {
+                None => {
+                    for (col, value) in values.iter().enumerate() {
+                        let idx = row_offset * col_count + col;
+                        fill_ffi_value_view(&mut stmt.row_views[idx], value);
+                    }
+                }
+                Some(columns) => {
+                    for (col, column) in columns.iter().enumerate() {
+                        let idx = row_offset * col_count + col;
+                        fill_ffi_value_view(
+                            &mut stmt.row_views[idx],
+                            view_column(values, *column)?,
+                        );
+                    }
+                }
             }
         }
 
@@ -3791,6 +4344,21 @@ pub extern "C" fn ddb_db_describe_table_json(
     })
 }
 
+#[no_mangle]
+pub extern "C" fn ddb_db_table_statistics_json(
+    db: *mut DbHandle,
+    name: *const c_char,
+    out_json: *mut *mut c_char,
+) -> u32 {
+    ffi_boundary(|| {
+        let stats = handle_ref(db, "db")?
+            .db
+            .table_statistics(&utf8_arg(name, "name")?)?;
+        *out_ptr(out_json, "out_json")? = cstring_from_string(to_json_string(&stats)?)?;
+        Ok(())
+    })
+}
+
 #[no_mangle]
 pub extern "C" fn ddb_db_get_table_ddl(
     db: *mut DbHandle,
@@ -3885,6 +4453,96 @@ pub extern "C" fn ddb_db_describe_query_json(
     })
 }
 
+#[no_mangle]
+/// Salvages this database into a new database at `dest_path` and returns the
+/// recovery report as JSON. Fails if `dest_path` already exists.
+pub extern "C" fn ddb_db_recover_to_json(
+    db: *mut DbHandle,
+    dest_path: *const c_char,
+    out_json: *mut *mut c_char,
+) -> u32 {
+    ffi_boundary(|| {
+        let dest = utf8_arg(dest_path, "dest_path")?;
+        let report = handle_ref(db, "db")?.db.recover_to(dest)?;
+        *out_ptr(out_json, "out_json")? = cstring_from_string(to_json_string(&report)?)?;
+        Ok(())
+    })
+}
+
+#[no_mangle]
+/// Copies the tables and rows selected by `options_json` into a new database
+/// at `dest_path` and returns the clone report as JSON. `options_json` may be
+/// NULL to clone everything, or an object with an optional `tables` array,
+/// a `where` object mapping table names to row filter expressions, and a
+/// `transforms` object mapping table names to column replacement expressions.
+pub extern "C" fn ddb_db_clone_to_json(
+    db: *mut DbHandle,
+    dest_path: *const c_char,
+    options_json: *const c_char,
+    out_json: *mut *mut c_char,
+) -> u32 {
+    ffi_boundary(|| {
+        let dest = utf8_arg(dest_path, "dest_path")?;
+        let options = if options_json.is_null() {
+            CloneOptions::default()
+        } else {
+            serde_json::from_str(&utf8_arg(options_json, "options_json")?)
+                .map_err(|error| DbError::sql(format!("invalid clone options JSON: {error}")))?
+        };
+        let report = handle_ref(db, "db")?.db.clone_to(dest, &options)?;
+        *out_ptr(out_json, "out_json")? = cstring_from_string(to_json_string(&report)?)?;
+        Ok(())
+    })
+}
+
+#[no_mangle]
+/// Compares this database's rows with the database file at `other_path` and
+/// returns the table-by-table diff report as JSON.
+pub extern "C" fn ddb_db_diff_json(
+    db: *mut DbHandle,
+    other_path: *const c_char,
+    out_json: *mut *mut c_char,
+) -> u32 {
+    ffi_boundary(|| {
+        let other = Db::open(utf8_arg(other_path, "other_path")?, DbConfig::default())?;
+        let report = handle_ref(db, "db")?.db.diff_database(&other)?;
+        *out_ptr(out_json, "out_json")? = cstring_from_string(to_json_string(&report)?)?;
+        Ok(())
+    })
+}
+
+#[no_mangle]
+/// Returns a changeset that, applied to this database, makes its rows match
+/// the database file at `other_path`.
+pub extern "C" fn ddb_db_diff_changeset_json(
+    db: *mut DbHandle,
+    other_path: *const c_char,
+    out_json: *mut *mut c_char,
+) -> u32 {
+    ffi_boundary(|| {
+        let other = Db::open(utf8_arg(other_path, "other_path")?, DbConfig::default())?;
+        let changeset = handle_ref(db, "db")?.db.diff_database_changeset(&other)?;
+        *out_ptr(out_json, "out_json")? = cstring_from_string(to_json_string(&changeset)?)?;
+        Ok(())
+    })
+}
+
+#[no_mangle]
+/// Compares per-table row counts and content checksums with the database file
+/// at `other_path`.
+pub extern "C" fn ddb_db_verify_json(
+    db: *mut DbHandle,
+    other_path: *const c_char,
+    out_json: *mut *mut c_char,
+) -> u32 {
+    ffi_boundary(|| {
+        let other = Db::open(utf8_arg(other_path, "other_path")?, DbConfig::default())?;
+        let report = handle_ref(db, "db")?.db.verify_against(&other)?;
+        *out_ptr(out_json, "out_json")? = cstring_from_string(to_json_string(&report)?)?;
+        Ok(())
+    })
+}
+
 #[no_mangle]
 pub extern "C" fn ddb_db_inspect_storage_state_json(
     db: *mut DbHandle,
@@ -3897,6 +4555,22 @@ pub extern "C" fn ddb_db_inspect_storage_state_json(
     })
 }
 
+#[no_mangle]
+/// Writes the fingerprint of `sql` to `out_fingerprint`: the statement with
+/// literals replaced by `?` and comments, whitespace, and keyword case
+/// normalized. Free the result with `ddb_string_free`.
+pub extern "C" fn ddb_normalize_query(
+    sql: *const c_char,
+    out_fingerprint: *mut *mut c_char,
+) -> u32 {
+    ffi_boundary(|| {
+        let sql = utf8_arg(sql, "sql")?;
+        let fingerprint = normalize_query(sql)?;
+        *out_ptr(out_fingerprint, "out_fingerprint")? = cstring_from_string(fingerprint)?;
+        Ok(())
+    })
+}
+
 #[no_mangle]
 pub extern "C" fn ddb_evict_shared_wal(path: *const c_char) -> u32 {
     ffi_boundary(|| {
@@ -3905,6 +4579,31 @@ pub extern "C" fn ddb_evict_shared_wal(path: *const c_char) -> u32 {
     })
 }
 
+#[no_mangle]
+/// Registers a host-implemented VFS under `name`, replacing any earlier
+/// registration. Databases opened with the `vfs=<name>` option route their
+/// file I/O through `methods`, which is copied. `user_data` and every
+/// callback must stay valid, and be callable from any thread, until the VFS
+/// is unregistered and every database opened with it is closed.
+pub extern "C" fn ddb_vfs_register(name: *const c_char, methods: *const DdbVfsMethods) -> u32 {
+    ffi_boundary(|| {
+        let name = utf8_arg(name, "name")?;
+        let methods = *handle_ref(methods, "methods")?;
+        register_external_vfs(name, methods)
+    })
+}
+
+#[no_mangle]
+/// Removes the VFS registered under `name`. Databases already open with it
+/// keep using it. Unregistering an unknown name is not an error.
+pub extern "C" fn ddb_vfs_unregister(name: *const c_char) -> u32 {
+    ffi_boundary(|| {
+        let name = utf8_arg(name, "name")?;
+        unregister_external_vfs(name)?;
+        Ok(())
+    })
+}
+
 #[no_mangle]
 /// Frees a result handle returned by `ddb_db_execute`.
 ///
@@ -4005,7 +4704,7 @@ pub extern "C" fn ddb_result_value_copy(
 ///
 /// `kind` selects the trace view:
 ///   "slow_queries", "lock_waits", "sessions",
-///   "index_usage", "doctor_findings", "fix_plan"
+///   "index_usage", "doctor_findings", "fix_plan", "statement_stats"
 ///
 /// On success, `out_json` receives an owned JSON string.
 /// The caller must free it with `ddb_string_free`.
@@ -4025,6 +4724,7 @@ pub extern "C" fn ddb_runtime_tracing_snapshot(
             "index_usage" => "SELECT * FROM sys.index_usage",
             "doctor_findings" => "SELECT * FROM sys.doctor_findings",
             "fix_plan" => "SELECT * FROM sys.fix_plan",
+            "statement_stats" => "SELECT * FROM decentdb_stat_statements()",
             _ => return Err(DbError::sql(format!("unknown tracing kind: {kind}"))),
         };
         let result = db.db.execute(sql)?;
@@ -4067,7 +4767,8 @@ pub extern "C" fn ddb_runtime_tracing_snapshot(
 
 /// Reset a specific runtime trace ring buffer.
 ///
-/// `kind` may be "slow_queries", "lock_waits", or "index_usage".
+/// `kind` may be "slow_queries", "lock_waits", "index_usage", or
+/// "statement_stats".
 #[no_mangle]
 pub extern "C" fn ddb_runtime_tracing_reset(db: *mut DbHandle, kind: *const c_char) -> u32 {
     ffi_boundary(|| {
@@ -4236,6 +4937,77 @@ mod tests {
         assert_eq!(ddb_db_free(&mut db), DDB_OK);
     }
 
+    #[test]
+    fn ffi_row_view_columns_project_step_and_fetch() {
+        let mut db = ptr::null_mut();
+        let path = CString::new(":memory:").expect("path");
+        assert_eq!(ddb_db_open_or_create(path.as_ptr(), &mut db), DDB_OK);
+
+        let mut result = ptr::null_mut();
+        for sql in [
+            "CREATE TABLE wide (id INT64 PRIMARY KEY, a TEXT, b TEXT, c INT64)",
+            "INSERT INTO wide VALUES (1, 'a1', 'b1', 10), (2, 'a2', 'b2', 20)",
+        ] {
+            let sql = CString::new(sql).expect("sql");
+            assert_eq!(
+                ddb_db_execute(db, sql.as_ptr(), ptr::null(), 0, &mut result),
+                DDB_OK
+            );
+            assert_eq!(ddb_result_free(&mut result), DDB_OK);
+        }
+
+        let sql = CString::new("SELECT id, a, b, c FROM wide ORDER BY id").expect("sql");
+        let mut stmt = ptr::null_mut();
+        assert_eq!(ddb_db_prepare(db, sql.as_ptr(), &mut stmt), DDB_OK);
+        let projection = [3_usize, 0];
+        assert_eq!(
+            ddb_stmt_set_row_view_columns(stmt, projection.as_ptr(), projection.len()),
+            DDB_OK
+        );
+
+        let mut values = ptr::null();
+        let mut columns = 0_usize;
+        let mut has_row = 0_u8;
+        assert_eq!(
+            ddb_stmt_step_row_view(stmt, &mut values, &mut columns, &mut has_row),
+            DDB_OK
+        );
+        assert_eq!((has_row, columns), (1, 2));
+        let row = unsafe { std::slice::from_raw_parts(values, columns) };
+        assert_eq!((row[0].int64_value, row[1].int64_value), (10, 1));
+
+        let mut rows = 0_usize;
+        assert_eq!(
+            ddb_stmt_fetch_row_views(stmt, 0, 0, &mut values, &mut rows, &mut columns),
+            DDB_OK
+        );
+        assert_eq!((rows, columns), (1, 2));
+        let row = unsafe { std::slice::from_raw_parts(values, columns) };
+        assert_eq!((row[0].int64_value, row[1].int64_value), (20, 2));
+
+        assert_eq!(ddb_stmt_reset(stmt), DDB_OK);
+        let out_of_range = [4_usize];
+        assert_eq!(
+            ddb_stmt_set_row_view_columns(stmt, out_of_range.as_ptr(), 1),
+            DDB_OK
+        );
+        assert_ne!(
+            ddb_stmt_step_row_view(stmt, &mut values, &mut columns, &mut has_row),
+            DDB_OK
+        );
+
+        assert_eq!(ddb_stmt_reset(stmt), DDB_OK);
+        assert_eq!(ddb_stmt_set_row_view_columns(stmt, ptr::null(), 0), DDB_OK);
+        assert_eq!(
+            ddb_stmt_step_row_view(stmt, &mut values, &mut columns, &mut has_row),
+            DDB_OK
+        );
+        assert_eq!(columns, 4);
+
+        assert_eq!(ddb_stmt_free(&mut stmt), DDB_OK);
+        assert_eq!(ddb_db_free(&mut db), DDB_OK);
+    }
+
     #[test]
     fn db_config_options_parse_tuned_profile() {
         let config = db_config_from_options(Some(
@@ -4279,6 +5051,55 @@ mod tests {
         );
     }
 
+    #[test]
+    fn db_config_options_parse_statement_stats() {
+        let config = db_config_from_options(Some("statement_stats=true;statement_stats_max=50"))
+            .expect("statement stats options should parse");
+        assert!(config.tracing.enabled);
+        assert!(config.tracing.statement_stats.enabled);
+        assert_eq!(config.tracing.statement_stats.max_entries, 50);
+        assert!(!config.tracing.slow_query.enabled);
+    }
+
+    #[test]
+    fn db_config_options_parse_synchronous_levels() {
+        for (value, mode) in [
+            ("full", WalSyncMode::Full),
+            ("NORMAL", WalSyncMode::Normal),
+            ("off", WalSyncMode::TestingOnlyUnsafeNoSync),
+            (
+                "async_commit:5",
+                WalSyncMode::AsyncCommit { interval_ms: 5 },
+            ),
+        ] {
+            let config = db_config_from_options(Some(&format!("synchronous={value}")))
+                .expect("synchronous option should parse");
+            assert_eq!(config.wal_sync_mode, mode);
+        }
+        assert!(db_config_from_options(Some("synchronous=extra")).is_err());
+    }
+
+    #[test]
+    fn c_api_flush_after_unsynced_commits() {
+        let dir = tempfile::TempDir::with_prefix("decentdb-c-api-flush").unwrap();
+        let path = CString::new(dir.path().join("flush.ddb").display().to_string()).expect("path");
+        let options = CString::new("synchronous=off").expect("options");
+        let mut db = ptr::null_mut();
+        assert_eq!(
+            ddb_db_open_or_create_with_options(path.as_ptr(), options.as_ptr(), &mut db),
+            DDB_OK
+        );
+        let sql = CString::new("CREATE TABLE t (id INT64 PRIMARY KEY)").expect("sql");
+        let mut result = ptr::null_mut();
+        assert_eq!(
+            ddb_db_execute(db, sql.as_ptr(), ptr::null(), 0, &mut result),
+            DDB_OK
+        );
+        assert_eq!(ddb_result_free(&mut result), DDB_OK);
+        assert_eq!(ddb_db_flush(db), DDB_OK);
+        assert_eq!(ddb_db_free(&mut db), DDB_OK);
+    }
+
     #[test]
     fn db_config_options_reject_unknown_profile() {
         let err = db_config_from_options(Some("profile=fastest")).expect_err("unknown profile");
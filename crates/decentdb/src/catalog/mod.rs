@@ -7,8 +7,10 @@ pub(crate) mod schema;
 
 pub(crate) use objects::CatalogHandle;
 pub(crate) use schema::{
-    identifiers_equal, CatalogState, CheckConstraint, ColumnSchema, ColumnType, EnumLabel,
-    EnumTypeInfo, ForeignKeyAction, ForeignKeyConstraint, IndexColumn, IndexKind, IndexSchema,
-    IndexStats, SchemaInfo, SpatialDimensions, SpatialSubtype, SpatialTypeInfo, TableSchema,
-    TableStats, TriggerEvent, TriggerKind, TriggerSchema, ViewSchema,
+    identifiers_equal, CatalogState, CheckConstraint, ColumnCollation, ColumnSchema, ColumnStats,
+    ColumnType, EnumLabel, EnumTypeInfo, ForeignKeyAction, ForeignKeyConstraint, IndexColumn,
+    IndexKind, IndexSchema, IndexStats, Partition, PartitionBound, PartitionStrategy,
+    PartitionedTable, SchemaInfo, SpatialDimensions, SpatialSubtype, SpatialTypeInfo,
+    TableColumnStats, TableComments, TableSchema, TableStats, TriggerEvent, TriggerKind,
+    TriggerSchema, ViewSchema,
 };
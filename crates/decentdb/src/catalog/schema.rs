@@ -2,6 +2,9 @@
 
 use std::collections::BTreeMap;
 
+use crate::record::row::Row;
+use crate::record::value::Value;
+
 #[must_use]
 pub(crate) fn identifiers_equal(left: &str, right: &str) -> bool {
     left.eq_ignore_ascii_case(right)
@@ -149,12 +152,44 @@ pub(crate) struct ForeignKeyConstraint {
     pub(crate) on_update: ForeignKeyAction,
 }
 
+/// Text collation declared on a column with `COLLATE`. Columns without one
+/// compare exact bytes.
+#[derive(Clone, Copy, Debug, Eq, PartialEq)]
+pub(crate) enum ColumnCollation {
+    /// Compares ASCII letters without regard to case.
+    NoCase,
+    /// Ignores trailing spaces.
+    RTrim,
+}
+
+impl ColumnCollation {
+    #[must_use]
+    pub(crate) fn name(self) -> &'static str {
+        match self {
+            Self::NoCase => "NOCASE",
+            Self::RTrim => "RTRIM",
+        }
+    }
+
+    #[must_use]
+    pub(crate) fn from_name(name: &str) -> Option<Self> {
+        if name.eq_ignore_ascii_case("nocase") {
+            Some(Self::NoCase)
+        } else if name.eq_ignore_ascii_case("rtrim") {
+            Some(Self::RTrim)
+        } else {
+            None
+        }
+    }
+}
+
 #[derive(Clone, Debug, Eq, PartialEq)]
 pub(crate) struct ColumnSchema {
     pub(crate) name: String,
     pub(crate) column_type: ColumnType,
     pub(crate) spatial_type: Option<SpatialTypeInfo>,
     pub(crate) enum_type: Option<EnumTypeInfo>,
+    pub(crate) collation: Option<ColumnCollation>,
     pub(crate) nullable: bool,
     pub(crate) default_sql: Option<String>,
     pub(crate) generated_sql: Option<String>,
@@ -248,6 +283,124 @@ pub(crate) struct IndexStats {
     pub(crate) distinct_key_count: i64,
 }
 
+/// Per-column statistics collected by `ANALYZE`.
+#[derive(Clone, Debug, Eq, PartialEq)]
+pub(crate) struct ColumnStats {
+    pub(crate) column_name: String,
+    pub(crate) null_count: i64,
+    pub(crate) distinct_count: i64,
+    /// Upper bounds of equi-depth histogram buckets over the non-NULL values,
+    /// in ascending order, encoded as one record so the catalog stays `Eq`.
+    /// Empty when the column type has no useful ordering.
+    pub(crate) histogram: Vec<u8>,
+}
+
+/// Column statistics for one table. Unlike [`TableStats`], these survive
+/// later writes as an approximation until the next `ANALYZE`.
+#[derive(Clone, Debug, Eq, PartialEq)]
+pub(crate) struct TableColumnStats {
+    pub(crate) analyzed_row_count: i64,
+    pub(crate) columns: Vec<ColumnStats>,
+}
+
+impl ColumnStats {
+    /// Decodes the histogram bucket bounds.
+    #[must_use]
+    pub(crate) fn histogram_bounds(&self) -> Vec<Value> {
+        if self.histogram.is_empty() {
+            return Vec::new();
+        }
+        Row::decode(&self.histogram)
+            .map(Row::into_values)
+            .unwrap_or_default()
+    }
+}
+
+impl TableColumnStats {
+    #[must_use]
+    pub(crate) fn column(&self, name: &str) -> Option<&ColumnStats> {
+        self.columns
+            .iter()
+            .find(|column| identifiers_equal(&column.column_name, name))
+    }
+}
+
+/// `COMMENT ON` text for one table and its columns. Column keys use the
+/// column's catalog spelling.
+#[derive(Clone, Debug, Default, Eq, PartialEq)]
+pub(crate) struct TableComments {
+    pub(crate) table: Option<String>,
+    pub(crate) columns: BTreeMap<String, String>,
+}
+
+impl TableComments {
+    #[must_use]
+    pub(crate) fn column(&self, name: &str) -> Option<&str> {
+        map_get_ci(&self.columns, name).map(String::as_str)
+    }
+
+    #[must_use]
+    pub(crate) fn is_empty(&self) -> bool {
+        self.table.is_none() && self.columns.is_empty()
+    }
+}
+
+#[derive(Clone, Copy, Debug, Eq, PartialEq)]
+pub(crate) enum PartitionStrategy {
+    Range,
+    Hash,
+}
+
+impl PartitionStrategy {
+    #[must_use]
+    pub(crate) fn as_str(self) -> &'static str {
+        match self {
+            Self::Range => "RANGE",
+            Self::Hash => "HASH",
+        }
+    }
+}
+
+/// Rows a partition accepts. Range bounds hold the SQL text of the bound
+/// expression; `None` stands for `MINVALUE` or `MAXVALUE`.
+#[derive(Clone, Debug, Eq, PartialEq)]
+pub(crate) enum PartitionBound {
+    Range {
+        from: Option<String>,
+        to: Option<String>,
+    },
+    Hash {
+        modulus: u32,
+        remainder: u32,
+    },
+}
+
+#[derive(Clone, Debug, Eq, PartialEq)]
+pub(crate) struct Partition {
+    pub(crate) table_name: String,
+    pub(crate) bound: PartitionBound,
+}
+
+/// A table created `PARTITION BY`. The parent itself is a view over its
+/// partitions, which are ordinary tables created from `template_sql`.
+#[derive(Clone, Debug, Eq, PartialEq)]
+pub(crate) struct PartitionedTable {
+    pub(crate) strategy: PartitionStrategy,
+    pub(crate) key_column: String,
+    /// `CREATE TABLE` statement for the parent's columns and constraints.
+    pub(crate) template_sql: String,
+    pub(crate) partitions: Vec<Partition>,
+}
+
+impl PartitionedTable {
+    #[must_use]
+    pub(crate) fn partition(&self, table_name: &str) -> Option<&Partition> {
+        self.partitions
+            .iter()
+            .find(|partition| identifiers_equal(&partition.table_name, table_name))
+    }
+}
+
 #[derive(Clone, Debug, Eq, PartialEq)]
 pub(crate) struct CatalogState {
     pub(crate) schema_cookie: u32,
@@ -258,6 +411,13 @@ pub(crate) struct CatalogState {
     pub(crate) triggers: BTreeMap<String, TriggerSchema>,
     pub(crate) table_stats: BTreeMap<String, TableStats>,
     pub(crate) index_stats: BTreeMap<String, IndexStats>,
+    pub(crate) column_stats: BTreeMap<String, TableColumnStats>,
+    pub(crate) comments: BTreeMap<String, TableComments>,
+    /// TTL column of each table created `WITH (ttl_column = ...)`, keyed by
+    /// table name.
+    pub(crate) table_ttl: BTreeMap<String, String>,
+    /// Tables created `PARTITION BY`, keyed by parent name.
+    pub(crate) partitioned_tables: BTreeMap<String, PartitionedTable>,
 }
 
 impl CatalogState {
@@ -272,6 +432,10 @@ impl CatalogState {
             triggers: BTreeMap::new(),
             table_stats: BTreeMap::new(),
             index_stats: BTreeMap::new(),
+            column_stats: BTreeMap::new(),
+            comments: BTreeMap::new(),
+            table_ttl: BTreeMap::new(),
+            partitioned_tables: BTreeMap::new(),
         }
     }
 
@@ -316,6 +480,21 @@ impl CatalogState {
     pub(crate) fn trigger(&self, name: &str) -> Option<&TriggerSchema> {
         map_get_ci(&self.triggers, name)
     }
+
+    #[must_use]
+    pub(crate) fn partitioned_table(&self, name: &str) -> Option<&PartitionedTable> {
+        map_get_ci(&self.partitioned_tables, name)
+    }
+
+    /// Returns the name of the partitioned table that `table_name` is a
+    /// partition of.
+    #[must_use]
+    pub(crate) fn partition_parent(&self, table_name: &str) -> Option<&str> {
+        self.partitioned_tables
+            .iter()
+            .find(|(_, parent)| parent.partition(table_name).is_some())
+            .map(|(name, _)| name.as_str())
+    }
 }
 
 #[derive(Clone, Debug, Eq, PartialEq)]
@@ -440,6 +619,10 @@ mod tests {
         assert!(catalog.triggers.is_empty());
         assert!(catalog.table_stats.is_empty());
         assert!(catalog.index_stats.is_empty());
+        assert!(catalog.column_stats.is_empty());
+        assert!(catalog.comments.is_empty());
+        assert!(catalog.table_ttl.is_empty());
+        assert!(catalog.partitioned_tables.is_empty());
     }
 
     #[test]
@@ -503,6 +686,7 @@ mod tests {
             column_type: ColumnType::Int64,
             spatial_type: None,
             enum_type: None,
+            collation: None,
             nullable: false,
             default_sql: None,
             generated_sql: None,
@@ -2,6 +2,7 @@
 
 use std::fmt;
 use std::path::PathBuf;
+use std::sync::Arc;
 
 use crate::error::{DbError, Result};
 use crate::extensions::ExtensionTrustAnchor;
@@ -20,6 +21,12 @@ pub enum ProcessCoordinationMode {
     /// Bypass process coordination. Safe only when one OS process can access
     /// the database file.
     SingleProcessUnsafe,
+    /// Require process coordination and hold the database exclusively for
+    /// the life of the handle. Opening it while any other coordinated handle,
+    /// in this process or another, has it open fails with
+    /// `DbError::Timeout`, and later coordinated opens fail the same way
+    /// until this handle closes.
+    Exclusive,
 }
 
 impl ProcessCoordinationMode {
@@ -29,8 +36,15 @@ impl ProcessCoordinationMode {
             Self::Auto => "auto",
             Self::Required => "required",
             Self::SingleProcessUnsafe => "single_process_unsafe",
+            Self::Exclusive => "exclusive",
         }
     }
+
+    /// Reports whether open must fail when coordination is unavailable.
+    #[must_use]
+    pub(crate) fn requires_coordination(self) -> bool {
+        matches!(self, Self::Required | Self::Exclusive)
+    }
 }
 
 /// WAL sync policy used by the engine.
@@ -59,6 +73,51 @@ pub enum WalSyncMode {
     TestingOnlyUnsafeNoSync,
 }
 
+/// Collation given to `TEXT` columns created without a `COLLATE` clause.
+#[derive(Clone, Copy, Debug, Default, Eq, PartialEq)]
+pub enum TextCollation {
+    /// Compare exact bytes.
+    #[default]
+    Binary,
+    /// Compare ASCII letters case-insensitively.
+    NoCase,
+    /// Ignore trailing spaces.
+    RTrim,
+}
+
+impl TextCollation {
+    pub(crate) fn column_collation(self) -> Option<crate::catalog::ColumnCollation> {
+        match self {
+            Self::Binary => None,
+            Self::NoCase => Some(crate::catalog::ColumnCollation::NoCase),
+            Self::RTrim => Some(crate::catalog::ColumnCollation::RTrim),
+        }
+    }
+}
+
+/// Where a handle keeps the rows of its temporary tables.
+#[derive(Clone, Copy, Debug, Default, Eq, PartialEq)]
+pub enum TempStore {
+    /// Keep temporary table rows in memory for the life of the handle.
+    #[default]
+    Memory,
+    /// Write temporary table rows to files under `DbConfig::temp_dir` while
+    /// no statement is running on the handle, and read them back when the
+    /// next statement starts.
+    File,
+}
+
+impl TempStore {
+    /// Returns the stable option string for this storage mode.
+    #[must_use]
+    pub fn as_str(self) -> &'static str {
+        match self {
+            Self::Memory => "memory",
+            Self::File => "file",
+        }
+    }
+}
+
 /// Application-supplied encryption key material for local TDE.
 ///
 /// DecentDB owns a copy of these bytes so it can derive per-file encryption
@@ -104,6 +163,48 @@ impl Drop for EncryptionKey {
     }
 }
 
+/// Progress of WAL replay while a database opens after an unclean shutdown.
+#[derive(Clone, Copy, Debug, Default, Eq, PartialEq)]
+pub struct RecoveryProgress {
+    /// WAL frames replayed so far.
+    pub frames_replayed: u64,
+    /// WAL bytes replayed so far.
+    pub bytes_replayed: u64,
+    /// WAL bytes to replay in total.
+    pub bytes_total: u64,
+}
+
+/// Callback run periodically while an open replays the WAL. Returning
+/// `false` stops recovery and fails the open with `DbError::Canceled`,
+/// leaving the WAL untouched for a later open.
+#[derive(Clone)]
+pub struct RecoveryProgressHook(Arc<dyn Fn(RecoveryProgress) -> bool + Send + Sync>);
+
+impl RecoveryProgressHook {
+    /// Wraps `hook` for `DbConfig::recovery_progress`.
+    pub fn new(hook: impl Fn(RecoveryProgress) -> bool + Send + Sync + 'static) -> Self {
+        Self(Arc::new(hook))
+    }
+
+    pub(crate) fn report(&self, progress: RecoveryProgress) -> bool {
+        (self.0)(progress)
+    }
+}
+
+impl fmt::Debug for RecoveryProgressHook {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        f.write_str("RecoveryProgressHook")
+    }
+}
+
+impl PartialEq for RecoveryProgressHook {
+    fn eq(&self, other: &Self) -> bool {
+        Arc::ptr_eq(&self.0, &other.0)
+    }
+}
+
+impl Eq for RecoveryProgressHook {}
+
 /// Local transparent data encryption configuration.
 #[derive(Clone, Debug, Eq, PartialEq)]
 pub struct DbEncryptionConfig {
@@ -155,6 +256,13 @@ pub struct DbConfig {
     pub trigram_postings_threshold: usize,
     pub temp_dir: PathBuf,
 
+    /// Where temporary tables keep their rows between statements. With
+    /// `TempStore::File` the rows are written to files under `temp_dir`,
+    /// which are removed when the table is dropped or the handle closes.
+    ///
+    /// Default: `TempStore::Memory`.
+    pub temp_store: TempStore,
+
     /// Optional local transparent data encryption for database, WAL, and sync
     /// journal files.
     ///
@@ -166,6 +274,14 @@ pub struct DbConfig {
     /// Default: `None`.
     pub encryption: Option<DbEncryptionConfig>,
 
+    /// Name of a host-registered VFS (see `ddb_vfs_register`) that database,
+    /// WAL, and sidecar file I/O is routed through instead of the OS
+    /// filesystem. Paths are passed to the VFS unchanged. The VFS must be
+    /// registered before the database is opened.
+    ///
+    /// Default: `None`.
+    pub vfs: Option<String>,
+
     /// Trigger an automatic checkpoint when the in-memory WAL has accumulated
     /// at least this many distinct dirty page versions since the last
     /// checkpoint. `0` disables the page-count trigger. The trigger only
@@ -296,6 +412,29 @@ pub struct DbConfig {
     /// See ADR 0143 — engine-memory plan, open-time checkpoint heuristic.
     pub auto_checkpoint_on_open_mb: u32,
 
+    /// Run a synchronous checkpoint when the last handle on the database
+    /// closes, instead of leaving committed frames in the WAL for the next
+    /// open to replay. Skipped while a transaction or named snapshot is
+    /// still open.
+    ///
+    /// Default: `false`.
+    pub checkpoint_on_close: bool,
+
+    /// Like `checkpoint_on_close`, and then delete the emptied WAL and WAL
+    /// index sidecar files, so a closed database is a single file. Skipped
+    /// under process coordination, where another process may still be
+    /// using the WAL.
+    ///
+    /// Default: `false`.
+    pub truncate_wal_on_close: bool,
+
+    /// Called as an open replays WAL frames left by an unclean shutdown,
+    /// so hosts can show progress for large WALs and cancel the open. It
+    /// only runs during the open call that first attaches to the WAL.
+    ///
+    /// Default: `None`.
+    pub recovery_progress: Option<RecoveryProgressHook>,
+
     /// Advertises that high-level bindings may use the engine-owned write
     /// queue for normal execution paths.
     ///
@@ -355,6 +494,116 @@ pub struct DbConfig {
     /// Default: `4096`.
     pub reactive_max_row_changes_per_event: usize,
 
+    /// Automatically run `ANALYZE` on a table once at least this many of its
+    /// rows have been inserted, updated, or deleted through this handle since
+    /// its statistics were last refreshed. `0` disables auto-analyze.
+    ///
+    /// Default: `0`.
+    pub auto_analyze_min_rows: u64,
+
+    /// Additional churn, as a percentage of the row count recorded by the
+    /// table's last `ANALYZE`, required before auto-analyze runs. Large
+    /// tables therefore wait for proportionally more changes than small ones.
+    ///
+    /// Default: `10`.
+    pub auto_analyze_churn_percent: u32,
+
+    /// Worker threads a single read may use to evaluate a large scan's
+    /// filter. `1` keeps execution on the calling thread and `0` uses one
+    /// worker per available core. Adjustable per handle at runtime with
+    /// `PRAGMA max_parallel_workers`.
+    ///
+    /// Default: `1`.
+    pub max_parallel_workers: usize,
+
+    /// Pages to read ahead when a scan reads the main database file
+    /// sequentially. A read that misses on the page after the one it loaded
+    /// last fetches this many pages with one I/O, which helps cold-cache scans
+    /// on spinning disks and network filesystems. `0` disables read-ahead.
+    /// Adjustable per handle at runtime with `PRAGMA read_ahead_pages`, and
+    /// per statement with a leading `/*+ READ_AHEAD(n) */` comment.
+    ///
+    /// Default: `0`.
+    pub read_ahead_pages: usize,
+
+    /// Bytes a single statement may hold in sorts, grouping and DISTINCT
+    /// hash tables, and materialized intermediate results such as join
+    /// outputs and CTEs. A statement that would exceed it fails with
+    /// `DbError::memory_limit_exceeded` instead of exhausting process memory.
+    /// `0` disables the limit. Adjustable per handle at runtime with
+    /// `PRAGMA statement_memory_limit_bytes`.
+    ///
+    /// Default: `0`.
+    pub statement_memory_limit_bytes: usize,
+
+    /// Rows a single query may return. A query whose result would hold more
+    /// fails with `DbError::result_row_limit_exceeded` instead of handing an
+    /// unbounded result to the caller. Writes, including `RETURNING` rows,
+    /// are not limited. `0` disables the limit. Adjustable per handle at
+    /// runtime with `PRAGMA max_result_rows`.
+    ///
+    /// Default: `0`.
+    pub max_result_rows: u64,
+
+    /// Seconds a single statement may run. A statement still running at the
+    /// deadline fails with `DbError::statement_timeout` at its next
+    /// cancellation point, the same points `Db::interrupt` is observed at.
+    /// `0` disables the limit. Adjustable per handle at runtime with
+    /// `PRAGMA max_statement_seconds`.
+    ///
+    /// Default: `0`.
+    pub max_statement_seconds: u64,
+
+    /// Name of the service using this handle. It is reported in
+    /// `sys.sessions`, `sys.slow_queries`, and the context of audit events so
+    /// activity on a shared database can be attributed. Adjustable per handle
+    /// at runtime with `PRAGMA application_name`.
+    ///
+    /// Default: `None`.
+    pub application_name: Option<String>,
+
+    /// Enforce foreign key constraints on writes through this handle. Bulk
+    /// loads can turn enforcement off and validate afterward with
+    /// `Db::check_foreign_keys`. Adjustable per handle at runtime with
+    /// `PRAGMA foreign_keys`.
+    ///
+    /// Default: `true`.
+    pub foreign_keys: bool,
+
+    /// Collation of `TEXT` columns that `CREATE TABLE` and `ALTER TABLE ADD
+    /// COLUMN` on this handle create without a `COLLATE` clause. The
+    /// collation is stored with each column, so it keeps applying when the
+    /// database is opened with a different setting.
+    ///
+    /// Default: `TextCollation::Binary`.
+    pub default_collation: TextCollation,
+
+    /// Harden the handle for databases and SQL from untrusted sources, such
+    /// as user-uploaded files. Statements that change the schema, security
+    /// commands, and extension commands are refused; statement text is
+    /// capped in size and nesting depth; and open skips the checkpoint that
+    /// `auto_checkpoint_on_open_mb` would otherwise write to the file.
+    ///
+    /// Default: `false`.
+    pub defensive: bool,
+
+    /// Check each page read from the main database file against the
+    /// checksum recorded when it was last written, failing with a
+    /// page-checksum corruption error on mismatch. Checksums are recorded
+    /// whether or not this is set; verification costs one CRC-32C per page
+    /// loaded into the cache.
+    ///
+    /// Default: `false`.
+    pub verify_checksums: bool,
+
+    /// Largest size, in bytes, the database may grow to. A commit that
+    /// would extend the database past it fails with a quota-exceeded error
+    /// and writes nothing; commits that reuse freed pages still succeed.
+    /// `0` disables the limit.
+    ///
+    /// Default: `0`.
+    pub max_database_size_bytes: u64,
+
     /// Connection-level allowlist for enabled Lua extension packages.
     ///
     /// Installed packages are inert until enabled in the database and allowed
@@ -455,7 +704,18 @@ impl DbConfig {
         }
     }
 
+    /// Rejects open options that cannot be used together.
+    pub(crate) fn validate_options(&self) -> Result<()> {
+        if self.temp_store == TempStore::File && self.encryption.is_some() {
+            return Err(DbError::invalid_config(
+                "temp_store=file cannot be combined with encryption; temporary table files are not encrypted",
+            ));
+        }
+        Ok(())
+    }
+
     pub(crate) fn validate_for_create(&self) -> Result<()> {
+        self.validate_options()?;
         if page::is_supported_page_size(self.page_size) {
             Ok(())
         } else {
@@ -499,7 +759,9 @@ impl Default for DbConfig {
             checkpoint_timeout_sec: 30,
             trigram_postings_threshold: 100_000,
             temp_dir: default_temp_dir(),
+            temp_store: TempStore::Memory,
             encryption: None,
+            vfs: None,
             wal_checkpoint_threshold_pages: 4096,
             wal_checkpoint_threshold_bytes: 64 * 1024 * 1024,
             release_freed_memory_after_checkpoint: cfg!(all(
@@ -514,6 +776,9 @@ impl Default for DbConfig {
             paged_row_storage: true,
             retain_paged_row_sources_after_commit: false,
             auto_checkpoint_on_open_mb: 16,
+            checkpoint_on_close: false,
+            truncate_wal_on_close: false,
+            recovery_progress: None,
             write_queue_enabled: false,
             write_queue_capacity: 1024,
             write_queue_default_timeout_ms: 0,
@@ -523,6 +788,19 @@ impl Default for DbConfig {
             reactive_watch_queue_capacity: 1024,
             reactive_watch_queue_max_capacity: 8192,
             reactive_max_row_changes_per_event: 4096,
+            auto_analyze_min_rows: 0,
+            auto_analyze_churn_percent: 10,
+            max_parallel_workers: 1,
+            read_ahead_pages: 0,
+            statement_memory_limit_bytes: 0,
+            max_result_rows: 0,
+            max_statement_seconds: 0,
+            application_name: None,
+            foreign_keys: true,
+            default_collation: TextCollation::Binary,
+            defensive: false,
+            verify_checksums: false,
+            max_database_size_bytes: 0,
             extension_trust_anchors: Vec::new(),
             extension_unsigned_development_mode: false,
             tracing: crate::tracing::RuntimeTracingConfig::default(),
@@ -543,7 +821,7 @@ fn default_temp_dir() -> PathBuf {
 
 #[cfg(test)]
 mod tests {
-    use super::{DbConfig, ProcessCoordinationMode, WalSyncMode};
+    use super::{DbConfig, ProcessCoordinationMode, TempStore, WalSyncMode};
     use crate::storage::page;
 
     #[test]
@@ -560,6 +838,7 @@ mod tests {
         assert_eq!(config.checkpoint_timeout_sec, 30);
         assert_eq!(config.trigram_postings_threshold, 100_000);
         assert!(!config.temp_dir.as_os_str().is_empty());
+        assert_eq!(config.temp_store, TempStore::Memory);
         assert!(config.encryption.is_none());
         assert_eq!(config.wal_checkpoint_threshold_pages, 4096);
         assert_eq!(config.wal_checkpoint_threshold_bytes, 64 * 1024 * 1024);
@@ -5,7 +5,7 @@ use std::collections::VecDeque;
 use std::collections::{BTreeMap, BTreeSet};
 use std::path::{Path, PathBuf};
 use std::str::FromStr;
-use std::sync::atomic::{AtomicBool, AtomicU64, Ordering};
+use std::sync::atomic::{AtomicBool, AtomicU64, AtomicUsize, Ordering};
 use std::sync::{Arc, Mutex, OnceLock, RwLock, RwLockReadGuard, RwLockWriteGuard, Weak};
 use std::time::Duration;
 
@@ -16,28 +16,32 @@ use crate::benchmark::{
 };
 use crate::catalog::{
     identifiers_equal, CatalogHandle, CheckConstraint, ColumnSchema, ColumnType, ForeignKeyAction,
-    ForeignKeyConstraint, IndexColumn, IndexKind, IndexSchema, TableSchema, TriggerEvent,
-    TriggerKind, TriggerSchema, ViewSchema,
+    ForeignKeyConstraint, IndexColumn, IndexKind, IndexSchema, Partition, PartitionBound,
+    PartitionedTable, TableComments, TableSchema, TriggerEvent, TriggerKind, TriggerSchema,
+    ViewSchema,
 };
-use crate::config::{DbConfig, ProcessCoordinationMode, WalSyncMode};
+use crate::config::{DbConfig, ProcessCoordinationMode, TempStore, WalSyncMode};
 use crate::error::{DbError, Result};
 use crate::exec::dml::{
     resolve_prepared_simple_value, row_id_alias_column_name, PreparedDeleteLookup,
     PreparedSimpleDelete, PreparedSimpleInsert, PreparedSimpleUpdate, PreparedSimpleValueSource,
 };
 use crate::exec::{
+    covered_projection_columns, evaluate_row_expression, project_covered_row,
     read_persisted_table_row_count, read_table_payload_live_row_count_from_bytes,
-    row_satisfies_expression, statement_is_read_only, BulkLoadOptions, EngineRuntime, QueryResult,
-    QueryRow, ResolvedSimpleJoinProjection, ResolvedSimpleOrderedRowIdProjectionRequest,
-    ResolvedSimpleRowIdJoinProjectionRequest, ResolvedSimpleRowIdProjectionRequest,
-    ResolvedSimpleRowIdRangeProjectionRequest, RuntimeIndex, RuntimeRowIdSet,
-    SimpleJoinProjectionSide, SimpleRangeBoundValue, SimpleRowIdProjectionRequest, TableData,
+    row_satisfies_expression, statement_is_read_only, BulkLoadOptions, EngineRuntime,
+    PersistedTableState, QueryResult, QueryRow, ResolvedSimpleJoinProjection,
+    ResolvedSimpleOrderedRowIdProjectionRequest, ResolvedSimpleRowIdJoinProjectionRequest,
+    ResolvedSimpleRowIdProjectionRequest, ResolvedSimpleRowIdRangeProjectionRequest, RuntimeIndex,
+    RuntimeRowIdSet, SimpleJoinProjectionSide, SimpleRangeBoundValue, SimpleRowIdProjectionRequest,
+    TableData,
 };
 use crate::metadata::{
-    CheckConstraintInfo, ColumnInfo, ForeignKeyInfo, HeaderInfo, IndexInfo, IndexVerification,
-    QueryContract, SchemaColumnInfo, SchemaIndexInfo, SchemaSnapshot, SchemaTableInfo,
-    SchemaTriggerInfo, SchemaViewInfo, StorageInfo, TableInfo, ToolingMetadata, TriggerInfo,
-    ViewInfo,
+    CheckConstraintInfo, CloneReport, ClonedTable, ColumnInfo, ForeignKeyInfo, ForeignKeyViolation,
+    HeaderInfo, IndexInfo, IndexVerification, PageCacheStats, QueryContract, RecoveredTable,
+    RecoveryLoss, RecoveryReport, SchemaColumnInfo, SchemaIndexInfo, SchemaSnapshot,
+    SchemaTableInfo, SchemaTriggerInfo, SchemaViewInfo, StorageInfo, TableChecksum, TableInfo,
+    TableStatistics, TableVerification, ToolingMetadata, TriggerInfo, VerificationReport, ViewInfo,
 };
 use crate::plan_cache::PlanCache;
 use crate::reactive::{
@@ -62,6 +66,7 @@ use crate::sql::ast::{
 use crate::sql::parser::{parse_expression_sql, parse_sql_statement, rewrite_legacy_trigger_body};
 use crate::storage::freelist::{decode_freelist_next, encode_freelist_page};
 use crate::storage::page::{self, PageId, PageStore};
+use crate::storage::page_checksums::PageChecksums;
 use crate::storage::{self, DatabaseHeader, PagerHandle};
 use crate::sync::SyncContext;
 use crate::sync::{
@@ -81,19 +86,30 @@ use crate::vfs::faulty::{self, FailAction, Failpoint};
 use crate::vfs::{
     is_memory_path, read_exact_at, write_all_at, FileKind, OpenMode, VfsFile, VfsHandle,
 };
+use crate::wal::advisory::AdvisoryLockScope;
+use crate::wal::archive::WalSegment;
 use crate::wal::reader_registry::ReaderGuard;
 use crate::wal::savepoint::StatementSavepoint;
 use crate::wal::WalHandle;
 use crate::write_queue::{QueuedWriteOptions, WriteQueue, WriteQueueMetricsSnapshot};
+use serde::Deserialize;
 use serde_json::Value as JsonValue;
 use sha2::{Digest, Sha256};
 
 mod audit;
+mod backup;
 mod branches;
 mod open;
 mod query_api;
 mod schema;
 mod sync_api;
+mod temp_spill;
+mod two_phase;
+
+pub use self::backup::BackupReader;
+#[cfg(not(all(target_arch = "wasm32", target_os = "unknown")))]
+pub(crate) use self::backup::RestoreWriter;
+pub(crate) use self::branches::render_create_table;
 use audit::*;
 use branches::*;
 use open::*;
@@ -103,6 +119,7 @@ use sync_api::*;
 
 const APPLICATION_PRAGMA_TABLE: &str = "__decentdb_application_pragmas";
 static AUDIT_EVENT_COUNTER: AtomicU64 = AtomicU64::new(1);
+static LOCK_OWNER_COUNTER: AtomicU64 = AtomicU64::new(1);
 
 /// Stable engine owner used across later storage, SQL, and FFI slices.
 #[derive(Clone, Debug)]
@@ -209,6 +226,53 @@ impl PageStore for PagerReadStore<'_> {
     }
 }
 
+/// Filters applied by [`Db::dump_sql_with_options`].
+///
+/// The default dumps every table with both schema and data, which matches
+/// [`Db::dump_sql`].
+#[derive(Clone, Debug, Eq, PartialEq)]
+pub struct DumpOptions {
+    /// Restricts the dump to these tables (and their indexes and triggers).
+    /// Views are omitted when a table filter is set. Empty means all tables.
+    pub tables: Vec<String>,
+    /// Emits `CREATE` statements for tables, views, indexes, and triggers.
+    pub include_schema: bool,
+    /// Emits `INSERT` statements for table rows.
+    pub include_data: bool,
+}
+
+impl Default for DumpOptions {
+    fn default() -> Self {
+        Self {
+            tables: Vec::new(),
+            include_schema: true,
+            include_data: true,
+        }
+    }
+}
+
+/// Selects what [`Db::clone_to`] copies and how it rewrites column values.
+///
+/// The default clones every table with all of its rows unchanged. As JSON,
+/// the options are an object with optional `tables`, `where` (the row
+/// filters), and `transforms` fields.
+#[derive(Clone, Debug, Default, Eq, PartialEq, Deserialize)]
+#[serde(default, deny_unknown_fields)]
+pub struct CloneOptions {
+    /// Restricts the clone to these tables (and their indexes and triggers).
+    /// Views are omitted when a table filter is set. Empty means all tables.
+    pub tables: Vec<String>,
+    /// Row filters keyed by table name. Each is a SQL boolean expression
+    /// over the table's columns; only rows for which it is true are copied.
+    #[serde(rename = "where")]
+    pub filters: BTreeMap<String, String>,
+    /// Column transforms keyed by table and then column name. Each is a SQL
+    /// expression over the source row's columns whose value replaces the
+    /// column's in the clone, such as `NULL` or `sha256(email)`, for
+    /// scrubbing personal data out of a shared copy.
+    pub transforms: BTreeMap<String, BTreeMap<String, String>>,
+}
+
 /// Reusable single-statement execution handle bound to the current schema.
 ///
 /// Prepared statements become invalid after schema changes and must be
@@ -584,7 +648,25 @@ impl PreparedStatement {
     /// Executes the prepared statement with the provided positional `$n`
     /// parameters.
     pub fn execute(&self, params: &[Value]) -> Result<QueryResult> {
-        self.db.execute_prepared_statement(self, params)
+        let _hint = crate::storage::read_ahead::ReadAheadHint::install(&self.prepared_sql);
+        let _memory = self.db.install_statement_memory();
+        let _interrupt = self.db.install_statement_interrupt();
+        let _limits = self.db.install_statement_limits();
+        let _temp_store = self.db.install_temp_store_for(&self.prepared_sql)?;
+        if !self.db.inner.tracing.any_enabled() {
+            return self.db.execute_prepared_statement(self, params);
+        }
+        let started_at_unix_ms = crate::tracing::unix_millis_now();
+        let t0 = std::time::Instant::now();
+        let result = self.db.execute_prepared_statement(self, params);
+        self.db.record_statement_trace(
+            &self.prepared_sql,
+            self.read_only,
+            t0.elapsed(),
+            started_at_unix_ms,
+            result.as_ref(),
+        );
+        result
     }
 
     /// Executes the prepared statement with mutable positional parameters.
@@ -593,7 +675,25 @@ impl PreparedStatement {
     /// that consume all parameters directly. Callers should treat parameter
     /// values as consumed once execution completes.
     pub fn execute_mut(&self, params: &mut [Value]) -> Result<QueryResult> {
-        self.db.execute_prepared_statement_mut(self, params)
+        let _hint = crate::storage::read_ahead::ReadAheadHint::install(&self.prepared_sql);
+        let _memory = self.db.install_statement_memory();
+        let _interrupt = self.db.install_statement_interrupt();
+        let _limits = self.db.install_statement_limits();
+        let _temp_store = self.db.install_temp_store_for(&self.prepared_sql)?;
+        if !self.db.inner.tracing.any_enabled() {
+            return self.db.execute_prepared_statement_mut(self, params);
+        }
+        let started_at_unix_ms = crate::tracing::unix_millis_now();
+        let t0 = std::time::Instant::now();
+        let result = self.db.execute_prepared_statement_mut(self, params);
+        self.db.record_statement_trace(
+            &self.prepared_sql,
+            self.read_only,
+            t0.elapsed(),
+            started_at_unix_ms,
+            result.as_ref(),
+        );
+        result
     }
 
     /// Executes the prepared statement inside an active [`SqlTransaction`].
@@ -691,6 +791,7 @@ impl<'db> SqlTransaction<'db> {
 
     /// Commits this transaction's reserved runtime into the WAL-backed database.
     pub fn commit(mut self) -> Result<u64> {
+        let _temp_store = self.db.install_temp_store()?;
         let state = self
             .state
             .take()
@@ -700,12 +801,16 @@ impl<'db> SqlTransaction<'db> {
         match (result, release) {
             (Err(error), _) => Err(error),
             (Ok(_), Err(error)) => Err(error),
-            (Ok(lsn), Ok(())) => Ok(lsn),
+            (Ok(lsn), Ok(())) => {
+                self.db.maybe_auto_analyze();
+                Ok(lsn)
+            }
         }
     }
 
     /// Rolls this transaction back and releases the handle.
     pub fn rollback(mut self) -> Result<()> {
+        let _temp_store = self.db.install_temp_store()?;
         let state = self
             .state
             .take()
@@ -816,8 +921,25 @@ struct DbInner {
     sql_txn_active: AtomicBool,
     write_txn_active: AtomicBool,
     write_txn: Mutex<WriteTxn>,
-    busy_timeout_ms: AtomicU64,
+    /// Shared with the runtime's advisory lock scope, whose `advisory_lock`
+    /// waits this long.
+    busy_timeout_ms: Arc<AtomicU64>,
+    /// Configured worker budget for reads; `0` means one per core.
+    max_parallel_workers: AtomicUsize,
+    /// Pages scans read ahead of a sequential run; `0` disables read-ahead.
+    read_ahead_pages: AtomicUsize,
+    /// Bytes a statement may buffer before it is aborted; `0` is unlimited.
+    statement_memory_limit_bytes: AtomicUsize,
+    /// Rows a query may return before it is aborted; `0` is unlimited.
+    max_result_rows: AtomicU64,
+    /// Seconds a statement may run before it is aborted; `0` is unlimited.
+    max_statement_seconds: AtomicU64,
+    /// Raised by `Db::interrupt` to cancel the running statement.
+    interrupt: Arc<AtomicBool>,
+    /// Whether writes through this handle enforce foreign keys.
+    foreign_keys: AtomicBool,
     temp_state: Mutex<TempSchemaState>,
+    temp_spill: Mutex<temp_spill::TempSpill>,
     statement_cache: Mutex<StatementCache>,
     prepared_insert_cache: Mutex<PreparedInsertCache>,
     plan_cache: Mutex<PlanCache>,
@@ -828,14 +950,36 @@ struct DbInner {
     reactive_registry_key: Option<PathBuf>,
     reactive_hub: OnceLock<Arc<ReactiveHub>>,
     audit_context: Arc<Mutex<crate::security::AuditContext>>,
+    /// Schemas searched for unqualified relation names; empty means the
+    /// default (main) schema only.
+    search_path: Mutex<Vec<String>>,
     read_only_paged_row_source_residency: Mutex<ReadOnlyPagedRowSourceResidency>,
     write_queue: OnceLock<WriteQueue>,
     tracing: Arc<crate::tracing::RuntimeTraceState>,
+    /// Shared by a handle and every session opened from it; its strong
+    /// count is the number of handles sharing the page cache.
+    session_group: Arc<()>,
+    /// Identifies this handle as the holder of advisory and table locks.
+    lock_owner: u64,
+    /// Prepared transactions whose tables could not be reserved when this
+    /// handle opened; `COMMIT PREPARED` refuses them here.
+    unreserved_prepared_xacts: Mutex<BTreeSet<String>>,
 }
 
 impl Drop for DbInner {
     fn drop(&mut self) {
+        self.wal.advisory_unlock_all(self.lock_owner);
+        self.wal.unlock_tables(self.lock_owner);
+        // Sessions from `Db::open_session` share the WAL; leave it running
+        // until the last of them goes.
+        if Arc::strong_count(&self.session_group) > 1 {
+            return;
+        }
         self.wal.shutdown_background_checkpointer();
+        if self.config.checkpoint_on_close || self.config.truncate_wal_on_close {
+            self.checkpoint_on_close();
+            return;
+        }
         if self.wal.latest_snapshot() == 0 {
             return;
         }
@@ -891,6 +1035,43 @@ impl Drop for DbInner {
     }
 }
 
+impl DbInner {
+    /// Checkpoints synchronously for `checkpoint_on_close`, then removes the
+    /// emptied WAL files for `truncate_wal_on_close`. Unlike the drop-time
+    /// checkpoint this covers shared WALs too, since it only runs when this
+    /// is the last handle on the WAL and so no other pager cache can go
+    /// stale.
+    fn checkpoint_on_close(&self) {
+        self.wal.set_checkpoint_pending(true);
+        let busy = self.wal.strong_handle_count() != 1
+            || self.write_txn.lock().map(|txn| txn.active).unwrap_or(true)
+            || self
+                .sql_txn
+                .lock()
+                .map(|txn| !matches!(*txn, SqlTxnSlot::None))
+                .unwrap_or(true);
+        if busy {
+            self.wal.set_checkpoint_pending(false);
+            return;
+        }
+        if let Ok(mut held_snapshots) = self.held_snapshots.lock() {
+            held_snapshots.clear();
+        }
+        if self.wal.latest_snapshot() == 0 {
+            self.wal.set_checkpoint_pending(false);
+        } else if self
+            .wal
+            .checkpoint(&self.pager, self.config.checkpoint_timeout_sec)
+            .is_err()
+        {
+            return;
+        }
+        if self.config.truncate_wal_on_close && !is_memory_path(&self.path) {
+            let _ = self.wal.remove_if_sole_handle(&self.vfs);
+        }
+    }
+}
+
 #[derive(Debug, Default)]
 struct WriteTxn {
     active: bool,
@@ -1169,6 +1350,37 @@ struct SimpleRowIdRangeDeleteSql {
     high: i64,
 }
 
+/// Checks a two-phase commit global id: non-empty and at most 200 bytes, as
+/// in PostgreSQL.
+fn validate_prepared_gid(gid: &str) -> Result<()> {
+    if gid.is_empty() || gid.len() > 200 {
+        return Err(DbError::transaction(
+            "prepared transaction identifier must be 1 to 200 bytes long",
+        ));
+    }
+    Ok(())
+}
+
+/// Names the first table a losing transaction wrote whose stored state the
+/// winning commit also changed. Other errors are returned unchanged.
+fn attribute_transaction_conflict(
+    error: DbError,
+    written_tables: &BTreeSet<String>,
+    base_tables: &BTreeMap<String, PersistedTableState>,
+    latest_tables: &BTreeMap<String, PersistedTableState>,
+) -> DbError {
+    if !error.is_transaction_conflict() {
+        return error;
+    }
+    match written_tables
+        .iter()
+        .find(|table| base_tables.get(*table) != latest_tables.get(*table))
+    {
+        Some(table) => error.with_conflict_relation(table.clone()),
+        None => error,
+    }
+}
+
 fn parse_simple_row_id_range_delete_sql(sql: &str) -> Option<SimpleRowIdRangeDeleteSql> {
     let tokens = sql.split_ascii_whitespace().collect::<Vec<_>>();
     if tokens.len() != 9
@@ -1265,7 +1477,7 @@ impl Db {
         config: &DbConfig,
     ) -> Result<HeaderInfo> {
         let path = path.as_ref();
-        let vfs = VfsHandle::for_path(path).with_config(config);
+        let vfs = VfsHandle::for_config(path, config)?.with_config(config);
         let file = vfs.open(path, OpenMode::OpenExisting, FileKind::Database)?;
         let header = storage::read_database_header_vfs_loose(file.as_ref())?;
         Ok(HeaderInfo {
@@ -1285,6 +1497,7 @@ impl Db {
     /// Begins an exclusive SQL transaction handle that reserves mutable runtime
     /// state until commit or rollback.
     pub fn transaction(&self) -> Result<SqlTransaction<'_>> {
+        let _temp_store = self.install_temp_store()?;
         let state = self.build_exclusive_sql_txn_state()?;
         let mut txn = self
             .inner
@@ -1308,7 +1521,7 @@ impl Db {
     /// reserved catalog root page.
     pub fn create(path: impl AsRef<Path>, config: DbConfig) -> Result<Self> {
         let path = path.as_ref();
-        let vfs = VfsHandle::for_path(path);
+        let vfs = VfsHandle::for_config(path, &config)?;
         Self::create_with_vfs(path, config, vfs)
     }
 
@@ -1337,7 +1550,7 @@ impl Db {
     /// Opens an existing database file and validates its fixed header.
     pub fn open(path: impl AsRef<Path>, config: DbConfig) -> Result<Self> {
         let path = path.as_ref();
-        let vfs = VfsHandle::for_path(path);
+        let vfs = VfsHandle::for_config(path, &config)?;
         Self::open_existing_with_vfs(path, config, vfs)
     }
 
@@ -1367,7 +1580,7 @@ impl Db {
     /// yet exist.
     pub fn open_or_create(path: impl AsRef<Path>, config: DbConfig) -> Result<Self> {
         let path = path.as_ref();
-        let vfs = VfsHandle::for_path(path);
+        let vfs = VfsHandle::for_config(path, &config)?;
         Self::open_or_create_with_vfs(path, config, vfs)
     }
 
@@ -1386,6 +1599,7 @@ impl Db {
 
     /// Begins an explicit SQL transaction on this database handle.
     pub fn begin_transaction(&self) -> Result<()> {
+        let _temp_store = self.install_temp_store()?;
         let state = self.build_sql_txn_state()?;
         let mut txn = self
             .inner
@@ -1404,6 +1618,7 @@ impl Db {
 
     /// Commits the current explicit SQL transaction.
     pub fn commit_transaction(&self) -> Result<u64> {
+        let _temp_store = self.install_temp_store()?;
         let state = {
             let mut txn = self
                 .inner
@@ -1426,19 +1641,100 @@ impl Db {
                 }
             }
         };
+        let result = self.commit_sql_txn_state(state);
+        self.inner.wal.unlock_tables(self.inner.lock_owner);
+        result
+    }
+
+    fn commit_sql_txn_state(&self, state: SqlTxnState) -> Result<u64> {
         if !state.persistent_changed {
             self.install_temp_runtime(state.runtime)?;
             return Ok(state.base_lsn);
         }
-        self.persist_runtime_if_latest(
+        let lsn = self.persist_runtime_if_latest(
             state.runtime,
             Some((state.base_lsn, state.base_checkpoint_epoch)),
             state.indexes_maybe_stale,
-        )
+        )?;
+        self.maybe_auto_analyze();
+        Ok(lsn)
+    }
+
+    /// Ends the current explicit SQL transaction as a prepared transaction
+    /// named `gid`, the first phase of a two-phase commit. The handle
+    /// returns to autocommit; `commit_prepared` or `rollback_prepared` on
+    /// any handle finishes the transaction, also after the database is
+    /// closed and reopened.
+    ///
+    /// Preparing records the rows of every table the transaction wrote in
+    /// one commit and reserves those tables: until the second phase, other
+    /// commits that write them wait as if the tables were held with `LOCK
+    /// TABLE`. Preparing fails with a transaction conflict, rolling the
+    /// transaction back, if another write committed after it began; it
+    /// also rolls back a transaction that changed the schema or temporary
+    /// tables, which cannot be prepared.
+    pub fn prepare_transaction(&self, gid: &str) -> Result<()> {
+        validate_prepared_gid(gid)?;
+        if !self.in_transaction()? {
+            return Err(DbError::transaction(
+                "PREPARE TRANSACTION requires an active SQL transaction",
+            ));
+        }
+        self.ensure_prepared_gid_free(gid)?;
+        let state = {
+            let mut txn = self
+                .inner
+                .sql_txn
+                .lock()
+                .map_err(|_| DbError::internal("SQL transaction lock poisoned"))?;
+            match std::mem::replace(&mut *txn, SqlTxnSlot::None) {
+                SqlTxnSlot::Shared(state) => state,
+                SqlTxnSlot::Exclusive => {
+                    *txn = SqlTxnSlot::Exclusive;
+                    return Err(self.exclusive_sql_txn_error());
+                }
+                SqlTxnSlot::None => {
+                    return Err(DbError::transaction(
+                        "PREPARE TRANSACTION requires an active SQL transaction",
+                    ));
+                }
+            }
+        };
+        self.inner.sql_txn_active.store(false, Ordering::Release);
+        self.record_prepared_transaction(gid, *state)
+    }
+
+    /// Commits the prepared transaction `gid`, the second phase of a
+    /// two-phase commit, and releases its tables.
+    pub fn commit_prepared(&self, gid: &str) -> Result<u64> {
+        self.ensure_no_transaction_for("COMMIT PREPARED")?;
+        self.finish_prepared_transaction(gid, true)
+    }
+
+    /// Rolls back the prepared transaction `gid` and releases its tables.
+    pub fn rollback_prepared(&self, gid: &str) -> Result<()> {
+        self.ensure_no_transaction_for("ROLLBACK PREPARED")?;
+        self.finish_prepared_transaction(gid, false).map(drop)
+    }
+
+    /// Returns the global ids of the prepared transactions recorded in the
+    /// database.
+    pub fn prepared_transactions(&self) -> Result<Vec<String>> {
+        self.prepared_transaction_ids()
+    }
+
+    fn ensure_no_transaction_for(&self, command: &str) -> Result<()> {
+        if self.in_transaction()? {
+            return Err(DbError::transaction(format!(
+                "{command} cannot run inside a transaction"
+            )));
+        }
+        Ok(())
     }
 
     /// Rolls back the current explicit SQL transaction.
     pub fn rollback_transaction(&self) -> Result<()> {
+        let _temp_store = self.install_temp_store()?;
         let mut txn = self
             .inner
             .sql_txn
@@ -1448,6 +1744,7 @@ impl Db {
             SqlTxnSlot::Shared(_) => {
                 *txn = SqlTxnSlot::None;
                 self.inner.sql_txn_active.store(false, Ordering::Release);
+                self.inner.wal.unlock_tables(self.inner.lock_owner);
                 Ok(())
             }
             SqlTxnSlot::Exclusive => Err(self.exclusive_sql_txn_error()),
@@ -1553,6 +1850,12 @@ impl Db {
         Ok(())
     }
 
+    /// Returns the current size of the WAL file in bytes. It shrinks only
+    /// when a checkpoint truncates the WAL.
+    pub fn wal_size(&self) -> Result<u64> {
+        self.inner.wal.file_size()
+    }
+
     /// Returns a structured snapshot of the current storage state.
     pub fn storage_info(&self) -> Result<StorageInfo> {
         let header = self.inner.pager.header_snapshot()?;
@@ -1648,7 +1951,7 @@ impl Db {
         dest: &Path,
         dest_vfs: &VfsHandle,
     ) -> Result<bool> {
-        if is_memory_path(self.path()) || dest_vfs.is_memory() {
+        if is_memory_path(self.path()) || dest_vfs.is_memory() || self.inner.config.vfs.is_some() {
             return Ok(false);
         }
         #[cfg(not(all(target_arch = "wasm32", target_os = "unknown")))]
@@ -1895,6 +2198,7 @@ impl Db {
         self.inner.write_txn_active.store(false, Ordering::Release);
 
         let pages: Vec<_> = pages.into_iter().collect();
+        self.check_storage_quota(max_page_id)?;
         let max_page_count = self.inner.wal.max_page_count().max(max_page_id);
         self.inner
             .wal
@@ -1928,6 +2232,7 @@ impl Db {
         self.inner.write_txn_active.store(false, Ordering::Release);
 
         let pages: Vec<_> = pages.into_iter().collect();
+        self.check_storage_quota(max_page_id)?;
         let max_page_count = self.inner.wal.max_page_count().max(max_page_id);
         self.inner.wal.commit_pages_if_latest(
             &self.inner.pager,
@@ -1938,6 +2243,29 @@ impl Db {
         )
     }
 
+    /// Fails with a quota-exceeded error when committing pages up to
+    /// `max_page_id` would grow the database past
+    /// `DbConfig::max_database_size_bytes`.
+    fn check_storage_quota(&self, max_page_id: PageId) -> Result<()> {
+        let limit = self.inner.config.max_database_size_bytes;
+        if limit == 0 {
+            return Ok(());
+        }
+        let current_pages = self
+            .inner
+            .pager
+            .on_disk_page_count()?
+            .max(self.inner.wal.max_page_count());
+        if max_page_id <= current_pages {
+            return Ok(());
+        }
+        let required = u64::from(max_page_id) * u64::from(self.inner.config.page_size);
+        if required > limit {
+            return Err(DbError::quota_exceeded(limit, required));
+        }
+        Ok(())
+    }
+
     /// Rolls back the current write transaction.
     pub fn rollback(&self) -> Result<()> {
         let mut txn = self
@@ -2079,19 +2407,52 @@ impl Db {
         Ok(())
     }
 
+    /// Starts archiving WAL segments. Every checkpoint that truncates the WAL
+    /// first queues the frames it is about to discard; drain the queue with
+    /// [`Db::next_wal_segment`]. Once `capacity` segments are waiting,
+    /// checkpoints stop truncating so the WAL grows instead of losing frames.
+    pub fn enable_wal_archive(&self, capacity: usize) -> Result<()> {
+        self.inner.wal.enable_archive(capacity);
+        Ok(())
+    }
+
+    /// Stops archiving WAL segments and discards any not yet drained.
+    pub fn disable_wal_archive(&self) -> Result<()> {
+        self.inner.wal.disable_archive();
+        Ok(())
+    }
+
+    /// Takes the oldest archived WAL segment, or `None` when the queue is
+    /// empty or archiving is off.
+    pub fn next_wal_segment(&self) -> Result<Option<WalSegment>> {
+        Ok(self.inner.wal.next_archived_segment())
+    }
+
     /// Blocks until every commit acknowledged before this call is durable on
     /// disk.
     ///
-    /// For the default [`crate::WalSyncMode::Full`] mode (and `Normal`), every
-    /// commit is already synchronously durable when it returns, so this is a
-    /// cheap no-op. Under [`crate::WalSyncMode::AsyncCommit`] it forces the
+    /// For the default [`crate::WalSyncMode::Full`] mode every commit is
+    /// already synchronously durable when it returns, so this is a cheap
+    /// no-op. Under [`crate::WalSyncMode::AsyncCommit`] it forces the
     /// background flusher to run and waits until the WAL is on stable storage.
+    /// Under `Normal` and `TestingOnlyUnsafeNoSync` it syncs the WAL file and
+    /// its metadata, so a bulk load opened with `synchronous=off` can restore
+    /// durability with one call.
     ///
     /// See `design/adr/0135-async-commit-wal-group-commit.md`.
     pub fn sync(&self) -> Result<()> {
         self.inner.wal.flush_to_durable()
     }
 
+    /// Cancels the statement currently running on this handle, which fails
+    /// with `DbError::Canceled`. Safe to call from any thread; it has no
+    /// effect when the handle is idle, and the next statement runs normally.
+    /// Statements check for interrupts between batches of rows, so a
+    /// statement stopped inside a single storage operation finishes it first.
+    pub fn interrupt(&self) {
+        self.inner.interrupt.store(true, Ordering::Release);
+    }
+
     /// Executes a single SQL statement without parameters.
     pub fn execute(&self, sql: &str) -> Result<QueryResult> {
         self.execute_with_params(sql, &[])
@@ -2099,6 +2460,11 @@ impl Db {
 
     /// Executes a single SQL statement with positional `$n` parameters.
     pub fn execute_with_params(&self, sql: &str, params: &[Value]) -> Result<QueryResult> {
+        let _hint = crate::storage::read_ahead::ReadAheadHint::install(sql);
+        let _memory = self.install_statement_memory();
+        let _interrupt = self.install_statement_interrupt();
+        let _limits = self.install_statement_limits();
+        let _temp_store = self.install_temp_store_for(sql)?;
         if let Some(trimmed) = simple_single_statement_fast_path_sql(sql) {
             if let Some(result) = self.try_execute_simple_count_sql_fast_path(trimmed, params)? {
                 self.record_statement_trace(
@@ -2313,6 +2679,7 @@ impl Db {
         snapshot_lsn: u64,
         params: &[Value],
     ) -> Result<Vec<QueryResult>> {
+        let _temp_store = self.install_temp_store_for(sql)?;
         let latest_lsn = self.inner.wal.latest_snapshot();
         if snapshot_lsn > latest_lsn {
             return Err(DbError::transaction(format!(
@@ -2532,6 +2899,132 @@ impl Db {
         diff_materialized_refs(left_ref, right_ref, &left_db, &right_db)
     }
 
+    /// Compares this database with `other` row by row, keyed by primary key.
+    /// This database is the left side: rows only in `other` are reported as
+    /// added.
+    pub fn diff_database(&self, other: &Db) -> Result<crate::branch::BranchDiffReport> {
+        diff_materialized_refs(
+            &self.path().display().to_string(),
+            &other.path().display().to_string(),
+            self,
+            other,
+        )
+    }
+
+    /// Builds a changeset that turns this database's rows into `other`'s.
+    /// Applying it to this database reconciles the two. Tables whose schema
+    /// differs, or that lack a primary key, cannot be expressed as row
+    /// changes and fail with `SCHEMA_INCOMPATIBLE` or `CHANGESET_UNSUPPORTED`.
+    pub fn diff_database_changeset(&self, other: &Db) -> Result<SyncChangeset> {
+        // The changeset pins this database's schema cookie, so create the
+        // sync tables an apply would create first.
+        self.ensure_sync_tables()?;
+        let diff = self.diff_database(other)?;
+        let tooling = self.get_tooling_metadata()?;
+        let schema_cookie = self
+            .runtime_for_metadata_inspection()?
+            .catalog
+            .schema_cookie;
+        let mut changeset = self.sync_changeset_from_diff(
+            &diff,
+            SyncDiffChangesetContext {
+                base_kind: "database",
+                from_ref: &diff.left_ref,
+                to_ref: &diff.right_ref,
+                scope_name: None,
+                shape_id: None,
+                tenant_id: None,
+                schema_fingerprint: &tooling.schema_fingerprint,
+                schema_cookie,
+                created_at_micros: current_time_micros(),
+                max_records: usize::MAX,
+            },
+        )?;
+        self.sync_finalize_changeset(&mut changeset, None)?;
+        Ok(changeset)
+    }
+
+    /// Returns the row count and a content checksum of every table, read from
+    /// one consistent snapshot. A table's checksum is the sum of a SHA-256
+    /// digest per row, so it does not depend on row order and two copies of a
+    /// table can be compared without sorting either. Internal sync tables are
+    /// left out.
+    pub fn table_checksums(&self) -> Result<Vec<TableChecksum>> {
+        let (mut runtime, snapshot_lsn) = self.runtime_for_targeted_row_source_inspection()?;
+        let table_names = runtime
+            .catalog
+            .tables
+            .keys()
+            .filter(|name| !crate::sync::is_internal_table_name(name))
+            .cloned()
+            .collect::<Vec<_>>();
+        let mut checksums = Vec::with_capacity(table_names.len());
+        for table_name in table_names {
+            self.ensure_inspection_table_row_source(&mut runtime, &table_name, snapshot_lsn)?;
+            let row_source = runtime.table_row_source(&table_name).ok_or_else(|| {
+                DbError::internal(format!("table row source for {table_name} is missing"))
+            })?;
+            let mut row_count = 0_u64;
+            let mut sum = 0_u128;
+            for row in row_source.rows() {
+                let mut hasher = Sha256::new();
+                for value in row?.values() {
+                    let rendered = render_value_sql(value);
+                    hasher.update((rendered.len() as u64).to_le_bytes());
+                    hasher.update(rendered.as_bytes());
+                }
+                let digest = hasher.finalize();
+                let mut prefix = [0_u8; 16];
+                prefix.copy_from_slice(&digest[..16]);
+                sum = sum.wrapping_add(u128::from_le_bytes(prefix));
+                row_count += 1;
+            }
+            self.redefer_inspection_table_row_source(&mut runtime, &table_name, snapshot_lsn);
+            checksums.push(TableChecksum {
+                name: table_name,
+                row_count,
+                checksum: format!("{sum:032x}"),
+            });
+        }
+        Ok(checksums)
+    }
+
+    /// Compares the row counts and content checksums of every table in this
+    /// database with those in `other`, to confirm that a backup, replica, or
+    /// migrated copy holds the same data without a row-by-row diff. This
+    /// database is the left side.
+    pub fn verify_against(&self, other: &Db) -> Result<VerificationReport> {
+        let mut left = self
+            .table_checksums()?
+            .into_iter()
+            .map(|table| (table.name.clone(), table))
+            .collect::<BTreeMap<_, _>>();
+        let mut right = other
+            .table_checksums()?
+            .into_iter()
+            .map(|table| (table.name.clone(), table))
+            .collect::<BTreeMap<_, _>>();
+        let mut table_names = left.keys().cloned().collect::<BTreeSet<_>>();
+        table_names.extend(right.keys().cloned());
+        let tables = table_names
+            .into_iter()
+            .map(|name| {
+                let left = left.remove(&name);
+                let right = right.remove(&name);
+                let matches = matches!((&left, &right), (Some(left), Some(right)) if left == right);
+                TableVerification {
+                    name,
+                    left_row_count: left.as_ref().map(|table| table.row_count),
+                    right_row_count: right.as_ref().map(|table| table.row_count),
+                    left_checksum: left.map(|table| table.checksum),
+                    right_checksum: right.map(|table| table.checksum),
+                    matches,
+                }
+            })
+            .collect();
+        Ok(VerificationReport { tables })
+    }
+
     /// Restores a non-main branch head to another branch, named snapshot, or head ID.
     pub fn branch_restore(
         &self,
@@ -2669,7 +3162,8 @@ impl Db {
 
     /// Reset a specific runtime trace store by name.
     ///
-    /// `kind` may be "slow_queries", "lock_waits", or "index_usage".
+    /// `kind` may be "slow_queries", "lock_waits", "index_usage", or
+    /// "statement_stats".
     pub fn tracing_reset(&self, kind: &str) -> Result<()> {
         match kind {
             "slow_queries" => self
@@ -2693,6 +3187,13 @@ impl Db {
                 .lock()
                 .map_err(|_| DbError::internal("index usage store poisoned"))?
                 .reset(),
+            "statement_stats" => self
+                .inner
+                .tracing
+                .statement_stats_store
+                .lock()
+                .map_err(|_| DbError::internal("statement stats store poisoned"))?
+                .reset(),
             _ => {
                 return Err(DbError::sql(format!(
                     "unknown tracing kind for reset: {kind}"
@@ -2707,6 +3208,7 @@ impl Db {
         sql: &str,
         params: &[Value],
     ) -> Result<Vec<QueryResult>> {
+        let _temp_store = self.install_temp_store_for(sql)?;
         if params.is_empty() && !self.inner.sql_txn_active.load(Ordering::Acquire) {
             if let Some(results) = self.try_execute_schema_batch_with_single_commit(sql)? {
                 return Ok(results);
@@ -2741,6 +3243,16 @@ impl Db {
                     TransactionControl::RollbackToSavepoint(name) => {
                         self.rollback_to_savepoint(&name)?;
                     }
+                    TransactionControl::PrepareTransaction(gid) => {
+                        self.prepare_transaction(&gid)?;
+                        self.inner.tracing.mark_active();
+                    }
+                    TransactionControl::CommitPrepared(gid) => {
+                        self.commit_prepared(&gid)?;
+                    }
+                    TransactionControl::RollbackPrepared(gid) => {
+                        self.rollback_prepared(&gid)?;
+                    }
                 }
                 results.push(QueryResult::with_affected_rows(0));
                 continue;
@@ -2750,6 +3262,11 @@ impl Db {
                 results.push(result);
                 continue;
             }
+            if let Some(command) = crate::sql::search_path::parse_search_path_command(trimmed)? {
+                let result = self.execute_search_path_command(command)?;
+                results.push(result);
+                continue;
+            }
             if let Some(command) = crate::security::parse_set_audit_context(trimmed)? {
                 let result = self.execute_set_audit_context(command)?;
                 // Per ADR 0192, audit context writes do not invalidate
@@ -2758,17 +3275,31 @@ impl Db {
                 continue;
             }
             if let Some(command) = crate::security::parse_security_command(trimmed)? {
+                if self.inner.config.defensive {
+                    return Err(crate::sql::defensive::refused("security DDL"));
+                }
                 let result = self.execute_security_command(trimmed, command)?;
                 crate::plan_cache::PlanCacheInvalidator::on_policy_mask_change(&*self.inner);
                 results.push(result);
                 continue;
             }
             if let Some(command) = crate::extensions::parse_extension_sql(trimmed)? {
+                if self.inner.config.defensive {
+                    return Err(crate::sql::defensive::refused("extension DDL"));
+                }
                 let result = crate::extensions::execute_extension_sql(self, command)?;
                 crate::plan_cache::PlanCacheInvalidator::on_extension_change(&*self.inner);
                 results.push(result);
                 continue;
             }
+            if let Some(command) = parse_publication_command(trimmed)? {
+                results.push(self.execute_publication_command(command)?);
+                continue;
+            }
+            if let Some(command) = parse_lock_table_command(trimmed)? {
+                results.push(self.execute_lock_table(command)?);
+                continue;
+            }
             if let Some(result) = self.try_execute_sync_inspection_query(trimmed, params)? {
                 results.push(result);
                 continue;
@@ -2831,7 +3362,10 @@ impl Db {
                     continue;
                 }
             }
-            if !self.inner.sql_txn_active.load(Ordering::Acquire) && params.is_empty() {
+            if !self.inner.sql_txn_active.load(Ordering::Acquire)
+                && params.is_empty()
+                && !self.search_path_active()
+            {
                 if let Ok(prepared_sql) = prepared_statement_sql(trimmed) {
                     if let Some(prepared) = self.try_prepare_from_plan_cache(&prepared_sql)? {
                         if prepared.read_only {
@@ -2892,9 +3426,41 @@ impl Db {
             self.dispatch_plan_cache_invalidation(&statement);
             results.push(result);
         }
+        self.maybe_auto_analyze();
         Ok(results)
     }
 
+    /// Runs `ANALYZE` on every table whose churn crossed the configured
+    /// auto-analyze threshold. Called after autocommit writes and commits;
+    /// the triggering write is already durable, so failures are dropped and
+    /// the table waits for its next threshold crossing.
+    fn maybe_auto_analyze(&self) {
+        let min_rows = self.inner.config.auto_analyze_min_rows;
+        if min_rows == 0 || self.inner.sql_txn_active.load(Ordering::Acquire) {
+            return;
+        }
+        let candidates = match self.inner.engine.read() {
+            Ok(runtime) => runtime
+                .auto_analyze_candidates(min_rows, self.inner.config.auto_analyze_churn_percent),
+            Err(_) => return,
+        };
+        for table_name in candidates {
+            let sql = format!("ANALYZE {}", sql_relation_name(&table_name));
+            let analyzed = self.parsed_statement(&sql).and_then(|statement| {
+                self.execute_write_statement(&sql, &statement, &[])?;
+                self.dispatch_plan_cache_invalidation(&statement);
+                Ok(())
+            });
+            if analyzed.is_err() {
+                if let Ok(mut runtime) = self.inner.engine.write() {
+                    runtime
+                        .analyze_churn
+                        .retain(|churned, _| !identifiers_equal(churned, &table_name));
+                }
+            }
+        }
+    }
+
     fn try_execute_schema_batch_with_single_commit(
         &self,
         sql: &str,
@@ -2908,9 +3474,12 @@ impl Db {
 
             if parse_transaction_control(trimmed).is_some()
                 || parse_pragma_command(trimmed)?.is_some()
+                || crate::sql::search_path::parse_search_path_command(trimmed)?.is_some()
                 || crate::security::parse_set_audit_context(trimmed)?.is_some()
                 || crate::security::parse_security_command(trimmed)?.is_some()
                 || crate::extensions::parse_extension_sql(trimmed)?.is_some()
+                || parse_publication_command(trimmed)?.is_some()
+                || parse_lock_table_command(trimmed)?.is_some()
                 || self
                     .try_execute_sync_inspection_query(trimmed, &[])?
                     .is_some()
@@ -2985,6 +3554,9 @@ impl Db {
         &self,
         sql: &str,
     ) -> Result<Option<QueryResult>> {
+        if self.search_path_active() {
+            return Ok(None);
+        }
         let Some(request) = parse_simple_row_id_range_delete_sql(sql) else {
             return Ok(None);
         };
@@ -3093,7 +3665,8 @@ impl Db {
             | AlterIndexRebuild { .. }
             | AlterIndexVerify { .. }
             | AlterViewRename { .. }
-            | TruncateTable { .. } => {
+            | TruncateTable { .. }
+            | CommentOn { .. } => {
                 crate::plan_cache::PlanCacheInvalidator::on_persistent_ddl(inner);
             }
             Analyze { .. } => {
@@ -3127,10 +3700,13 @@ impl Db {
         if !self.inner.tracing.any_enabled() {
             return;
         }
-        let status = match result {
-            Ok(_) => "ok",
-            Err(_) => "error",
+        let (status, rows) = match result {
+            Ok(result) => ("ok", result.affected_rows().max(result.rows().len() as u64)),
+            Err(_) => ("error", 0),
         };
+        self.inner
+            .tracing
+            .record_statement_stats(sql, duration, rows, result.is_err());
         self.inner.tracing.record_slow_query(
             duration,
             started_at_unix_ms,
@@ -3157,7 +3733,10 @@ impl Db {
         sql: &str,
         params: &[Value],
     ) -> Result<Option<QueryResult>> {
-        if !params.is_empty() || self.inner.sql_txn_active.load(Ordering::Acquire) {
+        if !params.is_empty()
+            || self.inner.sql_txn_active.load(Ordering::Acquire)
+            || self.search_path_active()
+        {
             return Ok(None);
         }
         let Some(plan) = parse_simple_count_star_sql(sql) else {
@@ -3202,7 +3781,10 @@ impl Db {
         sql: &str,
         params: &[Value],
     ) -> Result<Option<QueryResult>> {
-        if !params.is_empty() || self.inner.sql_txn_active.load(Ordering::Acquire) {
+        if !params.is_empty()
+            || self.inner.sql_txn_active.load(Ordering::Acquire)
+            || self.search_path_active()
+        {
             return Ok(None);
         }
         let Some(plan) = parse_simple_grouped_count_sql(sql) else {
@@ -3221,6 +3803,9 @@ impl Db {
         )?;
         drop(runtime);
         drop(reader);
+        if let Some(result) = &result {
+            crate::exec::governor::check_result_rows(result.rows().len())?;
+        }
         Ok(result)
     }
 
@@ -3229,7 +3814,7 @@ impl Db {
         sql: &str,
         params: &[Value],
     ) -> Result<Option<QueryResult>> {
-        if self.inner.sql_txn_active.load(Ordering::Acquire) {
+        if self.inner.sql_txn_active.load(Ordering::Acquire) || self.search_path_active() {
             return Ok(None);
         }
         let Some(plan) = parse_simple_row_id_projection_sql(sql) else {
@@ -3327,7 +3912,7 @@ impl Db {
     /// Prepared statements are bound to the current schema cookie. If the schema
     /// changes, the handle must be recreated before it can be executed again.
     pub fn prepare(&self, sql: &str) -> Result<PreparedStatement> {
-        if !self.inner.sql_txn_active.load(Ordering::Acquire) {
+        if !self.inner.sql_txn_active.load(Ordering::Acquire) && !self.search_path_active() {
             let prepared_sql = prepared_statement_sql(sql)?;
             if let Some(prepared) = self.try_prepare_from_plan_cache(&prepared_sql)? {
                 return Ok(prepared);
@@ -3345,6 +3930,8 @@ impl Db {
         rows: &[Vec<Value>],
         options: BulkLoadOptions,
     ) -> Result<u64> {
+        let _foreign_keys = self.install_foreign_key_enforcement();
+        let _temp_store = self.install_temp_store_for(table_name)?;
         let lw_start = if self.inner.tracing.config.lock_wait.enabled {
             Some(std::time::Instant::now())
         } else {
@@ -3721,12 +4308,14 @@ impl Db {
             }
             tables.push(table_info(
                 table,
+                runtime.catalog.comments.get(&table.name),
                 self.runtime_table_row_count(&runtime, &table.name, None)?,
             ));
         }
         for table in runtime.temp_tables.values() {
             tables.push(table_info(
                 table,
+                None,
                 self.runtime_table_row_count(&runtime, &table.name, None)?,
             ));
         }
@@ -3742,15 +4331,118 @@ impl Db {
             .any(|table| identifiers_equal(&table.name, name)))
     }
 
+    /// Refreshes planner statistics for `tables`, or for every table when
+    /// `tables` is empty. Equivalent to running `ANALYZE` on each table.
+    pub fn analyze(&self, tables: &[&str]) -> Result<()> {
+        if tables.is_empty() {
+            self.execute("ANALYZE")?;
+        }
+        for table in tables {
+            self.execute(&format!("ANALYZE {}", sql_relation_name(table)))?;
+        }
+        Ok(())
+    }
+
+    /// Deletes up to about `batch_size` expired rows from each table declared
+    /// `WITH (ttl_column = ...)`, returning the number of rows deleted.
+    ///
+    /// A row expires once its TTL column is at or before the current time;
+    /// rows with a NULL TTL column never expire. Each table is swept with an
+    /// ordinary `DELETE`, so foreign key actions and triggers run as usual.
+    /// Callers drain a backlog by sweeping until no rows are deleted.
+    pub fn sweep_expired_rows(&self, batch_size: usize) -> Result<u64> {
+        if batch_size == 0 {
+            return Err(DbError::sql("ttl sweep batch size must be positive"));
+        }
+        if self.in_transaction()? {
+            return Err(DbError::transaction(
+                "cannot sweep expired rows inside an explicit SQL transaction",
+            ));
+        }
+        let targets = {
+            let runtime = self.runtime_for_metadata_inspection()?;
+            runtime
+                .catalog
+                .table_ttl
+                .iter()
+                .filter_map(|(table_name, column_name)| {
+                    let table = runtime.catalog.tables.get(table_name)?;
+                    let column = table
+                        .columns
+                        .iter()
+                        .find(|column| identifiers_equal(&column.name, column_name))?;
+                    Some((table_name.clone(), column_name.clone(), column.column_type))
+                })
+                .collect::<Vec<_>>()
+        };
+        let now_micros = std::time::SystemTime::now()
+            .duration_since(std::time::UNIX_EPOCH)
+            .unwrap_or_default()
+            .as_micros() as i64;
+        let mut deleted = 0;
+        for (table_name, column_name, column_type) in targets {
+            let now = if column_type == ColumnType::TimestampTz {
+                Value::TimestampTzMicros(now_micros)
+            } else {
+                Value::TimestampMicros(now_micros)
+            };
+            let table = sql_relation_name(&table_name);
+            let column = sql_identifier(&column_name);
+            // Bound the batch by the TTL value of its last row so the DELETE
+            // can be a plain range predicate.
+            let cutoff = self
+                .execute_with_params(
+                    &format!(
+                        "SELECT {column} FROM {table} WHERE {column} <= $1 \
+                         ORDER BY {column} LIMIT 1 OFFSET {}",
+                        batch_size - 1
+                    ),
+                    std::slice::from_ref(&now),
+                )?
+                .rows()
+                .first()
+                .and_then(|row| row.values().first().cloned())
+                .unwrap_or(now);
+            deleted += self
+                .execute_with_params(
+                    &format!("DELETE FROM {table} WHERE {column} <= $1"),
+                    &[cutoff],
+                )?
+                .affected_rows();
+        }
+        Ok(deleted)
+    }
+
+    /// Returns the statistics the planner uses for a table: the row count
+    /// and per-column NULL counts, distinct counts, and histograms recorded
+    /// by the last `ANALYZE`, plus index statistics.
+    pub fn table_statistics(&self, name: &str) -> Result<TableStatistics> {
+        let runtime = self.runtime_for_metadata_inspection()?;
+        runtime.table_statistics(name)
+    }
+
+    /// Lists the rows whose foreign keys reference a missing parent row, for
+    /// validating data written while `PRAGMA foreign_keys` was off. An empty
+    /// list means every foreign key holds.
+    pub fn check_foreign_keys(&self) -> Result<Vec<ForeignKeyViolation>> {
+        let (mut runtime, snapshot_lsn) = self.runtime_for_targeted_row_source_inspection()?;
+        if let Some(snapshot_lsn) = snapshot_lsn {
+            self.load_all_runtime_row_sources_at_snapshot(&mut runtime, snapshot_lsn)?;
+        }
+        runtime.rebuild_stale_indexes(self.inner.config.page_size)?;
+        runtime.foreign_key_violations()
+    }
+
     /// Returns a single table definition by name.
     pub fn describe_table(&self, name: &str) -> Result<TableInfo> {
         let runtime = self.runtime_for_metadata_inspection()?;
         if runtime.temp_views.contains_key(name) && !runtime.temp_tables.contains_key(name) {
             return Err(DbError::sql(format!("unknown table {name}")));
         }
-        let (table, row_count) = if let Some(table) = runtime.temp_tables.get(name) {
+        let (table, comments, row_count) = if let Some(table) = runtime.temp_tables.get(name) {
             (
                 table,
+                None,
                 self.runtime_table_row_count(&runtime, &table.name, None)?,
             )
         } else {
@@ -3761,10 +4453,11 @@ impl Db {
                 .ok_or_else(|| DbError::sql(format!("unknown table {name}")))?;
             (
                 table,
+                runtime.catalog.comments.get(&table.name),
                 self.runtime_table_row_count(&runtime, &table.name, None)?,
             )
         };
-        Ok(table_info(table, row_count))
+        Ok(table_info(table, comments, row_count))
     }
 
     /// Returns canonical `CREATE TABLE` SQL for a named table.
@@ -3773,12 +4466,27 @@ impl Db {
         if runtime.temp_views.contains_key(name) && !runtime.temp_tables.contains_key(name) {
             return Err(DbError::sql(format!("unknown table {name}")));
         }
+        if let Some(table) = runtime.temp_tables.get(name) {
+            return Ok(render_create_table(table, None));
+        }
+        if let Some(parent) = runtime.catalog.partitioned_tables.get(name) {
+            return Ok(render_create_partitioned_table(parent));
+        }
+        if let Some(parent_name) = runtime.catalog.partition_parent(name) {
+            let partition = runtime.catalog.partitioned_tables[parent_name]
+                .partition(name)
+                .ok_or_else(|| DbError::internal(format!("partition {name} is missing")))?;
+            return Ok(render_create_partition(parent_name, partition));
+        }
         let table = runtime
-            .temp_tables
+            .catalog
+            .tables
             .get(name)
-            .or_else(|| runtime.catalog.tables.get(name))
             .ok_or_else(|| DbError::sql(format!("unknown table {name}")))?;
-        Ok(render_create_table(table))
+        Ok(render_create_table(
+            table,
+            runtime.catalog.table_ttl.get(name).map(String::as_str),
+        ))
     }
 
     /// Returns all index definitions.
@@ -3794,6 +4502,7 @@ impl Db {
             .catalog
             .views
             .values()
+            .filter(|view| !runtime.catalog.partitioned_tables.contains_key(&view.name))
             .map(view_info)
             .collect::<Vec<_>>();
         views.extend(runtime.temp_views.values().map(view_info));
@@ -3880,8 +4589,70 @@ impl Db {
 
     /// Dumps the current catalog and table contents as deterministic SQL.
     pub fn dump_sql(&self) -> Result<String> {
+        self.dump_sql_with_options(&DumpOptions::default())
+    }
+
+    /// Dumps the current catalog and table contents as deterministic SQL,
+    /// restricted by `options`.
+    pub fn dump_sql_with_options(&self, options: &DumpOptions) -> Result<String> {
+        let (mut runtime, snapshot_lsn) = self.runtime_for_targeted_row_source_inspection()?;
+        render_runtime_dump(self, &mut runtime, snapshot_lsn, options)
+    }
+
+    /// Salvages schema and rows from this database into a new database at
+    /// `dest`, which must not exist yet.
+    ///
+    /// Recovery is logical: each table is recreated and its rows are copied
+    /// until the first unreadable row, so a damaged table keeps every row
+    /// read before the damage. Views, indexes, and triggers are recreated
+    /// afterwards. Whatever could not be copied is listed in the report
+    /// instead of failing the whole recovery.
+    pub fn recover_to(&self, dest: impl AsRef<Path>) -> Result<RecoveryReport> {
+        let dest = dest.as_ref();
+        if is_memory_path(dest) {
+            return Err(DbError::transaction(
+                "recover destination must be an on-disk path",
+            ));
+        }
+        let vfs = VfsHandle::for_path(dest).with_config(&self.inner.config);
+        if vfs.file_exists(dest)? {
+            return Err(DbError::io(
+                format!("destination {} already exists", dest.display()),
+                std::io::Error::new(std::io::ErrorKind::AlreadyExists, "destination exists"),
+            ));
+        }
+        let target = Db::create(dest, DbConfig::default())?;
+        let (mut runtime, snapshot_lsn) = self.runtime_for_targeted_row_source_inspection()?;
+        salvage_runtime(self, &mut runtime, snapshot_lsn, &target)
+    }
+
+    /// Copies the schema and rows selected by `options` into a new database
+    /// at `dest`, which must not exist yet.
+    ///
+    /// Tables are created parents first, so foreign keys between cloned
+    /// tables hold. A row whose foreign key points at a row that a filter
+    /// left out is skipped and counted in the report. Every cloned table
+    /// must be cloned together with the tables it references. Transforms
+    /// are applied to rows that pass the filter, and every transform of a
+    /// row sees the row's original values.
+    pub fn clone_to(&self, dest: impl AsRef<Path>, options: &CloneOptions) -> Result<CloneReport> {
+        let dest = dest.as_ref();
+        if is_memory_path(dest) {
+            return Err(DbError::transaction(
+                "clone destination must be an on-disk path",
+            ));
+        }
+        let vfs = VfsHandle::for_path(dest).with_config(&self.inner.config);
+        if vfs.file_exists(dest)? {
+            return Err(DbError::io(
+                format!("destination {} already exists", dest.display()),
+                std::io::Error::new(std::io::ErrorKind::AlreadyExists, "destination exists"),
+            ));
+        }
         let (mut runtime, snapshot_lsn) = self.runtime_for_targeted_row_source_inspection()?;
-        render_runtime_dump(self, &mut runtime, snapshot_lsn)
+        let plan = plan_runtime_clone(&runtime, options)?;
+        let target = Db::create(dest, DbConfig::default())?;
+        clone_runtime(self, &mut runtime, snapshot_lsn, &target, &plan)
     }
 
     /// Dumps a retained historical snapshot as deterministic SQL.
@@ -3894,7 +4665,12 @@ impl Db {
             &self.inner.config,
             snapshot_lsn,
         )?;
-        render_runtime_dump(self, &mut runtime, Some(snapshot_lsn))
+        render_runtime_dump(
+            self,
+            &mut runtime,
+            Some(snapshot_lsn),
+            &DumpOptions::default(),
+        )
     }
 
     /// Installs a global FaultyVfs failpoint used by the storage harness.
@@ -3951,6 +4727,7 @@ impl Db {
         vfs: VfsHandle,
         coordination_vfs: VfsHandle,
     ) -> Result<Self> {
+        config.validate_options()?;
         let open_lock_key = if vfs.is_memory() {
             None
         } else {
@@ -3991,12 +4768,23 @@ impl Db {
             effective_config.process_coordination_timeout_ms,
         )?;
 
+        let database_id = header.database_id;
+        let last_checkpoint_lsn = header.last_checkpoint_lsn;
         let pager = PagerHandle::open_with_page_pool(
             Arc::clone(&file),
             header,
             effective_config.cache_size_mb,
             effective_config.page_pool_max,
         )?;
+        if !vfs.is_memory() {
+            pager.attach_page_checksums(PageChecksums::open(
+                &vfs,
+                &path,
+                database_id,
+                last_checkpoint_lsn,
+                effective_config.verify_checksums,
+            )?);
+        }
         let wal = WalHandle::acquire(&vfs, &path, &effective_config, &pager, process_coordinator)?;
         wal.set_max_page_count(pager.on_disk_page_count()?);
 
@@ -4011,7 +4799,7 @@ impl Db {
         // downstream reads service straight from the page cache.
         let on_open_threshold_bytes =
             u64::from(effective_config.auto_checkpoint_on_open_mb) * 1024 * 1024;
-        if on_open_threshold_bytes > 0 {
+        if on_open_threshold_bytes > 0 && !effective_config.defensive {
             let wal_size = wal
                 .latest_snapshot()
                 .saturating_sub(crate::wal::format::WAL_HEADER_SIZE);
@@ -4027,16 +4815,57 @@ impl Db {
             }
         }
 
+        let db = Self::assemble(
+            path,
+            effective_config,
+            vfs,
+            pager,
+            wal,
+            schema_cookie,
+            open_lock_key.clone(),
+            Arc::new(()),
+        )?;
+        db.restore_prepared_reservations()?;
+        drop(open_guard);
+        drop(open_lock);
+        if let Some(canonical_path) = open_lock_key {
+            prune_db_open_lock_registry(&canonical_path);
+        }
+        Ok(db)
+    }
+
+    /// Loads the engine runtime over already-open storage and builds the
+    /// handle. Shared by `open_with_vfs` and `open_session`.
+    #[allow(clippy::too_many_arguments)]
+    fn assemble(
+        path: PathBuf,
+        effective_config: DbConfig,
+        vfs: VfsHandle,
+        pager: PagerHandle,
+        wal: WalHandle,
+        schema_cookie: u32,
+        reactive_registry_key: Option<PathBuf>,
+        session_group: Arc<()>,
+    ) -> Result<Self> {
         let (mut runtime, runtime_lsn) =
             EngineRuntime::load_from_storage(&pager, &wal, schema_cookie, &effective_config)?;
+        if effective_config.defensive {
+            crate::sql::defensive::check_untrusted_catalog(&runtime.catalog)?;
+        }
         let audit_context = Arc::new(Mutex::new(crate::security::AuditContext::default()));
         runtime.set_audit_context_handle(Arc::clone(&audit_context));
 
-        let tracing_state = crate::tracing::RuntimeTraceState::new(
+        let mut tracing_state = crate::tracing::RuntimeTraceState::new(
             &effective_config.tracing,
             crate::tracing::next_connection_id(),
             crate::error::short_hex_sha256(&path.to_string_lossy()),
         );
+        if !vfs.is_memory() {
+            if let Ok(canonical_path) = vfs.canonicalize_path(&path) {
+                tracing_state.share_statement_stats(&canonical_path);
+            }
+        }
+        tracing_state.set_application_name(effective_config.application_name.clone());
         let tracing_arc = Arc::new(tracing_state);
         runtime.set_tracing(Arc::clone(&tracing_arc));
 
@@ -4052,8 +4881,15 @@ impl Db {
 
         let catalog = CatalogHandle::new(runtime.catalog.as_ref().clone());
         let last_seen_checkpoint_epoch = wal.checkpoint_epoch();
-        let reactive_registry_key = open_lock_key.clone();
-        let busy_timeout_ms = effective_config.write_queue_default_timeout_ms;
+        let busy_timeout_ms = Arc::new(AtomicU64::new(
+            effective_config.write_queue_default_timeout_ms,
+        ));
+        let lock_owner = LOCK_OWNER_COUNTER.fetch_add(1, Ordering::Relaxed);
+        runtime.set_advisory_lock_scope(AdvisoryLockScope::new(
+            wal.clone(),
+            lock_owner,
+            Arc::clone(&busy_timeout_ms),
+        ));
         let mut parsed_plan_cache_config = effective_config.plan_cache.clone();
         let mut prepared_plan_cache_config = effective_config.plan_cache.clone();
         let prepared_budget = effective_config.plan_cache.max_size_bytes / 2;
@@ -4081,8 +4917,18 @@ impl Db {
                 sql_txn_active: AtomicBool::new(false),
                 write_txn: Mutex::new(WriteTxn::default()),
                 write_txn_active: AtomicBool::new(false),
-                busy_timeout_ms: AtomicU64::new(busy_timeout_ms),
+                busy_timeout_ms,
+                max_parallel_workers: AtomicUsize::new(effective_config.max_parallel_workers),
+                read_ahead_pages: AtomicUsize::new(effective_config.read_ahead_pages),
+                statement_memory_limit_bytes: AtomicUsize::new(
+                    effective_config.statement_memory_limit_bytes,
+                ),
+                max_result_rows: AtomicU64::new(effective_config.max_result_rows),
+                max_statement_seconds: AtomicU64::new(effective_config.max_statement_seconds),
+                interrupt: Arc::new(AtomicBool::new(false)),
+                foreign_keys: AtomicBool::new(effective_config.foreign_keys),
                 temp_state: Mutex::new(TempSchemaState::default()),
+                temp_spill: Mutex::new(temp_spill::TempSpill::default()),
                 statement_cache: Mutex::new(StatementCache::default()),
                 prepared_insert_cache: Mutex::new(PreparedInsertCache::default()),
                 plan_cache: Mutex::new(PlanCache::new(&parsed_plan_cache_config)),
@@ -4095,23 +4941,99 @@ impl Db {
                 reactive_registry_key,
                 reactive_hub: OnceLock::new(),
                 audit_context,
+                search_path: Mutex::new(Vec::new()),
                 read_only_paged_row_source_residency: Mutex::new(
                     ReadOnlyPagedRowSourceResidency::default(),
                 ),
                 write_queue: OnceLock::new(),
                 tracing: Arc::clone(&tracing_arc),
+                session_group,
+                lock_owner,
+                unreserved_prepared_xacts: Mutex::new(BTreeSet::new()),
             }),
         };
         db.backfill_paged_row_storage()?;
         db.refresh_named_snapshot_retention()?;
-        drop(open_guard);
-        drop(open_lock);
-        if let Some(canonical_path) = open_lock_key {
-            prune_db_open_lock_registry(&canonical_path);
-        }
         Ok(db)
     }
 
+    /// Opens another handle on this database that shares its page cache,
+    /// file handles, and WAL, but has its own session state: transactions,
+    /// temporary objects, `SET` values, and statement caches. Pooled
+    /// connections use sessions so that N connections cost one page cache
+    /// instead of N.
+    ///
+    /// The session stays valid after this handle is dropped.
+    pub fn open_session(&self) -> Result<Self> {
+        let open_lock = self
+            .inner
+            .reactive_registry_key
+            .as_ref()
+            .map(|canonical_path| db_open_lock(canonical_path.clone()))
+            .transpose()?;
+        let _open_guard = open_lock
+            .as_ref()
+            .map(|lock| {
+                lock.lock()
+                    .map_err(|_| DbError::internal("database open lock poisoned"))
+            })
+            .transpose()?;
+        let schema_cookie = self.inner.pager.header_snapshot()?.schema_cookie;
+        Self::assemble(
+            self.inner.path.clone(),
+            self.inner.config.clone(),
+            self.inner.vfs.clone(),
+            self.inner.pager.clone(),
+            self.inner.wal.clone(),
+            schema_cookie,
+            self.inner.reactive_registry_key.clone(),
+            Arc::clone(&self.inner.session_group),
+        )
+    }
+
+    /// Reports page cache activity for this handle's cache, which is shared
+    /// by every handle from `open_session`.
+    pub fn page_cache_stats(&self) -> Result<PageCacheStats> {
+        let (capacity_pages, resident_pages, hits, misses) = self.inner.pager.cache_stats()?;
+        Ok(PageCacheStats {
+            capacity_pages,
+            resident_pages,
+            hits,
+            misses,
+            sharing_handles: Arc::strong_count(&self.inner.session_group),
+        })
+    }
+
+    /// Takes advisory lock `key` without waiting and reports whether it
+    /// was taken. Advisory locks mean nothing to the engine; cooperating
+    /// handles and processes use them to elect a leader or run a singleton
+    /// job. They are reentrant per handle, are not tied to transactions,
+    /// and are released by `advisory_unlock` or when the handle is dropped.
+    /// Other processes are excluded only when process coordination is on.
+    pub fn advisory_try_lock(&self, key: i64) -> Result<bool> {
+        self.advisory_lock_scope().try_lock(key)
+    }
+
+    /// Takes advisory lock `key`, waiting up to `timeout` for its holder to
+    /// release it, or indefinitely when `timeout` is `None`.
+    pub fn advisory_lock(&self, key: i64, timeout: Option<Duration>) -> Result<()> {
+        self.advisory_lock_scope().lock(key, timeout)
+    }
+
+    /// Releases one hold of advisory lock `key`; returns false if this
+    /// handle does not hold it.
+    pub fn advisory_unlock(&self, key: i64) -> Result<bool> {
+        self.advisory_lock_scope().unlock(key)
+    }
+
+    fn advisory_lock_scope(&self) -> AdvisoryLockScope {
+        AdvisoryLockScope::new(
+            self.inner.wal.clone(),
+            self.inner.lock_owner,
+            Arc::clone(&self.inner.busy_timeout_ms),
+        )
+    }
+
     #[must_use]
     pub fn config(&self) -> &DbConfig {
         &self.inner.config
@@ -4156,6 +5078,58 @@ impl Db {
             .snapshot())
     }
 
+    /// Sets the per-handle search path: unqualified relation names resolve
+    /// to the first listed schema that holds them, and new tables and views
+    /// are created in the first listed schema. `main` and `public` name the
+    /// default schema, which is searched last when it is not listed.
+    pub fn set_search_path<S: AsRef<str>>(&self, schemas: &[S]) -> Result<()> {
+        if schemas.is_empty() {
+            return Err(DbError::sql("search_path requires at least one schema"));
+        }
+        let known = self.with_visible_catalog(|catalog, _, _| {
+            schemas
+                .iter()
+                .map(|schema| {
+                    let schema = schema.as_ref();
+                    if crate::sql::search_path::is_default_schema(schema) {
+                        return Ok(schema.to_ascii_lowercase());
+                    }
+                    catalog
+                        .schema(schema)
+                        .map(|info| info.name.clone())
+                        .ok_or_else(|| DbError::sql(format!("schema {schema} does not exist")))
+                })
+                .collect::<Result<Vec<_>>>()
+        })??;
+        *self
+            .inner
+            .search_path
+            .lock()
+            .map_err(|_| DbError::internal("search path lock poisoned"))? = known;
+        Ok(())
+    }
+
+    /// Restores the default search path, which holds only the main schema.
+    pub fn reset_search_path(&self) -> Result<()> {
+        self.inner
+            .search_path
+            .lock()
+            .map_err(|_| DbError::internal("search path lock poisoned"))?
+            .clear();
+        Ok(())
+    }
+
+    /// Returns the per-handle search path; empty when the default is in
+    /// effect.
+    pub fn search_path(&self) -> Result<Vec<String>> {
+        Ok(self
+            .inner
+            .search_path
+            .lock()
+            .map_err(|_| DbError::internal("search path lock poisoned"))?
+            .clone())
+    }
+
     pub fn schema_cookie(&self) -> Result<u32> {
         self.inner.catalog.schema_cookie()
     }
@@ -4251,6 +5225,65 @@ impl Db {
         self.inner.pager.set_schema_cookie(schema_cookie)
     }
 
+    /// Installs this handle's worker budget for the reads run on the
+    /// current thread until the returned guard is dropped.
+    fn install_parallel_workers(&self) -> crate::exec::parallel::ParallelWorkers {
+        crate::exec::parallel::ParallelWorkers::install(
+            crate::exec::parallel::effective_parallel_workers(
+                self.inner.max_parallel_workers.load(Ordering::Acquire),
+            ),
+        )
+    }
+
+    /// Installs this handle's read-ahead window for the reads run on the
+    /// current thread until the returned guard is dropped.
+    fn install_read_ahead(&self) -> crate::storage::read_ahead::ReadAhead {
+        crate::storage::read_ahead::ReadAhead::install(
+            self.inner.read_ahead_pages.load(Ordering::Acquire),
+        )
+    }
+
+    /// Installs this handle's statement memory limit for the statement run on
+    /// the current thread until the returned guard is dropped.
+    fn install_statement_memory(&self) -> crate::exec::memory::StatementMemory {
+        crate::exec::memory::StatementMemory::install(
+            self.inner
+                .statement_memory_limit_bytes
+                .load(Ordering::Acquire),
+        )
+    }
+
+    /// Installs this handle's interrupt flag for the statement run on the
+    /// current thread until the returned guard is dropped.
+    fn install_statement_interrupt(&self) -> crate::exec::interrupt::StatementInterrupt {
+        crate::exec::interrupt::StatementInterrupt::install(&self.inner.interrupt)
+    }
+
+    /// Installs this handle's result row and runtime limits for the statement
+    /// run on the current thread until the returned guard is dropped.
+    fn install_statement_limits(&self) -> crate::exec::governor::StatementLimits {
+        crate::exec::governor::StatementLimits::install(
+            self.inner.max_result_rows.load(Ordering::Acquire),
+            self.inner.max_statement_seconds.load(Ordering::Acquire),
+        )
+    }
+
+    /// Installs this handle's foreign key enforcement setting for the writes
+    /// run on the current thread until the returned guard is dropped.
+    fn install_foreign_key_enforcement(&self) -> crate::exec::constraints::ForeignKeyEnforcement {
+        crate::exec::constraints::ForeignKeyEnforcement::install(
+            self.inner.foreign_keys.load(Ordering::Acquire),
+        )
+    }
+
+    /// Installs this handle's default text collation for the DDL run on the
+    /// current thread until the returned guard is dropped.
+    fn install_default_collation(&self) -> crate::exec::ddl::DefaultCollation {
+        crate::exec::ddl::DefaultCollation::install(
+            self.inner.config.default_collation.column_collation(),
+        )
+    }
+
     fn execute_read_statement(
         &self,
         statement: &crate::sql::ast::Statement,
@@ -4287,6 +5320,8 @@ impl Db {
         params: &[Value],
         prepared: Option<&PreparedStatement>,
     ) -> Result<QueryResult> {
+        let _workers = self.install_parallel_workers();
+        let _read_ahead = self.install_read_ahead();
         {
             let runtime = self
                 .inner
@@ -4655,6 +5690,30 @@ impl Db {
         Ok(QueryResult::with_affected_rows(0))
     }
 
+    fn execute_search_path_command(
+        &self,
+        command: crate::sql::search_path::SearchPathCommand,
+    ) -> Result<QueryResult> {
+        use crate::sql::search_path::SearchPathCommand;
+        match command {
+            SearchPathCommand::Set(schemas) => self.set_search_path(&schemas)?,
+            SearchPathCommand::Reset => self.reset_search_path()?,
+            SearchPathCommand::Show => {
+                let schemas = self.search_path()?;
+                let shown = if schemas.is_empty() {
+                    "main".to_string()
+                } else {
+                    schemas.join(", ")
+                };
+                return Ok(QueryResult::with_rows(
+                    vec!["search_path".to_string()],
+                    vec![QueryRow::new(vec![Value::Text(shown)])],
+                ));
+            }
+        }
+        Ok(QueryResult::with_affected_rows(0))
+    }
+
     fn execute_security_command(
         &self,
         sql: &str,
@@ -4748,7 +5807,12 @@ impl Db {
         statement: Option<&str>,
     ) -> Result<()> {
         self.ensure_security_catalog()?;
-        let context = self.audit_context_snapshot()?;
+        let mut context = self.audit_context_snapshot()?;
+        if let Some(name) = self.inner.tracing.application_name() {
+            context
+                .entry("application_name".to_string())
+                .or_insert(Value::Text(name));
+        }
         let context_json = audit_context_json(&context)?;
         let actor = context
             .get("actor")
@@ -4831,7 +5895,16 @@ impl Db {
             PragmaName::IntegrityCheck | PragmaName::QuickCheck => self.integrity_check_results(),
             PragmaName::ForeignKeys => Ok(QueryResult::with_rows(
                 vec!["foreign_keys".to_string()],
-                vec![QueryRow::new(vec![Value::Int64(1)])],
+                vec![QueryRow::new(vec![Value::Int64(i64::from(
+                    self.inner.foreign_keys.load(Ordering::Acquire),
+                ))])],
+            )),
+            PragmaName::ForeignKeyCheck => self.foreign_key_check_results(None),
+            PragmaName::Defensive => Ok(QueryResult::with_rows(
+                vec!["defensive".to_string()],
+                vec![QueryRow::new(vec![Value::Int64(i64::from(
+                    self.inner.config.defensive,
+                ))])],
             )),
             PragmaName::JournalMode => Ok(QueryResult::with_rows(
                 vec!["journal_mode".to_string()],
@@ -4865,14 +5938,74 @@ impl Db {
                 vec!["locking_mode".to_string()],
                 vec![QueryRow::new(vec![Value::Text("normal".to_string())])],
             )),
-            PragmaName::TempStore => Ok(QueryResult::with_rows(
-                vec!["temp_store".to_string()],
-                vec![QueryRow::new(vec![Value::Int64(1)])],
+            PragmaName::TempStore => Ok(QueryResult::with_rows(
+                vec!["temp_store".to_string()],
+                vec![QueryRow::new(vec![Value::Int64(1)])],
+            )),
+            PragmaName::BusyTimeout => Ok(QueryResult::with_rows(
+                vec!["busy_timeout".to_string()],
+                vec![QueryRow::new(vec![Value::Int64(
+                    i64::try_from(self.inner.busy_timeout_ms.load(Ordering::Acquire))
+                        .unwrap_or(i64::MAX),
+                )])],
+            )),
+            PragmaName::MaxParallelWorkers => Ok(QueryResult::with_rows(
+                vec!["max_parallel_workers".to_string()],
+                vec![QueryRow::new(vec![Value::Int64(
+                    i64::try_from(self.inner.max_parallel_workers.load(Ordering::Acquire))
+                        .unwrap_or(i64::MAX),
+                )])],
+            )),
+            PragmaName::ReadAheadPages => Ok(QueryResult::with_rows(
+                vec!["read_ahead_pages".to_string()],
+                vec![QueryRow::new(vec![Value::Int64(
+                    i64::try_from(self.inner.read_ahead_pages.load(Ordering::Acquire))
+                        .unwrap_or(i64::MAX),
+                )])],
+            )),
+            PragmaName::StatementMemoryLimitBytes => Ok(QueryResult::with_rows(
+                vec!["statement_memory_limit_bytes".to_string()],
+                vec![QueryRow::new(vec![Value::Int64(
+                    i64::try_from(
+                        self.inner
+                            .statement_memory_limit_bytes
+                            .load(Ordering::Acquire),
+                    )
+                    .unwrap_or(i64::MAX),
+                )])],
+            )),
+            PragmaName::MaxResultRows => Ok(QueryResult::with_rows(
+                vec!["max_result_rows".to_string()],
+                vec![QueryRow::new(vec![Value::Int64(
+                    i64::try_from(self.inner.max_result_rows.load(Ordering::Acquire))
+                        .unwrap_or(i64::MAX),
+                )])],
+            )),
+            PragmaName::MaxStatementSeconds => Ok(QueryResult::with_rows(
+                vec!["max_statement_seconds".to_string()],
+                vec![QueryRow::new(vec![Value::Int64(
+                    i64::try_from(self.inner.max_statement_seconds.load(Ordering::Acquire))
+                        .unwrap_or(i64::MAX),
+                )])],
+            )),
+            PragmaName::ApplicationName => Ok(QueryResult::with_rows(
+                vec!["application_name".to_string()],
+                vec![QueryRow::new(vec![self
+                    .inner
+                    .tracing
+                    .application_name()
+                    .map_or(Value::Null, Value::Text)])],
+            )),
+            PragmaName::WalCheckpointThresholdPages => Ok(QueryResult::with_rows(
+                vec!["wal_checkpoint_threshold_pages".to_string()],
+                vec![QueryRow::new(vec![Value::Int64(i64::from(
+                    self.inner.wal.auto_checkpoint_thresholds().0,
+                ))])],
             )),
-            PragmaName::BusyTimeout => Ok(QueryResult::with_rows(
-                vec!["busy_timeout".to_string()],
+            PragmaName::WalCheckpointThresholdBytes => Ok(QueryResult::with_rows(
+                vec!["wal_checkpoint_threshold_bytes".to_string()],
                 vec![QueryRow::new(vec![Value::Int64(
-                    i64::try_from(self.inner.busy_timeout_ms.load(Ordering::Acquire))
+                    i64::try_from(self.inner.wal.auto_checkpoint_thresholds().1)
                         .unwrap_or(i64::MAX),
                 )])],
             )),
@@ -4933,6 +6066,10 @@ impl Db {
                     sql_string_literal(&table_name)
                 ))
             }
+            PragmaName::ForeignKeyCheck => {
+                let table_name = pragma_required_argument(&target, argument)?;
+                self.foreign_key_check_results(Some(&table_name))
+            }
             PragmaName::FlushPlanCache => {
                 self.flush_plan_cache()?;
                 Ok(QueryResult::with_affected_rows(0))
@@ -5103,6 +6240,41 @@ impl Db {
         Ok(())
     }
 
+    /// Reports foreign key violations in SQLite's `PRAGMA foreign_key_check`
+    /// shape, optionally limited to one child table.
+    fn foreign_key_check_results(&self, table_name: Option<&str>) -> Result<QueryResult> {
+        if let Some(table_name) = table_name {
+            let runtime = self.runtime_for_metadata_inspection()?;
+            if runtime.catalog.table(table_name).is_none() {
+                return Err(DbError::sql(format!("unknown table {table_name}")));
+            }
+        }
+        let rows = self
+            .check_foreign_keys()?
+            .into_iter()
+            .filter(|violation| {
+                table_name.is_none_or(|name| identifiers_equal(name, &violation.table_name))
+            })
+            .map(|violation| {
+                QueryRow::new(vec![
+                    Value::Text(violation.table_name),
+                    Value::Int64(violation.row_id),
+                    Value::Text(violation.referenced_table),
+                    Value::Int64(i64::try_from(violation.foreign_key_ordinal).unwrap_or(i64::MAX)),
+                ])
+            })
+            .collect();
+        Ok(QueryResult::with_rows(
+            vec![
+                "table".to_string(),
+                "rowid".to_string(),
+                "parent".to_string(),
+                "fkid".to_string(),
+            ],
+            rows,
+        ))
+    }
+
     fn integrity_check_results(&self) -> Result<QueryResult> {
         let (mut runtime, snapshot_lsn) = self.runtime_for_targeted_row_source_inspection()?;
         runtime.rebuild_stale_indexes(self.inner.config.page_size)?;
@@ -5193,6 +6365,7 @@ impl Db {
             | PragmaName::IndexInfo
             | PragmaName::IndexXInfo
             | PragmaName::ForeignKeyList
+            | PragmaName::ForeignKeyCheck
             | PragmaName::WalCheckpoint
             | PragmaName::QuickCheck => Err(DbError::sql(format!(
                 "PRAGMA {} does not support assignment",
@@ -5209,15 +6382,18 @@ impl Db {
                     ))
                 }
             }
+            PragmaName::Defensive => Err(DbError::sql(
+                "PRAGMA defensive cannot be changed on an open connection; reopen with DbConfig::defensive",
+            )),
             PragmaName::ForeignKeys => {
-                let value = parse_pragma_bool_value(&value, "PRAGMA foreign_keys")?;
-                if value {
-                    Ok(QueryResult::with_affected_rows(0))
-                } else {
-                    Err(DbError::sql(
-                        "PRAGMA foreign_keys cannot disable foreign key enforcement in DecentDB",
-                    ))
-                }
+                let value = match &value {
+                    PragmaValue::Text(text) if text.trim().eq_ignore_ascii_case("DEFAULT") => {
+                        self.inner.config.foreign_keys
+                    }
+                    _ => parse_pragma_bool_value(&value, "PRAGMA foreign_keys")?,
+                };
+                self.inner.foreign_keys.store(value, Ordering::Release);
+                Ok(QueryResult::with_affected_rows(0))
             }
             PragmaName::JournalMode => {
                 let mode = parse_pragma_text_or_mode(&value, "PRAGMA journal_mode")?;
@@ -5319,6 +6495,129 @@ impl Db {
                 self.inner.busy_timeout_ms.store(value, Ordering::Release);
                 Ok(QueryResult::with_affected_rows(0))
             }
+            PragmaName::MaxParallelWorkers => {
+                let workers = match &value {
+                    PragmaValue::Text(text) if text.trim().eq_ignore_ascii_case("DEFAULT") => {
+                        self.inner.config.max_parallel_workers
+                    }
+                    _ => usize::try_from(pragma_value_i64(&value)?).map_err(|_| {
+                        DbError::sql(
+                            "PRAGMA max_parallel_workers requires a non-negative integer or DEFAULT",
+                        )
+                    })?,
+                };
+                self.inner
+                    .max_parallel_workers
+                    .store(workers, Ordering::Release);
+                Ok(QueryResult::with_affected_rows(0))
+            }
+            PragmaName::ReadAheadPages => {
+                let pages = match &value {
+                    PragmaValue::Text(text) if text.trim().eq_ignore_ascii_case("DEFAULT") => {
+                        self.inner.config.read_ahead_pages
+                    }
+                    _ => usize::try_from(pragma_value_i64(&value)?).map_err(|_| {
+                        DbError::sql(
+                            "PRAGMA read_ahead_pages requires a non-negative integer or DEFAULT",
+                        )
+                    })?,
+                };
+                self.inner.read_ahead_pages.store(
+                    pages.min(crate::storage::read_ahead::MAX_READ_AHEAD_PAGES),
+                    Ordering::Release,
+                );
+                Ok(QueryResult::with_affected_rows(0))
+            }
+            PragmaName::StatementMemoryLimitBytes => {
+                let bytes = match &value {
+                    PragmaValue::Text(text) if text.trim().eq_ignore_ascii_case("DEFAULT") => {
+                        self.inner.config.statement_memory_limit_bytes
+                    }
+                    _ => usize::try_from(pragma_value_i64(&value)?).map_err(|_| {
+                        DbError::sql(
+                            "PRAGMA statement_memory_limit_bytes requires a non-negative integer or DEFAULT",
+                        )
+                    })?,
+                };
+                self.inner
+                    .statement_memory_limit_bytes
+                    .store(bytes, Ordering::Release);
+                Ok(QueryResult::with_affected_rows(0))
+            }
+            PragmaName::MaxResultRows => {
+                let rows = match &value {
+                    PragmaValue::Text(text) if text.trim().eq_ignore_ascii_case("DEFAULT") => {
+                        self.inner.config.max_result_rows
+                    }
+                    _ => u64::try_from(pragma_value_i64(&value)?).map_err(|_| {
+                        DbError::sql(
+                            "PRAGMA max_result_rows requires a non-negative integer or DEFAULT",
+                        )
+                    })?,
+                };
+                self.inner.max_result_rows.store(rows, Ordering::Release);
+                Ok(QueryResult::with_affected_rows(0))
+            }
+            PragmaName::MaxStatementSeconds => {
+                let seconds = match &value {
+                    PragmaValue::Text(text) if text.trim().eq_ignore_ascii_case("DEFAULT") => {
+                        self.inner.config.max_statement_seconds
+                    }
+                    _ => u64::try_from(pragma_value_i64(&value)?).map_err(|_| {
+                        DbError::sql(
+                            "PRAGMA max_statement_seconds requires a non-negative integer or DEFAULT",
+                        )
+                    })?,
+                };
+                self.inner
+                    .max_statement_seconds
+                    .store(seconds, Ordering::Release);
+                Ok(QueryResult::with_affected_rows(0))
+            }
+            PragmaName::ApplicationName => {
+                let name = match value {
+                    PragmaValue::Text(text) if text.trim().eq_ignore_ascii_case("DEFAULT") => {
+                        self.inner.config.application_name.clone()
+                    }
+                    PragmaValue::Text(text) if text.is_empty() => None,
+                    PragmaValue::Text(text) => Some(text),
+                    PragmaValue::Int(_) => {
+                        return Err(DbError::sql(
+                            "PRAGMA application_name requires a quoted string or DEFAULT",
+                        ))
+                    }
+                };
+                self.inner.tracing.set_application_name(name);
+                Ok(QueryResult::with_affected_rows(0))
+            }
+            PragmaName::WalCheckpointThresholdPages => {
+                let pages = match &value {
+                    PragmaValue::Text(text) if text.trim().eq_ignore_ascii_case("DEFAULT") => {
+                        self.inner.config.wal_checkpoint_threshold_pages
+                    }
+                    _ => u32::try_from(pragma_value_i64(&value)?).map_err(|_| {
+                        DbError::sql(
+                            "PRAGMA wal_checkpoint_threshold_pages requires a non-negative integer or DEFAULT",
+                        )
+                    })?,
+                };
+                self.inner.wal.set_auto_checkpoint_threshold_pages(pages);
+                Ok(QueryResult::with_affected_rows(0))
+            }
+            PragmaName::WalCheckpointThresholdBytes => {
+                let bytes = match &value {
+                    PragmaValue::Text(text) if text.trim().eq_ignore_ascii_case("DEFAULT") => {
+                        self.inner.config.wal_checkpoint_threshold_bytes
+                    }
+                    _ => u64::try_from(pragma_value_i64(&value)?).map_err(|_| {
+                        DbError::sql(
+                            "PRAGMA wal_checkpoint_threshold_bytes requires a non-negative integer or DEFAULT",
+                        )
+                    })?,
+                };
+                self.inner.wal.set_auto_checkpoint_threshold_bytes(bytes);
+                Ok(QueryResult::with_affected_rows(0))
+            }
         }
     }
 
@@ -5328,9 +6627,19 @@ impl Db {
         params: &[Value],
     ) -> Result<QueryResult> {
         if prepared.read_only {
-            self.execute_prepared_read_statement(prepared, params)
+            let result = self.execute_prepared_read_statement(prepared, params)?;
+            if matches!(prepared.statement.as_ref(), SqlStatement::Query(_)) {
+                crate::exec::governor::check_result_rows(result.rows().len())?;
+            }
+            Ok(result)
         } else {
-            self.execute_prepared_write_statement(prepared, params)
+            let _foreign_keys = self.install_foreign_key_enforcement();
+            let _default_collation = self.install_default_collation();
+            let result = self.execute_prepared_write_statement(prepared, params);
+            if result.is_ok() {
+                self.maybe_auto_analyze();
+            }
+            result
         }
     }
 
@@ -5340,9 +6649,19 @@ impl Db {
         params: &mut [Value],
     ) -> Result<QueryResult> {
         if prepared.read_only {
-            self.execute_prepared_read_statement(prepared, params)
+            let result = self.execute_prepared_read_statement(prepared, params)?;
+            if matches!(prepared.statement.as_ref(), SqlStatement::Query(_)) {
+                crate::exec::governor::check_result_rows(result.rows().len())?;
+            }
+            Ok(result)
         } else {
-            self.execute_prepared_write_statement_mut(prepared, params)
+            let _foreign_keys = self.install_foreign_key_enforcement();
+            let _default_collation = self.install_default_collation();
+            let result = self.execute_prepared_write_statement_mut(prepared, params);
+            if result.is_ok() {
+                self.maybe_auto_analyze();
+            }
+            result
         }
     }
 
@@ -5356,10 +6675,12 @@ impl Db {
     where
         F: FnMut(usize, &mut [Value]) -> Result<()>,
     {
+        let _temp_store = self.install_temp_store_for(&prepared.prepared_sql)?;
         let mut params = vec![Value::Null; param_count];
         if row_count == 0 {
             return Ok(0);
         }
+        let _foreign_keys = self.install_foreign_key_enforcement();
 
         if self.inner.sql_txn_active.load(Ordering::Acquire) {
             let mut txn = self
@@ -5755,6 +7076,11 @@ impl Db {
         plan: &PreparedSimpleIndexedProjection,
         params: &[Value],
     ) -> Result<Option<QueryResult>> {
+        // Row-level security policies and masks are applied by the generic
+        // executor only, so leave secured reads to it.
+        if Self::runtime_has_deferred_security_tables(runtime) || runtime.security_rules_active()? {
+            return Ok(None);
+        }
         let lookup_value = match &plan.lookup {
             PreparedSimpleIndexedProjectionLookup::RowId { value_source }
             | PreparedSimpleIndexedProjectionLookup::Index { value_source, .. } => {
@@ -5791,45 +7117,54 @@ impl Db {
                 }
             }
             PreparedSimpleIndexedProjectionLookup::Index { index_name, .. } => {
-                let Some(RuntimeIndex::Btree { keys, .. }) = runtime.index(index_name) else {
+                let Some(RuntimeIndex::Btree { keys, covering }) = runtime.index(index_name) else {
                     return Ok(None);
                 };
+                // Answer from the index alone when it holds every projected
+                // column, under the same conditions the planner uses for a
+                // covering seek; tombstoned rows may still have index entries.
+                let index_schema = runtime.catalog.index(index_name);
+                let covered_columns = match runtime.catalog.table(&plan.table_name) {
+                    Some(table)
+                        if index_schema.is_some_and(|index| {
+                            crate::planner::index_can_serve_covered_reads(table, index)
+                        }) && !row_source.has_tombstoned_rows() =>
+                    {
+                        covered_projection_columns(
+                            covering.as_ref(),
+                            table,
+                            &plan.projection_indexes,
+                        )
+                    }
+                    _ => None,
+                };
+                let mut push_row = |row_id: i64| -> Result<()> {
+                    if let Some(row) = covered_columns
+                        .as_deref()
+                        .and_then(|columns| project_covered_row(covering.as_ref(), row_id, columns))
+                    {
+                        rows.push(row);
+                    } else if let Some(stored_row) = row_source.row_by_id(row_id)? {
+                        rows.push(QueryRow::new(
+                            plan.projection_indexes
+                                .iter()
+                                .map(|index| stored_row.values()[*index].clone())
+                                .collect(),
+                        ));
+                    }
+                    Ok(())
+                };
                 match keys.row_ids_for_value_set(&lookup_value)? {
                     RuntimeRowIdSet::Empty => {}
-                    RuntimeRowIdSet::Single(row_id) => {
-                        if let Some(stored_row) = row_source.row_by_id(row_id)? {
-                            rows.push(QueryRow::new(
-                                plan.projection_indexes
-                                    .iter()
-                                    .map(|index| stored_row.values()[*index].clone())
-                                    .collect(),
-                            ));
-                        }
-                    }
+                    RuntimeRowIdSet::Single(row_id) => push_row(row_id)?,
                     RuntimeRowIdSet::Many(row_ids) => {
-                        rows.reserve(row_ids.len());
                         for row_id in row_ids {
-                            if let Some(stored_row) = row_source.row_by_id(*row_id)? {
-                                rows.push(QueryRow::new(
-                                    plan.projection_indexes
-                                        .iter()
-                                        .map(|index| stored_row.values()[*index].clone())
-                                        .collect(),
-                                ));
-                            }
+                            push_row(*row_id)?;
                         }
                     }
                     RuntimeRowIdSet::Owned(row_ids) => {
-                        rows.reserve(row_ids.len());
                         for row_id in row_ids {
-                            if let Some(stored_row) = row_source.row_by_id(row_id)? {
-                                rows.push(QueryRow::new(
-                                    plan.projection_indexes
-                                        .iter()
-                                        .map(|index| stored_row.values()[*index].clone())
-                                        .collect(),
-                                ));
-                            }
+                            push_row(row_id)?;
                         }
                     }
                 }
@@ -6351,6 +7686,8 @@ impl Db {
         statement: &crate::sql::ast::Statement,
         params: &[Value],
     ) -> Result<QueryResult> {
+        let _foreign_keys = self.install_foreign_key_enforcement();
+        let _default_collation = self.install_default_collation();
         let temp_only = {
             let runtime = self
                 .inner
@@ -7252,7 +8589,7 @@ impl Db {
     }
 
     fn runtime_has_persistent_commit_work(&self, runtime: &EngineRuntime) -> Result<bool> {
-        if !runtime.dirty_tables.is_empty() {
+        if !runtime.dirty_tables.is_empty() || runtime.stats_dirty {
             return Ok(true);
         }
         Ok(self.inner.catalog.schema_cookie()? != runtime.catalog.schema_cookie)
@@ -7386,8 +8723,7 @@ impl Db {
             Ok(lsn) => lsn,
             Err(error) => {
                 self.restore_runtime_from_storage(&mut runtime)?;
-                return if matches!(&error, DbError::Transaction { message } if message.starts_with("transaction conflict: WAL advanced"))
-                {
+                return if error.is_transaction_conflict() {
                     Ok(())
                 } else {
                     Err(error)
@@ -7609,13 +8945,115 @@ impl Db {
         temp_only
     }
 
+    /// Runs `f` against the catalog and temp objects this handle currently
+    /// sees, inside its open SQL transaction when there is one.
+    fn with_visible_catalog<T>(
+        &self,
+        f: impl FnOnce(
+            &crate::catalog::CatalogState,
+            &BTreeMap<String, TableSchema>,
+            &BTreeMap<String, ViewSchema>,
+        ) -> T,
+    ) -> Result<T> {
+        if self.inner.sql_txn_active.load(Ordering::Acquire) {
+            let txn = self
+                .inner
+                .sql_txn
+                .lock()
+                .map_err(|_| DbError::internal("SQL transaction lock poisoned"))?;
+            if let SqlTxnSlot::Shared(state) = &*txn {
+                let runtime = &state.runtime;
+                return Ok(f(
+                    &runtime.catalog,
+                    &runtime.temp_tables,
+                    &runtime.temp_views,
+                ));
+            }
+        }
+        self.refresh_engine_from_storage()?;
+        let runtime = self
+            .inner
+            .engine
+            .read()
+            .map_err(|_| DbError::internal("engine runtime lock poisoned"))?;
+        let temp = self
+            .inner
+            .temp_state
+            .lock()
+            .map_err(|_| DbError::internal("temp schema lock poisoned"))?;
+        Ok(f(&runtime.catalog, &temp.tables, &temp.views))
+    }
+
+    /// Returns true when a non-default search path is set. Name resolution
+    /// then depends on session state the SQL text does not carry, so the
+    /// SQL-keyed caches and fast paths are bypassed.
+    fn search_path_active(&self) -> bool {
+        self.inner
+            .search_path
+            .lock()
+            .map(|schemas| !schemas.is_empty())
+            .unwrap_or(false)
+    }
+
+    /// Parses `sql` against the session search path, or returns `None` when
+    /// the default path is in effect.
+    fn parse_with_search_path(&self, sql: &str) -> Result<Option<Arc<SqlStatement>>> {
+        if !self.search_path_active() {
+            return Ok(None);
+        }
+        self.with_visible_catalog(|catalog, temp_tables, temp_views| {
+            self.parse_with_search_path_in(sql, catalog, temp_tables, temp_views)
+        })?
+    }
+
+    /// Like [`Self::parse_with_search_path`], resolving names against the
+    /// given catalog instead of locking the handle's own.
+    fn parse_with_search_path_in(
+        &self,
+        sql: &str,
+        catalog: &crate::catalog::CatalogState,
+        temp_tables: &BTreeMap<String, TableSchema>,
+        temp_views: &BTreeMap<String, ViewSchema>,
+    ) -> Result<Option<Arc<SqlStatement>>> {
+        let schemas = self.search_path()?;
+        if schemas.is_empty() {
+            return Ok(None);
+        }
+        let search_path = crate::sql::search_path::SearchPath::new(
+            schemas,
+            catalog
+                .tables
+                .keys()
+                .chain(catalog.views.keys())
+                .chain(catalog.indexes.keys())
+                .cloned(),
+            temp_tables.keys().chain(temp_views.keys()).cloned(),
+        );
+        let statement =
+            crate::sql::search_path::with_search_path(search_path, || parse_sql_statement(sql))?;
+        Ok(Some(Arc::new(statement)))
+    }
+
     fn parsed_statement(&self, sql: &str) -> Result<Arc<SqlStatement>> {
+        if !self.inner.config.defensive {
+            return self.parse_statement_cached(sql);
+        }
+        crate::sql::defensive::check_untrusted_sql(sql)?;
+        let statement = self.parse_statement_cached(sql)?;
+        crate::sql::defensive::check_untrusted_statement(&statement)?;
+        Ok(statement)
+    }
+
+    fn parse_statement_cached(&self, sql: &str) -> Result<Arc<SqlStatement>> {
         // Try the connection-local plan cache first. The cache is keyed
         // by the prepared SQL text plus the current schema cookies and
         // policy/mask generation; on a hit we still get a fresh
         // `PreparedStatement` (which is cheap to construct) but we
         // skip the parse step.
         let prepared_sql = prepared_statement_sql(sql)?;
+        if let Some(statement) = self.parse_with_search_path(&prepared_sql)? {
+            return Ok(statement);
+        }
         let parameter_shape = parameter_shape_for_prepared_sql(&prepared_sql);
         if parameter_shape.arity() == 0 {
             return self
@@ -7728,18 +9166,24 @@ impl Db {
             runtime.temp_schema_cookie,
             policy_gen,
         );
-        if let Some(bundle) = self
-            .inner
-            .prepared_plan_cache
-            .lock()
-            .map_err(|_| DbError::internal("prepared plan cache lock poisoned"))?
-            .get(
-                &key,
-                runtime.catalog.schema_cookie,
-                runtime.temp_schema_cookie,
-                policy_gen,
-            )
-        {
+        // Under a search path the plan depends on more than the SQL text,
+        // so neither the cache nor the SQL-text plans apply.
+        let plan_follows_sql = !self.search_path_active();
+        let cached = if plan_follows_sql {
+            self.inner
+                .prepared_plan_cache
+                .lock()
+                .map_err(|_| DbError::internal("prepared plan cache lock poisoned"))?
+                .get(
+                    &key,
+                    runtime.catalog.schema_cookie,
+                    runtime.temp_schema_cookie,
+                    policy_gen,
+                )
+        } else {
+            None
+        };
+        if let Some(bundle) = cached {
             return Ok(PreparedStatement {
                 db: self.clone(),
                 schema_cookie: runtime.catalog.schema_cookie,
@@ -7758,7 +9202,9 @@ impl Db {
                 read_only: bundle.read_only,
             });
         }
-        if let Some(request) = parse_simple_row_id_range_delete_sql(&prepared_sql) {
+        if let Some(request) =
+            parse_simple_row_id_range_delete_sql(&prepared_sql).filter(|_| plan_follows_sql)
+        {
             if let Some(prepared_delete) = runtime.prepare_simple_row_id_range_delete(
                 &request.table_name,
                 &request.column_name,
@@ -7805,7 +9251,17 @@ impl Db {
                 });
             }
         }
-        let statement = self.parsed_statement(&prepared_sql)?;
+        // Callers may hold the engine or transaction locks, so resolve the
+        // search path against the runtime being prepared against.
+        let statement = match self.parse_with_search_path_in(
+            &prepared_sql,
+            &runtime.catalog,
+            &runtime.temp_tables,
+            &runtime.temp_views,
+        )? {
+            Some(statement) => statement,
+            None => self.parsed_statement(&prepared_sql)?,
+        };
         let read_only = statement_is_read_only(statement.as_ref());
         let (prepared_insert, prepared_update, prepared_delete) = match statement.as_ref() {
             SqlStatement::Insert(insert) => (
@@ -7825,12 +9281,14 @@ impl Db {
             ),
             _ => (None, None, None),
         };
-        let simple_row_id_projection =
-            Self::prepared_simple_row_id_projection(&prepared_sql, runtime);
+        let simple_row_id_projection = plan_follows_sql
+            .then(|| Self::prepared_simple_row_id_projection(&prepared_sql, runtime))
+            .flatten();
         let simple_indexed_projection =
             Self::prepared_simple_indexed_projection(statement.as_ref(), runtime);
-        let simple_row_id_range_projection =
-            Self::prepared_simple_row_id_range_projection(&prepared_sql, runtime);
+        let simple_row_id_range_projection = plan_follows_sql
+            .then(|| Self::prepared_simple_row_id_range_projection(&prepared_sql, runtime))
+            .flatten();
         let simple_ordered_row_id_projection =
             Self::prepared_simple_ordered_row_id_projection(statement.as_ref(), runtime);
         let simple_row_id_join_projection =
@@ -7850,7 +9308,7 @@ impl Db {
             prepared_delete,
             read_only,
         };
-        if Self::statement_can_enter_plan_cache(bundle.statement.as_ref()) {
+        if plan_follows_sql && Self::statement_can_enter_plan_cache(bundle.statement.as_ref()) {
             if let Ok(mut cache) = self.inner.prepared_plan_cache.lock() {
                 cache.insert(
                     key,
@@ -7957,7 +9415,9 @@ impl Db {
             return None;
         }
         let table = runtime.catalog.table(name)?;
-        if !prepared_table_generated_columns_are_stored(table) {
+        if !prepared_table_generated_columns_are_stored(table)
+            || prepared_table_has_collated_columns(table)
+        {
             return None;
         }
         let binding_name = alias.as_deref().unwrap_or(name);
@@ -8422,7 +9882,9 @@ impl Db {
             return None;
         }
         let table = runtime.catalog.table(name)?;
-        if !prepared_table_generated_columns_are_stored(table) {
+        if !prepared_table_generated_columns_are_stored(table)
+            || prepared_table_has_collated_columns(table)
+        {
             return None;
         }
         let param_index = prepared_scalar_filter_param(select.filter.as_ref()?, name, alias)?;
@@ -8464,6 +9926,9 @@ impl Db {
         statement: &crate::sql::ast::InsertStatement,
         runtime: &EngineRuntime,
     ) -> Result<Option<Arc<PreparedSimpleInsert>>> {
+        if self.search_path_active() {
+            return Ok(runtime.prepare_simple_insert(statement)?.map(Arc::new));
+        }
         self.inner
             .prepared_insert_cache
             .lock()
@@ -8595,6 +10060,8 @@ impl Db {
                 .rebuild_stale_indexes(self.inner.config.page_size)?;
         }
         let reactive_pending = self.take_reactive_pending_commit(&mut state.runtime);
+        let written_tables = Arc::clone(&state.runtime.dirty_tables);
+        let base_tables = Arc::clone(&state.runtime.persisted_tables);
         self.begin_write()?;
         if let Err(error) = state.runtime.persist_to_db(self) {
             let _ = self.rollback();
@@ -8608,7 +10075,12 @@ impl Db {
             Err(error) => {
                 let _ = self.rollback();
                 self.restore_runtime_from_storage(&mut state.runtime)?;
-                return Err(error);
+                return Err(attribute_transaction_conflict(
+                    error,
+                    &written_tables,
+                    &base_tables,
+                    &state.runtime.persisted_tables,
+                ));
             }
         };
         self.sync_post_commit(&mut state.runtime, committed_lsn)?;
@@ -8651,6 +10123,8 @@ impl Db {
         }
         let compacted_bytes = runtime.compact_dirty_resident_storage_after_transaction_commit();
         let reactive_pending = self.take_reactive_pending_commit(&mut runtime);
+        let written_tables = Arc::clone(&runtime.dirty_tables);
+        let base_tables = Arc::clone(&runtime.persisted_tables);
         self.begin_write()?;
         if let Err(error) = runtime.persist_to_db(self) {
             let _ = self.rollback();
@@ -8664,7 +10138,28 @@ impl Db {
             Ok(lsn) => lsn,
             Err(error) => {
                 let _ = self.rollback();
-                return Err(error);
+                if !error.is_transaction_conflict() {
+                    return Err(error);
+                }
+                // The engine runtime refreshes on the next statement; load
+                // the winning commit's table states only to name the table.
+                let latest = self.current_schema_cookie().and_then(|schema_cookie| {
+                    EngineRuntime::load_from_storage(
+                        &self.inner.pager,
+                        &self.inner.wal,
+                        schema_cookie,
+                        &self.inner.config,
+                    )
+                });
+                return Err(match latest {
+                    Ok((latest, _)) => attribute_transaction_conflict(
+                        error,
+                        &written_tables,
+                        &base_tables,
+                        &latest.persisted_tables,
+                    ),
+                    Err(_) => error,
+                });
             }
         };
         self.sync_post_commit(&mut runtime, committed_lsn)?;
@@ -8699,6 +10194,7 @@ impl Db {
     }
 
     fn runtime_for_targeted_row_source_inspection(&self) -> Result<(EngineRuntime, Option<u64>)> {
+        let _temp_store = self.install_temp_store()?;
         if let Some((runtime, snapshot_lsn)) = self.transaction_runtime_snapshot_with_lsn()? {
             return Ok((runtime, Some(snapshot_lsn)));
         }
@@ -8863,6 +10359,7 @@ impl Db {
     }
 
     fn runtime_for_metadata_inspection(&self) -> Result<EngineRuntime> {
+        let _temp_store = self.install_temp_store()?;
         if let Some(runtime) = self.transaction_runtime_snapshot()? {
             return Ok(runtime);
         }
@@ -8993,10 +10490,13 @@ impl Db {
             &self.inner.config,
         )?;
         restored.set_audit_context_handle(Arc::clone(&self.inner.audit_context));
+        restored.set_advisory_lock_scope(self.advisory_lock_scope());
+        restored.set_tracing(Arc::clone(&self.inner.tracing));
         self.apply_temp_state_to_runtime(&mut restored)?;
         self.inner
             .catalog
             .replace(restored.catalog.as_ref().clone())?;
+        restored.analyze_churn = std::mem::take(&mut runtime.analyze_churn);
         *runtime = restored;
         self.inner
             .last_runtime_lsn
@@ -9062,6 +10562,7 @@ impl Db {
             &self.inner.config,
             snapshot_lsn,
         )?;
+        runtime.set_tracing(Arc::clone(&self.inner.tracing));
         self.apply_temp_state_to_runtime(&mut runtime)?;
         self.inner
             .catalog
@@ -9071,6 +10572,7 @@ impl Db {
             .engine
             .write()
             .map_err(|_| DbError::internal("engine runtime lock poisoned"))?;
+        runtime.analyze_churn = std::mem::take(&mut guard.analyze_churn);
         *guard = runtime;
         self.inner
             .last_runtime_lsn
@@ -9142,6 +10644,8 @@ impl Db {
             &self.inner.config,
         )?;
         runtime.set_audit_context_handle(Arc::clone(&self.inner.audit_context));
+        runtime.set_advisory_lock_scope(self.advisory_lock_scope());
+        runtime.set_tracing(Arc::clone(&self.inner.tracing));
         self.apply_temp_state_to_runtime(&mut runtime)?;
         self.inner
             .catalog
@@ -9151,6 +10655,7 @@ impl Db {
             .engine
             .write()
             .map_err(|_| DbError::internal("engine runtime lock poisoned"))?;
+        runtime.analyze_churn = std::mem::take(&mut guard.analyze_churn);
         *guard = runtime;
         self.inner
             .last_runtime_lsn
@@ -10493,6 +11998,8 @@ impl Db {
         snapshot_lsn: u64,
         indexes_maybe_stale: &mut bool,
     ) -> Result<QueryResult> {
+        let _workers = self.install_parallel_workers();
+        let _read_ahead = self.install_read_ahead();
         let security_active =
             self.load_security_tables_for_runtime_at_snapshot(runtime, snapshot_lsn)?;
         if self.statement_is_temp_only(runtime, statement) {
@@ -10564,9 +12071,7 @@ impl Db {
         indexes_maybe_stale: &mut bool,
     ) -> Result<QueryResult> {
         if matches!(statement, SqlStatement::Analyze { .. }) {
-            return Err(DbError::transaction(
-                "ANALYZE is not supported inside an explicit SQL transaction",
-            ));
+            return Err(DbError::ddl_not_transactional("ANALYZE"));
         }
 
         if *indexes_maybe_stale {
@@ -11437,6 +12942,24 @@ impl Db {
         Ok(())
     }
 
+    fn execute_publication_command(&self, command: PublicationCommand) -> Result<QueryResult> {
+        match command {
+            PublicationCommand::Create { name, tables } => {
+                if self.sync_scope(&name)?.is_some() {
+                    return Err(DbError::sql(format!("publication '{name}' already exists")));
+                }
+                let table_refs = tables.iter().map(String::as_str).collect::<Vec<_>>();
+                self.sync_create_scope(&name, &table_refs, None)?;
+            }
+            PublicationCommand::Drop { name, if_exists } => {
+                if !self.sync_drop_scope(&name)? && !if_exists {
+                    return Err(DbError::sql(format!("publication '{name}' does not exist")));
+                }
+            }
+        }
+        Ok(QueryResult::with_affected_rows(0))
+    }
+
     pub fn sync_drop_scope(&self, name: &str) -> Result<bool> {
         self.ensure_sync_tables()?;
         let scope_name = name.trim();
@@ -11485,6 +13008,104 @@ impl Db {
         }
     }
 
+    /// Runs `LOCK TABLE`: locks each table for the current transaction,
+    /// waiting up to the busy timeout (or indefinitely when none is set)
+    /// unless `NOWAIT` is given. A transaction that has not written yet is
+    /// moved to the latest commit once it holds the locks, so writes that
+    /// committed while it waited do not make it conflict.
+    fn execute_lock_table(&self, command: LockTableCommand) -> Result<QueryResult> {
+        let tables = {
+            let txn = self
+                .inner
+                .sql_txn
+                .lock()
+                .map_err(|_| DbError::internal("SQL transaction lock poisoned"))?;
+            let SqlTxnSlot::Shared(state) = &*txn else {
+                return Err(DbError::transaction(
+                    "LOCK TABLE can only be used in transaction blocks",
+                ));
+            };
+            command
+                .tables
+                .iter()
+                .map(|table| {
+                    state
+                        .runtime
+                        .catalog
+                        .table(table)
+                        .filter(|schema| !schema.temporary)
+                        .map(|schema| schema.name.clone())
+                        .ok_or_else(|| DbError::sql(format!("table {table} does not exist")))
+                })
+                .collect::<Result<Vec<_>>>()?
+        };
+        let timeout_ms = self.inner.busy_timeout_ms.load(Ordering::Acquire);
+        let started = std::time::Instant::now();
+        for table in &tables {
+            let mut backoff = Duration::from_micros(100);
+            while !self
+                .inner
+                .wal
+                .try_lock_table(self.inner.lock_owner, table)?
+            {
+                if command.nowait
+                    || (timeout_ms > 0 && started.elapsed() >= Duration::from_millis(timeout_ms))
+                {
+                    return Err(DbError::busy(format!(
+                        "could not obtain lock on table {table}"
+                    )));
+                }
+                std::thread::sleep(backoff);
+                backoff = (backoff * 2).min(Duration::from_millis(5));
+            }
+        }
+
+        let mut txn = self
+            .inner
+            .sql_txn
+            .lock()
+            .map_err(|_| DbError::internal("SQL transaction lock poisoned"))?;
+        if let SqlTxnSlot::Shared(state) = &mut *txn {
+            if !state.persistent_changed
+                && state.savepoints.is_empty()
+                && self.inner.wal.latest_snapshot() != state.base_lsn
+            {
+                let mut temp = TempSchemaState::default();
+                temp.update_from_runtime(&state.runtime);
+                let mut rebased = self.build_sql_txn_state()?;
+                temp.apply_to_runtime(&mut rebased.runtime);
+                **state = rebased;
+            }
+        }
+        Ok(QueryResult::with_affected_rows(0))
+    }
+
+    /// Makes a commit that writes `tables` wait while another handle's
+    /// transaction holds one of them with `LOCK TABLE`, up to the busy
+    /// timeout or indefinitely when none is set.
+    pub(crate) fn wait_for_table_locks<'a>(
+        &self,
+        tables: impl IntoIterator<Item = &'a str> + Clone,
+    ) -> Result<()> {
+        let timeout_ms = self.inner.busy_timeout_ms.load(Ordering::Acquire);
+        let started = std::time::Instant::now();
+        let mut backoff = Duration::from_micros(100);
+        while let Some(table) = self
+            .inner
+            .wal
+            .table_locked_by_other(self.inner.lock_owner, tables.clone())?
+        {
+            if timeout_ms > 0 && started.elapsed() >= Duration::from_millis(timeout_ms) {
+                return Err(DbError::busy(format!(
+                    "table {table} is locked by another transaction"
+                )));
+            }
+            std::thread::sleep(backoff);
+            backoff = (backoff * 2).min(Duration::from_millis(5));
+        }
+        Ok(())
+    }
+
     fn try_execute_prepared_inspection_query(
         &self,
         prepared: &PreparedStatement,
@@ -12341,6 +13962,9 @@ impl Db {
                     .iter()
                     .map(sync_changeset_record_from_journal_record)
                     .collect::<Vec<_>>();
+                let before_images = records
+                    .iter()
+                    .all(|record| record.operation == "insert" || record.before.is_some());
                 let start_checkpoint = batch.first_sequence;
                 let end_checkpoint = batch.last_sequence;
                 let source_high_watermark = batch
@@ -12379,7 +14003,10 @@ impl Db {
                     schema_cookie,
                     sync_contract_version: crate::sync::SYNC_CONTRACT_VERSION,
                     query_contract_fingerprint: None,
-                    producer_capabilities: SyncChangesetCapabilities::default(),
+                    producer_capabilities: SyncChangesetCapabilities {
+                        before_images,
+                        ..SyncChangesetCapabilities::default()
+                    },
                     limits: SyncChangesetLimits::default(),
                     records,
                     conflict_policy_hint: None,
@@ -12426,6 +14053,14 @@ impl Db {
         ctx: SyncDiffChangesetContext<'_>,
     ) -> Result<SyncChangeset> {
         let diff = self.branch_diff(ctx.from_ref, ctx.to_ref)?;
+        self.sync_changeset_from_diff(&diff, ctx)
+    }
+
+    fn sync_changeset_from_diff(
+        &self,
+        diff: &crate::branch::BranchDiffReport,
+        ctx: SyncDiffChangesetContext<'_>,
+    ) -> Result<SyncChangeset> {
         let table_infos = self
             .list_tables()?
             .into_iter()
@@ -12710,7 +14345,9 @@ impl Db {
         self.sync_validate_changeset_envelope(changeset)?;
         let created_at_micros = current_time_micros();
         let mut inverse_records = Vec::with_capacity(changeset.records.len());
-        for (index, record) in changeset.records.iter().enumerate() {
+        // Undo the records last-first so that a row touched several times
+        // walks back through its own history.
+        for (index, record) in changeset.records.iter().enumerate().rev() {
             let operation = match record.operation.as_str() {
                 "insert" => "delete",
                 "delete" if record.before.is_some() => "insert",
@@ -12732,9 +14369,9 @@ impl Db {
                 operation: operation.to_string(),
                 primary_key: record.primary_key.clone(),
                 origin_replica_id: format!("inverse:{}", changeset.changeset_id),
-                origin_sequence: (index as u64) + 1,
+                origin_sequence: (inverse_records.len() as u64) + 1,
                 transaction_id: format!("txn:inverse:{}", changeset.changeset_id),
-                transaction_lsn: (index as u64) + 1,
+                transaction_lsn: (inverse_records.len() as u64) + 1,
                 schema_cookie: record.schema_cookie,
                 before_hash: None,
                 before: record.after.clone(),
@@ -13297,7 +14934,7 @@ impl Db {
                 .unwrap_or_default();
             let sql = format!(
                 "SELECT {column_sql} FROM {}{where_sql} ORDER BY {order_by}",
-                sql_identifier(&table.name)
+                sql_relation_name(&table.name)
             );
             let result = self.execute(&sql)?;
             for row in result.rows() {
@@ -13563,6 +15200,27 @@ impl Db {
         &self,
         batch: &SyncChangeBatch,
         policy: SyncConflictPolicy,
+    ) -> Result<SyncImportSummary> {
+        self.sync_import_batch_checked(batch, policy, true)
+    }
+
+    /// Imports a batch exported from a publication. Records need not carry
+    /// this database's schema cookie; instead each table they touch must
+    /// exist here with the same columns, so a subscriber can hold the
+    /// published tables alongside tables of its own.
+    pub fn sync_import_publication_batch(
+        &self,
+        batch: &SyncChangeBatch,
+        policy: SyncConflictPolicy,
+    ) -> Result<SyncImportSummary> {
+        self.sync_import_batch_checked(batch, policy, false)
+    }
+
+    fn sync_import_batch_checked(
+        &self,
+        batch: &SyncChangeBatch,
+        policy: SyncConflictPolicy,
+        check_schema_cookie: bool,
     ) -> Result<SyncImportSummary> {
         batch.validate()?;
         self.ensure_sync_tables()?;
@@ -13660,7 +15318,7 @@ impl Db {
                 )));
             }
 
-            if record.schema_cookie != schema_cookie {
+            if check_schema_cookie && record.schema_cookie != schema_cookie {
                 return Err(DbError::sql(format!(
                     "schema mismatch for table '{}': record has schema_cookie {} but local schema is {}",
                     record.table, record.schema_cookie, schema_cookie
@@ -13671,6 +15329,9 @@ impl Db {
                 .catalog
                 .table(&record.table)
                 .ok_or_else(|| DbError::sql(format!("unknown table '{}'", record.table)))?;
+            if !check_schema_cookie {
+                sync_check_record_columns(table, record)?;
+            }
             if crate::sync::is_internal_table_name(&table.name) {
                 return Err(DbError::sql(format!(
                     "cannot import into internal table '{}'",
@@ -14347,7 +16008,7 @@ impl Db {
         let apply_remote_replace = |operation: SyncOperation| -> Result<()> {
             let sql = format!(
                 "DELETE FROM {} WHERE {}",
-                sql_identifier(&table.name),
+                sql_relation_name(&table.name),
                 table
                     .primary_key_columns
                     .iter()
@@ -14402,7 +16063,7 @@ impl Db {
             }
             let sql = format!(
                 "INSERT INTO {} ({}) VALUES ({})",
-                sql_identifier(&table.name),
+                sql_relation_name(&table.name),
                 columns.join(", "),
                 (1..=values.len())
                     .map(|idx| format!("${idx}"))
@@ -14435,7 +16096,7 @@ impl Db {
                 }
                 let sql = format!(
                     "INSERT INTO {} ({}) VALUES ({})",
-                    sql_identifier(&table.name),
+                    sql_relation_name(&table.name),
                     columns.join(", "),
                     (1..=values.len())
                         .map(|idx| format!("${idx}"))
@@ -14530,7 +16191,7 @@ impl Db {
                 }
                 let sql = format!(
                     "UPDATE {} SET {} WHERE {}",
-                    sql_identifier(&table.name),
+                    sql_relation_name(&table.name),
                     expressions.join(", "),
                     table
                         .primary_key_columns
@@ -14628,7 +16289,7 @@ impl Db {
                 }
                 let sql = format!(
                     "DELETE FROM {} WHERE {}",
-                    sql_identifier(&table.name),
+                    sql_relation_name(&table.name),
                     where_parts.join(" AND ")
                 );
                 match self.execute_with_params(&sql, &where_values) {
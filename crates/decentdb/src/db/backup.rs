@@ -0,0 +1,220 @@
+use std::io::Read;
+
+use super::*;
+
+/// A consistent copy of the database file, read as a byte stream.
+///
+/// The reader pins a WAL snapshot for its lifetime, so commits made while a
+/// backup streams are not included and checkpoints leave the database file
+/// alone until it is dropped. The bytes form a complete database file that
+/// opens without a WAL.
+#[derive(Debug)]
+pub struct BackupReader {
+    db: Db,
+    reader: ReaderGuard,
+    page_count: u32,
+    next_page_id: PageId,
+    page: Option<Arc<[u8]>>,
+    page_offset: usize,
+}
+
+impl BackupReader {
+    /// Total length of the stream in bytes.
+    #[must_use]
+    pub fn len(&self) -> u64 {
+        page::page_offset(
+            self.page_count.saturating_add(1),
+            self.db.inner.config.page_size,
+        )
+    }
+
+    #[must_use]
+    pub fn is_empty(&self) -> bool {
+        self.page_count == 0
+    }
+
+    /// Fills `buf` with the next bytes of the stream and returns how many
+    /// were written; zero means the backup is complete.
+    pub fn read_chunk(&mut self, buf: &mut [u8]) -> Result<usize> {
+        loop {
+            if let Some(page) = &self.page {
+                if self.page_offset < page.len() {
+                    let count = buf.len().min(page.len() - self.page_offset);
+                    buf[..count].copy_from_slice(&page[self.page_offset..self.page_offset + count]);
+                    self.page_offset += count;
+                    return Ok(count);
+                }
+            }
+            if self.next_page_id > self.page_count || buf.is_empty() {
+                return Ok(0);
+            }
+            self.page = Some(self.read_snapshot_page(self.next_page_id)?);
+            self.page_offset = 0;
+            self.next_page_id += 1;
+        }
+    }
+
+    /// Fills `raw` with up to its length of the stream, compresses those
+    /// bytes with zstd at `level` into `out`, and returns how many bytes of
+    /// each were written; `(0, 0)` means the backup is complete. Fails when
+    /// `out` cannot hold the compressed chunk.
+    #[cfg(not(all(target_arch = "wasm32", target_os = "unknown")))]
+    pub fn read_compressed_chunk(
+        &mut self,
+        raw: &mut [u8],
+        level: i32,
+        out: &mut [u8],
+    ) -> Result<(usize, usize)> {
+        let mut filled = 0;
+        while filled < raw.len() {
+            let read = self.read_chunk(&mut raw[filled..])?;
+            if read == 0 {
+                break;
+            }
+            filled += read;
+        }
+        if filled == 0 {
+            return Ok((0, 0));
+        }
+        let written = zstd::bulk::compress_to_buffer(&raw[..filled], out, level)
+            .map_err(|error| DbError::internal(format!("zstd compression failed: {error}")))?;
+        Ok((filled, written))
+    }
+
+    fn read_snapshot_page(&self, page_id: PageId) -> Result<Arc<[u8]>> {
+        if let Some(wal_page) = self.db.inner.wal.read_page_at_snapshot(
+            &self.db.inner.pager,
+            page_id,
+            self.reader.snapshot_lsn(),
+        )? {
+            return Ok(wal_page);
+        }
+        self.db.inner.pager.read_page_from_disk(page_id)
+    }
+}
+
+impl Read for BackupReader {
+    fn read(&mut self, buf: &mut [u8]) -> std::io::Result<usize> {
+        self.read_chunk(buf).map_err(std::io::Error::other)
+    }
+}
+
+impl Db {
+    /// Starts streaming a backup of the database as of now. Unlike
+    /// [`Db::save_as`] nothing is written locally; the caller copies the
+    /// stream wherever the backup should go.
+    ///
+    /// Encrypted databases are refused because the stream carries decrypted
+    /// pages; use [`Db::save_as`] for those.
+    pub fn backup_reader(&self) -> Result<BackupReader> {
+        if self.inner.config.encryption.is_some() {
+            return Err(DbError::transaction(
+                "streaming backup is not available for encrypted databases; use save_as",
+            ));
+        }
+        // Fold the WAL first when no reader is holding it so most pages come
+        // straight from the database file; a blocked checkpoint is harmless
+        // because the snapshot below still sees every committed page.
+        if self.inner.wal.latest_snapshot() != 0 {
+            self.checkpoint_wal()?;
+        }
+        let reader = self.inner.wal.begin_reader_with_pager(&self.inner.pager)?;
+        let page_count = self
+            .inner
+            .pager
+            .on_disk_page_count()?
+            .max(self.inner.wal.max_page_count());
+        Ok(BackupReader {
+            db: self.clone(),
+            reader,
+            page_count,
+            next_page_id: 1,
+            page: None,
+            page_offset: 0,
+        })
+    }
+}
+
+/// Writes a restored backup to a new database file.
+///
+/// Envelope chunks may arrive zstd-compressed; the writer decompresses them
+/// and hashes every byte it writes so the caller can check the backup's
+/// digest. The file is removed unless [`RestoreWriter::finish`] succeeds.
+#[cfg(not(all(target_arch = "wasm32", target_os = "unknown")))]
+#[derive(Debug)]
+pub(crate) struct RestoreWriter {
+    path: PathBuf,
+    file: Option<std::fs::File>,
+    digest: sha2::Sha256,
+    len: u64,
+}
+
+#[cfg(not(all(target_arch = "wasm32", target_os = "unknown")))]
+impl RestoreWriter {
+    /// Creates the file at `path`, which must not exist.
+    pub(crate) fn create(path: impl AsRef<Path>) -> Result<Self> {
+        let path = path.as_ref().to_path_buf();
+        let file = std::fs::OpenOptions::new()
+            .write(true)
+            .create_new(true)
+            .open(&path)
+            .map_err(|source| DbError::io(format!("create {}", path.display()), source))?;
+        Ok(Self {
+            path,
+            file: Some(file),
+            digest: sha2::Sha256::default(),
+            len: 0,
+        })
+    }
+
+    /// Appends `data` to the file.
+    pub(crate) fn write(&mut self, data: &[u8]) -> Result<()> {
+        use sha2::Digest;
+        use std::io::Write;
+
+        let file = self
+            .file
+            .as_mut()
+            .ok_or_else(|| DbError::internal("restore is already finished"))?;
+        file.write_all(data)
+            .map_err(|source| DbError::io(format!("write {}", self.path.display()), source))?;
+        self.digest.update(data);
+        self.len += data.len() as u64;
+        Ok(())
+    }
+
+    /// Decompresses one zstd chunk and appends it, refusing output larger
+    /// than `max_len` so a crafted backup cannot balloon memory. Returns the
+    /// decompressed length.
+    pub(crate) fn write_compressed(&mut self, data: &[u8], max_len: usize) -> Result<usize> {
+        let chunk = zstd::bulk::decompress(data, max_len).map_err(|error| {
+            DbError::corruption(format!("backup chunk does not decompress: {error}"))
+        })?;
+        self.write(&chunk)?;
+        Ok(chunk.len())
+    }
+
+    /// Syncs and closes the file, returning its length and SHA-256.
+    pub(crate) fn finish(&mut self) -> Result<(u64, [u8; 32])> {
+        use sha2::Digest;
+
+        let file = self
+            .file
+            .take()
+            .ok_or_else(|| DbError::internal("restore is already finished"))?;
+        if let Err(source) = file.sync_all() {
+            let _ = std::fs::remove_file(&self.path);
+            return Err(DbError::io(format!("sync {}", self.path.display()), source));
+        }
+        Ok((self.len, std::mem::take(&mut self.digest).finalize().into()))
+    }
+}
+
+#[cfg(not(all(target_arch = "wasm32", target_os = "unknown")))]
+impl Drop for RestoreWriter {
+    fn drop(&mut self) {
+        if self.file.take().is_some() {
+            let _ = std::fs::remove_file(&self.path);
+        }
+    }
+}
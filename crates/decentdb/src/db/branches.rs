@@ -244,7 +244,7 @@ pub(super) fn merge_insert_sql(table: &TableInfo, after: &[String]) -> String {
         .join(", ");
     format!(
         "INSERT INTO {} ({columns}) VALUES ({});",
-        sql_identifier(&table.name),
+        sql_relation_name(&table.name),
         after.join(", ")
     )
 }
@@ -252,7 +252,7 @@ pub(super) fn merge_insert_sql(table: &TableInfo, after: &[String]) -> String {
 pub(super) fn merge_delete_sql(table: &TableInfo, primary_key: &[String]) -> Result<String> {
     Ok(format!(
         "DELETE FROM {} WHERE {};",
-        sql_identifier(&table.name),
+        sql_relation_name(&table.name),
         merge_where_clause(table, primary_key)?
     ))
 }
@@ -279,7 +279,7 @@ pub(super) fn merge_update_sql(
     }
     Ok(Some(format!(
         "UPDATE {} SET {} WHERE {};",
-        sql_identifier(&table.name),
+        sql_relation_name(&table.name),
         assignments.join(", "),
         merge_where_clause(table, primary_key)?
     )))
@@ -557,7 +557,7 @@ pub(super) fn diff_table_rows(
         .join(", ");
     let sql = format!(
         "SELECT {columns} FROM {} ORDER BY {order_by}",
-        sql_identifier(&table.name)
+        sql_relation_name(&table.name)
     );
     let result = db.execute(&sql)?;
     let primary_key_indexes = table
@@ -601,43 +601,214 @@ pub(super) fn render_runtime_dump(
     db: &Db,
     runtime: &mut EngineRuntime,
     snapshot_lsn: Option<u64>,
+    options: &DumpOptions,
 ) -> Result<String> {
+    for name in &options.tables {
+        let known = runtime.catalog.table(name).is_some()
+            || runtime
+                .temp_tables
+                .keys()
+                .any(|temp_name| identifiers_equal(temp_name, name));
+        if !known {
+            return Err(DbError::sql(format!("unknown table {name}")));
+        }
+    }
+    let selected = |table_name: &str| {
+        options.tables.is_empty()
+            || options
+                .tables
+                .iter()
+                .any(|name| identifiers_equal(name, table_name))
+    };
     let mut lines = Vec::new();
 
-    for table in runtime.catalog.tables.values() {
-        lines.push(render_create_table(table));
+    if options.include_schema {
+        for schema in runtime.catalog.schemas.values() {
+            let needed = options.tables.is_empty()
+                || runtime.catalog.tables.values().any(|table| {
+                    selected(&table.name)
+                        && crate::exec::owning_schema_name(&table.name)
+                            .is_some_and(|owner| identifiers_equal(owner, &schema.name))
+                });
+            if needed {
+                lines.push(format!(
+                    "CREATE SCHEMA IF NOT EXISTS {};",
+                    sql_identifier(&schema.name)
+                ));
+            }
+        }
+        // Partitions are dumped as PARTITION OF their parent only when the
+        // whole database is, since a selective dump may omit the parent.
+        if options.tables.is_empty() {
+            for parent in runtime.catalog.partitioned_tables.values() {
+                lines.push(render_create_partitioned_table(parent));
+            }
+        }
+        for table in runtime.catalog.tables.values() {
+            if !selected(&table.name) {
+                continue;
+            }
+            let partition = options
+                .tables
+                .is_empty()
+                .then(|| runtime.catalog.partition_parent(&table.name))
+                .flatten()
+                .and_then(|parent_name| {
+                    runtime.catalog.partitioned_tables[parent_name]
+                        .partition(&table.name)
+                        .map(|partition| render_create_partition(parent_name, partition))
+                });
+            if let Some(partition) = partition {
+                lines.push(partition);
+            } else {
+                lines.push(render_create_table(
+                    table,
+                    runtime
+                        .catalog
+                        .table_ttl
+                        .get(&table.name)
+                        .map(String::as_str),
+                ));
+            }
+            if let Some(comments) = runtime.catalog.comments.get(&table.name) {
+                lines.extend(render_comments(&table.name, comments));
+            }
+        }
     }
-    let table_names = runtime.catalog.tables.keys().cloned().collect::<Vec<_>>();
-    for table_name in table_names {
-        db.ensure_inspection_table_row_source(runtime, &table_name, snapshot_lsn)?;
-        let table = runtime
+    if options.include_data {
+        let table_names = runtime
             .catalog
-            .table(&table_name)
+            .tables
+            .keys()
+            .filter(|name| selected(name.as_str()))
             .cloned()
-            .ok_or_else(|| DbError::internal(format!("unknown table {table_name}")))?;
-        let row_source = runtime.table_row_source(&table.name).ok_or_else(|| {
-            DbError::internal(format!("table row source for {} is missing", table.name))
-        })?;
-        for row in row_source.rows() {
-            lines.push(render_insert(&table, row?.values()));
+            .collect::<Vec<_>>();
+        for table_name in table_names {
+            db.ensure_inspection_table_row_source(runtime, &table_name, snapshot_lsn)?;
+            let table = runtime
+                .catalog
+                .table(&table_name)
+                .cloned()
+                .ok_or_else(|| DbError::internal(format!("unknown table {table_name}")))?;
+            let row_source = runtime.table_row_source(&table.name).ok_or_else(|| {
+                DbError::internal(format!("table row source for {} is missing", table.name))
+            })?;
+            for row in row_source.rows() {
+                lines.push(render_insert(&table, row?.values()));
+            }
+            db.redefer_inspection_table_row_source(runtime, &table_name, snapshot_lsn);
         }
-        db.redefer_inspection_table_row_source(runtime, &table_name, snapshot_lsn);
     }
-    for view in runtime.catalog.views.values() {
-        lines.push(render_create_view(view));
+    let include_views = options.include_schema && options.tables.is_empty();
+    if include_views {
+        for view in runtime.catalog.views.values() {
+            if !runtime.catalog.partitioned_tables.contains_key(&view.name) {
+                lines.push(render_create_view(view));
+            }
+        }
     }
-    for table in runtime.temp_tables.values() {
-        lines.push(render_create_table(table));
+    if options.include_schema {
+        for table in runtime.temp_tables.values() {
+            if selected(&table.name) {
+                lines.push(render_create_table(table, None));
+            }
+        }
     }
-    for (table_name, table_data) in runtime.temp_table_data.iter() {
-        if let Some(table) = runtime.temp_tables.get(table_name) {
-            for row in table_data.visible_rows() {
-                lines.push(render_insert(table, &row.values));
+    if options.include_data {
+        for (table_name, table_data) in runtime.temp_table_data.iter() {
+            if !selected(table_name.as_str()) {
+                continue;
             }
+            if let Some(table) = runtime.temp_tables.get(table_name) {
+                for row in table_data.visible_rows() {
+                    lines.push(render_insert(table, &row.values));
+                }
+            }
+        }
+    }
+    if include_views {
+        for view in runtime.temp_views.values() {
+            lines.push(render_create_view(view));
         }
     }
-    for view in runtime.temp_views.values() {
-        lines.push(render_create_view(view));
+    if options.include_schema {
+        for index in runtime.catalog.indexes.values() {
+            if !selected(&index.table_name) {
+                continue;
+            }
+            if runtime
+                .catalog
+                .table(&index.table_name)
+                .is_some_and(|table| is_auto_table_index(table, index))
+            {
+                continue;
+            }
+            lines.push(render_create_index(index));
+        }
+        for trigger in runtime.catalog.triggers.values() {
+            if trigger.on_view && !include_views {
+                continue;
+            }
+            if !trigger.on_view && !selected(&trigger.target_name) {
+                continue;
+            }
+            lines.push(render_create_trigger(trigger));
+        }
+    }
+
+    Ok(lines.join("\n"))
+}
+
+pub(super) fn salvage_runtime(
+    db: &Db,
+    runtime: &mut EngineRuntime,
+    snapshot_lsn: Option<u64>,
+    target: &Db,
+) -> Result<RecoveryReport> {
+    let mut report = RecoveryReport::default();
+    let table_names = runtime.catalog.tables.keys().cloned().collect::<Vec<_>>();
+    for table_name in table_names {
+        let Some(table) = runtime.catalog.table(&table_name).cloned() else {
+            continue;
+        };
+        let mut recovered = RecoveredTable {
+            name: table.name.clone(),
+            schema_recovered: false,
+            rows_recovered: 0,
+            error: None,
+        };
+        if let Err(err) = target.execute(&render_create_table(
+            &table,
+            runtime
+                .catalog
+                .table_ttl
+                .get(&table.name)
+                .map(String::as_str),
+        )) {
+            recovered.error = Some(err.to_string());
+            report.tables.push(recovered);
+            continue;
+        }
+        recovered.schema_recovered = true;
+        if let Err(err) =
+            salvage_table_rows(db, runtime, snapshot_lsn, target, &table, &mut recovered)
+        {
+            recovered.error = Some(err.to_string());
+        }
+        report.tables.push(recovered);
+    }
+
+    let lost_object = |kind: &str, name: &str, err: DbError| RecoveryLoss {
+        kind: kind.to_string(),
+        name: name.to_string(),
+        error: err.to_string(),
+    };
+    for view in runtime.catalog.views.values() {
+        if let Err(err) = target.execute(&render_create_view(view)) {
+            report
+                .lost_objects
+                .push(lost_object("view", &view.name, err));
+        }
     }
     for index in runtime.catalog.indexes.values() {
         if runtime
@@ -647,16 +818,384 @@ pub(super) fn render_runtime_dump(
         {
             continue;
         }
-        lines.push(render_create_index(index));
+        if let Err(err) = target.execute(&render_create_index(index)) {
+            report
+                .lost_objects
+                .push(lost_object("index", &index.name, err));
+        }
     }
     for trigger in runtime.catalog.triggers.values() {
-        lines.push(render_create_trigger(trigger));
+        if let Err(err) = target.execute(&render_create_trigger(trigger)) {
+            report
+                .lost_objects
+                .push(lost_object("trigger", &trigger.name, err));
+        }
     }
+    Ok(report)
+}
 
-    Ok(lines.join("\n"))
+/// Copies rows of `table` into `target` in one transaction, stopping at the
+/// first row that cannot be read. Rows copied before the failure are kept.
+fn salvage_table_rows(
+    db: &Db,
+    runtime: &mut EngineRuntime,
+    snapshot_lsn: Option<u64>,
+    target: &Db,
+    table: &TableSchema,
+    recovered: &mut RecoveredTable,
+) -> Result<()> {
+    db.ensure_inspection_table_row_source(runtime, &table.name, snapshot_lsn)?;
+    let row_source = runtime.table_row_source(&table.name).ok_or_else(|| {
+        DbError::internal(format!("table row source for {} is missing", table.name))
+    })?;
+    target.begin_transaction()?;
+    let mut read_error = None;
+    for row in row_source.rows() {
+        let row = match row {
+            Ok(row) => row,
+            Err(err) => {
+                read_error = Some(err);
+                break;
+            }
+        };
+        if let Err(err) = target.execute(&render_insert(table, row.values())) {
+            read_error = Some(err);
+            break;
+        }
+        recovered.rows_recovered += 1;
+    }
+    target.commit_transaction()?;
+    db.redefer_inspection_table_row_source(runtime, &table.name, snapshot_lsn);
+    match read_error {
+        Some(err) => Err(err),
+        None => Ok(()),
+    }
+}
+
+/// What [`Db::clone_to`] copies, resolved against the source catalog before
+/// the destination is created.
+pub(super) struct ClonePlan {
+    /// Cloned tables, each after the tables its foreign keys reference.
+    tables: Vec<TableSchema>,
+    /// Parsed row filters keyed by canonical table name.
+    filters: BTreeMap<String, Expr>,
+    /// Parsed column transforms keyed by canonical table name, each paired
+    /// with the position of the column it replaces.
+    transforms: BTreeMap<String, Vec<(usize, Expr)>>,
+    include_views: bool,
 }
 
-pub(super) fn render_create_table(table: &TableSchema) -> String {
+pub(super) fn plan_runtime_clone(
+    runtime: &EngineRuntime,
+    options: &CloneOptions,
+) -> Result<ClonePlan> {
+    for name in options.tables.iter().chain(options.filters.keys()) {
+        if runtime.catalog.table(name).is_none() {
+            return Err(DbError::sql(format!("unknown table {name}")));
+        }
+    }
+    let selected = |table_name: &str| {
+        options.tables.is_empty()
+            || options
+                .tables
+                .iter()
+                .any(|name| identifiers_equal(name, table_name))
+    };
+    let mut filters = BTreeMap::new();
+    for (name, filter_sql) in &options.filters {
+        if !selected(name) {
+            return Err(DbError::sql(format!(
+                "row filter for table {name}, which is not cloned"
+            )));
+        }
+        let expr = parse_expression_sql(filter_sql).map_err(|error| {
+            DbError::sql(format!("invalid row filter for table {name}: {error}"))
+        })?;
+        let table_name = runtime
+            .catalog
+            .table(name)
+            .map(|table| table.name.clone())
+            .ok_or_else(|| DbError::sql(format!("unknown table {name}")))?;
+        filters.insert(table_name, expr);
+    }
+    let mut transforms = BTreeMap::<String, Vec<(usize, Expr)>>::new();
+    for (name, columns) in &options.transforms {
+        let table = runtime
+            .catalog
+            .table(name)
+            .ok_or_else(|| DbError::sql(format!("unknown table {name}")))?;
+        if !selected(&table.name) {
+            return Err(DbError::sql(format!(
+                "transform for table {name}, which is not cloned"
+            )));
+        }
+        for (column_name, transform_sql) in columns {
+            let position = table
+                .columns
+                .iter()
+                .position(|column| identifiers_equal(&column.name, column_name))
+                .ok_or_else(|| {
+                    DbError::sql(format!("unknown column {column_name} in table {name}"))
+                })?;
+            if table.columns[position].generated_sql.is_some() {
+                return Err(DbError::sql(format!(
+                    "cannot transform generated column {name}.{column_name}"
+                )));
+            }
+            let expr = parse_expression_sql(transform_sql).map_err(|error| {
+                DbError::sql(format!(
+                    "invalid transform for column {name}.{column_name}: {error}"
+                ))
+            })?;
+            transforms
+                .entry(table.name.clone())
+                .or_default()
+                .push((position, expr));
+        }
+    }
+
+    let mut pending = runtime
+        .catalog
+        .tables
+        .values()
+        .filter(|table| selected(&table.name))
+        .cloned()
+        .collect::<Vec<_>>();
+    for table in &pending {
+        if let Some(referenced) =
+            referenced_table_names(table).find(|referenced| !selected(*referenced))
+        {
+            return Err(DbError::sql(format!(
+                "table {} references {referenced}, which is not cloned",
+                table.name
+            )));
+        }
+    }
+    // Tables in a reference cycle cannot all come after their parents; they
+    // keep catalog order and the cycle surfaces as a CREATE TABLE error.
+    let mut tables: Vec<TableSchema> = Vec::with_capacity(pending.len());
+    while !pending.is_empty() {
+        let next = pending
+            .iter()
+            .position(|table| {
+                referenced_table_names(table).all(|referenced| {
+                    identifiers_equal(referenced, &table.name)
+                        || tables
+                            .iter()
+                            .any(|done| identifiers_equal(&done.name, referenced))
+                })
+            })
+            .unwrap_or(0);
+        tables.push(pending.remove(next));
+    }
+    Ok(ClonePlan {
+        tables,
+        filters,
+        transforms,
+        include_views: options.tables.is_empty(),
+    })
+}
+
+fn referenced_table_names(table: &TableSchema) -> impl Iterator<Item = &str> {
+    table
+        .foreign_keys
+        .iter()
+        .chain(
+            table
+                .columns
+                .iter()
+                .filter_map(|column| column.foreign_key.as_ref()),
+        )
+        .map(|foreign_key| foreign_key.referenced_table.as_str())
+}
+
+pub(super) fn clone_runtime(
+    db: &Db,
+    runtime: &mut EngineRuntime,
+    snapshot_lsn: Option<u64>,
+    target: &Db,
+    plan: &ClonePlan,
+) -> Result<CloneReport> {
+    for schema in runtime.catalog.schemas.values() {
+        let needed = plan.tables.iter().any(|table| {
+            crate::exec::owning_schema_name(&table.name)
+                .is_some_and(|owner| identifiers_equal(owner, &schema.name))
+        });
+        if needed {
+            target.execute(&format!(
+                "CREATE SCHEMA IF NOT EXISTS {};",
+                sql_identifier(&schema.name)
+            ))?;
+        }
+    }
+    // As in a dump, partitions are recreated as PARTITION OF their parent
+    // only when the whole database is cloned.
+    if plan.include_views {
+        for parent in runtime.catalog.partitioned_tables.values() {
+            target.execute(&render_create_partitioned_table(parent))?;
+        }
+    }
+    for table in &plan.tables {
+        let partition = plan
+            .include_views
+            .then(|| runtime.catalog.partition_parent(&table.name))
+            .flatten()
+            .and_then(|parent_name| {
+                runtime.catalog.partitioned_tables[parent_name]
+                    .partition(&table.name)
+                    .map(|partition| render_create_partition(parent_name, partition))
+            });
+        target.execute(&partition.unwrap_or_else(|| {
+            render_create_table(
+                table,
+                runtime
+                    .catalog
+                    .table_ttl
+                    .get(&table.name)
+                    .map(String::as_str),
+            )
+        }))?;
+        if let Some(comments) = runtime.catalog.comments.get(&table.name) {
+            for statement in render_comments(&table.name, comments) {
+                target.execute(&statement)?;
+            }
+        }
+    }
+
+    let mut report = CloneReport::default();
+    for table in &plan.tables {
+        db.ensure_inspection_table_row_source(runtime, &table.name, snapshot_lsn)?;
+        target.begin_transaction()?;
+        match clone_table_rows(
+            runtime,
+            target,
+            table,
+            plan.filters.get(&table.name),
+            plan.transforms.get(&table.name).map(Vec::as_slice),
+        ) {
+            Ok(cloned) => {
+                target.commit_transaction()?;
+                report.tables.push(cloned);
+            }
+            Err(err) => {
+                target.rollback_transaction()?;
+                return Err(err);
+            }
+        }
+        db.redefer_inspection_table_row_source(runtime, &table.name, snapshot_lsn);
+    }
+
+    if plan.include_views {
+        for view in runtime.catalog.views.values() {
+            if !runtime.catalog.partitioned_tables.contains_key(&view.name) {
+                target.execute(&render_create_view(view))?;
+            }
+        }
+    }
+    let cloned = |table_name: &str| {
+        plan.tables
+            .iter()
+            .any(|table| identifiers_equal(&table.name, table_name))
+    };
+    for index in runtime.catalog.indexes.values() {
+        if !cloned(&index.table_name)
+            || runtime
+                .catalog
+                .table(&index.table_name)
+                .is_some_and(|table| is_auto_table_index(table, index))
+        {
+            continue;
+        }
+        target.execute(&render_create_index(index))?;
+    }
+    for trigger in runtime.catalog.triggers.values() {
+        let included = if trigger.on_view {
+            plan.include_views
+        } else {
+            cloned(&trigger.target_name)
+        };
+        if included {
+            target.execute(&render_create_trigger(trigger))?;
+        }
+    }
+    Ok(report)
+}
+
+/// Copies the rows of `table` that pass `filter` into `target`, replacing
+/// the transformed columns' values. A row whose foreign key points at a row
+/// some filter left out is skipped instead of failing the clone.
+fn clone_table_rows(
+    runtime: &EngineRuntime,
+    target: &Db,
+    table: &TableSchema,
+    filter: Option<&Expr>,
+    transforms: Option<&[(usize, Expr)]>,
+) -> Result<ClonedTable> {
+    let row_source = runtime.table_row_source(&table.name).ok_or_else(|| {
+        DbError::internal(format!("table row source for {} is missing", table.name))
+    })?;
+    let column_names = table
+        .columns
+        .iter()
+        .map(|column| column.name.clone())
+        .collect::<Vec<_>>();
+    let mut cloned = ClonedTable {
+        name: table.name.clone(),
+        rows_copied: 0,
+        rows_skipped: 0,
+    };
+    for row in row_source.rows() {
+        let row = row?;
+        if let Some(filter) = filter {
+            if !row_satisfies_expression(runtime, &table.name, &column_names, row.values(), filter)?
+            {
+                continue;
+            }
+        }
+        let mut values = row.values().to_vec();
+        for (position, transform) in transforms.unwrap_or_default() {
+            values[*position] = evaluate_row_expression(
+                runtime,
+                &table.name,
+                &column_names,
+                row.values(),
+                transform,
+            )?;
+        }
+        match target.execute(&render_insert(table, &values)) {
+            Ok(_) => cloned.rows_copied += 1,
+            Err(err)
+                if err.diagnostic().subcode == crate::error::SUBCODE_CONSTRAINT_FOREIGN_KEY =>
+            {
+                cloned.rows_skipped += 1
+            }
+            Err(err) => return Err(err),
+        }
+    }
+    Ok(cloned)
+}
+
+/// Renders `COMMENT ON` statements that restore a table's comments.
+pub(super) fn render_comments(table_name: &str, comments: &TableComments) -> Vec<String> {
+    let table = sql_relation_name(table_name);
+    let mut lines = Vec::with_capacity(comments.columns.len() + 1);
+    if let Some(comment) = &comments.table {
+        lines.push(format!(
+            "COMMENT ON TABLE {table} IS {};",
+            sql_string_literal(comment)
+        ));
+    }
+    for (column_name, comment) in &comments.columns {
+        lines.push(format!(
+            "COMMENT ON COLUMN {table}.{} IS {};",
+            sql_identifier(column_name),
+            sql_string_literal(comment)
+        ));
+    }
+    lines
+}
+
+pub(crate) fn render_create_table(table: &TableSchema, ttl_column: Option<&str>) -> String {
     let mut definitions = Vec::new();
     for column in &table.columns {
         let mut definition = format!(
@@ -664,6 +1203,10 @@ pub(super) fn render_create_table(table: &TableSchema) -> String {
             sql_identifier(&column.name),
             render_column_type(column)
         );
+        if let Some(collation) = column.collation {
+            definition.push_str(" COLLATE ");
+            definition.push_str(collation.name());
+        }
         if !column.nullable {
             definition.push_str(" NOT NULL");
         }
@@ -716,10 +1259,42 @@ pub(super) fn render_create_table(table: &TableSchema) -> String {
     }
 
     format!(
-        "CREATE {}TABLE {} ({});",
+        "CREATE {}TABLE {} ({}){};",
         if table.temporary { "TEMP " } else { "" },
-        sql_identifier(&table.name),
-        definitions.join(", ")
+        sql_relation_name(&table.name),
+        definitions.join(", "),
+        ttl_column
+            .map(|column| format!(" WITH (ttl_column = {})", sql_identifier(column)))
+            .unwrap_or_default()
+    )
+}
+
+/// Renders a partitioned table as its column template plus `PARTITION BY`.
+pub(super) fn render_create_partitioned_table(parent: &PartitionedTable) -> String {
+    format!(
+        "{} PARTITION BY {} ({});",
+        parent.template_sql.trim_end_matches(';'),
+        parent.strategy.as_str(),
+        sql_identifier(&parent.key_column)
+    )
+}
+
+pub(super) fn render_create_partition(parent_name: &str, partition: &Partition) -> String {
+    let bound = match &partition.bound {
+        PartitionBound::Range { from, to } => format!(
+            "FROM ({}) TO ({})",
+            from.as_deref().unwrap_or("MINVALUE"),
+            to.as_deref().unwrap_or("MAXVALUE")
+        ),
+        PartitionBound::Hash { modulus, remainder } => {
+            format!("WITH (MODULUS {modulus}, REMAINDER {remainder})")
+        }
+    };
+    format!(
+        "CREATE TABLE {} PARTITION OF {} FOR VALUES {};",
+        sql_relation_name(&partition.table_name),
+        sql_relation_name(parent_name),
+        bound
     )
 }
 
@@ -777,7 +1352,7 @@ pub(super) fn render_insert(table: &TableSchema, values: &[Value]) -> String {
         .join(", ");
     format!(
         "INSERT INTO {} ({columns}) VALUES ({values});",
-        sql_identifier(&table.name)
+        sql_relation_name(&table.name)
     )
 }
 
@@ -834,7 +1409,7 @@ pub(super) fn render_create_view(view: &ViewSchema) -> String {
     format!(
         "CREATE {}VIEW {}{columns} AS {};",
         if view.temporary { "TEMP " } else { "" },
-        sql_identifier(&view.name),
+        sql_relation_name(&view.name),
         view.sql_text
     )
 }
@@ -874,8 +1449,8 @@ pub(super) fn render_create_index(index: &IndexSchema) -> String {
         .unwrap_or_default();
     format!(
         "CREATE {unique}INDEX {} ON {}{using} ({columns}){full_text_options}{include}{predicate};",
-        sql_identifier(&index.name),
-        sql_identifier(&index.table_name)
+        sql_owned_object_name(&index.name),
+        sql_relation_name(&index.table_name)
     )
 }
 
@@ -990,16 +1565,21 @@ pub(super) fn identifier_lists_equal(left: &[String], right: &[String]) -> bool
 }
 
 pub(super) fn dump_auto_index_name(prefix: &str, table_name: &str, columns: &[String]) -> String {
+    // Indexes of schema-owned tables live in the table's schema.
+    if let Some(schema) = crate::exec::owning_schema_name(table_name) {
+        let table_name = &table_name[schema.len() + 1..];
+        return format!("{schema}.{prefix}_{}_{}", table_name, columns.join("_"));
+    }
     format!("{prefix}_{}_{}", table_name, columns.join("_"))
 }
 
 pub(super) fn render_create_trigger(trigger: &TriggerSchema) -> String {
     format!(
         "CREATE TRIGGER {} {} {} ON {} FOR EACH ROW EXECUTE FUNCTION decentdb_exec_sql({});",
-        sql_identifier(&trigger.name),
+        sql_owned_object_name(&trigger.name),
         trigger_kind_name(trigger.kind),
         trigger_event_name(trigger.event),
-        sql_identifier(&trigger.target_name),
+        sql_relation_name(&trigger.target_name),
         render_value_sql(&Value::Text(trigger.action_sql.clone()))
     )
 }
@@ -1145,6 +1725,28 @@ pub(super) fn sql_identifier(name: &str) -> String {
     format!("\"{}\"", name.replace('"', "\"\""))
 }
 
+/// Quotes a table, view, index, or trigger name. Objects owned by an
+/// application schema render as `"schema"."name"`.
+pub(super) fn sql_relation_name(name: &str) -> String {
+    match crate::exec::owning_schema_name(name) {
+        Some(schema) => format!(
+            "{}.{}",
+            sql_identifier(schema),
+            sql_identifier(&name[schema.len() + 1..])
+        ),
+        None => sql_identifier(name),
+    }
+}
+
+/// Quotes the bare name of an index or trigger; the schema comes from the
+/// table it is created on.
+fn sql_owned_object_name(name: &str) -> String {
+    match crate::exec::owning_schema_name(name) {
+        Some(schema) => sql_identifier(&name[schema.len() + 1..]),
+        None => sql_identifier(name),
+    }
+}
+
 pub(super) fn sql_string_literal(value: &str) -> String {
     format!("'{}'", value.replace('\'', "''"))
 }
@@ -8,6 +8,9 @@ pub(super) enum TransactionControl {
     Savepoint(String),
     ReleaseSavepoint(String),
     RollbackToSavepoint(String),
+    PrepareTransaction(String),
+    CommitPrepared(String),
+    RollbackPrepared(String),
 }
 
 #[derive(Clone, Debug, Eq, PartialEq)]
@@ -63,7 +66,17 @@ pub(super) enum PragmaName {
     IndexInfo,
     IndexXInfo,
     ForeignKeyList,
+    ForeignKeyCheck,
     FlushPlanCache,
+    MaxParallelWorkers,
+    ReadAheadPages,
+    StatementMemoryLimitBytes,
+    MaxResultRows,
+    MaxStatementSeconds,
+    ApplicationName,
+    Defensive,
+    WalCheckpointThresholdPages,
+    WalCheckpointThresholdBytes,
 }
 
 #[derive(Clone, Copy, Debug, Eq, PartialEq)]
@@ -478,6 +491,15 @@ pub(super) fn prepared_table_generated_columns_are_stored(table: &TableSchema) -
         .all(|column| column.generated_sql.is_none() || column.generated_stored)
 }
 
+/// Prepared lookups compare values as stored, so a table with a collated
+/// column is left to the general path, which honours the collation.
+pub(super) fn prepared_table_has_collated_columns(table: &TableSchema) -> bool {
+    table
+        .columns
+        .iter()
+        .any(|column| column.collation.is_some())
+}
+
 pub(super) fn prepared_scalar_count_star(expr: &crate::sql::ast::Expr) -> bool {
     let crate::sql::ast::Expr::Aggregate {
         name,
@@ -700,8 +722,116 @@ pub(super) fn parse_transaction_control(sql: &str) -> Option<TransactionControl>
         | "BEGIN EXCLUSIVE TRANSACTION" => Some(TransactionControl::Begin),
         "COMMIT" | "END" | "END TRANSACTION" => Some(TransactionControl::Commit),
         "ROLLBACK" | "ROLLBACK TRANSACTION" => Some(TransactionControl::Rollback),
-        _ => parse_savepoint_control(&normalized),
+        _ => parse_two_phase_control(sql).or_else(|| parse_savepoint_control(&normalized)),
+    }
+}
+
+/// Parses PREPARE TRANSACTION, COMMIT PREPARED, and ROLLBACK PREPARED, each
+/// followed by a single-quoted global id. The id is taken from the original
+/// text so its whitespace survives.
+pub(super) fn parse_two_phase_control(sql: &str) -> Option<TransactionControl> {
+    let trimmed = sql.trim().trim_end_matches(';').trim_end();
+    let keyword = trimmed.split_whitespace().next()?;
+    if !["PREPARE", "COMMIT", "ROLLBACK"]
+        .iter()
+        .any(|candidate| keyword.eq_ignore_ascii_case(candidate))
+    {
+        return None;
+    }
+    let quote = trimmed.find('\'')?;
+    let command = trimmed[..quote]
+        .split_whitespace()
+        .collect::<Vec<_>>()
+        .join(" ")
+        .to_ascii_uppercase();
+    let literal = &trimmed[quote..];
+    if literal.len() < 2 || !literal.ends_with('\'') {
+        return None;
+    }
+    let body = &literal[1..literal.len() - 1];
+    if body.replace("''", "").contains('\'') {
+        return None;
+    }
+    let gid = body.replace("''", "'");
+    match command.as_str() {
+        "PREPARE TRANSACTION" => Some(TransactionControl::PrepareTransaction(gid)),
+        "COMMIT PREPARED" => Some(TransactionControl::CommitPrepared(gid)),
+        "ROLLBACK PREPARED" => Some(TransactionControl::RollbackPrepared(gid)),
+        _ => None,
+    }
+}
+
+#[derive(Clone, Debug, PartialEq, Eq)]
+pub(super) struct LockTableCommand {
+    pub(super) tables: Vec<String>,
+    pub(super) nowait: bool,
+}
+
+/// Parses `LOCK [TABLE] [ONLY] name [, ...] [IN [ACCESS] EXCLUSIVE MODE]
+/// [NOWAIT]`. Weaker PostgreSQL lock modes are rejected rather than
+/// silently strengthened.
+pub(super) fn parse_lock_table_command(sql: &str) -> Result<Option<LockTableCommand>> {
+    let spaced = sql.trim().trim_end_matches(';').replace(',', " , ");
+    let mut tokens = spaced.split_whitespace().collect::<Vec<_>>();
+    if tokens
+        .first()
+        .is_none_or(|token| !token.eq_ignore_ascii_case("LOCK"))
+    {
+        return Ok(None);
+    }
+    let usage =
+        || DbError::sql("expected LOCK [TABLE] <table>[, ...] [IN EXCLUSIVE MODE] [NOWAIT]");
+    let nowait = tokens
+        .last()
+        .is_some_and(|token| token.eq_ignore_ascii_case("NOWAIT"));
+    if nowait {
+        tokens.pop();
+    }
+    if let Some(in_kw) = tokens
+        .iter()
+        .position(|token| token.eq_ignore_ascii_case("IN"))
+    {
+        let mode = tokens[in_kw + 1..].join(" ").to_ascii_uppercase();
+        match mode.as_str() {
+            "EXCLUSIVE MODE" | "ACCESS EXCLUSIVE MODE" => {}
+            _ if mode.ends_with(" MODE") => {
+                return Err(DbError::sql(format!(
+                    "LOCK TABLE supports only EXCLUSIVE and ACCESS EXCLUSIVE modes, not {}",
+                    &mode[..mode.len() - " MODE".len()]
+                )))
+            }
+            _ => return Err(usage()),
+        }
+        tokens.truncate(in_kw);
+    }
+    let mut list = &tokens[1..];
+    if list
+        .first()
+        .is_some_and(|token| token.eq_ignore_ascii_case("TABLE"))
+    {
+        list = &list[1..];
+    }
+    if list
+        .first()
+        .is_some_and(|token| token.eq_ignore_ascii_case("ONLY"))
+    {
+        list = &list[1..];
+    }
+    // The table list alternates names and commas and ends with a name.
+    if list.len() % 2 == 0 {
+        return Err(usage());
+    }
+    let mut tables = Vec::new();
+    for (index, token) in list.iter().enumerate() {
+        let expect_table = index % 2 == 0;
+        if expect_table == (*token == ",") {
+            return Err(usage());
+        }
+        if expect_table {
+            tables.push(token.trim_matches('"').to_string());
+        }
     }
+    Ok(Some(LockTableCommand { tables, nowait }))
 }
 
 pub(super) fn parse_pragma_command(sql: &str) -> Result<Option<PragmaCommand>> {
@@ -830,7 +960,17 @@ pub(super) fn parse_pragma_name(name: &str) -> Result<PragmaTarget> {
         "index_info" => Ok(PragmaName::IndexInfo),
         "index_xinfo" => Ok(PragmaName::IndexXInfo),
         "foreign_key_list" => Ok(PragmaName::ForeignKeyList),
+        "foreign_key_check" => Ok(PragmaName::ForeignKeyCheck),
         "flush_plan_cache" => Ok(PragmaName::FlushPlanCache),
+        "max_parallel_workers" => Ok(PragmaName::MaxParallelWorkers),
+        "read_ahead_pages" => Ok(PragmaName::ReadAheadPages),
+        "statement_memory_limit_bytes" => Ok(PragmaName::StatementMemoryLimitBytes),
+        "max_result_rows" => Ok(PragmaName::MaxResultRows),
+        "max_statement_seconds" => Ok(PragmaName::MaxStatementSeconds),
+        "application_name" => Ok(PragmaName::ApplicationName),
+        "defensive" => Ok(PragmaName::Defensive),
+        "wal_checkpoint_threshold_pages" => Ok(PragmaName::WalCheckpointThresholdPages),
+        "wal_checkpoint_threshold_bytes" => Ok(PragmaName::WalCheckpointThresholdBytes),
         "auto_vacuum" => Err(DbError::sql(
             "PRAGMA auto_vacuum is not supported; not applicable to DecentDB storage/checkpointing",
         )),
@@ -1011,7 +1151,17 @@ pub(super) fn pragma_name_sql(name: &PragmaName) -> &'static str {
         PragmaName::IndexInfo => "index_info",
         PragmaName::IndexXInfo => "index_xinfo",
         PragmaName::ForeignKeyList => "foreign_key_list",
+        PragmaName::ForeignKeyCheck => "foreign_key_check",
         PragmaName::FlushPlanCache => "flush_plan_cache",
+        PragmaName::MaxParallelWorkers => "max_parallel_workers",
+        PragmaName::ReadAheadPages => "read_ahead_pages",
+        PragmaName::StatementMemoryLimitBytes => "statement_memory_limit_bytes",
+        PragmaName::MaxResultRows => "max_result_rows",
+        PragmaName::MaxStatementSeconds => "max_statement_seconds",
+        PragmaName::ApplicationName => "application_name",
+        PragmaName::Defensive => "defensive",
+        PragmaName::WalCheckpointThresholdPages => "wal_checkpoint_threshold_pages",
+        PragmaName::WalCheckpointThresholdBytes => "wal_checkpoint_threshold_bytes",
     }
 }
 
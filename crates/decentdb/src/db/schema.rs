@@ -1,10 +1,18 @@
 use super::*;
 
-pub(super) fn table_info(table: &TableSchema, row_count: usize) -> TableInfo {
+pub(super) fn table_info(
+    table: &TableSchema,
+    comments: Option<&TableComments>,
+    row_count: usize,
+) -> TableInfo {
     TableInfo {
         name: table.name.clone(),
         temporary: table.temporary,
-        columns: table.columns.iter().map(column_info).collect(),
+        columns: table
+            .columns
+            .iter()
+            .map(|column| column_info(column, comments))
+            .collect(),
         checks: table
             .checks
             .iter()
@@ -13,10 +21,11 @@ pub(super) fn table_info(table: &TableSchema, row_count: usize) -> TableInfo {
         foreign_keys: table.foreign_keys.iter().map(foreign_key_info).collect(),
         primary_key_columns: table.primary_key_columns.clone(),
         row_count,
+        comment: comments.and_then(|comments| comments.table.clone()),
     }
 }
 
-pub(super) fn column_info(column: &ColumnSchema) -> ColumnInfo {
+pub(super) fn column_info(column: &ColumnSchema, comments: Option<&TableComments>) -> ColumnInfo {
     ColumnInfo {
         name: column.name.clone(),
         column_type: column.column_type.as_str().to_string(),
@@ -25,12 +34,20 @@ pub(super) fn column_info(column: &ColumnSchema) -> ColumnInfo {
         primary_key: column.primary_key,
         unique: column.unique,
         auto_increment: column.auto_increment,
+        generated_sql: column.generated_sql.clone(),
+        generated_stored: column.generated_stored,
+        collation: column
+            .collation
+            .map(|collation| collation.name().to_string()),
         checks: column
             .checks
             .iter()
             .map(|check| check.expression_sql.clone())
             .collect(),
         foreign_key: column.foreign_key.as_ref().map(foreign_key_info),
+        comment: comments
+            .and_then(|comments| comments.column(&column.name))
+            .map(str::to_string),
     }
 }
 
@@ -94,12 +111,18 @@ pub(super) fn schema_snapshot(db: &Db, runtime: &EngineRuntime) -> Result<Schema
         }
         tables.push(schema_table_info(
             table,
+            runtime
+                .catalog
+                .table_ttl
+                .get(&table.name)
+                .map(String::as_str),
             db.runtime_table_row_count(runtime, &table.name, None)?,
         ));
     }
     for table in runtime.temp_tables.values() {
         tables.push(schema_table_info(
             table,
+            None,
             db.runtime_table_row_count(runtime, &table.name, None)?,
         ));
     }
@@ -140,11 +163,15 @@ pub(super) fn schema_snapshot(db: &Db, runtime: &EngineRuntime) -> Result<Schema
     })
 }
 
-pub(super) fn schema_table_info(table: &TableSchema, row_count: usize) -> SchemaTableInfo {
+pub(super) fn schema_table_info(
+    table: &TableSchema,
+    ttl_column: Option<&str>,
+    row_count: usize,
+) -> SchemaTableInfo {
     SchemaTableInfo {
         name: table.name.clone(),
         temporary: table.temporary,
-        ddl: render_create_table(table),
+        ddl: render_create_table(table, ttl_column),
         row_count,
         primary_key_columns: table.primary_key_columns.clone(),
         checks: table.checks.iter().map(check_constraint_info).collect(),
@@ -164,6 +191,9 @@ pub(super) fn schema_column_info(column: &ColumnSchema) -> SchemaColumnInfo {
         auto_increment: column.auto_increment,
         generated_sql: column.generated_sql.clone(),
         generated_stored: column.generated_stored,
+        collation: column
+            .collation
+            .map(|collation| collation.name().to_string()),
         checks: column.checks.iter().map(check_constraint_info).collect(),
         foreign_key: column.foreign_key.as_ref().map(foreign_key_info),
     }
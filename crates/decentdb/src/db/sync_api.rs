@@ -188,6 +188,116 @@ pub(super) fn plan_cache_doctor_row(
     ]
 }
 
+#[derive(Clone, Debug, PartialEq, Eq)]
+pub(super) enum PublicationCommand {
+    Create { name: String, tables: Vec<String> },
+    Drop { name: String, if_exists: bool },
+}
+
+/// Parses `CREATE PUBLICATION <name> FOR TABLE <table>[, ...]` and
+/// `DROP PUBLICATION [IF EXISTS] <name>`. A publication is stored as a sync
+/// scope without a row filter.
+///
+/// Subscription statements are refused rather than reported as unknown SQL:
+/// the engine has no transport to reach a publisher, so subscribers pull
+/// batches through the bindings instead.
+pub(super) fn parse_publication_command(sql: &str) -> Result<Option<PublicationCommand>> {
+    let spaced = sql.trim().trim_end_matches(';').replace(',', " , ");
+    let tokens = spaced.split_whitespace().collect::<Vec<_>>();
+    if tokens.len() >= 2
+        && tokens[1].eq_ignore_ascii_case("SUBSCRIPTION")
+        && ["CREATE", "ALTER", "DROP"]
+            .iter()
+            .any(|verb| tokens[0].eq_ignore_ascii_case(verb))
+    {
+        return Err(DbError::sql(format!(
+            "{} SUBSCRIPTION is not supported; subscribe to a publication through the bindings, such as the Go driver's DB.Subscribe",
+            tokens[0].to_ascii_uppercase()
+        )));
+    }
+    if tokens.len() < 2 || !tokens[1].eq_ignore_ascii_case("PUBLICATION") {
+        return Ok(None);
+    }
+    let identifier = |token: &str| token.trim_matches('"').to_string();
+    if tokens[0].eq_ignore_ascii_case("DROP") {
+        return match tokens[2..] {
+            [name] => Ok(Some(PublicationCommand::Drop {
+                name: identifier(name),
+                if_exists: false,
+            })),
+            [if_kw, exists_kw, name]
+                if if_kw.eq_ignore_ascii_case("IF") && exists_kw.eq_ignore_ascii_case("EXISTS") =>
+            {
+                Ok(Some(PublicationCommand::Drop {
+                    name: identifier(name),
+                    if_exists: true,
+                }))
+            }
+            _ => Err(DbError::sql("expected DROP PUBLICATION [IF EXISTS] <name>")),
+        };
+    }
+    if !tokens[0].eq_ignore_ascii_case("CREATE") {
+        return Ok(None);
+    }
+    let usage = || DbError::sql("expected CREATE PUBLICATION <name> FOR TABLE <table>[, ...]");
+    if tokens.len() < 6
+        || !tokens[3].eq_ignore_ascii_case("FOR")
+        || !tokens[4].eq_ignore_ascii_case("TABLE")
+    {
+        return Err(usage());
+    }
+    // The table list alternates names and commas and ends with a name.
+    let list = &tokens[5..];
+    if list.len() % 2 == 0 {
+        return Err(usage());
+    }
+    let mut tables = Vec::new();
+    for (index, token) in list.iter().enumerate() {
+        let expect_table = index % 2 == 0;
+        if expect_table == (*token == ",") {
+            return Err(usage());
+        }
+        if expect_table {
+            tables.push(identifier(token));
+        }
+    }
+    Ok(Some(PublicationCommand::Create {
+        name: identifier(tokens[2]),
+        tables,
+    }))
+}
+
+/// Checks that a record's row images name exactly the columns of `table`.
+/// Publication imports use this in place of the schema cookie check.
+pub(super) fn sync_check_record_columns(
+    table: &TableSchema,
+    record: &SyncJournalRecord,
+) -> Result<()> {
+    let local = table
+        .columns
+        .iter()
+        .map(|column| column.name.as_str())
+        .collect::<BTreeSet<_>>();
+    for image in [record.after.as_ref(), record.before.as_ref()]
+        .into_iter()
+        .flatten()
+    {
+        let Some(object) = image.as_object() else {
+            continue;
+        };
+        let remote = object.keys().map(String::as_str).collect::<BTreeSet<_>>();
+        if remote != local {
+            return Err(DbError::sql(format!(
+                "schema mismatch for table '{}': record has columns ({}) but local table has ({})",
+                record.table,
+                remote.into_iter().collect::<Vec<_>>().join(", "),
+                local.into_iter().collect::<Vec<_>>().join(", ")
+            )));
+        }
+    }
+    Ok(())
+}
+
 pub(super) fn parse_sync_journal_where_sequence(normalized: &str) -> Option<u64> {
     let rest = normalized.strip_prefix("select * from sys_sync_journal where sequence > ")?;
     let rest = rest.strip_suffix(" order by sequence asc").unwrap_or(rest);
@@ -244,7 +354,7 @@ pub(super) fn sync_changeset_record_from_journal_record(
         transaction_lsn: record.transaction_lsn,
         schema_cookie: record.schema_cookie,
         before_hash: None,
-        before: None,
+        before: record.before.clone(),
         after: record.after.clone(),
         column_mask: Vec::new(),
         tombstone: record.operation == "delete",
@@ -274,6 +384,7 @@ pub(super) fn sync_journal_record_from_changeset_record(
         table: record.table.clone(),
         operation: record.operation.clone(),
         primary_key: record.primary_key.clone(),
+        before: record.before.clone(),
         after,
         schema_cookie: record.schema_cookie,
         committed_at_micros: current_time_micros(),
@@ -0,0 +1,286 @@
+use super::*;
+
+/// Files holding a handle's temporary table rows when `temp_store` is
+/// `file`.
+///
+/// A statement reads back only the tables it can touch: those its SQL
+/// names, the tables behind temporary views it names, tables named by
+/// trigger bodies, and tables tied to those by foreign keys. Loaded rows are
+/// written out again when the last running statement finishes outside an
+/// explicit transaction; a table whose rows did not change since they were
+/// read keeps its existing file, and tables that were not loaded are not
+/// touched. Explicit transactions load every table when they begin.
+#[derive(Debug, Default)]
+pub(super) struct TempSpill {
+    /// Statements currently running with their rows loaded.
+    active: usize,
+    files: BTreeMap<String, SpillFile>,
+    next_file: u64,
+}
+
+#[derive(Debug)]
+struct SpillFile {
+    path: PathBuf,
+    /// Rows last written to or read from `path`.
+    rows: Weak<TableData>,
+}
+
+impl Drop for SpillFile {
+    fn drop(&mut self) {
+        let _ = std::fs::remove_file(&self.path);
+    }
+}
+
+/// Keeps temporary table rows loaded until dropped.
+pub(super) struct TempStoreGuard<'a> {
+    db: Option<&'a Db>,
+}
+
+impl Drop for TempStoreGuard<'_> {
+    fn drop(&mut self) {
+        if let Some(db) = self.db {
+            db.release_temp_store();
+        }
+    }
+}
+
+impl Db {
+    /// Loads all of this handle's spilled temporary table rows for work that
+    /// may touch any of them, until the returned guard is dropped.
+    pub(super) fn install_temp_store(&self) -> Result<TempStoreGuard<'_>> {
+        self.install_temp_store_with(None)
+    }
+
+    /// Loads the spilled temporary tables `sql` can touch for the statements
+    /// it holds, until the returned guard is dropped. `sql` may also be a
+    /// bare table name.
+    pub(super) fn install_temp_store_for(&self, sql: &str) -> Result<TempStoreGuard<'_>> {
+        self.install_temp_store_with(Some(sql))
+    }
+
+    fn install_temp_store_with(&self, sql: Option<&str>) -> Result<TempStoreGuard<'_>> {
+        if self.inner.config.temp_store != TempStore::File {
+            return Ok(TempStoreGuard { db: None });
+        }
+        let mut spill = self
+            .inner
+            .temp_spill
+            .lock()
+            .map_err(|_| DbError::internal("temp store lock poisoned"))?;
+        if !spill.files.is_empty() {
+            self.load_temp_rows(&mut spill, sql)?;
+        }
+        spill.active += 1;
+        Ok(TempStoreGuard { db: Some(self) })
+    }
+
+    fn release_temp_store(&self) {
+        let Ok(mut spill) = self.inner.temp_spill.lock() else {
+            return;
+        };
+        spill.active = spill.active.saturating_sub(1);
+        if spill.active == 0 && !self.inner.sql_txn_active.load(Ordering::Acquire) {
+            // Rows that cannot be written stay in memory until the next
+            // statement finishes.
+            let _ = self.spill_temp_rows(&mut spill);
+        }
+    }
+
+    fn load_temp_rows(&self, spill: &mut TempSpill, sql: Option<&str>) -> Result<()> {
+        {
+            let temp = self
+                .inner
+                .temp_state
+                .lock()
+                .map_err(|_| DbError::internal("temp schema lock poisoned"))?;
+            if spill
+                .files
+                .keys()
+                .all(|name| !temp.tables.contains_key(name) || temp.table_data.contains_key(name))
+            {
+                return Ok(());
+            }
+        }
+        let mut engine = self
+            .inner
+            .engine
+            .write()
+            .map_err(|_| DbError::internal("engine runtime lock poisoned"))?;
+        let mut temp = self
+            .inner
+            .temp_state
+            .lock()
+            .map_err(|_| DbError::internal("temp schema lock poisoned"))?;
+        let wanted = sql.map(|sql| temp_tables_touched_by(&temp, &engine.catalog.triggers, sql));
+        let mut table_data = (*temp.table_data).clone();
+        for (name, file) in &mut spill.files {
+            if !temp.tables.contains_key(name)
+                || table_data.contains_key(name)
+                || wanted.as_ref().is_some_and(|wanted| !wanted.contains(name))
+            {
+                continue;
+            }
+            let bytes = std::fs::read(&file.path).map_err(|source| {
+                DbError::io(format!("read temp table {}", file.path.display()), source)
+            })?;
+            let rows = Arc::new(TableData::decode_spill(&bytes)?);
+            file.rows = Arc::downgrade(&rows);
+            table_data.insert(name.clone(), rows);
+        }
+        let table_data = Arc::new(table_data);
+        temp.table_data = Arc::clone(&table_data);
+        engine.temp_table_data = table_data;
+        Ok(())
+    }
+
+    fn spill_temp_rows(&self, spill: &mut TempSpill) -> Result<()> {
+        let has_temp_tables = !self
+            .inner
+            .temp_state
+            .lock()
+            .map_err(|_| DbError::internal("temp schema lock poisoned"))?
+            .tables
+            .is_empty();
+        if !has_temp_tables {
+            spill.files.clear();
+            return Ok(());
+        }
+        let mut engine = self
+            .inner
+            .engine
+            .write()
+            .map_err(|_| DbError::internal("engine runtime lock poisoned"))?;
+        let mut temp = self
+            .inner
+            .temp_state
+            .lock()
+            .map_err(|_| DbError::internal("temp schema lock poisoned"))?;
+        spill.files.retain(|name, _| temp.tables.contains_key(name));
+        for (name, rows) in temp.table_data.iter() {
+            if !temp.tables.contains_key(name) {
+                continue;
+            }
+            if let Some(file) = spill.files.get(name) {
+                if file
+                    .rows
+                    .upgrade()
+                    .is_some_and(|written| Arc::ptr_eq(&written, rows))
+                {
+                    continue;
+                }
+            }
+            let bytes = rows.encode_spill()?;
+            let path = match spill.files.get(name) {
+                Some(file) => file.path.clone(),
+                None => {
+                    spill.next_file += 1;
+                    self.inner.config.temp_dir.join(format!(
+                        "decentdb-temp-{}-{}-{}.rows",
+                        std::process::id(),
+                        self.inner.lock_owner,
+                        spill.next_file
+                    ))
+                }
+            };
+            if let Err(source) = std::fs::write(&path, &bytes) {
+                if !spill.files.contains_key(name) {
+                    let _ = std::fs::remove_file(&path);
+                }
+                return Err(DbError::io(
+                    format!("write temp table {}", path.display()),
+                    source,
+                ));
+            }
+            spill
+                .files
+                .entry(name.clone())
+                .or_insert_with(|| SpillFile {
+                    path,
+                    rows: Weak::new(),
+                })
+                .rows = Arc::downgrade(rows);
+        }
+        let empty = Arc::new(BTreeMap::new());
+        temp.table_data = Arc::clone(&empty);
+        engine.temp_table_data = empty;
+        Ok(())
+    }
+}
+
+/// Returns the temporary tables a statement with this SQL text can read or
+/// write. Names are matched as case-insensitive substrings, so the set may
+/// include tables the statement does not touch but never misses one it
+/// names.
+fn temp_tables_touched_by(
+    temp: &TempSchemaState,
+    triggers: &BTreeMap<String, TriggerSchema>,
+    sql: &str,
+) -> BTreeSet<String> {
+    let mut text = sql.to_ascii_lowercase();
+    // Any trigger may fire, and temporary tables shadow the names its body
+    // uses.
+    for trigger in triggers.values() {
+        text.push(' ');
+        text.push_str(&trigger.action_sql.to_ascii_lowercase());
+    }
+    let mut expanded_views = BTreeSet::new();
+    loop {
+        let named_views = temp
+            .views
+            .iter()
+            .filter(|(name, _)| {
+                !expanded_views.contains(*name) && text.contains(&name.to_ascii_lowercase())
+            })
+            .map(|(name, view)| (name.clone(), view.sql_text.to_ascii_lowercase()))
+            .collect::<Vec<_>>();
+        if named_views.is_empty() {
+            break;
+        }
+        for (name, view_sql) in named_views {
+            text.push(' ');
+            text.push_str(&view_sql);
+            expanded_views.insert(name);
+        }
+    }
+    let mut tables = temp
+        .tables
+        .keys()
+        .filter(|name| text.contains(&name.to_ascii_lowercase()))
+        .cloned()
+        .collect::<BTreeSet<_>>();
+    // Writes check or cascade to the tables on either side of a foreign key.
+    loop {
+        let linked = temp
+            .tables
+            .iter()
+            .filter(|(name, table)| {
+                !tables.contains(*name) && foreign_key_links(&temp.tables, name, table, &tables)
+            })
+            .map(|(name, _)| name.clone())
+            .collect::<Vec<_>>();
+        if linked.is_empty() {
+            break;
+        }
+        tables.extend(linked);
+    }
+    tables
+}
+
+/// Reports whether a foreign key runs between `table` and any of `touched`.
+fn foreign_key_links(
+    tables: &BTreeMap<String, TableSchema>,
+    name: &str,
+    table: &TableSchema,
+    touched: &BTreeSet<String>,
+) -> bool {
+    touched.iter().any(|other| {
+        table
+            .foreign_keys
+            .iter()
+            .any(|foreign_key| identifiers_equal(other, &foreign_key.referenced_table))
+            || tables[other]
+                .foreign_keys
+                .iter()
+                .any(|foreign_key| identifiers_equal(name, &foreign_key.referenced_table))
+    })
+}
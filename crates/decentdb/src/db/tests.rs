@@ -7,7 +7,7 @@ use std::time::{Duration, Instant};
 use tempfile::TempDir;
 
 use crate::catalog::{ColumnSchema, ColumnType, IndexKind, IndexSchema, TableSchema, ViewSchema};
-use crate::config::DbConfig;
+use crate::config::{DbConfig, ProcessCoordinationMode, TempStore};
 use crate::db::SqlTxnSlot;
 use crate::error::{DbError, Result};
 use crate::exec::{
@@ -21,13 +21,15 @@ use crate::storage::DatabaseHeader;
 
 use crate::exec::dml::{PreparedInsertColumn, PreparedInsertValueSource, PreparedSimpleInsert};
 use crate::sql::parser::parse_sql_statement;
-use crate::{BulkLoadOptions, Db, QueuedWriteOptions, Value, WalSyncMode};
+use crate::{
+    BulkLoadOptions, CloneOptions, Db, DumpOptions, QueuedWriteOptions, Value, WalSyncMode,
+};
 
 use super::{
     parse_simple_count_star_sql, parse_simple_grouped_count_sql,
     parse_simple_row_id_projection_sql, parse_simple_row_id_range_projection_sql,
-    simple_single_statement_fast_path_sql, split_sql_batch, PreparedInsertCache, StatementCache,
-    TempSchemaState,
+    simple_single_statement_fast_path_sql, split_sql_batch, PreparedInsertCache, RestoreWriter,
+    StatementCache, TempSchemaState,
 };
 
 #[derive(Debug)]
@@ -88,6 +90,7 @@ fn runtime_has_stale_indexes_detects_only_missing_or_nonfresh_indexes() {
                 column_type: ColumnType::Int64,
                 spatial_type: None,
                 enum_type: None,
+                collation: None,
                 nullable: false,
                 default_sql: None,
                 generated_sql: None,
@@ -144,6 +147,7 @@ fn runtime_has_stale_indexes_detects_only_missing_or_nonfresh_indexes() {
                 column_type: ColumnType::Int64,
                 spatial_type: None,
                 enum_type: None,
+                collation: None,
                 nullable: false,
                 default_sql: None,
                 generated_sql: None,
@@ -683,6 +687,7 @@ fn temp_schema_apply_is_shallow_when_unmutated() {
             column_type: ColumnType::Int64,
             spatial_type: None,
             enum_type: None,
+            collation: None,
             nullable: false,
             default_sql: None,
             generated_sql: None,
@@ -860,6 +865,252 @@ fn enum_columns_store_label_ids_and_dump_labels() -> Result<()> {
     Ok(())
 }
 
+#[test]
+fn dump_sql_with_options_filters_tables_schema_and_data() -> Result<()> {
+    let db = Db::open_or_create(":memory:", DbConfig::default())?;
+    db.execute_batch(
+        "CREATE TABLE users (id INT PRIMARY KEY, name TEXT);
+         CREATE TABLE orders (id INT PRIMARY KEY, user_id INT);
+         CREATE INDEX orders_user_idx ON orders (user_id);
+         CREATE VIEW user_names AS SELECT name FROM users;
+         INSERT INTO users VALUES (1, 'Ada');
+         INSERT INTO orders VALUES (10, 1);",
+    )?;
+
+    let orders_only = db.dump_sql_with_options(&DumpOptions {
+        tables: vec!["ORDERS".to_string()],
+        ..DumpOptions::default()
+    })?;
+    assert!(orders_only.contains("\"orders\""));
+    assert!(orders_only.contains("orders_user_idx"));
+    assert!(!orders_only.contains("\"users\""));
+    assert!(!orders_only.contains("user_names"));
+
+    let schema_only = db.dump_sql_with_options(&DumpOptions {
+        include_data: false,
+        ..DumpOptions::default()
+    })?;
+    assert!(schema_only.contains("CREATE TABLE"));
+    assert!(schema_only.contains("user_names"));
+    assert!(!schema_only.contains("INSERT INTO"));
+
+    let data_only = db.dump_sql_with_options(&DumpOptions {
+        include_schema: false,
+        ..DumpOptions::default()
+    })?;
+    assert!(data_only.contains("INSERT INTO"));
+    assert!(!data_only.contains("CREATE "));
+
+    let restored = Db::open_or_create(":memory:", DbConfig::default())?;
+    restored.execute_batch(&db.dump_sql()?)?;
+    let count = restored.execute("SELECT COUNT(*) FROM orders")?;
+    assert_eq!(count.rows()[0].values(), &[Value::Int64(1)]);
+
+    assert!(db
+        .dump_sql_with_options(&DumpOptions {
+            tables: vec!["missing".to_string()],
+            ..DumpOptions::default()
+        })
+        .is_err());
+    Ok(())
+}
+
+#[test]
+fn recover_to_copies_schema_rows_and_refuses_existing_destination() -> Result<()> {
+    let dir = TempDir::new().expect("create temp dir");
+    let source = Db::open_or_create(dir.path().join("source.ddb"), DbConfig::default())?;
+    source.execute_batch(
+        "CREATE TABLE users (id INT PRIMARY KEY, name TEXT);
+         CREATE INDEX users_name_idx ON users (name);
+         CREATE VIEW user_names AS SELECT name FROM users;
+         INSERT INTO users VALUES (1, 'Ada'), (2, 'Grace');",
+    )?;
+
+    let dest = dir.path().join("salvaged.ddb");
+    let report = source.recover_to(&dest)?;
+    assert!(report.is_complete());
+    assert_eq!(report.tables.len(), 1);
+    assert_eq!(report.tables[0].name, "users");
+    assert!(report.tables[0].schema_recovered);
+    assert_eq!(report.tables[0].rows_recovered, 2);
+
+    let salvaged = Db::open(&dest, DbConfig::default())?;
+    let count = salvaged.execute("SELECT COUNT(*) FROM user_names")?;
+    assert_eq!(count.rows()[0].values(), &[Value::Int64(2)]);
+    assert!(salvaged
+        .list_indexes()?
+        .iter()
+        .any(|index| index.name == "users_name_idx"));
+    drop(salvaged);
+
+    assert!(source.recover_to(&dest).is_err());
+    Ok(())
+}
+
+#[test]
+fn clone_to_copies_selected_tables_and_filtered_rows() -> Result<()> {
+    let dir = TempDir::new().expect("create temp dir");
+    let source = Db::open_or_create(dir.path().join("source.ddb"), DbConfig::default())?;
+    source.execute_batch(
+        "CREATE TABLE users (id INT PRIMARY KEY, email TEXT NOT NULL);
+         CREATE TABLE orders (
+             id INT PRIMARY KEY,
+             user_id INT REFERENCES users (id),
+             total INT NOT NULL
+         );
+         CREATE INDEX orders_total_idx ON orders (total);
+         CREATE TABLE audit_log (id INT PRIMARY KEY, note TEXT);
+         INSERT INTO users VALUES (1, 'ada@example.com'), (2, 'grace@example.com');
+         INSERT INTO orders VALUES (10, 1, 5), (11, 2, 50), (12, 1, 70);
+         INSERT INTO audit_log VALUES (1, 'secret');",
+    )?;
+
+    let dest = dir.path().join("staging.ddb");
+    let report = source.clone_to(
+        &dest,
+        &CloneOptions {
+            tables: vec!["orders".to_string(), "users".to_string()],
+            filters: BTreeMap::from([
+                ("users".to_string(), "id = 1".to_string()),
+                ("orders".to_string(), "total > 10".to_string()),
+            ]),
+        },
+    )?;
+    let names = report
+        .tables
+        .iter()
+        .map(|table| table.name.as_str())
+        .collect::<Vec<_>>();
+    assert_eq!(names, ["users", "orders"]);
+    assert_eq!(report.tables[0].rows_copied, 1);
+    assert_eq!(report.tables[1].rows_copied, 1);
+    assert_eq!(report.tables[1].rows_skipped, 1);
+
+    let staging = Db::open(&dest, DbConfig::default())?;
+    let ids = staging.execute("SELECT id FROM orders")?;
+    assert_eq!(ids.rows().len(), 1);
+    assert_eq!(ids.rows()[0].values(), &[Value::Int64(12)]);
+    assert!(staging.execute("SELECT * FROM audit_log").is_err());
+    assert!(staging
+        .list_indexes()?
+        .iter()
+        .any(|index| index.name == "orders_total_idx"));
+    drop(staging);
+
+    assert!(source.clone_to(&dest, &CloneOptions::default()).is_err());
+    let orphan = CloneOptions {
+        tables: vec!["orders".to_string()],
+        ..CloneOptions::default()
+    };
+    assert!(source
+        .clone_to(dir.path().join("orphan.ddb"), &orphan)
+        .is_err());
+    assert!(!dir.path().join("orphan.ddb").exists());
+    Ok(())
+}
+
+#[test]
+fn clone_to_applies_column_transforms() -> Result<()> {
+    let dir = TempDir::new().expect("create temp dir");
+    let source = Db::open_or_create(dir.path().join("source.ddb"), DbConfig::default())?;
+    source.execute_batch(
+        "CREATE TABLE users (id INT PRIMARY KEY, email TEXT UNIQUE, bio TEXT);
+         INSERT INTO users VALUES
+             (1, 'ada@example.com', 'likes engines'),
+             (2, 'grace@example.com', NULL);",
+    )?;
+
+    let options: CloneOptions = serde_json::from_str(
+        r#"{"transforms": {"users": {
+            "email": "'user' || CAST(id AS TEXT) || '@example.invalid'",
+            "bio": "NULL"
+        }}}"#,
+    )
+    .expect("parse clone options");
+    let dest = dir.path().join("scrubbed.ddb");
+    source.clone_to(&dest, &options)?;
+
+    let scrubbed = Db::open(&dest, DbConfig::default())?;
+    let rows = scrubbed.execute("SELECT email, bio FROM users ORDER BY id")?;
+    assert_eq!(
+        rows.rows()[0].values(),
+        &[
+            Value::Text("user1@example.invalid".to_string()),
+            Value::Null
+        ]
+    );
+    assert_eq!(
+        rows.rows()[1].values()[0],
+        Value::Text("user2@example.invalid".to_string())
+    );
+    drop(scrubbed);
+
+    let mut unknown_column = CloneOptions::default();
+    unknown_column.transforms.insert(
+        "users".to_string(),
+        BTreeMap::from([("phone".to_string(), "NULL".to_string())]),
+    );
+    assert!(source
+        .clone_to(dir.path().join("bad.ddb"), &unknown_column)
+        .is_err());
+    assert!(serde_json::from_str::<CloneOptions>(r#"{"filter": {}}"#).is_err());
+    Ok(())
+}
+
+#[test]
+fn verify_against_compares_row_counts_and_checksums() -> Result<()> {
+    let dir = TempDir::new().expect("create temp dir");
+    let primary = Db::open_or_create(dir.path().join("primary.ddb"), DbConfig::default())?;
+    let replica = Db::open_or_create(dir.path().join("replica.ddb"), DbConfig::default())?;
+    primary.execute_batch(
+        "CREATE TABLE users (id INT PRIMARY KEY, name TEXT);
+         CREATE TABLE audit (note TEXT);
+         INSERT INTO users VALUES (1, 'Ada'), (2, 'Grace');
+         INSERT INTO audit VALUES ('a'), ('b');",
+    )?;
+    // Same rows written in a different order.
+    replica.execute_batch(
+        "CREATE TABLE users (id INT PRIMARY KEY, name TEXT);
+         CREATE TABLE audit (note TEXT);
+         INSERT INTO users VALUES (2, 'Grace'), (1, 'Ada');
+         INSERT INTO audit VALUES ('b'), ('a');",
+    )?;
+
+    let report = primary.verify_against(&replica)?;
+    assert!(report.matches());
+    assert_eq!(report.tables.len(), 2);
+    assert_eq!(report.tables[1].name, "users");
+    assert_eq!(report.tables[1].left_row_count, Some(2));
+    assert_eq!(
+        report.tables[1].left_checksum,
+        report.tables[1].right_checksum
+    );
+
+    replica.execute_batch(
+        "UPDATE users SET name = 'Grace Hopper' WHERE id = 2;
+         CREATE TABLE extra (id INT);",
+    )?;
+    let report = primary.verify_against(&replica)?;
+    assert!(!report.matches());
+    let by_name = |name: &str| {
+        report
+            .tables
+            .iter()
+            .find(|table| table.name == name)
+            .expect("table in report")
+    };
+    assert!(by_name("audit").matches);
+    let users = by_name("users");
+    assert!(!users.matches);
+    assert_eq!(users.left_row_count, users.right_row_count);
+    assert_ne!(users.left_checksum, users.right_checksum);
+    let extra = by_name("extra");
+    assert!(!extra.matches);
+    assert_eq!(extra.left_row_count, None);
+    assert_eq!(extra.right_row_count, Some(0));
+    Ok(())
+}
+
 #[test]
 fn macaddr_columns_store_binary_and_dump_text() -> Result<()> {
     let dir = TempDir::new().expect("create temp dir");
@@ -1284,12 +1535,20 @@ fn pragma_pragma_assignment_compatibility_is_narrow() {
             .affected_rows()
             == 0
     );
-    let foreign_keys_off = normal_db
+    normal_db
         .execute("PRAGMA foreign_keys = OFF")
-        .expect_err("disabling foreign_keys should fail");
-    assert!(foreign_keys_off
-        .to_string()
-        .contains("cannot disable foreign key enforcement"));
+        .expect("disable foreign keys");
+    assert_eq!(
+        normal_db
+            .execute("PRAGMA foreign_keys")
+            .expect("foreign keys off")
+            .rows()[0]
+            .values(),
+        &[Value::Int64(0)]
+    );
+    normal_db
+        .execute("PRAGMA foreign_keys = DEFAULT")
+        .expect("restore foreign keys");
 
     assert_eq!(
         normal_db
@@ -1462,18 +1721,660 @@ fn pragma_extended_compatibility_surface_is_implemented() -> Result<()> {
         .all(|value| matches!(value, Value::Int64(v) if *v >= 0)));
 
     assert_eq!(
-        db.execute("PRAGMA busy_timeout")?.rows()[0].values(),
-        &[Value::Int64(0)]
+        db.execute("PRAGMA busy_timeout")?.rows()[0].values(),
+        &[Value::Int64(0)]
+    );
+    db.execute("PRAGMA busy_timeout = 1250")?;
+    assert_eq!(
+        db.execute("PRAGMA busy_timeout")?.rows()[0].values(),
+        &[Value::Int64(1_250)]
+    );
+    assert_eq!(
+        db.execute("PRAGMA journal_mode = WAL")?.rows()[0].values(),
+        &[Value::Text("wal".to_string())]
+    );
+    Ok(())
+}
+
+#[test]
+fn parallel_scan_filters_match_serial_results() -> Result<()> {
+    let dir = TempDir::new().expect("tempdir");
+    let path = dir.path().join("parallel-scan.ddb");
+    let config = DbConfig {
+        max_parallel_workers: 4,
+        ..DbConfig::default()
+    };
+    let db = Db::open_or_create(&path, config)?;
+    db.execute("CREATE TABLE t (id INTEGER PRIMARY KEY, v INTEGER, label TEXT)")?;
+    db.execute(
+        "INSERT INTO t SELECT value, value % 97, 'row' || CAST(value AS TEXT) FROM generate_series(1, 40000)",
+    )?;
+    assert_eq!(
+        db.execute("PRAGMA max_parallel_workers")?.rows()[0].values(),
+        &[Value::Int64(4)]
+    );
+
+    let sql = "SELECT id, label FROM t WHERE v % 7 = 3 AND label LIKE 'row1%' ORDER BY id";
+    let parallel = db.execute(sql)?;
+    assert_eq!(
+        db.execute_with_params(
+            "SELECT id FROM t WHERE v BETWEEN $1 AND $2",
+            &[Value::Int64(10), Value::Int64(12)]
+        )?
+        .rows()
+        .len(),
+        40000 / 97 * 3 + 3
+    );
+
+    db.execute("PRAGMA max_parallel_workers = 1")?;
+    let serial = db.execute(sql)?;
+    assert!(!serial.rows().is_empty());
+    assert_eq!(parallel.rows(), serial.rows());
+
+    db.execute("PRAGMA max_parallel_workers = DEFAULT")?;
+    assert_eq!(
+        db.execute("PRAGMA max_parallel_workers")?.rows()[0].values(),
+        &[Value::Int64(4)]
+    );
+    assert!(db.execute("PRAGMA max_parallel_workers = -1").is_err());
+    Ok(())
+}
+
+#[test]
+fn concurrent_commit_reports_retryable_transaction_conflict() -> Result<()> {
+    let temp = TempDir::new().expect("tempdir");
+    let path = temp.path().join("conflict.ddb");
+    let config = DbConfig {
+        background_checkpoint_worker: false,
+        ..DbConfig::default()
+    };
+    let db1 = Db::open_or_create(&path, config.clone())?;
+    db1.execute("CREATE TABLE a (id INTEGER PRIMARY KEY)")?;
+    db1.execute("CREATE TABLE b (id INTEGER PRIMARY KEY)")?;
+    let db2 = Db::open_or_create(&path, config)?;
+
+    db1.begin_transaction()?;
+    db1.execute("INSERT INTO a VALUES (1)")?;
+    db2.execute("INSERT INTO a VALUES (2)")?;
+    let error = db1
+        .commit_transaction()
+        .expect_err("commit after a foreign writer must conflict");
+    assert!(error.is_transaction_conflict(), "{error}");
+    assert!(error.to_string().contains("transaction conflict"));
+    let diagnostic = error.diagnostic();
+    assert_eq!(diagnostic.subcode, "transaction.conflict");
+    assert_eq!(diagnostic.sqlstate, Some("40001"));
+    assert!(diagnostic.retryable && !diagnostic.permanent);
+    assert_eq!(diagnostic.context.relation.as_deref(), Some("a"));
+    let details = diagnostic.context.details.expect("conflict details");
+    assert!(details.contains_key("page_id"));
+
+    db1.begin_transaction()?;
+    db1.execute("INSERT INTO a VALUES (1)")?;
+    db1.commit_transaction()?;
+    assert_eq!(scalar_i64(&db2.execute("SELECT COUNT(*) FROM a")?), 2);
+
+    // The engine conflicts on any foreign commit, but only names a table
+    // both transactions changed.
+    db1.begin_transaction()?;
+    db1.execute("INSERT INTO b VALUES (1)")?;
+    db2.execute("INSERT INTO a VALUES (3)")?;
+    let error = db1.commit_transaction().expect_err("conflict");
+    assert!(error.is_transaction_conflict());
+    assert_eq!(error.diagnostic().context.relation, None);
+    Ok(())
+}
+
+#[test]
+fn wal_checkpoint_threshold_pragmas_change_open_database() -> Result<()> {
+    // File-backed WALs are shared and skip auto-checkpoints, so the trigger
+    // is observed on an in-memory database.
+    let config = DbConfig {
+        wal_checkpoint_threshold_pages: 0,
+        wal_checkpoint_threshold_bytes: 0,
+        background_checkpoint_worker: false,
+        ..DbConfig::default()
+    };
+    let db = Db::open_or_create(":memory:", config)?;
+    db.execute("CREATE TABLE t (id INTEGER PRIMARY KEY)")?;
+    assert_eq!(
+        db.execute("PRAGMA wal_checkpoint_threshold_pages")?.rows()[0].values(),
+        &[Value::Int64(0)]
+    );
+
+    db.execute("PRAGMA wal_checkpoint_threshold_pages = 1")?;
+    db.execute("PRAGMA wal_checkpoint_threshold_bytes = 4096")?;
+    assert_eq!(
+        db.execute("PRAGMA wal_checkpoint_threshold_pages")?.rows()[0].values(),
+        &[Value::Int64(1)]
+    );
+    assert_eq!(
+        db.execute("PRAGMA wal_checkpoint_threshold_bytes")?.rows()[0].values(),
+        &[Value::Int64(4096)]
+    );
+    let epoch = db.inner.wal.checkpoint_epoch();
+    db.execute("INSERT INTO t VALUES (1)")?;
+    assert!(db.inner.wal.checkpoint_epoch() > epoch);
+
+    db.execute("PRAGMA wal_checkpoint_threshold_pages = DEFAULT")?;
+    db.execute("PRAGMA wal_checkpoint_threshold_bytes = DEFAULT")?;
+    assert_eq!(
+        db.execute("PRAGMA wal_checkpoint_threshold_pages")?.rows()[0].values(),
+        &[Value::Int64(0)]
+    );
+    assert!(db
+        .execute("PRAGMA wal_checkpoint_threshold_pages = -1")
+        .is_err());
+    Ok(())
+}
+
+#[test]
+fn foreign_keys_toggle_defers_checks_until_check_foreign_keys() -> Result<()> {
+    let dir = TempDir::new().expect("tempdir");
+    let config = DbConfig {
+        foreign_keys: false,
+        ..DbConfig::default()
+    };
+    let db = Db::open_or_create(dir.path().join("fk-toggle.ddb"), config)?;
+    db.execute("CREATE TABLE parent (id INTEGER PRIMARY KEY)")?;
+    db.execute(
+        "CREATE TABLE child (id INTEGER PRIMARY KEY, parent_id INTEGER REFERENCES parent(id) ON DELETE CASCADE)",
+    )?;
+    assert_eq!(
+        db.execute("PRAGMA foreign_keys")?.rows()[0].values(),
+        &[Value::Int64(0)]
+    );
+
+    db.execute("INSERT INTO parent VALUES (1)")?;
+    db.execute("INSERT INTO child VALUES (10, 1), (11, 2), (12, NULL)")?;
+    let prepared = db.prepare("INSERT INTO child VALUES ($1, $2)")?;
+    prepared.execute(&[Value::Int64(13), Value::Int64(3)])?;
+    db.execute("DELETE FROM parent WHERE id = 1")?;
+    assert_eq!(
+        db.execute("SELECT COUNT(*) FROM child")?.rows()[0].values(),
+        &[Value::Int64(4)]
+    );
+
+    let violations = db.check_foreign_keys()?;
+    assert_eq!(violations.len(), 3);
+    assert!(violations
+        .iter()
+        .all(|violation| violation.table_name == "child"
+            && violation.referenced_table == "parent"
+            && violation.foreign_key_ordinal == 0));
+    let pragma = db.execute("PRAGMA foreign_key_check(child)")?;
+    assert_eq!(pragma.columns(), &["table", "rowid", "parent", "fkid"]);
+    assert_eq!(pragma.rows().len(), 3);
+
+    db.execute("PRAGMA foreign_keys = ON")?;
+    let err = db
+        .execute("INSERT INTO child VALUES (14, 4)")
+        .expect_err("enforced foreign key");
+    assert!(err.to_string().contains("missing parent row"));
+    db.execute("DELETE FROM child WHERE id IN (10, 11, 13)")?;
+    assert!(db.check_foreign_keys()?.is_empty());
+    Ok(())
+}
+
+#[test]
+fn defensive_mode_refuses_schema_changes_and_deep_nesting() -> Result<()> {
+    let dir = TempDir::new().expect("tempdir");
+    let path = dir.path().join("defensive.ddb");
+    {
+        let db = Db::open_or_create(&path, DbConfig::default())?;
+        db.execute("CREATE TABLE t (id INTEGER PRIMARY KEY, v INTEGER)")?;
+        db.execute(
+            "CREATE TRIGGER t_loop AFTER INSERT ON t FOR EACH ROW \
+             EXECUTE FUNCTION decentdb_exec_sql('INSERT INTO t (v) VALUES (1)')",
+        )?;
+    }
+
+    let config = DbConfig {
+        defensive: true,
+        ..DbConfig::default()
+    };
+    let db = Db::open(&path, config)?;
+    assert_eq!(
+        db.execute("PRAGMA defensive")?.rows()[0].values(),
+        &[Value::Int64(1)]
+    );
+    for sql in [
+        "CREATE TABLE u (id INTEGER)",
+        "DROP TABLE t",
+        "ALTER TABLE t ADD COLUMN w INTEGER",
+        "CREATE INDEX t_v ON t (v)",
+        "COMMENT ON TABLE t IS 'x'",
+        "DROP TRIGGER t_loop ON t",
+    ] {
+        let err = db.execute(sql).expect_err(sql);
+        assert!(
+            err.to_string().contains("not allowed in defensive mode"),
+            "{sql}: {err}"
+        );
+    }
+    assert!(db.prepare("CREATE TABLE u (id INTEGER)").is_err());
+    assert!(db.execute("PRAGMA defensive = OFF").is_err());
+
+    let nested = format!("SELECT {}1{}", "(".repeat(65), ")".repeat(65));
+    let err = db.execute(&nested).expect_err("nesting limit");
+    assert!(err.to_string().contains("defensive mode limit"), "{err}");
+    let quoted = format!("SELECT '{}'", "(".repeat(200));
+    db.execute(&quoted)?;
+
+    let err = db
+        .execute("INSERT INTO t (v) VALUES (0)")
+        .expect_err("recursive trigger");
+    assert!(err.to_string().contains("level limit"), "{err}");
+    assert_eq!(
+        db.execute("SELECT COUNT(*) FROM t")?.rows()[0].values(),
+        &[Value::Int64(0)]
+    );
+    Ok(())
+}
+
+#[test]
+fn verify_checksums_reports_the_corrupted_page() -> Result<()> {
+    let dir = TempDir::new().expect("tempdir");
+    let path = dir.path().join("checksums.ddb");
+    let page_size = {
+        let db = Db::open_or_create(&path, DbConfig::default())?;
+        db.execute("CREATE TABLE t (id INTEGER PRIMARY KEY, body TEXT)")?;
+        db.execute("INSERT INTO t SELECT value, repeat('x', 200) FROM generate_series(1, 500)")?;
+        db.checkpoint()?;
+        db.config().page_size as u64
+    };
+    let mut sidecar = path.as_os_str().to_os_string();
+    sidecar.push(".pagesum");
+    assert!(Path::new(&sidecar).exists());
+
+    let mut bytes = std::fs::read(&path).expect("read database");
+    let page_count = bytes.len() as u64 / page_size;
+    let target = page_count;
+    let offset = ((target - 1) * page_size + page_size / 2) as usize;
+    bytes[offset] ^= 0x40;
+    std::fs::write(&path, &bytes).expect("write corrupted database");
+
+    let config = DbConfig {
+        verify_checksums: true,
+        ..DbConfig::default()
+    };
+    let err = Db::open(&path, config)
+        .and_then(|db| db.execute("SELECT COUNT(*), MAX(length(body)) FROM t"))
+        .expect_err("checksum mismatch");
+    let diagnostic = err.diagnostic();
+    assert_eq!(
+        diagnostic.subcode,
+        crate::error::SUBCODE_CORRUPTION_PAGE_CHECKSUM
+    );
+    assert_eq!(
+        diagnostic
+            .context
+            .details
+            .and_then(|details| details.get("page_id").cloned()),
+        Some(serde_json::Value::from(target))
+    );
+    Ok(())
+}
+
+#[test]
+fn storage_quota_rejects_commits_that_grow_the_database() -> Result<()> {
+    let dir = TempDir::new().expect("tempdir");
+    let path = dir.path().join("quota.ddb");
+    let page_size = {
+        let db = Db::open_or_create(&path, DbConfig::default())?;
+        db.execute("CREATE TABLE t (id INTEGER PRIMARY KEY, body TEXT)")?;
+        db.checkpoint()?;
+        u64::from(db.config().page_size)
+    };
+    let base = std::fs::metadata(&path).expect("metadata").len();
+    let config = DbConfig {
+        max_database_size_bytes: base + 8 * page_size,
+        ..DbConfig::default()
+    };
+    let db = Db::open(&path, config)?;
+
+    let body = "x".repeat(page_size as usize / 2);
+    let mut inserted = 0_i64;
+    let err = loop {
+        match db.execute_with_params(
+            "INSERT INTO t VALUES ($1, $2)",
+            &[Value::Int64(inserted), Value::Text(body.clone())],
+        ) {
+            Ok(_) => inserted += 1,
+            Err(err) => break err,
+        }
+        assert!(inserted < 64, "quota never enforced");
+    };
+    assert!(err.is_quota_exceeded(), "{err:?}");
+    assert!(inserted > 0);
+    let count = db.execute("SELECT COUNT(*) FROM t")?;
+    assert_eq!(count.rows()[0].values()[0], Value::Int64(inserted));
+
+    // Freed pages are reused without growing the database.
+    db.execute("DELETE FROM t")?;
+    db.execute("INSERT INTO t VALUES (0, 'small')")?;
+    Ok(())
+}
+
+#[test]
+fn read_ahead_scans_return_the_same_rows() -> Result<()> {
+    let dir = TempDir::new().expect("tempdir");
+    let path = dir.path().join("read_ahead.ddb");
+    {
+        let db = Db::open_or_create(&path, DbConfig::default())?;
+        db.execute("CREATE TABLE t (id INTEGER PRIMARY KEY, body TEXT)")?;
+        db.execute("INSERT INTO t SELECT value, repeat('x', 300) FROM generate_series(1, 5000)")?;
+        db.checkpoint()?;
+    }
+    let config = DbConfig {
+        read_ahead_pages: 32,
+        ..DbConfig::default()
+    };
+    let db = Db::open(&path, config)?;
+    let sum = |sql: &str| -> Result<Value> { Ok(db.execute(sql)?.rows()[0].values()[0].clone()) };
+    let expected = Value::Int64(5000 * 300);
+    assert_eq!(sum("SELECT SUM(length(body)) FROM t")?, expected);
+    assert_eq!(
+        sum("/*+ READ_AHEAD(4096) */ SELECT SUM(length(body)) FROM t")?,
+        expected
+    );
+
+    assert_eq!(sum("PRAGMA read_ahead_pages")?, Value::Int64(32));
+    db.execute("PRAGMA read_ahead_pages = 0")?;
+    assert_eq!(sum("SELECT SUM(length(body)) FROM t")?, expected);
+    db.execute("PRAGMA read_ahead_pages = DEFAULT")?;
+    assert_eq!(sum("PRAGMA read_ahead_pages")?, Value::Int64(32));
+    Ok(())
+}
+
+#[test]
+fn statement_memory_limit_aborts_large_joins() -> Result<()> {
+    let db = Db::open_or_create(":memory:", DbConfig::default())?;
+    db.execute("CREATE TABLE t (id INTEGER PRIMARY KEY, body TEXT)")?;
+    db.execute("INSERT INTO t SELECT value, repeat('x', 100) FROM generate_series(1, 200)")?;
+    let join = "SELECT a.body, b.body FROM t a JOIN t b ON a.id < b.id ORDER BY a.id, b.id";
+
+    let explain = db.execute(&format!("EXPLAIN ANALYZE {join}"))?;
+    let peak = explain
+        .explain_lines()
+        .iter()
+        .find_map(|line| line.strip_prefix("Peak Memory: "))
+        .and_then(|line| line.strip_suffix(" bytes"))
+        .and_then(|bytes| bytes.parse::<usize>().ok())
+        .expect("EXPLAIN ANALYZE reports peak memory");
+    assert!(
+        peak > 200 * 199 / 2 * 200,
+        "peak {peak} is below the join output"
+    );
+    assert!(explain
+        .explain_lines()
+        .iter()
+        .any(|line| line.starts_with("Memory: sort=")));
+
+    db.execute("PRAGMA statement_memory_limit_bytes = 1048576")?;
+    let error = db.execute(join).expect_err("join exceeds the limit");
+    assert!(error.is_memory_limit_exceeded(), "{error:?}");
+    assert_eq!(db.execute("SELECT COUNT(*) FROM t")?.rows().len(), 1);
+
+    db.execute("PRAGMA statement_memory_limit_bytes = DEFAULT")?;
+    assert_eq!(db.execute(join)?.rows().len(), 200 * 199 / 2);
+    Ok(())
+}
+
+#[test]
+fn interrupt_cancels_the_running_statement_only() -> Result<()> {
+    let db = Db::open_or_create(":memory:", DbConfig::default())?;
+    db.execute("CREATE TABLE t (id INTEGER PRIMARY KEY)")?;
+    db.execute("INSERT INTO t SELECT value FROM generate_series(1, 3000)")?;
+
+    db.interrupt();
+    assert_eq!(db.execute("SELECT COUNT(*) FROM t")?.rows().len(), 1);
+
+    let done = Arc::new(AtomicBool::new(false));
+    let interrupter = {
+        let db = db.clone();
+        let done = Arc::clone(&done);
+        thread::spawn(move || {
+            while !done.load(AtomicOrdering::Acquire) {
+                db.interrupt();
+                thread::sleep(Duration::from_millis(5));
+            }
+        })
+    };
+    let result = db.execute("SELECT COUNT(*) FROM t a JOIN t b ON a.id + b.id < 0");
+    done.store(true, AtomicOrdering::Release);
+    interrupter.join().expect("interrupter thread");
+    let error = result.expect_err("statement is interrupted");
+    assert_eq!(
+        error.code(),
+        crate::error::DbErrorCode::Canceled,
+        "{error:?}"
+    );
+
+    assert_eq!(db.execute("SELECT COUNT(*) FROM t")?.rows().len(), 1);
+    Ok(())
+}
+
+#[test]
+fn max_result_rows_aborts_queries_but_not_writes() -> Result<()> {
+    let config = DbConfig {
+        max_result_rows: 3,
+        ..DbConfig::default()
+    };
+    let db = Db::open_or_create(":memory:", config)?;
+    db.execute("CREATE TABLE t (id INTEGER PRIMARY KEY)")?;
+    let inserted =
+        db.execute("INSERT INTO t SELECT value FROM generate_series(1, 5) RETURNING id")?;
+    assert_eq!(inserted.rows().len(), 5);
+
+    let error = db
+        .execute("SELECT id FROM t ORDER BY id")
+        .expect_err("result is over the limit");
+    assert!(error.is_result_row_limit_exceeded(), "{error:?}");
+    let prepared = db.prepare("SELECT id FROM t WHERE id > $1")?;
+    assert!(prepared
+        .execute(&[Value::Int64(0)])
+        .expect_err("result is over the limit")
+        .is_result_row_limit_exceeded());
+    assert_eq!(prepared.execute(&[Value::Int64(2)])?.rows().len(), 3);
+    assert_eq!(db.execute("SELECT COUNT(*) FROM t")?.rows().len(), 1);
+
+    db.execute("PRAGMA max_result_rows = 0")?;
+    assert_eq!(db.execute("SELECT id FROM t")?.rows().len(), 5);
+    db.execute("PRAGMA max_result_rows = DEFAULT")?;
+    assert_eq!(
+        db.execute("PRAGMA max_result_rows")?.rows()[0].values(),
+        &[Value::Int64(3)]
+    );
+    Ok(())
+}
+
+#[test]
+fn max_statement_seconds_aborts_long_statements() -> Result<()> {
+    let db = Db::open_or_create(":memory:", DbConfig::default())?;
+    db.execute("CREATE TABLE t (id INTEGER PRIMARY KEY)")?;
+    db.execute("INSERT INTO t SELECT value FROM generate_series(1, 5000)")?;
+    db.execute("PRAGMA max_statement_seconds = 1")?;
+
+    let started = Instant::now();
+    let error = db
+        .execute("SELECT COUNT(*) FROM t a JOIN t b ON a.id + b.id < 0")
+        .expect_err("statement times out");
+    assert!(error.is_statement_timeout(), "{error:?}");
+    assert_eq!(error.code(), crate::error::DbErrorCode::Timeout);
+    assert!(started.elapsed() < Duration::from_secs(30));
+
+    assert_eq!(db.execute("SELECT COUNT(*) FROM t")?.rows().len(), 1);
+    Ok(())
+}
+
+#[test]
+fn wal_archive_hands_out_segments_before_truncation() -> Result<()> {
+    let dir = TempDir::new().expect("tempdir");
+    let db = Db::open_or_create(dir.path().join("archive.ddb"), DbConfig::default())?;
+    db.enable_wal_archive(1)?;
+    db.execute("CREATE TABLE t (id INTEGER PRIMARY KEY)")?;
+    db.checkpoint()?;
+    db.checkpoint()?;
+    db.execute("INSERT INTO t VALUES (1)")?;
+    let wal_len_before = db.inner.wal.latest_snapshot();
+    db.checkpoint()?;
+    assert_eq!(
+        db.inner.wal.latest_snapshot(),
+        wal_len_before,
+        "a full queue keeps the WAL"
+    );
+
+    let first = db.next_wal_segment()?.expect("first segment");
+    assert_eq!(first.sequence, 1);
+    assert_eq!(first.start_lsn, 32);
+    assert_eq!(first.frames.len() as u64, first.end_lsn);
+    assert_eq!(first.page_size, db.config().page_size);
+    assert!(
+        db.next_wal_segment()?.is_none(),
+        "empty WAL is not archived"
+    );
+
+    db.checkpoint()?;
+    let second = db.next_wal_segment()?.expect("second segment");
+    assert_eq!(second.sequence, 2);
+    assert!(second.end_lsn > wal_len_before);
+
+    db.disable_wal_archive()?;
+    db.execute("INSERT INTO t VALUES (2)")?;
+    db.checkpoint()?;
+    assert!(db.next_wal_segment()?.is_none());
+    Ok(())
+}
+
+#[test]
+fn backup_reader_streams_a_consistent_database_file() -> Result<()> {
+    use std::io::Read;
+
+    let dir = TempDir::new().expect("tempdir");
+    let db = Db::open_or_create(dir.path().join("source.ddb"), DbConfig::default())?;
+    db.execute("CREATE TABLE t (id INTEGER PRIMARY KEY, body TEXT)")?;
+    db.execute("INSERT INTO t SELECT value, repeat('x', 100) FROM generate_series(1, 300)")?;
+
+    let mut backup = db.backup_reader()?;
+    db.execute("INSERT INTO t VALUES (1000, 'after the backup started')")?;
+    let mut bytes = Vec::new();
+    backup.read_to_end(&mut bytes).expect("read backup stream");
+    assert_eq!(bytes.len() as u64, backup.len());
+    drop(backup);
+
+    let restored_path = dir.path().join("restored.ddb");
+    std::fs::write(&restored_path, &bytes).expect("write restored database");
+    let restored = Db::open(&restored_path, DbConfig::default())?;
+    let result = restored.execute("SELECT COUNT(*), MAX(id) FROM t")?;
+    assert_eq!(
+        result.rows()[0].values(),
+        &[Value::Int64(300), Value::Int64(300)]
+    );
+    Ok(())
+}
+
+#[test]
+fn compressed_backup_chunks_restore_through_restore_writer() -> Result<()> {
+    use sha2::{Digest, Sha256};
+
+    let dir = TempDir::new().expect("tempdir");
+    let db = Db::open_or_create(dir.path().join("source.ddb"), DbConfig::default())?;
+    db.execute("CREATE TABLE t (id INTEGER PRIMARY KEY, body TEXT)")?;
+    db.execute("INSERT INTO t SELECT value, repeat('x', 100) FROM generate_series(1, 300)")?;
+
+    const CHUNK: usize = 16 * 1024;
+    let restored_path = dir.path().join("restored.ddb");
+    let mut backup = db.backup_reader()?;
+    let mut restore = RestoreWriter::create(&restored_path)?;
+    let (mut raw, mut out) = (vec![0; CHUNK], vec![0; CHUNK * 2]);
+    let mut plain = Vec::new();
+    loop {
+        let (read, written) = backup.read_compressed_chunk(&mut raw, 0, &mut out)?;
+        if read == 0 {
+            break;
+        }
+        assert!(written < read, "pages of repeated text compress");
+        plain.extend_from_slice(&raw[..read]);
+        assert!(restore.write_compressed(&out[..written], read - 1).is_err());
+        assert_eq!(restore.write_compressed(&out[..written], CHUNK)?, read);
+    }
+    assert!(restore.write_compressed(b"not zstd", CHUNK).is_err());
+    drop(backup);
+    let (len, digest) = restore.finish()?;
+    assert_eq!(len, plain.len() as u64);
+    assert_eq!(digest, <[u8; 32]>::from(Sha256::digest(&plain)));
+
+    let restored = Db::open(&restored_path, DbConfig::default())?;
+    let result = restored.execute("SELECT COUNT(*) FROM t")?;
+    assert_eq!(result.rows()[0].values(), &[Value::Int64(300)]);
+
+    // An unfinished restore leaves nothing behind.
+    let abandoned = dir.path().join("abandoned.ddb");
+    RestoreWriter::create(&abandoned)?.write(b"partial")?;
+    assert!(!abandoned.exists());
+    Ok(())
+}
+
+#[test]
+fn tablesample_returns_a_repeatable_subset() -> Result<()> {
+    let dir = TempDir::new().expect("tempdir");
+    let db = Db::open_or_create(dir.path().join("tablesample.ddb"), DbConfig::default())?;
+    db.execute("CREATE TABLE t (id INTEGER PRIMARY KEY, v INTEGER)")?;
+    db.execute("INSERT INTO t SELECT value, value % 10 FROM generate_series(1, 20000)")?;
+    let count = |sql: &str| -> Result<i64> {
+        match db.execute(sql)?.rows()[0].values() {
+            [Value::Int64(count)] => Ok(*count),
+            other => panic!("unexpected count {other:?}"),
+        }
+    };
+
+    let sampled = count("SELECT COUNT(*) FROM t TABLESAMPLE BERNOULLI (10) REPEATABLE (7)")?;
+    assert!((1500..2500).contains(&sampled), "{sampled}");
+    assert_eq!(
+        count("SELECT COUNT(*) FROM t TABLESAMPLE BERNOULLI (10) REPEATABLE (7)")?,
+        sampled
     );
-    db.execute("PRAGMA busy_timeout = 1250")?;
     assert_eq!(
-        db.execute("PRAGMA busy_timeout")?.rows()[0].values(),
-        &[Value::Int64(1_250)]
+        db.execute("SELECT id FROM t AS s TABLESAMPLE SYSTEM (5) REPEATABLE (1) ORDER BY s.id")?
+            .rows(),
+        db.execute("SELECT id FROM t TABLESAMPLE SYSTEM (5) REPEATABLE (1) ORDER BY id")?
+            .rows()
     );
     assert_eq!(
-        db.execute("PRAGMA journal_mode = WAL")?.rows()[0].values(),
-        &[Value::Text("wal".to_string())]
+        count("SELECT COUNT(*) FROM t TABLESAMPLE SYSTEM (100)")?,
+        20000
     );
+    assert_eq!(
+        count("SELECT COUNT(*) FROM t TABLESAMPLE BERNOULLI (0)")?,
+        0
+    );
+    let filtered = db.execute_with_params(
+        "SELECT COUNT(*) FROM t TABLESAMPLE BERNOULLI ($1) REPEATABLE (3) WHERE v = 0",
+        &[Value::Int64(50)],
+    )?;
+    let [Value::Int64(filtered)] = filtered.rows()[0].values() else {
+        panic!("unexpected count");
+    };
+    assert!((700..1300).contains(filtered), "{filtered}");
+
+    for (sql, message) in [
+        (
+            "SELECT * FROM t TABLESAMPLE BERNOULLI (101)",
+            "between 0 and 100",
+        ),
+        (
+            "SELECT * FROM t TABLESAMPLE BERNOULLI (NULL)",
+            "cannot be null",
+        ),
+        (
+            "SELECT * FROM t TABLESAMPLE reservoir (10)",
+            "does not exist",
+        ),
+    ] {
+        let err = db.execute(sql).expect_err(sql);
+        assert!(err.to_string().contains(message), "{sql}: {err}");
+    }
     Ok(())
 }
 
@@ -1802,13 +2703,93 @@ fn main_and_temp_schema_qualified_names_are_narrowly_supported() -> Result<()> {
     db.execute("DROP TABLE temp.shadow")?;
 
     db.execute("CREATE SCHEMA app")?;
-    let schema_error = db
-        .execute("SELECT * FROM app.shadow")
-        .expect_err("registered schema object lookup should fail");
-    assert!(schema_error.to_string().contains("schema 'app'"));
-    assert!(schema_error
+    db.execute("CREATE TABLE app.shadow(id INT PRIMARY KEY)")?;
+    db.execute("INSERT INTO app.shadow VALUES (7)")?;
+    assert_eq!(
+        db.execute("SELECT shadow.id FROM app.shadow")?.rows()[0].values(),
+        &[Value::Int64(7)]
+    );
+    let missing = db
+        .execute("CREATE TABLE nowhere.shadow(id INT)")
+        .expect_err("unknown schema should be rejected");
+    assert!(missing
+        .to_string()
+        .contains("schema nowhere does not exist"));
+    Ok(())
+}
+
+#[test]
+fn search_path_resolves_unqualified_names_per_handle() -> Result<()> {
+    let db = Db::open_or_create(":memory:", DbConfig::default())?;
+    db.execute("CREATE SCHEMA tenant_a")?;
+    db.execute("CREATE SCHEMA tenant_b")?;
+    db.execute("CREATE TABLE plans(name TEXT)")?;
+    db.execute("INSERT INTO plans VALUES ('basic')")?;
+
+    for (schema, total) in [("tenant_a", 10), ("tenant_b", 20)] {
+        db.execute(&format!("SET search_path TO {schema}"))?;
+        db.execute("CREATE TABLE orders(id INT PRIMARY KEY, total INT)")?;
+        db.execute("CREATE INDEX orders_total_idx ON orders(total)")?;
+        db.execute(&format!("INSERT INTO orders VALUES (1, {total})"))?;
+    }
+
+    db.execute("SET search_path TO tenant_a, public")?;
+    assert_eq!(
+        db.execute("SHOW search_path")?.rows()[0].values(),
+        &[Value::Text("tenant_a, public".to_string())]
+    );
+    assert_eq!(
+        db.execute("SELECT orders.total FROM orders WHERE id = 1")?
+            .rows()[0]
+            .values(),
+        &[Value::Int64(10)]
+    );
+    assert_eq!(
+        db.execute("SELECT name FROM plans")?.rows()[0].values(),
+        &[Value::Text("basic".to_string())]
+    );
+    let prepared = db.prepare("SELECT total FROM orders WHERE id = $1")?;
+    assert_eq!(
+        prepared.execute(&[Value::Int64(1)])?.rows()[0].values(),
+        &[Value::Int64(10)]
+    );
+    assert_eq!(
+        db.execute("SELECT total FROM tenant_b.orders")?.rows()[0].values(),
+        &[Value::Int64(20)]
+    );
+    db.execute("DROP INDEX orders_total_idx")?;
+    db.execute("CREATE INDEX orders_total_idx ON orders(total)")?;
+
+    db.execute("RESET search_path")?;
+    assert_eq!(
+        db.execute("SHOW search_path")?.rows()[0].values(),
+        &[Value::Text("main".to_string())]
+    );
+    assert!(db.execute("SELECT * FROM orders").is_err());
+    let tables = db.execute(
+        "SELECT table_schema, table_name FROM information_schema.tables \
+         WHERE table_name = 'orders' ORDER BY table_schema",
+    )?;
+    assert_eq!(tables.rows().len(), 2);
+    assert_eq!(
+        tables.rows()[1].values(),
+        &[
+            Value::Text("tenant_b".to_string()),
+            Value::Text("orders".to_string())
+        ]
+    );
+
+    let unknown = db
+        .execute("SET search_path TO tenant_c")
+        .expect_err("unknown schema should be rejected");
+    assert!(unknown
         .to_string()
-        .contains("advanced compatibility work"));
+        .contains("schema tenant_c does not exist"));
+
+    let dump = db.dump_sql()?;
+    assert!(dump.contains("CREATE SCHEMA IF NOT EXISTS \"tenant_a\";"));
+    assert!(dump.contains("CREATE TABLE \"tenant_b\".\"orders\""));
+    assert!(dump.contains("CREATE INDEX \"orders_total_idx\" ON \"tenant_a\".\"orders\""));
     Ok(())
 }
 
@@ -1847,11 +2828,12 @@ fn query_time_collations_and_scalar_compatibility_helpers_work() -> Result<()> {
         .expect_err("unsupported collation should fail")
         .to_string()
         .contains("unsupported collation"));
+    db.execute("CREATE INDEX names_nocase_idx ON names(name COLLATE NOCASE)")?;
     assert!(db
-        .execute("CREATE INDEX names_nocase_idx ON names(name COLLATE NOCASE)")
-        .expect_err("persistent non-binary index collation should fail")
+        .execute("CREATE INDEX names_unicode_idx ON names(name COLLATE unicode)")
+        .expect_err("extension index collation should fail")
         .to_string()
-        .contains("persistent index collations"));
+        .contains("cannot be stored on a column or index"));
     assert!(db
         .execute("SELECT DISTINCT name COLLATE NOCASE FROM names")
         .expect_err("collated distinct should fail clearly")
@@ -13872,6 +14854,9 @@ fn describe_query_contract_infers_insert_returning_contracts() {
         .expect("describe insert returning");
     assert_eq!(contract.statement_kind, "insert");
     assert!(!contract.read_only);
+    assert_eq!(contract.target_table.as_deref(), Some("users"));
+    assert_eq!(contract.referenced_tables, vec!["users".to_string()]);
+    assert!(contract.referenced_tables_complete);
     assert_eq!(contract.parameters.len(), 2);
     assert_eq!(contract.parameters[0].type_name.as_deref(), Some("INT64"));
     assert_eq!(
@@ -13901,6 +14886,150 @@ fn describe_query_contract_infers_insert_returning_contracts() {
     );
 }
 
+#[test]
+fn describe_query_contract_unifies_set_operation_column_types() {
+    let db = Db::open_or_create(":memory:", DbConfig::default()).expect("open db");
+    db.execute(
+        "CREATE TABLE prices (id INT64 PRIMARY KEY, qty INT64 NOT NULL, price DECIMAL(10,2), rate FLOAT64)",
+    )
+    .expect("create prices");
+
+    let contract = db
+        .describe_query_contract(
+            "SELECT id, qty, price FROM prices UNION SELECT id, rate, qty FROM prices",
+        )
+        .expect("describe union");
+    let types = contract
+        .result_columns
+        .iter()
+        .map(|column| column.type_name.as_deref())
+        .collect::<Vec<_>>();
+    assert_eq!(types, vec![Some("INT64"), Some("FLOAT64"), Some("DECIMAL")]);
+    assert_eq!(contract.result_columns[0].nullable, Some(false));
+    assert_eq!(
+        contract.result_columns[0].source_column.as_deref(),
+        Some("id")
+    );
+    assert_eq!(contract.result_columns[1].nullable, Some(true));
+    assert_eq!(contract.result_columns[1].source_column, None);
+    assert_eq!(contract.result_columns[2].source_column, None);
+}
+
+#[test]
+fn describe_query_contract_marks_subtotal_columns_nullable() {
+    let db = Db::open_or_create(":memory:", DbConfig::default()).expect("open db");
+    db.execute(
+        "CREATE TABLE sales (id INT64 PRIMARY KEY, region TEXT NOT NULL, amount INT64 NOT NULL)",
+    )
+    .expect("create sales");
+
+    let contract = db
+        .describe_query_contract(
+            "SELECT region, GROUPING(region) AS level, COUNT(*) AS n FROM sales GROUP BY ROLLUP (region)",
+        )
+        .expect("describe rollup");
+    let types = contract
+        .result_columns
+        .iter()
+        .map(|column| (column.type_name.as_deref(), column.nullable))
+        .collect::<Vec<_>>();
+    assert_eq!(
+        types,
+        vec![
+            (Some("TEXT"), Some(true)),
+            (Some("INT64"), Some(false)),
+            (Some("INT64"), Some(false)),
+        ]
+    );
+
+    let contract = db
+        .describe_query_contract("SELECT region, COUNT(*) FROM sales GROUP BY region")
+        .expect("describe group by");
+    assert_eq!(contract.result_columns[0].nullable, Some(false));
+}
+
+#[test]
+fn describe_query_contract_reports_referenced_tables() {
+    let db = Db::open_or_create(":memory:", DbConfig::default()).expect("open db");
+    db.execute("CREATE TABLE users (id INT64 PRIMARY KEY, name TEXT)")
+        .expect("create users");
+    db.execute("CREATE TABLE orders (id INT64 PRIMARY KEY, user_id INT64)")
+        .expect("create orders");
+
+    let contract = db
+        .describe_query_contract(
+            "SELECT u.name FROM users u JOIN orders o ON o.user_id = u.id WHERE o.id = $1",
+        )
+        .expect("describe join");
+    assert_eq!(
+        contract.referenced_tables,
+        vec!["orders".to_string(), "users".to_string()]
+    );
+    assert!(contract.referenced_tables_complete);
+    assert_eq!(contract.target_table, None);
+
+    let contract = db
+        .describe_query_contract("DELETE FROM orders WHERE user_id = $1")
+        .expect("describe delete");
+    assert_eq!(contract.target_table.as_deref(), Some("orders"));
+    assert_eq!(contract.referenced_tables, vec!["orders".to_string()]);
+
+    let contract = db
+        .describe_query_contract("CREATE INDEX orders_user ON orders (user_id)")
+        .expect("describe ddl");
+    assert!(!contract.referenced_tables_complete);
+    assert!(contract.referenced_tables.is_empty());
+    assert_eq!(contract.object_kind.as_deref(), Some("index"));
+    assert_eq!(contract.object_name.as_deref(), Some("orders_user"));
+
+    let contract = db
+        .describe_query_contract("ALTER TABLE orders ADD COLUMN note TEXT")
+        .expect("describe alter");
+    assert_eq!(contract.object_kind.as_deref(), Some("table"));
+    assert_eq!(contract.object_name.as_deref(), Some("orders"));
+    assert_eq!(contract.target_table, None);
+    let contract = db
+        .describe_query_contract("DELETE FROM orders")
+        .expect("describe delete");
+    assert_eq!(contract.object_kind, None);
+}
+
+#[test]
+fn describe_query_contract_expands_views_into_dependency_tables() {
+    let db = Db::open_or_create(":memory:", DbConfig::default()).expect("open db");
+    db.execute("CREATE TABLE users (id INT64 PRIMARY KEY, name TEXT)")
+        .expect("create users");
+    db.execute("CREATE TABLE orders (id INT64 PRIMARY KEY, user_id INT64)")
+        .expect("create orders");
+    db.execute(
+        "CREATE VIEW buyers AS SELECT u.name FROM users u JOIN orders o ON o.user_id = u.id",
+    )
+    .expect("create view");
+    db.execute("CREATE VIEW buyer_names AS SELECT name FROM buyers")
+        .expect("create nested view");
+
+    let contract = db
+        .describe_query_contract("SELECT name FROM buyer_names")
+        .expect("describe view query");
+    assert_eq!(contract.referenced_tables, vec!["buyer_names".to_string()]);
+    assert_eq!(
+        contract.dependency_tables,
+        Some(vec!["orders".to_string(), "users".to_string()])
+    );
+
+    let contract = db
+        .describe_query_contract("DELETE FROM orders WHERE id = $1")
+        .expect("describe delete");
+    assert_eq!(contract.dependency_tables, None);
+
+    db.execute("CREATE TEMP TABLE scratch (id INT64)")
+        .expect("create temp table");
+    let contract = db
+        .describe_query_contract("SELECT id FROM scratch")
+        .expect("describe temp query");
+    assert_eq!(contract.dependency_tables, None);
+}
+
 #[test]
 fn write_transaction_page_allocation_stays_off_main_file_until_commit() {
     let tempdir = TempDir::new().expect("tempdir");
@@ -18095,6 +19224,148 @@ fn process_coordination_refreshes_independent_wal_handle() -> Result<()> {
     Ok(())
 }
 
+#[test]
+fn exclusive_process_coordination_excludes_other_handles() -> Result<()> {
+    let temp = TempDir::new().expect("tempdir");
+    let path = temp.path().join("exclusive.ddb");
+    let exclusive = DbConfig {
+        process_coordination: ProcessCoordinationMode::Exclusive,
+        process_coordination_timeout_ms: 0,
+        ..DbConfig::default()
+    };
+    let shared = DbConfig {
+        process_coordination_timeout_ms: 0,
+        ..DbConfig::default()
+    };
+
+    let db = Db::open_or_create(&path, exclusive.clone())?;
+    db.execute("CREATE TABLE t (id INTEGER PRIMARY KEY)")?;
+    for config in [exclusive.clone(), shared.clone()] {
+        let err = Db::open_or_create(&path, config).expect_err("exclusive handle is open");
+        assert!(
+            matches!(err, DbError::Timeout { .. }),
+            "unexpected error: {err}"
+        );
+    }
+    drop(db);
+
+    let db = Db::open_or_create(&path, shared.clone())?;
+    let err = Db::open_or_create(&path, exclusive.clone()).expect_err("shared handle is open");
+    assert!(
+        matches!(err, DbError::Timeout { .. }),
+        "unexpected error: {err}"
+    );
+    let second = Db::open_or_create(&path, shared)?;
+    drop(second);
+    drop(db);
+
+    let db = Db::open_or_create(&path, exclusive)?;
+    db.execute("INSERT INTO t VALUES (1)")?;
+    Ok(())
+}
+
+#[test]
+fn file_temp_store_keeps_temp_rows_in_temp_dir() -> Result<()> {
+    let temp = TempDir::new().expect("tempdir");
+    let spill_dir = TempDir::new().expect("spill dir");
+    let spill_files = || {
+        std::fs::read_dir(spill_dir.path())
+            .expect("read spill dir")
+            .count()
+    };
+    let notes = |db: &Db| -> Result<Vec<Value>> {
+        Ok(db
+            .execute("SELECT note FROM scratch ORDER BY id")?
+            .rows()
+            .iter()
+            .map(|row| row.values()[0].clone())
+            .collect())
+    };
+
+    let memory = Db::open_or_create(
+        temp.path().join("memory.ddb"),
+        DbConfig {
+            temp_dir: spill_dir.path().to_path_buf(),
+            ..DbConfig::default()
+        },
+    )?;
+    memory.execute("CREATE TEMP TABLE scratch (id INT PRIMARY KEY, note TEXT)")?;
+    memory.execute("INSERT INTO scratch VALUES (1, 'a')")?;
+    assert_eq!(spill_files(), 0);
+    drop(memory);
+
+    let db = Db::open_or_create(
+        temp.path().join("file.ddb"),
+        DbConfig {
+            temp_store: TempStore::File,
+            temp_dir: spill_dir.path().to_path_buf(),
+            ..DbConfig::default()
+        },
+    )?;
+    db.execute("CREATE TEMP TABLE scratch (id INT PRIMARY KEY, note TEXT)")?;
+    db.execute("INSERT INTO scratch VALUES (1, 'a'), (2, 'b')")?;
+    assert_eq!(spill_files(), 1);
+    assert!(db.inner.engine.read().unwrap().temp_table_data.is_empty());
+    assert_eq!(
+        notes(&db)?,
+        vec![Value::Text("a".to_string()), Value::Text("b".to_string())]
+    );
+
+    db.begin_transaction()?;
+    db.execute("INSERT INTO scratch VALUES (3, 'c')")?;
+    db.execute("UPDATE scratch SET note = 'z' WHERE id = 1")?;
+    db.rollback_transaction()?;
+    db.execute("DELETE FROM scratch WHERE id = 2")?;
+    assert_eq!(notes(&db)?, vec![Value::Text("a".to_string())]);
+
+    let scratch_file = std::fs::read_dir(spill_dir.path())
+        .expect("read spill dir")
+        .next()
+        .expect("scratch file")
+        .expect("spill entry")
+        .path();
+    db.execute("CREATE TEMP TABLE other (id INT)")?;
+    db.execute("INSERT INTO other VALUES (1)")?;
+    assert_eq!(spill_files(), 2);
+
+    // Statements that do not name scratch never read its file back.
+    std::fs::write(&scratch_file, b"not a row file").expect("damage scratch file");
+    db.execute("INSERT INTO other VALUES (2)")?;
+    assert_eq!(
+        db.execute("SELECT COUNT(*) FROM other")?.rows()[0].values(),
+        &[Value::Int64(2)]
+    );
+    db.execute("SELECT note FROM scratch")
+        .expect_err("scratch is read back when named");
+
+    db.execute("DROP TABLE other")?;
+    assert_eq!(spill_files(), 1);
+    drop(db);
+    assert_eq!(spill_files(), 0);
+    Ok(())
+}
+
+#[test]
+fn file_temp_store_refuses_encryption_as_a_configuration_error() {
+    let temp = TempDir::new().expect("tempdir");
+    let path = temp.path().join("encrypted.ddb");
+    let error = Db::open_or_create(
+        &path,
+        DbConfig {
+            temp_store: TempStore::File,
+            encryption: Some(
+                crate::config::DbEncryptionConfig::from_key_bytes(b"temp-key").expect("valid key"),
+            ),
+            ..DbConfig::default()
+        },
+    )
+    .expect_err("file temp store on an encrypted database");
+    let diagnostic = error.diagnostic();
+    assert_eq!(diagnostic.subcode, "config.invalid");
+    assert_eq!(diagnostic.sqlstate, Some("22023"));
+    assert!(!path.exists(), "no database file is created");
+}
+
 #[test]
 fn open_repairs_current_header_with_empty_coordination_identity() -> Result<()> {
     let temp = TempDir::new().expect("tempdir");
@@ -18274,6 +19545,29 @@ fn plan_cache_ddl_invalidates_eagerly() {
     assert_eq!(after.total_entries, 0);
 }
 
+#[test]
+fn plan_cache_shares_entries_across_formatting_and_drops_them_on_analyze() {
+    let temp = TempDir::new().expect("tempdir");
+    let path = temp.path().join("plan_cache_normalized.ddb");
+    let db = Db::create(&path, DbConfig::default()).expect("create db");
+    db.execute("CREATE TABLE t (id INTEGER PRIMARY KEY, val TEXT)")
+        .expect("create table");
+
+    let _ = db
+        .prepare("SELECT val FROM t WHERE id = $1")
+        .expect("prepare");
+    let before = db.plan_cache_summary().expect("summary");
+    let _ = db
+        .prepare("SELECT val\n  FROM t /* orm */\n WHERE id = $1 -- lookup")
+        .expect("prepare reformatted");
+    let after = db.plan_cache_summary().expect("summary");
+    assert_eq!(after.total_hits, before.total_hits + 1);
+    assert_eq!(after.total_entries, before.total_entries);
+
+    db.execute("ANALYZE t").expect("analyze");
+    assert_eq!(db.plan_cache_summary().expect("summary").total_entries, 0);
+}
+
 #[test]
 fn plan_cache_audit_context_does_not_evict() {
     let temp = TempDir::new().expect("tempdir");
@@ -18519,3 +19813,88 @@ fn resident_delete_rollback_restores_rows() {
         "rolled-back delete must not persist"
     );
 }
+
+#[test]
+fn open_session_shares_page_cache_with_independent_transactions() {
+    let tempdir = TempDir::new().expect("tempdir");
+    let path = tempdir.path().join("sessions.ddb");
+    let db = Db::open_or_create(&path, DbConfig::default()).expect("create db");
+    db.execute("CREATE TABLE t (id INTEGER PRIMARY KEY, v TEXT NOT NULL)")
+        .expect("create table");
+    db.execute("INSERT INTO t SELECT value, 'row' FROM generate_series(1, 200)")
+        .expect("insert");
+    db.checkpoint().expect("checkpoint");
+
+    let session = db.open_session().expect("open session");
+    assert_eq!(db.page_cache_stats().expect("stats").sharing_handles, 2);
+
+    session.begin_transaction().expect("begin");
+    session
+        .execute("DELETE FROM t WHERE id <= 100")
+        .expect("delete in session");
+    assert_eq!(
+        scalar_i64(&db.execute("SELECT COUNT(*) FROM t").expect("count")),
+        200,
+        "another session's open transaction must stay invisible"
+    );
+    session.commit_transaction().expect("commit session");
+    assert_eq!(
+        scalar_i64(&db.execute("SELECT COUNT(*) FROM t").expect("count")),
+        100
+    );
+
+    let before = session.page_cache_stats().expect("stats");
+    assert!(before.hits + before.misses > 0);
+    drop(db);
+    assert_eq!(
+        scalar_i64(&session.execute("SELECT COUNT(*) FROM t").expect("count")),
+        100,
+        "a session outlives the handle it was opened from"
+    );
+    assert_eq!(
+        session.page_cache_stats().expect("stats").sharing_handles,
+        1
+    );
+}
+
+#[test]
+fn close_options_checkpoint_and_remove_wal() {
+    let tempdir = TempDir::new().expect("tempdir");
+    let path = tempdir.path().join("close.ddb");
+    let wal_path = tempdir.path().join("close.ddb.wal");
+    let config = DbConfig {
+        wal_checkpoint_threshold_pages: 0,
+        wal_checkpoint_threshold_bytes: 0,
+        background_checkpoint_worker: false,
+        checkpoint_on_close: true,
+        ..DbConfig::default()
+    };
+
+    let db = Db::open_or_create(&path, config.clone()).expect("create db");
+    db.execute("CREATE TABLE t (id INTEGER PRIMARY KEY)")
+        .expect("create table");
+    db.execute("INSERT INTO t SELECT value FROM generate_series(1, 100)")
+        .expect("insert");
+    assert!(std::fs::metadata(&wal_path).expect("wal").len() > 0);
+    drop(db);
+    let header_only = std::fs::metadata(&wal_path).expect("wal kept").len();
+
+    let db = Db::open_or_create(
+        &path,
+        DbConfig {
+            truncate_wal_on_close: true,
+            ..config
+        },
+    )
+    .expect("reopen");
+    db.execute("INSERT INTO t VALUES (101)").expect("insert");
+    assert!(std::fs::metadata(&wal_path).expect("wal").len() > header_only);
+    drop(db);
+    assert!(!wal_path.exists(), "truncate_wal_on_close leaves no WAL");
+
+    let db = Db::open(&path, DbConfig::default()).expect("open single file");
+    assert_eq!(
+        scalar_i64(&db.execute("SELECT COUNT(*) FROM t").expect("count")),
+        101
+    );
+}
@@ -24,6 +24,9 @@ const _PHASE1_SUBCODES: &[&str] = &[
     SUBCODE_SQL_PARAMETER_MISSING,
     SUBCODE_SQL_PARAMETER_TYPE_MISMATCH,
     SUBCODE_SQL_UNSUPPORTED_FEATURE,
+    SUBCODE_SQL_MEMORY_LIMIT_EXCEEDED,
+    SUBCODE_SQL_RESULT_ROW_LIMIT_EXCEEDED,
+    SUBCODE_SQL_STATEMENT_TIMEOUT,
     SUBCODE_CONSTRAINT_UNKNOWN,
     SUBCODE_CONSTRAINT_UNIQUE,
     SUBCODE_CONSTRAINT_NOT_NULL,
@@ -32,6 +35,8 @@ const _PHASE1_SUBCODES: &[&str] = &[
     SUBCODE_TRANSACTION_UNKNOWN,
     SUBCODE_TRANSACTION_NO_ACTIVE,
     SUBCODE_TRANSACTION_INVALID_STATE,
+    SUBCODE_TRANSACTION_CONFLICT,
+    SUBCODE_TRANSACTION_DDL_NOT_TRANSACTIONAL,
     SUBCODE_QUEUE_WRITE_TIMEOUT,
     SUBCODE_QUEUE_CANCELED,
     SUBCODE_QUEUE_FULL,
@@ -44,6 +49,7 @@ const _PHASE1_SUBCODES: &[&str] = &[
     SUBCODE_IO_UNKNOWN,
     SUBCODE_IO_PERMISSION_DENIED,
     SUBCODE_IO_DISK_FULL,
+    SUBCODE_IO_QUOTA_EXCEEDED,
     SUBCODE_IO_NOT_FOUND,
     SUBCODE_FORMAT_UNSUPPORTED_VERSION,
     SUBCODE_CORRUPTION_UNKNOWN,
@@ -61,6 +67,7 @@ const _PHASE1_SUBCODES: &[&str] = &[
     SUBCODE_BRANCH_NOT_FOUND,
     SUBCODE_BRANCH_MERGE_CONFLICT,
     SUBCODE_EXTENSION_UNTRUSTED_PACKAGE,
+    SUBCODE_CONFIG_INVALID,
     SUBCODE_INTERNAL_UNKNOWN,
     SUBCODE_INTERNAL_PANIC_CAPTURED,
     SUBCODE_INTERNAL_INVARIANT,
@@ -78,6 +85,9 @@ pub const SUBCODE_SQL_AMBIGUOUS_COLUMN: &str = "sql.ambiguous_column";
 pub const SUBCODE_SQL_PARAMETER_MISSING: &str = "sql.parameter_missing";
 pub const SUBCODE_SQL_PARAMETER_TYPE_MISMATCH: &str = "sql.parameter_type_mismatch";
 pub const SUBCODE_SQL_UNSUPPORTED_FEATURE: &str = "sql.unsupported_feature";
+pub const SUBCODE_SQL_MEMORY_LIMIT_EXCEEDED: &str = "sql.memory_limit_exceeded";
+pub const SUBCODE_SQL_RESULT_ROW_LIMIT_EXCEEDED: &str = "sql.result_row_limit_exceeded";
+pub const SUBCODE_SQL_STATEMENT_TIMEOUT: &str = "sql.statement_timeout";
 pub const SUBCODE_CONSTRAINT_UNKNOWN: &str = "constraint.unknown";
 pub const SUBCODE_CONSTRAINT_UNIQUE: &str = "constraint.unique";
 pub const SUBCODE_CONSTRAINT_NOT_NULL: &str = "constraint.not_null";
@@ -86,6 +96,8 @@ pub const SUBCODE_CONSTRAINT_FOREIGN_KEY: &str = "constraint.foreign_key";
 pub const SUBCODE_TRANSACTION_UNKNOWN: &str = "transaction.unknown";
 pub const SUBCODE_TRANSACTION_NO_ACTIVE: &str = "transaction.no_active_transaction";
 pub const SUBCODE_TRANSACTION_INVALID_STATE: &str = "transaction.invalid_state";
+pub const SUBCODE_TRANSACTION_CONFLICT: &str = "transaction.conflict";
+pub const SUBCODE_TRANSACTION_DDL_NOT_TRANSACTIONAL: &str = "transaction.ddl_not_transactional";
 pub const SUBCODE_QUEUE_WRITE_TIMEOUT: &str = "queue.write_timeout";
 pub const SUBCODE_QUEUE_CANCELED: &str = "queue.canceled";
 pub const SUBCODE_QUEUE_FULL: &str = "queue.full";
@@ -98,6 +110,7 @@ pub const SUBCODE_COORDINATION_SIDECAR_UNAVAILABLE: &str = "coordination.sidecar
 pub const SUBCODE_IO_UNKNOWN: &str = "io.unknown";
 pub const SUBCODE_IO_PERMISSION_DENIED: &str = "io.permission_denied";
 pub const SUBCODE_IO_DISK_FULL: &str = "io.disk_full";
+pub const SUBCODE_IO_QUOTA_EXCEEDED: &str = "io.quota_exceeded";
 pub const SUBCODE_IO_NOT_FOUND: &str = "io.not_found";
 pub const SUBCODE_FORMAT_UNSUPPORTED_VERSION: &str = "format.unsupported_version";
 pub const SUBCODE_CORRUPTION_UNKNOWN: &str = "corruption.unknown";
@@ -115,6 +128,7 @@ pub const SUBCODE_SYNC_RETENTION_BLOCKED: &str = "sync.retention_blocked";
 pub const SUBCODE_BRANCH_NOT_FOUND: &str = "branch.not_found";
 pub const SUBCODE_BRANCH_MERGE_CONFLICT: &str = "branch.merge_conflict";
 pub const SUBCODE_EXTENSION_UNTRUSTED_PACKAGE: &str = "extension.untrusted_package";
+pub const SUBCODE_CONFIG_INVALID: &str = "config.invalid";
 pub const SUBCODE_INTERNAL_UNKNOWN: &str = "internal.unknown";
 pub const SUBCODE_INTERNAL_PANIC_CAPTURED: &str = "internal.panic_captured";
 pub const SUBCODE_INTERNAL_INVARIANT: &str = "internal.invariant";
@@ -1331,6 +1345,147 @@ impl DbError {
         )
     }
 
+    /// Structured variant for a main-file page whose contents no longer
+    /// match the checksum recorded when it was written.
+    #[must_use]
+    pub fn corruption_page_checksum(page_id: u32, expected: u32, actual: u32) -> Self {
+        Self::structured(
+            DbErrorCode::Corruption,
+            SUBCODE_CORRUPTION_PAGE_CHECKSUM,
+            format!(
+                "page {page_id} checksum mismatch: recorded {expected:08x}, computed {actual:08x}"
+            ),
+            false,
+            true,
+            DbDiagnosticContext::default().with_detail("page_id", Value::from(page_id)),
+            Some("XX001"),
+            None,
+            Some("errors/corruption-page-checksum"),
+        )
+    }
+
+    /// Structured variant for open options that cannot be used together.
+    /// The database was not opened.
+    #[must_use]
+    pub fn invalid_config(message: impl Into<String>) -> Self {
+        Self::structured(
+            DbErrorCode::Sql,
+            SUBCODE_CONFIG_INVALID,
+            message,
+            false,
+            true,
+            DbDiagnosticContext::default(),
+            Some("22023"),
+            Some("change the conflicting open options"),
+            Some("errors/config-invalid"),
+        )
+    }
+
+    /// Structured variant for a commit that would grow the database past
+    /// `DbConfig::max_database_size_bytes`. Nothing was written.
+    #[must_use]
+    pub fn quota_exceeded(limit_bytes: u64, required_bytes: u64) -> Self {
+        Self::structured(
+            DbErrorCode::Io,
+            SUBCODE_IO_QUOTA_EXCEEDED,
+            format!(
+                "database size quota exceeded: commit needs {required_bytes} bytes, limit is {limit_bytes}"
+            ),
+            false,
+            true,
+            DbDiagnosticContext::default()
+                .with_detail("limit_bytes", Value::from(limit_bytes))
+                .with_detail("required_bytes", Value::from(required_bytes)),
+            Some("53100"),
+            Some("delete data and checkpoint, or raise max_database_size_bytes"),
+            Some("errors/io-quota-exceeded"),
+        )
+    }
+
+    /// Whether this error is a `quota_exceeded`.
+    #[must_use]
+    pub fn is_quota_exceeded(&self) -> bool {
+        matches!(self, Self::Structured { diagnostic, .. }
+            if diagnostic.subcode == SUBCODE_IO_QUOTA_EXCEEDED)
+    }
+
+    /// Structured variant for a statement whose sorts, hash tables, and
+    /// intermediate results would hold more than its memory limit. The
+    /// statement was aborted.
+    #[must_use]
+    pub fn memory_limit_exceeded(limit_bytes: usize, required_bytes: usize) -> Self {
+        Self::structured(
+            DbErrorCode::Sql,
+            SUBCODE_SQL_MEMORY_LIMIT_EXCEEDED,
+            format!(
+                "statement memory limit exceeded: statement needs {required_bytes} bytes, limit is {limit_bytes}"
+            ),
+            false,
+            true,
+            DbDiagnosticContext::default()
+                .with_detail("limit_bytes", Value::from(limit_bytes))
+                .with_detail("required_bytes", Value::from(required_bytes)),
+            Some("53200"),
+            Some("narrow the query, add a LIMIT or an index, or raise statement_memory_limit_bytes"),
+            Some("errors/sql-memory-limit-exceeded"),
+        )
+    }
+
+    /// Whether this error is a `memory_limit_exceeded`.
+    #[must_use]
+    pub fn is_memory_limit_exceeded(&self) -> bool {
+        matches!(self, Self::Structured { diagnostic, .. }
+            if diagnostic.subcode == SUBCODE_SQL_MEMORY_LIMIT_EXCEEDED)
+    }
+
+    /// Structured variant for a query whose result would hold more rows than
+    /// its handle's `max_result_rows`. The query was aborted.
+    #[must_use]
+    pub fn result_row_limit_exceeded(limit_rows: u64) -> Self {
+        Self::structured(
+            DbErrorCode::Sql,
+            SUBCODE_SQL_RESULT_ROW_LIMIT_EXCEEDED,
+            format!("result row limit exceeded: query returns more than {limit_rows} rows"),
+            false,
+            true,
+            DbDiagnosticContext::default().with_detail("limit_rows", Value::from(limit_rows)),
+            Some("54000"),
+            Some("add a LIMIT or a narrower WHERE clause, page through the results, or raise max_result_rows"),
+            Some("errors/sql-result-row-limit-exceeded"),
+        )
+    }
+
+    /// Whether this error is a `result_row_limit_exceeded`.
+    #[must_use]
+    pub fn is_result_row_limit_exceeded(&self) -> bool {
+        matches!(self, Self::Structured { diagnostic, .. }
+            if diagnostic.subcode == SUBCODE_SQL_RESULT_ROW_LIMIT_EXCEEDED)
+    }
+
+    /// Structured variant for a statement that ran longer than its handle's
+    /// `max_statement_seconds`. The statement was aborted.
+    #[must_use]
+    pub fn statement_timeout(limit_seconds: u64) -> Self {
+        Self::structured(
+            DbErrorCode::Timeout,
+            SUBCODE_SQL_STATEMENT_TIMEOUT,
+            format!("statement timeout: statement ran longer than {limit_seconds} seconds"),
+            false,
+            true,
+            DbDiagnosticContext::default().with_detail("limit_seconds", Value::from(limit_seconds)),
+            Some("57014"),
+            Some("narrow the query, add an index, or raise max_statement_seconds"),
+            Some("errors/sql-statement-timeout"),
+        )
+    }
+
+    /// Whether this error is a `statement_timeout`.
+    #[must_use]
+    pub fn is_statement_timeout(&self) -> bool {
+        matches!(self, Self::Structured { diagnostic, .. }
+            if diagnostic.subcode == SUBCODE_SQL_STATEMENT_TIMEOUT)
+    }
+
     /// Structured variant for unique constraints with object context.
     #[must_use]
     pub fn constraint_unique(
@@ -1415,6 +1570,81 @@ impl DbError {
         )
     }
 
+    /// Structured variant for a transaction that lost an optimistic
+    /// write-write race: another writer committed after it began, so none of
+    /// its changes were applied and re-running it from the start is safe.
+    /// `page_id` is a page both commits wrote, when there was one.
+    #[must_use]
+    pub fn transaction_conflict(
+        expected_lsn: u64,
+        latest_lsn: u64,
+        page_id: Option<u32>,
+        contended_pages: usize,
+    ) -> Self {
+        let mut context = DbDiagnosticContext::default()
+            .with_detail("expected_lsn", Value::from(expected_lsn))
+            .with_detail("latest_lsn", Value::from(latest_lsn))
+            .with_detail("contended_pages", Value::from(contended_pages));
+        if let Some(page_id) = page_id {
+            context = context.with_detail("page_id", Value::from(page_id));
+        }
+        Self::structured(
+            DbErrorCode::Transaction,
+            SUBCODE_TRANSACTION_CONFLICT,
+            format!("transaction conflict: WAL advanced from {expected_lsn} to {latest_lsn}"),
+            true,
+            false,
+            context,
+            Some("40001"),
+            Some("another writer committed first; re-run the whole transaction"),
+            Some("errors/transaction-conflict"),
+        )
+    }
+
+    /// Whether this error is a `transaction_conflict`.
+    #[must_use]
+    pub fn is_transaction_conflict(&self) -> bool {
+        matches!(self, Self::Structured { diagnostic, .. }
+            if diagnostic.subcode == SUBCODE_TRANSACTION_CONFLICT)
+    }
+
+    /// Names the table a conflicting transaction shares with the commit that
+    /// beat it. Other errors are returned unchanged.
+    #[must_use]
+    pub(crate) fn with_conflict_relation(mut self, relation: impl Into<String>) -> Self {
+        if let Self::Structured { diagnostic, .. } = &mut self {
+            if diagnostic.subcode == SUBCODE_TRANSACTION_CONFLICT {
+                diagnostic.context.relation = Some(relation.into());
+            }
+        }
+        self
+    }
+
+    /// Structured variant for a statement that cannot take part in an
+    /// explicit transaction because its effect could not be rolled back with
+    /// it. The statement was not run and the transaction is still open.
+    #[must_use]
+    pub fn ddl_not_transactional(statement: &str) -> Self {
+        Self::structured(
+            DbErrorCode::Transaction,
+            SUBCODE_TRANSACTION_DDL_NOT_TRANSACTIONAL,
+            format!("{statement} is not supported inside an explicit SQL transaction"),
+            false,
+            true,
+            DbDiagnosticContext::default().with_detail("statement", Value::from(statement)),
+            Some("25001"),
+            Some("run the statement on its own, outside BEGIN ... COMMIT"),
+            Some("errors/transaction-ddl-not-transactional"),
+        )
+    }
+
+    /// Whether this error is a `ddl_not_transactional`.
+    #[must_use]
+    pub fn is_ddl_not_transactional(&self) -> bool {
+        matches!(self, Self::Structured { diagnostic, .. }
+            if diagnostic.subcode == SUBCODE_TRANSACTION_DDL_NOT_TRANSACTIONAL)
+    }
+
     /// Structured variant for writer lock contention.
     #[must_use]
     pub fn busy_writer_lock(message: impl Into<String>) -> Self {
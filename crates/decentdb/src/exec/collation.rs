@@ -0,0 +1,519 @@
+//! Column collations in queries.
+//!
+//! A column declared `COLLATE NOCASE` or `COLLATE RTRIM` compares under that
+//! collation in `WHERE`, `JOIN ... ON`, and `ORDER BY`. Instead of looking the
+//! collation up for every row, a statement that reads such a column is
+//! rewritten once so each compared reference carries an explicit `COLLATE`,
+//! which the evaluator, the planner, and index selection already understand.
+//! Projections, `GROUP BY`, `DISTINCT`, and `HAVING` keep binary comparison.
+
+use std::borrow::Cow;
+use std::sync::Arc;
+
+use crate::catalog::{identifiers_equal, TableSchema};
+use crate::record::value::Value;
+use crate::sql::ast::{
+    BinaryOp, Collation, Expr, FromItem, InsertSource, JoinConstraint, OrderBy, Query, QueryBody,
+    Select, SelectItem, Statement,
+};
+
+use super::EngineRuntime;
+
+impl EngineRuntime {
+    /// Returns `statement` with the declared collations of the columns it
+    /// compares made explicit, borrowing it unchanged when none apply.
+    pub(super) fn apply_column_collations<'s>(
+        &self,
+        statement: &'s Statement,
+    ) -> Cow<'s, Statement> {
+        if !matches!(
+            statement,
+            Statement::Query(_)
+                | Statement::Explain(_)
+                | Statement::Insert(_)
+                | Statement::Update(_)
+                | Statement::Delete(_)
+                | Statement::CreateTableAs(_)
+        ) || !self.has_collated_columns()
+        {
+            return Cow::Borrowed(statement);
+        }
+        let mut rewritten = statement.clone();
+        let mut rewriter = CollationRewriter::new(self);
+        rewriter.statement(&mut rewritten);
+        if rewriter.changed {
+            Cow::Owned(rewritten)
+        } else {
+            Cow::Borrowed(statement)
+        }
+    }
+
+    /// Same as [`Self::apply_column_collations`] for the stored query of a
+    /// view. The rewrite is not cached with the parsed view, so recreating
+    /// a table with different collations takes effect immediately.
+    pub(super) fn apply_view_column_collations(&self, query: Arc<Query>) -> Arc<Query> {
+        if !self.has_collated_columns() {
+            return query;
+        }
+        let mut rewritten = (*query).clone();
+        let mut rewriter = CollationRewriter::new(self);
+        rewriter.query(&mut rewritten, &mut Vec::new());
+        if rewriter.changed {
+            Arc::new(rewritten)
+        } else {
+            query
+        }
+    }
+
+    /// True when `query` compares a collated column. Fast paths that are
+    /// entered without going through the statement entry points use this to
+    /// leave such queries to the general executor.
+    pub(crate) fn query_uses_column_collations(&self, query: &Query) -> bool {
+        if !self.has_collated_columns() {
+            return false;
+        }
+        let mut rewritten = query.clone();
+        let mut rewriter = CollationRewriter::new(self);
+        rewriter.query(&mut rewritten, &mut Vec::new());
+        rewriter.changed
+    }
+
+    fn has_collated_columns(&self) -> bool {
+        self.catalog
+            .tables
+            .values()
+            .chain(self.temp_tables.values())
+            .any(|table| {
+                table
+                    .columns
+                    .iter()
+                    .any(|column| column.collation.is_some())
+            })
+    }
+}
+
+/// A name that columns can be qualified with.
+enum Binding<'r> {
+    /// A base table, whose column collations are known.
+    Table {
+        name: String,
+        table: &'r TableSchema,
+    },
+    /// A derived table, CTE, view, or table function. Its columns are not
+    /// resolved here, so references that might belong to it are left alone.
+    Opaque(String),
+}
+
+impl Binding<'_> {
+    fn name(&self) -> &str {
+        match self {
+            Self::Table { name, .. } | Self::Opaque(name) => name,
+        }
+    }
+}
+
+/// The bindings introduced by one `FROM` clause.
+type Scope<'r> = Vec<Binding<'r>>;
+
+struct CollationRewriter<'r> {
+    runtime: &'r EngineRuntime,
+    /// CTE names visible at the current point; they shadow tables.
+    ctes: Vec<String>,
+    changed: bool,
+}
+
+impl<'r> CollationRewriter<'r> {
+    fn new(runtime: &'r EngineRuntime) -> Self {
+        Self {
+            runtime,
+            ctes: Vec::new(),
+            changed: false,
+        }
+    }
+
+    fn statement(&mut self, statement: &mut Statement) {
+        match statement {
+            Statement::Query(query) => self.query(query, &mut Vec::new()),
+            Statement::Explain(explain) => self.statement(&mut explain.statement),
+            Statement::Insert(insert) => {
+                if let InsertSource::Query(query) = &mut insert.source {
+                    self.query(query, &mut Vec::new());
+                }
+            }
+            Statement::Update(update) => {
+                let mut scopes = vec![self.target_scope(&update.table_name)];
+                if let Some(filter) = &mut update.filter {
+                    self.expr(filter, &mut scopes, true);
+                }
+                for assignment in &mut update.assignments {
+                    self.expr(&mut assignment.expr, &mut scopes, false);
+                }
+            }
+            Statement::Delete(delete) => {
+                let mut scopes = vec![self.target_scope(&delete.table_name)];
+                if let Some(filter) = &mut delete.filter {
+                    self.expr(filter, &mut scopes, true);
+                }
+            }
+            Statement::CreateTableAs(create) => self.query(&mut create.query, &mut Vec::new()),
+            _ => {}
+        }
+    }
+
+    fn target_scope(&self, table_name: &str) -> Scope<'r> {
+        vec![self.binding(table_name, table_name)]
+    }
+
+    fn binding(&self, table_name: &str, binding_name: &str) -> Binding<'r> {
+        let shadowed = self
+            .ctes
+            .iter()
+            .any(|cte| identifiers_equal(cte, table_name));
+        match self.runtime.table_schema(table_name) {
+            Some(table) if !shadowed => Binding::Table {
+                name: binding_name.to_string(),
+                table,
+            },
+            _ => Binding::Opaque(binding_name.to_string()),
+        }
+    }
+
+    fn query(&mut self, query: &mut Query, scopes: &mut Vec<Scope<'r>>) {
+        let visible_ctes = self.ctes.len();
+        if query.recursive {
+            self.ctes
+                .extend(query.ctes.iter().map(|cte| cte.name.clone()));
+        }
+        for cte in &mut query.ctes {
+            self.query(&mut cte.query, scopes);
+            if !query.recursive {
+                self.ctes.push(cte.name.clone());
+            }
+        }
+        match &mut query.body {
+            QueryBody::Select(select) => {
+                let scope = self.select(select, scopes);
+                scopes.push(scope);
+                self.order_by(&mut query.order_by, &select.projection, scopes);
+                scopes.pop();
+            }
+            body => self.body(body, scopes),
+        }
+        self.ctes.truncate(visible_ctes);
+    }
+
+    fn body(&mut self, body: &mut QueryBody, scopes: &mut Vec<Scope<'r>>) {
+        match body {
+            QueryBody::Select(select) => {
+                self.select(select, scopes);
+            }
+            QueryBody::Values(rows) => {
+                for value in rows.iter_mut().flatten() {
+                    self.expr(value, scopes, false);
+                }
+            }
+            QueryBody::SetOperation { left, right, .. } => {
+                self.body(left, scopes);
+                self.body(right, scopes);
+            }
+        }
+    }
+
+    fn select(&mut self, select: &mut Select, scopes: &mut Vec<Scope<'r>>) -> Scope<'r> {
+        let mut scope = Scope::new();
+        for item in &mut select.from {
+            self.from_item(item, scopes, &mut scope);
+        }
+        scopes.push(scope);
+        if let Some(filter) = &mut select.filter {
+            self.expr(filter, scopes, true);
+        }
+        for item in &mut select.projection {
+            if let SelectItem::Expr { expr, .. } = item {
+                self.expr(expr, scopes, false);
+            }
+        }
+        for expr in select
+            .group_by
+            .iter_mut()
+            .chain(select.having.iter_mut())
+            .chain(select.distinct_on.iter_mut())
+        {
+            self.expr(expr, scopes, false);
+        }
+        scopes.pop().unwrap_or_default()
+    }
+
+    fn from_item(
+        &mut self,
+        item: &mut FromItem,
+        scopes: &mut Vec<Scope<'r>>,
+        scope: &mut Scope<'r>,
+    ) {
+        match item {
+            FromItem::Table { name, alias } => {
+                let binding = self.binding(name, alias.as_deref().unwrap_or(name.as_str()));
+                scope.push(binding);
+            }
+            FromItem::Subquery { query, alias, .. } => {
+                self.query(query, scopes);
+                scope.push(Binding::Opaque(alias.clone()));
+            }
+            FromItem::Function { name, alias, .. } => {
+                scope.push(Binding::Opaque(
+                    alias.clone().unwrap_or_else(|| name.clone()),
+                ));
+            }
+            FromItem::Join {
+                left,
+                right,
+                constraint,
+                ..
+            } => {
+                self.from_item(left, scopes, scope);
+                self.from_item(right, scopes, scope);
+                if let JoinConstraint::On(on) = constraint {
+                    scopes.push(std::mem::take(scope));
+                    self.expr(on, scopes, true);
+                    *scope = scopes.pop().unwrap_or_default();
+                }
+            }
+            FromItem::Sample { source, .. } => self.from_item(source, scopes, scope),
+        }
+    }
+
+    /// Gives `ORDER BY column` the column's collation unless the clause
+    /// names one or the column is shadowed by an output alias.
+    fn order_by(
+        &mut self,
+        order_by: &mut [OrderBy],
+        projection: &[SelectItem],
+        scopes: &[Scope<'r>],
+    ) {
+        for order in order_by {
+            let Expr::Column { table, column } = &order.expr else {
+                continue;
+            };
+            if order.collation.is_some() {
+                continue;
+            }
+            let shadowed = table.is_none()
+                && projection.iter().any(|item| {
+                    matches!(
+                        item,
+                        SelectItem::Expr { expr, alias: Some(alias) }
+                            if identifiers_equal(alias, column) && expr != &order.expr
+                    )
+                });
+            if shadowed {
+                continue;
+            }
+            if let Some(collation) = column_collation(scopes, table.as_deref(), column) {
+                order.collation = Some(collation);
+                self.changed = true;
+            }
+        }
+    }
+
+    /// Walks `expr`, rewriting nested queries. When `compare` is set the
+    /// expression is a predicate, so compared column operands also receive
+    /// their declared collation.
+    fn expr(&mut self, expr: &mut Expr, scopes: &mut Vec<Scope<'r>>, compare: bool) {
+        match expr {
+            Expr::Literal(_) | Expr::Column { .. } | Expr::Parameter(_) => {}
+            Expr::Unary { expr, .. } | Expr::Cast { expr, .. } | Expr::IsNull { expr, .. } => {
+                self.expr(expr, scopes, compare);
+            }
+            Expr::Collate { expr, .. } => self.expr(expr, scopes, false),
+            Expr::Binary { left, op, right } => {
+                if compare && is_comparison(*op) && !self.collate_operand(left, scopes) {
+                    self.collate_operand(right, scopes);
+                }
+                self.expr(left, scopes, compare);
+                self.expr(right, scopes, compare);
+            }
+            Expr::Between {
+                expr, low, high, ..
+            } => {
+                if compare {
+                    self.collate_operand(expr, scopes);
+                }
+                self.expr(expr, scopes, compare);
+                self.expr(low, scopes, compare);
+                self.expr(high, scopes, compare);
+            }
+            Expr::InList { expr, items, .. } => {
+                if compare {
+                    self.collate_operand(expr, scopes);
+                }
+                self.expr(expr, scopes, compare);
+                for item in items {
+                    self.expr(item, scopes, compare);
+                }
+            }
+            Expr::InSubquery { expr, query, .. } | Expr::CompareSubquery { expr, query, .. } => {
+                if compare {
+                    self.collate_operand(expr, scopes);
+                }
+                self.expr(expr, scopes, compare);
+                self.query(query, scopes);
+            }
+            Expr::ScalarSubquery(query) | Expr::Exists(query) => self.query(query, scopes),
+            Expr::Like {
+                expr,
+                pattern,
+                escape,
+                ..
+            } => {
+                if compare {
+                    self.collate_operand(expr, scopes);
+                }
+                self.expr(expr, scopes, compare);
+                self.expr(pattern, scopes, compare);
+                if let Some(escape) = escape {
+                    self.expr(escape, scopes, compare);
+                }
+            }
+            Expr::Function { args, .. } | Expr::Row(args) => {
+                for arg in args {
+                    self.expr(arg, scopes, compare);
+                }
+            }
+            Expr::Aggregate { args, order_by, .. } => {
+                for arg in args {
+                    self.expr(arg, scopes, false);
+                }
+                for order in order_by {
+                    self.expr(&mut order.expr, scopes, false);
+                }
+            }
+            Expr::RowNumber {
+                partition_by,
+                order_by,
+                ..
+            } => {
+                for expr in partition_by {
+                    self.expr(expr, scopes, false);
+                }
+                for order in order_by {
+                    self.expr(&mut order.expr, scopes, false);
+                }
+            }
+            Expr::WindowFunction {
+                args,
+                partition_by,
+                order_by,
+                ..
+            } => {
+                for expr in args.iter_mut().chain(partition_by) {
+                    self.expr(expr, scopes, false);
+                }
+                for order in order_by {
+                    self.expr(&mut order.expr, scopes, false);
+                }
+            }
+            Expr::Case {
+                operand,
+                branches,
+                else_expr,
+            } => {
+                if let Some(operand) = operand {
+                    self.expr(operand, scopes, compare);
+                }
+                for (condition, result) in branches {
+                    self.expr(condition, scopes, compare);
+                    self.expr(result, scopes, compare);
+                }
+                if let Some(else_expr) = else_expr {
+                    self.expr(else_expr, scopes, compare);
+                }
+            }
+        }
+    }
+
+    /// Wraps a column operand in its declared collation. Returns true when
+    /// the operand now carries a collation, written or declared, so the
+    /// other side of a comparison is left as is.
+    fn collate_operand(&mut self, operand: &mut Expr, scopes: &[Scope<'r>]) -> bool {
+        let collation = match operand {
+            Expr::Collate { .. } => return true,
+            Expr::Column { table, column } => column_collation(scopes, table.as_deref(), column),
+            _ => None,
+        };
+        let Some(collation) = collation else {
+            return false;
+        };
+        let column = std::mem::replace(operand, Expr::Literal(Value::Null));
+        *operand = Expr::Collate {
+            expr: Box::new(column),
+            collation,
+        };
+        self.changed = true;
+        true
+    }
+}
+
+fn is_comparison(op: BinaryOp) -> bool {
+    matches!(
+        op,
+        BinaryOp::Eq
+            | BinaryOp::NotEq
+            | BinaryOp::Lt
+            | BinaryOp::LtEq
+            | BinaryOp::Gt
+            | BinaryOp::GtEq
+            | BinaryOp::IsDistinctFrom
+            | BinaryOp::IsNotDistinctFrom
+    )
+}
+
+/// Resolves a column reference against the enclosing scopes, innermost
+/// first, and returns its declared collation. References that cannot be
+/// resolved with certainty resolve to `None` and compare as before.
+fn column_collation(scopes: &[Scope<'_>], table: Option<&str>, column: &str) -> Option<Collation> {
+    let declared = |schema: &TableSchema| {
+        schema
+            .columns
+            .iter()
+            .find(|candidate| identifiers_equal(&candidate.name, column))
+            .and_then(|candidate| candidate.collation)
+            .map(Collation::from)
+    };
+    for scope in scopes.iter().rev() {
+        match table {
+            Some(table) => {
+                if let Some(binding) = scope
+                    .iter()
+                    .find(|binding| identifiers_equal(binding.name(), table))
+                {
+                    return match binding {
+                        Binding::Table { table, .. } => declared(*table),
+                        Binding::Opaque(_) => None,
+                    };
+                }
+            }
+            None => {
+                if scope
+                    .iter()
+                    .any(|binding| matches!(binding, Binding::Opaque(_)))
+                {
+                    return None;
+                }
+                let mut owners = scope.iter().filter_map(|binding| match binding {
+                    Binding::Table { table, .. } => table
+                        .columns
+                        .iter()
+                        .any(|candidate| identifiers_equal(&candidate.name, column))
+                        .then_some(*table),
+                    Binding::Opaque(_) => None,
+                });
+                match (owners.next(), owners.next()) {
+                    (Some(owner), None) => return declared(owner),
+                    (Some(_), Some(_)) => return None,
+                    (None, _) => {}
+                }
+            }
+        }
+    }
+    None
+}
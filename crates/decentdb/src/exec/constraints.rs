@@ -1,20 +1,53 @@
 //! Constraint enforcement helpers.
 
+use std::cell::Cell;
+
 use crate::catalog::{
-    identifiers_equal, ColumnSchema, ColumnType, IndexKind, IndexSchema, TableSchema,
+    identifiers_equal, ColumnSchema, ColumnType, ForeignKeyConstraint, IndexKind, IndexSchema,
+    TableSchema,
 };
 use crate::error::{DbError, Result};
+use crate::metadata::ForeignKeyViolation;
 use crate::record::row::Row;
 use crate::record::value::Value;
 use crate::spatial::types::{CoordinateDimensions, SpatialGeometry, SpatialKind, SpatialValue};
 use crate::sql::ast::ConflictTarget;
 use crate::sql::parser::parse_expression_sql;
 
+use super::expressions::{expr_collation, fold_collation_key};
 use super::{
     compare_values, generated_columns_are_stored, row_satisfies_index_predicate, table_row_dataset,
     EngineRuntime, RuntimeBtreeKey, RuntimeIndex, StoredRow, TableRowRef,
 };
 
+thread_local! {
+    static FOREIGN_KEYS_ENFORCED: Cell<bool> = const { Cell::new(true) };
+}
+
+/// Installs a handle's foreign key enforcement setting (`DbConfig::foreign_keys`,
+/// adjusted at runtime with `PRAGMA foreign_keys`) for the writes run on the
+/// current thread, restoring the previous setting when dropped.
+pub(crate) struct ForeignKeyEnforcement(bool);
+
+impl ForeignKeyEnforcement {
+    pub(crate) fn install(enforced: bool) -> Self {
+        Self(FOREIGN_KEYS_ENFORCED.with(|slot| slot.replace(enforced)))
+    }
+}
+
+impl Drop for ForeignKeyEnforcement {
+    fn drop(&mut self) {
+        FOREIGN_KEYS_ENFORCED.with(|slot| slot.set(self.0));
+    }
+}
+
+/// Returns false while a write runs with foreign key enforcement turned off,
+/// in which case child rows are not checked and parent deletes and updates
+/// apply no referential actions.
+pub(crate) fn foreign_keys_enforced() -> bool {
+    FOREIGN_KEYS_ENFORCED.with(Cell::get)
+}
+
 impl EngineRuntime {
     pub(super) fn coerce_row_values(
         &self,
@@ -162,92 +195,126 @@ impl EngineRuntime {
             }
         }
 
-        if !check_foreign_keys {
+        if !check_foreign_keys || !foreign_keys_enforced() {
             return Ok(());
         }
 
         for foreign_key in &table.foreign_keys {
-            let child_values = foreign_key
-                .columns
-                .iter()
-                .map(|column_name| lookup_column_value(table, row_for_eval, column_name))
-                .collect::<Result<Vec<_>>>()?;
-            if child_values
-                .iter()
-                .any(|value| matches!(value, Value::Null))
-            {
-                continue;
-            }
-            let parent = self
-                .catalog
-                .tables
-                .get(&foreign_key.referenced_table)
-                .ok_or_else(|| {
-                    DbError::constraint(format!(
-                        "foreign key references unknown table {}",
-                        foreign_key.referenced_table
-                    ))
-                })?;
-            let referenced_columns = if foreign_key.referenced_columns.is_empty() {
-                parent.primary_key_columns.clone()
-            } else {
-                foreign_key.referenced_columns.clone()
-            };
-            if referenced_columns.is_empty() {
-                return Err(DbError::constraint(format!(
-                    "foreign key {} must reference a primary or explicit parent key",
-                    foreign_key
-                        .name
-                        .clone()
-                        .unwrap_or_else(|| format!("{}_fk", table.name))
-                )));
-            }
-            if let Some(exists) = parent_exists_via_single_or_composite_index(
-                self,
-                parent,
-                &foreign_key.referenced_table,
-                &referenced_columns,
-                &child_values,
-            )? {
-                if exists {
-                    continue;
-                }
+            if !self.row_foreign_key_satisfied(table, row_for_eval, foreign_key)? {
                 return Err(DbError::constraint(format!(
                     "foreign key on {} references missing parent row in {}",
                     table.name, foreign_key.referenced_table
                 )));
             }
-            let Some(parent_rows) = self.visible_table_row_source(&foreign_key.referenced_table)
-            else {
-                return Err(DbError::constraint(format!(
-                    "foreign key parent table {} has no row store",
+        }
+
+        Ok(())
+    }
+
+    /// Returns whether the parent row `foreign_key` requires for `row` exists.
+    /// Rows with a NULL in any child column satisfy the constraint.
+    fn row_foreign_key_satisfied(
+        &self,
+        table: &TableSchema,
+        row: &[Value],
+        foreign_key: &ForeignKeyConstraint,
+    ) -> Result<bool> {
+        let child_values = foreign_key
+            .columns
+            .iter()
+            .map(|column_name| lookup_column_value(table, row, column_name))
+            .collect::<Result<Vec<_>>>()?;
+        if child_values
+            .iter()
+            .any(|value| matches!(value, Value::Null))
+        {
+            return Ok(true);
+        }
+        let parent = self
+            .catalog
+            .tables
+            .get(&foreign_key.referenced_table)
+            .ok_or_else(|| {
+                DbError::constraint(format!(
+                    "foreign key references unknown table {}",
                     foreign_key.referenced_table
-                )));
-            };
-            let mut exists = false;
-            for parent_row in parent_rows.rows() {
-                let parent_row = materialize_constraint_row(self, parent, parent_row?)?;
-                let is_match = referenced_columns.iter().zip(&child_values).all(
-                    |(column_name, child_value)| {
+                ))
+            })?;
+        let referenced_columns = if foreign_key.referenced_columns.is_empty() {
+            parent.primary_key_columns.clone()
+        } else {
+            foreign_key.referenced_columns.clone()
+        };
+        if referenced_columns.is_empty() {
+            return Err(DbError::constraint(format!(
+                "foreign key {} must reference a primary or explicit parent key",
+                foreign_key
+                    .name
+                    .clone()
+                    .unwrap_or_else(|| format!("{}_fk", table.name))
+            )));
+        }
+        if let Some(exists) = parent_exists_via_single_or_composite_index(
+            self,
+            parent,
+            &foreign_key.referenced_table,
+            &referenced_columns,
+            &child_values,
+        )? {
+            return Ok(exists);
+        }
+        let Some(parent_rows) = self.visible_table_row_source(&foreign_key.referenced_table) else {
+            return Err(DbError::constraint(format!(
+                "foreign key parent table {} has no row store",
+                foreign_key.referenced_table
+            )));
+        };
+        for parent_row in parent_rows.rows() {
+            let parent_row = materialize_constraint_row(self, parent, parent_row?)?;
+            let is_match =
+                referenced_columns
+                    .iter()
+                    .zip(&child_values)
+                    .all(|(column_name, child_value)| {
                         lookup_column_value(parent, &parent_row.values, column_name)
                             .and_then(|parent_value| compare_values(parent_value, child_value))
                             .is_ok_and(|ordering| ordering == std::cmp::Ordering::Equal)
-                    },
-                );
-                if is_match {
-                    exists = true;
-                    break;
-                }
-            }
-            if !exists {
-                return Err(DbError::constraint(format!(
-                    "foreign key on {} references missing parent row in {}",
-                    table.name, foreign_key.referenced_table
-                )));
+                    });
+            if is_match {
+                return Ok(true);
             }
         }
+        Ok(false)
+    }
 
-        Ok(())
+    /// Lists the rows of persistent tables whose foreign keys reference a
+    /// missing parent row, in table and row id order. Row sources of every
+    /// table must be loaded.
+    pub(crate) fn foreign_key_violations(&self) -> Result<Vec<ForeignKeyViolation>> {
+        let mut violations = Vec::new();
+        for table in self.catalog.tables.values() {
+            if table.foreign_keys.is_empty() {
+                continue;
+            }
+            let Some(row_source) = self.visible_table_row_source(&table.name) else {
+                continue;
+            };
+            for row in row_source.rows() {
+                let row = materialize_constraint_row(self, table, row?)?;
+                for (ordinal, foreign_key) in table.foreign_keys.iter().enumerate() {
+                    if !self.row_foreign_key_satisfied(table, &row.values, foreign_key)? {
+                        violations.push(ForeignKeyViolation {
+                            table_name: table.name.clone(),
+                            row_id: row.row_id,
+                            referenced_table: foreign_key.referenced_table.clone(),
+                            foreign_key_ordinal: ordinal,
+                            foreign_key_name: foreign_key.name.clone(),
+                        });
+                    }
+                }
+            }
+        }
+        Ok(violations)
     }
 
     pub(super) fn find_conflicting_row(
@@ -329,6 +396,11 @@ impl EngineRuntime {
 }
 
 pub(super) fn auto_index_name(prefix: &str, table_name: &str, columns: &[String]) -> String {
+    // Indexes of schema-owned tables live in the table's schema.
+    if let Some(schema) = super::owning_schema_name(table_name) {
+        let table_name = &table_name[schema.len() + 1..];
+        return format!("{schema}.{prefix}_{}_{}", table_name, columns.join("_"));
+    }
     format!("{prefix}_{}_{}", table_name, columns.join("_"))
 }
 
@@ -626,14 +698,15 @@ fn index_values(
                 lookup_column_value(table, row, column_name).cloned()
             } else if let Some(sql) = &column.expression_sql {
                 let expr = parse_expression_sql(sql)?;
-                runtime.eval_expr(
+                let value = runtime.eval_expr(
                     &expr,
                     &dataset,
                     row,
                     &[],
                     &std::collections::BTreeMap::new(),
                     None,
-                )
+                )?;
+                Ok(fold_collation_key(value, expr_collation(&expr).as_ref()))
             } else {
                 Err(DbError::constraint("index key definition is empty"))
             }
@@ -757,6 +830,7 @@ mod tests {
             column_type: crate::catalog::ColumnType::Int64,
             spatial_type: None,
             enum_type: None,
+            collation: None,
             nullable,
             default_sql: None,
             generated_sql: None,
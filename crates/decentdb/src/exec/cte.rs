@@ -129,6 +129,18 @@ fn from_item_references_outer(
         FromItem::Function { args, .. } => args
             .iter()
             .any(|arg| expr_references_outer(arg, outer_tables, local_tables)),
+        FromItem::Sample {
+            source,
+            percent,
+            seed,
+            ..
+        } => {
+            from_item_references_outer(source, outer_tables, local_tables)
+                || expr_references_outer(percent, outer_tables, local_tables)
+                || seed
+                    .as_ref()
+                    .is_some_and(|seed| expr_references_outer(seed, outer_tables, local_tables))
+        }
         FromItem::Join {
             left,
             right,
@@ -290,6 +302,7 @@ pub(crate) fn collect_from_item_table_names(item: &FromItem, names: &mut BTreeSe
         FromItem::Subquery { alias, .. } => {
             names.insert(alias.clone());
         }
+        FromItem::Sample { source, .. } => collect_from_item_table_names(source, names),
         FromItem::Join { left, right, .. } => {
             collect_from_item_table_names(left, names);
             collect_from_item_table_names(right, names);
@@ -431,6 +444,7 @@ pub(crate) fn from_item_contains_subquery(item: &FromItem) -> bool {
         FromItem::Table { .. } => false,
         FromItem::Function { .. } => false,
         FromItem::Subquery { .. } => true,
+        FromItem::Sample { source, .. } => from_item_contains_subquery(source),
         FromItem::Join { left, right, .. } => {
             from_item_contains_subquery(left) || from_item_contains_subquery(right)
         }
@@ -440,14 +454,14 @@ pub(crate) fn from_item_contains_subquery(item: &FromItem) -> bool {
 pub(crate) fn from_item_is_lateral(item: &FromItem) -> bool {
     match item {
         FromItem::Subquery { lateral, .. } | FromItem::Function { lateral, .. } => *lateral,
-        FromItem::Table { .. } | FromItem::Join { .. } => false,
+        FromItem::Table { .. } | FromItem::Join { .. } | FromItem::Sample { .. } => false,
     }
 }
 
 pub(crate) fn from_item_contains_lateral(item: &FromItem) -> bool {
     match item {
         FromItem::Subquery { lateral, .. } | FromItem::Function { lateral, .. } => *lateral,
-        FromItem::Table { .. } => false,
+        FromItem::Table { .. } | FromItem::Sample { .. } => false,
         FromItem::Join { left, right, .. } => {
             from_item_contains_lateral(left) || from_item_contains_lateral(right)
         }
@@ -518,6 +532,7 @@ pub(crate) fn from_item_table_reference_count(item: &FromItem, table_name: &str)
                     .is_some_and(|alias| identifiers_equal(alias, table_name)),
         ),
         FromItem::Subquery { query, .. } => query_table_reference_count(query, table_name),
+        FromItem::Sample { source, .. } => from_item_table_reference_count(source, table_name),
         FromItem::Join { left, right, .. } => {
             from_item_table_reference_count(left, table_name)
                 + from_item_table_reference_count(right, table_name)
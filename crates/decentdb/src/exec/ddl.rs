@@ -1,8 +1,9 @@
 //! DDL execution helpers.
 
 use crate::catalog::{
-    identifiers_equal, CheckConstraint, ColumnSchema, ColumnType, EnumTypeInfo, ForeignKeyAction,
-    ForeignKeyConstraint, IndexColumn, IndexKind, IndexSchema, SchemaInfo, TableSchema,
+    identifiers_equal, CheckConstraint, ColumnCollation, ColumnSchema, ColumnType, EnumTypeInfo,
+    ForeignKeyAction, ForeignKeyConstraint, IndexColumn, IndexKind, IndexSchema, SchemaInfo,
+    TableSchema,
 };
 use crate::error::{DbError, Result};
 use crate::record::value::Value;
@@ -11,16 +12,45 @@ use crate::search::fulltext::{
     FTS_DDL_ERROR_PREFIX,
 };
 use crate::sql::ast::{
-    AlterTableAction, ColumnDefinition, CreateIndexStatement, CreateTableStatement, Expr,
-    ForeignKeyActionSpec, ForeignKeyDefinition, IndexExpression, IndexOption, TableConstraint,
+    AlterTableAction, Collation, ColumnDefinition, CreateIndexStatement, CreateTableStatement,
+    Expr, ForeignKeyActionSpec, ForeignKeyDefinition, IndexExpression, IndexOption,
+    TableConstraint,
 };
 use crate::sql::parser::parse_expression_sql;
 
 use super::constraints::auto_index_name;
+use super::expressions::default_uuid_generator;
 use super::{table_row_dataset, EngineRuntime, StoredRow, TableData, TableRowSource};
-use std::collections::BTreeSet;
+use std::cell::Cell;
+use std::collections::{BTreeSet, HashSet};
 use std::sync::Arc;
 
+thread_local! {
+    static DEFAULT_COLLATION: Cell<Option<ColumnCollation>> = const { Cell::new(None) };
+}
+
+/// Installs a handle's default text collation (`DbConfig::default_collation`)
+/// for the DDL run on the current thread, restoring the previous setting when
+/// dropped.
+pub(crate) struct DefaultCollation(Option<ColumnCollation>);
+
+impl DefaultCollation {
+    pub(crate) fn install(collation: Option<ColumnCollation>) -> Self {
+        Self(DEFAULT_COLLATION.with(|slot| slot.replace(collation)))
+    }
+}
+
+impl Drop for DefaultCollation {
+    fn drop(&mut self) {
+        DEFAULT_COLLATION.with(|slot| slot.set(self.0));
+    }
+}
+
+/// The collation given to TEXT columns created without a `COLLATE` clause.
+fn default_collation() -> Option<ColumnCollation> {
+    DEFAULT_COLLATION.with(Cell::get)
+}
+
 impl EngineRuntime {
     pub(super) fn execute_create_schema(&mut self, name: &str, if_not_exists: bool) -> Result<()> {
         if self.catalog.schema(name).is_some() {
@@ -42,7 +72,27 @@ impl EngineRuntime {
         Ok(())
     }
 
+    /// Checks that the application schema a new object is named into
+    /// exists. Temporary objects always live in the temp schema.
+    pub(super) fn ensure_owning_schema_exists(&self, name: &str, temporary: bool) -> Result<()> {
+        let Some(schema) = super::owning_schema_name(name) else {
+            return Ok(());
+        };
+        if temporary {
+            return Err(DbError::sql(format!(
+                "temporary objects cannot be created in schema {schema}"
+            )));
+        }
+        if self.catalog.schema(schema).is_none() {
+            return Err(DbError::sql(format!("schema {schema} does not exist")));
+        }
+        Ok(())
+    }
+
     pub(super) fn execute_create_table(&mut self, statement: &CreateTableStatement) -> Result<()> {
+        if let Some(spec) = &statement.partition_of {
+            return self.execute_create_partition(statement, spec);
+        }
         let (qualifier, object_name) = super::compat_schema_qualified_name(&statement.table_name);
         if statement.temporary && qualifier == Some(super::CompatSchemaQualifier::Main) {
             return Err(DbError::sql(
@@ -52,6 +102,7 @@ impl EngineRuntime {
         let temporary =
             statement.temporary || qualifier == Some(super::CompatSchemaQualifier::Temp);
         let table_name = object_name.to_string();
+        self.ensure_owning_schema_exists(&table_name, temporary)?;
 
         if temporary {
             if self.temp_relation_exists(&table_name) {
@@ -64,7 +115,10 @@ impl EngineRuntime {
                 )));
             }
         } else if self.catalog.contains_object(&table_name) {
-            if statement.if_not_exists && self.catalog.table(&table_name).is_some() {
+            if statement.if_not_exists
+                && (self.catalog.table(&table_name).is_some()
+                    || self.catalog.partitioned_table(&table_name).is_some())
+            {
                 return Ok(());
             }
             return Err(DbError::sql(format!(
@@ -153,7 +207,25 @@ impl EngineRuntime {
             pk_index_root: None,
         };
         validate_generated_columns(self, &table)?;
+        let ttl_column = statement
+            .ttl_column
+            .as_deref()
+            .map(|name| ttl_column_name(&table, name))
+            .transpose()?;
+        if let Some(spec) = &statement.partition_by {
+            return self.create_partitioned_table(
+                &table,
+                spec,
+                ttl_column.as_deref(),
+                &secondary_unique_indexes,
+            );
+        }
         if table.temporary {
+            if ttl_column.is_some() {
+                return Err(DbError::sql(
+                    "ttl_column is not supported on temporary tables",
+                ));
+            }
             let mut temp_indexes = Vec::new();
             if !table.foreign_keys.is_empty() {
                 return Err(DbError::sql(
@@ -215,6 +287,11 @@ impl EngineRuntime {
             .insert(table_name.clone(), table.clone());
         self.tables_mut()
             .insert(table_name.clone(), TableData::default().into());
+        if let Some(ttl_column) = ttl_column {
+            self.catalog_mut()
+                .table_ttl
+                .insert(table_name.clone(), ttl_column);
+        }
 
         if !table.primary_key_columns.is_empty() {
             self.insert_index_schema(IndexSchema {
@@ -239,7 +316,10 @@ impl EngineRuntime {
 
         for (name, columns) in secondary_unique_indexes {
             self.insert_index_schema(IndexSchema {
-                name: name.unwrap_or_else(|| auto_index_name("uq", &table.name, &columns)),
+                name: name.map_or_else(
+                    || auto_index_name("uq", &table.name, &columns),
+                    |name| super::schema_owned_object_name(&table.name, &name),
+                ),
                 table_name: table.name.clone(),
                 kind: IndexKind::Btree,
                 unique: true,
@@ -300,7 +380,7 @@ impl EngineRuntime {
                 "CREATE INDEX in the temp schema is not supported; temporary tables are connection-local",
             ));
         }
-        let index_name = index_object.to_string();
+        let index_name = super::schema_owned_object_name(&statement.table_name, index_object);
         if self.catalog.contains_object(&index_name) {
             if statement.if_not_exists && self.catalog.indexes.contains_key(&index_name) {
                 return Ok(None);
@@ -357,15 +437,19 @@ impl EngineRuntime {
             None
         };
 
-        let has_expression = statement
-            .columns
+        let columns = if kind == IndexKind::Btree && statement.predicate.is_none() {
+            collated_index_key_columns(table, &statement.columns)
+        } else {
+            statement.columns.clone()
+        };
+        let has_expression = columns
             .iter()
             .any(|column| matches!(column, IndexExpression::Expr(_)));
         if kind == IndexKind::Trigram {
             if statement.unique {
                 return Err(DbError::sql("trigram indexes cannot be UNIQUE"));
             }
-            if statement.columns.len() != 1 || has_expression {
+            if columns.len() != 1 || has_expression {
                 return Err(DbError::sql(
                     "trigram indexes require a single plain column key",
                 ));
@@ -383,7 +467,7 @@ impl EngineRuntime {
             if statement.unique {
                 return Err(DbError::sql("spatial indexes cannot be UNIQUE"));
             }
-            if statement.columns.len() != 1 || has_expression {
+            if columns.len() != 1 || has_expression {
                 return Err(DbError::sql(
                     "spatial indexes require a single plain spatial column",
                 ));
@@ -396,7 +480,7 @@ impl EngineRuntime {
                     "spatial indexes do not support INCLUDE columns",
                 ));
             }
-            let IndexExpression::Column(column_name) = &statement.columns[0] else {
+            let IndexExpression::Column(column_name) = &columns[0] else {
                 unreachable!("spatial index expression already rejected");
             };
             let column = table
@@ -421,7 +505,7 @@ impl EngineRuntime {
                     "{FTS_DDL_ERROR_PREFIX} fulltext indexes cannot be UNIQUE"
                 )));
             }
-            if statement.columns.is_empty() || has_expression {
+            if columns.is_empty() || has_expression {
                 return Err(DbError::sql(format!(
                     "{FTS_DDL_ERROR_PREFIX} fulltext indexes require one or more plain TEXT columns"
                 )));
@@ -436,7 +520,7 @@ impl EngineRuntime {
                     "{FTS_DDL_ERROR_PREFIX} fulltext indexes do not support INCLUDE columns"
                 )));
             }
-            for column in &statement.columns {
+            for column in &columns {
                 let IndexExpression::Column(column_name) = column else {
                     unreachable!("fulltext expression already rejected");
                 };
@@ -461,10 +545,10 @@ impl EngineRuntime {
             if kind != IndexKind::Btree {
                 return Err(DbError::sql("expression indexes must use BTREE"));
             }
-            if statement.unique {
+            if statement.unique && !is_collated_column_key(&columns[0]) {
                 return Err(DbError::sql("UNIQUE expression indexes are not supported"));
             }
-            if statement.columns.len() != 1 {
+            if columns.len() != 1 {
                 return Err(DbError::sql(
                     "expression indexes must define exactly one key expression",
                 ));
@@ -479,12 +563,12 @@ impl EngineRuntime {
             }
         }
         if let Some(_predicate) = &statement.predicate {
-            if kind != IndexKind::Btree || statement.columns.len() != 1 {
+            if kind != IndexKind::Btree || columns.len() != 1 {
                 return Err(DbError::sql(
                     "only single-column BTREE partial indexes are supported",
                 ));
             }
-            let _column_name = match &statement.columns[0] {
+            let _column_name = match &columns[0] {
                 IndexExpression::Column(column_name) => column_name,
                 IndexExpression::Expr(_) => {
                     return Err(DbError::sql(
@@ -494,7 +578,7 @@ impl EngineRuntime {
             };
         }
 
-        for column in &statement.columns {
+        for column in &columns {
             if let IndexExpression::Column(column_name) = column {
                 if !table
                     .columns
@@ -519,7 +603,7 @@ impl EngineRuntime {
                     include_column, table.name
                 )));
             }
-            if statement.columns.iter().any(|column| {
+            if columns.iter().any(|column| {
                 matches!(
                     column,
                     IndexExpression::Column(column_name)
@@ -549,8 +633,7 @@ impl EngineRuntime {
             table_name: table_name.clone(),
             kind,
             unique: statement.unique,
-            columns: statement
-                .columns
+            columns: columns
                 .iter()
                 .map(|column| match column {
                     IndexExpression::Column(column_name) => IndexColumn {
@@ -574,8 +657,7 @@ impl EngineRuntime {
 
         // Drop any redundant auto FK index that covers the same column(s).
         if kind == IndexKind::Btree && !has_expression {
-            let column_names: Vec<String> = statement
-                .columns
+            let column_names: Vec<String> = columns
                 .iter()
                 .filter_map(|c| {
                     if let IndexExpression::Column(n) = c {
@@ -603,7 +685,7 @@ impl EngineRuntime {
         &mut self,
         name: &str,
         if_exists: bool,
-        _page_size: u32,
+        page_size: u32,
     ) -> Result<()> {
         if self.temp_view(name).is_some() {
             if if_exists {
@@ -627,12 +709,18 @@ impl EngineRuntime {
             self.bump_temp_schema_cookie();
             return Ok(());
         }
+        if let Some(parent_name) = self.partitioned_table_name(name) {
+            return self.execute_drop_partitioned_table(&parent_name, page_size);
+        }
         let Some(table_name) = self.canonical_catalog_table_name(name) else {
             if if_exists {
                 return Ok(());
             }
             return Err(DbError::sql(format!("unknown table {name}")));
         };
+        // A dropped partition leaves its parent first, so the parent's view
+        // does not count as depending on it.
+        self.remove_partition(&table_name);
         let dependent_views = super::views::dependent_views(self, &table_name, false);
         if !dependent_views.is_empty() {
             return Err(DbError::sql(format!(
@@ -662,6 +750,10 @@ impl EngineRuntime {
 
         self.catalog_mut().tables.remove(&table_name);
         self.tables_mut().remove(&table_name);
+        self.catalog_mut().column_stats.remove(&table_name);
+        self.catalog_mut().comments.remove(&table_name);
+        self.catalog_mut().table_ttl.remove(&table_name);
+        self.analyze_churn.remove(&table_name);
         self.catalog_mut()
             .indexes
             .retain(|_, index| !identifiers_equal(&index.table_name, &table_name));
@@ -759,6 +851,8 @@ impl EngineRuntime {
             self.catalog_mut()
                 .table_stats
                 .insert(target.clone(), super::TableStats { row_count: 0 });
+            self.catalog_mut().column_stats.remove(target);
+            self.analyze_churn.remove(target);
         }
 
         self.rebuild_indexes(page_size)?;
@@ -771,7 +865,7 @@ impl EngineRuntime {
         table_name: &str,
         actions: &[AlterTableAction],
         params: &[Value],
-        _page_size: u32,
+        page_size: u32,
     ) -> Result<()> {
         if self.temp_table_schema(table_name).is_some() {
             return Err(DbError::sql(
@@ -781,10 +875,31 @@ impl EngineRuntime {
         if self.temp_view(table_name).is_some() && self.catalog.table(table_name).is_none() {
             return Err(DbError::sql(format!("unknown table {table_name}")));
         }
+        if let Some(parent_name) = self.partitioned_table_name(table_name) {
+            let [AlterTableAction::DetachPartition { partition_name }] = actions else {
+                return Err(DbError::sql(format!(
+                    "ALTER TABLE on partitioned table {parent_name} supports only DETACH PARTITION"
+                )));
+            };
+            return self.execute_detach_partition(&parent_name, partition_name);
+        }
+        if actions
+            .iter()
+            .any(|action| matches!(action, AlterTableAction::DetachPartition { .. }))
+        {
+            return Err(DbError::sql(format!(
+                "{table_name} is not a partitioned table"
+            )));
+        }
         let canonical_table_name = self
             .canonical_catalog_table_name(table_name)
             .ok_or_else(|| DbError::sql(format!("unknown table {table_name}")))?;
         let table_name = canonical_table_name.as_str();
+        if let Some(parent_name) = self.catalog.partition_parent(table_name) {
+            return Err(DbError::sql(format!(
+                "cannot alter partition {table_name} of {parent_name}; detach it first"
+            )));
+        }
         if actions
             .iter()
             .any(|action| matches!(action, AlterTableAction::RenameTable { .. }))
@@ -812,7 +927,7 @@ impl EngineRuntime {
                     "ALTER TABLE ADD/DROP CONSTRAINT cannot be combined with other ALTER TABLE actions",
                 ));
             }
-            return self.execute_alter_table_constraint(table_name, &actions[0], _page_size);
+            return self.execute_alter_table_constraint(table_name, &actions[0], page_size);
         }
         let mut table = self
             .catalog
@@ -847,6 +962,8 @@ impl EngineRuntime {
             ));
         }
         self.materialize_table_row_source(table_name)?;
+        let mut comments = self.catalog.comments.get(table_name).cloned();
+        let mut ttl_column = self.catalog.table_ttl.get(table_name).cloned();
         for action in actions {
             match action {
                 AlterTableAction::AddColumn(definition) => {
@@ -874,8 +991,10 @@ impl EngineRuntime {
                         ));
                     }
                     let column = column_schema_from_definition(table_name, definition)?;
+                    let mut uuid_default = None;
                     let fill_value = if let Some(default) = &column.default_sql {
                         let expr = crate::sql::parser::parse_expression_sql(default)?;
+                        uuid_default = default_uuid_generator(&expr);
                         self.eval_expr(
                             &expr,
                             &super::row::Dataset::empty(),
@@ -907,14 +1026,27 @@ impl EngineRuntime {
                         let entry = self.tables_mut().get_mut(table_name).ok_or_else(|| {
                             DbError::internal(format!("table data for {table_name} is missing"))
                         })?;
+                        // A UUID generator gives each existing row its own
+                        // value, as it does each inserted row.
                         entry.resident_data_mut().mutate_visible_rows(|row| {
-                            row.values.push(fill_value.clone());
+                            let value = match uuid_default {
+                                Some(generate) => {
+                                    super::cast_value(generate(Vec::new())?, column.column_type)?
+                                }
+                                None => fill_value.clone(),
+                            };
+                            row.values.push(value);
                             Ok(())
                         })?;
                     }
                     table.columns.push(column);
                 }
                 AlterTableAction::DropColumn { column_name } => {
+                    if !super::views::dependent_views(self, table_name, false).is_empty() {
+                        return Err(DbError::sql(
+                            "DROP COLUMN is rejected when dependent views exist",
+                        ));
+                    }
                     if table
                         .primary_key_columns
                         .iter()
@@ -936,6 +1068,12 @@ impl EngineRuntime {
                             column_name
                         )));
                     }
+                    if ttl_column.as_deref() == Some(column_name.as_str()) {
+                        return Err(DbError::sql(format!(
+                            "cannot drop ttl column {}",
+                            column_name
+                        )));
+                    }
                     if self.catalog.indexes.values().any(|index| {
                         index.table_name == table_name
                             && (index
@@ -954,7 +1092,10 @@ impl EngineRuntime {
                         .iter()
                         .position(|column| column.name == *column_name)
                         .ok_or_else(|| DbError::sql(format!("unknown column {column_name}")))?;
-                    table.columns.remove(index);
+                    let dropped = table.columns.remove(index);
+                    if let Some(comments) = comments.as_mut() {
+                        comments.columns.remove(&dropped.name);
+                    }
                     {
                         let entry = self.tables_mut().get_mut(table_name).ok_or_else(|| {
                             DbError::internal(format!("table data for {table_name} is missing"))
@@ -983,32 +1124,39 @@ impl EngineRuntime {
                         .position(|column| column.name == *old_name)
                         .ok_or_else(|| DbError::sql(format!("unknown column {old_name}")))?;
                     table.columns[column_index].name = new_name.clone();
+                    if let Some(comments) = comments.as_mut() {
+                        if let Some(comment) = comments.columns.remove(old_name) {
+                            comments.columns.insert(new_name.clone(), comment);
+                        }
+                    }
+                    if ttl_column.as_deref() == Some(old_name.as_str()) {
+                        ttl_column = Some(new_name.clone());
+                    }
+                    rename_table_column_references(&mut table, old_name, new_name);
                     rename_column_references(self, table_name, old_name, new_name);
                 }
                 AlterTableAction::AlterColumnType {
                     column_name,
                     new_type,
+                    using,
                 } => {
                     let index = table
                         .columns
                         .iter()
                         .position(|column| column.name == *column_name)
                         .ok_or_else(|| DbError::sql(format!("unknown column {column_name}")))?;
-                    if !matches!(
-                        table.columns[index].column_type,
-                        crate::catalog::ColumnType::Int64
-                            | crate::catalog::ColumnType::Float64
-                            | crate::catalog::ColumnType::Text
-                            | crate::catalog::ColumnType::Bool
-                    ) || !matches!(
-                        new_type,
-                        crate::catalog::ColumnType::Int64
-                            | crate::catalog::ColumnType::Float64
-                            | crate::catalog::ColumnType::Text
-                            | crate::catalog::ColumnType::Bool
-                    ) {
+                    // These types carry label or spatial metadata that a cast
+                    // cannot supply.
+                    let needs_metadata = |column_type: ColumnType| {
+                        matches!(
+                            column_type,
+                            ColumnType::Enum | ColumnType::Geometry | ColumnType::Geography
+                        )
+                    };
+                    if needs_metadata(table.columns[index].column_type) || needs_metadata(*new_type)
+                    {
                         return Err(DbError::sql(
-                            "ALTER COLUMN TYPE supports only INT64, FLOAT64, TEXT, and BOOL",
+                            "ALTER COLUMN TYPE does not support ENUM, GEOMETRY, or GEOGRAPHY columns",
                         ));
                     }
                     if table.columns[index].primary_key {
@@ -1042,13 +1190,53 @@ impl EngineRuntime {
                             column_name
                         )));
                     }
+                    // Convert every row before touching any, so USING can
+                    // read the other columns as they were.
+                    let converted = {
+                        let table_data = self
+                            .tables
+                            .get(table_name)
+                            .ok_or_else(|| {
+                                DbError::internal(format!("table data for {table_name} is missing"))
+                            })?
+                            .resident_data();
+                        let mut converted = Vec::with_capacity(table_data.row_count());
+                        for row in table_data.visible_rows() {
+                            let value = match using {
+                                Some(expr) => {
+                                    let dataset =
+                                        table_row_dataset(&table, &row.values, table_name);
+                                    self.eval_expr(
+                                        expr,
+                                        &dataset,
+                                        &row.values,
+                                        params,
+                                        &std::collections::BTreeMap::new(),
+                                        None,
+                                    )?
+                                }
+                                None => row.values[index].clone(),
+                            };
+                            let value = super::cast_value(value, *new_type)?;
+                            if !table.columns[index].nullable && matches!(value, Value::Null) {
+                                return Err(DbError::constraint(format!(
+                                    "column {}.{} may not be NULL",
+                                    table_name, column_name
+                                )));
+                            }
+                            converted.push(value);
+                        }
+                        converted
+                    };
                     {
                         let entry = self.tables_mut().get_mut(table_name).ok_or_else(|| {
                             DbError::internal(format!("table data for {table_name} is missing"))
                         })?;
+                        let mut converted = converted.into_iter();
                         entry.resident_data_mut().mutate_visible_rows(|row| {
-                            row.values[index] =
-                                super::cast_value(row.values[index].clone(), *new_type)?;
+                            row.values[index] = converted.next().ok_or_else(|| {
+                                DbError::internal("ALTER COLUMN TYPE row count changed")
+                            })?;
                             Ok(())
                         })?;
                     }
@@ -1064,17 +1252,82 @@ impl EngineRuntime {
                         "ALTER TABLE constraint action should have been dispatched earlier",
                     ));
                 }
+                AlterTableAction::DetachPartition { .. } => {
+                    return Err(DbError::internal(
+                        "ALTER TABLE DETACH PARTITION action should have been dispatched earlier",
+                    ));
+                }
             }
         }
 
         self.catalog_mut()
             .tables
             .insert(table_name.to_string(), table);
+        if let Some(comments) = comments {
+            self.catalog_mut()
+                .comments
+                .insert(table_name.to_string(), comments);
+        }
+        if let Some(ttl_column) = ttl_column {
+            self.catalog_mut()
+                .table_ttl
+                .insert(table_name.to_string(), ttl_column);
+        }
+        // Column statistics are keyed by column name and type; recollect them.
+        self.catalog_mut().column_stats.remove(table_name);
+        self.rebuild_altered_table_indexes(table_name, page_size)?;
         self.mark_table_dirty(table_name);
         self.bump_schema_cookie();
         Ok(())
     }
 
+    /// Rebuilds the indexes on a table whose rows ALTER TABLE rewrote. A
+    /// type change can make two keys of a unique index equal, which is
+    /// reported as a constraint violation rather than index corruption.
+    fn rebuild_altered_table_indexes(&mut self, table_name: &str, page_size: u32) -> Result<()> {
+        let indexes = self
+            .catalog
+            .indexes
+            .values()
+            .filter(|index| identifiers_equal(&index.table_name, table_name))
+            .cloned()
+            .collect::<Vec<_>>();
+        {
+            let table = self.catalog.tables.get(table_name).ok_or_else(|| {
+                DbError::internal(format!("table schema for {table_name} is missing"))
+            })?;
+            let table_data = self
+                .tables
+                .get(table_name)
+                .ok_or_else(|| {
+                    DbError::internal(format!("table data for {table_name} is missing"))
+                })?
+                .resident_data();
+            for index in indexes
+                .iter()
+                .filter(|index| index.unique && index.kind == IndexKind::Btree)
+            {
+                let mut keys = HashSet::with_capacity(table_data.row_count());
+                for row in table_data.visible_rows() {
+                    let Some(key) = super::compute_index_key(self, index, table, &row.values)?
+                    else {
+                        continue;
+                    };
+                    if !keys.insert(key) {
+                        return Err(DbError::constraint(format!(
+                            "unique constraint {} on {} was violated",
+                            index.name, table_name
+                        )));
+                    }
+                }
+            }
+        }
+        for index in indexes {
+            self.rebuild_index(&index.name, page_size)?;
+        }
+        Ok(())
+    }
+
     fn materialize_table_row_source(&mut self, table_name: &str) -> Result<()> {
         if !matches!(
             self.table_row_source(table_name),
@@ -1251,9 +1504,10 @@ impl EngineRuntime {
                         constraint_name,
                     )?;
                 }
-                let index_name = name
-                    .clone()
-                    .unwrap_or_else(|| auto_index_name("uq", &table_name, columns));
+                let index_name = name.as_ref().map_or_else(
+                    || auto_index_name("uq", &table_name, columns),
+                    |name| super::schema_owned_object_name(&table_name, name),
+                );
                 let index = IndexSchema {
                     name: index_name.clone(),
                     table_name: table_name.clone(),
@@ -1361,7 +1615,7 @@ impl EngineRuntime {
                 "ALTER TABLE RENAME TO cannot move a persistent table into temp",
             ));
         }
-        let new_name = new_object.to_string();
+        let new_name = super::schema_owned_object_name(table_name, new_object);
         if self.catalog.contains_object(&new_name) {
             return Err(DbError::sql(format!("object {} already exists", new_name)));
         }
@@ -1401,6 +1655,24 @@ impl EngineRuntime {
                 .table_stats
                 .insert(new_name.clone(), stats);
         }
+        if let Some(stats) = self.catalog_mut().column_stats.remove(&old_table_name) {
+            self.catalog_mut()
+                .column_stats
+                .insert(new_name.clone(), stats);
+        }
+        if let Some(comments) = self.catalog_mut().comments.remove(&old_table_name) {
+            self.catalog_mut()
+                .comments
+                .insert(new_name.clone(), comments);
+        }
+        if let Some(ttl_column) = self.catalog_mut().table_ttl.remove(&old_table_name) {
+            self.catalog_mut()
+                .table_ttl
+                .insert(new_name.clone(), ttl_column);
+        }
+        if let Some(churn) = self.analyze_churn.remove(&old_table_name) {
+            self.analyze_churn.insert(new_name.clone(), churn);
+        }
         if self.dirty_tables_mut().remove(&old_table_name) {
             self.dirty_tables_mut().insert(new_name.clone());
         }
@@ -1413,6 +1685,58 @@ impl EngineRuntime {
         Ok(())
     }
 
+    pub(super) fn execute_comment_on(
+        &mut self,
+        table_name: &str,
+        column_name: Option<&str>,
+        comment: Option<String>,
+    ) -> Result<()> {
+        if self.temp_table_schema(table_name).is_some() {
+            return Err(DbError::sql(
+                "COMMENT ON is not supported for temporary tables",
+            ));
+        }
+        let table_name = self
+            .canonical_catalog_table_name(table_name)
+            .ok_or_else(|| DbError::sql(format!("unknown table {table_name}")))?;
+        let table = self.catalog.tables.get(&table_name).ok_or_else(|| {
+            DbError::internal(format!("table schema for {table_name} is missing"))
+        })?;
+        let column_name = column_name
+            .map(|column_name| {
+                table
+                    .columns
+                    .iter()
+                    .find(|column| identifiers_equal(&column.name, column_name))
+                    .map(|column| column.name.clone())
+                    .ok_or_else(|| DbError::sql(format!("unknown column {column_name}")))
+            })
+            .transpose()?;
+
+        let mut comments = self
+            .catalog
+            .comments
+            .get(&table_name)
+            .cloned()
+            .unwrap_or_default();
+        match (column_name, comment) {
+            (None, comment) => comments.table = comment,
+            (Some(column_name), Some(comment)) => {
+                comments.columns.insert(column_name, comment);
+            }
+            (Some(column_name), None) => {
+                comments.columns.remove(&column_name);
+            }
+        }
+        if comments.is_empty() {
+            self.catalog_mut().comments.remove(&table_name);
+        } else {
+            self.catalog_mut().comments.insert(table_name, comments);
+        }
+        self.bump_schema_cookie();
+        Ok(())
+    }
+
     fn insert_index_schema(&mut self, index: IndexSchema) -> Result<()> {
         if self.catalog.contains_object(&index.name) {
             return Err(DbError::sql(format!(
@@ -1469,6 +1793,60 @@ fn collect_truncate_targets(
     Ok(())
 }
 
+/// Rewrites the keys of a full BTREE index for collations. A single-column
+/// key on a collated column inherits the column's collation, so it is stored
+/// as a `column COLLATE ...` expression key whose entries are folded by
+/// `fold_collation_key`; an explicit `COLLATE BINARY` keeps a plain key.
+/// Multi-column keys compare exact bytes.
+fn collated_index_key_columns(
+    table: &TableSchema,
+    columns: &[IndexExpression],
+) -> Vec<IndexExpression> {
+    let inherit = columns.len() == 1;
+    columns
+        .iter()
+        .map(|column| match column {
+            IndexExpression::Expr(Expr::Collate {
+                expr,
+                collation: Collation::Binary,
+            }) => match expr.as_ref() {
+                Expr::Column {
+                    table: None,
+                    column,
+                } => IndexExpression::Column(column.clone()),
+                other => IndexExpression::Expr(other.clone()),
+            },
+            IndexExpression::Column(column_name) if inherit => {
+                let collation = table
+                    .columns
+                    .iter()
+                    .find(|column| identifiers_equal(&column.name, column_name))
+                    .and_then(|column| column.collation);
+                match collation {
+                    Some(collation) => IndexExpression::Expr(Expr::Collate {
+                        expr: Box::new(Expr::Column {
+                            table: None,
+                            column: column_name.clone(),
+                        }),
+                        collation: collation.into(),
+                    }),
+                    None => column.clone(),
+                }
+            }
+            other => other.clone(),
+        })
+        .collect()
+}
+
+/// True for a `column COLLATE ...` key, which may back a UNIQUE index
+/// because its entries are plain folded column values.
+fn is_collated_column_key(column: &IndexExpression) -> bool {
+    matches!(
+        column,
+        IndexExpression::Expr(Expr::Collate { expr, .. }) if matches!(expr.as_ref(), Expr::Column { .. })
+    )
+}
+
 fn column_schema_from_definition(
     table_name: &str,
     definition: &ColumnDefinition,
@@ -1487,6 +1865,7 @@ fn column_schema_from_definition(
         column_type: definition.column_type,
         spatial_type: definition.spatial_type,
         enum_type,
+        collation: column_collation_for_definition(definition)?,
         nullable: definition.nullable && !definition.primary_key,
         default_sql: definition
             .generated
@@ -1514,6 +1893,34 @@ fn column_schema_from_definition(
     })
 }
 
+/// Resolves the collation a new column stores: its own `COLLATE`, or for a
+/// TEXT column declared without one, the handle's `DbConfig::default_collation`.
+fn column_collation_for_definition(
+    definition: &ColumnDefinition,
+) -> Result<Option<ColumnCollation>> {
+    let collation = match &definition.collation {
+        Some(collation) => collation.clone(),
+        None if definition.column_type == ColumnType::Text => {
+            return Ok(default_collation());
+        }
+        None => return Ok(None),
+    };
+    match collation {
+        Collation::Binary => Ok(None),
+        _ if definition.column_type != ColumnType::Text => Err(DbError::sql(format!(
+            "COLLATE {} requires a TEXT column, but {} is {}",
+            collation.to_sql(),
+            definition.name,
+            definition.column_type.as_str()
+        ))),
+        Collation::NoCase => Ok(Some(ColumnCollation::NoCase)),
+        Collation::RTrim => Ok(Some(ColumnCollation::RTrim)),
+        Collation::Extension(name) => Err(DbError::sql(format!(
+            "collation {name} cannot be stored on a column"
+        ))),
+    }
+}
+
 fn enum_type_for_column(
     table_name: &str,
     definition: &ColumnDefinition,
@@ -1652,6 +2059,31 @@ fn ensure_unique_column_names(columns: &[ColumnSchema], table_name: &str) -> Res
     Ok(())
 }
 
+/// Resolves a `ttl_column` option to the catalog spelling of a TIMESTAMP or
+/// TIMESTAMPTZ column of `table`.
+fn ttl_column_name(table: &TableSchema, name: &str) -> Result<String> {
+    let column = table
+        .columns
+        .iter()
+        .find(|column| identifiers_equal(&column.name, name))
+        .ok_or_else(|| {
+            DbError::sql(format!(
+                "ttl_column {} does not exist on {}",
+                name, table.name
+            ))
+        })?;
+    if !matches!(
+        column.column_type,
+        ColumnType::Timestamp | ColumnType::TimestampTz
+    ) {
+        return Err(DbError::sql(format!(
+            "ttl_column {} must be TIMESTAMP or TIMESTAMPTZ",
+            column.name
+        )));
+    }
+    Ok(column.name.clone())
+}
+
 fn validate_generated_columns(runtime: &EngineRuntime, table: &TableSchema) -> Result<()> {
     let row = vec![Value::Null; table.columns.len()];
     let dataset = table_row_dataset(table, &row, &table.name);
@@ -1887,27 +2319,38 @@ fn identifier_lists_equal(left: &[String], right: &[String]) -> bool {
             .all(|(left, right)| identifiers_equal(left, right))
 }
 
+/// Renames a column in the constraints of its own table. The caller holds
+/// the schema being altered, which replaces the catalog entry afterwards.
+fn rename_table_column_references(table: &mut TableSchema, old_name: &str, new_name: &str) {
+    let rename = |column_name: &mut String| {
+        if column_name == old_name {
+            *column_name = new_name.to_string();
+        }
+    };
+    table.primary_key_columns.iter_mut().for_each(rename);
+    let table_name = table.name.clone();
+    let foreign_keys = table.foreign_keys.iter_mut().chain(
+        table
+            .columns
+            .iter_mut()
+            .filter_map(|column| column.foreign_key.as_mut()),
+    );
+    for foreign_key in foreign_keys {
+        foreign_key.columns.iter_mut().for_each(rename);
+        if foreign_key.referenced_table == table_name {
+            foreign_key.referenced_columns.iter_mut().for_each(rename);
+        }
+    }
+}
+
+/// Renames a column in the indexes on its table and in the foreign keys of
+/// other tables that reference it.
 fn rename_column_references(
     runtime: &mut EngineRuntime,
     table_name: &str,
     old_name: &str,
     new_name: &str,
 ) {
-    if let Some(table) = runtime.catalog_mut().tables.get_mut(table_name) {
-        for primary_key_column in &mut table.primary_key_columns {
-            if primary_key_column == old_name {
-                *primary_key_column = new_name.to_string();
-            }
-        }
-        for foreign_key in &mut table.foreign_keys {
-            for column_name in &mut foreign_key.columns {
-                if column_name == old_name {
-                    *column_name = new_name.to_string();
-                }
-            }
-        }
-    }
-
     for index in runtime.catalog_mut().indexes.values_mut() {
         if index.table_name == table_name {
             for column in &mut index.columns {
@@ -1927,7 +2370,16 @@ fn rename_column_references(
     }
 
     for table in runtime.catalog_mut().tables.values_mut() {
-        for foreign_key in &mut table.foreign_keys {
+        if table.name == table_name {
+            continue;
+        }
+        let foreign_keys = table.foreign_keys.iter_mut().chain(
+            table
+                .columns
+                .iter_mut()
+                .filter_map(|column| column.foreign_key.as_mut()),
+        );
+        for foreign_key in foreign_keys {
             if foreign_key.referenced_table == table_name {
                 for column_name in &mut foreign_key.referenced_columns {
                     if column_name == old_name {
@@ -20,6 +20,7 @@ use crate::sql::ast::{
 use crate::sql::parser::parse_expression_sql;
 use crate::sync::{self, SyncOperation};
 
+use super::constraints::foreign_keys_enforced;
 use super::row::{ColumnBinding, Dataset, QueryResult, QueryRow};
 use super::{
     compare_values, compute_index_key, compute_index_values, covering_payload_values_for_row,
@@ -322,17 +323,20 @@ impl EngineRuntime {
     fn record_sync_update_for_row(
         &mut self,
         table: &crate::catalog::TableSchema,
+        old_values: &[Value],
         values: &[Value],
     ) {
         if !self.should_record_sync_mutation_for_table(table) {
             return;
         }
         let pk = sync::build_primary_key_json(table, values);
+        let before = sync::build_after_json(table, old_values);
         let after = sync::build_after_json(table, values);
         self.record_sync_mutation(
             &table.name,
             SyncOperation::Update,
             pk,
+            Some(before),
             Some(after),
             self.catalog.schema_cookie,
         );
@@ -347,10 +351,12 @@ impl EngineRuntime {
             return;
         }
         let pk = sync::build_primary_key_json(table, values);
+        let before = sync::build_after_json(table, values);
         self.record_sync_mutation(
             &table.name,
             SyncOperation::Delete,
             pk,
+            Some(before),
             None,
             self.catalog.schema_cookie,
         );
@@ -953,6 +959,11 @@ impl EngineRuntime {
         let table = self
             .table_schema(&statement.table_name)
             .ok_or_else(|| DbError::sql(format!("unknown table {}", statement.table_name)))?;
+        // The prepared lookup compares stored bytes; collated columns need
+        // the general path.
+        if table_has_collated_columns(table) {
+            return Ok(None);
+        }
         let Some(filter) = statement.filter.as_ref() else {
             return Ok(None);
         };
@@ -1206,15 +1217,17 @@ impl EngineRuntime {
                             (
                                 schema.name.clone(),
                                 sync::build_primary_key_json(schema, &next_values),
+                                sync::build_after_json(schema, &current_values),
                                 sync::build_after_json(schema, &next_values),
                             )
                         });
-                    if let Some((table_name, pk, after)) = sync_info {
+                    if let Some((table_name, pk, before, after)) = sync_info {
                         let schema_cookie = self.catalog.schema_cookie;
                         self.record_sync_mutation(
                             &table_name,
                             SyncOperation::Update,
                             pk,
+                            Some(before),
                             Some(after),
                             schema_cookie,
                         );
@@ -1306,15 +1319,17 @@ impl EngineRuntime {
                             (
                                 schema.name.clone(),
                                 sync::build_primary_key_json(schema, &next_values),
+                                sync::build_after_json(schema, &current_values),
                                 sync::build_after_json(schema, &next_values),
                             )
                         });
-                    if let Some((table_name, pk, after)) = sync_data {
+                    if let Some((table_name, pk, before, after)) = sync_data {
                         let schema_cookie = self.catalog.schema_cookie;
                         self.record_sync_mutation(
                             &table_name,
                             SyncOperation::Update,
                             pk,
+                            Some(before),
                             Some(after),
                             schema_cookie,
                         );
@@ -1339,6 +1354,9 @@ impl EngineRuntime {
             .table_schema(&statement.table_name)
             .cloned()
             .ok_or_else(|| DbError::sql(format!("unknown table {}", statement.table_name)))?;
+        if table_has_collated_columns(&table) {
+            return Ok(None);
+        }
         let Some(restrict_children) =
             prepare_simple_delete_prepared_restrict_children(self, &table)?
         else {
@@ -1583,7 +1601,12 @@ impl EngineRuntime {
             && !self.should_record_sync_mutation_for_table(&prepared.table)
             && self.prepared_simple_delete_restrict_children_are_row_id_index_checks(prepared);
         if can_fast_delete_by_row_id {
-            for child in &prepared.restrict_children {
+            let restrict_children = if foreign_keys_enforced() {
+                prepared.restrict_children.as_slice()
+            } else {
+                &[]
+            };
+            for child in restrict_children {
                 let Some(index_name) = child.child_index_name.as_deref() else {
                     continue;
                 };
@@ -1660,6 +1683,7 @@ impl EngineRuntime {
                         TableRowSource::Paged(Arc::new(updated_manifest)),
                     )?;
                     self.mark_table_dirty(&prepared.table_name);
+                    self.note_analyze_churn(&prepared.table_name, deleted_row_ids.len() as u64);
                     stale_indexes.extend(prepared.indexes.iter().map(|index| index.name.clone()));
                 }
                 None => {
@@ -1770,14 +1794,17 @@ impl EngineRuntime {
             }
         } else {
             self.mark_table_dirty(&prepared.table_name);
+            self.note_analyze_churn(&prepared.table_name, removed_rows.len() as u64);
         }
         if self.should_record_sync_mutation_for_table(&prepared.table) {
             for row in &removed_rows {
                 let pk = sync::build_primary_key_json(&prepared.table, &row.values);
+                let before = sync::build_after_json(&prepared.table, &row.values);
                 self.record_sync_mutation(
                     &prepared.table.name,
                     SyncOperation::Delete,
                     pk,
+                    Some(before),
                     None,
                     self.catalog.schema_cookie,
                 );
@@ -1865,6 +1892,7 @@ impl EngineRuntime {
                     &schema.name,
                     SyncOperation::Insert,
                     pk,
+                    None,
                     Some(after),
                     schema_cookie,
                 );
@@ -1912,6 +1940,7 @@ impl EngineRuntime {
                     &schema.name,
                     SyncOperation::Insert,
                     pk,
+                    None,
                     Some(after),
                     schema_cookie,
                 );
@@ -2394,6 +2423,99 @@ impl EngineRuntime {
         )
     }
 
+    /// Applies row changes from [`EngineRuntime::encode_table_changes`] to
+    /// persistent table `table_name`: `deleted` rows are removed and each
+    /// row of `upserted` replaces the row with its id or is inserted.
+    /// Indexes are updated row by row; returns whether any had to be marked
+    /// stale instead.
+    pub(crate) fn apply_table_changes(
+        &mut self,
+        table_name: &str,
+        deleted: &[i64],
+        upserted: &[u8],
+        next_row_id: i64,
+        page_size: u32,
+    ) -> Result<bool> {
+        let table = self
+            .table_schema(table_name)
+            .cloned()
+            .ok_or_else(|| DbError::sql(format!("unknown table {table_name}")))?;
+        let table_indexes = self
+            .catalog
+            .indexes
+            .values()
+            .filter(|index| identifiers_equal(&index.table_name, &table.name))
+            .cloned()
+            .collect::<Vec<_>>();
+        let row_source = self.table_row_source(&table.name).ok_or_else(|| {
+            DbError::internal(format!("table data for {} is missing", table.name))
+        })?;
+        let mut removed = Vec::with_capacity(deleted.len());
+        for &row_id in deleted {
+            if let Some(row) = row_source.row_by_id(row_id)? {
+                removed.push(StoredRow {
+                    row_id,
+                    values: row.values().to_vec(),
+                });
+            }
+        }
+        let mut updated = Vec::new();
+        let mut inserted = Vec::new();
+        for row in super::decode_table_payload_rows(upserted)? {
+            match row_source.row_by_id(row.row_id)? {
+                Some(current) => updated.push((
+                    StoredRow {
+                        row_id: row.row_id,
+                        values: current.values().to_vec(),
+                    },
+                    row,
+                )),
+                None => inserted.push(row),
+            }
+        }
+
+        let mut stale_indexes = incremental_delete_indexes(self, &table, &table_indexes, &removed)?;
+        stale_indexes.extend(incremental_update_indexes(
+            self,
+            &table,
+            &table_indexes,
+            &updated,
+        )?);
+        stale_indexes.extend(incremental_insert_indexes(
+            self,
+            &table,
+            &table_indexes,
+            &inserted,
+        )?);
+        stale_indexes.sort();
+        stale_indexes.dedup();
+
+        let mut row_changes = removed
+            .iter()
+            .map(|row| (row.row_id, None))
+            .collect::<BTreeMap<_, _>>();
+        row_changes.extend(
+            updated
+                .into_iter()
+                .map(|(_, row)| (row.row_id, Some(row.values))),
+        );
+        self.apply_row_changes_to_table_row_source(&table.name, &row_changes, page_size)?;
+        for row in inserted {
+            self.append_owned_stored_row_to_table_row_source_with_mode(
+                &table.name,
+                row,
+                page_size,
+                true,
+            )?;
+        }
+        if let Some(schema) = self.catalog_mut().tables.get_mut(&table.name) {
+            schema.next_row_id = schema.next_row_id.max(next_row_id);
+        }
+        self.mark_table_dirty(&table.name);
+        self.mark_named_indexes_stale(&stale_indexes);
+        Ok(!stale_indexes.is_empty())
+    }
+
     fn apply_row_changes_to_resident_table_data(
         table_data: &mut super::TableData,
         row_changes: &BTreeMap<i64, Option<Vec<Value>>>,
@@ -2426,6 +2548,9 @@ impl EngineRuntime {
         params: &[Value],
         page_size: u32,
     ) -> Result<QueryResult> {
+        if let Some(result) = self.try_execute_partitioned_insert(statement, params, page_size)? {
+            return Ok(result);
+        }
         if self
             .visible_view(&statement.table_name, super::NameResolutionScope::Session)
             .is_some()
@@ -2715,7 +2840,7 @@ impl EngineRuntime {
                     values: next_values.clone(),
                 });
             }
-            self.record_sync_update_for_row(table, &next_values);
+            self.record_sync_update_for_row(table, &current_row.values, &next_values);
             row_changes.insert(row_id, Some(next_values));
             affected_rows += 1;
             changed_rows += 1;
@@ -3007,7 +3132,7 @@ impl EngineRuntime {
                 }
             }
 
-            self.record_sync_update_for_row(table, &next_values);
+            self.record_sync_update_for_row(table, &current_row.values, &next_values);
             row_changes.insert(row_id, Some(next_values.clone()));
             if !returning.is_empty() {
                 returning_rows.push(StoredRow {
@@ -3146,7 +3271,7 @@ impl EngineRuntime {
                 }
             }
 
-            self.record_sync_update_for_row(table, &next_values);
+            self.record_sync_update_for_row(table, &current_row.values, &next_values);
             row_changes.insert(row_id, Some(next_values.clone()));
             if !returning.is_empty() {
                 returning_rows.push(StoredRow {
@@ -3331,7 +3456,7 @@ impl EngineRuntime {
                     old_values,
                     &next_values,
                 );
-                self.record_sync_update_for_row(table, &next_values);
+                self.record_sync_update_for_row(table, old_values, &next_values);
             } else {
                 // No index touches the updated column: write just the changed
                 // value back without cloning the rest of the row.
@@ -3371,7 +3496,7 @@ impl EngineRuntime {
                     &old_values,
                     &next_values,
                 );
-                self.record_sync_update_for_row(table, &next_values);
+                self.record_sync_update_for_row(table, &old_values, &next_values);
             }
             changed_rows += 1;
             affected_rows += 1;
@@ -3532,7 +3657,7 @@ impl EngineRuntime {
                     old_values,
                     &next_values,
                 );
-                self.record_sync_update_for_row(table, &next_values);
+                self.record_sync_update_for_row(table, old_values, &next_values);
                 if !returning.is_empty() {
                     returning_rows.push(StoredRow {
                         row_id,
@@ -3579,7 +3704,7 @@ impl EngineRuntime {
                     &old_values,
                     &next_values,
                 );
-                self.record_sync_update_for_row(table, &next_values);
+                self.record_sync_update_for_row(table, &old_values, &next_values);
             }
 
             changed_rows += 1;
@@ -3614,6 +3739,9 @@ impl EngineRuntime {
         params: &[Value],
         page_size: u32,
     ) -> Result<QueryResult> {
+        if let Some(result) = self.try_execute_partitioned_update(statement, params, page_size)? {
+            return Ok(result);
+        }
         if self
             .visible_view(&statement.table_name, super::NameResolutionScope::Session)
             .is_some()
@@ -3852,6 +3980,7 @@ impl EngineRuntime {
                         };
                         let mut updated_values = None;
                         if current_value != &next_email {
+                            let old_value = current_value.clone();
                             table_data
                                 .replace_value(row_index, column_index, next_email)
                                 .ok_or_else(|| {
@@ -3859,19 +3988,24 @@ impl EngineRuntime {
                                         "column index {column_index} is invalid for {table_name}"
                                     ))
                                 })?;
-                            updated_values = Some(table_data.rows[row_index].values.clone());
+                            updated_values =
+                                Some((old_value, table_data.rows[row_index].values.clone()));
                         }
                         let returning_values = table_data.rows[row_index].values.clone();
                         (row_index, returning_values, updated_values)
                     };
-                    if let Some(updated_values) = updated_values {
+                    if let Some((old_value, updated_values)) = updated_values {
                         self.mark_table_row_dirty(
                             &table_name,
                             row_index,
                             single_row_id,
                             &updated_values,
                         );
-                        self.record_sync_update_for_row(&table, &updated_values);
+                        if self.mutation_capture_active() {
+                            let mut old_values = updated_values.clone();
+                            old_values[column_index] = old_value;
+                            self.record_sync_update_for_row(&table, &old_values, &updated_values);
+                        }
                     }
                     self.execute_after_triggers(&table_name, TriggerEvent::Update, 1, page_size)?;
                     if statement.returning.is_empty() {
@@ -3989,7 +4123,7 @@ impl EngineRuntime {
                         single_row_id,
                         &updated_values,
                     );
-                    self.record_sync_update_for_row(&table, &updated_values);
+                    self.record_sync_update_for_row(&table, &current_row.values, &updated_values);
                 }
                 self.execute_after_triggers(&table_name, TriggerEvent::Update, 1, page_size)?;
                 if statement.returning.is_empty() {
@@ -4128,7 +4262,7 @@ impl EngineRuntime {
                 .replace_row_values(row_index, next_values.clone())
                 .ok_or_else(|| DbError::internal(format!("row {row_id} vanished during UPDATE")))?;
             self.mark_table_row_dirty(&table_name, row_index, row_id, &next_values);
-            self.record_sync_update_for_row(&table, &next_values);
+            self.record_sync_update_for_row(&table, &current_row.values, &next_values);
             if let Some(values) = returning_values {
                 returning_rows.push(StoredRow { row_id, values });
             }
@@ -4159,6 +4293,9 @@ impl EngineRuntime {
         params: &[Value],
         page_size: u32,
     ) -> Result<QueryResult> {
+        if let Some(result) = self.try_execute_partitioned_delete(statement, params, page_size)? {
+            return Ok(result);
+        }
         if self
             .visible_view(&statement.table_name, super::NameResolutionScope::Session)
             .is_some()
@@ -5113,6 +5250,7 @@ impl EngineRuntime {
                     TableRowSource::Paged(Arc::new(updated_manifest)),
                 )?;
                 self.mark_table_dirty(table_name);
+                self.note_analyze_churn(table_name, 1);
                 Ok(Some(StoredRow {
                     row_id,
                     values: next_values,
@@ -5129,7 +5267,7 @@ impl EngineRuntime {
         params: &[Value],
         page_size: u32,
     ) -> Result<()> {
-        if table.temporary {
+        if table.temporary || !foreign_keys_enforced() {
             return Ok(());
         }
         let parent_row = StoredRow {
@@ -5161,7 +5299,7 @@ impl EngineRuntime {
         params: &[Value],
         page_size: u32,
     ) -> Result<()> {
-        if table.temporary {
+        if table.temporary || !foreign_keys_enforced() {
             return Ok(());
         }
         if rows.is_empty() {
@@ -5395,7 +5533,7 @@ impl EngineRuntime {
         params: &[Value],
         page_size: u32,
     ) -> Result<()> {
-        if table.temporary {
+        if table.temporary || !foreign_keys_enforced() {
             return Ok(());
         }
         let referencing_tables =
@@ -6230,7 +6368,12 @@ fn validate_prepared_insert(
             }
         }
     }
-    for foreign_key in &prepared.foreign_keys {
+    let foreign_keys = if foreign_keys_enforced() {
+        prepared.foreign_keys.as_slice()
+    } else {
+        &[]
+    };
+    for foreign_key in foreign_keys {
         let child_values = foreign_key
             .child_column_indexes
             .iter()
@@ -6969,7 +7112,7 @@ fn apply_prepared_btree_index_update_for_row_change(
     Ok(true)
 }
 
-fn materialize_insert_source(
+pub(super) fn materialize_insert_source(
     runtime: &EngineRuntime,
     source: &InsertSource,
     params: &[Value],
@@ -7373,6 +7516,7 @@ fn is_company_revenue_summary_query(query: &Query) -> bool {
         || select.having.is_some()
         || select.distinct
         || !select.distinct_on.is_empty()
+        || !select.grouping_sets.is_empty()
         || select.group_by.len() != 1
         || !is_summary_column(&select.group_by[0], &["c", "companies"], "id")
     {
@@ -8622,6 +8766,13 @@ fn cast_prepared_simple_value(value: Value, column_type: ColumnType) -> Result<V
     super::cast_value(value, column_type)
 }
 
+fn table_has_collated_columns(table: &TableSchema) -> bool {
+    table
+        .columns
+        .iter()
+        .any(|column| column.collation.is_some())
+}
+
 fn prepare_simple_delete_restrict_children(
     runtime: &EngineRuntime,
     table: &crate::catalog::TableSchema,
@@ -9347,6 +9498,9 @@ fn prepared_delete_has_referencing_child(
     child: &PreparedSimpleDeleteRestrictChild,
     parent_row: &[Value],
 ) -> Result<bool> {
+    if !foreign_keys_enforced() {
+        return Ok(false);
+    }
     let parent_values = child
         .parent_column_indexes
         .iter()
@@ -9920,7 +10074,6 @@ fn incremental_insert_indexes(
 /// updated incrementally.
 ///
 /// See [`incremental_delete_indexes`] for the rationale.
-#[allow(dead_code)]
 fn incremental_update_indexes(
     runtime: &mut EngineRuntime,
     table: &crate::catalog::TableSchema,
@@ -11162,6 +11315,7 @@ mod tests {
                 column_type: crate::catalog::ColumnType::Int64,
                 spatial_type: None,
                 enum_type: None,
+                collation: None,
                 nullable: false,
                 default_sql: None,
                 generated_sql: None,
@@ -11277,6 +11431,7 @@ mod tests {
                 column_type: crate::catalog::ColumnType::Int64,
                 spatial_type: None,
                 enum_type: None,
+                collation: None,
                 nullable: false,
                 default_sql: None,
                 generated_sql: None,
@@ -11310,6 +11465,7 @@ mod tests {
                 column_type: crate::catalog::ColumnType::Int64,
                 spatial_type: None,
                 enum_type: None,
+                collation: None,
                 nullable: false,
                 default_sql: None,
                 generated_sql: None,
@@ -11336,6 +11492,7 @@ mod tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -11351,6 +11508,7 @@ mod tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -11413,6 +11571,7 @@ mod tests {
                 column_type: crate::catalog::ColumnType::Int64,
                 spatial_type: None,
                 enum_type: None,
+                collation: None,
                 nullable: false,
                 default_sql: None,
                 generated_sql: None,
@@ -11439,6 +11598,7 @@ mod tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -11454,6 +11614,7 @@ mod tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: true,
                     default_sql: None,
                     generated_sql: None,
@@ -11518,6 +11679,7 @@ mod tests {
                 column_type: crate::catalog::ColumnType::Int64,
                 spatial_type: None,
                 enum_type: None,
+                collation: None,
                 nullable: false,
                 default_sql: None,
                 generated_sql: None,
@@ -11544,6 +11706,7 @@ mod tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -11559,6 +11722,7 @@ mod tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -11751,6 +11915,7 @@ mod tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -11766,6 +11931,7 @@ mod tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -11781,6 +11947,7 @@ mod tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -11883,6 +12050,7 @@ mod tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -11898,6 +12066,7 @@ mod tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -11925,6 +12094,7 @@ mod tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -11940,6 +12110,7 @@ mod tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -11955,6 +12126,7 @@ mod tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -12168,6 +12340,7 @@ mod tests {
                 column_type: crate::catalog::ColumnType::Int64,
                 spatial_type: None,
                 enum_type: None,
+                collation: None,
                 nullable: false,
                 default_sql: None,
                 generated_sql: None,
@@ -12194,6 +12367,7 @@ mod tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -12209,6 +12383,7 @@ mod tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -12269,6 +12444,7 @@ mod tests {
                 column_type: crate::catalog::ColumnType::Int64,
                 spatial_type: None,
                 enum_type: None,
+                collation: None,
                 nullable: false,
                 default_sql: None,
                 generated_sql: None,
@@ -12295,6 +12471,7 @@ mod tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -12310,6 +12487,7 @@ mod tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -12381,6 +12559,7 @@ mod tests {
                 column_type: crate::catalog::ColumnType::Int64,
                 spatial_type: None,
                 enum_type: None,
+                collation: None,
                 nullable: false,
                 default_sql: None,
                 generated_sql: None,
@@ -12407,6 +12586,7 @@ mod tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -12422,6 +12602,7 @@ mod tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: true,
                     default_sql: None,
                     generated_sql: None,
@@ -12493,6 +12674,7 @@ mod tests {
                 column_type: crate::catalog::ColumnType::Int64,
                 spatial_type: None,
                 enum_type: None,
+                collation: None,
                 nullable: false,
                 default_sql: None,
                 generated_sql: None,
@@ -12519,6 +12701,7 @@ mod tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -12534,6 +12717,7 @@ mod tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: true,
                     default_sql: None,
                     generated_sql: None,
@@ -12609,6 +12793,7 @@ mod tests {
                 column_type: crate::catalog::ColumnType::Int64,
                 spatial_type: None,
                 enum_type: None,
+                collation: None,
                 nullable: false,
                 default_sql: None,
                 generated_sql: None,
@@ -12635,6 +12820,7 @@ mod tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -12650,6 +12836,7 @@ mod tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -12724,6 +12911,7 @@ mod apply_conflict_tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -12739,6 +12927,7 @@ mod apply_conflict_tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -12800,6 +12989,7 @@ mod apply_conflict_tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -12815,6 +13005,7 @@ mod apply_conflict_tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: Some("1".to_string()),
@@ -12830,6 +13021,7 @@ mod apply_conflict_tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -12888,6 +13080,7 @@ mod apply_conflict_tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -12903,6 +13096,7 @@ mod apply_conflict_tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -12971,6 +13165,7 @@ mod apply_conflict_tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -12986,6 +13181,7 @@ mod apply_conflict_tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -13052,6 +13248,7 @@ mod apply_conflict_tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -13067,6 +13264,7 @@ mod apply_conflict_tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -13245,6 +13443,7 @@ mod dml_private_tests {
                 column_type: crate::catalog::ColumnType::Int64,
                 spatial_type: None,
                 enum_type: None,
+                collation: None,
                 nullable: false,
                 default_sql: None,
                 generated_sql: None,
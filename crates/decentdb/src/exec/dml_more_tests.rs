@@ -20,6 +20,7 @@ mod tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -35,6 +36,7 @@ mod tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -101,6 +103,7 @@ mod tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -116,6 +119,7 @@ mod tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -180,6 +184,7 @@ mod tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -195,6 +200,7 @@ mod tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -232,6 +238,7 @@ mod tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: Some("expr".to_string()),
@@ -247,6 +254,7 @@ mod tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -289,6 +297,7 @@ mod tests {
                 column_type: crate::catalog::ColumnType::Int64,
                 spatial_type: None,
                 enum_type: None,
+                collation: None,
                 nullable: false,
                 default_sql: None,
                 generated_sql: None,
@@ -331,6 +340,7 @@ mod tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: true,
                     default_sql: None,
                     generated_sql: None,
@@ -346,6 +356,7 @@ mod tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: true,
                     default_sql: None,
                     generated_sql: None,
@@ -420,6 +431,7 @@ mod tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -435,6 +447,7 @@ mod tests {
                     column_type: crate::catalog::ColumnType::Text,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -532,6 +545,7 @@ mod tests {
                 column_type: crate::catalog::ColumnType::Int64,
                 spatial_type: None,
                 enum_type: None,
+                collation: None,
                 nullable: false,
                 default_sql: None,
                 generated_sql: None,
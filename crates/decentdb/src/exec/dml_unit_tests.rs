@@ -30,6 +30,7 @@ mod tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -45,6 +46,7 @@ mod tests {
                     column_type: crate::catalog::ColumnType::Text,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: true,
                     default_sql: None,
                     generated_sql: None,
@@ -87,6 +89,7 @@ mod tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -102,6 +105,7 @@ mod tests {
                     column_type: crate::catalog::ColumnType::Text,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -152,6 +156,7 @@ mod tests {
                         column_type: crate::catalog::ColumnType::Int64,
                         spatial_type: None,
                         enum_type: None,
+                        collation: None,
                         nullable: false,
                         default_sql: None,
                         generated_sql: None,
@@ -167,6 +172,7 @@ mod tests {
                         column_type: crate::catalog::ColumnType::Uuid,
                         spatial_type: None,
                         enum_type: None,
+                        collation: None,
                         nullable: false,
                         default_sql: None,
                         generated_sql: None,
@@ -236,6 +242,7 @@ mod tests {
                 column_type: crate::catalog::ColumnType::Int64,
                 spatial_type: None,
                 enum_type: None,
+                collation: None,
                 nullable: false,
                 default_sql: None,
                 generated_sql: None,
@@ -274,6 +281,7 @@ mod tests {
                 column_type: crate::catalog::ColumnType::Int64,
                 spatial_type: None,
                 enum_type: None,
+                collation: None,
                 nullable: false,
                 default_sql: None,
                 generated_sql: None,
@@ -314,6 +322,7 @@ mod tests {
                         column_type: crate::catalog::ColumnType::Int64,
                         spatial_type: None,
                         enum_type: None,
+                        collation: None,
                         nullable: false,
                         default_sql: None,
                         generated_sql: None,
@@ -329,6 +338,7 @@ mod tests {
                         column_type: crate::catalog::ColumnType::Text,
                         spatial_type: None,
                         enum_type: None,
+                        collation: None,
                         nullable: false,
                         default_sql: None,
                         generated_sql: None,
@@ -409,6 +419,7 @@ mod tests {
                         column_type: crate::catalog::ColumnType::Int64,
                         spatial_type: None,
                         enum_type: None,
+                        collation: None,
                         nullable: false,
                         default_sql: None,
                         generated_sql: None,
@@ -424,6 +435,7 @@ mod tests {
                         column_type: crate::catalog::ColumnType::Text,
                         spatial_type: None,
                         enum_type: None,
+                        collation: None,
                         nullable: false,
                         default_sql: None,
                         generated_sql: None,
@@ -439,6 +451,7 @@ mod tests {
                         column_type: crate::catalog::ColumnType::Float64,
                         spatial_type: None,
                         enum_type: None,
+                        collation: None,
                         nullable: false,
                         default_sql: None,
                         generated_sql: None,
@@ -513,6 +526,7 @@ mod tests {
                         column_type: crate::catalog::ColumnType::Int64,
                         spatial_type: None,
                         enum_type: None,
+                        collation: None,
                         nullable: false,
                         default_sql: None,
                         generated_sql: None,
@@ -528,6 +542,7 @@ mod tests {
                         column_type: crate::catalog::ColumnType::Text,
                         spatial_type: None,
                         enum_type: None,
+                        collation: None,
                         nullable: false,
                         default_sql: None,
                         generated_sql: None,
@@ -543,6 +558,7 @@ mod tests {
                         column_type: crate::catalog::ColumnType::Float64,
                         spatial_type: None,
                         enum_type: None,
+                        collation: None,
                         nullable: false,
                         default_sql: None,
                         generated_sql: None,
@@ -632,6 +648,7 @@ mod tests {
                         column_type: crate::catalog::ColumnType::Int64,
                         spatial_type: None,
                         enum_type: None,
+                        collation: None,
                         nullable: false,
                         default_sql: None,
                         generated_sql: None,
@@ -647,6 +664,7 @@ mod tests {
                         column_type: crate::catalog::ColumnType::Float64,
                         spatial_type: None,
                         enum_type: None,
+                        collation: None,
                         nullable: false,
                         default_sql: None,
                         generated_sql: None,
@@ -738,6 +756,7 @@ mod tests {
                         column_type: crate::catalog::ColumnType::Int64,
                         spatial_type: None,
                         enum_type: None,
+                        collation: None,
                         nullable: false,
                         default_sql: None,
                         generated_sql: None,
@@ -753,6 +772,7 @@ mod tests {
                         column_type: crate::catalog::ColumnType::Text,
                         spatial_type: None,
                         enum_type: None,
+                        collation: None,
                         nullable: false,
                         default_sql: None,
                         generated_sql: None,
@@ -870,6 +890,7 @@ mod tests {
                 column_type: crate::catalog::ColumnType::Int64,
                 spatial_type: None,
                 enum_type: None,
+                collation: None,
                 nullable: false,
                 default_sql: None,
                 generated_sql: None,
@@ -916,6 +937,7 @@ mod tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -931,6 +953,7 @@ mod tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -990,6 +1013,7 @@ mod tests {
                         column_type: crate::catalog::ColumnType::Int64,
                         spatial_type: None,
                         enum_type: None,
+                        collation: None,
                         nullable: false,
                         default_sql: None,
                         generated_sql: None,
@@ -1005,6 +1029,7 @@ mod tests {
                         column_type: crate::catalog::ColumnType::Text,
                         spatial_type: None,
                         enum_type: None,
+                        collation: None,
                         nullable: false,
                         default_sql: None,
                         generated_sql: None,
@@ -1020,6 +1045,7 @@ mod tests {
                         column_type: crate::catalog::ColumnType::Text,
                         spatial_type: None,
                         enum_type: None,
+                        collation: None,
                         nullable: false,
                         default_sql: None,
                         generated_sql: None,
@@ -1085,6 +1111,7 @@ mod tests {
                         column_type: crate::catalog::ColumnType::Int64,
                         spatial_type: None,
                         enum_type: None,
+                        collation: None,
                         nullable: false,
                         default_sql: None,
                         generated_sql: None,
@@ -1100,6 +1127,7 @@ mod tests {
                         column_type: crate::catalog::ColumnType::Text,
                         spatial_type: None,
                         enum_type: None,
+                        collation: None,
                         nullable: false,
                         default_sql: None,
                         generated_sql: None,
@@ -1115,6 +1143,7 @@ mod tests {
                         column_type: crate::catalog::ColumnType::Text,
                         spatial_type: None,
                         enum_type: None,
+                        collation: None,
                         nullable: false,
                         default_sql: None,
                         generated_sql: None,
@@ -1177,6 +1206,7 @@ mod tests {
                 column_type: crate::catalog::ColumnType::Int64,
                 spatial_type: None,
                 enum_type: None,
+                collation: None,
                 nullable: false,
                 default_sql: None,
                 generated_sql: None,
@@ -1202,6 +1232,7 @@ mod tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -1217,6 +1248,7 @@ mod tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -1274,6 +1306,7 @@ mod tests {
                 column_type: crate::catalog::ColumnType::Int64,
                 spatial_type: None,
                 enum_type: None,
+                collation: None,
                 nullable: false,
                 default_sql: None,
                 generated_sql: None,
@@ -1299,6 +1332,7 @@ mod tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -1314,6 +1348,7 @@ mod tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -1377,6 +1412,7 @@ mod tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -1392,6 +1428,7 @@ mod tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -1418,6 +1455,7 @@ mod tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -1433,6 +1471,7 @@ mod tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -1448,6 +1487,7 @@ mod tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -1523,6 +1563,7 @@ mod tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -1538,6 +1579,7 @@ mod tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -1564,6 +1606,7 @@ mod tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -1579,6 +1622,7 @@ mod tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -1594,6 +1638,7 @@ mod tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -1686,6 +1731,7 @@ mod tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -1701,6 +1747,7 @@ mod tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -1727,6 +1774,7 @@ mod tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -1742,6 +1790,7 @@ mod tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -1757,6 +1806,7 @@ mod tests {
                     column_type: crate::catalog::ColumnType::Int64,
                     spatial_type: None,
                     enum_type: None,
+                    collation: None,
                     nullable: false,
                     default_sql: None,
                     generated_sql: None,
@@ -22,6 +22,65 @@ mod tests {
         assert_eq!(v, Value::Int64(5));
     }
 
+    #[test]
+    fn eval_fuzzy_string_functions() {
+        let runtime = EngineRuntime::empty(1);
+        let call = |name: &str, args: &[&str]| {
+            let func = Expr::Function {
+                name: name.to_string(),
+                args: args
+                    .iter()
+                    .map(|arg| Expr::Literal(Value::Text(arg.to_string())))
+                    .collect(),
+            };
+            runtime
+                .eval_expr(&func, &Dataset::empty(), &[], &[], &BTreeMap::new(), None)
+                .expect("eval func")
+        };
+        assert_eq!(call("levenshtein", &["kitten", "sitting"]), Value::Int64(3));
+        assert_eq!(call("levenshtein", &["", "abc"]), Value::Int64(3));
+        assert_eq!(call("levenshtein", &["naïve", "naive"]), Value::Int64(1));
+        for (name, code) in [
+            ("Robert", "R163"),
+            ("Rupert", "R163"),
+            ("Ashcraft", "A261"),
+            ("Tymczak", "T522"),
+            ("Pfister", "P236"),
+            ("Lee", "L000"),
+            ("123", ""),
+        ] {
+            assert_eq!(
+                call("soundex", &[name]),
+                Value::Text(code.to_string()),
+                "{name}"
+            );
+        }
+        assert_eq!(
+            call("similarity", &["Motley", "motley"]),
+            Value::Float64(1.0)
+        );
+        assert_eq!(call("similarity", &["abc", "xyz"]), Value::Float64(0.0));
+
+        let null_arg = Expr::Function {
+            name: "levenshtein".to_string(),
+            args: vec![
+                Expr::Literal(Value::Null),
+                Expr::Literal(Value::Text("a".into())),
+            ],
+        };
+        let v = runtime
+            .eval_expr(
+                &null_arg,
+                &Dataset::empty(),
+                &[],
+                &[],
+                &BTreeMap::new(),
+                None,
+            )
+            .expect("eval null");
+        assert_eq!(v, Value::Null);
+    }
+
     #[test]
     fn eval_unary_not_and_negate() {
         let runtime = EngineRuntime::empty(1);
@@ -97,4 +156,100 @@ mod tests {
             .expect("eval func");
         assert_eq!(v, Value::Int64(5));
     }
+
+    #[test]
+    fn eval_hash_and_random_bytes_functions() {
+        let runtime = EngineRuntime::empty(1);
+        let call = |name: &str, args: Vec<Value>| {
+            let func = Expr::Function {
+                name: name.to_string(),
+                args: args.into_iter().map(Expr::Literal).collect(),
+            };
+            runtime.eval_expr(&func, &Dataset::empty(), &[], &[], &BTreeMap::new(), None)
+        };
+        let text = |value: &str| Value::Text(value.to_string());
+        let fox = text("The quick brown fox jumps over the lazy dog");
+
+        assert_eq!(
+            call("sha512", vec![text("abc")]).expect("sha512"),
+            text(
+                "ddaf35a193617abacc417349ae20413112e6fa4e89a97ea20a9eeee64b55d39a\
+                 2192992a274fc1a836ba3c23a3feebbd454d4423643ce80e2a9ac94fa54ca49f"
+            )
+        );
+        assert_eq!(
+            call("md5", vec![Value::Blob(vec![0, 1, 2])]).expect("md5 blob"),
+            text("b95f67f61ebb03619622d798f45fc2d3")
+        );
+        assert_eq!(
+            call("hmac", vec![fox.clone(), text("key"), text("sha256")]).expect("hmac"),
+            text("f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8")
+        );
+        assert_eq!(
+            call("hmac", vec![fox.clone(), text("key"), text("MD5")]).expect("hmac md5"),
+            text("80070713463e7749b90c2dc24911e275")
+        );
+        // Keys longer than the block are hashed first.
+        assert_eq!(
+            call(
+                "hmac",
+                vec![fox.clone(), Value::Blob(vec![b'k'; 200]), text("sha512")]
+            )
+            .expect("hmac long key"),
+            text(
+                "2ec850d56a434619da67d65f350b4a2caad666d274cf844ee9ac03f73e14d201\
+                 2bc00387fc44ee2404aa91155181ae98ee75b0497788ca045997ef2462e82f91"
+            )
+        );
+        assert_eq!(
+            call("hmac", vec![Value::Null, text("key"), text("sha256")]).expect("hmac null"),
+            Value::Null
+        );
+        let err = call("hmac", vec![fox, text("key"), text("sha1")]).expect_err("sha1");
+        assert!(err.to_string().contains("sha1"), "{err}");
+
+        let Value::Blob(first) = call("random_bytes", vec![Value::Int64(32)]).expect("random")
+        else {
+            panic!("RANDOM_BYTES should return a BLOB");
+        };
+        let Value::Blob(second) = call("gen_random_bytes", vec![Value::Int64(32)]).expect("random")
+        else {
+            panic!("GEN_RANDOM_BYTES should return a BLOB");
+        };
+        assert_eq!(first.len(), 32);
+        assert_ne!(first, second);
+        for len in [0, 1025] {
+            assert!(
+                call("random_bytes", vec![Value::Int64(len)]).is_err(),
+                "{len}"
+            );
+        }
+    }
+
+    #[test]
+    fn eval_regex_match_reuses_compiled_patterns() {
+        let runtime = EngineRuntime::empty(1);
+        let matches = |text: &str, pattern: &str, op: BinaryOp| {
+            let expr = Expr::Binary {
+                left: Box::new(Expr::Literal(Value::Text(text.to_string()))),
+                op,
+                right: Box::new(Expr::Literal(Value::Text(pattern.to_string()))),
+            };
+            runtime.eval_expr(&expr, &Dataset::empty(), &[], &[], &BTreeMap::new(), None)
+        };
+        for (text, want) in [("alice", true), ("bob", false), ("ALICE", false)] {
+            let v = matches(text, "^a[a-z]+$", BinaryOp::RegexMatch).expect("eval regex");
+            assert_eq!(v, Value::Bool(want), "{text}");
+        }
+        let v = matches("ALICE", "^a[a-z]+$", BinaryOp::RegexMatchCaseInsensitive)
+            .expect("eval case-insensitive regex");
+        assert_eq!(v, Value::Bool(true));
+        for _ in 0..2 {
+            let err = matches("x", "(", BinaryOp::RegexMatch).expect_err("invalid pattern");
+            assert!(
+                err.to_string().contains("invalid regular expression"),
+                "{err}"
+            );
+        }
+    }
 }
@@ -666,6 +666,40 @@ pub(super) fn eval_function(
             }
             Ok(Value::Text(output))
         }
+        "levenshtein" => {
+            if values.len() != 2 {
+                return Err(DbError::sql("LEVENSHTEIN expects 2 arguments"));
+            }
+            let Some(left) = expect_text_arg("LEVENSHTEIN", "first", &values[0])? else {
+                return Ok(Value::Null);
+            };
+            let Some(right) = expect_text_arg("LEVENSHTEIN", "second", &values[1])? else {
+                return Ok(Value::Null);
+            };
+            Ok(Value::Int64(levenshtein_distance(left, right) as i64))
+        }
+        "soundex" => {
+            if values.len() != 1 {
+                return Err(DbError::sql("SOUNDEX expects 1 argument"));
+            }
+            let Some(value) = expect_text_arg("SOUNDEX", "first", &values[0])? else {
+                return Ok(Value::Null);
+            };
+            Ok(Value::Text(soundex_code(value)))
+        }
+        "similarity" => {
+            if values.len() != 2 {
+                return Err(DbError::sql("SIMILARITY expects 2 arguments"));
+            }
+            let Some(left) = expect_text_arg("SIMILARITY", "first", &values[0])? else {
+                return Ok(Value::Null);
+            };
+            let Some(right) = expect_text_arg("SIMILARITY", "second", &values[1])? else {
+                return Ok(Value::Null);
+            };
+            let score = crate::search::trigram::similarity(left, right);
+            Ok(Value::Float64(score))
+        }
         "ascii" => {
             if values.len() != 1 {
                 return Err(DbError::sql("ASCII expects 1 argument"));
@@ -860,21 +894,72 @@ pub(super) fn eval_function(
             if values.len() != 1 {
                 return Err(DbError::sql("MD5 expects 1 argument"));
             }
-            let Some(value) = expect_text_arg("MD5", "first", &values[0])? else {
+            let Some(value) = expect_bytes_arg("MD5", "first", &values[0])? else {
                 return Ok(Value::Null);
             };
-            Ok(Value::Text(format!("{:x}", md5::compute(value.as_bytes()))))
+            let digest = digest_bytes(HashAlgorithm::Md5, value);
+            Ok(Value::Text(hex_encode_lower(&digest)))
         }
-        "sha256" => {
+        "sha256" | "sha512" => {
+            let (function_name, algorithm) = if name == "sha256" {
+                ("SHA256", HashAlgorithm::Sha256)
+            } else {
+                ("SHA512", HashAlgorithm::Sha512)
+            };
             if values.len() != 1 {
-                return Err(DbError::sql("SHA256 expects 1 argument"));
+                return Err(DbError::sql(format!("{function_name} expects 1 argument")));
             }
-            let Some(value) = expect_text_arg("SHA256", "first", &values[0])? else {
+            let Some(value) = expect_bytes_arg(function_name, "first", &values[0])? else {
                 return Ok(Value::Null);
             };
-            let digest = <sha2::Sha256 as sha2::Digest>::digest(value.as_bytes());
+            let digest = digest_bytes(algorithm, value);
             Ok(Value::Text(hex_encode_lower(&digest)))
         }
+        "hmac" => {
+            if values.len() != 3 {
+                return Err(DbError::sql("HMAC expects 3 arguments"));
+            }
+            let Some(data) = expect_bytes_arg("HMAC", "first", &values[0])? else {
+                return Ok(Value::Null);
+            };
+            let Some(key) = expect_bytes_arg("HMAC", "second", &values[1])? else {
+                return Ok(Value::Null);
+            };
+            let Some(algorithm) = expect_text_arg("HMAC", "third", &values[2])? else {
+                return Ok(Value::Null);
+            };
+            let algorithm = HashAlgorithm::parse(algorithm).ok_or_else(|| {
+                DbError::sql(format!(
+                    "HMAC does not support algorithm '{algorithm}'; use md5, sha256, or sha512"
+                ))
+            })?;
+            let mac = hmac_bytes(algorithm, key, data);
+            Ok(Value::Text(hex_encode_lower(&mac)))
+        }
+        "advisory_lock"
+        | "pg_advisory_lock"
+        | "advisory_try_lock"
+        | "pg_advisory_try_lock"
+        | "pg_try_advisory_lock"
+        | "advisory_unlock"
+        | "pg_advisory_unlock" => eval_advisory_lock_function(runtime, name, &values),
+        "random_bytes" | "gen_random_bytes" => {
+            if values.len() != 1 {
+                return Err(DbError::sql("RANDOM_BYTES expects 1 argument"));
+            }
+            let Some(len) = expect_int_arg("RANDOM_BYTES", "first", &values[0])? else {
+                return Ok(Value::Null);
+            };
+            let len = usize::try_from(len)
+                .ok()
+                .filter(|len| (1..=MAX_RANDOM_BYTES).contains(len))
+                .ok_or_else(|| {
+                    DbError::sql(format!(
+                        "RANDOM_BYTES length must be between 1 and {MAX_RANDOM_BYTES}, got {len}"
+                    ))
+                })?;
+            Ok(Value::Blob(secure_random_bytes(len)?))
+        }
         "instr" => {
             if values.len() != 2 {
                 return Err(DbError::sql("INSTR expects 2 arguments"));
@@ -1311,7 +1396,8 @@ pub(super) fn eval_function(
         "datetime" => eval_datetime(values),
         "strftime" => eval_strftime(values),
         "extract" | "pg_catalog.extract" => eval_extract(values),
-        "gen_random_uuid" => eval_gen_random_uuid(values),
+        "gen_random_uuid" | "uuidv4" => eval_gen_random_uuid(values),
+        "uuid_v7" | "uuidv7" => eval_uuid_v7(values),
         "uuid_parse" => eval_uuid_parse(values),
         "uuid_to_string" => eval_uuid_to_string(values),
         "json_array" | "pg_catalog.json_array" => eval_json_array(values),
@@ -2224,6 +2310,22 @@ pub(super) fn expect_text_arg<'a>(
     }
 }
 
+/// Accepts text, as its UTF-8 bytes, or a BLOB.
+pub(super) fn expect_bytes_arg<'a>(
+    function_name: &str,
+    ordinal: &str,
+    value: &'a Value,
+) -> Result<Option<&'a [u8]>> {
+    match value {
+        Value::Text(value) => Ok(Some(value.as_bytes())),
+        Value::Blob(value) => Ok(Some(value)),
+        Value::Null => Ok(None),
+        other => Err(DbError::sql(format!(
+            "{function_name} expects text or BLOB for {ordinal} argument, got {other:?}"
+        ))),
+    }
+}
+
 pub(super) fn expect_int_arg(
     function_name: &str,
     ordinal: &str,
@@ -2315,6 +2417,172 @@ pub(super) fn repeat_to_char_len(pattern: &str, len: usize) -> String {
     output.chars().take(len).collect()
 }
 
+/// Counts the single-character insertions, deletions, and substitutions
+/// that turn `left` into `right`.
+pub(super) fn levenshtein_distance(left: &str, right: &str) -> usize {
+    let right = right.chars().collect::<Vec<_>>();
+    let mut row = (0..=right.len()).collect::<Vec<_>>();
+    for (i, left_ch) in left.chars().enumerate() {
+        let mut diagonal = row[0];
+        row[0] = i + 1;
+        for (j, right_ch) in right.iter().enumerate() {
+            let substitution = diagonal + usize::from(left_ch != *right_ch);
+            diagonal = row[j + 1];
+            row[j + 1] = substitution.min(row[j] + 1).min(diagonal + 1);
+        }
+    }
+    row[right.len()]
+}
+
+/// American Soundex: the first letter followed by three digits coding the
+/// consonants that follow. Characters other than ASCII letters are ignored;
+/// input without letters codes to the empty string.
+pub(super) fn soundex_code(value: &str) -> String {
+    fn digit(letter: u8) -> u8 {
+        match letter {
+            b'B' | b'F' | b'P' | b'V' => b'1',
+            b'C' | b'G' | b'J' | b'K' | b'Q' | b'S' | b'X' | b'Z' => b'2',
+            b'D' | b'T' => b'3',
+            b'L' => b'4',
+            b'M' | b'N' => b'5',
+            b'R' => b'6',
+            // H and W do not separate letters with the same code; vowels do.
+            b'H' | b'W' => b'-',
+            _ => b'0',
+        }
+    }
+
+    let mut letters = value
+        .bytes()
+        .filter(u8::is_ascii_alphabetic)
+        .map(|byte| byte.to_ascii_uppercase());
+    let Some(first) = letters.next() else {
+        return String::new();
+    };
+    let mut code = vec![first];
+    let mut previous = digit(first);
+    for letter in letters {
+        let current = digit(letter);
+        if current == b'-' {
+            continue;
+        }
+        if current != b'0' && current != previous {
+            code.push(current);
+            if code.len() == 4 {
+                break;
+            }
+        }
+        previous = current;
+    }
+    code.resize(4, b'0');
+    String::from_utf8(code).unwrap_or_default()
+}
+
+/// Takes, tries, or releases the advisory lock named by the argument for the
+/// handle running the statement. `advisory_lock` waits up to the handle's
+/// busy timeout.
+fn eval_advisory_lock_function(
+    runtime: &EngineRuntime,
+    name: &str,
+    values: &[Value],
+) -> Result<Value> {
+    let function_name = name.to_ascii_uppercase();
+    if values.len() != 1 {
+        return Err(DbError::sql(format!("{function_name} expects 1 argument")));
+    }
+    let Some(key) = expect_int_arg(&function_name, "first", &values[0])? else {
+        return Ok(Value::Null);
+    };
+    let scope = runtime.advisory_locks.as_ref().ok_or_else(|| {
+        DbError::sql(format!(
+            "{function_name} is only available on a database handle"
+        ))
+    })?;
+    let held = match name {
+        "advisory_lock" | "pg_advisory_lock" => {
+            scope.lock(key, scope.busy_timeout())?;
+            true
+        }
+        "advisory_try_lock" | "pg_advisory_try_lock" | "pg_try_advisory_lock" => {
+            scope.try_lock(key)?
+        }
+        _ => scope.unlock(key)?,
+    };
+    Ok(Value::Bool(held))
+}
+
+/// Largest length RANDOM_BYTES accepts, as in pgcrypto's gen_random_bytes.
+pub(super) const MAX_RANDOM_BYTES: usize = 1024;
+
+#[derive(Clone, Copy, Debug, Eq, PartialEq)]
+pub(super) enum HashAlgorithm {
+    Md5,
+    Sha256,
+    Sha512,
+}
+
+impl HashAlgorithm {
+    pub(super) fn parse(name: &str) -> Option<Self> {
+        match name.to_ascii_lowercase().replace('-', "").as_str() {
+            "md5" => Some(Self::Md5),
+            "sha256" => Some(Self::Sha256),
+            "sha512" => Some(Self::Sha512),
+            _ => None,
+        }
+    }
+
+    fn block_len(self) -> usize {
+        match self {
+            Self::Md5 | Self::Sha256 => 64,
+            Self::Sha512 => 128,
+        }
+    }
+}
+
+pub(super) fn digest_bytes(algorithm: HashAlgorithm, data: &[u8]) -> Vec<u8> {
+    match algorithm {
+        HashAlgorithm::Md5 => md5::compute(data).0.to_vec(),
+        HashAlgorithm::Sha256 => <sha2::Sha256 as sha2::Digest>::digest(data).to_vec(),
+        HashAlgorithm::Sha512 => <sha2::Sha512 as sha2::Digest>::digest(data).to_vec(),
+    }
+}
+
+/// HMAC as defined by RFC 2104.
+pub(super) fn hmac_bytes(algorithm: HashAlgorithm, key: &[u8], data: &[u8]) -> Vec<u8> {
+    let block_len = algorithm.block_len();
+    let mut block_key = if key.len() > block_len {
+        digest_bytes(algorithm, key)
+    } else {
+        key.to_vec()
+    };
+    block_key.resize(block_len, 0);
+
+    let mut inner = block_key.iter().map(|byte| byte ^ 0x36).collect::<Vec<_>>();
+    inner.extend_from_slice(data);
+    let mut outer = block_key.iter().map(|byte| byte ^ 0x5c).collect::<Vec<_>>();
+    outer.extend_from_slice(&digest_bytes(algorithm, &inner));
+    digest_bytes(algorithm, &outer)
+}
+
+/// Bytes from the operating system's secure random source, suitable for
+/// tokens and salts, unlike RANDOM and GEN_RANDOM_UUID.
+#[cfg(not(all(target_arch = "wasm32", target_os = "unknown")))]
+pub(super) fn secure_random_bytes(len: usize) -> Result<Vec<u8>> {
+    let mut bytes = vec![0_u8; len];
+    getrandom::fill(&mut bytes).map_err(|error| {
+        DbError::io(
+            "generate RANDOM_BYTES",
+            std::io::Error::other(error.to_string()),
+        )
+    })?;
+    Ok(bytes)
+}
+
+#[cfg(all(target_arch = "wasm32", target_os = "unknown"))]
+pub(super) fn secure_random_bytes(_len: usize) -> Result<Vec<u8>> {
+    Err(DbError::sql("RANDOM_BYTES requires platform randomness"))
+}
+
 pub(super) fn hex_encode_upper(bytes: &[u8]) -> String {
     let mut output = String::with_capacity(bytes.len() * 2);
     for byte in bytes {
@@ -2356,11 +2624,7 @@ pub(super) fn eval_regexp_replace(
             }
         }
     }
-    let mut builder = regex::RegexBuilder::new(pattern);
-    builder.case_insensitive(case_insensitive);
-    let regex = builder
-        .build()
-        .map_err(|error| DbError::sql(format!("invalid regular expression: {error}")))?;
+    let regex = compiled_regex(pattern, case_insensitive)?;
     if global {
         Ok(regex.replace_all(input, replacement).to_string())
     } else {
@@ -2888,14 +3152,79 @@ pub(super) fn eval_gen_random_uuid(values: Vec<Value>) -> Result<Value> {
     if !values.is_empty() {
         return Err(DbError::sql("GEN_RANDOM_UUID expects 0 arguments"));
     }
-    let mut value = [0u8; 16];
-    value[..8].copy_from_slice(&next_random_u64().to_be_bytes());
-    value[8..].copy_from_slice(&next_random_u64().to_be_bytes());
+    let mut value = uuid_random_bytes();
     value[6] = (value[6] & 0x0f) | 0x40;
     value[8] = (value[8] & 0x3f) | 0x80;
     Ok(Value::Uuid(value))
 }
 
+/// The last `unix_ms << 12 | counter` UUID_V7 returned, so the values one
+/// process generates sort in generation order even within a millisecond.
+static UUID_V7_STATE: AtomicU64 = AtomicU64::new(0);
+
+/// Returns a version 7 UUID (RFC 9562): a 48-bit Unix millisecond timestamp,
+/// a 12-bit counter, and 62 random bits. Keys generated this way arrive in
+/// roughly ascending order, so inserts append to the primary key index.
+pub(super) fn eval_uuid_v7(values: Vec<Value>) -> Result<Value> {
+    if !values.is_empty() {
+        return Err(DbError::sql("UUID_V7 expects 0 arguments"));
+    }
+    let mut value = uuid_random_bytes();
+    let now_ms = u64::try_from(current_utc_timestamp_micros()? / 1_000).unwrap_or(0);
+    // Start each millisecond's counter in its lower half, leaving room to
+    // count up before spilling into the timestamp.
+    let start = (now_ms << 12) | (u64::from(value[7] & 0x07) << 8) | u64::from(value[6]);
+    let mut observed = UUID_V7_STATE.load(Ordering::Relaxed);
+    let state = loop {
+        let next = start.max(observed.wrapping_add(1));
+        match UUID_V7_STATE.compare_exchange_weak(
+            observed,
+            next,
+            Ordering::Relaxed,
+            Ordering::Relaxed,
+        ) {
+            Ok(_) => break next,
+            Err(actual) => observed = actual,
+        }
+    };
+    value[..6].copy_from_slice(&(state >> 12).to_be_bytes()[2..]);
+    value[6] = 0x70 | ((state >> 8) & 0x0f) as u8;
+    value[7] = state as u8;
+    value[8] = (value[8] & 0x3f) | 0x80;
+    Ok(Value::Uuid(value))
+}
+
+/// Returns the generator a column default calls when it is just
+/// `GEN_RANDOM_UUID()` or `UUID_V7()`.
+pub(super) fn default_uuid_generator(expr: &Expr) -> Option<fn(Vec<Value>) -> Result<Value>> {
+    let Expr::Function { name, args } = expr else {
+        return None;
+    };
+    if !args.is_empty() {
+        return None;
+    }
+    match name.to_ascii_lowercase().as_str() {
+        "gen_random_uuid" | "uuidv4" => Some(eval_gen_random_uuid),
+        "uuid_v7" | "uuidv7" => Some(eval_uuid_v7),
+        _ => None,
+    }
+}
+
+/// Random bytes for UUIDs, from the operating system when it can provide
+/// them, so separate processes never share a sequence.
+fn uuid_random_bytes() -> [u8; 16] {
+    let mut value = [0_u8; 16];
+    #[cfg(not(all(target_arch = "wasm32", target_os = "unknown")))]
+    {
+        if getrandom::fill(&mut value).is_ok() {
+            return value;
+        }
+    }
+    value[..8].copy_from_slice(&next_random_u64().to_be_bytes());
+    value[8..].copy_from_slice(&next_random_u64().to_be_bytes());
+    value
+}
+
 pub(super) fn eval_uuid_parse(values: Vec<Value>) -> Result<Value> {
     if values.len() != 1 {
         return Err(DbError::sql("UUID_PARSE expects 1 argument"));
@@ -4801,6 +5130,38 @@ pub(super) fn eval_binary_with_collation(
     }
 }
 
+/// Patterns compiled per thread before the cache is cleared.
+const REGEX_CACHE_CAPACITY: usize = 64;
+
+thread_local! {
+    /// Compiled patterns of the regex operators and REGEXP_REPLACE, keyed by
+    /// pattern and case sensitivity, so a pattern applied to every row of a
+    /// scan is compiled once rather than per row.
+    static REGEX_CACHE: std::cell::RefCell<std::collections::HashMap<(String, bool), regex::Regex>> =
+        std::cell::RefCell::new(std::collections::HashMap::new());
+}
+
+/// Returns the compiled form of `pattern`, from the thread's cache when it
+/// was compiled before. Invalid patterns are not cached.
+pub(super) fn compiled_regex(pattern: &str, case_insensitive: bool) -> Result<regex::Regex> {
+    let key = (pattern.to_string(), case_insensitive);
+    if let Some(regex) = REGEX_CACHE.with(|cache| cache.borrow().get(&key).cloned()) {
+        return Ok(regex);
+    }
+    let regex = regex::RegexBuilder::new(pattern)
+        .case_insensitive(case_insensitive)
+        .build()
+        .map_err(|error| DbError::sql(format!("invalid regular expression: {error}")))?;
+    REGEX_CACHE.with(|cache| {
+        let mut cache = cache.borrow_mut();
+        if cache.len() >= REGEX_CACHE_CAPACITY {
+            cache.clear();
+        }
+        cache.insert(key, regex.clone());
+    });
+    Ok(regex)
+}
+
 pub(super) fn eval_regex(
     left: Value,
     right: Value,
@@ -4810,11 +5171,7 @@ pub(super) fn eval_regex(
     match (left, right) {
         (Value::Null, _) | (_, Value::Null) => Ok(Value::Null),
         (Value::Text(left), Value::Text(pattern)) => {
-            let mut builder = regex::RegexBuilder::new(&pattern);
-            builder.case_insensitive(case_insensitive);
-            let regex = builder
-                .build()
-                .map_err(|error| DbError::sql(format!("invalid regular expression: {error}")))?;
+            let regex = compiled_regex(&pattern, case_insensitive)?;
             let matched = regex.is_match(&left);
             Ok(Value::Bool(if negated { !matched } else { matched }))
         }
@@ -5370,6 +5727,18 @@ pub(super) fn compare_values_with_collation(
     }
 }
 
+/// Folds the key of an index on `expr COLLATE collation` so values the
+/// collation compares equal share one index entry.
+pub(super) fn fold_collation_key(value: Value, collation: Option<&Collation>) -> Value {
+    match (collation, value) {
+        (Some(Collation::NoCase), Value::Text(text)) => Value::Text(text.to_ascii_lowercase()),
+        (Some(Collation::RTrim), Value::Text(text)) => {
+            Value::Text(text.trim_end_matches(' ').to_string())
+        }
+        (_, value) => value,
+    }
+}
+
 pub(super) fn compare_ascii_nocase(left: &str, right: &str) -> std::cmp::Ordering {
     left.bytes()
         .map(|byte| byte.to_ascii_lowercase())
@@ -0,0 +1,133 @@
+//! Per-statement resource limits.
+//!
+//! A handle's limits (`DbConfig::max_result_rows` and
+//! `DbConfig::max_statement_seconds`, adjusted at runtime with the PRAGMAs of
+//! the same names) are installed for the thread running a statement with
+//! [`StatementLimits::install`]. The runtime deadline is polled together with
+//! the interrupt flag by [`check_interrupt`](super::interrupt::check_interrupt),
+//! so a statement that runs too long fails with `DbError::statement_timeout`
+//! at the same points a cancelled one does. Queries check the size of their
+//! result with [`check_result_rows`] and fail with
+//! `DbError::result_row_limit_exceeded`.
+
+use std::cell::Cell;
+use std::time::{Duration, Instant};
+
+use crate::error::{DbError, Result};
+
+thread_local! {
+    static LIMITS: Cell<Limits> = const { Cell::new(Limits::NONE) };
+}
+
+#[derive(Clone, Copy, Debug)]
+struct Limits {
+    max_rows: u64,
+    max_seconds: u64,
+    deadline: Option<Instant>,
+}
+
+impl Limits {
+    const NONE: Self = Self {
+        max_rows: 0,
+        max_seconds: 0,
+        deadline: None,
+    };
+}
+
+/// Restores the previous limits of the thread when dropped.
+pub(crate) struct StatementLimits(Limits);
+
+impl StatementLimits {
+    /// Limits the statement run on the current thread to `max_rows` result
+    /// rows and `max_seconds` of runtime, where `0` means no limit. A
+    /// statement nested inside a limited one keeps the outer deadline when
+    /// it is earlier.
+    pub(crate) fn install(max_rows: u64, max_seconds: u64) -> Self {
+        Self(LIMITS.with(|slot| {
+            let previous = slot.get();
+            let mut limits = Limits {
+                max_rows,
+                max_seconds,
+                deadline: (max_seconds > 0)
+                    .then(|| Instant::now().checked_add(Duration::from_secs(max_seconds)))
+                    .flatten(),
+            };
+            if let Some(outer) = previous.deadline {
+                if limits.deadline.is_none_or(|deadline| outer < deadline) {
+                    limits.deadline = Some(outer);
+                    limits.max_seconds = previous.max_seconds;
+                }
+            }
+            slot.replace(limits)
+        }))
+    }
+}
+
+impl Drop for StatementLimits {
+    fn drop(&mut self) {
+        LIMITS.with(|slot| slot.set(self.0));
+    }
+}
+
+/// Fails with `DbError::statement_timeout` when the statement running on the
+/// current thread is past its deadline.
+pub(crate) fn check_deadline() -> Result<()> {
+    let limits = LIMITS.with(Cell::get);
+    match limits.deadline {
+        Some(deadline) if Instant::now() >= deadline => {
+            Err(DbError::statement_timeout(limits.max_seconds))
+        }
+        _ => Ok(()),
+    }
+}
+
+/// Fails with `DbError::result_row_limit_exceeded` when a query result of
+/// `rows` rows is over the limit of the statement running on the current
+/// thread.
+pub(crate) fn check_result_rows(rows: usize) -> Result<()> {
+    let max_rows = LIMITS.with(|slot| slot.get().max_rows);
+    if max_rows > 0 && u64::try_from(rows).unwrap_or(u64::MAX) > max_rows {
+        return Err(DbError::result_row_limit_exceeded(max_rows));
+    }
+    Ok(())
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn no_limits_means_no_errors() {
+        let _statement = StatementLimits::install(0, 0);
+        assert!(check_deadline().is_ok());
+        assert!(check_result_rows(usize::MAX).is_ok());
+    }
+
+    #[test]
+    fn row_limit_allows_exactly_the_limit() {
+        let _statement = StatementLimits::install(10, 0);
+        assert!(check_result_rows(10).is_ok());
+        let error = check_result_rows(11).expect_err("over limit");
+        assert!(error.is_result_row_limit_exceeded());
+    }
+
+    #[test]
+    fn nested_statement_keeps_the_earlier_deadline() {
+        let _outer = StatementLimits::install(0, 1);
+        LIMITS.with(|slot| {
+            let mut limits = slot.get();
+            limits.deadline = Some(Instant::now());
+            slot.set(limits);
+        });
+        {
+            let _nested = StatementLimits::install(0, 60);
+            let error = check_deadline().expect_err("outer deadline passed");
+            assert!(error.is_statement_timeout());
+        }
+        {
+            let _nested = StatementLimits::install(0, 0);
+            assert!(check_deadline().is_err());
+        }
+        assert!(check_deadline().is_err());
+    }
+}
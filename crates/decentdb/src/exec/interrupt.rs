@@ -0,0 +1,98 @@
+//! Cancelling a running statement from another thread.
+//!
+//! Every handle owns an interrupt flag that `Db::interrupt` raises. The flag
+//! is installed for the thread running a statement with
+//! [`StatementInterrupt::install`], which lowers it first so an interrupt only
+//! reaches statements that are already running. Long-running operator loops
+//! (joins, sorts, grouping, projections and recursive CTEs) poll
+//! [`check_interrupt`] and fail with `DbError::canceled` once it is raised.
+
+use std::cell::RefCell;
+use std::sync::atomic::{AtomicBool, Ordering};
+use std::sync::Arc;
+
+use crate::error::{DbError, Result};
+
+/// Rows an operator processes between two interrupt checks.
+pub(crate) const INTERRUPT_CHECK_INTERVAL: usize = 1024;
+
+thread_local! {
+    static INTERRUPT: RefCell<Option<Arc<AtomicBool>>> = const { RefCell::new(None) };
+}
+
+/// Restores the previous interrupt flag of the thread when dropped.
+pub(crate) struct StatementInterrupt(Option<Arc<AtomicBool>>);
+
+impl StatementInterrupt {
+    /// Installs `flag` for the statement run on the current thread. A
+    /// statement nested inside one that already watches the same flag keeps
+    /// a pending interrupt instead of clearing it.
+    pub(crate) fn install(flag: &Arc<AtomicBool>) -> Self {
+        Self(INTERRUPT.with(|slot| {
+            let mut slot = slot.borrow_mut();
+            let nested = slot
+                .as_ref()
+                .is_some_and(|current| Arc::ptr_eq(current, flag));
+            if !nested {
+                flag.store(false, Ordering::Release);
+            }
+            slot.replace(Arc::clone(flag))
+        }))
+    }
+}
+
+impl Drop for StatementInterrupt {
+    fn drop(&mut self) {
+        let previous = self.0.take();
+        INTERRUPT.with(|slot| *slot.borrow_mut() = previous);
+    }
+}
+
+/// Fails with `DbError::canceled` when the statement running on the current
+/// thread has been interrupted, or with `DbError::statement_timeout` when it
+/// is past its `max_statement_seconds` deadline.
+pub(crate) fn check_interrupt() -> Result<()> {
+    INTERRUPT.with(|slot| match slot.borrow().as_ref() {
+        Some(flag) if flag.load(Ordering::Acquire) => {
+            Err(DbError::canceled("statement was interrupted"))
+        }
+        _ => Ok(()),
+    })?;
+    super::governor::check_deadline()
+}
+
+/// Polls [`check_interrupt`] every [`INTERRUPT_CHECK_INTERVAL`] iterations of
+/// a loop indexed by `index`.
+pub(crate) fn check_interrupt_every(index: usize) -> Result<()> {
+    if index % INTERRUPT_CHECK_INTERVAL == 0 {
+        check_interrupt()
+    } else {
+        Ok(())
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn interrupt_is_cleared_when_a_statement_starts() {
+        let flag = Arc::new(AtomicBool::new(true));
+        let _statement = StatementInterrupt::install(&flag);
+        assert!(check_interrupt().is_ok());
+        flag.store(true, Ordering::Release);
+        let error = check_interrupt().expect_err("interrupted");
+        assert_eq!(error.code(), crate::error::DbErrorCode::Canceled);
+        {
+            let _nested = StatementInterrupt::install(&flag);
+            assert!(check_interrupt().is_err());
+        }
+        assert!(check_interrupt().is_err());
+    }
+
+    #[test]
+    fn no_flag_means_no_interrupt() {
+        assert!(check_interrupt().is_ok());
+        assert!(check_interrupt_every(0).is_ok());
+    }
+}
@@ -0,0 +1,249 @@
+//! Per-statement memory accounting.
+//!
+//! Operators that buffer rows (sorts, the hash tables behind grouping and
+//! DISTINCT, and materialized intermediate results such as join outputs and
+//! CTEs) reserve an estimate of the bytes they hold with [`reserve`] and give
+//! it back when the returned [`MemoryReservation`] drops. A handle's cap
+//! (`DbConfig::statement_memory_limit_bytes`, adjusted at runtime with
+//! `PRAGMA statement_memory_limit_bytes`) is installed for the thread running a
+//! statement with [`StatementMemory::install`]; a reservation that would take
+//! the statement past it fails with `DbError::memory_limit_exceeded`.
+//!
+//! Estimates are only computed while a cap is installed or a
+//! [`MemoryProfile`] is open for `EXPLAIN ANALYZE`, so unlimited statements
+//! do not pay for the bookkeeping.
+
+use std::cell::Cell;
+
+use crate::error::{DbError, Result};
+use crate::record::value::Value;
+
+thread_local! {
+    static TRACKER: Cell<Tracker> = const { Cell::new(Tracker::new(0)) };
+}
+
+/// What a reservation holds memory for.
+#[derive(Clone, Copy, Debug, PartialEq, Eq)]
+pub(crate) enum MemoryUse {
+    Sort,
+    Hash,
+    Intermediate,
+}
+
+/// High-water marks of a statement's reservations, in bytes.
+#[derive(Clone, Copy, Debug, Default, PartialEq, Eq)]
+pub(crate) struct MemoryUsage {
+    pub(crate) peak: usize,
+    pub(crate) sort: usize,
+    pub(crate) hash: usize,
+    pub(crate) intermediate: usize,
+}
+
+#[derive(Clone, Copy, Debug)]
+struct Tracker {
+    limit: usize,
+    profiling: bool,
+    current: usize,
+    by_use: [usize; 3],
+    usage: MemoryUsage,
+}
+
+impl Tracker {
+    const fn new(limit: usize) -> Self {
+        Self {
+            limit,
+            profiling: false,
+            current: 0,
+            by_use: [0; 3],
+            usage: MemoryUsage {
+                peak: 0,
+                sort: 0,
+                hash: 0,
+                intermediate: 0,
+            },
+        }
+    }
+
+    fn active(&self) -> bool {
+        self.limit > 0 || self.profiling
+    }
+
+    fn charge(&mut self, kind: MemoryUse, bytes: usize) -> Result<()> {
+        let current = self.current.saturating_add(bytes);
+        if self.limit > 0 && current > self.limit {
+            return Err(DbError::memory_limit_exceeded(self.limit, current));
+        }
+        self.current = current;
+        let slot = &mut self.by_use[kind as usize];
+        *slot = slot.saturating_add(bytes);
+        let held = *slot;
+        self.usage.peak = self.usage.peak.max(current);
+        let peak = match kind {
+            MemoryUse::Sort => &mut self.usage.sort,
+            MemoryUse::Hash => &mut self.usage.hash,
+            MemoryUse::Intermediate => &mut self.usage.intermediate,
+        };
+        *peak = (*peak).max(held);
+        Ok(())
+    }
+
+    fn release(&mut self, kind: MemoryUse, bytes: usize) {
+        self.current = self.current.saturating_sub(bytes);
+        let slot = &mut self.by_use[kind as usize];
+        *slot = slot.saturating_sub(bytes);
+    }
+}
+
+/// Restores the previous memory accounting of the thread when dropped.
+pub(crate) struct StatementMemory(Tracker);
+
+impl StatementMemory {
+    /// Starts accounting for a statement capped at `limit_bytes`, where `0`
+    /// means no cap.
+    pub(crate) fn install(limit_bytes: usize) -> Self {
+        Self(TRACKER.with(|slot| slot.replace(Tracker::new(limit_bytes))))
+    }
+}
+
+impl Drop for StatementMemory {
+    fn drop(&mut self) {
+        TRACKER.with(|slot| slot.set(self.0));
+    }
+}
+
+/// Turns on accounting for `EXPLAIN ANALYZE` and collects the peaks reached
+/// while it is open.
+pub(crate) struct MemoryProfile(bool);
+
+impl MemoryProfile {
+    pub(crate) fn start() -> Self {
+        TRACKER.with(|slot| {
+            let mut tracker = slot.get();
+            let previous = tracker.profiling;
+            tracker.profiling = true;
+            tracker.usage = MemoryUsage::default();
+            slot.set(tracker);
+            Self(previous)
+        })
+    }
+
+    pub(crate) fn usage(&self) -> MemoryUsage {
+        TRACKER.with(|slot| slot.get().usage)
+    }
+}
+
+impl Drop for MemoryProfile {
+    fn drop(&mut self) {
+        TRACKER.with(|slot| {
+            let mut tracker = slot.get();
+            tracker.profiling = self.0;
+            slot.set(tracker);
+        });
+    }
+}
+
+/// Bytes held by a buffering operator; released when dropped.
+#[must_use]
+pub(crate) struct MemoryReservation {
+    kind: MemoryUse,
+    bytes: usize,
+    active: bool,
+}
+
+impl MemoryReservation {
+    /// Adds the bytes computed by `bytes` to the reservation. The closure is
+    /// not called when accounting is off.
+    pub(crate) fn grow(&mut self, bytes: impl FnOnce() -> usize) -> Result<()> {
+        if !self.active {
+            return Ok(());
+        }
+        let bytes = bytes();
+        TRACKER.with(|slot| {
+            let mut tracker = slot.get();
+            tracker.charge(self.kind, bytes)?;
+            slot.set(tracker);
+            Ok(())
+        })?;
+        self.bytes = self.bytes.saturating_add(bytes);
+        Ok(())
+    }
+}
+
+impl Drop for MemoryReservation {
+    fn drop(&mut self) {
+        if self.bytes > 0 {
+            TRACKER.with(|slot| {
+                let mut tracker = slot.get();
+                tracker.release(self.kind, self.bytes);
+                slot.set(tracker);
+            });
+        }
+    }
+}
+
+/// Reserves the bytes computed by `bytes` for `kind`. The closure is not
+/// called when accounting is off.
+pub(crate) fn reserve(kind: MemoryUse, bytes: impl FnOnce() -> usize) -> Result<MemoryReservation> {
+    let mut reservation = MemoryReservation {
+        kind,
+        bytes: 0,
+        active: TRACKER.with(|slot| slot.get().active()),
+    };
+    reservation.grow(bytes)?;
+    Ok(reservation)
+}
+
+/// Estimates the heap bytes held by one materialized row.
+pub(crate) fn row_bytes(row: &[Value]) -> usize {
+    std::mem::size_of::<Vec<Value>>()
+        + std::mem::size_of_val(row)
+        + row.iter().map(Value::approximate_heap_bytes).sum::<usize>()
+}
+
+/// Estimates the heap bytes held by materialized rows.
+pub(crate) fn rows_bytes(rows: &[Vec<Value>]) -> usize {
+    rows.iter().map(|row| row_bytes(row)).sum()
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn reservations_are_free_without_a_cap_or_profile() {
+        let _statement = StatementMemory::install(0);
+        let reservation = reserve(MemoryUse::Sort, || unreachable!("not accounting"));
+        assert!(reservation.is_ok());
+    }
+
+    #[test]
+    fn cap_rejects_reservations_and_releases_on_drop() {
+        let _statement = StatementMemory::install(100);
+        let first = reserve(MemoryUse::Hash, || 60).expect("within cap");
+        let error = reserve(MemoryUse::Sort, || 60).err().expect("over cap");
+        assert!(error.is_memory_limit_exceeded());
+        drop(first);
+        let _second = reserve(MemoryUse::Sort, || 60).expect("released");
+    }
+
+    #[test]
+    fn profile_records_peaks_per_use() {
+        let _statement = StatementMemory::install(0);
+        let profile = MemoryProfile::start();
+        {
+            let mut join = reserve(MemoryUse::Intermediate, || 10).expect("reserve");
+            join.grow(|| 30).expect("grow");
+            let _sort = reserve(MemoryUse::Sort, || 5).expect("reserve");
+        }
+        let _hash = reserve(MemoryUse::Hash, || 20).expect("reserve");
+        assert_eq!(
+            profile.usage(),
+            MemoryUsage {
+                peak: 45,
+                sort: 5,
+                hash: 20,
+                intermediate: 40,
+            }
+        );
+    }
+}
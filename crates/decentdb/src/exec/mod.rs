@@ -3,11 +3,18 @@
 pub(crate) mod bulk_load;
 #[cfg(test)]
 mod bulk_load_tests;
+mod collation;
 pub(crate) mod constraints;
 pub(crate) mod ddl;
 pub(crate) mod dml;
+pub(crate) mod governor;
+pub(crate) mod interrupt;
+pub(crate) mod memory;
 pub(crate) mod operators;
+pub(crate) mod parallel;
+pub(crate) mod partitions;
 pub(crate) mod row;
+pub(crate) mod sample;
 pub(crate) mod triggers;
 pub(crate) mod txn;
 pub(crate) mod views;
@@ -47,9 +54,10 @@ use crate::btree::read::{
 use crate::btree::table::free_table_btree;
 use crate::btree::write::Btree;
 use crate::catalog::{
-    identifiers_equal, CatalogState, ColumnSchema, ColumnType, EnumLabel, EnumTypeInfo,
-    ForeignKeyAction, IndexKind, IndexSchema, IndexStats, SchemaInfo, TableSchema, TableStats,
-    TriggerEvent, TriggerKind, ViewSchema,
+    identifiers_equal, CatalogState, ColumnCollation, ColumnSchema, ColumnStats, ColumnType,
+    EnumLabel, EnumTypeInfo, ForeignKeyAction, IndexKind, IndexSchema, IndexStats, Partition,
+    PartitionBound, PartitionStrategy, PartitionedTable, SchemaInfo, TableColumnStats,
+    TableComments, TableSchema, TableStats, TriggerEvent, TriggerKind, ViewSchema,
 };
 use crate::error::{DbError, Result};
 use crate::json::{parse_json, parse_json_path, JsonValue};
@@ -91,6 +99,8 @@ use crate::storage::PagerHandle;
 use crate::wal::WalHandle;
 
 use self::cte::*;
+use self::interrupt::{check_interrupt, check_interrupt_every};
+use self::memory::MemoryUse;
 pub(crate) use self::row::{ColumnBinding, Dataset};
 
 pub use row::{QueryResult, QueryRow};
@@ -100,6 +110,7 @@ const ENGINE_ROOT_VERSION: u32 = 1;
 const ENGINE_ROOT_HEADER_SIZE: usize = 32;
 const RECURSIVE_CTE_MAX_ITERATIONS: usize = 1000;
 const GENERATE_SERIES_MAX_ROWS: usize = 1_000_000;
+const ANALYZE_HISTOGRAM_BUCKETS: usize = 32;
 const LEGACY_RUNTIME_PAYLOAD_MAGIC: &[u8; 9] = b"DDBSTATE1";
 const MANIFEST_PAYLOAD_MAGIC: &[u8; 8] = b"DDBMANF1";
 const TABLE_PAYLOAD_MAGIC: &[u8; 8] = b"DDBTBL01";
@@ -128,9 +139,14 @@ const GENERATED_COLUMNS_SECTION_MAGIC: &[u8; 8] = b"DDBGCM02";
 const INDEX_INCLUDE_COLUMNS_SECTION_MAGIC: &[u8; 8] = b"DDBICL1\0";
 const SCHEMAS_SECTION_MAGIC: &[u8; 8] = b"DDBSCH01";
 const PK_INDEX_ROOTS_SECTION_MAGIC: &[u8; 8] = b"DDBPKR01";
+const COLUMN_STATS_SECTION_MAGIC: &[u8; 8] = b"DDBCST01";
+const COMMENTS_SECTION_MAGIC: &[u8; 8] = b"DDBCMT01";
+const TABLE_TTL_SECTION_MAGIC: &[u8; 8] = b"DDBTTL01";
+const PARTITIONED_TABLES_SECTION_MAGIC: &[u8; 8] = b"DDBPRT01";
 const SPATIAL_COLUMNS_SECTION_MAGIC: &[u8; 8] = b"DDBSPT01";
 const ENUM_COLUMNS_SECTION_MAGIC: &[u8; 8] = b"DDBENU01";
 const FULL_TEXT_OPTIONS_SECTION_MAGIC: &[u8; 8] = b"DDBFTS01";
+const COLUMN_COLLATIONS_SECTION_MAGIC: &[u8; 8] = b"DDBCOL01";
 const SIGNED_ROW_ID_BIAS: u64 = 0x8000_0000_0000_0000;
 const DEFERRED_COMPRESSED_LOOKUP_CACHE_LIMIT: usize = 32;
 const DEFERRED_RUNTIME_BTREE_INDEX_CACHE_LIMIT: usize = 16;
@@ -229,6 +245,38 @@ pub(super) fn compat_unqualified_name(name: &str) -> &str {
     compat_schema_qualified_name(name).1
 }
 
+/// Returns the application schema that owns `name`, for objects stored as
+/// `schema.name`. The main, temp, and built-in virtual schemas own nothing.
+pub(super) fn owning_schema_name(name: &str) -> Option<&str> {
+    if compat_schema_qualified_name(name).0.is_some() {
+        return None;
+    }
+    let (schema, _) = name.split_once('.')?;
+    if schema.eq_ignore_ascii_case("sys") || schema.eq_ignore_ascii_case("information_schema") {
+        return None;
+    }
+    Some(schema)
+}
+
+/// Splits a persistent object name into the schema reported for it and its
+/// unqualified name.
+pub(super) fn catalog_schema_and_object(name: &str) -> (&str, &str) {
+    match owning_schema_name(name) {
+        Some(schema) => (schema, &name[schema.len() + 1..]),
+        None => ("main", name),
+    }
+}
+
+/// Places an unqualified index, trigger, or rename target in the schema
+/// that owns `owner`, so same-named objects in different schemas do not
+/// collide.
+pub(super) fn schema_owned_object_name(owner: &str, object: &str) -> String {
+    match owning_schema_name(owner) {
+        Some(schema) if !object.contains('.') => format!("{schema}.{object}"),
+        _ => object.to_string(),
+    }
+}
+
 fn map_get_ci<'a, V>(map: &'a BTreeMap<String, V>, name: &str) -> Option<&'a V> {
     map.get(name).or_else(|| {
         map.iter()
@@ -463,6 +511,16 @@ impl TableData {
         data
     }
 
+    /// Encodes the visible rows, with their row ids, for a temp-store file.
+    pub(crate) fn encode_spill(&self) -> Result<Vec<u8>> {
+        encode_table_payload(self)
+    }
+
+    /// Decodes rows written by [`TableData::encode_spill`].
+    pub(crate) fn decode_spill(bytes: &[u8]) -> Result<Self> {
+        decode_table_payload(bytes)
+    }
+
     pub(crate) fn row_count(&self) -> usize {
         self.rows
             .len()
@@ -2209,7 +2267,7 @@ impl Default for PersistedTableState {
     }
 }
 
-#[derive(Clone, Debug, Eq, PartialEq)]
+#[derive(Clone, Debug, Eq, Hash, PartialEq)]
 pub(crate) enum RuntimeBtreeKey {
     Encoded(Vec<u8>),
     Int64(i64),
@@ -3169,15 +3227,6 @@ impl RuntimeCoveringPayloads {
         }
         freed
     }
-
-    fn project_row(&self, row_id: i64, offsets: &[usize]) -> Option<QueryRow> {
-        let values = self.rows.get(&row_id)?;
-        let mut projected = Vec::with_capacity(offsets.len());
-        for offset in offsets {
-            projected.push(values.get(*offset)?.clone());
-        }
-        Some(QueryRow::new(projected))
-    }
 }
 
 #[derive(Clone, Debug)]
@@ -3264,6 +3313,12 @@ pub(crate) struct EngineRuntime {
     pub(crate) deferred_tables: Arc<BTreeSet<String>>,
     pub(crate) dirty_tables: Arc<BTreeSet<String>>,
     pub(crate) paged_mutations: BTreeMap<String, PagedMutationDelta>,
+    /// Rows inserted, updated, or deleted per table since its last `ANALYZE`,
+    /// used by auto-analyze. Not persisted.
+    pub(crate) analyze_churn: BTreeMap<String, u64>,
+    /// Set by `ANALYZE` so the refreshed statistics are committed even when
+    /// no table rows changed.
+    pub(crate) stats_dirty: bool,
     /// Per-session cache; capped at `cached_payloads_max_entries`. Eviction is LRU.
     payload_cache: Arc<Mutex<PayloadCache>>,
     root_state: Option<RootHeader>,
@@ -3279,6 +3334,9 @@ pub(crate) struct EngineRuntime {
     pub(crate) extension_trust_anchors: Arc<Vec<crate::extensions::ExtensionTrustAnchor>>,
     pub(crate) extension_unsigned_development_mode: bool,
     pub(crate) audit_context: Arc<Mutex<crate::security::AuditContext>>,
+    /// The handle `advisory_lock()` and its siblings lock for; `None` for
+    /// runtimes that no handle runs statements on.
+    pub(crate) advisory_locks: Option<crate::wal::advisory::AdvisoryLockScope>,
     pub(crate) tracing: Option<Arc<crate::tracing::RuntimeTraceState>>,
     fts_eval_context: Arc<Mutex<FtsEvalContext>>,
 }
@@ -3456,6 +3514,8 @@ impl Clone for EngineRuntime {
             // subsequent generic execution path may modify the same rows
             // in ways that invalidate the splice assumption.
             paged_mutations: BTreeMap::new(),
+            analyze_churn: self.analyze_churn.clone(),
+            stats_dirty: self.stats_dirty,
             payload_cache: Arc::clone(&self.payload_cache),
             root_state: self.root_state,
             index_state_epoch: self.index_state_epoch,
@@ -3473,6 +3533,7 @@ impl Clone for EngineRuntime {
             extension_trust_anchors: Arc::clone(&self.extension_trust_anchors),
             extension_unsigned_development_mode: self.extension_unsigned_development_mode,
             audit_context: Arc::clone(&self.audit_context),
+            advisory_locks: self.advisory_locks.clone(),
             tracing: self.tracing.as_ref().map(Arc::clone),
             fts_eval_context: Arc::clone(&self.fts_eval_context),
         }
@@ -3590,6 +3651,8 @@ impl EngineRuntime {
             deferred_tables: Arc::new(BTreeSet::new()),
             dirty_tables: Arc::new(BTreeSet::new()),
             paged_mutations: BTreeMap::new(),
+            analyze_churn: BTreeMap::new(),
+            stats_dirty: false,
             payload_cache: Arc::new(Mutex::new(PayloadCache::new(
                 config.cached_payloads_max_entries,
             ))),
@@ -3606,6 +3669,7 @@ impl EngineRuntime {
             extension_trust_anchors: Arc::new(config.extension_trust_anchors.clone()),
             extension_unsigned_development_mode: config.extension_unsigned_development_mode,
             audit_context: Arc::new(Mutex::new(crate::security::AuditContext::default())),
+            advisory_locks: None,
             tracing: None,
             fts_eval_context: Arc::new(Mutex::new(FtsEvalContext::default())),
         }
@@ -3618,6 +3682,13 @@ impl EngineRuntime {
         self.audit_context = handle;
     }
 
+    pub(crate) fn set_advisory_lock_scope(
+        &mut self,
+        scope: crate::wal::advisory::AdvisoryLockScope,
+    ) {
+        self.advisory_locks = Some(scope);
+    }
+
     pub(crate) fn set_sync_capture_active(&mut self, active: bool) {
         self.sync_capture_active = active;
         if !active {
@@ -3668,6 +3739,7 @@ impl EngineRuntime {
         table_name: &str,
         operation: crate::sync::SyncOperation,
         primary_key: serde_json::Value,
+        before: Option<serde_json::Value>,
         after: Option<serde_json::Value>,
         schema_cookie: u32,
     ) {
@@ -3676,6 +3748,7 @@ impl EngineRuntime {
                 table: table_name.to_string(),
                 operation,
                 primary_key: primary_key.clone(),
+                before,
                 after: after.clone(),
                 schema_cookie,
             });
@@ -3718,6 +3791,11 @@ impl EngineRuntime {
     }
 
     fn cached_view_query(&self, view: &ViewSchema) -> Result<Arc<Query>> {
+        let query = self.parsed_view_query(view)?;
+        Ok(self.apply_view_column_collations(query))
+    }
+
+    fn parsed_view_query(&self, view: &ViewSchema) -> Result<Arc<Query>> {
         let key = ViewQueryCacheKey::new(view);
         {
             let cache = self
@@ -4506,6 +4584,12 @@ impl EngineRuntime {
             .filter(|table_name| self.catalog.table(table_name).is_none())
             .cloned()
             .collect::<Vec<_>>();
+        db.wait_for_table_locks(
+            dirty_tables
+                .iter()
+                .chain(&removed_tables)
+                .map(String::as_str),
+        )?;
 
         {
             let mut store = DbTxnPageStore { db };
@@ -5383,6 +5467,7 @@ impl EngineRuntime {
         db.write_page_owned(page::CATALOG_ROOT_PAGE_ID, root_page)?;
         self.dirty_tables_mut().clear();
         self.paged_mutations.clear();
+        self.stats_dirty = false;
         self.root_state = Some(RootHeader {
             schema_cookie: self.catalog.schema_cookie,
             payload_checksum: checksum,
@@ -6045,6 +6130,41 @@ impl EngineRuntime {
         Ok(())
     }
 
+    /// Encodes how persistent table `name` differs from the same table in
+    /// `base`, the state its transaction started from, as the row changes a
+    /// prepared transaction stores: the ids of rows it no longer has, and a
+    /// table payload of the rows it inserted or changed.
+    pub(crate) fn encode_table_changes(
+        &self,
+        base: &EngineRuntime,
+        name: &str,
+    ) -> Result<(Vec<i64>, Vec<u8>)> {
+        let missing = || DbError::internal(format!("table data for {name} is missing"));
+        let after = self.table_row_source(name).ok_or_else(missing)?;
+        let before = base.table_row_source(name).ok_or_else(missing)?;
+        let mut before_rows = BTreeMap::new();
+        for row in before.rows() {
+            let row = row?;
+            before_rows.insert(row.row_id(), row.values().to_vec());
+        }
+        let mut changed = Vec::new();
+        for row in after.rows() {
+            let row = row?;
+            match before_rows.remove(&row.row_id()) {
+                Some(values) if values.as_slice() == row.values() => {}
+                _ => changed.push(StoredRow {
+                    row_id: row.row_id(),
+                    values: row.values().to_vec(),
+                }),
+            }
+        }
+        let deleted = before_rows.into_keys().collect();
+        Ok((
+            deleted,
+            encode_table_payload(&TableData::from_rows(changed))?,
+        ))
+    }
+
     pub(crate) fn redefer_persisted_tables(&mut self, names: &[&str]) {
         for name in names {
             let Some(table_name) = self.canonical_catalog_table_name(name) else {
@@ -6230,6 +6350,14 @@ impl EngineRuntime {
         }
     }
 
+    pub(super) fn note_analyze_churn(&mut self, table_name: &str, rows: u64) {
+        if let Some(churn) = self.analyze_churn.get_mut(table_name) {
+            *churn = churn.saturating_add(rows);
+        } else {
+            self.analyze_churn.insert(table_name.to_string(), rows);
+        }
+    }
+
     pub(super) fn mark_table_dirty(&mut self, table_name: &str) {
         if self.visible_table_is_temporary(table_name) {
             return;
@@ -6256,6 +6384,7 @@ impl EngineRuntime {
             return;
         };
         self.catalog_mut().table_stats.remove(&table_name);
+        self.note_analyze_churn(&table_name, 1);
         if self.dirty_tables.contains(&table_name)
             && !self.paged_mutations.contains_key(&table_name)
         {
@@ -6284,6 +6413,7 @@ impl EngineRuntime {
             return;
         };
         self.catalog_mut().table_stats.remove(&table_name);
+        self.note_analyze_churn(&table_name, 1);
         if self.dirty_tables.contains(&table_name)
             && !self.paged_mutations.contains_key(&table_name)
         {
@@ -6328,6 +6458,7 @@ impl EngineRuntime {
             return;
         };
         self.catalog_mut().table_stats.remove(&table_name);
+        self.note_analyze_churn(&table_name, 1);
         if self.dirty_tables.contains(&table_name)
             && !self.paged_mutations.contains_key(&table_name)
         {
@@ -6349,6 +6480,7 @@ impl EngineRuntime {
             return;
         };
         self.catalog_mut().table_stats.remove(&table_name);
+        self.note_analyze_churn(&table_name, row_ids.len() as u64);
         if self.dirty_tables.contains(&table_name)
             && !self.paged_mutations.contains_key(&table_name)
         {
@@ -6371,6 +6503,7 @@ impl EngineRuntime {
         if self.visible_table_is_temporary(table_name) {
             return;
         }
+        self.note_analyze_churn(table_name, 1);
         if let Some(delta) = self.paged_mutations.get_mut(table_name) {
             delta.append_count += 1;
             return;
@@ -6637,8 +6770,12 @@ impl EngineRuntime {
         params: &[Value],
         page_size: u32,
     ) -> Result<QueryResult> {
-        match statement {
-            Statement::Query(_) | Statement::Explain(_) => {
+        let statement = match statement {
+            Statement::Query(_) | Statement::Explain(_) => Cow::Borrowed(statement),
+            _ => self.apply_column_collations(statement),
+        };
+        match statement.as_ref() {
+            statement @ (Statement::Query(_) | Statement::Explain(_)) => {
                 self.execute_read_statement(statement, params, page_size)
             }
             Statement::Insert(statement) => {
@@ -6762,6 +6899,14 @@ impl EngineRuntime {
                 )?;
                 Ok(QueryResult::with_affected_rows(0))
             }
+            Statement::CommentOn {
+                table_name,
+                column_name,
+                comment,
+            } => {
+                self.execute_comment_on(table_name, column_name.as_deref(), comment.clone())?;
+                Ok(QueryResult::with_affected_rows(0))
+            }
         }
     }
 
@@ -6779,6 +6924,7 @@ impl EngineRuntime {
         }
         let temporary = statement.temporary || qualifier == Some(CompatSchemaQualifier::Temp);
         let table_name = object_name.to_string();
+        self.ensure_owning_schema_exists(&table_name, temporary)?;
         if temporary {
             if self.temp_relation_exists(&table_name) {
                 if statement.if_not_exists && self.temp_table_schema(&table_name).is_some() {
@@ -6824,22 +6970,70 @@ impl EngineRuntime {
             statement.column_names.clone()
         };
 
+        // A column read straight from a table keeps that column's type, so
+        // an empty result or a column of NULLs is not typed as TEXT. The
+        // description is only a hint; a query it cannot follow falls back to
+        // typing columns from their values.
+        let described =
+            crate::tooling::describe_query_columns(&statement.query, self).unwrap_or_default();
         let columns = target_columns
             .iter()
             .enumerate()
-            .map(|(index, name)| ColumnDefinition {
-                name: name.clone(),
-                column_type: infer_column_type_for_ctas(&source.rows, index),
-                spatial_type: None,
-                enum_type: None,
-                nullable: true,
-                default: None,
-                generated: None,
-                generated_stored: true,
-                primary_key: false,
-                unique: false,
-                checks: Vec::new(),
-                references: None,
+            .map(|(index, name)| {
+                let described = described.get(index);
+                let source_column = described.and_then(|column| {
+                    let table = self.table_schema(column.source_table.as_deref()?)?;
+                    let column_name = column.source_column.as_deref()?;
+                    table
+                        .columns
+                        .iter()
+                        .find(|candidate| identifiers_equal(&candidate.name, column_name))
+                });
+                let (column_type, spatial_type, enum_type) = match source_column {
+                    Some(column) => (
+                        column.column_type,
+                        column.spatial_type,
+                        column.enum_type.clone(),
+                    ),
+                    None if source
+                        .rows
+                        .iter()
+                        .all(|row| matches!(row.get(index), None | Some(Value::Null))) =>
+                    {
+                        let column_type = described
+                            .and_then(|column| column.type_name.as_deref())
+                            .and_then(crate::tooling::column_type_from_name)
+                            .filter(|column_type| {
+                                !matches!(
+                                    column_type,
+                                    ColumnType::Enum | ColumnType::Geometry | ColumnType::Geography
+                                )
+                            })
+                            .unwrap_or(ColumnType::Text);
+                        (column_type, None, None)
+                    }
+                    None => (infer_column_type_for_ctas(&source.rows, index), None, None),
+                };
+                ColumnDefinition {
+                    name: name.clone(),
+                    column_type,
+                    spatial_type,
+                    enum_type,
+                    // A copied column keeps its source's comparison rules;
+                    // computed TEXT columns take the connection default.
+                    collation: source_column.and_then(|column| {
+                        (column.column_type == ColumnType::Text)
+                            .then(|| column.collation.map_or(Collation::Binary, Collation::from))
+                    }),
+                    nullable: true,
+                    default: None,
+                    generated: None,
+                    generated_stored: true,
+                    primary_key: false,
+                    unique: false,
+                    checks: Vec::new(),
+                    references: None,
+                }
             })
             .collect::<Vec<_>>();
         let create_statement = CreateTableStatement {
@@ -6848,6 +7042,9 @@ impl EngineRuntime {
             if_not_exists: false,
             columns,
             constraints: Vec::new(),
+            ttl_column: None,
+            partition_by: None,
+            partition_of: None,
         };
         self.execute_create_table(&create_statement)?;
         if !statement.with_data {
@@ -6904,6 +7101,7 @@ impl EngineRuntime {
             self.apply_insert_index_updates(index_updates)?;
             if !temporary {
                 self.mark_table_dirty(&table_name);
+                self.note_analyze_churn(&table_name, 1);
             }
             affected_rows += 1;
         }
@@ -6912,13 +7110,27 @@ impl EngineRuntime {
     }
 
     pub(crate) fn execute_read_statement(
+        &self,
+        statement: &Statement,
+        params: &[Value],
+        page_size: u32,
+    ) -> Result<QueryResult> {
+        let result = self.execute_read_statement_unlimited(statement, params, page_size)?;
+        if matches!(statement, Statement::Query(_)) {
+            governor::check_result_rows(result.rows().len())?;
+        }
+        Ok(result)
+    }
+
+    fn execute_read_statement_unlimited(
         &self,
         statement: &Statement,
         params: &[Value],
         _page_size: u32,
     ) -> Result<QueryResult> {
         self.clear_fts_eval_context()?;
-        match statement {
+        let statement = self.apply_column_collations(statement);
+        match statement.as_ref() {
             Statement::Query(query) => {
                 if self.security_rules_active()? {
                     return self
@@ -7121,6 +7333,7 @@ impl EngineRuntime {
                         .render();
                         if explain.analyze {
                             lines.insert(0, "ANALYZE true".to_string());
+                            let profile = memory::MemoryProfile::start();
                             let started = Instant::now();
                             let actual_rows = match explain.statement.as_ref() {
                                 Statement::Query(query) => self
@@ -7138,6 +7351,12 @@ impl EngineRuntime {
                                 "Actual Time: {:.3} ms",
                                 started.elapsed().as_secs_f64() * 1_000.0
                             ));
+                            let usage = profile.usage();
+                            lines.push(format!("Peak Memory: {} bytes", usage.peak));
+                            lines.push(format!(
+                                "Memory: sort={} hash={} intermediate={} bytes",
+                                usage.sort, usage.hash, usage.intermediate
+                            ));
                         }
                         Ok(QueryResult::with_explain(lines))
                     }
@@ -7566,6 +7785,7 @@ impl EngineRuntime {
         if select.distinct
             || !select.distinct_on.is_empty()
             || select.from.len() != 1
+            || !select.grouping_sets.is_empty()
             || select.group_by.is_empty()
             || select.projection.len() != select.group_by.len() + 1
         {
@@ -8397,6 +8617,9 @@ impl EngineRuntime {
         query: &Query,
         params: &[Value],
     ) -> Result<Option<QueryResult>> {
+        if self.query_uses_column_collations(query) {
+            return Ok(None);
+        }
         let Some(plan) = self.analyze_simple_grouped_numeric_aggregate_query(query, params)? else {
             return Ok(None);
         };
@@ -8422,6 +8645,7 @@ impl EngineRuntime {
         if select.distinct
             || !select.distinct_on.is_empty()
             || select.from.len() != 1
+            || !select.grouping_sets.is_empty()
             || select.projection.len() <= select.group_by.len()
         {
             return Ok(None);
@@ -9507,6 +9731,9 @@ impl EngineRuntime {
         wal: &WalHandle,
         snapshot_lsn: u64,
     ) -> Result<Option<QueryResult>> {
+        if self.query_uses_column_collations(query) {
+            return Ok(None);
+        }
         let Some(plan) = self.analyze_simple_grouped_numeric_aggregate_query(query, params)? else {
             return Ok(None);
         };
@@ -9556,6 +9783,9 @@ impl EngineRuntime {
         let QueryBody::Select(select) = &query.body else {
             return None;
         };
+        if !select.grouping_sets.is_empty() {
+            return None;
+        }
         if select.group_by.is_empty() && !projection_has_aggregate_items(&select.projection) {
             return None;
         }
@@ -10782,6 +11012,7 @@ impl EngineRuntime {
         if select.distinct
             || !select.distinct_on.is_empty()
             || select.having.is_some()
+            || !select.grouping_sets.is_empty()
             || select.group_by.len() != 1
             || select.projection.len() != 4
             || select.from.len() != 1
@@ -11172,6 +11403,7 @@ impl EngineRuntime {
         };
         if select.distinct
             || !select.distinct_on.is_empty()
+            || !select.grouping_sets.is_empty()
             || select.group_by.len() != 1
             || select.projection.len() != 11
             || select.from.len() != 1
@@ -11477,6 +11709,7 @@ impl EngineRuntime {
             || !select.distinct_on.is_empty()
             || select.filter.is_some()
             || select.having.is_some()
+            || !select.grouping_sets.is_empty()
             || select.group_by.len() != 1
             || select.projection.len() != 5
             || select.from.len() != 1
@@ -12558,6 +12791,7 @@ impl EngineRuntime {
             || !select.distinct_on.is_empty()
             || select.filter.is_some()
             || select.projection.len() != 3
+            || !select.grouping_sets.is_empty()
             || select.group_by.len() != 1
             || select.from.len() != 1
         {
@@ -12635,6 +12869,7 @@ impl EngineRuntime {
             || select.filter.is_some()
             || select.having.is_some()
             || select.projection.len() != 4
+            || !select.grouping_sets.is_empty()
             || select.group_by.len() != 3
             || select.from.len() != 1
         {
@@ -12761,6 +12996,7 @@ impl EngineRuntime {
             || !select.distinct_on.is_empty()
             || select.filter.is_some()
             || select.having.is_some()
+            || !select.grouping_sets.is_empty()
             || select.group_by.len() != 1
             || select.projection.len() != 3
             || select.from.len() != 1
@@ -12958,6 +13194,7 @@ impl EngineRuntime {
             || !select.distinct_on.is_empty()
             || select.filter.is_some()
             || select.having.is_some()
+            || !select.grouping_sets.is_empty()
             || select.group_by.is_empty()
             || select.from.len() != 1
         {
@@ -13195,6 +13432,7 @@ impl EngineRuntime {
             || query.order_by.len() > 2
             || select.filter.is_some()
             || select.having.is_some()
+            || !select.grouping_sets.is_empty()
             || select.group_by.len() != 2
             || select.projection.len() != 7
             || select.from.len() != 1
@@ -13494,6 +13732,9 @@ impl EngineRuntime {
         query: &Query,
         params: &[Value],
     ) -> Result<Option<QueryResult>> {
+        if self.query_uses_column_collations(query) {
+            return Ok(None);
+        }
         let Some(plan) = self.analyze_indexed_join_grouped_count_query(query, params)? else {
             return Ok(None);
         };
@@ -13614,6 +13855,7 @@ impl EngineRuntime {
             || !select.distinct_on.is_empty()
             || select.filter.is_some()
             || select.having.is_some()
+            || !select.grouping_sets.is_empty()
             || select.group_by.is_empty()
             || select.from.len() != 1
             || select.projection.len() != select.group_by.len() + 1
@@ -15161,6 +15403,9 @@ impl EngineRuntime {
         query: &Query,
         params: &[Value],
     ) -> Result<Option<QueryResult>> {
+        if self.query_uses_column_collations(query) {
+            return Ok(None);
+        }
         if !query.ctes.is_empty() {
             return Ok(None);
         }
@@ -16423,6 +16668,7 @@ impl EngineRuntime {
             || select.having.is_some()
             || select.distinct
             || !select.distinct_on.is_empty()
+            || !select.grouping_sets.is_empty()
             || select.group_by.len() != 2
             || select.projection.len() != 3
         {
@@ -16578,7 +16824,8 @@ impl EngineRuntime {
             return Ok(None);
         }
 
-        if select.group_by.len() != 2
+        if !select.grouping_sets.is_empty()
+            || select.group_by.len() != 2
             || !expr_matches_binding_column(&select.group_by[0], item_binding, "id")
             || !expr_matches_binding_column(&select.group_by[1], item_binding, "name")
         {
@@ -16820,9 +17067,84 @@ impl EngineRuntime {
         for table_name in target_tables {
             self.refresh_table_stats(&table_name)?;
         }
+        // The cached manifest only patches row pointers, not statistics.
+        self.manifest_template = None;
+        self.stats_dirty = true;
         Ok(())
     }
 
+    /// Returns the tables whose churn since their last `ANALYZE` reached
+    /// `min_rows` plus `churn_percent` of the row count that `ANALYZE` saw.
+    pub(crate) fn auto_analyze_candidates(&self, min_rows: u64, churn_percent: u32) -> Vec<String> {
+        self.analyze_churn
+            .iter()
+            .filter_map(|(name, churn)| {
+                let table = self.catalog.table(name)?;
+                let analyzed_rows = self
+                    .catalog
+                    .column_stats
+                    .get(&table.name)
+                    .map_or(0, |stats| stats.analyzed_row_count.max(0) as u64);
+                let threshold = min_rows
+                    .saturating_add(analyzed_rows.saturating_mul(u64::from(churn_percent)) / 100);
+                (*churn >= threshold).then(|| table.name.clone())
+            })
+            .collect::<BTreeSet<_>>()
+            .into_iter()
+            .collect()
+    }
+
+    pub(crate) fn table_statistics(&self, name: &str) -> Result<crate::metadata::TableStatistics> {
+        let table = self
+            .catalog
+            .table(name)
+            .filter(|_| !self.visible_table_is_temporary(name))
+            .ok_or_else(|| DbError::sql(format!("unknown table {name}")))?;
+        let column_stats = self.catalog.column_stats.get(&table.name);
+        let columns = column_stats.map_or_else(Vec::new, |stats| {
+            stats
+                .columns
+                .iter()
+                .map(|column| crate::metadata::ColumnStatistics {
+                    column_name: column.column_name.clone(),
+                    null_count: column.null_count,
+                    distinct_count: column.distinct_count,
+                    histogram_bounds: column
+                        .histogram_bounds()
+                        .iter()
+                        .filter_map(|bound| crate::exec::expressions::value_to_text(bound).ok())
+                        .collect(),
+                })
+                .collect()
+        });
+        let indexes = self
+            .catalog
+            .indexes
+            .values()
+            .filter(|index| identifiers_equal(&index.table_name, &table.name))
+            .filter_map(|index| {
+                let stats = self.catalog.index_stats.get(&index.name)?;
+                Some(crate::metadata::IndexStatistics {
+                    index_name: index.name.clone(),
+                    entry_count: stats.entry_count,
+                    distinct_key_count: stats.distinct_key_count,
+                })
+            })
+            .collect();
+        Ok(crate::metadata::TableStatistics {
+            table_name: table.name.clone(),
+            row_count: column_stats.map(|stats| stats.analyzed_row_count),
+            modified_rows_since_analyze: self
+                .analyze_churn
+                .iter()
+                .filter(|(churned, _)| identifiers_equal(churned, &table.name))
+                .map(|(_, rows)| *rows)
+                .sum(),
+            columns,
+            indexes,
+        })
+    }
+
     fn refresh_table_stats(&mut self, table_name: &str) -> Result<()> {
         let row_count = self
             .table_row_source(table_name)
@@ -16839,6 +17161,13 @@ impl EngineRuntime {
         self.catalog_mut()
             .table_stats
             .insert(table_name.to_string(), TableStats { row_count });
+        if let Some(column_stats) = self.collect_column_stats(table_name, row_count)? {
+            self.catalog_mut()
+                .column_stats
+                .insert(table_name.to_string(), column_stats);
+        }
+        self.analyze_churn
+            .retain(|churned, _| !identifiers_equal(churned, table_name));
 
         let index_names = self
             .catalog
@@ -16907,6 +17236,72 @@ impl EngineRuntime {
         Ok(())
     }
 
+    /// Scans a loaded table for per-column NULL counts, distinct counts, and
+    /// equi-depth histograms. Returns `None` when the rows are not loaded.
+    fn collect_column_stats(
+        &self,
+        table_name: &str,
+        row_count: i64,
+    ) -> Result<Option<TableColumnStats>> {
+        let (Some(table), Some(source)) = (
+            self.catalog.table(table_name),
+            self.table_row_source(table_name),
+        ) else {
+            return Ok(None);
+        };
+        let mut null_counts = vec![0_i64; table.columns.len()];
+        let mut values = vec![Vec::new(); table.columns.len()];
+        for row in source.rows() {
+            let row = row?;
+            for (index, value) in row.values().iter().take(values.len()).enumerate() {
+                if matches!(value, Value::Null) {
+                    null_counts[index] += 1;
+                } else {
+                    values[index].push(value.clone());
+                }
+            }
+        }
+
+        let mut columns = Vec::with_capacity(table.columns.len());
+        for ((column, null_count), mut values) in table.columns.iter().zip(null_counts).zip(values)
+        {
+            let (distinct_count, histogram) = if column_type_has_histogram(column.column_type) {
+                values.sort_by(|left, right| {
+                    compare_values_no_error(left, right).unwrap_or(std::cmp::Ordering::Equal)
+                });
+                let distinct_count = usize::from(!values.is_empty())
+                    + values
+                        .windows(2)
+                        .filter(|pair| {
+                            compare_values_no_error(&pair[0], &pair[1])
+                                != Some(std::cmp::Ordering::Equal)
+                        })
+                        .count();
+                let bucket_count = ANALYZE_HISTOGRAM_BUCKETS.min(values.len());
+                let bounds = (1..=bucket_count)
+                    .map(|bucket| values[bucket * values.len() / bucket_count - 1].clone())
+                    .collect::<Vec<_>>();
+                (distinct_count, Row::encode_values(&bounds)?)
+            } else {
+                let mut distinct = BTreeSet::new();
+                for value in &values {
+                    distinct.insert(Row::encode_values(std::slice::from_ref(value))?);
+                }
+                (distinct.len(), Vec::new())
+            };
+            columns.push(ColumnStats {
+                column_name: column.name.clone(),
+                null_count,
+                distinct_count: i64::try_from(distinct_count).unwrap_or(i64::MAX),
+                histogram,
+            });
+        }
+        Ok(Some(TableColumnStats {
+            analyzed_row_count: row_count,
+            columns,
+        }))
+    }
+
     fn try_execute_simple_table_projection_query(
         &self,
         query: &Query,
@@ -19543,10 +19938,12 @@ impl EngineRuntime {
         let Some(RuntimeIndex::Btree { keys, covering }) = self.index(&index.name) else {
             return Ok(None);
         };
-        let covering_offsets = if row_source.is_some_and(|source| !source.has_tombstoned_rows()) {
-            covering.as_ref().and_then(|covering| {
-                covering_projection_offsets(covering, plan.table_schema, &plan.projection_indexes)
-            })
+        let covered_columns = if row_source.is_some_and(|source| !source.has_tombstoned_rows()) {
+            covered_projection_columns(
+                covering.as_ref(),
+                plan.table_schema,
+                &plan.projection_indexes,
+            )
         } else {
             None
         };
@@ -19569,10 +19966,8 @@ impl EngineRuntime {
                 if row_lookup_error.is_some() || rows.len() >= scan_limit {
                     break;
                 }
-                if let (Some(covering), Some(offsets)) =
-                    (covering.as_ref(), covering_offsets.as_ref())
-                {
-                    if let Some(row) = covering.project_row(row_id, offsets) {
+                if let Some(columns) = covered_columns.as_deref() {
+                    if let Some(row) = project_covered_row(covering.as_ref(), row_id, columns) {
                         rows.push(row);
                         continue;
                     }
@@ -19602,10 +19997,8 @@ impl EngineRuntime {
                 if row_lookup_error.is_some() || rows.len() >= scan_limit {
                     return;
                 }
-                if let (Some(covering), Some(offsets)) =
-                    (covering.as_ref(), covering_offsets.as_ref())
-                {
-                    if let Some(row) = covering.project_row(row_id, offsets) {
+                if let Some(columns) = covered_columns.as_deref() {
+                    if let Some(row) = project_covered_row(covering.as_ref(), row_id, columns) {
                         rows.push(row);
                         return;
                     }
@@ -19686,14 +20079,12 @@ impl EngineRuntime {
                 return Ok(None);
             };
             let row_id_order = indexed_projection_row_id_order(&plan);
-            let covering_offsets = if !row_source.has_tombstoned_rows() {
-                covering.as_ref().and_then(|covering| {
-                    covering_projection_offsets(
-                        covering,
-                        plan.table_schema,
-                        &plan.projection_indexes,
-                    )
-                })
+            let covered_columns = if !row_source.has_tombstoned_rows() {
+                covered_projection_columns(
+                    covering.as_ref(),
+                    plan.table_schema,
+                    &plan.projection_indexes,
+                )
             } else {
                 None
             };
@@ -19715,10 +20106,8 @@ impl EngineRuntime {
                     if row_lookup_error.is_some() || rows.len() >= scan_limit {
                         break;
                     }
-                    if let (Some(covering), Some(offsets)) =
-                        (covering.as_ref(), covering_offsets.as_ref())
-                    {
-                        if let Some(row) = covering.project_row(row_id, offsets) {
+                    if let Some(columns) = covered_columns.as_deref() {
+                        if let Some(row) = project_covered_row(covering.as_ref(), row_id, columns) {
                             rows.push(row);
                             continue;
                         }
@@ -19741,10 +20130,8 @@ impl EngineRuntime {
                 if row_lookup_error.is_some() || rows.len() >= scan_limit {
                     return;
                 }
-                if let (Some(covering), Some(offsets)) =
-                    (covering.as_ref(), covering_offsets.as_ref())
-                {
-                    if let Some(row) = covering.project_row(row_id, offsets) {
+                if let Some(columns) = covered_columns.as_deref() {
+                    if let Some(row) = project_covered_row(covering.as_ref(), row_id, columns) {
                         rows.push(row);
                         return;
                     }
@@ -19833,9 +20220,11 @@ impl EngineRuntime {
         let Some(RuntimeIndex::Btree { keys, covering }) = self.index(&index.name) else {
             return Ok(None);
         };
-        let covering_offsets = covering.as_ref().and_then(|covering| {
-            covering_projection_offsets(covering, plan.table_schema, &plan.projection_indexes)
-        });
+        let covered_columns = covered_projection_columns(
+            covering.as_ref(),
+            plan.table_schema,
+            &plan.projection_indexes,
+        );
         let row_id_order = indexed_projection_row_id_order(&plan);
         let row_ids = row_ids_for_simple_indexed_projection_lookup(keys, &plan)?;
         let scan_limit = if let Some((_, limit_with_offset)) = row_id_order {
@@ -19854,10 +20243,8 @@ impl EngineRuntime {
                 if row_lookup_error.is_some() || rows.len() >= scan_limit {
                     break;
                 }
-                if let (Some(covering), Some(offsets)) =
-                    (covering.as_ref(), covering_offsets.as_ref())
-                {
-                    if let Some(row) = covering.project_row(row_id, offsets) {
+                if let Some(columns) = covered_columns.as_deref() {
+                    if let Some(row) = project_covered_row(covering.as_ref(), row_id, columns) {
                         rows.push(row);
                         continue;
                     }
@@ -19890,10 +20277,8 @@ impl EngineRuntime {
                 if row_lookup_error.is_some() || rows.len() >= scan_limit {
                     return;
                 }
-                if let (Some(covering), Some(offsets)) =
-                    (covering.as_ref(), covering_offsets.as_ref())
-                {
-                    if let Some(row) = covering.project_row(row_id, offsets) {
+                if let Some(columns) = covered_columns.as_deref() {
+                    if let Some(row) = project_covered_row(covering.as_ref(), row_id, columns) {
                         rows.push(row);
                         return;
                     }
@@ -22105,6 +22490,9 @@ impl EngineRuntime {
         snapshot_lsn: u64,
         use_persistent_pk_index: bool,
     ) -> Result<Option<QueryResult>> {
+        if self.query_uses_column_collations(query) {
+            return Ok(None);
+        }
         if let Some(result) = self.try_execute_simple_deferred_view_projection_limit_query(
             query,
             params,
@@ -24247,6 +24635,7 @@ impl EngineRuntime {
             || select.having.is_some()
             || select.distinct
             || !select.distinct_on.is_empty()
+            || !select.grouping_sets.is_empty()
             || select.group_by.len() != 2
             || !crm_column(&select.group_by[0], &["c", "companies"], "id")
             || !crm_column(&select.group_by[1], &["c", "companies"], "name")
@@ -24354,12 +24743,14 @@ impl EngineRuntime {
     ) -> Result<Dataset> {
         let mut ctes = inherited_ctes.clone();
         let recursive_ctes = validate_recursive_ctes(query)?;
+        let mut cte_memory = memory::reserve(MemoryUse::Intermediate, || 0)?;
         for cte in &query.ctes {
             let dataset = if recursive_ctes.contains(&cte.name) {
                 self.evaluate_recursive_cte(cte, params, &ctes)?
             } else {
                 prepare_cte_dataset(cte, self.evaluate_query(&cte.query, params, &ctes)?)?
             };
+            cte_memory.grow(|| memory::rows_bytes(&dataset.rows))?;
             ctes.insert(cte.name.clone(), dataset);
         }
 
@@ -24388,6 +24779,9 @@ impl EngineRuntime {
                     let projection_order_by =
                         projection_order_by_plan(&query.order_by, &select.projection);
                     let mut source = self.build_select_dataset(select, params, &ctes)?;
+                    let _source_memory = memory::reserve(MemoryUse::Intermediate, || {
+                        memory::rows_bytes(&source.rows)
+                    })?;
                     if !query.order_by.is_empty() && projection_order_by.is_none() {
                         self.sort_dataset(&mut source, &query.order_by, params, &ctes)?;
                         sorted_during_select = true;
@@ -24551,6 +24945,7 @@ impl EngineRuntime {
             if next_rows.is_empty() {
                 return Ok(result);
             }
+            check_interrupt()?;
 
             result.rows_mut().extend(next_rows.clone());
             working.set_rows(next_rows);
@@ -24775,14 +25170,26 @@ impl EngineRuntime {
         if !query_references_outer_scope(query, outer_dataset) {
             return self.evaluate_query(query, params, inherited_ctes);
         }
-        if query.recursive {
-            return Err(DbError::sql(
-                "WITH RECURSIVE is not supported in correlated subqueries yet",
-            ));
-        }
-
+        let recursive_ctes = validate_recursive_ctes(query)?;
         let mut ctes = inherited_ctes.clone();
         for cte in &query.ctes {
+            // A CTE that does not read the outer row, such as a hierarchy
+            // walk joined to it in the body, is evaluated as usual.
+            if !query_references_outer_scope(&cte.query, outer_dataset) {
+                let dataset = if recursive_ctes.contains(&cte.name) {
+                    self.evaluate_recursive_cte(cte, params, &ctes)?
+                } else {
+                    prepare_cte_dataset(cte, self.evaluate_query(&cte.query, params, &ctes)?)?
+                };
+                ctes.insert(cte.name.clone(), dataset);
+                continue;
+            }
+            if recursive_ctes.contains(&cte.name) {
+                return Err(DbError::sql(format!(
+                    "recursive CTE {} cannot reference columns of an outer query",
+                    cte.name
+                )));
+            }
             let mut dataset = self.evaluate_query_with_outer(
                 &cte.query,
                 params,
@@ -25016,6 +25423,7 @@ impl EngineRuntime {
         }
 
         let Dataset { columns, rows } = dataset;
+        let _memory = memory::reserve(MemoryUse::Hash, || memory::rows_bytes(&rows))?;
         let rows = if select.distinct_on.is_empty() {
             deduplicate_rows_stable(Arc::unwrap_or_clone(rows))?
         } else {
@@ -25046,7 +25454,9 @@ impl EngineRuntime {
     ) -> Result<Dataset> {
         let has_lateral = select.from.iter().any(from_item_contains_lateral);
         let mut dataset = if !has_lateral {
-            if let Some(dataset) = self.try_view_filter_pushdown(select, params, ctes)? {
+            if let Some(dataset) = self.try_partition_pruned_scan(select, params, ctes)? {
+                dataset
+            } else if let Some(dataset) = self.try_view_filter_pushdown(select, params, ctes)? {
                 dataset
             } else if let Some(dataset) = self.try_indexed_scan(select, params, ctes)? {
                 dataset
@@ -25067,15 +25477,17 @@ impl EngineRuntime {
 
         if let Some(filter) = &select.filter {
             let filter_dataset = Dataset::with_rows(dataset.columns.clone(), Vec::new());
-            let mut filtered = Vec::with_capacity(dataset.rows.len());
-            for row in dataset.take_rows() {
-                if matches!(
-                    self.eval_expr(filter, &filter_dataset, &row, params, ctes, None)?,
+            let workers = if parallel::expr_is_parallel_safe(filter) {
+                parallel::parallel_workers()
+            } else {
+                1
+            };
+            let filtered = parallel::retain_rows(dataset.take_rows(), workers, |row| {
+                Ok(matches!(
+                    self.eval_expr(filter, &filter_dataset, row, params, ctes, None)?,
                     Value::Bool(true)
-                ) {
-                    filtered.push(row);
-                }
-            }
+                ))
+            })?;
             dataset.set_rows(filtered);
         }
 
@@ -25433,6 +25845,47 @@ impl EngineRuntime {
             }
         }
 
+        if let Some(lookup) = collated_btree_lookup(filter)
+            .filter(|lookup| matches_filter_binding(name, alias, lookup.table_qualifier))
+        {
+            if let Some(index) = self.catalog.indexes.values().find(|index| {
+                identifiers_equal(&index.table_name, name)
+                    && index.fresh
+                    && index.kind == IndexKind::Btree
+                    && index.predicate_sql.is_none()
+                    && index_is_collated_column_key(index, lookup.column_name, &lookup.collation)
+            }) {
+                let value = self.eval_expr(
+                    lookup.value_expr,
+                    &Dataset::empty(),
+                    &[],
+                    params,
+                    ctes,
+                    None,
+                )?;
+                // A pattern with wildcards can match more than one key.
+                let exact = !lookup.pattern
+                    || matches!(&value, Value::Text(text) if !text.contains(['%', '_']));
+                if let (true, Some(RuntimeIndex::Btree { keys, .. })) =
+                    (exact, self.index(&index.name))
+                {
+                    let value = fold_collation_key(value, Some(&lookup.collation));
+                    let row_ids = keys.row_ids_for_value_set(&value)?;
+                    if let Some(ref tracing) = self.tracing {
+                        tracing.record_index_usage(
+                            name,
+                            &index.name,
+                            "btree",
+                            crate::tracing::index_usage::IndexUsageKind::Read,
+                        );
+                    }
+                    return self
+                        .dataset_from_row_id_set(table, row_source, alias, row_ids, false)
+                        .map(Some);
+                }
+            }
+        }
+
         if let Some(row_ids) =
             self.trigram_candidate_row_ids_for_filter(name, alias, filter, params, ctes)?
         {
@@ -25696,7 +26149,30 @@ impl EngineRuntime {
         if !index.planner_may_use_index() {
             return Ok(None);
         }
-        let row_ids = match index.query_candidates(&pattern, lookup.has_additional_filter)? {
+        let result = match lookup.kind {
+            TrigramLookupKind::Like => {
+                index.query_candidates(&pattern, lookup.has_additional_filter)?
+            }
+            TrigramLookupKind::Similarity {
+                threshold_expr,
+                inclusive,
+            } => {
+                let threshold = match self.eval_expr(
+                    threshold_expr,
+                    &Dataset::empty(),
+                    &[],
+                    params,
+                    ctes,
+                    None,
+                )? {
+                    Value::Float64(value) => value,
+                    Value::Int64(value) => value as f64,
+                    _ => return Ok(None),
+                };
+                index.similarity_candidates(&pattern, threshold, inclusive)?
+            }
+        };
+        let row_ids = match result {
             TrigramQueryResult::Candidates(ids) | TrigramQueryResult::Capped(ids) => ids
                 .into_iter()
                 .filter_map(|row_id| i64::try_from(row_id).ok())
@@ -27069,6 +27545,36 @@ impl EngineRuntime {
                     .collect::<Result<Vec<_>>>()?;
                 self.evaluate_table_function(name, values, alias)
             }
+            FromItem::Sample {
+                source,
+                method,
+                percent,
+                seed,
+            } => {
+                let fraction = sample::sample_fraction(&self.eval_expr(
+                    percent,
+                    &Dataset::empty(),
+                    &[],
+                    params,
+                    ctes,
+                    None,
+                )?)?;
+                let seed = seed
+                    .as_ref()
+                    .map(|seed| self.eval_expr(seed, &Dataset::empty(), &[], params, ctes, None))
+                    .transpose()?;
+                let seed = sample::sample_seed(seed.as_ref())?;
+                let mut dataset = self.evaluate_from_item_in_scope(
+                    source,
+                    params,
+                    ctes,
+                    scope_dataset,
+                    scope_row,
+                )?;
+                let rows = sample::sample_rows(&dataset.rows, *method, fraction, seed);
+                dataset.set_rows(rows);
+                Ok(dataset)
+            }
             FromItem::Join {
                 left,
                 right,
@@ -27203,6 +27709,9 @@ impl EngineRuntime {
             "pragma_database_list" | "main.pragma_database_list" | "temp.pragma_database_list" => {
                 self.evaluate_pragma_database_list_function(table_name, values)
             }
+            "decentdb_stat_statements" => {
+                self.evaluate_stat_statements_function(table_name, values)
+            }
             other => {
                 if let Some(dataset) = crate::extensions::evaluate_table_function_from_runtime(
                     self, other, values, table_name,
@@ -27390,6 +27899,33 @@ impl EngineRuntime {
         ))
     }
 
+    fn evaluate_stat_statements_function(
+        &self,
+        table_name: String,
+        values: Vec<Value>,
+    ) -> Result<Dataset> {
+        if !values.is_empty() {
+            return Err(DbError::sql(
+                "decentdb_stat_statements expects no arguments",
+            ));
+        }
+        let rows = self
+            .tracing
+            .as_ref()
+            .map(|tracing| tracing.statement_stats_snapshot())
+            .unwrap_or_default()
+            .iter()
+            .map(|row| row.to_query_row())
+            .collect();
+        Ok(Dataset::with_rows(
+            visible_columns(
+                &table_name,
+                crate::tracing::statement_stats::STATEMENT_STATS_COLUMNS,
+            ),
+            rows,
+        ))
+    }
+
     fn pragma_table_list_dataset(&self, table_name: String) -> Dataset {
         let mut rows = Vec::new();
         for table in self.catalog.tables.values() {
@@ -27554,14 +28090,12 @@ impl EngineRuntime {
             if !compat_catalog_object_is_visible(&table.name) {
                 continue;
             }
-            rows.push(information_schema_table_row(
-                "main",
-                &table.name,
-                "BASE TABLE",
-            ));
+            let (schema, name) = catalog_schema_and_object(&table.name);
+            rows.push(information_schema_table_row(schema, name, "BASE TABLE"));
         }
         for view in self.catalog.views.values() {
-            rows.push(information_schema_table_row("main", &view.name, "VIEW"));
+            let (schema, name) = catalog_schema_and_object(&view.name);
+            rows.push(information_schema_table_row(schema, name, "VIEW"));
         }
         for table in self.temp_tables.values() {
             if !compat_catalog_object_is_visible(&table.name) {
@@ -27596,11 +28130,8 @@ impl EngineRuntime {
             if !compat_catalog_object_is_visible(&table.name) {
                 continue;
             }
-            rows.extend(information_schema_column_rows(
-                "main",
-                &table.name,
-                &table.columns,
-            ));
+            let (schema, name) = catalog_schema_and_object(&table.name);
+            rows.extend(information_schema_column_rows(schema, name, &table.columns));
         }
         for table in self.temp_tables.values() {
             if !compat_catalog_object_is_visible(&table.name) {
@@ -28464,6 +28995,22 @@ fn compare_values_no_error(a: &Value, b: &Value) -> Option<std::cmp::Ordering> {
     crate::exec::expressions::compare_values(a, b).ok()
 }
 
+/// Column types whose values have an ordering the planner can estimate
+/// ranges against.
+fn column_type_has_histogram(column_type: ColumnType) -> bool {
+    matches!(
+        column_type,
+        ColumnType::Int64
+            | ColumnType::Float64
+            | ColumnType::Text
+            | ColumnType::Decimal
+            | ColumnType::Timestamp
+            | ColumnType::Date
+            | ColumnType::Time
+            | ColumnType::TimestampTz
+    )
+}
+
 enum SimpleIndexedProjectionRowIds<'a> {
     Borrowed(RuntimeRowIdSet<'a>),
     Owned(Vec<i64>),
@@ -28716,6 +29263,109 @@ fn window_key_from_positions(row: &[Value], positions: &[usize]) -> Result<Vec<u
     row_identity(&values_from_positions(row, positions)?)
 }
 
+/// Returns the partition ordinal a `RANGE offset PRECEDING|FOLLOWING` bound
+/// reaches from the row at `ordinal`: the first row whose sort key is at or
+/// past `key -/+ offset` for a frame start, the last row before it for a frame
+/// end. Offsets are added with the SQL `+`/`-` operators, so numeric keys take
+/// numeric offsets and date or timestamp keys take intervals. Rows with a NULL
+/// key are never in range of a non-NULL key; `None` means the current key is
+/// NULL and the caller uses its peer group.
+fn window_range_offset_bound_index(
+    order_keys: &[Vec<Value>],
+    descending: bool,
+    ordinal: usize,
+    offset: Value,
+    preceding: bool,
+    start: bool,
+) -> Result<Option<i64>> {
+    let key_at = |index: usize| -> Result<&Value> {
+        order_keys
+            .get(index)
+            .and_then(|keys| keys.first())
+            .ok_or_else(|| DbError::internal("window sort key is missing"))
+    };
+    let key = key_at(ordinal)?;
+    if matches!(key, Value::Null) {
+        return Ok(None);
+    }
+    match &offset {
+        Value::Null => return Err(DbError::sql("window frame offset cannot be NULL")),
+        Value::Int64(_) | Value::Float64(_) | Value::Decimal { .. } => {
+            if compare_values(&offset, &Value::Int64(0))? == std::cmp::Ordering::Less {
+                return Err(DbError::sql("window frame offset must not be negative"));
+            }
+        }
+        Value::Interval {
+            months,
+            days,
+            micros,
+        } => {
+            if *months < 0 || *days < 0 || *micros < 0 {
+                return Err(DbError::sql("window frame offset must not be negative"));
+            }
+        }
+        other => {
+            return Err(DbError::sql(format!(
+                "RANGE frame offset must be numeric or an interval, got {other:?}"
+            )))
+        }
+    }
+    // PRECEDING walks toward the start of the sort order, which holds larger
+    // keys when it is descending.
+    let op = if preceding != descending {
+        BinaryOp::Sub
+    } else {
+        BinaryOp::Add
+    };
+    let target = eval_binary(&op, key.clone(), offset)?;
+    // DATE plus an INTERVAL is a TIMESTAMP; compare the dates as midnights.
+    let comparable = |value: &Value| -> Result<Value> {
+        match (value, &target) {
+            (Value::DateDays(days), Value::TimestampMicros(_)) => {
+                Ok(Value::TimestampMicros(date_days_to_micros(*days)?))
+            }
+            _ => Ok(value.clone()),
+        }
+    };
+
+    // NULL keys sort together at one end of the partition; search the rest.
+    let mut lo = 0;
+    while lo < order_keys.len() && matches!(key_at(lo)?, Value::Null) {
+        lo += 1;
+    }
+    let mut hi = order_keys.len();
+    while hi > lo && matches!(key_at(hi - 1)?, Value::Null) {
+        hi -= 1;
+    }
+    let mut ordering_error = None;
+    let before_target = order_keys[lo..hi].partition_point(|keys| {
+        let ordering = match comparable(&keys[0]).and_then(|key| compare_values(&key, &target)) {
+            Ok(ordering) => ordering,
+            Err(err) => {
+                ordering_error.get_or_insert(err);
+                std::cmp::Ordering::Equal
+            }
+        };
+        let ordering = if descending {
+            ordering.reverse()
+        } else {
+            ordering
+        };
+        if start {
+            ordering == std::cmp::Ordering::Less
+        } else {
+            ordering != std::cmp::Ordering::Greater
+        }
+    });
+    if let Some(err) = ordering_error {
+        return Err(err);
+    }
+    let index = lo + before_target;
+    let index =
+        i64::try_from(index).map_err(|_| DbError::internal("window ordinal is too large"))?;
+    Ok(Some(if start { index } else { index - 1 }))
+}
+
 fn rows_preceding_current_frame(frame: Option<&crate::sql::ast::WindowFrame>) -> Option<usize> {
     let frame = frame?;
     if frame.unit != crate::sql::ast::WindowFrameUnit::Rows {
@@ -30008,7 +30658,9 @@ pub(super) fn compute_index_values(
                 Ok(row_for_eval[position].clone())
             } else if let Some(expression_sql) = &column.expression_sql {
                 let expr = crate::sql::parser::parse_expression_sql(expression_sql)?;
-                runtime.eval_expr(&expr, &dataset, bindings, &[], &BTreeMap::new(), None)
+                let value =
+                    runtime.eval_expr(&expr, &dataset, bindings, &[], &BTreeMap::new(), None)?;
+                Ok(fold_collation_key(value, expr_collation(&expr).as_ref()))
             } else {
                 Err(DbError::constraint("index column definition is empty"))
             }
@@ -30106,9 +30758,24 @@ pub(crate) fn row_satisfies_expression(
     row_values: &[Value],
     expr: &Expr,
 ) -> Result<bool> {
+    Ok(matches!(
+        evaluate_row_expression(runtime, table_name, column_names, row_values, expr)?,
+        Value::Bool(true)
+    ))
+}
+
+/// Evaluates `expr` against one row of `table_name`, binding each of
+/// `column_names` to the matching entry of `row_values`.
+pub(crate) fn evaluate_row_expression(
+    runtime: &EngineRuntime,
+    table_name: &str,
+    column_names: &[String],
+    row_values: &[Value],
+    expr: &Expr,
+) -> Result<Value> {
     if column_names.len() != row_values.len() {
         return Err(DbError::internal(
-            "row filter evaluation received mismatched column/value counts",
+            "row expression evaluation received mismatched column/value counts",
         ));
     }
     let dataset = Dataset::with_rows(
@@ -30119,10 +30786,7 @@ pub(crate) fn row_satisfies_expression(
         vec![row_values.to_vec()],
     );
     let row = dataset.rows.first().map(Vec::as_slice).unwrap_or(&[]);
-    Ok(matches!(
-        runtime.eval_expr(expr, &dataset, row, &[], &BTreeMap::new(), None)?,
-        Value::Bool(true)
-    ))
+    runtime.eval_expr(expr, &dataset, row, &[], &BTreeMap::new(), None)
 }
 
 fn simple_stored_column_eq_literal_predicate(
@@ -30338,6 +31002,7 @@ fn encode_runtime_payload(runtime: &EngineRuntime) -> Result<Vec<u8>> {
     encode_generated_columns_section(&mut output, &runtime.catalog.tables)?;
     encode_spatial_columns_section(&mut output, &runtime.catalog.tables)?;
     encode_enum_columns_section(&mut output, &runtime.catalog.tables)?;
+    encode_column_collations_section(&mut output, &runtime.catalog.tables)?;
     Ok(output)
 }
 
@@ -30389,6 +31054,7 @@ fn decode_runtime_payload(bytes: &[u8]) -> Result<EngineRuntime> {
                 column_type,
                 spatial_type: None,
                 enum_type: None,
+                collation: None,
                 nullable,
                 default_sql,
                 generated_sql: None,
@@ -30511,6 +31177,24 @@ fn decode_runtime_payload(bytes: &[u8]) -> Result<EngineRuntime> {
     if cursor.offset < cursor.bytes.len() {
         decode_pk_index_roots_section(&mut cursor, &mut runtime.catalog_mut().tables)?;
     }
+    if cursor.offset < cursor.bytes.len() {
+        decode_column_stats_section(&mut cursor, &mut runtime.catalog_mut().column_stats)?;
+    }
+    if cursor.offset < cursor.bytes.len() {
+        decode_comments_section(&mut cursor, &mut runtime.catalog_mut().comments)?;
+    }
+    if cursor.offset < cursor.bytes.len() {
+        decode_table_ttl_section(&mut cursor, &mut runtime.catalog_mut().table_ttl)?;
+    }
+    if cursor.offset < cursor.bytes.len() {
+        decode_partitioned_tables_section(
+            &mut cursor,
+            &mut runtime.catalog_mut().partitioned_tables,
+        )?;
+    }
+    if cursor.offset < cursor.bytes.len() {
+        decode_column_collations_section(&mut cursor, &mut runtime.catalog_mut().tables)?;
+    }
     Ok(runtime)
 }
 
@@ -30641,6 +31325,11 @@ fn encode_manifest_payload_with_offsets(
         &runtime.catalog.tables,
         Some(&mut table_pk_index_root_offsets),
     )?;
+    encode_column_stats_section(&mut output, runtime)?;
+    encode_comments_section(&mut output, runtime)?;
+    encode_table_ttl_section(&mut output, runtime)?;
+    encode_partitioned_tables_section(&mut output, runtime)?;
+    encode_column_collations_section(&mut output, &runtime.catalog.tables)?;
     Ok(ManifestEncoding {
         bytes: output,
         table_next_row_id_offsets,
@@ -30751,6 +31440,7 @@ fn decode_manifest_payload<S: PageStore>(_store: &S, bytes: &[u8]) -> Result<Eng
                 column_type,
                 spatial_type: None,
                 enum_type: None,
+                collation: None,
                 nullable,
                 default_sql,
                 generated_sql: None,
@@ -30909,6 +31599,24 @@ fn decode_manifest_payload<S: PageStore>(_store: &S, bytes: &[u8]) -> Result<Eng
     if cursor.offset < cursor.bytes.len() {
         decode_pk_index_roots_section(&mut cursor, &mut runtime.catalog_mut().tables)?;
     }
+    if cursor.offset < cursor.bytes.len() {
+        decode_column_stats_section(&mut cursor, &mut runtime.catalog_mut().column_stats)?;
+    }
+    if cursor.offset < cursor.bytes.len() {
+        decode_comments_section(&mut cursor, &mut runtime.catalog_mut().comments)?;
+    }
+    if cursor.offset < cursor.bytes.len() {
+        decode_table_ttl_section(&mut cursor, &mut runtime.catalog_mut().table_ttl)?;
+    }
+    if cursor.offset < cursor.bytes.len() {
+        decode_partitioned_tables_section(
+            &mut cursor,
+            &mut runtime.catalog_mut().partitioned_tables,
+        )?;
+    }
+    if cursor.offset < cursor.bytes.len() {
+        decode_column_collations_section(&mut cursor, &mut runtime.catalog_mut().tables)?;
+    }
     let table_pk_roots = runtime
         .catalog
         .tables
@@ -34670,7 +35378,6 @@ fn append_table_payload(mut previous: Vec<u8>, data: &TableData) -> Result<Vec<u
     Ok(previous)
 }
 
-#[cfg(test)]
 fn decode_table_payload(bytes: &[u8]) -> Result<TableData> {
     if bytes.is_empty() {
         return Ok(TableData::default());
@@ -34877,6 +35584,35 @@ fn encode_enum_columns_section(
     Ok(())
 }
 
+fn encode_column_collations_section(
+    output: &mut Vec<u8>,
+    tables: &BTreeMap<String, TableSchema>,
+) -> Result<()> {
+    let collated_columns = tables
+        .values()
+        .flat_map(|table| {
+            table.columns.iter().filter_map(move |column| {
+                column
+                    .collation
+                    .map(|collation| (table.name.as_str(), column.name.as_str(), collation))
+            })
+        })
+        .collect::<Vec<_>>();
+    output.extend_from_slice(COLUMN_COLLATIONS_SECTION_MAGIC);
+    output.push(1);
+    encode_u32(
+        output,
+        u32::try_from(collated_columns.len())
+            .map_err(|_| DbError::constraint("collated column count exceeds u32"))?,
+    );
+    for (table_name, column_name, collation) in collated_columns {
+        encode_string(output, table_name)?;
+        encode_string(output, column_name)?;
+        encode_string(output, collation.name())?;
+    }
+    Ok(())
+}
+
 fn encode_index_include_columns_section(
     output: &mut Vec<u8>,
     indexes: &BTreeMap<String, IndexSchema>,
@@ -34963,6 +35699,135 @@ fn encode_pk_index_roots_section(
     Ok(())
 }
 
+fn encode_column_stats_section(output: &mut Vec<u8>, runtime: &EngineRuntime) -> Result<()> {
+    let column_stats = runtime
+        .catalog
+        .column_stats
+        .iter()
+        .filter(|(name, _)| runtime.catalog.tables.contains_key(*name))
+        .collect::<Vec<_>>();
+    output.extend_from_slice(COLUMN_STATS_SECTION_MAGIC);
+    output.push(1);
+    encode_u32(
+        output,
+        u32::try_from(column_stats.len())
+            .map_err(|_| DbError::constraint("column stats entry count exceeds u32"))?,
+    );
+    for (table_name, stats) in column_stats {
+        encode_string(output, table_name)?;
+        encode_i64(output, stats.analyzed_row_count);
+        encode_u32(
+            output,
+            u32::try_from(stats.columns.len())
+                .map_err(|_| DbError::constraint("column stats column count exceeds u32"))?,
+        );
+        for column in &stats.columns {
+            encode_string(output, &column.column_name)?;
+            encode_i64(output, column.null_count);
+            encode_i64(output, column.distinct_count);
+            encode_u32(
+                output,
+                u32::try_from(column.histogram.len())
+                    .map_err(|_| DbError::constraint("column histogram exceeds u32 bytes"))?,
+            );
+            output.extend_from_slice(&column.histogram);
+        }
+    }
+    Ok(())
+}
+
+fn encode_comments_section(output: &mut Vec<u8>, runtime: &EngineRuntime) -> Result<()> {
+    let comments = runtime
+        .catalog
+        .comments
+        .iter()
+        .filter(|(name, _)| runtime.catalog.tables.contains_key(*name))
+        .collect::<Vec<_>>();
+    output.extend_from_slice(COMMENTS_SECTION_MAGIC);
+    output.push(1);
+    encode_u32(
+        output,
+        u32::try_from(comments.len())
+            .map_err(|_| DbError::constraint("comment entry count exceeds u32"))?,
+    );
+    for (table_name, comments) in comments {
+        encode_string(output, table_name)?;
+        encode_optional_string(output, comments.table.as_deref())?;
+        encode_u32(
+            output,
+            u32::try_from(comments.columns.len())
+                .map_err(|_| DbError::constraint("column comment count exceeds u32"))?,
+        );
+        for (column_name, comment) in &comments.columns {
+            encode_string(output, column_name)?;
+            encode_string(output, comment)?;
+        }
+    }
+    Ok(())
+}
+
+fn encode_table_ttl_section(output: &mut Vec<u8>, runtime: &EngineRuntime) -> Result<()> {
+    let entries = runtime
+        .catalog
+        .table_ttl
+        .iter()
+        .filter(|(name, _)| runtime.catalog.tables.contains_key(*name))
+        .collect::<Vec<_>>();
+    output.extend_from_slice(TABLE_TTL_SECTION_MAGIC);
+    output.push(1);
+    encode_u32(
+        output,
+        u32::try_from(entries.len())
+            .map_err(|_| DbError::constraint("table ttl entry count exceeds u32"))?,
+    );
+    for (table_name, ttl_column) in entries {
+        encode_string(output, table_name)?;
+        encode_string(output, ttl_column)?;
+    }
+    Ok(())
+}
+
+fn encode_partitioned_tables_section(output: &mut Vec<u8>, runtime: &EngineRuntime) -> Result<()> {
+    let parents = &runtime.catalog.partitioned_tables;
+    output.extend_from_slice(PARTITIONED_TABLES_SECTION_MAGIC);
+    output.push(1);
+    encode_u32(
+        output,
+        u32::try_from(parents.len())
+            .map_err(|_| DbError::constraint("partitioned table count exceeds u32"))?,
+    );
+    for (parent_name, parent) in parents {
+        encode_string(output, parent_name)?;
+        output.push(match parent.strategy {
+            PartitionStrategy::Range => 0,
+            PartitionStrategy::Hash => 1,
+        });
+        encode_string(output, &parent.key_column)?;
+        encode_string(output, &parent.template_sql)?;
+        encode_u32(
+            output,
+            u32::try_from(parent.partitions.len())
+                .map_err(|_| DbError::constraint("partition count exceeds u32"))?,
+        );
+        for partition in &parent.partitions {
+            encode_string(output, &partition.table_name)?;
+            match &partition.bound {
+                PartitionBound::Range { from, to } => {
+                    output.push(0);
+                    encode_optional_string(output, from.as_deref())?;
+                    encode_optional_string(output, to.as_deref())?;
+                }
+                PartitionBound::Hash { modulus, remainder } => {
+                    output.push(1);
+                    encode_u32(output, *modulus);
+                    encode_u32(output, *remainder);
+                }
+            }
+        }
+    }
+    Ok(())
+}
+
 fn decode_schemas_section(
     cursor: &mut Cursor<'_>,
     schemas: &mut BTreeMap<String, SchemaInfo>,
@@ -35245,6 +36110,54 @@ fn decode_enum_columns_section(
     Ok(())
 }
 
+fn decode_column_collations_section(
+    cursor: &mut Cursor<'_>,
+    tables: &mut BTreeMap<String, TableSchema>,
+) -> Result<()> {
+    let section_is_present = cursor
+        .bytes
+        .get(cursor.offset..cursor.offset + COLUMN_COLLATIONS_SECTION_MAGIC.len())
+        .is_some_and(|magic| magic == COLUMN_COLLATIONS_SECTION_MAGIC);
+    if !section_is_present {
+        return Ok(());
+    }
+    cursor.offset += COLUMN_COLLATIONS_SECTION_MAGIC.len();
+    let version = cursor.read_u8()?;
+    if version != 1 {
+        return Err(DbError::corruption(format!(
+            "unknown column collations section version {version}"
+        )));
+    }
+    let entry_count = cursor.read_u32()?;
+    for _ in 0..entry_count {
+        let table_name = cursor.read_string()?;
+        let column_name = cursor.read_string()?;
+        let collation_name = cursor.read_string()?;
+        let collation = ColumnCollation::from_name(&collation_name).ok_or_else(|| {
+            DbError::corruption(format!(
+                "column collation metadata names unknown collation {collation_name}"
+            ))
+        })?;
+        let table = tables.get_mut(&table_name).ok_or_else(|| {
+            DbError::corruption(format!(
+                "column collation metadata referenced unknown table {table_name}"
+            ))
+        })?;
+        let column = table
+            .columns
+            .iter_mut()
+            .find(|column| identifiers_equal(&column.name, &column_name))
+            .ok_or_else(|| {
+                DbError::corruption(format!(
+                    "column collation metadata referenced unknown column {}.{}",
+                    table_name, column_name
+                ))
+            })?;
+        column.collation = Some(collation);
+    }
+    Ok(())
+}
+
 fn decode_pk_index_roots_section(
     cursor: &mut Cursor<'_>,
     tables: &mut BTreeMap<String, TableSchema>,
@@ -35280,6 +36193,179 @@ fn decode_pk_index_roots_section(
     Ok(())
 }
 
+fn decode_column_stats_section(
+    cursor: &mut Cursor<'_>,
+    column_stats: &mut BTreeMap<String, TableColumnStats>,
+) -> Result<()> {
+    let section_is_present = cursor
+        .bytes
+        .get(cursor.offset..cursor.offset + COLUMN_STATS_SECTION_MAGIC.len())
+        .is_some_and(|magic| magic == COLUMN_STATS_SECTION_MAGIC);
+    if !section_is_present {
+        return Ok(());
+    }
+    cursor.offset += COLUMN_STATS_SECTION_MAGIC.len();
+    let version = cursor.read_u8()?;
+    if version != 1 {
+        return Err(DbError::corruption(format!(
+            "unknown column stats section version {version}"
+        )));
+    }
+    let entry_count = cursor.read_u32()?;
+    for _ in 0..entry_count {
+        let table_name = cursor.read_string()?;
+        let analyzed_row_count = cursor.read_i64()?;
+        let column_count = cursor.read_u32()?;
+        let mut columns = Vec::with_capacity(column_count as usize);
+        for _ in 0..column_count {
+            let column_name = cursor.read_string()?;
+            let null_count = cursor.read_i64()?;
+            let distinct_count = cursor.read_i64()?;
+            let histogram_len = cursor.read_u32()? as usize;
+            let histogram = cursor.read_slice(histogram_len)?.to_vec();
+            columns.push(ColumnStats {
+                column_name,
+                null_count,
+                distinct_count,
+                histogram,
+            });
+        }
+        column_stats.insert(
+            table_name,
+            TableColumnStats {
+                analyzed_row_count,
+                columns,
+            },
+        );
+    }
+    Ok(())
+}
+
+fn decode_comments_section(
+    cursor: &mut Cursor<'_>,
+    comments: &mut BTreeMap<String, TableComments>,
+) -> Result<()> {
+    let section_is_present = cursor
+        .bytes
+        .get(cursor.offset..cursor.offset + COMMENTS_SECTION_MAGIC.len())
+        .is_some_and(|magic| magic == COMMENTS_SECTION_MAGIC);
+    if !section_is_present {
+        return Ok(());
+    }
+    cursor.offset += COMMENTS_SECTION_MAGIC.len();
+    let version = cursor.read_u8()?;
+    if version != 1 {
+        return Err(DbError::corruption(format!(
+            "unknown comments section version {version}"
+        )));
+    }
+    let entry_count = cursor.read_u32()?;
+    for _ in 0..entry_count {
+        let table_name = cursor.read_string()?;
+        let table = cursor.read_optional_string()?;
+        let column_count = cursor.read_u32()?;
+        let mut columns = BTreeMap::new();
+        for _ in 0..column_count {
+            let column_name = cursor.read_string()?;
+            columns.insert(column_name, cursor.read_string()?);
+        }
+        comments.insert(table_name, TableComments { table, columns });
+    }
+    Ok(())
+}
+
+fn decode_table_ttl_section(
+    cursor: &mut Cursor<'_>,
+    table_ttl: &mut BTreeMap<String, String>,
+) -> Result<()> {
+    let section_is_present = cursor
+        .bytes
+        .get(cursor.offset..cursor.offset + TABLE_TTL_SECTION_MAGIC.len())
+        .is_some_and(|magic| magic == TABLE_TTL_SECTION_MAGIC);
+    if !section_is_present {
+        return Ok(());
+    }
+    cursor.offset += TABLE_TTL_SECTION_MAGIC.len();
+    let version = cursor.read_u8()?;
+    if version != 1 {
+        return Err(DbError::corruption(format!(
+            "unknown table ttl section version {version}"
+        )));
+    }
+    let entry_count = cursor.read_u32()?;
+    for _ in 0..entry_count {
+        let table_name = cursor.read_string()?;
+        table_ttl.insert(table_name, cursor.read_string()?);
+    }
+    Ok(())
+}
+
+fn decode_partitioned_tables_section(
+    cursor: &mut Cursor<'_>,
+    partitioned_tables: &mut BTreeMap<String, PartitionedTable>,
+) -> Result<()> {
+    let section_is_present = cursor
+        .bytes
+        .get(cursor.offset..cursor.offset + PARTITIONED_TABLES_SECTION_MAGIC.len())
+        .is_some_and(|magic| magic == PARTITIONED_TABLES_SECTION_MAGIC);
+    if !section_is_present {
+        return Ok(());
+    }
+    cursor.offset += PARTITIONED_TABLES_SECTION_MAGIC.len();
+    let version = cursor.read_u8()?;
+    if version != 1 {
+        return Err(DbError::corruption(format!(
+            "unknown partitioned table section version {version}"
+        )));
+    }
+    let parent_count = cursor.read_u32()?;
+    for _ in 0..parent_count {
+        let parent_name = cursor.read_string()?;
+        let strategy = match cursor.read_u8()? {
+            0 => PartitionStrategy::Range,
+            1 => PartitionStrategy::Hash,
+            tag => {
+                return Err(DbError::corruption(format!(
+                    "unknown partition strategy tag {tag}"
+                )))
+            }
+        };
+        let key_column = cursor.read_string()?;
+        let template_sql = cursor.read_string()?;
+        let partition_count = cursor.read_u32()?;
+        let mut partitions = Vec::new();
+        for _ in 0..partition_count {
+            let table_name = cursor.read_string()?;
+            let bound = match cursor.read_u8()? {
+                0 => PartitionBound::Range {
+                    from: cursor.read_optional_string()?,
+                    to: cursor.read_optional_string()?,
+                },
+                1 => PartitionBound::Hash {
+                    modulus: cursor.read_u32()?,
+                    remainder: cursor.read_u32()?,
+                },
+                tag => {
+                    return Err(DbError::corruption(format!(
+                        "unknown partition bound tag {tag}"
+                    )))
+                }
+            };
+            partitions.push(Partition { table_name, bound });
+        }
+        partitioned_tables.insert(
+            parent_name,
+            PartitionedTable {
+                strategy,
+                key_column,
+                template_sql,
+                partitions,
+            },
+        );
+    }
+    Ok(())
+}
+
 fn encode_spatial_subtype_tag(subtype: crate::catalog::SpatialSubtype) -> u8 {
     match subtype {
         crate::catalog::SpatialSubtype::Any => 0,
@@ -35839,6 +36925,151 @@ fn select_requires_grouped_evaluation(runtime: &EngineRuntime, select: &Select)
     )
 }
 
+/// Finds the grouping expression `expr` refers to. A bare column matches a
+/// qualified grouping column of the same name and vice versa.
+fn grouping_expr_position(expr: &Expr, group_by: &[Expr]) -> Option<usize> {
+    if matches!(expr, Expr::Literal(_) | Expr::Parameter(_)) {
+        return None;
+    }
+    group_by.iter().position(|group_expr| {
+        group_expr == expr
+            || matches!(
+                (group_expr, expr),
+                (
+                    Expr::Column { table: group_table, column: group_column },
+                    Expr::Column { table, column },
+                ) if identifiers_equal(group_column, column)
+                    && match (group_table, table) {
+                        (Some(group_table), Some(table)) => identifiers_equal(group_table, table),
+                        _ => true,
+                    }
+            )
+    })
+}
+
+/// Rewrites a grouped output expression for one grouping set: grouping
+/// expressions left out of the set read as NULL, and `GROUPING()` becomes
+/// its bit mask. Aggregate arguments still see the input values.
+fn mask_grouping_set_expr(expr: &Expr, group_by: &[Expr], active: &[bool]) -> Result<Expr> {
+    if let Some(index) = grouping_expr_position(expr, group_by) {
+        return Ok(if active[index] {
+            expr.clone()
+        } else {
+            Expr::Literal(Value::Null)
+        });
+    }
+    let mask = |expr: &Expr| mask_grouping_set_expr(expr, group_by, active);
+    let mask_box = |expr: &Expr| mask(expr).map(Box::new);
+    let mask_all = |exprs: &[Expr]| exprs.iter().map(mask).collect::<Result<Vec<_>>>();
+    Ok(match expr {
+        Expr::Aggregate { name, args, .. } if name == "grouping" => {
+            let mut bits = 0_i64;
+            for arg in args {
+                let index = grouping_expr_position(arg, group_by).ok_or_else(|| {
+                    DbError::sql(format!(
+                        "arguments to GROUPING must be grouping expressions, got {}",
+                        arg.to_sql()
+                    ))
+                })?;
+                bits = (bits << 1) | i64::from(!active[index]);
+            }
+            Expr::Literal(Value::Int64(bits))
+        }
+        Expr::Unary { op, expr } => Expr::Unary {
+            op: *op,
+            expr: mask_box(expr)?,
+        },
+        Expr::Binary { left, op, right } => Expr::Binary {
+            left: mask_box(left)?,
+            op: *op,
+            right: mask_box(right)?,
+        },
+        Expr::Between {
+            expr,
+            low,
+            high,
+            negated,
+        } => Expr::Between {
+            expr: mask_box(expr)?,
+            low: mask_box(low)?,
+            high: mask_box(high)?,
+            negated: *negated,
+        },
+        Expr::InList {
+            expr,
+            items,
+            negated,
+        } => Expr::InList {
+            expr: mask_box(expr)?,
+            items: mask_all(items)?,
+            negated: *negated,
+        },
+        Expr::InSubquery {
+            expr,
+            query,
+            negated,
+        } => Expr::InSubquery {
+            expr: mask_box(expr)?,
+            query: query.clone(),
+            negated: *negated,
+        },
+        Expr::CompareSubquery {
+            expr,
+            op,
+            quantifier,
+            query,
+        } => Expr::CompareSubquery {
+            expr: mask_box(expr)?,
+            op: *op,
+            quantifier: *quantifier,
+            query: query.clone(),
+        },
+        Expr::Like {
+            expr,
+            pattern,
+            escape,
+            case_insensitive,
+            negated,
+        } => Expr::Like {
+            expr: mask_box(expr)?,
+            pattern: mask_box(pattern)?,
+            escape: escape.as_deref().map(mask_box).transpose()?,
+            case_insensitive: *case_insensitive,
+            negated: *negated,
+        },
+        Expr::IsNull { expr, negated } => Expr::IsNull {
+            expr: mask_box(expr)?,
+            negated: *negated,
+        },
+        Expr::Function { name, args } => Expr::Function {
+            name: name.clone(),
+            args: mask_all(args)?,
+        },
+        Expr::Collate { expr, collation } => Expr::Collate {
+            expr: mask_box(expr)?,
+            collation: collation.clone(),
+        },
+        Expr::Case {
+            operand,
+            branches,
+            else_expr,
+        } => Expr::Case {
+            operand: operand.as_deref().map(mask_box).transpose()?,
+            branches: branches
+                .iter()
+                .map(|(condition, result)| Ok((mask(condition)?, mask(result)?)))
+                .collect::<Result<Vec<_>>>()?,
+            else_expr: else_expr.as_deref().map(mask_box).transpose()?,
+        },
+        Expr::Row(items) => Expr::Row(mask_all(items)?),
+        Expr::Cast { expr, target_type } => Expr::Cast {
+            expr: mask_box(expr)?,
+            target_type: *target_type,
+        },
+        _ => expr.clone(),
+    })
+}
+
 fn projection_has_runtime_extension_aggregate_items(
     runtime: &EngineRuntime,
     items: &[SelectItem],
@@ -35868,6 +37099,107 @@ fn simple_btree_lookup(filter: &Expr) -> Option<(Option<&str>, &str, &Expr)> {
     }
 }
 
+/// A probe of a `column COLLATE ...` index found among a filter's `AND`
+/// terms. The filter is still applied to the rows the probe returns.
+struct CollatedBtreeLookup<'a> {
+    table_qualifier: Option<&'a str>,
+    column_name: &'a str,
+    collation: Collation,
+    value_expr: &'a Expr,
+    /// Set for `ILIKE` and NOCASE `LIKE`, whose pattern only probes the
+    /// index when it has no wildcards.
+    pattern: bool,
+}
+
+fn collated_btree_lookup(filter: &Expr) -> Option<CollatedBtreeLookup<'_>> {
+    match filter {
+        Expr::Binary {
+            left,
+            op: BinaryOp::And,
+            right,
+        } => collated_btree_lookup(left).or_else(|| collated_btree_lookup(right)),
+        Expr::Binary {
+            left,
+            op: BinaryOp::Eq,
+            right,
+        } => {
+            let (operand, value_expr) = if simple_btree_lookup_value_expr(right) {
+                (&**left, &**right)
+            } else if simple_btree_lookup_value_expr(left) {
+                (&**right, &**left)
+            } else {
+                return None;
+            };
+            let Expr::Collate { expr, collation } = operand else {
+                return None;
+            };
+            let Expr::Column { table, column } = expr.as_ref() else {
+                return None;
+            };
+            matches!(collation, Collation::NoCase | Collation::RTrim).then(|| CollatedBtreeLookup {
+                table_qualifier: table.as_deref(),
+                column_name: column,
+                collation: collation.clone(),
+                value_expr,
+                pattern: false,
+            })
+        }
+        Expr::Like {
+            expr,
+            pattern,
+            escape: None,
+            case_insensitive,
+            negated: false,
+        } if simple_btree_lookup_value_expr(pattern) => {
+            let (operand, nocase) = match expr.as_ref() {
+                Expr::Collate {
+                    expr,
+                    collation: Collation::NoCase,
+                } => (expr.as_ref(), true),
+                other => (other, *case_insensitive),
+            };
+            let Expr::Column { table, column } = operand else {
+                return None;
+            };
+            nocase.then(|| CollatedBtreeLookup {
+                table_qualifier: table.as_deref(),
+                column_name: column,
+                collation: Collation::NoCase,
+                value_expr: pattern,
+                pattern: true,
+            })
+        }
+        _ => None,
+    }
+}
+
+/// True for an index whose only key is `column_name COLLATE collation`.
+fn index_is_collated_column_key(
+    index: &IndexSchema,
+    column_name: &str,
+    collation: &Collation,
+) -> bool {
+    let [column] = index.columns.as_slice() else {
+        return false;
+    };
+    let Some(Ok(key)) = column
+        .expression_sql
+        .as_deref()
+        .map(crate::sql::parser::parse_expression_sql)
+    else {
+        return false;
+    };
+    matches!(
+        &key,
+        Expr::Collate { expr, collation: key_collation }
+            if key_collation == collation
+                && matches!(
+                    expr.as_ref(),
+                    Expr::Column { table: None, column } if identifiers_equal(column, column_name)
+                )
+    )
+}
+
 fn simple_btree_lookup_value_expr(expr: &Expr) -> bool {
     match expr {
         Expr::Literal(_) | Expr::Parameter(_) => true,
@@ -39205,22 +40537,61 @@ fn project_resolved_simple_join_row_from_full_values(
     Ok(QueryRow::new(projected))
 }
 
-fn covering_projection_offsets(
-    covering: &RuntimeCoveringPayloads,
+/// Where an index-only read takes one projected column from.
+#[derive(Clone, Copy, Debug, Eq, PartialEq)]
+pub(crate) enum CoveredColumn {
+    /// Offset into the index's `INCLUDE` payload row.
+    Payload(usize),
+    /// The table's row-id alias, which every index entry already carries.
+    RowId,
+}
+
+/// Maps each projected column to the index data that holds it, or returns
+/// `None` when one of them can only be read from the table row. The row-id
+/// alias is always covered, so a key-only index answers `SELECT id ... WHERE
+/// key = ?` on its own.
+pub(crate) fn covered_projection_columns(
+    covering: Option<&RuntimeCoveringPayloads>,
     table_schema: &TableSchema,
     projection_indexes: &[usize],
-) -> Option<Vec<usize>> {
+) -> Option<Vec<CoveredColumn>> {
+    let row_id_alias = row_id_alias_column_name(table_schema);
     projection_indexes
         .iter()
         .map(|projection_index| {
-            table_schema
-                .columns
-                .get(*projection_index)
-                .and_then(|column| covering.column_position(&column.name))
+            let column = table_schema.columns.get(*projection_index)?;
+            if row_id_alias.is_some_and(|alias| identifiers_equal(alias, &column.name)) {
+                return Some(CoveredColumn::RowId);
+            }
+            covering?
+                .column_position(&column.name)
+                .map(CoveredColumn::Payload)
         })
         .collect()
 }
 
+/// Builds a result row for `row_id` without reading the table. Returns
+/// `None` when the index has no payload for the row, so the caller falls
+/// back to the table row.
+pub(crate) fn project_covered_row(
+    covering: Option<&RuntimeCoveringPayloads>,
+    row_id: i64,
+    columns: &[CoveredColumn],
+) -> Option<QueryRow> {
+    let payload = match covering {
+        Some(covering) => Some(covering.rows.get(&row_id)?),
+        None => None,
+    };
+    columns
+        .iter()
+        .map(|column| match column {
+            CoveredColumn::RowId => Some(Value::Int64(row_id)),
+            CoveredColumn::Payload(offset) => payload?.get(*offset).cloned(),
+        })
+        .collect::<Option<Vec<_>>>()
+        .map(QueryRow::new)
+}
+
 fn simple_expression_projection_plan<'a>(
     table_schema: &'a TableSchema,
     table_name: &str,
@@ -40811,14 +42182,23 @@ fn membership_value_has_nulls(value: &MembershipValue) -> bool {
     }
 }
 
+/// `collation` is the one written on the tested operand, so
+/// `name COLLATE NOCASE IN ('a', 'b')` matches case variants.
 fn compare_membership_values(
     left: &MembershipValue,
     right: &MembershipValue,
+    collation: Option<&Collation>,
 ) -> Result<Option<bool>> {
     match (left, right) {
         (MembershipValue::Scalar(left), MembershipValue::Scalar(right)) => {
             if matches!(left, Value::Null) || matches!(right, Value::Null) {
                 Ok(None)
+            } else if collation.is_some() {
+                let left = fold_collation_key(left.clone(), collation);
+                let right = fold_collation_key(right.clone(), collation);
+                Ok(Some(
+                    compare_values(&left, &right)? == std::cmp::Ordering::Equal,
+                ))
             } else {
                 Ok(Some(
                     compare_values(left, right)? == std::cmp::Ordering::Equal,
@@ -43661,9 +45041,20 @@ struct SimpleTrigramLookup<'a> {
     table_qualifier: Option<&'a str>,
     column_name: &'a str,
     pattern_expr: &'a Expr,
+    kind: TrigramLookupKind<'a>,
     has_additional_filter: bool,
 }
 
+#[derive(Clone, Copy, Debug)]
+enum TrigramLookupKind<'a> {
+    Like,
+    /// `similarity(column, pattern) > threshold`, or `>=` when inclusive.
+    Similarity {
+        threshold_expr: &'a Expr,
+        inclusive: bool,
+    },
+}
+
 fn simple_trigram_lookup(filter: &Expr) -> Option<SimpleTrigramLookup<'_>> {
     match filter {
         Expr::Like {
@@ -43678,11 +45069,22 @@ fn simple_trigram_lookup(filter: &Expr) -> Option<SimpleTrigramLookup<'_>> {
                     table_qualifier: table.as_deref(),
                     column_name: column.as_str(),
                     pattern_expr: pattern,
+                    kind: TrigramLookupKind::Like,
                     has_additional_filter: false,
                 })
             }
             _ => None,
         },
+        Expr::Binary {
+            left,
+            op: op @ (BinaryOp::Gt | BinaryOp::GtEq),
+            right,
+        } => similarity_trigram_lookup(left, right, *op == BinaryOp::GtEq),
+        Expr::Binary {
+            left,
+            op: op @ (BinaryOp::Lt | BinaryOp::LtEq),
+            right,
+        } => similarity_trigram_lookup(right, left, *op == BinaryOp::LtEq),
         Expr::Binary {
             left,
             op: BinaryOp::And,
@@ -43702,6 +45104,38 @@ fn simple_trigram_lookup(filter: &Expr) -> Option<SimpleTrigramLookup<'_>> {
     }
 }
 
+fn similarity_trigram_lookup<'a>(
+    function: &'a Expr,
+    threshold: &'a Expr,
+    inclusive: bool,
+) -> Option<SimpleTrigramLookup<'a>> {
+    let Expr::Function { name, args } = function else {
+        return None;
+    };
+    if !name.eq_ignore_ascii_case("similarity")
+        || args.len() != 2
+        || !matches!(threshold, Expr::Literal(_) | Expr::Parameter(_))
+    {
+        return None;
+    }
+    match (&args[0], &args[1]) {
+        (Expr::Column { table, column }, pattern @ (Expr::Literal(_) | Expr::Parameter(_)))
+        | (pattern @ (Expr::Literal(_) | Expr::Parameter(_)), Expr::Column { table, column }) => {
+            Some(SimpleTrigramLookup {
+                table_qualifier: table.as_deref(),
+                column_name: column.as_str(),
+                pattern_expr: pattern,
+                kind: TrigramLookupKind::Similarity {
+                    threshold_expr: threshold,
+                    inclusive,
+                },
+                has_additional_filter: false,
+            })
+        }
+        _ => None,
+    }
+}
+
 #[derive(Clone, Copy, Debug)]
 struct SimpleFullTextLookup<'a> {
     index_name_expr: &'a Expr,
@@ -44421,12 +45855,14 @@ fn nested_loop_join(
     };
     let columns = join_output_columns(&left, &right, &using_columns);
     let mut rows = Vec::new();
+    let mut output_memory = memory::reserve(MemoryUse::Intermediate, || 0)?;
     let mut matched_right = vec![false; right.rows.len()];
     let left_nulls = vec![Value::Null; left.columns.len()];
     let right_nulls = vec![Value::Null; right.columns.len()];
     for left_row in left.rows.iter() {
         let mut matched = false;
         for (right_index, right_row) in right.rows.iter().enumerate() {
+            check_interrupt_every(right_index)?;
             let mut eval_row = left_row.clone();
             eval_row.extend(right_row.clone());
             if join_rows_match(
@@ -44439,7 +45875,9 @@ fn nested_loop_join(
             )? {
                 matched = true;
                 matched_right[right_index] = true;
-                rows.push(join_output_row(left_row, right_row, &using_columns)?);
+                let row = join_output_row(left_row, right_row, &using_columns)?;
+                output_memory.grow(|| memory::row_bytes(&row))?;
+                rows.push(row);
             }
         }
         if !matched && matches!(kind, JoinKind::Left | JoinKind::Full) {
@@ -44456,6 +45894,88 @@ fn nested_loop_join(
     Ok(Dataset::with_rows(columns, rows))
 }
 
+/// Brings every column of both set-operation inputs to one value kind so
+/// that duplicate detection compares values rather than encodings and the
+/// result column never mixes kinds.
+fn unify_set_operation_columns(
+    width: usize,
+    left_rows: &mut [Vec<Value>],
+    right_rows: &mut [Vec<Value>],
+) -> Result<()> {
+    for column in 0..width {
+        let mut has_int = false;
+        let mut has_float = false;
+        let mut decimal_scales = BTreeSet::new();
+        let mut has_text = false;
+        let mut has_blob = false;
+        let mut has_date = false;
+        let mut has_timestamp = false;
+        for row in left_rows.iter().chain(right_rows.iter()) {
+            match row.get(column) {
+                Some(Value::Int64(_)) => has_int = true,
+                Some(Value::Float64(_)) => has_float = true,
+                Some(Value::Decimal { scale, .. }) => {
+                    decimal_scales.insert(*scale);
+                }
+                Some(Value::Text(_)) => has_text = true,
+                Some(Value::Blob(_)) => has_blob = true,
+                Some(Value::DateDays(_)) => has_date = true,
+                Some(Value::TimestampMicros(_)) => has_timestamp = true,
+                _ => {}
+            }
+        }
+        if has_text && has_blob {
+            return Err(DbError::sql(format!(
+                "set operation column {} mixes TEXT and BLOB values; CAST one side to match",
+                column + 1
+            )));
+        }
+        let mixed_numeric =
+            usize::from(has_int) + usize::from(has_float) + usize::from(!decimal_scales.is_empty())
+                > 1;
+        if !mixed_numeric && decimal_scales.len() <= 1 && !(has_date && has_timestamp) {
+            continue;
+        }
+        // FLOAT64 absorbs every other numeric kind; otherwise integers and
+        // decimals share the widest decimal scale. DATE widens to TIMESTAMP.
+        let target_scale = decimal_scales.last().copied();
+        for row in left_rows.iter_mut().chain(right_rows.iter_mut()) {
+            let Some(value) = row.get_mut(column) else {
+                continue;
+            };
+            let unified = match (&*value, target_scale) {
+                (Value::Int64(int), _) if has_float => Value::Float64(*int as f64),
+                (Value::Decimal { scaled, scale }, _) if has_float => {
+                    Value::Float64(decimal_to_f64(*scaled, *scale))
+                }
+                (Value::Int64(int), Some(target)) => Value::Decimal {
+                    scaled: rescale_set_operation_decimal(*int, 0, target)?,
+                    scale: target,
+                },
+                (Value::Decimal { scaled, scale }, Some(target)) if *scale != target => {
+                    Value::Decimal {
+                        scaled: rescale_set_operation_decimal(*scaled, *scale, target)?,
+                        scale: target,
+                    }
+                }
+                (Value::DateDays(days), _) if has_timestamp => {
+                    Value::TimestampMicros(date_days_to_micros(*days)?)
+                }
+                _ => continue,
+            };
+            *value = unified;
+        }
+    }
+    Ok(())
+}
+
+fn rescale_set_operation_decimal(scaled: i64, scale: u8, target: u8) -> Result<i64> {
+    10_i64
+        .checked_pow(u32::from(target - scale))
+        .and_then(|factor| scaled.checked_mul(factor))
+        .ok_or_else(|| DbError::sql("DECIMAL value out of range in set operation"))
+}
+
 impl EngineRuntime {
     fn evaluate_set_operation(
         &self,
@@ -44470,8 +45990,9 @@ impl EngineRuntime {
             ));
         }
         let columns = left.columns.clone();
-        let left_rows = left.into_rows();
-        let right_rows = right.into_rows();
+        let mut left_rows = left.into_rows();
+        let mut right_rows = right.into_rows();
+        unify_set_operation_columns(columns.len(), &mut left_rows, &mut right_rows)?;
         let rows = match op {
             crate::sql::ast::SetOperation::Union => {
                 let mut rows = left_rows;
@@ -44573,6 +46094,7 @@ impl EngineRuntime {
         }
         let mut rows = Vec::with_capacity(dataset.rows.len());
         for (row_index, row) in dataset.rows.iter().enumerate() {
+            check_interrupt_every(row_index)?;
             let mut output = Vec::new();
             for (item_index, item) in items.iter().enumerate() {
                 match item {
@@ -45532,6 +47054,7 @@ impl EngineRuntime {
                             dataset,
                             &sorted,
                             order_by,
+                            &order_keys,
                             &peer_starts,
                             &peer_ends,
                             ordinal,
@@ -45589,6 +47112,7 @@ impl EngineRuntime {
                             dataset,
                             &sorted,
                             order_by,
+                            &order_keys,
                             &peer_starts,
                             &peer_ends,
                             ordinal,
@@ -45616,6 +47140,7 @@ impl EngineRuntime {
                             dataset,
                             &sorted,
                             order_by,
+                            &order_keys,
                             &peer_starts,
                             &peer_ends,
                             ordinal,
@@ -45653,6 +47178,7 @@ impl EngineRuntime {
         dataset: &Dataset,
         sorted: &[usize],
         order_by: &[crate::sql::ast::OrderBy],
+        order_keys: &[Vec<Value>],
         peer_starts: &[usize],
         peer_ends: &[usize],
         ordinal: usize,
@@ -45689,6 +47215,8 @@ impl EngineRuntime {
             true,
             ordinal,
             sorted.len(),
+            order_by,
+            order_keys,
             peer_starts,
             peer_ends,
             frame.unit,
@@ -45702,6 +47230,8 @@ impl EngineRuntime {
             false,
             ordinal,
             sorted.len(),
+            order_by,
+            order_keys,
             peer_starts,
             peer_ends,
             frame.unit,
@@ -45720,6 +47250,8 @@ impl EngineRuntime {
         start: bool,
         ordinal: usize,
         partition_len: usize,
+        order_by: &[crate::sql::ast::OrderBy],
+        order_keys: &[Vec<Value>],
         peer_starts: &[usize],
         peer_ends: &[usize],
         unit: crate::sql::ast::WindowFrameUnit,
@@ -45733,11 +47265,34 @@ impl EngineRuntime {
         match (unit, bound) {
             (
                 crate::sql::ast::WindowFrameUnit::Range,
-                crate::sql::ast::WindowFrameBound::Preceding(_)
-                | crate::sql::ast::WindowFrameBound::Following(_),
-            ) => Err(DbError::sql(
-                "RANGE frames with offset bounds are not supported yet",
-            )),
+                crate::sql::ast::WindowFrameBound::Preceding(offset)
+                | crate::sql::ast::WindowFrameBound::Following(offset),
+            ) => {
+                let [order] = order_by else {
+                    return Err(DbError::sql(
+                        "RANGE frames with offset bounds require exactly one ORDER BY expression",
+                    ));
+                };
+                let offset = self.eval_expr(offset, dataset, row, params, ctes, None)?;
+                let preceding = matches!(bound, crate::sql::ast::WindowFrameBound::Preceding(_));
+                let bound = window_range_offset_bound_index(
+                    order_keys,
+                    order.descending,
+                    ordinal as usize,
+                    offset,
+                    preceding,
+                    start,
+                )?;
+                match bound {
+                    Some(bound) => i64::try_from(bound)
+                        .map_err(|_| DbError::internal("window frame bound is too large")),
+                    // A NULL sort key's frame is its peers, the other NULLs.
+                    None if start => i64::try_from(peer_starts[ordinal as usize])
+                        .map_err(|_| DbError::internal("window peer start is too large")),
+                    None => i64::try_from(peer_ends[ordinal as usize])
+                        .map_err(|_| DbError::internal("window peer end is too large")),
+                }
+            }
             (_, crate::sql::ast::WindowFrameBound::UnboundedPreceding) => Ok(0),
             (_, crate::sql::ast::WindowFrameBound::UnboundedFollowing) => {
                 if start {
@@ -46022,20 +47577,43 @@ impl EngineRuntime {
                 "COLLATE in GROUP BY keys is not supported in this compatibility slice",
             ));
         }
-        let mut groups = BTreeMap::<Vec<u8>, Vec<usize>>::new();
-        if dataset.rows.is_empty() && select.group_by.is_empty() {
-            groups.insert(Vec::new(), Vec::new());
+        // A plain GROUP BY is a single set over every grouping expression.
+        // Grouping sets key each row once per set in the same pass.
+        let all_keys;
+        let sets = if select.grouping_sets.is_empty() {
+            all_keys = [(0..select.group_by.len()).collect::<Vec<_>>()];
+            &all_keys[..]
         } else {
-            for (row_index, row) in dataset.rows.iter().enumerate() {
-                let key_values = select
-                    .group_by
-                    .iter()
-                    .map(|expr| self.eval_expr(expr, &dataset, row, params, ctes, None))
-                    .collect::<Result<Vec<_>>>()?;
-                groups
-                    .entry(row_identity(&key_values)?)
-                    .or_default()
-                    .push(row_index);
+            &select.grouping_sets[..]
+        };
+        let mut set_groups = vec![BTreeMap::<Vec<u8>, Vec<usize>>::new(); sets.len()];
+        let mut group_memory = memory::reserve(MemoryUse::Hash, || 0)?;
+        for (row_index, row) in dataset.rows.iter().enumerate() {
+            check_interrupt_every(row_index)?;
+            let key_values = select
+                .group_by
+                .iter()
+                .map(|expr| self.eval_expr(expr, &dataset, row, params, ctes, None))
+                .collect::<Result<Vec<_>>>()?;
+            for (set, groups) in sets.iter().zip(set_groups.iter_mut()) {
+                let key = if set.len() == key_values.len() {
+                    row_identity(&key_values)?
+                } else {
+                    row_identity(
+                        &set.iter()
+                            .map(|index| key_values[*index].clone())
+                            .collect::<Vec<_>>(),
+                    )?
+                };
+                group_memory.grow(|| {
+                    std::mem::size_of::<usize>()
+                        + if groups.contains_key(&key) {
+                            0
+                        } else {
+                            key.len() + std::mem::size_of::<(Vec<u8>, Vec<usize>)>()
+                        }
+                })?;
+                groups.entry(key).or_default().push(row_index);
             }
         }
         let columns = select
@@ -46056,33 +47634,56 @@ impl EngineRuntime {
             })
             .collect::<Vec<_>>();
         let mut rows = Vec::new();
-        for group_row_indexes in groups.into_values() {
-            if let Some(having) = &select.having {
-                if !matches!(
-                    self.eval_group_expr(having, &dataset, &group_row_indexes, params, ctes)?,
-                    Value::Bool(true)
-                ) {
-                    continue;
-                }
+        for (set, mut groups) in sets.iter().zip(set_groups) {
+            // An empty set totals the whole input, even when it has no rows.
+            if set.is_empty() && groups.is_empty() {
+                groups.insert(Vec::new(), Vec::new());
             }
-            let mut output = Vec::new();
-            for item in &select.projection {
-                match item {
-                    SelectItem::Expr { expr, .. } => output.push(self.eval_group_expr(
+            let mut active = vec![false; select.group_by.len()];
+            for index in set {
+                active[*index] = true;
+            }
+            let projection = select
+                .projection
+                .iter()
+                .map(|item| match item {
+                    SelectItem::Expr { expr, .. } => {
+                        mask_grouping_set_expr(expr, &select.group_by, &active).map(Some)
+                    }
+                    SelectItem::Wildcard | SelectItem::QualifiedWildcard(_) => Ok(None),
+                })
+                .collect::<Result<Vec<_>>>()?;
+            let having = select
+                .having
+                .as_ref()
+                .map(|having| mask_grouping_set_expr(having, &select.group_by, &active))
+                .transpose()?;
+            for group_row_indexes in groups.into_values() {
+                if let Some(having) = &having {
+                    if !matches!(
+                        self.eval_group_expr(having, &dataset, &group_row_indexes, params, ctes)?,
+                        Value::Bool(true)
+                    ) {
+                        continue;
+                    }
+                }
+                let mut output = Vec::with_capacity(projection.len());
+                for expr in &projection {
+                    let Some(expr) = expr else {
+                        return Err(DbError::sql(
+                            "wildcards are not supported in grouped SELECT output",
+                        ));
+                    };
+                    output.push(self.eval_group_expr(
                         expr,
                         &dataset,
                         &group_row_indexes,
                         params,
                         ctes,
-                    )?),
-                    SelectItem::Wildcard | SelectItem::QualifiedWildcard(_) => {
-                        return Err(DbError::sql(
-                            "wildcards are not supported in grouped SELECT output",
-                        ))
-                    }
+                    )?);
                 }
+                rows.push(output);
             }
-            rows.push(output);
         }
         Ok(Dataset::with_rows(columns, rows))
     }
@@ -46120,6 +47721,10 @@ impl EngineRuntime {
                     .collect::<Vec<_>>()
             })
             .collect::<Vec<_>>();
+        check_interrupt()?;
+        let _memory = memory::reserve(MemoryUse::Sort, || {
+            memory::rows_bytes(&sort_keys) + dataset.rows.len() * std::mem::size_of::<usize>()
+        })?;
 
         let mut sort_error = None;
         let mut order = (0..dataset.rows.len()).collect::<Vec<_>>();
@@ -46573,6 +48178,7 @@ impl EngineRuntime {
                 items,
                 negated,
             } => {
+                let collation = expr_collation(expr);
                 let value = self.eval_group_membership_value(
                     expr,
                     dataset,
@@ -46592,7 +48198,7 @@ impl EngineRuntime {
                         params,
                         ctes,
                     )?;
-                    match compare_membership_values(&value, &candidate)? {
+                    match compare_membership_values(&value, &candidate, collation.as_ref())? {
                         Some(true) => return Ok(Value::Bool(!*negated)),
                         Some(false) => {}
                         None => saw_null = true,
@@ -46621,7 +48227,13 @@ impl EngineRuntime {
                         self.eval_group_expr(expr, dataset, group_row_indexes, params, ctes)
                     })
                     .transpose()?;
-                eval_like(left, right, escape, *case_insensitive, *negated)
+                eval_like(
+                    left,
+                    right,
+                    escape,
+                    *case_insensitive || matches!(expr_collation(expr), Some(Collation::NoCase)),
+                    *negated,
+                )
             }
             Expr::IsNull { expr, negated } => {
                 let is_null = matches!(
@@ -46856,6 +48468,7 @@ impl EngineRuntime {
                 items,
                 negated,
             } => {
+                let collation = expr_collation(expr);
                 let value =
                     self.eval_membership_value(expr, dataset, row, params, ctes, excluded)?;
                 if membership_value_has_nulls(&value) {
@@ -46865,7 +48478,7 @@ impl EngineRuntime {
                 for item in items {
                     let candidate =
                         self.eval_membership_value(item, dataset, row, params, ctes, excluded)?;
-                    match compare_membership_values(&value, &candidate)? {
+                    match compare_membership_values(&value, &candidate, collation.as_ref())? {
                         Some(true) => return Ok(Value::Bool(!*negated)),
                         Some(false) => {}
                         None => saw_null = true,
@@ -46882,6 +48495,7 @@ impl EngineRuntime {
                 query,
                 negated,
             } => {
+                let collation = expr_collation(expr);
                 let value =
                     self.eval_membership_value(expr, dataset, row, params, ctes, excluded)?;
                 if membership_value_has_nulls(&value) {
@@ -46908,7 +48522,7 @@ impl EngineRuntime {
                     } else {
                         MembershipValue::Row(subquery_row.clone())
                     };
-                    match compare_membership_values(&value, &candidate)? {
+                    match compare_membership_values(&value, &candidate, collation.as_ref())? {
                         Some(true) => return Ok(Value::Bool(!*negated)),
                         Some(false) => {}
                         None => saw_null = true,
@@ -47001,7 +48615,13 @@ impl EngineRuntime {
                     .as_ref()
                     .map(|expr| self.eval_expr(expr, dataset, row, params, ctes, excluded))
                     .transpose()?;
-                eval_like(left, right, escape, *case_insensitive, *negated)
+                eval_like(
+                    left,
+                    right,
+                    escape,
+                    *case_insensitive || matches!(expr_collation(expr), Some(Collation::NoCase)),
+                    *negated,
+                )
             }
             Expr::IsNull { expr, negated } => {
                 let is_null = matches!(
@@ -47202,6 +48822,7 @@ mod exec_mod_private_tests {
                 column_type: crate::catalog::ColumnType::Int64,
                 spatial_type: None,
                 enum_type: None,
+                collation: None,
                 nullable: false,
                 default_sql: None,
                 generated_sql: None,
@@ -47228,6 +48849,7 @@ mod exec_mod_private_tests {
                 column_type: crate::catalog::ColumnType::Int64,
                 spatial_type: None,
                 enum_type: None,
+                collation: None,
                 nullable: false,
                 default_sql: None,
                 generated_sql: Some("1".to_string()),
@@ -47254,6 +48876,7 @@ mod exec_mod_private_tests {
                 column_type: crate::catalog::ColumnType::Int64,
                 spatial_type: None,
                 enum_type: None,
+                collation: None,
                 nullable: false,
                 default_sql: None,
                 generated_sql: Some("1".to_string()),
@@ -0,0 +1,227 @@
+//! Intra-query parallelism.
+//!
+//! A handle's worker budget (`DbConfig::max_parallel_workers`, adjusted at
+//! runtime with `PRAGMA max_parallel_workers`) is installed for the thread
+//! running a read with [`ParallelWorkers::install`]. Operators that can split
+//! their input ask [`parallel_workers`] how many scoped threads they may use
+//! and join them before returning, so results never outlive the statement's
+//! borrow of the runtime.
+
+use std::cell::Cell;
+use std::thread;
+
+use crate::error::Result;
+use crate::record::value::Value;
+use crate::sql::ast::Expr;
+
+thread_local! {
+    static PARALLEL_WORKERS: Cell<usize> = const { Cell::new(1) };
+}
+
+/// Inputs smaller than this many rows per extra worker are filtered on the
+/// calling thread; spawning costs more than it saves.
+pub(crate) const PARALLEL_MIN_ROWS_PER_WORKER: usize = 4096;
+
+/// Restores the previous worker budget of the thread when dropped.
+pub(crate) struct ParallelWorkers(usize);
+
+impl ParallelWorkers {
+    pub(crate) fn install(workers: usize) -> Self {
+        Self(PARALLEL_WORKERS.with(|slot| slot.replace(workers.max(1))))
+    }
+}
+
+impl Drop for ParallelWorkers {
+    fn drop(&mut self) {
+        PARALLEL_WORKERS.with(|slot| slot.set(self.0));
+    }
+}
+
+/// Returns the worker budget installed for the current thread.
+pub(crate) fn parallel_workers() -> usize {
+    PARALLEL_WORKERS.with(Cell::get)
+}
+
+/// Resolves a configured worker count, where `0` means one worker per
+/// available core.
+pub(crate) fn effective_parallel_workers(configured: usize) -> usize {
+    #[cfg(all(target_arch = "wasm32", target_os = "unknown"))]
+    {
+        let _ = configured;
+        1
+    }
+    #[cfg(not(all(target_arch = "wasm32", target_os = "unknown")))]
+    {
+        if configured == 0 {
+            thread::available_parallelism().map_or(1, usize::from)
+        } else {
+            configured
+        }
+    }
+}
+
+/// Returns true when `expr` can be evaluated on a worker thread. Only
+/// self-contained expressions qualify: subqueries, functions, aggregates,
+/// and window functions may consult per-thread state (index-usage tracing,
+/// full-text scores, extension hosts), so filters using them stay serial.
+pub(crate) fn expr_is_parallel_safe(expr: &Expr) -> bool {
+    match expr {
+        Expr::Literal(_) | Expr::Column { .. } | Expr::Parameter(_) => true,
+        Expr::Unary { expr, .. }
+        | Expr::IsNull { expr, .. }
+        | Expr::Collate { expr, .. }
+        | Expr::Cast { expr, .. } => expr_is_parallel_safe(expr),
+        Expr::Binary { left, right, .. } => {
+            expr_is_parallel_safe(left) && expr_is_parallel_safe(right)
+        }
+        Expr::Between {
+            expr, low, high, ..
+        } => {
+            expr_is_parallel_safe(expr) && expr_is_parallel_safe(low) && expr_is_parallel_safe(high)
+        }
+        Expr::InList { expr, items, .. } => {
+            expr_is_parallel_safe(expr) && items.iter().all(expr_is_parallel_safe)
+        }
+        Expr::Like {
+            expr,
+            pattern,
+            escape,
+            ..
+        } => {
+            expr_is_parallel_safe(expr)
+                && expr_is_parallel_safe(pattern)
+                && escape.as_deref().is_none_or(expr_is_parallel_safe)
+        }
+        Expr::Case {
+            operand,
+            branches,
+            else_expr,
+        } => {
+            operand.as_deref().is_none_or(expr_is_parallel_safe)
+                && branches
+                    .iter()
+                    .all(|(when, then)| expr_is_parallel_safe(when) && expr_is_parallel_safe(then))
+                && else_expr.as_deref().is_none_or(expr_is_parallel_safe)
+        }
+        Expr::Row(items) => items.iter().all(expr_is_parallel_safe),
+        Expr::InSubquery { .. }
+        | Expr::CompareSubquery { .. }
+        | Expr::ScalarSubquery(_)
+        | Expr::Exists(_)
+        | Expr::Function { .. }
+        | Expr::Aggregate { .. }
+        | Expr::RowNumber { .. }
+        | Expr::WindowFunction { .. } => false,
+    }
+}
+
+/// Keeps the rows for which `keep` returns true, in their original order.
+/// Large inputs are split into contiguous chunks evaluated by up to
+/// `workers` scoped threads; the first error in row order is returned, as
+/// a serial scan would.
+pub(crate) fn retain_rows<F>(
+    rows: Vec<Vec<Value>>,
+    workers: usize,
+    keep: F,
+) -> Result<Vec<Vec<Value>>>
+where
+    F: Fn(&[Value]) -> Result<bool> + Sync,
+{
+    let workers = workers.min(rows.len() / PARALLEL_MIN_ROWS_PER_WORKER);
+    if workers <= 1 {
+        let mut kept = Vec::with_capacity(rows.len());
+        for row in rows {
+            if keep(&row)? {
+                kept.push(row);
+            }
+        }
+        return Ok(kept);
+    }
+
+    let evaluate =
+        |chunk: &[Vec<Value>]| -> Result<Vec<bool>> { chunk.iter().map(|row| keep(row)).collect() };
+    let chunk_len = rows.len().div_ceil(workers);
+    let masks = thread::scope(|scope| {
+        let evaluate = &evaluate;
+        let pending = rows
+            .chunks(chunk_len)
+            .map(|chunk| {
+                // Evaluate inline when the OS refuses another thread.
+                thread::Builder::new()
+                    .name("decentdb-parallel".to_string())
+                    .spawn_scoped(scope, move || evaluate(chunk))
+                    .map_err(|_| evaluate(chunk))
+            })
+            .collect::<Vec<_>>();
+        pending
+            .into_iter()
+            .map(|handle| match handle {
+                Ok(handle) => handle
+                    .join()
+                    .unwrap_or_else(|panic| std::panic::resume_unwind(panic)),
+                Err(inline) => inline,
+            })
+            .collect::<Vec<_>>()
+    });
+
+    let mut mask = Vec::with_capacity(rows.len());
+    for chunk in masks {
+        mask.extend(chunk?);
+    }
+    Ok(rows
+        .into_iter()
+        .zip(mask)
+        .filter_map(|(row, keep)| keep.then_some(row))
+        .collect())
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::error::DbError;
+
+    fn rows(count: i64) -> Vec<Vec<Value>> {
+        (0..count).map(|i| vec![Value::Int64(i)]).collect()
+    }
+
+    fn even(row: &[Value]) -> Result<bool> {
+        match row[0] {
+            Value::Int64(i) => Ok(i % 2 == 0),
+            _ => Err(DbError::internal("unexpected value")),
+        }
+    }
+
+    #[test]
+    fn parallel_retain_matches_serial_order() {
+        let serial = retain_rows(rows(50_000), 1, even).expect("serial");
+        let parallel = retain_rows(rows(50_000), 4, even).expect("parallel");
+        assert_eq!(serial.len(), 25_000);
+        assert_eq!(serial, parallel);
+    }
+
+    #[test]
+    fn parallel_retain_reports_the_first_error_in_row_order() {
+        let err = retain_rows(rows(50_000), 8, |row| match row[0] {
+            Value::Int64(i) if i == 30_000 || i == 45_000 => {
+                Err(DbError::sql(format!("bad row {i}")))
+            }
+            _ => Ok(true),
+        })
+        .expect_err("the filter fails");
+        assert!(err.to_string().contains("bad row 30000"), "{err}");
+    }
+
+    #[test]
+    fn installed_budget_is_restored() {
+        assert_eq!(parallel_workers(), 1);
+        {
+            let _outer = ParallelWorkers::install(4);
+            {
+                let _inner = ParallelWorkers::install(0);
+                assert_eq!(parallel_workers(), 1);
+            }
+            assert_eq!(parallel_workers(), 4);
+        }
+        assert_eq!(parallel_workers(), 1);
+    }
+}
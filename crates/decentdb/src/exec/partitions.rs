@@ -0,0 +1,1091 @@
+//! Declarative range and hash partitioning.
+//!
+//! A partitioned table is stored as a view that unions its partitions, so it
+//! reads anywhere a view does. The catalog records which rows each partition
+//! accepts: writes against the parent are routed to partitions here, and
+//! scans that filter on the partition key skip partitions that cannot match.
+
+use std::cmp::Ordering;
+use std::collections::BTreeMap;
+
+use crate::catalog::{
+    identifiers_equal, ColumnType, Partition, PartitionBound, PartitionStrategy, PartitionedTable,
+    TableSchema, ViewSchema,
+};
+use crate::error::{DbError, Result};
+use crate::record::row::Row;
+use crate::record::value::Value;
+use crate::sql::ast::{
+    BinaryOp, CreateTableStatement, DeleteStatement, Expr, FromItem, InsertSource, InsertStatement,
+    PartitionBoundSpec, PartitionBySpec, PartitionOfSpec, Query, QueryBody, Select, SelectItem,
+    SetOperation, Statement, TableConstraint, UnaryOp, UpdateStatement,
+};
+use crate::sql::parser::{parse_expression_sql, parse_sql_statement};
+
+use super::expressions::{cast_value, compare_values, sql_identifier_exec};
+use super::{ColumnBinding, Dataset, EngineRuntime, NameResolutionScope, QueryResult};
+
+/// Name of the CHECK constraint that keeps a range partition's rows within
+/// its bounds.
+const PARTITION_BOUND_CONSTRAINT: &str = "partition_bound";
+
+/// A partition with its bound values evaluated and cast to the key type.
+struct ResolvedPartition {
+    table_name: String,
+    bound: ResolvedBound,
+}
+
+enum ResolvedBound {
+    Range {
+        from: Option<Value>,
+        to: Option<Value>,
+    },
+    Hash {
+        modulus: u32,
+        remainder: u32,
+    },
+}
+
+/// What a filter conjunct says about the partition key.
+enum KeyPredicate {
+    OneOf(Vec<Value>),
+    Lower { value: Value, inclusive: bool },
+    Upper { value: Value, inclusive: bool },
+    IsNull,
+}
+
+impl EngineRuntime {
+    /// Returns the catalog name of the partitioned table `name` resolves to.
+    pub(super) fn partitioned_table_name(&self, name: &str) -> Option<String> {
+        let view = self.visible_view(name, NameResolutionScope::Session)?;
+        if view.temporary || !self.catalog.partitioned_tables.contains_key(&view.name) {
+            return None;
+        }
+        Some(view.name.clone())
+    }
+
+    /// Finishes `CREATE TABLE ... PARTITION BY` once the column list has been
+    /// validated like any other table's.
+    pub(super) fn create_partitioned_table(
+        &mut self,
+        table: &TableSchema,
+        spec: &PartitionBySpec,
+        ttl_column: Option<&str>,
+        unique_constraints: &[(Option<String>, Vec<String>)],
+    ) -> Result<()> {
+        if table.temporary {
+            return Err(DbError::sql("partitioned tables cannot be temporary"));
+        }
+        if ttl_column.is_some() {
+            return Err(DbError::sql(
+                "ttl_column is not supported on partitioned tables; drop old partitions instead",
+            ));
+        }
+        let key = table
+            .columns
+            .iter()
+            .find(|column| identifiers_equal(&column.name, &spec.column))
+            .ok_or_else(|| {
+                DbError::sql(format!(
+                    "partition key column {} does not exist on {}",
+                    spec.column, table.name
+                ))
+            })?;
+        if key.generated_sql.is_some() {
+            return Err(DbError::sql(format!(
+                "partition key column {} may not be a generated column",
+                key.name
+            )));
+        }
+        if spec.strategy == PartitionStrategy::Range
+            && !matches!(
+                key.column_type,
+                ColumnType::Int64
+                    | ColumnType::Float64
+                    | ColumnType::Decimal
+                    | ColumnType::Text
+                    | ColumnType::Date
+                    | ColumnType::Timestamp
+                    | ColumnType::TimestampTz
+            )
+        {
+            return Err(DbError::sql(format!(
+                "range partition key column {} may not be of type {}",
+                key.name,
+                key.column_type.as_str()
+            )));
+        }
+        if table
+            .columns
+            .iter()
+            .any(|column| column.column_type == ColumnType::Enum)
+        {
+            return Err(DbError::sql(
+                "ENUM columns are not supported on partitioned tables",
+            ));
+        }
+
+        // Uniqueness is enforced per partition, so it only holds across the
+        // whole table when the key decides the partition.
+        let includes_key = |columns: &[String]| {
+            columns
+                .iter()
+                .any(|column| identifiers_equal(column, &key.name))
+        };
+        if !table.primary_key_columns.is_empty() && !includes_key(&table.primary_key_columns) {
+            return Err(DbError::sql(format!(
+                "PRIMARY KEY of partitioned table {} must include partition key column {}",
+                table.name, key.name
+            )));
+        }
+        let mut template = table.clone();
+        for (_, columns) in unique_constraints {
+            if !includes_key(columns) {
+                return Err(DbError::sql(format!(
+                    "UNIQUE constraint on partitioned table {} must include partition key column {}",
+                    table.name, key.name
+                )));
+            }
+            let [column_name] = columns.as_slice() else {
+                return Err(DbError::sql(
+                    "multi-column UNIQUE constraints are not supported on partitioned tables",
+                ));
+            };
+            if let Some(column) = template
+                .columns
+                .iter_mut()
+                .find(|column| identifiers_equal(&column.name, column_name))
+            {
+                column.unique = true;
+            }
+        }
+
+        let parent_name = table.name.clone();
+        let column_names = table
+            .columns
+            .iter()
+            .map(|column| column.name.clone())
+            .collect();
+        self.catalog_mut().partitioned_tables.insert(
+            parent_name.clone(),
+            PartitionedTable {
+                strategy: spec.strategy,
+                key_column: key.name.clone(),
+                template_sql: crate::db::render_create_table(&template, None),
+                partitions: Vec::new(),
+            },
+        );
+        self.install_partitioned_view(&parent_name, column_names);
+        self.bump_schema_cookie();
+        Ok(())
+    }
+
+    /// `CREATE TABLE name PARTITION OF parent FOR VALUES ...`.
+    pub(super) fn execute_create_partition(
+        &mut self,
+        statement: &CreateTableStatement,
+        spec: &PartitionOfSpec,
+    ) -> Result<()> {
+        if statement.temporary {
+            return Err(DbError::sql("partitions cannot be temporary"));
+        }
+        let parent_name = self
+            .partitioned_table_name(&spec.parent)
+            .ok_or_else(|| DbError::sql(format!("{} is not a partitioned table", spec.parent)))?;
+        let parent = self.partitioned_table(&parent_name)?;
+        if statement.if_not_exists && parent.partition(&statement.table_name).is_some() {
+            return Ok(());
+        }
+        let Statement::CreateTable(mut create) = parse_sql_statement(&parent.template_sql)? else {
+            return Err(DbError::internal(format!(
+                "template of partitioned table {parent_name} is not CREATE TABLE"
+            )));
+        };
+        let key_type = create
+            .columns
+            .iter()
+            .find(|column| identifiers_equal(&column.name, &parent.key_column))
+            .map(|column| column.column_type)
+            .ok_or_else(|| {
+                DbError::internal(format!(
+                    "partition key of {parent_name} is missing from its template"
+                ))
+            })?;
+
+        let bound = match (&spec.bound, parent.strategy) {
+            (PartitionBoundSpec::Range { from, to }, PartitionStrategy::Range) => {
+                PartitionBound::Range {
+                    from: from.as_ref().map(constant_bound_sql).transpose()?,
+                    to: to.as_ref().map(constant_bound_sql).transpose()?,
+                }
+            }
+            (PartitionBoundSpec::Hash { modulus, remainder }, PartitionStrategy::Hash) => {
+                if *modulus == 0 {
+                    return Err(DbError::sql("partition MODULUS must be positive"));
+                }
+                if remainder >= modulus {
+                    return Err(DbError::sql(
+                        "partition REMAINDER must be less than its MODULUS",
+                    ));
+                }
+                PartitionBound::Hash {
+                    modulus: *modulus,
+                    remainder: *remainder,
+                }
+            }
+            (_, strategy) => {
+                return Err(DbError::sql(format!(
+                    "partition bound does not match {} partitioning of {}",
+                    strategy.as_str(),
+                    parent_name
+                )))
+            }
+        };
+        let resolved = self.resolve_partition_bound(&bound, key_type)?;
+        if let ResolvedBound::Range {
+            from: Some(from),
+            to: Some(to),
+        } = &resolved
+        {
+            if compare_values(from, to)? != Ordering::Less {
+                return Err(DbError::sql(format!(
+                    "empty range bound for partition {}",
+                    statement.table_name
+                )));
+            }
+        }
+        for existing in self.resolve_partitions(&parent, key_type)? {
+            if bounds_overlap(&resolved, &existing.bound)? {
+                return Err(DbError::sql(format!(
+                    "partition {} would overlap partition {}",
+                    statement.table_name, existing.table_name
+                )));
+            }
+        }
+
+        create.table_name = statement.table_name.clone();
+        create.if_not_exists = false;
+        if let PartitionBound::Range { from, to } = &bound {
+            let key = sql_identifier_exec(&parent.key_column);
+            let mut terms = vec![format!("{key} IS NOT NULL")];
+            if let Some(from) = from {
+                terms.push(format!("{key} >= CAST({from} AS {})", key_type.as_str()));
+            }
+            if let Some(to) = to {
+                terms.push(format!("{key} < CAST({to} AS {})", key_type.as_str()));
+            }
+            create.constraints.push(TableConstraint::Check {
+                name: Some(PARTITION_BOUND_CONSTRAINT.to_string()),
+                expr: parse_expression_sql(&terms.join(" AND "))?,
+            });
+        }
+        self.execute_create_table(&create)?;
+        let table_name = self
+            .canonical_catalog_table_name(&create.table_name)
+            .ok_or_else(|| DbError::internal("created partition is missing from the catalog"))?;
+        if let Some(parent) = self.catalog_mut().partitioned_tables.get_mut(&parent_name) {
+            parent.partitions.push(Partition { table_name, bound });
+        }
+        self.refresh_partitioned_view(&parent_name);
+        self.bump_schema_cookie();
+        Ok(())
+    }
+
+    /// `ALTER TABLE parent DETACH PARTITION name`: the partition stays as an
+    /// ordinary table without its bound constraint.
+    pub(super) fn execute_detach_partition(
+        &mut self,
+        parent_name: &str,
+        partition_name: &str,
+    ) -> Result<()> {
+        let table_name = self
+            .partitioned_table(parent_name)?
+            .partition(partition_name)
+            .map(|partition| partition.table_name.clone())
+            .ok_or_else(|| {
+                DbError::sql(format!(
+                    "{partition_name} is not a partition of {parent_name}"
+                ))
+            })?;
+        self.remove_partition(&table_name);
+        if let Some(table) = self.catalog_table_mut(&table_name) {
+            table
+                .checks
+                .retain(|check| check.name.as_deref() != Some(PARTITION_BOUND_CONSTRAINT));
+        }
+        self.bump_schema_cookie();
+        Ok(())
+    }
+
+    /// Drops a partitioned table together with all of its partitions.
+    pub(super) fn execute_drop_partitioned_table(
+        &mut self,
+        parent_name: &str,
+        page_size: u32,
+    ) -> Result<()> {
+        let dependents = super::views::dependent_views(self, parent_name, false);
+        if !dependents.is_empty() {
+            return Err(DbError::sql(format!(
+                "cannot drop table {} because views depend on it: {}",
+                parent_name,
+                dependents.join(", ")
+            )));
+        }
+        let parent = self
+            .catalog_mut()
+            .partitioned_tables
+            .remove(parent_name)
+            .ok_or_else(|| DbError::sql(format!("unknown table {parent_name}")))?;
+        self.catalog_mut().views.remove(parent_name);
+        self.catalog_mut()
+            .triggers
+            .retain(|_, trigger| !(trigger.on_view && trigger.target_name == parent_name));
+        for partition in &parent.partitions {
+            self.execute_drop_table(&partition.table_name, true, page_size)?;
+        }
+        self.bump_schema_cookie();
+        Ok(())
+    }
+
+    /// Removes `table_name` from the partitioned table it belongs to, if any.
+    pub(super) fn remove_partition(&mut self, table_name: &str) {
+        let Some(parent_name) = self
+            .catalog
+            .partition_parent(table_name)
+            .map(str::to_string)
+        else {
+            return;
+        };
+        if let Some(parent) = self.catalog_mut().partitioned_tables.get_mut(&parent_name) {
+            parent
+                .partitions
+                .retain(|partition| !identifiers_equal(&partition.table_name, table_name));
+        }
+        self.refresh_partitioned_view(&parent_name);
+    }
+
+    /// Routes `INSERT` into a partitioned table to its partitions. Returns
+    /// `None` when the target is not partitioned.
+    pub(super) fn try_execute_partitioned_insert(
+        &mut self,
+        statement: &InsertStatement,
+        params: &[Value],
+        page_size: u32,
+    ) -> Result<Option<QueryResult>> {
+        let Some(parent_name) = self.partitioned_table_name(&statement.table_name) else {
+            return Ok(None);
+        };
+        let parent = self.partitioned_table(&parent_name)?;
+        let Some(first) = parent
+            .partitions
+            .first()
+            .and_then(|partition| self.catalog.table(&partition.table_name))
+        else {
+            return Err(DbError::constraint(format!(
+                "partitioned table {parent_name} has no partitions"
+            )));
+        };
+        let target_columns = if statement.columns.is_empty() {
+            first
+                .columns
+                .iter()
+                .filter(|column| column.generated_sql.is_none())
+                .map(|column| column.name.clone())
+                .collect::<Vec<_>>()
+        } else {
+            statement.columns.clone()
+        };
+        let key_column = first
+            .columns
+            .iter()
+            .find(|column| identifiers_equal(&column.name, &parent.key_column))
+            .cloned()
+            .ok_or_else(|| {
+                DbError::internal(format!("partition key of {parent_name} is missing"))
+            })?;
+        let key_position = target_columns
+            .iter()
+            .position(|column| identifiers_equal(column, &key_column.name));
+        let default_key = match (&key_position, &key_column.default_sql) {
+            (Some(_), _) => None,
+            (None, Some(default_sql)) => {
+                Some(self.eval_constant(&parse_expression_sql(default_sql)?)?)
+            }
+            (None, None) => Some(Value::Null),
+        };
+        let partitions = self.resolve_partitions(&parent, key_column.column_type)?;
+
+        let source_rows = super::dml::materialize_insert_source(self, &statement.source, params)?;
+        let mut batches: Vec<(usize, Vec<Vec<Expr>>)> = Vec::new();
+        for row in source_rows {
+            let key = match (key_position, &default_key) {
+                (Some(position), _) => row.get(position).cloned().unwrap_or(Value::Null),
+                (None, Some(default)) => default.clone(),
+                (None, None) => Value::Null,
+            };
+            let key = cast_value(key, key_column.column_type)?;
+            let index = route_partition(&partitions, &key)?.ok_or_else(|| {
+                DbError::constraint(format!(
+                    "no partition of {parent_name} accepts {} = {}",
+                    key_column.name,
+                    Expr::Literal(key.clone()).to_sql()
+                ))
+            })?;
+            let row = row.into_iter().map(Expr::Literal).collect();
+            match batches.last_mut() {
+                Some((last, rows)) if *last == index => rows.push(row),
+                _ => batches.push((index, vec![row])),
+            }
+        }
+
+        let mut results = Vec::with_capacity(batches.len());
+        for (index, rows) in batches {
+            let partition_insert = InsertStatement {
+                table_name: partitions[index].table_name.clone(),
+                columns: statement.columns.clone(),
+                source: InsertSource::Values(rows),
+                on_conflict: statement.on_conflict.clone(),
+                returning: statement.returning.clone(),
+            };
+            results.push(self.execute_insert(&partition_insert, params, page_size)?);
+        }
+        Ok(Some(merge_partition_results(
+            results,
+            !statement.returning.is_empty(),
+        )))
+    }
+
+    /// Runs `UPDATE` on a partitioned table against each partition its
+    /// filter may match. Returns `None` when the target is not partitioned.
+    pub(super) fn try_execute_partitioned_update(
+        &mut self,
+        statement: &UpdateStatement,
+        params: &[Value],
+        page_size: u32,
+    ) -> Result<Option<QueryResult>> {
+        let Some(parent_name) = self.partitioned_table_name(&statement.table_name) else {
+            return Ok(None);
+        };
+        let parent = self.partitioned_table(&parent_name)?;
+        if statement
+            .assignments
+            .iter()
+            .any(|assignment| identifiers_equal(&assignment.column_name, &parent.key_column))
+        {
+            return Err(DbError::sql(format!(
+                "UPDATE of partition key column {} is not supported; delete and re-insert the row",
+                parent.key_column
+            )));
+        }
+        let mut results = Vec::new();
+        for table_name in self.pruned_partition_names(
+            &parent,
+            statement.filter.as_ref(),
+            &[statement.table_name.as_str()],
+            params,
+        )? {
+            let mut partition_update = statement.clone();
+            partition_update.table_name = table_name;
+            results.push(self.execute_update(&partition_update, params, page_size)?);
+        }
+        Ok(Some(merge_partition_results(
+            results,
+            !statement.returning.is_empty(),
+        )))
+    }
+
+    /// Runs `DELETE` on a partitioned table against each partition its
+    /// filter may match. Returns `None` when the target is not partitioned.
+    pub(super) fn try_execute_partitioned_delete(
+        &mut self,
+        statement: &DeleteStatement,
+        params: &[Value],
+        page_size: u32,
+    ) -> Result<Option<QueryResult>> {
+        let Some(parent_name) = self.partitioned_table_name(&statement.table_name) else {
+            return Ok(None);
+        };
+        let parent = self.partitioned_table(&parent_name)?;
+        let mut results = Vec::new();
+        for table_name in self.pruned_partition_names(
+            &parent,
+            statement.filter.as_ref(),
+            &[statement.table_name.as_str()],
+            params,
+        )? {
+            let mut partition_delete = statement.clone();
+            partition_delete.table_name = table_name;
+            results.push(self.execute_delete(&partition_delete, params, page_size)?);
+        }
+        Ok(Some(merge_partition_results(
+            results,
+            !statement.returning.is_empty(),
+        )))
+    }
+
+    /// Scans only the partitions a single-table `SELECT` on a partitioned
+    /// table can match. Returns `None` when the filter prunes nothing, so the
+    /// view over all partitions is read as usual.
+    pub(super) fn try_partition_pruned_scan(
+        &self,
+        select: &Select,
+        params: &[Value],
+        ctes: &BTreeMap<String, Dataset>,
+    ) -> Result<Option<Dataset>> {
+        let Some(filter) = select.filter.as_ref() else {
+            return Ok(None);
+        };
+        let [FromItem::Table { name, alias }] = select.from.as_slice() else {
+            return Ok(None);
+        };
+        if ctes.contains_key(name) {
+            return Ok(None);
+        }
+        let Some(parent_name) = self.partitioned_table_name(name) else {
+            return Ok(None);
+        };
+        let parent = self.partitioned_table(&parent_name)?;
+        let binding = alias.clone().unwrap_or_else(|| parent_name.clone());
+        let table_names = self.pruned_partition_names(
+            &parent,
+            Some(filter),
+            &[binding.as_str(), name.as_str()],
+            params,
+        )?;
+        if table_names.len() == parent.partitions.len() {
+            return Ok(None);
+        }
+
+        let mut columns = None;
+        let mut rows = Vec::new();
+        for table_name in table_names {
+            let dataset = self.evaluate_from_item(
+                &FromItem::Table {
+                    name: table_name,
+                    alias: None,
+                },
+                params,
+                ctes,
+            )?;
+            if columns.is_none() {
+                columns = Some(dataset.columns.clone());
+            }
+            rows.extend(dataset.into_rows());
+        }
+        let mut columns = columns.unwrap_or_else(|| {
+            self.catalog
+                .view(&parent_name)
+                .map(|view| {
+                    view.column_names
+                        .iter()
+                        .map(|name| ColumnBinding {
+                            table: None,
+                            source_table: None,
+                            name: name.clone(),
+                            hidden: false,
+                        })
+                        .collect()
+                })
+                .unwrap_or_default()
+        });
+        for column in &mut columns {
+            column.table = Some(binding.clone());
+        }
+        Ok(Some(Dataset::with_rows(columns, rows)))
+    }
+
+    fn partitioned_table(&self, parent_name: &str) -> Result<PartitionedTable> {
+        self.catalog
+            .partitioned_table(parent_name)
+            .cloned()
+            .ok_or_else(|| DbError::sql(format!("{parent_name} is not a partitioned table")))
+    }
+
+    /// Returns the partitions of `parent` that rows matching `filter` may
+    /// live in. `bindings` are the names the filter may qualify the key
+    /// column with.
+    fn pruned_partition_names(
+        &self,
+        parent: &PartitionedTable,
+        filter: Option<&Expr>,
+        bindings: &[&str],
+        params: &[Value],
+    ) -> Result<Vec<String>> {
+        let all = || {
+            parent
+                .partitions
+                .iter()
+                .map(|partition| partition.table_name.clone())
+                .collect::<Vec<_>>()
+        };
+        let Some(filter) = filter else {
+            return Ok(all());
+        };
+        let Some(key_type) = parent
+            .partitions
+            .first()
+            .and_then(|partition| self.catalog.table(&partition.table_name))
+            .and_then(|table| {
+                table
+                    .columns
+                    .iter()
+                    .find(|column| identifiers_equal(&column.name, &parent.key_column))
+            })
+            .map(|column| column.column_type)
+        else {
+            return Ok(all());
+        };
+        let mut predicates = Vec::new();
+        collect_key_predicates(
+            filter,
+            &parent.key_column,
+            bindings,
+            key_type,
+            params,
+            &mut predicates,
+        );
+        if predicates.is_empty() {
+            return Ok(all());
+        }
+        let mut names = Vec::new();
+        for partition in self.resolve_partitions(parent, key_type)? {
+            if predicates
+                .iter()
+                .all(|predicate| partition_may_match(&partition.bound, predicate))
+            {
+                names.push(partition.table_name);
+            }
+        }
+        Ok(names)
+    }
+
+    fn resolve_partitions(
+        &self,
+        parent: &PartitionedTable,
+        key_type: ColumnType,
+    ) -> Result<Vec<ResolvedPartition>> {
+        parent
+            .partitions
+            .iter()
+            .map(|partition| {
+                Ok(ResolvedPartition {
+                    table_name: partition.table_name.clone(),
+                    bound: self.resolve_partition_bound(&partition.bound, key_type)?,
+                })
+            })
+            .collect()
+    }
+
+    fn resolve_partition_bound(
+        &self,
+        bound: &PartitionBound,
+        key_type: ColumnType,
+    ) -> Result<ResolvedBound> {
+        let resolve = |sql: &Option<String>| -> Result<Option<Value>> {
+            let Some(sql) = sql else {
+                return Ok(None);
+            };
+            let value = cast_value(self.eval_constant(&parse_expression_sql(sql)?)?, key_type)?;
+            if matches!(value, Value::Null) {
+                return Err(DbError::sql("partition bounds may not be NULL"));
+            }
+            Ok(Some(value))
+        };
+        Ok(match bound {
+            PartitionBound::Range { from, to } => ResolvedBound::Range {
+                from: resolve(from)?,
+                to: resolve(to)?,
+            },
+            PartitionBound::Hash { modulus, remainder } => ResolvedBound::Hash {
+                modulus: *modulus,
+                remainder: *remainder,
+            },
+        })
+    }
+
+    fn eval_constant(&self, expr: &Expr) -> Result<Value> {
+        self.eval_expr(expr, &Dataset::empty(), &[], &[], &BTreeMap::new(), None)
+    }
+
+    fn refresh_partitioned_view(&mut self, parent_name: &str) {
+        let column_names = self
+            .catalog
+            .view(parent_name)
+            .map(|view| view.column_names.clone())
+            .unwrap_or_default();
+        self.install_partitioned_view(parent_name, column_names);
+    }
+
+    /// Stores the parent of a partitioned table as a `UNION ALL` view over
+    /// its partitions, or an empty relation while it has none.
+    fn install_partitioned_view(&mut self, parent_name: &str, column_names: Vec<String>) {
+        let partitions = self
+            .catalog
+            .partitioned_tables
+            .get(parent_name)
+            .map(|parent| {
+                parent
+                    .partitions
+                    .iter()
+                    .map(|partition| partition.table_name.clone())
+                    .collect::<Vec<_>>()
+            })
+            .unwrap_or_default();
+        let select = |projection, from, filter| {
+            QueryBody::Select(Select {
+                projection,
+                from,
+                filter,
+                group_by: Vec::new(),
+                grouping_sets: Vec::new(),
+                having: None,
+                distinct: false,
+                distinct_on: Vec::new(),
+            })
+        };
+        let body = partitions
+            .iter()
+            .map(|name| {
+                select(
+                    vec![SelectItem::Wildcard],
+                    vec![FromItem::Table {
+                        name: name.clone(),
+                        alias: None,
+                    }],
+                    None,
+                )
+            })
+            .reduce(|left, right| QueryBody::SetOperation {
+                op: SetOperation::Union,
+                all: true,
+                left: Box::new(left),
+                right: Box::new(right),
+            })
+            .unwrap_or_else(|| {
+                select(
+                    column_names
+                        .iter()
+                        .map(|name| SelectItem::Expr {
+                            expr: Expr::Literal(Value::Null),
+                            alias: Some(name.clone()),
+                        })
+                        .collect(),
+                    Vec::new(),
+                    Some(Expr::Literal(Value::Bool(false))),
+                )
+            });
+        let query = Query {
+            recursive: false,
+            ctes: Vec::new(),
+            body,
+            order_by: Vec::new(),
+            limit: None,
+            offset: None,
+        };
+        let view = ViewSchema {
+            name: parent_name.to_string(),
+            temporary: false,
+            sql_text: query.to_sql(),
+            column_names,
+            dependencies: partitions,
+        };
+        self.cache_view_query(&view, query);
+        self.catalog_mut()
+            .views
+            .insert(parent_name.to_string(), view);
+    }
+}
+
+/// Returns the SQL text of a range bound, which must be a constant.
+fn constant_bound_sql(expr: &Expr) -> Result<String> {
+    let constant = match expr {
+        Expr::Literal(_) => true,
+        Expr::Unary {
+            op: UnaryOp::Negate,
+            expr,
+        } => matches!(expr.as_ref(), Expr::Literal(_)),
+        Expr::Cast { expr, .. } => matches!(expr.as_ref(), Expr::Literal(_)),
+        _ => false,
+    };
+    if !constant {
+        return Err(DbError::sql("partition bounds must be constants"));
+    }
+    Ok(expr.to_sql())
+}
+
+fn route_partition(partitions: &[ResolvedPartition], key: &Value) -> Result<Option<usize>> {
+    for (index, partition) in partitions.iter().enumerate() {
+        let accepts = match &partition.bound {
+            ResolvedBound::Range { from, to } => {
+                !matches!(key, Value::Null)
+                    && from.as_ref().map_or(Ok(true), |from| {
+                        compare_values(key, from).map(|ordering| ordering != Ordering::Less)
+                    })?
+                    && to.as_ref().map_or(Ok(true), |to| {
+                        compare_values(key, to).map(|ordering| ordering == Ordering::Less)
+                    })?
+            }
+            ResolvedBound::Hash { modulus, remainder } => {
+                hash_partition_remainder(key, *modulus)? == *remainder
+            }
+        };
+        if accepts {
+            return Ok(Some(index));
+        }
+    }
+    Ok(None)
+}
+
+/// Hash partitions place integer keys by their value modulo the modulus
+/// and other keys by an FNV-1a hash of their record encoding. NULL keys go
+/// to remainder 0.
+fn hash_partition_remainder(key: &Value, modulus: u32) -> Result<u32> {
+    const FNV_OFFSET: u64 = 0xcbf2_9ce4_8422_2325;
+    const FNV_PRIME: u64 = 0x0000_0100_0000_01b3;
+
+    let remainder = match key {
+        Value::Null => 0,
+        Value::Int64(value) => value.rem_euclid(i64::from(modulus)).unsigned_abs(),
+        other => {
+            let mut hash = FNV_OFFSET;
+            for byte in Row::encode_values(std::slice::from_ref(other))? {
+                hash ^= u64::from(byte);
+                hash = hash.wrapping_mul(FNV_PRIME);
+            }
+            hash % u64::from(modulus)
+        }
+    };
+    u32::try_from(remainder).map_err(|_| DbError::internal("hash remainder exceeds modulus"))
+}
+
+fn bounds_overlap(left: &ResolvedBound, right: &ResolvedBound) -> Result<bool> {
+    match (left, right) {
+        (
+            ResolvedBound::Range {
+                from: left_from,
+                to: left_to,
+            },
+            ResolvedBound::Range {
+                from: right_from,
+                to: right_to,
+            },
+        ) => {
+            Ok(range_starts_before(left_from, right_to)?
+                && range_starts_before(right_from, left_to)?)
+        }
+        (
+            ResolvedBound::Hash {
+                modulus: left_modulus,
+                remainder: left_remainder,
+            },
+            ResolvedBound::Hash {
+                modulus: right_modulus,
+                remainder: right_remainder,
+            },
+        ) => {
+            // Some key hashes to both remainders exactly when they agree
+            // modulo the greatest common divisor of the moduli.
+            let divisor = gcd(*left_modulus, *right_modulus);
+            Ok(left_remainder % divisor == right_remainder % divisor)
+        }
+        _ => Ok(true),
+    }
+}
+
+/// Whether a range starting at `from` (unbounded when `None`) begins before
+/// another ending at `to`.
+fn range_starts_before(from: &Option<Value>, to: &Option<Value>) -> Result<bool> {
+    match (from, to) {
+        (Some(from), Some(to)) => Ok(compare_values(from, to)? == Ordering::Less),
+        _ => Ok(true),
+    }
+}
+
+fn gcd(mut left: u32, mut right: u32) -> u32 {
+    while right != 0 {
+        (left, right) = (right, left % right);
+    }
+    left
+}
+
+/// Collects predicates on the partition key from the AND-ed conjuncts of
+/// `filter`. Conjuncts that cannot be read as a comparison with a constant
+/// are skipped, which only makes pruning more conservative.
+fn collect_key_predicates(
+    filter: &Expr,
+    key_column: &str,
+    bindings: &[&str],
+    key_type: ColumnType,
+    params: &[Value],
+    predicates: &mut Vec<KeyPredicate>,
+) {
+    let is_key = |expr: &Expr| match expr {
+        Expr::Column { table, column } => {
+            identifiers_equal(column, key_column)
+                && table.as_deref().map_or(true, |table| {
+                    bindings
+                        .iter()
+                        .any(|binding| identifiers_equal(binding, table))
+                })
+        }
+        _ => false,
+    };
+    let constant = |expr: &Expr| -> Option<Value> {
+        let value = match expr {
+            Expr::Literal(value) => value.clone(),
+            Expr::Parameter(number) => params.get(number.checked_sub(1)?)?.clone(),
+            _ => return None,
+        };
+        match cast_value(value, key_type) {
+            Ok(Value::Null) | Err(_) => None,
+            Ok(value) => Some(value),
+        }
+    };
+
+    match filter {
+        Expr::Binary {
+            left,
+            op: BinaryOp::And,
+            right,
+        } => {
+            collect_key_predicates(left, key_column, bindings, key_type, params, predicates);
+            collect_key_predicates(right, key_column, bindings, key_type, params, predicates);
+        }
+        Expr::Binary { left, op, right } => {
+            let (op, value) = if is_key(left) {
+                (*op, constant(right))
+            } else if is_key(right) {
+                let flipped = match op {
+                    BinaryOp::Lt => BinaryOp::Gt,
+                    BinaryOp::LtEq => BinaryOp::GtEq,
+                    BinaryOp::Gt => BinaryOp::Lt,
+                    BinaryOp::GtEq => BinaryOp::LtEq,
+                    other => *other,
+                };
+                (flipped, constant(left))
+            } else {
+                return;
+            };
+            let Some(value) = value else {
+                return;
+            };
+            predicates.push(match op {
+                BinaryOp::Eq => KeyPredicate::OneOf(vec![value]),
+                BinaryOp::Lt => KeyPredicate::Upper {
+                    value,
+                    inclusive: false,
+                },
+                BinaryOp::LtEq => KeyPredicate::Upper {
+                    value,
+                    inclusive: true,
+                },
+                BinaryOp::Gt => KeyPredicate::Lower {
+                    value,
+                    inclusive: false,
+                },
+                BinaryOp::GtEq => KeyPredicate::Lower {
+                    value,
+                    inclusive: true,
+                },
+                _ => return,
+            });
+        }
+        Expr::Between {
+            expr,
+            low,
+            high,
+            negated: false,
+        } if is_key(expr) => {
+            if let Some(value) = constant(low) {
+                predicates.push(KeyPredicate::Lower {
+                    value,
+                    inclusive: true,
+                });
+            }
+            if let Some(value) = constant(high) {
+                predicates.push(KeyPredicate::Upper {
+                    value,
+                    inclusive: true,
+                });
+            }
+        }
+        Expr::InList {
+            expr,
+            items,
+            negated: false,
+        } if is_key(expr) => {
+            if let Some(values) = items.iter().map(constant).collect::<Option<Vec<_>>>() {
+                predicates.push(KeyPredicate::OneOf(values));
+            }
+        }
+        Expr::IsNull {
+            expr,
+            negated: false,
+        } if is_key(expr) => predicates.push(KeyPredicate::IsNull),
+        _ => {}
+    }
+}
+
+/// Whether a partition may hold rows satisfying `predicate`. Values that
+/// cannot be compared keep the partition.
+fn partition_may_match(bound: &ResolvedBound, predicate: &KeyPredicate) -> bool {
+    let ordering = |left: &Value, right: &Value| compare_values(left, right).ok();
+    match (bound, predicate) {
+        // The bound constraint keeps NULL keys out of range partitions.
+        (ResolvedBound::Range { .. }, KeyPredicate::IsNull) => false,
+        (ResolvedBound::Range { from, to }, KeyPredicate::OneOf(values)) => {
+            values.iter().any(|value| {
+                from.as_ref()
+                    .map_or(true, |from| ordering(value, from) != Some(Ordering::Less))
+                    && to.as_ref().map_or(true, |to| {
+                        !matches!(
+                            ordering(value, to),
+                            Some(Ordering::Equal | Ordering::Greater)
+                        )
+                    })
+            })
+        }
+        (ResolvedBound::Range { to, .. }, KeyPredicate::Lower { value, .. }) => {
+            to.as_ref().map_or(true, |to| {
+                !matches!(ordering(to, value), Some(Ordering::Less | Ordering::Equal))
+            })
+        }
+        (ResolvedBound::Range { from, .. }, KeyPredicate::Upper { value, inclusive }) => from
+            .as_ref()
+            .map_or(true, |from| match ordering(from, value) {
+                Some(Ordering::Less) | None => true,
+                Some(Ordering::Equal) => *inclusive,
+                Some(Ordering::Greater) => false,
+            }),
+        (ResolvedBound::Hash { modulus, remainder }, KeyPredicate::OneOf(values)) => {
+            values.iter().any(|value| {
+                hash_partition_remainder(value, *modulus)
+                    .map_or(true, |hashed| hashed == *remainder)
+            })
+        }
+        (ResolvedBound::Hash { modulus, remainder }, KeyPredicate::IsNull) => {
+            hash_partition_remainder(&Value::Null, *modulus)
+                .map_or(true, |hashed| hashed == *remainder)
+        }
+        (ResolvedBound::Hash { .. }, _) => true,
+    }
+}
+
+fn merge_partition_results(results: Vec<QueryResult>, returning: bool) -> QueryResult {
+    if !returning {
+        return QueryResult::with_affected_rows(
+            results.iter().map(QueryResult::affected_rows).sum(),
+        );
+    }
+    let columns = results
+        .iter()
+        .map(QueryResult::columns)
+        .find(|columns| !columns.is_empty())
+        .map(<[String]>::to_vec)
+        .unwrap_or_default();
+    let rows = results
+        .iter()
+        .flat_map(|result| result.rows().iter().cloned())
+        .collect();
+    QueryResult::with_rows(columns, rows)
+}
@@ -257,6 +257,7 @@ mod tests {
             column_type: ColumnType::Int64,
             spatial_type: None,
             enum_type: None,
+            collation: None,
             nullable: false,
             default_sql: None,
             generated_sql: Some("g()".to_string()),
@@ -320,6 +321,7 @@ mod tests {
             column_type: ColumnType::Int64,
             spatial_type: None,
             enum_type: None,
+            collation: None,
             nullable: false,
             default_sql: None,
             generated_sql: None,
@@ -375,6 +377,7 @@ mod tests {
             column_type: ColumnType::Int64,
             spatial_type: None,
             enum_type: None,
+            collation: None,
             nullable: false,
             default_sql: None,
             generated_sql: None,
@@ -477,6 +480,7 @@ mod tests {
             column_type: ColumnType::Int64,
             spatial_type: None,
             enum_type: None,
+            collation: None,
             nullable: false,
             default_sql: None,
             generated_sql: None,
@@ -529,6 +533,7 @@ mod tests {
             column_type: ColumnType::Int64,
             spatial_type: None,
             enum_type: None,
+            collation: None,
             nullable: false,
             default_sql: None,
             generated_sql: None,
@@ -1007,6 +1012,7 @@ mod tests {
             column_type: ColumnType::Int64,
             spatial_type: None,
             enum_type: None,
+            collation: None,
             nullable: false,
             default_sql: None,
             generated_sql: None,
@@ -1109,6 +1115,7 @@ mod tests {
             column_type: ColumnType::Text,
             spatial_type: None,
             enum_type: None,
+            collation: None,
             nullable: false,
             default_sql: None,
             generated_sql: None,
@@ -1124,6 +1131,7 @@ mod tests {
             column_type: ColumnType::Int64,
             spatial_type: None,
             enum_type: None,
+            collation: None,
             nullable: false,
             default_sql: None,
             generated_sql: None,
@@ -1189,6 +1197,7 @@ mod tests {
             column_type: ColumnType::Text,
             spatial_type: None,
             enum_type: None,
+            collation: None,
             nullable: false,
             default_sql: None,
             generated_sql: None,
@@ -1249,6 +1258,7 @@ mod tests {
             column_type: ColumnType::Int64,
             spatial_type: None,
             enum_type: None,
+            collation: None,
             nullable: false,
             default_sql: None,
             generated_sql: None,
@@ -1264,6 +1274,7 @@ mod tests {
             column_type: ColumnType::Int64,
             spatial_type: None,
             enum_type: None,
+            collation: None,
             nullable: false,
             default_sql: None,
             generated_sql: None,
@@ -1308,6 +1319,7 @@ mod tests {
             column_type: ColumnType::Int64,
             spatial_type: None,
             enum_type: None,
+            collation: None,
             nullable: false,
             default_sql: None,
             generated_sql: None,
@@ -1323,6 +1335,7 @@ mod tests {
             column_type: ColumnType::Int64,
             spatial_type: None,
             enum_type: None,
+            collation: None,
             nullable: false,
             default_sql: None,
             generated_sql: None,
@@ -1338,6 +1351,7 @@ mod tests {
             column_type: ColumnType::Text,
             spatial_type: None,
             enum_type: None,
+            collation: None,
             nullable: false,
             default_sql: None,
             generated_sql: None,
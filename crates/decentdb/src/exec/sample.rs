@@ -0,0 +1,122 @@
+//! `TABLESAMPLE` row selection.
+//!
+//! Both methods draw from a splitmix64 stream, so a `REPEATABLE` seed picks
+//! the same rows for as long as the table holds the same rows in the same
+//! order. `BERNOULLI` draws once per row; `SYSTEM` draws once per block of
+//! [`SYSTEM_SAMPLE_BLOCK_ROWS`] consecutive rows and keeps or skips the whole
+//! block, trading uniformity for fewer draws on large tables.
+
+use crate::error::{DbError, Result};
+use crate::record::value::Value;
+use crate::sql::ast::SampleMethod;
+
+use super::expressions::{next_random_u64, splitmix64};
+
+/// Consecutive rows kept or skipped together by `TABLESAMPLE SYSTEM`.
+pub(crate) const SYSTEM_SAMPLE_BLOCK_ROWS: usize = 64;
+
+/// Validates a `TABLESAMPLE` percentage and returns it as a fraction.
+pub(crate) fn sample_fraction(percent: &Value) -> Result<f64> {
+    let percent = match percent {
+        Value::Null => return Err(DbError::sql("TABLESAMPLE parameter cannot be null")),
+        Value::Int64(value) => *value as f64,
+        Value::Float64(value) => *value,
+        Value::Decimal { scaled, scale } => *scaled as f64 / 10_f64.powi(i32::from(*scale)),
+        _ => return Err(DbError::sql("TABLESAMPLE percentage must be numeric")),
+    };
+    if !(0.0..=100.0).contains(&percent) {
+        return Err(DbError::sql("sample percentage must be between 0 and 100"));
+    }
+    Ok(percent / 100.0)
+}
+
+/// Turns a `REPEATABLE` argument into a stream seed. Without one, each
+/// evaluation draws a fresh seed.
+pub(crate) fn sample_seed(seed: Option<&Value>) -> Result<u64> {
+    match seed {
+        None => Ok(next_random_u64()),
+        Some(Value::Null) => Err(DbError::sql(
+            "TABLESAMPLE REPEATABLE parameter cannot be null",
+        )),
+        Some(Value::Int64(value)) => Ok(*value as u64),
+        Some(Value::Float64(value)) => Ok(value.to_bits()),
+        Some(_) => Err(DbError::sql("TABLESAMPLE REPEATABLE seed must be numeric")),
+    }
+}
+
+/// Returns the sampled rows in their original order.
+pub(crate) fn sample_rows(
+    rows: &[Vec<Value>],
+    method: SampleMethod,
+    fraction: f64,
+    seed: u64,
+) -> Vec<Vec<Value>> {
+    let rows_per_draw = match method {
+        SampleMethod::Bernoulli => 1,
+        SampleMethod::System => SYSTEM_SAMPLE_BLOCK_ROWS,
+    };
+    let mut state = seed;
+    let mut kept = Vec::with_capacity((rows.len() as f64 * fraction) as usize);
+    for chunk in rows.chunks(rows_per_draw) {
+        state = splitmix64(state);
+        let draw = ((state >> 11) as f64) / ((1_u64 << 53) as f64);
+        if draw < fraction {
+            kept.extend_from_slice(chunk);
+        }
+    }
+    kept
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn rows(count: i64) -> Vec<Vec<Value>> {
+        (0..count).map(|i| vec![Value::Int64(i)]).collect()
+    }
+
+    #[test]
+    fn repeatable_seed_picks_the_same_rows_in_order() {
+        let table = rows(10_000);
+        let first = sample_rows(&table, SampleMethod::Bernoulli, 0.1, 42);
+        let second = sample_rows(&table, SampleMethod::Bernoulli, 0.1, 42);
+        assert_eq!(first, second);
+        assert!((800..1200).contains(&first.len()), "{}", first.len());
+        let ids = first
+            .iter()
+            .map(|row| match row[0] {
+                Value::Int64(id) => id,
+                _ => panic!("unexpected value"),
+            })
+            .collect::<Vec<_>>();
+        assert!(ids.windows(2).all(|pair| pair[0] < pair[1]));
+        assert_ne!(first, sample_rows(&table, SampleMethod::Bernoulli, 0.1, 43));
+    }
+
+    #[test]
+    fn system_keeps_whole_blocks() {
+        let table = rows(64_000);
+        let sampled = sample_rows(&table, SampleMethod::System, 0.25, 7);
+        assert_eq!(sampled.len() % SYSTEM_SAMPLE_BLOCK_ROWS, 0);
+        for block in sampled.chunks(SYSTEM_SAMPLE_BLOCK_ROWS) {
+            let Value::Int64(first) = block[0][0] else {
+                panic!("unexpected value");
+            };
+            assert_eq!(first % SYSTEM_SAMPLE_BLOCK_ROWS as i64, 0);
+        }
+    }
+
+    #[test]
+    fn percentage_bounds_are_enforced() {
+        assert_eq!(sample_fraction(&Value::Int64(100)).expect("100%"), 1.0);
+        assert_eq!(sample_fraction(&Value::Float64(0.0)).expect("0%"), 0.0);
+        assert!(sample_fraction(&Value::Float64(100.5)).is_err());
+        assert!(sample_fraction(&Value::Int64(-1)).is_err());
+        assert!(sample_fraction(&Value::Null).is_err());
+        assert!(sample_rows(&rows(100), SampleMethod::Bernoulli, 0.0, 1).is_empty());
+        assert_eq!(
+            sample_rows(&rows(100), SampleMethod::System, 1.0, 1).len(),
+            100
+        );
+    }
+}
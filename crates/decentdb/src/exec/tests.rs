@@ -22,7 +22,7 @@ use super::{
     try_append_only_paged_table_from_manifest, ColumnBinding, Dataset, DbTxnPageStore,
     EngineRuntime, OverflowPointer, PersistedTableState, QueryRow, RuntimeBtreeKeys, RuntimeIndex,
     SimpleOrderByPlan, StoredRow, TableData, TablePageManifest, TablePageManifestChunk,
-    TableRowSource, DEFERRED_VIEW_LIMIT_MIN_PERSISTED_ROWS,
+    TableRowSource, ANALYZE_HISTOGRAM_BUCKETS, DEFERRED_VIEW_LIMIT_MIN_PERSISTED_ROWS,
 };
 
 const PAGE_SIZE: u32 = 4096;
@@ -257,6 +257,59 @@ fn trigram_candidate_lookup_handles_like_wildcards() {
     );
 }
 
+#[test]
+fn trigram_candidate_lookup_handles_similarity_thresholds() {
+    let mut runtime = EngineRuntime::empty(1);
+    execute_sql(
+        &mut runtime,
+        "CREATE TABLE docs (id INT64 PRIMARY KEY, body TEXT)",
+    );
+    execute_sql(&mut runtime, "INSERT INTO docs VALUES (1, 'Motley')");
+    execute_sql(&mut runtime, "INSERT INTO docs VALUES (2, 'Motly')");
+    execute_sql(&mut runtime, "INSERT INTO docs VALUES (3, 'Unrelated')");
+    execute_sql(
+        &mut runtime,
+        "CREATE INDEX docs_body_trgm ON docs USING gin (body)",
+    );
+
+    let statement = parse_sql_statement(
+        "SELECT id FROM docs WHERE similarity(body, 'motley') > 0.5 ORDER BY id",
+    )
+    .expect("parse");
+    let crate::sql::ast::Statement::Query(query) = &statement else {
+        panic!("expected query");
+    };
+    let crate::sql::ast::QueryBody::Select(select) = &query.body else {
+        panic!("expected select");
+    };
+    // Both rows share at least half of the query's trigrams, but only
+    // 'Motley' is more than half similar.
+    let row_ids = runtime
+        .trigram_candidate_row_ids_for_filter(
+            "docs",
+            &None,
+            select.filter.as_ref().expect("filter"),
+            &[],
+            &BTreeMap::new(),
+        )
+        .expect("lookup")
+        .expect("trigram index should produce candidates");
+    assert_eq!(row_ids, vec![1, 2]);
+
+    let result = runtime
+        .try_execute_simple_expression_projection_query(query, &[])
+        .expect("execute")
+        .expect("similarity query should use fast projection path");
+    assert_eq!(
+        result
+            .rows()
+            .iter()
+            .map(|row| row.values()[0].clone())
+            .collect::<Vec<_>>(),
+        vec![Value::Int64(1)]
+    );
+}
+
 #[test]
 fn simple_trigram_lookup_rejects_non_candidate_safe_filters() {
     fn lookup_has_additional_filter(sql: &str) -> Option<bool> {
@@ -282,6 +335,14 @@ fn simple_trigram_lookup_rejects_non_candidate_safe_filters() {
         lookup_has_additional_filter("SELECT * FROM docs WHERE body LIKE '%Motley%' AND id > 0"),
         Some(true)
     );
+    assert_eq!(
+        lookup_has_additional_filter("SELECT * FROM docs WHERE 0.4 <= similarity('motley', body)"),
+        Some(false)
+    );
+    assert!(
+        lookup_has_additional_filter("SELECT * FROM docs WHERE similarity(body, title) > 0.4")
+            .is_none()
+    );
 }
 
 #[test]
@@ -5607,6 +5668,71 @@ fn manifest_round_trip_preserves_analyze_stats() {
     );
 }
 
+#[test]
+fn analyze_collects_column_stats_that_survive_the_manifest() {
+    let mut runtime = EngineRuntime::empty(14);
+    execute_sql(
+        &mut runtime,
+        "CREATE TABLE docs (id INT64 PRIMARY KEY, score INT64, tag BLOB)",
+    );
+    for id in 1..=100 {
+        let score = if id % 10 == 0 {
+            "NULL".to_string()
+        } else {
+            (id % 7).to_string()
+        };
+        execute_sql(
+            &mut runtime,
+            &format!("INSERT INTO docs VALUES ({id}, {score}, X'0{}')", id % 3),
+        );
+    }
+    assert_eq!(runtime.analyze_churn.get("docs"), Some(&100));
+    assert_eq!(
+        runtime.auto_analyze_candidates(50, 10),
+        vec!["docs".to_string()]
+    );
+
+    execute_sql(&mut runtime, "ANALYZE docs");
+    assert!(runtime.analyze_churn.is_empty());
+    assert!(runtime.stats_dirty);
+
+    let stats = runtime
+        .catalog
+        .column_stats
+        .get("docs")
+        .expect("column stats")
+        .clone();
+    assert_eq!(stats.analyzed_row_count, 100);
+    let score = stats.column("SCORE").expect("score stats");
+    assert_eq!((score.null_count, score.distinct_count), (10, 7));
+    let bounds = score.histogram_bounds();
+    assert_eq!(bounds.len(), ANALYZE_HISTOGRAM_BUCKETS);
+    assert_eq!(bounds.first(), Some(&Value::Int64(0)));
+    assert_eq!(bounds.last(), Some(&Value::Int64(6)));
+    let tag = stats.column("tag").expect("tag stats");
+    assert_eq!((tag.null_count, tag.distinct_count), (0, 3));
+    assert!(tag.histogram.is_empty());
+
+    let statistics = runtime.table_statistics("DOCS").expect("table statistics");
+    assert_eq!(statistics.row_count, Some(100));
+    assert_eq!(
+        statistics.columns[1]
+            .histogram_bounds
+            .first()
+            .map(String::as_str),
+        Some("0")
+    );
+
+    let store = InMemoryPageStore::new(PAGE_SIZE);
+    let manifest = encode_manifest_payload(&runtime, &runtime.persisted_tables)
+        .expect("encode manifest payload");
+    let decoded = decode_manifest_payload(&store, &manifest).expect("decode manifest payload");
+    assert_eq!(decoded.catalog.column_stats.get("docs"), Some(&stats));
+
+    execute_sql(&mut runtime, "ALTER TABLE docs ADD COLUMN extra TEXT");
+    assert!(!runtime.catalog.column_stats.contains_key("docs"));
+}
+
 fn execute_sql(runtime: &mut EngineRuntime, sql: &str) {
     let statement = parse_sql_statement(sql).expect("parse SQL");
     runtime
@@ -1,5 +1,7 @@
 //! Trigger execution helpers.
 
+use std::cell::Cell;
+
 use crate::catalog::{TriggerEvent, TriggerKind, TriggerSchema};
 use crate::error::{DbError, Result};
 use crate::sql::ast::{CreateTriggerStatement, Statement, TriggerEventSpec, TriggerKindSpec};
@@ -7,6 +9,40 @@ use crate::sql::parser::parse_sql_statement;
 
 use super::EngineRuntime;
 
+/// Deepest chain of triggers firing further triggers. A trigger that writes
+/// to its own table would otherwise recurse until the stack overflows.
+const TRIGGER_MAX_DEPTH: usize = 32;
+
+thread_local! {
+    static TRIGGER_DEPTH: Cell<usize> = const { Cell::new(0) };
+}
+
+/// Counts one level of trigger nesting on the current thread while alive.
+struct TriggerDepth;
+
+impl TriggerDepth {
+    fn enter() -> Result<Self> {
+        let depth = TRIGGER_DEPTH.with(|slot| {
+            let depth = slot.get() + 1;
+            slot.set(depth);
+            depth
+        });
+        let guard = Self;
+        if depth > TRIGGER_MAX_DEPTH {
+            return Err(DbError::sql(format!(
+                "triggers nested deeper than the {TRIGGER_MAX_DEPTH} level limit"
+            )));
+        }
+        Ok(guard)
+    }
+}
+
+impl Drop for TriggerDepth {
+    fn drop(&mut self) {
+        TRIGGER_DEPTH.with(|slot| slot.set(slot.get().saturating_sub(1)));
+    }
+}
+
 impl EngineRuntime {
     pub(super) fn execute_create_trigger(
         &mut self,
@@ -19,7 +55,6 @@ impl EngineRuntime {
                 "temporary triggers are not supported in this compatibility slice",
             ));
         }
-        let trigger_name = trigger_object.to_string();
         let (target_qualifier, target_object) =
             super::compat_schema_qualified_name(&statement.target_name);
         if target_qualifier == Some(super::CompatSchemaQualifier::Temp) {
@@ -28,6 +63,7 @@ impl EngineRuntime {
             ));
         }
         let target_name = target_object.to_string();
+        let trigger_name = super::schema_owned_object_name(&target_name, trigger_object);
         if self.catalog.contains_object(&trigger_name) {
             return Err(DbError::sql(format!(
                 "object {} already exists",
@@ -92,7 +128,9 @@ impl EngineRuntime {
         table_name: &str,
         if_exists: bool,
     ) -> Result<()> {
-        let trigger_name = super::compat_unqualified_name(name);
+        let trigger_name =
+            super::schema_owned_object_name(table_name, super::compat_unqualified_name(name));
+        let trigger_name = trigger_name.as_str();
         let Some(trigger) = self.catalog.triggers.get(trigger_name).cloned() else {
             if if_exists {
                 return Ok(());
@@ -125,6 +163,7 @@ impl EngineRuntime {
                 target_name, event
             )));
         }
+        let _depth = TriggerDepth::enter()?;
         let mut affected_rows = 0_u64;
         for _ in 0..invocations {
             for trigger in &triggers {
@@ -148,6 +187,10 @@ impl EngineRuntime {
             return Ok(());
         }
         let triggers = matching_triggers(self, target_name, event, false);
+        if triggers.is_empty() {
+            return Ok(());
+        }
+        let _depth = TriggerDepth::enter()?;
         for _ in 0..invocations {
             for trigger in &triggers {
                 let statement = parse_sql_statement(&trigger.action_sql)?;
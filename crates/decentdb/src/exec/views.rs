@@ -84,6 +84,7 @@ impl EngineRuntime {
         let temporary =
             statement.temporary || qualifier == Some(super::CompatSchemaQualifier::Temp);
         let view_name = object_name.to_string();
+        self.ensure_owning_schema_exists(&view_name, temporary)?;
 
         // Handle existence conflicts for temporary and persistent views.
         if temporary {
@@ -97,7 +98,9 @@ impl EngineRuntime {
                 }
             }
         } else if self.catalog.contains_object(&view_name) {
-            if self.catalog.view(&view_name).is_some() {
+            if self.catalog.view(&view_name).is_some()
+                && self.catalog.partitioned_table(&view_name).is_none()
+            {
                 if statement.replace {
                     self.catalog_mut().views.remove(&view_name);
                 } else if statement.if_not_exists {
@@ -213,6 +216,11 @@ impl EngineRuntime {
             }
             return Err(DbError::sql(format!("unknown view {name}")));
         };
+        if self.catalog.partitioned_table(&view_name).is_some() {
+            return Err(DbError::sql(format!(
+                "{view_name} is a partitioned table; use DROP TABLE"
+            )));
+        }
         let dependents = dependent_views(self, &view_name, false);
         if !dependents.is_empty() {
             return Err(DbError::sql(format!(
@@ -239,9 +247,16 @@ impl EngineRuntime {
                 "ALTER VIEW RENAME is not supported for temporary views",
             ));
         }
+        let new_name = super::schema_owned_object_name(view_name, new_name);
+        let new_name = new_name.as_str();
         if self.temp_table_schema(view_name).is_some() && self.catalog.view(view_name).is_none() {
             return Err(DbError::sql(format!("unknown view {view_name}")));
         }
+        if self.partitioned_table_name(view_name).is_some() {
+            return Err(DbError::sql(format!(
+                "{view_name} is a partitioned table, not a view"
+            )));
+        }
         if self.catalog.contains_object(new_name) {
             return Err(DbError::sql(format!("object {} already exists", new_name)));
         }
@@ -318,6 +333,7 @@ fn collect_from_dependencies(item: &FromItem, dependencies: &mut BTreeSet<String
             dependencies.insert(name.clone());
         }
         FromItem::Function { .. } => {}
+        FromItem::Sample { source, .. } => collect_from_dependencies(source, dependencies),
         FromItem::Subquery { query, .. } => {
             collect_body_dependencies(&query.body, dependencies);
         }
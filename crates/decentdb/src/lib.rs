@@ -47,10 +47,12 @@ pub use crate::branch::{
     BranchTableDiffStatus, NamedSnapshot,
 };
 pub use crate::config::{
-    DbConfig, DbEncryptionConfig, EncryptionKey, ProcessCoordinationMode, WalSyncMode,
+    DbConfig, DbEncryptionConfig, EncryptionKey, ProcessCoordinationMode, RecoveryProgress,
+    RecoveryProgressHook, TempStore, TextCollation, WalSyncMode,
 };
 pub use crate::db::{
-    evict_shared_wal, Db, PreparedStatement, PreparedStatementBatch, SqlTransaction,
+    evict_shared_wal, BackupReader, CloneOptions, Db, DumpOptions, PreparedStatement,
+    PreparedStatementBatch, SqlTransaction,
 };
 pub use crate::doctor::{
     render_markdown, run_doctor, sort_findings, DoctorCategory, DoctorCheckSelection,
@@ -75,11 +77,13 @@ pub use crate::extensions::{
     SUPPORTED_EXTENSION_API_VERSION,
 };
 pub use crate::metadata::{
-    CheckConstraintInfo, ColumnInfo, ForeignKeyInfo, HeaderInfo, IndexInfo, IndexVerification,
-    QueryContract, QueryParameterInfo, QueryResultColumnInfo, SchemaColumnInfo, SchemaIndexInfo,
-    SchemaSnapshot, SchemaTableInfo, SchemaTriggerInfo, SchemaViewInfo, StorageInfo, TableInfo,
-    ToolingCapabilities, ToolingColumnTypeMetadata, ToolingMetadata, ToolingSpatialTypeInfo,
-    ToolingTypeInfo, TriggerInfo, ViewInfo,
+    CheckConstraintInfo, CloneReport, ClonedTable, ColumnInfo, ColumnStatistics, ForeignKeyInfo,
+    ForeignKeyViolation, HeaderInfo, IndexInfo, IndexStatistics, IndexVerification, PageCacheStats,
+    QueryContract, QueryParameterInfo, QueryResultColumnInfo, RecoveredTable, RecoveryLoss,
+    RecoveryReport, SchemaColumnInfo, SchemaIndexInfo, SchemaSnapshot, SchemaTableInfo,
+    SchemaTriggerInfo, SchemaViewInfo, StorageInfo, TableChecksum, TableInfo, TableStatistics,
+    TableVerification, ToolingCapabilities, ToolingColumnTypeMetadata, ToolingMetadata,
+    ToolingSpatialTypeInfo, ToolingTypeInfo, TriggerInfo, VerificationReport, ViewInfo,
 };
 pub use crate::plan_cache::{PlanCacheConfig, PlanCacheSummary};
 pub use crate::reactive::{
@@ -89,6 +93,7 @@ pub use crate::reactive::{
     TableWatchOptions, WatchEvent, WatchHandle, WatchKind,
 };
 pub use crate::record::value::Value;
+pub use crate::sql::fingerprint::normalize_query;
 pub use crate::storage::DB_FORMAT_VERSION;
 pub use crate::sync::{
     ApplyChangesetOptions, CreateChangesetOptions, CreateShapeOptions, InspectChangesetOptions,
@@ -105,6 +110,7 @@ pub use crate::sync::{
     SYNC_CONTRACT_VERSION, SYNC_RELAY_PROTOCOL_VERSION, SYNC_SHAPE_STREAM_VERSION,
 };
 pub use crate::tracing::config::SqlTextMode;
+pub use crate::wal::archive::WalSegment;
 #[cfg(all(target_arch = "wasm32", target_os = "unknown"))]
 pub use crate::wasm::WebDb;
 pub use crate::write_queue::{QueuedWriteOptions, WriteQueueMetricsSnapshot};
@@ -4,6 +4,21 @@ use std::path::PathBuf;
 
 use serde::Serialize;
 
+/// Page cache activity for a database and the sessions sharing its cache.
+#[derive(Clone, Debug, Eq, PartialEq, Serialize)]
+pub struct PageCacheStats {
+    /// Pages the cache can hold.
+    pub capacity_pages: usize,
+    /// Pages currently cached.
+    pub resident_pages: usize,
+    /// Reads served from the cache.
+    pub hits: u64,
+    /// Reads that loaded a page from storage.
+    pub misses: u64,
+    /// Open handles sharing this cache, counting the handle asked.
+    pub sharing_handles: usize,
+}
+
 #[derive(Clone, Debug, Eq, PartialEq)]
 pub struct StorageInfo {
     pub path: PathBuf,
@@ -46,6 +61,19 @@ pub struct ForeignKeyInfo {
     pub on_update: String,
 }
 
+/// A row whose foreign key references a missing parent row, as reported by
+/// `Db::check_foreign_keys`.
+#[derive(Clone, Debug, Eq, PartialEq, Serialize)]
+pub struct ForeignKeyViolation {
+    pub table_name: String,
+    pub row_id: i64,
+    pub referenced_table: String,
+    /// Position of the violated constraint in the table's foreign keys,
+    /// matching the `id` column of `PRAGMA foreign_key_list`.
+    pub foreign_key_ordinal: usize,
+    pub foreign_key_name: Option<String>,
+}
+
 #[derive(Clone, Debug, Eq, PartialEq, Serialize)]
 pub struct ColumnInfo {
     pub name: String,
@@ -55,8 +83,18 @@ pub struct ColumnInfo {
     pub primary_key: bool,
     pub unique: bool,
     pub auto_increment: bool,
+    /// Expression of a `GENERATED ALWAYS AS (...)` column.
+    pub generated_sql: Option<String>,
+    /// True when a generated column is `STORED`, false when it is `VIRTUAL`
+    /// or not generated.
+    pub generated_stored: bool,
+    /// Collation declared on the column (`NOCASE` or `RTRIM`); `None` for
+    /// the default binary comparison.
+    pub collation: Option<String>,
     pub checks: Vec<String>,
     pub foreign_key: Option<ForeignKeyInfo>,
+    /// Text set by `COMMENT ON COLUMN`.
+    pub comment: Option<String>,
 }
 
 #[derive(Clone, Debug, Eq, PartialEq, Serialize)]
@@ -68,6 +106,8 @@ pub struct TableInfo {
     pub foreign_keys: Vec<ForeignKeyInfo>,
     pub primary_key_columns: Vec<String>,
     pub row_count: usize,
+    /// Text set by `COMMENT ON TABLE`.
+    pub comment: Option<String>,
 }
 
 #[derive(Clone, Debug, Eq, PartialEq, Serialize)]
@@ -83,6 +123,122 @@ pub struct IndexInfo {
     pub fresh: bool,
 }
 
+/// Planner statistics for one table, as returned by
+/// [`crate::Db::table_statistics`]. `row_count` is `None` until the table
+/// has been analyzed.
+#[derive(Clone, Debug, Eq, PartialEq, Serialize)]
+pub struct TableStatistics {
+    pub table_name: String,
+    pub row_count: Option<i64>,
+    /// Rows inserted, updated, or deleted through this handle since the last
+    /// `ANALYZE`. This is what auto-analyze thresholds are compared against.
+    pub modified_rows_since_analyze: u64,
+    pub columns: Vec<ColumnStatistics>,
+    pub indexes: Vec<IndexStatistics>,
+}
+
+#[derive(Clone, Debug, Eq, PartialEq, Serialize)]
+pub struct ColumnStatistics {
+    pub column_name: String,
+    pub null_count: i64,
+    pub distinct_count: i64,
+    /// Upper bounds of equi-depth histogram buckets, rendered as text.
+    /// Empty for types without an ordering, such as BLOB or UUID.
+    pub histogram_bounds: Vec<String>,
+}
+
+#[derive(Clone, Debug, Eq, PartialEq, Serialize)]
+pub struct IndexStatistics {
+    pub index_name: String,
+    pub entry_count: i64,
+    pub distinct_key_count: i64,
+}
+
+/// Outcome of [`crate::Db::recover_to`].
+#[derive(Clone, Debug, Default, Eq, PartialEq, Serialize)]
+pub struct RecoveryReport {
+    pub tables: Vec<RecoveredTable>,
+    /// Views, indexes, and triggers that could not be recreated.
+    pub lost_objects: Vec<RecoveryLoss>,
+}
+
+impl RecoveryReport {
+    /// Returns true when every table, row, and schema object was salvaged.
+    pub fn is_complete(&self) -> bool {
+        self.lost_objects.is_empty() && self.tables.iter().all(|table| table.error.is_none())
+    }
+}
+
+/// Per-table salvage result. `error` is set when the table's schema or some
+/// of its rows could not be copied; rows before the failure are kept.
+#[derive(Clone, Debug, Eq, PartialEq, Serialize)]
+pub struct RecoveredTable {
+    pub name: String,
+    pub schema_recovered: bool,
+    pub rows_recovered: u64,
+    pub error: Option<String>,
+}
+
+/// A schema object that recovery could not recreate.
+#[derive(Clone, Debug, Eq, PartialEq, Serialize)]
+pub struct RecoveryLoss {
+    pub kind: String,
+    pub name: String,
+    pub error: String,
+}
+
+/// Outcome of [`crate::Db::clone_to`].
+#[derive(Clone, Debug, Default, Eq, PartialEq, Serialize)]
+pub struct CloneReport {
+    pub tables: Vec<ClonedTable>,
+}
+
+/// Rows copied into one cloned table. `rows_skipped` counts rows that
+/// passed the table's filter but referenced a row the clone left out.
+#[derive(Clone, Debug, Eq, PartialEq, Serialize)]
+pub struct ClonedTable {
+    pub name: String,
+    pub rows_copied: u64,
+    pub rows_skipped: u64,
+}
+
+/// Row count and content checksum of one table, from
+/// [`crate::Db::table_checksums`]. The checksum does not depend on row
+/// order, so two tables holding the same rows match however they were
+/// written.
+#[derive(Clone, Debug, Eq, PartialEq, Serialize)]
+pub struct TableChecksum {
+    pub name: String,
+    pub row_count: u64,
+    pub checksum: String,
+}
+
+/// Outcome of [`crate::Db::verify_against`].
+#[derive(Clone, Debug, Default, Eq, PartialEq, Serialize)]
+pub struct VerificationReport {
+    pub tables: Vec<TableVerification>,
+}
+
+impl VerificationReport {
+    /// Returns true when every table exists on both sides with the same rows.
+    pub fn matches(&self) -> bool {
+        self.tables.iter().all(|table| table.matches)
+    }
+}
+
+/// One table compared by [`crate::Db::verify_against`]. The left side is the
+/// database the comparison was run on; a side's fields are `None` when the
+/// table does not exist there.
+#[derive(Clone, Debug, Eq, PartialEq, Serialize)]
+pub struct TableVerification {
+    pub name: String,
+    pub left_row_count: Option<u64>,
+    pub right_row_count: Option<u64>,
+    pub left_checksum: Option<String>,
+    pub right_checksum: Option<String>,
+    pub matches: bool,
+}
+
 #[derive(Clone, Debug, Eq, PartialEq, Serialize)]
 pub struct ViewInfo {
     pub name: String,
@@ -127,6 +283,7 @@ pub struct SchemaColumnInfo {
     pub auto_increment: bool,
     pub generated_sql: Option<String>,
     pub generated_stored: bool,
+    pub collation: Option<String>,
     pub checks: Vec<CheckConstraintInfo>,
     pub foreign_key: Option<ForeignKeyInfo>,
 }
@@ -245,6 +402,21 @@ pub struct QueryContract {
     pub schema_cookie: u32,
     pub temp_schema_cookie: u32,
     pub schema_fingerprint: String,
+    /// Tables and views the statement reads or writes, sorted by name.
+    pub referenced_tables: Vec<String>,
+    /// False when `referenced_tables` could not be proven exhaustive (DDL and
+    /// statements with constructs the analyzer does not follow).
+    pub referenced_tables_complete: bool,
+    /// Persistent base tables whose committed changes can alter a read-only
+    /// statement's result, with views expanded. `None` for writes and when
+    /// the set cannot be proven, for example for temporary tables.
+    pub dependency_tables: Option<Vec<String>>,
+    /// Table written by an INSERT, UPDATE, or DELETE.
+    pub target_table: Option<String>,
+    /// Kind (`table`, `index`, `view`, `trigger`, or `schema`) and name of
+    /// the object a DDL statement creates, alters, comments on, or drops.
+    pub object_kind: Option<String>,
+    pub object_name: Option<String>,
     pub parameters: Vec<QueryParameterInfo>,
     pub result_columns: Vec<QueryResultColumnInfo>,
     pub diagnostics: Vec<String>,
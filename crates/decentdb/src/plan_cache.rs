@@ -149,6 +149,8 @@ impl ParameterShape {
 /// Per ADR 0190, the key is the tuple
 /// `(sql_text, parameter_shape, persistent_schema_cookie, temp_schema_cookie,
 /// policy_mask_generation)`.
+/// `sql_text` is stored in normalized form (see [`normalize_sql_text`]) so
+/// that re-formatted copies of the same statement share an entry.
 #[derive(Clone, Debug, Eq, Hash, PartialEq)]
 pub struct PlanCacheKey {
     pub sql_text: String,
@@ -167,7 +169,7 @@ impl PlanCacheKey {
         policy_mask_generation: u32,
     ) -> Self {
         Self {
-            sql_text,
+            sql_text: normalize_sql_text(sql_text),
             parameter_shape,
             persistent_schema_cookie,
             temp_schema_cookie,
@@ -253,7 +255,8 @@ impl StatementCategory {
             | SqlStatement::AlterIndexRebuild { .. }
             | SqlStatement::AlterIndexVerify { .. }
             | SqlStatement::AlterViewRename { .. }
-            | SqlStatement::TruncateTable { .. } => Self::Other,
+            | SqlStatement::TruncateTable { .. }
+            | SqlStatement::CommentOn { .. } => Self::Other,
         }
     }
 }
@@ -544,10 +547,81 @@ pub fn statement_accounted_size(stmt: &SqlStatement) -> u64 {
         SqlStatement::AlterIndexVerify { .. } => 64,
         SqlStatement::AlterViewRename { .. } => 64,
         SqlStatement::TruncateTable { .. } => 64,
+        SqlStatement::CommentOn { .. } => 64,
     };
     raw.saturating_add(per_stmt)
 }
 
+/// Collapses whitespace and comments outside literals and quoted
+/// identifiers to single spaces, so statements that differ only in ORM or
+/// hand formatting map to one cache key. The result parses to the same
+/// statement as the input.
+///
+/// Text the scanner cannot classify safely (backslash escapes inside a
+/// string, dollar-quoted bodies, an unterminated literal or comment) is
+/// returned unchanged; such statements simply cache under their exact text.
+pub fn normalize_sql_text(sql: String) -> String {
+    let mut out = String::with_capacity(sql.len());
+    let mut pending_space = false;
+    let mut chars = sql.chars().peekable();
+    while let Some(ch) = chars.next() {
+        match ch {
+            c if c.is_whitespace() => pending_space = true,
+            '-' if chars.peek() == Some(&'-') => {
+                for c in chars.by_ref() {
+                    if c == '\n' {
+                        break;
+                    }
+                }
+                pending_space = true;
+            }
+            '/' if chars.peek() == Some(&'*') => {
+                chars.next();
+                let mut depth = 1usize;
+                while depth > 0 {
+                    match chars.next() {
+                        Some('*') if chars.peek() == Some(&'/') => {
+                            chars.next();
+                            depth -= 1;
+                        }
+                        Some('/') if chars.peek() == Some(&'*') => {
+                            chars.next();
+                            depth += 1;
+                        }
+                        Some(_) => {}
+                        None => return sql,
+                    }
+                }
+                pending_space = true;
+            }
+            _ => {
+                if pending_space && !out.is_empty() {
+                    out.push(' ');
+                }
+                pending_space = false;
+                out.push(ch);
+                match ch {
+                    '\'' | '"' => loop {
+                        match chars.next() {
+                            Some('\\') if ch == '\'' => return sql,
+                            Some(c) => {
+                                out.push(c);
+                                if c == ch {
+                                    break;
+                                }
+                            }
+                            None => return sql,
+                        }
+                    },
+                    '$' if !chars.peek().is_some_and(char::is_ascii_digit) => return sql,
+                    _ => {}
+                }
+            }
+        }
+    }
+    out
+}
+
 fn current_time_micros() -> i64 {
     #[cfg(miri)]
     {
@@ -756,6 +830,33 @@ mod tests {
         assert!((s.hit_rate - expected).abs() < 1e-6);
     }
 
+    #[test]
+    fn key_text_ignores_formatting_outside_literals() {
+        let canonical = "SELECT name FROM t WHERE id = $1";
+        for sql in [
+            "SELECT  name\n\tFROM t\nWHERE id = $1",
+            "  SELECT name /* orm: find */ FROM t -- trailing\nWHERE id = $1 ",
+            "SELECT name FROM t WHERE /* outer /* nested */ */ id = $1",
+        ] {
+            assert_eq!(parameterized_key(sql, 0), parameterized_key(canonical, 0));
+        }
+        assert_eq!(
+            normalize_sql_text("SELECT 'a  b', \"odd  --name\" FROM t".to_string()),
+            "SELECT 'a  b', \"odd  --name\" FROM t"
+        );
+        assert_eq!(
+            normalize_sql_text("SELECT 'it''s'  ,  1".to_string()),
+            "SELECT 'it''s' , 1"
+        );
+        for opaque in [
+            "SELECT E'a\\'  b'",
+            "SELECT $$ body  $$",
+            "SELECT 1 /* unterminated",
+        ] {
+            assert_eq!(normalize_sql_text(opaque.to_string()), opaque);
+        }
+    }
+
     #[test]
     fn policy_mask_generation_bumps_monotonically() {
         let p = PolicyMaskGeneration::new(0);
@@ -3,14 +3,15 @@
 pub(crate) mod logical;
 pub(crate) mod physical;
 
-use crate::catalog::{identifiers_equal, CatalogState, IndexKind, TableSchema};
+use crate::catalog::{identifiers_equal, CatalogState, ColumnStats, IndexKind, TableSchema};
 use crate::error::Result;
+use crate::record::key::compare_index_values;
 use crate::record::value::Value;
 use crate::sql::ast::{
-    BinaryOp, Expr, FromItem, JoinConstraint, JoinKind, Query, QueryBody, Select, SelectItem,
-    Statement,
+    BinaryOp, Collation, Expr, FromItem, JoinConstraint, JoinKind, Query, QueryBody, Select,
+    SelectItem, Statement,
 };
-use crate::sql::parser::parse_sql_statement;
+use crate::sql::parser::{parse_expression_sql, parse_sql_statement};
 
 use self::physical::{PhysicalPlan, PlanEstimate};
 
@@ -245,6 +246,7 @@ fn plan_from_item(item: &FromItem, catalog: &CatalogState) -> Result<PhysicalPla
             estimate: PlanEstimate::ZERO,
         },
         FromItem::Subquery { query, .. } => plan_query(query, catalog)?,
+        FromItem::Sample { source, .. } => plan_from_item(source, catalog)?,
         FromItem::Join {
             left,
             right,
@@ -1082,6 +1084,22 @@ fn maybe_index_plan(
             estimate: PlanEstimate::ZERO,
         });
     }
+    if let Some((column_name, collation)) = collated_indexable_filter(filter) {
+        if let Some(index) = catalog.indexes.values().find(|index| {
+            identifiers_equal(&index.table_name, &table.name)
+                && index.kind == IndexKind::Btree
+                && index.predicate_sql.is_none()
+                && index.fresh
+                && index_is_collated_column_key(index, column_name, &collation)
+        }) {
+            return Some(PhysicalPlan::IndexSeek {
+                table: table.name.clone(),
+                index: index.name.clone(),
+                predicate: filter.clone(),
+                estimate: PlanEstimate::ZERO,
+            });
+        }
+    }
     let (column_name, uses_like) = simple_indexable_filter(filter)?;
     if !uses_like {
         if let Some(row_id_alias) = planner_row_id_alias_column_name(table)
@@ -1217,22 +1235,40 @@ fn flattened_and_predicates(expr: &Expr) -> Vec<&Expr> {
     }
 }
 
+/// Whether reads of `table` may be answered from `index` without visiting
+/// the heap: the index must be a fresh, complete B-tree, and every stored
+/// value must be materialized. The prepared-statement fast path applies the
+/// same test before it serves rows from index payloads.
+pub(crate) fn index_can_serve_covered_reads(
+    table: &TableSchema,
+    index: &crate::catalog::IndexSchema,
+) -> bool {
+    index.kind == IndexKind::Btree
+        && index.fresh
+        && index.predicate_sql.is_none()
+        && generated_columns_are_stored_for_planner(table)
+}
+
 fn select_projection_is_covered_by_index(
     select: &Select,
     table: &TableSchema,
     index: &crate::catalog::IndexSchema,
 ) -> bool {
-    if index.kind != IndexKind::Btree
-        || !index.fresh
-        || index.predicate_sql.is_some()
-        || index.include_columns.is_empty()
-        || !generated_columns_are_stored_for_planner(table)
-    {
+    if !index_can_serve_covered_reads(table, index) {
         return false;
     }
-    let Some(covered_columns) = planner_covering_index_columns(index, table) else {
-        return false;
+    // `INCLUDE` payloads hold the key and included columns, and every index
+    // entry carries the row id, so a key-only index still covers the row-id
+    // alias.
+    let mut covered_columns = if index.include_columns.is_empty() {
+        Vec::new()
+    } else {
+        let Some(columns) = planner_covering_index_columns(index, table) else {
+            return false;
+        };
+        columns
     };
+    covered_columns.extend(planner_row_id_alias_column_name(table).map(str::to_string));
     let Some(FromItem::Table { name, alias }) = select.from.first() else {
         return false;
     };
@@ -1651,10 +1687,124 @@ fn simple_indexable_filter(filter: &Expr) -> Option<(&str, bool)> {
                 ) if !*negated && escape.is_none() => Some((column.as_str(), true)),
                 _ => None,
             },
+            Expr::Binary {
+                left,
+                op: BinaryOp::Gt | BinaryOp::GtEq,
+                right,
+            } => simple_similarity_filter(left, right).map(|column| (column, true)),
+            Expr::Binary {
+                left,
+                op: BinaryOp::Lt | BinaryOp::LtEq,
+                right,
+            } => simple_similarity_filter(right, left).map(|column| (column, true)),
             _ => None,
         })
 }
 
+/// Matches an `AND` term the executor answers from a `column COLLATE ...`
+/// index: an equality on the collated column, or an `ILIKE` (or NOCASE
+/// `LIKE`) whose literal pattern has no wildcards.
+fn collated_indexable_filter(filter: &Expr) -> Option<(&str, Collation)> {
+    match filter {
+        Expr::Binary {
+            left,
+            op: BinaryOp::And,
+            right,
+        } => collated_indexable_filter(left).or_else(|| collated_indexable_filter(right)),
+        Expr::Binary {
+            left,
+            op: BinaryOp::Eq,
+            right,
+        } => {
+            let operand = match (&**left, &**right) {
+                (operand, Expr::Literal(_) | Expr::Parameter(_))
+                | (Expr::Literal(_) | Expr::Parameter(_), operand) => operand,
+                _ => return None,
+            };
+            let Expr::Collate { expr, collation } = operand else {
+                return None;
+            };
+            match (expr.as_ref(), collation) {
+                (Expr::Column { column, .. }, Collation::NoCase | Collation::RTrim) => {
+                    Some((column.as_str(), collation.clone()))
+                }
+                _ => None,
+            }
+        }
+        Expr::Like {
+            expr,
+            pattern,
+            escape: None,
+            case_insensitive,
+            negated: false,
+        } => {
+            let Expr::Literal(Value::Text(pattern)) = pattern.as_ref() else {
+                return None;
+            };
+            if pattern.contains(['%', '_']) {
+                return None;
+            }
+            match expr.as_ref() {
+                Expr::Collate {
+                    expr,
+                    collation: Collation::NoCase,
+                } => match expr.as_ref() {
+                    Expr::Column { column, .. } => Some((column.as_str(), Collation::NoCase)),
+                    _ => None,
+                },
+                Expr::Column { column, .. } if *case_insensitive => {
+                    Some((column.as_str(), Collation::NoCase))
+                }
+                _ => None,
+            }
+        }
+        _ => None,
+    }
+}
+
+fn index_is_collated_column_key(
+    index: &crate::catalog::IndexSchema,
+    column_name: &str,
+    collation: &Collation,
+) -> bool {
+    let [column] = index.columns.as_slice() else {
+        return false;
+    };
+    let Some(Ok(key)) = column.expression_sql.as_deref().map(parse_expression_sql) else {
+        return false;
+    };
+    matches!(
+        &key,
+        Expr::Collate { expr, collation: key_collation }
+            if key_collation == collation
+                && matches!(
+                    expr.as_ref(),
+                    Expr::Column { table: None, column } if identifiers_equal(column, column_name)
+                )
+    )
+}
+
+/// Matches `similarity(column, pattern)` compared against a constant
+/// threshold, which a trigram index on the column can narrow.
+fn simple_similarity_filter<'a>(function: &'a Expr, threshold: &Expr) -> Option<&'a str> {
+    let Expr::Function { name, args } = function else {
+        return None;
+    };
+    if !name.eq_ignore_ascii_case("similarity")
+        || args.len() != 2
+        || !matches!(threshold, Expr::Literal(_) | Expr::Parameter(_))
+    {
+        return None;
+    }
+    match (&args[0], &args[1]) {
+        (Expr::Column { column, .. }, Expr::Literal(_) | Expr::Parameter(_))
+        | (Expr::Literal(_) | Expr::Parameter(_), Expr::Column { column, .. }) => {
+            Some(column.as_str())
+        }
+        _ => None,
+    }
+}
+
 fn simple_indexable_equality_filter(filter: &Expr) -> Option<&str> {
     match filter {
         Expr::Binary { left, op, right } => match (&**left, op, &**right) {
@@ -1745,7 +1895,7 @@ fn annotate_plan(plan: PhysicalPlan, catalog: &CatalogState) -> PhysicalPlan {
             ..
         } => {
             let table_rows = estimate_table_rows(catalog, &table);
-            let rows = estimate_filter_rows(table_rows, &predicate, catalog);
+            let rows = estimate_filter_rows(table_rows, &predicate, catalog, &table);
             PhysicalPlan::IndexSeek {
                 table,
                 index,
@@ -1763,7 +1913,7 @@ fn annotate_plan(plan: PhysicalPlan, catalog: &CatalogState) -> PhysicalPlan {
             ..
         } => {
             let table_rows = estimate_table_rows(catalog, &table);
-            let rows = estimate_filter_rows(table_rows, &predicate, catalog);
+            let rows = estimate_filter_rows(table_rows, &predicate, catalog, &table);
             PhysicalPlan::CoveringIndexSeek {
                 table,
                 index,
@@ -1808,8 +1958,12 @@ fn annotate_plan(plan: PhysicalPlan, catalog: &CatalogState) -> PhysicalPlan {
             predicate,
             ..
         } => {
-            let rows =
-                estimate_filter_rows(estimate_table_rows(catalog, &table), &predicate, catalog);
+            let rows = estimate_filter_rows(
+                estimate_table_rows(catalog, &table),
+                &predicate,
+                catalog,
+                &table,
+            );
             PhysicalPlan::TrigramSearch {
                 table,
                 index,
@@ -1823,8 +1977,12 @@ fn annotate_plan(plan: PhysicalPlan, catalog: &CatalogState) -> PhysicalPlan {
             predicate,
             ..
         } => {
-            let rows =
-                estimate_filter_rows(estimate_table_rows(catalog, &table), &predicate, catalog);
+            let rows = estimate_filter_rows(
+                estimate_table_rows(catalog, &table),
+                &predicate,
+                catalog,
+                &table,
+            );
             PhysicalPlan::SpatialFilter {
                 table,
                 index,
@@ -1874,8 +2032,8 @@ fn annotate_plan(plan: PhysicalPlan, catalog: &CatalogState) -> PhysicalPlan {
         } => {
             let input = Box::new(annotate_plan(*input, catalog));
             let input_estimate = input.estimate();
-            let rows = ((input_estimate.rows as f64) * estimate_selectivity(&predicate, catalog))
-                .max(1.0) as u64;
+            let selectivity = estimate_selectivity(&predicate, catalog, scanned_table(&input));
+            let rows = ((input_estimate.rows as f64) * selectivity).max(1.0) as u64;
             PhysicalPlan::Filter {
                 input,
                 predicate,
@@ -2105,37 +2263,76 @@ fn estimate_table_rows(catalog: &CatalogState, table: &str) -> u64 {
     catalog
         .table_stats
         .get(table)
-        .map_or(PLANNER_TABLE_ROWS_HEURISTIC, |stats| {
-            stats.row_count.max(0) as u64
+        .map(|stats| stats.row_count)
+        .or_else(|| {
+            catalog
+                .column_stats
+                .get(table)
+                .map(|stats| stats.analyzed_row_count)
         })
+        .map_or(PLANNER_TABLE_ROWS_HEURISTIC, |rows| rows.max(0) as u64)
 }
 
 fn scan_cost_u64(rows: u64) -> f64 {
     (rows as f64 / PLANNER_ROWS_PER_PAGE).max(1.0)
 }
 
-fn estimate_filter_rows(row_count: u64, filter: &Expr, catalog: &CatalogState) -> u64 {
-    ((row_count as f64) * estimate_selectivity(filter, catalog)).max(1.0) as u64
+fn estimate_filter_rows(row_count: u64, filter: &Expr, catalog: &CatalogState, table: &str) -> u64 {
+    ((row_count as f64) * estimate_selectivity(filter, catalog, Some(table))).max(1.0) as u64
+}
+
+/// The table a scan-like plan reads, used to find column statistics for a
+/// filter above it.
+fn scanned_table(plan: &PhysicalPlan) -> Option<&str> {
+    match plan {
+        PhysicalPlan::TableScan { table, .. }
+        | PhysicalPlan::IndexSeek { table, .. }
+        | PhysicalPlan::CoveringIndexSeek { table, .. }
+        | PhysicalPlan::RowIdLookup { table, .. }
+        | PhysicalPlan::OrderedRowIdScan { table, .. }
+        | PhysicalPlan::TrigramSearch { table, .. }
+        | PhysicalPlan::SpatialFilter { table, .. } => Some(table.as_str()),
+        _ => None,
+    }
 }
 
-fn estimate_selectivity(expr: &Expr, catalog: &CatalogState) -> f64 {
-    let _ = catalog;
+/// `table` is the single table the predicate filters, when known. Its
+/// `ANALYZE` column statistics replace the fixed selectivity guesses for
+/// `column = value`, `column < value`, and `column IS NULL`.
+fn estimate_selectivity(expr: &Expr, catalog: &CatalogState, table: Option<&str>) -> f64 {
     match expr {
         Expr::Binary { left, op, right } => match op {
             BinaryOp::And => {
-                estimate_selectivity(left, catalog) * estimate_selectivity(right, catalog)
+                estimate_selectivity(left, catalog, table)
+                    * estimate_selectivity(right, catalog, table)
             }
             BinaryOp::Or => {
-                let left = estimate_selectivity(left, catalog);
-                let right = estimate_selectivity(right, catalog);
+                let left = estimate_selectivity(left, catalog, table);
+                let right = estimate_selectivity(right, catalog, table);
                 (left + right - (left * right)).min(1.0)
             }
             BinaryOp::Eq => {
+                if let Some(selectivity) =
+                    column_comparison(left, right, *op).and_then(|(column, value, _)| {
+                        let stats = column_stats(catalog, table, column)?;
+                        Some(stats_eq_selectivity(stats, value, catalog, table))
+                    })
+                {
+                    return selectivity;
+                }
                 let index_selectivity = estimate_eq_selectivity_with_expr(left, right);
                 index_selectivity.clamp(PLANNER_EQ_SELECTIVITY_WITHOUT_STATS, 1.0)
             }
             BinaryOp::Lt | BinaryOp::LtEq | BinaryOp::Gt | BinaryOp::GtEq => {
-                PLANNER_RANGE_SELECTIVITY
+                column_comparison(left, right, *op)
+                    .and_then(|(column, value, op)| {
+                        let Expr::Literal(value) = value else {
+                            return None;
+                        };
+                        let stats = column_stats(catalog, table, column)?;
+                        stats_range_selectivity(stats, value, op, catalog, table)
+                    })
+                    .unwrap_or(PLANNER_RANGE_SELECTIVITY)
             }
             BinaryOp::NotEq => 1.0 - PLANNER_EQ_SELECTIVITY_WITHOUT_STATS,
             _ => 1.0,
@@ -2157,10 +2354,21 @@ fn estimate_selectivity(expr: &Expr, catalog: &CatalogState) -> f64 {
         Expr::CompareSubquery { .. } => PLANNER_RANGE_SELECTIVITY,
         Expr::Function { .. } => PLANNER_LIKE_SELECTIVITY,
         Expr::ScalarSubquery(_) | Expr::Exists(_) => 1.0,
-        Expr::Collate { expr, .. } => estimate_selectivity(expr, catalog),
-        Expr::Cast { expr, .. } => estimate_selectivity(expr, catalog),
-        Expr::Unary { expr, .. } => estimate_selectivity(expr, catalog),
-        Expr::IsNull { expr, .. } => estimate_selectivity(expr, catalog),
+        Expr::Collate { expr, .. } => estimate_selectivity(expr, catalog, table),
+        Expr::Cast { expr, .. } => estimate_selectivity(expr, catalog, table),
+        Expr::Unary { expr, .. } => estimate_selectivity(expr, catalog, table),
+        Expr::IsNull { expr, negated } => {
+            let fraction = match expr.as_ref() {
+                Expr::Column { column, .. } => column_stats(catalog, table, column)
+                    .map(|stats| null_fraction(stats, catalog, table)),
+                _ => None,
+            };
+            match fraction {
+                Some(fraction) if *negated => 1.0 - fraction,
+                Some(fraction) => fraction,
+                None => estimate_selectivity(expr, catalog, table),
+            }
+        }
         Expr::InList { .. } => PLANNER_EQ_SELECTIVITY_WITHOUT_STATS,
         Expr::Case { .. } => PLANNER_RANGE_SELECTIVITY,
         Expr::Row(_) => PLANNER_RANGE_SELECTIVITY,
@@ -2171,6 +2379,103 @@ fn estimate_selectivity(expr: &Expr, catalog: &CatalogState) -> f64 {
     }
 }
 
+/// Splits `column op value` (in either order) into the column, the value, and
+/// the operator as seen from the column's side.
+fn column_comparison<'a>(
+    left: &'a Expr,
+    right: &'a Expr,
+    op: BinaryOp,
+) -> Option<(&'a str, &'a Expr, BinaryOp)> {
+    match (left, right) {
+        (Expr::Column { column, .. }, value @ (Expr::Literal(_) | Expr::Parameter(_))) => {
+            Some((column.as_str(), value, op))
+        }
+        (value @ (Expr::Literal(_) | Expr::Parameter(_)), Expr::Column { column, .. }) => {
+            let op = match op {
+                BinaryOp::Lt => BinaryOp::Gt,
+                BinaryOp::LtEq => BinaryOp::GtEq,
+                BinaryOp::Gt => BinaryOp::Lt,
+                BinaryOp::GtEq => BinaryOp::LtEq,
+                other => other,
+            };
+            Some((column.as_str(), value, op))
+        }
+        _ => None,
+    }
+}
+
+fn column_stats<'a>(
+    catalog: &'a CatalogState,
+    table: Option<&str>,
+    column: &str,
+) -> Option<&'a ColumnStats> {
+    let table = catalog.table(table?)?;
+    catalog.column_stats.get(&table.name)?.column(column)
+}
+
+fn analyzed_rows(catalog: &CatalogState, table: Option<&str>) -> f64 {
+    table
+        .and_then(|table| catalog.table(table))
+        .and_then(|table| catalog.column_stats.get(&table.name))
+        .map_or(0.0, |stats| stats.analyzed_row_count.max(0) as f64)
+}
+
+fn null_fraction(stats: &ColumnStats, catalog: &CatalogState, table: Option<&str>) -> f64 {
+    let rows = analyzed_rows(catalog, table);
+    if rows == 0.0 {
+        return 0.0;
+    }
+    (stats.null_count.max(0) as f64 / rows).min(1.0)
+}
+
+fn stats_eq_selectivity(
+    stats: &ColumnStats,
+    value: &Expr,
+    catalog: &CatalogState,
+    table: Option<&str>,
+) -> f64 {
+    if matches!(value, Expr::Literal(Value::Null)) || stats.distinct_count <= 0 {
+        return 0.0;
+    }
+    (1.0 - null_fraction(stats, catalog, table)) / stats.distinct_count as f64
+}
+
+/// Estimates `column op value` from the equi-depth histogram: each bucket
+/// holds the same share of the non-NULL rows.
+fn stats_range_selectivity(
+    stats: &ColumnStats,
+    value: &Value,
+    op: BinaryOp,
+    catalog: &CatalogState,
+    table: Option<&str>,
+) -> Option<f64> {
+    let bounds = stats.histogram_bounds();
+    if bounds.is_empty() || matches!(value, Value::Null) {
+        return None;
+    }
+    let mut below = 0_usize;
+    let mut at_or_below = 0_usize;
+    for bound in &bounds {
+        match compare_index_values(bound, value).ok()? {
+            std::cmp::Ordering::Less => {
+                below += 1;
+                at_or_below += 1;
+            }
+            std::cmp::Ordering::Equal => at_or_below += 1,
+            std::cmp::Ordering::Greater => {}
+        }
+    }
+    let buckets = bounds.len() as f64;
+    let fraction = match op {
+        BinaryOp::Lt => below as f64 / buckets,
+        BinaryOp::LtEq => at_or_below as f64 / buckets,
+        BinaryOp::Gt => 1.0 - at_or_below as f64 / buckets,
+        BinaryOp::GtEq => 1.0 - below as f64 / buckets,
+        _ => return None,
+    };
+    Some(fraction * (1.0 - null_fraction(stats, catalog, table)))
+}
+
 fn estimate_eq_selectivity_with_expr(left: &Expr, right: &Expr) -> f64 {
     let has_column = matches!(
         (left, right),
@@ -2310,6 +2615,7 @@ mod tests {
             column_type: ColumnType::Int64,
             spatial_type: None,
             enum_type: None,
+            collation: None,
             nullable: false,
             default_sql: None,
             generated_sql: None,
@@ -2448,6 +2754,7 @@ mod tests {
             }],
             filter: Some(filter),
             group_by: vec![],
+            grouping_sets: vec![],
             having: None,
         }
     }
@@ -2463,6 +2770,7 @@ mod tests {
             }],
             filter: Some(filter),
             group_by: vec![],
+            grouping_sets: vec![],
             having: None,
         }
     }
@@ -2777,6 +3085,7 @@ mod tests {
                     from: vec![],
                     filter: None,
                     group_by: vec![],
+                    grouping_sets: vec![],
                     having: None,
                 }),
                 order_by: vec![],
@@ -2804,6 +3113,7 @@ mod tests {
                     from: vec![],
                     filter: None,
                     group_by: vec![],
+                    grouping_sets: vec![],
                     having: None,
                 }),
                 order_by: vec![],
@@ -2826,6 +3136,7 @@ mod tests {
                 from: vec![],
                 filter: None,
                 group_by: vec![],
+                grouping_sets: vec![],
                 having: None,
             }),
             order_by: vec![],
@@ -2988,6 +3299,27 @@ mod tests {
         assert_eq!(result, Some(("id", false)));
     }
 
+    #[test]
+    fn indexable_similarity_threshold() {
+        let similarity = Expr::Function {
+            name: "similarity".to_string(),
+            args: vec![col("name"), lit_text("helo")],
+        };
+        let filter = Expr::Binary {
+            left: Box::new(similarity.clone()),
+            op: BinaryOp::Gt,
+            right: Box::new(Expr::Parameter(1)),
+        };
+        assert_eq!(simple_indexable_filter(&filter), Some(("name", true)));
+
+        let filter = Expr::Binary {
+            left: Box::new(similarity),
+            op: BinaryOp::Lt,
+            right: Box::new(Expr::Parameter(1)),
+        };
+        assert!(simple_indexable_filter(&filter).is_none());
+    }
+
     #[test]
     fn indexable_like_column_text() {
         let filter = Expr::Like {
@@ -3116,6 +3448,7 @@ mod tests {
             from: vec![],
             filter: None,
             group_by: vec![],
+            grouping_sets: vec![],
             having: None,
         };
         assert!(projection_has_aggregate(&select));
@@ -3136,6 +3469,7 @@ mod tests {
             from: vec![],
             filter: None,
             group_by: vec![],
+            grouping_sets: vec![],
             having: None,
         };
         assert!(!projection_has_aggregate(&select));
@@ -3304,4 +3638,54 @@ mod tests {
         // estimated_matches = 50, 50*4 = 200 >= 100 => false
         assert!(!should_use_btree_index("t", "idx", &catalog));
     }
+
+    #[test]
+    fn selectivity_uses_analyzed_column_stats() {
+        let mut catalog = catalog_with_artist_table();
+        let bounds = (1..=4)
+            .map(|bound| Value::Int64(bound * 25))
+            .collect::<Vec<_>>();
+        catalog.column_stats.insert(
+            "Artist".to_string(),
+            crate::catalog::TableColumnStats {
+                analyzed_row_count: 100,
+                columns: vec![crate::catalog::ColumnStats {
+                    column_name: "NameNormalized".to_string(),
+                    null_count: 20,
+                    distinct_count: 8,
+                    histogram: crate::record::row::Row::encode_values(&bounds)
+                        .expect("encode bounds"),
+                }],
+            },
+        );
+        let column = || {
+            Box::new(Expr::Column {
+                table: None,
+                column: "namenormalized".to_string(),
+            })
+        };
+        let compare = |op, value| Expr::Binary {
+            left: column(),
+            op,
+            right: Box::new(Expr::Literal(Value::Int64(value))),
+        };
+        let estimate =
+            |expr: &Expr, table: Option<&str>| estimate_selectivity(expr, &catalog, table);
+
+        assert!((estimate(&compare(BinaryOp::Eq, 7), Some("artist")) - 0.1).abs() < 1e-9);
+        assert!((estimate(&compare(BinaryOp::Lt, 60), Some("Artist")) - 0.4).abs() < 1e-9);
+        assert!((estimate(&compare(BinaryOp::GtEq, 75), Some("Artist")) - 0.4).abs() < 1e-9);
+        let is_null = Expr::IsNull {
+            expr: column(),
+            negated: false,
+        };
+        assert!((estimate(&is_null, Some("Artist")) - 0.2).abs() < 1e-9);
+
+        // Without a known table the fixed guesses still apply.
+        assert_eq!(
+            estimate(&compare(BinaryOp::Lt, 60), None),
+            PLANNER_RANGE_SELECTIVITY
+        );
+        assert_eq!(estimate_table_rows(&catalog, "Artist"), 100);
+    }
 }
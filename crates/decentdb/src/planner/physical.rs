@@ -203,7 +203,7 @@ impl PhysicalPlan {
                 predicate,
                 estimate,
             } => output.push(format!(
-                "{indent}CoveringIndexSeek(table={table}, index={index}, predicate={}, estRows={}, estCost={:.3})",
+                "{indent}CoveringIndexSeek(table={table}, index={index}, predicate={}, heap=skipped, estRows={}, estCost={:.3})",
                 predicate.to_sql(),
                 estimate.rows,
                 estimate.cost
@@ -266,6 +266,50 @@ impl TrigramIndex {
         }
     }
 
+    /// Returns the rows that may have a trigram similarity to `query` above
+    /// `threshold` (or equal to it, when `inclusive`). Similarity is at most
+    /// the fraction of the query's trigrams a row shares, so a row needs at
+    /// least `threshold` of them; the caller rechecks the exact similarity.
+    pub(crate) fn similarity_candidates(
+        &self,
+        query: &str,
+        threshold: f64,
+        inclusive: bool,
+    ) -> Result<TrigramQueryResult> {
+        if self.freshness() == Freshness::Stale {
+            return Ok(TrigramQueryResult::RebuildRequired);
+        }
+
+        let tokens = unique_tokens(query);
+        // Rows sharing no trigram with the query have similarity 0 and only
+        // qualify for thresholds the index cannot narrow.
+        if tokens.is_empty()
+            || threshold.is_nan()
+            || threshold < 0.0
+            || (inclusive && threshold == 0.0)
+        {
+            return Ok(TrigramQueryResult::FallbackTooShort);
+        }
+        let required = ((threshold * tokens.len() as f64).ceil() as usize).max(1);
+        if required > tokens.len() {
+            return Ok(TrigramQueryResult::Candidates(Vec::new()));
+        }
+
+        let mut shared = BTreeMap::<u64, usize>::new();
+        for &token in &tokens {
+            for row_id in self.materialized_live_postings(token)? {
+                *shared.entry(row_id).or_default() += 1;
+            }
+        }
+        Ok(TrigramQueryResult::Candidates(
+            shared
+                .into_iter()
+                .filter(|&(_, count)| count >= required)
+                .map(|(row_id, _)| row_id)
+                .collect(),
+        ))
+    }
+
     fn materialized_postings(&self, token: u32) -> Result<BTreeSet<u64>> {
         let mut postings = self
             .postings
@@ -356,6 +400,41 @@ mod tests {
         assert_eq!(result, TrigramQueryResult::Candidates(vec![1, 2]));
     }
 
+    #[test]
+    fn similarity_candidates_require_a_share_of_query_trigrams() {
+        let mut index = TrigramIndex::new(1024, 100_000);
+        index.queue_insert(1, "Motley Crue");
+        index.queue_insert(2, "Motly");
+        index.queue_insert(3, "Unrelated");
+        index.checkpoint().expect("checkpoint");
+
+        // "motley" has four trigrams; "Motly" shares MOT and OTL.
+        assert_eq!(
+            index
+                .similarity_candidates("motley", 0.3, false)
+                .expect("query"),
+            TrigramQueryResult::Candidates(vec![1, 2])
+        );
+        assert_eq!(
+            index
+                .similarity_candidates("motley", 0.6, false)
+                .expect("query"),
+            TrigramQueryResult::Candidates(vec![1])
+        );
+        assert_eq!(
+            index
+                .similarity_candidates("motley", 0.0, true)
+                .expect("query"),
+            TrigramQueryResult::FallbackTooShort
+        );
+        assert_eq!(
+            index
+                .similarity_candidates("mo", 0.3, false)
+                .expect("query"),
+            TrigramQueryResult::FallbackTooShort
+        );
+    }
+
     #[test]
     fn bulk_builder_creates_queryable_postings() {
         let mut builder = TrigramIndexBuilder::new();
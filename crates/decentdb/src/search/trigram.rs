@@ -67,6 +67,42 @@ fn unique_ascii_tokens(bytes: &[u8]) -> Vec<u32> {
     tokens
 }
 
+/// Trigram similarity of two strings: the number of trigrams they share
+/// divided by the number of distinct trigrams in either, from 0 to 1.
+/// Strings too short to have trigrams are similar only when equal.
+#[must_use]
+pub(crate) fn similarity(left: &str, right: &str) -> f64 {
+    let left_tokens = unique_tokens(left);
+    let right_tokens = unique_tokens(right);
+    if left_tokens.is_empty() || right_tokens.is_empty() {
+        return if normalize(left) == normalize(right) {
+            1.0
+        } else {
+            0.0
+        };
+    }
+    let shared = shared_token_count(&left_tokens, &right_tokens);
+    let union = left_tokens.len() + right_tokens.len() - shared;
+    shared as f64 / union as f64
+}
+
+/// Counts the tokens two sorted, deduplicated token lists have in common.
+fn shared_token_count(left: &[u32], right: &[u32]) -> usize {
+    let (mut i, mut j, mut shared) = (0, 0, 0);
+    while i < left.len() && j < right.len() {
+        match left[i].cmp(&right[j]) {
+            std::cmp::Ordering::Less => i += 1,
+            std::cmp::Ordering::Greater => j += 1,
+            std::cmp::Ordering::Equal => {
+                shared += 1;
+                i += 1;
+                j += 1;
+            }
+        }
+    }
+    shared
+}
+
 #[must_use]
 pub(crate) fn decide_guardrails(
     pattern: &str,
@@ -149,7 +185,7 @@ pub(crate) fn pack_trigram(bytes: [u8; 3]) -> u32 {
 mod tests {
     use super::{
         decide_guardrails, like_required_char_len, like_required_tokens, normalize, pack_trigram,
-        pattern_char_len, unique_tokens, GuardrailDecision,
+        pattern_char_len, similarity, unique_tokens, GuardrailDecision,
     };
 
     #[test]
@@ -159,6 +195,16 @@ mod tests {
         assert_eq!(normalize("abCd"), "ABCD");
     }
 
+    #[test]
+    fn similarity_is_jaccard_over_trigrams() {
+        assert_eq!(similarity("Motley", "MOTLEY"), 1.0);
+        // MOT, OTL, TLE, LEY vs MOT, OTL, TLY: two shared of five.
+        assert!((similarity("motley", "motly") - 0.4).abs() < 1e-9);
+        assert_eq!(similarity("abc", "xyz"), 0.0);
+        assert_eq!(similarity("ab", "AB"), 1.0);
+        assert_eq!(similarity("ab", "abc"), 0.0);
+    }
+
     #[test]
     fn short_and_broad_patterns_trigger_guardrails() {
         assert_eq!(
@@ -1,7 +1,9 @@
 //! Strongly typed internal AST for the supported DecentDB 1.0 SQL subset.
 #![cfg_attr(all(target_arch = "wasm32", target_os = "unknown"), allow(dead_code))]
 
-use crate::catalog::{ColumnType, EnumTypeInfo, SpatialTypeInfo};
+use crate::catalog::{
+    ColumnCollation, ColumnType, EnumTypeInfo, PartitionStrategy, SpatialTypeInfo,
+};
 use crate::record::value::{
     format_cidr, format_date_days, format_interval, format_ip_addr, format_mac_addr,
     format_time_micros, format_timestamp_tz_micros, Value,
@@ -64,6 +66,13 @@ pub(crate) enum Statement {
         identity: TruncateIdentityMode,
         cascade: bool,
     },
+    /// `COMMENT ON TABLE` when `column_name` is `None`, otherwise
+    /// `COMMENT ON COLUMN`. A `None` comment removes the existing one.
+    CommentOn {
+        table_name: String,
+        column_name: Option<String>,
+        comment: Option<String>,
+    },
 }
 
 #[derive(Clone, Copy, Debug, Eq, PartialEq)]
@@ -121,6 +130,10 @@ pub(crate) struct Select {
     pub(crate) from: Vec<FromItem>,
     pub(crate) filter: Option<Expr>,
     pub(crate) group_by: Vec<Expr>,
+    /// `GROUPING SETS`, `ROLLUP`, and `CUBE` expanded into the sets of
+    /// `group_by` indexes each output group is keyed on. Empty for a plain
+    /// `GROUP BY`, which groups on every expression.
+    pub(crate) grouping_sets: Vec<Vec<usize>>,
     pub(crate) having: Option<Expr>,
     pub(crate) distinct: bool,
     pub(crate) distinct_on: Vec<Expr>,
@@ -157,6 +170,22 @@ pub(crate) enum FromItem {
         kind: JoinKind,
         constraint: JoinConstraint,
     },
+    /// `source TABLESAMPLE method (percent) [REPEATABLE (seed)]`. The source
+    /// is always a `Table`.
+    Sample {
+        source: Box<FromItem>,
+        method: SampleMethod,
+        percent: Expr,
+        seed: Option<Expr>,
+    },
+}
+
+#[derive(Clone, Copy, Debug, Eq, PartialEq)]
+pub(crate) enum SampleMethod {
+    /// Keeps each row independently with the requested probability.
+    Bernoulli,
+    /// Keeps or skips whole blocks of consecutive rows.
+    System,
 }
 
 #[derive(Clone, Copy, Debug, Eq, PartialEq)]
@@ -407,6 +436,36 @@ pub(crate) struct CreateTableStatement {
     pub(crate) if_not_exists: bool,
     pub(crate) columns: Vec<ColumnDefinition>,
     pub(crate) constraints: Vec<TableConstraint>,
+    /// `WITH (ttl_column = ...)`: rows expire once this column's timestamp
+    /// has passed.
+    pub(crate) ttl_column: Option<String>,
+    pub(crate) partition_by: Option<PartitionBySpec>,
+    pub(crate) partition_of: Option<PartitionOfSpec>,
+}
+
+/// `PARTITION BY RANGE (column)` or `PARTITION BY HASH (column)`.
+#[derive(Clone, Debug, PartialEq)]
+pub(crate) struct PartitionBySpec {
+    pub(crate) strategy: PartitionStrategy,
+    pub(crate) column: String,
+}
+
+/// `PARTITION OF parent FOR VALUES ...`.
+#[derive(Clone, Debug, PartialEq)]
+pub(crate) struct PartitionOfSpec {
+    pub(crate) parent: String,
+    pub(crate) bound: PartitionBoundSpec,
+}
+
+#[derive(Clone, Debug, PartialEq)]
+pub(crate) enum PartitionBoundSpec {
+    /// `FROM (from) TO (to)`; `None` is `MINVALUE` or `MAXVALUE`.
+    Range {
+        from: Option<Expr>,
+        to: Option<Expr>,
+    },
+    /// `WITH (MODULUS modulus, REMAINDER remainder)`.
+    Hash { modulus: u32, remainder: u32 },
 }
 
 #[derive(Clone, Debug, PartialEq)]
@@ -425,6 +484,8 @@ pub(crate) struct ColumnDefinition {
     pub(crate) column_type: ColumnType,
     pub(crate) spatial_type: Option<SpatialTypeInfo>,
     pub(crate) enum_type: Option<EnumTypeInfo>,
+    /// `COLLATE` written on the column, if any.
+    pub(crate) collation: Option<Collation>,
     pub(crate) nullable: bool,
     pub(crate) default: Option<Expr>,
     pub(crate) generated: Option<Expr>,
@@ -548,6 +609,10 @@ pub(crate) enum AlterTableAction {
     AlterColumnType {
         column_name: String,
         new_type: ColumnType,
+        using: Option<Expr>,
+    },
+    DetachPartition {
+        partition_name: String,
     },
 }
 
@@ -677,7 +742,22 @@ impl Select {
         if let Some(filter) = &self.filter {
             parts.push(format!("WHERE {}", filter.to_sql()));
         }
-        if !self.group_by.is_empty() {
+        if !self.grouping_sets.is_empty() {
+            parts.push(format!(
+                "GROUP BY GROUPING SETS ({})",
+                self.grouping_sets
+                    .iter()
+                    .map(|set| format!(
+                        "({})",
+                        set.iter()
+                            .map(|index| self.group_by[*index].to_sql())
+                            .collect::<Vec<_>>()
+                            .join(", ")
+                    ))
+                    .collect::<Vec<_>>()
+                    .join(", ")
+            ));
+        } else if !self.group_by.is_empty() {
             parts.push(format!(
                 "GROUP BY {}",
                 self.group_by
@@ -755,6 +835,25 @@ impl FromItem {
                     rendered
                 }
             }
+            Self::Sample {
+                source,
+                method,
+                percent,
+                seed,
+            } => {
+                let method = match method {
+                    SampleMethod::Bernoulli => "BERNOULLI",
+                    SampleMethod::System => "SYSTEM",
+                };
+                let base = format!(
+                    "{} TABLESAMPLE {method} ({})",
+                    source.to_sql(),
+                    percent.to_sql()
+                );
+                seed.as_ref().map_or(base.clone(), |seed| {
+                    format!("{base} REPEATABLE ({})", seed.to_sql())
+                })
+            }
             Self::Join {
                 left,
                 right,
@@ -845,6 +944,15 @@ impl Collation {
     }
 }
 
+impl From<ColumnCollation> for Collation {
+    fn from(collation: ColumnCollation) -> Self {
+        match collation {
+            ColumnCollation::NoCase => Self::NoCase,
+            ColumnCollation::RTrim => Self::RTrim,
+        }
+    }
+}
+
 impl WindowFrameUnit {
     fn to_sql(self) -> &'static str {
         match self {
@@ -1521,6 +1629,18 @@ fn is_safe_from_item(
         FromItem::Subquery { query, lateral, .. } => {
             !*lateral && is_safe_query(query, tables, available_ctes)
         }
+        FromItem::Sample {
+            source,
+            percent,
+            seed,
+            ..
+        } => {
+            is_safe_from_item(source, tables, available_ctes, local_ctes)
+                && is_safe_expr(percent, tables, available_ctes, local_ctes)
+                && seed
+                    .as_ref()
+                    .is_none_or(|seed| is_safe_expr(seed, tables, available_ctes, local_ctes))
+        }
         // Table-valued functions are not analyzed — fall back to load-all.
         FromItem::Function { .. } => false,
     }
@@ -1535,6 +1655,9 @@ fn is_safe_expr(
 ) -> bool {
     match expr {
         Expr::Literal(_) | Expr::Column { .. } | Expr::Parameter(_) => true,
+        // A statement that takes or releases advisory locks must run every
+        // time, so it never gets a reusable dependency set.
+        Expr::Function { name, .. } if is_advisory_lock_function(name) => false,
         // Function and aggregate args may be expressions — walk them.
         Expr::Function { args, .. } | Expr::Aggregate { args, .. } => args
             .iter()
@@ -1637,6 +1760,185 @@ fn is_safe_expr(
     }
 }
 
+/// Reports whether `name` is one of the built-in functions that take or
+/// release the calling handle's advisory locks. They act outside the
+/// database, so a query calling one is not read-only.
+pub(crate) fn is_advisory_lock_function(name: &str) -> bool {
+    matches!(
+        name.to_ascii_lowercase().as_str(),
+        "advisory_lock"
+            | "pg_advisory_lock"
+            | "advisory_try_lock"
+            | "pg_advisory_try_lock"
+            | "pg_try_advisory_lock"
+            | "advisory_unlock"
+            | "pg_advisory_unlock"
+    )
+}
+
+/// Reports whether a query, or the query `EXPLAIN ANALYZE` runs, calls an
+/// advisory lock function anywhere, including in CTEs and subqueries.
+pub(crate) fn statement_calls_advisory_lock(stmt: &Statement) -> bool {
+    match stmt {
+        Statement::Query(query) => query_calls_advisory_lock(query),
+        Statement::Explain(explain) => {
+            explain.analyze && statement_calls_advisory_lock(&explain.statement)
+        }
+        _ => false,
+    }
+}
+
+fn query_calls_advisory_lock(query: &Query) -> bool {
+    query
+        .ctes
+        .iter()
+        .any(|cte| query_calls_advisory_lock(&cte.query))
+        || query_body_calls_advisory_lock(&query.body)
+        || query
+            .order_by
+            .iter()
+            .any(|order| expr_calls_advisory_lock(&order.expr))
+        || query
+            .limit
+            .iter()
+            .chain(&query.offset)
+            .any(expr_calls_advisory_lock)
+}
+
+fn query_body_calls_advisory_lock(body: &QueryBody) -> bool {
+    match body {
+        QueryBody::Select(select) => {
+            select.from.iter().any(from_item_calls_advisory_lock)
+                || select.projection.iter().any(|item| match item {
+                    SelectItem::Expr { expr, .. } => expr_calls_advisory_lock(expr),
+                    SelectItem::Wildcard | SelectItem::QualifiedWildcard(_) => false,
+                })
+                || select
+                    .filter
+                    .iter()
+                    .chain(&select.having)
+                    .chain(&select.group_by)
+                    .chain(&select.distinct_on)
+                    .any(expr_calls_advisory_lock)
+        }
+        QueryBody::Values(rows) => rows.iter().flatten().any(expr_calls_advisory_lock),
+        QueryBody::SetOperation { left, right, .. } => {
+            query_body_calls_advisory_lock(left) || query_body_calls_advisory_lock(right)
+        }
+    }
+}
+
+fn from_item_calls_advisory_lock(item: &FromItem) -> bool {
+    match item {
+        FromItem::Table { .. } => false,
+        FromItem::Subquery { query, .. } => query_calls_advisory_lock(query),
+        FromItem::Function { name, args, .. } => {
+            is_advisory_lock_function(name) || args.iter().any(expr_calls_advisory_lock)
+        }
+        FromItem::Join {
+            left,
+            right,
+            constraint,
+            ..
+        } => {
+            from_item_calls_advisory_lock(left)
+                || from_item_calls_advisory_lock(right)
+                || matches!(constraint, JoinConstraint::On(expr) if expr_calls_advisory_lock(expr))
+        }
+        FromItem::Sample {
+            source,
+            percent,
+            seed,
+            ..
+        } => {
+            from_item_calls_advisory_lock(source)
+                || expr_calls_advisory_lock(percent)
+                || seed.as_ref().is_some_and(expr_calls_advisory_lock)
+        }
+    }
+}
+
+fn expr_calls_advisory_lock(expr: &Expr) -> bool {
+    match expr {
+        Expr::Literal(_) | Expr::Column { .. } | Expr::Parameter(_) => false,
+        Expr::Function { name, args } => {
+            is_advisory_lock_function(name) || args.iter().any(expr_calls_advisory_lock)
+        }
+        Expr::Aggregate { args, order_by, .. } => {
+            args.iter().any(expr_calls_advisory_lock)
+                || order_by
+                    .iter()
+                    .any(|order| expr_calls_advisory_lock(&order.expr))
+        }
+        Expr::WindowFunction {
+            args,
+            partition_by,
+            order_by,
+            ..
+        } => {
+            args.iter()
+                .chain(partition_by)
+                .any(expr_calls_advisory_lock)
+                || order_by
+                    .iter()
+                    .any(|order| expr_calls_advisory_lock(&order.expr))
+        }
+        Expr::RowNumber {
+            partition_by,
+            order_by,
+            ..
+        } => {
+            partition_by.iter().any(expr_calls_advisory_lock)
+                || order_by
+                    .iter()
+                    .any(|order| expr_calls_advisory_lock(&order.expr))
+        }
+        Expr::Unary { expr, .. }
+        | Expr::IsNull { expr, .. }
+        | Expr::Collate { expr, .. }
+        | Expr::Cast { expr, .. } => expr_calls_advisory_lock(expr),
+        Expr::Binary { left, right, .. } => {
+            expr_calls_advisory_lock(left) || expr_calls_advisory_lock(right)
+        }
+        Expr::Between {
+            expr, low, high, ..
+        } => {
+            expr_calls_advisory_lock(expr)
+                || expr_calls_advisory_lock(low)
+                || expr_calls_advisory_lock(high)
+        }
+        Expr::InList { expr, items, .. } => {
+            expr_calls_advisory_lock(expr) || items.iter().any(expr_calls_advisory_lock)
+        }
+        Expr::InSubquery { expr, query, .. } | Expr::CompareSubquery { expr, query, .. } => {
+            expr_calls_advisory_lock(expr) || query_calls_advisory_lock(query)
+        }
+        Expr::ScalarSubquery(query) | Expr::Exists(query) => query_calls_advisory_lock(query),
+        Expr::Like {
+            expr,
+            pattern,
+            escape,
+            ..
+        } => {
+            expr_calls_advisory_lock(expr)
+                || expr_calls_advisory_lock(pattern)
+                || escape.as_deref().is_some_and(expr_calls_advisory_lock)
+        }
+        Expr::Case {
+            operand,
+            branches,
+            else_expr,
+        } => {
+            operand.as_deref().is_some_and(expr_calls_advisory_lock)
+                || branches.iter().any(|(when, then)| {
+                    expr_calls_advisory_lock(when) || expr_calls_advisory_lock(then)
+                })
+                || else_expr.as_deref().is_some_and(expr_calls_advisory_lock)
+        }
+        Expr::Row(exprs) => exprs.iter().any(expr_calls_advisory_lock),
+    }
+}
+
 fn cte_name_in_scope(name: &str, cte_names: &BTreeSet<String>) -> bool {
     cte_names
         .iter()
@@ -0,0 +1,138 @@
+//! Limits applied to SQL on handles opened with `DbConfig::defensive`.
+//!
+//! Defensive handles are meant for services that open databases and run SQL
+//! they do not control. Statement text is bounded in size and nesting depth
+//! before it reaches the parser, and statements that change the schema are
+//! refused so an untrusted caller cannot leave objects behind in the file.
+
+use crate::catalog::CatalogState;
+use crate::error::{DbError, Result};
+
+use super::ast::Statement;
+
+/// Longest statement text a defensive handle will parse.
+pub(crate) const DEFENSIVE_MAX_SQL_BYTES: usize = 1024 * 1024;
+
+/// Deepest parenthesis nesting a defensive handle will parse. Nested
+/// subqueries and expressions recurse through the parser and evaluator, so
+/// this bounds their stack use.
+pub(crate) const DEFENSIVE_MAX_NESTING_DEPTH: usize = 64;
+
+/// Checks statement text against the defensive size and nesting limits.
+pub(crate) fn check_untrusted_sql(sql: &str) -> Result<()> {
+    match limit_violation(sql) {
+        Some(problem) => Err(DbError::sql(problem)),
+        None => Ok(()),
+    }
+}
+
+/// Checks the view and trigger SQL stored in a database file against the
+/// same limits, so a crafted file cannot exhaust the stack when a query
+/// first expands one of them.
+pub(crate) fn check_untrusted_catalog(catalog: &CatalogState) -> Result<()> {
+    for view in catalog.views.values() {
+        if let Some(problem) = limit_violation(&view.sql_text) {
+            return Err(DbError::corruption(format!(
+                "view {}: {problem}",
+                view.name
+            )));
+        }
+    }
+    for trigger in catalog.triggers.values() {
+        if let Some(problem) = limit_violation(&trigger.action_sql) {
+            return Err(DbError::corruption(format!(
+                "trigger {}: {problem}",
+                trigger.name
+            )));
+        }
+    }
+    Ok(())
+}
+
+/// Describes the first limit `sql` exceeds. Parentheses inside string
+/// literals, quoted identifiers, and comments do not count toward nesting.
+fn limit_violation(sql: &str) -> Option<String> {
+    if sql.len() > DEFENSIVE_MAX_SQL_BYTES {
+        return Some(format!(
+            "statement is {} bytes, over the {} byte defensive mode limit",
+            sql.len(),
+            DEFENSIVE_MAX_SQL_BYTES
+        ));
+    }
+    let bytes = sql.as_bytes();
+    let mut depth = 0_usize;
+    let mut index = 0_usize;
+    while index < bytes.len() {
+        match bytes[index] {
+            quote @ (b'\'' | b'"') => {
+                index += 1;
+                while index < bytes.len() {
+                    if bytes[index] == quote {
+                        if bytes.get(index + 1) == Some(&quote) {
+                            index += 1;
+                        } else {
+                            break;
+                        }
+                    }
+                    index += 1;
+                }
+            }
+            b'-' if bytes.get(index + 1) == Some(&b'-') => {
+                while index < bytes.len() && bytes[index] != b'\n' {
+                    index += 1;
+                }
+            }
+            b'/' if bytes.get(index + 1) == Some(&b'*') => {
+                index += 2;
+                while index < bytes.len()
+                    && !(bytes[index] == b'*' && bytes.get(index + 1) == Some(&b'/'))
+                {
+                    index += 1;
+                }
+                index += 1;
+            }
+            b'(' => {
+                depth += 1;
+                if depth > DEFENSIVE_MAX_NESTING_DEPTH {
+                    return Some(format!(
+                        "statement nests deeper than the {} level defensive mode limit",
+                        DEFENSIVE_MAX_NESTING_DEPTH
+                    ));
+                }
+            }
+            b')' => depth = depth.saturating_sub(1),
+            _ => {}
+        }
+        index += 1;
+    }
+    None
+}
+
+/// Refuses statements that create, alter, or drop schema objects.
+pub(crate) fn check_untrusted_statement(statement: &Statement) -> Result<()> {
+    let kind = match statement {
+        Statement::Explain(explain) if explain.analyze => {
+            return check_untrusted_statement(&explain.statement);
+        }
+        Statement::CreateTable(_) | Statement::CreateTableAs(_) => "CREATE TABLE",
+        Statement::CreateSchema { .. } => "CREATE SCHEMA",
+        Statement::CreateIndex(_) => "CREATE INDEX",
+        Statement::CreateView(_) => "CREATE VIEW",
+        Statement::CreateTrigger(_) => "CREATE TRIGGER",
+        Statement::DropTable { .. } => "DROP TABLE",
+        Statement::DropIndex { .. } => "DROP INDEX",
+        Statement::DropView { .. } => "DROP VIEW",
+        Statement::DropTrigger { .. } => "DROP TRIGGER",
+        Statement::AlterViewRename { .. } => "ALTER VIEW",
+        Statement::AlterTable { .. } => "ALTER TABLE",
+        Statement::AlterIndexRebuild { .. } => "ALTER INDEX",
+        Statement::CommentOn { .. } => "COMMENT ON",
+        _ => return Ok(()),
+    };
+    Err(refused(kind))
+}
+
+/// Returns the error for a command a defensive handle does not run.
+pub(crate) fn refused(kind: &str) -> DbError {
+    DbError::sql(format!("{kind} is not allowed in defensive mode"))
+}
@@ -0,0 +1,483 @@
+//! Query fingerprints: SQL text with literals replaced by `?`, comments
+//! other than `decentdb:` tags dropped, whitespace and keyword case normalized, and `IN` lists of
+//! constants collapsed, so every execution of one query shape aggregates
+//! under the same key.
+
+use crate::error::{DbError, Result};
+
+#[derive(Debug, PartialEq)]
+enum Token {
+    /// Unquoted identifier or keyword, lowercased.
+    Word(String),
+    /// Double-quoted identifier, kept verbatim.
+    Quoted(String),
+    /// String, numeric, or bit-string constant.
+    Literal,
+    /// `$N` or `?` parameter, kept verbatim.
+    Param(String),
+    Punct(String),
+    /// `/* decentdb:... */` comment, such as a Go driver query tag, kept
+    /// verbatim so it attributes the fingerprint to its call site.
+    Tag(String),
+}
+
+/// Words after which a parenthesis opens an expression, not a call's
+/// argument list, and after which `-` and `+` are unary.
+const EXPRESSION_KEYWORDS: &[&str] = &[
+    "all",
+    "and",
+    "any",
+    "as",
+    "between",
+    "by",
+    "case",
+    "distinct",
+    "else",
+    "except",
+    "exists",
+    "filter",
+    "from",
+    "group",
+    "having",
+    "ilike",
+    "in",
+    "intersect",
+    "is",
+    "join",
+    "lateral",
+    "like",
+    "limit",
+    "not",
+    "offset",
+    "on",
+    "or",
+    "over",
+    "returning",
+    "select",
+    "set",
+    "some",
+    "then",
+    "union",
+    "using",
+    "values",
+    "when",
+    "where",
+    "with",
+];
+
+/// Returns the fingerprint of `sql`. Two statements that differ only in
+/// constant values, comments, whitespace, or the case of unquoted words have
+/// the same fingerprint. Fails only on unterminated quotes or comments.
+pub fn normalize_query(sql: &str) -> Result<String> {
+    let tokens = tokenize(sql)?;
+    let mut out = String::with_capacity(sql.len());
+    let mut prev: Option<&Token> = None;
+    let mut i = 0;
+    while i < tokens.len() {
+        let token = &tokens[i];
+        if let Some(end) = constant_in_list_end(&tokens, i) {
+            push_spaced(&mut out, prev, token);
+            out.push_str("in (...)");
+            prev = Some(&tokens[end]);
+            i = end + 1;
+            continue;
+        }
+        if is_unary_sign(prev, token) && tokens.get(i + 1) == Some(&Token::Literal) {
+            push_spaced(&mut out, prev, &Token::Literal);
+            out.push('?');
+            prev = Some(&tokens[i + 1]);
+            i += 2;
+            continue;
+        }
+        push_spaced(&mut out, prev, token);
+        match token {
+            Token::Word(text)
+            | Token::Quoted(text)
+            | Token::Param(text)
+            | Token::Punct(text)
+            | Token::Tag(text) => out.push_str(text),
+            Token::Literal => out.push('?'),
+        }
+        prev = Some(token);
+        i += 1;
+    }
+    while out.ends_with(';') {
+        out.pop();
+        out.truncate(out.trim_end().len());
+    }
+    Ok(out)
+}
+
+/// If `tokens[start]` begins `in (c, ...)` with only constants and
+/// parameters in the list, returns the index of the closing parenthesis.
+fn constant_in_list_end(tokens: &[Token], start: usize) -> Option<usize> {
+    if !matches!(tokens.get(start), Some(Token::Word(w)) if w == "in")
+        || !matches!(tokens.get(start + 1), Some(Token::Punct(p)) if p == "(")
+    {
+        return None;
+    }
+    let mut i = start + 2;
+    loop {
+        match tokens.get(i) {
+            Some(Token::Literal | Token::Param(_)) => i += 1,
+            Some(Token::Punct(p)) if p == "-" || p == "+" => {
+                if tokens.get(i + 1) != Some(&Token::Literal) {
+                    return None;
+                }
+                i += 2;
+            }
+            _ => return None,
+        }
+        match tokens.get(i) {
+            Some(Token::Punct(p)) if p == "," => i += 1,
+            Some(Token::Punct(p)) if p == ")" => return Some(i),
+            _ => return None,
+        }
+    }
+}
+
+fn is_unary_sign(prev: Option<&Token>, token: &Token) -> bool {
+    if !matches!(token, Token::Punct(p) if p == "-" || p == "+") {
+        return false;
+    }
+    match prev {
+        None => true,
+        Some(Token::Word(word)) => EXPRESSION_KEYWORDS.contains(&word.as_str()),
+        Some(Token::Punct(p)) => p != ")",
+        Some(Token::Quoted(_) | Token::Literal | Token::Param(_) | Token::Tag(_)) => false,
+    }
+}
+
+fn push_spaced(out: &mut String, prev: Option<&Token>, token: &Token) {
+    let Some(prev) = prev else {
+        return;
+    };
+    let tight_after = matches!(prev, Token::Punct(p) if p == "(" || p == "." || p == "::");
+    let tight_before = match token {
+        Token::Punct(p) if p == "," || p == ")" || p == "." || p == ";" || p == "::" => true,
+        Token::Punct(p) if p == "(" => match prev {
+            Token::Word(word) => !EXPRESSION_KEYWORDS.contains(&word.as_str()),
+            Token::Quoted(_) => true,
+            _ => false,
+        },
+        _ => false,
+    };
+    if !tight_after && !tight_before {
+        out.push(' ');
+    }
+}
+
+fn tokenize(sql: &str) -> Result<Vec<Token>> {
+    let chars: Vec<char> = sql.chars().collect();
+    let mut tokens = Vec::new();
+    let mut i = 0;
+    while i < chars.len() {
+        let c = chars[i];
+        let next = chars.get(i + 1).copied();
+        if c.is_whitespace() {
+            i += 1;
+        } else if c == '-' && next == Some('-') {
+            while i < chars.len() && chars[i] != '\n' {
+                i += 1;
+            }
+        } else if c == '/' && next == Some('*') {
+            let start = i;
+            i = skip_block_comment(&chars, i)?;
+            let comment: String = chars[start..i].iter().collect();
+            if comment[2..].trim_start().starts_with("decentdb:") {
+                tokens.push(Token::Tag(comment));
+            }
+        } else if c == '\'' {
+            i = skip_string(&chars, i, false)?;
+            tokens.push(Token::Literal);
+        } else if c == '"' {
+            let end = skip_quoted(&chars, i, '"', "quoted identifier")?;
+            tokens.push(Token::Quoted(chars[i..end].iter().collect()));
+            i = end;
+        } else if c.is_ascii_digit() || (c == '.' && next.is_some_and(|n| n.is_ascii_digit())) {
+            i = skip_number(&chars, i);
+            tokens.push(Token::Literal);
+        } else if is_word_start(c) {
+            let start = i;
+            while i < chars.len() && is_word_char(chars[i]) {
+                i += 1;
+            }
+            let word: String = chars[start..i].iter().collect::<String>().to_lowercase();
+            if i - start == 1
+                && chars.get(i) == Some(&'\'')
+                && matches!(word.as_str(), "e" | "x" | "b" | "n")
+            {
+                i = skip_string(&chars, i, word == "e")?;
+                tokens.push(Token::Literal);
+            } else {
+                tokens.push(Token::Word(word));
+            }
+        } else if c == '$' && next.is_some_and(|n| n.is_ascii_digit()) {
+            let start = i;
+            i += 1;
+            while i < chars.len() && chars[i].is_ascii_digit() {
+                i += 1;
+            }
+            tokens.push(Token::Param(chars[start..i].iter().collect()));
+        } else if c == '$' {
+            match dollar_quote_end(&chars, i)? {
+                Some(end) => {
+                    tokens.push(Token::Literal);
+                    i = end;
+                }
+                None => {
+                    tokens.push(Token::Punct("$".to_string()));
+                    i += 1;
+                }
+            }
+        } else if c == '?' {
+            tokens.push(Token::Param("?".to_string()));
+            i += 1;
+        } else if matches!(c, '(' | ')' | ',' | ';' | '.' | '[' | ']' | '{' | '}') {
+            tokens.push(Token::Punct(c.to_string()));
+            i += 1;
+        } else {
+            let start = i;
+            while i < chars.len()
+                && is_operator_char(chars[i])
+                && !(i > start && starts_comment(&chars, i))
+            {
+                i += 1;
+            }
+            if i == start {
+                i += 1;
+            }
+            // As in PostgreSQL, a trailing `+` or `-` belongs to the next
+            // token unless the operator also contains a character that
+            // cannot start a unary expression: `=-1` is `=` and `-1`.
+            while i - start > 1
+                && matches!(chars[i - 1], '+' | '-')
+                && !chars[start..i]
+                    .iter()
+                    .any(|c| matches!(c, '~' | '!' | '@' | '#' | '%' | '^' | '&' | '|'))
+            {
+                i -= 1;
+            }
+            tokens.push(Token::Punct(chars[start..i].iter().collect()));
+        }
+    }
+    Ok(tokens)
+}
+
+fn is_word_start(c: char) -> bool {
+    c.is_alphabetic() || c == '_'
+}
+
+fn is_word_char(c: char) -> bool {
+    c.is_alphanumeric() || c == '_' || c == '$'
+}
+
+fn is_operator_char(c: char) -> bool {
+    matches!(
+        c,
+        '+' | '-'
+            | '*'
+            | '/'
+            | '<'
+            | '>'
+            | '='
+            | '~'
+            | '!'
+            | '@'
+            | '#'
+            | '%'
+            | '^'
+            | '&'
+            | '|'
+            | ':'
+    )
+}
+
+fn starts_comment(chars: &[char], i: usize) -> bool {
+    matches!(
+        (chars[i], chars.get(i + 1)),
+        ('-', Some('-')) | ('/', Some('*'))
+    )
+}
+
+fn skip_block_comment(chars: &[char], start: usize) -> Result<usize> {
+    let mut depth = 0usize;
+    let mut i = start;
+    while i < chars.len() {
+        if chars[i] == '/' && chars.get(i + 1) == Some(&'*') {
+            depth += 1;
+            i += 2;
+        } else if chars[i] == '*' && chars.get(i + 1) == Some(&'/') {
+            depth -= 1;
+            i += 2;
+            if depth == 0 {
+                return Ok(i);
+            }
+        } else {
+            i += 1;
+        }
+    }
+    Err(DbError::sql_syntax("unterminated block comment"))
+}
+
+/// Skips a single-quoted string starting at `start`, where `''` escapes a
+/// quote and, in an `E'...'` string, a backslash escapes any character.
+fn skip_string(chars: &[char], start: usize, backslash_escapes: bool) -> Result<usize> {
+    let mut i = start + 1;
+    while i < chars.len() {
+        match chars[i] {
+            '\\' if backslash_escapes => i += 2,
+            '\'' if chars.get(i + 1) == Some(&'\'') => i += 2,
+            '\'' => return Ok(i + 1),
+            _ => i += 1,
+        }
+    }
+    Err(DbError::sql_syntax("unterminated quoted string"))
+}
+
+fn skip_quoted(chars: &[char], start: usize, quote: char, what: &str) -> Result<usize> {
+    let mut i = start + 1;
+    while i < chars.len() {
+        if chars[i] == quote {
+            if chars.get(i + 1) == Some(&quote) {
+                i += 2;
+                continue;
+            }
+            return Ok(i + 1);
+        }
+        i += 1;
+    }
+    Err(DbError::sql_syntax(format!("unterminated {what}")))
+}
+
+fn skip_number(chars: &[char], start: usize) -> usize {
+    let mut i = start;
+    if chars[i] == '0' && matches!(chars.get(i + 1), Some('x' | 'X')) {
+        i += 2;
+        while i < chars.len() && (chars[i].is_ascii_hexdigit() || chars[i] == '_') {
+            i += 1;
+        }
+        return i;
+    }
+    while i < chars.len() && (chars[i].is_ascii_digit() || chars[i] == '_') {
+        i += 1;
+    }
+    if chars.get(i) == Some(&'.') {
+        i += 1;
+        while i < chars.len() && chars[i].is_ascii_digit() {
+            i += 1;
+        }
+    }
+    if matches!(chars.get(i), Some('e' | 'E')) {
+        let mut j = i + 1;
+        if matches!(chars.get(j), Some('+' | '-')) {
+            j += 1;
+        }
+        if chars.get(j).is_some_and(|c| c.is_ascii_digit()) {
+            i = j;
+            while i < chars.len() && chars[i].is_ascii_digit() {
+                i += 1;
+            }
+        }
+    }
+    i
+}
+
+/// If a dollar-quoted string (`$$...$$` or `$tag$...$tag$`) starts at
+/// `start`, returns the index after its closing delimiter.
+fn dollar_quote_end(chars: &[char], start: usize) -> Result<Option<usize>> {
+    let mut i = start + 1;
+    while i < chars.len() && chars[i] != '$' {
+        if !(chars[i].is_alphanumeric() || chars[i] == '_')
+            || (chars[i].is_ascii_digit() && i == start + 1)
+        {
+            return Ok(None);
+        }
+        i += 1;
+    }
+    if i >= chars.len() {
+        return Ok(None);
+    }
+    let tag = &chars[start..=i];
+    let mut j = i + 1;
+    while j + tag.len() <= chars.len() {
+        if &chars[j..j + tag.len()] == tag {
+            return Ok(Some(j + tag.len()));
+        }
+        j += 1;
+    }
+    Err(DbError::sql_syntax("unterminated dollar-quoted string"))
+}
+
+#[cfg(test)]
+mod tests {
+    use super::normalize_query;
+
+    fn fp(sql: &str) -> String {
+        normalize_query(sql).expect("normalize")
+    }
+
+    #[test]
+    fn replaces_literals_and_normalizes_layout() {
+        assert_eq!(
+            fp("SELECT name FROM Users WHERE id = 42 AND email = 'a@b.c'"),
+            "select name from users where id = ? and email = ?"
+        );
+        assert_eq!(
+            fp("select  name\n  from users -- by key\n where id=7 and email=E'x\\'y';"),
+            "select name from users where id = ? and email = ?"
+        );
+        assert_eq!(
+            fp("SELECT 1.5e3, -2, x - 3, $$body$$, X'ff'"),
+            "select ?, ?, x - ?, ?, ?"
+        );
+        assert_eq!(
+            fp("UPDATE t SET a=-1 WHERE b<>-2"),
+            "update t set a = ? where b <> ?"
+        );
+    }
+
+    #[test]
+    fn keeps_parameters_and_quoted_identifiers() {
+        assert_eq!(
+            fp(r#"SELECT "Mixed"."Col" FROM t WHERE a = $1 AND b = $12"#),
+            r#"select "Mixed"."Col" from t where a = $1 and b = $12"#
+        );
+        assert_eq!(
+            fp("SELECT count(*) FROM t /* c /* nested */ */"),
+            "select count(*) from t"
+        );
+        assert_eq!(
+            fp("SELECT 1\n/* decentdb:tag=Cart:get */"),
+            "select ? /* decentdb:tag=Cart:get */"
+        );
+    }
+
+    #[test]
+    fn collapses_constant_in_lists() {
+        assert_eq!(
+            fp("SELECT * FROM t WHERE id IN (1, 2, 3)"),
+            fp("select * from t where id in (4)")
+        );
+        assert_eq!(
+            fp("SELECT * FROM t WHERE id IN ($1, -2)"),
+            "select * from t where id in (...)"
+        );
+        assert_eq!(
+            fp("SELECT * FROM t WHERE id IN (SELECT id FROM u)"),
+            "select * from t where id in (select id from u)"
+        );
+    }
+
+    #[test]
+    fn rejects_unterminated_text() {
+        for sql in [
+            "SELECT 'open",
+            "SELECT \"open",
+            "SELECT 1 /* open",
+            "SELECT $q$ open",
+        ] {
+            assert!(normalize_query(sql).is_err(), "{sql}");
+        }
+    }
+}
@@ -1,10 +1,13 @@
 //! SQL parsing and normalization entry points.
 
 pub(crate) mod ast;
+pub(crate) mod defensive;
+pub(crate) mod fingerprint;
 #[cfg(not(all(target_arch = "wasm32", target_os = "unknown")))]
 pub(crate) mod normalize;
 pub(crate) mod parser;
 pub(crate) mod parser_tests;
+pub(crate) mod search_path;
 #[cfg(any(all(target_arch = "wasm32", target_os = "unknown"), test))]
 pub(crate) mod wasm_minimal;
 
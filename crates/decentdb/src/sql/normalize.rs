@@ -4,7 +4,8 @@ use libpg_query_sys::protobuf;
 use libpg_query_sys::protobuf::node::Node as NodeEnum;
 
 use crate::catalog::{
-    ColumnType, EnumLabel, EnumTypeInfo, SpatialDimensions, SpatialSubtype, SpatialTypeInfo,
+    ColumnType, EnumLabel, EnumTypeInfo, PartitionStrategy, SpatialDimensions, SpatialSubtype,
+    SpatialTypeInfo,
 };
 use crate::error::{DbError, Result};
 use crate::record::value::Value;
@@ -14,11 +15,13 @@ use super::ast::{
     ConflictAction, ConflictTarget, CreateIndexStatement, CreateTableAsStatement,
     CreateTableStatement, CreateTriggerStatement, CreateViewStatement, DeleteStatement,
     ExplainStatement, Expr, ForeignKeyActionSpec, ForeignKeyDefinition, FromItem, IndexExpression,
-    IndexOption, InsertSource, InsertStatement, JoinConstraint, JoinKind, OrderBy, Query,
-    QueryBody, Select, SelectItem, SetOperation, Statement, SubqueryQuantifier, TableConstraint,
-    TriggerEventSpec, TriggerKindSpec, TruncateIdentityMode, UnaryOp, UpdateStatement, WindowFrame,
-    WindowFrameBound, WindowFrameUnit,
+    IndexOption, InsertSource, InsertStatement, JoinConstraint, JoinKind, OrderBy,
+    PartitionBoundSpec, PartitionBySpec, PartitionOfSpec, Query, QueryBody, SampleMethod, Select,
+    SelectItem, SetOperation, Statement, SubqueryQuantifier, TableConstraint, TriggerEventSpec,
+    TriggerKindSpec, TruncateIdentityMode, UnaryOp, UpdateStatement, WindowFrame, WindowFrameBound,
+    WindowFrameUnit,
 };
+use super::search_path;
 
 // Thread-local flag set by `detect_and_rewrite_create_view_if_not_exists` and
 // consumed by `normalize_create_view` to propagate the IF NOT EXISTS signal
@@ -112,6 +115,7 @@ fn normalize_statement_with_generated_modes(
             })
         }
         NodeEnum::TruncateStmt(statement) => normalize_truncate(statement),
+        NodeEnum::CommentStmt(statement) => normalize_comment(statement),
         NodeEnum::CreateTrigStmt(statement) => Ok(Statement::CreateTrigger(
             normalize_create_trigger(statement, original_sql)?,
         )),
@@ -155,6 +159,161 @@ fn normalize_truncate(statement: &protobuf::TruncateStmt) -> Result<Statement> {
 }
 
 fn normalize_query(statement: &protobuf::SelectStmt) -> Result<Query> {
+    // The search path must not qualify references to the query's own CTEs.
+    search_path::with_cte_names(
+        with_clause_cte_names(statement.with_clause.as_ref()),
+        || normalize_query_in_cte_scope(statement),
+    )
+}
+
+fn with_clause_cte_names(clause: Option<&protobuf::WithClause>) -> Vec<String> {
+    clause
+        .iter()
+        .flat_map(|clause| clause.ctes.iter())
+        .filter_map(|cte| match cte.node.as_ref() {
+            Some(NodeEnum::CommonTableExpr(cte)) => Some(cte.ctename.clone()),
+            _ => None,
+        })
+        .collect()
+}
+
+/// The WITH clause leading an INSERT, UPDATE, or DELETE. The statement has no
+/// query of its own to hold the CTEs, so each query and subquery directly in
+/// it gets a copy and sees them the way a SELECT's subqueries see its CTEs.
+struct StatementCtes {
+    recursive: bool,
+    ctes: Vec<CommonTableExpr>,
+}
+
+impl StatementCtes {
+    /// Normalizes `clause`, then runs `f` with the CTE names in scope.
+    fn scoped<T>(
+        clause: Option<&protobuf::WithClause>,
+        f: impl FnOnce(&Self) -> Result<T>,
+    ) -> Result<T> {
+        search_path::with_cte_names(with_clause_cte_names(clause), || {
+            let ctes = Self {
+                recursive: clause.is_some_and(|clause| clause.recursive),
+                ctes: normalize_with_clause(clause)?,
+            };
+            f(&ctes)
+        })
+    }
+
+    fn attach_to_query(&self, query: &mut Query) {
+        if self.ctes.is_empty() {
+            return;
+        }
+        query.recursive |= self.recursive;
+        // The query's own CTEs come later and shadow these.
+        query.ctes.splice(0..0, self.ctes.iter().cloned());
+    }
+
+    fn attach_to_select_item(&self, item: &mut SelectItem) {
+        if let SelectItem::Expr { expr, .. } = item {
+            self.attach_to_expr(expr);
+        }
+    }
+
+    fn attach_to_expr(&self, expr: &mut Expr) {
+        if self.ctes.is_empty() {
+            return;
+        }
+        match expr {
+            Expr::Literal(_) | Expr::Column { .. } | Expr::Parameter(_) => {}
+            Expr::Unary { expr, .. }
+            | Expr::IsNull { expr, .. }
+            | Expr::Collate { expr, .. }
+            | Expr::Cast { expr, .. } => self.attach_to_expr(expr),
+            Expr::Binary { left, right, .. } => {
+                self.attach_to_expr(left);
+                self.attach_to_expr(right);
+            }
+            Expr::Between {
+                expr, low, high, ..
+            } => {
+                self.attach_to_expr(expr);
+                self.attach_to_expr(low);
+                self.attach_to_expr(high);
+            }
+            Expr::InList { expr, items, .. } => {
+                self.attach_to_expr(expr);
+                items.iter_mut().for_each(|item| self.attach_to_expr(item));
+            }
+            Expr::InSubquery { expr, query, .. } | Expr::CompareSubquery { expr, query, .. } => {
+                self.attach_to_expr(expr);
+                self.attach_to_query(query);
+            }
+            Expr::ScalarSubquery(query) | Expr::Exists(query) => self.attach_to_query(query),
+            Expr::Like {
+                expr,
+                pattern,
+                escape,
+                ..
+            } => {
+                self.attach_to_expr(expr);
+                self.attach_to_expr(pattern);
+                if let Some(escape) = escape {
+                    self.attach_to_expr(escape);
+                }
+            }
+            Expr::Function { args, .. } => {
+                args.iter_mut().for_each(|arg| self.attach_to_expr(arg));
+            }
+            Expr::Aggregate { args, order_by, .. } => {
+                args.iter_mut().for_each(|arg| self.attach_to_expr(arg));
+                order_by
+                    .iter_mut()
+                    .for_each(|order| self.attach_to_expr(&mut order.expr));
+            }
+            Expr::RowNumber {
+                partition_by,
+                order_by,
+                ..
+            } => {
+                partition_by
+                    .iter_mut()
+                    .for_each(|expr| self.attach_to_expr(expr));
+                order_by
+                    .iter_mut()
+                    .for_each(|order| self.attach_to_expr(&mut order.expr));
+            }
+            Expr::WindowFunction {
+                args,
+                partition_by,
+                order_by,
+                ..
+            } => {
+                args.iter_mut().for_each(|arg| self.attach_to_expr(arg));
+                partition_by
+                    .iter_mut()
+                    .for_each(|expr| self.attach_to_expr(expr));
+                order_by
+                    .iter_mut()
+                    .for_each(|order| self.attach_to_expr(&mut order.expr));
+            }
+            Expr::Case {
+                operand,
+                branches,
+                else_expr,
+            } => {
+                if let Some(operand) = operand {
+                    self.attach_to_expr(operand);
+                }
+                for (condition, result) in branches {
+                    self.attach_to_expr(condition);
+                    self.attach_to_expr(result);
+                }
+                if let Some(else_expr) = else_expr {
+                    self.attach_to_expr(else_expr);
+                }
+            }
+            Expr::Row(items) => items.iter_mut().for_each(|item| self.attach_to_expr(item)),
+        }
+    }
+}
+
+fn normalize_query_in_cte_scope(statement: &protobuf::SelectStmt) -> Result<Query> {
     let recursive = statement
         .with_clause
         .as_ref()
@@ -244,11 +403,7 @@ fn normalize_query_body(statement: &protobuf::SelectStmt) -> Result<QueryBody> {
         .as_deref()
         .map(normalize_expr_node)
         .transpose()?;
-    let group_by = statement
-        .group_clause
-        .iter()
-        .map(normalize_expr_container)
-        .collect::<Result<Vec<_>>>()?;
+    let (group_by, grouping_sets) = normalize_group_clause(&statement.group_clause)?;
     let having = statement
         .having_clause
         .as_deref()
@@ -260,12 +415,146 @@ fn normalize_query_body(statement: &protobuf::SelectStmt) -> Result<QueryBody> {
         from,
         filter,
         group_by,
+        grouping_sets,
         having,
         distinct,
         distinct_on,
     }))
 }
 
+const MAX_GROUPING_SETS: usize = 4096;
+
+/// Splits a GROUP BY clause into its distinct grouping expressions and, when
+/// it uses `GROUPING SETS`, `ROLLUP`, or `CUBE`, the expanded list of sets.
+/// Items are combined as a cross product, so `GROUP BY a, ROLLUP (b)` groups
+/// by `(a, b)` and then `(a)`.
+fn normalize_group_clause(nodes: &[protobuf::Node]) -> Result<(Vec<Expr>, Vec<Vec<usize>>)> {
+    if !nodes
+        .iter()
+        .any(|node| matches!(node.node, Some(NodeEnum::GroupingSet(_))))
+    {
+        let group_by = nodes
+            .iter()
+            .map(normalize_expr_container)
+            .collect::<Result<Vec<_>>>()?;
+        return Ok((group_by, Vec::new()));
+    }
+
+    let mut group_by = Vec::new();
+    let mut sets = vec![Vec::new()];
+    for node in nodes {
+        let item_sets = normalize_grouping_item(node, &mut group_by)?;
+        if sets.len().saturating_mul(item_sets.len()) > MAX_GROUPING_SETS {
+            return Err(unsupported(format!(
+                "too many grouping sets (maximum {MAX_GROUPING_SETS})"
+            )));
+        }
+        sets = sets
+            .iter()
+            .flat_map(|prefix| {
+                item_sets.iter().map(move |set| {
+                    let mut combined = prefix.clone();
+                    combined.extend(set);
+                    combined.sort_unstable();
+                    combined.dedup();
+                    combined
+                })
+            })
+            .collect();
+    }
+    Ok((group_by, sets))
+}
+
+fn normalize_grouping_item(
+    node: &protobuf::Node,
+    group_by: &mut Vec<Expr>,
+) -> Result<Vec<Vec<usize>>> {
+    let NodeEnum::GroupingSet(set) = node_kind(node)? else {
+        return Ok(vec![vec![grouping_expr_index(node, group_by)?]]);
+    };
+    let kind = protobuf::GroupingSetKind::try_from(set.kind)
+        .unwrap_or(protobuf::GroupingSetKind::Undefined);
+    match kind {
+        protobuf::GroupingSetKind::GroupingSetEmpty => Ok(vec![Vec::new()]),
+        protobuf::GroupingSetKind::GroupingSetSimple => Ok(vec![set
+            .content
+            .iter()
+            .map(|node| grouping_expr_index(node, group_by))
+            .collect::<Result<Vec<_>>>()?]),
+        protobuf::GroupingSetKind::GroupingSetRollup => {
+            let elements = grouping_elements(&set.content, group_by)?;
+            Ok((0..=elements.len())
+                .rev()
+                .map(|len| elements[..len].concat())
+                .collect())
+        }
+        protobuf::GroupingSetKind::GroupingSetCube => {
+            let elements = grouping_elements(&set.content, group_by)?;
+            if elements.len() > MAX_GROUPING_SETS.trailing_zeros() as usize {
+                return Err(unsupported(format!(
+                    "CUBE is limited to {} elements",
+                    MAX_GROUPING_SETS.trailing_zeros()
+                )));
+            }
+            Ok((0..1_usize << elements.len())
+                .rev()
+                .map(|mask| {
+                    elements
+                        .iter()
+                        .enumerate()
+                        .filter(|(index, _)| mask & (1 << (elements.len() - 1 - index)) != 0)
+                        .flat_map(|(_, element)| element.iter().copied())
+                        .collect()
+                })
+                .collect())
+        }
+        protobuf::GroupingSetKind::GroupingSetSets => {
+            let mut sets = Vec::new();
+            for node in &set.content {
+                match node_kind(node)? {
+                    NodeEnum::RowExpr(row) => sets.push(
+                        row.args
+                            .iter()
+                            .map(|node| grouping_expr_index(node, group_by))
+                            .collect::<Result<Vec<_>>>()?,
+                    ),
+                    _ => sets.extend(normalize_grouping_item(node, group_by)?),
+                }
+            }
+            Ok(sets)
+        }
+        protobuf::GroupingSetKind::Undefined => Err(unsupported("unknown grouping set kind")),
+    }
+}
+
+/// Resolves the elements of `ROLLUP` or `CUBE`, where a parenthesized list
+/// such as `(a, b)` is one element.
+fn grouping_elements(
+    nodes: &[protobuf::Node],
+    group_by: &mut Vec<Expr>,
+) -> Result<Vec<Vec<usize>>> {
+    nodes
+        .iter()
+        .map(|node| match node_kind(node)? {
+            NodeEnum::RowExpr(row) => row
+                .args
+                .iter()
+                .map(|node| grouping_expr_index(node, group_by))
+                .collect(),
+            _ => Ok(vec![grouping_expr_index(node, group_by)?]),
+        })
+        .collect()
+}
+
+fn grouping_expr_index(node: &protobuf::Node, group_by: &mut Vec<Expr>) -> Result<usize> {
+    let expr = normalize_expr_container(node)?;
+    if let Some(index) = group_by.iter().position(|existing| *existing == expr) {
+        return Ok(index);
+    }
+    group_by.push(expr);
+    Ok(group_by.len() - 1)
+}
+
 fn normalize_distinct_clause(distinct_clause: &[protobuf::Node]) -> Result<(bool, Vec<Expr>)> {
     if distinct_clause.is_empty() {
         return Ok((false, Vec::new()));
@@ -291,27 +580,52 @@ fn normalize_insert(statement: &protobuf::InsertStmt) -> Result<InsertStatement>
         .iter()
         .map(normalize_target_column)
         .collect::<Result<Vec<_>>>()?;
-    let source_node = statement
-        .select_stmt
-        .as_deref()
-        .ok_or_else(|| unsupported("INSERT is missing its source rows"))?;
-    let source = normalize_insert_source(source_node)?;
-    let on_conflict = statement
-        .on_conflict_clause
-        .as_deref()
-        .map(normalize_on_conflict)
-        .transpose()?;
-    let returning = statement
-        .returning_list
-        .iter()
-        .map(normalize_select_item)
-        .collect::<Result<Vec<_>>>()?;
-    Ok(InsertStatement {
-        table_name,
-        columns,
-        source,
-        on_conflict,
-        returning,
+    StatementCtes::scoped(statement.with_clause.as_ref(), |ctes| {
+        let source_node = statement
+            .select_stmt
+            .as_deref()
+            .ok_or_else(|| unsupported("INSERT is missing its source rows"))?;
+        let mut source = normalize_insert_source(source_node)?;
+        match &mut source {
+            InsertSource::Values(rows) => rows
+                .iter_mut()
+                .flatten()
+                .for_each(|expr| ctes.attach_to_expr(expr)),
+            InsertSource::Query(query) => ctes.attach_to_query(query),
+        }
+        let mut on_conflict = statement
+            .on_conflict_clause
+            .as_deref()
+            .map(normalize_on_conflict)
+            .transpose()?;
+        if let Some(ConflictAction::DoUpdate {
+            assignments,
+            filter,
+            ..
+        }) = &mut on_conflict
+        {
+            assignments
+                .iter_mut()
+                .for_each(|assignment| ctes.attach_to_expr(&mut assignment.expr));
+            if let Some(filter) = filter {
+                ctes.attach_to_expr(filter);
+            }
+        }
+        let mut returning = statement
+            .returning_list
+            .iter()
+            .map(normalize_select_item)
+            .collect::<Result<Vec<_>>>()?;
+        returning
+            .iter_mut()
+            .for_each(|item| ctes.attach_to_select_item(item));
+        Ok(InsertStatement {
+            table_name,
+            columns,
+            source,
+            on_conflict,
+            returning,
+        })
     })
 }
 
@@ -413,28 +727,43 @@ fn normalize_update(statement: &protobuf::UpdateStmt) -> Result<UpdateStatement>
             "UPDATE ... FROM is not supported in DecentDB 1.0",
         ));
     }
-    Ok(UpdateStatement {
-        table_name: normalize_range_var(
-            statement
-                .relation
-                .as_ref()
-                .ok_or_else(|| unsupported("UPDATE is missing a target table"))?,
-        )?,
-        assignments: statement
-            .target_list
-            .iter()
-            .map(normalize_assignment)
-            .collect::<Result<Vec<_>>>()?,
-        filter: statement
-            .where_clause
-            .as_deref()
-            .map(normalize_expr_node)
-            .transpose()?,
-        returning: statement
-            .returning_list
-            .iter()
-            .map(normalize_select_item)
-            .collect::<Result<Vec<_>>>()?,
+    let table_name = normalize_range_var(
+        statement
+            .relation
+            .as_ref()
+            .ok_or_else(|| unsupported("UPDATE is missing a target table"))?,
+    )?;
+    StatementCtes::scoped(statement.with_clause.as_ref(), |ctes| {
+        let mut update = UpdateStatement {
+            table_name,
+            assignments: statement
+                .target_list
+                .iter()
+                .map(normalize_assignment)
+                .collect::<Result<Vec<_>>>()?,
+            filter: statement
+                .where_clause
+                .as_deref()
+                .map(normalize_expr_node)
+                .transpose()?,
+            returning: statement
+                .returning_list
+                .iter()
+                .map(normalize_select_item)
+                .collect::<Result<Vec<_>>>()?,
+        };
+        update
+            .assignments
+            .iter_mut()
+            .for_each(|assignment| ctes.attach_to_expr(&mut assignment.expr));
+        if let Some(filter) = &mut update.filter {
+            ctes.attach_to_expr(filter);
+        }
+        update
+            .returning
+            .iter_mut()
+            .for_each(|item| ctes.attach_to_select_item(item));
+        Ok(update)
     })
 }
 
@@ -444,23 +773,34 @@ fn normalize_delete(statement: &protobuf::DeleteStmt) -> Result<DeleteStatement>
             "DELETE ... USING is not supported in DecentDB 1.0",
         ));
     }
-    Ok(DeleteStatement {
-        table_name: normalize_range_var(
-            statement
-                .relation
-                .as_ref()
-                .ok_or_else(|| unsupported("DELETE is missing a target table"))?,
-        )?,
-        filter: statement
-            .where_clause
-            .as_deref()
-            .map(normalize_expr_node)
-            .transpose()?,
-        returning: statement
-            .returning_list
-            .iter()
-            .map(normalize_select_item)
-            .collect::<Result<Vec<_>>>()?,
+    let table_name = normalize_range_var(
+        statement
+            .relation
+            .as_ref()
+            .ok_or_else(|| unsupported("DELETE is missing a target table"))?,
+    )?;
+    StatementCtes::scoped(statement.with_clause.as_ref(), |ctes| {
+        let mut delete = DeleteStatement {
+            table_name,
+            filter: statement
+                .where_clause
+                .as_deref()
+                .map(normalize_expr_node)
+                .transpose()?,
+            returning: statement
+                .returning_list
+                .iter()
+                .map(normalize_select_item)
+                .collect::<Result<Vec<_>>>()?,
+        };
+        if let Some(filter) = &mut delete.filter {
+            ctes.attach_to_expr(filter);
+        }
+        delete
+            .returning
+            .iter_mut()
+            .for_each(|item| ctes.attach_to_select_item(item));
+        Ok(delete)
     })
 }
 
@@ -472,7 +812,7 @@ fn normalize_create_table(
         .relation
         .as_ref()
         .ok_or_else(|| unsupported("CREATE TABLE is missing a relation name"))?;
-    let table_name = normalize_range_var(relation)?;
+    let table_name = normalize_new_range_var(relation)?;
     let mut columns = Vec::new();
     let mut constraints = Vec::new();
     let mut generated_mode_index = 0_usize;
@@ -507,15 +847,142 @@ fn normalize_create_table(
             }
         }
     }
+    let partition_of = normalize_partition_of(statement)?;
+    if partition_of.is_some() && !statement.table_elts.is_empty() {
+        return Err(unsupported(
+            "CREATE TABLE ... PARTITION OF takes its columns from the partitioned table",
+        ));
+    }
+    let partition_by = statement
+        .partspec
+        .as_ref()
+        .map(normalize_partition_spec)
+        .transpose()?;
+    if partition_by.is_some() && partition_of.is_some() {
+        return Err(unsupported("sub-partitioning is not supported"));
+    }
     Ok(CreateTableStatement {
         table_name,
         temporary: relation.relpersistence == "t",
         if_not_exists: statement.if_not_exists,
         columns,
         constraints,
+        ttl_column: normalize_ttl_column_option(&statement.options)?,
+        partition_by,
+        partition_of,
     })
 }
 
+fn normalize_partition_spec(spec: &protobuf::PartitionSpec) -> Result<PartitionBySpec> {
+    let strategy = match protobuf::PartitionStrategy::try_from(spec.strategy)
+        .unwrap_or(protobuf::PartitionStrategy::Undefined)
+    {
+        protobuf::PartitionStrategy::Range => PartitionStrategy::Range,
+        protobuf::PartitionStrategy::Hash => PartitionStrategy::Hash,
+        _ => {
+            return Err(unsupported(
+                "only PARTITION BY RANGE and PARTITION BY HASH are supported",
+            ))
+        }
+    };
+    let [param] = spec.part_params.as_slice() else {
+        return Err(unsupported("PARTITION BY must name exactly one column"));
+    };
+    match node_kind(param)? {
+        NodeEnum::PartitionElem(element) if !element.name.is_empty() => Ok(PartitionBySpec {
+            strategy,
+            column: element.name.clone(),
+        }),
+        _ => Err(unsupported("PARTITION BY expressions are not supported")),
+    }
+}
+
+fn normalize_partition_of(statement: &protobuf::CreateStmt) -> Result<Option<PartitionOfSpec>> {
+    let Some(bound) = statement.partbound.as_ref() else {
+        return Ok(None);
+    };
+    let parent = match statement.inh_relations.as_slice() {
+        [node] => match node_kind(node)? {
+            NodeEnum::RangeVar(range) => normalize_range_var(range)?,
+            other => {
+                return Err(unsupported(format!(
+                    "PARTITION OF expects a table name, got {}",
+                    describe_node(other)
+                )))
+            }
+        },
+        _ => return Err(unsupported("PARTITION OF must name one partitioned table")),
+    };
+    if bound.is_default {
+        return Err(unsupported("DEFAULT partitions are not supported"));
+    }
+    let bound = match bound.strategy.as_str() {
+        "r" => PartitionBoundSpec::Range {
+            from: normalize_range_partition_datum(&bound.lowerdatums)?,
+            to: normalize_range_partition_datum(&bound.upperdatums)?,
+        },
+        "h" => PartitionBoundSpec::Hash {
+            modulus: u32::try_from(bound.modulus)
+                .map_err(|_| unsupported("partition MODULUS must be positive"))?,
+            remainder: u32::try_from(bound.remainder)
+                .map_err(|_| unsupported("partition REMAINDER must not be negative"))?,
+        },
+        _ => {
+            return Err(unsupported(
+                "FOR VALUES IN (...) partitions are not supported",
+            ))
+        }
+    };
+    Ok(Some(PartitionOfSpec { parent, bound }))
+}
+
+/// Normalizes one side of `FOR VALUES FROM (...) TO (...)`. `MINVALUE` and
+/// `MAXVALUE` arrive as bare column references and map to `None`.
+fn normalize_range_partition_datum(datums: &[protobuf::Node]) -> Result<Option<Expr>> {
+    let [datum] = datums else {
+        return Err(unsupported(
+            "range partition bounds must hold exactly one value",
+        ));
+    };
+    if let NodeEnum::ColumnRef(column) = node_kind(datum)? {
+        if let [field] = column.fields.as_slice() {
+            let name = normalize_string_node(field)?;
+            if name.eq_ignore_ascii_case("minvalue") || name.eq_ignore_ascii_case("maxvalue") {
+                return Ok(None);
+            }
+        }
+    }
+    normalize_expr_node(datum).map(Some)
+}
+
+/// Extracts `ttl_column` from `CREATE TABLE ... WITH (...)`. Other storage
+/// options are accepted and ignored, as before.
+fn normalize_ttl_column_option(nodes: &[protobuf::Node]) -> Result<Option<String>> {
+    let mut ttl_column = None;
+    for node in nodes {
+        let NodeEnum::DefElem(def) = node_kind(node)? else {
+            continue;
+        };
+        if def.defname.eq_ignore_ascii_case("compression") {
+            return Err(unsupported(
+                "the compression table option is not supported; TEXT and BLOB payloads are compressed automatically",
+            ));
+        }
+        if !def.defname.eq_ignore_ascii_case("ttl_column") {
+            continue;
+        }
+        let column = match def.arg.as_deref().map(node_kind).transpose()? {
+            Some(NodeEnum::String(value)) => value.sval.clone(),
+            Some(NodeEnum::TypeName(type_name)) if type_name.names.len() == 1 => {
+                normalize_string_node(&type_name.names[0])?
+            }
+            _ => return Err(unsupported("ttl_column option must name a column")),
+        };
+        ttl_column = Some(column);
+    }
+    Ok(ttl_column)
+}
+
 fn normalize_create_table_as(
     statement: &protobuf::CreateTableAsStmt,
 ) -> Result<CreateTableAsStatement> {
@@ -554,7 +1021,7 @@ fn normalize_create_table_as(
         .rel
         .as_ref()
         .ok_or_else(|| unsupported("CREATE TABLE AS is missing target relation"))?;
-    let table_name = normalize_range_var(relation)?;
+    let table_name = normalize_new_range_var(relation)?;
     let temporary = relation.relpersistence == "t";
     let column_names = into
         .col_names
@@ -579,14 +1046,17 @@ fn normalize_column_definition(
     column: &protobuf::ColumnDef,
     generated_stored: bool,
 ) -> Result<ColumnDefinition> {
-    if let Some(collation) = &column.coll_clause {
-        let collation = normalize_collation_name(&collation.collname)?;
-        if collation != Collation::Binary {
-            return Err(unsupported(
-                "persistent column collations other than BINARY are not supported in this compatibility slice",
-            ));
-        }
+    if !column.compression.is_empty() {
+        return Err(unsupported(format!(
+            "COMPRESSION {} on column {} is not supported; TEXT and BLOB payloads are compressed automatically",
+            column.compression, column.colname
+        )));
     }
+    let collation = column
+        .coll_clause
+        .as_ref()
+        .map(|collation| normalize_persistent_collation(&collation.collname))
+        .transpose()?;
 
     let mut primary_key = false;
     let mut unique = false;
@@ -665,6 +1135,7 @@ fn normalize_column_definition(
         column_type,
         spatial_type,
         enum_type,
+        collation,
         nullable: !not_null && !primary_key,
         default,
         generated,
@@ -770,17 +1241,33 @@ fn normalize_create_index(statement: &protobuf::IndexStmt) -> Result<CreateIndex
             .iter()
             .map(|node| match node_kind(node)? {
                 NodeEnum::IndexElem(index) if !index.name.is_empty() => {
-                    normalize_index_collation(index)?;
-                    Ok(IndexExpression::Column(index.name.clone()))
+                    match normalize_index_collation(index)? {
+                        Some(collation) => Ok(IndexExpression::Expr(Expr::Collate {
+                            expr: Box::new(Expr::Column {
+                                table: None,
+                                column: index.name.clone(),
+                            }),
+                            collation,
+                        })),
+                        None => Ok(IndexExpression::Column(index.name.clone())),
+                    }
                 }
                 NodeEnum::IndexElem(index) if index.expr.is_some() => {
-                    normalize_index_collation(index)?;
-                    Ok(IndexExpression::Expr(normalize_expr_node(
+                    let expr = normalize_expr_node(
                         index
                             .expr
                             .as_deref()
                             .ok_or_else(|| unsupported("index expression is missing its AST"))?,
-                    )?))
+                    )?;
+                    Ok(IndexExpression::Expr(
+                        match normalize_index_collation(index)? {
+                            Some(collation) => Expr::Collate {
+                                expr: Box::new(expr),
+                                collation,
+                            },
+                            None => expr,
+                        },
+                    ))
                 }
                 _ => Err(unsupported("unsupported index key expression")),
             })
@@ -845,17 +1332,24 @@ fn normalize_index_option_value(node: &protobuf::Node) -> Result<Value> {
     }
 }
 
-fn normalize_index_collation(index: &protobuf::IndexElem) -> Result<()> {
+/// Returns the collation written on an index key. An explicit `COLLATE
+/// BINARY` is kept so the key does not inherit the column's collation.
+fn normalize_index_collation(index: &protobuf::IndexElem) -> Result<Option<Collation>> {
     if index.collation.is_empty() {
-        return Ok(());
+        return Ok(None);
     }
-    let collation = normalize_collation_name(&index.collation)?;
-    if collation == Collation::Binary {
-        Ok(())
-    } else {
-        Err(unsupported(
-            "persistent index collations other than BINARY are not supported in this compatibility slice",
-        ))
+    normalize_persistent_collation(&index.collation).map(Some)
+}
+
+/// Collations a column or index key can store. Extension collations are
+/// only registered for the session that loads them, so they cannot be
+/// persisted.
+fn normalize_persistent_collation(nodes: &[protobuf::Node]) -> Result<Collation> {
+    match normalize_collation_name(nodes)? {
+        Collation::Extension(name) => Err(unsupported(format!(
+            "collation {name} cannot be stored on a column or index; use BINARY, NOCASE, or RTRIM"
+        ))),
+        collation => Ok(collation),
     }
 }
 
@@ -877,7 +1371,7 @@ fn normalize_create_view(
     let if_not_exists = take_pending_view_if_not_exists();
 
     Ok(CreateViewStatement {
-        view_name: normalize_range_var(view)?,
+        view_name: normalize_new_range_var(view)?,
         temporary: view.relpersistence == "t",
         replace: statement.replace,
         if_not_exists,
@@ -959,15 +1453,15 @@ fn normalize_drop(statement: &protobuf::DropStmt) -> Result<Statement> {
         .unwrap_or(protobuf::ObjectType::Undefined);
     match object_type {
         protobuf::ObjectType::ObjectTable => Ok(Statement::DropTable {
-            name: join_name_parts(&name_parts),
+            name: join_relation_name_parts(&name_parts),
             if_exists: statement.missing_ok,
         }),
         protobuf::ObjectType::ObjectIndex => Ok(Statement::DropIndex {
-            name: join_name_parts(&name_parts),
+            name: join_relation_name_parts(&name_parts),
             if_exists: statement.missing_ok,
         }),
         protobuf::ObjectType::ObjectView => Ok(Statement::DropView {
-            name: join_name_parts(&name_parts),
+            name: join_relation_name_parts(&name_parts),
             if_exists: statement.missing_ok,
         }),
         protobuf::ObjectType::ObjectTrigger => {
@@ -981,7 +1475,7 @@ fn normalize_drop(statement: &protobuf::DropStmt) -> Result<Statement> {
                     .last()
                     .cloned()
                     .ok_or_else(|| unsupported("DROP TRIGGER is missing the trigger name"))?,
-                table_name: join_name_parts(&name_parts[..name_parts.len() - 1]),
+                table_name: join_relation_name_parts(&name_parts[..name_parts.len() - 1]),
                 if_exists: statement.missing_ok,
             })
         }
@@ -992,6 +1486,45 @@ fn normalize_drop(statement: &protobuf::DropStmt) -> Result<Statement> {
     }
 }
 
+fn normalize_comment(statement: &protobuf::CommentStmt) -> Result<Statement> {
+    let name_parts = normalize_object_name_list(
+        statement
+            .object
+            .as_deref()
+            .ok_or_else(|| unsupported("COMMENT ON is missing its target"))?,
+    )?;
+    // PostgreSQL treats an empty comment like NULL: both remove the comment.
+    let comment = (!statement.comment.is_empty()).then(|| statement.comment.clone());
+    let object_type = protobuf::ObjectType::try_from(statement.objtype)
+        .unwrap_or(protobuf::ObjectType::Undefined);
+    match object_type {
+        protobuf::ObjectType::ObjectTable => Ok(Statement::CommentOn {
+            table_name: join_relation_name_parts(&name_parts),
+            column_name: None,
+            comment,
+        }),
+        protobuf::ObjectType::ObjectColumn => {
+            let Some((column_name, table_parts)) = name_parts.split_last() else {
+                return Err(unsupported("COMMENT ON COLUMN is missing the column name"));
+            };
+            if table_parts.is_empty() {
+                return Err(unsupported(
+                    "COMMENT ON COLUMN must name the column as table.column",
+                ));
+            }
+            Ok(Statement::CommentOn {
+                table_name: join_relation_name_parts(table_parts),
+                column_name: Some(column_name.clone()),
+                comment,
+            })
+        }
+        other => Err(unsupported(format!(
+            "COMMENT ON {} is not supported in DecentDB 1.0",
+            other.as_str_name()
+        ))),
+    }
+}
+
 fn normalize_create_schema(statement: &protobuf::CreateSchemaStmt) -> Result<Statement> {
     if statement.schemaname.is_empty() {
         return Err(unsupported("CREATE SCHEMA is missing schema name"));
@@ -1100,6 +1633,7 @@ fn normalize_alter_table_command(command: &protobuf::AlterTableCmd) -> Result<Al
                 NodeEnum::TypeName(type_name) => Ok(AlterTableAction::AlterColumnType {
                     column_name: command.name.clone(),
                     new_type: normalize_type_name(type_name)?,
+                    using: None,
                 }),
                 NodeEnum::ColumnDef(column) => {
                     Ok(AlterTableAction::AlterColumnType {
@@ -1107,6 +1641,12 @@ fn normalize_alter_table_command(command: &protobuf::AlterTableCmd) -> Result<Al
                         new_type: normalize_type_name(column.type_name.as_ref().ok_or_else(
                             || unsupported("ALTER COLUMN TYPE is missing its type"),
                         )?)?,
+                        // PostgreSQL carries the USING expression in raw_default.
+                        using: column
+                            .raw_default
+                            .as_deref()
+                            .map(normalize_expr_node)
+                            .transpose()?,
                     })
                 }
                 _ => Err(unsupported("unsupported ALTER TABLE type specification")),
@@ -1129,6 +1669,28 @@ fn normalize_alter_table_command(command: &protobuf::AlterTableCmd) -> Result<Al
         protobuf::AlterTableType::AtDropConstraint => Ok(AlterTableAction::DropConstraint {
             constraint_name: command.name.clone(),
         }),
+        protobuf::AlterTableType::AtDetachPartition => {
+            let definition = command.def.as_deref().ok_or_else(|| {
+                unsupported("ALTER TABLE DETACH PARTITION is missing a partition")
+            })?;
+            match node_kind(definition)? {
+                NodeEnum::PartitionCmd(command) => {
+                    if command.concurrent {
+                        return Err(unsupported(
+                            "DETACH PARTITION CONCURRENTLY is not supported",
+                        ));
+                    }
+                    Ok(AlterTableAction::DetachPartition {
+                        partition_name: normalize_range_var(command.name.as_ref().ok_or_else(
+                            || unsupported("ALTER TABLE DETACH PARTITION is missing a partition"),
+                        )?)?,
+                    })
+                }
+                _ => Err(unsupported(
+                    "unsupported ALTER TABLE DETACH PARTITION definition",
+                )),
+            }
+        }
         other => Err(unsupported(format!(
             "ALTER TABLE action {} is not supported",
             other.as_str_name()
@@ -1229,10 +1791,14 @@ fn normalize_select_item(node: &protobuf::Node) -> Result<SelectItem> {
 
 fn normalize_from_item(node: &protobuf::Node) -> Result<FromItem> {
     match node_kind(node)? {
-        NodeEnum::RangeVar(range) => Ok(FromItem::Table {
-            name: normalize_range_var(range)?,
-            alias: range.alias.as_ref().map(|alias| alias.aliasname.clone()),
-        }),
+        NodeEnum::RangeVar(range) => {
+            let name = normalize_range_var(range)?;
+            let alias = match range.alias.as_ref() {
+                Some(alias) => Some(alias.aliasname.clone()),
+                None => default_range_alias(range, &name),
+            };
+            Ok(FromItem::Table { name, alias })
+        }
         NodeEnum::RangeSubselect(range) => Ok(FromItem::Subquery {
             query: Box::new(normalize_query(as_select_stmt(
                 range
@@ -1251,6 +1817,7 @@ fn normalize_from_item(node: &protobuf::Node) -> Result<FromItem> {
             lateral: range.lateral,
         }),
         NodeEnum::RangeFunction(range) => normalize_range_function(range),
+        NodeEnum::RangeTableSample(sample) => normalize_range_table_sample(sample),
         NodeEnum::JoinExpr(join) => Ok(FromItem::Join {
             left: Box::new(normalize_from_item(
                 join.larg
@@ -1349,6 +1916,40 @@ fn normalize_range_function(range: &protobuf::RangeFunction) -> Result<FromItem>
     })
 }
 
+fn normalize_range_table_sample(sample: &protobuf::RangeTableSample) -> Result<FromItem> {
+    let source = sample
+        .relation
+        .as_deref()
+        .ok_or_else(|| unsupported("TABLESAMPLE is missing its table"))?;
+    let method = match normalize_qualified_name(&sample.method)?
+        .to_ascii_lowercase()
+        .as_str()
+    {
+        "bernoulli" => SampleMethod::Bernoulli,
+        "system" => SampleMethod::System,
+        other => {
+            return Err(unsupported(format!(
+                "tablesample method {other} does not exist (expected BERNOULLI or SYSTEM)"
+            )))
+        }
+    };
+    let [percent] = sample.args.as_slice() else {
+        return Err(unsupported(
+            "TABLESAMPLE expects exactly one argument, the sample percentage",
+        ));
+    };
+    Ok(FromItem::Sample {
+        source: Box::new(normalize_from_item(source)?),
+        method,
+        percent: normalize_expr_node(percent)?,
+        seed: sample
+            .repeatable
+            .as_deref()
+            .map(normalize_expr_node)
+            .transpose()?,
+    })
+}
+
 fn normalize_assignment(node: &protobuf::Node) -> Result<Assignment> {
     match node_kind(node)? {
         NodeEnum::ResTarget(target) => Ok(Assignment {
@@ -1461,6 +2062,23 @@ fn normalize_expr_node(node: &protobuf::Node) -> Result<Expr> {
                 .collect::<Result<Vec<_>>>()?,
         }),
         NodeEnum::SubLink(link) => normalize_sublink(link),
+        NodeEnum::GroupingFunc(func) => {
+            if func.args.len() > 31 {
+                return Err(unsupported("GROUPING accepts at most 31 arguments"));
+            }
+            Ok(Expr::Aggregate {
+                name: "grouping".to_string(),
+                args: func
+                    .args
+                    .iter()
+                    .map(normalize_expr_container)
+                    .collect::<Result<Vec<_>>>()?,
+                distinct: false,
+                star: false,
+                order_by: Vec::new(),
+                within_group: false,
+            })
+        }
         NodeEnum::MinMaxExpr(expr) => Ok(Expr::Function {
             name: match protobuf::MinMaxOp::try_from(expr.op)
                 .unwrap_or(protobuf::MinMaxOp::Undefined)
@@ -2324,12 +2942,40 @@ fn normalize_range_var(range: &protobuf::RangeVar) -> Result<String> {
             "main" => Ok(format!("main.{}", range.relname)),
             "temp" | "information_schema" => Ok(format!("{schema}.{}", range.relname)),
             "sys" => Ok(format!("sys.{}", range.relname)),
-            other => Err(unsupported(format!(
-                "schema-qualified objects outside main/temp are not supported yet; schema '{other}' is registered but object ownership by schema is advanced compatibility work"
-            ))),
+            // Objects owned by application schemas are stored as
+            // `schema.name`; the executor checks that the schema exists.
+            _ => Ok(format!("{}.{}", range.schemaname, range.relname)),
         };
     }
-    Ok(range.relname.clone())
+    Ok(search_path::resolve_relation(&range.relname).unwrap_or_else(|| range.relname.clone()))
+}
+
+/// Normalizes the name of a relation a statement creates. Unqualified
+/// persistent relations are created in the first schema on the session
+/// search path.
+fn normalize_new_range_var(range: &protobuf::RangeVar) -> Result<String> {
+    if range.schemaname.is_empty() && range.relpersistence != "t" && !range.relname.is_empty() {
+        if let Some(name) = search_path::resolve_new_relation(&range.relname) {
+            return Ok(name);
+        }
+    }
+    normalize_range_var(range)
+}
+
+/// Returns the alias a FROM item gets when none is written: references to
+/// application-schema relations keep their bare name as the qualifier, so
+/// `orders.id` binds against `tenant.orders`.
+fn default_range_alias(range: &protobuf::RangeVar, name: &str) -> Option<String> {
+    if name == range.relname {
+        return None;
+    }
+    let schema = name
+        .strip_suffix(range.relname.as_str())?
+        .strip_suffix('.')?;
+    match schema.to_ascii_lowercase().as_str() {
+        "main" | "temp" | "information_schema" | "sys" => None,
+        _ => Some(range.relname.clone()),
+    }
 }
 
 fn normalize_type_name(type_name: &protobuf::TypeName) -> Result<ColumnType> {
@@ -2578,6 +3224,15 @@ fn normalize_qualified_name(nodes: &[protobuf::Node]) -> Result<String> {
     Ok(join_name_parts(&parts))
 }
 
+/// Joins a relation name, resolving an unqualified one through the search
+/// path.
+fn join_relation_name_parts(parts: &[String]) -> String {
+    match parts {
+        [name] => search_path::resolve_relation(name).unwrap_or_else(|| name.clone()),
+        _ => join_name_parts(parts),
+    }
+}
+
 fn normalize_object_name_list(node: &protobuf::Node) -> Result<Vec<String>> {
     match node_kind(node)? {
         NodeEnum::List(list) => list.items.iter().map(normalize_string_node).collect(),
@@ -2679,6 +3334,7 @@ fn describe_node(node: &NodeEnum) -> &'static str {
         NodeEnum::CreateTrigStmt(_) => "CreateTrigStmt",
         NodeEnum::CreateSchemaStmt(_) => "CreateSchemaStmt",
         NodeEnum::ExplainStmt(_) => "ExplainStmt",
+        NodeEnum::CommentStmt(_) => "CommentStmt",
         NodeEnum::AExpr(_) => "AExpr",
         NodeEnum::BoolExpr(_) => "BoolExpr",
         NodeEnum::FuncCall(_) => "FuncCall",
@@ -2693,6 +3349,7 @@ fn describe_node(node: &NodeEnum) -> &'static str {
         NodeEnum::IndexElem(_) => "IndexElem",
         NodeEnum::CommonTableExpr(_) => "CommonTableExpr",
         NodeEnum::RangeSubselect(_) => "RangeSubselect",
+        NodeEnum::RangeTableSample(_) => "RangeTableSample",
         NodeEnum::TypeName(_) => "TypeName",
         NodeEnum::TypeCast(_) => "TypeCast",
         NodeEnum::CaseExpr(_) => "CaseExpr",
@@ -2735,6 +3392,101 @@ mod tests {
         }
     }
 
+    #[test]
+    fn create_table_ttl_column_option() {
+        let Statement::CreateTable(ct) = norm(
+            "CREATE TABLE sessions (id TEXT PRIMARY KEY, expires_at TIMESTAMP) \
+             WITH (fillfactor = 90, ttl_column = expires_at)",
+        ) else {
+            panic!("expected CreateTable");
+        };
+        assert_eq!(ct.ttl_column.as_deref(), Some("expires_at"));
+
+        let Statement::CreateTable(ct) = norm("CREATE TABLE t (id INT PRIMARY KEY)") else {
+            panic!("expected CreateTable");
+        };
+        assert_eq!(ct.ttl_column, None);
+
+        let err =
+            norm_err("CREATE TABLE t (id INT PRIMARY KEY, e TIMESTAMP) WITH (ttl_column = 5)");
+        assert!(err.contains("ttl_column"), "{err}");
+    }
+
+    #[test]
+    fn create_table_rejects_compression_settings() {
+        let err = norm_err("CREATE TABLE docs (id INT PRIMARY KEY, body TEXT COMPRESSION lz4)");
+        assert!(err.contains("COMPRESSION lz4 on column body"), "{err}");
+        let err =
+            norm_err("CREATE TABLE docs (id INT PRIMARY KEY, body TEXT) WITH (compression = zstd)");
+        assert!(err.contains("compression table option"), "{err}");
+        let err = norm_err("ALTER TABLE docs ALTER COLUMN body SET COMPRESSION zstd");
+        assert!(err.contains("not supported"), "{err}");
+    }
+
+    #[test]
+    fn create_table_partition_clauses() {
+        let Statement::CreateTable(ct) = norm(
+            "CREATE TABLE events (id INT, created_at TIMESTAMP) PARTITION BY RANGE (created_at)",
+        ) else {
+            panic!("expected CreateTable");
+        };
+        assert_eq!(
+            ct.partition_by,
+            Some(PartitionBySpec {
+                strategy: PartitionStrategy::Range,
+                column: "created_at".to_string(),
+            })
+        );
+
+        let Statement::CreateTable(ct) = norm(
+            "CREATE TABLE events_old PARTITION OF events FOR VALUES FROM (MINVALUE) TO ('2024-01-01')",
+        ) else {
+            panic!("expected CreateTable");
+        };
+        let partition_of = ct.partition_of.expect("partition_of");
+        assert_eq!(partition_of.parent, "events");
+        assert!(matches!(
+            partition_of.bound,
+            PartitionBoundSpec::Range {
+                from: None,
+                to: Some(_)
+            }
+        ));
+
+        let Statement::CreateTable(ct) = norm(
+            "CREATE TABLE users_p1 PARTITION OF users FOR VALUES WITH (MODULUS 4, REMAINDER 1)",
+        ) else {
+            panic!("expected CreateTable");
+        };
+        assert_eq!(
+            ct.partition_of.expect("partition_of").bound,
+            PartitionBoundSpec::Hash {
+                modulus: 4,
+                remainder: 1
+            }
+        );
+
+        let err = norm_err("CREATE TABLE t (id INT, k TEXT) PARTITION BY LIST (k)");
+        assert!(err.contains("PARTITION BY"), "{err}");
+        let err = norm_err("CREATE TABLE t_default PARTITION OF t DEFAULT");
+        assert!(err.contains("DEFAULT"), "{err}");
+    }
+
+    #[test]
+    fn alter_table_detach_partition() {
+        let Statement::AlterTable { actions, .. } =
+            norm("ALTER TABLE events DETACH PARTITION events_old")
+        else {
+            panic!("expected AlterTable");
+        };
+        assert_eq!(
+            actions,
+            [AlterTableAction::DetachPartition {
+                partition_name: "events_old".to_string()
+            }]
+        );
+    }
+
     #[test]
     fn type_real() {
         if let Statement::CreateTable(ct) = norm("CREATE TABLE t (id INT PRIMARY KEY, a REAL)") {
@@ -3633,6 +4385,32 @@ mod tests {
         }
     }
 
+    #[test]
+    fn from_tablesample() {
+        if let Statement::Query(q) =
+            norm("SELECT * FROM t AS s TABLESAMPLE BERNOULLI (10) REPEATABLE (42)")
+        {
+            if let QueryBody::Select(s) = q.body {
+                let FromItem::Sample {
+                    source,
+                    method,
+                    seed,
+                    ..
+                } = &s.from[0]
+                else {
+                    panic!("expected Sample");
+                };
+                assert!(
+                    matches!(&**source, FromItem::Table { alias: Some(alias), .. } if alias == "s")
+                );
+                assert_eq!(*method, SampleMethod::Bernoulli);
+                assert_eq!(seed, &Some(Expr::Literal(Value::Int64(42))));
+            }
+        } else {
+            panic!("expected Query");
+        }
+    }
+
     // ── normalize_select_item paths ────────────────────────────────
 
     #[test]
@@ -4142,6 +4920,29 @@ mod tests {
         ));
     }
 
+    // ── normalize_comment paths ────────────────────────────────────
+
+    #[test]
+    fn comment_on_table_and_column() {
+        assert_eq!(
+            norm("COMMENT ON TABLE t IS 'accounts'"),
+            Statement::CommentOn {
+                table_name: "t".to_string(),
+                column_name: None,
+                comment: Some("accounts".to_string()),
+            }
+        );
+        assert_eq!(
+            norm("COMMENT ON COLUMN t.email IS NULL"),
+            Statement::CommentOn {
+                table_name: "t".to_string(),
+                column_name: Some("email".to_string()),
+                comment: None,
+            }
+        );
+        assert!(norm_err("COMMENT ON INDEX idx IS 'x'").contains("not supported"));
+    }
+
     // ── normalize_rename paths ─────────────────────────────────────
 
     #[test]
@@ -4213,6 +5014,23 @@ mod tests {
         }
     }
 
+    #[test]
+    fn alter_table_alter_column_type_using() {
+        let Statement::AlterTable { actions, .. } =
+            norm("ALTER TABLE t ALTER COLUMN x TYPE INT64 USING x * 100")
+        else {
+            panic!("expected AlterTable");
+        };
+        assert!(matches!(
+            &actions[0],
+            AlterTableAction::AlterColumnType {
+                new_type: ColumnType::Int64,
+                using: Some(Expr::Binary { .. }),
+                ..
+            }
+        ));
+    }
+
     #[test]
     fn alter_table_add_constraint() {
         if let Statement::AlterTable { actions, .. } =
@@ -4288,6 +5106,22 @@ mod tests {
         }
     }
 
+    #[test]
+    fn with_clause_leading_delete_is_attached_to_its_subqueries() {
+        let Statement::Delete(statement) = norm(
+            "WITH RECURSIVE t(id) AS (SELECT 1 UNION SELECT id + 1 FROM t WHERE id < 3) \
+             DELETE FROM items WHERE id IN (SELECT id FROM t)",
+        ) else {
+            panic!("expected Delete");
+        };
+        let Some(Expr::InSubquery { query, .. }) = statement.filter else {
+            panic!("expected IN subquery");
+        };
+        assert!(query.recursive);
+        assert_eq!(query.ctes.len(), 1);
+        assert_eq!(query.ctes[0].name, "t");
+    }
+
     #[test]
     fn create_table_as_normalizes() {
         if let Statement::CreateTableAs(statement) =
@@ -231,4 +231,42 @@ mod tests {
         let s8 = "ALTER TABLE t RENAME COLUMN a TO b";
         let _ = normalize_statement_text(s8);
     }
+
+    #[test]
+    fn grouping_sets_rollup_and_cube_expand_to_index_sets() {
+        let grouping = |sql: &str| match normalize_statement_text(sql).expect("parsed") {
+            Statement::Query(q) => match q.body {
+                QueryBody::Select(sel) => (sel.group_by.len(), sel.grouping_sets),
+                other => panic!("unexpected: {:?}", other),
+            },
+            other => panic!("unexpected: {:?}", other),
+        };
+
+        assert_eq!(
+            grouping("SELECT a, b, COUNT(*) FROM t GROUP BY a, b"),
+            (2, Vec::<Vec<usize>>::new())
+        );
+        assert_eq!(
+            grouping("SELECT a, b, COUNT(*) FROM t GROUP BY ROLLUP (a, b)"),
+            (2, vec![vec![0, 1], vec![0], vec![]])
+        );
+        assert_eq!(
+            grouping("SELECT a, b, COUNT(*) FROM t GROUP BY CUBE (a, b)"),
+            (2, vec![vec![0, 1], vec![0], vec![1], vec![]])
+        );
+        assert_eq!(
+            grouping("SELECT a, b, COUNT(*) FROM t GROUP BY GROUPING SETS ((a, b), (b), ())"),
+            (2, vec![vec![0, 1], vec![1], vec![]])
+        );
+        // Plain items cross with every set of a ROLLUP, and repeats share an index.
+        assert_eq!(
+            grouping("SELECT a, b, c, COUNT(*) FROM t GROUP BY a, ROLLUP (b, (a, c))"),
+            (3, vec![vec![0, 1, 2], vec![0, 1], vec![0]])
+        );
+
+        assert!(normalize_statement_text(
+            "SELECT COUNT(*) FROM t GROUP BY CUBE (a, b, c, d, e, f, g, h, i, j, k, l, m)"
+        )
+        .is_err());
+    }
 }
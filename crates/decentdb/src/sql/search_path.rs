@@ -0,0 +1,325 @@
+//! Session `search_path` support.
+//!
+//! A connection's search path lists the schemas that unqualified relation
+//! names are looked up in. Resolution happens while a statement is
+//! normalized: [`with_search_path`] installs the path for the current
+//! thread, and the normalizer asks [`resolve_relation`] and
+//! [`resolve_new_relation`] for the stored `schema.name` form of each
+//! unqualified name. Names that no schema on the path holds are left
+//! unqualified, so they fall back to the default (main) schema.
+
+use std::cell::RefCell;
+use std::collections::BTreeSet;
+
+use crate::error::{DbError, Result};
+
+thread_local! {
+    static ACTIVE_SEARCH_PATH: RefCell<Option<ActiveSearchPath>> = const { RefCell::new(None) };
+}
+
+/// Returns true for the schema names that address the default namespace.
+pub(crate) fn is_default_schema(name: &str) -> bool {
+    name.eq_ignore_ascii_case("main") || name.eq_ignore_ascii_case("public")
+}
+
+/// A search path together with the relation names visible to the statement
+/// being normalized.
+#[derive(Clone, Debug, Default)]
+pub(crate) struct SearchPath {
+    schemas: Vec<String>,
+    /// Lowercased persistent table, view, and index names, in their stored
+    /// `schema.name` form for schema-owned objects.
+    relations: BTreeSet<String>,
+    /// Lowercased session-local temp table and view names.
+    temp_relations: BTreeSet<String>,
+}
+
+impl SearchPath {
+    pub(crate) fn new(
+        schemas: Vec<String>,
+        relations: impl IntoIterator<Item = String>,
+        temp_relations: impl IntoIterator<Item = String>,
+    ) -> Self {
+        Self {
+            schemas,
+            relations: relations
+                .into_iter()
+                .map(|name| name.to_ascii_lowercase())
+                .collect(),
+            temp_relations: temp_relations
+                .into_iter()
+                .map(|name| name.to_ascii_lowercase())
+                .collect(),
+        }
+    }
+
+    fn resolve(&self, name: &str) -> Option<String> {
+        let lower = name.to_ascii_lowercase();
+        if self.temp_relations.contains(&lower) {
+            return None;
+        }
+        for schema in &self.schemas {
+            if is_default_schema(schema) {
+                if self.relations.contains(&lower) {
+                    return None;
+                }
+            } else if self
+                .relations
+                .contains(&format!("{}.{lower}", schema.to_ascii_lowercase()))
+            {
+                return Some(format!("{schema}.{name}"));
+            }
+        }
+        None
+    }
+
+    fn creation_schema(&self) -> Option<&str> {
+        self.schemas
+            .first()
+            .filter(|schema| !is_default_schema(schema))
+            .map(String::as_str)
+    }
+}
+
+#[derive(Debug)]
+struct ActiveSearchPath {
+    path: SearchPath,
+    /// CTE names in scope; references to them are never qualified.
+    ctes: Vec<String>,
+}
+
+/// Runs `f` with `search_path` applied to every statement normalized on
+/// this thread.
+pub(crate) fn with_search_path<T>(search_path: SearchPath, f: impl FnOnce() -> T) -> T {
+    struct Restore(Option<ActiveSearchPath>);
+    impl Drop for Restore {
+        fn drop(&mut self) {
+            let previous = self.0.take();
+            ACTIVE_SEARCH_PATH.with(|slot| *slot.borrow_mut() = previous);
+        }
+    }
+    let _restore = Restore(ACTIVE_SEARCH_PATH.with(|slot| {
+        slot.replace(Some(ActiveSearchPath {
+            path: search_path,
+            ctes: Vec::new(),
+        }))
+    }));
+    f()
+}
+
+/// Runs `f` with `names` in scope as CTE names.
+pub(crate) fn with_cte_names<T>(
+    names: impl IntoIterator<Item = String>,
+    f: impl FnOnce() -> T,
+) -> T {
+    let depth = ACTIVE_SEARCH_PATH.with(|slot| {
+        slot.borrow_mut().as_mut().map(|active| {
+            let depth = active.ctes.len();
+            active.ctes.extend(names);
+            depth
+        })
+    });
+    let result = f();
+    if let Some(depth) = depth {
+        ACTIVE_SEARCH_PATH.with(|slot| {
+            if let Some(active) = slot.borrow_mut().as_mut() {
+                active.ctes.truncate(depth);
+            }
+        });
+    }
+    result
+}
+
+/// Returns the stored name an unqualified reference to `name` resolves to,
+/// or `None` when it stays unqualified.
+pub(crate) fn resolve_relation(name: &str) -> Option<String> {
+    ACTIVE_SEARCH_PATH.with(|slot| {
+        let slot = slot.borrow();
+        let active = slot.as_ref()?;
+        if active.ctes.iter().any(|cte| cte.eq_ignore_ascii_case(name)) {
+            return None;
+        }
+        active.path.resolve(name)
+    })
+}
+
+/// Returns the stored name for a new persistent relation called `name`,
+/// which is created in the first schema on the path.
+pub(crate) fn resolve_new_relation(name: &str) -> Option<String> {
+    ACTIVE_SEARCH_PATH.with(|slot| {
+        let slot = slot.borrow();
+        let schema = slot.as_ref()?.path.creation_schema()?;
+        Some(format!("{schema}.{name}"))
+    })
+}
+
+#[derive(Clone, Debug, PartialEq, Eq)]
+pub(crate) enum SearchPathCommand {
+    Set(Vec<String>),
+    Reset,
+    Show,
+}
+
+/// Parses `SET search_path`, `RESET search_path`, and `SHOW search_path`.
+pub(crate) fn parse_search_path_command(sql: &str) -> Result<Option<SearchPathCommand>> {
+    let body = sql.trim().trim_end_matches(';').trim_end();
+    let Some((verb, rest)) = split_keyword(body) else {
+        return Ok(None);
+    };
+    if verb.eq_ignore_ascii_case("SHOW") || verb.eq_ignore_ascii_case("RESET") {
+        let Some((name, rest)) = split_keyword(rest) else {
+            return Ok(None);
+        };
+        if !name.eq_ignore_ascii_case("search_path") || !rest.trim().is_empty() {
+            return Ok(None);
+        }
+        return Ok(Some(if verb.eq_ignore_ascii_case("SHOW") {
+            SearchPathCommand::Show
+        } else {
+            SearchPathCommand::Reset
+        }));
+    }
+    if !verb.eq_ignore_ascii_case("SET") {
+        return Ok(None);
+    }
+    let Some((mut name, mut rest)) = split_keyword(rest) else {
+        return Ok(None);
+    };
+    if name.eq_ignore_ascii_case("SESSION") {
+        let Some(next) = split_keyword(rest) else {
+            return Ok(None);
+        };
+        (name, rest) = next;
+    }
+    if !name.eq_ignore_ascii_case("search_path") {
+        return Ok(None);
+    }
+    let rest = rest.trim_start();
+    let values = if let Some(values) = rest.strip_prefix('=') {
+        values
+    } else {
+        match split_keyword(rest) {
+            Some((to, values)) if to.eq_ignore_ascii_case("TO") => values,
+            _ => return Err(DbError::sql("SET search_path requires TO or '='")),
+        }
+    };
+    parse_schema_list(values).map(Some)
+}
+
+fn parse_schema_list(values: &str) -> Result<SearchPathCommand> {
+    let values = values.trim();
+    if values.eq_ignore_ascii_case("DEFAULT") {
+        return Ok(SearchPathCommand::Reset);
+    }
+    let mut schemas = Vec::<String>::new();
+    for item in values.split(',') {
+        let item = item.trim();
+        let name = if let Some(quoted) = item
+            .strip_prefix('"')
+            .and_then(|item| item.strip_suffix('"'))
+        {
+            quoted.replace("\"\"", "\"")
+        } else if let Some(quoted) = item
+            .strip_prefix('\'')
+            .and_then(|item| item.strip_suffix('\''))
+        {
+            quoted.replace("''", "'")
+        } else if !item.is_empty()
+            && item
+                .chars()
+                .all(|ch| ch.is_ascii_alphanumeric() || ch == '_' || ch == '$')
+        {
+            item.to_ascii_lowercase()
+        } else {
+            return Err(DbError::sql(format!(
+                "invalid schema name in search_path: {item}"
+            )));
+        };
+        if name.is_empty() {
+            return Err(DbError::sql("search_path schema names must not be empty"));
+        }
+        // PostgreSQL's default path starts with the per-user schema, which
+        // DecentDB does not have.
+        if name == "$user" {
+            continue;
+        }
+        if !schemas
+            .iter()
+            .any(|schema| schema.eq_ignore_ascii_case(&name))
+        {
+            schemas.push(name);
+        }
+    }
+    if schemas.is_empty() {
+        return Err(DbError::sql("SET search_path requires at least one schema"));
+    }
+    Ok(SearchPathCommand::Set(schemas))
+}
+
+fn split_keyword(sql: &str) -> Option<(&str, &str)> {
+    let sql = sql.trim_start();
+    let end = sql
+        .find(|ch: char| !(ch.is_ascii_alphanumeric() || ch == '_'))
+        .unwrap_or(sql.len());
+    (end > 0).then(|| (&sql[..end], &sql[end..]))
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn parses_search_path_commands() {
+        assert_eq!(
+            parse_search_path_command("SET search_path TO tenant_42, public;").unwrap(),
+            Some(SearchPathCommand::Set(vec![
+                "tenant_42".to_string(),
+                "public".to_string()
+            ]))
+        );
+        assert_eq!(
+            parse_search_path_command("set session search_path = \"$user\", 'Tenant'").unwrap(),
+            Some(SearchPathCommand::Set(vec!["Tenant".to_string()]))
+        );
+        assert_eq!(
+            parse_search_path_command("SET search_path TO DEFAULT").unwrap(),
+            Some(SearchPathCommand::Reset)
+        );
+        assert_eq!(
+            parse_search_path_command("RESET search_path").unwrap(),
+            Some(SearchPathCommand::Reset)
+        );
+        assert_eq!(
+            parse_search_path_command("SHOW search_path").unwrap(),
+            Some(SearchPathCommand::Show)
+        );
+        assert_eq!(
+            parse_search_path_command("SET AUDIT CONTEXT k = 1").unwrap(),
+            None
+        );
+        assert!(parse_search_path_command("SET search_path tenant").is_err());
+        assert!(parse_search_path_command("SET search_path = a b").is_err());
+    }
+
+    #[test]
+    fn resolves_against_the_first_schema_holding_the_name() {
+        let path = SearchPath::new(
+            vec!["tenant".to_string(), "main".to_string()],
+            ["tenant.orders", "orders", "plans"].map(String::from),
+            ["scratch"].map(String::from),
+        );
+        with_search_path(path, || {
+            assert_eq!(resolve_relation("Orders").as_deref(), Some("tenant.Orders"));
+            assert_eq!(resolve_relation("plans"), None);
+            assert_eq!(resolve_relation("scratch"), None);
+            assert_eq!(resolve_relation("missing"), None);
+            with_cte_names(["orders".to_string()], || {
+                assert_eq!(resolve_relation("orders"), None);
+            });
+            assert!(resolve_relation("orders").is_some());
+            assert_eq!(resolve_new_relation("t").as_deref(), Some("tenant.t"));
+        });
+        assert_eq!(resolve_relation("orders"), None);
+        assert_eq!(resolve_new_relation("t"), None);
+    }
+}
@@ -227,6 +227,9 @@ fn parse_create_table(sql: &str) -> Result<CreateTableStatement> {
         if_not_exists,
         columns,
         constraints: Vec::new(),
+        ttl_column: None,
+        partition_by: None,
+        partition_of: None,
     })
 }
 
@@ -305,6 +308,7 @@ fn parse_column_definition(sql: &str) -> Result<ColumnDefinition> {
         column_type,
         spatial_type: None::<SpatialTypeInfo>,
         enum_type: None::<EnumTypeInfo>,
+        collation: None,
         nullable,
         default,
         generated,
@@ -646,6 +650,7 @@ fn parse_select(sql: &str) -> Result<Query> {
             from,
             filter,
             group_by: Vec::new(),
+            grouping_sets: Vec::new(),
             having: None,
             distinct: false,
             distinct_on: Vec::new(),
@@ -13,6 +13,8 @@ pub(crate) struct PageCache {
     capacity_pages: usize,
     page_size: usize,
     access_counter: AtomicU64,
+    hits: AtomicU64,
+    misses: AtomicU64,
     state: RwLock<PageCacheState>,
 }
 
@@ -47,6 +49,8 @@ impl PageCache {
             capacity_pages: capacity_pages.max(1),
             page_size,
             access_counter: AtomicU64::new(0),
+            hits: AtomicU64::new(0),
+            misses: AtomicU64::new(0),
             state: RwLock::new(PageCacheState::default()),
         }
     }
@@ -56,9 +60,11 @@ impl PageCache {
         F: FnOnce() -> Result<Vec<u8>>,
     {
         if let Some(handle) = self.try_pin_existing(page_id)? {
+            self.hits.fetch_add(1, Ordering::Relaxed);
             return Ok(handle);
         }
 
+        self.misses.fetch_add(1, Ordering::Relaxed);
         let mut loaded = loader()?;
         if loaded.len() != self.page_size {
             return Err(DbError::internal(format!(
@@ -130,6 +136,22 @@ impl PageCache {
         Ok(())
     }
 
+    /// Returns `(capacity_pages, resident_pages, hits, misses)`.
+    pub(crate) fn stats(&self) -> Result<(usize, usize, u64, u64)> {
+        let resident = self
+            .state
+            .read()
+            .map_err(|_| DbError::internal("page cache lock poisoned"))?
+            .pages
+            .len();
+        Ok((
+            self.capacity_pages,
+            resident,
+            self.hits.load(Ordering::Relaxed),
+            self.misses.load(Ordering::Relaxed),
+        ))
+    }
+
     pub(crate) fn clear(&self) -> Result<()> {
         let mut state = self
             .state
@@ -5,7 +5,9 @@ pub(crate) mod checksum;
 pub(crate) mod freelist;
 pub(crate) mod header;
 pub(crate) mod page;
+pub(crate) mod page_checksums;
 pub(crate) mod pager;
+pub(crate) mod read_ahead;
 
 pub use header::DB_FORMAT_VERSION;
 pub(crate) use header::{
@@ -0,0 +1,472 @@
+//! Per-page checksums for the main database file.
+//!
+//! Every page the pager writes to the main file records a CRC-32C of its
+//! contents in a sidecar next to the database (`mydb.ddb.pagesum`). With
+//! `DbConfig::verify_checksums` set, pages read back from disk are checked
+//! against their recorded checksum so bit rot surfaces as a corruption error
+//! naming the page instead of as wrong results.
+//!
+//! The sidecar is keyed by the database id in its header and is reset when
+//! that id does not match, so a file left behind by another database is
+//! never trusted. Pages written before the sidecar existed have no entry and
+//! are not verified until their next checkpoint rewrites them. The header
+//! page carries its own checksum and is skipped.
+//!
+//! Entries are not synced as they are written. Before the first entry of a
+//! checkpoint the header is marked as updating and synced, and every entry
+//! the checkpoint writes carries the next generation number. Once the
+//! checkpoint's pages are synced to the main file, the entries are synced
+//! and the header records the checkpoint LSN and generation and is marked
+//! complete. Open drops only the entries newer than the last complete
+//! generation when the sidecar is still marked as updating, so a crash
+//! between a page write and its entry reaching disk never reports a false
+//! mismatch while the pages that checkpoint did not touch stay verified. A
+//! complete sidecar whose LSN differs from the database header's last
+//! checkpoint cannot say which pages changed since, and is reset.
+
+use std::path::{Path, PathBuf};
+use std::sync::{Arc, Mutex};
+
+use crate::error::{DbError, Result};
+use crate::vfs::{read_exact_at, write_all_at, FileKind, OpenMode, VfsFile, VfsHandle};
+
+use super::checksum::crc32c;
+use super::page::{PageId, HEADER_PAGE_ID};
+
+/// File extension for the page checksum sidecar (e.g. `mydb.ddb.pagesum`).
+pub(crate) const PAGE_CHECKSUM_SIDECAR_EXT: &str = "pagesum";
+
+const PAGE_CHECKSUM_MAGIC: &[u8; 8] = b"DDB-PSUM";
+/// Magic, checkpoint LSN, database id, the state byte, and the last
+/// complete generation.
+const PAGE_CHECKSUM_HEADER_LEN: u64 = 48;
+const PAGE_CHECKSUM_LSN_OFFSET: u64 = 8;
+const PAGE_CHECKSUM_STATE_OFFSET: u64 = 32;
+const PAGE_CHECKSUM_GENERATION_OFFSET: u64 = 40;
+const PAGE_CHECKSUM_STATE_UPDATING: u8 = 0;
+const PAGE_CHECKSUM_STATE_COMPLETE: u8 = 1;
+/// Each entry holds the page's CRC-32C, its page id, and the generation of
+/// the checkpoint that wrote it; a zero page id marks a page with no
+/// recorded checksum.
+const PAGE_CHECKSUM_ENTRY_LEN: u64 = 16;
+/// Entries read at a time when dropping an unfinished checkpoint's entries.
+const PAGE_CHECKSUM_SCRUB_BATCH: u64 = 4096;
+
+#[derive(Debug)]
+pub(crate) struct PageChecksums {
+    vfs: VfsHandle,
+    path: PathBuf,
+    database_id: [u8; 16],
+    verify: bool,
+    sidecar: Mutex<Sidecar>,
+}
+
+#[derive(Debug)]
+struct Sidecar {
+    file: Option<Arc<dyn VfsFile>>,
+    /// Whether the header is marked as updating for entries not yet synced.
+    updating: bool,
+    /// The last complete generation; entries being written carry the next.
+    generation: u64,
+}
+
+impl PageChecksums {
+    /// Opens the sidecar for the database at `db_path` if it exists. A
+    /// missing sidecar is created by the first recorded page.
+    /// `checkpoint_lsn` is the database header's last checkpoint LSN.
+    pub(crate) fn open(
+        vfs: &VfsHandle,
+        db_path: &Path,
+        database_id: [u8; 16],
+        checkpoint_lsn: u64,
+        verify: bool,
+    ) -> Result<Self> {
+        let path = sidecar_path_for_db(db_path);
+        let mut generation = 0;
+        let file = if vfs.file_exists(&path)? {
+            let file = vfs.open(&path, OpenMode::OpenExisting, FileKind::Database)?;
+            match read_header(file.as_ref(), &database_id)? {
+                Some(header)
+                    if header.state == PAGE_CHECKSUM_STATE_COMPLETE
+                        && header.checkpoint_lsn == checkpoint_lsn =>
+                {
+                    generation = header.generation;
+                }
+                // The checkpoint that marked the sidecar as updating never
+                // completed, so it never stamped a new LSN either.
+                Some(header)
+                    if header.state == PAGE_CHECKSUM_STATE_UPDATING
+                        && header.checkpoint_lsn == checkpoint_lsn =>
+                {
+                    generation = header.generation;
+                    drop_entries_after(file.as_ref(), generation)?;
+                    file.sync_data()?;
+                    write_all_at(
+                        file.as_ref(),
+                        PAGE_CHECKSUM_STATE_OFFSET,
+                        &[PAGE_CHECKSUM_STATE_COMPLETE],
+                    )?;
+                    file.sync_data()?;
+                }
+                _ => {
+                    write_header(
+                        file.as_ref(),
+                        &database_id,
+                        checkpoint_lsn,
+                        0,
+                        PAGE_CHECKSUM_STATE_COMPLETE,
+                    )?;
+                    file.sync_data()?;
+                }
+            }
+            Some(file)
+        } else {
+            None
+        };
+        Ok(Self {
+            vfs: vfs.clone(),
+            path,
+            database_id,
+            verify,
+            sidecar: Mutex::new(Sidecar {
+                file,
+                updating: false,
+                generation,
+            }),
+        })
+    }
+
+    /// Records the checksum of a page just written to the main file.
+    pub(crate) fn record(&self, page_id: PageId, data: &[u8]) -> Result<()> {
+        if page_id <= HEADER_PAGE_ID {
+            return Ok(());
+        }
+        let mut sidecar = self
+            .sidecar
+            .lock()
+            .map_err(|_| DbError::internal("page checksum sidecar lock poisoned"))?;
+        if sidecar.file.is_none() {
+            let file = self
+                .vfs
+                .open(&self.path, OpenMode::OpenOrCreate, FileKind::Database)?;
+            write_header(
+                file.as_ref(),
+                &self.database_id,
+                0,
+                0,
+                PAGE_CHECKSUM_STATE_UPDATING,
+            )?;
+            file.sync_data()?;
+            sidecar.file = Some(file);
+            sidecar.updating = true;
+            sidecar.generation = 0;
+        }
+        let updating = sidecar.updating;
+        let generation = sidecar.generation + 1;
+        let Some(file) = sidecar.file.as_ref() else {
+            return Ok(());
+        };
+        if !updating {
+            write_all_at(
+                file.as_ref(),
+                PAGE_CHECKSUM_STATE_OFFSET,
+                &[PAGE_CHECKSUM_STATE_UPDATING],
+            )?;
+            file.sync_data()?;
+        }
+        let mut entry = [0_u8; PAGE_CHECKSUM_ENTRY_LEN as usize];
+        entry[..4].copy_from_slice(&crc32c(data).to_le_bytes());
+        entry[4..8].copy_from_slice(&page_id.to_le_bytes());
+        entry[8..].copy_from_slice(&generation.to_le_bytes());
+        write_all_at(file.as_ref(), entry_offset(page_id), &entry)?;
+        sidecar.updating = true;
+        Ok(())
+    }
+
+    /// Syncs the recorded entries and marks them complete for the checkpoint
+    /// at `checkpoint_lsn`. The pages they describe must already be synced
+    /// to the main file.
+    pub(crate) fn commit(&self, checkpoint_lsn: u64) -> Result<()> {
+        let mut sidecar = self
+            .sidecar
+            .lock()
+            .map_err(|_| DbError::internal("page checksum sidecar lock poisoned"))?;
+        let generation = if sidecar.updating {
+            sidecar.generation + 1
+        } else {
+            sidecar.generation
+        };
+        let Some(file) = sidecar.file.as_ref() else {
+            return Ok(());
+        };
+        file.sync_data()?;
+        write_all_at(
+            file.as_ref(),
+            PAGE_CHECKSUM_LSN_OFFSET,
+            &checkpoint_lsn.to_le_bytes(),
+        )?;
+        write_all_at(
+            file.as_ref(),
+            PAGE_CHECKSUM_GENERATION_OFFSET,
+            &generation.to_le_bytes(),
+        )?;
+        write_all_at(
+            file.as_ref(),
+            PAGE_CHECKSUM_STATE_OFFSET,
+            &[PAGE_CHECKSUM_STATE_COMPLETE],
+        )?;
+        file.sync_data()?;
+        sidecar.updating = false;
+        sidecar.generation = generation;
+        Ok(())
+    }
+
+    /// Checks a page read from the main file against its recorded checksum
+    /// when verification is on.
+    pub(crate) fn verify(&self, page_id: PageId, data: &[u8]) -> Result<()> {
+        if !self.verify || page_id <= HEADER_PAGE_ID {
+            return Ok(());
+        }
+        let sidecar = self
+            .sidecar
+            .lock()
+            .map_err(|_| DbError::internal("page checksum sidecar lock poisoned"))?;
+        let Some(file) = sidecar.file.as_ref() else {
+            return Ok(());
+        };
+        let offset = entry_offset(page_id);
+        if file.file_size()? < offset + PAGE_CHECKSUM_ENTRY_LEN {
+            return Ok(());
+        }
+        let mut entry = [0_u8; PAGE_CHECKSUM_ENTRY_LEN as usize];
+        read_exact_at(file.as_ref(), offset, &mut entry)?;
+        let recorded_page = u32::from_le_bytes(entry[4..8].try_into().expect("entry page id"));
+        if recorded_page != page_id {
+            return Ok(());
+        }
+        let expected = u32::from_le_bytes(entry[..4].try_into().expect("entry checksum"));
+        let actual = crc32c(data);
+        if expected != actual {
+            return Err(DbError::corruption_page_checksum(page_id, expected, actual));
+        }
+        Ok(())
+    }
+}
+
+struct SidecarHeader {
+    checkpoint_lsn: u64,
+    state: u8,
+    generation: u64,
+}
+
+/// Reads the sidecar header, or `None` when it is missing or belongs to
+/// another database.
+fn read_header(file: &dyn VfsFile, database_id: &[u8; 16]) -> Result<Option<SidecarHeader>> {
+    if file.file_size()? < PAGE_CHECKSUM_HEADER_LEN {
+        return Ok(None);
+    }
+    let mut header = [0_u8; PAGE_CHECKSUM_HEADER_LEN as usize];
+    read_exact_at(file, 0, &mut header)?;
+    if &header[..8] != PAGE_CHECKSUM_MAGIC || &header[16..32] != database_id {
+        return Ok(None);
+    }
+    let generation_offset = PAGE_CHECKSUM_GENERATION_OFFSET as usize;
+    Ok(Some(SidecarHeader {
+        checkpoint_lsn: u64::from_le_bytes(header[8..16].try_into().expect("header lsn")),
+        state: header[PAGE_CHECKSUM_STATE_OFFSET as usize],
+        generation: u64::from_le_bytes(
+            header[generation_offset..generation_offset + 8]
+                .try_into()
+                .expect("header generation"),
+        ),
+    }))
+}
+
+/// Writes a fresh header and drops every recorded entry.
+fn write_header(
+    file: &dyn VfsFile,
+    database_id: &[u8; 16],
+    checkpoint_lsn: u64,
+    generation: u64,
+    state: u8,
+) -> Result<()> {
+    let mut header = [0_u8; PAGE_CHECKSUM_HEADER_LEN as usize];
+    header[..8].copy_from_slice(PAGE_CHECKSUM_MAGIC);
+    header[8..16].copy_from_slice(&checkpoint_lsn.to_le_bytes());
+    header[16..32].copy_from_slice(database_id);
+    header[PAGE_CHECKSUM_STATE_OFFSET as usize] = state;
+    let generation_offset = PAGE_CHECKSUM_GENERATION_OFFSET as usize;
+    header[generation_offset..generation_offset + 8].copy_from_slice(&generation.to_le_bytes());
+    file.set_len(0)?;
+    write_all_at(file, 0, &header)
+}
+
+/// Clears the entries written after the complete `generation`, leaving their
+/// pages unverified until a later checkpoint records them again.
+fn drop_entries_after(file: &dyn VfsFile, generation: u64) -> Result<()> {
+    let file_size = file.file_size()?;
+    let entry_len = PAGE_CHECKSUM_ENTRY_LEN as usize;
+    let mut offset = PAGE_CHECKSUM_HEADER_LEN;
+    let mut batch = Vec::new();
+    while offset + PAGE_CHECKSUM_ENTRY_LEN <= file_size {
+        let entries =
+            ((file_size - offset) / PAGE_CHECKSUM_ENTRY_LEN).min(PAGE_CHECKSUM_SCRUB_BATCH);
+        batch.resize(entries as usize * entry_len, 0);
+        read_exact_at(file, offset, &mut batch)?;
+        let mut dirty = false;
+        for entry in batch.chunks_exact_mut(entry_len) {
+            let written = u64::from_le_bytes(entry[8..].try_into().expect("entry generation"));
+            if written > generation {
+                entry.fill(0);
+                dirty = true;
+            }
+        }
+        if dirty {
+            write_all_at(file, offset, &batch)?;
+        }
+        offset += entries * PAGE_CHECKSUM_ENTRY_LEN;
+    }
+    Ok(())
+}
+
+fn sidecar_path_for_db(db_path: &Path) -> PathBuf {
+    let mut path = db_path.as_os_str().to_os_string();
+    path.push(".");
+    path.push(PAGE_CHECKSUM_SIDECAR_EXT);
+    PathBuf::from(path)
+}
+
+fn entry_offset(page_id: PageId) -> u64 {
+    PAGE_CHECKSUM_HEADER_LEN + u64::from(page_id - 1) * PAGE_CHECKSUM_ENTRY_LEN
+}
+
+#[cfg(test)]
+mod tests {
+    use std::path::Path;
+    use std::sync::Arc;
+
+    use crate::vfs::mem::MemVfs;
+    use crate::vfs::VfsHandle;
+
+    use super::PageChecksums;
+
+    #[test]
+    fn recorded_pages_verify_and_mismatches_name_the_page() {
+        let vfs = VfsHandle::from_vfs(Arc::new(MemVfs::default()));
+        let path = Path::new("demo.ddb");
+        let checksums = PageChecksums::open(&vfs, path, [7; 16], 0, true).expect("open sidecar");
+        let page = vec![3_u8; 4096];
+        checksums.verify(5, &page).expect("unrecorded page");
+        checksums.record(5, &page).expect("record page");
+        checksums.verify(5, &page).expect("matching page");
+
+        let mut flipped = page.clone();
+        flipped[100] ^= 1;
+        let err = checksums.verify(5, &flipped).expect_err("flipped bit");
+        assert!(err.to_string().contains("page 5"), "{err}");
+        checksums.verify(6, &flipped).expect("unrecorded page");
+
+        let reopened =
+            PageChecksums::open(&vfs, path, [9; 16], 0, true).expect("reopen for another database");
+        reopened
+            .verify(5, &flipped)
+            .expect("foreign sidecar is reset");
+    }
+
+    #[test]
+    fn only_committed_entries_survive_reopen() {
+        let vfs = VfsHandle::from_vfs(Arc::new(MemVfs::default()));
+        let path = Path::new("demo.ddb");
+        let page = vec![3_u8; 4096];
+        let mut flipped = page.clone();
+        flipped[100] ^= 1;
+
+        let checksums = PageChecksums::open(&vfs, path, [7; 16], 0, true).expect("open sidecar");
+        checksums.record(5, &page).expect("record page");
+        let reopened =
+            PageChecksums::open(&vfs, path, [7; 16], 0, true).expect("reopen mid-checkpoint");
+        reopened
+            .verify(5, &flipped)
+            .expect("uncommitted entries are reset");
+
+        reopened.record(5, &page).expect("record page");
+        reopened.commit(40).expect("commit checkpoint");
+        let committed =
+            PageChecksums::open(&vfs, path, [7; 16], 40, true).expect("reopen after commit");
+        committed
+            .verify(5, &flipped)
+            .expect_err("committed entry is verified");
+
+        committed
+            .record(5, &flipped)
+            .expect("record rewritten page");
+        let stale = PageChecksums::open(&vfs, path, [7; 16], 40, true)
+            .expect("reopen after an unfinished checkpoint");
+        stale.verify(5, &page).expect("updating sidecar is reset");
+
+        stale
+            .verify(5, &flipped)
+            .expect("unfinished entry is dropped");
+
+        stale.record(5, &page).expect("record page");
+        stale.commit(80).expect("commit checkpoint");
+        let other = PageChecksums::open(&vfs, path, [7; 16], 120, true)
+            .expect("reopen at another checkpoint");
+        other
+            .verify(5, &flipped)
+            .expect("sidecar from another checkpoint is reset");
+    }
+
+    #[test]
+    fn crash_mid_checkpoint_keeps_earlier_entries() {
+        let vfs = VfsHandle::from_vfs(Arc::new(MemVfs::default()));
+        let path = Path::new("demo.ddb");
+        let page = vec![3_u8; 4096];
+        let rewritten = vec![4_u8; 4096];
+        let mut flipped = page.clone();
+        flipped[100] ^= 1;
+
+        let checksums = PageChecksums::open(&vfs, path, [7; 16], 0, true).expect("open sidecar");
+        for page_id in [5, 6, 7] {
+            checksums.record(page_id, &page).expect("record page");
+        }
+        checksums.commit(40).expect("commit checkpoint");
+
+        // The next checkpoint rewrites page 6 and dies before committing.
+        let checkpointing =
+            PageChecksums::open(&vfs, path, [7; 16], 40, true).expect("reopen after commit");
+        checkpointing
+            .record(6, &rewritten)
+            .expect("record rewritten page");
+        checkpointing
+            .record(9, &rewritten)
+            .expect("record new page");
+        drop(checkpointing);
+
+        let recovered =
+            PageChecksums::open(&vfs, path, [7; 16], 40, true).expect("reopen after crash");
+        recovered.verify(5, &page).expect("earlier entry verifies");
+        let err = recovered
+            .verify(7, &flipped)
+            .expect_err("earlier entry still catches bit rot");
+        assert!(err.to_string().contains("page 7"), "{err}");
+        recovered
+            .verify(6, &page)
+            .expect("interrupted entry is not trusted");
+        recovered
+            .verify(9, &flipped)
+            .expect("interrupted entry is not trusted");
+
+        recovered.record(6, &rewritten).expect("record page again");
+        recovered.commit(80).expect("commit checkpoint");
+        let reopened =
+            PageChecksums::open(&vfs, path, [7; 16], 80, true).expect("reopen after commit");
+        reopened.verify(6, &rewritten).expect("new entry verifies");
+        reopened
+            .verify(6, &page)
+            .expect_err("new entry is verified");
+        reopened
+            .verify(5, &flipped)
+            .expect_err("earlier entry survives later checkpoints");
+    }
+}
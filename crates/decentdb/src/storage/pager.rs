@@ -4,7 +4,8 @@
 //! - design/adr/0001-page-size.md
 
 use std::collections::HashSet;
-use std::sync::{Arc, Mutex};
+use std::sync::atomic::{AtomicU64, Ordering};
+use std::sync::{Arc, Mutex, OnceLock};
 
 use crate::error::{DbError, Result};
 use crate::vfs::{read_exact_at, write_all_at, VfsFile};
@@ -13,6 +14,10 @@ use super::cache::PageCache;
 use super::freelist::{decode_freelist_next, encode_freelist_page};
 use super::header::{DatabaseHeader, DB_HEADER_SIZE};
 use super::page::{self, PageId};
+use super::page_checksums::PageChecksums;
+use super::read_ahead;
+
+static PAGER_ID_COUNTER: AtomicU64 = AtomicU64::new(1);
 
 #[derive(Clone, Debug)]
 pub(crate) struct PagerHandle {
@@ -27,6 +32,12 @@ struct Pager {
     header: Mutex<DatabaseHeader>,
     page_pool: Mutex<Vec<Vec<u8>>>,
     page_pool_max: usize,
+    checksums: OnceLock<PageChecksums>,
+    /// Identifies this pager to the per-thread read-ahead buffers.
+    id: u64,
+    /// Bumped whenever this pager writes the file, so read-ahead buffers
+    /// filled before the write are not served after it.
+    write_generation: AtomicU64,
     #[cfg(test)]
     page_pool_reuse_count: std::sync::atomic::AtomicUsize,
 }
@@ -61,12 +72,32 @@ impl PagerHandle {
                 header: Mutex::new(header),
                 page_pool: Mutex::new(Vec::with_capacity(page_pool_max.min(256))),
                 page_pool_max,
+                checksums: OnceLock::new(),
+                id: PAGER_ID_COUNTER.fetch_add(1, Ordering::Relaxed),
+                write_generation: AtomicU64::new(0),
                 #[cfg(test)]
                 page_pool_reuse_count: std::sync::atomic::AtomicUsize::new(0),
             }),
         })
     }
 
+    /// Records a checksum for every page this pager writes from now on and,
+    /// when the sidecar was opened with verification, checks pages it reads
+    /// from disk against them.
+    pub(crate) fn attach_page_checksums(&self, checksums: PageChecksums) {
+        let _ = self.inner.checksums.set(checksums);
+    }
+
+    /// Syncs checkpointed pages to the main file, then marks the checksums
+    /// recorded for them complete as of `checkpoint_lsn`.
+    pub(crate) fn commit_page_checksums(&self, checkpoint_lsn: u64) -> Result<()> {
+        let Some(checksums) = self.inner.checksums.get() else {
+            return Ok(());
+        };
+        self.inner.file.sync_data()?;
+        checksums.commit(checkpoint_lsn)
+    }
+
     pub(crate) fn read_page(&self, page_id: PageId) -> Result<Arc<[u8]>> {
         page::validate_page_id(page_id)?;
         let handle = self
@@ -78,6 +109,9 @@ impl PagerHandle {
 
     pub(crate) fn read_page_from_disk(&self, page_id: PageId) -> Result<Arc<[u8]>> {
         page::validate_page_id(page_id)?;
+        if let Some(data) = self.inner.read_ahead_page(page_id)? {
+            return Ok(Arc::from(data));
+        }
         Ok(Arc::from(self.inner.load_page_from_disk(page_id)?))
     }
 
@@ -95,11 +129,15 @@ impl PagerHandle {
             )));
         }
 
+        self.inner.note_write();
         write_all_at(
             self.inner.file.as_ref(),
             page::page_offset(page_id, self.inner.page_size),
             data,
         )?;
+        if let Some(checksums) = self.inner.checksums.get() {
+            checksums.record(page_id, data)?;
+        }
         let required_len =
             page::page_offset(page_id, self.inner.page_size) + u64::from(self.inner.page_size);
         if self.inner.file.file_size()? < required_len {
@@ -211,6 +249,7 @@ impl PagerHandle {
                 self.inner.page_size, header.page_size
             )));
         }
+        self.inner.note_write();
         self.inner.cache.clear()?;
         *self
             .inner
@@ -254,6 +293,7 @@ impl PagerHandle {
             return Ok(None);
         }
 
+        self.inner.note_write();
         let remaining_pages = ordered_freelist_pages
             .into_iter()
             .filter(|page_id| !trimmed_pages.contains(page_id))
@@ -286,6 +326,11 @@ impl PagerHandle {
         Ok(Some(new_page_count))
     }
 
+    /// Returns `(capacity_pages, resident_pages, hits, misses)`.
+    pub(crate) fn cache_stats(&self) -> Result<(usize, usize, u64, u64)> {
+        self.inner.cache.stats()
+    }
+
     #[must_use]
     pub(crate) fn page_size(&self) -> u32 {
         self.inner.page_size
@@ -304,6 +349,7 @@ impl PagerHandle {
     }
 
     fn persist_header(&self, header: &DatabaseHeader) -> Result<()> {
+        self.inner.note_write();
         let bytes = header.encode();
         write_all_at(self.inner.file.as_ref(), 0, &bytes)?;
         self.inner
@@ -334,9 +380,54 @@ impl Pager {
             page::page_offset(page_id, self.page_size),
             &mut data,
         )?;
+        if let Some(checksums) = self.checksums.get() {
+            checksums.verify(page_id, &data)?;
+        }
         Ok(data)
     }
 
+    /// Serves `page_id` through the thread's read-ahead buffer when a
+    /// window is installed and the read continues a sequential run.
+    fn read_ahead_page(&self, page_id: PageId) -> Result<Option<Vec<u8>>> {
+        let window = read_ahead::read_ahead_pages();
+        if window <= 1 {
+            return Ok(None);
+        }
+        let page_size = self.page_size as usize;
+        let data = read_ahead::read_page(
+            self.id,
+            self.write_generation.load(Ordering::Acquire),
+            page_id,
+            page_size,
+            window,
+            |first, window, bytes| {
+                let page_count = self
+                    .file
+                    .file_size()
+                    .map(|size| page::page_count_for_len(size, self.page_size))?;
+                if first > page_count {
+                    return Ok(0);
+                }
+                let count = window.min((page_count - first) as usize + 1);
+                bytes.resize(count * page_size, 0);
+                read_exact_at(
+                    self.file.as_ref(),
+                    page::page_offset(first, self.page_size),
+                    bytes,
+                )?;
+                Ok(count)
+            },
+        )?;
+        if let (Some(data), Some(checksums)) = (&data, self.checksums.get()) {
+            checksums.verify(page_id, data)?;
+        }
+        Ok(data)
+    }
+
+    fn note_write(&self) {
+        self.write_generation.fetch_add(1, Ordering::AcqRel);
+    }
+
     fn take_page_buffer(&self) -> Result<Vec<u8>> {
         let page_size = self.page_size as usize;
         if self.page_pool_max != 0 {
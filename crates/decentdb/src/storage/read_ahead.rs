@@ -0,0 +1,210 @@
+//! Read-ahead for sequential scans of the main database file.
+//!
+//! A handle's window (`DbConfig::read_ahead_pages`, adjusted at runtime with
+//! `PRAGMA read_ahead_pages`) is installed for the thread running a read with
+//! [`ReadAhead::install`]; a `/*+ READ_AHEAD(n) */` comment at the start of a
+//! statement overrides it for that statement through [`ReadAheadHint`]. When
+//! a read misses on the page right after the one it loaded last, the pager
+//! fetches the next `n` pages with a single read and serves them from a
+//! per-thread buffer. The buffer is dropped with the guard: checkpoints leave
+//! the main file untouched while a snapshot is open, so it cannot go stale
+//! within one read.
+
+use std::cell::{Cell, RefCell};
+
+use crate::error::Result;
+
+use super::page::PageId;
+
+/// Upper bound on a read-ahead window, in pages.
+pub(crate) const MAX_READ_AHEAD_PAGES: usize = 4096;
+
+thread_local! {
+    static READ_AHEAD_PAGES: Cell<usize> = const { Cell::new(0) };
+    static READ_AHEAD_HINT: Cell<Option<usize>> = const { Cell::new(None) };
+    static READ_AHEAD_BUFFER: RefCell<ReadAheadBuffer> = RefCell::new(ReadAheadBuffer::default());
+}
+
+#[derive(Debug, Default)]
+struct ReadAheadBuffer {
+    pager_id: u64,
+    generation: u64,
+    last_page: PageId,
+    first_page: PageId,
+    page_count: usize,
+    bytes: Vec<u8>,
+}
+
+impl ReadAheadBuffer {
+    fn page(&self, page_id: PageId, page_size: usize) -> Option<&[u8]> {
+        if self.page_count == 0 || page_id < self.first_page {
+            return None;
+        }
+        let index = (page_id - self.first_page) as usize;
+        if index >= self.page_count {
+            return None;
+        }
+        self.bytes.get(index * page_size..(index + 1) * page_size)
+    }
+}
+
+/// Restores the previous read-ahead window of the thread when dropped.
+pub(crate) struct ReadAhead(usize);
+
+impl ReadAhead {
+    /// Installs `pages` as the thread's window, unless a statement hint is
+    /// active, in which case the hint wins.
+    pub(crate) fn install(pages: usize) -> Self {
+        let pages = READ_AHEAD_HINT.with(Cell::get).unwrap_or(pages);
+        Self(READ_AHEAD_PAGES.with(|slot| slot.replace(pages.min(MAX_READ_AHEAD_PAGES))))
+    }
+}
+
+impl Drop for ReadAhead {
+    fn drop(&mut self) {
+        READ_AHEAD_PAGES.with(|slot| slot.set(self.0));
+        READ_AHEAD_BUFFER.with(|buffer| {
+            let mut buffer = buffer.borrow_mut();
+            buffer.page_count = 0;
+            buffer.last_page = 0;
+            if self.0 == 0 {
+                buffer.bytes = Vec::new();
+            }
+        });
+    }
+}
+
+/// Restores the previous statement hint of the thread when dropped.
+pub(crate) struct ReadAheadHint(Option<usize>);
+
+impl ReadAheadHint {
+    /// Installs the `/*+ READ_AHEAD(n) */` hint of `sql`, if it has one.
+    pub(crate) fn install(sql: &str) -> Self {
+        let hint = statement_read_ahead_hint(sql);
+        Self(READ_AHEAD_HINT.with(|slot| {
+            let previous = slot.get();
+            if hint.is_some() {
+                slot.set(hint);
+            }
+            previous
+        }))
+    }
+}
+
+impl Drop for ReadAheadHint {
+    fn drop(&mut self) {
+        READ_AHEAD_HINT.with(|slot| slot.set(self.0));
+    }
+}
+
+/// Returns the window installed for the current thread.
+pub(crate) fn read_ahead_pages() -> usize {
+    READ_AHEAD_PAGES.with(Cell::get)
+}
+
+/// Parses a leading `/*+ READ_AHEAD(n) */` optimizer-hint comment.
+pub(crate) fn statement_read_ahead_hint(sql: &str) -> Option<usize> {
+    let body = sql.trim_start().strip_prefix("/*+")?;
+    let body = &body[..body.find("*/")?];
+    let upper = body.to_ascii_uppercase();
+    let start = upper.find("READ_AHEAD")? + "READ_AHEAD".len();
+    let args = upper[start..].trim_start().strip_prefix('(')?;
+    let pages = args[..args.find(')')?].trim().parse::<usize>().ok()?;
+    Some(pages.min(MAX_READ_AHEAD_PAGES))
+}
+
+/// Serves `page_id` from the thread's read-ahead buffer, refilling it with
+/// `fill` when the read continues a sequential run. `fill` reads up to
+/// `window` pages starting at the given page into the buffer and returns how
+/// many it read. Returns `None` when the page should be read on its own.
+pub(crate) fn read_page(
+    pager_id: u64,
+    generation: u64,
+    page_id: PageId,
+    page_size: usize,
+    window: usize,
+    fill: impl FnOnce(PageId, usize, &mut Vec<u8>) -> Result<usize>,
+) -> Result<Option<Vec<u8>>> {
+    READ_AHEAD_BUFFER.with(|buffer| {
+        let mut buffer = buffer.borrow_mut();
+        if buffer.pager_id != pager_id || buffer.generation != generation {
+            buffer.pager_id = pager_id;
+            buffer.generation = generation;
+            buffer.last_page = 0;
+            buffer.page_count = 0;
+        }
+        let sequential = buffer.last_page != 0 && page_id == buffer.last_page.wrapping_add(1);
+        buffer.last_page = page_id;
+        if let Some(page) = buffer.page(page_id, page_size) {
+            return Ok(Some(page.to_vec()));
+        }
+        if !sequential {
+            return Ok(None);
+        }
+        buffer.first_page = page_id;
+        buffer.page_count = 0;
+        let page_count = fill(page_id, window, &mut buffer.bytes)?;
+        buffer.page_count = page_count;
+        Ok(buffer.page(page_id, page_size).map(<[u8]>::to_vec))
+    })
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn parses_leading_hint_comment() {
+        assert_eq!(
+            statement_read_ahead_hint("/*+ READ_AHEAD(64) */ SELECT * FROM t"),
+            Some(64)
+        );
+        assert_eq!(
+            statement_read_ahead_hint("  /*+ parallel read_ahead ( 8 ) */SELECT 1"),
+            Some(8)
+        );
+        assert_eq!(
+            statement_read_ahead_hint("/* READ_AHEAD(8) */ SELECT 1"),
+            None
+        );
+        assert_eq!(
+            statement_read_ahead_hint("SELECT 1 /*+ READ_AHEAD(8) */"),
+            None
+        );
+        assert_eq!(
+            statement_read_ahead_hint("/*+ READ_AHEAD(999999) */ SELECT 1"),
+            Some(MAX_READ_AHEAD_PAGES)
+        );
+    }
+
+    #[test]
+    fn sequential_misses_fill_the_buffer_once() {
+        let _window = ReadAhead::install(4);
+        let mut fills = 0;
+        let mut read = |page_id: PageId| {
+            read_page(
+                7,
+                0,
+                page_id,
+                2,
+                read_ahead_pages(),
+                |first, window, bytes| {
+                    fills += 1;
+                    bytes.clear();
+                    for page in first..first + window as PageId {
+                        bytes.extend_from_slice(&[page as u8; 2]);
+                    }
+                    Ok(window)
+                },
+            )
+            .expect("read")
+        };
+        assert_eq!(read(10), None);
+        assert_eq!(read(11), Some(vec![11, 11]));
+        assert_eq!(read(12), Some(vec![12, 12]));
+        assert_eq!(read(14), Some(vec![14, 14]));
+        assert_eq!(read(3), None);
+        drop(read);
+        assert_eq!(fills, 1);
+    }
+}
@@ -80,6 +80,10 @@ pub struct SyncJournalRecord {
     pub table: String,
     pub operation: String,
     pub primary_key: serde_json::Value,
+    /// Row image before an update or delete; absent for inserts and for
+    /// records written before before-images were captured.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub before: Option<serde_json::Value>,
     pub after: Option<serde_json::Value>,
     pub schema_cookie: u32,
     pub committed_at_micros: i64,
@@ -1107,6 +1111,7 @@ pub(crate) struct SyncMutation {
     pub table: String,
     pub operation: SyncOperation,
     pub primary_key: serde_json::Value,
+    pub before: Option<serde_json::Value>,
     pub after: Option<serde_json::Value>,
     pub schema_cookie: u32,
 }
@@ -1748,6 +1753,7 @@ impl SyncContext {
                 table: mutation.table.clone(),
                 operation: mutation.operation.as_str().to_string(),
                 primary_key: std::mem::take(&mut mutation.primary_key),
+                before: std::mem::take(&mut mutation.before),
                 after: std::mem::take(&mut mutation.after),
                 schema_cookie: mutation.schema_cookie,
                 committed_at_micros: now_micros,
@@ -2225,6 +2231,7 @@ mod tests {
             table: seed.table.clone(),
             operation: operation.to_string(),
             primary_key,
+            before: None,
             after,
             schema_cookie: seed.schema_cookie,
             committed_at_micros: sequence as i64,
@@ -1,6 +1,6 @@
 //! Stable schema and query-contract metadata for external tooling.
 
-use std::collections::BTreeMap;
+use std::collections::{BTreeMap, BTreeSet};
 
 use serde_json::{json, Value as JsonValue};
 use sha2::{Digest, Sha256};
@@ -87,20 +87,95 @@ pub(crate) fn describe_query_contract(
     let result_columns =
         describe_statement_outputs(statement, runtime, &mut params, &mut diagnostics)?;
     collect_statement_parameters(statement, runtime, &mut params, &mut diagnostics)?;
+    let referenced_tables = crate::sql::ast::safe_referenced_tables(statement);
+    let read_only = statement_is_read_only(statement)
+        && !crate::sql::ast::statement_calls_advisory_lock(statement);
+    let dependency_tables = read_only
+        .then(|| query_dependency_tables(statement, runtime, &mut BTreeSet::new()))
+        .flatten()
+        .map(|tables| tables.into_iter().collect());
+    let target_table = match statement {
+        Statement::Insert(insert) => Some(insert.table_name.clone()),
+        Statement::Update(update) => Some(update.table_name.clone()),
+        Statement::Delete(delete) => Some(delete.table_name.clone()),
+        _ => None,
+    };
+    let (object_kind, object_name) = schema_object(statement)
+        .map(|(kind, name)| (kind.to_string(), name.clone()))
+        .unzip();
     Ok(QueryContract {
         contract_version: QUERY_CONTRACT_VERSION,
         sql: sql.to_string(),
         statement_kind: statement_kind(statement).to_string(),
-        read_only: statement_is_read_only(statement),
+        read_only,
         schema_cookie: runtime.catalog.schema_cookie,
         temp_schema_cookie: runtime.temp_schema_cookie,
         schema_fingerprint: schema_fingerprint.to_string(),
+        referenced_tables_complete: referenced_tables.is_some(),
+        referenced_tables: referenced_tables.unwrap_or_default().into_iter().collect(),
+        dependency_tables,
+        target_table,
+        object_kind,
+        object_name,
         parameters: params.into_sorted(),
         result_columns,
         diagnostics,
     })
 }
 
+/// Describes the output columns of `query` without executing it, so CREATE
+/// TABLE AS can copy the type of each column the query selects directly.
+pub(crate) fn describe_query_columns(
+    query: &Query,
+    runtime: &EngineRuntime,
+) -> Result<Vec<QueryResultColumnInfo>> {
+    describe_query_outputs(
+        query,
+        runtime,
+        &mut ParameterAccumulator::default(),
+        &mut Vec::new(),
+    )
+}
+
+/// Returns the persistent tables a read-only statement's result depends on,
+/// expanding views into the tables they read. Returns `None` when the set is
+/// not provably exhaustive or the statement reads a temporary or internal
+/// relation, whose changes are not published to watchers.
+fn query_dependency_tables(
+    statement: &Statement,
+    runtime: &EngineRuntime,
+    visiting_views: &mut BTreeSet<String>,
+) -> Option<BTreeSet<String>> {
+    let mut tables = BTreeSet::new();
+    for name in crate::sql::ast::safe_referenced_tables(statement)? {
+        let is_temp = runtime
+            .temp_tables
+            .keys()
+            .chain(runtime.temp_views.keys())
+            .any(|entry| identifiers_equal(entry, &name));
+        if is_temp {
+            return None;
+        }
+        if let Some(table) = runtime.catalog.table(&name) {
+            if crate::sync::is_internal_table_name(&table.name) {
+                return None;
+            }
+            tables.insert(table.name.clone());
+        } else if let Some(view) = runtime.catalog.view(&name) {
+            if !visiting_views.insert(view.name.clone()) {
+                return None;
+            }
+            let view_statement = crate::sql::parser::parse_sql_statement(&view.sql_text).ok()?;
+            let view_tables = query_dependency_tables(&view_statement, runtime, visiting_views);
+            visiting_views.remove(&view.name);
+            tables.extend(view_tables?);
+        } else {
+            return None;
+        }
+    }
+    Some(tables)
+}
+
 fn tooling_column_type_metadata(runtime: &EngineRuntime) -> Vec<ToolingColumnTypeMetadata> {
     let mut columns = Vec::new();
     for table in runtime.catalog.tables.values() {
@@ -348,7 +423,29 @@ fn describe_query_body_outputs(
             for expr in &select.group_by {
                 infer_params_from_expr(expr, &scope, params, diagnostics, None);
             }
-            describe_select_items(&select.projection, &scope, params, diagnostics)
+            let mut columns =
+                describe_select_items(&select.projection, &scope, params, diagnostics)?;
+            // A grouping column left out of any grouping set is NULL on
+            // that set's subtotal rows. Grouped output rejects wildcards, so
+            // columns line up with projection items whenever sets are present.
+            if !select.grouping_sets.is_empty() && columns.len() == select.projection.len() {
+                for (column, item) in columns.iter_mut().zip(&select.projection) {
+                    let SelectItem::Expr { expr, .. } = item else {
+                        continue;
+                    };
+                    let subtotaled = select
+                        .group_by
+                        .iter()
+                        .position(|group_expr| group_expr == expr)
+                        .is_some_and(|index| {
+                            select.grouping_sets.iter().any(|set| !set.contains(&index))
+                        });
+                    if subtotaled {
+                        column.nullable = Some(true);
+                    }
+                }
+            }
+            Ok(columns)
         }
         QueryBody::Values(rows) => {
             let width = rows.first().map_or(0, Vec::len);
@@ -377,12 +474,59 @@ fn describe_query_body_outputs(
         }
         QueryBody::SetOperation { left, right, .. } => {
             let left_columns = describe_query_body_outputs(left, runtime, params, diagnostics)?;
-            let _ = describe_query_body_outputs(right, runtime, params, diagnostics)?;
-            Ok(left_columns)
+            let right_columns = describe_query_body_outputs(right, runtime, params, diagnostics)?;
+            Ok(left_columns
+                .into_iter()
+                .zip(right_columns)
+                .map(|(left, right)| set_operation_result_column(left, right))
+                .collect())
         }
     }
 }
 
+/// Names a set-operation column after its left input and types it the way
+/// execution unifies the values from both inputs.
+fn set_operation_result_column(
+    left: QueryResultColumnInfo,
+    right: QueryResultColumnInfo,
+) -> QueryResultColumnInfo {
+    let left_type = left.type_name.as_deref().and_then(column_type_from_name);
+    let right_type = right.type_name.as_deref().and_then(column_type_from_name);
+    let column_type = match (left_type, right_type) {
+        (Some(left_type), Some(right_type)) if left_type == right_type => Some(left_type),
+        (
+            Some(ColumnType::Int64 | ColumnType::Float64 | ColumnType::Decimal),
+            Some(ColumnType::Int64 | ColumnType::Float64 | ColumnType::Decimal),
+        ) => numeric_result_type(
+            left_type.map(|column_type| DescribedType::scalar(column_type, true)),
+            right_type.map(|column_type| DescribedType::scalar(column_type, true)),
+        )
+        .map(|described| described.column_type),
+        (Some(ColumnType::Date), Some(ColumnType::Timestamp))
+        | (Some(ColumnType::Timestamp), Some(ColumnType::Date)) => Some(ColumnType::Timestamp),
+        (left_type, right_type) => left_type.or(right_type),
+    };
+    let same_source = left.source_table.is_some()
+        && left.source_table == right.source_table
+        && left.source_column == right.source_column;
+    let mut diagnostics = left.diagnostics;
+    if column_type.is_some() {
+        diagnostics.clear();
+    }
+    QueryResultColumnInfo {
+        type_name: column_type.map(|column_type| column_type.as_str().to_string()),
+        nullable: match (left.nullable, right.nullable) {
+            (Some(true), _) | (_, Some(true)) => Some(true),
+            (Some(false), Some(false)) => Some(false),
+            _ => None,
+        },
+        source_table: left.source_table.filter(|_| same_source),
+        source_column: left.source_column.filter(|_| same_source),
+        diagnostics,
+        ..left
+    }
+}
+
 fn scope_for_select(
     select: &Select,
     runtime: &EngineRuntime,
@@ -449,6 +593,20 @@ fn append_from_item_scope(
                 | crate::sql::ast::JoinConstraint::Natural => {}
             }
         }
+        FromItem::Sample {
+            source,
+            percent,
+            seed,
+            ..
+        } => {
+            append_from_item_scope(scope, source, runtime, params, diagnostics)?;
+            let percent_type = DescribedType::scalar(ColumnType::Float64, false);
+            infer_params_from_expr(percent, scope, params, diagnostics, Some(&percent_type));
+            if let Some(seed) = seed {
+                let seed_type = DescribedType::scalar(ColumnType::Int64, false);
+                infer_params_from_expr(seed, scope, params, diagnostics, Some(&seed_type));
+            }
+        }
     }
     Ok(())
 }
@@ -953,9 +1111,11 @@ fn infer_function_type(
     let normalized = name.to_ascii_lowercase();
     match normalized.as_str() {
         "lower" | "upper" | "trim" | "ltrim" | "rtrim" | "substr" | "substring" | "replace"
-        | "printf" | "format" | "hex" | "sha256" | "md5" | "uuid" | "st_astext"
-        | "st_asgeojson" | "st_geometrytype" => Some(DescribedType::scalar(ColumnType::Text, true)),
-        "length" | "json_array_length" | "st_srid" => {
+        | "printf" | "format" | "hex" | "sha256" | "sha512" | "md5" | "hmac" | "uuid"
+        | "st_astext" | "st_asgeojson" | "st_geometrytype" | "soundex" => {
+            Some(DescribedType::scalar(ColumnType::Text, true))
+        }
+        "length" | "json_array_length" | "st_srid" | "levenshtein" => {
             Some(DescribedType::scalar(ColumnType::Int64, true))
         }
         "abs" | "round" | "ceil" | "ceiling" | "floor" => args
@@ -963,16 +1123,20 @@ fn infer_function_type(
             .and_then(|expr| infer_expr_type(expr, scope, diagnostics))
             .or_else(|| Some(DescribedType::scalar(ColumnType::Float64, true))),
         "sin" | "cos" | "tan" | "asin" | "acos" | "atan" | "atan2" | "sqrt" | "pow" | "power"
-        | "radians" | "degrees" | "st_distance" | "st_length" | "st_area" | "bm25" => {
-            Some(DescribedType::scalar(ColumnType::Float64, true))
-        }
+        | "radians" | "degrees" | "st_distance" | "st_length" | "st_area" | "bm25"
+        | "similarity" => Some(DescribedType::scalar(ColumnType::Float64, true)),
         "coalesce" => args
             .iter()
             .filter_map(|expr| infer_expr_type(expr, scope, diagnostics))
             .next(),
         "st_dwithin" | "st_intersects" | "st_contains" | "st_within" | "st_equals"
         | "st_isvalid" | "fulltext_match" => Some(DescribedType::scalar(ColumnType::Bool, true)),
-        "st_asbinary" => Some(DescribedType::scalar(ColumnType::Blob, true)),
+        name if crate::sql::ast::is_advisory_lock_function(name) => {
+            Some(DescribedType::scalar(ColumnType::Bool, true))
+        }
+        "st_asbinary" | "random_bytes" | "gen_random_bytes" => {
+            Some(DescribedType::scalar(ColumnType::Blob, true))
+        }
         "st_geogpoint" | "st_geogpointz" | "st_geogpointm" | "st_geogpointzm"
         | "st_geogfromwkb" | "st_geogfromtext" | "st_geogfromgeojson" => {
             Some(DescribedType::scalar(ColumnType::Geography, false))
@@ -981,6 +1145,9 @@ fn infer_function_type(
         | "st_geomfromtext" | "st_geomfromgeojson" => {
             Some(DescribedType::scalar(ColumnType::Geometry, false))
         }
+        "gen_random_uuid" | "uuidv4" | "uuid_v7" | "uuidv7" => {
+            Some(DescribedType::scalar(ColumnType::Uuid, false))
+        }
         "st_setsrid" => args
             .first()
             .and_then(|expr| infer_expr_type(expr, scope, diagnostics)),
@@ -995,7 +1162,7 @@ fn infer_function_type(
 
 fn infer_aggregate_type(name: &str) -> Option<DescribedType> {
     match name.to_ascii_lowercase().as_str() {
-        "count" | "row_number" | "rank" | "dense_rank" => {
+        "count" | "grouping" | "row_number" | "rank" | "dense_rank" => {
             Some(DescribedType::scalar(ColumnType::Int64, false))
         }
         "sum" | "avg" | "min" | "max" | "median" | "percentile_cont" | "percentile_disc" => {
@@ -1077,7 +1244,7 @@ fn described_from_result_column(column: &QueryResultColumnInfo) -> DescribedType
     }
 }
 
-fn column_type_from_name(name: &str) -> Option<ColumnType> {
+pub(crate) fn column_type_from_name(name: &str) -> Option<ColumnType> {
     match name.to_ascii_uppercase().as_str() {
         "INT" | "INTEGER" | "INT64" => Some(ColumnType::Int64),
         "FLOAT" | "FLOAT64" | "REAL" | "DOUBLE" => Some(ColumnType::Float64),
@@ -1170,6 +1337,39 @@ fn infer_expr_name(expr: &Expr, ordinal: usize) -> String {
     }
 }
 
+/// Returns the kind and name of the schema object a DDL statement changes.
+/// Index maintenance, `TRUNCATE`, and `ANALYZE` leave the schema as is.
+fn schema_object(statement: &Statement) -> Option<(&'static str, &String)> {
+    match statement {
+        Statement::CreateTable(create) => Some(("table", &create.table_name)),
+        Statement::CreateTableAs(create) => Some(("table", &create.table_name)),
+        Statement::CreateSchema { name, .. } => Some(("schema", name)),
+        Statement::CreateIndex(create) => Some(("index", &create.index_name)),
+        Statement::CreateView(create) => Some(("view", &create.view_name)),
+        Statement::CreateTrigger(create) => Some(("trigger", &create.trigger_name)),
+        Statement::DropTable { name, .. }
+        | Statement::AlterTable {
+            table_name: name, ..
+        } => Some(("table", name)),
+        Statement::DropIndex { name, .. } => Some(("index", name)),
+        Statement::DropView { name, .. }
+        | Statement::AlterViewRename {
+            view_name: name, ..
+        } => Some(("view", name)),
+        Statement::DropTrigger { name, .. } => Some(("trigger", name)),
+        Statement::CommentOn { table_name, .. } => Some(("table", table_name)),
+        Statement::Query(_)
+        | Statement::Explain(_)
+        | Statement::Insert(_)
+        | Statement::Update(_)
+        | Statement::Delete(_)
+        | Statement::Analyze { .. }
+        | Statement::AlterIndexRebuild { .. }
+        | Statement::AlterIndexVerify { .. }
+        | Statement::TruncateTable { .. } => None,
+    }
+}
+
 fn statement_kind(statement: &Statement) -> &'static str {
     match statement {
         Statement::Query(_) => "query",
@@ -1193,6 +1393,7 @@ fn statement_kind(statement: &Statement) -> &'static str {
         Statement::AlterViewRename { .. } => "alter_view_rename",
         Statement::AlterTable { .. } => "alter_table",
         Statement::TruncateTable { .. } => "truncate_table",
+        Statement::CommentOn { .. } => "comment_on",
     }
 }
 
@@ -1396,6 +1597,18 @@ fn collect_params_in_from_item(item: &FromItem, params: &mut ParameterAccumulato
                 collect_params_in_expr(expr, params);
             }
         }
+        FromItem::Sample {
+            source,
+            percent,
+            seed,
+            ..
+        } => {
+            collect_params_in_from_item(source, params);
+            collect_params_in_expr(percent, params);
+            if let Some(seed) = seed {
+                collect_params_in_expr(seed, params);
+            }
+        }
         FromItem::Table { .. } => {}
     }
 }
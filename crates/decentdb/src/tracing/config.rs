@@ -88,6 +88,24 @@ impl Default for SessionTraceConfig {
     }
 }
 
+/// Per-fingerprint statement statistics controls.
+#[derive(Clone, Debug, Eq, PartialEq)]
+pub struct StatementStatsConfig {
+    pub enabled: bool,
+    /// Maximum distinct fingerprints tracked; the least-called entry is
+    /// evicted to make room for a new one.
+    pub max_entries: usize,
+}
+
+impl Default for StatementStatsConfig {
+    fn default() -> Self {
+        Self {
+            enabled: false,
+            max_entries: 1000,
+        }
+    }
+}
+
 /// Top-level runtime tracing configuration applied at open time.
 #[derive(Clone, Debug, Eq, PartialEq)]
 pub struct RuntimeTracingConfig {
@@ -96,6 +114,7 @@ pub struct RuntimeTracingConfig {
     pub lock_wait: LockWaitTraceConfig,
     pub index_usage: IndexUsageTraceConfig,
     pub sessions: SessionTraceConfig,
+    pub statement_stats: StatementStatsConfig,
     pub sql_text: SqlTextMode,
     /// Total memory budget across all trace buffers.
     pub memory_budget_bytes: usize,
@@ -109,6 +128,7 @@ impl Default for RuntimeTracingConfig {
             lock_wait: LockWaitTraceConfig::default(),
             index_usage: IndexUsageTraceConfig::default(),
             sessions: SessionTraceConfig::default(),
+            statement_stats: StatementStatsConfig::default(),
             sql_text: SqlTextMode::None,
             memory_budget_bytes: 2 * 1024 * 1024,
         }
@@ -123,6 +143,7 @@ impl RuntimeTracingConfig {
             && (self.slow_query.enabled
                 || self.lock_wait.enabled
                 || self.index_usage.enabled
-                || self.sessions.enabled)
+                || self.sessions.enabled
+                || self.statement_stats.enabled)
     }
 }
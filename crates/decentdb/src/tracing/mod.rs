@@ -1,8 +1,9 @@
 //! Runtime tracing infrastructure for DecentDB.
 //!
 //! Implements opt-in bounded in-memory trace history for slow queries, lock
-//! waits, index usage, and session lifecycle. Disabled by default; disabled
-//! paths must not allocate, normalize SQL, or acquire extra locks.
+//! waits, index usage, per-fingerprint statement statistics, and session
+//! lifecycle. Disabled by default; disabled paths must not allocate,
+//! normalize SQL, or acquire extra locks.
 
 pub(crate) mod advisor;
 mod buffer;
@@ -14,6 +15,7 @@ pub(crate) mod redact;
 pub(crate) mod sessions;
 pub(crate) mod sink;
 pub(crate) mod slow_query;
+pub(crate) mod statement_stats;
 
 pub use config::RuntimeTracingConfig;
 pub use sink::RuntimeTraceState;
@@ -1,8 +1,9 @@
+use crate::sql::fingerprint::normalize_query;
+
 /// Redaction of SQL text for trace capture.
 ///
-/// Phase 1 supports `None` and `Full`. `Template` and `Redacted` require a
-/// parser-backed redactor and will be added once the redaction path is
-/// benchmarked and tested.
+/// `Template` and `Redacted` both capture the query fingerprint: the
+/// statement with its literals replaced by `?`.
 pub(crate) fn redact_sql(
     sql: &str,
     mode: crate::tracing::config::SqlTextMode,
@@ -11,40 +12,42 @@ pub(crate) fn redact_sql(
     use crate::tracing::config::SqlTextMode;
     match mode {
         SqlTextMode::None => String::new(),
-        SqlTextMode::Full => {
-            let limit = max_chars.max(1);
-            if sql.chars().count() > limit {
-                let trunc: String = sql.chars().take(limit).collect();
-                format!("{}…", trunc)
-            } else {
-                sql.to_string()
-            }
-        }
+        SqlTextMode::Full => truncate_chars(sql, max_chars),
         SqlTextMode::Template | SqlTextMode::Redacted => {
-            // Phase 1 fallback: hash of the SQL after trimming.
-            // We do not capture raw SQL unless mode is Full.
-            use std::collections::hash_map::DefaultHasher;
-            use std::hash::{Hash, Hasher};
-            let mut hasher = DefaultHasher::new();
-            sql.hash(&mut hasher);
-            format!("fingerprint:{}", hasher.finish())
+            // The fingerprint is the statement with its literals replaced.
+            // Text that does not tokenize is reduced to a hash so no raw SQL
+            // is captured unless mode is Full.
+            match normalize_query(sql) {
+                Ok(template) => truncate_chars(&template, max_chars),
+                Err(_) => {
+                    use std::collections::hash_map::DefaultHasher;
+                    use std::hash::{Hash, Hasher};
+                    let mut hasher = DefaultHasher::new();
+                    sql.hash(&mut hasher);
+                    format!("fingerprint:{}", hasher.finish())
+                }
+            }
         }
     }
 }
 
-/// Strip parameter values from a SQL string for fingerprinting.
-pub(crate) fn sql_fingerprint(sql: &str) -> String {
-    // Phase 1: trim and lowercase for a crude fingerprint.
-    // This is intentionally cheap and avoids parser overhead.
-    let trimmed = sql.trim();
-    if trimmed.len() > 256 {
-        let end = trimmed
-            .char_indices()
-            .nth(256)
-            .map(|(i, _)| i)
-            .unwrap_or(trimmed.len());
-        trimmed[..end].to_ascii_lowercase()
+fn truncate_chars(text: &str, max_chars: usize) -> String {
+    let limit = max_chars.max(1);
+    if text.chars().count() > limit {
+        let trunc: String = text.chars().take(limit).collect();
+        format!("{}…", trunc)
     } else {
-        trimmed.to_ascii_lowercase()
+        text.to_string()
+    }
+}
+
+/// Strip literal values from a SQL string for fingerprinting, capped at 256
+/// characters. Text that does not tokenize falls back to its trimmed,
+/// lowercased form.
+pub(crate) fn sql_fingerprint(sql: &str) -> String {
+    let fingerprint = normalize_query(sql).unwrap_or_else(|_| sql.trim().to_ascii_lowercase());
+    match fingerprint.char_indices().nth(256) {
+        Some((end, _)) => fingerprint[..end].to_string(),
+        None => fingerprint,
     }
 }
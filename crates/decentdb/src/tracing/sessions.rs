@@ -24,6 +24,7 @@ pub struct SessionSnapshot {
     pub tracing_enabled: bool,
     pub slow_query_threshold_us: Option<u64>,
     pub internal: bool,
+    pub application_name: Option<String>,
 }
 
 impl SessionSnapshot {
@@ -48,6 +49,9 @@ impl SessionSnapshot {
                 Value::Int64(i64::try_from(v).unwrap_or(-1))
             }),
             Value::Bool(self.internal),
+            self.application_name
+                .as_ref()
+                .map_or(Value::Null, |name| Value::Text(name.clone())),
         ]
     }
 }
@@ -62,6 +66,7 @@ pub(crate) struct SessionTracker {
     tracing_enabled: bool,
     slow_query_threshold_us: Option<u64>,
     internal: bool,
+    application_name: Option<String>,
 }
 
 impl SessionTracker {
@@ -86,9 +91,19 @@ impl SessionTracker {
             tracing_enabled,
             slow_query_threshold_us,
             internal: false,
+            application_name: None,
         }
     }
 
+    /// Name the client gave for the service behind this session, if any.
+    pub(crate) fn application_name(&self) -> Option<&str> {
+        self.application_name.as_deref()
+    }
+
+    pub(crate) fn set_application_name(&mut self, name: Option<String>) {
+        self.application_name = name;
+    }
+
     pub(crate) fn snapshot(&self, database_id_hash: String) -> SessionSnapshot {
         SessionSnapshot {
             session_id: self.session_id,
@@ -101,6 +116,7 @@ impl SessionTracker {
             tracing_enabled: self.tracing_enabled,
             slow_query_threshold_us: self.slow_query_threshold_us,
             internal: self.internal,
+            application_name: self.application_name.clone(),
         }
     }
 
@@ -1,5 +1,6 @@
+use std::path::Path;
 use std::sync::atomic::{AtomicU64, Ordering};
-use std::sync::Mutex;
+use std::sync::{Arc, Mutex};
 use std::time::Duration;
 
 use crate::tracing::config::RuntimeTracingConfig;
@@ -9,6 +10,7 @@ use crate::tracing::sessions::{
     RecentSessionBuffer, SessionSnapshot, SessionState, SessionTracker,
 };
 use crate::tracing::slow_query::SlowQueryStore;
+use crate::tracing::statement_stats::{StatementStatsRow, StatementStatsStore};
 
 #[allow(dead_code)]
 /// Mutable runtime trace state owned by `DbInner`.
@@ -25,6 +27,7 @@ pub struct RuntimeTraceState {
     pub(crate) index_usage_store: Mutex<IndexUsageStore>,
     pub(crate) session_tracker: Mutex<SessionTracker>,
     pub(crate) recent_sessions: Mutex<RecentSessionBuffer>,
+    pub(crate) statement_stats_store: Arc<Mutex<StatementStatsStore>>,
     pub(crate) slow_query_counter: AtomicU64,
 }
 
@@ -55,10 +58,22 @@ impl RuntimeTraceState {
             recent_sessions: Mutex::new(RecentSessionBuffer::with_capacity(
                 config.sessions.max_recent_sessions.clamp(1, 16384),
             )),
+            statement_stats_store: Arc::new(Mutex::new(StatementStatsStore::new(config))),
             slow_query_counter: AtomicU64::new(0),
         }
     }
 
+    /// Aggregate statement statistics with the other handles open on
+    /// `canonical_path` instead of keeping them per handle.
+    pub(crate) fn share_statement_stats(&mut self, canonical_path: &Path) {
+        if self.config.enabled && self.config.statement_stats.enabled {
+            self.statement_stats_store = crate::tracing::statement_stats::shared_statement_stats(
+                canonical_path,
+                &self.config,
+            );
+        }
+    }
+
     /// True if *any* trace family is enabled.
     #[inline]
     pub fn any_enabled(&self) -> bool {
@@ -80,13 +95,15 @@ impl RuntimeTraceState {
         internal: bool,
     ) {
         if let Ok(mut store) = self.slow_query_store.lock() {
+            let (session_id, application_name) = self
+                .session_tracker
+                .lock()
+                .map(|t| (t.session_id(), t.application_name().map(str::to_string)))
+                .unwrap_or((0, None));
             store.maybe_record(
                 duration,
                 started_at_unix_ms,
-                self.session_tracker
-                    .lock()
-                    .map(|t| t.session_id())
-                    .unwrap_or(0),
+                session_id,
                 self.connection_id,
                 statement_kind,
                 read_only,
@@ -95,11 +112,37 @@ impl RuntimeTraceState {
                 error_code,
                 internal,
                 &self.database_id_hash,
+                application_name.as_deref(),
             );
         }
         self.slow_query_counter.fetch_add(1, Ordering::Relaxed);
     }
 
+    /// Fold one statement execution into its fingerprint's statistics.
+    #[inline]
+    pub fn record_statement_stats(&self, sql: &str, duration: Duration, rows: u64, failed: bool) {
+        if !self.config.statement_stats.enabled {
+            return;
+        }
+        if let Ok(mut store) = self.statement_stats_store.lock() {
+            store.record(
+                sql,
+                duration,
+                rows,
+                failed,
+                crate::tracing::unix_millis_now(),
+            );
+        }
+    }
+
+    /// Snapshot per-fingerprint statement statistics.
+    pub fn statement_stats_snapshot(&self) -> Vec<StatementStatsRow> {
+        self.statement_stats_store
+            .lock()
+            .map(|store| store.snapshot())
+            .unwrap_or_default()
+    }
+
     /// Snapshot current sessions.
     pub fn sessions_snapshot(&self) -> Vec<SessionSnapshot> {
         let mut out = Vec::new();
@@ -164,6 +207,22 @@ impl RuntimeTraceState {
         }
     }
 
+    /// Name the client gave for the service behind this handle, if any.
+    pub fn application_name(&self) -> Option<String> {
+        self.session_tracker
+            .lock()
+            .ok()
+            .and_then(|t| t.application_name().map(str::to_string))
+    }
+
+    /// Attribute this handle's sessions, slow queries, and audit events to
+    /// `name`.
+    pub fn set_application_name(&self, name: Option<String>) {
+        if let Ok(mut tracker) = self.session_tracker.lock() {
+            tracker.set_application_name(name);
+        }
+    }
+
     /// Mark session as entering a transaction.
     pub fn mark_in_transaction(&self) {
         if let Ok(mut tracker) = self.session_tracker.lock() {
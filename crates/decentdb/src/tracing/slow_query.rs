@@ -27,6 +27,7 @@ pub(crate) struct SlowQueryEventInternal {
     pub error_code: Option<String>,
     pub internal: bool,
     pub truncated: bool,
+    pub application_name: Option<String>,
 }
 
 /// Owned slow-query event for external consumers and SQL rows.
@@ -48,6 +49,7 @@ pub struct SlowQueryEvent {
     pub error_code: Option<String>,
     pub internal: bool,
     pub truncated: bool,
+    pub application_name: Option<String>,
 }
 
 impl SlowQueryEvent {
@@ -76,6 +78,9 @@ impl SlowQueryEvent {
                 .map_or(Value::Null, |e| Value::Text(e.clone())),
             Value::Bool(self.internal),
             Value::Bool(self.truncated),
+            self.application_name
+                .as_ref()
+                .map_or(Value::Null, |name| Value::Text(name.clone())),
         ]
     }
 }
@@ -99,6 +104,7 @@ impl From<SlowQueryEventInternal> for SlowQueryEvent {
             error_code: e.error_code,
             internal: e.internal,
             truncated: e.truncated,
+            application_name: e.application_name,
         }
     }
 }
@@ -138,6 +144,7 @@ impl SlowQueryStore {
         error_code: Option<&str>,
         internal: bool,
         database_id_hash: &str,
+        application_name: Option<&str>,
     ) {
         if !self.config.enabled || !self.config.slow_query.enabled {
             return;
@@ -172,6 +179,7 @@ impl SlowQueryStore {
             error_code: error_code.map(|s| s.to_string()),
             internal,
             truncated,
+            application_name: application_name.map(str::to_string),
         };
         self.buffer.push_back(event);
     }
@@ -206,6 +214,7 @@ mod tests {
             None,
             false,
             "hash",
+            None,
         );
         assert!(store.snapshot().items.is_empty());
     }
@@ -234,6 +243,7 @@ mod tests {
             None,
             false,
             "hash",
+            None,
         );
         assert!(store.snapshot().items.is_empty());
     }
@@ -262,13 +272,15 @@ mod tests {
             None,
             false,
             "hash",
+            Some("billing"),
         );
         let snap = store.snapshot();
         assert_eq!(snap.items.len(), 1);
         let evt = &snap.items[0];
         assert_eq!(evt.status, "ok");
-        // Phase 1 fingerprint is lowercase SQL (does not strip literals).
-        assert!(evt.sql_fingerprint.contains("password"));
+        assert_eq!(evt.application_name.as_deref(), Some("billing"));
+        // The fingerprint replaces literals, so the secret is not captured.
+        assert_eq!(evt.sql_fingerprint, "select * from users where secret = ?");
         assert!(evt.sql_template.is_empty()); // None mode default
     }
 }
@@ -0,0 +1,294 @@
+use std::collections::HashMap;
+use std::path::{Path, PathBuf};
+use std::sync::{Arc, Mutex, OnceLock, Weak};
+use std::time::Duration;
+
+use crate::record::value::Value;
+use crate::tracing::config::RuntimeTracingConfig;
+use crate::tracing::redact::sql_fingerprint;
+
+/// Column names of `decentdb_stat_statements()`, in row order.
+pub(crate) const STATEMENT_STATS_COLUMNS: &[&str] = &[
+    "fingerprint",
+    "calls",
+    "errors",
+    "total_time_us",
+    "mean_time_us",
+    "min_time_us",
+    "max_time_us",
+    "total_rows",
+    "first_seen_unix_ms",
+    "last_seen_unix_ms",
+];
+
+/// Accumulated statistics for one query fingerprint.
+#[derive(Clone, Debug)]
+pub struct StatementStatsRow {
+    pub fingerprint: String,
+    pub calls: u64,
+    pub errors: u64,
+    pub total_time_us: u64,
+    pub min_time_us: u64,
+    pub max_time_us: u64,
+    /// Rows returned or affected, summed over calls.
+    pub total_rows: u64,
+    pub first_seen_unix_ms: i64,
+    pub last_seen_unix_ms: i64,
+}
+
+impl StatementStatsRow {
+    pub fn mean_time_us(&self) -> f64 {
+        if self.calls == 0 {
+            return 0.0;
+        }
+        self.total_time_us as f64 / self.calls as f64
+    }
+
+    pub fn to_query_row(&self) -> Vec<Value> {
+        let int = |v: u64| Value::Int64(i64::try_from(v).unwrap_or(i64::MAX));
+        vec![
+            Value::Text(self.fingerprint.clone()),
+            int(self.calls),
+            int(self.errors),
+            int(self.total_time_us),
+            Value::Float64(self.mean_time_us()),
+            int(self.min_time_us),
+            int(self.max_time_us),
+            int(self.total_rows),
+            Value::Int64(self.first_seen_unix_ms),
+            Value::Int64(self.last_seen_unix_ms),
+        ]
+    }
+}
+
+/// Per-fingerprint statement statistics, bounded by
+/// `StatementStatsConfig::max_entries`.
+///
+/// Fingerprints of recently executed SQL texts are cached so a prepared
+/// statement executed in a loop is normalized once, not per call.
+#[derive(Debug)]
+pub(crate) struct StatementStatsStore {
+    enabled: bool,
+    max_entries: usize,
+    rows: HashMap<String, StatementStatsRow>,
+    fingerprints: HashMap<String, String>,
+}
+
+impl StatementStatsStore {
+    pub(crate) fn new(config: &RuntimeTracingConfig) -> Self {
+        Self {
+            enabled: config.enabled && config.statement_stats.enabled,
+            max_entries: config.statement_stats.max_entries.clamp(1, 65_536),
+            rows: HashMap::new(),
+            fingerprints: HashMap::new(),
+        }
+    }
+
+    pub(crate) fn record(
+        &mut self,
+        sql: &str,
+        duration: Duration,
+        rows: u64,
+        failed: bool,
+        now_unix_ms: i64,
+    ) {
+        if !self.enabled {
+            return;
+        }
+        let fingerprint = match self.fingerprints.get(sql) {
+            Some(fingerprint) => fingerprint.clone(),
+            None => {
+                let fingerprint = sql_fingerprint(sql);
+                // Many texts share a fingerprint, so the cache may hold
+                // more entries than the stats; start over once it is full.
+                if self.fingerprints.len() >= self.max_entries * 4 {
+                    self.fingerprints.clear();
+                }
+                self.fingerprints
+                    .insert(sql.to_string(), fingerprint.clone());
+                fingerprint
+            }
+        };
+        let micros = u64::try_from(duration.as_micros()).unwrap_or(u64::MAX);
+        if !self.rows.contains_key(&fingerprint) && self.rows.len() >= self.max_entries {
+            self.evict_least_called();
+        }
+        let row = self
+            .rows
+            .entry(fingerprint)
+            .or_insert_with_key(|fingerprint| StatementStatsRow {
+                fingerprint: fingerprint.clone(),
+                calls: 0,
+                errors: 0,
+                total_time_us: 0,
+                min_time_us: micros,
+                max_time_us: micros,
+                total_rows: 0,
+                first_seen_unix_ms: now_unix_ms,
+                last_seen_unix_ms: now_unix_ms,
+            });
+        row.calls += 1;
+        if failed {
+            row.errors += 1;
+        }
+        row.total_time_us = row.total_time_us.saturating_add(micros);
+        row.min_time_us = row.min_time_us.min(micros);
+        row.max_time_us = row.max_time_us.max(micros);
+        row.total_rows = row.total_rows.saturating_add(rows);
+        row.last_seen_unix_ms = now_unix_ms;
+    }
+
+    /// Drops the entry with the fewest calls, the oldest on a tie.
+    fn evict_least_called(&mut self) {
+        let victim = self
+            .rows
+            .values()
+            .min_by_key(|row| (row.calls, row.last_seen_unix_ms))
+            .map(|row| row.fingerprint.clone());
+        if let Some(victim) = victim {
+            self.rows.remove(&victim);
+        }
+    }
+
+    /// Returns the entries ordered by total time, busiest first.
+    pub(crate) fn snapshot(&self) -> Vec<StatementStatsRow> {
+        let mut rows: Vec<_> = self.rows.values().cloned().collect();
+        rows.sort_by(|a, b| {
+            b.total_time_us
+                .cmp(&a.total_time_us)
+                .then_with(|| a.fingerprint.cmp(&b.fingerprint))
+        });
+        rows
+    }
+
+    pub(crate) fn reset(&mut self) {
+        self.rows.clear();
+        self.fingerprints.clear();
+    }
+}
+
+type SharedStatementStats = Arc<Mutex<StatementStatsStore>>;
+
+fn shared_store_registry() -> &'static Mutex<HashMap<PathBuf, Weak<Mutex<StatementStatsStore>>>> {
+    static REGISTRY: OnceLock<Mutex<HashMap<PathBuf, Weak<Mutex<StatementStatsStore>>>>> =
+        OnceLock::new();
+    REGISTRY.get_or_init(|| Mutex::new(HashMap::new()))
+}
+
+/// Returns the statement stats store shared by every handle open on
+/// `canonical_path` in this process, creating it from `config` if none is
+/// live. Like a server's statement statistics, the view then covers all
+/// connections to the database rather than only the querying one.
+pub(crate) fn shared_statement_stats(
+    canonical_path: &Path,
+    config: &RuntimeTracingConfig,
+) -> SharedStatementStats {
+    let Ok(mut registry) = shared_store_registry().lock() else {
+        return Arc::new(Mutex::new(StatementStatsStore::new(config)));
+    };
+    if let Some(existing) = registry.get(canonical_path).and_then(Weak::upgrade) {
+        return existing;
+    }
+    registry.retain(|_, store| store.strong_count() > 0);
+    let store = Arc::new(Mutex::new(StatementStatsStore::new(config)));
+    registry.insert(canonical_path.to_path_buf(), Arc::downgrade(&store));
+    store
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::tracing::config::StatementStatsConfig;
+
+    fn store(max_entries: usize) -> StatementStatsStore {
+        StatementStatsStore::new(&RuntimeTracingConfig {
+            enabled: true,
+            statement_stats: StatementStatsConfig {
+                enabled: true,
+                max_entries,
+            },
+            ..Default::default()
+        })
+    }
+
+    #[test]
+    fn disabled_store_records_nothing() {
+        let mut store = StatementStatsStore::new(&RuntimeTracingConfig::default());
+        store.record("SELECT 1", Duration::from_micros(5), 1, false, 1);
+        assert!(store.snapshot().is_empty());
+    }
+
+    #[test]
+    fn statements_aggregate_by_fingerprint() {
+        let mut store = store(16);
+        store.record(
+            "SELECT * FROM t WHERE id = 1",
+            Duration::from_micros(10),
+            1,
+            false,
+            100,
+        );
+        store.record(
+            "select *  from t where id = 42",
+            Duration::from_micros(30),
+            0,
+            true,
+            200,
+        );
+        store.record("DELETE FROM t", Duration::from_micros(5), 3, false, 300);
+
+        let snap = store.snapshot();
+        assert_eq!(snap.len(), 2);
+        let select = &snap[0];
+        assert_eq!(select.fingerprint, "select * from t where id = ?");
+        assert_eq!(select.calls, 2);
+        assert_eq!(select.errors, 1);
+        assert_eq!(select.total_time_us, 40);
+        assert_eq!(select.min_time_us, 10);
+        assert_eq!(select.max_time_us, 30);
+        assert_eq!(select.total_rows, 1);
+        assert_eq!(select.first_seen_unix_ms, 100);
+        assert_eq!(select.last_seen_unix_ms, 200);
+        assert!((select.mean_time_us() - 20.0).abs() < f64::EPSILON);
+        assert_eq!(snap[1].total_rows, 3);
+
+        store.reset();
+        assert!(store.snapshot().is_empty());
+    }
+
+    #[test]
+    fn full_store_evicts_least_called() {
+        let mut store = store(2);
+        for _ in 0..3 {
+            store.record("SELECT a FROM t", Duration::ZERO, 0, false, 1);
+        }
+        store.record("SELECT b FROM t", Duration::ZERO, 0, false, 2);
+        store.record("SELECT c FROM t", Duration::ZERO, 0, false, 3);
+
+        let mut fingerprints: Vec<_> = store
+            .snapshot()
+            .into_iter()
+            .map(|row| row.fingerprint)
+            .collect();
+        fingerprints.sort();
+        assert_eq!(fingerprints, ["select a from t", "select c from t"]);
+    }
+
+    #[test]
+    fn handles_on_one_path_share_a_store() {
+        let config = RuntimeTracingConfig {
+            enabled: true,
+            statement_stats: StatementStatsConfig {
+                enabled: true,
+                max_entries: 8,
+            },
+            ..Default::default()
+        };
+        let path = Path::new("/statement-stats-test/shared.ddb");
+        let first = shared_statement_stats(path, &config);
+        let second = shared_statement_stats(path, &config);
+        assert!(Arc::ptr_eq(&first, &second));
+        let other = shared_statement_stats(Path::new("/statement-stats-test/other.ddb"), &config);
+        assert!(!Arc::ptr_eq(&first, &other));
+    }
+}
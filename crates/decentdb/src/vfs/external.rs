@@ -0,0 +1,563 @@
+//! VFS implemented by the host application through C callbacks.
+//!
+//! Bindings register a method table under a name with `ddb_vfs_register`;
+//! databases opened with `DbConfig::vfs` set to that name route every
+//! database, WAL, and sidecar file operation through it. This lets a host
+//! keep files in memory, inside an encrypted container, or in remote storage
+//! without engine changes.
+//!
+//! Callbacks return `DDB_VFS_OK` on success. `DDB_VFS_ERR_NOT_FOUND` and
+//! `DDB_VFS_ERR_EXISTS` map to the matching I/O error kinds so open-mode
+//! checks behave as they do on the OS filesystem; any other value is a
+//! generic I/O error.
+
+use std::collections::HashMap;
+use std::ffi::{c_char, c_void, CString};
+use std::path::{Path, PathBuf};
+use std::sync::{Arc, Mutex, OnceLock};
+
+use crate::error::{DbError, Result};
+
+use super::{FileKind, OpenMode, Vfs, VfsFile, VfsFileLock};
+
+pub(crate) const DDB_VFS_OK: i32 = 0;
+pub(crate) const DDB_VFS_ERR_NOT_FOUND: i32 = 2;
+pub(crate) const DDB_VFS_ERR_EXISTS: i32 = 3;
+
+/// Callback table for a host-implemented VFS. Files are identified by the
+/// `u64` handle `open` returns. `lock` and `unlock` are optional; without
+/// them the VFS cannot be used with process coordination.
+#[repr(C)]
+#[derive(Clone, Copy, Debug)]
+pub struct DdbVfsMethods {
+    pub user_data: *mut c_void,
+    pub open: Option<unsafe extern "C" fn(*mut c_void, *const c_char, u32, u32, *mut u64) -> i32>,
+    pub exists: Option<unsafe extern "C" fn(*mut c_void, *const c_char, *mut u8) -> i32>,
+    pub remove: Option<unsafe extern "C" fn(*mut c_void, *const c_char) -> i32>,
+    pub read_at:
+        Option<unsafe extern "C" fn(*mut c_void, u64, u64, *mut u8, usize, *mut usize) -> i32>,
+    pub write_at:
+        Option<unsafe extern "C" fn(*mut c_void, u64, u64, *const u8, usize, *mut usize) -> i32>,
+    pub sync: Option<unsafe extern "C" fn(*mut c_void, u64, u8) -> i32>,
+    pub size: Option<unsafe extern "C" fn(*mut c_void, u64, *mut u64) -> i32>,
+    pub truncate: Option<unsafe extern "C" fn(*mut c_void, u64, u64) -> i32>,
+    pub lock: Option<unsafe extern "C" fn(*mut c_void, u64, u64, u64, u8, *mut u8) -> i32>,
+    pub unlock: Option<unsafe extern "C" fn(*mut c_void, u64, u64, u64) -> i32>,
+    pub close: Option<unsafe extern "C" fn(*mut c_void, u64)>,
+}
+
+// SAFETY: the registrant promises that `user_data` and every callback may be
+// used from any thread, as `ddb_vfs_register` documents.
+unsafe impl Send for DdbVfsMethods {}
+// SAFETY: see the `Send` impl above.
+unsafe impl Sync for DdbVfsMethods {}
+
+#[derive(Clone, Debug)]
+pub(crate) struct ExternalVfs {
+    name: Arc<str>,
+    methods: DdbVfsMethods,
+}
+
+fn registry() -> &'static Mutex<HashMap<String, ExternalVfs>> {
+    static REGISTRY: OnceLock<Mutex<HashMap<String, ExternalVfs>>> = OnceLock::new();
+    REGISTRY.get_or_init(|| Mutex::new(HashMap::new()))
+}
+
+/// Registers `methods` under `name`, replacing an earlier registration.
+/// Databases already open keep the table they were opened with.
+pub(crate) fn register_external_vfs(name: &str, methods: DdbVfsMethods) -> Result<()> {
+    if name.is_empty() {
+        return Err(DbError::sql("VFS name must not be empty"));
+    }
+    let missing = [
+        ("open", methods.open.is_none()),
+        ("exists", methods.exists.is_none()),
+        ("remove", methods.remove.is_none()),
+        ("read_at", methods.read_at.is_none()),
+        ("write_at", methods.write_at.is_none()),
+        ("sync", methods.sync.is_none()),
+        ("size", methods.size.is_none()),
+        ("truncate", methods.truncate.is_none()),
+        ("close", methods.close.is_none()),
+    ]
+    .into_iter()
+    .find_map(|(method, missing)| missing.then_some(method));
+    if let Some(method) = missing {
+        return Err(DbError::sql(format!(
+            "VFS {name} is missing its {method} method"
+        )));
+    }
+    if methods.lock.is_some() != methods.unlock.is_some() {
+        return Err(DbError::sql(format!(
+            "VFS {name} must provide both lock and unlock or neither"
+        )));
+    }
+    registry()
+        .lock()
+        .map_err(|_| DbError::internal("external VFS registry poisoned"))?
+        .insert(
+            name.to_string(),
+            ExternalVfs {
+                name: Arc::from(name),
+                methods,
+            },
+        );
+    Ok(())
+}
+
+/// Removes the registration for `name`, returning whether one existed.
+pub(crate) fn unregister_external_vfs(name: &str) -> Result<bool> {
+    Ok(registry()
+        .lock()
+        .map_err(|_| DbError::internal("external VFS registry poisoned"))?
+        .remove(name)
+        .is_some())
+}
+
+pub(crate) fn lookup_external_vfs(name: &str) -> Result<ExternalVfs> {
+    registry()
+        .lock()
+        .map_err(|_| DbError::internal("external VFS registry poisoned"))?
+        .get(name)
+        .cloned()
+        .ok_or_else(|| DbError::sql(format!("no VFS is registered as {name}")))
+}
+
+impl ExternalVfs {
+    fn check(&self, status: i32, op: &str, path: &Path) -> Result<()> {
+        let kind = match status {
+            DDB_VFS_OK => return Ok(()),
+            DDB_VFS_ERR_NOT_FOUND => std::io::ErrorKind::NotFound,
+            DDB_VFS_ERR_EXISTS => std::io::ErrorKind::AlreadyExists,
+            _ => std::io::ErrorKind::Other,
+        };
+        Err(DbError::io(
+            format!("{op} {} through VFS {}", path.display(), self.name),
+            std::io::Error::new(kind, format!("VFS callback returned {status}")),
+        ))
+    }
+}
+
+fn c_path(path: &Path) -> Result<CString> {
+    CString::new(path.to_string_lossy().as_bytes())
+        .map_err(|_| DbError::sql(format!("path {} contains a NUL byte", path.display())))
+}
+
+impl Vfs for ExternalVfs {
+    fn open(&self, path: &Path, mode: OpenMode, kind: FileKind) -> Result<Arc<dyn VfsFile>> {
+        let c_path = c_path(path)?;
+        let mode_code = match mode {
+            OpenMode::CreateNew => 0,
+            OpenMode::OpenExisting => 1,
+            OpenMode::OpenOrCreate => 2,
+        };
+        let kind_code = match kind {
+            FileKind::Database => 0,
+            FileKind::Wal => 1,
+            FileKind::SyncJournal => 2,
+            FileKind::Coordination => 3,
+        };
+        let open = self.methods.open.expect("validated at registration");
+        let mut handle = 0_u64;
+        // SAFETY: the callback receives a valid C string and out-pointer for
+        // the duration of the call.
+        let status = unsafe {
+            open(
+                self.methods.user_data,
+                c_path.as_ptr(),
+                mode_code,
+                kind_code,
+                &mut handle,
+            )
+        };
+        self.check(status, "open", path)?;
+        Ok(Arc::new(ExternalVfsFile {
+            vfs: self.clone(),
+            path: path.to_path_buf(),
+            kind,
+            handle,
+        }))
+    }
+
+    fn file_exists(&self, path: &Path) -> Result<bool> {
+        let c_path = c_path(path)?;
+        let exists = self.methods.exists.expect("validated at registration");
+        let mut flag = 0_u8;
+        // SAFETY: see `open`.
+        let status = unsafe { exists(self.methods.user_data, c_path.as_ptr(), &mut flag) };
+        self.check(status, "stat", path)?;
+        Ok(flag != 0)
+    }
+
+    fn remove_file(&self, path: &Path) -> Result<()> {
+        let c_path = c_path(path)?;
+        let remove = self.methods.remove.expect("validated at registration");
+        // SAFETY: see `open`.
+        let status = unsafe { remove(self.methods.user_data, c_path.as_ptr()) };
+        self.check(status, "remove", path)
+    }
+
+    fn canonicalize_path(&self, path: &Path) -> Result<PathBuf> {
+        Ok(path.to_path_buf())
+    }
+
+    fn supports_file_locks(&self) -> bool {
+        self.methods.lock.is_some()
+    }
+}
+
+#[derive(Debug)]
+struct ExternalVfsFile {
+    vfs: ExternalVfs,
+    path: PathBuf,
+    kind: FileKind,
+    handle: u64,
+}
+
+impl VfsFile for ExternalVfsFile {
+    fn kind(&self) -> FileKind {
+        self.kind
+    }
+
+    fn path(&self) -> &Path {
+        &self.path
+    }
+
+    fn read_at(&self, offset: u64, buf: &mut [u8]) -> Result<usize> {
+        let read_at = self.vfs.methods.read_at.expect("validated at registration");
+        let mut read = 0_usize;
+        // SAFETY: `buf` is valid for `buf.len()` writable bytes during the call.
+        let status = unsafe {
+            read_at(
+                self.vfs.methods.user_data,
+                self.handle,
+                offset,
+                buf.as_mut_ptr(),
+                buf.len(),
+                &mut read,
+            )
+        };
+        self.vfs.check(status, "read", &self.path)?;
+        Ok(read.min(buf.len()))
+    }
+
+    fn write_at(&self, offset: u64, buf: &[u8]) -> Result<usize> {
+        let write_at = self
+            .vfs
+            .methods
+            .write_at
+            .expect("validated at registration");
+        let mut written = 0_usize;
+        // SAFETY: `buf` is valid for `buf.len()` readable bytes during the call.
+        let status = unsafe {
+            write_at(
+                self.vfs.methods.user_data,
+                self.handle,
+                offset,
+                buf.as_ptr(),
+                buf.len(),
+                &mut written,
+            )
+        };
+        self.vfs.check(status, "write", &self.path)?;
+        Ok(written.min(buf.len()))
+    }
+
+    fn advise_sequential(&self) -> Result<()> {
+        Ok(())
+    }
+
+    fn sync_data(&self) -> Result<()> {
+        self.sync(false)
+    }
+
+    fn sync_metadata(&self) -> Result<()> {
+        self.sync(true)
+    }
+
+    fn file_size(&self) -> Result<u64> {
+        let size = self.vfs.methods.size.expect("validated at registration");
+        let mut len = 0_u64;
+        // SAFETY: the out-pointer is valid for the duration of the call.
+        let status = unsafe { size(self.vfs.methods.user_data, self.handle, &mut len) };
+        self.vfs.check(status, "stat", &self.path)?;
+        Ok(len)
+    }
+
+    fn set_len(&self, len: u64) -> Result<()> {
+        let truncate = self
+            .vfs
+            .methods
+            .truncate
+            .expect("validated at registration");
+        // SAFETY: plain values only.
+        let status = unsafe { truncate(self.vfs.methods.user_data, self.handle, len) };
+        self.vfs.check(status, "truncate", &self.path)
+    }
+
+    fn try_lock_range(
+        &self,
+        offset: u64,
+        len: u64,
+        exclusive: bool,
+    ) -> Result<Option<Box<dyn VfsFileLock>>> {
+        let Some(lock) = self.vfs.methods.lock else {
+            return Err(DbError::transaction(format!(
+                "VFS {} does not support process coordination locks",
+                self.vfs.name
+            )));
+        };
+        let mut acquired = 0_u8;
+        // SAFETY: the out-pointer is valid for the duration of the call.
+        let status = unsafe {
+            lock(
+                self.vfs.methods.user_data,
+                self.handle,
+                offset,
+                len,
+                u8::from(exclusive),
+                &mut acquired,
+            )
+        };
+        self.vfs.check(status, "lock", &self.path)?;
+        if acquired == 0 {
+            return Ok(None);
+        }
+        Ok(Some(Box::new(ExternalVfsLock {
+            vfs: self.vfs.clone(),
+            handle: self.handle,
+            offset,
+            len,
+        })))
+    }
+}
+
+impl ExternalVfsFile {
+    fn sync(&self, metadata: bool) -> Result<()> {
+        let sync = self.vfs.methods.sync.expect("validated at registration");
+        // SAFETY: plain values only.
+        let status = unsafe { sync(self.vfs.methods.user_data, self.handle, u8::from(metadata)) };
+        self.vfs.check(status, "sync", &self.path)
+    }
+}
+
+impl Drop for ExternalVfsFile {
+    fn drop(&mut self) {
+        let close = self.vfs.methods.close.expect("validated at registration");
+        // SAFETY: the handle came from this VFS's `open` and is closed once.
+        unsafe { close(self.vfs.methods.user_data, self.handle) };
+    }
+}
+
+#[derive(Debug)]
+struct ExternalVfsLock {
+    vfs: ExternalVfs,
+    handle: u64,
+    offset: u64,
+    len: u64,
+}
+
+impl VfsFileLock for ExternalVfsLock {}
+
+impl Drop for ExternalVfsLock {
+    fn drop(&mut self) {
+        if let Some(unlock) = self.vfs.methods.unlock {
+            // SAFETY: plain values only; the range was locked by `lock`.
+            let _ = unsafe {
+                unlock(
+                    self.vfs.methods.user_data,
+                    self.handle,
+                    self.offset,
+                    self.len,
+                )
+            };
+        }
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use std::collections::HashMap;
+    use std::ffi::{c_char, c_void, CStr};
+    use std::sync::Mutex;
+
+    use super::{
+        register_external_vfs, unregister_external_vfs, DdbVfsMethods, DDB_VFS_ERR_EXISTS,
+        DDB_VFS_ERR_NOT_FOUND, DDB_VFS_OK,
+    };
+    use crate::{Db, DbConfig};
+
+    #[derive(Default)]
+    struct Store {
+        files: HashMap<String, Vec<u8>>,
+        handles: HashMap<u64, String>,
+        next_handle: u64,
+        writes: u64,
+    }
+
+    fn store(user_data: *mut c_void) -> &'static Mutex<Store> {
+        // SAFETY: tests pass a leaked `Mutex<Store>` as user data.
+        unsafe { &*(user_data as *const Mutex<Store>) }
+    }
+
+    unsafe extern "C" fn open(
+        user_data: *mut c_void,
+        path: *const c_char,
+        mode: u32,
+        _kind: u32,
+        out_file: *mut u64,
+    ) -> i32 {
+        let path = CStr::from_ptr(path).to_string_lossy().into_owned();
+        let mut store = store(user_data).lock().unwrap();
+        match (mode, store.files.contains_key(&path)) {
+            (0, true) => return DDB_VFS_ERR_EXISTS,
+            (1, false) => return DDB_VFS_ERR_NOT_FOUND,
+            _ => {}
+        }
+        store.files.entry(path.clone()).or_default();
+        store.next_handle += 1;
+        let handle = store.next_handle;
+        store.handles.insert(handle, path);
+        *out_file = handle;
+        DDB_VFS_OK
+    }
+
+    unsafe extern "C" fn exists(
+        user_data: *mut c_void,
+        path: *const c_char,
+        out_exists: *mut u8,
+    ) -> i32 {
+        let path = CStr::from_ptr(path).to_string_lossy();
+        *out_exists = u8::from(store(user_data).lock().unwrap().files.contains_key(&*path));
+        DDB_VFS_OK
+    }
+
+    unsafe extern "C" fn remove(user_data: *mut c_void, path: *const c_char) -> i32 {
+        let path = CStr::from_ptr(path).to_string_lossy();
+        store(user_data).lock().unwrap().files.remove(&*path);
+        DDB_VFS_OK
+    }
+
+    unsafe extern "C" fn read_at(
+        user_data: *mut c_void,
+        file: u64,
+        offset: u64,
+        buf: *mut u8,
+        len: usize,
+        out_read: *mut usize,
+    ) -> i32 {
+        let store = store(user_data).lock().unwrap();
+        let data = &store.files[&store.handles[&file]];
+        let start = (offset as usize).min(data.len());
+        let n = len.min(data.len() - start);
+        std::ptr::copy_nonoverlapping(data[start..].as_ptr(), buf, n);
+        *out_read = n;
+        DDB_VFS_OK
+    }
+
+    unsafe extern "C" fn write_at(
+        user_data: *mut c_void,
+        file: u64,
+        offset: u64,
+        buf: *const u8,
+        len: usize,
+        out_written: *mut usize,
+    ) -> i32 {
+        let mut store = store(user_data).lock().unwrap();
+        store.writes += 1;
+        let path = store.handles[&file].clone();
+        let data = store.files.get_mut(&path).unwrap();
+        let end = offset as usize + len;
+        if data.len() < end {
+            data.resize(end, 0);
+        }
+        std::ptr::copy_nonoverlapping(buf, data[offset as usize..].as_mut_ptr(), len);
+        *out_written = len;
+        DDB_VFS_OK
+    }
+
+    unsafe extern "C" fn sync(_user_data: *mut c_void, _file: u64, _metadata: u8) -> i32 {
+        DDB_VFS_OK
+    }
+
+    unsafe extern "C" fn size(user_data: *mut c_void, file: u64, out_size: *mut u64) -> i32 {
+        let store = store(user_data).lock().unwrap();
+        *out_size = store.files[&store.handles[&file]].len() as u64;
+        DDB_VFS_OK
+    }
+
+    unsafe extern "C" fn truncate(user_data: *mut c_void, file: u64, len: u64) -> i32 {
+        let mut store = store(user_data).lock().unwrap();
+        let path = store.handles[&file].clone();
+        store.files.get_mut(&path).unwrap().resize(len as usize, 0);
+        DDB_VFS_OK
+    }
+
+    unsafe extern "C" fn close(user_data: *mut c_void, file: u64) {
+        store(user_data).lock().unwrap().handles.remove(&file);
+    }
+
+    fn methods(user_data: &'static Mutex<Store>) -> DdbVfsMethods {
+        DdbVfsMethods {
+            user_data: user_data as *const Mutex<Store> as *mut c_void,
+            open: Some(open),
+            exists: Some(exists),
+            remove: Some(remove),
+            read_at: Some(read_at),
+            write_at: Some(write_at),
+            sync: Some(sync),
+            size: Some(size),
+            truncate: Some(truncate),
+            lock: None,
+            unlock: None,
+            close: Some(close),
+        }
+    }
+
+    #[test]
+    fn external_vfs_round_trips_a_database() {
+        let backing: &'static Mutex<Store> = Box::leak(Box::default());
+        register_external_vfs("external-vfs-test", methods(backing)).expect("register");
+        let config = || DbConfig {
+            vfs: Some("external-vfs-test".to_string()),
+            ..DbConfig::default()
+        };
+
+        let db = Db::open_or_create("/virtual/app.ddb", config()).expect("create");
+        db.execute("CREATE TABLE t (id INT PRIMARY KEY, v TEXT)")
+            .expect("create table");
+        db.execute("INSERT INTO t VALUES (1, 'one'), (2, 'two')")
+            .expect("insert");
+        db.checkpoint().expect("checkpoint");
+        drop(db);
+
+        {
+            let store = backing.lock().unwrap();
+            assert!(store.files.contains_key("/virtual/app.ddb"));
+            assert!(store.writes > 0);
+            assert!(store.handles.is_empty(), "every file should be closed");
+        }
+        assert!(!std::path::Path::new("/virtual/app.ddb").exists());
+
+        let db = Db::open("/virtual/app.ddb", config()).expect("reopen");
+        let result = db.execute("SELECT v FROM t WHERE id = 2").expect("select");
+        assert_eq!(
+            result.rows()[0].values()[0],
+            crate::Value::Text("two".into())
+        );
+        drop(db);
+
+        assert!(unregister_external_vfs("external-vfs-test").expect("unregister"));
+        let err = Db::open("/virtual/app.ddb", config()).expect_err("unregistered");
+        assert!(err.to_string().contains("no VFS is registered"));
+    }
+
+    #[test]
+    fn registration_requires_core_methods() {
+        let backing: &'static Mutex<Store> = Box::leak(Box::default());
+        let mut incomplete = methods(backing);
+        incomplete.read_at = None;
+        let err = register_external_vfs("incomplete", incomplete).expect_err("missing read_at");
+        assert!(err.to_string().contains("read_at"));
+    }
+}
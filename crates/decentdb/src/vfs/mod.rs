@@ -5,6 +5,7 @@
 //! - design/adr/0105-in-memory-vfs.md
 
 pub(crate) mod encrypted;
+pub(crate) mod external;
 pub(crate) mod faulty;
 pub(crate) mod mem;
 #[cfg(all(target_arch = "wasm32", target_os = "unknown"))]
@@ -137,6 +138,17 @@ impl VfsHandle {
         }
     }
 
+    /// Resolves the base VFS for `path`, honoring `config.vfs` when set.
+    /// Encryption is layered on separately by `with_config`.
+    pub(crate) fn for_config(path: &Path, config: &DbConfig) -> Result<Self> {
+        match &config.vfs {
+            Some(name) => Ok(Self {
+                inner: Arc::new(external::lookup_external_vfs(name)?),
+            }),
+            None => Ok(Self::for_path(path)),
+        }
+    }
+
     pub(crate) fn with_config(self, config: &DbConfig) -> Self {
         if let Some(encryption) = &config.encryption {
             Self {
@@ -165,6 +177,10 @@ impl VfsHandle {
         self.inner.file_exists(path)
     }
 
+    pub(crate) fn remove_file(&self, path: &Path) -> Result<()> {
+        self.inner.remove_file(path)
+    }
+
     pub(crate) fn canonicalize_path(&self, path: &Path) -> Result<PathBuf> {
         self.inner.canonicalize_path(path)
     }
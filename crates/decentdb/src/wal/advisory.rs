@@ -0,0 +1,168 @@
+//! Advisory locks.
+//!
+//! Advisory locks are application-chosen 64-bit keys that cooperating
+//! handles lock to elect a leader or run a singleton job; the engine never
+//! takes them itself. Handles in one process share the registry through the
+//! shared WAL, and other processes are excluded by a byte-range lock on the
+//! coordination sidecar when process coordination is on.
+
+use std::collections::HashMap;
+use std::sync::atomic::{AtomicU64, Ordering};
+use std::sync::{Arc, Mutex};
+use std::time::{Duration, Instant};
+
+use crate::error::{DbError, Result};
+use crate::exec::interrupt::check_interrupt;
+use crate::vfs::VfsFileLock;
+
+use super::coordination::ProcessCoordinator;
+use super::WalHandle;
+
+#[derive(Debug, Default)]
+pub(crate) struct AdvisoryLocks {
+    held: Mutex<HashMap<i64, AdvisoryHold>>,
+}
+
+#[derive(Debug)]
+struct AdvisoryHold {
+    owner: u64,
+    count: usize,
+    _lock: Option<Box<dyn VfsFileLock>>,
+}
+
+impl AdvisoryLocks {
+    /// Takes `key` for `owner` without waiting and reports whether it was
+    /// taken. Locks are reentrant: every successful call needs a matching
+    /// `unlock`.
+    pub(crate) fn try_lock(
+        &self,
+        coordinator: Option<&ProcessCoordinator>,
+        owner: u64,
+        key: i64,
+    ) -> Result<bool> {
+        let mut held = self.held()?;
+        if let Some(hold) = held.get_mut(&key) {
+            if hold.owner != owner {
+                return Ok(false);
+            }
+            hold.count += 1;
+            return Ok(true);
+        }
+        let lock = match coordinator {
+            Some(coordinator) => match coordinator.try_lock_advisory(key)? {
+                Some(lock) => Some(lock),
+                None => return Ok(false),
+            },
+            None => None,
+        };
+        held.insert(
+            key,
+            AdvisoryHold {
+                owner,
+                count: 1,
+                _lock: lock,
+            },
+        );
+        Ok(true)
+    }
+
+    /// Releases one hold of `key` by `owner`; returns false if `owner` does
+    /// not hold it.
+    pub(crate) fn unlock(&self, owner: u64, key: i64) -> Result<bool> {
+        let mut held = self.held()?;
+        let Some(hold) = held.get_mut(&key).filter(|hold| hold.owner == owner) else {
+            return Ok(false);
+        };
+        hold.count -= 1;
+        if hold.count == 0 {
+            held.remove(&key);
+        }
+        Ok(true)
+    }
+
+    /// Releases every lock `owner` holds, as when its handle closes.
+    pub(crate) fn unlock_all(&self, owner: u64) {
+        if let Ok(mut held) = self.held.lock() {
+            held.retain(|_, hold| hold.owner != owner);
+        }
+    }
+
+    fn held(&self) -> Result<std::sync::MutexGuard<'_, HashMap<i64, AdvisoryHold>>> {
+        self.held
+            .lock()
+            .map_err(|_| DbError::internal("advisory lock registry poisoned"))
+    }
+}
+
+/// One handle's view of the advisory locks: the registry it locks in, the
+/// owner it holds locks as, and its busy timeout. The engine runtime carries
+/// it so the `advisory_lock` SQL functions act for the handle running them.
+#[derive(Clone, Debug)]
+pub(crate) struct AdvisoryLockScope {
+    wal: WalHandle,
+    owner: u64,
+    busy_timeout_ms: Arc<AtomicU64>,
+}
+
+impl AdvisoryLockScope {
+    pub(crate) fn new(wal: WalHandle, owner: u64, busy_timeout_ms: Arc<AtomicU64>) -> Self {
+        Self {
+            wal,
+            owner,
+            busy_timeout_ms,
+        }
+    }
+
+    pub(crate) fn try_lock(&self, key: i64) -> Result<bool> {
+        self.wal.try_advisory_lock(self.owner, key)
+    }
+
+    /// Takes `key`, waiting up to `timeout` for its holder to release it, or
+    /// indefinitely when `timeout` is `None`. An interrupted statement stops
+    /// waiting with `DbError::canceled`.
+    pub(crate) fn lock(&self, key: i64, timeout: Option<Duration>) -> Result<()> {
+        let started = Instant::now();
+        let mut backoff = Duration::from_micros(100);
+        while !self.try_lock(key)? {
+            check_interrupt()?;
+            if timeout.is_some_and(|timeout| started.elapsed() >= timeout) {
+                return Err(DbError::timeout(format!(
+                    "timed out waiting for advisory lock {key}"
+                )));
+            }
+            std::thread::sleep(backoff);
+            backoff = (backoff * 2).min(Duration::from_millis(5));
+        }
+        Ok(())
+    }
+
+    /// The handle's busy timeout, or `None` when it waits indefinitely.
+    pub(crate) fn busy_timeout(&self) -> Option<Duration> {
+        let timeout_ms = self.busy_timeout_ms.load(Ordering::Acquire);
+        (timeout_ms > 0).then(|| Duration::from_millis(timeout_ms))
+    }
+
+    pub(crate) fn unlock(&self, key: i64) -> Result<bool> {
+        self.wal.advisory_unlock(self.owner, key)
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn advisory_locks_are_reentrant_per_owner() {
+        let locks = AdvisoryLocks::default();
+        assert!(locks.try_lock(None, 1, 42).unwrap());
+        assert!(locks.try_lock(None, 1, 42).unwrap());
+        assert!(!locks.try_lock(None, 2, 42).unwrap());
+        assert!(!locks.unlock(2, 42).unwrap());
+        assert!(locks.unlock(1, 42).unwrap());
+        assert!(!locks.try_lock(None, 2, 42).unwrap());
+        assert!(locks.unlock(1, 42).unwrap());
+        assert!(locks.try_lock(None, 2, 42).unwrap());
+        locks.unlock_all(2);
+        assert!(locks.try_lock(None, 1, 42).unwrap());
+    }
+}
@@ -0,0 +1,74 @@
+//! WAL segment archiving.
+//!
+//! A segment is the WAL content a checkpoint is about to truncate: every
+//! frame committed since the previous truncation, ending with the checkpoint
+//! frame. While archiving is enabled, each truncating checkpoint captures the
+//! segment into a bounded queue that callers drain with
+//! `Db::next_wal_segment`. When the queue is full, checkpoints leave the WAL
+//! in place until the archiver catches up, so no committed frame is dropped
+//! before it has been handed out.
+
+use std::collections::VecDeque;
+
+use crate::error::Result;
+use crate::vfs::read_exact_at;
+
+use super::format::WAL_HEADER_SIZE;
+use super::WalHandle;
+
+/// One archived WAL segment.
+///
+/// `frames` holds the WAL file image up to `end_lsn`, header included, so a
+/// segment can be written out as-is and replayed against a copy of the
+/// database taken at the previous segment's end.
+#[derive(Clone, Debug, Eq, PartialEq)]
+pub struct WalSegment {
+    /// Position of this segment in the handle's archive stream, starting at 1.
+    pub sequence: u64,
+    /// LSN of the first frame in the segment.
+    pub start_lsn: u64,
+    /// LSN just past the last frame, i.e. the checkpoint frame's end.
+    pub end_lsn: u64,
+    pub page_size: u32,
+    pub frames: Vec<u8>,
+}
+
+#[derive(Debug)]
+pub(crate) struct WalArchive {
+    capacity: usize,
+    next_sequence: u64,
+    pending: VecDeque<WalSegment>,
+}
+
+impl WalArchive {
+    pub(crate) fn new(capacity: usize) -> Self {
+        Self {
+            capacity: capacity.max(1),
+            next_sequence: 1,
+            pending: VecDeque::new(),
+        }
+    }
+
+    pub(crate) fn is_full(&self) -> bool {
+        self.pending.len() >= self.capacity
+    }
+
+    pub(crate) fn pop(&mut self) -> Option<WalSegment> {
+        self.pending.pop_front()
+    }
+
+    /// Copies the WAL image up to `end_lsn` into the queue.
+    pub(crate) fn capture(&mut self, wal: &WalHandle, end_lsn: u64) -> Result<()> {
+        let mut frames = vec![0_u8; end_lsn as usize];
+        read_exact_at(wal.inner.file.as_ref(), 0, &mut frames)?;
+        self.pending.push_back(WalSegment {
+            sequence: self.next_sequence,
+            start_lsn: WAL_HEADER_SIZE,
+            end_lsn,
+            page_size: wal.inner.page_size,
+            frames,
+        });
+        self.next_sequence += 1;
+        Ok(())
+    }
+}
@@ -173,9 +173,8 @@ fn worker_loop(ctrl: Arc<BgCtrl>, weak: Weak<SharedWalInner>, pager: PagerHandle
 /// `pub(super)` visibility in a sibling module and to keep the BG worker
 /// self-contained.
 fn run_checkpoint_if_needed(wal: &WalHandle, pager: &PagerHandle) -> crate::error::Result<()> {
-    let cfg = wal.inner.auto_checkpoint;
-    let pages_threshold = cfg.threshold_pages;
-    let bytes_threshold = cfg.threshold_bytes;
+    let cfg = &wal.inner.auto_checkpoint;
+    let (pages_threshold, bytes_threshold) = cfg.thresholds();
     if pages_threshold == 0 && bytes_threshold == 0 {
         return Ok(());
     }
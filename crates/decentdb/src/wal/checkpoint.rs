@@ -9,6 +9,7 @@ use std::sync::atomic::Ordering;
 use crate::error::Result;
 use crate::storage::PagerHandle;
 
+use super::format::WAL_HEADER_SIZE;
 use super::writer;
 use super::WalHandle;
 
@@ -46,6 +47,17 @@ pub(crate) fn checkpoint(wal: &WalHandle, pager: &PagerHandle, timeout_sec: u64)
             .expect("wal index lock should not be poisoned");
     }
 
+    let mut archive = wal
+        .inner
+        .archive
+        .lock()
+        .expect("wal archive lock should not be poisoned");
+    if archive.as_ref().is_some_and(|archive| archive.is_full()) {
+        // Truncating now would discard frames the archiver has not been
+        // handed yet; keep the WAL until the queue drains.
+        return Ok(());
+    }
+
     let current_lsn = wal.latest_snapshot();
     let active_reader_lsn = wal.inner.reader_registry.min_snapshot_lsn()?;
     let retained_snapshot_lsn = wal.retained_snapshot_lsn();
@@ -105,9 +117,18 @@ pub(crate) fn checkpoint(wal: &WalHandle, pager: &PagerHandle, timeout_sec: u64)
     } else {
         wal.reset_max_page_count(pager.on_disk_page_count()?);
     }
+    // Checksums recorded during copyback become trusted only once the pages
+    // they describe are on disk.
+    pager.commit_page_checksums(safe_lsn)?;
     pager.set_last_checkpoint_lsn(safe_lsn)?;
 
-    let _checkpoint_end = writer::append_checkpoint_frame(wal, safe_lsn)?;
+    let checkpoint_end = writer::append_checkpoint_frame(wal, safe_lsn)?;
+    if let Some(archive) = archive.as_mut() {
+        if current_lsn > WAL_HEADER_SIZE {
+            archive.capture(wal, checkpoint_end)?;
+        }
+    }
+    drop(archive);
 
     {
         let mut index = wal
@@ -33,7 +33,17 @@ const READER_SLOT_CHECKSUM_OFFSET: usize = READER_SLOT_LEN as usize - 4;
 const INIT_LOCK_OFFSET: u64 = 0;
 const WRITER_LOCK_OFFSET: u64 = 1;
 const META_LOCK_OFFSET: u64 = 2;
+/// Held shared by every process with a coordinated handle open, or
+/// exclusively by a `process_coordination=exclusive` handle.
+const HANDLE_LOCK_OFFSET: u64 = 3;
 const READER_LOCK_BASE: u64 = 4096;
+/// Advisory lock keys map to single bytes above this offset, far past the
+/// header, reader slots, and their locks.
+const ADVISORY_LOCK_BASE: u64 = 1 << 61;
+const ADVISORY_LOCK_KEY_MASK: u64 = (1 << 60) - 1;
+/// Table locks map a hash of the table name to single bytes above this
+/// offset, clear of the advisory range.
+const TABLE_LOCK_BASE: u64 = 1 << 62;
 
 const READER_STATE_EMPTY: u8 = 0;
 const READER_STATE_ACTIVE: u8 = 1;
@@ -61,6 +71,7 @@ struct ProcessCoordinatorInner {
     metrics: ProcessCoordinationMetrics,
     #[allow(clippy::type_complexity)]
     lock_wait_callback: Mutex<Option<Arc<dyn Fn(bool, Duration, &str) + Send + Sync>>>,
+    _registration: HandleRegistration,
 }
 
 impl std::fmt::Debug for ProcessCoordinatorInner {
@@ -177,6 +188,23 @@ pub(crate) struct ProcessReaderGuard {
     _lock: Option<Box<dyn VfsFileLock>>,
 }
 
+/// Coordinated handles open on one database in this process. Byte-range
+/// locks belong to the process, so the first handle takes the handle lock
+/// and opens the sidecar for all of them, and exclusivity between handles
+/// in the process is decided here.
+struct OpenHandles {
+    exclusive: bool,
+    count: usize,
+    _lock: Box<dyn VfsFileLock>,
+    file: Arc<dyn VfsFile>,
+}
+
+/// Counts one handle in `OpenHandles` until dropped.
+#[derive(Debug)]
+struct HandleRegistration {
+    key: PathBuf,
+}
+
 #[derive(Clone, Debug, Eq, Hash, PartialEq)]
 struct ReaderSlotKey {
     coord_path: PathBuf,
@@ -206,23 +234,30 @@ impl ProcessCoordinator {
             return Ok(None);
         }
         if vfs.is_memory() {
-            if mode == ProcessCoordinationMode::Required {
-                return Err(DbError::transaction(
-                    "process_coordination=required is not supported for in-memory databases",
-                ));
+            if mode.requires_coordination() {
+                return Err(DbError::transaction(format!(
+                    "process_coordination={} is not supported for in-memory databases",
+                    mode.as_str()
+                )));
             }
             return Ok(None);
         }
         #[cfg(all(target_arch = "wasm32", target_os = "unknown"))]
         {
-            if mode == ProcessCoordinationMode::Required {
-                return Err(DbError::transaction(
-                    "process_coordination=required is not supported by the wasm OPFS runtime",
-                ));
+            if mode.requires_coordination() {
+                return Err(DbError::transaction(format!(
+                    "process_coordination={} is not supported by the wasm OPFS runtime",
+                    mode.as_str()
+                )));
             }
             return Ok(None);
         }
         if !vfs.supports_file_locks() {
+            // Host VFSes registered without lock callbacks cannot coordinate
+            // processes; in auto mode they run single-process like memory.
+            if mode == ProcessCoordinationMode::Auto {
+                return Ok(None);
+            }
             return Err(DbError::transaction(format!(
                 "process coordination requires native local file locks for {}",
                 db_path.display()
@@ -235,13 +270,14 @@ impl ProcessCoordinator {
         }
 
         let coord_path = coord_path_for_db(db_path);
-        let file = vfs.open(&coord_path, OpenMode::OpenOrCreate, FileKind::Coordination)?;
+        let timeout = Duration::from_millis(timeout_ms);
+        let (file, registration) = register_handle(vfs, db_path, &coord_path, mode, timeout)?;
         let coordinator = Self {
             inner: Arc::new(ProcessCoordinatorInner {
                 file,
                 coord_path,
                 mode,
-                timeout: Some(Duration::from_millis(timeout_ms)),
+                timeout: Some(timeout),
                 process_id: current_process_id(),
                 process_token: random_process_token(),
                 database_id: header.database_id,
@@ -254,6 +290,7 @@ impl ProcessCoordinator {
                 ),
                 metrics: ProcessCoordinationMetrics::default(),
                 lock_wait_callback: Mutex::new(None),
+                _registration: registration,
             }),
         };
         coordinator.initialize_or_rebuild()?;
@@ -381,6 +418,26 @@ impl ProcessCoordinator {
         self.lock_writer_inner(true)
     }
 
+    /// Tries to take the cross-process lock for advisory key `key` without
+    /// waiting. Keys that agree in their low 60 bits share a lock byte.
+    pub(crate) fn try_lock_advisory(&self, key: i64) -> Result<Option<Box<dyn VfsFileLock>>> {
+        self.inner
+            .file
+            .try_lock_range(advisory_lock_offset(key), 1, true)
+    }
+
+    /// Tries to take the cross-process lock for `table`, exclusively for
+    /// `LOCK TABLE` or shared to probe whether another process holds it.
+    pub(crate) fn try_lock_table(
+        &self,
+        table: &str,
+        exclusive: bool,
+    ) -> Result<Option<Box<dyn VfsFileLock>>> {
+        self.inner
+            .file
+            .try_lock_range(table_lock_offset(table), 1, exclusive)
+    }
+
     #[allow(dead_code)]
     #[allow(clippy::type_complexity)]
     pub(crate) fn set_lock_wait_callback(
@@ -935,6 +992,96 @@ impl Drop for ProcessReaderGuard {
     }
 }
 
+impl Drop for HandleRegistration {
+    fn drop(&mut self) {
+        let Ok(mut handles) = open_handles().lock() else {
+            return;
+        };
+        let Some(entry) = handles.get_mut(&self.key) else {
+            return;
+        };
+        entry.count -= 1;
+        if entry.count == 0 {
+            handles.remove(&self.key);
+        }
+    }
+}
+
+/// Registers a coordinated handle on `db_path` and returns the sidecar file
+/// shared by the process's handles. The first handle in the process takes
+/// the handle lock, waiting up to `timeout`; an exclusive handle and any
+/// other handle on the same database fail with `DbError::Timeout`.
+fn register_handle(
+    vfs: &VfsHandle,
+    db_path: &Path,
+    coord_path: &Path,
+    mode: ProcessCoordinationMode,
+    timeout: Duration,
+) -> Result<(Arc<dyn VfsFile>, HandleRegistration)> {
+    let key = coord_path_for_db(&vfs.canonicalize_path(db_path)?);
+    let exclusive = mode == ProcessCoordinationMode::Exclusive;
+    let mut handles = open_handles()
+        .lock()
+        .map_err(|_| DbError::internal("process handle registry poisoned"))?;
+    if let Some(entry) = handles.get_mut(&key) {
+        if entry.exclusive {
+            return Err(DbError::timeout(format!(
+                "database {} is held by an exclusive handle in this process",
+                db_path.display()
+            )));
+        }
+        if exclusive {
+            return Err(DbError::timeout(format!(
+                "process_coordination=exclusive cannot open {}: another handle in this process has it open",
+                db_path.display()
+            )));
+        }
+        entry.count += 1;
+        return Ok((Arc::clone(&entry.file), HandleRegistration { key }));
+    }
+
+    // Opening the sidecar only when no handle in the process has it open
+    // matters: closing any descriptor drops the process's POSIX locks.
+    let file = vfs.open(coord_path, OpenMode::OpenOrCreate, FileKind::Coordination)?;
+    let lock = match lock_range_with_timeout(
+        file.as_ref(),
+        HANDLE_LOCK_OFFSET,
+        1,
+        exclusive,
+        Some(timeout),
+    ) {
+        Ok(lock) => lock,
+        Err(DbError::Busy { .. } | DbError::Timeout { .. }) if exclusive => {
+            return Err(DbError::timeout(format!(
+                "process_coordination=exclusive cannot open {}: another process has it open",
+                db_path.display()
+            )));
+        }
+        Err(DbError::Busy { .. } | DbError::Timeout { .. }) => {
+            return Err(DbError::timeout(format!(
+                "database {} is held by an exclusive handle in another process",
+                db_path.display()
+            )));
+        }
+        Err(error) => return Err(error),
+    };
+    handles.insert(
+        key.clone(),
+        OpenHandles {
+            exclusive,
+            count: 1,
+            _lock: lock,
+            file: Arc::clone(&file),
+        },
+    );
+    Ok((file, HandleRegistration { key }))
+}
+
+fn open_handles() -> &'static Mutex<HashMap<PathBuf, OpenHandles>> {
+    static HANDLES: OnceLock<Mutex<HashMap<PathBuf, OpenHandles>>> = OnceLock::new();
+    HANDLES.get_or_init(|| Mutex::new(HashMap::new()))
+}
+
 fn coord_path_for_db(db_path: &Path) -> PathBuf {
     let mut path = db_path.as_os_str().to_os_string();
     path.push(".coord");
@@ -1078,6 +1225,17 @@ fn reader_lock_offset(slot: u16) -> u64 {
     READER_LOCK_BASE + u64::from(slot)
 }
 
+fn advisory_lock_offset(key: i64) -> u64 {
+    ADVISORY_LOCK_BASE + (key as u64 & ADVISORY_LOCK_KEY_MASK)
+}
+
+fn table_lock_offset(table: &str) -> u64 {
+    let digest = Sha256::digest(table.as_bytes());
+    let mut hash = [0_u8; 8];
+    hash.copy_from_slice(&digest[..8]);
+    TABLE_LOCK_BASE + (u64::from_le_bytes(hash) & ADVISORY_LOCK_KEY_MASK)
+}
+
 fn current_process_id() -> u64 {
     u64::from(std::process::id())
 }
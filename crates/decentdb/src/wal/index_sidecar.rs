@@ -192,7 +192,7 @@ struct SidecarRecord {
     encoding: FrameEncoding,
 }
 
-fn sidecar_path_for_db(db_path: &Path) -> PathBuf {
+pub(crate) fn sidecar_path_for_db(db_path: &Path) -> PathBuf {
     let mut path = db_path.as_os_str().to_os_string();
     path.push(".");
     path.push(WAL_INDEX_SIDECAR_EXT);
@@ -1,5 +1,7 @@
 //! Write-ahead log ownership, recovery, and checkpointing.
 
+pub(crate) mod advisory;
+pub(crate) mod archive;
 pub(crate) mod async_commit;
 pub(crate) mod background;
 pub(crate) mod checkpoint;
@@ -17,6 +19,7 @@ pub(crate) mod reader_registry;
 pub(crate) mod recovery;
 pub(crate) mod savepoint;
 pub(crate) mod shared;
+pub(crate) mod table_lock;
 pub(crate) mod writer;
 
 use std::path::{Path, PathBuf};
@@ -36,6 +39,8 @@ use crate::benchmark::{
     WAL_DELTA_MATERIALIZE_CALLS, WAL_DELTA_SCRATCH_GROWS, WAL_DELTA_SCRATCH_REUSES,
 };
 
+use self::advisory::AdvisoryLocks;
+use self::archive::{WalArchive, WalSegment};
 use self::async_commit::AsyncCommitState;
 use self::background::BgCheckpointer;
 use self::coordination::{
@@ -47,6 +52,7 @@ use self::format::{FrameEncoding, WalFrame};
 use self::index::{WalIndex, WalVersion};
 use self::index_sidecar::WalIndexSidecar;
 use self::reader_registry::{ReaderGuard, ReaderRegistry};
+use self::table_lock::TableLocks;
 
 const NO_RETAINED_SNAPSHOT_LSN: u64 = u64::MAX;
 
@@ -109,15 +115,24 @@ pub(crate) struct SharedWalInner {
     pub(crate) process_coordinator: Option<ProcessCoordinator>,
     pub(crate) observed_coord_wal_generation: AtomicU64,
     pub(crate) observed_coord_checkpoint_generation: AtomicU64,
+    /// Segment queue filled by truncating checkpoints while WAL archiving is
+    /// enabled; `None` when it is off.
+    pub(crate) archive: Mutex<Option<WalArchive>>,
+    /// Advisory locks held by the handles sharing this WAL.
+    pub(crate) advisory_locks: AdvisoryLocks,
+    /// Tables locked by `LOCK TABLE` in the handles sharing this WAL.
+    pub(crate) table_locks: TableLocks,
 }
 
 /// Snapshot of the checkpoint-related `DbConfig` fields. Held inside
 /// `SharedWalInner` so the writer can evaluate auto-checkpoint thresholds
-/// without re-threading config through every call site.
-#[derive(Clone, Copy, Debug)]
+/// without re-threading config through every call site. The thresholds are
+/// atomics because `PRAGMA wal_checkpoint_threshold_*` can change them on an
+/// open database.
+#[derive(Debug)]
 pub(crate) struct AutoCheckpointConfig {
-    pub(crate) threshold_pages: u32,
-    pub(crate) threshold_bytes: u64,
+    pub(crate) threshold_pages: AtomicU32,
+    pub(crate) threshold_bytes: AtomicU64,
     pub(crate) checkpoint_timeout_sec: u64,
     pub(crate) release_freed_after_checkpoint: bool,
 }
@@ -125,12 +140,20 @@ pub(crate) struct AutoCheckpointConfig {
 impl AutoCheckpointConfig {
     pub(crate) fn from_db_config(cfg: &DbConfig) -> Self {
         Self {
-            threshold_pages: cfg.wal_checkpoint_threshold_pages,
-            threshold_bytes: cfg.wal_checkpoint_threshold_bytes,
+            threshold_pages: AtomicU32::new(cfg.wal_checkpoint_threshold_pages),
+            threshold_bytes: AtomicU64::new(cfg.wal_checkpoint_threshold_bytes),
             checkpoint_timeout_sec: cfg.checkpoint_timeout_sec,
             release_freed_after_checkpoint: cfg.release_freed_memory_after_checkpoint,
         }
     }
+
+    /// Returns the current page and byte thresholds; zero disables either.
+    pub(crate) fn thresholds(&self) -> (u32, u64) {
+        (
+            self.threshold_pages.load(Ordering::Acquire),
+            self.threshold_bytes.load(Ordering::Acquire),
+        )
+    }
 }
 
 pub(crate) type WalBasePage = Option<(Arc<[u8]>, bool)>;
@@ -174,6 +197,10 @@ impl WalHandle {
         shared::evict(vfs, db_path)
     }
 
+    pub(crate) fn remove_if_sole_handle(&self, vfs: &VfsHandle) -> Result<bool> {
+        shared::remove_if_sole_handle(vfs, self)
+    }
+
     pub(crate) fn commit_pages(
         &self,
         pager: &PagerHandle,
@@ -213,6 +240,37 @@ impl WalHandle {
         checkpoint::checkpoint(self, pager, timeout_sec)
     }
 
+    /// Starts capturing WAL segments at each truncating checkpoint, keeping
+    /// at most `capacity` undrained segments. Re-enabling keeps the queue.
+    pub(crate) fn enable_archive(&self, capacity: usize) {
+        let mut archive = self
+            .inner
+            .archive
+            .lock()
+            .expect("wal archive lock should not be poisoned");
+        if archive.is_none() {
+            *archive = Some(WalArchive::new(capacity));
+        }
+    }
+
+    /// Stops capturing segments and drops any that were not drained.
+    pub(crate) fn disable_archive(&self) {
+        *self
+            .inner
+            .archive
+            .lock()
+            .expect("wal archive lock should not be poisoned") = None;
+    }
+
+    pub(crate) fn next_archived_segment(&self) -> Option<WalSegment> {
+        self.inner
+            .archive
+            .lock()
+            .expect("wal archive lock should not be poisoned")
+            .as_mut()
+            .and_then(WalArchive::pop)
+    }
+
     pub(crate) fn shutdown_background_checkpointer(&self) {
         if let Some(bg) = self.inner.bg_checkpointer.get() {
             bg.shutdown_and_join();
@@ -261,6 +319,28 @@ impl WalHandle {
         self.inner.checkpoint_epoch.load(Ordering::Acquire)
     }
 
+    pub(crate) fn auto_checkpoint_thresholds(&self) -> (u32, u64) {
+        self.inner.auto_checkpoint.thresholds()
+    }
+
+    /// Replaces the page threshold. It applies to every handle sharing this
+    /// WAL and is evaluated on the next commit.
+    pub(crate) fn set_auto_checkpoint_threshold_pages(&self, pages: u32) {
+        self.inner
+            .auto_checkpoint
+            .threshold_pages
+            .store(pages, Ordering::Release);
+    }
+
+    /// Replaces the byte threshold. It applies to every handle sharing this
+    /// WAL and is evaluated on the next commit.
+    pub(crate) fn set_auto_checkpoint_threshold_bytes(&self, bytes: u64) {
+        self.inner
+            .auto_checkpoint
+            .threshold_bytes
+            .store(bytes, Ordering::Release);
+    }
+
     pub(crate) fn begin_reader(&self) -> Result<ReaderGuard> {
         self.begin_reader_with_process_guard(None)
     }
@@ -364,6 +444,46 @@ impl WalHandle {
         }
     }
 
+    pub(crate) fn try_advisory_lock(&self, owner: u64, key: i64) -> Result<bool> {
+        self.inner
+            .advisory_locks
+            .try_lock(self.inner.process_coordinator.as_ref(), owner, key)
+    }
+
+    pub(crate) fn advisory_unlock(&self, owner: u64, key: i64) -> Result<bool> {
+        self.inner.advisory_locks.unlock(owner, key)
+    }
+
+    pub(crate) fn advisory_unlock_all(&self, owner: u64) {
+        self.inner.advisory_locks.unlock_all(owner);
+    }
+
+    pub(crate) fn try_lock_table(&self, owner: u64, table: &str) -> Result<bool> {
+        self.inner
+            .table_locks
+            .try_lock(self.inner.process_coordinator.as_ref(), owner, table)
+    }
+
+    pub(crate) fn table_locked_by_other<'a>(
+        &self,
+        owner: u64,
+        tables: impl IntoIterator<Item = &'a str>,
+    ) -> Result<Option<String>> {
+        self.inner.table_locks.locked_by_other(
+            self.inner.process_coordinator.as_ref(),
+            owner,
+            tables,
+        )
+    }
+
+    pub(crate) fn unlock_tables(&self, owner: u64) {
+        self.inner.table_locks.unlock_all(owner);
+    }
+
+    pub(crate) fn transfer_table_locks(&self, from: u64, to: u64) {
+        self.inner.table_locks.transfer_all(from, to);
+    }
+
     pub(crate) fn publish_process_commit(&self, wal_end_lsn: u64) -> Result<()> {
         if let Some(coordinator) = &self.inner.process_coordinator {
             let snapshot = coordinator.publish_commit(wal_end_lsn)?;
@@ -434,6 +554,7 @@ impl WalHandle {
                     self.inner.page_size,
                     self.inner.wal_index_hot_set_pages,
                     sidecar.as_deref_mut(),
+                    None,
                 )?;
             {
                 let mut current = self
@@ -819,9 +940,17 @@ impl WalHandle {
     /// disk. For sync modes other than `AsyncCommit` this is a no-op because
     /// commits are already synchronously durable.
     pub(crate) fn flush_to_durable(&self) -> Result<()> {
-        match self.inner.async_commit.as_ref() {
-            Some(state) => state.flush_to_durable(),
-            None => Ok(()),
+        match self.inner.sync_mode {
+            WalSyncMode::Full => Ok(()),
+            WalSyncMode::AsyncCommit { .. } => match self.inner.async_commit.as_ref() {
+                Some(state) => state.flush_to_durable(),
+                None => Ok(()),
+            },
+            // Normal commits sync data but not a grown file's length, and
+            // unsynced commits sync nothing; one metadata sync covers both.
+            WalSyncMode::Normal | WalSyncMode::TestingOnlyUnsafeNoSync => {
+                self.inner.file.sync_metadata()
+            }
         }
     }
 }
@@ -3,6 +3,7 @@
 use std::collections::HashMap;
 use std::sync::Arc;
 
+use crate::config::{RecoveryProgress, RecoveryProgressHook};
 use crate::error::{DbError, Result};
 use crate::storage::page::PageId;
 use crate::storage::PagerHandle;
@@ -18,6 +19,8 @@ use super::index_sidecar::WalIndexSidecar;
 const MAX_PENDING_RECOVERY_FRAMES: usize = 1_000_000;
 const RECOVERY_PENDING_OVERFLOW_MESSAGE: &str =
     "WAL recovery aborted: more than 1,000,000 uncommitted page frames before commit";
+/// Frames replayed between calls to a recovery progress hook.
+const RECOVERY_PROGRESS_INTERVAL_FRAMES: u64 = 4096;
 
 #[derive(Debug)]
 struct PendingRecoveryPage {
@@ -34,6 +37,7 @@ pub(crate) fn initialize_or_recover(
     page_size: u32,
     hot_set_pages: u32,
     mut sidecar: Option<&mut WalIndexSidecar>,
+    progress: Option<&RecoveryProgressHook>,
 ) -> Result<(WalIndex, u64, u32)> {
     let size = file.file_size()?;
     if size == 0 {
@@ -71,6 +75,10 @@ pub(crate) fn initialize_or_recover(
     let mut offset = WAL_HEADER_SIZE;
     let mut pending = Vec::<PageId>::new();
     let mut pending_pages = HashMap::<PageId, PendingRecoveryPage>::new();
+    let mut replayed = RecoveryProgress {
+        bytes_total: header.wal_end_offset.saturating_sub(WAL_HEADER_SIZE),
+        ..RecoveryProgress::default()
+    };
     while offset < header.wal_end_offset {
         let Some(frame) =
             WalFrame::decode_from_file(file.as_ref(), offset, page_size, header.wal_end_offset)?
@@ -147,6 +155,23 @@ pub(crate) fn initialize_or_recover(
             }
         }
         offset = next_offset;
+        if let Some(progress) = progress {
+            replayed.frames_replayed += 1;
+            replayed.bytes_replayed = offset - WAL_HEADER_SIZE;
+            if replayed.frames_replayed % RECOVERY_PROGRESS_INTERVAL_FRAMES == 0
+                && !progress.report(replayed)
+            {
+                return Err(DbError::canceled("WAL recovery canceled by progress hook"));
+            }
+        }
+    }
+    if let Some(progress) = progress {
+        if replayed.frames_replayed % RECOVERY_PROGRESS_INTERVAL_FRAMES != 0 {
+            replayed.bytes_replayed = replayed.bytes_total;
+            if !progress.report(replayed) {
+                return Err(DbError::canceled("WAL recovery canceled by progress hook"));
+            }
+        }
     }
 
     Ok((index, header.wal_end_offset, max_page_id))
@@ -263,7 +288,7 @@ mod tests {
         write_all_at(file.as_ref(), 0, &bad_header).expect("write corrupt header");
         file.set_len(WAL_HEADER_SIZE).expect("size wal header");
 
-        let error = initialize_or_recover(&file, &pager, page::DEFAULT_PAGE_SIZE, 0, None)
+        let error = initialize_or_recover(&file, &pager, page::DEFAULT_PAGE_SIZE, 0, None, None)
             .expect_err("header is corrupt");
         assert!(matches!(error, crate::error::DbError::Corruption { .. }));
     }
@@ -276,7 +301,7 @@ mod tests {
             .expect("create wal file");
         let pager = test_pager(&vfs, Path::new(":memory-db:"));
         let (index, end, max_page_id) =
-            initialize_or_recover(&file, &pager, page::DEFAULT_PAGE_SIZE, 0, None)
+            initialize_or_recover(&file, &pager, page::DEFAULT_PAGE_SIZE, 0, None, None)
                 .expect("initialize wal");
 
         assert_eq!(index.version_count(), 0);
@@ -301,7 +326,7 @@ mod tests {
         write_all_at(file.as_ref(), 0, &header.encode()).expect("write header");
         file.set_len(WAL_HEADER_SIZE).expect("size wal header");
 
-        let error = initialize_or_recover(&file, &pager, page::DEFAULT_PAGE_SIZE, 0, None)
+        let error = initialize_or_recover(&file, &pager, page::DEFAULT_PAGE_SIZE, 0, None, None)
             .expect_err("mismatch");
         assert!(matches!(error, crate::error::DbError::Corruption { .. }));
     }
@@ -318,7 +343,7 @@ mod tests {
         write_all_at(file.as_ref(), 0, &header.encode()).expect("write header");
         file.set_len(WAL_HEADER_SIZE).expect("size wal header");
 
-        let error = initialize_or_recover(&file, &pager, page::DEFAULT_PAGE_SIZE, 0, None)
+        let error = initialize_or_recover(&file, &pager, page::DEFAULT_PAGE_SIZE, 0, None, None)
             .expect_err("end_exceeds");
         assert!(matches!(error, crate::error::DbError::Corruption { .. }));
     }
@@ -347,7 +372,7 @@ mod tests {
         file.set_len(logical_end).expect("set len");
 
         let (index, end, max_page_id) =
-            initialize_or_recover(&file, &pager, ps, 0, None).expect("recover");
+            initialize_or_recover(&file, &pager, ps, 0, None, None).expect("recover");
         assert_eq!(index.version_count(), 1);
         assert_eq!(end, logical_end);
         assert_eq!(max_page_id, 3);
@@ -381,7 +406,7 @@ mod tests {
         file.set_len(logical_end).expect("set len");
 
         let (index, _end, _max_page_id) =
-            initialize_or_recover(&file, &pager, ps, 0, None).expect("recover");
+            initialize_or_recover(&file, &pager, ps, 0, None, None).expect("recover");
         assert_eq!(index.version_count(), 1);
         let latest = index
             .latest_visible(7, u64::MAX)
@@ -420,7 +445,7 @@ mod tests {
         file.set_len(logical_end).expect("set len");
 
         let (index, _end, _max_page_id) =
-            initialize_or_recover(&file, &pager, ps, 0, None).expect("recover");
+            initialize_or_recover(&file, &pager, ps, 0, None, None).expect("recover");
         assert_eq!(
             index.version_count(),
             1,
@@ -467,7 +492,7 @@ mod tests {
         let mut sidecar = crate::wal::index_sidecar::WalIndexSidecar::open(&handle, db_path)
             .expect("open sidecar");
         let (index, _end, _max_page_id) =
-            initialize_or_recover(&file, &pager, ps, 1, Some(&mut sidecar)).expect("recover");
+            initialize_or_recover(&file, &pager, ps, 1, Some(&mut sidecar), None).expect("recover");
 
         assert_eq!(index.version_count(), 1);
         assert_eq!(sidecar.version_count(), 1);
@@ -526,7 +551,7 @@ mod tests {
         let mut sidecar = crate::wal::index_sidecar::WalIndexSidecar::open(&handle, db_path)
             .expect("open sidecar");
         let (index, _end, _max_page_id) =
-            initialize_or_recover(&file, &pager, ps, 1, Some(&mut sidecar)).expect("recover");
+            initialize_or_recover(&file, &pager, ps, 1, Some(&mut sidecar), None).expect("recover");
 
         assert_eq!(index.version_count(), 1);
         assert_eq!(sidecar.version_count(), 1);
@@ -565,7 +590,7 @@ mod tests {
         file.set_len(logical_end).expect("set len");
 
         let (index, _end, _max_page_id) =
-            initialize_or_recover(&file, &pager, ps, 0, None).expect("recover");
+            initialize_or_recover(&file, &pager, ps, 0, None, None).expect("recover");
         // No committed frames -> index should be empty
         assert_eq!(index.version_count(), 0);
     }
@@ -603,7 +628,7 @@ mod tests {
         file.set_len(logical_end).expect("set len");
 
         let (index, _end, _max_page_id) =
-            initialize_or_recover(&file, &pager, ps, 0, None).expect("recover");
+            initialize_or_recover(&file, &pager, ps, 0, None, None).expect("recover");
         let version = index
             .latest_visible(3, u64::MAX)
             .expect("recovered page version");
@@ -650,7 +675,7 @@ mod tests {
         file.set_len(logical_end).expect("set len");
 
         let (index, _end, _max_page_id) =
-            initialize_or_recover(&file, &pager, ps, 0, None).expect("recover");
+            initialize_or_recover(&file, &pager, ps, 0, None, None).expect("recover");
         assert_eq!(index.version_count(), 1);
         let version = index
             .latest_visible(page_id, u64::MAX)
@@ -695,10 +720,62 @@ mod tests {
         file.set_len(offset).expect("set wal len");
 
         let error =
-            initialize_or_recover(&file, &pager, ps, 0, None).expect_err("pending overflow");
+            initialize_or_recover(&file, &pager, ps, 0, None, None).expect_err("pending overflow");
         assert!(matches!(error, crate::error::DbError::Corruption { .. }));
         assert!(error
             .to_string()
             .contains("more than 1,000,000 uncommitted page frames"));
     }
+
+    #[test]
+    fn recovery_reports_progress_and_can_be_canceled() {
+        use std::sync::atomic::{AtomicU64, Ordering};
+
+        use crate::config::RecoveryProgressHook;
+
+        let vfs = crate::vfs::mem::MemVfs::default();
+        let file = vfs
+            .open(Path::new(":memory:"), OpenMode::CreateNew, FileKind::Wal)
+            .expect("create wal file");
+        let pager = test_pager(&vfs, Path::new(":memory-db:"));
+
+        let ps = page::DEFAULT_PAGE_SIZE;
+        let mut data = Vec::new();
+        for _ in 0..2100 {
+            let frame = crate::wal::format::WalFrame::page(2, vec![0x11; ps as usize]);
+            data.extend_from_slice(&frame.encode(ps).unwrap());
+            let commit = crate::wal::format::WalFrame::commit();
+            data.extend_from_slice(&commit.encode(ps).unwrap());
+        }
+        let logical_end = WAL_HEADER_SIZE + data.len() as u64;
+        write_all_at(file.as_ref(), 0, &WalHeader::new(ps, logical_end).encode())
+            .expect("write header");
+        write_all_at(file.as_ref(), WAL_HEADER_SIZE, &data).expect("write frames");
+        file.set_len(logical_end).expect("set len");
+
+        let reports = Arc::new(std::sync::Mutex::new(Vec::new()));
+        let seen = Arc::clone(&reports);
+        let hook = RecoveryProgressHook::new(move |progress| {
+            seen.lock().unwrap().push(progress);
+            true
+        });
+        initialize_or_recover(&file, &pager, ps, 0, None, Some(&hook)).expect("recover");
+        let reports = reports.lock().unwrap();
+        assert_eq!(reports.len(), 2, "every 4096 frames plus a final report");
+        let last = reports.last().unwrap();
+        assert_eq!(last.frames_replayed, 4200);
+        assert_eq!(last.bytes_replayed, last.bytes_total);
+        assert_eq!(last.bytes_total, data.len() as u64);
+
+        let calls = Arc::new(AtomicU64::new(0));
+        let counted = Arc::clone(&calls);
+        let cancel = RecoveryProgressHook::new(move |_| {
+            counted.fetch_add(1, Ordering::SeqCst);
+            false
+        });
+        let error =
+            initialize_or_recover(&file, &pager, ps, 0, None, Some(&cancel)).expect_err("canceled");
+        assert!(matches!(error, crate::error::DbError::Canceled { .. }));
+        assert_eq!(calls.load(Ordering::SeqCst), 1);
+    }
 }
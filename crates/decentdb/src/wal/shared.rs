@@ -14,10 +14,12 @@ use crate::error::{DbError, Result};
 use crate::storage::PagerHandle;
 use crate::vfs::{FileKind, OpenMode, VfsHandle};
 
+use super::advisory::AdvisoryLocks;
 use super::coordination::ProcessCoordinator;
-use super::index_sidecar::{WalIndexBackendKind, WalIndexSidecar};
+use super::index_sidecar::{self, WalIndexBackendKind, WalIndexSidecar};
 use super::reader_registry::ReaderRegistry;
 use super::recovery;
+use super::table_lock::TableLocks;
 use super::{AutoCheckpointConfig, SharedWalInner, WalHandle, WalWriteState};
 
 pub(crate) fn acquire(
@@ -91,6 +93,7 @@ fn build_handle(
         config.page_size,
         config.wal_index_hot_set_pages,
         index_sidecar.as_mut(),
+        config.recovery_progress.as_ref(),
     )?;
     let allocated_len = file.file_size()?;
 
@@ -143,6 +146,9 @@ fn build_handle(
         process_coordinator,
         observed_coord_wal_generation: AtomicU64::new(observed_coord_wal_generation),
         observed_coord_checkpoint_generation: AtomicU64::new(observed_coord_checkpoint_generation),
+        archive: Mutex::new(None),
+        advisory_locks: AdvisoryLocks::default(),
+        table_locks: TableLocks::default(),
     });
 
     if let Some(sidecar) = &inner.index_sidecar {
@@ -209,6 +215,33 @@ impl Drop for SharedWalInner {
     }
 }
 
+/// Deletes the WAL and WAL index sidecar files behind `wal` when it is empty
+/// and `wal` is the only handle on it, and drops its registry entry so the
+/// next open starts a fresh WAL. The registry lock is held throughout, so
+/// another open in this process cannot attach to the WAL in between. WALs
+/// under process coordination are left alone, since another process may
+/// still be using them. Returns whether the files were removed.
+pub(crate) fn remove_if_sole_handle(vfs: &VfsHandle, wal: &WalHandle) -> Result<bool> {
+    let Some(canonical_path) = wal.inner.canonical_path.as_ref() else {
+        return Ok(false);
+    };
+    if wal.inner.process_coordinator.is_some() {
+        return Ok(false);
+    }
+    let mut registry = registry()
+        .lock()
+        .expect("shared wal registry lock should not be poisoned");
+    if wal.strong_handle_count() != 1 || wal.latest_snapshot() != 0 {
+        return Ok(false);
+    }
+    vfs.remove_file(&wal_path_for_db(canonical_path))?;
+    if wal.inner.index_sidecar.is_some() {
+        vfs.remove_file(&index_sidecar::sidecar_path_for_db(canonical_path))?;
+    }
+    registry.remove(canonical_path);
+    Ok(true)
+}
+
 pub(crate) fn evict(vfs: &VfsHandle, db_path: &Path) -> Result<()> {
     if vfs.is_memory() {
         return Ok(());
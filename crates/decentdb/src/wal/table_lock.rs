@@ -0,0 +1,137 @@
+//! Explicit table locks taken by `LOCK TABLE`, and the tables reserved by
+//! prepared transactions.
+//!
+//! A table lock is held by one handle's transaction, or by a prepared
+//! transaction until it is committed or rolled back, and makes every other
+//! commit that writes the table wait until then.
+//! Handles in one process share the registry through the shared WAL; other
+//! processes see the lock as a byte-range lock on the coordination sidecar
+//! when process coordination is on.
+
+use std::collections::HashMap;
+use std::sync::{Mutex, MutexGuard};
+
+use crate::error::{DbError, Result};
+use crate::vfs::VfsFileLock;
+
+use super::coordination::ProcessCoordinator;
+
+#[derive(Debug, Default)]
+pub(crate) struct TableLocks {
+    held: Mutex<HashMap<String, TableHold>>,
+}
+
+#[derive(Debug)]
+struct TableHold {
+    owner: u64,
+    _lock: Option<Box<dyn VfsFileLock>>,
+}
+
+impl TableLocks {
+    /// Locks `table` for `owner` without waiting and reports whether
+    /// `owner` now holds it. Locking a table twice is a no-op.
+    pub(crate) fn try_lock(
+        &self,
+        coordinator: Option<&ProcessCoordinator>,
+        owner: u64,
+        table: &str,
+    ) -> Result<bool> {
+        let key = table.to_ascii_lowercase();
+        let mut held = self.held()?;
+        if let Some(hold) = held.get(&key) {
+            return Ok(hold.owner == owner);
+        }
+        let lock = match coordinator {
+            Some(coordinator) => match coordinator.try_lock_table(&key, true)? {
+                Some(lock) => Some(lock),
+                None => return Ok(false),
+            },
+            None => None,
+        };
+        held.insert(key, TableHold { owner, _lock: lock });
+        Ok(true)
+    }
+
+    /// Returns the first of `tables` locked by someone other than `owner`.
+    pub(crate) fn locked_by_other<'a>(
+        &self,
+        coordinator: Option<&ProcessCoordinator>,
+        owner: u64,
+        tables: impl IntoIterator<Item = &'a str>,
+    ) -> Result<Option<String>> {
+        let held = self.held()?;
+        for table in tables {
+            let key = table.to_ascii_lowercase();
+            match held.get(&key) {
+                Some(hold) if hold.owner == owner => continue,
+                Some(_) => return Ok(Some(table.to_string())),
+                None => {}
+            }
+            // A shared probe fails only while another process holds the
+            // table; dropping it releases nothing this process relies on,
+            // since no handle here holds the table.
+            if let Some(coordinator) = coordinator {
+                if coordinator.try_lock_table(&key, false)?.is_none() {
+                    return Ok(Some(table.to_string()));
+                }
+            }
+        }
+        Ok(None)
+    }
+
+    /// Releases every table `owner` holds, as when its transaction ends.
+    pub(crate) fn unlock_all(&self, owner: u64) {
+        if let Ok(mut held) = self.held.lock() {
+            held.retain(|_, hold| hold.owner != owner);
+        }
+    }
+
+    /// Hands every table `from` holds to `to` without releasing it, as when
+    /// a transaction is prepared or its prepared form commits.
+    pub(crate) fn transfer_all(&self, from: u64, to: u64) {
+        if let Ok(mut held) = self.held.lock() {
+            for hold in held.values_mut().filter(|hold| hold.owner == from) {
+                hold.owner = to;
+            }
+        }
+    }
+
+    fn held(&self) -> Result<MutexGuard<'_, HashMap<String, TableHold>>> {
+        self.held
+            .lock()
+            .map_err(|_| DbError::internal("table lock registry poisoned"))
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn table_locks_exclude_other_owners_until_released() {
+        let locks = TableLocks::default();
+        assert!(locks.try_lock(None, 1, "Orders").unwrap());
+        assert!(locks.try_lock(None, 1, "orders").unwrap());
+        assert!(!locks.try_lock(None, 2, "ORDERS").unwrap());
+        assert_eq!(
+            locks.locked_by_other(None, 2, ["items", "orders"]).unwrap(),
+            Some("orders".to_string())
+        );
+        assert_eq!(locks.locked_by_other(None, 1, ["orders"]).unwrap(), None);
+        locks.unlock_all(1);
+        assert_eq!(locks.locked_by_other(None, 2, ["orders"]).unwrap(), None);
+        assert!(locks.try_lock(None, 2, "orders").unwrap());
+    }
+
+    #[test]
+    fn transferred_table_locks_stay_held() {
+        let locks = TableLocks::default();
+        assert!(locks.try_lock(None, 1, "orders").unwrap());
+        locks.transfer_all(1, 3);
+        locks.unlock_all(1);
+        assert!(!locks.try_lock(None, 2, "orders").unwrap());
+        assert!(locks.try_lock(None, 3, "orders").unwrap());
+        locks.unlock_all(3);
+        assert!(locks.try_lock(None, 2, "orders").unwrap());
+    }
+}
@@ -9,7 +9,7 @@ use std::sync::Arc;
 
 use crate::config::WalSyncMode;
 use crate::error::{DbError, Result};
-use crate::storage::page::PageId;
+use crate::storage::page::{PageId, CATALOG_ROOT_PAGE_ID};
 use crate::storage::PagerHandle;
 use crate::vfs::write_all_at_many;
 
@@ -286,9 +286,12 @@ pub(crate) fn commit_pages_if_latest(
         // current WAL end.
         let current_epoch = wal.inner.checkpoint_epoch.load(Ordering::Acquire);
         if current_epoch == expected_checkpoint_epoch {
-            return Err(DbError::transaction(format!(
-                "transaction conflict: WAL advanced from {expected_latest_lsn} to {latest}"
-            )));
+            return Err(transaction_conflict(
+                wal,
+                &pages,
+                expected_latest_lsn,
+                latest,
+            ));
         }
     }
 
@@ -570,6 +573,39 @@ fn append_best_page_frame_with_base(
 }
 
 /// Look up base pages for an entire batch under a single index lock.
+/// Builds the error for a commit that lost to a foreign writer, naming a page
+/// both commits wrote. Every commit rewrites the catalog root, so a data page
+/// is preferred when the two commits share one.
+fn transaction_conflict(
+    wal: &WalHandle,
+    pages: &[(PageId, Vec<u8>)],
+    expected_latest_lsn: u64,
+    latest: u64,
+) -> DbError {
+    let contended: Vec<PageId> = {
+        let index = wal
+            .inner
+            .index
+            .lock()
+            .expect("wal index lock should not be poisoned");
+        pages
+            .iter()
+            .map(|(page_id, _)| *page_id)
+            .filter(|page_id| {
+                index
+                    .latest_visible(*page_id, u64::MAX)
+                    .is_some_and(|version| version.lsn > expected_latest_lsn)
+            })
+            .collect()
+    };
+    let page_id = contended
+        .iter()
+        .copied()
+        .find(|page_id| *page_id != CATALOG_ROOT_PAGE_ID)
+        .or_else(|| contended.first().copied());
+    DbError::transaction_conflict(expected_latest_lsn, latest, page_id, contended.len())
+}
+
 fn lookup_base_pages_batch(
     wal: &WalHandle,
     pager: &PagerHandle,
@@ -647,9 +683,8 @@ fn demote_cold_versions(wal: &WalHandle) -> Result<()> {
 /// is already pending, this is a silent no-op; the next reader-free commit
 /// re-evaluates and may trigger then.
 fn maybe_auto_checkpoint(wal: &WalHandle, pager: &PagerHandle) -> Result<()> {
-    let cfg = wal.inner.auto_checkpoint;
-    let pages_threshold = cfg.threshold_pages;
-    let bytes_threshold = cfg.threshold_bytes;
+    let cfg = &wal.inner.auto_checkpoint;
+    let (pages_threshold, bytes_threshold) = cfg.thresholds();
     if pages_threshold == 0 && bytes_threshold == 0 {
         return Ok(());
     }
@@ -1,4 +1,4 @@
-use decentdb::{Db, DbConfig};
+use decentdb::{Db, DbConfig, Value};
 
 fn setup_db_with_tracing(threshold_us: u64) -> Db {
     let tmp = tempfile::tempdir().unwrap();
@@ -104,3 +104,147 @@ fn test_lock_wait_tracing_sql_write_lock() {
     let source = rows[0].values()[5].as_text().unwrap_or_default();
     assert_eq!(source, "sql_write", "unexpected lock wait source: {source}");
 }
+
+fn setup_db_with_statement_stats(tmp: &tempfile::TempDir) -> Db {
+    let mut config = DbConfig::default();
+    config.tracing.enabled = true;
+    config.tracing.statement_stats.enabled = true;
+    Db::create(tmp.path().join("test.ddb"), config).unwrap()
+}
+
+#[test]
+fn test_stat_statements_empty_when_disabled() {
+    let db = setup_db_with_tracing(0);
+    db.execute("SELECT 1").unwrap();
+    let result = db
+        .execute("SELECT * FROM decentdb_stat_statements()")
+        .unwrap();
+    assert!(result.rows().is_empty());
+}
+
+#[test]
+fn test_stat_statements_aggregate_by_fingerprint() {
+    let tmp = tempfile::tempdir().unwrap();
+    let db = setup_db_with_statement_stats(&tmp);
+    db.execute("CREATE TABLE t (id INT64 PRIMARY KEY, name TEXT)")
+        .unwrap();
+    db.execute("INSERT INTO t VALUES (1, 'a'), (2, 'b')")
+        .unwrap();
+    let select = db.prepare("SELECT name FROM t WHERE id = $1").unwrap();
+    for id in 1..=3 {
+        select.execute(&[decentdb::Value::Int64(id)]).unwrap();
+    }
+    db.execute("SELECT name FROM t WHERE id = 1").unwrap();
+
+    let result = db
+        .execute(
+            "SELECT fingerprint, calls, total_rows FROM decentdb_stat_statements() \
+             WHERE fingerprint LIKE 'select name%' ORDER BY fingerprint",
+        )
+        .unwrap();
+    let rows: Vec<_> = result
+        .rows()
+        .iter()
+        .map(|row| {
+            (
+                row.values()[0].as_text().unwrap_or_default().to_string(),
+                row.values()[1].clone(),
+                row.values()[2].clone(),
+            )
+        })
+        .collect();
+    assert_eq!(
+        rows,
+        vec![
+            (
+                "select name from t where id = $1".to_string(),
+                decentdb::Value::Int64(3),
+                decentdb::Value::Int64(2),
+            ),
+            (
+                "select name from t where id = ?".to_string(),
+                decentdb::Value::Int64(1),
+                decentdb::Value::Int64(1),
+            ),
+        ]
+    );
+
+    db.tracing_reset("statement_stats").unwrap();
+    let result = db
+        .execute("SELECT * FROM decentdb_stat_statements() WHERE calls > 1")
+        .unwrap();
+    assert!(result.rows().is_empty());
+}
+
+#[test]
+fn test_stat_statements_shared_across_handles() {
+    let tmp = tempfile::tempdir().unwrap();
+    let first = setup_db_with_statement_stats(&tmp);
+    let mut config = DbConfig::default();
+    config.tracing.enabled = true;
+    config.tracing.statement_stats.enabled = true;
+    let second = Db::open(tmp.path().join("test.ddb"), config).unwrap();
+    second.execute("CREATE TABLE shared_t (id INT64)").unwrap();
+
+    let result = first
+        .execute("SELECT calls FROM decentdb_stat_statements() WHERE fingerprint LIKE 'create%'")
+        .unwrap();
+    assert_eq!(result.rows().len(), 1);
+}
+
+#[test]
+fn test_application_name_attributes_activity() {
+    let tmp = tempfile::tempdir().unwrap();
+    let path = tmp.path().join("test.ddb");
+    let mut config = DbConfig::default();
+    config.tracing.enabled = true;
+    config.tracing.slow_query.enabled = true;
+    config.tracing.slow_query.threshold_us = 1;
+    config.application_name = Some("billing".to_string());
+    let db = Db::create(&path, config).unwrap();
+    let application_name = |sql: &str| {
+        let result = db.execute(sql).unwrap();
+        let column = result
+            .columns()
+            .iter()
+            .position(|column| column == "application_name")
+            .expect("application_name column");
+        result.rows()[0].values()[column].clone()
+    };
+
+    assert_eq!(
+        application_name("SELECT * FROM sys.sessions"),
+        Value::Text("billing".to_string())
+    );
+    db.execute("SELECT 1").unwrap();
+    assert_eq!(
+        application_name("SELECT * FROM sys.slow_queries"),
+        Value::Text("billing".to_string())
+    );
+
+    db.execute("CREATE TABLE t (id INT PRIMARY KEY, tenant_id TEXT)")
+        .unwrap();
+    db.execute("CREATE POLICY tenant_rows ON t USING tenant_id = 'a'")
+        .unwrap();
+    let audit = db
+        .execute("SELECT context_json FROM __decentdb_audit_events")
+        .unwrap();
+    let context = audit.rows()[0].values()[0].as_text().unwrap_or_default();
+    assert!(
+        context.contains("\"application_name\":\"billing\""),
+        "{context}"
+    );
+
+    db.execute("PRAGMA application_name = 'reports'").unwrap();
+    assert_eq!(
+        application_name("PRAGMA application_name"),
+        Value::Text("reports".to_string())
+    );
+    db.execute("PRAGMA application_name = ''").unwrap();
+    assert_eq!(application_name("PRAGMA application_name"), Value::Null);
+    db.execute("PRAGMA application_name = DEFAULT").unwrap();
+    assert_eq!(
+        application_name("PRAGMA application_name"),
+        Value::Text("billing".to_string())
+    );
+}
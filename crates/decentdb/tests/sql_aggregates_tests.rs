@@ -1894,3 +1894,171 @@ fn total_aggregate_uses_float_semantics_and_zero_for_empty_inputs() {
         &[Value::Float64(0.0), Value::Null]
     );
 }
+
+fn sales_db() -> Db {
+    let db = mem_db();
+    exec(
+        &db,
+        "CREATE TABLE sales(id INT64 PRIMARY KEY, region TEXT, product TEXT, amount INT64)",
+    );
+    exec(
+        &db,
+        "INSERT INTO sales VALUES
+            (1, 'east', 'apples', 10), (2, 'east', 'pears', 20), (3, 'east', 'apples', 5),
+            (4, 'west', 'apples', 7), (5, 'west', 'plums', 3)",
+    );
+    db
+}
+
+fn text(value: &str) -> Value {
+    Value::Text(value.to_string())
+}
+
+#[test]
+fn rollup_adds_subtotal_and_grand_total_rows() {
+    let db = sales_db();
+    let r = exec(
+        &db,
+        "SELECT region, product, SUM(amount) AS total, GROUPING(region, product) AS level
+         FROM sales
+         GROUP BY ROLLUP (region, product)
+         ORDER BY level, region, product",
+    );
+    assert_eq!(
+        rows(&r),
+        vec![
+            vec![
+                text("east"),
+                text("apples"),
+                Value::Int64(15),
+                Value::Int64(0)
+            ],
+            vec![
+                text("east"),
+                text("pears"),
+                Value::Int64(20),
+                Value::Int64(0)
+            ],
+            vec![
+                text("west"),
+                text("apples"),
+                Value::Int64(7),
+                Value::Int64(0)
+            ],
+            vec![
+                text("west"),
+                text("plums"),
+                Value::Int64(3),
+                Value::Int64(0)
+            ],
+            vec![text("east"), Value::Null, Value::Int64(35), Value::Int64(1)],
+            vec![text("west"), Value::Null, Value::Int64(10), Value::Int64(1)],
+            vec![Value::Null, Value::Null, Value::Int64(45), Value::Int64(3)],
+        ]
+    );
+}
+
+#[test]
+fn cube_and_grouping_sets_group_each_set_in_one_query() {
+    let db = sales_db();
+    let cube = exec(
+        &db,
+        "SELECT GROUPING(region) AS g_region, GROUPING(product) AS g_product, COUNT(*) AS n
+         FROM sales
+         GROUP BY CUBE (region, product)
+         ORDER BY g_region, g_product, n",
+    );
+    // 4 region/product pairs, 2 region subtotals, 3 product subtotals, and
+    // the grand total.
+    let levels = rows(&cube)
+        .into_iter()
+        .map(|row| (row[0].clone(), row[1].clone()))
+        .collect::<Vec<_>>();
+    assert_eq!(levels.len(), 10);
+    assert_eq!(
+        levels
+            .iter()
+            .filter(|level| **level == (Value::Int64(0), Value::Int64(1)))
+            .count(),
+        2
+    );
+    assert_eq!(
+        levels
+            .iter()
+            .filter(|level| **level == (Value::Int64(1), Value::Int64(0)))
+            .count(),
+        3
+    );
+    assert_eq!(
+        rows(&cube).last().unwrap(),
+        &vec![Value::Int64(1), Value::Int64(1), Value::Int64(5)]
+    );
+
+    let sets = exec(
+        &db,
+        "SELECT region, product, COUNT(*) AS n
+         FROM sales
+         GROUP BY GROUPING SETS ((region), (product))
+         ORDER BY region, product",
+    );
+    assert_eq!(
+        rows(&sets),
+        vec![
+            vec![Value::Null, text("apples"), Value::Int64(3)],
+            vec![Value::Null, text("pears"), Value::Int64(1)],
+            vec![Value::Null, text("plums"), Value::Int64(1)],
+            vec![text("east"), Value::Null, Value::Int64(3)],
+            vec![text("west"), Value::Null, Value::Int64(2)],
+        ]
+    );
+}
+
+#[test]
+fn grouping_distinguishes_subtotals_from_null_group_values() {
+    let db = sales_db();
+    exec(&db, "INSERT INTO sales VALUES (6, NULL, 'figs', 1)");
+    let r = exec(
+        &db,
+        "SELECT region, GROUPING(region) AS is_total, SUM(amount) AS total
+         FROM sales
+         GROUP BY ROLLUP (region)
+         HAVING GROUPING(region) = 1 OR region IS NULL
+         ORDER BY is_total",
+    );
+    assert_eq!(
+        rows(&r),
+        vec![
+            vec![Value::Null, Value::Int64(0), Value::Int64(1)],
+            vec![Value::Null, Value::Int64(1), Value::Int64(46)],
+        ]
+    );
+}
+
+#[test]
+fn grouping_sets_keep_grand_total_for_empty_input() {
+    let db = sales_db();
+    let r = exec(
+        &db,
+        "SELECT region, COUNT(*), SUM(amount)
+         FROM sales
+         WHERE amount > 100
+         GROUP BY ROLLUP (region)",
+    );
+    assert_eq!(
+        rows(&r),
+        vec![vec![Value::Null, Value::Int64(0), Value::Null]]
+    );
+}
+
+#[test]
+fn grouping_rejects_arguments_outside_group_by() {
+    let db = sales_db();
+    let err = db
+        .execute("SELECT region, GROUPING(amount) FROM sales GROUP BY ROLLUP (region)")
+        .unwrap_err()
+        .to_string();
+    assert!(
+        err.contains("arguments to GROUPING must be grouping expressions"),
+        "{err}"
+    );
+}
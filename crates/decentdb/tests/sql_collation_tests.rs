@@ -0,0 +1,356 @@
+//! Column collation tests.
+//!
+//! Covers: NOCASE/RTRIM column declarations, collation-aware comparisons,
+//! LIKE/ILIKE and IN, ORDER BY, collated BTREE indexes (lookups, UNIQUE,
+//! EXPLAIN), the `default_collation` option, metadata, and persistence.
+
+use decentdb::{Db, DbConfig, QueryResult, TextCollation, Value};
+use tempfile::TempDir;
+
+fn mem_db() -> Db {
+    Db::open_or_create(":memory:", DbConfig::default()).unwrap()
+}
+
+fn exec(db: &Db, sql: &str) -> QueryResult {
+    db.execute(sql).unwrap()
+}
+
+fn exec_err(db: &Db, sql: &str) -> String {
+    db.execute(sql).unwrap_err().to_string()
+}
+
+fn ids(r: &QueryResult) -> Vec<i64> {
+    r.rows()
+        .iter()
+        .map(|row| match &row.values()[0] {
+            Value::Int64(id) => *id,
+            other => panic!("expected INT64 id, got {other:?}"),
+        })
+        .collect()
+}
+
+fn texts(r: &QueryResult) -> Vec<String> {
+    r.rows()
+        .iter()
+        .map(|row| match &row.values()[0] {
+            Value::Text(text) => text.clone(),
+            other => panic!("expected TEXT, got {other:?}"),
+        })
+        .collect()
+}
+
+fn explain(db: &Db, sql: &str) -> Vec<String> {
+    exec(db, &format!("EXPLAIN {sql}"))
+        .explain_lines()
+        .iter()
+        .map(|line| line.to_string())
+        .collect()
+}
+
+fn users_db() -> Db {
+    let db = mem_db();
+    exec(
+        &db,
+        "CREATE TABLE users (id INT PRIMARY KEY, email TEXT COLLATE NOCASE, code TEXT COLLATE RTRIM)",
+    );
+    exec(
+        &db,
+        "INSERT INTO users VALUES
+            (1, 'Alice@Example.com', 'a1  '),
+            (2, 'bob@example.com', 'b2'),
+            (3, 'carol@example.com', 'c3 ')",
+    );
+    db
+}
+
+#[test]
+fn nocase_column_comparisons_ignore_ascii_case() {
+    let db = users_db();
+    assert_eq!(
+        ids(&exec(
+            &db,
+            "SELECT id FROM users WHERE email = 'ALICE@example.COM'"
+        )),
+        vec![1]
+    );
+    assert_eq!(
+        ids(&exec(
+            &db,
+            "SELECT id FROM users WHERE email IN ('BOB@EXAMPLE.COM', 'nobody') ORDER BY id"
+        )),
+        vec![2]
+    );
+    assert_eq!(
+        ids(&exec(
+            &db,
+            "SELECT id FROM users WHERE email > 'B' ORDER BY id"
+        )),
+        vec![2, 3]
+    );
+    let stmt = db.prepare("SELECT id FROM users WHERE email = $1").unwrap();
+    assert_eq!(
+        ids(&stmt
+            .execute(&[Value::Text("CAROL@EXAMPLE.COM".to_string())])
+            .unwrap()),
+        vec![3]
+    );
+}
+
+#[test]
+fn rtrim_column_comparisons_ignore_trailing_spaces() {
+    let db = users_db();
+    assert_eq!(
+        ids(&exec(&db, "SELECT id FROM users WHERE code = 'a1'")),
+        vec![1]
+    );
+    assert_eq!(
+        ids(&exec(&db, "SELECT id FROM users WHERE code = 'c3   '")),
+        vec![3]
+    );
+    // RTRIM does not fold case.
+    assert!(ids(&exec(&db, "SELECT id FROM users WHERE code = 'A1'")).is_empty());
+}
+
+#[test]
+fn like_on_nocase_column_is_case_insensitive() {
+    let db = users_db();
+    assert_eq!(
+        ids(&exec(
+            &db,
+            "SELECT id FROM users WHERE email LIKE 'A%' ORDER BY id"
+        )),
+        vec![1]
+    );
+    assert_eq!(
+        ids(&exec(
+            &db,
+            "SELECT id FROM users WHERE email ILIKE '%EXAMPLE.COM' ORDER BY id"
+        )),
+        vec![1, 2, 3]
+    );
+    assert_eq!(
+        ids(&exec(
+            &db,
+            "SELECT id FROM users WHERE email NOT LIKE 'b%' ORDER BY id"
+        )),
+        vec![1, 3]
+    );
+}
+
+#[test]
+fn order_by_and_join_use_column_collation() {
+    let db = mem_db();
+    exec(
+        &db,
+        "CREATE TABLE names (id INT PRIMARY KEY, name TEXT COLLATE NOCASE)",
+    );
+    exec(
+        &db,
+        "INSERT INTO names VALUES (1, 'b'), (2, 'A'), (3, 'c'), (4, 'B')",
+    );
+    assert_eq!(
+        ids(&exec(&db, "SELECT id FROM names ORDER BY name, id")),
+        vec![2, 1, 4, 3]
+    );
+    // An explicit COLLATE on the sort key still wins.
+    assert_eq!(
+        ids(&exec(
+            &db,
+            "SELECT id FROM names ORDER BY name COLLATE BINARY, id"
+        )),
+        vec![2, 4, 1, 3]
+    );
+
+    exec(&db, "CREATE TABLE tags (label TEXT)");
+    exec(&db, "INSERT INTO tags VALUES ('a'), ('C')");
+    assert_eq!(
+        ids(&exec(
+            &db,
+            "SELECT n.id FROM names n JOIN tags t ON n.name = t.label ORDER BY n.id"
+        )),
+        vec![2, 3]
+    );
+}
+
+#[test]
+fn update_and_delete_filters_use_column_collation() {
+    let db = users_db();
+    let updated = exec(
+        &db,
+        "UPDATE users SET code = 'z' WHERE email = 'BOB@EXAMPLE.COM'",
+    );
+    assert_eq!(updated.affected_rows(), 1);
+    let deleted = exec(&db, "DELETE FROM users WHERE email = 'CAROL@example.com'");
+    assert_eq!(deleted.affected_rows(), 1);
+    assert_eq!(
+        ids(&exec(&db, "SELECT id FROM users ORDER BY id")),
+        vec![1, 2]
+    );
+}
+
+#[test]
+fn nocase_index_serves_lookups_and_enforces_unique() {
+    let db = users_db();
+    exec(&db, "CREATE UNIQUE INDEX users_email ON users(email)");
+
+    let query = "SELECT id FROM users WHERE email = 'ALICE@EXAMPLE.COM'";
+    let lines = explain(&db, query);
+    assert!(
+        lines
+            .iter()
+            .any(|line| line.contains("IndexSeek") && line.contains("users_email")),
+        "expected IndexSeek on users_email, got: {lines:?}"
+    );
+    assert_eq!(ids(&exec(&db, query)), vec![1]);
+    assert_eq!(
+        ids(&exec(
+            &db,
+            "SELECT id FROM users WHERE email ILIKE 'bob@EXAMPLE.com'"
+        )),
+        vec![2]
+    );
+
+    let err = exec_err(
+        &db,
+        "INSERT INTO users VALUES (4, 'ALICE@example.com', 'd4')",
+    );
+    assert!(err.contains("unique"), "unexpected error: {err}");
+    exec(
+        &db,
+        "INSERT INTO users VALUES (4, 'dave@example.com', 'd4')",
+    );
+    assert_eq!(
+        ids(&exec(
+            &db,
+            "SELECT id FROM users WHERE email = 'DAVE@EXAMPLE.COM'"
+        )),
+        vec![4]
+    );
+}
+
+#[test]
+fn explicit_index_collation_serves_collated_queries_on_binary_column() {
+    let db = mem_db();
+    exec(&db, "CREATE TABLE artists (id INT PRIMARY KEY, name TEXT)");
+    exec(
+        &db,
+        "INSERT INTO artists VALUES (1, 'Nina Simone'), (2, 'nina simone'), (3, 'Miles Davis')",
+    );
+    exec(
+        &db,
+        "CREATE INDEX artists_name_nocase ON artists(name COLLATE NOCASE)",
+    );
+
+    let query = "SELECT id FROM artists WHERE name COLLATE NOCASE = 'NINA SIMONE' ORDER BY id";
+    let lines = explain(&db, query);
+    assert!(
+        lines
+            .iter()
+            .any(|line| line.contains("IndexSeek") && line.contains("artists_name_nocase")),
+        "expected IndexSeek on artists_name_nocase, got: {lines:?}"
+    );
+    assert_eq!(ids(&exec(&db, query)), vec![1, 2]);
+    // The column itself stays binary.
+    assert_eq!(
+        ids(&exec(
+            &db,
+            "SELECT id FROM artists WHERE name = 'nina simone'"
+        )),
+        vec![2]
+    );
+}
+
+#[test]
+fn extension_collations_cannot_be_stored() {
+    let db = mem_db();
+    let err = exec_err(&db, "CREATE TABLE words (name TEXT COLLATE reverse_text)");
+    assert!(
+        err.contains("cannot be stored on a column or index"),
+        "unexpected error: {err}"
+    );
+}
+
+#[test]
+fn default_collation_applies_to_new_text_columns() {
+    let config = DbConfig {
+        default_collation: TextCollation::NoCase,
+        ..DbConfig::default()
+    };
+    let db = Db::open_or_create(":memory:", config).unwrap();
+    exec(
+        &db,
+        "CREATE TABLE people (id INT PRIMARY KEY, name TEXT, badge TEXT COLLATE BINARY, age INT)",
+    );
+    exec(&db, "ALTER TABLE people ADD COLUMN city TEXT");
+    exec(
+        &db,
+        "INSERT INTO people VALUES (1, 'Ada', 'X1', 36, 'London')",
+    );
+
+    assert_eq!(
+        ids(&exec(
+            &db,
+            "SELECT id FROM people WHERE name = 'ADA' AND city = 'london'"
+        )),
+        vec![1]
+    );
+    assert!(ids(&exec(&db, "SELECT id FROM people WHERE badge = 'x1'")).is_empty());
+
+    let table = db.describe_table("people").unwrap();
+    let collations = table
+        .columns
+        .iter()
+        .map(|column| (column.name.as_str(), column.collation.as_deref()))
+        .collect::<Vec<_>>();
+    assert_eq!(
+        collations,
+        vec![
+            ("id", None),
+            ("name", Some("NOCASE")),
+            ("badge", None),
+            ("age", None),
+            ("city", Some("NOCASE")),
+        ]
+    );
+}
+
+#[test]
+fn column_collations_persist_across_reopen() {
+    let dir = TempDir::new().unwrap();
+    let path = dir.path().join("collation.ddb");
+    let path = path.to_str().unwrap();
+    {
+        let db = Db::open_or_create(path, DbConfig::default()).unwrap();
+        exec(
+            &db,
+            "CREATE TABLE users (id INT PRIMARY KEY, email TEXT COLLATE NOCASE)",
+        );
+        exec(&db, "CREATE INDEX users_email ON users(email)");
+        exec(&db, "INSERT INTO users VALUES (1, 'Alice@Example.com')");
+        db.checkpoint().unwrap();
+    }
+
+    let db = Db::open_or_create(path, DbConfig::default()).unwrap();
+    assert_eq!(
+        ids(&exec(
+            &db,
+            "SELECT id FROM users WHERE email = 'alice@example.com'"
+        )),
+        vec![1]
+    );
+    let snapshot = db.get_schema_snapshot().unwrap();
+    let table = snapshot
+        .tables
+        .iter()
+        .find(|table| table.name == "users")
+        .unwrap();
+    assert!(table.ddl.contains("COLLATE NOCASE"), "ddl: {}", table.ddl);
+    assert_eq!(table.columns[1].collation.as_deref(), Some("NOCASE"));
+    assert_eq!(
+        texts(&exec(
+            &db,
+            "SELECT email FROM users WHERE email LIKE 'ALICE%'"
+        )),
+        vec!["Alice@Example.com".to_string()]
+    );
+}
@@ -708,14 +708,13 @@ fn alter_table_rename_column_with_fk() {
         &db,
         "CREATE TABLE arc2 (id INT PRIMARY KEY, pid INT REFERENCES arp(id))",
     );
-    // Renaming an FK column — exercises rename_column_references path
-    // The rename may work but FK column reference may become stale
-    let rename_result = db.execute("ALTER TABLE arc2 RENAME COLUMN pid TO parent_id");
-    if rename_result.is_ok() {
-        // Verify column was renamed
-        let r = db.execute("SELECT parent_id FROM arc2");
-        assert!(r.is_ok(), "Column should be accessible after rename");
-    }
+    exec(&db, "INSERT INTO arp VALUES (1)");
+    exec(&db, "ALTER TABLE arc2 RENAME COLUMN pid TO parent_id");
+    exec(&db, "INSERT INTO arc2 (id, parent_id) VALUES (1, 1)");
+    let err = exec_err(&db, "INSERT INTO arc2 (id, parent_id) VALUES (2, 99)");
+    assert!(err.to_lowercase().contains("foreign"), "{err}");
+    let table = db.describe_table("arc2").unwrap();
+    assert_eq!(table.foreign_keys[0].columns, vec!["parent_id".to_string()]);
 }
 
 #[test]
@@ -2598,6 +2597,331 @@ fn metadata_describe_table() {
     assert!(info.columns.len() >= 3);
 }
 
+#[test]
+fn metadata_describe_table_reports_generated_columns() {
+    let db = mem_db();
+    db.execute(
+        "CREATE TABLE t(a INT64, b INT64, s INT64 GENERATED ALWAYS AS (a + b) STORED, \
+         v INT64 GENERATED ALWAYS AS (a * 2) VIRTUAL)",
+    )
+    .unwrap();
+    let info = db.describe_table("t").unwrap();
+    let column = |name: &str| info.columns.iter().find(|c| c.name == name).unwrap();
+    assert_eq!(column("a").generated_sql, None);
+    assert!(!column("a").generated_stored);
+    assert_eq!(column("s").generated_sql.as_deref(), Some("(a + b)"));
+    assert!(column("s").generated_stored);
+    assert_eq!(column("v").generated_sql.as_deref(), Some("(a * 2)"));
+    assert!(!column("v").generated_stored);
+}
+
+#[test]
+fn comment_on_table_and_column_survives_reopen_and_renames() {
+    let dir = TempDir::new().unwrap();
+    let path = dir.path().join("comments.ddb");
+
+    {
+        let db = Db::open_or_create(&path, DbConfig::default()).unwrap();
+        exec(
+            &db,
+            "CREATE TABLE users(id INT64 PRIMARY KEY, email TEXT, age INT64)",
+        );
+        exec(&db, "COMMENT ON TABLE users IS 'Registered accounts'");
+        exec(
+            &db,
+            "COMMENT ON COLUMN users.email IS 'Login address, it''s unique'",
+        );
+        exec(&db, "COMMENT ON COLUMN users.age IS 'Years'");
+        exec(&db, "COMMENT ON COLUMN users.age IS NULL");
+        assert!(exec_err(&db, "COMMENT ON COLUMN users.missing IS 'x'").contains("unknown column"));
+        assert!(exec_err(&db, "COMMENT ON TABLE missing IS 'x'").contains("unknown table"));
+        db.checkpoint().unwrap();
+    }
+
+    let db = Db::open_or_create(&path, DbConfig::default()).unwrap();
+    let info = db.describe_table("users").unwrap();
+    let column = |info: &decentdb::TableInfo, name: &str| {
+        info.columns
+            .iter()
+            .find(|c| c.name == name)
+            .unwrap()
+            .comment
+            .clone()
+    };
+    assert_eq!(info.comment.as_deref(), Some("Registered accounts"));
+    assert_eq!(
+        column(&info, "email").as_deref(),
+        Some("Login address, it's unique")
+    );
+    assert_eq!(column(&info, "age"), None);
+    assert!(db
+        .dump_sql()
+        .unwrap()
+        .contains("COMMENT ON COLUMN \"users\".\"email\" IS 'Login address, it''s unique';"));
+
+    exec(&db, "ALTER TABLE users RENAME COLUMN email TO login");
+    exec(&db, "ALTER TABLE users RENAME TO accounts");
+    let info = db.describe_table("accounts").unwrap();
+    assert_eq!(info.comment.as_deref(), Some("Registered accounts"));
+    assert_eq!(
+        column(&info, "login").as_deref(),
+        Some("Login address, it's unique")
+    );
+
+    exec(&db, "DROP TABLE accounts");
+    exec(&db, "CREATE TABLE accounts(id INT64)");
+    assert_eq!(db.describe_table("accounts").unwrap().comment, None);
+}
+
+#[test]
+fn ttl_column_sweeps_expired_rows_and_survives_reopen() {
+    let dir = TempDir::new().unwrap();
+    let path = dir.path().join("ttl.ddb");
+
+    {
+        let db = Db::open_or_create(&path, DbConfig::default()).unwrap();
+        exec(
+            &db,
+            "CREATE TABLE sessions(id INT64 PRIMARY KEY, expires_at TIMESTAMP) \
+             WITH (ttl_column = expires_at)",
+        );
+        assert!(exec_err(
+            &db,
+            "CREATE TABLE bad(id INT64, expires_at TEXT) WITH (ttl_column = expires_at)"
+        )
+        .contains("must be TIMESTAMP or TIMESTAMPTZ"));
+        assert!(exec_err(
+            &db,
+            "CREATE TABLE bad(id INT64) WITH (ttl_column = missing)"
+        )
+        .contains("does not exist"));
+        assert!(exec_err(&db, "ALTER TABLE sessions DROP COLUMN expires_at")
+            .contains("cannot drop ttl column"));
+        db.checkpoint().unwrap();
+    }
+
+    let db = Db::open_or_create(&path, DbConfig::default()).unwrap();
+    assert!(db
+        .table_ddl("sessions")
+        .unwrap()
+        .ends_with("WITH (ttl_column = \"expires_at\");"));
+    for id in 1..=5 {
+        exec(
+            &db,
+            &format!("INSERT INTO sessions VALUES ({id}, '2000-01-0{id} 00:00:00')"),
+        );
+    }
+    exec(
+        &db,
+        "INSERT INTO sessions VALUES (10, '2999-01-01 00:00:00')",
+    );
+    exec(&db, "INSERT INTO sessions VALUES (11, NULL)");
+
+    assert_eq!(db.sweep_expired_rows(2).unwrap(), 2);
+    assert_eq!(db.sweep_expired_rows(10).unwrap(), 3);
+    assert_eq!(db.sweep_expired_rows(10).unwrap(), 0);
+    let ids = db
+        .execute("SELECT id FROM sessions ORDER BY id")
+        .unwrap()
+        .rows()
+        .iter()
+        .map(|row| row.values()[0].clone())
+        .collect::<Vec<_>>();
+    assert_eq!(ids, vec![Value::Int64(10), Value::Int64(11)]);
+
+    exec(
+        &db,
+        "ALTER TABLE sessions RENAME COLUMN expires_at TO expiry",
+    );
+    exec(&db, "ALTER TABLE sessions RENAME TO web_sessions");
+    assert!(db
+        .table_ddl("web_sessions")
+        .unwrap()
+        .ends_with("WITH (ttl_column = \"expiry\");"));
+    db.begin_transaction().unwrap();
+    assert!(db.sweep_expired_rows(10).is_err());
+    db.rollback_transaction().unwrap();
+}
+
+#[test]
+fn range_partitions_route_prune_detach_and_survive_reopen() {
+    let dir = TempDir::new().unwrap();
+    let path = dir.path().join("partitions.ddb");
+
+    {
+        let db = Db::open_or_create(&path, DbConfig::default()).unwrap();
+        exec(
+            &db,
+            "CREATE TABLE events(id INT64, day DATE, note TEXT, PRIMARY KEY (id, day)) \
+             PARTITION BY RANGE (day)",
+        );
+        assert!(
+            exec_err(&db, "INSERT INTO events VALUES (1, '2024-01-05', 'a')")
+                .contains("has no partitions")
+        );
+        exec(
+            &db,
+            "CREATE TABLE events_old PARTITION OF events FOR VALUES FROM (MINVALUE) TO ('2024-01-01')",
+        );
+        exec(
+            &db,
+            "CREATE TABLE events_2024 PARTITION OF events \
+             FOR VALUES FROM ('2024-01-01') TO ('2025-01-01')",
+        );
+        assert!(exec_err(
+            &db,
+            "CREATE TABLE events_overlap PARTITION OF events \
+             FOR VALUES FROM ('2024-06-01') TO ('2024-07-01')"
+        )
+        .contains("would overlap partition events_2024"));
+        assert!(exec_err(
+            &db,
+            "CREATE TABLE events_hash PARTITION OF events FOR VALUES WITH (MODULUS 2, REMAINDER 0)"
+        )
+        .contains("does not match RANGE partitioning"));
+        assert!(exec_err(
+            &db,
+            "CREATE TABLE bad(id INT64 PRIMARY KEY, day DATE) PARTITION BY RANGE (day)"
+        )
+        .contains("must include partition key column day"));
+
+        exec(
+            &db,
+            "INSERT INTO events VALUES (1, '2023-12-31', 'old'), (2, '2024-03-01', 'new'), \
+             (3, '2024-11-30', 'new')",
+        );
+        assert!(
+            exec_err(&db, "INSERT INTO events VALUES (4, '2025-02-01', 'late')")
+                .contains("no partition of events accepts day")
+        );
+        assert!(
+            exec_err(&db, "INSERT INTO events_2024 VALUES (5, '2023-01-01', 'x')")
+                .contains("CHECK")
+        );
+        assert_eq!(
+            rows(&exec(&db, "SELECT COUNT(*) FROM events_old")),
+            vec![vec![Value::Int64(1)]]
+        );
+        assert_eq!(
+            rows(&exec(
+                &db,
+                "SELECT id FROM events WHERE day >= '2024-01-01' ORDER BY id"
+            )),
+            vec![vec![Value::Int64(2)], vec![Value::Int64(3)]]
+        );
+        assert_eq!(
+            rows(&exec(
+                &db,
+                "SELECT e.note FROM events e WHERE e.day = '2023-12-31'"
+            )),
+            vec![vec![Value::Text("old".to_string())]]
+        );
+        assert_eq!(
+            exec(
+                &db,
+                "UPDATE events SET note = 'seen' WHERE day BETWEEN '2024-01-01' AND '2024-06-30'"
+            )
+            .affected_rows(),
+            1
+        );
+        assert!(
+            exec_err(&db, "UPDATE events SET day = '2024-02-01' WHERE id = 1")
+                .contains("UPDATE of partition key column")
+        );
+        assert!(db
+            .table_ddl("events")
+            .unwrap()
+            .ends_with("PARTITION BY RANGE (\"day\");"));
+        assert!(db.list_views().unwrap().is_empty());
+        db.checkpoint().unwrap();
+    }
+
+    let db = Db::open_or_create(&path, DbConfig::default()).unwrap();
+    assert_eq!(
+        db.table_ddl("events_old").unwrap(),
+        "CREATE TABLE \"events_old\" PARTITION OF \"events\" FOR VALUES FROM (MINVALUE) TO ('2024-01-01');"
+    );
+    assert_eq!(
+        exec(&db, "DELETE FROM events WHERE day < '2024-01-01'").affected_rows(),
+        1
+    );
+    exec(
+        &db,
+        "INSERT INTO events VALUES (6, '2024-07-04', 'after reopen')",
+    );
+    exec(&db, "ALTER TABLE events DETACH PARTITION events_2024");
+    assert_eq!(
+        rows(&exec(&db, "SELECT COUNT(*) FROM events")),
+        vec![vec![Value::Int64(0)]]
+    );
+    exec(
+        &db,
+        "INSERT INTO events_2024 VALUES (7, '1999-01-01', 'unbounded')",
+    );
+    assert_eq!(
+        rows(&exec(&db, "SELECT COUNT(*) FROM events_2024")),
+        vec![vec![Value::Int64(4)]]
+    );
+    assert!(exec_err(&db, "DROP VIEW events").contains("is a partitioned table"));
+    exec(&db, "DROP TABLE events");
+    assert!(exec_err(&db, "SELECT * FROM events_old").contains("events_old"));
+    exec(&db, "DROP TABLE events_2024");
+}
+
+#[test]
+fn hash_partitions_spread_rows_by_key() {
+    let db = mem_db();
+    exec(
+        &db,
+        "CREATE TABLE users(id INT64 PRIMARY KEY, name TEXT) PARTITION BY HASH (id)",
+    );
+    for remainder in 0..4 {
+        exec(
+            &db,
+            &format!(
+                "CREATE TABLE users_{remainder} PARTITION OF users \
+                 FOR VALUES WITH (MODULUS 4, REMAINDER {remainder})"
+            ),
+        );
+    }
+    assert!(exec_err(
+        &db,
+        "CREATE TABLE users_x PARTITION OF users FOR VALUES WITH (MODULUS 2, REMAINDER 1)"
+    )
+    .contains("would overlap"));
+    for id in 1..=20 {
+        exec(&db, &format!("INSERT INTO users VALUES ({id}, 'u{id}')"));
+    }
+    for remainder in 0..4 {
+        assert_eq!(
+            rows(&exec(
+                &db,
+                &format!("SELECT COUNT(*) FROM users_{remainder}")
+            )),
+            vec![vec![Value::Int64(5)]]
+        );
+    }
+    assert_eq!(
+        rows(&exec(
+            &db,
+            "SELECT name FROM users WHERE id IN (6, 7)  ORDER BY id"
+        )),
+        vec![
+            vec![Value::Text("u6".to_string())],
+            vec![Value::Text("u7".to_string())]
+        ]
+    );
+    assert!(db.execute("INSERT INTO users VALUES (6, 'dup')").is_err());
+    assert_eq!(
+        rows(&exec(&db, "DELETE FROM users WHERE id = 9 RETURNING name")),
+        vec![vec![Value::Text("u9".to_string())]]
+    );
+    assert_eq!(
+        rows(&exec(&db, "SELECT COUNT(*) FROM users")),
+        vec![vec![Value::Int64(19)]]
+    );
+}
+
 #[test]
 fn metadata_header_info() {
     let db = mem_db();
@@ -3896,3 +4220,154 @@ fn create_index_build_fast_path_produces_correct_single_column_and_partial_index
         vec![vec![Value::Int64(2)], vec![Value::Int64(3)]]
     );
 }
+
+#[test]
+fn alter_table_rename_keeps_primary_key_and_self_reference() {
+    let db = mem_db();
+    exec(
+        &db,
+        "CREATE TABLE nodes (id INT64 PRIMARY KEY, parent INT64 REFERENCES nodes (id))",
+    );
+    exec(&db, "INSERT INTO nodes VALUES (1, NULL), (2, 1)");
+    exec(&db, "ALTER TABLE nodes RENAME COLUMN id TO node_id");
+
+    let table = db.describe_table("nodes").unwrap();
+    assert_eq!(table.primary_key_columns, vec!["node_id".to_string()]);
+    assert_eq!(
+        table.foreign_keys[0].referenced_columns,
+        vec!["node_id".to_string()]
+    );
+    assert!(db.execute("INSERT INTO nodes VALUES (2, NULL)").is_err());
+    assert!(exec_err(&db, "INSERT INTO nodes VALUES (3, 99)")
+        .to_lowercase()
+        .contains("foreign"));
+    exec(&db, "INSERT INTO nodes VALUES (3, 2)");
+}
+
+#[test]
+fn alter_column_type_using_rewrites_rows_and_indexes() {
+    let db = mem_db();
+    exec(
+        &db,
+        "CREATE TABLE prices (id INT64 PRIMARY KEY, amount FLOAT64, sku TEXT, listed TEXT)",
+    );
+    exec(&db, "CREATE INDEX prices_sku_idx ON prices (sku)");
+    exec(
+        &db,
+        "INSERT INTO prices VALUES (1, 1.25, '100', '2026-01-02'), (2, 2.5, '200', '2026-03-04')",
+    );
+
+    exec(
+        &db,
+        "ALTER TABLE prices ALTER COLUMN amount TYPE INT64 USING CAST(amount * 100 AS INT64)",
+    );
+    exec(&db, "ALTER TABLE prices ALTER COLUMN sku TYPE INT64");
+    exec(&db, "ALTER TABLE prices ALTER COLUMN listed TYPE DATE");
+
+    let result = exec(&db, "SELECT id, amount FROM prices WHERE sku = 200");
+    assert_eq!(
+        rows(&result),
+        vec![vec![Value::Int64(2), Value::Int64(250)]]
+    );
+    let result = exec(
+        &db,
+        "SELECT id FROM prices WHERE listed > CAST('2026-02-01' AS DATE)",
+    );
+    assert_eq!(rows(&result), vec![vec![Value::Int64(2)]]);
+    let table = db.describe_table("prices").unwrap();
+    let types = table
+        .columns
+        .iter()
+        .map(|column| column.column_type.as_str())
+        .collect::<Vec<_>>();
+    assert_eq!(types, vec!["INT64", "INT64", "INT64", "DATE"]);
+
+    let err = exec_err(&db, "ALTER TABLE prices ALTER COLUMN sku TYPE UUID");
+    assert!(!err.is_empty());
+    let result = exec(&db, "SELECT sku FROM prices ORDER BY id");
+    assert_eq!(
+        rows(&result),
+        vec![vec![Value::Int64(100)], vec![Value::Int64(200)]]
+    );
+}
+
+#[test]
+fn alter_column_type_rejects_collapsing_unique_keys() {
+    let db = mem_db();
+    exec(
+        &db,
+        "CREATE TABLE readings (id INT64 PRIMARY KEY, value FLOAT64 UNIQUE)",
+    );
+    exec(&db, "INSERT INTO readings VALUES (1, 1.2), (2, 1.4)");
+    let err = exec_err(&db, "ALTER TABLE readings ALTER COLUMN value TYPE INT64");
+    assert!(err.contains("unique constraint"), "{err}");
+    let result = exec(&db, "SELECT value FROM readings ORDER BY id");
+    assert_eq!(
+        rows(&result),
+        vec![vec![Value::Float64(1.2)], vec![Value::Float64(1.4)]]
+    );
+
+    exec(
+        &db,
+        "CREATE TABLE labels (id INT64 PRIMARY KEY, name TEXT NOT NULL)",
+    );
+    exec(&db, "INSERT INTO labels VALUES (1, 'a')");
+    let err = exec_err(
+        &db,
+        "ALTER TABLE labels ALTER COLUMN name TYPE TEXT USING NULL",
+    );
+    assert!(err.contains("may not be NULL"), "{err}");
+}
+
+#[test]
+fn alter_table_drop_column_rejects_dependent_views() {
+    let db = mem_db();
+    exec(
+        &db,
+        "CREATE TABLE accounts (id INT64 PRIMARY KEY, email TEXT, notes TEXT)",
+    );
+    exec(
+        &db,
+        "CREATE VIEW account_emails AS SELECT id, email FROM accounts",
+    );
+    let err = exec_err(&db, "ALTER TABLE accounts DROP COLUMN notes");
+    assert!(err.contains("dependent views"), "{err}");
+    exec(&db, "DROP VIEW account_emails");
+    exec(&db, "ALTER TABLE accounts DROP COLUMN notes");
+}
+
+#[test]
+fn alter_table_changes_are_visible_to_introspection_inside_transaction() {
+    let db = mem_db();
+    exec(
+        &db,
+        "CREATE TABLE items (id INT64 PRIMARY KEY, qty TEXT, legacy TEXT)",
+    );
+    exec(&db, "BEGIN");
+    exec(&db, "ALTER TABLE items DROP COLUMN legacy");
+    exec(&db, "ALTER TABLE items RENAME COLUMN qty TO quantity");
+    exec(&db, "ALTER TABLE items ALTER COLUMN quantity TYPE INT64");
+    let columns = db
+        .describe_table("items")
+        .unwrap()
+        .columns
+        .into_iter()
+        .map(|column| (column.name, column.column_type))
+        .collect::<Vec<_>>();
+    assert_eq!(
+        columns,
+        vec![
+            ("id".to_string(), "INT64".to_string()),
+            ("quantity".to_string(), "INT64".to_string())
+        ]
+    );
+    exec(&db, "ROLLBACK");
+    let names = db
+        .describe_table("items")
+        .unwrap()
+        .columns
+        .into_iter()
+        .map(|column| column.name)
+        .collect::<Vec<_>>();
+    assert_eq!(names, vec!["id", "qty", "legacy"]);
+}
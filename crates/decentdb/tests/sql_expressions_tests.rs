@@ -3167,6 +3167,59 @@ fn create_table_as_with_column_list_and_no_data() {
     assert_eq!(r.columns(), &["a".to_string(), "b".to_string()]);
 }
 
+#[test]
+fn create_table_as_keeps_source_column_types() {
+    let db = mem_db();
+    exec(
+        &db,
+        "CREATE TABLE orders (id INT64 PRIMARY KEY, total DECIMAL(10, 2), placed DATE, note TEXT, paid BOOL)",
+    );
+    exec(
+        &db,
+        "INSERT INTO orders VALUES (1, 9.50, '2026-01-02', NULL, NULL), (2, 20.00, '2026-02-03', NULL, NULL)",
+    );
+
+    exec(
+        &db,
+        "CREATE TABLE orders_shape AS SELECT * FROM orders WHERE id < 0",
+    );
+    let created = exec(
+        &db,
+        "CREATE TABLE orders_copy AS SELECT id, total, placed, paid, id * 2 AS doubled FROM orders",
+    );
+    assert_eq!(created.affected_rows(), 2);
+    let copied = exec(
+        &db,
+        "INSERT INTO orders_shape SELECT * FROM orders WHERE id = 2",
+    );
+    assert_eq!(copied.affected_rows(), 1);
+
+    let types = |table: &str| {
+        db.describe_table(table)
+            .unwrap()
+            .columns
+            .into_iter()
+            .map(|column| column.column_type)
+            .collect::<Vec<_>>()
+    };
+    assert_eq!(
+        types("orders_shape"),
+        vec!["INT64", "DECIMAL", "DATE", "TEXT", "BOOL"]
+    );
+    assert_eq!(
+        types("orders_copy"),
+        vec!["INT64", "DECIMAL", "DATE", "BOOL", "INT64"]
+    );
+    let r = exec(&db, "SELECT id, placed FROM orders_shape");
+    assert_eq!(
+        rows(&r),
+        vec![vec![
+            Value::Int64(2),
+            Value::DateDays(date_days(2026, 2, 3))
+        ]]
+    );
+}
+
 #[test]
 fn set_except() {
     let db = mem_db();
@@ -4375,3 +4428,50 @@ fn uuid_helper_functions_round_trip_and_generate_v4_values() {
     assert_eq!(row[3], Value::Null);
     assert_eq!(row[4], Value::Null);
 }
+
+#[test]
+fn uuid_v7_defaults_generate_ascending_keys_returned_by_insert() {
+    let db = mem_db();
+    exec(
+        &db,
+        "CREATE TABLE orders (id UUID PRIMARY KEY DEFAULT UUID_V7(), total INT64)",
+    );
+    let mut ids = Vec::new();
+    for total in 0..50 {
+        let result = exec(
+            &db,
+            &format!("INSERT INTO orders (total) VALUES ({total}) RETURNING id"),
+        );
+        match &result.rows()[0].values()[0] {
+            Value::Uuid(value) => {
+                assert_eq!(value[6] & 0xf0, 0x70);
+                assert_eq!(value[8] & 0xc0, 0x80);
+                ids.push(*value);
+            }
+            other => panic!("expected RETURNING id to be a UUID, got {other:?}"),
+        }
+    }
+    // Generated in one process, v7 values sort in generation order.
+    assert!(ids.windows(2).all(|pair| pair[0] < pair[1]));
+
+    let by_id = exec(&db, "SELECT total FROM orders ORDER BY id");
+    let totals = by_id
+        .rows()
+        .iter()
+        .map(|row| row.values()[0].clone())
+        .collect::<Vec<_>>();
+    assert_eq!(totals, (0..50).map(Value::Int64).collect::<Vec<_>>());
+}
+
+#[test]
+fn add_column_with_uuid_default_generates_a_value_per_row() {
+    let db = mem_db();
+    exec(&db, "CREATE TABLE items (id INT64 PRIMARY KEY)");
+    exec(&db, "INSERT INTO items VALUES (1), (2), (3)");
+    exec(
+        &db,
+        "ALTER TABLE items ADD COLUMN public_id UUID NOT NULL DEFAULT GEN_RANDOM_UUID()",
+    );
+    let result = exec(&db, "SELECT COUNT(DISTINCT public_id) FROM items");
+    assert_eq!(result.rows()[0].values()[0], Value::Int64(3));
+}
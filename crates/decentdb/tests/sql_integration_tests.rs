@@ -254,6 +254,252 @@ fn covering_index_projection_uses_include_payloads_and_safe_fallbacks() {
     cleanup_db(&path);
 }
 
+#[test]
+fn covering_index_scans_skip_the_heap_for_prepared_and_row_id_projections() {
+    let path = unique_db_path("covering-index-only-scan");
+    let db = Db::create(&path, DbConfig::default()).expect("create database");
+
+    db.execute(
+        "CREATE TABLE accounts (\
+         id INT64 PRIMARY KEY, \
+         email TEXT NOT NULL, \
+         plan TEXT NOT NULL, \
+         notes TEXT\
+         )",
+    )
+    .expect("create accounts");
+    db.execute("CREATE UNIQUE INDEX accounts_email ON accounts (email)")
+        .expect("create key-only index");
+    db.execute("CREATE INDEX accounts_plan_cover ON accounts (plan) INCLUDE (email)")
+        .expect("create covering index");
+    db.execute(
+        "INSERT INTO accounts (id, email, plan, notes) VALUES \
+         (1, 'ada@example.com', 'pro', 'first'), \
+         (2, 'grace@example.com', 'free', NULL), \
+         (3, 'alan@example.com', 'pro', NULL)",
+    )
+    .expect("insert accounts");
+
+    // Every index entry carries the row id, so a key-only index covers it.
+    let explain = db
+        .execute("EXPLAIN SELECT id FROM accounts WHERE email = 'grace@example.com'")
+        .expect("explain row-id lookup");
+    assert!(
+        explain.explain_lines().iter().any(|line| line
+            .contains("CoveringIndexSeek(table=accounts, index=accounts_email")
+            && line.contains("heap=skipped")),
+        "expected heap=skipped in {:?}",
+        explain.explain_lines()
+    );
+    let explain = db
+        .execute("EXPLAIN SELECT id, email FROM accounts WHERE plan = 'pro'")
+        .expect("explain covered lookup");
+    assert!(
+        explain.explain_lines().iter().any(
+            |line| line.contains("CoveringIndexSeek(table=accounts, index=accounts_plan_cover")
+        ),
+        "expected CoveringIndexSeek in {:?}",
+        explain.explain_lines()
+    );
+    let explain = db
+        .execute("EXPLAIN SELECT notes FROM accounts WHERE email = 'ada@example.com'")
+        .expect("explain uncovered lookup");
+    assert!(
+        explain
+            .explain_lines()
+            .iter()
+            .all(|line| !line.contains("heap=skipped")),
+        "uncovered projection must read the heap: {:?}",
+        explain.explain_lines()
+    );
+
+    let by_email = db
+        .prepare("SELECT id FROM accounts WHERE email = $1")
+        .expect("prepare row-id lookup");
+    let by_plan = db
+        .prepare("SELECT id, email FROM accounts WHERE plan = $1")
+        .expect("prepare covered lookup");
+    let plan_rows = |plan: &str| {
+        let mut rows = by_plan
+            .execute(&[Value::Text(plan.to_string())])
+            .expect("covered lookup")
+            .rows()
+            .iter()
+            .map(|row| row.values().to_vec())
+            .collect::<Vec<_>>();
+        rows.sort_by_key(|row| match row[0] {
+            Value::Int64(id) => id,
+            _ => i64::MAX,
+        });
+        rows
+    };
+
+    assert_eq!(
+        by_email
+            .execute(&[Value::Text("grace@example.com".to_string())])
+            .expect("row-id lookup")
+            .rows()[0]
+            .values(),
+        &[Value::Int64(2)]
+    );
+    assert_eq!(
+        plan_rows("pro"),
+        vec![
+            vec![Value::Int64(1), Value::Text("ada@example.com".to_string())],
+            vec![Value::Int64(3), Value::Text("alan@example.com".to_string())],
+        ]
+    );
+
+    // Writes keep the index payloads in step with the table.
+    db.execute("UPDATE accounts SET email = 'ada@lovelace.dev' WHERE id = 1")
+        .expect("update included column");
+    db.execute("DELETE FROM accounts WHERE id = 3")
+        .expect("delete covered row");
+    assert_eq!(
+        plan_rows("pro"),
+        vec![vec![
+            Value::Int64(1),
+            Value::Text("ada@lovelace.dev".to_string())
+        ]]
+    );
+    assert!(by_email
+        .execute(&[Value::Text("alan@example.com".to_string())])
+        .expect("deleted row-id lookup")
+        .rows()
+        .is_empty());
+    assert_eq!(
+        db.execute("SELECT id FROM accounts WHERE email = 'ada@lovelace.dev'")
+            .expect("ad hoc row-id lookup")
+            .rows()[0]
+            .values(),
+        &[Value::Int64(1)]
+    );
+
+    drop(by_email);
+    drop(by_plan);
+    drop(db);
+    let db = Db::open(&path, DbConfig::default()).expect("reopen database");
+    let by_plan = db
+        .prepare("SELECT id, email FROM accounts WHERE plan = $1")
+        .expect("prepare after reopen");
+    let reopened = by_plan
+        .execute(&[Value::Text("free".to_string())])
+        .expect("covered lookup after reopen");
+    assert_eq!(
+        reopened.rows()[0].values(),
+        &[
+            Value::Int64(2),
+            Value::Text("grace@example.com".to_string())
+        ]
+    );
+    drop(by_plan);
+    drop(db);
+
+    cleanup_db(&path);
+}
+
+#[test]
+fn prepared_lookups_do_not_serve_rows_from_partial_indexes() {
+    let path = unique_db_path("covering-partial-index");
+    let db = Db::create(&path, DbConfig::default()).expect("create database");
+    db.execute("CREATE TABLE accounts (id INT64 PRIMARY KEY, email TEXT, plan TEXT)")
+        .expect("create accounts");
+    db.execute(
+        "CREATE INDEX accounts_paid_plan ON accounts (plan) INCLUDE (email) WHERE plan <> 'free'",
+    )
+    .expect("create partial covering index");
+    db.execute(
+        "INSERT INTO accounts (id, email, plan) VALUES \
+         (1, 'ada@example.com', 'pro'), \
+         (2, 'grace@example.com', 'free'), \
+         (3, 'alan@example.com', 'free')",
+    )
+    .expect("insert accounts");
+
+    let explain = db
+        .execute("EXPLAIN SELECT id, email FROM accounts WHERE plan = 'free'")
+        .expect("explain partial index lookup");
+    assert!(
+        explain
+            .explain_lines()
+            .iter()
+            .all(|line| !line.contains("heap=skipped")),
+        "a partial index must not skip the heap: {:?}",
+        explain.explain_lines()
+    );
+
+    let by_plan = db
+        .prepare("SELECT id, email FROM accounts WHERE plan = $1")
+        .expect("prepare lookup");
+    let mut ids = by_plan
+        .execute(&[Value::Text("free".to_string())])
+        .expect("lookup outside the index predicate")
+        .rows()
+        .iter()
+        .map(|row| row.values()[0].clone())
+        .collect::<Vec<_>>();
+    ids.sort_by_key(|value| match value {
+        Value::Int64(id) => *id,
+        _ => i64::MAX,
+    });
+    assert_eq!(ids, vec![Value::Int64(2), Value::Int64(3)]);
+
+    drop(by_plan);
+    drop(db);
+    cleanup_db(&path);
+}
+
+#[test]
+fn prepared_covering_lookups_apply_row_level_security() {
+    let path = unique_db_path("covering-row-security");
+    let db = Db::create(&path, DbConfig::default()).expect("create database");
+    db.execute("CREATE TABLE docs (id INT64 PRIMARY KEY, tenant_id TEXT, title TEXT, topic TEXT)")
+        .expect("create docs");
+    db.execute("CREATE UNIQUE INDEX docs_title ON docs (title)")
+        .expect("create key-only index");
+    db.execute("CREATE INDEX docs_topic_cover ON docs (topic) INCLUDE (title)")
+        .expect("create covering index");
+    db.execute(
+        "INSERT INTO docs (id, tenant_id, title, topic) VALUES \
+         (1, 'tenant-a', 'roadmap', 'plans'), \
+         (2, 'tenant-b', 'budget', 'plans')",
+    )
+    .expect("insert docs");
+
+    db.execute("SET AUDIT CONTEXT tenant_id = 'tenant-a'")
+        .expect("set tenant");
+    db.execute("CREATE POLICY docs_tenant ON docs USING tenant_id = current_tenant()")
+        .expect("create policy");
+
+    let by_title = db
+        .prepare("SELECT id FROM docs WHERE title = $1")
+        .expect("prepare row-id lookup");
+    let by_topic = db
+        .prepare("SELECT id, title FROM docs WHERE topic = $1")
+        .expect("prepare covered lookup");
+    assert!(by_title
+        .execute(&[Value::Text("budget".to_string())])
+        .expect("row-id lookup of another tenant's row")
+        .rows()
+        .is_empty());
+    let visible = by_topic
+        .execute(&[Value::Text("plans".to_string())])
+        .expect("covered lookup with policy");
+    assert_eq!(
+        visible
+            .rows()
+            .iter()
+            .map(|row| row.values().to_vec())
+            .collect::<Vec<_>>(),
+        vec![vec![Value::Int64(1), Value::Text("roadmap".to_string())]]
+    );
+
+    drop(by_title);
+    drop(by_topic);
+    drop(db);
+    cleanup_db(&path);
+}
+
 #[test]
 fn simple_filtered_projection_query_supports_range_order_and_limit() {
     let path = unique_db_path("phase3-simple-filtered-projection");
@@ -631,6 +877,64 @@ fn analyze_stats_change_btree_explain_plan_based_on_selectivity() {
     cleanup_db(&path);
 }
 
+#[test]
+fn auto_analyze_refreshes_statistics_after_churn_and_they_persist() {
+    let path = unique_db_path("phase3-auto-analyze");
+    let config = DbConfig {
+        auto_analyze_min_rows: 20,
+        auto_analyze_churn_percent: 50,
+        ..DbConfig::default()
+    };
+    let db = Db::create(&path, config.clone()).expect("create database");
+    db.execute("CREATE TABLE readings (id INT64 PRIMARY KEY, sensor TEXT)")
+        .expect("create readings");
+    for id in 1..=19 {
+        db.execute(&format!(
+            "INSERT INTO readings (id, sensor) VALUES ({id}, 's{}')",
+            id % 4
+        ))
+        .expect("insert reading");
+    }
+    let stats = db.table_statistics("readings").expect("statistics");
+    assert_eq!(stats.row_count, None);
+    assert_eq!(stats.modified_rows_since_analyze, 19);
+
+    db.execute("INSERT INTO readings (id, sensor) VALUES (20, 's0')")
+        .expect("insert threshold row");
+    let stats = db.table_statistics("readings").expect("statistics");
+    assert_eq!(stats.row_count, Some(20));
+    assert_eq!(stats.modified_rows_since_analyze, 0);
+    assert_eq!(stats.columns[1].distinct_count, 4);
+
+    // The next threshold is 20 rows plus 50% of the 20 analyzed rows.
+    for id in 21..=49 {
+        db.execute(&format!(
+            "INSERT INTO readings (id, sensor) VALUES ({id}, 'x{id}')"
+        ))
+        .expect("insert reading");
+    }
+    assert_eq!(
+        db.table_statistics("readings")
+            .expect("statistics")
+            .row_count,
+        Some(20)
+    );
+    db.execute("DELETE FROM readings WHERE id = 49")
+        .expect("delete reading");
+    let stats = db.table_statistics("readings").expect("statistics");
+    assert_eq!(stats.row_count, Some(48));
+    drop(db);
+
+    let reopened = Db::open(&path, config).expect("reopen database");
+    let stats = reopened.table_statistics("readings").expect("statistics");
+    assert_eq!(stats.row_count, Some(48));
+    assert_eq!(stats.columns[1].distinct_count, 32);
+    assert!(!stats.columns[1].histogram_bounds.is_empty());
+    assert!(reopened.table_statistics("missing").is_err());
+
+    cleanup_db(&path);
+}
+
 #[test]
 fn read_executor_supports_parameterized_alias_joins_on_either_side() {
     let path = unique_db_path("phase3-join-fastpath");
@@ -1259,3 +1259,123 @@ fn test_offset_fetch() {
         Err(e) => println!("OFFSET ... FETCH: Error: {}", e),
     }
 }
+
+fn set_operation_kinds_db() -> Db {
+    let db = mem_db();
+    exec(
+        &db,
+        "CREATE TABLE a (i INT64, f FLOAT64, d2 DECIMAL(10,2), d4 DECIMAL(12,4), day DATE, at TIMESTAMP, s TEXT, b BLOB)",
+    );
+    exec(
+        &db,
+        "INSERT INTO a VALUES
+            (1, 1.0, 1.50, 1.5, '2026-03-01', '2026-03-01 00:00:00', 'x', X'78'),
+            (2, 2.5, 2.00, 3.25, '2026-03-02', '2026-03-02 12:00:00', NULL, NULL),
+            (NULL, NULL, NULL, NULL, NULL, NULL, NULL, NULL)",
+    );
+    db
+}
+
+#[test]
+fn set_operations_unify_decimal_scales() {
+    let db = set_operation_kinds_db();
+    let r = exec(&db, "SELECT d2 FROM a UNION SELECT d4 FROM a ORDER BY 1");
+    assert_eq!(
+        rows(&r),
+        vec![
+            vec![Value::Null],
+            vec![Value::Decimal {
+                scaled: 15000,
+                scale: 4
+            }],
+            vec![Value::Decimal {
+                scaled: 20000,
+                scale: 4
+            }],
+            vec![Value::Decimal {
+                scaled: 32500,
+                scale: 4
+            }],
+        ]
+    );
+
+    let r = exec(
+        &db,
+        "SELECT d2 FROM a INTERSECT SELECT d4 FROM a ORDER BY 1",
+    );
+    assert_eq!(rows(&r).len(), 2, "1.5 and NULL appear on both sides");
+
+    let r = exec(&db, "SELECT d4 FROM a EXCEPT SELECT d2 FROM a");
+    assert_eq!(
+        rows(&r),
+        vec![vec![Value::Decimal {
+            scaled: 32500,
+            scale: 4
+        }]]
+    );
+
+    let r = exec(&db, "SELECT i FROM a UNION ALL SELECT d2 FROM a ORDER BY 1");
+    let values = rows(&r);
+    assert!(values
+        .iter()
+        .all(|row| matches!(row[0], Value::Null | Value::Decimal { scale: 2, .. })));
+    assert_eq!(values.len(), 6);
+}
+
+#[test]
+fn set_operations_widen_integers_and_decimals_to_float() {
+    let db = set_operation_kinds_db();
+    let r = exec(&db, "SELECT i FROM a UNION SELECT f FROM a ORDER BY 1");
+    assert_eq!(
+        rows(&r),
+        vec![
+            vec![Value::Null],
+            vec![Value::Float64(1.0)],
+            vec![Value::Float64(2.0)],
+            vec![Value::Float64(2.5)],
+        ]
+    );
+
+    let r = exec(
+        &db,
+        "SELECT f FROM a INTERSECT ALL SELECT d2 FROM a ORDER BY 1",
+    );
+    assert_eq!(rows(&r), vec![vec![Value::Null]]);
+
+    let r = exec(&db, "SELECT 1 UNION SELECT 1.0");
+    assert_eq!(rows(&r), vec![vec![Value::Float64(1.0)]]);
+}
+
+#[test]
+fn set_operations_widen_dates_to_timestamps() {
+    let db = set_operation_kinds_db();
+    let r = exec(&db, "SELECT day FROM a UNION SELECT at FROM a ORDER BY 1");
+    let values = rows(&r);
+    assert_eq!(values.len(), 4, "a midnight timestamp equals its date");
+    assert!(values
+        .iter()
+        .all(|row| matches!(row[0], Value::Null | Value::TimestampMicros(_))));
+}
+
+#[test]
+fn set_operations_treat_nulls_as_duplicates() {
+    let db = set_operation_kinds_db();
+    let r = exec(&db, "SELECT s FROM a UNION SELECT NULL ORDER BY 1");
+    assert_eq!(
+        rows(&r),
+        vec![vec![Value::Null], vec![Value::Text("x".to_string())]]
+    );
+    let r = exec(&db, "SELECT s FROM a EXCEPT SELECT NULL");
+    assert_eq!(rows(&r), vec![vec![Value::Text("x".to_string())]]);
+    let r = exec(&db, "SELECT s FROM a EXCEPT ALL SELECT NULL");
+    assert_eq!(rows(&r).len(), 2);
+}
+
+#[test]
+fn set_operations_reject_mixed_text_and_blob_columns() {
+    let db = set_operation_kinds_db();
+    let err = exec_err(&db, "SELECT s FROM a UNION SELECT b FROM a");
+    assert!(err.contains("mixes TEXT and BLOB"), "{err}");
+    let err = exec_err(&db, "SELECT b FROM a UNION ALL SELECT s FROM a");
+    assert!(err.contains("mixes TEXT and BLOB"), "{err}");
+}
@@ -1933,3 +1933,114 @@ fn recursive_ctes_support_sequence_generation_and_tree_traversal() {
         ]
     );
 }
+
+fn org_chart_db() -> Db {
+    let db = mem_db();
+    db.execute(
+        "CREATE TABLE staff(id INT64 PRIMARY KEY, manager_id INT64, name TEXT, depth INT64)",
+    )
+    .unwrap();
+    db.execute(
+        "INSERT INTO staff VALUES
+         (1, NULL, 'ceo', NULL), (2, 1, 'cto', NULL), (3, 2, 'dev', NULL),
+         (4, 2, 'ops', NULL), (5, 1, 'cfo', NULL), (6, 5, 'clerk', NULL)",
+    )
+    .unwrap();
+    db
+}
+
+#[test]
+fn with_clause_leading_insert_update_and_delete() {
+    let db = org_chart_db();
+    db.execute("CREATE TABLE reports(manager_id INT64, report_id INT64)")
+        .unwrap();
+
+    let inserted = exec(
+        &db,
+        "WITH RECURSIVE chain(manager_id, report_id) AS (
+             SELECT manager_id, id FROM staff WHERE manager_id IS NOT NULL
+             UNION ALL
+             SELECT s.manager_id, c.report_id FROM staff s JOIN chain c ON s.id = c.manager_id
+             WHERE s.manager_id IS NOT NULL
+         )
+         INSERT INTO reports SELECT manager_id, report_id FROM chain",
+    );
+    assert_eq!(inserted.affected_rows(), 8);
+    let r = exec(
+        &db,
+        "SELECT report_id FROM reports WHERE manager_id = 1 ORDER BY report_id",
+    );
+    let ids: Vec<Value> = rows(&r).into_iter().map(|row| row[0].clone()).collect();
+    assert_eq!(
+        ids,
+        (2..=6).map(Value::Int64).collect::<Vec<_>>(),
+        "every transitive report of the root"
+    );
+
+    // A correlated subquery may join a recursive CTE that does not read the
+    // outer row.
+    exec(
+        &db,
+        "WITH RECURSIVE tree(id, lvl) AS (
+             SELECT id, 0 FROM staff WHERE manager_id IS NULL
+             UNION ALL
+             SELECT s.id, t.lvl + 1 FROM staff s JOIN tree t ON s.manager_id = t.id
+         )
+         UPDATE staff SET depth = (SELECT lvl FROM tree WHERE tree.id = staff.id)",
+    );
+    let r = exec(&db, "SELECT id, depth FROM staff ORDER BY id");
+    let depths: Vec<Value> = rows(&r).into_iter().map(|row| row[1].clone()).collect();
+    assert_eq!(depths, [0, 1, 2, 2, 1, 2].map(Value::Int64).to_vec());
+
+    let deleted = exec(
+        &db,
+        "WITH RECURSIVE subtree(id) AS (
+             SELECT id FROM staff WHERE name = 'cto'
+             UNION
+             SELECT s.id FROM staff s JOIN subtree t ON s.manager_id = t.id
+         )
+         DELETE FROM staff WHERE id IN (SELECT id FROM subtree) RETURNING name",
+    );
+    let mut names: Vec<Value> = rows(&deleted)
+        .into_iter()
+        .map(|row| row[0].clone())
+        .collect();
+    names.sort_by_key(|value| format!("{value:?}"));
+    assert_eq!(
+        names,
+        vec![
+            Value::Text("cto".into()),
+            Value::Text("dev".into()),
+            Value::Text("ops".into()),
+        ]
+    );
+    let r = exec(&db, "SELECT COUNT(*) FROM staff");
+    assert_eq!(rows(&r)[0][0], Value::Int64(3));
+}
+
+#[test]
+fn recursive_cte_with_union_stops_on_graph_cycles() {
+    let db = mem_db();
+    db.execute("CREATE TABLE edges(src INT64, dst INT64)")
+        .unwrap();
+    db.execute("INSERT INTO edges VALUES (1, 2), (2, 3), (3, 1), (3, 4)")
+        .unwrap();
+    let r = exec(
+        &db,
+        "WITH RECURSIVE reach(node) AS (
+             SELECT 1
+             UNION
+             SELECT e.dst FROM edges e JOIN reach r ON e.src = r.node
+         )
+         SELECT node FROM reach ORDER BY node",
+    );
+    assert_eq!(
+        rows(&r),
+        vec![
+            vec![Value::Int64(1)],
+            vec![Value::Int64(2)],
+            vec![Value::Int64(3)],
+            vec![Value::Int64(4)],
+        ]
+    );
+}
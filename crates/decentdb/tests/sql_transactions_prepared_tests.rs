@@ -2,7 +2,7 @@
 
 //! SQL transaction, prepared statement, EXPLAIN, and snapshot tests.
 //!
-//! Covers: BEGIN/COMMIT/ROLLBACK, savepoints, autocommit, prepared
+//! Covers: BEGIN/COMMIT/ROLLBACK, savepoints, two-phase commit, autocommit, prepared
 //! statements (INSERT/SELECT/UPDATE/DELETE), schema invalidation,
 //! batch execution, EXPLAIN, EXPLAIN ANALYZE, ANALYZE, snapshots,
 //! and transaction state validation.
@@ -1568,9 +1568,60 @@ fn analyze_executes_in_autocommit_and_rejects_explicit_transactions() {
             .contains("ANALYZE is not supported inside an explicit SQL transaction"),
         "unexpected error: {err}"
     );
+    assert!(err.is_ddl_not_transactional(), "{err}");
+    // The refused statement leaves the transaction open and usable.
+    assert!(db.in_transaction().unwrap());
+    db.execute("INSERT INTO docs VALUES (3, 'b@example.com')")
+        .unwrap();
     db.execute("ROLLBACK").unwrap();
 }
 
+#[test]
+fn ddl_commits_and_rolls_back_with_the_transaction() {
+    let db = mem_db();
+    exec(
+        &db,
+        "CREATE TABLE accounts (id INT64 PRIMARY KEY, name TEXT)",
+    );
+    exec(&db, "INSERT INTO accounts VALUES (1, 'ada')");
+
+    db.execute("BEGIN").unwrap();
+    exec(&db, "CREATE TABLE audit (id INT64 PRIMARY KEY, note TEXT)");
+    exec(&db, "ALTER TABLE accounts ADD COLUMN email TEXT");
+    exec(&db, "CREATE INDEX accounts_name_idx ON accounts (name)");
+    exec(&db, "INSERT INTO audit VALUES (1, 'created')");
+    exec(&db, "UPDATE accounts SET email = 'ada@example.com'");
+    db.execute("ROLLBACK").unwrap();
+
+    assert!(db.execute("SELECT * FROM audit").is_err());
+    assert!(db.execute("SELECT email FROM accounts").is_err());
+    assert!(db
+        .list_indexes()
+        .unwrap()
+        .iter()
+        .all(|index| index.name != "accounts_name_idx"));
+    let r = exec(&db, "SELECT name FROM accounts");
+    assert_eq!(rows(&r), vec![vec![Value::Text("ada".to_string())]]);
+
+    db.execute("BEGIN").unwrap();
+    exec(&db, "DROP TABLE accounts");
+    db.execute("ROLLBACK").unwrap();
+    let r = exec(&db, "SELECT COUNT(*) FROM accounts");
+    assert_eq!(rows(&r)[0][0], Value::Int64(1));
+
+    db.execute("BEGIN").unwrap();
+    exec(&db, "ALTER TABLE accounts ADD COLUMN email TEXT");
+    exec(&db, "UPDATE accounts SET email = 'ada@example.com'");
+    exec(&db, "CREATE TABLE audit (id INT64 PRIMARY KEY, note TEXT)");
+    db.execute("COMMIT").unwrap();
+    let r = exec(&db, "SELECT email FROM accounts");
+    assert_eq!(
+        rows(&r),
+        vec![vec![Value::Text("ada@example.com".to_string())]]
+    );
+    exec(&db, "SELECT * FROM audit");
+}
+
 #[test]
 fn commit_persists_changes() {
     let db = Db::open_or_create(":memory:", DbConfig::default()).unwrap();
@@ -1785,3 +1836,281 @@ fn fast_update_then_generic_delete_preserves_dirty() {
         "CASCADE should have cleaned child"
     );
 }
+
+#[test]
+fn two_phase_commit_prepared() {
+    let db = mem_db();
+    exec(&db, "CREATE TABLE tpc (id INT PRIMARY KEY)");
+    exec(&db, "BEGIN");
+    exec(&db, "INSERT INTO tpc VALUES (1)");
+    exec(&db, "PREPARE TRANSACTION 'order 42'");
+    assert!(!db.in_transaction().unwrap());
+    assert_eq!(db.prepared_transactions().unwrap(), vec!["order 42"]);
+    // Prepared work stays invisible until the second phase.
+    let r = exec(&db, "SELECT COUNT(*) FROM tpc");
+    assert_eq!(r.rows()[0].values()[0], Value::Int64(0));
+
+    exec(&db, "commit prepared 'order 42';");
+    let r = exec(&db, "SELECT COUNT(*) FROM tpc");
+    assert_eq!(r.rows()[0].values()[0], Value::Int64(1));
+    assert!(db.prepared_transactions().unwrap().is_empty());
+    assert!(exec_err(&db, "COMMIT PREPARED 'order 42'").contains("does not exist"));
+}
+
+#[test]
+fn two_phase_rollback_prepared() {
+    let db = mem_db();
+    exec(&db, "CREATE TABLE tpc (id INT PRIMARY KEY)");
+    db.begin_transaction().unwrap();
+    exec(&db, "INSERT INTO tpc VALUES (1)");
+    db.prepare_transaction("it's").unwrap();
+    exec(&db, "ROLLBACK PREPARED 'it''s'");
+    let r = exec(&db, "SELECT COUNT(*) FROM tpc");
+    assert_eq!(r.rows()[0].values()[0], Value::Int64(0));
+}
+
+#[test]
+fn two_phase_state_validation() {
+    let db = mem_db();
+    exec(&db, "CREATE TABLE tpc (id INT PRIMARY KEY)");
+    exec(&db, "CREATE TABLE other (id INT PRIMARY KEY)");
+    assert!(exec_err(&db, "PREPARE TRANSACTION 'g'").contains("requires an active SQL transaction"));
+    exec(&db, "BEGIN");
+    assert!(exec_err(&db, "PREPARE TRANSACTION ''").contains("1 to 200 bytes"));
+    exec(&db, "INSERT INTO tpc VALUES (1)");
+    exec(&db, "PREPARE TRANSACTION 'g'");
+    exec(&db, "BEGIN");
+    assert!(exec_err(&db, "PREPARE TRANSACTION 'g'").contains("already in use"));
+    assert!(exec_err(&db, "COMMIT PREPARED 'g'").contains("inside a transaction"));
+    exec(&db, "ROLLBACK");
+    assert!(db.prepare("PREPARE TRANSACTION 'g'").is_err());
+
+    // The prepared transaction keeps the tables it wrote until the second
+    // phase; other tables stay writable.
+    exec(&db, "PRAGMA busy_timeout = 20");
+    let err = exec_err(&db, "INSERT INTO tpc VALUES (2)");
+    assert!(err.contains("locked by another transaction"), "{err}");
+    exec(&db, "INSERT INTO other VALUES (1)");
+    db.commit_prepared("g").unwrap();
+    exec(&db, "INSERT INTO tpc VALUES (2)");
+    let r = exec(&db, "SELECT id FROM tpc ORDER BY id");
+    assert_eq!(rows(&r), vec![vec![Value::Int64(1)], vec![Value::Int64(2)]]);
+
+    exec(&db, "BEGIN");
+    exec(&db, "CREATE TABLE later (id INT PRIMARY KEY)");
+    assert!(exec_err(&db, "PREPARE TRANSACTION 'ddl'").contains("schema changes"));
+    assert!(!db.in_transaction().unwrap());
+    assert!(db.prepared_transactions().unwrap().is_empty());
+}
+
+#[test]
+fn two_phase_prepared_transaction_survives_reopen() {
+    let dir = TempDir::new().unwrap();
+    let path = dir.path().join("two_phase.ddb");
+    {
+        let db = Db::open_or_create(&path, DbConfig::default()).unwrap();
+        exec(&db, "CREATE TABLE tpc (id INT PRIMARY KEY, note TEXT)");
+        exec(&db, "CREATE INDEX tpc_note ON tpc(note)");
+        exec(&db, "CREATE TABLE audit (id INT PRIMARY KEY)");
+        exec(&db, "INSERT INTO tpc VALUES (1, 'kept')");
+        exec(&db, "BEGIN");
+        exec(&db, "INSERT INTO tpc VALUES (2, 'prepared')");
+        exec(&db, "DELETE FROM tpc WHERE id = 1");
+        exec(&db, "PREPARE TRANSACTION 'restart'");
+        exec(&db, "BEGIN");
+        exec(&db, "INSERT INTO audit VALUES (1)");
+        exec(&db, "PREPARE TRANSACTION 'discard'");
+    }
+
+    let db = Db::open_or_create(&path, DbConfig::default()).unwrap();
+    assert_eq!(
+        db.prepared_transactions().unwrap(),
+        vec!["discard", "restart"]
+    );
+    let r = exec(&db, "SELECT id FROM tpc ORDER BY id");
+    assert_eq!(rows(&r), vec![vec![Value::Int64(1)]]);
+    // Reopening reserves the prepared tables again.
+    exec(&db, "PRAGMA busy_timeout = 20");
+    let err = exec_err(&db, "UPDATE tpc SET note = 'changed'");
+    assert!(err.contains("locked by another transaction"), "{err}");
+
+    exec(&db, "COMMIT PREPARED 'restart'");
+    exec(&db, "ROLLBACK PREPARED 'discard'");
+    assert!(db.prepared_transactions().unwrap().is_empty());
+    let r = exec(&db, "SELECT id, note FROM tpc WHERE note = 'prepared'");
+    assert_eq!(
+        rows(&r),
+        vec![vec![Value::Int64(2), Value::Text("prepared".to_string())]]
+    );
+    exec(&db, "INSERT INTO tpc VALUES (3, 'after')");
+    exec(&db, "INSERT INTO audit VALUES (2)");
+    drop(db);
+
+    let db = Db::open_or_create(&path, DbConfig::default()).unwrap();
+    assert!(db.prepared_transactions().unwrap().is_empty());
+    let r = exec(&db, "SELECT id FROM tpc ORDER BY id");
+    assert_eq!(rows(&r), vec![vec![Value::Int64(2)], vec![Value::Int64(3)]]);
+    let r = exec(&db, "SELECT id FROM audit");
+    assert_eq!(rows(&r), vec![vec![Value::Int64(2)]]);
+}
+
+#[test]
+fn two_phase_reserved_tables_hold_off_other_handles() {
+    let dir = TempDir::new().unwrap();
+    let path = dir.path().join("two_phase_handles.ddb");
+    let db = Db::open_or_create(&path, DbConfig::default()).unwrap();
+    exec(&db, "CREATE TABLE tpc (id INT PRIMARY KEY, note TEXT)");
+    exec(&db, "CREATE INDEX tpc_note ON tpc(note)");
+    exec(
+        &db,
+        "INSERT INTO tpc VALUES (1, 'one'), (2, 'two'), (3, 'three')",
+    );
+    exec(&db, "BEGIN");
+    exec(&db, "UPDATE tpc SET note = 'TWO' WHERE id = 2");
+    exec(&db, "DELETE FROM tpc WHERE id = 3");
+    exec(&db, "INSERT INTO tpc VALUES (4, 'four')");
+    exec(&db, "PREPARE TRANSACTION 'rows'");
+
+    let other = Db::open_or_create(&path, DbConfig::default()).unwrap();
+    exec(&other, "PRAGMA busy_timeout = 20");
+    for sql in [
+        "INSERT INTO tpc VALUES (5, 'five')",
+        "UPDATE tpc SET note = 'changed' WHERE id = 1",
+        "DELETE FROM tpc",
+    ] {
+        let err = exec_err(&other, sql);
+        assert!(
+            err.contains("locked by another transaction"),
+            "{sql}: {err}"
+        );
+    }
+    let r = exec(&other, "SELECT id FROM tpc ORDER BY id");
+    assert_eq!(
+        rows(&r),
+        vec![
+            vec![Value::Int64(1)],
+            vec![Value::Int64(2)],
+            vec![Value::Int64(3)]
+        ]
+    );
+
+    exec(&other, "COMMIT PREPARED 'rows'");
+    exec(&other, "INSERT INTO tpc VALUES (5, 'five')");
+    let r = exec(&db, "SELECT id, note FROM tpc ORDER BY id");
+    assert_eq!(
+        rows(&r),
+        vec![
+            vec![Value::Int64(1), Value::Text("one".into())],
+            vec![Value::Int64(2), Value::Text("TWO".into())],
+            vec![Value::Int64(4), Value::Text("four".into())],
+            vec![Value::Int64(5), Value::Text("five".into())],
+        ]
+    );
+    // The index followed the row changes.
+    let r = exec(&db, "SELECT id FROM tpc WHERE note = 'TWO'");
+    assert_eq!(rows(&r), vec![vec![Value::Int64(2)]]);
+    let r = exec(&db, "SELECT id FROM tpc WHERE note = 'three'");
+    assert!(rows(&r).is_empty());
+}
+
+#[test]
+fn advisory_locks_exclude_other_handles_until_released() {
+    let dir = TempDir::new().unwrap();
+    let path = dir.path().join("advisory.ddb");
+    let leader = Db::open_or_create(&path, DbConfig::default()).unwrap();
+    let follower = Db::open_or_create(&path, DbConfig::default()).unwrap();
+
+    assert!(leader.advisory_try_lock(42).unwrap());
+    assert!(leader.advisory_try_lock(42).unwrap(), "reentrant");
+    assert!(!follower.advisory_try_lock(42).unwrap());
+    assert!(follower.advisory_try_lock(-42).unwrap());
+    let err = follower
+        .advisory_lock(42, Some(std::time::Duration::from_millis(10)))
+        .unwrap_err();
+    assert!(err.to_string().contains("advisory lock 42"), "{err}");
+    assert!(!follower.advisory_unlock(42).unwrap());
+
+    assert!(leader.advisory_unlock(42).unwrap());
+    assert!(!follower.advisory_try_lock(42).unwrap());
+    assert!(leader.advisory_unlock(42).unwrap());
+    assert!(follower.advisory_try_lock(42).unwrap());
+
+    drop(follower);
+    assert!(leader.advisory_try_lock(42).unwrap());
+    assert!(leader.advisory_try_lock(-42).unwrap());
+}
+
+#[test]
+fn advisory_lock_sql_functions() {
+    let db = mem_db();
+    let r = exec(&db, "SELECT advisory_lock(7)");
+    assert_eq!(r.columns(), ["advisory_lock"]);
+    assert_eq!(rows(&r), vec![vec![Value::Bool(true)]]);
+    let r = db
+        .execute_with_params("SELECT pg_advisory_try_lock($1)", &[Value::Int64(7)])
+        .unwrap();
+    assert_eq!(rows(&r), vec![vec![Value::Bool(true)]]);
+    let stmt = db.prepare("SELECT advisory_unlock($1)").unwrap();
+    for expected in [true, true, false] {
+        let r = stmt.execute(&[Value::Int64(7)]).unwrap();
+        assert_eq!(rows(&r), vec![vec![Value::Bool(expected)]]);
+    }
+    assert!(exec_err(&db, "SELECT advisory_unlock('x')").contains("expects int"));
+
+    // The functions are ordinary scalars: any case, aliased, and inside
+    // larger queries.
+    let r = exec(
+        &db,
+        "select Pg_Try_Advisory_Lock(8) AS held, n FROM (SELECT 1 AS n) AS t WHERE n = 1",
+    );
+    assert_eq!(r.columns(), ["held", "n"]);
+    assert_eq!(rows(&r), vec![vec![Value::Bool(true), Value::Int64(1)]]);
+    let r = db
+        .execute_with_params(
+            "SELECT CASE WHEN ADVISORY_UNLOCK($1 + 0) THEN 'released' ELSE 'not held' END",
+            &[Value::Int64(8)],
+        )
+        .unwrap();
+    assert_eq!(rows(&r), vec![vec![Value::Text("released".into())]]);
+    assert!(!db.advisory_unlock(8).unwrap());
+}
+
+#[test]
+fn lock_table_makes_other_writers_wait_for_the_transaction() {
+    let dir = TempDir::new().unwrap();
+    let path = dir.path().join("lock_table.ddb");
+    let maintainer = Db::open_or_create(&path, DbConfig::default()).unwrap();
+    let writer = Db::open_or_create(&path, DbConfig::default()).unwrap();
+    exec(&maintainer, "CREATE TABLE jobs (id INT64 PRIMARY KEY)");
+    exec(&writer, "PRAGMA busy_timeout = 20");
+
+    assert!(exec_err(&maintainer, "LOCK TABLE jobs").contains("transaction blocks"));
+    exec(&maintainer, "BEGIN");
+    assert!(exec_err(&maintainer, "LOCK TABLE jobs IN SHARE MODE").contains("not SHARE"));
+    assert!(exec_err(&maintainer, "LOCK TABLE missing").contains("does not exist"));
+    // Commits that landed before the lock was granted do not make the
+    // locking transaction conflict.
+    exec(&writer, "INSERT INTO jobs VALUES (1)");
+    exec(&maintainer, "LOCK TABLE jobs IN EXCLUSIVE MODE");
+
+    let err = writer.execute("INSERT INTO jobs VALUES (2)").unwrap_err();
+    assert!(
+        err.to_string().contains("locked by another transaction"),
+        "{err}"
+    );
+    exec(&writer, "BEGIN");
+    let err = writer.execute("LOCK TABLE jobs NOWAIT").unwrap_err();
+    assert!(err.to_string().contains("could not obtain lock"), "{err}");
+    exec(&writer, "ROLLBACK");
+
+    exec(&maintainer, "DELETE FROM jobs");
+    exec(&maintainer, "INSERT INTO jobs VALUES (10)");
+    exec(&maintainer, "COMMIT");
+
+    exec(&writer, "INSERT INTO jobs VALUES (2)");
+    let r = exec(&writer, "SELECT id FROM jobs ORDER BY id");
+    assert_eq!(
+        rows(&r),
+        vec![vec![Value::Int64(2)], vec![Value::Int64(10)]]
+    );
+}
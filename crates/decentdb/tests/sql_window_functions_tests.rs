@@ -939,3 +939,109 @@ fn window_value_accessors_work_with_partitions() {
         ]
     );
 }
+
+#[test]
+fn window_range_frame_with_offset_bounds() {
+    let db = mem_db();
+    db.execute("CREATE TABLE t(id INT64, day INT64, val INT64)")
+        .unwrap();
+    db.execute(
+        "INSERT INTO t VALUES (1,1,10),(2,2,20),(3,2,5),(4,5,40),(5,9,50),(6,NULL,7),(7,NULL,8)",
+    )
+    .unwrap();
+
+    // Rows within two days before, peers included, whatever the row count.
+    let r = exec(
+        &db,
+        "SELECT id, SUM(val) OVER (ORDER BY day RANGE BETWEEN 2 PRECEDING AND CURRENT ROW)
+         FROM t ORDER BY id",
+    );
+    let sums: Vec<Value> = rows(&r).into_iter().map(|row| row[1].clone()).collect();
+    assert_eq!(
+        sums,
+        vec![
+            Value::Int64(10),
+            Value::Int64(35),
+            Value::Int64(35),
+            Value::Int64(40),
+            Value::Int64(50),
+            Value::Int64(15),
+            Value::Int64(15),
+        ]
+    );
+
+    // Descending order turns PRECEDING toward larger keys.
+    let r = exec(
+        &db,
+        "SELECT id, COUNT(*) OVER (ORDER BY day DESC RANGE BETWEEN CURRENT ROW AND 3 FOLLOWING)
+         FROM t WHERE day IS NOT NULL ORDER BY id",
+    );
+    let counts: Vec<Value> = rows(&r).into_iter().map(|row| row[1].clone()).collect();
+    assert_eq!(
+        counts,
+        vec![
+            Value::Int64(1),
+            Value::Int64(3),
+            Value::Int64(3),
+            Value::Int64(3),
+            Value::Int64(1),
+        ]
+    );
+
+    let r = exec(
+        &db,
+        "SELECT id, SUM(val) OVER (ORDER BY day RANGE BETWEEN 1.5 PRECEDING AND 3 FOLLOWING)
+         FROM t WHERE id <= 4 ORDER BY id",
+    );
+    let sums: Vec<Value> = rows(&r).into_iter().map(|row| row[1].clone()).collect();
+    assert_eq!(
+        sums,
+        vec![
+            Value::Int64(35),
+            Value::Int64(75),
+            Value::Int64(75),
+            Value::Int64(40),
+        ]
+    );
+
+    let err = db
+        .execute("SELECT SUM(val) OVER (ORDER BY day, id RANGE BETWEEN 1 PRECEDING AND CURRENT ROW) FROM t")
+        .unwrap_err();
+    assert!(err.to_string().contains("exactly one ORDER BY"), "{err}");
+    let err = db
+        .execute(
+            "SELECT SUM(val) OVER (ORDER BY day RANGE BETWEEN -1 PRECEDING AND CURRENT ROW) FROM t",
+        )
+        .unwrap_err();
+    assert!(err.to_string().contains("must not be negative"), "{err}");
+}
+
+#[test]
+fn window_range_frame_over_timestamps_takes_intervals() {
+    let db = mem_db();
+    db.execute("CREATE TABLE events(id INT64, at TIMESTAMP, amount INT64)")
+        .unwrap();
+    db.execute(
+        "INSERT INTO events VALUES
+         (1, '2026-01-01 00:00:00', 1),
+         (2, '2026-01-01 12:00:00', 2),
+         (3, '2026-01-02 06:00:00', 4),
+         (4, '2026-01-05 00:00:00', 8)",
+    )
+    .unwrap();
+    let r = exec(
+        &db,
+        "SELECT id, SUM(amount) OVER (ORDER BY at RANGE BETWEEN INTERVAL '1 day' PRECEDING AND CURRENT ROW)
+         FROM events ORDER BY id",
+    );
+    let sums: Vec<Value> = rows(&r).into_iter().map(|row| row[1].clone()).collect();
+    assert_eq!(
+        sums,
+        vec![
+            Value::Int64(1),
+            Value::Int64(3),
+            Value::Int64(6),
+            Value::Int64(8),
+        ]
+    );
+}
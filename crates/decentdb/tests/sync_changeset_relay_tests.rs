@@ -1,6 +1,7 @@
 use decentdb::{
     ApplyChangesetOptions, CreateChangesetOptions, CreateShapeOptions, Db, DbConfig,
-    InspectChangesetOptions, ShapeAckOptions, SyncChangesetSource, Value,
+    InspectChangesetOptions, InvertChangesetOptions, ShapeAckOptions, SyncChangesetSource,
+    SyncConflictPolicy, Value,
 };
 
 fn create_sync_db(path: &std::path::Path, replica_id: &str) -> Db {
@@ -118,6 +119,66 @@ fn public_changeset_rejects_tampering_before_mutation() {
     assert!(rows.rows().is_empty());
 }
 
+#[test]
+fn checkpoint_changeset_carries_before_images_and_inverts() {
+    let dir = tempfile::TempDir::with_prefix("decentdb-changeset-invert").unwrap();
+    let source = create_sync_db(&dir.path().join("source.ddb"), "node-a");
+    let target = create_sync_db(&dir.path().join("target.ddb"), "node-b");
+
+    for sql in [
+        "INSERT INTO tasks (tenant_id, id, title) VALUES (42, 1, 'draft')",
+        "INSERT INTO tasks (tenant_id, id, title) VALUES (42, 2, 'spare')",
+        "UPDATE tasks SET title = 'ready' WHERE tenant_id = 42 AND id = 1",
+        "DELETE FROM tasks WHERE tenant_id = 42 AND id = 2",
+    ] {
+        source.execute(sql).unwrap();
+    }
+    let changeset = source
+        .sync_create_changeset(CreateChangesetOptions {
+            source: SyncChangesetSource::Checkpoint {
+                peer: "node-b".to_string(),
+                since_sequence: 0,
+            },
+            scope_name: None,
+            shape_id: None,
+            max_records: None,
+            max_bytes: None,
+            principal: None,
+        })
+        .unwrap();
+    assert!(changeset.producer_capabilities.before_images);
+    assert_eq!(
+        changeset.records[2].before,
+        Some(serde_json::json!({"tenant_id": 42, "id": 1, "title": "draft"}))
+    );
+    assert_eq!(
+        changeset.records[3].before,
+        Some(serde_json::json!({"tenant_id": 42, "id": 2, "title": "spare"}))
+    );
+
+    let applied = target
+        .sync_apply_changeset(&changeset, ApplyChangesetOptions::default())
+        .unwrap();
+    assert_eq!(applied.outcome, "applied");
+
+    let inverse = target
+        .sync_invert_changeset(&changeset, InvertChangesetOptions::default())
+        .unwrap();
+    let operations = inverse
+        .records
+        .iter()
+        .map(|record| record.operation.as_str())
+        .collect::<Vec<_>>();
+    assert_eq!(operations, ["insert", "update", "delete", "delete"]);
+    let undone = target
+        .sync_apply_changeset(&inverse, ApplyChangesetOptions::default())
+        .unwrap();
+    assert_eq!(undone.outcome, "applied");
+    assert_eq!(undone.rows_applied, 4);
+    let rows = target.execute("SELECT * FROM tasks").unwrap();
+    assert!(rows.rows().is_empty());
+}
+
 #[test]
 fn relay_shapes_snapshot_ack_and_retention_diagnostics_are_durable() {
     let dir = tempfile::TempDir::with_prefix("decentdb-shape-diagnostics").unwrap();
@@ -170,3 +231,72 @@ fn relay_shapes_snapshot_ack_and_retention_diagnostics_are_durable() {
         .iter()
         .any(|entry| entry.contains("shape:tenant_42_tasks_v1:client:web-1")));
 }
+
+#[test]
+fn publication_batches_replicate_selected_tables_to_a_different_schema() {
+    let dir = tempfile::TempDir::with_prefix("decentdb-publication").unwrap();
+    let publisher = create_sync_db(&dir.path().join("publisher.ddb"), "node-a");
+    publisher
+        .execute("CREATE TABLE audit (id INT64 PRIMARY KEY, note TEXT)")
+        .unwrap();
+    publisher
+        .execute("CREATE PUBLICATION task_feed FOR TABLE tasks")
+        .unwrap();
+    let duplicate = publisher
+        .execute("CREATE PUBLICATION task_feed FOR TABLE tasks")
+        .unwrap_err();
+    assert!(duplicate.to_string().contains("already exists"));
+    assert!(publisher
+        .execute("CREATE PUBLICATION broken FOR TABLE tasks,")
+        .is_err());
+    for sql in [
+        "CREATE SUBSCRIPTION task_sub CONNECTION 'node-a' PUBLICATION task_feed",
+        "drop subscription task_sub",
+    ] {
+        let refused = publisher.execute(sql).unwrap_err();
+        assert!(refused
+            .to_string()
+            .contains("SUBSCRIPTION is not supported"));
+    }
+
+    // The subscriber has tables of its own, so its schema cookie differs.
+    let subscriber = Db::create(dir.path().join("subscriber.ddb"), DbConfig::default()).unwrap();
+    for sql in [
+        "CREATE TABLE local_notes (id INT64 PRIMARY KEY)",
+        "CREATE TABLE local_tags (id INT64 PRIMARY KEY)",
+    ] {
+        subscriber.execute(sql).unwrap();
+    }
+    subscriber
+        .execute(
+            "CREATE TABLE tasks (tenant_id INT64, id INT64, title TEXT, PRIMARY KEY (tenant_id, id))",
+        )
+        .unwrap();
+
+    publisher
+        .execute("INSERT INTO tasks (tenant_id, id, title) VALUES (7, 1, 'ship')")
+        .unwrap();
+    publisher
+        .execute("INSERT INTO audit (id, note) VALUES (1, 'private')")
+        .unwrap();
+    let batch = publisher
+        .sync_export_batch_for_scope("task_feed", 0, 100)
+        .unwrap();
+    assert_eq!(batch.record_count, 1);
+    assert_eq!(batch.source_high_watermark, Some(2));
+    assert!(subscriber.sync_import_batch(&batch).is_err());
+
+    let summary = subscriber
+        .sync_import_publication_batch(&batch, SyncConflictPolicy::Record)
+        .unwrap();
+    assert_eq!(summary.applied, 1);
+    assert_eq!(subscriber.sync_peer_watermark("node-a").unwrap(), Some(2));
+    let rows = subscriber.execute("SELECT title FROM tasks").unwrap();
+    assert_eq!(rows.rows()[0].values(), &[Value::Text("ship".to_string())]);
+
+    publisher.execute("DROP PUBLICATION task_feed").unwrap();
+    publisher
+        .execute("DROP PUBLICATION IF EXISTS task_feed")
+        .unwrap();
+    assert!(publisher.execute("DROP PUBLICATION task_feed").is_err());
+}
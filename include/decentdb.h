@@ -37,6 +37,8 @@ typedef struct ddb_db_handle ddb_db_t;
 typedef struct ddb_result_handle ddb_result_t;
 typedef struct ddb_stmt_handle ddb_stmt_t;
 typedef struct ddb_watch_handle ddb_watch_t;
+typedef struct ddb_backup_handle ddb_backup_t;
+typedef struct ddb_restore_handle ddb_restore_t;
 
 typedef enum ddb_value_tag_t {
   DDB_VALUE_NULL = 0,
@@ -105,6 +107,32 @@ typedef struct ddb_write_queue_metrics_t {
   uint64_t total_queue_wait_ns;
 } ddb_write_queue_metrics_t;
 
+/*
+ * Host-implemented VFS registered with ddb_vfs_register. Callbacks return
+ * DDB_VFS_OK, DDB_VFS_ERR_NOT_FOUND, DDB_VFS_ERR_EXISTS, or any other
+ * nonzero value for a generic I/O error. open's mode is 0 (create new),
+ * 1 (open existing), or 2 (open or create); kind is 0 (database), 1 (WAL),
+ * 2 (sync journal), or 3 (coordination). lock and unlock may both be NULL.
+ */
+#define DDB_VFS_OK 0
+#define DDB_VFS_ERR_NOT_FOUND 2
+#define DDB_VFS_ERR_EXISTS 3
+
+typedef struct ddb_vfs_methods_t {
+  void *user_data;
+  int32_t (*open)(void *user_data, const char *path, uint32_t mode, uint32_t kind, uint64_t *out_file);
+  int32_t (*exists)(void *user_data, const char *path, uint8_t *out_exists);
+  int32_t (*remove)(void *user_data, const char *path);
+  int32_t (*read_at)(void *user_data, uint64_t file, uint64_t offset, uint8_t *buf, size_t len, size_t *out_read);
+  int32_t (*write_at)(void *user_data, uint64_t file, uint64_t offset, const uint8_t *buf, size_t len, size_t *out_written);
+  int32_t (*sync)(void *user_data, uint64_t file, uint8_t metadata);
+  int32_t (*size)(void *user_data, uint64_t file, uint64_t *out_size);
+  int32_t (*truncate)(void *user_data, uint64_t file, uint64_t len);
+  int32_t (*lock)(void *user_data, uint64_t file, uint64_t offset, uint64_t len, uint8_t exclusive, uint8_t *out_acquired);
+  int32_t (*unlock)(void *user_data, uint64_t file, uint64_t offset, uint64_t len);
+  void (*close)(void *user_data, uint64_t file);
+} ddb_vfs_methods_t;
+
 typedef struct ddb_value_view_t {
   uint32_t tag;
   uint8_t bool_value;
@@ -190,6 +218,19 @@ ddb_status_t ddb_db_open_or_create(const char *path, ddb_db_t **out_db);
 ddb_status_t ddb_db_create_with_options(const char *path, const char *options, ddb_db_t **out_db);
 ddb_status_t ddb_db_open_with_options(const char *path, const char *options, ddb_db_t **out_db);
 ddb_status_t ddb_db_open_or_create_with_options(const char *path, const char *options, ddb_db_t **out_db);
+/*
+ * Opens a database, creating it first when create_if_missing is non-zero,
+ * and calls progress periodically while WAL frames left by an unclean
+ * shutdown are replayed. progress only runs during this call, on the calling
+ * thread. Returning non-zero from it fails the open with DDB_ERR_CANCELED and
+ * leaves the WAL in place for a later open. progress may be NULL.
+ */
+typedef int32_t (*ddb_recovery_progress_fn)(void *user_data, uint64_t frames_replayed,
+                                            uint64_t bytes_replayed, uint64_t bytes_total);
+ddb_status_t ddb_db_open_with_recovery_progress(const char *path, const char *options,
+                                                uint8_t create_if_missing,
+                                                ddb_recovery_progress_fn progress,
+                                                void *user_data, ddb_db_t **out_db);
 ddb_status_t ddb_db_sync_execute_json(ddb_db_t *db, const char *request_json, char **out_json);
 ddb_status_t ddb_db_branch_execute_json(ddb_db_t *db, const char *request_json, char **out_json);
 /*
@@ -401,6 +442,16 @@ ddb_status_t ddb_stmt_fetch_row_views(
     const ddb_value_view_t **out_values,
     size_t *out_rows,
     size_t *out_columns);
+/*
+ * Restricts the row-view functions above to the result columns listed in
+ * column_indexes, in that order; out_columns then reports count. Use it to
+ * read a few columns of a wide row without converting the rest. A count of
+ * 0 restores every column. The projection lasts until changed.
+ */
+ddb_status_t ddb_stmt_set_row_view_columns(
+    ddb_stmt_t *stmt,
+    const size_t *column_indexes,
+    size_t count);
 ddb_status_t ddb_stmt_fetch_rows_i64_text_f64(
     ddb_stmt_t *stmt,
     uint8_t include_current_row,
@@ -468,13 +519,70 @@ ddb_status_t ddb_watch_next_json(
 ddb_status_t ddb_watch_close(ddb_watch_t **watch);
 
 ddb_status_t ddb_db_checkpoint(ddb_db_t *db);
+ddb_status_t ddb_db_flush(ddb_db_t *db);
+/* Cancels the statement running on db from another thread; the statement
+ * fails with DDB_ERR_CANCELED. A no-op when the handle is idle. */
+ddb_status_t ddb_db_interrupt(ddb_db_t *db);
+ddb_status_t ddb_db_wal_size(ddb_db_t *db, uint64_t *out_bytes);
+ddb_status_t ddb_db_sweep_expired_rows(ddb_db_t *db, size_t batch_size, uint64_t *out_deleted);
+ddb_status_t ddb_db_wal_archive_enable(ddb_db_t *db, size_t capacity);
+ddb_status_t ddb_db_wal_archive_disable(ddb_db_t *db);
+ddb_status_t ddb_db_wal_archive_next_json(ddb_db_t *db, char **out_json);
+ddb_status_t ddb_db_open_session(ddb_db_t *db, ddb_db_t **out_db);
+ddb_status_t ddb_db_page_cache_stats_json(ddb_db_t *db, char **out_json);
 ddb_status_t ddb_db_begin_transaction(ddb_db_t *db);
 ddb_status_t ddb_db_commit_transaction(ddb_db_t *db, uint64_t *out_lsn);
 ddb_status_t ddb_db_rollback_transaction(ddb_db_t *db);
 ddb_status_t ddb_db_in_transaction(ddb_db_t *db, uint8_t *out_flag);
+ddb_status_t ddb_db_advisory_try_lock(ddb_db_t *db, int64_t key, uint8_t *out_flag);
+ddb_status_t ddb_db_advisory_unlock(ddb_db_t *db, int64_t key, uint8_t *out_flag);
 ddb_status_t ddb_db_save_as(ddb_db_t *db, const char *dest_path);
+
+/*
+ * Streaming backup. The handle pins a snapshot of the database; read the
+ * stream with ddb_backup_read until *out_read is 0, then release it with
+ * ddb_backup_free exactly once.
+ */
+ddb_status_t ddb_db_backup_begin(ddb_db_t *db, ddb_backup_t **out_backup);
+ddb_status_t ddb_backup_read(ddb_backup_t *backup, uint8_t *buf, size_t len, size_t *out_read);
+ddb_status_t ddb_backup_free(ddb_backup_t **backup);
+
+/*
+ * Reads the next chunk of the backup stream into raw and zstd-compresses it
+ * into out; *out_raw_read is 0 once the stream is complete.
+ */
+ddb_status_t ddb_backup_read_compressed(
+    ddb_backup_t *backup,
+    uint8_t *raw,
+    size_t raw_len,
+    int32_t level,
+    uint8_t *out,
+    size_t out_cap,
+    size_t *out_raw_read,
+    size_t *out_len);
+
+/*
+ * Restore. ddb_restore_begin creates the database file at path, which must
+ * not exist. Append the database bytes with ddb_restore_write, passing
+ * compressed non-zero for a zstd backup chunk of at most max_len bytes, then
+ * call ddb_restore_finish for the length and SHA-256 of what was written.
+ * Release the handle with ddb_restore_free exactly once; freeing an
+ * unfinished restore removes the file.
+ */
+ddb_status_t ddb_restore_begin(const char *path, ddb_restore_t **out_restore);
+ddb_status_t ddb_restore_write(
+    ddb_restore_t *restore,
+    const uint8_t *data,
+    size_t len,
+    uint8_t compressed,
+    size_t max_len,
+    size_t *out_written);
+ddb_status_t ddb_restore_finish(ddb_restore_t *restore, uint64_t *out_len, uint8_t *out_sha256);
+ddb_status_t ddb_restore_free(ddb_restore_t **restore);
+
 ddb_status_t ddb_db_list_tables_json(ddb_db_t *db, char **out_json);
 ddb_status_t ddb_db_describe_table_json(ddb_db_t *db, const char *name, char **out_json);
+ddb_status_t ddb_db_table_statistics_json(ddb_db_t *db, const char *name, char **out_json);
 ddb_status_t ddb_db_get_table_ddl(ddb_db_t *db, const char *name, char **out_ddl);
 ddb_status_t ddb_db_list_indexes_json(ddb_db_t *db, char **out_json);
 ddb_status_t ddb_db_list_views_json(ddb_db_t *db, char **out_json);
@@ -484,6 +592,12 @@ ddb_status_t ddb_db_get_schema_snapshot_json(ddb_db_t *db, char **out_json);
 ddb_status_t ddb_db_get_tooling_metadata_json(ddb_db_t *db, char **out_json);
 ddb_status_t ddb_db_describe_query_json(ddb_db_t *db, const char *sql, char **out_json);
 ddb_status_t ddb_db_inspect_storage_state_json(ddb_db_t *db, char **out_json);
+ddb_status_t ddb_db_recover_to_json(ddb_db_t *db, const char *dest_path, char **out_json);
+ddb_status_t ddb_db_clone_to_json(ddb_db_t *db, const char *dest_path, const char *options_json,
+                                  char **out_json);
+ddb_status_t ddb_db_diff_json(ddb_db_t *db, const char *other_path, char **out_json);
+ddb_status_t ddb_db_diff_changeset_json(ddb_db_t *db, const char *other_path, char **out_json);
+ddb_status_t ddb_db_verify_json(ddb_db_t *db, const char *other_path, char **out_json);
 
 /*
  * Lua extension package lifecycle JSON APIs.
@@ -521,7 +635,14 @@ ddb_status_t ddb_extension_purge_json(
     const char *request_json,
     char **out_json);
 
+/*
+ * Query fingerprint: sql with literals replaced by '?' and comments,
+ * whitespace, and keyword case normalized. Free with ddb_string_free.
+ */
+ddb_status_t ddb_normalize_query(const char *sql, char **out_fingerprint);
 ddb_status_t ddb_evict_shared_wal(const char *path);
+ddb_status_t ddb_vfs_register(const char *name, const ddb_vfs_methods_t *methods);
+ddb_status_t ddb_vfs_unregister(const char *name);
 
 /*
  * Frees a result handle returned by ddb_db_execute.
@@ -557,7 +678,7 @@ ddb_status_t ddb_result_value_copy(
  *
  * `kind` selects the trace view:
  *   "slow_queries", "lock_waits", "sessions",
- *   "index_usage", "doctor_findings", "fix_plan"
+ *   "index_usage", "doctor_findings", "fix_plan", "statement_stats"
  *
  * On success, `out_json` receives an owned JSON string.
  * The caller must free it with `ddb_string_free`.
@@ -570,7 +691,8 @@ ddb_status_t ddb_runtime_tracing_snapshot(
 /**
  * Reset a specific runtime trace ring buffer.
  *
- * `kind` may be "slow_queries", "lock_waits", or "index_usage".
+ * `kind` may be "slow_queries", "lock_waits", "index_usage", or
+ * "statement_stats".
  */
 ddb_status_t ddb_runtime_tracing_reset(
     ddb_db_t *db,
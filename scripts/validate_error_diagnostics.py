@@ -16,12 +16,17 @@ REQUIRED_SUBCODES = {
     "sql.parameter_missing": "errors/sql-parameter-missing",
     "sql.parameter_type_mismatch": "errors/sql-parameter-type-mismatch",
     "sql.unsupported_feature": "errors/sql-unsupported-feature",
+    "sql.memory_limit_exceeded": "errors/sql-memory-limit-exceeded",
+    "sql.result_row_limit_exceeded": "errors/sql-result-row-limit-exceeded",
+    "sql.statement_timeout": "errors/sql-statement-timeout",
     "constraint.unique": "errors/constraint-unique",
     "constraint.not_null": "errors/constraint-not-null",
     "constraint.check": "errors/constraint-check",
     "constraint.foreign_key": "errors/constraint-foreign-key",
     "transaction.no_active_transaction": "errors/transaction-no-active-transaction",
     "transaction.invalid_state": "errors/transaction-invalid-state",
+    "transaction.conflict": "errors/transaction-conflict",
+    "transaction.ddl_not_transactional": "errors/transaction-ddl-not-transactional",
     "queue.write_timeout": "errors/queue-write-timeout",
     "queue.canceled": "errors/queue-canceled",
     "queue.full": "errors/queue-full",
@@ -32,6 +37,7 @@ REQUIRED_SUBCODES = {
     "coordination.sidecar_unavailable": "errors/coordination-sidecar-unavailable",
     "io.permission_denied": "errors/io-permission-denied",
     "io.disk_full": "errors/io-disk-full",
+    "io.quota_exceeded": "errors/io-quota-exceeded",
     "io.not_found": "errors/io-not-found",
     "format.unsupported_version": "errors/format-unsupported-version",
     "corruption.database_header": "errors/corruption-database-header",